@@ -7,14 +7,25 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/ory/x/configx"
+	"github.com/ory/x/flagx"
 )
 
 func NewCleanupCmd() *cobra.Command {
 	c := &cobra.Command{
-		Use:   "cleanup",
+		Use:   "cleanup [<database-url>]",
 		Short: "Various cleanup helpers",
+		Long: `Use the --watch flag to keep running and clean up the SQL database in the interval configured by
+database.cleanup.interval, like "kratos cleanup sql --watch" does.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !flagx.MustGetBool(cmd, "watch") {
+				return cmd.Help()
+			}
+			return runCleanupSQL(cmd, args)
+		},
 	}
 	configx.RegisterFlags(c.PersistentFlags())
+	registerCleanupSQLFlags(c.Flags())
 	return c
 }
 
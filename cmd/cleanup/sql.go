@@ -10,6 +10,7 @@ import (
 	"github.com/ory/x/cmdx"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/ory/kratos/driver/config"
 
@@ -25,26 +26,36 @@ func NewCleanupSQLCmd() *cobra.Command {
 		Long: `Run this command as frequently as you need.
 It is recommended to run this command close to the SQL instance (e.g. same subnet) instead of over the public internet.
 This decreases risk of failure and decreases time required.
+Use the --watch flag to keep running and clean up in the interval configured by database.cleanup.interval.
 You can read in the database URL using the -e flag, for example:
 	export DSN=...
 	kratos cleanup sql -e
 ### WARNING ###
 Before running this command on an existing database, create a back up!
 `,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			err := cliclient.NewCleanupHandler().CleanupSQL(cmd, args)
-			if err != nil {
-				fmt.Fprintln(cmd.OutOrStdout(), err)
-				return cmdx.FailSilently(cmd)
-			}
-			return nil
-		},
+		RunE: runCleanupSQL,
 	}
 
 	configx.RegisterFlags(c.PersistentFlags())
-	c.Flags().BoolP("read-from-env", "e", true, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
-	c.Flags().Duration(config.ViperKeyDatabaseCleanupSleepTables, time.Minute, "How long to wait between each table cleanup")
-	c.Flags().IntP(config.ViperKeyDatabaseCleanupBatchSize, "b", 100, "Set the number of records to be cleaned per run")
-	c.Flags().Duration("keep-last", 0, "Don't remove records younger than")
+	registerCleanupSQLFlags(c.Flags())
 	return c
 }
+
+func runCleanupSQL(cmd *cobra.Command, args []string) error {
+	err := cliclient.NewCleanupHandler().CleanupSQL(cmd, args)
+	if err != nil {
+		fmt.Fprintln(cmd.OutOrStdout(), err)
+		return cmdx.FailSilently(cmd)
+	}
+	return nil
+}
+
+func registerCleanupSQLFlags(flags *pflag.FlagSet) {
+	flags.BoolP("read-from-env", "e", true, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
+	flags.Duration(config.ViperKeyDatabaseCleanupSleepTables, time.Minute, "How long to wait between each table cleanup")
+	flags.IntP(config.ViperKeyDatabaseCleanupBatchSize, "b", 100, "Set the number of records to be cleaned per run")
+	flags.Duration("keep-last", 0, "Don't remove records younger than")
+	flags.Bool("watch", false, "If set, keeps running and cleans up the database periodically")
+	flags.Duration(config.ViperKeyDatabaseCleanupInterval, time.Hour, "How long to wait between two cleanup runs when watching")
+	flags.String("metrics-listen", "", "If set together with --watch, serves Prometheus metrics about deleted rows on this address, for example :9090")
+}
@@ -25,3 +25,18 @@ func Test_ExecuteCleanupFailedDSN(t *testing.T) {
 	}
 	_ = cmd.Execute()
 }
+
+func Test_ExecuteCleanupWatchFailedDSN(t *testing.T) {
+	cmd := NewCleanupCmd()
+	b := bytes.NewBufferString("")
+	cmd.SetOut(b)
+	cmd.SetArgs([]string{"--watch", "--read-from-env=false"})
+	_ = cmd.Execute()
+	out, err := io.ReadAll(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "expected to get the DSN as an argument") {
+		t.Fatalf("expected \"%s\" got \"%s\"", "expected to get the DSN as an argument", string(out))
+	}
+}
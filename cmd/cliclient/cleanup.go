@@ -4,7 +4,12 @@
 package cliclient
 
 import (
+	"net/http"
+	"time"
+
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/ory/x/servicelocatorx"
 
@@ -16,6 +21,7 @@ import (
 
 	"github.com/ory/kratos/driver"
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/persistence"
 	"github.com/ory/x/flagx"
 )
 
@@ -58,14 +64,37 @@ func (h *CleanupHandler) CleanupSQL(cmd *cobra.Command, args []string) error {
 
 	keepLast := flagx.MustGetDuration(cmd, "keep-last")
 
-	err = d.Persister().CleanupDatabase(
-		cmd.Context(),
-		d.Config().DatabaseCleanupSleepTables(cmd.Context()),
-		keepLast,
-		d.Config().DatabaseCleanupBatchSize(cmd.Context()))
-	if err != nil {
-		return errors.Wrap(err, "An error occurred while cleaning up expired data")
+	var cleanupOpts []persistence.CleanupOption
+	if d.Config().DatabaseCleanupExpiredSessionsEnabled(cmd.Context()) {
+		cleanupOpts = append(cleanupOpts, persistence.WithExpiredSessionsCallback(d.SessionExpiredNotifier().Notify))
+	}
+
+	if !flagx.MustGetBool(cmd, "watch") {
+		if err := d.Janitor().Run(cmd.Context(), keepLast, cleanupOpts...); err != nil {
+			return errors.Wrap(err, "An error occurred while cleaning up expired data")
+		}
+		return nil
+	}
+
+	if addr := flagx.MustGetString(cmd, "metrics-listen"); addr != "" {
+		metrics := prometheus.NewRegistry()
+		if err := metrics.Register(d.Janitor()); err != nil {
+			return errors.WithStack(err)
+		}
+
+		server := &http.Server{
+			Addr:              addr,
+			Handler:           promhttp.HandlerFor(metrics, promhttp.HandlerOpts{}),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				d.Logger().WithError(err).Error("Unable to serve cleanup metrics.")
+			}
+		}()
+		defer server.Close()
 	}
 
+	d.Janitor().Watch(cmd.Context(), keepLast, cleanupOpts...)
 	return nil
 }
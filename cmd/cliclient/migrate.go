@@ -6,6 +6,7 @@ package cliclient
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -22,6 +23,8 @@ import (
 
 	"github.com/ory/kratos/driver"
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/persistence"
 	"github.com/ory/x/cmdx"
 	"github.com/ory/x/flagx"
 )
@@ -32,7 +35,7 @@ func NewMigrateHandler() *MigrateHandler {
 	return &MigrateHandler{}
 }
 
-func (h *MigrateHandler) MigrateSQL(cmd *cobra.Command, args []string, opts ...driver.RegistryOption) error {
+func (h *MigrateHandler) initRegistry(cmd *cobra.Command, args []string, opts ...driver.RegistryOption) (driver.Registry, error) {
 	var d driver.Registry
 	var err error
 
@@ -47,21 +50,18 @@ func (h *MigrateHandler) MigrateSQL(cmd *cobra.Command, args []string, opts ...d
 				configx.SkipValidation(),
 			})
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if len(d.Config().DSN(cmd.Context())) == 0 {
 			fmt.Println(cmd.UsageString())
 			fmt.Println("")
 			fmt.Println("When using flag -e, environment variable DSN must be set")
-			return cmdx.FailSilently(cmd)
-		}
-		if err != nil {
-			return err
+			return nil, cmdx.FailSilently(cmd)
 		}
 	} else {
 		if len(args) != 1 {
 			fmt.Println(cmd.UsageString())
-			return cmdx.FailSilently(cmd)
+			return nil, cmdx.FailSilently(cmd)
 		}
 		d, err = driver.NewWithoutInit(
 			cmd.Context(),
@@ -74,13 +74,39 @@ func (h *MigrateHandler) MigrateSQL(cmd *cobra.Command, args []string, opts ...d
 				configx.WithValue(config.ViperKeyDSN, args[0]),
 			})
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	err = d.Init(cmd.Context(), &contextx.Default{}, append(opts, driver.SkipNetworkInit)...)
 	if err != nil {
-		return errors.Wrap(err, "an error occurred initializing migrations")
+		return nil, errors.Wrap(err, "an error occurred initializing migrations")
+	}
+
+	return d, nil
+}
+
+func (h *MigrateHandler) MigrateSQL(cmd *cobra.Command, args []string, opts ...driver.RegistryOption) error {
+	d, err := h.initRegistry(cmd, args, opts...)
+	if err != nil {
+		return err
+	}
+
+	if flagx.MustGetBool(cmd, "dry-run") {
+		scripts, err := d.Persister().PendingMigrationScripts(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "an error occurred rendering the pending migrations")
+		}
+
+		if len(scripts) == 0 {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "All migrations are already applied.")
+			return nil
+		}
+
+		for _, s := range scripts {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "-- Migration %s: %s\n%s\n\n", s.Version, s.Name, strings.TrimSpace(s.SQL))
+		}
+		return nil
 	}
 
 	var plan bytes.Buffer
@@ -108,6 +134,122 @@ func (h *MigrateHandler) MigrateSQL(cmd *cobra.Command, args []string, opts ...d
 	return nil
 }
 
+func (h *MigrateHandler) MigrationStatus(cmd *cobra.Command, args []string, opts ...driver.RegistryOption) error {
+	d, err := h.initRegistry(cmd, args, opts...)
+	if err != nil {
+		return err
+	}
+
+	report, err := d.Persister().MigrationReport(cmd.Context())
+	if err != nil {
+		return errors.Wrap(err, "an error occurred reading the migration status")
+	}
+
+	cmdx.PrintTable(cmd, report)
+
+	if len(report.Drift) > 0 {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "\nThe database schema drifted from the migrations of this version:\n%s", report.DriftString())
+		return cmdx.FailSilently(cmd)
+	}
+
+	if flagx.MustGetBool(cmd, "block") && report.HasPending() {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "\nThe database has pending migrations.")
+		return cmdx.FailSilently(cmd)
+	}
+
+	return nil
+}
+
+func (h *MigrateHandler) Backfill(cmd *cobra.Command, args []string, opts ...driver.RegistryOption) error {
+	d, err := h.initRegistry(cmd, args, opts...)
+	if err != nil {
+		return err
+	}
+
+	migrations, err := d.Persister().PhasedMigrations(cmd.Context())
+	if err != nil {
+		return errors.Wrap(err, "an error occurred reading the phased migrations")
+	}
+
+	name := flagx.MustGetString(cmd, "name")
+	var found bool
+	for _, m := range migrations {
+		if name != "" && m.Name != name {
+			continue
+		}
+		found = true
+		if m.Phase == persistence.PhasedMigrationPhaseBackfilled {
+			continue
+		}
+
+		processed, remaining := m.ProcessedRows, m.RemainingRows
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Backfilling phased migration %s (%d rows remaining).\n", m.Name, remaining)
+		if err := d.PhasedMigrationRunner().Run(cmd.Context(), m.Name, func(n int) {
+			processed += int64(n)
+			remaining = max(remaining-int64(n), 0)
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%s: %d rows backfilled, %d rows remaining\n", m.Name, processed, remaining)
+		}); err != nil {
+			return errors.Wrapf(err, "an error occurred backfilling phased migration %s", m.Name)
+		}
+	}
+
+	if name != "" && !found {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "The phased migration %s is not known to this version of Ory Kratos.\n", name)
+		return cmdx.FailSilently(cmd)
+	}
+
+	_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "All phased migrations are backfilled.")
+	return nil
+}
+
+func (h *MigrateHandler) MigrateIdentities(cmd *cobra.Command, args []string, opts ...driver.RegistryOption) error {
+	d, err := h.initRegistry(cmd, args, opts...)
+	if err != nil {
+		return err
+	}
+
+	body := identity.MigrateIdentitiesBody{
+		From:     flagx.MustGetString(cmd, "from"),
+		To:       flagx.MustGetString(cmd, "to"),
+		DryRun:   flagx.MustGetBool(cmd, "dry-run"),
+		PageSize: flagx.MustGetInt(cmd, "page-size"),
+	}
+
+	var processed, migrated, failed int
+	failures := json.NewEncoder(cmd.OutOrStdout())
+	for {
+		report, err := d.IdentityMigrator().MigrateIdentities(cmd.Context(), &body)
+		if err != nil {
+			return errors.Wrapf(err, "an error occurred migrating identities from %s to %s", body.From, body.To)
+		}
+
+		for _, f := range report.Failed {
+			if err := failures.Encode(f); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		processed, migrated, failed = processed+report.Processed, migrated+report.Migrated, failed+len(report.Failed)
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%d identities processed, %d migrated, %d failed\n", processed, migrated, failed)
+
+		if report.NextPageToken == "" {
+			break
+		}
+		body.PageToken = report.NextPageToken
+	}
+
+	if body.DryRun {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "This was a dry run, no identity was changed.")
+	}
+	if failed > 0 {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "%d identities do not match the identity schema %s and were not migrated.\n", failed, body.To)
+		return cmdx.FailSilently(cmd)
+	}
+
+	_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "All identities are migrated.")
+	return nil
+}
+
 func askForConfirmation(s string) bool {
 	reader := bufio.NewReader(os.Stdin)
 
@@ -0,0 +1,65 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package cliclient
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/ory/kratos/driver"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/x/configx"
+	"github.com/ory/x/contextx"
+	"github.com/ory/x/flagx"
+	"github.com/ory/x/servicelocatorx"
+)
+
+type VerificationHandler struct{}
+
+func NewVerificationHandler() *VerificationHandler {
+	return &VerificationHandler{}
+}
+
+func (h *VerificationHandler) Remind(cmd *cobra.Command, args []string) error {
+	opts := []configx.OptionModifier{
+		configx.WithFlags(cmd.Flags()),
+		configx.SkipValidation(),
+	}
+
+	if !flagx.MustGetBool(cmd, "read-from-env") {
+		if len(args) != 1 {
+			return errors.New(`expected to get the DSN as an argument, or the "read-from-env" flag`)
+		}
+		opts = append(opts, configx.WithValue(config.ViperKeyDSN, args[0]))
+	}
+
+	d, err := driver.NewWithoutInit(
+		cmd.Context(),
+		cmd.ErrOrStderr(),
+		servicelocatorx.NewOptions(),
+		nil,
+		opts,
+	)
+	if len(d.Config().DSN(cmd.Context())) == 0 {
+		return errors.New(`required config value "dsn" was not set`)
+	} else if err != nil {
+		return errors.Wrap(err, "An error occurred initializing verification reminders")
+	}
+
+	if err := d.Init(cmd.Context(), &contextx.Default{}); err != nil {
+		return errors.Wrap(err, "An error occurred initializing verification reminders")
+	}
+
+	report, err := d.VerificationReminder().Run(cmd.Context(), flagx.MustGetInt(cmd, "batch-size"))
+	if err != nil {
+		return errors.Wrap(err, "An error occurred while sending verification reminders")
+	}
+
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(),
+		"Sent %d reminders, deactivated %d and deleted %d unverified accounts. Skipped %d opted-out identities, failed to process %d identities.\n",
+		report.RemindersSent, report.AccountsDeactivated, report.AccountsDeleted, report.IdentitiesOptedOut, report.IdentitiesFailed)
+	return nil
+}
@@ -46,6 +46,8 @@ func init() {
 		"NewInfoNodeLabelVerifyOTP":                  text.NewInfoNodeLabelVerifyOTP(),
 		"NewInfoNodeLabelVerificationCode":           text.NewInfoNodeLabelVerificationCode(),
 		"NewInfoNodeLabelRecoveryCode":               text.NewInfoNodeLabelRecoveryCode(),
+		"NewInfoNodeLabelRecoveryAddress":            text.NewInfoNodeLabelRecoveryAddress("j***@example.com"),
+		"NewInfoNodeLabelCodeChannel":                text.NewInfoNodeLabelCodeChannel("phone", "+49•••123"),
 		"NewInfoNodeInputPassword":                   text.NewInfoNodeInputPassword(),
 		"NewInfoNodeLabelGenerated":                  text.NewInfoNodeLabelGenerated("{title}"),
 		"NewInfoNodeLabelSave":                       text.NewInfoNodeLabelSave(),
@@ -71,7 +73,9 @@ func init() {
 		"NewInfoSelfServiceSettingsUpdateLinkOIDC":                text.NewInfoSelfServiceSettingsUpdateLinkOIDC("{provider}"),
 		"NewInfoSelfServiceSettingsUpdateUnlinkOIDC":              text.NewInfoSelfServiceSettingsUpdateUnlinkOIDC("{provider}"),
 		"NewInfoSelfServiceRegisterWebAuthnDisplayName":           text.NewInfoSelfServiceRegisterWebAuthnDisplayName(),
-		"NewInfoSelfServiceRemoveWebAuthn":                        text.NewInfoSelfServiceRemoveWebAuthn("{display_name}", aSecondAgo),
+		"NewInfoSelfServiceRemoveWebAuthn":                        text.NewInfoSelfServiceRemoveWebAuthn("{display_name}", aSecondAgo, &aSecondAgo),
+		"NewInfoSelfServiceRenameWebAuthn":                        text.NewInfoSelfServiceRenameWebAuthn("{display_name}", aSecondAgo, &aSecondAgo),
+		"NewInfoSelfServiceRenameWebAuthnDisplayName":             text.NewInfoSelfServiceRenameWebAuthnDisplayName(),
 		"NewErrorValidationVerificationFlowExpired":               text.NewErrorValidationVerificationFlowExpired(aSecondAgo),
 		"NewInfoSelfServiceVerificationSuccessful":                text.NewInfoSelfServiceVerificationSuccessful(),
 		"NewVerificationEmailSent":                                text.NewVerificationEmailSent(),
@@ -116,6 +120,7 @@ func init() {
 		"NewErrorValidationNoWebAuthnDevice":                      text.NewErrorValidationNoWebAuthnDevice(),
 		"NewInfoLoginReAuth":                                      text.NewInfoLoginReAuth(),
 		"NewInfoLoginMFA":                                         text.NewInfoLoginMFA(),
+		"NewInfoLoginSessionExpired":                              text.NewInfoLoginSessionExpired(),
 		"NewInfoLoginTOTPLabel":                                   text.NewInfoLoginTOTPLabel(),
 		"NewInfoLoginLookupLabel":                                 text.NewInfoLoginLookupLabel(),
 		"NewInfoLogin":                                            text.NewInfoLogin(),
@@ -133,6 +138,7 @@ func init() {
 		"NewErrorValidationRecoveryNoStrategyFound":               text.NewErrorValidationRecoveryNoStrategyFound(),
 		"NewErrorValidationVerificationNoStrategyFound":           text.NewErrorValidationVerificationNoStrategyFound(),
 		"NewInfoSelfServiceLoginWebAuthn":                         text.NewInfoSelfServiceLoginWebAuthn(),
+		"NewInfoSelfServiceLoginPasskey":                          text.NewInfoSelfServiceLoginPasskey(),
 		"NewInfoRegistration":                                     text.NewInfoRegistration(),
 		"NewInfoRegistrationWith":                                 text.NewInfoRegistrationWith("{provider}"),
 		"NewInfoRegistrationContinue":                             text.NewInfoRegistrationContinue(),
@@ -141,8 +147,10 @@ func init() {
 		"NewRecoverySuccessful":                                   text.NewRecoverySuccessful(inAMinute),
 		"NewRecoveryEmailSent":                                    text.NewRecoveryEmailSent(),
 		"NewRecoveryEmailWithCodeSent":                            text.NewRecoveryEmailWithCodeSent(),
+		"NewRecoveryChooseAddress":                                text.NewRecoveryChooseAddress(),
 		"NewErrorValidationRecoveryTokenInvalidOrAlreadyUsed":     text.NewErrorValidationRecoveryTokenInvalidOrAlreadyUsed(),
 		"NewErrorValidationRecoveryCodeInvalidOrAlreadyUsed":      text.NewErrorValidationRecoveryCodeInvalidOrAlreadyUsed(),
+		"NewErrorValidationRecoveryCodeSubmittedTooOften":         text.NewErrorValidationRecoveryCodeSubmittedTooOften(),
 		"NewErrorValidationRecoveryRetrySuccess":                  text.NewErrorValidationRecoveryRetrySuccess(),
 		"NewErrorValidationRecoveryStateFailure":                  text.NewErrorValidationRecoveryStateFailure(),
 		"NewInfoNodeInputEmail":                                   text.NewInfoNodeInputEmail(),
@@ -152,11 +160,14 @@ func init() {
 		"NewInfoSelfServiceSettingsRegisterWebAuthn":              text.NewInfoSelfServiceSettingsRegisterWebAuthn(),
 		"NewInfoLoginWebAuthnPasswordless":                        text.NewInfoLoginWebAuthnPasswordless(),
 		"NewInfoSelfServiceRegistrationRegisterWebAuthn":          text.NewInfoSelfServiceRegistrationRegisterWebAuthn(),
+		"NewInfoSelfServiceRegistrationPendingApproval":           text.NewInfoSelfServiceRegistrationPendingApproval(),
 		"NewInfoSelfServiceContinueLoginWebAuthn":                 text.NewInfoSelfServiceContinueLoginWebAuthn(),
 		"NewInfoSelfServiceLoginContinue":                         text.NewInfoSelfServiceLoginContinue(),
 		"NewErrorValidationSuchNoWebAuthnUser":                    text.NewErrorValidationSuchNoWebAuthnUser(),
 		"NewRegistrationEmailWithCodeSent":                        text.NewRegistrationEmailWithCodeSent(),
 		"NewLoginEmailWithCodeSent":                               text.NewLoginEmailWithCodeSent(),
+		"NewLoginSMSWithCodeSent":                                 text.NewLoginSMSWithCodeSent(),
+		"NewInfoSelfServiceLoginCodeChooseChannel":                text.NewInfoSelfServiceLoginCodeChooseChannel(),
 		"NewErrorValidationRegistrationCodeInvalidOrAlreadyUsed":  text.NewErrorValidationRegistrationCodeInvalidOrAlreadyUsed(),
 		"NewErrorValidationLoginCodeInvalidOrAlreadyUsed":         text.NewErrorValidationLoginCodeInvalidOrAlreadyUsed(),
 		"NewErrorValidationNoCodeUser":                            text.NewErrorValidationNoCodeUser(),
@@ -164,10 +175,44 @@ func init() {
 		"NewInfoNodeLabelLoginCode":                               text.NewInfoNodeLabelLoginCode(),
 		"NewErrorValidationLoginRetrySuccessful":                  text.NewErrorValidationLoginRetrySuccessful(),
 		"NewErrorValidationTraitsMismatch":                        text.NewErrorValidationTraitsMismatch(),
+		"NewErrorValidationNoPushDevice":                          text.NewErrorValidationNoPushDevice(),
+		"NewErrorValidationPushDenied":                            text.NewErrorValidationPushDenied(),
+		"NewErrorValidationPushExpired":                           text.NewErrorValidationPushExpired(),
+		"NewInfoSelfServiceLoginPush":                             text.NewInfoSelfServiceLoginPush(),
+		"NewInfoSelfServiceLoginPushSent":                         text.NewInfoSelfServiceLoginPushSent(aSecondAgo),
+		"NewInfoSelfServiceSettingsRegisterPush":                  text.NewInfoSelfServiceSettingsRegisterPush(),
+		"NewInfoSelfServiceRegisterPushDisplayName":               text.NewInfoSelfServiceRegisterPushDisplayName(),
+		"NewInfoSelfServiceRegisterPushToken":                     text.NewInfoSelfServiceRegisterPushToken(),
+		"NewInfoSelfServiceRegisterPushPlatform":                  text.NewInfoSelfServiceRegisterPushPlatform(),
+		"NewInfoSelfServiceRemovePush":                            text.NewInfoSelfServiceRemovePush("{display_name}", aSecondAgo),
 		"NewInfoSelfServiceLoginCode":                             text.NewInfoSelfServiceLoginCode(),
 		"NewErrorValidationRegistrationRetrySuccessful":           text.NewErrorValidationRegistrationRetrySuccessful(),
 		"NewInfoSelfServiceRegistrationRegisterCode":              text.NewInfoSelfServiceRegistrationRegisterCode(),
 		"NewErrorValidationLoginLinkedCredentialsDoNotMatch":      text.NewErrorValidationLoginLinkedCredentialsDoNotMatch(),
+		"NewErrorValidationLoginLocked":                           text.NewErrorValidationLoginLocked(inAMinute),
+		"NewErrorValidationLoginRiskDenied":                       text.NewErrorValidationLoginRiskDenied(),
+		"NewInfoSelfServiceLoginRememberDevice":                   text.NewInfoSelfServiceLoginRememberDevice(),
+		"NewInfoSelfServiceSettingsRevokeTrustedDevice":           text.NewInfoSelfServiceSettingsRevokeTrustedDevice("{user_agent}", aSecondAgo),
+		"NewInfoSelfServiceSettingsRevokeAllTrustedDevices":       text.NewInfoSelfServiceSettingsRevokeAllTrustedDevices(),
+		"NewInfoSelfServiceSettingsRequiredAction":                text.NewInfoSelfServiceSettingsRequiredAction("review_mfa"),
+		"NewInfoSelfServiceSettingsEmailChangePending":            text.NewInfoSelfServiceSettingsEmailChangePending("{address}"),
+		"NewInfoSelfServiceSettingsRevokeSession":                 text.NewInfoSelfServiceSettingsRevokeSession("{user_agent}", "{ip_address}", "{location}", aSecondAgo, "aal1"),
+		"NewInfoSelfServiceSettingsRevokeOtherSessions":           text.NewInfoSelfServiceSettingsRevokeOtherSessions(),
+		"NewInfoSelfServiceLoginLookupSecretsLow":                 text.NewInfoSelfServiceLoginLookupSecretsLow(2),
+		"NewErrorValidationInvalidPhoneNumber":                    text.NewErrorValidationInvalidPhoneNumber(),
+		"NewRecoverySMSWithCodeSent":                              text.NewRecoverySMSWithCodeSent(),
+		"NewInfoNodeInputPhoneNumber":                             text.NewInfoNodeInputPhoneNumber(),
+		"NewInfoSelfServiceLoginExternalMFA":                      text.NewInfoSelfServiceLoginExternalMFA(),
+		"NewInfoSelfServiceLoginExternalMFAPending":               text.NewInfoSelfServiceLoginExternalMFAPending(aSecondAgo),
+		"NewErrorValidationExternalMFADenied":                     text.NewErrorValidationExternalMFADenied(),
+		"NewErrorValidationExternalMFAExpired":                    text.NewErrorValidationExternalMFAExpired(),
+		"NewErrorValidationEmailDomainNotAllowed":                 text.NewErrorValidationEmailDomainNotAllowed("{domain}"),
+		"NewErrorValidationDisposableEmail":                       text.NewErrorValidationDisposableEmail("{domain}"),
+		"NewInfoNodeLabelConsent":                                 text.NewInfoNodeLabelConsent("{id}", "{version}", "{url}", "{label}"),
+		"NewErrorValidationConsentRequired":                       text.NewErrorValidationConsentRequired("{id}"),
+		"NewInfoNodeLabelSignupCode":                              text.NewInfoNodeLabelSignupCode(),
+		"NewErrorValidationRegistrationSignupCodeInvalid":         text.NewErrorValidationRegistrationSignupCodeInvalid(),
+		"NewErrorValidationRegistrationSignupCodeRequired":        text.NewErrorValidationRegistrationSignupCodeRequired(),
 	}
 }
 
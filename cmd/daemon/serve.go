@@ -14,6 +14,7 @@ import (
 	"github.com/ory/x/otelx/semconv"
 
 	"github.com/pkg/errors"
+	promclient "github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
 	"github.com/urfave/negroni"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
@@ -325,6 +326,36 @@ func bgTasks(d driver.Registry, cmd *cobra.Command, opts []Option) error {
 	modifiers := NewOptions(cmd.Context(), opts)
 	ctx := modifiers.ctx
 
+	if d.Config().DatabaseTableMetricsEnabled(ctx) {
+		collector := d.TableMetricsCollector()
+		if err := promclient.Register(collector); err != nil && !errors.As(err, new(promclient.AlreadyRegisteredError)) {
+			return errors.WithStack(err)
+		}
+		go collector.Watch(ctx)
+	}
+
+	if d.Config().SessionWhoAmICacheEnabled(ctx) {
+		if err := promclient.Register(d.SessionCache()); err != nil && !errors.As(err, new(promclient.AlreadyRegisteredError)) {
+			return errors.WithStack(err)
+		}
+	}
+
+	if d.Config().DatabasePhasedMigrationsBackfillEnabled(ctx) {
+		go d.PhasedMigrationRunner().Watch(ctx)
+	}
+
+	if d.Config().SelfServiceFlowVerificationRemindersBackground(ctx) {
+		reminder := d.VerificationReminder()
+		if err := promclient.Register(reminder); err != nil && !errors.As(err, new(promclient.AlreadyRegisteredError)) {
+			return errors.WithStack(err)
+		}
+		go reminder.Watch(ctx, d.Config().SelfServiceFlowVerificationRemindersBatchSize(ctx))
+	}
+
+	if d.Config().IdentityEventsEnabled(ctx) {
+		go d.IdentityEventRelay().Watch(ctx, 100)
+	}
+
 	if d.Config().IsBackgroundCourierEnabled(ctx) {
 		return courier.Watch(ctx, d)
 	}
@@ -0,0 +1,37 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package migrate
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ory/kratos/cmd/cliclient"
+	"github.com/ory/kratos/driver"
+	"github.com/ory/x/configx"
+)
+
+func NewMigrateBackfillCmd(opts ...driver.RegistryOption) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "backfill <database-url>",
+		Short: "Backfill phased migrations",
+		Long: `Copies the existing rows of phased migrations to the new schema in small batches. The backfill
+can run while Ory Kratos serves traffic and can be interrupted and resumed at any time.
+
+Once a phased migration is backfilled, add its name to "database.phased_migrations.cutover" to
+read from the new schema.
+
+You can read in the database URL using the -e flag, for example:
+	export DSN=...
+	kratos migrate backfill -e
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cliclient.NewMigrateHandler().Backfill(cmd, args, opts...)
+		},
+	}
+
+	configx.RegisterFlags(c.PersistentFlags())
+	c.Flags().BoolP("read-from-env", "e", false, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
+	c.Flags().String("name", "", "If set, only backfills the phased migration with this name.")
+	return c
+}
@@ -0,0 +1,42 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package migrate
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ory/kratos/cmd/cliclient"
+	"github.com/ory/kratos/driver"
+	"github.com/ory/x/configx"
+)
+
+func NewMigrateIdentitiesCmd(opts ...driver.RegistryOption) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "identities <database-url>",
+		Short: "Migrate identities to another identity schema",
+		Long: `Migrates all identities which use the identity schema --from to the identity schema --to. The traits and
+metadata of the identities are transformed using the migration configured in "identity.migrations", and are then
+validated against the new identity schema. Identities which fail the new identity schema are not changed and are
+printed as JSON, one per line.
+
+Use --dry-run to find the identities which fail the new identity schema without changing any identity. If --from
+and --to are the same identity schema, the identities are validated against the current version of their schema.
+
+You can read in the database URL using the -e flag, for example:
+	export DSN=...
+	kratos migrate identities -e -c path/to/config.yml --from employee --to employee-v2
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cliclient.NewMigrateHandler().MigrateIdentities(cmd, args, opts...)
+		},
+	}
+
+	configx.RegisterFlags(c.PersistentFlags())
+	c.Flags().BoolP("read-from-env", "e", false, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
+	c.Flags().String("from", "", "The ID of the identity schema the identities are migrated from.")
+	c.Flags().String("to", "", "The ID of the identity schema the identities are migrated to.")
+	c.Flags().Bool("dry-run", false, "If set, validates the migrated identities without storing them.")
+	c.Flags().Int("page-size", 250, "The number of identities migrated at once.")
+	return c
+}
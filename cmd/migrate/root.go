@@ -18,4 +18,7 @@ func RegisterCommandRecursive(parent *cobra.Command) {
 	c := NewMigrateCmd()
 	parent.AddCommand(c)
 	c.AddCommand(NewMigrateSQLCmd())
+	c.AddCommand(NewMigrateStatusCmd())
+	c.AddCommand(NewMigrateBackfillCmd())
+	c.AddCommand(NewMigrateIdentitiesCmd())
 }
@@ -25,6 +25,9 @@ You can read in the database URL using the -e flag, for example:
 	export DSN=...
 	kratos migrate sql -e
 
+Use --dry-run to print the SQL of all pending migrations without applying them, for example to
+have them reviewed before they are applied.
+
 ### WARNING ###
 
 Before running this command on an existing database, create a back up!
@@ -37,5 +40,6 @@ Before running this command on an existing database, create a back up!
 	configx.RegisterFlags(c.PersistentFlags())
 	c.Flags().BoolP("read-from-env", "e", false, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
 	c.Flags().BoolP("yes", "y", false, "If set all confirmation requests are accepted without user interaction.")
+	c.Flags().Bool("dry-run", false, "If set, prints the SQL of the pending migrations without applying them.")
 	return c
 }
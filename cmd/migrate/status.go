@@ -0,0 +1,39 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package migrate
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ory/kratos/cmd/cliclient"
+	"github.com/ory/kratos/driver"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/configx"
+)
+
+func NewMigrateStatusCmd(opts ...driver.RegistryOption) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "status <database-url>",
+		Short: "Show applied and pending SQL migrations and detect schema drift",
+		Long: `Lists all SQL migrations of this version of Ory Kratos for the database dialect, whether they were
+applied and the SHA-256 checksum of each migration file.
+
+The command fails if the live database schema drifted from the migrations, for example because
+migrations of a newer Ory Kratos version were applied, migrations were skipped, or tables are missing.
+
+You can read in the database URL using the -e flag, for example:
+	export DSN=...
+	kratos migrate status -e
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cliclient.NewMigrateHandler().MigrationStatus(cmd, args, opts...)
+		},
+	}
+
+	configx.RegisterFlags(c.PersistentFlags())
+	cmdx.RegisterFormatFlags(c.PersistentFlags())
+	c.Flags().BoolP("read-from-env", "e", false, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
+	c.Flags().Bool("block", false, "If set, the command fails if migrations are pending.")
+	return c
+}
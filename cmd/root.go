@@ -21,6 +21,7 @@ import (
 	"github.com/ory/kratos/cmd/jsonnet"
 	"github.com/ory/kratos/cmd/migrate"
 	"github.com/ory/kratos/cmd/serve"
+	"github.com/ory/kratos/cmd/verification"
 	"github.com/ory/x/cmdx"
 
 	"github.com/spf13/cobra"
@@ -43,6 +44,7 @@ func NewRootCmd() (cmd *cobra.Command) {
 	migrate.RegisterCommandRecursive(cmd)
 	serve.RegisterCommandRecursive(cmd, nil, nil)
 	cleanup.RegisterCommandRecursive(cmd)
+	verification.RegisterCommandRecursive(cmd)
 	remote.RegisterCommandRecursive(cmd)
 	cmd.AddCommand(identities.NewValidateCmd())
 	cmd.AddCommand(cmdx.Version(&config.Version, &config.Commit, &config.Date))
@@ -4,7 +4,10 @@
 package serve
 
 import (
+	"context"
+
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/hash"
 	"github.com/ory/x/configx"
 	"github.com/ory/x/servicelocatorx"
 
@@ -46,6 +49,10 @@ DON'T DO THIS IN PRODUCTION!
 				d.Logger().Warnf("Config version is '%s' but kratos runs on version '%s'", configVersion, config.Version)
 			}
 
+			if err := calibrateArgon2(ctx, d); err != nil {
+				return err
+			}
+
 			return daemon.ServeAll(d, sl, nil)(cmd, args)
 		},
 	}
@@ -57,6 +64,34 @@ DON'T DO THIS IN PRODUCTION!
 	return serveCmd
 }
 
+// calibrateArgon2 benchmarks the host and logs or applies the derived Argon2 parameters, depending on
+// `hashers.argon2.calibration.mode`.
+func calibrateArgon2(ctx context.Context, d driver.Registry) error {
+	conf := d.Config()
+	mode := conf.HasherArgon2CalibrationMode(ctx)
+	if mode == config.Argon2CalibrationModeOff || conf.HasherPasswordHashingAlgorithm(ctx) != "argon2" {
+		return nil
+	}
+
+	d.Logger().Info("Calibrating Argon2 parameters, this might take a while.")
+	calibrated, err := hash.CalibrateArgon2(ctx, conf.HasherArgon2(ctx), conf.HasherArgon2CalibrationMaxMemory(ctx))
+	if err != nil {
+		return err
+	}
+
+	l := d.Logger().
+		WithField(config.ViperKeyHasherArgon2ConfigMemory, calibrated.Memory.String()).
+		WithField(config.ViperKeyHasherArgon2ConfigIterations, calibrated.Iterations)
+	if mode != config.Argon2CalibrationModeApply {
+		l.Info("Calibrated Argon2 parameters. Persist them in the configuration or set `hashers.argon2.calibration.mode` to `apply` to use them.")
+		return nil
+	}
+
+	conf.SetHasherArgon2(ctx, calibrated)
+	l.Info("Calibrated and applied Argon2 parameters until the next restart. Persist them in the configuration to skip the calibration.")
+	return nil
+}
+
 func RegisterCommandRecursive(parent *cobra.Command, slOpts []servicelocatorx.Option, dOpts []driver.RegistryOption) {
 	parent.AddCommand(NewServeCmd(slOpts, dOpts))
 }
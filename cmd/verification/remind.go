@@ -0,0 +1,44 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package verification
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ory/kratos/cmd/cliclient"
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/configx"
+)
+
+func NewRemindCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "remind <database-url>",
+		Short: "Send verification reminders and expire unverified accounts",
+		Long: `Sends reminder emails to identities with unverified addresses and deactivates or deletes
+accounts which stay unverified, following the policy in selfservice.flows.verification.reminders.
+
+Run this command periodically, for example once an hour from a cron job. Reminders are queued
+in the courier and sent by "kratos courier watch" or "kratos serve --watch-courier".
+
+You can read in the database URL using the -e flag, for example:
+	export DSN=...
+	kratos verification remind -e
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := cliclient.NewVerificationHandler().Remind(cmd, args)
+			if err != nil {
+				fmt.Fprintln(cmd.OutOrStdout(), err)
+				return cmdx.FailSilently(cmd)
+			}
+			return nil
+		},
+	}
+
+	configx.RegisterFlags(c.PersistentFlags())
+	c.Flags().BoolP("read-from-env", "e", true, "If set, reads the database connection string from the environment variable DSN or config file key dsn.")
+	c.Flags().IntP("batch-size", "b", 100, "Set the number of addresses to be processed per batch")
+	return c
+}
@@ -0,0 +1,25 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package verification
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ory/x/configx"
+)
+
+func NewVerificationCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "verification",
+		Short: "Verification helpers",
+	}
+	configx.RegisterFlags(c.PersistentFlags())
+	return c
+}
+
+func RegisterCommandRecursive(parent *cobra.Command) {
+	c := NewVerificationCmd()
+	parent.AddCommand(c)
+	c.AddCommand(NewRemindCmd())
+}
@@ -25,6 +25,7 @@ type (
 		x.TracingProvider
 		x.LoggingProvider
 		ConfigProvider
+		config.Provider
 		x.HTTPClientProvider
 		jsonnetsecure.VMProvider
 	}
@@ -33,6 +34,7 @@ type (
 		Work(ctx context.Context) error
 		QueueEmail(ctx context.Context, t EmailTemplate) (uuid.UUID, error)
 		QueueSMS(ctx context.Context, t SMSTemplate) (uuid.UUID, error)
+		QueueWhatsApp(ctx context.Context, t WhatsAppTemplate) (uuid.UUID, error)
 		SmtpDialer() *gomail.Dialer
 		DispatchQueue(ctx context.Context) error
 		DispatchMessage(ctx context.Context, msg Message) error
@@ -52,6 +54,7 @@ type (
 
 	courier struct {
 		smsClient           *smsClient
+		whatsAppClient      *whatsAppClient
 		smtpClient          *smtpClient
 		httpClient          *httpClient
 		deps                Dependencies
@@ -66,11 +69,12 @@ func NewCourier(ctx context.Context, deps Dependencies) (Courier, error) {
 		return nil, err
 	}
 	return &courier{
-		smsClient:  newSMS(ctx, deps),
-		smtpClient: smtp,
-		httpClient: newHTTP(ctx, deps),
-		deps:       deps,
-		backoff:    backoff.NewExponentialBackOff(),
+		smsClient:      newSMS(ctx, deps),
+		whatsAppClient: newWhatsApp(ctx, deps),
+		smtpClient:     smtp,
+		httpClient:     newHTTP(ctx, deps),
+		deps:           deps,
+		backoff:        backoff.NewExponentialBackOff(),
 	}, nil
 }
 
@@ -28,6 +28,10 @@ func (c *courier) DispatchMessage(ctx context.Context, msg Message) error {
 		if err := c.dispatchSMS(ctx, msg); err != nil {
 			return err
 		}
+	case MessageTypeWhatsApp:
+		if err := c.dispatchWhatsApp(ctx, msg); err != nil {
+			return err
+		}
 	default:
 		return errors.Errorf("received unexpected message type: %d", msg.Type)
 	}
@@ -42,6 +42,12 @@ const (
 	TypeTestStub                TemplateType = "stub"
 	TypeLoginCodeValid          TemplateType = "login_code_valid"
 	TypeRegistrationCodeValid   TemplateType = "registration_code_valid"
+	TypeRecoveryNotification    TemplateType = "recovery_notification"
+	TypeLoginNewDevice          TemplateType = "login_new_device"
+	TypeRegistrationApproved    TemplateType = "registration_approved"
+	TypeEmailChangeConfirm      TemplateType = "email_change_confirm"
+	TypeEmailChangeNotice       TemplateType = "email_change_notice"
+	TypeRegistrationDuplicate   TemplateType = "registration_duplicate"
 )
 
 func GetEmailTemplateType(t EmailTemplate) (TemplateType, error) {
@@ -66,6 +72,18 @@ func GetEmailTemplateType(t EmailTemplate) (TemplateType, error) {
 		return TypeLoginCodeValid, nil
 	case *email.RegistrationCodeValid:
 		return TypeRegistrationCodeValid, nil
+	case *email.RecoveryNotification:
+		return TypeRecoveryNotification, nil
+	case *email.LoginNewDevice:
+		return TypeLoginNewDevice, nil
+	case *email.RegistrationApproved:
+		return TypeRegistrationApproved, nil
+	case *email.EmailChangeConfirm:
+		return TypeEmailChangeConfirm, nil
+	case *email.EmailChangeNotice:
+		return TypeEmailChangeNotice, nil
+	case *email.RegistrationDuplicate:
+		return TypeRegistrationDuplicate, nil
 	case *email.TestStub:
 		return TypeTestStub, nil
 	default:
@@ -141,6 +159,42 @@ func NewEmailTemplateFromMessage(d template.Dependencies, msg Message) (EmailTem
 			return nil, err
 		}
 		return email.NewRegistrationCodeValid(d, &t), nil
+	case TypeRecoveryNotification:
+		var t email.RecoveryNotificationModel
+		if err := json.Unmarshal(msg.TemplateData, &t); err != nil {
+			return nil, err
+		}
+		return email.NewRecoveryNotification(d, &t), nil
+	case TypeLoginNewDevice:
+		var t email.LoginNewDeviceModel
+		if err := json.Unmarshal(msg.TemplateData, &t); err != nil {
+			return nil, err
+		}
+		return email.NewLoginNewDevice(d, &t), nil
+	case TypeRegistrationApproved:
+		var t email.RegistrationApprovedModel
+		if err := json.Unmarshal(msg.TemplateData, &t); err != nil {
+			return nil, err
+		}
+		return email.NewRegistrationApproved(d, &t), nil
+	case TypeEmailChangeConfirm:
+		var t email.EmailChangeConfirmModel
+		if err := json.Unmarshal(msg.TemplateData, &t); err != nil {
+			return nil, err
+		}
+		return email.NewEmailChangeConfirm(d, &t), nil
+	case TypeEmailChangeNotice:
+		var t email.EmailChangeNoticeModel
+		if err := json.Unmarshal(msg.TemplateData, &t); err != nil {
+			return nil, err
+		}
+		return email.NewEmailChangeNotice(d, &t), nil
+	case TypeRegistrationDuplicate:
+		var t email.RegistrationDuplicateModel
+		if err := json.Unmarshal(msg.TemplateData, &t); err != nil {
+			return nil, err
+		}
+		return email.NewRegistrationDuplicate(d, &t), nil
 	default:
 		return nil, errors.Errorf("received unexpected message template type: %s", msg.TemplateType)
 	}
@@ -29,6 +29,12 @@ func TestGetTemplateType(t *testing.T) {
 		courier.TypeTestStub:                &email.TestStub{},
 		courier.TypeLoginCodeValid:          &email.LoginCodeValid{},
 		courier.TypeRegistrationCodeValid:   &email.RegistrationCodeValid{},
+		courier.TypeRecoveryNotification:    &email.RecoveryNotification{},
+		courier.TypeLoginNewDevice:          &email.LoginNewDevice{},
+		courier.TypeRegistrationApproved:    &email.RegistrationApproved{},
+		courier.TypeEmailChangeConfirm:      &email.EmailChangeConfirm{},
+		courier.TypeEmailChangeNotice:       &email.EmailChangeNotice{},
+		courier.TypeRegistrationDuplicate:   &email.RegistrationDuplicate{},
 	} {
 		t.Run(fmt.Sprintf("case=%s", expectedType), func(t *testing.T) {
 			actualType, err := courier.GetEmailTemplateType(tmpl)
@@ -54,6 +60,12 @@ func TestNewEmailTemplateFromMessage(t *testing.T) {
 		courier.TypeTestStub:                email.NewTestStub(reg, &email.TestStubModel{To: "far", Subject: "test subject", Body: "test body"}),
 		courier.TypeLoginCodeValid:          email.NewLoginCodeValid(reg, &email.LoginCodeValidModel{To: "far", LoginCode: "123456"}),
 		courier.TypeRegistrationCodeValid:   email.NewRegistrationCodeValid(reg, &email.RegistrationCodeValidModel{To: "far", RegistrationCode: "123456"}),
+		courier.TypeRecoveryNotification:    email.NewRecoveryNotification(reg, &email.RecoveryNotificationModel{To: "far", IPAddress: "192.0.2.1"}),
+		courier.TypeLoginNewDevice:          email.NewLoginNewDevice(reg, &email.LoginNewDeviceModel{To: "far", IPAddress: "192.0.2.1"}),
+		courier.TypeRegistrationApproved:    email.NewRegistrationApproved(reg, &email.RegistrationApprovedModel{To: "far", LoginURL: "http://foo.bar/login"}),
+		courier.TypeEmailChangeConfirm:      email.NewEmailChangeConfirm(reg, &email.EmailChangeConfirmModel{To: "far", ConfirmURL: "http://foo.bar/confirm", OriginalAddress: "bar"}),
+		courier.TypeEmailChangeNotice:       email.NewEmailChangeNotice(reg, &email.EmailChangeNoticeModel{To: "bar", NewAddress: "far", RevertURL: "http://foo.bar/revert"}),
+		courier.TypeRegistrationDuplicate:   email.NewRegistrationDuplicate(reg, &email.RegistrationDuplicateModel{To: "bar", LoginURL: "http://foo.bar/login"}),
 	} {
 		t.Run(fmt.Sprintf("case=%s", tmplType), func(t *testing.T) {
 			tmplData, err := json.Marshal(expectedTmpl)
@@ -99,7 +99,7 @@ func (ms *MessageStatus) UnmarshalJSON(data []byte) error {
 
 // A Message's Type
 //
-// It can either be `email` or `phone`
+// It can either be `email`, `phone` or `whatsapp`
 //
 // swagger:model courierMessageType
 type MessageType int
@@ -107,11 +107,13 @@ type MessageType int
 const (
 	MessageTypeEmail MessageType = iota + 1
 	MessageTypePhone
+	MessageTypeWhatsApp
 )
 
 const (
-	messageTypeEmailText = "email"
-	messageTypePhoneText = "phone"
+	messageTypeEmailText    = "email"
+	messageTypePhoneText    = "phone"
+	messageTypeWhatsAppText = "whatsapp"
 )
 
 // The format we need to use in the Page tokens, as it's the only format that is understood by all DBs
@@ -123,6 +125,8 @@ func ToMessageType(str string) (MessageType, error) {
 		return MessageTypeEmail, nil
 	case s.AddCase(messageTypePhoneText):
 		return MessageTypePhone, nil
+	case s.AddCase(messageTypeWhatsAppText):
+		return MessageTypeWhatsApp, nil
 	default:
 		return 0, errors.WithStack(herodot.ErrBadRequest.WithWrap(s.ToUnknownCaseErr()).WithReason("Message type is not valid"))
 	}
@@ -134,6 +138,8 @@ func (mt MessageType) String() string {
 		return messageTypeEmailText
 	case MessageTypePhone:
 		return messageTypePhoneText
+	case MessageTypeWhatsApp:
+		return messageTypeWhatsAppText
 	default:
 		return ""
 	}
@@ -141,7 +147,7 @@ func (mt MessageType) String() string {
 
 func (mt MessageType) IsValid() error {
 	switch mt {
-	case MessageTypeEmail, MessageTypePhone:
+	case MessageTypeEmail, MessageTypePhone, MessageTypeWhatsApp:
 		return nil
 	default:
 		return errors.WithStack(herodot.ErrBadRequest.WithReason("Message type is not valid"))
@@ -45,8 +45,9 @@ func TestMessageTypeValidity(t *testing.T) {
 func TestToMessageType(t *testing.T) {
 	t.Run("case=should return corresponding MessageType for given str", func(t *testing.T) {
 		for str, exp := range map[string]courier.MessageType{
-			"email": courier.MessageTypeEmail,
-			"phone": courier.MessageTypePhone,
+			"email":    courier.MessageTypeEmail,
+			"phone":    courier.MessageTypePhone,
+			"whatsapp": courier.MessageTypeWhatsApp,
 		} {
 			result, err := courier.ToMessageType(str)
 			require.NoError(t, err)
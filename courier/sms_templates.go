@@ -22,6 +22,8 @@ func SMSTemplateType(t SMSTemplate) (TemplateType, error) {
 	switch t.(type) {
 	case *sms.OTPMessage:
 		return TypeOTP, nil
+	case *sms.RecoveryNotification:
+		return TypeRecoveryNotification, nil
 	case *sms.TestStub:
 		return TypeTestStub, nil
 	default:
@@ -37,6 +39,12 @@ func NewSMSTemplateFromMessage(d Dependencies, m Message) (SMSTemplate, error) {
 			return nil, err
 		}
 		return sms.NewOTPMessage(d, &t), nil
+	case TypeRecoveryNotification:
+		var t sms.RecoveryNotificationModel
+		if err := json.Unmarshal(m.TemplateData, &t); err != nil {
+			return nil, err
+		}
+		return sms.NewRecoveryNotification(d, &t), nil
 	case TypeTestStub:
 		var t sms.TestStubModel
 		if err := json.Unmarshal(m.TemplateData, &t); err != nil {
@@ -0,0 +1,64 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/ory/kratos/courier/template"
+)
+
+type (
+	EmailChangeConfirm struct {
+		deps  template.Dependencies
+		model *EmailChangeConfirmModel
+	}
+	EmailChangeConfirmModel struct {
+		To              string
+		ConfirmURL      string
+		OriginalAddress string
+		Identity        map[string]interface{}
+		Locale          string
+		Theme           map[string]interface{}
+	}
+)
+
+// SetTheme implements template.ThemedModel.
+func (m *EmailChangeConfirmModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *EmailChangeConfirmModel) TemplateLocale() string {
+	return m.Locale
+}
+
+func NewEmailChangeConfirm(d template.Dependencies, m *EmailChangeConfirmModel) *EmailChangeConfirm {
+	return &EmailChangeConfirm{deps: d, model: m}
+}
+
+func (t *EmailChangeConfirm) EmailRecipient() (string, error) {
+	return t.model.To, nil
+}
+
+func (t *EmailChangeConfirm) EmailSubject(ctx context.Context) (string, error) {
+	subject, err := template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "email_change_confirm/email.subject.gotmpl", "email_change_confirm/email.subject*", t.model, t.deps.CourierConfig().CourierTemplatesEmailChangeConfirm(ctx).Subject)
+
+	return strings.TrimSpace(subject), err
+}
+
+func (t *EmailChangeConfirm) EmailBody(ctx context.Context) (string, error) {
+	return template.LoadHTML(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "email_change_confirm/email.body.gotmpl", "email_change_confirm/email.body*", t.model, t.deps.CourierConfig().CourierTemplatesEmailChangeConfirm(ctx).Body.HTML)
+}
+
+func (t *EmailChangeConfirm) EmailBodyPlaintext(ctx context.Context) (string, error) {
+	return template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "email_change_confirm/email.body.plaintext.gotmpl", "email_change_confirm/email.body.plaintext*", t.model, t.deps.CourierConfig().CourierTemplatesEmailChangeConfirm(ctx).Body.PlainText)
+}
+
+func (t *EmailChangeConfirm) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.model)
+}
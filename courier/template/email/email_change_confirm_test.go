@@ -0,0 +1,42 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package email_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/courier/template/email"
+	"github.com/ory/kratos/courier/template/testhelpers"
+	"github.com/ory/kratos/internal"
+)
+
+func TestEmailChangeConfirm(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	t.Run("test=with courier templates directory", func(t *testing.T) {
+		_, reg := internal.NewFastRegistryWithMocks(t)
+		tpl := email.NewEmailChangeConfirm(reg, &email.EmailChangeConfirmModel{
+			To:              "new@ory.sh",
+			ConfirmURL:      "https://www.ory.sh/confirm",
+			OriginalAddress: "old@ory.sh",
+		})
+
+		testhelpers.TestRendered(t, ctx, tpl)
+
+		body, err := tpl.EmailBodyPlaintext(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, body, "https://www.ory.sh/confirm")
+		assert.Contains(t, body, "old@ory.sh")
+	})
+
+	t.Run("test=with remote resources", func(t *testing.T) {
+		testhelpers.TestRemoteTemplates(t, "../courier/builtin/templates/email_change_confirm", courier.TypeEmailChangeConfirm)
+	})
+}
@@ -0,0 +1,66 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/ory/kratos/courier/template"
+)
+
+type (
+	EmailChangeNotice struct {
+		deps  template.Dependencies
+		model *EmailChangeNoticeModel
+	}
+	EmailChangeNoticeModel struct {
+		To         string
+		NewAddress string
+		RevertURL  string
+		IPAddress  string
+		UserAgent  string
+		Identity   map[string]interface{}
+		Locale     string
+		Theme      map[string]interface{}
+	}
+)
+
+// SetTheme implements template.ThemedModel.
+func (m *EmailChangeNoticeModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *EmailChangeNoticeModel) TemplateLocale() string {
+	return m.Locale
+}
+
+func NewEmailChangeNotice(d template.Dependencies, m *EmailChangeNoticeModel) *EmailChangeNotice {
+	return &EmailChangeNotice{deps: d, model: m}
+}
+
+func (t *EmailChangeNotice) EmailRecipient() (string, error) {
+	return t.model.To, nil
+}
+
+func (t *EmailChangeNotice) EmailSubject(ctx context.Context) (string, error) {
+	subject, err := template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "email_change_notice/email.subject.gotmpl", "email_change_notice/email.subject*", t.model, t.deps.CourierConfig().CourierTemplatesEmailChangeNotice(ctx).Subject)
+
+	return strings.TrimSpace(subject), err
+}
+
+func (t *EmailChangeNotice) EmailBody(ctx context.Context) (string, error) {
+	return template.LoadHTML(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "email_change_notice/email.body.gotmpl", "email_change_notice/email.body*", t.model, t.deps.CourierConfig().CourierTemplatesEmailChangeNotice(ctx).Body.HTML)
+}
+
+func (t *EmailChangeNotice) EmailBodyPlaintext(ctx context.Context) (string, error) {
+	return template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "email_change_notice/email.body.plaintext.gotmpl", "email_change_notice/email.body.plaintext*", t.model, t.deps.CourierConfig().CourierTemplatesEmailChangeNotice(ctx).Body.PlainText)
+}
+
+func (t *EmailChangeNotice) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.model)
+}
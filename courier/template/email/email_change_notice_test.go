@@ -0,0 +1,44 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package email_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/courier/template/email"
+	"github.com/ory/kratos/courier/template/testhelpers"
+	"github.com/ory/kratos/internal"
+)
+
+func TestEmailChangeNotice(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	t.Run("test=with courier templates directory", func(t *testing.T) {
+		_, reg := internal.NewFastRegistryWithMocks(t)
+		tpl := email.NewEmailChangeNotice(reg, &email.EmailChangeNoticeModel{
+			To:         "old@ory.sh",
+			NewAddress: "new@ory.sh",
+			RevertURL:  "https://www.ory.sh/revert",
+			IPAddress:  "192.0.2.1",
+		})
+
+		testhelpers.TestRendered(t, ctx, tpl)
+
+		body, err := tpl.EmailBodyPlaintext(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, body, "https://www.ory.sh/revert")
+		assert.Contains(t, body, "new@ory.sh")
+		assert.Contains(t, body, "192.0.2.1")
+	})
+
+	t.Run("test=with remote resources", func(t *testing.T) {
+		testhelpers.TestRemoteTemplates(t, "../courier/builtin/templates/email_change_notice", courier.TypeEmailChangeNotice)
+	})
+}
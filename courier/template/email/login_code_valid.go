@@ -21,9 +21,21 @@ type (
 		To        string
 		LoginCode string
 		Identity  map[string]interface{}
+		Locale    string
+		Theme     map[string]interface{}
 	}
 )
 
+// SetTheme implements template.ThemedModel.
+func (m *LoginCodeValidModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *LoginCodeValidModel) TemplateLocale() string {
+	return m.Locale
+}
+
 func NewLoginCodeValid(d template.Dependencies, m *LoginCodeValidModel) *LoginCodeValid {
 	return &LoginCodeValid{deps: d, model: m}
 }
@@ -0,0 +1,68 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ory/kratos/courier/template"
+)
+
+type (
+	LoginNewDevice struct {
+		deps  template.Dependencies
+		model *LoginNewDeviceModel
+	}
+	LoginNewDeviceModel struct {
+		To          string
+		IPAddress   string
+		UserAgent   string
+		Location    string
+		LoggedInAt  time.Time
+		RecoveryURL string
+		Identity    map[string]interface{}
+		Locale      string
+		Theme       map[string]interface{}
+	}
+)
+
+// SetTheme implements template.ThemedModel.
+func (m *LoginNewDeviceModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *LoginNewDeviceModel) TemplateLocale() string {
+	return m.Locale
+}
+
+func NewLoginNewDevice(d template.Dependencies, m *LoginNewDeviceModel) *LoginNewDevice {
+	return &LoginNewDevice{deps: d, model: m}
+}
+
+func (t *LoginNewDevice) EmailRecipient() (string, error) {
+	return t.model.To, nil
+}
+
+func (t *LoginNewDevice) EmailSubject(ctx context.Context) (string, error) {
+	subject, err := template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "login_new_device/email.subject.gotmpl", "login_new_device/email.subject*", t.model, t.deps.CourierConfig().CourierTemplatesLoginNewDevice(ctx).Subject)
+
+	return strings.TrimSpace(subject), err
+}
+
+func (t *LoginNewDevice) EmailBody(ctx context.Context) (string, error) {
+	return template.LoadHTML(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "login_new_device/email.body.gotmpl", "login_new_device/email.body*", t.model, t.deps.CourierConfig().CourierTemplatesLoginNewDevice(ctx).Body.HTML)
+}
+
+func (t *LoginNewDevice) EmailBodyPlaintext(ctx context.Context) (string, error) {
+	return template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "login_new_device/email.body.plaintext.gotmpl", "login_new_device/email.body.plaintext*", t.model, t.deps.CourierConfig().CourierTemplatesLoginNewDevice(ctx).Body.PlainText)
+}
+
+func (t *LoginNewDevice) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.model)
+}
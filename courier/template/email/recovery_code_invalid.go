@@ -18,10 +18,16 @@ type (
 		model *RecoveryCodeInvalidModel
 	}
 	RecoveryCodeInvalidModel struct {
-		To string
+		To    string
+		Theme map[string]interface{}
 	}
 )
 
+// SetTheme implements template.ThemedModel.
+func (m *RecoveryCodeInvalidModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
 func NewRecoveryCodeInvalid(d template.Dependencies, m *RecoveryCodeInvalidModel) *RecoveryCodeInvalid {
 	return &RecoveryCodeInvalid{deps: d, model: m}
 }
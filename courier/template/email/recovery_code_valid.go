@@ -21,9 +21,21 @@ type (
 		To           string
 		RecoveryCode string
 		Identity     map[string]interface{}
+		Locale       string
+		Theme        map[string]interface{}
 	}
 )
 
+// SetTheme implements template.ThemedModel.
+func (m *RecoveryCodeValidModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *RecoveryCodeValidModel) TemplateLocale() string {
+	return m.Locale
+}
+
 func NewRecoveryCodeValid(d template.Dependencies, m *RecoveryCodeValidModel) *RecoveryCodeValid {
 	return &RecoveryCodeValid{deps: d, model: m}
 }
@@ -18,10 +18,16 @@ type (
 		m *RecoveryInvalidModel
 	}
 	RecoveryInvalidModel struct {
-		To string
+		To    string
+		Theme map[string]interface{}
 	}
 )
 
+// SetTheme implements template.ThemedModel.
+func (m *RecoveryInvalidModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
 func NewRecoveryInvalid(d template.Dependencies, m *RecoveryInvalidModel) *RecoveryInvalid {
 	return &RecoveryInvalid{d: d, m: m}
 }
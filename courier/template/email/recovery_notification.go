@@ -0,0 +1,67 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ory/kratos/courier/template"
+)
+
+type (
+	RecoveryNotification struct {
+		deps  template.Dependencies
+		model *RecoveryNotificationModel
+	}
+	RecoveryNotificationModel struct {
+		To          string
+		IPAddress   string
+		UserAgent   string
+		Location    string
+		RecoveredAt time.Time
+		Identity    map[string]interface{}
+		Locale      string
+		Theme       map[string]interface{}
+	}
+)
+
+// SetTheme implements template.ThemedModel.
+func (m *RecoveryNotificationModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *RecoveryNotificationModel) TemplateLocale() string {
+	return m.Locale
+}
+
+func NewRecoveryNotification(d template.Dependencies, m *RecoveryNotificationModel) *RecoveryNotification {
+	return &RecoveryNotification{deps: d, model: m}
+}
+
+func (t *RecoveryNotification) EmailRecipient() (string, error) {
+	return t.model.To, nil
+}
+
+func (t *RecoveryNotification) EmailSubject(ctx context.Context) (string, error) {
+	subject, err := template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "recovery_notification/email.subject.gotmpl", "recovery_notification/email.subject*", t.model, t.deps.CourierConfig().CourierTemplatesRecoveryNotification(ctx).Subject)
+
+	return strings.TrimSpace(subject), err
+}
+
+func (t *RecoveryNotification) EmailBody(ctx context.Context) (string, error) {
+	return template.LoadHTML(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "recovery_notification/email.body.gotmpl", "recovery_notification/email.body*", t.model, t.deps.CourierConfig().CourierTemplatesRecoveryNotification(ctx).Body.HTML)
+}
+
+func (t *RecoveryNotification) EmailBodyPlaintext(ctx context.Context) (string, error) {
+	return template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "recovery_notification/email.body.plaintext.gotmpl", "recovery_notification/email.body.plaintext*", t.model, t.deps.CourierConfig().CourierTemplatesRecoveryNotification(ctx).Body.PlainText)
+}
+
+func (t *RecoveryNotification) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.model)
+}
@@ -21,9 +21,21 @@ type (
 		To          string
 		RecoveryURL string
 		Identity    map[string]interface{}
+		Locale      string
+		Theme       map[string]interface{}
 	}
 )
 
+// SetTheme implements template.ThemedModel.
+func (m *RecoveryValidModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *RecoveryValidModel) TemplateLocale() string {
+	return m.Locale
+}
+
 func NewRecoveryValid(d template.Dependencies, m *RecoveryValidModel) *RecoveryValid {
 	return &RecoveryValid{d: d, m: m}
 }
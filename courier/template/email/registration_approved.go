@@ -0,0 +1,63 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/ory/kratos/courier/template"
+)
+
+type (
+	RegistrationApproved struct {
+		deps  template.Dependencies
+		model *RegistrationApprovedModel
+	}
+	RegistrationApprovedModel struct {
+		To       string
+		LoginURL string
+		Identity map[string]interface{}
+		Locale   string
+		Theme    map[string]interface{}
+	}
+)
+
+// SetTheme implements template.ThemedModel.
+func (m *RegistrationApprovedModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *RegistrationApprovedModel) TemplateLocale() string {
+	return m.Locale
+}
+
+func NewRegistrationApproved(d template.Dependencies, m *RegistrationApprovedModel) *RegistrationApproved {
+	return &RegistrationApproved{deps: d, model: m}
+}
+
+func (t *RegistrationApproved) EmailRecipient() (string, error) {
+	return t.model.To, nil
+}
+
+func (t *RegistrationApproved) EmailSubject(ctx context.Context) (string, error) {
+	subject, err := template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "registration_approved/email.subject.gotmpl", "registration_approved/email.subject*", t.model, t.deps.CourierConfig().CourierTemplatesRegistrationApproved(ctx).Subject)
+
+	return strings.TrimSpace(subject), err
+}
+
+func (t *RegistrationApproved) EmailBody(ctx context.Context) (string, error) {
+	return template.LoadHTML(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "registration_approved/email.body.gotmpl", "registration_approved/email.body*", t.model, t.deps.CourierConfig().CourierTemplatesRegistrationApproved(ctx).Body.HTML)
+}
+
+func (t *RegistrationApproved) EmailBodyPlaintext(ctx context.Context) (string, error) {
+	return template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "registration_approved/email.body.plaintext.gotmpl", "registration_approved/email.body.plaintext*", t.model, t.deps.CourierConfig().CourierTemplatesRegistrationApproved(ctx).Body.PlainText)
+}
+
+func (t *RegistrationApproved) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.model)
+}
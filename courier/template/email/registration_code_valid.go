@@ -21,9 +21,21 @@ type (
 		To               string
 		Traits           map[string]interface{}
 		RegistrationCode string
+		Locale           string
+		Theme            map[string]interface{}
 	}
 )
 
+// SetTheme implements template.ThemedModel.
+func (m *RegistrationCodeValidModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *RegistrationCodeValidModel) TemplateLocale() string {
+	return m.Locale
+}
+
 func NewRegistrationCodeValid(d template.Dependencies, m *RegistrationCodeValidModel) *RegistrationCodeValid {
 	return &RegistrationCodeValid{deps: d, model: m}
 }
@@ -0,0 +1,65 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/ory/kratos/courier/template"
+)
+
+type (
+	RegistrationDuplicate struct {
+		deps  template.Dependencies
+		model *RegistrationDuplicateModel
+	}
+	RegistrationDuplicateModel struct {
+		To        string
+		LoginURL  string
+		IPAddress string
+		UserAgent string
+		Identity  map[string]interface{}
+		Locale    string
+		Theme     map[string]interface{}
+	}
+)
+
+// SetTheme implements template.ThemedModel.
+func (m *RegistrationDuplicateModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *RegistrationDuplicateModel) TemplateLocale() string {
+	return m.Locale
+}
+
+func NewRegistrationDuplicate(d template.Dependencies, m *RegistrationDuplicateModel) *RegistrationDuplicate {
+	return &RegistrationDuplicate{deps: d, model: m}
+}
+
+func (t *RegistrationDuplicate) EmailRecipient() (string, error) {
+	return t.model.To, nil
+}
+
+func (t *RegistrationDuplicate) EmailSubject(ctx context.Context) (string, error) {
+	subject, err := template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "registration_duplicate/email.subject.gotmpl", "registration_duplicate/email.subject*", t.model, t.deps.CourierConfig().CourierTemplatesRegistrationDuplicate(ctx).Subject)
+
+	return strings.TrimSpace(subject), err
+}
+
+func (t *RegistrationDuplicate) EmailBody(ctx context.Context) (string, error) {
+	return template.LoadHTML(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "registration_duplicate/email.body.gotmpl", "registration_duplicate/email.body*", t.model, t.deps.CourierConfig().CourierTemplatesRegistrationDuplicate(ctx).Body.HTML)
+}
+
+func (t *RegistrationDuplicate) EmailBodyPlaintext(ctx context.Context) (string, error) {
+	return template.LoadText(ctx, t.deps, os.DirFS(t.deps.CourierConfig().CourierTemplatesRoot(ctx)), "registration_duplicate/email.body.plaintext.gotmpl", "registration_duplicate/email.body.plaintext*", t.model, t.deps.CourierConfig().CourierTemplatesRegistrationDuplicate(ctx).Body.PlainText)
+}
+
+func (t *RegistrationDuplicate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.model)
+}
@@ -18,10 +18,16 @@ type (
 		m *VerificationCodeInvalidModel
 	}
 	VerificationCodeInvalidModel struct {
-		To string
+		To    string
+		Theme map[string]interface{}
 	}
 )
 
+// SetTheme implements template.ThemedModel.
+func (m *VerificationCodeInvalidModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
 func NewVerificationCodeInvalid(d template.Dependencies, m *VerificationCodeInvalidModel) *VerificationCodeInvalid {
 	return &VerificationCodeInvalid{d: d, m: m}
 }
@@ -22,9 +22,21 @@ type (
 		VerificationURL  string
 		VerificationCode string
 		Identity         map[string]interface{}
+		Locale           string
+		Theme            map[string]interface{}
 	}
 )
 
+// SetTheme implements template.ThemedModel.
+func (m *VerificationCodeValidModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *VerificationCodeValidModel) TemplateLocale() string {
+	return m.Locale
+}
+
 func NewVerificationCodeValid(d template.Dependencies, m *VerificationCodeValidModel) *VerificationCodeValid {
 	return &VerificationCodeValid{d: d, m: m}
 }
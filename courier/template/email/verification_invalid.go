@@ -18,10 +18,16 @@ type (
 		m *VerificationInvalidModel
 	}
 	VerificationInvalidModel struct {
-		To string
+		To    string
+		Theme map[string]interface{}
 	}
 )
 
+// SetTheme implements template.ThemedModel.
+func (m *VerificationInvalidModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
 func NewVerificationInvalid(d template.Dependencies, m *VerificationInvalidModel) *VerificationInvalid {
 	return &VerificationInvalid{d: d, m: m}
 }
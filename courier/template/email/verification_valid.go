@@ -21,9 +21,21 @@ type (
 		To              string
 		VerificationURL string
 		Identity        map[string]interface{}
+		Locale          string
+		Theme           map[string]interface{}
 	}
 )
 
+// SetTheme implements template.ThemedModel.
+func (m *VerificationValidModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *VerificationValidModel) TemplateLocale() string {
+	return m.Locale
+}
+
 func NewVerificationValid(d template.Dependencies, m *VerificationValidModel) *VerificationValid {
 	return &VerificationValid{d: d, m: m}
 }
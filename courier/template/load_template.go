@@ -11,10 +11,14 @@ import (
 	"io"
 	"io/fs"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"text/template"
 
 	"github.com/hashicorp/go-retryablehttp"
+	"golang.org/x/text/language"
 
+	"github.com/ory/kratos/driver/config"
 	"github.com/ory/x/fetcher"
 	"github.com/ory/x/httpx"
 
@@ -28,17 +32,51 @@ var templates embed.FS
 
 var Cache, _ = lru.New(16)
 
+// templateCacheKey identifies a template by the file system it was loaded from, as the templates root is
+// resolved per request context and may differ between tenants using the same template names.
+type templateCacheKey struct {
+	filesystem fs.FS
+	name       string
+	html       bool
+}
+
+// cacheKey returns the cache key of the template or false if templates from the file system can not be cached
+// because the file system is not comparable.
+func cacheKey(filesystem fs.FS, name string, html bool) (templateCacheKey, bool) {
+	if filesystem == nil || !reflect.TypeOf(filesystem).Comparable() {
+		return templateCacheKey{}, false
+	}
+	return templateCacheKey{filesystem: filesystem, name: name, html: html}, true
+}
+
 type Template interface {
 	Execute(wr io.Writer, data interface{}) error
 }
 
+// LocalizedModel is implemented by template models which know the preferred
+// locale of the recipient.
+type LocalizedModel interface {
+	TemplateLocale() string
+}
+
+// ThemedModel is implemented by template models which expose the configured
+// theme variables as `.Theme`.
+type ThemedModel interface {
+	SetTheme(theme map[string]interface{})
+}
+
 type templateDependencies interface {
+	CourierConfig() config.CourierConfigs
+	config.Provider
 	HTTPClient(ctx context.Context, opts ...httpx.ResilientOptions) *retryablehttp.Client
 }
 
 func loadBuiltInTemplate(filesystem fs.FS, name string, html bool) (Template, error) {
-	if t, found := Cache.Get(name); found {
-		return t.(Template), nil
+	key, cacheable := cacheKey(filesystem, name, html)
+	if cacheable {
+		if t, found := Cache.Get(key); found {
+			return t.(Template), nil
+		}
 	}
 
 	file, err := filesystem.Open(name)
@@ -74,7 +112,9 @@ func loadBuiltInTemplate(filesystem fs.FS, name string, html bool) (Template, er
 		tpl = t
 	}
 
-	_ = Cache.Add(name, tpl)
+	if cacheable {
+		_ = Cache.Add(key, tpl)
+	}
 	return tpl, nil
 }
 
@@ -112,8 +152,11 @@ func loadRemoteTemplate(ctx context.Context, d templateDependencies, url string,
 }
 
 func loadTemplate(filesystem fs.FS, name, pattern string, html bool) (Template, error) {
-	if t, found := Cache.Get(name); found {
-		return t.(Template), nil
+	key, cacheable := cacheKey(filesystem, name, html)
+	if cacheable {
+		if t, found := Cache.Get(key); found {
+			return t.(Template), nil
+		}
 	}
 
 	matches, _ := fs.Glob(filesystem, name)
@@ -148,10 +191,61 @@ func loadTemplate(filesystem fs.FS, name, pattern string, html bool) (Template,
 		tpl = t
 	}
 
-	_ = Cache.Add(name, tpl)
+	if cacheable {
+		_ = Cache.Add(key, tpl)
+	}
 	return tpl, nil
 }
 
+// LocaleFallbacks returns the chain of locales to try for the given locale,
+// most specific first. For example, "de-AT" results in "de-AT" and "de". The
+// chain does not include the default locale, which is served by the template
+// without a locale suffix.
+func LocaleFallbacks(locale string) []string {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil
+	}
+
+	var chain []string
+	for ; !tag.IsRoot(); tag = tag.Parent() {
+		chain = append(chain, tag.String())
+	}
+	return chain
+}
+
+// localizedName inserts the locale before the file extension of the template
+// name, e.g. "recovery/valid/email.body.gotmpl" becomes
+// "recovery/valid/email.body.de.gotmpl".
+func localizedName(name, locale string) string {
+	return strings.TrimSuffix(name, ".gotmpl") + "." + locale + ".gotmpl"
+}
+
+func templateExists(filesystem fs.FS, name string) bool {
+	if _, err := fs.Stat(filesystem, name); err == nil {
+		return true
+	}
+	_, err := fs.Stat(templates, filepath.Join("courier/builtin/templates", name))
+	return err == nil
+}
+
+// resolveLocalizedName returns the name of the most specific template which
+// exists for the locale of the model. If the model has no locale or no
+// localized template exists, the name is returned unchanged.
+func resolveLocalizedName(filesystem fs.FS, name string, model interface{}) string {
+	m, ok := model.(LocalizedModel)
+	if !ok || m.TemplateLocale() == "" || !strings.HasSuffix(name, ".gotmpl") {
+		return name
+	}
+
+	for _, locale := range LocaleFallbacks(m.TemplateLocale()) {
+		if candidate := localizedName(name, locale); templateExists(filesystem, candidate) {
+			return candidate
+		}
+	}
+	return name
+}
+
 func LoadText(ctx context.Context, d templateDependencies, filesystem fs.FS, name, pattern string, model interface{}, remoteURL string) (string, error) {
 	var t Template
 	var err error
@@ -161,12 +255,16 @@ func LoadText(ctx context.Context, d templateDependencies, filesystem fs.FS, nam
 			return "", err
 		}
 	} else {
-		t, err = loadTemplate(filesystem, name, pattern, false)
+		t, err = loadTemplate(filesystem, resolveLocalizedName(filesystem, name, model), pattern, false)
 		if err != nil {
 			return "", err
 		}
 	}
 
+	if m, ok := model.(ThemedModel); ok {
+		m.SetTheme(d.Config().SelfServiceThemeVariables(ctx))
+	}
+
 	var b bytes.Buffer
 	if err := t.Execute(&b, model); err != nil {
 		return "", err
@@ -183,12 +281,16 @@ func LoadHTML(ctx context.Context, d templateDependencies, filesystem fs.FS, nam
 			return "", err
 		}
 	} else {
-		t, err = loadTemplate(filesystem, name, pattern, true)
+		t, err = loadTemplate(filesystem, resolveLocalizedName(filesystem, name, model), pattern, true)
 		if err != nil {
 			return "", err
 		}
 	}
 
+	if m, ok := model.(ThemedModel); ok {
+		m.SetTheme(d.Config().SelfServiceThemeVariables(ctx))
+	}
+
 	var b bytes.Buffer
 	if err := t.Execute(&b, model); err != nil {
 		return "", err
@@ -12,6 +12,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -28,6 +29,30 @@ import (
 	"github.com/ory/kratos/x"
 )
 
+type localizedModel struct {
+	Locale string
+}
+
+func (m *localizedModel) TemplateLocale() string {
+	return m.Locale
+}
+
+type themedModel struct {
+	Theme map[string]interface{}
+}
+
+func (m *themedModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+func TestLocaleFallbacks(t *testing.T) {
+	assert.Equal(t, []string{"de-AT", "de"}, template.LocaleFallbacks("de-AT"))
+	assert.Equal(t, []string{"de-AT", "de"}, template.LocaleFallbacks("de_AT"))
+	assert.Equal(t, []string{"en"}, template.LocaleFallbacks("en"))
+	assert.Empty(t, template.LocaleFallbacks(""))
+	assert.Empty(t, template.LocaleFallbacks("not a locale"))
+}
+
 func TestLoadTextTemplate(t *testing.T) {
 	var executeTextTemplate = func(t *testing.T, dir, name, pattern string, model map[string]interface{}) string {
 		ctx := context.Background()
@@ -86,6 +111,72 @@ func TestLoadTextTemplate(t *testing.T) {
 		assert.Contains(t, actual, "lang=en_US")
 	})
 
+	t.Run("method=localized", func(t *testing.T) {
+		template.Cache, _ = lru.New(16) // prevent Cache hit
+		ctx := context.Background()
+		_, reg := internal.NewFastRegistryWithMocks(t)
+		fsys := fstest.MapFS{
+			"localized/email.body.gotmpl":       {Data: []byte("hello")},
+			"localized/email.body.de.gotmpl":    {Data: []byte("hallo")},
+			"localized/email.body.de-CH.gotmpl": {Data: []byte("grüezi")},
+		}
+
+		for _, tc := range []struct{ locale, expected string }{
+			{locale: "", expected: "hello"},
+			{locale: "en-US", expected: "hello"},
+			{locale: "de", expected: "hallo"},
+			{locale: "de-AT", expected: "hallo"},
+			{locale: "de-CH", expected: "grüezi"},
+		} {
+			t.Run("locale="+tc.locale, func(t *testing.T) {
+				m := &localizedModel{Locale: tc.locale}
+
+				actual, err := template.LoadText(ctx, reg, fsys, "localized/email.body.gotmpl", "", m, "")
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, actual)
+
+				actual, err = template.LoadHTML(ctx, reg, fsys, "localized/email.body.gotmpl", "localized/email.body*", m, "")
+				require.NoError(t, err)
+				assert.Equal(t, tc.expected, actual)
+			})
+		}
+
+		t.Run("case=falls back to bundled template", func(t *testing.T) {
+			actual, err := template.LoadText(ctx, reg, fsys, "test_stub/email.body.gotmpl", "", &localizedModel{Locale: "de"}, "")
+			require.NoError(t, err)
+			assert.Contains(t, actual, "stub email")
+		})
+	})
+
+	t.Run("method=separate templates roots", func(t *testing.T) {
+		ctx := context.Background()
+		_, reg := internal.NewFastRegistryWithMocks(t)
+
+		var roots []string
+		for _, content := range []string{"first tenant", "second tenant"} {
+			dir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "tenant"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "tenant", "email.body.gotmpl"), []byte(content), 0o600))
+			roots = append(roots, dir)
+		}
+
+		for k, expected := range []string{"first tenant", "second tenant", "first tenant"} {
+			actual, err := template.LoadText(ctx, reg, os.DirFS(roots[k%2]), "tenant/email.body.gotmpl", "", nil, "")
+			require.NoError(t, err)
+			assert.Equal(t, expected, actual, "templates loaded from one root must not be served for another")
+		}
+	})
+
+	t.Run("method=themed", func(t *testing.T) {
+		ctx := context.Background()
+		conf, reg := internal.NewFastRegistryWithMocks(t)
+		conf.MustSet(ctx, config.ViperKeySelfServiceThemeVariables, map[string]interface{}{"product_name": "Ory"})
+
+		actual, err := template.LoadText(ctx, reg, x.NewStubFS("themed", []byte("Welcome to {{ .Theme.product_name }}")), "themed", "", &themedModel{}, "")
+		require.NoError(t, err)
+		assert.Equal(t, "Welcome to Ory", actual)
+	})
+
 	t.Run("method=Cache works", func(t *testing.T) {
 		dir := os.TempDir()
 		name := x.NewUUID().String() + ".body.gotmpl"
@@ -21,9 +21,21 @@ type (
 		To       string
 		Code     string
 		Identity map[string]interface{}
+		Locale   string
+		Theme    map[string]interface{}
 	}
 )
 
+// SetTheme implements template.ThemedModel.
+func (m *OTPMessageModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *OTPMessageModel) TemplateLocale() string {
+	return m.Locale
+}
+
 func NewOTPMessage(d template.Dependencies, m *OTPMessageModel) *OTPMessage {
 	return &OTPMessage{d: d, m: m}
 }
@@ -0,0 +1,57 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/ory/kratos/courier/template"
+)
+
+type (
+	RecoveryNotification struct {
+		d template.Dependencies
+		m *RecoveryNotificationModel
+	}
+
+	RecoveryNotificationModel struct {
+		To          string
+		IPAddress   string
+		UserAgent   string
+		Location    string
+		RecoveredAt time.Time
+		Identity    map[string]interface{}
+		Locale      string
+		Theme       map[string]interface{}
+	}
+)
+
+// SetTheme implements template.ThemedModel.
+func (m *RecoveryNotificationModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *RecoveryNotificationModel) TemplateLocale() string {
+	return m.Locale
+}
+
+func NewRecoveryNotification(d template.Dependencies, m *RecoveryNotificationModel) *RecoveryNotification {
+	return &RecoveryNotification{d: d, m: m}
+}
+
+func (t *RecoveryNotification) PhoneNumber() (string, error) {
+	return t.m.To, nil
+}
+
+func (t *RecoveryNotification) SMSBody(ctx context.Context) (string, error) {
+	return template.LoadText(ctx, t.d, os.DirFS(t.d.CourierConfig().CourierTemplatesRoot(ctx)), "recovery_notification/sms.body.gotmpl", "recovery_notification/sms.body*", t.m, "")
+}
+
+func (t *RecoveryNotification) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.m)
+}
@@ -0,0 +1,31 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sms_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/courier/template/sms"
+	"github.com/ory/kratos/internal"
+)
+
+func TestNewRecoveryNotification(t *testing.T) {
+	_, reg := internal.NewFastRegistryWithMocks(t)
+
+	const expectedPhone = "+12345678901"
+
+	tpl := sms.NewRecoveryNotification(reg, &sms.RecoveryNotificationModel{To: expectedPhone, IPAddress: "192.0.2.1"})
+
+	actualBody, err := tpl.SMSBody(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, actualBody, "192.0.2.1")
+
+	actualPhone, err := tpl.PhoneNumber()
+	require.NoError(t, err)
+	assert.Equal(t, expectedPhone, actualPhone)
+}
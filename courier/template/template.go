@@ -25,6 +25,7 @@ type (
 
 	Dependencies interface {
 		CourierConfig() config.CourierConfigs
+		config.Provider
 		HTTPClient(ctx context.Context, opts ...httpx.ResilientOptions) *retryablehttp.Client
 	}
 )
@@ -88,6 +88,18 @@ func TestRemoteTemplates(t *testing.T, basePath string, tmplType courier.Templat
 			return email.NewLoginCodeValid(d, &email.LoginCodeValidModel{})
 		case courier.TypeRegistrationCodeValid:
 			return email.NewRegistrationCodeValid(d, &email.RegistrationCodeValidModel{})
+		case courier.TypeRecoveryNotification:
+			return email.NewRecoveryNotification(d, &email.RecoveryNotificationModel{})
+		case courier.TypeLoginNewDevice:
+			return email.NewLoginNewDevice(d, &email.LoginNewDeviceModel{})
+		case courier.TypeRegistrationApproved:
+			return email.NewRegistrationApproved(d, &email.RegistrationApprovedModel{})
+		case courier.TypeEmailChangeConfirm:
+			return email.NewEmailChangeConfirm(d, &email.EmailChangeConfirmModel{})
+		case courier.TypeEmailChangeNotice:
+			return email.NewEmailChangeNotice(d, &email.EmailChangeNoticeModel{})
+		case courier.TypeRegistrationDuplicate:
+			return email.NewRegistrationDuplicate(d, &email.RegistrationDuplicateModel{})
 		default:
 			return nil
 		}
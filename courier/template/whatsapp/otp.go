@@ -0,0 +1,53 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/ory/kratos/courier/template"
+)
+
+type (
+	OTPMessage struct {
+		d template.Dependencies
+		m *OTPMessageModel
+	}
+
+	OTPMessageModel struct {
+		To       string
+		Code     string
+		Identity map[string]interface{}
+		Locale   string
+		Theme    map[string]interface{}
+	}
+)
+
+// SetTheme implements template.ThemedModel.
+func (m *OTPMessageModel) SetTheme(theme map[string]interface{}) {
+	m.Theme = theme
+}
+
+// TemplateLocale implements template.LocalizedModel.
+func (m *OTPMessageModel) TemplateLocale() string {
+	return m.Locale
+}
+
+func NewOTPMessage(d template.Dependencies, m *OTPMessageModel) *OTPMessage {
+	return &OTPMessage{d: d, m: m}
+}
+
+func (t *OTPMessage) PhoneNumber() (string, error) {
+	return t.m.To, nil
+}
+
+func (t *OTPMessage) WhatsAppBody(ctx context.Context) (string, error) {
+	return template.LoadText(ctx, t.d, os.DirFS(t.d.CourierConfig().CourierTemplatesRoot(ctx)), "otp/whatsapp.body.gotmpl", "otp/whatsapp.body*", t.m, "")
+}
+
+func (t *OTPMessage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.m)
+}
@@ -0,0 +1,37 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package whatsapp_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/courier/template/whatsapp"
+	"github.com/ory/kratos/internal"
+)
+
+func TestNewOTPMessage(t *testing.T) {
+	_, reg := internal.NewFastRegistryWithMocks(t)
+
+	const (
+		expectedPhone = "+12345678901"
+		otp           = "012345"
+	)
+
+	tpl := whatsapp.NewOTPMessage(reg, &whatsapp.OTPMessageModel{To: expectedPhone, Code: otp})
+
+	expectedBody := fmt.Sprintf("Your verification code is: *%s*. Do not share this code with anyone.\n", otp)
+
+	actualBody, err := tpl.WhatsAppBody(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expectedBody, actualBody)
+
+	actualPhone, err := tpl.PhoneNumber()
+	require.NoError(t, err)
+	assert.Equal(t, expectedPhone, actualPhone)
+}
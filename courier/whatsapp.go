@@ -0,0 +1,109 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package courier
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/request"
+)
+
+type sendWhatsAppRequestBody struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+type whatsAppClient struct {
+	RequestConfig json.RawMessage
+
+	GetTemplateType        func(t WhatsAppTemplate) (TemplateType, error)
+	NewTemplateFromMessage func(d Dependencies, msg Message) (WhatsAppTemplate, error)
+}
+
+func newWhatsApp(ctx context.Context, deps Dependencies) *whatsAppClient {
+	return &whatsAppClient{
+		RequestConfig:          deps.CourierConfig().CourierWhatsAppRequestConfig(ctx),
+		GetTemplateType:        WhatsAppTemplateType,
+		NewTemplateFromMessage: NewWhatsAppTemplateFromMessage,
+	}
+}
+
+func (c *courier) QueueWhatsApp(ctx context.Context, t WhatsAppTemplate) (uuid.UUID, error) {
+	recipient, err := t.PhoneNumber()
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	templateType, err := c.whatsAppClient.GetTemplateType(t)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	templateData, err := json.Marshal(t)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	message := &Message{
+		Status:       MessageStatusQueued,
+		Type:         MessageTypeWhatsApp,
+		Recipient:    recipient,
+		TemplateType: templateType,
+		TemplateData: templateData,
+	}
+	if err := c.deps.CourierPersister().AddMessage(ctx, message); err != nil {
+		return uuid.Nil, err
+	}
+
+	return message.ID, nil
+}
+
+func (c *courier) dispatchWhatsApp(ctx context.Context, msg Message) error {
+	if !c.deps.CourierConfig().CourierWhatsAppEnabled(ctx) {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Courier tried to deliver a WhatsApp message but courier.whatsapp.enabled is set to false!"))
+	}
+
+	tmpl, err := c.whatsAppClient.NewTemplateFromMessage(c.deps, msg)
+	if err != nil {
+		return err
+	}
+
+	body, err := tmpl.WhatsAppBody(ctx)
+	if err != nil {
+		return err
+	}
+
+	builder, err := request.NewBuilder(ctx, c.whatsAppClient.RequestConfig, c.deps)
+	if err != nil {
+		return err
+	}
+
+	req, err := builder.BuildRequest(ctx, &sendWhatsAppRequestBody{
+		To:   msg.Recipient,
+		From: c.deps.CourierConfig().CourierWhatsAppFrom(ctx),
+		Body: body,
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := c.deps.HTTPClient(ctx).Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.Errorf("unable to dispatch WhatsApp message because upstream server replied with status code %d", res.StatusCode)
+	}
+
+	return nil
+}
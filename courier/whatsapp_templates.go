@@ -0,0 +1,41 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package courier
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/courier/template/whatsapp"
+)
+
+type WhatsAppTemplate interface {
+	json.Marshaler
+	WhatsAppBody(context.Context) (string, error)
+	PhoneNumber() (string, error)
+}
+
+func WhatsAppTemplateType(t WhatsAppTemplate) (TemplateType, error) {
+	switch t.(type) {
+	case *whatsapp.OTPMessage:
+		return TypeOTP, nil
+	default:
+		return "", errors.Errorf("unexpected template type")
+	}
+}
+
+func NewWhatsAppTemplateFromMessage(d Dependencies, m Message) (WhatsAppTemplate, error) {
+	switch m.TemplateType {
+	case TypeOTP:
+		var t whatsapp.OTPMessageModel
+		if err := json.Unmarshal(m.TemplateData, &t); err != nil {
+			return nil, err
+		}
+		return whatsapp.NewOTPMessage(d, &t), nil
+	default:
+		return nil, errors.Errorf("received unexpected message template type: %s", m.TemplateType)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package courier_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/courier/template/whatsapp"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/x/resilience"
+)
+
+func TestQueueWhatsApp(t *testing.T) {
+	ctx := context.Background()
+
+	type sendWhatsAppRequestBody struct {
+		To   string
+		From string
+		Body string
+	}
+
+	var actual []sendWhatsAppRequestBody
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rb, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		var body sendWhatsAppRequestBody
+		require.NoError(t, json.Unmarshal(rb, &body))
+		actual = append(actual, body)
+	}))
+	t.Cleanup(srv.Close)
+
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(ctx, config.ViperKeyCourierWhatsAppRequestConfig, fmt.Sprintf(`{
+		"url": "%s",
+		"method": "POST",
+		"body": "file://./stub/request.config.twilio.jsonnet"
+	}`, srv.URL))
+	conf.MustSet(ctx, config.ViperKeyCourierWhatsAppFrom, "106540352242922")
+	conf.MustSet(ctx, config.ViperKeyCourierWhatsAppEnabled, true)
+	conf.MustSet(ctx, config.ViperKeyCourierSMTPURL, "http://foo.url")
+
+	c, err := reg.Courier(ctx)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(ctx)
+	t.Cleanup(cancel)
+
+	id, err := c.QueueWhatsApp(ctx, whatsapp.NewOTPMessage(reg, &whatsapp.OTPMessageModel{To: "+12065550101", Code: "123456"}))
+	require.NoError(t, err)
+	require.NotEqual(t, uuid.Nil, id)
+
+	go func() {
+		require.NoError(t, c.Work(ctx))
+	}()
+
+	require.NoError(t, resilience.Retry(reg.Logger(), time.Millisecond*250, time.Second*10, func() error {
+		if len(actual) == 1 {
+			return nil
+		}
+		return errors.New("message not received")
+	}))
+
+	assert.Equal(t, "+12065550101", actual[0].To)
+	assert.Equal(t, "106540352242922", actual[0].From)
+	assert.Equal(t, "Your verification code is: *123456*. Do not share this code with anyone.\n", actual[0].Body)
+}
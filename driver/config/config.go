@@ -14,7 +14,10 @@ import (
 	"net/url"
 	"os"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -28,6 +31,7 @@ import (
 	"github.com/rs/cors"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/maps"
 	"golang.org/x/net/publicsuffix"
 
 	"github.com/ory/herodot"
@@ -69,6 +73,12 @@ const (
 	ViperKeyCourierHTTPRequestConfig                         = "courier.http.request_config"
 	ViperKeyCourierTemplatesLoginCodeValidEmail              = "courier.templates.login_code.valid.email"
 	ViperKeyCourierTemplatesRegistrationCodeValidEmail       = "courier.templates.registration_code.valid.email"
+	ViperKeyCourierTemplatesRecoveryNotificationEmail        = "courier.templates.recovery_notification.email"
+	ViperKeyCourierTemplatesLoginNewDeviceEmail              = "courier.templates.login_new_device.email"
+	ViperKeyCourierTemplatesRegistrationApprovedEmail        = "courier.templates.registration_approved.email"
+	ViperKeyCourierTemplatesEmailChangeConfirmEmail          = "courier.templates.email_change.confirm.email"
+	ViperKeyCourierTemplatesEmailChangeNoticeEmail           = "courier.templates.email_change.notice.email"
+	ViperKeyCourierTemplatesRegistrationDuplicateEmail       = "courier.templates.registration_duplicate.email"
 	ViperKeyCourierSMTPFrom                                  = "courier.smtp.from_address"
 	ViperKeyCourierSMTPFromName                              = "courier.smtp.from_name"
 	ViperKeyCourierSMTPHeaders                               = "courier.smtp.headers"
@@ -76,6 +86,9 @@ const (
 	ViperKeyCourierSMSRequestConfig                          = "courier.sms.request_config"
 	ViperKeyCourierSMSEnabled                                = "courier.sms.enabled"
 	ViperKeyCourierSMSFrom                                   = "courier.sms.from"
+	ViperKeyCourierWhatsAppRequestConfig                     = "courier.whatsapp.request_config"
+	ViperKeyCourierWhatsAppEnabled                           = "courier.whatsapp.enabled"
+	ViperKeyCourierWhatsAppFrom                              = "courier.whatsapp.from"
 	ViperKeyCourierMessageRetries                            = "courier.message_retries"
 	ViperKeyCourierWorkerPullCount                           = "courier.worker.pull_count"
 	ViperKeyCourierWorkerPullWait                            = "courier.worker.pull_wait"
@@ -93,6 +106,7 @@ const (
 	ViperKeyPublicTLSKeyBase64                               = "serve.public.tls.key.base64"
 	ViperKeyPublicTLSCertPath                                = "serve.public.tls.cert.path"
 	ViperKeyPublicTLSKeyPath                                 = "serve.public.tls.key.path"
+	ViperKeyPublicTrustedProxies                             = "serve.public.trusted_proxies"
 	ViperKeyDisableAdminHealthRequestLog                     = "serve.admin.request_log.disable_for_health"
 	ViperKeyAdminBaseURL                                     = "serve.admin.base_url"
 	ViperKeyAdminPort                                        = "serve.admin.port"
@@ -105,23 +119,64 @@ const (
 	ViperKeyAdminTLSCertPath                                 = "serve.admin.tls.cert.path"
 	ViperKeyAdminTLSKeyPath                                  = "serve.admin.tls.key.path"
 	ViperKeySessionLifespan                                  = "session.lifespan"
+	ViperKeySessionMethodLifespans                           = "session.method_lifespans"
 	ViperKeySessionSameSite                                  = "session.cookie.same_site"
 	ViperKeySessionDomain                                    = "session.cookie.domain"
 	ViperKeySessionName                                      = "session.cookie.name"
 	ViperKeySessionPath                                      = "session.cookie.path"
 	ViperKeySessionPersistentCookie                          = "session.cookie.persistent"
+	ViperKeySessionCookieDomains                             = "session.cookie.domains"
+	ViperKeySessionCookieSameSiteOverrides                   = "session.cookie.same_site_overrides"
+	ViperKeySessionCookieHostPrefix                          = "session.cookie.host_prefix"
+	ViperKeySessionCookiePartitioned                         = "session.cookie.partitioned"
 	ViperKeySessionTokenizerTemplates                        = "session.whoami.tokenizer.templates"
 	ViperKeySessionWhoAmIAAL                                 = "session.whoami.required_aal"
 	ViperKeySessionWhoAmICaching                             = "feature_flags.cacheable_sessions"
+	ViperKeySessionWhoAmICacheEnabled                        = "session.whoami.cache.enabled"
+	ViperKeySessionWhoAmICacheTTL                            = "session.whoami.cache.ttl"
+	ViperKeySessionWhoAmICacheMaxEntries                     = "session.whoami.cache.max_entries"
 	ViperKeySessionRefreshMinTimeLeft                        = "session.earliest_possible_extend"
+	ViperKeySessionSelfServiceExtendEnabled                  = "session.self_service_extend.enabled"
+	ViperKeySessionSelfServiceExtendMaxLifetime              = "session.self_service_extend.max_lifetime"
+	ViperKeySessionSelfServiceExtendRequiredAAL              = "session.self_service_extend.required_aal"
+	ViperKeySessionTokenBindingEnabled                       = "session.token_binding.enabled"
+	ViperKeySessionTokenBindingProofMaxAge                   = "session.token_binding.proof_max_age"
+	ViperKeySessionDevicesEnabled                            = "session.devices.enabled"
+	ViperKeySessionIdleTimeout                               = "session.idle.timeout"
+	ViperKeySessionIdleUpdateInterval                        = "session.idle.update_interval"
+	ViperKeySessionDevicesLocationHeaders                    = "session.devices.location_headers"
+	ViperKeySessionEventsWebhook                             = "session.events.webhook"
+	ViperKeySessionEventsTypes                               = "session.events.types"
+	ViperKeySessionBackChannelLogoutEnabled                  = "session.backchannel_logout.enabled"
+	ViperKeySessionBackChannelLogoutJWKSURL                  = "session.backchannel_logout.jwks_url"
+	ViperKeySessionRefreshTokensEnabled                      = "session.refresh_tokens.enabled"
+	ViperKeySessionRefreshTokensSessionTokenLifespan         = "session.refresh_tokens.session_token_lifespan"
+	ViperKeySessionPersistenceMode                           = "session.persistence.mode"
+	ViperKeySessionPersistenceRedisDSN                       = "session.persistence.redis.dsn"
+	ViperKeySessionPersistenceRedisKeyPrefix                 = "session.persistence.redis.key_prefix"
+	ViperKeySessionPersistenceRedisTTL                       = "session.persistence.redis.ttl"
 	ViperKeyCookieSameSite                                   = "cookies.same_site"
 	ViperKeyCookieDomain                                     = "cookies.domain"
 	ViperKeyCookiePath                                       = "cookies.path"
 	ViperKeySelfServiceStrategyConfig                        = "selfservice.methods"
 	ViperKeySelfServiceBrowserDefaultReturnTo                = "selfservice." + DefaultBrowserReturnURL
 	ViperKeyURLsAllowedReturnToDomains                       = "selfservice.allowed_return_urls"
+	ViperKeySelfServiceThemeVariables                        = "selfservice.theme.variables"
+	ViperKeySelfServiceAccountEnumerationMitigate            = "selfservice.account_enumeration.mitigate"
+	ViperKeySelfServiceAccountEnumerationResponseTime        = "selfservice.account_enumeration.response_time"
 	ViperKeySelfServiceRegistrationEnabled                   = "selfservice.flows.registration.enabled"
 	ViperKeySelfServiceRegistrationLoginHints                = "selfservice.flows.registration.login_hints"
+	ViperKeySelfServiceRegistrationVerifyBeforeCreation      = "selfservice.flows.registration.verify_before_creation"
+	ViperKeySelfServiceRegistrationInvitationsRequired       = "selfservice.flows.registration.invitations.required"
+	ViperKeySelfServiceRegistrationInvitationsLifespan       = "selfservice.flows.registration.invitations.lifespan"
+	ViperKeySelfServiceRegistrationSignupCodesEnabled        = "selfservice.flows.registration.signup_codes.enabled"
+	ViperKeySelfServiceRegistrationSignupCodesRequired       = "selfservice.flows.registration.signup_codes.required"
+	ViperKeySelfServiceRegistrationApprovalRequired          = "selfservice.flows.registration.approval.required"
+	ViperKeySelfServiceRegistrationStepsCredentialsFirst     = "selfservice.flows.registration.steps.credentials_first"
+	ViperKeySelfServiceRegistrationLoginHandoffEnabled       = "selfservice.flows.registration.login_handoff.enabled"
+	ViperKeySelfServiceRegistrationLoginHandoffRecovery      = "selfservice.flows.registration.login_handoff.offer_recovery"
+	ViperKeySelfServiceRegistrationEmailDomainsAllow         = "selfservice.flows.registration.email_domains.allow"
+	ViperKeySelfServiceRegistrationEmailDomainsDeny          = "selfservice.flows.registration.email_domains.deny"
 	ViperKeySelfServiceRegistrationUI                        = "selfservice.flows.registration.ui_url"
 	ViperKeySelfServiceRegistrationRequestLifespan           = "selfservice.flows.registration.lifespan"
 	ViperKeySelfServiceRegistrationAfter                     = "selfservice.flows.registration.after"
@@ -130,6 +185,28 @@ const (
 	ViperKeySelfServiceLoginRequestLifespan                  = "selfservice.flows.login.lifespan"
 	ViperKeySelfServiceLoginAfter                            = "selfservice.flows.login.after"
 	ViperKeySelfServiceLoginBeforeHooks                      = "selfservice.flows.login.before.hooks"
+	ViperKeySelfServiceLoginSoftReauthenticationEnabled      = "selfservice.flows.login.soft_reauthentication.enabled"
+	ViperKeySelfServiceLoginSoftReauthenticationLifespan     = "selfservice.flows.login.soft_reauthentication.lifespan"
+	ViperKeySelfServiceLoginLockoutEnabled                   = "selfservice.flows.login.lockout.enabled"
+	ViperKeySelfServiceLoginLockoutMaxAttempts               = "selfservice.flows.login.lockout.max_attempts"
+	ViperKeySelfServiceLoginLockoutIPMaxAttempts             = "selfservice.flows.login.lockout.ip_max_attempts"
+	ViperKeySelfServiceLoginLockoutAttemptWindow             = "selfservice.flows.login.lockout.attempt_window"
+	ViperKeySelfServiceLoginLockoutBaseDuration              = "selfservice.flows.login.lockout.base_duration"
+	ViperKeySelfServiceLoginLockoutMaxDuration               = "selfservice.flows.login.lockout.max_duration"
+	ViperKeySelfServiceLoginRateLimitEnabled                 = "selfservice.flows.login.rate_limit.enabled"
+	ViperKeySelfServiceLoginRateLimitStore                   = "selfservice.flows.login.rate_limit.store"
+	ViperKeySelfServiceLoginRateLimitRedisURL                = "selfservice.flows.login.rate_limit.redis.url"
+	ViperKeySelfServiceLoginRateLimitIdentifierLimit         = "selfservice.flows.login.rate_limit.identifier.limit"
+	ViperKeySelfServiceLoginRateLimitIdentifierWindow        = "selfservice.flows.login.rate_limit.identifier.window"
+	ViperKeySelfServiceLoginRateLimitIPLimit                 = "selfservice.flows.login.rate_limit.ip.limit"
+	ViperKeySelfServiceLoginRateLimitIPWindow                = "selfservice.flows.login.rate_limit.ip.window"
+	ViperKeySelfServiceLoginNewDeviceNotificationEnabled     = "selfservice.flows.login.new_device_notification.enabled"
+	ViperKeySelfServiceLoginNewDeviceNotificationFlowTypes   = "selfservice.flows.login.new_device_notification.flow_types"
+	ViperKeySelfServiceLoginRequireVerifiedAddressEnabled    = "selfservice.flows.login.require_verified_address.enabled"
+	ViperKeySelfServiceLoginRequireVerifiedAddressMethods    = "selfservice.flows.login.require_verified_address.methods"
+	ViperKeySelfServiceLoginRiskAssessmentEnabled            = "selfservice.flows.login.risk_assessment.enabled"
+	ViperKeySelfServiceLoginRiskAssessmentRequestConfig      = "selfservice.flows.login.risk_assessment.request_config"
+	ViperKeySelfServiceLoginRiskAssessmentOnError            = "selfservice.flows.login.risk_assessment.on_error"
 	ViperKeySelfServiceErrorUI                               = "selfservice.flows.error.ui_url"
 	ViperKeySelfServiceLogoutBrowserDefaultReturnTo          = "selfservice.flows.logout.after." + DefaultBrowserReturnURL
 	ViperKeySelfServiceSettingsURL                           = "selfservice.flows.settings.ui_url"
@@ -138,6 +215,8 @@ const (
 	ViperKeySelfServiceSettingsRequestLifespan               = "selfservice.flows.settings.lifespan"
 	ViperKeySelfServiceSettingsPrivilegedAuthenticationAfter = "selfservice.flows.settings.privileged_session_max_age"
 	ViperKeySelfServiceSettingsRequiredAAL                   = "selfservice.flows.settings.required_aal"
+	ViperKeySelfServiceSettingsVerifiedEmailChangeEnabled    = "selfservice.flows.settings.verified_email_change.enabled"
+	ViperKeySelfServiceSettingsVerifiedEmailChangeLifespan   = "selfservice.flows.settings.verified_email_change.lifespan"
 	ViperKeySelfServiceRecoveryAfter                         = "selfservice.flows.recovery.after"
 	ViperKeySelfServiceRecoveryBeforeHooks                   = "selfservice.flows.recovery.before.hooks"
 	ViperKeySelfServiceRecoveryEnabled                       = "selfservice.flows.recovery.enabled"
@@ -146,6 +225,17 @@ const (
 	ViperKeySelfServiceRecoveryRequestLifespan               = "selfservice.flows.recovery.lifespan"
 	ViperKeySelfServiceRecoveryBrowserDefaultReturnTo        = "selfservice.flows.recovery.after." + DefaultBrowserReturnURL
 	ViperKeySelfServiceRecoveryNotifyUnknownRecipients       = "selfservice.flows.recovery.notify_unknown_recipients"
+	ViperKeySelfServiceRecoveryUnknownAddressNotify          = "selfservice.flows.recovery.unknown_address.notify"
+	ViperKeySelfServiceRecoveryUnknownAddressAudit           = "selfservice.flows.recovery.unknown_address.audit"
+	ViperKeySelfServiceRecoveryUnknownAddressHooks           = "selfservice.flows.recovery.unknown_address.hooks"
+	ViperKeySelfServiceRecoveryAddressSelection              = "selfservice.flows.recovery.address_selection"
+	ViperKeySelfServiceRecoveryRequiredActions               = "selfservice.flows.recovery.required_actions"
+	ViperKeySelfServiceRecoveryRestrictSession               = "selfservice.flows.recovery.restrict_session"
+	ViperKeySelfServiceRecoveryPhoneNumbers                  = "selfservice.flows.recovery.phone_numbers"
+	ViperKeySelfServiceRecoveryIdentifiers                   = "selfservice.flows.recovery.identifiers"
+	ViperKeySelfServiceRecoveryNotifyOnSuccess               = "selfservice.flows.recovery.notify_on_success"
+	ViperKeySelfServiceRecoveryRequiredAAL                   = "selfservice.flows.recovery.required_aal"
+	ViperKeySelfServiceRecoveryAllowTOTPReset                = "selfservice.flows.recovery.allow_totp_reset"
 	ViperKeySelfServiceVerificationEnabled                   = "selfservice.flows.verification.enabled"
 	ViperKeySelfServiceVerificationUI                        = "selfservice.flows.verification.ui_url"
 	ViperKeySelfServiceVerificationRequestLifespan           = "selfservice.flows.verification.lifespan"
@@ -154,8 +244,19 @@ const (
 	ViperKeySelfServiceVerificationBeforeHooks               = "selfservice.flows.verification.before.hooks"
 	ViperKeySelfServiceVerificationUse                       = "selfservice.flows.verification.use"
 	ViperKeySelfServiceVerificationNotifyUnknownRecipients   = "selfservice.flows.verification.notify_unknown_recipients"
+	ViperKeySelfServiceVerificationReminders                 = "selfservice.flows.verification.reminders"
+	ViperKeySelfServiceVerificationRemindersBackground       = "selfservice.flows.verification.reminders.background.enabled"
+	ViperKeySelfServiceVerificationRemindersInterval         = "selfservice.flows.verification.reminders.background.interval"
+	ViperKeySelfServiceVerificationRemindersBatchSize        = "selfservice.flows.verification.reminders.background.batch_size"
+	ViperKeySelfServiceVerificationPhoneNumbers              = "selfservice.flows.verification.phone_numbers"
 	ViperKeyDefaultIdentitySchemaID                          = "identity.default_schema_id"
 	ViperKeyIdentitySchemas                                  = "identity.schemas"
+	ViperKeyIdentitySchemaMigrations                         = "identity.migrations"
+	ViperKeyIdentitySoftDeleteRetention                      = "identity.soft_delete.retention"
+	ViperKeyIdentityEventsEnabled                            = "identity.events.enabled"
+	ViperKeyIdentityEventSinks                               = "identity.events.sinks"
+	ViperKeyIdentityEventsDeliveryInterval                   = "identity.events.delivery.interval"
+	ViperKeyIdentityEventsDeliveryMaxAttempts                = "identity.events.delivery.max_attempts"
 	ViperKeyHasherAlgorithm                                  = "hashers.algorithm"
 	ViperKeyHasherArgon2ConfigMemory                         = "hashers.argon2.memory"
 	ViperKeyHasherArgon2ConfigIterations                     = "hashers.argon2.iterations"
@@ -165,13 +266,29 @@ const (
 	ViperKeyHasherArgon2ConfigExpectedDuration               = "hashers.argon2.expected_duration"
 	ViperKeyHasherArgon2ConfigExpectedDeviation              = "hashers.argon2.expected_deviation"
 	ViperKeyHasherArgon2ConfigDedicatedMemory                = "hashers.argon2.dedicated_memory"
+	ViperKeyHasherArgon2ConfigCalibrationMode                = "hashers.argon2.calibration.mode"
+	ViperKeyHasherArgon2ConfigCalibrationMaxMemory           = "hashers.argon2.calibration.max_memory"
 	ViperKeyHasherBcryptCost                                 = "hashers.bcrypt.cost"
 	ViperKeyCipherAlgorithm                                  = "ciphers.algorithm"
 	ViperKeyDatabaseCleanupSleepTables                       = "database.cleanup.sleep.tables"
 	ViperKeyDatabaseCleanupBatchSize                         = "database.cleanup.batch_size"
+	ViperKeyDatabaseCleanupInterval                          = "database.cleanup.interval"
+	ViperKeyDatabaseCleanupExpiredSessionsEnabled            = "database.cleanup.expired_sessions.enabled"
+	ViperKeyDatabaseCleanupExpiredSessionsWebhook            = "database.cleanup.expired_sessions.webhook"
+	ViperKeyDatabaseCleanupExpiredSessionsBatchSize          = "database.cleanup.expired_sessions.batch_size"
+	ViperKeyDatabaseTableMetricsEnabled                      = "database.table_metrics.enabled"
+	ViperKeyDatabaseTableMetricsInterval                     = "database.table_metrics.interval"
+	ViperKeyDatabasePhasedMigrationsBackfillEnabled          = "database.phased_migrations.backfill.enabled"
+	ViperKeyDatabasePhasedMigrationsBackfillBatchSize        = "database.phased_migrations.backfill.batch_size"
+	ViperKeyDatabasePhasedMigrationsBackfillInterval         = "database.phased_migrations.backfill.interval"
+	ViperKeyDatabasePhasedMigrationsCutover                  = "database.phased_migrations.cutover"
 	ViperKeyLinkLifespan                                     = "selfservice.methods.link.config.lifespan"
 	ViperKeyLinkBaseURL                                      = "selfservice.methods.link.config.base_url"
 	ViperKeyCodeLifespan                                     = "selfservice.methods.code.config.lifespan"
+	ViperKeyCodeMaxSubmissions                               = "selfservice.methods.code.config.max_submissions"
+	ViperKeyCodeBackendType                                  = "selfservice.methods.code.config.backend.type"
+	ViperKeyCodeBackendRequestConfig                         = "selfservice.methods.code.config.backend.request_config"
+	ViperKeyCodeBackendTwilioVerify                          = "selfservice.methods.code.config.backend.twilio_verify"
 	ViperKeyPasswordHaveIBeenPwnedHost                       = "selfservice.methods.password.config.haveibeenpwned_host"
 	ViperKeyPasswordHaveIBeenPwnedEnabled                    = "selfservice.methods.password.config.haveibeenpwned_enabled"
 	ViperKeyPasswordMaxBreaches                              = "selfservice.methods.password.config.max_breaches"
@@ -179,18 +296,36 @@ const (
 	ViperKeyPasswordIdentifierSimilarityCheckEnabled         = "selfservice.methods.password.config.identifier_similarity_check_enabled"
 	ViperKeyIgnoreNetworkErrors                              = "selfservice.methods.password.config.ignore_network_errors"
 	ViperKeyTOTPIssuer                                       = "selfservice.methods.totp.config.issuer"
+	ViperKeyTOTPDigits                                       = "selfservice.methods.totp.config.digits"
+	ViperKeyTOTPPeriod                                       = "selfservice.methods.totp.config.period"
+	ViperKeyTOTPSkew                                         = "selfservice.methods.totp.config.skew"
+	ViperKeyPushRequestConfig                                = "selfservice.methods.push.config.request_config"
+	ViperKeyPushChallengeLifespan                            = "selfservice.methods.push.config.lifespan"
+	ViperKeyPushPollTimeout                                  = "selfservice.methods.push.config.poll_timeout"
+	ViperKeyExternalMFAInitiateRequestConfig                 = "selfservice.methods.external_mfa.config.initiate"
+	ViperKeyExternalMFAVerifyRequestConfig                   = "selfservice.methods.external_mfa.config.verify"
+	ViperKeyExternalMFALifespan                              = "selfservice.methods.external_mfa.config.lifespan"
+	ViperKeyExternalMFAPollTimeout                           = "selfservice.methods.external_mfa.config.poll_timeout"
+	ViperKeyTrustedDeviceLifespan                            = "selfservice.methods.trusted_device.config.lifespan"
+	ViperKeySelfServiceConsentDocuments                      = "selfservice.methods.consent.config.documents"
+	ViperKeyLookupSecretCount                                = "selfservice.methods.lookup_secret.config.count"
+	ViperKeyLookupSecretLength                               = "selfservice.methods.lookup_secret.config.length"
+	ViperKeyLookupSecretFormat                               = "selfservice.methods.lookup_secret.config.format"
+	ViperKeyLookupSecretWarnRemaining                        = "selfservice.methods.lookup_secret.config.warn_remaining"
 	ViperKeyOIDCBaseRedirectURL                              = "selfservice.methods.oidc.config.base_redirect_uri"
 	ViperKeyWebAuthnRPDisplayName                            = "selfservice.methods.webauthn.config.rp.display_name"
 	ViperKeyWebAuthnRPID                                     = "selfservice.methods.webauthn.config.rp.id"
 	ViperKeyWebAuthnRPOrigin                                 = "selfservice.methods.webauthn.config.rp.origin"
 	ViperKeyWebAuthnRPOrigins                                = "selfservice.methods.webauthn.config.rp.origins"
 	ViperKeyWebAuthnPasswordless                             = "selfservice.methods.webauthn.config.passwordless"
+	ViperKeyWebAuthnPasskeys                                 = "selfservice.methods.webauthn.config.passkeys"
 	ViperKeyOAuth2ProviderURL                                = "oauth2_provider.url"
 	ViperKeyOAuth2ProviderHeader                             = "oauth2_provider.headers"
 	ViperKeyOAuth2ProviderOverrideReturnTo                   = "oauth2_provider.override_return_to"
 	ViperKeyClientHTTPNoPrivateIPRanges                      = "clients.http.disallow_private_ip_ranges"
 	ViperKeyClientHTTPPrivateIPExceptionURLs                 = "clients.http.private_ip_exception_urls"
 	ViperKeyPreviewDefaultReadConsistencyLevel               = "preview.default_read_consistency_level"
+	ViperKeyPreviewTestClockEnabled                          = "preview.test_clock.enabled"
 	ViperKeyVersion                                          = "version"
 )
 
@@ -206,6 +341,27 @@ const (
 	BcryptDefaultCost            uint32 = 12
 )
 
+const (
+	Argon2CalibrationModeOff   = "off"
+	Argon2CalibrationModeLog   = "log"
+	Argon2CalibrationModeApply = "apply"
+)
+
+const (
+	UnverifiedAccountActionNone       UnverifiedAccountAction = "none"
+	UnverifiedAccountActionDeactivate UnverifiedAccountAction = "deactivate"
+	UnverifiedAccountActionDelete     UnverifiedAccountAction = "delete"
+)
+
+const (
+	// SessionPersistenceModeSQL stores sessions in the SQL database only.
+	SessionPersistenceModeSQL SessionPersistenceMode = "sql"
+	// SessionPersistenceModeWriteThrough writes sessions to the SQL database and serves lookups from Redis.
+	SessionPersistenceModeWriteThrough SessionPersistenceMode = "write_through"
+	// SessionPersistenceModeRedis stores sessions in Redis only, where they expire together with the session.
+	SessionPersistenceModeRedis SessionPersistenceMode = "redis"
+)
+
 // DefaultSessionCookieName returns the default cookie name for the kratos session.
 const DefaultSessionCookieName = "ory_kratos_session"
 
@@ -227,7 +383,24 @@ type (
 		Name   string          `json:"hook"`
 		Config json.RawMessage `json:"config"`
 	}
-	SelfServiceStrategy struct {
+	VerificationReminderPolicy struct {
+		// Enabled is false if identities of the schema are opted out of reminders and expiry.
+		Enabled bool
+
+		// Intervals are the ages of an unverified address at which a reminder is sent.
+		Intervals []time.Duration
+
+		// UnverifiedAccountAction is applied to identities which are still unverified after UnverifiedAccountAfter.
+		UnverifiedAccountAction UnverifiedAccountAction
+		UnverifiedAccountAfter  time.Duration
+	}
+	VerificationReminders struct {
+		Default VerificationReminderPolicy
+		Schemas map[string]VerificationReminderPolicy
+	}
+	UnverifiedAccountAction string
+	SessionPersistenceMode  string
+	SelfServiceStrategy     struct {
 		Enabled bool            `json:"enabled"`
 		Config  json.RawMessage `json:"config"`
 	}
@@ -235,10 +408,49 @@ type (
 		*SelfServiceStrategy
 		PasswordlessEnabled bool `json:"passwordless_enabled"`
 	}
+	ConsentDocument struct {
+		// ID identifies the document, for example "tos".
+		ID string `json:"id"`
+
+		// Version of the document. Identities have to accept a document again once its version changes.
+		Version string `json:"version"`
+
+		// URL at which the document can be read.
+		URL string `json:"url"`
+
+		// Label is shown next to the checkbox of the document.
+		Label string `json:"label"`
+
+		// Optional documents do not have to be accepted.
+		Optional bool `json:"optional"`
+	}
 	Schema struct {
 		ID  string `json:"id" koanf:"id"`
 		URL string `json:"url" koanf:"url"`
 	}
+	SchemaMigration struct {
+		// From is the ID of the identity schema the identities are migrated from.
+		From string `json:"from" koanf:"from"`
+
+		// To is the ID of the identity schema the identities are migrated to.
+		To string `json:"to" koanf:"to"`
+
+		// Jsonnet is the URL of a Jsonnet template which transforms the identity.
+		Jsonnet string `json:"jsonnet,omitempty" koanf:"jsonnet"`
+
+		// JSONPatch is the URL of a JSON Patch document which is applied to the identity.
+		JSONPatch string `json:"json_patch,omitempty" koanf:"json_patch"`
+	}
+	IdentityEventSink struct {
+		// URL is the URL the events are sent to.
+		URL string `json:"url" koanf:"url"`
+
+		// Headers are sent with every request.
+		Headers map[string]string `json:"headers,omitempty" koanf:"headers"`
+
+		// Events are the types of the events sent to the sink. All events are sent if empty.
+		Events []string `json:"events,omitempty" koanf:"events"`
+	}
 	PasswordPolicy struct {
 		HaveIBeenPwnedHost               string `json:"haveibeenpwned_host"`
 		HaveIBeenPwnedEnabled            bool   `json:"haveibeenpwned_enabled"`
@@ -262,6 +474,15 @@ type (
 		c                  contextx.Contextualizer
 		identityMetaSchema *jsonschema.Schema
 		stdOutOrErr        io.Writer
+
+		// argon2Calibrated maps a configuration provider to its argon2Calibration. They are kept outside of
+		// the provider so that they survive configuration reloads.
+		argon2Calibrated sync.Map
+	}
+	// argon2Calibration holds the calibrated memory and iterations of Argon2 and the configured values they
+	// replace.
+	argon2Calibration struct {
+		configured, calibrated Argon2
 	}
 	Provider interface {
 		Config() *Config
@@ -279,6 +500,9 @@ type (
 		CourierSMSEnabled(ctx context.Context) bool
 		CourierSMSFrom(ctx context.Context) string
 		CourierSMSRequestConfig(ctx context.Context) json.RawMessage
+		CourierWhatsAppEnabled(ctx context.Context) bool
+		CourierWhatsAppFrom(ctx context.Context) string
+		CourierWhatsAppRequestConfig(ctx context.Context) json.RawMessage
 		CourierTemplatesRoot(ctx context.Context) string
 		CourierTemplatesVerificationInvalid(ctx context.Context) *CourierEmailTemplate
 		CourierTemplatesVerificationValid(ctx context.Context) *CourierEmailTemplate
@@ -290,6 +514,12 @@ type (
 		CourierTemplatesVerificationCodeValid(ctx context.Context) *CourierEmailTemplate
 		CourierTemplatesLoginCodeValid(ctx context.Context) *CourierEmailTemplate
 		CourierTemplatesRegistrationCodeValid(ctx context.Context) *CourierEmailTemplate
+		CourierTemplatesRecoveryNotification(ctx context.Context) *CourierEmailTemplate
+		CourierTemplatesLoginNewDevice(ctx context.Context) *CourierEmailTemplate
+		CourierTemplatesRegistrationApproved(ctx context.Context) *CourierEmailTemplate
+		CourierTemplatesEmailChangeConfirm(ctx context.Context) *CourierEmailTemplate
+		CourierTemplatesEmailChangeNotice(ctx context.Context) *CourierEmailTemplate
+		CourierTemplatesRegistrationDuplicate(ctx context.Context) *CourierEmailTemplate
 		CourierMessageRetries(ctx context.Context) int
 		CourierWorkerPullCount(ctx context.Context) int
 		CourierWorkerPullWait(ctx context.Context) time.Duration
@@ -510,7 +740,7 @@ func (p *Config) SessionName(ctx context.Context) string {
 func (p *Config) HasherArgon2(ctx context.Context) *Argon2 {
 	// warn about usage of default values and point to the docs
 	// warning will require https://github.com/ory/viper/issues/19
-	return &Argon2{
+	c := &Argon2{
 		Memory:            p.GetProvider(ctx).ByteSizeF(ViperKeyHasherArgon2ConfigMemory, Argon2DefaultMemory),
 		Iterations:        uint32(p.GetProvider(ctx).IntF(ViperKeyHasherArgon2ConfigIterations, int(Argon2DefaultIterations))),
 		Parallelism:       uint8(p.GetProvider(ctx).IntF(ViperKeyHasherArgon2ConfigParallelism, int(Argon2DefaultParallelism))),
@@ -520,6 +750,48 @@ func (p *Config) HasherArgon2(ctx context.Context) *Argon2 {
 		ExpectedDeviation: p.GetProvider(ctx).DurationF(ViperKeyHasherArgon2ConfigExpectedDeviation, Argon2DefaultDeviation),
 		DedicatedMemory:   p.GetProvider(ctx).ByteSizeF(ViperKeyHasherArgon2ConfigDedicatedMemory, Argon2DefaultDedicatedMemory),
 	}
+
+	provider := p.GetProvider(ctx)
+	v, ok := p.argon2Calibrated.Load(provider)
+	if !ok {
+		return c
+	}
+
+	calibration := v.(*argon2Calibration)
+	if c.Memory == calibration.configured.Memory && c.Iterations == calibration.configured.Iterations {
+		c.Memory = calibration.calibrated.Memory
+		c.Iterations = calibration.calibrated.Iterations
+	} else if p.argon2Calibrated.CompareAndDelete(provider, v) {
+		p.l.
+			WithField(ViperKeyHasherArgon2ConfigMemory, c.Memory.String()).
+			WithField(ViperKeyHasherArgon2ConfigIterations, c.Iterations).
+			Warn("The Argon2 memory or iterations were changed in the configuration, the calibrated values are no longer applied.")
+	}
+	return c
+}
+
+func (p *Config) HasherArgon2CalibrationMode(ctx context.Context) string {
+	return p.GetProvider(ctx).StringF(ViperKeyHasherArgon2ConfigCalibrationMode, Argon2CalibrationModeOff)
+}
+
+// HasherArgon2CalibrationMaxMemory returns the memory a single hashing operation may use at most when calibrating
+// Argon2. Defaults to the dedicated memory.
+func (p *Config) HasherArgon2CalibrationMaxMemory(ctx context.Context) bytesize.ByteSize {
+	return p.GetProvider(ctx).ByteSizeF(ViperKeyHasherArgon2ConfigCalibrationMaxMemory, p.HasherArgon2(ctx).DedicatedMemory)
+}
+
+// SetHasherArgon2 overrides the memory and iterations of the Argon2 hasher of the configuration of ctx, e.g. after
+// calibrating them. The override survives configuration reloads until the memory or iterations are changed in the
+// configuration, but not restarts, which is why operators should persist the values.
+func (p *Config) SetHasherArgon2(ctx context.Context, c *Argon2) {
+	provider := p.GetProvider(ctx)
+	p.argon2Calibrated.Delete(provider)
+
+	configured := p.HasherArgon2(ctx)
+	p.argon2Calibrated.Store(provider, &argon2Calibration{
+		configured: Argon2{Memory: configured.Memory, Iterations: configured.Iterations},
+		calibrated: Argon2{Memory: c.Memory, Iterations: c.Iterations},
+	})
 }
 
 func (p *Config) HasherBcrypt(ctx context.Context) *Bcrypt {
@@ -569,6 +841,127 @@ func (p *Config) TOTPIssuer(ctx context.Context) string {
 	return p.GetProvider(ctx).StringF(ViperKeyTOTPIssuer, p.SelfPublicURL(ctx).Hostname())
 }
 
+// TOTPDigits returns the number of digits of newly enrolled TOTP devices.
+func (p *Config) TOTPDigits(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeyTOTPDigits, 6)
+}
+
+// TOTPPeriod returns the number of seconds a code of a newly enrolled TOTP device is valid for.
+func (p *Config) TOTPPeriod(ctx context.Context) uint {
+	return uint(p.GetProvider(ctx).IntF(ViperKeyTOTPPeriod, 30))
+}
+
+// TOTPSkew returns the number of periods before and after the current one in which a TOTP code is accepted.
+func (p *Config) TOTPSkew(ctx context.Context) uint {
+	return uint(p.GetProvider(ctx).IntF(ViperKeyTOTPSkew, 1))
+}
+
+// PushRequestConfig returns the HTTP request configuration used to deliver push approval requests.
+func (p *Config) PushRequestConfig(ctx context.Context) json.RawMessage {
+	config, err := json.Marshal(p.GetProvider(ctx).Get(ViperKeyPushRequestConfig))
+	if err != nil {
+		p.l.WithError(err).Warn("Unable to marshal push request configuration.")
+		return json.RawMessage("{}")
+	}
+	return config
+}
+
+// PushChallengeLifespan returns how long a push approval request can be answered.
+func (p *Config) PushChallengeLifespan(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeyPushChallengeLifespan, 2*time.Minute)
+}
+
+// PushPollTimeout returns how long a single poll for a push approval is held open.
+func (p *Config) PushPollTimeout(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeyPushPollTimeout, 10*time.Second)
+}
+
+// ExternalMFAInitiateRequestConfig returns the HTTP request configuration used to start a second
+// factor challenge at the external MFA provider.
+func (p *Config) ExternalMFAInitiateRequestConfig(ctx context.Context) json.RawMessage {
+	config, err := json.Marshal(p.GetProvider(ctx).Get(ViperKeyExternalMFAInitiateRequestConfig))
+	if err != nil {
+		p.l.WithError(err).Warn("Unable to marshal external MFA initiate request configuration.")
+		return json.RawMessage("{}")
+	}
+	return config
+}
+
+// ExternalMFAVerifyRequestConfig returns the HTTP request configuration used to ask the external
+// MFA provider for the result of a challenge.
+func (p *Config) ExternalMFAVerifyRequestConfig(ctx context.Context) json.RawMessage {
+	config, err := json.Marshal(p.GetProvider(ctx).Get(ViperKeyExternalMFAVerifyRequestConfig))
+	if err != nil {
+		p.l.WithError(err).Warn("Unable to marshal external MFA verify request configuration.")
+		return json.RawMessage("{}")
+	}
+	return config
+}
+
+// ExternalMFALifespan returns how long a challenge at the external MFA provider can be completed.
+func (p *Config) ExternalMFALifespan(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeyExternalMFALifespan, 5*time.Minute)
+}
+
+// ExternalMFAPollTimeout returns how long a single poll for the result of a challenge at the
+// external MFA provider is held open.
+func (p *Config) ExternalMFAPollTimeout(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeyExternalMFAPollTimeout, 10*time.Second)
+}
+
+// SelfServiceTrustedDeviceEnabled returns true if browsers can be remembered to skip the second factor.
+func (p *Config) SelfServiceTrustedDeviceEnabled(ctx context.Context) bool {
+	return p.SelfServiceStrategy(ctx, "trusted_device").Enabled
+}
+
+// SelfServiceTrustedDeviceLifespan returns how long a remembered browser may skip the second factor.
+func (p *Config) SelfServiceTrustedDeviceLifespan(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeyTrustedDeviceLifespan, 30*24*time.Hour)
+}
+
+// SelfServiceConsentDocuments returns the documents, such as the terms of service, which identities
+// accept in the registration and settings flows. It returns nil if the consent method is disabled.
+func (p *Config) SelfServiceConsentDocuments(ctx context.Context) []ConsentDocument {
+	if !p.SelfServiceStrategy(ctx, "consent").Enabled {
+		return nil
+	}
+
+	raw, err := json.Marshal(p.GetProvider(ctx).Get(ViperKeySelfServiceConsentDocuments))
+	if err != nil {
+		p.l.WithError(err).Warn("Unable to marshal consent documents.")
+		return nil
+	}
+
+	var documents []ConsentDocument
+	if err := json.Unmarshal(raw, &documents); err != nil {
+		p.l.WithError(err).Warn("Unable to unmarshal consent documents.")
+		return nil
+	}
+	return documents
+}
+
+// LookupSecretCount returns how many lookup secrets are generated at once.
+func (p *Config) LookupSecretCount(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeyLookupSecretCount, 12)
+}
+
+// LookupSecretLength returns the number of characters of a lookup secret.
+func (p *Config) LookupSecretLength(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeyLookupSecretLength, 8)
+}
+
+// LookupSecretFormat returns the characters lookup secrets are made of, either
+// "alphanumeric" or "numeric".
+func (p *Config) LookupSecretFormat(ctx context.Context) string {
+	return p.GetProvider(ctx).StringF(ViperKeyLookupSecretFormat, "alphanumeric")
+}
+
+// LookupSecretWarnRemaining returns the number of unused lookup secrets at or
+// below which users are asked to regenerate them. Zero disables the warning.
+func (p *Config) LookupSecretWarnRemaining(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeyLookupSecretWarnRemaining, 0)
+}
+
 func (p *Config) OIDCRedirectURIBase(ctx context.Context) *url.URL {
 	return p.GetProvider(ctx).URIF(ViperKeyOIDCBaseRedirectURL, p.SelfPublicURL(ctx))
 }
@@ -581,6 +974,38 @@ func (p *Config) IdentityTraitsSchemas(ctx context.Context) (ss Schemas, err err
 	return ss, nil
 }
 
+func (p *Config) IdentitySchemaMigrations(ctx context.Context) (ms []SchemaMigration, err error) {
+	if err = p.GetProvider(ctx).Koanf.Unmarshal(ViperKeyIdentitySchemaMigrations, &ms); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return ms, nil
+}
+
+func (p *Config) IdentitySoftDeleteRetention(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeyIdentitySoftDeleteRetention, 30*24*time.Hour)
+}
+
+func (p *Config) IdentityEventsEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeyIdentityEventsEnabled)
+}
+
+func (p *Config) IdentityEventSinks(ctx context.Context) (sinks []IdentityEventSink, err error) {
+	if err = p.GetProvider(ctx).Koanf.Unmarshal(ViperKeyIdentityEventSinks, &sinks); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return sinks, nil
+}
+
+func (p *Config) IdentityEventsDeliveryInterval(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeyIdentityEventsDeliveryInterval, 10*time.Second)
+}
+
+func (p *Config) IdentityEventsDeliveryMaxAttempts(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeyIdentityEventsDeliveryMaxAttempts, 10)
+}
+
 func (p *Config) AdminListenOn(ctx context.Context) string {
 	return p.listenOn(ctx, "admin")
 }
@@ -589,6 +1014,12 @@ func (p *Config) PublicListenOn(ctx context.Context) string {
 	return p.listenOn(ctx, "public")
 }
 
+// PublicTrustedProxies returns the IP addresses and CIDR ranges of the reverse proxies whose forwarding headers are
+// trusted to carry the client IP address.
+func (p *Config) PublicTrustedProxies(ctx context.Context) []string {
+	return p.GetProvider(ctx).StringsF(ViperKeyPublicTrustedProxies, []string{})
+}
+
 func (p *Config) PublicSocketPermission(ctx context.Context) *configx.UnixPermission {
 	pp := p.GetProvider(ctx)
 	return &configx.UnixPermission{
@@ -639,6 +1070,18 @@ func (p *Config) ClientHTTPPrivateIPExceptionURLs(ctx context.Context) []string
 	return p.GetProvider(ctx).Strings(ViperKeyClientHTTPPrivateIPExceptionURLs)
 }
 
+// SelfServiceAccountEnumerationMitigate returns true if login, registration, and recovery must respond
+// the same way for known and unknown identifiers.
+func (p *Config) SelfServiceAccountEnumerationMitigate(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySelfServiceAccountEnumerationMitigate)
+}
+
+// SelfServiceAccountEnumerationResponseTime returns the minimum duration of responses which could reveal
+// whether an identifier is known if account enumeration is mitigated.
+func (p *Config) SelfServiceAccountEnumerationResponseTime(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceAccountEnumerationResponseTime, 500*time.Millisecond)
+}
+
 func (p *Config) SelfServiceFlowRegistrationEnabled(ctx context.Context) bool {
 	return p.GetProvider(ctx).Bool(ViperKeySelfServiceRegistrationEnabled)
 }
@@ -647,6 +1090,68 @@ func (p *Config) SelfServiceFlowRegistrationLoginHints(ctx context.Context) bool
 	return p.GetProvider(ctx).Bool(ViperKeySelfServiceRegistrationLoginHints)
 }
 
+// SelfServiceFlowRegistrationVerifyBeforeCreation returns true if the email addresses of an identity must be
+// verified with a code before the identity is created.
+func (p *Config) SelfServiceFlowRegistrationVerifyBeforeCreation(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySelfServiceRegistrationVerifyBeforeCreation)
+}
+
+// SelfServiceFlowRegistrationInvitationsRequired returns true if registration flows can only be started
+// with a valid invitation.
+func (p *Config) SelfServiceFlowRegistrationInvitationsRequired(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySelfServiceRegistrationInvitationsRequired)
+}
+
+// SelfServiceFlowRegistrationInvitationsLifespan returns the default time after which invitations expire.
+func (p *Config) SelfServiceFlowRegistrationInvitationsLifespan(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceRegistrationInvitationsLifespan, 7*24*time.Hour)
+}
+
+// SelfServiceFlowRegistrationSignupCodesEnabled returns true if the registration form asks for a sign-up code.
+func (p *Config) SelfServiceFlowRegistrationSignupCodesEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySelfServiceRegistrationSignupCodesEnabled)
+}
+
+// SelfServiceFlowRegistrationSignupCodesRequired returns true if identities can only register with a valid
+// sign-up code.
+func (p *Config) SelfServiceFlowRegistrationSignupCodesRequired(ctx context.Context) bool {
+	return p.SelfServiceFlowRegistrationSignupCodesEnabled(ctx) && p.GetProvider(ctx).Bool(ViperKeySelfServiceRegistrationSignupCodesRequired)
+}
+
+// SelfServiceFlowRegistrationApprovalRequired returns true if registered identities must be approved
+// by an administrator before they can sign in.
+func (p *Config) SelfServiceFlowRegistrationApprovalRequired(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySelfServiceRegistrationApprovalRequired)
+}
+
+// SelfServiceFlowRegistrationStepsCredentialsFirst returns true if registration flows with several steps offer
+// the registration methods in the first instead of the last step.
+func (p *Config) SelfServiceFlowRegistrationStepsCredentialsFirst(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySelfServiceRegistrationStepsCredentialsFirst)
+}
+
+// SelfServiceFlowRegistrationLoginHandoff returns true if registrations for an identifier which exists already
+// offer a login flow which is pre-filled with the identifier.
+func (p *Config) SelfServiceFlowRegistrationLoginHandoff(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySelfServiceRegistrationLoginHandoffEnabled)
+}
+
+// SelfServiceFlowRegistrationLoginHandoffRecovery returns true if the login handoff also offers a recovery flow.
+func (p *Config) SelfServiceFlowRegistrationLoginHandoffRecovery(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySelfServiceRegistrationLoginHandoffRecovery)
+}
+
+// SelfServiceFlowRegistrationEmailDomainsAllow returns the email domains identities can register with.
+// All domains are allowed if the list is empty.
+func (p *Config) SelfServiceFlowRegistrationEmailDomainsAllow(ctx context.Context) []string {
+	return p.GetProvider(ctx).Strings(ViperKeySelfServiceRegistrationEmailDomainsAllow)
+}
+
+// SelfServiceFlowRegistrationEmailDomainsDeny returns the email domains identities can not register with.
+func (p *Config) SelfServiceFlowRegistrationEmailDomainsDeny(ctx context.Context) []string {
+	return p.GetProvider(ctx).Strings(ViperKeySelfServiceRegistrationEmailDomainsDeny)
+}
+
 func (p *Config) SelfServiceFlowVerificationEnabled(ctx context.Context) bool {
 	return p.GetProvider(ctx).Bool(ViperKeySelfServiceVerificationEnabled)
 }
@@ -679,10 +1184,162 @@ func (p *Config) SelfServiceFlowVerificationNotifyUnknownRecipients(ctx context.
 	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceVerificationNotifyUnknownRecipients, false)
 }
 
+// SelfServiceFlowVerificationPhoneNumbers returns true if phone numbers can be verified with codes sent
+// via SMS.
+func (p *Config) SelfServiceFlowVerificationPhoneNumbers(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceVerificationPhoneNumbers, false)
+}
+
+func (p *Config) SelfServiceFlowVerificationReminders(ctx context.Context) (*VerificationReminders, error) {
+	type policy struct {
+		Enabled            *bool    `json:"enabled"`
+		Intervals          []string `json:"intervals"`
+		UnverifiedAccounts *struct {
+			Action string `json:"action"`
+			After  string `json:"after"`
+		} `json:"unverified_accounts"`
+	}
+	var raw struct {
+		policy
+		Schemas []struct {
+			ID string `json:"id"`
+			policy
+		} `json:"schemas"`
+	}
+
+	if val := p.GetProvider(ctx).Get(ViperKeySelfServiceVerificationReminders); val != nil {
+		config, err := json.Marshal(val)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := json.Unmarshal(config, &raw); err != nil {
+			return nil, errors.Wrapf(err, "unable to decode value from configuration key: %s", ViperKeySelfServiceVerificationReminders)
+		}
+	}
+
+	apply := func(dst *VerificationReminderPolicy, src policy) error {
+		if src.Enabled != nil {
+			dst.Enabled = *src.Enabled
+		}
+		if src.Intervals != nil {
+			dst.Intervals = make([]time.Duration, len(src.Intervals))
+			for k, v := range src.Intervals {
+				d, err := time.ParseDuration(v)
+				if err != nil {
+					return errors.Wrapf(err, "unable to parse verification reminder interval %q", v)
+				}
+				dst.Intervals[k] = d
+			}
+			sort.Slice(dst.Intervals, func(i, j int) bool { return dst.Intervals[i] < dst.Intervals[j] })
+		}
+		if src.UnverifiedAccounts != nil {
+			if src.UnverifiedAccounts.Action != "" {
+				dst.UnverifiedAccountAction = UnverifiedAccountAction(src.UnverifiedAccounts.Action)
+			}
+			if src.UnverifiedAccounts.After != "" {
+				d, err := time.ParseDuration(src.UnverifiedAccounts.After)
+				if err != nil {
+					return errors.Wrapf(err, "unable to parse unverified account lifespan %q", src.UnverifiedAccounts.After)
+				}
+				dst.UnverifiedAccountAfter = d
+			}
+		}
+		return nil
+	}
+
+	r := &VerificationReminders{
+		Default: VerificationReminderPolicy{UnverifiedAccountAction: UnverifiedAccountActionNone},
+		Schemas: make(map[string]VerificationReminderPolicy, len(raw.Schemas)),
+	}
+	if err := apply(&r.Default, raw.policy); err != nil {
+		return nil, err
+	}
+	for _, s := range raw.Schemas {
+		policy := r.Default
+		if err := apply(&policy, s.policy); err != nil {
+			return nil, err
+		}
+		r.Schemas[s.ID] = policy
+	}
+
+	return r, nil
+}
+
+// SelfServiceFlowVerificationRemindersBackground returns true if the verification reminder job runs in
+// the background of the server.
+func (p *Config) SelfServiceFlowVerificationRemindersBackground(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySelfServiceVerificationRemindersBackground)
+}
+
+func (p *Config) SelfServiceFlowVerificationRemindersInterval(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceVerificationRemindersInterval, time.Hour)
+}
+
+// SelfServiceFlowVerificationRemindersBatchSize returns how many unverified addresses the background
+// verification reminder job loads at once.
+func (p *Config) SelfServiceFlowVerificationRemindersBatchSize(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeySelfServiceVerificationRemindersBatchSize, 100)
+}
+
+// ForSchema returns the reminder policy for identities of the given identity schema.
+func (r *VerificationReminders) ForSchema(id string) VerificationReminderPolicy {
+	if policy, ok := r.Schemas[id]; ok {
+		return policy
+	}
+	return r.Default
+}
+
+// MinAge returns the youngest address age at which any enabled policy sends a reminder or expires an
+// account. It returns false if no policy is enabled.
+func (r *VerificationReminders) MinAge() (age time.Duration, ok bool) {
+	policies := append([]VerificationReminderPolicy{r.Default}, maps.Values(r.Schemas)...)
+	for _, policy := range policies {
+		if !policy.Enabled {
+			continue
+		}
+		candidates := append([]time.Duration{}, policy.Intervals...)
+		if after, expires := policy.ExpiresAfter(); expires {
+			candidates = append(candidates, after)
+		}
+		for _, c := range candidates {
+			if !ok || c < age {
+				age, ok = c, true
+			}
+		}
+	}
+	return age, ok
+}
+
+// DueReminders returns the number of reminders which should have been sent for an address of the given age.
+func (p VerificationReminderPolicy) DueReminders(age time.Duration) int {
+	var due int
+	for _, interval := range p.Intervals {
+		if age >= interval {
+			due++
+		}
+	}
+	return due
+}
+
+// ExpiresAfter returns the age after which an unverified account is deactivated or deleted. It returns
+// false if unverified accounts are kept.
+func (p VerificationReminderPolicy) ExpiresAfter() (time.Duration, bool) {
+	if p.UnverifiedAccountAction == "" || p.UnverifiedAccountAction == UnverifiedAccountActionNone || p.UnverifiedAccountAfter <= 0 {
+		return 0, false
+	}
+	return p.UnverifiedAccountAfter, true
+}
+
 func (p *Config) SelfServiceFlowSettingsBeforeHooks(ctx context.Context) []SelfServiceHook {
 	return p.selfServiceHooks(ctx, ViperKeySelfServiceSettingsBeforeHooks)
 }
 
+// SelfServiceFlowBeforeRenderHooks returns the hooks which may change the UI of a flow before it is
+// returned to the client.
+func (p *Config) SelfServiceFlowBeforeRenderHooks(ctx context.Context, flow string) []SelfServiceHook {
+	return p.selfServiceHooks(ctx, fmt.Sprintf("selfservice.flows.%s.before_render.hooks", flow))
+}
+
 func (p *Config) SelfServiceFlowRegistrationBeforeHooks(ctx context.Context) []SelfServiceHook {
 	return p.selfServiceHooks(ctx, ViperKeySelfServiceRegistrationBeforeHooks)
 }
@@ -951,10 +1608,36 @@ func (p *Config) SessionLifespan(ctx context.Context) time.Duration {
 	return p.GetProvider(ctx).DurationF(ViperKeySessionLifespan, time.Hour*24)
 }
 
+// SessionMethodLifespan returns the session lifespan configured for the given authentication
+// method and whether one was configured at all.
+func (p *Config) SessionMethodLifespan(ctx context.Context, method string) (time.Duration, bool) {
+	key := ViperKeySessionMethodLifespans + "." + method
+	if !p.GetProvider(ctx).Exists(key) {
+		return 0, false
+	}
+	return p.GetProvider(ctx).DurationF(key, p.SessionLifespan(ctx)), true
+}
+
 func (p *Config) SessionPersistentCookie(ctx context.Context) bool {
 	return p.GetProvider(ctx).Bool(ViperKeySessionPersistentCookie)
 }
 
+// SelfServiceThemeVariables returns the branding variables which are passed to self-service flows and courier templates.
+func (p *Config) SelfServiceThemeVariables(ctx context.Context) map[string]interface{} {
+	raw, err := json.Marshal(p.GetProvider(ctx).Get(ViperKeySelfServiceThemeVariables))
+	if err != nil {
+		p.l.WithError(err).Warn("Unable to marshal theme variables.")
+		return nil
+	}
+
+	var vars map[string]interface{}
+	if err := json.Unmarshal(raw, &vars); err != nil {
+		p.l.WithError(err).Warn("Unable to unmarshal theme variables.")
+		return nil
+	}
+	return vars
+}
+
 func (p *Config) SelfServiceBrowserAllowedReturnToDomains(ctx context.Context) (us []url.URL) {
 	src := p.GetProvider(ctx).Strings(ViperKeyURLsAllowedReturnToDomains)
 	for k, u := range src {
@@ -990,6 +1673,118 @@ func (p *Config) SelfServiceFlowLoginRequestLifespan(ctx context.Context) time.D
 	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceLoginRequestLifespan, time.Hour)
 }
 
+func (p *Config) SelfServiceFlowLoginSoftReauthenticationEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceLoginSoftReauthenticationEnabled, false)
+}
+
+// SelfServiceFlowLoginSoftReauthenticationLifespan returns for how long after a session expired the
+// identifier and method of the last login are remembered. Defaults to 24 hours.
+func (p *Config) SelfServiceFlowLoginSoftReauthenticationLifespan(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceLoginSoftReauthenticationLifespan, time.Hour*24)
+}
+
+func (p *Config) SelfServiceFlowLoginLockoutEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceLoginLockoutEnabled, false)
+}
+
+// SelfServiceFlowLoginLockoutMaxAttempts returns the number of failed attempts after which an
+// identity is locked. Defaults to 5.
+func (p *Config) SelfServiceFlowLoginLockoutMaxAttempts(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeySelfServiceLoginLockoutMaxAttempts, 5)
+}
+
+// SelfServiceFlowLoginLockoutIPMaxAttempts returns the number of failed attempts after which an
+// IP address is locked. Defaults to 20.
+func (p *Config) SelfServiceFlowLoginLockoutIPMaxAttempts(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeySelfServiceLoginLockoutIPMaxAttempts, 20)
+}
+
+func (p *Config) SelfServiceFlowLoginLockoutAttemptWindow(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceLoginLockoutAttemptWindow, 15*time.Minute)
+}
+
+func (p *Config) SelfServiceFlowLoginLockoutBaseDuration(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceLoginLockoutBaseDuration, time.Minute)
+}
+
+func (p *Config) SelfServiceFlowLoginLockoutMaxDuration(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceLoginLockoutMaxDuration, time.Hour)
+}
+
+func (p *Config) SelfServiceFlowLoginRateLimitEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceLoginRateLimitEnabled, false)
+}
+
+// SelfServiceFlowLoginRateLimitStore returns where login attempts are counted, either `memory` or `redis`.
+func (p *Config) SelfServiceFlowLoginRateLimitStore(ctx context.Context) string {
+	return p.GetProvider(ctx).StringF(ViperKeySelfServiceLoginRateLimitStore, "memory")
+}
+
+func (p *Config) SelfServiceFlowLoginRateLimitRedisURL(ctx context.Context) string {
+	return p.GetProvider(ctx).String(ViperKeySelfServiceLoginRateLimitRedisURL)
+}
+
+// SelfServiceFlowLoginRateLimitIdentifierLimit returns the number of login attempts per identifier and
+// window. Defaults to 10.
+func (p *Config) SelfServiceFlowLoginRateLimitIdentifierLimit(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeySelfServiceLoginRateLimitIdentifierLimit, 10)
+}
+
+func (p *Config) SelfServiceFlowLoginRateLimitIdentifierWindow(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceLoginRateLimitIdentifierWindow, time.Minute)
+}
+
+// SelfServiceFlowLoginRateLimitIPLimit returns the number of login attempts per IP address and window.
+// Defaults to 100.
+func (p *Config) SelfServiceFlowLoginRateLimitIPLimit(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeySelfServiceLoginRateLimitIPLimit, 100)
+}
+
+func (p *Config) SelfServiceFlowLoginRateLimitIPWindow(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceLoginRateLimitIPWindow, time.Minute)
+}
+
+// SelfServiceFlowLoginNewDeviceNotificationEnabled returns true if identities are notified about logins
+// from devices or IP addresses which they did not use before.
+func (p *Config) SelfServiceFlowLoginNewDeviceNotificationEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceLoginNewDeviceNotificationEnabled, false)
+}
+
+// SelfServiceFlowLoginNewDeviceNotificationFlowTypes returns the login flow types, `browser` and `api`,
+// for which new devices are notified.
+func (p *Config) SelfServiceFlowLoginNewDeviceNotificationFlowTypes(ctx context.Context) []string {
+	return p.GetProvider(ctx).StringsF(ViperKeySelfServiceLoginNewDeviceNotificationFlowTypes, []string{"browser", "api"})
+}
+
+// SelfServiceFlowLoginRequireVerifiedAddress returns true if logins with the given method must use a verified address.
+func (p *Config) SelfServiceFlowLoginRequireVerifiedAddress(ctx context.Context, method string) bool {
+	if !p.GetProvider(ctx).BoolF(ViperKeySelfServiceLoginRequireVerifiedAddressEnabled, false) {
+		return false
+	}
+
+	methods := p.GetProvider(ctx).Strings(ViperKeySelfServiceLoginRequireVerifiedAddressMethods)
+	return len(methods) == 0 || slices.Contains(methods, method)
+}
+
+func (p *Config) SelfServiceFlowLoginRiskAssessmentEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceLoginRiskAssessmentEnabled, false)
+}
+
+func (p *Config) SelfServiceFlowLoginRiskAssessmentRequestConfig(ctx context.Context) json.RawMessage {
+	config, err := json.Marshal(p.GetProvider(ctx).Get(ViperKeySelfServiceLoginRiskAssessmentRequestConfig))
+	if err != nil {
+		p.l.WithError(err).Warn("Unable to marshal risk assessment request configuration.")
+		return json.RawMessage("{}")
+	}
+	return config
+}
+
+// SelfServiceFlowLoginRiskAssessmentOnError returns the decision which is applied if the risk engine can not be
+// reached or returns an invalid response. Defaults to "allow".
+func (p *Config) SelfServiceFlowLoginRiskAssessmentOnError(ctx context.Context) string {
+	return p.GetProvider(ctx).StringF(ViperKeySelfServiceLoginRiskAssessmentOnError, "allow")
+}
+
 func (p *Config) SelfServiceFlowSettingsFlowLifespan(ctx context.Context) time.Duration {
 	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceSettingsRequestLifespan, time.Hour)
 }
@@ -1110,6 +1905,30 @@ func (p *Config) CourierTemplatesRegistrationCodeValid(ctx context.Context) *Cou
 	return p.CourierTemplatesHelper(ctx, ViperKeyCourierTemplatesRegistrationCodeValidEmail)
 }
 
+func (p *Config) CourierTemplatesRecoveryNotification(ctx context.Context) *CourierEmailTemplate {
+	return p.CourierTemplatesHelper(ctx, ViperKeyCourierTemplatesRecoveryNotificationEmail)
+}
+
+func (p *Config) CourierTemplatesLoginNewDevice(ctx context.Context) *CourierEmailTemplate {
+	return p.CourierTemplatesHelper(ctx, ViperKeyCourierTemplatesLoginNewDeviceEmail)
+}
+
+func (p *Config) CourierTemplatesRegistrationApproved(ctx context.Context) *CourierEmailTemplate {
+	return p.CourierTemplatesHelper(ctx, ViperKeyCourierTemplatesRegistrationApprovedEmail)
+}
+
+func (p *Config) CourierTemplatesEmailChangeConfirm(ctx context.Context) *CourierEmailTemplate {
+	return p.CourierTemplatesHelper(ctx, ViperKeyCourierTemplatesEmailChangeConfirmEmail)
+}
+
+func (p *Config) CourierTemplatesEmailChangeNotice(ctx context.Context) *CourierEmailTemplate {
+	return p.CourierTemplatesHelper(ctx, ViperKeyCourierTemplatesEmailChangeNoticeEmail)
+}
+
+func (p *Config) CourierTemplatesRegistrationDuplicate(ctx context.Context) *CourierEmailTemplate {
+	return p.CourierTemplatesHelper(ctx, ViperKeyCourierTemplatesRegistrationDuplicateEmail)
+}
+
 func (p *Config) CourierMessageRetries(ctx context.Context) int {
 	return p.GetProvider(ctx).IntF(ViperKeyCourierMessageRetries, 5)
 }
@@ -1147,6 +1966,30 @@ func (p *Config) CourierSMSEnabled(ctx context.Context) bool {
 	return p.GetProvider(ctx).Bool(ViperKeyCourierSMSEnabled)
 }
 
+func (p *Config) CourierWhatsAppRequestConfig(ctx context.Context) json.RawMessage {
+	if !p.GetProvider(ctx).Bool(ViperKeyCourierWhatsAppEnabled) {
+		return nil
+	}
+
+	config, err := json.Marshal(p.GetProvider(ctx).Get(ViperKeyCourierWhatsAppRequestConfig))
+	if err != nil {
+		p.l.WithError(err).Warn("Unable to marshal WhatsApp request configuration.")
+		return json.RawMessage("{}")
+	}
+	return config
+}
+
+// CourierWhatsAppFrom returns the WhatsApp Business phone number ID or sender codes are sent from.
+func (p *Config) CourierWhatsAppFrom(ctx context.Context) string {
+	return p.GetProvider(ctx).String(ViperKeyCourierWhatsAppFrom)
+}
+
+// CourierWhatsAppEnabled returns true if codes sent to phone numbers are delivered via WhatsApp instead
+// of SMS.
+func (p *Config) CourierWhatsAppEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeyCourierWhatsAppEnabled)
+}
+
 func splitUrlAndFragment(s string) (string, string) {
 	i := strings.IndexByte(s, '#')
 	if i < 0 {
@@ -1206,6 +2049,19 @@ func (p *Config) IsInsecureDevMode(ctx context.Context) bool {
 	return p.GetProvider(ctx).Bool("dev")
 }
 
+// TestClockEnabled returns true if the test clock may be moved using the admin API. The test clock is
+// never enabled unless the "--dev" flag is set.
+func (p *Config) TestClockEnabled(ctx context.Context) bool {
+	if !p.GetProvider(ctx).Bool(ViperKeyPreviewTestClockEnabled) {
+		return false
+	}
+	if !p.IsInsecureDevMode(ctx) {
+		p.l.Warn("The test clock is only available if the \"--dev\" flag is set and remains disabled.")
+		return false
+	}
+	return true
+}
+
 func (p *Config) IsBackgroundCourierEnabled(ctx context.Context) bool {
 	return p.GetProvider(ctx).Bool("watch-courier")
 }
@@ -1243,9 +2099,70 @@ func (p *Config) SelfServiceFlowRecoveryRequestLifespan(ctx context.Context) tim
 }
 
 func (p *Config) SelfServiceFlowRecoveryNotifyUnknownRecipients(ctx context.Context) bool {
+	if p.GetProvider(ctx).Exists(ViperKeySelfServiceRecoveryUnknownAddressNotify) {
+		return p.GetProvider(ctx).Bool(ViperKeySelfServiceRecoveryUnknownAddressNotify)
+	}
 	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceRecoveryNotifyUnknownRecipients, false)
 }
 
+// SelfServiceFlowRecoveryUnknownAddressAudit returns true if recovery requests for unknown addresses
+// are written to the audit log.
+func (p *Config) SelfServiceFlowRecoveryUnknownAddressAudit(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceRecoveryUnknownAddressAudit, true)
+}
+
+// SelfServiceFlowRecoveryUnknownAddressHooks returns the hooks executed if recovery is requested
+// for an unknown address.
+func (p *Config) SelfServiceFlowRecoveryUnknownAddressHooks(ctx context.Context) []SelfServiceHook {
+	return p.selfServiceHooks(ctx, ViperKeySelfServiceRecoveryUnknownAddressHooks)
+}
+
+func (p *Config) SelfServiceFlowRecoveryAddressSelection(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceRecoveryAddressSelection, false)
+}
+
+// SelfServiceFlowRecoveryRequiredActions returns the settings the user must complete with the
+// session issued by a recovery flow before the session can be used elsewhere.
+func (p *Config) SelfServiceFlowRecoveryRequiredActions(ctx context.Context) []string {
+	return p.GetProvider(ctx).Strings(ViperKeySelfServiceRecoveryRequiredActions)
+}
+
+// SelfServiceFlowRecoveryRestrictSession returns true if the session issued by a recovery flow may
+// only be used for the settings flow until a password or second factor was set.
+func (p *Config) SelfServiceFlowRecoveryRestrictSession(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceRecoveryRestrictSession, false)
+}
+
+// SelfServiceFlowRecoveryAllowTOTPReset returns true if a session issued by a recovery flow may unlink the
+// TOTP authenticator app in the settings flow without reaching the AAL required for the settings flow.
+func (p *Config) SelfServiceFlowRecoveryAllowTOTPReset(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceRecoveryAllowTOTPReset, false)
+}
+
+// SelfServiceFlowRecoveryPhoneNumbers returns true if accounts can be recovered with codes sent to
+// phone numbers.
+func (p *Config) SelfServiceFlowRecoveryPhoneNumbers(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceRecoveryPhoneNumbers, false)
+}
+
+// SelfServiceFlowRecoveryIdentifiers returns true if accounts can be recovered by submitting a login
+// identifier instead of a recovery address.
+func (p *Config) SelfServiceFlowRecoveryIdentifiers(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceRecoveryIdentifiers, false)
+}
+
+// SelfServiceFlowRecoveryNotifyOnSuccess returns true if the other addresses of an identity are
+// notified when the identity was recovered.
+func (p *Config) SelfServiceFlowRecoveryNotifyOnSuccess(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceRecoveryNotifyOnSuccess, false)
+}
+
+// SelfServiceFlowRecoveryRequiredAAL returns the Authenticator Assurance Level the session issued by a
+// recovery flow must reach before it can be used. Either "aal1" or "highest_available".
+func (p *Config) SelfServiceFlowRecoveryRequiredAAL(ctx context.Context) string {
+	return p.GetProvider(ctx).StringF(ViperKeySelfServiceRecoveryRequiredAAL, "aal1")
+}
+
 func (p *Config) SelfServiceLinkMethodLifespan(ctx context.Context) time.Duration {
 	return p.GetProvider(ctx).DurationF(ViperKeyLinkLifespan, time.Hour)
 }
@@ -1258,6 +2175,44 @@ func (p *Config) SelfServiceCodeMethodLifespan(ctx context.Context) time.Duratio
 	return p.GetProvider(ctx).DurationF(ViperKeyCodeLifespan, time.Hour)
 }
 
+func (p *Config) SelfServiceCodeMethodMaxSubmissions(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeyCodeMaxSubmissions, 5)
+}
+
+// SelfServiceCodeMethodBackend returns the type of the backend which generates and validates codes.
+func (p *Config) SelfServiceCodeMethodBackend(ctx context.Context) string {
+	return p.GetProvider(ctx).StringF(ViperKeyCodeBackendType, "builtin")
+}
+
+// SelfServiceCodeMethodBackendRequestConfig returns the HTTP request configuration of the external code backend.
+func (p *Config) SelfServiceCodeMethodBackendRequestConfig(ctx context.Context) json.RawMessage {
+	config, err := json.Marshal(p.GetProvider(ctx).Get(ViperKeyCodeBackendRequestConfig))
+	if err != nil {
+		p.l.WithError(err).Warn("Unable to marshal code backend request configuration.")
+		return json.RawMessage("{}")
+	}
+	return config
+}
+
+// CodeBackendTwilioVerify configures the Twilio Verify (or a compatible) API which sends and checks codes.
+type CodeBackendTwilioVerify struct {
+	URL        string `json:"url"`
+	ServiceSID string `json:"service_sid"`
+	AccountSID string `json:"account_sid"`
+	AuthToken  string `json:"auth_token"`
+}
+
+// SelfServiceCodeMethodBackendTwilioVerify returns the configuration of the Twilio Verify code backend.
+func (p *Config) SelfServiceCodeMethodBackendTwilioVerify(ctx context.Context) *CodeBackendTwilioVerify {
+	pp := p.GetProvider(ctx)
+	return &CodeBackendTwilioVerify{
+		URL:        pp.StringF(ViperKeyCodeBackendTwilioVerify+".url", "https://verify.twilio.com"),
+		ServiceSID: pp.String(ViperKeyCodeBackendTwilioVerify + ".service_sid"),
+		AccountSID: pp.String(ViperKeyCodeBackendTwilioVerify + ".account_sid"),
+		AuthToken:  pp.String(ViperKeyCodeBackendTwilioVerify + ".auth_token"),
+	}
+}
+
 func (p *Config) DatabaseCleanupSleepTables(ctx context.Context) time.Duration {
 	return p.GetProvider(ctx).Duration(ViperKeyDatabaseCleanupSleepTables)
 }
@@ -1266,6 +2221,59 @@ func (p *Config) DatabaseCleanupBatchSize(ctx context.Context) int {
 	return p.GetProvider(ctx).Int(ViperKeyDatabaseCleanupBatchSize)
 }
 
+// DatabaseCleanupInterval returns the time between two cleanup runs of `kratos cleanup --watch`.
+func (p *Config) DatabaseCleanupInterval(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeyDatabaseCleanupInterval, time.Hour)
+}
+
+func (p *Config) DatabaseCleanupExpiredSessionsEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeyDatabaseCleanupExpiredSessionsEnabled)
+}
+
+// DatabaseCleanupExpiredSessionsWebhook returns the request configuration of
+// the expired sessions webhook or nil if no webhook is configured.
+func (p *Config) DatabaseCleanupExpiredSessionsWebhook(ctx context.Context) json.RawMessage {
+	if !p.GetProvider(ctx).Exists(ViperKeyDatabaseCleanupExpiredSessionsWebhook) {
+		return nil
+	}
+
+	config, err := json.Marshal(p.GetProvider(ctx).Get(ViperKeyDatabaseCleanupExpiredSessionsWebhook))
+	if err != nil {
+		p.l.WithError(err).Warn("Unable to marshal expired sessions webhook configuration.")
+		return nil
+	}
+	return config
+}
+
+func (p *Config) DatabaseCleanupExpiredSessionsBatchSize(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeyDatabaseCleanupExpiredSessionsBatchSize, 100)
+}
+
+func (p *Config) DatabaseTableMetricsEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeyDatabaseTableMetricsEnabled)
+}
+
+func (p *Config) DatabaseTableMetricsInterval(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeyDatabaseTableMetricsInterval, 5*time.Minute)
+}
+
+func (p *Config) DatabasePhasedMigrationsBackfillEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeyDatabasePhasedMigrationsBackfillEnabled)
+}
+
+func (p *Config) DatabasePhasedMigrationsBackfillBatchSize(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeyDatabasePhasedMigrationsBackfillBatchSize, 1000)
+}
+
+func (p *Config) DatabasePhasedMigrationsBackfillInterval(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeyDatabasePhasedMigrationsBackfillInterval, time.Second)
+}
+
+// DatabasePhasedMigrationsCutover returns the names of the phased migrations which read from the new schema.
+func (p *Config) DatabasePhasedMigrationsCutover(ctx context.Context) []string {
+	return p.GetProvider(ctx).Strings(ViperKeyDatabasePhasedMigrationsCutover)
+}
+
 func (p *Config) SelfServiceFlowRecoveryAfterHooks(ctx context.Context, strategy string) []SelfServiceHook {
 	return p.selfServiceHooks(ctx, HookStrategyKey(ViperKeySelfServiceRecoveryAfter, strategy))
 }
@@ -1274,6 +2282,18 @@ func (p *Config) SelfServiceFlowSettingsPrivilegedSessionMaxAge(ctx context.Cont
 	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceSettingsPrivilegedAuthenticationAfter, time.Hour)
 }
 
+// SelfServiceFlowSettingsVerifiedEmailChangeEnabled returns true if a new primary email address
+// must be confirmed before it replaces the current one.
+func (p *Config) SelfServiceFlowSettingsVerifiedEmailChangeEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySelfServiceSettingsVerifiedEmailChangeEnabled, false)
+}
+
+// SelfServiceFlowSettingsVerifiedEmailChangeLifespan returns how long the confirmation and
+// revert links of an email change are valid.
+func (p *Config) SelfServiceFlowSettingsVerifiedEmailChangeLifespan(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySelfServiceSettingsVerifiedEmailChangeLifespan, 24*time.Hour)
+}
+
 func (p *Config) SessionSameSiteMode(ctx context.Context) http.SameSite {
 	if !p.GetProvider(ctx).Exists(ViperKeySessionSameSite) {
 		return p.CookieSameSiteMode(ctx)
@@ -1297,6 +2317,61 @@ func (p *Config) SessionDomain(ctx context.Context) string {
 	return p.GetProvider(ctx).String(ViperKeySessionDomain)
 }
 
+// SessionCookieDomain scopes the session cookie of requests sent to the domain or its subdomains.
+type SessionCookieDomain struct {
+	Domain string `koanf:"domain" json:"domain"`
+	Name   string `koanf:"name" json:"name"`
+}
+
+// SessionCookieSameSiteOverride sets the SameSite attribute of session cookies issued by requests to paths with the prefix.
+type SessionCookieSameSiteOverride struct {
+	Path     string `koanf:"path" json:"path"`
+	SameSite string `koanf:"same_site" json:"same_site"`
+}
+
+// Mode returns the SameSite attribute of the override.
+func (o SessionCookieSameSiteOverride) Mode() http.SameSite {
+	switch o.SameSite {
+	case "Lax":
+		return http.SameSiteLaxMode
+	case "Strict":
+		return http.SameSiteStrictMode
+	case "None":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteDefaultMode
+}
+
+// SessionCookieDomains returns the domains which get their own session cookie in multi-domain setups.
+func (p *Config) SessionCookieDomains(ctx context.Context) []SessionCookieDomain {
+	var domains []SessionCookieDomain
+	if err := p.GetProvider(ctx).Unmarshal(ViperKeySessionCookieDomains, &domains); err != nil {
+		p.l.WithError(err).Warn("Unable to decode the session cookie domains.")
+		return nil
+	}
+	return domains
+}
+
+// SessionCookieSameSiteOverrides returns the SameSite attributes which apply to session cookies issued on certain paths.
+func (p *Config) SessionCookieSameSiteOverrides(ctx context.Context) []SessionCookieSameSiteOverride {
+	var overrides []SessionCookieSameSiteOverride
+	if err := p.GetProvider(ctx).Unmarshal(ViperKeySessionCookieSameSiteOverrides, &overrides); err != nil {
+		p.l.WithError(err).Warn("Unable to decode the session cookie SameSite overrides.")
+		return nil
+	}
+	return overrides
+}
+
+// SessionCookieHostPrefix returns true if the session cookie name must carry the `__Host-` prefix.
+func (p *Config) SessionCookieHostPrefix(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySessionCookieHostPrefix)
+}
+
+// SessionCookiePartitioned returns true if the session cookie is a partitioned (CHIPS) cookie.
+func (p *Config) SessionCookiePartitioned(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySessionCookiePartitioned)
+}
+
 func (p *Config) CookieDomain(ctx context.Context) string {
 	return p.GetProvider(ctx).String(ViperKeyCookieDomain)
 }
@@ -1309,10 +2384,136 @@ func (p *Config) SessionWhoAmICaching(ctx context.Context) bool {
 	return p.GetProvider(ctx).Bool(ViperKeySessionWhoAmICaching)
 }
 
+// SessionWhoAmICacheEnabled returns true if whoami looks sessions up in the session cache before the database.
+func (p *Config) SessionWhoAmICacheEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySessionWhoAmICacheEnabled)
+}
+
+// SessionWhoAmICacheTTL returns how long sessions are cached for whoami.
+func (p *Config) SessionWhoAmICacheTTL(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySessionWhoAmICacheTTL, 10*time.Second)
+}
+
+// SessionWhoAmICacheMaxEntries returns how many sessions each Kratos instance keeps in memory.
+func (p *Config) SessionWhoAmICacheMaxEntries(ctx context.Context) int {
+	return p.GetProvider(ctx).IntF(ViperKeySessionWhoAmICacheMaxEntries, 10000)
+}
+
 func (p *Config) SessionRefreshMinTimeLeft(ctx context.Context) time.Duration {
 	return p.GetProvider(ctx).DurationF(ViperKeySessionRefreshMinTimeLeft, p.SessionLifespan(ctx))
 }
 
+// SessionSelfServiceExtendEnabled returns true if clients may extend their own session.
+func (p *Config) SessionSelfServiceExtendEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySessionSelfServiceExtendEnabled)
+}
+
+// SessionSelfServiceExtendMaxLifetime returns how long after being authenticated a session may be extended to at most.
+func (p *Config) SessionSelfServiceExtendMaxLifetime(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySessionSelfServiceExtendMaxLifetime, 30*24*time.Hour)
+}
+
+// SessionSelfServiceExtendRequiredAAL returns the AAL a session needs to be extended by its client.
+func (p *Config) SessionSelfServiceExtendRequiredAAL(ctx context.Context) string {
+	return p.GetProvider(ctx).StringF(ViperKeySessionSelfServiceExtendRequiredAAL, "highest_available")
+}
+
+// SessionTokenBindingEnabled returns true if session tokens of API flows are bound to the key of a DPoP proof.
+func (p *Config) SessionTokenBindingEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySessionTokenBindingEnabled)
+}
+
+// SessionTokenBindingProofMaxAge returns how long after being issued a DPoP proof is accepted.
+func (p *Config) SessionTokenBindingProofMaxAge(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySessionTokenBindingProofMaxAge, time.Minute)
+}
+
+// SessionIdleTimeout returns how long a session may be unused before it is rejected, or zero if
+// sessions only expire after their lifespan.
+func (p *Config) SessionIdleTimeout(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySessionIdleTimeout, 0)
+}
+
+// SessionIdleUpdateInterval returns how often the last activity of a session is written at most.
+func (p *Config) SessionIdleUpdateInterval(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySessionIdleUpdateInterval, time.Minute)
+}
+
+// SessionDevicesEnabled returns false if the devices of new sessions must not be recorded.
+func (p *Config) SessionDevicesEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).BoolF(ViperKeySessionDevicesEnabled, true)
+}
+
+// SessionDevicesLocationHeaders returns the request headers from which the location of a client is looked up.
+func (p *Config) SessionDevicesLocationHeaders(ctx context.Context) []string {
+	return p.GetProvider(ctx).StringsF(ViperKeySessionDevicesLocationHeaders, []string{"Cf-Ipcity", "Cf-Ipcountry"})
+}
+
+// SessionEventsWebhook returns the request configuration of the session
+// events webhook or nil if no webhook is configured.
+func (p *Config) SessionEventsWebhook(ctx context.Context) json.RawMessage {
+	if !p.GetProvider(ctx).Exists(ViperKeySessionEventsWebhook) {
+		return nil
+	}
+
+	config, err := json.Marshal(p.GetProvider(ctx).Get(ViperKeySessionEventsWebhook))
+	if err != nil {
+		p.l.WithError(err).Warn("Unable to marshal session events webhook configuration.")
+		return nil
+	}
+	return config
+}
+
+// SessionEventsTypes returns the session event types which are published. An empty list publishes all events.
+// Back-channel logout receives all events regardless.
+func (p *Config) SessionEventsTypes(ctx context.Context) []string {
+	return p.GetProvider(ctx).Strings(ViperKeySessionEventsTypes)
+}
+
+// SessionBackChannelLogoutEnabled returns true if logout tokens are sent to the registered logout callbacks.
+func (p *Config) SessionBackChannelLogoutEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySessionBackChannelLogoutEnabled)
+}
+
+// SessionBackChannelLogoutJWKSURL returns the URL of the JSON Web Key Set which logout tokens are signed with.
+func (p *Config) SessionBackChannelLogoutJWKSURL(ctx context.Context) string {
+	return p.GetProvider(ctx).String(ViperKeySessionBackChannelLogoutJWKSURL)
+}
+
+// SessionRefreshTokensEnabled returns true if API flows issue a rotating refresh token next to the session token.
+func (p *Config) SessionRefreshTokensEnabled(ctx context.Context) bool {
+	return p.GetProvider(ctx).Bool(ViperKeySessionRefreshTokensEnabled)
+}
+
+// SessionRefreshTokensSessionTokenLifespan returns how long a session token issued together with a refresh token is valid.
+func (p *Config) SessionRefreshTokensSessionTokenLifespan(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySessionRefreshTokensSessionTokenLifespan, 15*time.Minute)
+}
+
+// SessionPersistenceMode returns where sessions are stored.
+func (p *Config) SessionPersistenceMode(ctx context.Context) SessionPersistenceMode {
+	switch mode := SessionPersistenceMode(p.GetProvider(ctx).String(ViperKeySessionPersistenceMode)); mode {
+	case SessionPersistenceModeWriteThrough, SessionPersistenceModeRedis:
+		return mode
+	}
+	return SessionPersistenceModeSQL
+}
+
+// SessionPersistenceRedisDSN returns the URL of the Redis server which stores sessions.
+func (p *Config) SessionPersistenceRedisDSN(ctx context.Context) string {
+	return p.GetProvider(ctx).String(ViperKeySessionPersistenceRedisDSN)
+}
+
+// SessionPersistenceRedisKeyPrefix returns the prefix of all keys Ory Kratos writes to Redis.
+func (p *Config) SessionPersistenceRedisKeyPrefix(ctx context.Context) string {
+	return p.GetProvider(ctx).StringF(ViperKeySessionPersistenceRedisKeyPrefix, "kratos:")
+}
+
+// SessionPersistenceRedisTTL returns how long sessions are kept in Redis in write-through mode.
+func (p *Config) SessionPersistenceRedisTTL(ctx context.Context) time.Duration {
+	return p.GetProvider(ctx).DurationF(ViperKeySessionPersistenceRedisTTL, time.Hour)
+}
+
 func (p *Config) SelfServiceSettingsRequiredAAL(ctx context.Context) string {
 	return p.GetProvider(ctx).String(ViperKeySelfServiceSettingsRequiredAAL)
 }
@@ -1385,19 +2586,35 @@ func (p *Config) WebAuthnForPasswordless(ctx context.Context) bool {
 	return p.GetProvider(ctx).BoolF(ViperKeyWebAuthnPasswordless, false)
 }
 
+// WebAuthnForPasskeys returns true if discoverable WebAuthn credentials (passkeys) are used for passwordless flows.
+func (p *Config) WebAuthnForPasskeys(ctx context.Context) bool {
+	return p.WebAuthnForPasswordless(ctx) && p.GetProvider(ctx).BoolF(ViperKeyWebAuthnPasskeys, false)
+}
+
 func (p *Config) WebAuthnConfig(ctx context.Context) *webauthn.Config {
 	scheme := p.SelfPublicURL(ctx).Scheme
 	id := p.GetProvider(ctx).String(ViperKeyWebAuthnRPID)
 	origin := p.GetProvider(ctx).String(ViperKeyWebAuthnRPOrigin)
 	origins := p.GetProvider(ctx).StringsF(ViperKeyWebAuthnRPOrigins, []string{stringsx.Coalesce(origin, scheme+"://"+id)})
+
+	selection := protocol.AuthenticatorSelection{
+		UserVerification: protocol.VerificationDiscouraged,
+	}
+	if p.WebAuthnForPasskeys(ctx) {
+		// Passkeys must be stored on the authenticator so that they can be used without an identifier.
+		selection = protocol.AuthenticatorSelection{
+			RequireResidentKey: protocol.ResidentKeyRequired(),
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationPreferred,
+		}
+	}
+
 	return &webauthn.Config{
-		RPDisplayName: p.GetProvider(ctx).String(ViperKeyWebAuthnRPDisplayName),
-		RPID:          id,
-		RPOrigins:     origins,
-		AuthenticatorSelection: protocol.AuthenticatorSelection{
-			UserVerification: protocol.VerificationDiscouraged,
-		},
-		EncodeUserIDAsString: false,
+		RPDisplayName:          p.GetProvider(ctx).String(ViperKeyWebAuthnRPDisplayName),
+		RPID:                   id,
+		RPOrigins:              origins,
+		AuthenticatorSelection: selection,
+		EncodeUserIDAsString:   false,
 	}
 }
 
@@ -1484,10 +2701,31 @@ func (p *Config) GetProvider(ctx context.Context) *configx.Provider {
 	return p.c.Config(ctx, p.p)
 }
 
+// WithContextualizer replaces the contextualizer which resolves the configuration of a request context, for
+// example the configuration of the tenant the request belongs to.
+func (p *Config) WithContextualizer(ctxer contextx.Contextualizer) {
+	p.c = ctxer
+}
+
 type SessionTokenizeFormat struct {
 	TTL             time.Duration `koanf:"ttl" json:"ttl"`
 	ClaimsMapperURL string        `koanf:"claims_mapper_url" json:"claims_mapper_url"`
 	JWKSURL         string        `koanf:"jwks_url" json:"jwks_url"`
+	Audience        []string      `koanf:"audience" json:"audience,omitempty"`
+}
+
+// TokenizeTemplates returns all tokenizer templates by their name.
+func (p *Config) TokenizeTemplates(ctx context.Context) (map[string]SessionTokenizeFormat, error) {
+	result := map[string]SessionTokenizeFormat{}
+	if !p.GetProvider(ctx).Exists(ViperKeySessionTokenizerTemplates) {
+		return result, nil
+	}
+
+	if err := p.GetProvider(ctx).Unmarshal(ViperKeySessionTokenizerTemplates, &result); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode tokenizer templates: %s", err))
+	}
+
+	return result, nil
 }
 
 func (p *Config) TokenizeTemplate(ctx context.Context, key string) (_ *SessionTokenizeFormat, err error) {
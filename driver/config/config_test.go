@@ -25,11 +25,13 @@ import (
 	"github.com/ory/x/snapshotx"
 
 	"github.com/ghodss/yaml"
+	"github.com/inhies/go-bytesize"
 	"github.com/spf13/cobra"
 
 	"github.com/ory/kratos/internal/testhelpers"
 
 	"github.com/ory/x/configx"
+	"github.com/ory/x/contextx"
 
 	"github.com/sirupsen/logrus/hooks/test"
 
@@ -398,6 +400,50 @@ func TestViperProvider(t *testing.T) {
 	})
 }
 
+func TestSetHasherArgon2(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("case=survives reloads", func(t *testing.T) {
+		t.Parallel()
+		p := config.MustNew(t, logrusx.New("", ""), os.Stderr, configx.SkipValidation())
+
+		p.SetHasherArgon2(ctx, &config.Argon2{Memory: 64 * bytesize.MB, Iterations: 5})
+		// Changing unrelated keys reloads the provider which must not drop the calibrated values.
+		require.NoError(t, p.Set(ctx, config.ViperKeyHasherArgon2ConfigParallelism, 2))
+
+		c := p.HasherArgon2(ctx)
+		assert.Equal(t, 64*bytesize.MB, c.Memory)
+		assert.EqualValues(t, 5, c.Iterations)
+		assert.EqualValues(t, 2, c.Parallelism)
+	})
+
+	t.Run("case=is dropped if the configured values change", func(t *testing.T) {
+		t.Parallel()
+		p := config.MustNew(t, logrusx.New("", ""), os.Stderr, configx.SkipValidation())
+
+		p.SetHasherArgon2(ctx, &config.Argon2{Memory: 64 * bytesize.MB, Iterations: 5})
+		require.NoError(t, p.Set(ctx, config.ViperKeyHasherArgon2ConfigIterations, 3))
+
+		c := p.HasherArgon2(ctx)
+		assert.Equal(t, config.Argon2DefaultMemory, c.Memory)
+		assert.EqualValues(t, 3, c.Iterations)
+	})
+
+	t.Run("case=only applies to the calibrated provider", func(t *testing.T) {
+		t.Parallel()
+		p := config.MustNew(t, logrusx.New("", ""), os.Stderr, configx.SkipValidation())
+		other := config.MustNew(t, logrusx.New("", ""), os.Stderr, configx.SkipValidation())
+
+		p.SetHasherArgon2(ctx, &config.Argon2{Memory: 64 * bytesize.MB, Iterations: 5})
+		p.WithContextualizer(&contextx.Static{C: other.GetProvider(ctx)})
+
+		c := p.HasherArgon2(ctx)
+		assert.Equal(t, config.Argon2DefaultMemory, c.Memory)
+		assert.Equal(t, config.Argon2DefaultIterations, c.Iterations)
+	})
+}
+
 func TestBcrypt(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -1351,4 +1397,138 @@ func TestCleanup(t *testing.T) {
 		p.MustSet(ctx, config.ViperKeyDatabaseCleanupBatchSize, "1")
 		assert.Equal(t, p.DatabaseCleanupBatchSize(ctx), 1)
 	})
+
+	t.Run("group=table metrics config", func(t *testing.T) {
+		assert.False(t, p.DatabaseTableMetricsEnabled(ctx))
+		assert.Equal(t, 5*time.Minute, p.DatabaseTableMetricsInterval(ctx))
+		p.MustSet(ctx, config.ViperKeyDatabaseTableMetricsEnabled, true)
+		p.MustSet(ctx, config.ViperKeyDatabaseTableMetricsInterval, "30s")
+		assert.True(t, p.DatabaseTableMetricsEnabled(ctx))
+		assert.Equal(t, 30*time.Second, p.DatabaseTableMetricsInterval(ctx))
+	})
+}
+
+func TestVerificationReminders(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("case=disabled by default", func(t *testing.T) {
+		conf, err := config.New(ctx, logrusx.New("", ""), os.Stderr, configx.SkipValidation())
+		require.NoError(t, err)
+
+		r, err := conf.SelfServiceFlowVerificationReminders(ctx)
+		require.NoError(t, err)
+		assert.False(t, r.Default.Enabled)
+		_, ok := r.MinAge()
+		assert.False(t, ok)
+	})
+
+	t.Run("case=schema policies override the default", func(t *testing.T) {
+		conf, err := config.New(ctx, logrusx.New("", ""), os.Stderr,
+			configx.SkipValidation(),
+			configx.WithValue(config.ViperKeySelfServiceVerificationReminders, map[string]interface{}{
+				"enabled":   true,
+				"intervals": []string{"72h", "24h"},
+				"unverified_accounts": map[string]interface{}{
+					"action": "deactivate",
+					"after":  "720h",
+				},
+				"schemas": []map[string]interface{}{
+					{"id": "employee", "enabled": false},
+					{"id": "customer", "unverified_accounts": map[string]interface{}{"action": "delete"}},
+				},
+			}))
+		require.NoError(t, err)
+
+		r, err := conf.SelfServiceFlowVerificationReminders(ctx)
+		require.NoError(t, err)
+
+		def := r.ForSchema("default")
+		assert.True(t, def.Enabled)
+		assert.Equal(t, []time.Duration{24 * time.Hour, 72 * time.Hour}, def.Intervals)
+		after, expires := def.ExpiresAfter()
+		assert.True(t, expires)
+		assert.Equal(t, 720*time.Hour, after)
+		assert.Equal(t, config.UnverifiedAccountActionDeactivate, def.UnverifiedAccountAction)
+
+		assert.False(t, r.ForSchema("employee").Enabled)
+
+		customer := r.ForSchema("customer")
+		assert.True(t, customer.Enabled)
+		assert.Equal(t, config.UnverifiedAccountActionDelete, customer.UnverifiedAccountAction)
+		assert.Equal(t, 720*time.Hour, customer.UnverifiedAccountAfter)
+
+		assert.Equal(t, 0, def.DueReminders(time.Hour))
+		assert.Equal(t, 1, def.DueReminders(25*time.Hour))
+		assert.Equal(t, 2, def.DueReminders(100*time.Hour))
+
+		minAge, ok := r.MinAge()
+		assert.True(t, ok)
+		assert.Equal(t, 24*time.Hour, minAge)
+	})
+
+	t.Run("case=fails on invalid durations", func(t *testing.T) {
+		conf, err := config.New(ctx, logrusx.New("", ""), os.Stderr,
+			configx.SkipValidation(),
+			configx.WithValue(config.ViperKeySelfServiceVerificationReminders+".intervals", []string{"not-a-duration"}))
+		require.NoError(t, err)
+
+		_, err = conf.SelfServiceFlowVerificationReminders(ctx)
+		require.Error(t, err)
+	})
+}
+
+func TestSelfServiceThemeVariables(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("case=empty by default", func(t *testing.T) {
+		conf, err := config.New(ctx, logrusx.New("", ""), os.Stderr, configx.SkipValidation())
+		require.NoError(t, err)
+		assert.Empty(t, conf.SelfServiceThemeVariables(ctx))
+	})
+
+	t.Run("case=returns configured variables", func(t *testing.T) {
+		conf, err := config.New(ctx, logrusx.New("", ""), os.Stderr,
+			configx.SkipValidation(),
+			configx.WithValue(config.ViperKeySelfServiceThemeVariables, map[string]interface{}{
+				"logo_url":     "https://www.ory.sh/logo.svg",
+				"product_name": "Ory",
+			}))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"logo_url":     "https://www.ory.sh/logo.svg",
+			"product_name": "Ory",
+		}, conf.SelfServiceThemeVariables(ctx))
+	})
+}
+
+func TestSelfServiceConsentDocuments(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	documents := []map[string]interface{}{
+		{"id": "tos", "version": "2023-10", "url": "https://www.ory.sh/tos", "label": "I accept the terms of service"},
+		{"id": "newsletter", "version": "1", "optional": true},
+	}
+
+	t.Run("case=empty if the method is disabled", func(t *testing.T) {
+		conf, err := config.New(ctx, logrusx.New("", ""), os.Stderr,
+			configx.SkipValidation(),
+			configx.WithValue(config.ViperKeySelfServiceConsentDocuments, documents))
+		require.NoError(t, err)
+		assert.Empty(t, conf.SelfServiceConsentDocuments(ctx))
+	})
+
+	t.Run("case=returns configured documents", func(t *testing.T) {
+		conf, err := config.New(ctx, logrusx.New("", ""), os.Stderr,
+			configx.SkipValidation(),
+			configx.WithValue(config.ViperKeySelfServiceStrategyConfig+".consent.enabled", true),
+			configx.WithValue(config.ViperKeySelfServiceConsentDocuments, documents))
+		require.NoError(t, err)
+		assert.Equal(t, []config.ConsentDocument{
+			{ID: "tos", Version: "2023-10", URL: "https://www.ory.sh/tos", Label: "I accept the terms of service"},
+			{ID: "newsletter", Version: "1", Optional: true},
+		}, conf.SelfServiceConsentDocuments(ctx))
+	})
 }
@@ -29,13 +29,22 @@ import (
 	"github.com/ory/kratos/selfservice/flow/verification"
 	"github.com/ory/kratos/selfservice/strategy/code"
 	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
 
 	"github.com/ory/x/healthx"
 
+	"github.com/ory/kratos/organization"
 	"github.com/ory/kratos/persistence"
+	"github.com/ory/kratos/selfservice/approval"
+	"github.com/ory/kratos/selfservice/consent"
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/flow/logout"
 	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/invitation"
+	"github.com/ory/kratos/selfservice/lockout"
+	"github.com/ory/kratos/selfservice/ratelimit"
+	"github.com/ory/kratos/selfservice/signupcode"
 
 	"github.com/ory/kratos/x"
 
@@ -46,6 +55,7 @@ import (
 	"github.com/ory/kratos/selfservice/errorx"
 	password2 "github.com/ory/kratos/selfservice/strategy/password"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/spec"
 )
 
 type Registry interface {
@@ -68,6 +78,11 @@ type Registry interface {
 	RegisterPublicRoutes(ctx context.Context, public *x.RouterPublic)
 	RegisterAdminRoutes(ctx context.Context, admin *x.RouterAdmin)
 	PrometheusManager() *prometheus.MetricsManager
+	TableMetricsCollector() *persistence.TableMetricsCollector
+	Janitor() *persistence.Janitor
+	PhasedMigrationRunner() *persistence.PhasedMigrationRunner
+	PhasedMigrationHandler() *persistence.PhasedMigrationHandler
+	TestClockHandler() *x.TestClockHandler
 	Tracer(context.Context) *otelx.Tracer
 	SetTracer(*otelx.Tracer)
 
@@ -100,17 +115,23 @@ type Registry interface {
 	identity.PoolProvider
 	identity.PrivilegedPoolProvider
 	identity.ManagementProvider
+	identity.MigratorProvider
+	identity.EventRelayProvider
 	identity.ActiveCredentialsCounterStrategyProvider
 
 	courier.HandlerProvider
 	courier.PersistenceProvider
 
 	schema.HandlerProvider
+	spec.HandlerProvider
 	schema.IdentityTraitsProvider
 
 	password2.ValidationProvider
 
 	session.HandlerProvider
+	session.ExpiredNotifierProvider
+	session.EventEmitterProvider
+	session.CacheProvider
 	session.ManagementProvider
 	session.PersistenceProvider
 	session.TokenizerProvider
@@ -118,6 +139,7 @@ type Registry interface {
 	settings.HandlerProvider
 	settings.ErrorHandlerProvider
 	settings.FlowPersistenceProvider
+	settings.EmailChangePersistenceProvider
 	settings.StrategyProvider
 
 	login.FlowPersistenceProvider
@@ -125,20 +147,55 @@ type Registry interface {
 	login.HooksProvider
 	login.HookExecutorProvider
 	login.HandlerProvider
+	login.NewDeviceNotifierProvider
+	login.RiskAssessorProvider
 	login.StrategyProvider
 
+	flow.BeforeRenderHooksProvider
+
 	logout.HandlerProvider
 
+	consent.HandlerProvider
+	consent.ManagementProvider
+	consent.PersistenceProvider
+
+	invitation.HandlerProvider
+	invitation.ManagementProvider
+	invitation.PersistenceProvider
+
+	signupcode.HandlerProvider
+	signupcode.ManagementProvider
+	signupcode.PersistenceProvider
+
+	oidc.HandlerProvider
+	oidc.ProviderPersistenceProvider
+	oidc.ProviderCacheProvider
+
+	organization.HandlerProvider
+	organization.PersistenceProvider
+
+	approval.HandlerProvider
+	approval.ManagementProvider
+
+	lockout.HandlerProvider
+	lockout.ManagementProvider
+	lockout.PersistenceProvider
+
+	ratelimit.ManagementProvider
+
 	registration.FlowPersistenceProvider
 	registration.ErrorHandlerProvider
 	registration.HooksProvider
 	registration.HookExecutorProvider
 	registration.HandlerProvider
+	registration.PendingRegistrationVerifierProvider
+	registration.DuplicateRegistrationResponderProvider
 	registration.StrategyProvider
 
 	verification.FlowPersistenceProvider
 	verification.ErrorHandlerProvider
 	verification.HandlerProvider
+	verification.ReminderProvider
 	verification.StrategyProvider
 
 	sessiontokenexchange.PersistenceProvider
@@ -187,6 +244,8 @@ type options struct {
 	extraMigrations         []fs.FS
 	replacementStrategies   []NewStrategy
 	extraHooks              map[string]func(config.SelfServiceHook) any
+	sessionEventSinks       []session.EventSink
+	sessionCacheStores      []session.CacheStore
 	disableMigrationLogging bool
 }
 
@@ -225,6 +284,22 @@ func WithExtraHooks(hooks map[string]func(config.SelfServiceHook) any) RegistryO
 	}
 }
 
+// WithSessionEventSinks publishes session lifecycle events to the sinks, for
+// example to a message bus, in addition to the configured webhook.
+func WithSessionEventSinks(sinks ...session.EventSink) RegistryOption {
+	return func(o *options) {
+		o.sessionEventSinks = append(o.sessionEventSinks, sinks...)
+	}
+}
+
+// WithSessionCacheStores adds stores, for example Redis, to the session cache which whoami uses. They
+// are asked after the in-memory store of each instance.
+func WithSessionCacheStores(stores ...session.CacheStore) RegistryOption {
+	return func(o *options) {
+		o.sessionCacheStores = append(o.sessionCacheStores, stores...)
+	}
+}
+
 func Inspect(f func(reg Registry) error) RegistryOption {
 	return func(o *options) {
 		o.inspect = f
@@ -39,6 +39,12 @@ import (
 
 	"github.com/ory/kratos/selfservice/strategy/totp"
 
+	"github.com/ory/kratos/selfservice/strategy/externalmfa"
+	"github.com/ory/kratos/selfservice/strategy/idfirst"
+	"github.com/ory/kratos/selfservice/strategy/push"
+	"github.com/ory/kratos/selfservice/strategy/sessions"
+	"github.com/ory/kratos/selfservice/strategy/trusteddevice"
+
 	"github.com/luna-duclos/instrumentedsql"
 
 	prometheus "github.com/ory/x/prometheusx"
@@ -54,8 +60,10 @@ import (
 	"github.com/ory/kratos/selfservice/strategy/link"
 	"github.com/ory/kratos/selfservice/strategy/profile"
 	"github.com/ory/kratos/x"
+	"github.com/ory/kratos/x/redisx"
 
 	"github.com/cenkalti/backoff"
+	"github.com/gofrs/uuid"
 	"github.com/gorilla/sessions"
 	"github.com/pkg/errors"
 
@@ -66,11 +74,19 @@ import (
 	"github.com/ory/x/logrusx"
 
 	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/organization"
 	"github.com/ory/kratos/persistence"
+	"github.com/ory/kratos/persistence/redis"
 	"github.com/ory/kratos/persistence/sql"
+	"github.com/ory/kratos/selfservice/approval"
+	"github.com/ory/kratos/selfservice/consent"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/flow/logout"
 	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/invitation"
+	"github.com/ory/kratos/selfservice/lockout"
+	"github.com/ory/kratos/selfservice/ratelimit"
+	"github.com/ory/kratos/selfservice/signupcode"
 	"github.com/ory/kratos/selfservice/strategy/oidc"
 
 	"github.com/ory/herodot"
@@ -78,8 +94,10 @@ import (
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/strategy"
 	"github.com/ory/kratos/selfservice/strategy/password"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/spec"
 )
 
 type RegistryDefault struct {
@@ -94,6 +112,11 @@ type RegistryDefault struct {
 	nosurf         nosurf.Handler
 	trc            *otelx.Tracer
 	pmm            *prometheus.MetricsManager
+	tableMetrics   *persistence.TableMetricsCollector
+	janitor        *persistence.Janitor
+	phasedRunner   *persistence.PhasedMigrationRunner
+	phasedHandler  *persistence.PhasedMigrationHandler
+	clockHandler   *x.TestClockHandler
 	writer         herodot.Writer
 	healthxHandler *healthx.Handler
 	metricsHandler *prometheus.Handler
@@ -105,27 +128,59 @@ type RegistryDefault struct {
 	hookSessionIssuer       *hook.SessionIssuer
 	hookSessionDestroyer    *hook.SessionDestroyer
 	hookAddressVerifier     *hook.AddressVerifier
+	hookVerifiedAddress     *hook.VerifiedAddressEnforcer
 	hookShowVerificationUI  *hook.ShowVerificationUIHook
 	hookCodeAddressVerifier *hook.CodeAddressVerifier
+	hookVerifyBeforeCreate  *hook.VerifyBeforeCreation
 
 	identityHandler   *identity.Handler
 	identityValidator *identity.Validator
 	identityManager   *identity.Manager
+	identityMigrator  *identity.Migrator
+	identityRelay     *identity.EventRelay
 
 	courierHandler *courier.Handler
 
 	continuityManager continuity.Manager
 
 	schemaHandler *schema.Handler
+	specHandler   *spec.Handler
+
+	strategiesHealthHandler   *strategy.HealthHandler
+	strategyMisconfigurations sync.Map
 
 	sessionHandler   *session.Handler
 	sessionManager   session.Manager
+	sessionNotifier  *session.ExpiredNotifier
+	sessionEvents    *session.EventEmitter
+	sessionCache     *session.Cache
 	sessionTokenizer *session.Tokenizer
 
-	passwordHasher    hash.Hasher
+	lockoutManager *lockout.Manager
+	lockoutHandler *lockout.Handler
+
+	loginRateLimiter *ratelimit.Limiter
+
+	consentManager *consent.Manager
+	consentHandler *consent.Handler
+
+	invitationManager   *invitation.Manager
+	invitationHandler   *invitation.Handler
+	signupCodeManager   *signupcode.Manager
+	signupCodeHandler   *signupcode.Handler
+	oidcProviderHandler *oidc.Handler
+	oidcProviderCache   *oidc.ProviderCache
+	organizationHandler *organization.Handler
+
+	registrationApprovalManager *approval.Manager
+	registrationApprovalHandler *approval.Handler
+
+	// passwordHashers and crypters are keyed by algorithm, as the algorithm
+	// is resolved from the (tenant's) request context.
+	passwordHashers   map[string]hash.Hasher
 	passwordValidator password.Validator
 
-	crypter cipher.Cipher
+	crypters map[string]cipher.Cipher
 
 	errorHandler *errorx.Handler
 	errorManager *errorx.Manager
@@ -138,6 +193,7 @@ type RegistryDefault struct {
 	selfserviceLoginExecutor            *login.HookExecutor
 	selfserviceLoginHandler             *login.Handler
 	selfserviceLoginRequestErrorHandler *login.ErrorHandler
+	selfserviceLoginNewDeviceNotifier   *login.NewDeviceNotifier
 
 	selfserviceSettingsHandler      *settings.Handler
 	selfserviceSettingsErrorHandler *settings.ErrorHandler
@@ -147,6 +203,7 @@ type RegistryDefault struct {
 	selfserviceVerifyManager        *identity.Manager
 	selfserviceVerifyHandler        *verification.Handler
 	selfserviceVerificationExecutor *verification.HookExecutor
+	selfserviceVerifyReminder       *verification.Reminder
 
 	selfserviceLinkSender *link.Sender
 	selfserviceCodeSender *code.Sender
@@ -154,11 +211,15 @@ type RegistryDefault struct {
 	selfserviceRecoveryErrorHandler *recovery.ErrorHandler
 	selfserviceRecoveryHandler      *recovery.Handler
 	selfserviceRecoveryExecutor     *recovery.HookExecutor
+	selfserviceRecoveryNotifier     *recovery.Notifier
 
 	selfserviceLogoutHandler *logout.Handler
 
 	selfserviceStrategies            []any
 	replacementSelfserviceStrategies []NewStrategy
+	sessionEventSinks                []session.EventSink
+	sessionCacheStores               []session.CacheStore
+	sessionRedis                     *redis.Store
 
 	hydra hydra.Hydra
 
@@ -196,6 +257,7 @@ func (m *RegistryDefault) RegisterPublicRoutes(ctx context.Context, router *x.Ro
 	m.SessionHandler().RegisterPublicRoutes(router)
 	m.SelfServiceErrorHandler().RegisterPublicRoutes(router)
 	m.SchemaHandler().RegisterPublicRoutes(router)
+	m.ConsentHandler().RegisterPublicRoutes(router)
 
 	m.AllRecoveryStrategies().RegisterPublicRoutes(router)
 	m.RecoveryHandler().RegisterPublicRoutes(router)
@@ -211,6 +273,8 @@ func (m *RegistryDefault) RegisterAdminRoutes(ctx context.Context, router *x.Rou
 	m.LoginHandler().RegisterAdminRoutes(router)
 	m.LogoutHandler().RegisterAdminRoutes(router)
 	m.SchemaHandler().RegisterAdminRoutes(router)
+	m.SpecHandler().RegisterAdminRoutes(router)
+	m.StrategiesHealthHandler().RegisterAdminRoutes(router)
 	m.SettingsHandler().RegisterAdminRoutes(router)
 	m.IdentityHandler().RegisterAdminRoutes(router)
 	m.CourierHandler().RegisterAdminRoutes(router)
@@ -219,6 +283,15 @@ func (m *RegistryDefault) RegisterAdminRoutes(ctx context.Context, router *x.Rou
 	m.RecoveryHandler().RegisterAdminRoutes(router)
 	m.AllRecoveryStrategies().RegisterAdminRoutes(router)
 	m.SessionHandler().RegisterAdminRoutes(router)
+	m.LockoutHandler().RegisterAdminRoutes(router)
+	m.InvitationHandler().RegisterAdminRoutes(router)
+	m.SignupCodeHandler().RegisterAdminRoutes(router)
+	m.OIDCProviderHandler().RegisterAdminRoutes(router)
+	m.OrganizationHandler().RegisterAdminRoutes(router)
+	m.RegistrationApprovalHandler().RegisterAdminRoutes(router)
+	m.ConsentHandler().RegisterAdminRoutes(router)
+	m.PhasedMigrationHandler().RegisterAdminRoutes(router)
+	m.TestClockHandler().RegisterAdminRoutes(router)
 
 	m.VerificationHandler().RegisterAdminRoutes(router)
 	m.AllVerificationStrategies().RegisterAdminRoutes(router)
@@ -336,6 +409,12 @@ func (m *RegistryDefault) selfServiceStrategies() []any {
 				totp.NewStrategy(m),
 				webauthn.NewStrategy(m),
 				lookup.NewStrategy(m),
+				push.NewStrategy(m),
+				externalmfa.NewStrategy(m),
+				trusteddevice.NewStrategy(m),
+				consent.NewStrategy(m),
+				sessions.NewStrategy(m),
+				idfirst.NewStrategy(m),
 			}
 		}
 	}
@@ -370,7 +449,7 @@ nextStrategy:
 					continue nextStrategy
 				}
 			}
-			if m.strategyRegistrationEnabled(ctx, s.ID().String()) {
+			if m.strategyRegistrationEnabled(ctx, s.ID().String()) && m.strategyConfigured(ctx, s.ID().String(), s) {
 				registrationStrategies = append(registrationStrategies, s)
 			}
 		}
@@ -398,7 +477,7 @@ nextStrategy:
 					continue nextStrategy
 				}
 			}
-			if m.strategyLoginEnabled(ctx, s.ID().String()) {
+			if m.strategyLoginEnabled(ctx, s.ID().String()) && m.strategyConfigured(ctx, s.ID().String(), s) {
 				loginStrategies = append(loginStrategies, s)
 			}
 		}
@@ -469,6 +548,10 @@ func (m *RegistryDefault) IdentityHandler() *identity.Handler {
 	return m.identityHandler
 }
 
+func (m *RegistryDefault) IdentitySessionSummarizer() identity.SessionSummarizer {
+	return m.SessionPersister()
+}
+
 func (m *RegistryDefault) CourierHandler() *courier.Handler {
 	if m.courierHandler == nil {
 		m.courierHandler = courier.NewHandler(m)
@@ -483,6 +566,13 @@ func (m *RegistryDefault) SchemaHandler() *schema.Handler {
 	return m.schemaHandler
 }
 
+func (m *RegistryDefault) SpecHandler() *spec.Handler {
+	if m.specHandler == nil {
+		m.specHandler = spec.NewHandler(m)
+	}
+	return m.specHandler
+}
+
 func (m *RegistryDefault) SessionHandler() *session.Handler {
 	if m.sessionHandler == nil {
 		m.sessionHandler = session.NewHandler(m)
@@ -491,29 +581,65 @@ func (m *RegistryDefault) SessionHandler() *session.Handler {
 }
 
 func (m *RegistryDefault) Cipher(ctx context.Context) cipher.Cipher {
-	if m.crypter == nil {
-		switch m.c.CipherAlgorithm(ctx) {
-		case "xchacha20-poly1305":
-			m.crypter = cipher.NewCryptChaCha20(m)
-		case "aes":
-			m.crypter = cipher.NewCryptAES(m)
-		default:
-			m.crypter = cipher.NewNoop(m)
-			m.l.Logger.Warning("No encryption configuration found. Default algorithm (noop) will be use that mean sensitive data will be recorded in plaintext")
-		}
+	algorithm := m.c.CipherAlgorithm(ctx)
+
+	m.rwl.RLock()
+	c, ok := m.crypters[algorithm]
+	m.rwl.RUnlock()
+	if ok {
+		return c
+	}
+
+	m.rwl.Lock()
+	defer m.rwl.Unlock()
+	if c, ok := m.crypters[algorithm]; ok {
+		return c
+	}
+
+	switch algorithm {
+	case "xchacha20-poly1305":
+		c = cipher.NewCryptChaCha20(m)
+	case "aes":
+		c = cipher.NewCryptAES(m)
+	default:
+		c = cipher.NewNoop(m)
+		m.l.Logger.Warning("No encryption configuration found. Default algorithm (noop) will be use that mean sensitive data will be recorded in plaintext")
+	}
+
+	if m.crypters == nil {
+		m.crypters = make(map[string]cipher.Cipher)
 	}
-	return m.crypter
+	m.crypters[algorithm] = c
+	return c
 }
 
 func (m *RegistryDefault) Hasher(ctx context.Context) hash.Hasher {
-	if m.passwordHasher == nil {
-		if m.c.HasherPasswordHashingAlgorithm(ctx) == "bcrypt" {
-			m.passwordHasher = hash.NewHasherBcrypt(m)
-		} else {
-			m.passwordHasher = hash.NewHasherArgon2(m)
-		}
+	algorithm := m.c.HasherPasswordHashingAlgorithm(ctx)
+
+	m.rwl.RLock()
+	h, ok := m.passwordHashers[algorithm]
+	m.rwl.RUnlock()
+	if ok {
+		return h
+	}
+
+	m.rwl.Lock()
+	defer m.rwl.Unlock()
+	if h, ok := m.passwordHashers[algorithm]; ok {
+		return h
+	}
+
+	if algorithm == "bcrypt" {
+		h = hash.NewHasherBcrypt(m)
+	} else {
+		h = hash.NewHasherArgon2(m)
 	}
-	return m.passwordHasher
+
+	if m.passwordHashers == nil {
+		m.passwordHashers = make(map[string]hash.Hasher)
+	}
+	m.passwordHashers[algorithm] = h
+	return h
 }
 
 func (m *RegistryDefault) PasswordValidator() password.Validator {
@@ -592,6 +718,29 @@ func (m *RegistryDefault) SessionManager() session.Manager {
 	return m.sessionManager
 }
 
+func (m *RegistryDefault) SessionExpiredNotifier() *session.ExpiredNotifier {
+	if m.sessionNotifier == nil {
+		m.sessionNotifier = session.NewExpiredNotifier(m)
+	}
+	return m.sessionNotifier
+}
+
+func (m *RegistryDefault) SessionEventEmitter() *session.EventEmitter {
+	if m.sessionEvents == nil {
+		m.sessionEvents = session.NewEventEmitter(m, append([]session.EventSink{session.NewBackChannelLogout(m)}, m.sessionEventSinks...)...)
+	}
+	return m.sessionEvents
+}
+
+func (m *RegistryDefault) SessionCache() *session.Cache {
+	m.rwl.Lock()
+	defer m.rwl.Unlock()
+	if m.sessionCache == nil {
+		m.sessionCache = session.NewCache(m, m.sessionCacheStores...)
+	}
+	return m.sessionCache
+}
+
 func (m *RegistryDefault) Hydra() hydra.Hydra {
 	if m.hydra == nil {
 		m.hydra = hydra.NewDefaultHydra(m)
@@ -651,6 +800,26 @@ func (m *RegistryDefault) Init(ctx context.Context, ctxer contextx.Contextualize
 		m.WithHooks(o.extraHooks)
 	}
 
+	if o.sessionEventSinks != nil {
+		m.sessionEventSinks = o.sessionEventSinks
+	}
+
+	if o.sessionCacheStores != nil {
+		m.sessionCacheStores = o.sessionCacheStores
+	}
+
+	if m.Config().SessionPersistenceMode(ctx) != config.SessionPersistenceModeSQL {
+		// The Redis URL can not be hot-reloaded either.
+		c, err := redisx.NewClient(m.Config().SessionPersistenceRedisDSN(ctx))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		m.sessionRedis = redis.NewStore(m, c, func(ctx context.Context) uuid.UUID {
+			return m.Persister().NetworkID(ctx)
+		})
+		m.sessionCacheStores = append(m.sessionCacheStores, m.sessionRedis.Invalidator())
+	}
+
 	bc := backoff.NewExponentialBackOff()
 	bc.MaxElapsedTime = time.Minute * 5
 	bc.Reset()
@@ -718,6 +887,9 @@ func (m *RegistryDefault) Init(ctx context.Context, ctxer contextx.Contextualize
 		return err
 	}
 
+	// Report misconfigured strategies at startup instead of on the first request.
+	_ = m.StrategyMisconfigurations(ctx)
+
 	if o.inspect != nil {
 		if err := o.inspect(m); err != nil {
 			return errors.WithStack(err)
@@ -769,11 +941,18 @@ func (m *RegistryDefault) SettingsFlowPersister() settings.FlowPersister {
 	return m.persister
 }
 
+func (m *RegistryDefault) EmailChangePersister() settings.EmailChangePersister {
+	return m.persister
+}
+
 func (m *RegistryDefault) SelfServiceErrorPersister() errorx.Persister {
 	return m.persister
 }
 
 func (m *RegistryDefault) SessionPersister() session.Persister {
+	if m.sessionRedis != nil {
+		return redis.NewSessionPersister(m, m.persister, m.sessionRedis)
+	}
 	return m.persister
 }
 
@@ -831,6 +1010,20 @@ func (m *RegistryDefault) IdentityManager() *identity.Manager {
 	return m.identityManager
 }
 
+func (m *RegistryDefault) IdentityEventRelay() *identity.EventRelay {
+	if m.identityRelay == nil {
+		m.identityRelay = identity.NewEventRelay(m)
+	}
+	return m.identityRelay
+}
+
+func (m *RegistryDefault) IdentityMigrator() *identity.Migrator {
+	if m.identityMigrator == nil {
+		m.identityMigrator = identity.NewMigrator(m)
+	}
+	return m.identityMigrator
+}
+
 func (m *RegistryDefault) PrometheusManager() *prometheus.MetricsManager {
 	m.rwl.Lock()
 	defer m.rwl.Unlock()
@@ -840,6 +1033,51 @@ func (m *RegistryDefault) PrometheusManager() *prometheus.MetricsManager {
 	return m.pmm
 }
 
+func (m *RegistryDefault) TableMetricsCollector() *persistence.TableMetricsCollector {
+	m.rwl.Lock()
+	defer m.rwl.Unlock()
+	if m.tableMetrics == nil {
+		m.tableMetrics = persistence.NewTableMetricsCollector(m)
+	}
+	return m.tableMetrics
+}
+
+func (m *RegistryDefault) Janitor() *persistence.Janitor {
+	m.rwl.Lock()
+	defer m.rwl.Unlock()
+	if m.janitor == nil {
+		m.janitor = persistence.NewJanitor(m)
+	}
+	return m.janitor
+}
+
+func (m *RegistryDefault) PhasedMigrationRunner() *persistence.PhasedMigrationRunner {
+	m.rwl.Lock()
+	defer m.rwl.Unlock()
+	if m.phasedRunner == nil {
+		m.phasedRunner = persistence.NewPhasedMigrationRunner(m)
+	}
+	return m.phasedRunner
+}
+
+func (m *RegistryDefault) PhasedMigrationHandler() *persistence.PhasedMigrationHandler {
+	m.rwl.Lock()
+	defer m.rwl.Unlock()
+	if m.phasedHandler == nil {
+		m.phasedHandler = persistence.NewPhasedMigrationHandler(m)
+	}
+	return m.phasedHandler
+}
+
+func (m *RegistryDefault) TestClockHandler() *x.TestClockHandler {
+	m.rwl.Lock()
+	defer m.rwl.Unlock()
+	if m.clockHandler == nil {
+		m.clockHandler = x.NewTestClockHandler(m)
+	}
+	return m.clockHandler
+}
+
 func (m *RegistryDefault) HTTPClient(ctx context.Context, opts ...httpx.ResilientOptions) *retryablehttp.Client {
 	opts = append(opts,
 		httpx.ResilientClientWithLogger(m.Logger()),
@@ -865,6 +1103,11 @@ func (m *RegistryDefault) HTTPClient(ctx context.Context, opts ...httpx.Resilien
 
 func (m *RegistryDefault) WithContextualizer(ctxer contextx.Contextualizer) Registry {
 	m.ctxer = ctxer
+	// Strategies and the courier read the configuration of the request context, so it must be resolved by the same
+	// contextualizer as the network.
+	if m.c != nil {
+		m.c.WithContextualizer(ctxer)
+	}
 	return m
 }
 
@@ -0,0 +1,20 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import "github.com/ory/kratos/selfservice/approval"
+
+func (m *RegistryDefault) RegistrationApprovalManager() *approval.Manager {
+	if m.registrationApprovalManager == nil {
+		m.registrationApprovalManager = approval.NewManager(m)
+	}
+	return m.registrationApprovalManager
+}
+
+func (m *RegistryDefault) RegistrationApprovalHandler() *approval.Handler {
+	if m.registrationApprovalHandler == nil {
+		m.registrationApprovalHandler = approval.NewHandler(m)
+	}
+	return m.registrationApprovalHandler
+}
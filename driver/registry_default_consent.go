@@ -0,0 +1,24 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import "github.com/ory/kratos/selfservice/consent"
+
+func (m *RegistryDefault) ConsentPersister() consent.Persister {
+	return m.Persister()
+}
+
+func (m *RegistryDefault) ConsentManager() *consent.Manager {
+	if m.consentManager == nil {
+		m.consentManager = consent.NewManager(m)
+	}
+	return m.consentManager
+}
+
+func (m *RegistryDefault) ConsentHandler() *consent.Handler {
+	if m.consentHandler == nil {
+		m.consentHandler = consent.NewHandler(m)
+	}
+	return m.consentHandler
+}
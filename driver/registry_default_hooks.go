@@ -4,7 +4,10 @@
 package driver
 
 import (
+	"context"
+
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/hook"
 )
 
@@ -22,6 +25,13 @@ func (m *RegistryDefault) HookCodeAddressVerifier() *hook.CodeAddressVerifier {
 	return m.hookCodeAddressVerifier
 }
 
+func (m *RegistryDefault) HookVerifyBeforeCreation() *hook.VerifyBeforeCreation {
+	if m.hookVerifyBeforeCreate == nil {
+		m.hookVerifyBeforeCreate = hook.NewVerifyBeforeCreation(m)
+	}
+	return m.hookVerifyBeforeCreate
+}
+
 func (m *RegistryDefault) HookSessionIssuer() *hook.SessionIssuer {
 	if m.hookSessionIssuer == nil {
 		m.hookSessionIssuer = hook.NewSessionIssuer(m)
@@ -43,6 +53,13 @@ func (m *RegistryDefault) HookAddressVerifier() *hook.AddressVerifier {
 	return m.hookAddressVerifier
 }
 
+func (m *RegistryDefault) HookVerifiedAddressEnforcer() *hook.VerifiedAddressEnforcer {
+	if m.hookVerifiedAddress == nil {
+		m.hookVerifiedAddress = hook.NewVerifiedAddressEnforcer(m)
+	}
+	return m.hookVerifiedAddress
+}
+
 func (m *RegistryDefault) HookShowVerificationUI() *hook.ShowVerificationUIHook {
 	if m.hookShowVerificationUI == nil {
 		m.hookShowVerificationUI = hook.NewShowVerificationUIHook(m)
@@ -50,6 +67,15 @@ func (m *RegistryDefault) HookShowVerificationUI() *hook.ShowVerificationUIHook
 	return m.hookShowVerificationUI
 }
 
+func (m *RegistryDefault) BeforeRenderHooks(ctx context.Context, name flow.FlowName) (b []flow.BeforeRenderHookExecutor) {
+	for _, v := range m.getHooks("", m.Config().SelfServiceFlowBeforeRenderHooks(ctx, string(name))) {
+		if executor, ok := v.(flow.BeforeRenderHookExecutor); ok {
+			b = append(b, executor)
+		}
+	}
+	return
+}
+
 func (m *RegistryDefault) WithHooks(hooks map[string]func(config.SelfServiceHook) interface{}) {
 	m.injectedSelfserviceHooks = hooks
 }
@@ -67,6 +93,8 @@ func (m *RegistryDefault) getHooks(credentialsType string, configs []config.Self
 			i = append(i, m.HookAddressVerifier())
 		case hook.KeyVerificationUI:
 			i = append(i, m.HookShowVerificationUI())
+		case hook.KeyDisposableEmail:
+			i = append(i, hook.NewDisposableEmail(m, h.Config))
 		default:
 			var found bool
 			for name, m := range m.injectedSelfserviceHooks {
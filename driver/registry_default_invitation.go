@@ -0,0 +1,24 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import "github.com/ory/kratos/selfservice/invitation"
+
+func (m *RegistryDefault) InvitationPersister() invitation.Persister {
+	return m.Persister()
+}
+
+func (m *RegistryDefault) InvitationManager() *invitation.Manager {
+	if m.invitationManager == nil {
+		m.invitationManager = invitation.NewManager(m)
+	}
+	return m.invitationManager
+}
+
+func (m *RegistryDefault) InvitationHandler() *invitation.Handler {
+	if m.invitationHandler == nil {
+		m.invitationHandler = invitation.NewHandler(m)
+	}
+	return m.invitationHandler
+}
@@ -0,0 +1,24 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import "github.com/ory/kratos/selfservice/lockout"
+
+func (m *RegistryDefault) LockoutPersister() lockout.Persister {
+	return m.Persister()
+}
+
+func (m *RegistryDefault) LockoutManager() *lockout.Manager {
+	if m.lockoutManager == nil {
+		m.lockoutManager = lockout.NewManager(m)
+	}
+	return m.lockoutManager
+}
+
+func (m *RegistryDefault) LockoutHandler() *lockout.Handler {
+	if m.lockoutHandler == nil {
+		m.lockoutHandler = lockout.NewHandler(m)
+	}
+	return m.lockoutHandler
+}
@@ -18,6 +18,17 @@ func (m *RegistryDefault) LoginHookExecutor() *login.HookExecutor {
 	return m.selfserviceLoginExecutor
 }
 
+func (m *RegistryDefault) LoginNewDeviceNotifier() *login.NewDeviceNotifier {
+	if m.selfserviceLoginNewDeviceNotifier == nil {
+		m.selfserviceLoginNewDeviceNotifier = login.NewNewDeviceNotifier(m)
+	}
+	return m.selfserviceLoginNewDeviceNotifier
+}
+
+func (m *RegistryDefault) LoginRiskAssessor() login.RiskAssessor {
+	return login.NewHTTPRiskAssessor(m)
+}
+
 func (m *RegistryDefault) PreLoginHooks(ctx context.Context) (b []login.PreHookExecutor) {
 	for _, v := range m.getHooks("", m.Config().SelfServiceFlowLoginBeforeHooks(ctx)) {
 		if hook, ok := v.(login.PreHookExecutor); ok {
@@ -43,6 +54,11 @@ func (m *RegistryDefault) PostLoginHooks(ctx context.Context, credentialsType id
 			}
 		}
 	}
+
+	if m.Config().SelfServiceFlowLoginRequireVerifiedAddress(ctx, string(credentialsType)) {
+		// The address check runs first so that no other hook acts on a login that is refused.
+		b = append([]login.PostHookExecutor{m.HookVerifiedAddressEnforcer()}, b...)
+	}
 	return
 }
 
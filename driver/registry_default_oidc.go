@@ -0,0 +1,24 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import "github.com/ory/kratos/selfservice/strategy/oidc"
+
+func (m *RegistryDefault) OIDCProviderPersister() oidc.ProviderPersister {
+	return m.Persister()
+}
+
+func (m *RegistryDefault) OIDCProviderCache() *oidc.ProviderCache {
+	if m.oidcProviderCache == nil {
+		m.oidcProviderCache = oidc.NewProviderCache()
+	}
+	return m.oidcProviderCache
+}
+
+func (m *RegistryDefault) OIDCProviderHandler() *oidc.Handler {
+	if m.oidcProviderHandler == nil {
+		m.oidcProviderHandler = oidc.NewHandler(m)
+	}
+	return m.oidcProviderHandler
+}
@@ -0,0 +1,17 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import "github.com/ory/kratos/organization"
+
+func (m *RegistryDefault) OrganizationPersister() organization.Persister {
+	return m.Persister()
+}
+
+func (m *RegistryDefault) OrganizationHandler() *organization.Handler {
+	if m.organizationHandler == nil {
+		m.organizationHandler = organization.NewHandler(m)
+	}
+	return m.organizationHandler
+}
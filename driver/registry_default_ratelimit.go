@@ -0,0 +1,26 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+
+	"github.com/ory/kratos/selfservice/ratelimit"
+)
+
+func (m *RegistryDefault) LoginRateLimiter() *ratelimit.Limiter {
+	if m.loginRateLimiter == nil {
+		ctx := context.Background()
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if m.Config().SelfServiceFlowLoginRateLimitStore(ctx) == "redis" {
+			rs, err := ratelimit.NewRedisStore(m.Config().SelfServiceFlowLoginRateLimitRedisURL(ctx))
+			if err != nil {
+				m.Logger().WithError(err).Fatal("could not initialize the login rate limit store")
+			}
+			store = rs
+		}
+		m.loginRateLimiter = ratelimit.NewLimiter(m, store)
+	}
+	return m.loginRateLimiter
+}
@@ -69,6 +69,13 @@ func (m *RegistryDefault) RecoveryExecutor() *recovery.HookExecutor {
 	return m.selfserviceRecoveryExecutor
 }
 
+func (m *RegistryDefault) RecoveryNotifier() *recovery.Notifier {
+	if m.selfserviceRecoveryNotifier == nil {
+		m.selfserviceRecoveryNotifier = recovery.NewNotifier(m)
+	}
+	return m.selfserviceRecoveryNotifier
+}
+
 func (m *RegistryDefault) PreRecoveryHooks(ctx context.Context) (b []recovery.PreHookExecutor) {
 	for _, v := range m.getHooks("", m.Config().SelfServiceFlowRecoveryBeforeHooks(ctx)) {
 		if hook, ok := v.(recovery.PreHookExecutor); ok {
@@ -88,6 +95,16 @@ func (m *RegistryDefault) PostRecoveryHooks(ctx context.Context) (b []recovery.P
 	return
 }
 
+func (m *RegistryDefault) RecoveryUnknownAddressHooks(ctx context.Context) (b []recovery.UnknownAddressHookExecutor) {
+	for _, v := range m.getHooks("", m.Config().SelfServiceFlowRecoveryUnknownAddressHooks(ctx)) {
+		if hook, ok := v.(recovery.UnknownAddressHookExecutor); ok {
+			b = append(b, hook)
+		}
+	}
+
+	return
+}
+
 func (m *RegistryDefault) CodeSender() *code.Sender {
 	if m.selfserviceCodeSender == nil {
 		m.selfserviceCodeSender = code.NewSender(m)
@@ -95,3 +112,7 @@ func (m *RegistryDefault) CodeSender() *code.Sender {
 
 	return m.selfserviceCodeSender
 }
+
+func (m *RegistryDefault) CodeBackend(ctx context.Context) code.Backend {
+	return code.NewBackend(ctx, m)
+}
@@ -11,7 +11,21 @@ import (
 	"github.com/ory/kratos/selfservice/flow/registration"
 )
 
+func (m *RegistryDefault) PendingRegistrationVerifier() registration.PendingRegistrationVerifier {
+	return m.HookVerifyBeforeCreation()
+}
+
+func (m *RegistryDefault) DuplicateRegistrationResponder() registration.DuplicateRegistrationResponder {
+	return m.HookVerifyBeforeCreation()
+}
+
 func (m *RegistryDefault) PostRegistrationPrePersistHooks(ctx context.Context, credentialsType identity.CredentialsType) (b []registration.PostHookPrePersistExecutor) {
+	// Codes and social sign in providers verify the address already.
+	if m.Config().SelfServiceFlowRegistrationVerifyBeforeCreation(ctx) &&
+		credentialsType != identity.CredentialsTypeCodeAuth && credentialsType != identity.CredentialsTypeOIDC {
+		b = append(b, m.HookVerifyBeforeCreation())
+	}
+
 	if credentialsType == identity.CredentialsTypeCodeAuth && m.Config().SelfServiceCodeStrategy(ctx).PasswordlessEnabled {
 		b = append(b, m.HookCodeAddressVerifier())
 	}
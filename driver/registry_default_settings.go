@@ -78,7 +78,7 @@ func (m *RegistryDefault) SettingsFlowErrorHandler() *settings.ErrorHandler {
 func (m *RegistryDefault) SettingsStrategies(ctx context.Context) (profileStrategies settings.Strategies) {
 	for _, strategy := range m.selfServiceStrategies() {
 		if s, ok := strategy.(settings.Strategy); ok {
-			if m.Config().SelfServiceStrategy(ctx, s.SettingsStrategyID()).Enabled {
+			if m.Config().SelfServiceStrategy(ctx, s.SettingsStrategyID()).Enabled && m.strategyConfigured(ctx, s.SettingsStrategyID(), s) {
 				profileStrategies = append(profileStrategies, s)
 			}
 		}
@@ -0,0 +1,24 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import "github.com/ory/kratos/selfservice/signupcode"
+
+func (m *RegistryDefault) SignupCodePersister() signupcode.Persister {
+	return m.Persister()
+}
+
+func (m *RegistryDefault) SignupCodeManager() *signupcode.Manager {
+	if m.signupCodeManager == nil {
+		m.signupCodeManager = signupcode.NewManager(m)
+	}
+	return m.signupCodeManager
+}
+
+func (m *RegistryDefault) SignupCodeHandler() *signupcode.Handler {
+	if m.signupCodeHandler == nil {
+		m.signupCodeHandler = signupcode.NewHandler(m)
+	}
+	return m.signupCodeHandler
+}
@@ -0,0 +1,79 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package driver
+
+import (
+	"context"
+
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/strategy"
+)
+
+var _ strategy.MisconfigurationProvider = new(RegistryDefault)
+
+func (m *RegistryDefault) StrategiesHealthHandler() *strategy.HealthHandler {
+	if m.strategiesHealthHandler == nil {
+		m.strategiesHealthHandler = strategy.NewHealthHandler(m)
+	}
+	return m.strategiesHealthHandler
+}
+
+// StrategyMisconfigurations returns the enabled strategies whose configuration is unusable.
+func (m *RegistryDefault) StrategyMisconfigurations(ctx context.Context) (misconfigurations []strategy.Misconfiguration) {
+	for _, s := range m.selfServiceStrategies() {
+		var id string
+		var enabled bool
+		switch s := s.(type) {
+		case login.Strategy:
+			id = s.ID().String()
+			enabled = m.strategyLoginEnabled(ctx, id)
+		case settings.Strategy:
+			id = s.SettingsStrategyID()
+			enabled = m.Config().SelfServiceStrategy(ctx, id).Enabled
+		default:
+			continue
+		}
+
+		if !enabled {
+			continue
+		}
+
+		if err := m.validateStrategyConfiguration(ctx, id, s); err != nil {
+			misconfigurations = append(misconfigurations, strategy.Misconfiguration{Strategy: id, Reason: err.Error()})
+		}
+	}
+	return misconfigurations
+}
+
+// strategyConfigured returns false if the strategy reports that its configuration is unusable.
+func (m *RegistryDefault) strategyConfigured(ctx context.Context, id string, s any) bool {
+	return m.validateStrategyConfiguration(ctx, id, s) == nil
+}
+
+// validateStrategyConfiguration validates the configuration of the strategy. Because the configuration may be
+// reloaded at any time, the result is not cached, but a change of the result is logged once.
+func (m *RegistryDefault) validateStrategyConfiguration(ctx context.Context, id string, s any) error {
+	v, ok := s.(strategy.ConfigurationValidator)
+	if !ok {
+		return nil
+	}
+
+	err := v.ValidateConfiguration(ctx)
+	previous, wasMisconfigured := m.strategyMisconfigurations.Load(id)
+	switch {
+	case err != nil && (!wasMisconfigured || previous != err.Error()):
+		m.strategyMisconfigurations.Store(id, err.Error())
+		m.Logger().
+			WithError(err).
+			WithField("strategy", id).
+			Warn("The strategy is enabled but its configuration is unusable. The strategy is hidden from all self-service flows until the configuration is fixed.")
+	case err == nil && wasMisconfigured:
+		m.strategyMisconfigurations.Delete(id)
+		m.Logger().
+			WithField("strategy", id).
+			Info("The configuration of the strategy is usable again.")
+	}
+	return err
+}
@@ -16,12 +16,15 @@ import (
 
 	"github.com/ory/kratos/driver"
 	"github.com/ory/x/configx"
+	"github.com/ory/x/contextx"
 	"github.com/ory/x/logrusx"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ory/kratos/cipher"
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/hash"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
 	"github.com/ory/kratos/selfservice/flow/login"
@@ -472,6 +475,41 @@ func TestDriverDefault_Hooks(t *testing.T) {
 					}
 				},
 			},
+			{
+				uc: "Verified addresses are required for all methods",
+				prep: func(conf *config.Config) {
+					conf.MustSet(ctx, config.ViperKeySelfServiceLoginRequireVerifiedAddressEnabled, true)
+					conf.MustSet(ctx, config.ViperKeySelfServiceLoginAfter+".password.hooks", []map[string]interface{}{
+						{"hook": "revoke_active_sessions"},
+					})
+				},
+				expect: func(reg *driver.RegistryDefault) []login.PostHookExecutor {
+					return []login.PostHookExecutor{
+						hook.NewVerifiedAddressEnforcer(reg),
+						hook.NewSessionDestroyer(reg),
+					}
+				},
+			},
+			{
+				uc: "Verified addresses are required for the password method",
+				prep: func(conf *config.Config) {
+					conf.MustSet(ctx, config.ViperKeySelfServiceLoginRequireVerifiedAddressEnabled, true)
+					conf.MustSet(ctx, config.ViperKeySelfServiceLoginRequireVerifiedAddressMethods, []string{"password"})
+				},
+				expect: func(reg *driver.RegistryDefault) []login.PostHookExecutor {
+					return []login.PostHookExecutor{
+						hook.NewVerifiedAddressEnforcer(reg),
+					}
+				},
+			},
+			{
+				uc: "Verified addresses are required for other methods only",
+				prep: func(conf *config.Config) {
+					conf.MustSet(ctx, config.ViperKeySelfServiceLoginRequireVerifiedAddressEnabled, true)
+					conf.MustSet(ctx, config.ViperKeySelfServiceLoginRequireVerifiedAddressMethods, []string{"oidc", "code"})
+				},
+				expect: func(reg *driver.RegistryDefault) []login.PostHookExecutor { return nil },
+			},
 		} {
 			t.Run(fmt.Sprintf("after/uc=%s", tc.uc), func(t *testing.T) {
 				conf, reg := internal.NewVeryFastRegistryWithoutDB(t)
@@ -657,6 +695,14 @@ func TestDriverDefault_Strategies(t *testing.T) {
 				},
 				expect: []string{"password", "code"},
 			},
+			{
+				prep: func(conf *config.Config) {
+					conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".password.enabled", true)
+					conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".code.passwordless_enabled", true)
+					conf.MustSet(ctx, config.ViperKeyCodeBackendType, "twilio_verify")
+				},
+				expect: []string{"password"},
+			},
 		} {
 			t.Run(fmt.Sprintf("run=%d", k), func(t *testing.T) {
 				conf, reg := internal.NewVeryFastRegistryWithoutDB(t)
@@ -703,6 +749,27 @@ func TestDriverDefault_Strategies(t *testing.T) {
 				},
 				expect: []string{"password", "oidc", "totp"},
 			},
+			{
+				prep: func(conf *config.Config) {
+					conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".oidc.enabled", true)
+					conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".oidc.config.providers", []map[string]interface{}{
+						{"id": "valid", "provider": "generic", "client_id": "client", "client_secret": "secret", "issuer_url": "https://www.ory.sh", "mapper_url": "file://./stub/oidc.jsonnet"},
+						{"id": "broken", "provider": "unsupported", "client_id": "client", "client_secret": "secret", "mapper_url": "file://./stub/oidc.jsonnet"},
+					})
+					conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".password.enabled", true)
+				},
+				expect: []string{"password", "oidc"},
+			},
+			{
+				prep: func(conf *config.Config) {
+					conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".oidc.enabled", true)
+					conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".oidc.config.providers", []map[string]interface{}{
+						{"id": "broken", "provider": "unsupported", "client_id": "client", "client_secret": "secret", "mapper_url": "file://./stub/oidc.jsonnet"},
+					})
+					conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".password.enabled", true)
+				},
+				expect: []string{"password"},
+			},
 			{
 				prep: func(conf *config.Config) {
 					conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".password.enabled", true)
@@ -710,6 +777,14 @@ func TestDriverDefault_Strategies(t *testing.T) {
 				},
 				expect: []string{"password", "code"},
 			},
+			{
+				prep: func(conf *config.Config) {
+					conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".password.enabled", true)
+					conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".code.passwordless_enabled", true)
+					conf.MustSet(ctx, config.ViperKeyCodeBackendType, "twilio_verify")
+				},
+				expect: []string{"password"},
+			},
 		} {
 			t.Run(fmt.Sprintf("run=%d", k), func(t *testing.T) {
 				conf, reg := internal.NewVeryFastRegistryWithoutDB(t)
@@ -846,12 +921,32 @@ func TestDriverDefault_Strategies(t *testing.T) {
 	})
 }
 
+func TestDefaultRegistry_StrategyMisconfigurations(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conf, reg := internal.NewVeryFastRegistryWithoutDB(t)
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".code.passwordless_enabled", true)
+
+	assert.Empty(t, reg.StrategyMisconfigurations(ctx))
+
+	conf.MustSet(ctx, config.ViperKeyCodeBackendType, "twilio_verify")
+	misconfigurations := reg.StrategyMisconfigurations(ctx)
+	require.Len(t, misconfigurations, 1)
+	assert.Equal(t, "code", misconfigurations[0].Strategy)
+	assert.Contains(t, misconfigurations[0].Reason, "Twilio Verify")
+
+	conf.MustSet(ctx, config.ViperKeyCodeBackendTwilioVerify+".service_sid", "VA123")
+	conf.MustSet(ctx, config.ViperKeyCodeBackendTwilioVerify+".account_sid", "AC123")
+	conf.MustSet(ctx, config.ViperKeyCodeBackendTwilioVerify+".auth_token", "secret")
+	assert.Empty(t, reg.StrategyMisconfigurations(ctx))
+}
+
 func TestDefaultRegistry_AllStrategies(t *testing.T) {
 	t.Parallel()
 	_, reg := internal.NewVeryFastRegistryWithoutDB(t)
 
 	t.Run("case=all login strategies", func(t *testing.T) {
-		expects := []string{"password", "oidc", "code", "totp", "webauthn", "lookup_secret"}
+		expects := []string{"password", "oidc", "code", "totp", "webauthn", "lookup_secret", "push", "external_mfa", "identifier_first"}
 		s := reg.AllLoginStrategies()
 		require.Len(t, s, len(expects))
 		for k, e := range expects {
@@ -869,7 +964,7 @@ func TestDefaultRegistry_AllStrategies(t *testing.T) {
 	})
 
 	t.Run("case=all settings strategies", func(t *testing.T) {
-		expects := []string{"password", "oidc", "profile", "totp", "webauthn", "lookup_secret"}
+		expects := []string{"password", "oidc", "profile", "totp", "webauthn", "lookup_secret", "push", "trusted_device", "consent", "sessions"}
 		s := reg.AllSettingsStrategies()
 		require.Len(t, s, len(expects))
 		for k, e := range expects {
@@ -940,3 +1035,90 @@ func TestGetActiveVerificationStrategy(t *testing.T) {
 		}
 	})
 }
+
+func TestDefaultRegistry_ResolvesAlgorithmsPerContext(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	conf, reg := internal.NewVeryFastRegistryWithoutDB(t)
+
+	t.Run("component=hasher", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeyHasherAlgorithm, "bcrypt")
+		assert.IsType(t, new(hash.Bcrypt), reg.Hasher(ctx))
+
+		conf.MustSet(ctx, config.ViperKeyHasherAlgorithm, "argon2")
+		assert.IsType(t, new(hash.Argon2), reg.Hasher(ctx))
+		assert.Same(t, reg.Hasher(ctx), reg.Hasher(ctx))
+	})
+
+	t.Run("component=cipher", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeyCipherAlgorithm, "aes")
+		assert.IsType(t, new(cipher.AES), reg.Cipher(ctx))
+
+		conf.MustSet(ctx, config.ViperKeyCipherAlgorithm, "xchacha20-poly1305")
+		assert.IsType(t, new(cipher.XChaCha20Poly1305), reg.Cipher(ctx))
+		assert.Same(t, reg.Cipher(ctx), reg.Cipher(ctx))
+	})
+}
+
+type tenantContextKey struct{}
+
+// tenantContextualizer resolves the configuration of the tenant stored in the request context.
+type tenantContextualizer struct {
+	contextx.Default
+	tenants map[string]*configx.Provider
+}
+
+func (c *tenantContextualizer) Config(ctx context.Context, fallback *configx.Provider) *configx.Provider {
+	if tenant, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		if p, ok := c.tenants[tenant]; ok {
+			return p
+		}
+	}
+	return fallback
+}
+
+func TestDefaultRegistry_ResolvesTenantsPerContext(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	confA, reg := internal.NewVeryFastRegistryWithoutDB(t)
+	confB := internal.NewConfigurationWithDefaults(t)
+	reg.WithContextualizer(&tenantContextualizer{tenants: map[string]*configx.Provider{
+		"a": confA.GetProvider(ctx),
+		"b": confB.GetProvider(ctx),
+	}})
+
+	ctxA := context.WithValue(ctx, tenantContextKey{}, "a")
+	ctxB := context.WithValue(ctx, tenantContextKey{}, "b")
+
+	t.Run("component=strategies", func(t *testing.T) {
+		confA.MustSet(ctxA, config.ViperKeySelfServiceStrategyConfig+".password.enabled", true)
+		confB.MustSet(ctxB, config.ViperKeySelfServiceStrategyConfig+".password.enabled", false)
+
+		_, err := reg.LoginStrategies(ctxA).Strategy(identity.CredentialsTypePassword)
+		assert.NoError(t, err)
+		_, err = reg.LoginStrategies(ctxB).Strategy(identity.CredentialsTypePassword)
+		assert.Error(t, err, "the password method is disabled for the other tenant")
+	})
+
+	t.Run("component=courier", func(t *testing.T) {
+		confA.MustSet(ctxA, config.ViperKeyCourierSMTPURL, "smtp://tenant-a.example.com:1025/")
+		confB.MustSet(ctxB, config.ViperKeyCourierSMTPURL, "smtp://tenant-b.example.com:1025/")
+
+		a, err := reg.Courier(ctxA)
+		require.NoError(t, err)
+		b, err := reg.Courier(ctxB)
+		require.NoError(t, err)
+
+		assert.NotSame(t, a, b)
+		assert.Equal(t, "tenant-a.example.com", a.SmtpDialer().Host)
+		assert.Equal(t, "tenant-b.example.com", b.SmtpDialer().Host)
+	})
+
+	t.Run("component=hasher", func(t *testing.T) {
+		confA.MustSet(ctxA, config.ViperKeyHasherAlgorithm, "bcrypt")
+		confB.MustSet(ctxB, config.ViperKeyHasherAlgorithm, "argon2")
+
+		assert.IsType(t, new(hash.Bcrypt), reg.Hasher(ctxA))
+		assert.IsType(t, new(hash.Argon2), reg.Hasher(ctxB))
+	})
+}
@@ -43,6 +43,14 @@ func (m *RegistryDefault) VerificationHandler() *verification.Handler {
 	return m.selfserviceVerifyHandler
 }
 
+func (m *RegistryDefault) VerificationReminder() *verification.Reminder {
+	if m.selfserviceVerifyReminder == nil {
+		m.selfserviceVerifyReminder = verification.NewReminder(m)
+	}
+
+	return m.selfserviceVerifyReminder
+}
+
 func (m *RegistryDefault) LinkSender() *link.Sender {
 	if m.selfserviceLinkSender == nil {
 		m.selfserviceLinkSender = link.NewSender(m)
@@ -0,0 +1,113 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package hash
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	"github.com/inhies/go-bytesize"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+const (
+	// Argon2CalibrationMinMemory is the lowest amount of memory CalibrateArgon2 will settle on.
+	Argon2CalibrationMinMemory = 16 * bytesize.MB
+
+	// Argon2CalibrationDefaultMaxMemory is the highest amount of memory CalibrateArgon2 will settle on
+	// if no memory budget is given.
+	Argon2CalibrationDefaultMaxMemory = 4 * bytesize.GB
+
+	// Argon2CalibrationMaxIterations is the highest number of iterations CalibrateArgon2 will settle on.
+	Argon2CalibrationMaxIterations = 64
+
+	argon2CalibrationRuns = 2
+)
+
+// CalibrateArgon2 benchmarks the current host and derives Argon2id parameters for which hashing a
+// password takes as long as possible without exceeding base.ExpectedDuration. Memory is increased
+// first (up to maxMemory, 0 means Argon2CalibrationDefaultMaxMemory) and the remaining time is spent
+// on additional iterations (up to Argon2CalibrationMaxIterations). All other parameters are copied
+// from base.
+func CalibrateArgon2(ctx context.Context, base *config.Argon2, maxMemory bytesize.ByteSize) (*config.Argon2, error) {
+	if maxMemory == 0 {
+		maxMemory = Argon2CalibrationDefaultMaxMemory
+	}
+
+	c := *base
+	c.Iterations = 1
+	if c.Memory < Argon2CalibrationMinMemory {
+		c.Memory = Argon2CalibrationMinMemory
+	}
+	if c.Memory > maxMemory {
+		c.Memory = maxMemory
+	}
+
+	fits := func(c config.Argon2) (bool, error) {
+		if err := ctx.Err(); err != nil {
+			return false, errors.WithStack(err)
+		}
+		took, err := probeArgon2(&c)
+		if err != nil {
+			return false, err
+		}
+		return took <= c.ExpectedDuration, nil
+	}
+
+	// Decrease memory until a single iteration fits into the expected duration.
+	for {
+		ok, err := fits(c)
+		if err != nil {
+			return nil, err
+		} else if ok || c.Memory/2 < Argon2CalibrationMinMemory {
+			break
+		}
+		c.Memory /= 2
+	}
+
+	// Increase memory while staying within the expected duration and the memory budget.
+	for c.Memory*2 <= maxMemory {
+		next := c
+		next.Memory *= 2
+		ok, err := fits(next)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+		c = next
+	}
+
+	// Spend the remaining time on additional iterations.
+	for c.Iterations < Argon2CalibrationMaxIterations {
+		next := c
+		next.Iterations++
+		ok, err := fits(next)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+		c = next
+	}
+
+	return &c, nil
+}
+
+func probeArgon2(c *config.Argon2) (time.Duration, error) {
+	salt := make([]byte, c.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	start := time.Now()
+	for i := 0; i < argon2CalibrationRuns; i++ {
+		_ = argon2.IDKey([]byte("password"), salt, c.Iterations, toKB(c.Memory), c.Parallelism, c.KeyLength)
+	}
+	return time.Since(start) / argon2CalibrationRuns, nil
+}
@@ -0,0 +1,56 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package hash_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/inhies/go-bytesize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/hash"
+)
+
+func TestCalibrateArgon2(t *testing.T) {
+	base := &config.Argon2{
+		Memory:           config.Argon2DefaultMemory,
+		Iterations:       3,
+		Parallelism:      1,
+		SaltLength:       config.Argon2DefaultSaltLength,
+		KeyLength:        config.Argon2DefaultKeyLength,
+		ExpectedDuration: 20 * time.Millisecond,
+	}
+
+	t.Run("case=respects memory budget", func(t *testing.T) {
+		c, err := hash.CalibrateArgon2(context.Background(), base, 32*bytesize.MB)
+		require.NoError(t, err)
+
+		assert.GreaterOrEqual(t, c.Memory, hash.Argon2CalibrationMinMemory)
+		assert.LessOrEqual(t, c.Memory, 32*bytesize.MB)
+		assert.GreaterOrEqual(t, c.Iterations, uint32(1))
+		assert.Equal(t, base.SaltLength, c.SaltLength)
+		assert.Equal(t, base.KeyLength, c.KeyLength)
+		assert.Equal(t, base.Parallelism, c.Parallelism)
+	})
+
+	t.Run("case=terminates without memory budget", func(t *testing.T) {
+		c, err := hash.CalibrateArgon2(context.Background(), base, 0)
+		require.NoError(t, err)
+
+		assert.LessOrEqual(t, c.Memory, hash.Argon2CalibrationDefaultMaxMemory)
+		assert.LessOrEqual(t, c.Iterations, uint32(hash.Argon2CalibrationMaxIterations))
+	})
+
+	t.Run("case=aborts when context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := hash.CalibrateArgon2(ctx, base, 32*bytesize.MB)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,415 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/sqlxx"
+)
+
+// AuditActorType is the kind of actor which changed an identity.
+type AuditActorType string
+
+const (
+	// AuditActorAdminAPI is used for changes made through the admin API.
+	AuditActorAdminAPI AuditActorType = "admin_api"
+
+	// AuditActorSelfService is used for changes made by the user in a self-service flow.
+	AuditActorSelfService AuditActorType = "self_service"
+
+	// AuditActorHook is used for changes made by a self-service hook.
+	AuditActorHook AuditActorType = "hook"
+
+	// AuditActorSystem is used for changes made by Ory Kratos itself, for example by background jobs.
+	AuditActorSystem AuditActorType = "system"
+)
+
+// AuditAction is the kind of change recorded by an audit event.
+type AuditAction string
+
+const (
+	AuditActionIdentityCreated          AuditAction = "identity.created"
+	AuditActionIdentityUpdated          AuditAction = "identity.updated"
+	AuditActionIdentityDeleted          AuditAction = "identity.deleted"
+	AuditActionVerifiableAddressUpdated AuditAction = "verifiable_address.updated"
+)
+
+// AuditActor identifies who changed an identity.
+type AuditActor struct {
+	Type AuditActorType
+	ID   string
+}
+
+type auditActorContextKey struct{}
+
+// WithAuditActor returns a context which attributes all changes to identities to the given actor.
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorContextKey{}, actor)
+}
+
+// AuditActorFromContext returns the actor of the context, or the system actor if none is set.
+func AuditActorFromContext(ctx context.Context) AuditActor {
+	if actor, ok := ctx.Value(auditActorContextKey{}).(AuditActor); ok {
+		return actor
+	}
+	return AuditActor{Type: AuditActorSystem}
+}
+
+// AdminAuditActor returns the actor of an admin API request. The API key of the request is identified by a
+// fingerprint, so that the key itself is never stored.
+func AdminAuditActor(r *http.Request) AuditActor {
+	actor := AuditActor{Type: AuditActorAdminAPI}
+	if key := strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")); key != "" {
+		actor.ID = fingerprint([]byte(key))
+	}
+	return actor
+}
+
+// WithSelfServiceAuditActor attributes the changes made while handling a self-service flow to the flow given in
+// the `flow` query parameter.
+func WithSelfServiceAuditActor(handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		actor := AuditActor{Type: AuditActorSelfService, ID: r.URL.Query().Get("flow")}
+		handle(w, r.WithContext(WithAuditActor(r.Context(), actor)), ps)
+	}
+}
+
+func withAdminAuditActor(handle httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		handle(w, r.WithContext(WithAuditActor(r.Context(), AdminAuditActor(r))), ps)
+	}
+}
+
+// Identity Audit Event
+//
+// An audit event records a change to an identity, its credentials, or its addresses. Audit events are never
+// changed or deleted, not even when the identity is deleted.
+//
+// swagger:model identityAuditEvent
+type AuditEvent struct {
+	// ID is the ID of the audit event.
+	//
+	// required: true
+	ID uuid.UUID `json:"id" db:"id"`
+
+	// IdentityID is the ID of the changed identity.
+	//
+	// required: true
+	IdentityID uuid.UUID `json:"identity_id" db:"identity_id"`
+
+	// Action is the kind of change, for example `identity.updated`.
+	//
+	// required: true
+	Action AuditAction `json:"action" db:"action"`
+
+	// ActorType is the kind of actor which made the change: `admin_api`, `self_service`, `hook`, or `system`.
+	//
+	// required: true
+	ActorType AuditActorType `json:"actor_type" db:"actor_type"`
+
+	// ActorID identifies the actor, for example the self-service flow, the hook, or the fingerprint of the
+	// admin API key.
+	ActorID string `json:"actor_id" db:"actor_id"`
+
+	// Changes lists the changed fields with their values before and after the change. Credential secrets are
+	// never recorded. Instead, a fingerprint of the credential configuration is recorded to show that it changed.
+	//
+	// required: true
+	Changes AuditChanges `json:"changes" db:"changes"`
+
+	// CreatedAt is the time of the change.
+	//
+	// required: true
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (AuditEvent) TableName(context.Context) string {
+	return "identity_audit_events"
+}
+
+// AuditChange is a changed field of an identity.
+type AuditChange struct {
+	// Path is the JSON Pointer of the changed field, for example `/traits/email`.
+	Path string `json:"path"`
+
+	// Before is the value before the change. It is not set if the field was added.
+	Before json.RawMessage `json:"before,omitempty"`
+
+	// After is the value after the change. It is not set if the field was removed.
+	After json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditChanges is stored as JSON.
+type AuditChanges []AuditChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		c = AuditChanges{}
+	}
+	v, err := json.Marshal(c)
+	return string(v), errors.WithStack(err)
+}
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	var v []byte
+	switch t := value.(type) {
+	case []byte:
+		v = t
+	case string:
+		v = []byte(t)
+	default:
+		return errors.Errorf("unable to scan audit changes of type %T", value)
+	}
+	return errors.WithStack(json.Unmarshal(v, c))
+}
+
+// NewAuditEvent records the change of an identity by the actor of the context. The identity was created if before
+// is nil and deleted if after is nil. It returns nil if nothing changed.
+func NewAuditEvent(ctx context.Context, action AuditAction, before, after *Identity) (*AuditEvent, error) {
+	id := after
+	if id == nil {
+		id = before
+	}
+
+	changes, err := auditDiff(auditDocumentOf(before), auditDocumentOf(after))
+	if err != nil {
+		return nil, err
+	}
+
+	return newAuditEvent(ctx, action, id.ID, changes), nil
+}
+
+// NewVerifiableAddressAuditEvent records the change of a verifiable address by the actor of the context. It
+// returns nil if nothing changed.
+func NewVerifiableAddressAuditEvent(ctx context.Context, before, after *VerifiableAddress) (*AuditEvent, error) {
+	changes, err := auditDiff(
+		&auditDocument{VerifiableAddresses: auditVerifiableAddresses([]VerifiableAddress{*before})},
+		&auditDocument{VerifiableAddresses: auditVerifiableAddresses([]VerifiableAddress{*after})},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAuditEvent(ctx, AuditActionVerifiableAddressUpdated, after.IdentityID, changes), nil
+}
+
+func newAuditEvent(ctx context.Context, action AuditAction, identityID uuid.UUID, changes AuditChanges) *AuditEvent {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	actor := AuditActorFromContext(ctx)
+	return &AuditEvent{
+		IdentityID: identityID,
+		Action:     action,
+		ActorType:  actor.Type,
+		ActorID:    actor.ID,
+		Changes:    changes,
+		CreatedAt:  time.Now().UTC(),
+	}
+}
+
+type (
+	// auditDocument contains the audited fields of an identity. Lists are keyed, so that changes to single
+	// credentials and addresses are recorded precisely.
+	auditDocument struct {
+		SchemaID            string                               `json:"schema_id,omitempty"`
+		State               State                                `json:"state,omitempty"`
+		Traits              Traits                               `json:"traits,omitempty"`
+		MetadataPublic      sqlxx.NullJSONRawMessage             `json:"metadata_public,omitempty"`
+		MetadataAdmin       sqlxx.NullJSONRawMessage             `json:"metadata_admin,omitempty"`
+		OrganizationID      string                               `json:"organization_id,omitempty"`
+		Credentials         map[CredentialsType]auditCredentials `json:"credentials,omitempty"`
+		VerifiableAddresses map[string]auditVerifiableAddress    `json:"verifiable_addresses,omitempty"`
+		RecoveryAddresses   map[string]bool                      `json:"recovery_addresses,omitempty"`
+	}
+	auditCredentials struct {
+		Identifiers []string `json:"identifiers"`
+		Version     int      `json:"version"`
+		Fingerprint string   `json:"fingerprint"`
+	}
+	auditVerifiableAddress struct {
+		Status     VerifiableAddressStatus `json:"status"`
+		Verified   bool                    `json:"verified"`
+		VerifiedAt *sqlxx.NullTime         `json:"verified_at,omitempty"`
+	}
+)
+
+func auditDocumentOf(i *Identity) *auditDocument {
+	if i == nil {
+		return &auditDocument{}
+	}
+
+	doc := &auditDocument{
+		SchemaID:            i.SchemaID,
+		State:               i.State,
+		Traits:              i.Traits,
+		MetadataPublic:      i.MetadataPublic,
+		MetadataAdmin:       i.MetadataAdmin,
+		VerifiableAddresses: auditVerifiableAddresses(i.VerifiableAddresses),
+		RecoveryAddresses:   make(map[string]bool, len(i.RecoveryAddresses)),
+		Credentials:         make(map[CredentialsType]auditCredentials, len(i.Credentials)),
+	}
+	if i.OrganizationID.Valid {
+		doc.OrganizationID = i.OrganizationID.UUID.String()
+	}
+	for _, a := range i.RecoveryAddresses {
+		doc.RecoveryAddresses[string(a.Via)+":"+a.Value] = true
+	}
+	for t, c := range i.Credentials {
+		identifiers := append([]string{}, c.Identifiers...)
+		sort.Strings(identifiers)
+		doc.Credentials[t] = auditCredentials{Identifiers: identifiers, Version: c.Version, Fingerprint: fingerprint(c.Config)}
+	}
+	return doc
+}
+
+func auditVerifiableAddresses(addresses []VerifiableAddress) map[string]auditVerifiableAddress {
+	result := make(map[string]auditVerifiableAddress, len(addresses))
+	for _, a := range addresses {
+		result[string(a.Via)+":"+a.Value] = auditVerifiableAddress{Status: a.Status, Verified: a.Verified, VerifiedAt: a.VerifiedAt}
+	}
+	return result
+}
+
+func fingerprint(v []byte) string {
+	sum := sha256.Sum256(v)
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// auditDiff compares the leaves of both documents. Arrays are compared as a whole, and empty objects are
+// ignored.
+func auditDiff(before, after *auditDocument) (AuditChanges, error) {
+	b, err := auditLeaves(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := auditLeaves(after)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(b)+len(a))
+	for p := range b {
+		paths = append(paths, p)
+	}
+	for p := range a {
+		if _, ok := b[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	changes := AuditChanges{}
+	for _, p := range paths {
+		if bytes.Equal(b[p], a[p]) {
+			continue
+		}
+		changes = append(changes, AuditChange{Path: p, Before: b[p], After: a[p]})
+	}
+	return changes, nil
+}
+
+func auditLeaves(doc *auditDocument) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	leaves := make(map[string]json.RawMessage)
+	if err := collectAuditLeaves(leaves, "", v); err != nil {
+		return nil, err
+	}
+	return leaves, nil
+}
+
+func collectAuditLeaves(leaves map[string]json.RawMessage, path string, v interface{}) error {
+	if o, ok := v.(map[string]interface{}); ok {
+		for k, child := range o {
+			escaped := strings.NewReplacer("~", "~0", "/", "~1").Replace(k)
+			if err := collectAuditLeaves(leaves, path+"/"+escaped, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	leaves[path] = raw
+	return nil
+}
+
+// AuditEventFilter filters audit events. Empty fields are ignored.
+type AuditEventFilter struct {
+	IdentityID    uuid.UUID
+	Action        AuditAction
+	ActorType     AuditActorType
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// ParseAuditEventFilter parses the filter of the audit events from the query parameters.
+func ParseAuditEventFilter(q url.Values) (f AuditEventFilter, err error) {
+	if raw := q.Get("identity_id"); raw != "" {
+		if f.IdentityID, err = uuid.FromString(raw); err != nil {
+			return f, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Could not parse parameter identity_id: %s", err))
+		}
+	}
+
+	f.Action = AuditAction(q.Get("action"))
+	switch f.Action {
+	case "", AuditActionIdentityCreated, AuditActionIdentityUpdated, AuditActionIdentityDeleted, AuditActionVerifiableAddressUpdated:
+	default:
+		return f, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Could not parse parameter action: %s", f.Action))
+	}
+
+	f.ActorType = AuditActorType(q.Get("actor_type"))
+	switch f.ActorType {
+	case "", AuditActorAdminAPI, AuditActorSelfService, AuditActorHook, AuditActorSystem:
+	default:
+		return f, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Could not parse parameter actor_type: %s", f.ActorType))
+	}
+
+	for key, t := range map[string]*time.Time{"created_after": &f.CreatedAfter, "created_before": &f.CreatedBefore} {
+		raw := q.Get(key)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return f, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Could not parse parameter %s, expected an RFC 3339 timestamp: %s", key, err))
+		}
+		*t = parsed.UTC()
+	}
+
+	return f, nil
+}
@@ -0,0 +1,133 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+func TestNewAuditEvent(t *testing.T) {
+	ctx := context.Background()
+
+	newIdentity := func() *identity.Identity {
+		i := identity.NewIdentity("default")
+		i.ID = x.NewUUID()
+		i.Traits = identity.Traits(`{"email":"foo@ory.sh","name":{"first":"Foo"}}`)
+		i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Type:        identity.CredentialsTypePassword,
+			Identifiers: []string{"foo@ory.sh"},
+			Config:      []byte(`{"hashed_password":"secret-hash"}`),
+		})
+		i.VerifiableAddresses = []identity.VerifiableAddress{{Value: "foo@ory.sh", Via: identity.AddressTypeEmail, Status: identity.VerifiableAddressStatusPending}}
+		return i
+	}
+
+	t.Run("case=records the created identity without secrets", func(t *testing.T) {
+		i := newIdentity()
+		event, err := identity.NewAuditEvent(ctx, identity.AuditActionIdentityCreated, nil, i)
+		require.NoError(t, err)
+		require.NotNil(t, event)
+
+		assert.Equal(t, i.ID, event.IdentityID)
+		assert.Equal(t, identity.AuditActorSystem, event.ActorType)
+
+		raw, err := json.Marshal(event.Changes)
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw), "secret-hash")
+		assert.Contains(t, string(raw), `"path":"/traits/name/first","after":"Foo"`)
+		assert.Contains(t, string(raw), `"path":"/credentials/password/fingerprint"`)
+	})
+
+	t.Run("case=records only the changed fields", func(t *testing.T) {
+		before, after := newIdentity(), newIdentity()
+		after.ID = before.ID
+		after.Traits = identity.Traits(`{"email":"foo@ory.sh","name":{"first":"Bar"}}`)
+		after.VerifiableAddresses[0].Status = identity.VerifiableAddressStatusCompleted
+
+		ctx := identity.WithAuditActor(ctx, identity.AuditActor{Type: identity.AuditActorSelfService, ID: "flow-id"})
+		event, err := identity.NewAuditEvent(ctx, identity.AuditActionIdentityUpdated, before, after)
+		require.NoError(t, err)
+		require.NotNil(t, event)
+
+		assert.Equal(t, identity.AuditActorSelfService, event.ActorType)
+		assert.Equal(t, "flow-id", event.ActorID)
+		assert.Equal(t, identity.AuditChanges{
+			{Path: "/traits/name/first", Before: json.RawMessage(`"Foo"`), After: json.RawMessage(`"Bar"`)},
+			{Path: "/verifiable_addresses/email:foo@ory.sh/status", Before: json.RawMessage(`"pending"`), After: json.RawMessage(`"completed"`)},
+		}, event.Changes)
+	})
+
+	t.Run("case=records changed credentials by their fingerprint", func(t *testing.T) {
+		before, after := newIdentity(), newIdentity()
+		after.ID = before.ID
+		after.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Type:        identity.CredentialsTypePassword,
+			Identifiers: []string{"foo@ory.sh"},
+			Config:      []byte(`{"hashed_password":"new-secret-hash"}`),
+		})
+
+		event, err := identity.NewAuditEvent(ctx, identity.AuditActionIdentityUpdated, before, after)
+		require.NoError(t, err)
+		require.NotNil(t, event)
+		require.Len(t, event.Changes, 1)
+		assert.Equal(t, "/credentials/password/fingerprint", event.Changes[0].Path)
+	})
+
+	t.Run("case=records nothing if nothing changed", func(t *testing.T) {
+		before, after := newIdentity(), newIdentity()
+		after.ID = before.ID
+
+		event, err := identity.NewAuditEvent(ctx, identity.AuditActionIdentityUpdated, before, after)
+		require.NoError(t, err)
+		assert.Nil(t, event)
+	})
+}
+
+func TestAdminAuditActor(t *testing.T) {
+	r := &http.Request{Header: http.Header{}}
+	assert.Equal(t, identity.AuditActor{Type: identity.AuditActorAdminAPI}, identity.AdminAuditActor(r))
+
+	r.Header.Set("Authorization", "Bearer ory_pat_secret")
+	actor := identity.AdminAuditActor(r)
+	assert.Equal(t, identity.AuditActorAdminAPI, actor.Type)
+	assert.Regexp(t, "^sha256:[0-9a-f]{16}$", actor.ID)
+	assert.NotContains(t, actor.ID, "ory_pat_secret")
+}
+
+func TestParseAuditEventFilter(t *testing.T) {
+	id := x.NewUUID()
+	f, err := identity.ParseAuditEventFilter(url.Values{
+		"identity_id":   {id.String()},
+		"action":        {"identity.updated"},
+		"actor_type":    {"admin_api"},
+		"created_after": {"2023-01-01T00:00:00Z"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, id, f.IdentityID)
+	assert.Equal(t, identity.AuditActionIdentityUpdated, f.Action)
+	assert.Equal(t, identity.AuditActorAdminAPI, f.ActorType)
+	assert.Equal(t, 2023, f.CreatedAfter.Year())
+	assert.True(t, f.CreatedBefore.IsZero())
+
+	for _, q := range []url.Values{
+		{"identity_id": {"not-a-uuid"}},
+		{"action": {"identity.read"}},
+		{"actor_type": {"unknown"}},
+		{"created_before": {"yesterday"}},
+	} {
+		_, err := identity.ParseAuditEventFilter(q)
+		assert.ErrorIs(t, err, herodot.ErrBadRequest, "%v", q)
+	}
+}
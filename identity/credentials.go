@@ -96,6 +96,10 @@ func (c CredentialsType) ToUiNodeGroup() node.UiNodeGroup {
 		return node.LookupGroup
 	case CredentialsTypeCodeAuth:
 		return node.CodeGroup
+	case CredentialsTypePush:
+		return node.PushGroup
+	case CredentialsTypeExternalMFA:
+		return node.ExternalMFAGroup
 	default:
 		return node.DefaultGroup
 	}
@@ -103,12 +107,14 @@ func (c CredentialsType) ToUiNodeGroup() node.UiNodeGroup {
 
 // Please make sure to add all of these values to the test that ensures they are created during migration
 const (
-	CredentialsTypePassword CredentialsType = "password"
-	CredentialsTypeOIDC     CredentialsType = "oidc"
-	CredentialsTypeTOTP     CredentialsType = "totp"
-	CredentialsTypeLookup   CredentialsType = "lookup_secret"
-	CredentialsTypeWebAuthn CredentialsType = "webauthn"
-	CredentialsTypeCodeAuth CredentialsType = "code"
+	CredentialsTypePassword    CredentialsType = "password"
+	CredentialsTypeOIDC        CredentialsType = "oidc"
+	CredentialsTypeTOTP        CredentialsType = "totp"
+	CredentialsTypeLookup      CredentialsType = "lookup_secret"
+	CredentialsTypeWebAuthn    CredentialsType = "webauthn"
+	CredentialsTypeCodeAuth    CredentialsType = "code"
+	CredentialsTypePush        CredentialsType = "push"
+	CredentialsTypeExternalMFA CredentialsType = "external_mfa"
 )
 
 var AllCredentialTypes = []CredentialsType{
@@ -118,6 +124,8 @@ var AllCredentialTypes = []CredentialsType{
 	CredentialsTypeLookup,
 	CredentialsTypeWebAuthn,
 	CredentialsTypeCodeAuth,
+	CredentialsTypePush,
+	CredentialsTypeExternalMFA,
 }
 
 const (
@@ -125,6 +133,14 @@ const (
 	// It is not used within the credentials object itself.
 	CredentialsTypeRecoveryLink CredentialsType = "link_recovery"
 	CredentialsTypeRecoveryCode CredentialsType = "code_recovery"
+
+	// CredentialsTypeTrustedDevice is a special credential type which is used in the authentication methods of a
+	// session if the second factor was skipped because the login happened on a trusted device.
+	CredentialsTypeTrustedDevice CredentialsType = "trusted_device"
+
+	// CredentialsTypeIdentifierFirst is a special credential type of the login method which asks for the
+	// identifier first and routes the user to the methods of their organization. It never completes a login.
+	CredentialsTypeIdentifierFirst CredentialsType = "identifier_first"
 )
 
 // ParseCredentialsType parses a string into a CredentialsType or returns false as the second argument.
@@ -136,8 +152,12 @@ func ParseCredentialsType(in string) (CredentialsType, bool) {
 		CredentialsTypeLookup,
 		CredentialsTypeWebAuthn,
 		CredentialsTypeCodeAuth,
+		CredentialsTypePush,
+		CredentialsTypeExternalMFA,
 		CredentialsTypeRecoveryLink,
 		CredentialsTypeRecoveryCode,
+		CredentialsTypeTrustedDevice,
+		CredentialsTypeIdentifierFirst,
 	} {
 		if t.String() == in {
 			return t, true
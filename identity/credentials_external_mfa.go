@@ -0,0 +1,18 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import "time"
+
+// CredentialsExternalMFAConfig is the struct that is being used as part of the identity credentials.
+//
+// The credentials are created when the external MFA provider confirmed a sign-in of the identity
+// for the first time, as enrollment happens at the provider.
+type CredentialsExternalMFAConfig struct {
+	// EnrolledAt is the time of the first sign-in confirmed by the external MFA provider.
+	EnrolledAt time.Time `json:"enrolled_at"`
+
+	// LastUsedAt is the time of the last sign-in confirmed by the external MFA provider.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
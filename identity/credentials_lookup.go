@@ -35,6 +35,16 @@ func (c *CredentialsLookupConfig) ToNode() *node.Node {
 		WithMetaLabel(text.NewInfoSelfServiceSettingsLookupSecretsLabel())
 }
 
+// RemainingCodes returns the number of recovery codes which were not used yet.
+func (c *CredentialsLookupConfig) RemainingCodes() (remaining int) {
+	for _, code := range c.RecoveryCodes {
+		if time.Time(code.UsedAt).IsZero() {
+			remaining++
+		}
+	}
+	return remaining
+}
+
 type RecoveryCode struct {
 	// A recovery code
 	Code string `json:"code"`
@@ -8,6 +8,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal/testhelpers"
 
@@ -24,3 +26,13 @@ func TestToNode(t *testing.T) {
 
 	testhelpers.SnapshotTExcept(t, c.ToNode(), []string{})
 }
+
+func TestRemainingCodes(t *testing.T) {
+	c := identity.CredentialsLookupConfig{RecoveryCodes: []identity.RecoveryCode{
+		{Code: "foo", UsedAt: sqlxx.NullTime(time.Unix(1629199958, 0).UTC())},
+		{Code: "bar"},
+		{Code: "baz"},
+	}}
+	assert.Equal(t, 2, c.RemainingCodes())
+	assert.Equal(t, 0, new(identity.CredentialsLookupConfig).RemainingCodes())
+}
@@ -7,6 +7,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -30,6 +31,9 @@ type CredentialsOIDCProvider struct {
 	InitialAccessToken  string `json:"initial_access_token"`
 	InitialRefreshToken string `json:"initial_refresh_token"`
 	Organization        string `json:"organization,omitempty"`
+
+	// AccessTokenExpiresAt is the time at which the stored access token expires, if the provider returned it.
+	AccessTokenExpiresAt *time.Time `json:"access_token_expires_at,omitempty"`
 }
 
 // NewCredentialsOIDC creates a new OIDC credential.
@@ -0,0 +1,38 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// CredentialsPushConfig is the struct that is being used as part of the identity credentials.
+type CredentialsPushConfig struct {
+	// List of devices which receive push approval requests.
+	Devices CredentialsPushDevices `json:"devices"`
+}
+
+type CredentialsPushDevices []CredentialPushDevice
+
+// Find returns the device with the given ID.
+func (d CredentialsPushDevices) Find(id string) (*CredentialPushDevice, bool) {
+	for k := range d {
+		if d[k].ID.String() == id {
+			return &d[k], true
+		}
+	}
+	return nil, false
+}
+
+// CredentialPushDevice is a mobile device registered to approve sign-in requests.
+type CredentialPushDevice struct {
+	ID          uuid.UUID  `json:"id"`
+	DisplayName string     `json:"display_name"`
+	Platform    string     `json:"platform,omitempty"`
+	Token       string     `json:"token"`
+	AddedAt     time.Time  `json:"added_at"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+}
@@ -4,9 +4,14 @@
 package identity
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofrs/uuid"
 )
 
 // CredentialsConfig is the struct that is being used as part of the identity credentials.
@@ -16,6 +21,35 @@ type CredentialsWebAuthnConfig struct {
 	UserHandle  []byte              `json:"user_handle"`
 }
 
+// UserHandles returns the user handles the credentials were registered with. Credentials registered before the
+// identity's current user handle keep their own.
+func (c *CredentialsWebAuthnConfig) UserHandles() [][]byte {
+	var handles [][]byte
+	for _, handle := range append([][]byte{c.UserHandle}, c.Credentials.userHandles()...) {
+		if len(handle) > 0 && !slices.ContainsFunc(handles, func(h []byte) bool { return bytes.Equal(h, handle) }) {
+			handles = append(handles, handle)
+		}
+	}
+	return handles
+}
+
+// WebAuthnUserHandle indexes a user handle of an identity's WebAuthn credentials. Passkeys only present their user
+// handle when signing in, which is resolved to the identity through this index.
+type WebAuthnUserHandle struct {
+	ID                    uuid.UUID `db:"id"`
+	NID                   uuid.UUID `db:"nid"`
+	IdentityID            uuid.UUID `db:"identity_id"`
+	IdentityCredentialsID uuid.UUID `db:"identity_credential_id"`
+	// UserHandle is the standard base64 encoded user handle, like in the credentials' configuration.
+	UserHandle string    `db:"user_handle"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+func (WebAuthnUserHandle) TableName(context.Context) string {
+	return "identity_webauthn_user_handles"
+}
+
 type CredentialsWebAuthn []CredentialWebAuthn
 
 func CredentialFromWebAuthn(credential *webauthn.Credential, isPasswordless bool) *CredentialWebAuthn {
@@ -32,6 +66,13 @@ func CredentialFromWebAuthn(credential *webauthn.Credential, isPasswordless bool
 	}
 }
 
+func (c CredentialsWebAuthn) userHandles() (handles [][]byte) {
+	for k := range c {
+		handles = append(handles, c[k].UserHandle)
+	}
+	return handles
+}
+
 func (c CredentialsWebAuthn) ToWebAuthn() (result []webauthn.Credential) {
 	for k := range c {
 		result = append(result, *c[k].ToWebAuthn())
@@ -65,6 +106,27 @@ func (c *CredentialWebAuthn) ToWebAuthn() *webauthn.Credential {
 	}
 }
 
+// Find returns the credential with the given hex encoded ID.
+func (c CredentialsWebAuthn) Find(hexID string) (*CredentialWebAuthn, bool) {
+	for k := range c {
+		if c[k].HexID() == hexID {
+			return &c[k], true
+		}
+	}
+	return nil, false
+}
+
+// Remove removes the credential with the given hex encoded ID and returns it.
+func (c *CredentialsWebAuthnConfig) Remove(hexID string) (*CredentialWebAuthn, bool) {
+	for k := range c.Credentials {
+		if removed := c.Credentials[k]; removed.HexID() == hexID {
+			c.Credentials = append(c.Credentials[:k], c.Credentials[k+1:]...)
+			return &removed, true
+		}
+	}
+	return nil, false
+}
+
 type CredentialWebAuthn struct {
 	ID              []byte                `json:"id"`
 	PublicKey       []byte                `json:"public_key"`
@@ -72,7 +134,55 @@ type CredentialWebAuthn struct {
 	Authenticator   AuthenticatorWebAuthn `json:"authenticator"`
 	DisplayName     string                `json:"display_name"`
 	AddedAt         time.Time             `json:"added_at"`
+	LastUsedAt      *time.Time            `json:"last_used_at,omitempty"`
 	IsPasswordless  bool                  `json:"is_passwordless"`
+	// UserHandle is the user handle the credential was registered with. It is empty for credentials which were
+	// registered with the user handle of the credentials' configuration.
+	UserHandle []byte `json:"user_handle,omitempty"`
+}
+
+// HexID returns the credential ID in the encoding used by the settings flow and the admin API.
+func (c *CredentialWebAuthn) HexID() string {
+	return fmt.Sprintf("%x", c.ID)
+}
+
+// Summary returns the parts of the credential which may be shown to the identity or an administrator.
+func (c *CredentialWebAuthn) Summary() WebAuthnCredentialSummary {
+	return WebAuthnCredentialSummary{
+		ID:             c.HexID(),
+		DisplayName:    c.DisplayName,
+		AddedAt:        c.AddedAt,
+		LastUsedAt:     c.LastUsedAt,
+		IsPasswordless: c.IsPasswordless,
+	}
+}
+
+// A WebAuthn Credential
+//
+// swagger:model identityWebAuthnCredential
+type WebAuthnCredentialSummary struct {
+	// The credential's ID, hex encoded.
+	//
+	// required: true
+	ID string `json:"id"`
+
+	// The name the identity gave the credential.
+	//
+	// required: true
+	DisplayName string `json:"display_name"`
+
+	// When the credential was added.
+	//
+	// required: true
+	AddedAt time.Time `json:"added_at"`
+
+	// When the credential was last used to sign in.
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	// Whether the credential can be used to sign in without a password.
+	//
+	// required: true
+	IsPasswordless bool `json:"is_passwordless"`
 }
 
 type AuthenticatorWebAuthn struct {
@@ -0,0 +1,112 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/x/jsonx"
+)
+
+// EmailDomainAllowed returns true if the domain of the email address is not denied and, if an allow
+// list is given, allowed. Domains are either exact (`example.com`) or wildcards matching all
+// subdomains (`*.example.com`).
+func EmailDomainAllowed(email string, allow, deny []string) bool {
+	_, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok {
+		return true
+	}
+
+	if emailDomainMatches(domain, deny) {
+		return false
+	}
+
+	return len(allow) == 0 || emailDomainMatches(domain, allow)
+}
+
+func emailDomainMatches(domain string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(domain, "."+suffix) {
+				return true
+			}
+		} else if domain == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRegistrationEmailDomains checks the email addresses of a validated identity against the
+// email domain allow and deny lists of the registration flow. The error points to the trait which
+// holds the rejected address.
+func (v *Validator) ValidateRegistrationEmailDomains(ctx context.Context, i *Identity) error {
+	allow := v.d.Config().SelfServiceFlowRegistrationEmailDomainsAllow(ctx)
+	deny := v.d.Config().SelfServiceFlowRegistrationEmailDomainsDeny(ctx)
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil
+	}
+
+	for _, address := range i.EmailAddresses() {
+		if EmailDomainAllowed(address, allow, deny) {
+			continue
+		}
+
+		_, domain, _ := strings.Cut(address, "@")
+		return schema.NewEmailDomainNotAllowedError(i.TraitPointer(address), domain)
+	}
+
+	return nil
+}
+
+// EmailAddresses returns the lower-cased email addresses of the identity's verifiable and recovery
+// addresses and credential identifiers. The identity must have been validated for them to be known.
+func (i *Identity) EmailAddresses() []string {
+	var addresses []string
+	add := func(address string) {
+		address = strings.ToLower(strings.TrimSpace(address))
+		for _, a := range addresses {
+			if a == address {
+				return
+			}
+		}
+		addresses = append(addresses, address)
+	}
+
+	for _, a := range i.VerifiableAddresses {
+		if a.Via == VerifiableAddressTypeEmail {
+			add(a.Value)
+		}
+	}
+	for _, a := range i.RecoveryAddresses {
+		if a.Via == RecoveryAddressTypeEmail {
+			add(a.Value)
+		}
+	}
+	for _, c := range i.Credentials {
+		for _, identifier := range c.Identifiers {
+			if jsonschema.Formats["email"](identifier) {
+				add(identifier)
+			}
+		}
+	}
+
+	return addresses
+}
+
+// TraitPointer returns the JSON pointer of the trait holding the value, for example to show a
+// validation error on the trait's UI node. It returns the pointer of all traits if none matches.
+func (i *Identity) TraitPointer(value string) string {
+	for path, v := range jsonx.Flatten(json.RawMessage(i.Traits)) {
+		if s, ok := v.(string); ok && strings.EqualFold(strings.TrimSpace(s), strings.TrimSpace(value)) {
+			return "#/traits/" + strings.ReplaceAll(path, ".", "/")
+		}
+	}
+	return "#/traits"
+}
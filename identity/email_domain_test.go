@@ -0,0 +1,73 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	. "github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/schema"
+)
+
+func TestEmailDomainAllowed(t *testing.T) {
+	for k, tc := range []struct {
+		email       string
+		allow, deny []string
+		expected    bool
+	}{
+		{email: "foo@example.com", expected: true},
+		{email: "foo@example.com", allow: []string{"example.com"}, expected: true},
+		{email: "foo@Example.COM", allow: []string{"example.com"}, expected: true},
+		{email: "foo@example.org", allow: []string{"example.com"}, expected: false},
+		{email: "foo@sub.example.com", allow: []string{"example.com"}, expected: false},
+		{email: "foo@sub.example.com", allow: []string{"*.example.com"}, expected: true},
+		{email: "foo@example.com", allow: []string{"*.example.com"}, expected: false},
+		{email: "foo@badexample.com", allow: []string{"*.example.com"}, expected: false},
+		{email: "foo@example.com", deny: []string{"example.com"}, expected: false},
+		{email: "foo@example.org", deny: []string{"example.com"}, expected: true},
+		{email: "foo@spam.example.com", allow: []string{"*.example.com"}, deny: []string{"spam.example.com"}, expected: false},
+		{email: "not-an-email", allow: []string{"example.com"}, expected: true},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			assert.Equal(t, tc.expected, EmailDomainAllowed(tc.email, tc.allow, tc.deny))
+		})
+	}
+}
+
+func TestValidateRegistrationEmailDomains(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	v := NewValidator(reg)
+
+	newIdentity := func(email string) *Identity {
+		i := NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = Traits(fmt.Sprintf(`{"emails":[%q]}`, email))
+		i.VerifiableAddresses = []VerifiableAddress{*NewVerifiableEmailAddress(email, i.ID)}
+		return i
+	}
+
+	require.NoError(t, v.ValidateRegistrationEmailDomains(ctx, newIdentity("foo@example.org")))
+
+	conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationEmailDomainsAllow, []string{"example.com"})
+	t.Cleanup(func() {
+		conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationEmailDomainsAllow, nil)
+	})
+
+	require.NoError(t, v.ValidateRegistrationEmailDomains(ctx, newIdentity("foo@example.com")))
+
+	err := v.ValidateRegistrationEmailDomains(ctx, newIdentity("foo@example.org"))
+	var e *schema.ValidationError
+	require.ErrorAs(t, err, &e)
+	assert.Equal(t, "#/traits/emails/0", e.InstancePtr)
+
+	raw, err := json.Marshal(e.Messages)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), "example.org")
+}
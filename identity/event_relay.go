@@ -0,0 +1,181 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/stringslice"
+)
+
+// eventRelayLease is the time for which a claimed lifecycle event is not claimed again. Events which are neither
+// delivered nor marked as failed within the lease, for example because the process crashed, are delivered again.
+const eventRelayLease = 5 * time.Minute
+
+// eventRelayMaxBackoff caps the time between two delivery attempts of a lifecycle event.
+const eventRelayMaxBackoff = time.Hour
+
+type (
+	EventRelayProvider interface {
+		IdentityEventRelay() *EventRelay
+	}
+	eventRelayDependencies interface {
+		config.Provider
+		PrivilegedPoolProvider
+		x.HTTPClientProvider
+		x.LoggingProvider
+		x.TracingProvider
+	}
+
+	// EventRelay delivers the identity lifecycle events from the outbox to the sinks configured in
+	// `identity.events.sinks`.
+	//
+	// Events are delivered at least once: an event is retried with an exponential backoff until every sink
+	// accepted it or `identity.events.delivery.max_attempts` is reached, so sinks may receive an event more
+	// than once and should deduplicate by the event ID.
+	EventRelay struct {
+		d eventRelayDependencies
+	}
+
+	// EventRelayReport summarizes a single run of the EventRelay.
+	EventRelayReport struct {
+		EventsDelivered int
+		EventsFailed    int
+	}
+)
+
+func NewEventRelay(d eventRelayDependencies) *EventRelay {
+	return &EventRelay{d: d}
+}
+
+// Watch runs the relay in the configured interval until the context is canceled.
+func (r *EventRelay) Watch(ctx context.Context, batchSize int) {
+	for {
+		if report, err := r.Run(ctx, batchSize); err != nil {
+			r.d.Logger().WithError(err).Warn("Unable to deliver identity lifecycle events.")
+		} else if report.EventsDelivered > 0 || report.EventsFailed > 0 {
+			r.d.Logger().
+				WithField("events_delivered", report.EventsDelivered).
+				WithField("events_failed", report.EventsFailed).
+				Info("Delivered identity lifecycle events.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.d.Config().IdentityEventsDeliveryInterval(ctx)):
+		}
+	}
+}
+
+// Run delivers all due lifecycle events in batches of batchSize.
+func (r *EventRelay) Run(ctx context.Context, batchSize int) (_ *EventRelayReport, err error) {
+	ctx, span := r.d.Tracer(ctx).Tracer().Start(ctx, "identity.EventRelay.Run")
+	defer otelx.End(span, &err)
+
+	report := new(EventRelayReport)
+
+	sinks, err := r.d.Config().IdentityEventSinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAttempts := r.d.Config().IdentityEventsDeliveryMaxAttempts(ctx)
+	client := r.d.HTTPClient(ctx)
+	for {
+		events, err := r.d.PrivilegedIdentityPool().ClaimLifecycleEvents(ctx, batchSize, maxAttempts, eventRelayLease)
+		if err != nil {
+			return nil, err
+		}
+
+		for k := range events {
+			event := &events[k]
+			if err := r.deliver(ctx, client, sinks, event); err != nil {
+				r.d.Logger().
+					WithError(err).
+					WithField("event_id", event.ID).
+					WithField("event_type", event.Type).
+					WithField("attempts", event.Attempts+1).
+					Warn("Unable to deliver identity lifecycle event.")
+				report.EventsFailed++
+
+				next := time.Now().UTC().Add(r.backoff(ctx, event.Attempts))
+				if err := r.d.PrivilegedIdentityPool().MarkLifecycleEventFailed(ctx, event.ID, next, err.Error()); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			if err := r.d.PrivilegedIdentityPool().MarkLifecycleEventDelivered(ctx, event.ID); err != nil {
+				return nil, err
+			}
+			report.EventsDelivered++
+		}
+
+		if len(events) < batchSize {
+			return report, nil
+		}
+	}
+}
+
+// backoff returns the time to wait before the next delivery attempt of an event which failed attempts times
+// before.
+func (r *EventRelay) backoff(ctx context.Context, attempts int) time.Duration {
+	backoff := r.d.Config().IdentityEventsDeliveryInterval(ctx)
+	for i := 0; i < attempts && backoff < eventRelayMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > eventRelayMaxBackoff {
+		return eventRelayMaxBackoff
+	}
+	return backoff
+}
+
+// deliver sends the event to every sink subscribed to its type. It fails if any of the sinks does not accept it.
+func (r *EventRelay) deliver(ctx context.Context, client *retryablehttp.Client, sinks []config.IdentityEventSink, event *LifecycleEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, sink := range sinks {
+		if len(sink.Events) > 0 && !stringslice.Has(sink.Events, string(event.Type)) {
+			continue
+		}
+
+		req, err := retryablehttp.NewRequestWithContext(ctx, http.MethodPost, sink.URL, bytes.NewReader(body))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range sink.Headers {
+			req.Header.Set(k, v)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		_, _ = io.Copy(io.Discard, res.Body)
+		_ = res.Body.Close()
+
+		if res.StatusCode < 200 || res.StatusCode >= 300 {
+			return errors.WithStack(fmt.Errorf("sink %s responded with status code %d", sink.URL, res.StatusCode))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+)
+
+func TestEventRelay(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/identity.schema.json")
+
+	var (
+		lock     sync.Mutex
+		received []identity.LifecycleEvent
+		status   = http.StatusOK
+	)
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		assert.Equal(t, "secret", r.Header.Get("Authorization"))
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		var event identity.LifecycleEvent
+		assert.NoError(t, json.Unmarshal(body, &event))
+		received = append(received, event)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(sink.Close)
+
+	setSinks := func(t *testing.T, events ...string) {
+		conf.MustSet(ctx, config.ViperKeyIdentityEventsEnabled, true)
+		s := map[string]interface{}{
+			"url":     sink.URL,
+			"headers": map[string]string{"Authorization": "secret"},
+		}
+		if len(events) > 0 {
+			s["events"] = events
+		}
+		conf.MustSet(ctx, config.ViperKeyIdentityEventSinks, []map[string]interface{}{s})
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeyIdentityEventsEnabled, false)
+			conf.MustSet(ctx, config.ViperKeyIdentityEventSinks, nil)
+		})
+	}
+	reset := func(s int) {
+		lock.Lock()
+		defer lock.Unlock()
+		received, status = nil, s
+	}
+
+	t.Run("case=does not record events if disabled", func(t *testing.T) {
+		reset(http.StatusOK)
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, identity.NewIdentity("")))
+
+		setSinks(t)
+		report, err := reg.IdentityEventRelay().Run(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.EventsDelivered)
+		assert.Empty(t, received)
+	})
+
+	t.Run("case=delivers each event once", func(t *testing.T) {
+		reset(http.StatusOK)
+		setSinks(t)
+
+		i := identity.NewIdentity("")
+		i.Traits = identity.Traits(`{"email":"relay@ory.sh"}`)
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+		i.Traits = identity.Traits(`{"email":"relay-updated@ory.sh"}`)
+		require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentity(ctx, i))
+		require.NoError(t, reg.PrivilegedIdentityPool().DeleteIdentity(ctx, i.ID))
+
+		report, err := reg.IdentityEventRelay().Run(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 3, report.EventsDelivered)
+
+		require.Len(t, received, 3)
+		var types []identity.LifecycleEventType
+		for _, e := range received {
+			assert.Equal(t, i.ID, e.IdentityID)
+			types = append(types, e.Type)
+		}
+		assert.ElementsMatch(t, []identity.LifecycleEventType{
+			identity.LifecycleEventIdentityCreated,
+			identity.LifecycleEventTraitsUpdated,
+			identity.LifecycleEventIdentityDeleted,
+		}, types)
+
+		report, err = reg.IdentityEventRelay().Run(ctx, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.EventsDelivered)
+		assert.Len(t, received, 3)
+	})
+
+	t.Run("case=only delivers subscribed events", func(t *testing.T) {
+		reset(http.StatusOK)
+		setSinks(t, string(identity.LifecycleEventIdentityDeleted))
+
+		i := identity.NewIdentity("")
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+		require.NoError(t, reg.PrivilegedIdentityPool().DeleteIdentity(ctx, i.ID))
+
+		report, err := reg.IdentityEventRelay().Run(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 2, report.EventsDelivered)
+
+		require.Len(t, received, 1)
+		assert.Equal(t, identity.LifecycleEventIdentityDeleted, received[0].Type)
+	})
+
+	t.Run("case=retries events the sink rejected", func(t *testing.T) {
+		reset(http.StatusBadRequest)
+		setSinks(t)
+
+		i := identity.NewIdentity("")
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+
+		report, err := reg.IdentityEventRelay().Run(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.EventsDelivered)
+		assert.Equal(t, 1, report.EventsFailed)
+		require.Len(t, received, 1)
+
+		// The next attempt is delayed by the backoff.
+		report, err = reg.IdentityEventRelay().Run(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.EventsFailed)
+
+		events, err := reg.PrivilegedIdentityPool().ClaimLifecycleEvents(ctx, 10, 1, 0)
+		require.NoError(t, err)
+		assert.Empty(t, events, "events are given up after the maximum number of attempts")
+	})
+}
@@ -0,0 +1,204 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlxx"
+)
+
+// LifecycleEventType is the type of an identity lifecycle event.
+type LifecycleEventType string
+
+const (
+	LifecycleEventIdentityCreated   LifecycleEventType = "identity.created"
+	LifecycleEventTraitsUpdated     LifecycleEventType = "identity.traits_updated"
+	LifecycleEventCredentialAdded   LifecycleEventType = "identity.credential_added"
+	LifecycleEventCredentialRemoved LifecycleEventType = "identity.credential_removed"
+	LifecycleEventAddressVerified   LifecycleEventType = "identity.address_verified"
+	LifecycleEventIdentityDeleted   LifecycleEventType = "identity.deleted"
+)
+
+// LifecycleEvent is an identity lifecycle event. It is stored in the outbox together with the change of the
+// identity and is delivered to the configured sinks at least once.
+type LifecycleEvent struct {
+	// ID identifies the event. Sinks can use it to ignore events which were delivered more than once.
+	ID uuid.UUID `json:"id" db:"id"`
+
+	// IdentityID is the ID of the identity the event belongs to.
+	IdentityID uuid.UUID `json:"identity_id" db:"identity_id"`
+
+	// Type is the type of the event, for example `identity.created`.
+	Type LifecycleEventType `json:"type" db:"type"`
+
+	// Data contains the details of the event, depending on its type.
+	Data sqlxx.JSONRawMessage `json:"data" db:"data"`
+
+	// CreatedAt is the time of the change.
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	// Attempts is the number of failed delivery attempts.
+	Attempts int `json:"-" db:"attempts"`
+
+	// NextAttemptAt is the time at which the event is delivered next.
+	NextAttemptAt time.Time `json:"-" db:"next_attempt_at"`
+
+	// DeliveredAt is the time at which the event was delivered to all sinks.
+	DeliveredAt sqlxx.NullTime `json:"-" db:"delivered_at"`
+
+	// LastError describes why the last delivery attempt failed.
+	LastError string `json:"-" db:"last_error"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (LifecycleEvent) TableName(context.Context) string {
+	return "identity_event_outbox"
+}
+
+type (
+	lifecycleIdentityData struct {
+		SchemaID       string                   `json:"schema_id"`
+		State          State                    `json:"state"`
+		Traits         Traits                   `json:"traits"`
+		MetadataPublic sqlxx.NullJSONRawMessage `json:"metadata_public,omitempty"`
+	}
+	lifecycleCredentialData struct {
+		CredentialsType CredentialsType `json:"credentials_type"`
+	}
+	lifecycleAddressData struct {
+		Via   VerifiableAddressType `json:"via"`
+		Value string                `json:"value"`
+	}
+)
+
+// NewLifecycleEvents returns the lifecycle events of a change to an identity. The identity was created if before
+// is nil and deleted if after is nil.
+func NewLifecycleEvents(before, after *Identity) ([]*LifecycleEvent, error) {
+	switch {
+	case before == nil:
+		e, err := newLifecycleEvent(after.ID, LifecycleEventIdentityCreated, lifecycleIdentityDataOf(after))
+		return []*LifecycleEvent{e}, err
+	case after == nil:
+		e, err := newLifecycleEvent(before.ID, LifecycleEventIdentityDeleted, struct{}{})
+		return []*LifecycleEvent{e}, err
+	}
+
+	var events []*LifecycleEvent
+	add := func(t LifecycleEventType, data interface{}) error {
+		e, err := newLifecycleEvent(after.ID, t, data)
+		if err != nil {
+			return err
+		}
+		events = append(events, e)
+		return nil
+	}
+
+	changed, err := traitsChanged(before.Traits, after.Traits)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if err := add(LifecycleEventTraitsUpdated, lifecycleIdentityDataOf(after)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, t := range sortedCredentialTypes(after.Credentials) {
+		if _, ok := before.Credentials[t]; !ok {
+			if err := add(LifecycleEventCredentialAdded, lifecycleCredentialData{CredentialsType: t}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, t := range sortedCredentialTypes(before.Credentials) {
+		if _, ok := after.Credentials[t]; !ok {
+			if err := add(LifecycleEventCredentialRemoved, lifecycleCredentialData{CredentialsType: t}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	verified := make(map[string]bool, len(before.VerifiableAddresses))
+	for _, a := range before.VerifiableAddresses {
+		verified[string(a.Via)+":"+a.Value] = a.Verified
+	}
+	for k := range after.VerifiableAddresses {
+		a := &after.VerifiableAddresses[k]
+		if a.Verified && !verified[string(a.Via)+":"+a.Value] {
+			if err := add(LifecycleEventAddressVerified, lifecycleAddressData{Via: a.Via, Value: a.Value}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// NewAddressVerifiedLifecycleEvent returns the lifecycle event of a verifiable address, or nil if the address was
+// not verified by the change.
+func NewAddressVerifiedLifecycleEvent(before, after *VerifiableAddress) (*LifecycleEvent, error) {
+	if before.Verified || !after.Verified {
+		return nil, nil
+	}
+	return newLifecycleEvent(after.IdentityID, LifecycleEventAddressVerified, lifecycleAddressData{Via: after.Via, Value: after.Value})
+}
+
+func newLifecycleEvent(identityID uuid.UUID, t LifecycleEventType, data interface{}) (*LifecycleEvent, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	now := time.Now().UTC()
+	return &LifecycleEvent{
+		ID:            uuid.Must(uuid.NewV4()),
+		IdentityID:    identityID,
+		Type:          t,
+		Data:          raw,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}, nil
+}
+
+func lifecycleIdentityDataOf(i *Identity) lifecycleIdentityData {
+	return lifecycleIdentityData{SchemaID: i.SchemaID, State: i.State, Traits: i.Traits, MetadataPublic: i.MetadataPublic}
+}
+
+func traitsChanged(before, after Traits) (bool, error) {
+	var b, a interface{}
+	if err := json.Unmarshal(orEmptyObject(before), &b); err != nil {
+		return false, errors.WithStack(err)
+	}
+	if err := json.Unmarshal(orEmptyObject(after), &a); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	rb, _ := json.Marshal(b)
+	ra, _ := json.Marshal(a)
+	return string(rb) != string(ra), nil
+}
+
+func orEmptyObject(t Traits) []byte {
+	if len(t) == 0 {
+		return []byte("{}")
+	}
+	return t
+}
+
+func sortedCredentialTypes(cs map[CredentialsType]Credentials) []CredentialsType {
+	types := make([]CredentialsType, 0, len(cs))
+	for t := range cs {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
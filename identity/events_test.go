@@ -0,0 +1,101 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+func TestNewLifecycleEvents(t *testing.T) {
+	newIdentity := func() *identity.Identity {
+		i := identity.NewIdentity("default")
+		i.ID = x.NewUUID()
+		i.Traits = identity.Traits(`{"email":"foo@ory.sh"}`)
+		i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Type:        identity.CredentialsTypePassword,
+			Identifiers: []string{"foo@ory.sh"},
+			Config:      []byte(`{"hashed_password":"secret-hash"}`),
+		})
+		i.VerifiableAddresses = []identity.VerifiableAddress{{Value: "foo@ory.sh", Via: identity.AddressTypeEmail, Status: identity.VerifiableAddressStatusPending}}
+		return i
+	}
+	types := func(events []*identity.LifecycleEvent) (types []identity.LifecycleEventType) {
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		return types
+	}
+
+	t.Run("case=emits created and deleted events", func(t *testing.T) {
+		i := newIdentity()
+
+		events, err := identity.NewLifecycleEvents(nil, i)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, identity.LifecycleEventIdentityCreated, events[0].Type)
+		assert.Equal(t, i.ID, events[0].IdentityID)
+		assert.JSONEq(t, `{"schema_id":"default","state":"active","traits":{"email":"foo@ory.sh"}}`, string(events[0].Data))
+		assert.NotContains(t, string(events[0].Data), "secret-hash")
+
+		events, err = identity.NewLifecycleEvents(i, nil)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, identity.LifecycleEventIdentityDeleted, events[0].Type)
+		assert.Equal(t, i.ID, events[0].IdentityID)
+	})
+
+	t.Run("case=emits an event per change", func(t *testing.T) {
+		before, after := newIdentity(), newIdentity()
+		after.ID = before.ID
+		after.Traits = identity.Traits(`{"email":"bar@ory.sh"}`)
+		after.VerifiableAddresses[0].Verified = true
+		delete(after.Credentials, identity.CredentialsTypePassword)
+		after.SetCredentials(identity.CredentialsTypeTOTP, identity.Credentials{Type: identity.CredentialsTypeTOTP, Identifiers: []string{before.ID.String()}})
+
+		events, err := identity.NewLifecycleEvents(before, after)
+		require.NoError(t, err)
+		assert.Equal(t, []identity.LifecycleEventType{
+			identity.LifecycleEventTraitsUpdated,
+			identity.LifecycleEventCredentialAdded,
+			identity.LifecycleEventCredentialRemoved,
+			identity.LifecycleEventAddressVerified,
+		}, types(events))
+		assert.JSONEq(t, `{"credentials_type":"totp"}`, string(events[1].Data))
+		assert.JSONEq(t, `{"credentials_type":"password"}`, string(events[2].Data))
+		assert.JSONEq(t, `{"via":"email","value":"foo@ory.sh"}`, string(events[3].Data))
+	})
+
+	t.Run("case=emits nothing if nothing relevant changed", func(t *testing.T) {
+		before, after := newIdentity(), newIdentity()
+		after.ID = before.ID
+		after.Traits = identity.Traits(`{ "email": "foo@ory.sh" }`)
+		after.VerifiableAddresses[0].Status = identity.VerifiableAddressStatusSent
+
+		events, err := identity.NewLifecycleEvents(before, after)
+		require.NoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("case=emits address verified events for verifiable addresses", func(t *testing.T) {
+		before := &identity.VerifiableAddress{IdentityID: x.NewUUID(), Value: "foo@ory.sh", Via: identity.AddressTypeEmail}
+		after := *before
+		after.Verified = true
+
+		event, err := identity.NewAddressVerifiedLifecycleEvent(before, &after)
+		require.NoError(t, err)
+		require.NotNil(t, event)
+		assert.Equal(t, identity.LifecycleEventAddressVerified, event.Type)
+		assert.Equal(t, before.IdentityID, event.IdentityID)
+
+		event, err = identity.NewAddressVerifiedLifecycleEvent(&after, &after)
+		require.NoError(t, err)
+		assert.Nil(t, event)
+	})
+}
@@ -0,0 +1,93 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+	"golang.org/x/text/language"
+
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/schema"
+)
+
+// SchemaExtensionLocale extracts the preferred locale of an identity from
+// the trait annotated with `"ory.sh/kratos": {"locale": true}`.
+type SchemaExtensionLocale struct {
+	l sync.Mutex
+	v string
+}
+
+func NewSchemaExtensionLocale() *SchemaExtensionLocale {
+	return &SchemaExtensionLocale{}
+}
+
+func (r *SchemaExtensionLocale) Run(ctx jsonschema.ValidationContext, s schema.ExtensionConfig, value interface{}) error {
+	if !s.Locale {
+		return nil
+	}
+
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	raw, ok := value.(string)
+	if !ok {
+		return ctx.Error("type", "expected locale to be a string but got %T", value)
+	}
+
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+
+	tag, err := language.Parse(raw)
+	if err != nil {
+		return ctx.Error("format", "%q is not a valid locale", raw)
+	}
+
+	r.v = tag.String()
+	return nil
+}
+
+func (r *SchemaExtensionLocale) Finish() error {
+	return nil
+}
+
+// Locale returns the canonical BCP 47 locale found in the identity traits
+// or an empty string if no locale was set.
+func (r *SchemaExtensionLocale) Locale() string {
+	r.l.Lock()
+	defer r.l.Unlock()
+	return r.v
+}
+
+// Locale returns the locale stored in the traits of the given identity without validating them. It is
+// empty, which selects the default locale, if the identity schema does not annotate a locale trait, if the
+// identity did not set a valid one, or if the schema could not be loaded.
+func (v *Validator) Locale(ctx context.Context, i *Identity) string {
+	ss, err := v.d.IdentityTraitsSchemas(ctx)
+	if err != nil {
+		return ""
+	}
+
+	s, err := ss.GetByID(i.SchemaID)
+	if err != nil {
+		return ""
+	}
+
+	path, err := schema.LocaleTrait(ctx, s.URL.String())
+	if err != nil || path == "" {
+		return ""
+	}
+
+	tag, err := language.Parse(strings.TrimSpace(gjson.GetBytes(i.Traits, path).String()))
+	if err != nil {
+		return ""
+	}
+	return tag.String()
+}
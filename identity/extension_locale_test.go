@@ -0,0 +1,56 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ory/jsonschema/v3"
+	_ "github.com/ory/jsonschema/v3/fileloader"
+
+	"github.com/ory/kratos/schema"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaExtensionLocale(t *testing.T) {
+	for k, tc := range []struct {
+		expectErr error
+		doc       string
+		expect    string
+	}{
+		{doc: `{"language":"de-AT"}`, expect: "de-AT"},
+		{doc: `{"language":"de_at"}`, expect: "de-AT"},
+		{doc: `{"language":"  en  "}`, expect: "en"},
+		{doc: `{"language":""}`, expect: ""},
+		{doc: `{"nickname":"de"}`, expect: ""},
+		{
+			doc:       `{"language":"not a locale"}`,
+			expectErr: errors.New("I[#/language] S[#/properties/language/format] \"not a locale\" is not a valid locale"),
+		},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			c := jsonschema.NewCompiler()
+			runner, err := schema.NewExtensionRunner(ctx)
+			require.NoError(t, err)
+
+			e := NewSchemaExtensionLocale()
+			runner.AddRunner(e).Register(c)
+
+			err = c.MustCompile(ctx, "file://./stub/extension/locale/schema.json").Validate(bytes.NewBufferString(tc.doc))
+			if tc.expectErr != nil {
+				require.EqualError(t, err, tc.expectErr.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			require.NoError(t, e.Finish())
+			assert.Equal(t, tc.expect, e.Locale())
+		})
+	}
+}
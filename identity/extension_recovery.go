@@ -11,6 +11,7 @@ import (
 	"github.com/ory/jsonschema/v3"
 
 	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/x"
 )
 
 type SchemaExtensionRecovery struct {
@@ -37,17 +38,19 @@ func (r *SchemaExtensionRecovery) Run(ctx jsonschema.ValidationContext, s schema
 			strings.ToLower(strings.TrimSpace(
 				fmt.Sprintf("%s", value))), r.i.ID)
 
-		if has := r.has(r.i.RecoveryAddresses, address); has != nil {
-			if r.has(r.v, address) == nil {
-				r.v = append(r.v, *has)
-			}
-			return nil
-		}
+		r.appendAddress(address)
 
-		if has := r.has(r.v, address); has == nil {
-			r.v = append(r.v, *address)
+		return nil
+	case AddressTypePhone:
+		// Numbers are stored in E.164 format so that differently formatted traits of the same
+		// number result in a single recovery address.
+		normalized, err := x.NormalizePhoneNumber(fmt.Sprintf("%s", value))
+		if err != nil {
+			return ctx.Error("format", "%q is not valid %q", value, "phone")
 		}
 
+		r.appendAddress(NewRecoveryPhoneAddress(normalized, r.i.ID))
+
 		return nil
 	case "":
 		return nil
@@ -56,6 +59,19 @@ func (r *SchemaExtensionRecovery) Run(ctx jsonschema.ValidationContext, s schema
 	return ctx.Error("", "recovery.via has unknown value %q", s.Recovery.Via)
 }
 
+func (r *SchemaExtensionRecovery) appendAddress(address *RecoveryAddress) {
+	if has := r.has(r.i.RecoveryAddresses, address); has != nil {
+		if r.has(r.v, address) == nil {
+			r.v = append(r.v, *has)
+		}
+		return
+	}
+
+	if has := r.has(r.v, address); has == nil {
+		r.v = append(r.v, *address)
+	}
+}
+
 func (r *SchemaExtensionRecovery) has(haystack []RecoveryAddress, needle *RecoveryAddress) *RecoveryAddress {
 	for _, has := range haystack {
 		if has.Value == needle.Value && has.Via == needle.Via {
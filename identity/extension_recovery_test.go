@@ -140,6 +140,27 @@ func TestSchemaExtensionRecovery(t *testing.T) {
 				},
 			},
 		},
+		{
+			doc:    `{"phones":["+49 151 12345678","+4915112345678","+1 (201) 555-0123"]}`,
+			schema: "file://./stub/extension/recovery/phone.schema.json",
+			expect: []RecoveryAddress{
+				{
+					Value:      "+4915112345678",
+					Via:        RecoveryAddressTypePhone,
+					IdentityID: iid,
+				},
+				{
+					Value:      "+12015550123",
+					Via:        RecoveryAddressTypePhone,
+					IdentityID: iid,
+				},
+			},
+		},
+		{
+			doc:       `{"phones":["015112345678"]}`,
+			schema:    "file://./stub/extension/recovery/phone.schema.json",
+			expectErr: errors.New("I[#/phones/0] S[#/properties/phones/items/format] \"015112345678\" is not valid \"phone\""),
+		},
 	} {
 		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
 			id := &Identity{ID: iid, RecoveryAddresses: tc.existing}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/ory/jsonschema/v3"
 	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/x"
 )
 
 type SchemaExtensionVerification struct {
@@ -47,7 +48,14 @@ func (r *SchemaExtensionVerification) Run(ctx jsonschema.ValidationContext, s sc
 			return ctx.Error("format", "%q is not valid %q", value, "phone")
 		}
 
-		address := NewVerifiablePhoneAddress(fmt.Sprintf("%s", value), r.i.ID)
+		// Valid numbers are stored in E.164 format so that they can be found by the number
+		// submitted to the verification flow.
+		phone := fmt.Sprintf("%s", value)
+		if normalized, err := x.NormalizePhoneNumber(phone); err == nil {
+			phone = normalized
+		}
+
+		address := NewVerifiablePhoneAddress(phone, r.i.ID)
 
 		r.appendAddress(address)
 
@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"mime"
 	"net/http"
 	"strings"
 	"time"
@@ -25,6 +26,7 @@ import (
 
 	"github.com/ory/herodot"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 
@@ -42,6 +44,9 @@ const (
 	RouteItem           = RouteCollection + "/:id"
 	RouteCredentialItem = RouteItem + "/credentials/:type"
 
+	RouteWebAuthnCredentialCollection = RouteItem + "/webauthn/credentials"
+	RouteWebAuthnCredentialItem       = RouteWebAuthnCredentialCollection + "/:credential"
+
 	BatchPatchIdentitiesLimit = 2000
 )
 
@@ -55,6 +60,9 @@ type (
 		x.CSRFProvider
 		cipher.Provider
 		hash.HashProvider
+		x.LoggingProvider
+		SessionSummarizerProvider
+		MigratorProvider
 	}
 	HandlerProvider interface {
 		IdentityHandler() *Handler
@@ -79,39 +87,72 @@ func NewHandler(r handlerDependencies) *Handler {
 func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	h.r.CSRFHandler().IgnoreGlobs(
 		RouteCollection, RouteCollection+"/*",
-		RouteCollection+"/*/credentials/*",
+		RouteCollection+"/*/credentials/*", RouteCollection+"/*/credentials/*/*",
 		x.AdminPrefix+RouteCollection, x.AdminPrefix+RouteCollection+"/*",
-		x.AdminPrefix+RouteCollection+"/*/credentials/*",
+		x.AdminPrefix+RouteCollection+"/*/credentials/*", x.AdminPrefix+RouteCollection+"/*/credentials/*/*",
+		RouteCollection+"/*/verifiable-addresses", x.AdminPrefix+RouteCollection+"/*/verifiable-addresses",
+		RouteCollection+"/*/state", x.AdminPrefix+RouteCollection+"/*/state",
+		RouteVerifiableAddresses, x.AdminPrefix+RouteVerifiableAddresses,
+		RouteExport, x.AdminPrefix+RouteExport,
+		RouteAuditEvents, x.AdminPrefix+RouteAuditEvents,
 	)
 
 	public.GET(RouteCollection, x.RedirectToAdminRoute(h.r))
 	public.GET(RouteItem, x.RedirectToAdminRoute(h.r))
 	public.DELETE(RouteItem, x.RedirectToAdminRoute(h.r))
 	public.POST(RouteCollection, x.RedirectToAdminRoute(h.r))
+	public.POST(RouteCollectionImport, x.RedirectToAdminRoute(h.r))
+	public.POST(RouteCollectionMigrate, x.RedirectToAdminRoute(h.r))
+	public.GET(RouteExport, x.RedirectToAdminRoute(h.r))
+	public.GET(RouteAuditEvents, x.RedirectToAdminRoute(h.r))
 	public.PUT(RouteItem, x.RedirectToAdminRoute(h.r))
+	public.PUT(RouteItemState, x.RedirectToAdminRoute(h.r))
 	public.PATCH(RouteItem, x.RedirectToAdminRoute(h.r))
 	public.DELETE(RouteCredentialItem, x.RedirectToAdminRoute(h.r))
+	public.GET(RouteWebAuthnCredentialCollection, x.RedirectToAdminRoute(h.r))
+	public.DELETE(RouteWebAuthnCredentialItem, x.RedirectToAdminRoute(h.r))
+	public.PATCH(RouteIdentityVerifiableAddresses, x.RedirectToAdminRoute(h.r))
+	public.PATCH(RouteVerifiableAddresses, x.RedirectToAdminRoute(h.r))
 
 	public.GET(x.AdminPrefix+RouteCollection, x.RedirectToAdminRoute(h.r))
 	public.GET(x.AdminPrefix+RouteItem, x.RedirectToAdminRoute(h.r))
 	public.DELETE(x.AdminPrefix+RouteItem, x.RedirectToAdminRoute(h.r))
 	public.POST(x.AdminPrefix+RouteCollection, x.RedirectToAdminRoute(h.r))
+	public.POST(x.AdminPrefix+RouteCollectionImport, x.RedirectToAdminRoute(h.r))
+	public.POST(x.AdminPrefix+RouteCollectionMigrate, x.RedirectToAdminRoute(h.r))
+	public.GET(x.AdminPrefix+RouteExport, x.RedirectToAdminRoute(h.r))
+	public.GET(x.AdminPrefix+RouteAuditEvents, x.RedirectToAdminRoute(h.r))
 	public.PUT(x.AdminPrefix+RouteItem, x.RedirectToAdminRoute(h.r))
+	public.PUT(x.AdminPrefix+RouteItemState, x.RedirectToAdminRoute(h.r))
 	public.PATCH(x.AdminPrefix+RouteItem, x.RedirectToAdminRoute(h.r))
 	public.DELETE(x.AdminPrefix+RouteCredentialItem, x.RedirectToAdminRoute(h.r))
+	public.GET(x.AdminPrefix+RouteWebAuthnCredentialCollection, x.RedirectToAdminRoute(h.r))
+	public.DELETE(x.AdminPrefix+RouteWebAuthnCredentialItem, x.RedirectToAdminRoute(h.r))
+	public.PATCH(x.AdminPrefix+RouteIdentityVerifiableAddresses, x.RedirectToAdminRoute(h.r))
+	public.PATCH(x.AdminPrefix+RouteVerifiableAddresses, x.RedirectToAdminRoute(h.r))
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 	admin.GET(RouteCollection, h.list)
 	admin.GET(RouteItem, h.get)
-	admin.DELETE(RouteItem, h.delete)
-	admin.PATCH(RouteItem, h.patch)
-
-	admin.POST(RouteCollection, h.create)
-	admin.PATCH(RouteCollection, h.batchPatchIdentities)
-	admin.PUT(RouteItem, h.update)
-
-	admin.DELETE(RouteCredentialItem, h.deleteIdentityCredentials)
+	admin.DELETE(RouteItem, withAdminAuditActor(h.delete))
+	admin.PATCH(RouteItem, withAdminAuditActor(h.patch))
+
+	admin.POST(RouteCollection, withAdminAuditActor(h.create))
+	admin.PATCH(RouteCollection, withAdminAuditActor(h.batchPatchIdentities))
+	admin.POST(RouteCollectionImport, withAdminAuditActor(h.importIdentities))
+	admin.POST(RouteCollectionMigrate, withAdminAuditActor(h.migrateIdentities))
+	admin.GET(RouteExport, h.exportIdentities)
+	admin.GET(RouteAuditEvents, h.listAuditEvents)
+	admin.PUT(RouteItem, withAdminAuditActor(h.update))
+	admin.PUT(RouteItemState, withAdminAuditActor(h.updateState))
+
+	admin.DELETE(RouteCredentialItem, withAdminAuditActor(h.deleteIdentityCredentials))
+	admin.GET(RouteWebAuthnCredentialCollection, h.listIdentityWebAuthnCredentials)
+	admin.DELETE(RouteWebAuthnCredentialItem, withAdminAuditActor(h.deleteIdentityWebAuthnCredential))
+
+	admin.PATCH(RouteIdentityVerifiableAddresses, withAdminAuditActor(h.patchIdentityVerifiableAddress))
+	admin.PATCH(RouteVerifiableAddresses, withAdminAuditActor(h.batchPatchVerifiableAddresses))
 }
 
 // Paginated Identity List Response
@@ -162,6 +203,24 @@ type listIdentitiesParameters struct {
 	// in: query
 	CredentialsIdentifierSimilar string `json:"preview_credentials_identifier_similar"`
 
+	// Query filters identities by the values of their searchable traits and has the format
+	// `<trait>:<operator>:<term>`, for example `emails:eq:foo@ory.sh`. Traits are made searchable in the identity
+	// schema using `"ory.sh/kratos": {"search": {"indexed": true}}` and are named by their path below `traits`
+	// without array indices. Supported operators are `eq`, `prefix`, and `contains`, which requires the trait to
+	// be annotated with `"contains": true`. Searches are case-insensitive. Multiple queries must all match.
+	// Trait values are indexed when identities are created or updated.
+	//
+	// required: false
+	// in: query
+	Query []string `json:"query"`
+
+	// State filters identities by their state. Soft-deleted identities are only listed if the state is
+	// `soft_deleted`.
+	//
+	// required: false
+	// in: query
+	State State `json:"state"`
+
 	crdbx.ConsistencyRequestParameters
 }
 
@@ -190,9 +249,16 @@ func (h *Handler) list(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 			IdsFilter:                    r.URL.Query()["ids"],
 			CredentialsIdentifier:        r.URL.Query().Get("credentials_identifier"),
 			CredentialsIdentifierSimilar: r.URL.Query().Get("preview_credentials_identifier_similar"),
+			StateFilter:                  State(r.URL.Query().Get("state")),
 			ConsistencyLevel:             crdbx.ConsistencyLevelFromRequest(r),
 		}
 	)
+	if params.StateFilter != "" {
+		if err := params.StateFilter.IsValid(); err != nil {
+			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The state filter %q is not a valid identity state.", params.StateFilter)))
+			return
+		}
+	}
 	if params.CredentialsIdentifier != "" && params.CredentialsIdentifierSimilar != "" {
 		h.r.Writer().WriteError(w, r, herodot.ErrBadRequest.WithReason("Cannot pass both credentials_identifier and preview_credentials_identifier_similar."))
 		return
@@ -200,6 +266,11 @@ func (h *Handler) list(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 	if params.CredentialsIdentifier != "" || params.CredentialsIdentifierSimilar != "" {
 		params.Expand = ExpandEverything
 	}
+	params.TraitQueries, err = ParseTraitQueries(r.URL.Query()["query"])
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
 	params.KeySetPagination, params.PagePagination, err = x.ParseKeysetOrPagePagination(r)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
@@ -214,7 +285,7 @@ func (h *Handler) list(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 
 	if params.PagePagination != nil {
 		total := int64(len(is))
-		if params.CredentialsIdentifier == "" {
+		if params.CredentialsIdentifier == "" && len(params.TraitQueries) == 0 && params.StateFilter == "" {
 			total, err = h.r.IdentityPool().CountIdentities(r.Context())
 			if err != nil {
 				h.r.Writer().WriteError(w, r, err)
@@ -799,8 +870,20 @@ type patchIdentity struct {
 // Partially updates an [identity's](https://www.ory.sh/docs/kratos/concepts/identity-user-model) field using [JSON Patch](https://jsonpatch.com/).
 // The fields `id`, `stateChangedAt` and `credentials` can not be updated using this method.
 //
+// JSON Patch `test` operations are supported if they precede all other operations. They are checked against the
+// current identity, and the request fails with 409 Conflict if they do not match. Use them to make sure that the
+// identity was not updated concurrently.
+//
+// Alternatively, the identity can be updated using a [JSON Merge Patch](https://www.rfc-editor.org/rfc/rfc7396) by
+// setting the content type to `application/merge-patch+json`. A merge patch may only contain the fields `traits`,
+// `metadata_public`, `metadata_admin`, and `state`.
+//
+// In both cases, the patched identity is validated against its identity schema.
+//
 //	Consumes:
 //	- application/json
+//	- application/json-patch+json
+//	- application/merge-patch+json
 //
 //	Produces:
 //	- application/json
@@ -835,14 +918,19 @@ func (h *Handler) patch(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 
 	patchedIdentity := WithAdminMetadataInJSON(*identity)
 
-	if err := jsonx.ApplyJSONPatch(requestBody, &patchedIdentity, "/id", "/stateChangedAt", "/credentials"); err != nil {
-		h.r.Writer().WriteError(w, r, errors.WithStack(
-			herodot.
-				ErrBadRequest.
-				WithReasonf("An error occured when applying the JSON patch").
-				WithErrorf("%v", err).
-				WithWrap(err),
-		))
+	opts := []ManagerOption{ManagerAllowWriteProtectedTraits}
+	if contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); contentType == "application/merge-patch+json" {
+		err = applyMergePatch(requestBody, &patchedIdentity)
+	} else {
+		var tested bool
+		tested, err = applyJSONPatch(requestBody, &patchedIdentity)
+		if tested {
+			// The test operations only hold if the identity was not updated since it was fetched.
+			opts = append(opts, ManagerRequireUnmodifiedSince(identity.UpdatedAt))
+		}
+	}
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
@@ -856,7 +944,7 @@ func (h *Handler) patch(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 			h.r.Writer().WriteError(w, r, errors.WithStack(
 				herodot.
 					ErrBadRequest.
-					WithReasonf("The supplied state ('%s') was not valid. Valid states are ('%s', '%s').", string(patchedIdentity.State), StateActive, StateInactive).
+					WithReasonf("The supplied state ('%s') was not valid. Valid states are ('%s', '%s', '%s', '%s', '%s').", string(patchedIdentity.State), StateActive, StateInactive, StatePendingApproval, StateDeactivated, StateSoftDeleted).
 					WithErrorf("%v", err).
 					WithWrap(err),
 			))
@@ -873,7 +961,7 @@ func (h *Handler) patch(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 	if err := h.r.IdentityManager().Update(
 		r.Context(),
 		&updatedIdenty,
-		ManagerAllowWriteProtectedTraits,
+		opts...,
 	); err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
@@ -882,6 +970,88 @@ func (h *Handler) patch(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 	h.r.Writer().Write(w, r, WithCredentialsMetadataAndAdminMetadataInJSON(updatedIdenty))
 }
 
+// mergePatchAllowList contains the fields which can be updated using a JSON Merge Patch.
+var mergePatchAllowList = map[string]struct{}{
+	"traits":          {},
+	"metadata_public": {},
+	"metadata_admin":  {},
+	"state":           {},
+}
+
+// applyMergePatch applies a JSON Merge Patch (RFC 7396) to the identity.
+func applyMergePatch(requestBody []byte, identity *WithAdminMetadataInJSON) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(requestBody, &fields); err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("The merge patch must be a JSON object.").WithErrorf("%v", err).WithWrap(err))
+	}
+	for field := range fields {
+		if _, ok := mergePatchAllowList[field]; !ok {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("The merge patch includes the field %q which can not be updated. Only the fields traits, metadata_public, metadata_admin, and state can be updated.", field))
+		}
+	}
+
+	original, err := json.Marshal(identity)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	modified, err := jsonpatch.MergePatch(original, requestBody)
+	if err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("An error occured when applying the merge patch").WithErrorf("%v", err).WithWrap(err))
+	}
+
+	if err := json.Unmarshal(modified, identity); err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("An error occured when applying the merge patch").WithErrorf("%v", err).WithWrap(err))
+	}
+	return nil
+}
+
+// applyJSONPatch applies a JSON Patch (RFC 6902) to the identity. Leading `test` operations are checked against the
+// current identity first, which allows callers to make sure that the identity was not changed concurrently. It
+// returns true if the patch contains test operations.
+func applyJSONPatch(requestBody []byte, identity *WithAdminMetadataInJSON) (tested bool, err error) {
+	patchError := func(err error) error {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("An error occured when applying the JSON patch").WithErrorf("%v", err).WithWrap(err))
+	}
+
+	operations, err := jsonpatch.DecodePatch(requestBody)
+	if err != nil {
+		return false, patchError(err)
+	}
+
+	var tests jsonpatch.Patch
+	for len(operations) > 0 && operations[0].Kind() == "test" {
+		tests, operations = append(tests, operations[0]), operations[1:]
+	}
+	for _, op := range operations {
+		if op.Kind() == "test" {
+			return false, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The JSON patch contains a test operation after other operations. Test operations must precede all other operations."))
+		}
+	}
+
+	if len(tests) > 0 {
+		original, err := json.Marshal(identity)
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+
+		if _, err := tests.Apply(original); errors.Is(err, jsonpatch.ErrTestFailed) {
+			return false, errors.WithStack(herodot.ErrConflict.WithReasonf("The identity does not match the test operations of the JSON patch. It was probably updated concurrently.").WithErrorf("%v", err).WithWrap(err))
+		} else if err != nil {
+			return false, patchError(err)
+		}
+
+		if requestBody, err = json.Marshal(operations); err != nil {
+			return false, errors.WithStack(err)
+		}
+	}
+
+	if err := jsonx.ApplyJSONPatch(requestBody, identity, "/id", "/stateChangedAt", "/credentials"); err != nil {
+		return false, patchError(err)
+	}
+	return len(tests) > 0, nil
+}
+
 func deletCredentialWebAuthFromIdentity(identity *Identity) (*Identity, error) {
 	cred, ok := identity.GetCredentials(CredentialsTypeWebAuthn)
 	if !ok {
@@ -1008,3 +1178,155 @@ func (h *Handler) deleteIdentityCredentials(w http.ResponseWriter, r *http.Reque
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// List WebAuthn Credentials Parameters
+//
+// swagger:parameters listIdentityWebAuthnCredentials
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listIdentityWebAuthnCredentials struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// List WebAuthn Credentials Response
+//
+// swagger:response listIdentityWebAuthnCredentials
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listIdentityWebAuthnCredentialsResponse struct {
+	// in: body
+	Body []WebAuthnCredentialSummary
+}
+
+// swagger:route GET /admin/identities/{id}/webauthn/credentials identity listIdentityWebAuthnCredentials
+//
+// # List the WebAuthn credentials of an identity
+//
+// Lists the WebAuthn credentials (security keys and passkeys) of an [identity](https://www.ory.sh/docs/kratos/concepts/identity-user-model)
+// with the name the identity gave them and when they were added and last used.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: listIdentityWebAuthnCredentials
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) listIdentityWebAuthnCredentials(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	identity, err := h.r.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	summaries := make([]WebAuthnCredentialSummary, 0)
+	if cred, ok := identity.GetCredentials(CredentialsTypeWebAuthn); ok {
+		var cc CredentialsWebAuthnConfig
+		if err := json.Unmarshal(cred.Config, &cc); err != nil {
+			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode identity credentials.").WithDebug(err.Error())))
+			return
+		}
+		for k := range cc.Credentials {
+			summaries = append(summaries, cc.Credentials[k].Summary())
+		}
+	}
+
+	h.r.Writer().Write(w, r, summaries)
+}
+
+// Delete WebAuthn Credential Parameters
+//
+// swagger:parameters deleteIdentityWebAuthnCredential
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type deleteIdentityWebAuthnCredential struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// Credential is the hex encoded ID of the WebAuthn credential.
+	//
+	// required: true
+	// in: path
+	Credential string `json:"credential"`
+}
+
+// swagger:route DELETE /admin/identities/{id}/webauthn/credentials/{credential} identity deleteIdentityWebAuthnCredential
+//
+// # Delete a WebAuthn credential of an identity
+//
+// Deletes a single WebAuthn credential (security key or passkey) of an [identity](https://www.ory.sh/docs/kratos/concepts/identity-user-model),
+// for example because the identity lost the device. The identity's other WebAuthn credentials are kept.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) deleteIdentityWebAuthnCredential(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	identity, err := h.r.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	cred, ok := identity.GetCredentials(CredentialsTypeWebAuthn)
+	if !ok {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReasonf("You tried to remove a WebAuthn credential but this user has no WebAuthn set up.")))
+		return
+	}
+
+	var cc CredentialsWebAuthnConfig
+	if err := json.Unmarshal(cred.Config, &cc); err != nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode identity credentials.").WithDebug(err.Error())))
+		return
+	}
+
+	if _, ok := cc.Remove(ps.ByName("credential")); !ok {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReasonf("You tried to remove a WebAuthn credential which does not exist.")))
+		return
+	}
+
+	if len(cc.Credentials) == 0 {
+		identity.DeleteCredentialsType(CredentialsTypeWebAuthn)
+	} else {
+		cred.Config, err = json.Marshal(cc)
+		if err != nil {
+			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to encode identity credentials.").WithDebug(err.Error())))
+			return
+		}
+		identity.SetCredentials(CredentialsTypeWebAuthn, *cred)
+	}
+
+	if err := h.r.IdentityManager().Update(
+		r.Context(),
+		identity,
+		ManagerAllowWriteProtectedTraits,
+	); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
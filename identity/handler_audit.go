@@ -0,0 +1,110 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/pagination/migrationpagination"
+)
+
+const RouteAuditEvents = "/audit/identities"
+
+// List Identity Audit Events Parameters
+//
+// swagger:parameters listIdentityAuditEvents
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listIdentityAuditEvents struct {
+	migrationpagination.RequestParameters
+
+	// IdentityID only returns the audit events of the identity with this ID. Audit events of deleted identities
+	// are kept and can be listed as well.
+	//
+	// required: false
+	// in: query
+	IdentityID string `json:"identity_id"`
+
+	// Action only returns audit events of this kind.
+	//
+	// required: false
+	// enum: identity.created,identity.updated,identity.deleted,verifiable_address.updated
+	// in: query
+	Action string `json:"action"`
+
+	// ActorType only returns audit events of changes made by this kind of actor.
+	//
+	// required: false
+	// enum: admin_api,self_service,hook,system
+	// in: query
+	ActorType string `json:"actor_type"`
+
+	// CreatedAfter only returns audit events recorded at or after this RFC 3339 timestamp.
+	//
+	// required: false
+	// in: query
+	CreatedAfter time.Time `json:"created_after"`
+
+	// CreatedBefore only returns audit events recorded before this RFC 3339 timestamp.
+	//
+	// required: false
+	// in: query
+	CreatedBefore time.Time `json:"created_before"`
+}
+
+// List Identity Audit Events Response
+//
+// swagger:response listIdentityAuditEvents
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listIdentityAuditEventsResponse struct {
+	migrationpagination.ResponseHeaderAnnotation
+
+	// in: body
+	Body []AuditEvent
+}
+
+// swagger:route GET /admin/audit/identities identity listIdentityAuditEvents
+//
+// # List Identity Audit Events
+//
+// Lists the audit trail of changes to [identities](https://www.ory.sh/docs/kratos/concepts/identity-user-model),
+// their credentials, and their addresses, most recent first. Every audit event records who made the change, when it
+// was made, and the changed values before and after the change.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: listIdentityAuditEvents
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) listAuditEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	filter, err := ParseAuditEventFilter(r.URL.Query())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	page, perPage := x.ParsePagination(r)
+	events, total, err := h.r.PrivilegedIdentityPool().ListAuditEvents(r.Context(), filter, page, perPage)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	x.PaginationHeader(w, *r.URL, total, page, perPage)
+	h.r.Writer().Write(w, r, events)
+}
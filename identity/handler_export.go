@@ -0,0 +1,221 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/pagination/keysetpagination"
+)
+
+// RouteExport is not nested below RouteCollection because the router does not allow a static segment next to the
+// :id parameter of RouteItem.
+const RouteExport = "/export/identities"
+
+type (
+	// SessionSummary summarizes the sessions of an identity.
+	//
+	// swagger:model identitySessionSummary
+	SessionSummary struct {
+		// Total is the number of sessions of the identity, including inactive and expired ones.
+		Total int `json:"total"`
+
+		// Active is the number of active sessions of the identity.
+		Active int `json:"active"`
+
+		// LastAuthenticatedAt is the time the identity last authenticated.
+		LastAuthenticatedAt *time.Time `json:"last_authenticated_at,omitempty"`
+	}
+
+	SessionSummarizer interface {
+		// SummarizeIdentitySessions returns the session summaries of the given identities. Identities
+		// without sessions are missing from the result.
+		SummarizeIdentitySessions(ctx context.Context, ids ...uuid.UUID) (map[uuid.UUID]SessionSummary, error)
+	}
+
+	SessionSummarizerProvider interface {
+		IdentitySessionSummarizer() SessionSummarizer
+	}
+)
+
+// Export Identity Record
+//
+// swagger:model exportIdentityRecord
+type ExportIdentityRecord struct {
+	// Identity is the exported identity including its admin metadata and addresses. The credentials contain
+	// their configuration, for example password hashes, only if requested.
+	Identity json.RawMessage `json:"identity"`
+
+	// Sessions summarizes the sessions of the identity.
+	Sessions SessionSummary `json:"sessions"`
+
+	// Cursor is the page token which resumes the export after this record.
+	Cursor string `json:"cursor"`
+}
+
+// Export Identities Parameters
+//
+// swagger:parameters exportIdentities
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type exportIdentities struct {
+	// PageToken resumes the export after the record with this cursor.
+	//
+	// in: query
+	PageToken string `json:"page_token"`
+
+	// PageSize is the number of identities loaded at once. Defaults to 250.
+	//
+	// in: query
+	PageSize int `json:"page_size"`
+
+	// Stream exports all identities in one response instead of one page. The response then
+	// does not contain a Link header.
+	//
+	// in: query
+	Stream bool `json:"stream"`
+
+	// IncludeCredentialHashes includes the configuration of the credentials, for example password hashes
+	// and encrypted OpenID Connect tokens, in the export.
+	//
+	// in: query
+	IncludeCredentialHashes bool `json:"include_credential_hashes"`
+}
+
+// swagger:route GET /admin/export/identities identity exportIdentities
+//
+// # Export identities
+//
+// Exports [identities](https://www.ory.sh/docs/kratos/concepts/identity-user-model) as newline-delimited JSON, for
+// example to move them to another system or to archive them. Each record contains the identity with its addresses,
+// the metadata of its credentials, and a summary of its sessions.
+//
+// The export is paginated using the Link header, or streamed completely if `stream` is set. Every record contains
+// a cursor, which can be passed as `page_token` to resume an interrupted export.
+//
+//	Produces:
+//	- application/x-ndjson
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: exportIdentityRecord
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) exportIdentities(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	stream, err := parseBoolQuery(q.Get("stream"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+	hashes, err := parseBoolQuery(q.Get("include_credential_hashes"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	opts, err := keysetpagination.Parse(q, keysetpagination.NewStringPageToken)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason(err.Error())))
+		return
+	}
+
+	params := ListIdentityParameters{Expand: ExpandEverything, KeySetPagination: opts}
+	is, next, err := h.r.IdentityPool().ListIdentities(ctx, params)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if !stream {
+		u := *r.URL
+		keysetpagination.Header(w, &u, next)
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	for {
+		if err := h.writeExportPage(ctx, enc, is, hashes); err != nil {
+			h.r.Logger().WithError(err).Warn("Unable to export identities.")
+			return
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		if !stream || next.IsLast() || ctx.Err() != nil {
+			return
+		}
+
+		params.KeySetPagination = next.ToOptions()
+		if is, next, err = h.r.IdentityPool().ListIdentities(ctx, params); err != nil {
+			// The status code has already been sent, so we can only end the stream. The client resumes using
+			// the cursor of the last record it received.
+			h.r.Logger().WithError(err).Warn("Unable to export identities.")
+			return
+		}
+	}
+}
+
+func (h *Handler) writeExportPage(ctx context.Context, enc *json.Encoder, is []Identity, hashes bool) error {
+	ids := make([]uuid.UUID, len(is))
+	for k := range is {
+		ids[k] = is[k].ID
+	}
+
+	summaries, err := h.r.IdentitySessionSummarizer().SummarizeIdentitySessions(ctx, ids...)
+	if err != nil {
+		return err
+	}
+
+	for k := range is {
+		var raw []byte
+		if hashes {
+			raw, err = json.Marshal(WithCredentialsAndAdminMetadataInJSON(is[k]))
+		} else {
+			raw, err = json.Marshal(WithCredentialsMetadataAndAdminMetadataInJSON(is[k]))
+		}
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err := enc.Encode(&ExportIdentityRecord{
+			Identity: raw,
+			Sessions: summaries[is[k].ID],
+			Cursor:   is[k].PageToken().Encode(),
+		}); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+func parseBoolQuery(v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to parse boolean query parameter %q.", v))
+	}
+	return b, nil
+}
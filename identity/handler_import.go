@@ -4,16 +4,237 @@
 package identity
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"net/http"
+	"strconv"
 
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
 
 	"github.com/ory/herodot"
 	"github.com/ory/kratos/hash"
 	"github.com/ory/kratos/x"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/sqlcon"
 )
 
+const (
+	RouteCollectionImport = RouteCollection + "/import"
+
+	// ImportIdentitiesDefaultBatchSize is the number of identities stored per transaction during an import
+	// unless the request sets another batch size.
+	ImportIdentitiesDefaultBatchSize = 250
+
+	// ImportIdentitiesMaxRecordSize is the maximum size of a single NDJSON record in bytes.
+	ImportIdentitiesMaxRecordSize = 1024 * 1024
+)
+
+// Import Identity Record
+//
+// swagger:model importIdentityRecord
+type ImportIdentityRecord struct {
+	CreateIdentityBody
+
+	// ImportID is an optional identifier of the record, for example the ID of the user in the system
+	// the identity is migrated from. It is returned in the result of the record.
+	ImportID string `json:"import_id,omitempty"`
+}
+
+// Import Identities Parameters
+//
+// swagger:parameters importIdentities
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type importIdentities struct {
+	// BatchSize is the number of identities which are stored at once. Defaults to 250 and must not exceed 2000.
+	//
+	// in: query
+	BatchSize int `json:"batch_size"`
+
+	// A newline-delimited stream of import identity records.
+	//
+	// in: body
+	Body ImportIdentityRecord
+}
+
+// Import Identity Result
+//
+// swagger:model importIdentityResult
+type ImportIdentityResult struct {
+	// Line is the line of the record in the request body, starting at 1.
+	Line int `json:"line"`
+
+	// ImportID is the import ID of the record, if one was set.
+	ImportID string `json:"import_id,omitempty"`
+
+	// IdentityID is the ID of the created identity, unless the import of the record failed.
+	IdentityID *uuid.UUID `json:"identity_id,omitempty"`
+
+	// Error describes why the import of the record failed.
+	Error *herodot.DefaultError `json:"error,omitempty"`
+}
+
+// swagger:route POST /admin/identities/import identity importIdentities
+//
+// # Import identities from a stream
+//
+// Creates [identities](https://www.ory.sh/docs/kratos/concepts/identity-user-model) from a newline-delimited
+// stream of JSON records, for example when migrating millions of users from another system. Each record uses the
+// payload of the create identity endpoint and can therefore contain
+// [hashed passwords](https://www.ory.sh/docs/kratos/manage-identities/import-user-accounts-identities#hashed-passwords),
+// social sign in links, and verified addresses.
+//
+// Records are stored in batches. The response streams one result per record, in the order of the records, as
+// soon as the batch of the record is stored. A failing record does not fail the other records.
+//
+//	Consumes:
+//	- application/x-ndjson
+//
+//	Produces:
+//	- application/x-ndjson
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: importIdentityResult
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) importIdentities(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	size := ImportIdentitiesDefaultBatchSize
+	if raw := r.URL.Query().Get("batch_size"); raw != "" {
+		var err error
+		size, err = strconv.Atoi(raw)
+		if err != nil || size < 1 || size > BatchPatchIdentitiesLimit {
+			h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+				"The batch size must be a number between 1 and %d.", BatchPatchIdentitiesLimit)))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	var (
+		ctx     = r.Context()
+		enc     = json.NewEncoder(w)
+		pending = make([]importRecord, 0, size)
+		scanner = bufio.NewScanner(r.Body)
+		line    = 0
+	)
+	scanner.Buffer(make([]byte, 0, 64*1024), ImportIdentitiesMaxRecordSize)
+
+	flush := func() bool {
+		for _, result := range h.importBatch(ctx, pending) {
+			if err := enc.Encode(result); err != nil {
+				return false
+			}
+		}
+		pending = pending[:0]
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return ctx.Err() == nil
+	}
+
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		rec := importRecord{line: line}
+		if err := jsonx.NewStrictDecoder(bytes.NewReader(raw)).Decode(&rec.body); err != nil {
+			rec.err = errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the record: %s", err).WithWrap(err))
+		}
+		pending = append(pending, rec)
+
+		if len(pending) >= size && !flush() {
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		pending = append(pending, importRecord{line: line + 1, err: errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+			"Unable to read the record, records must not be larger than %d bytes: %s", ImportIdentitiesMaxRecordSize, err).WithWrap(err))})
+	}
+	flush()
+}
+
+type importRecord struct {
+	line     int
+	body     ImportIdentityRecord
+	identity *Identity
+	err      error
+}
+
+// importBatch stores the identities of the records in one transaction. If that fails, it stores them one by one
+// to find out which records fail.
+func (h *Handler) importBatch(ctx context.Context, records []importRecord) []ImportIdentityResult {
+	identities := make([]*Identity, 0, len(records))
+	for k := range records {
+		rec := &records[k]
+		if rec.err != nil {
+			continue
+		}
+
+		rec.identity, rec.err = h.identityFromCreateIdentityBody(ctx, &rec.body.CreateIdentityBody)
+		if rec.err == nil {
+			identities = append(identities, rec.identity)
+		}
+	}
+
+	if len(identities) > 0 {
+		if err := h.r.IdentityManager().CreateIdentities(ctx, identities); err != nil {
+			if len(identities) == 1 {
+				for k := range records {
+					if records[k].identity != nil {
+						records[k].err = importError(err)
+					}
+				}
+			} else {
+				for k := range records {
+					rec := &records[k]
+					if rec.identity == nil {
+						continue
+					}
+					// The failed transaction might have modified the identity, so we start over.
+					if rec.identity, rec.err = h.identityFromCreateIdentityBody(ctx, &rec.body.CreateIdentityBody); rec.err != nil {
+						continue
+					}
+					rec.err = importError(h.r.IdentityManager().Create(ctx, rec.identity))
+				}
+			}
+		}
+	}
+
+	results := make([]ImportIdentityResult, len(records))
+	for k, rec := range records {
+		results[k] = ImportIdentityResult{Line: rec.line, ImportID: rec.body.ImportID}
+		if rec.err != nil {
+			results[k].Error = herodot.ToDefaultError(rec.err, "")
+		} else {
+			results[k].IdentityID = &rec.identity.ID
+		}
+	}
+	return results
+}
+
+func importError(err error) error {
+	if errors.Is(err, sqlcon.ErrUniqueViolation) {
+		return errors.WithStack(herodot.ErrConflict.WithReason("This identity conflicts with another identity that already exists."))
+	}
+	return err
+}
+
 func (h *Handler) importCredentials(ctx context.Context, i *Identity, creds *IdentityWithCredentials) error {
 	if creds == nil {
 		return nil
@@ -0,0 +1,72 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/jsonx"
+)
+
+const RouteCollectionMigrate = RouteCollection + "/migrate"
+
+// Migrate Identities Parameters
+//
+// swagger:parameters migrateIdentities
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type migrateIdentities struct {
+	// in: body
+	Body MigrateIdentitiesBody
+}
+
+// swagger:route POST /admin/identities/migrate identity migrateIdentities
+//
+// # Migrate Identities to another Identity Schema
+//
+// Migrates one page of the [identities](https://www.ory.sh/docs/kratos/concepts/identity-user-model) which use the
+// identity schema `from` to the identity schema `to`. Their traits and metadata are transformed using the migration
+// configured in `identity.migrations`, and are then validated against the new identity schema.
+//
+// Identities which fail the new identity schema are not changed and are listed in the report. Use `dry_run` to find
+// such identities without migrating any identity, and set `from` and `to` to the same identity schema to re-validate
+// the identities against the current version of their identity schema.
+//
+// To migrate all identities, repeat the request with the `next_page_token` of the report until it is empty.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: identityMigrationReport
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) migrateIdentities(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body MigrateIdentitiesBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError(err.Error())))
+		return
+	}
+
+	report, err := h.r.IdentityMigrator().MigrateIdentities(r.Context(), &body)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, report)
+}
@@ -0,0 +1,106 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/sqlxx"
+)
+
+const RouteItemState = RouteItem + "/state"
+
+// Update Identity State Request Body
+//
+// swagger:model updateIdentityStateBody
+type UpdateIdentityStateBody struct {
+	// State is the new state of the identity.
+	//
+	// required: true
+	State State `json:"state"`
+}
+
+// Update Identity State Parameters
+//
+// swagger:parameters updateIdentityState
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type updateIdentityState struct {
+	// ID must be set to the ID of identity you want to update
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	Body UpdateIdentityStateBody
+}
+
+// swagger:route PUT /admin/identities/{id}/state identity updateIdentityState
+//
+// # Update the State of an Identity
+//
+// Transitions an [identity](https://www.ory.sh/docs/kratos/concepts/identity-user-model) to another state, for
+// example to deactivate or soft-delete it.
+//
+// Deactivated identities can neither sign in nor recover their account, but their data is retained. Soft-deleted
+// identities can neither sign in nor recover their account either, are hidden when listing identities, and are purged
+// by `kratos cleanup sql` once `identity.soft_delete.retention` has passed. Until then, they can be restored by
+// transitioning them to another state.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: identity
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) updateState(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var body UpdateIdentityStateBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError(err.Error())))
+		return
+	}
+
+	if err := body.State.IsValid(); err != nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The state %q is not a valid identity state.", body.State).WithWrap(err)))
+		return
+	}
+
+	i, err := h.r.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if i.State != body.State {
+		stateChangedAt := sqlxx.NullTime(time.Now().UTC())
+		i.State = body.State
+		i.StateChangedAt = &stateChangedAt
+
+		if err := h.r.IdentityManager().Update(r.Context(), i, ManagerAllowWriteProtectedTraits); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+	}
+
+	h.r.Writer().Write(w, r, WithCredentialsMetadataAndAdminMetadataInJSON(*i))
+}
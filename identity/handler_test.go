@@ -4,6 +4,7 @@
 package identity_test
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -31,6 +32,7 @@ import (
 	"github.com/ory/kratos/internal"
 	"github.com/ory/kratos/internal/testhelpers"
 	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 	"github.com/ory/x/snapshotx"
 	"github.com/ory/x/sqlxx"
@@ -52,6 +54,7 @@ func TestHandler(t *testing.T) {
 		"customer":        "file://./stub/handler/customer.schema.json",
 		"multiple_emails": "file://./stub/handler/multiple_emails.schema.json",
 		"employee":        "file://./stub/handler/employee.schema.json",
+		"searchable":      "file://./stub/handler/searchable.schema.json",
 	})
 
 	conf.MustSet(ctx, config.ViperKeyPublicBaseURL, mockServerURL.String())
@@ -805,6 +808,363 @@ func TestHandler(t *testing.T) {
 		})
 	})
 
+	t.Run("suite=import identities", func(t *testing.T) {
+		importIdentities := func(t *testing.T, query string, body string) []gjson.Result {
+			t.Helper()
+			res, err := adminTS.Client().Post(adminTS.URL+"/identities/import"+query, "application/x-ndjson", strings.NewReader(body))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.EqualValues(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+
+			var results []gjson.Result
+			scanner := bufio.NewScanner(res.Body)
+			for scanner.Scan() {
+				results = append(results, gjson.ParseBytes(append([]byte{}, scanner.Bytes()...)))
+			}
+			require.NoError(t, scanner.Err())
+			return results
+		}
+
+		record := func(t *testing.T, importID string, body *identity.CreateIdentityBody) string {
+			raw, err := json.Marshal(&identity.ImportIdentityRecord{CreateIdentityBody: *body, ImportID: importID})
+			require.NoError(t, err)
+			return string(raw)
+		}
+
+		t.Run("case=fails on invalid batch size", func(t *testing.T) {
+			for _, size := range []string{"0", "abc", strconv.Itoa(identity.BatchPatchIdentitiesLimit + 1)} {
+				send(t, adminTS, "POST", "/identities/import?batch_size="+size, http.StatusBadRequest, json.RawMessage("{}"))
+			}
+		})
+
+		t.Run("case=imports valid records and reports failing ones", func(t *testing.T) {
+			withHash := validCreateIdentityBody("ndjson-import", 1)
+			withHash.Credentials.Password.Config = identity.AdminIdentityImportCredentialsPasswordConfig{
+				HashedPassword: "$2a$10$ZsCsoVQ3xfBG/K2z2XpBf.tm90GZmtOqtqWcB5.pYd5Eq8y7RlDyq",
+			}
+			withHash.Credentials.OIDC = &identity.AdminIdentityImportCredentialsOIDC{
+				Config: identity.AdminIdentityImportCredentialsOIDCConfig{
+					Providers: []identity.AdminCreateIdentityImportCredentialsOidcProvider{{Subject: "import-subject", Provider: "google"}},
+				},
+			}
+
+			results := importIdentities(t, "?batch_size=2", strings.Join([]string{
+				record(t, "a", validCreateIdentityBody("ndjson-import", 0)),
+				record(t, "b", validCreateIdentityBody("ndjson-import", 0)),
+				"",
+				`{"traits": `,
+				record(t, "c", withHash),
+				record(t, "d", &identity.CreateIdentityBody{Traits: json.RawMessage(`"invalid traits"`)}),
+			}, "\n"))
+			require.Len(t, results, 5)
+
+			for k, line := range []int64{1, 2, 4, 5, 6} {
+				assert.EqualValues(t, line, results[k].Get("line").Int(), "%s", results[k].Raw)
+			}
+
+			assert.Equal(t, "a", results[0].Get("import_id").String())
+			assert.False(t, results[0].Get("error").Exists(), "%s", results[0].Raw)
+			res := get(t, adminTS, "/identities/"+results[0].Get("identity_id").String(), http.StatusOK)
+			assert.Len(t, res.Get("verifiable_addresses.#(verified=true)#").Array(), 2)
+
+			assert.Equal(t, "b", results[1].Get("import_id").String())
+			assert.False(t, results[1].Get("identity_id").Exists(), "%s", results[1].Raw)
+			assert.EqualValues(t, http.StatusConflict, results[1].Get("error.code").Int(), "%s", results[1].Raw)
+
+			assert.EqualValues(t, http.StatusBadRequest, results[2].Get("error.code").Int(), "%s", results[2].Raw)
+
+			assert.Equal(t, "c", results[3].Get("import_id").String())
+			actual, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(ctx, x.ParseUUID(results[3].Get("identity_id").String()))
+			require.NoError(t, err, "%s", results[3].Raw)
+			assert.Equal(t, "$2a$10$ZsCsoVQ3xfBG/K2z2XpBf.tm90GZmtOqtqWcB5.pYd5Eq8y7RlDyq",
+				gjson.GetBytes(actual.Credentials[identity.CredentialsTypePassword].Config, "hashed_password").String())
+			assert.Contains(t, actual.Credentials[identity.CredentialsTypeOIDC].Identifiers, identity.OIDCUniqueID("google", "import-subject"))
+
+			assert.Equal(t, "d", results[4].Get("import_id").String())
+			assert.True(t, results[4].Get("error").Exists(), "%s", results[4].Raw)
+			assert.False(t, results[4].Get("identity_id").Exists(), "%s", results[4].Raw)
+		})
+	})
+
+	t.Run("suite=search identities by traits", func(t *testing.T) {
+		suffix := strings.ToLower(x.NewUUID().String()[:8])
+		create := func(t *testing.T, email, company string) string {
+			res := send(t, adminTS, "POST", "/identities", http.StatusCreated, json.RawMessage(fmt.Sprintf(
+				`{"schema_id": "searchable", "traits": {"email": %q, "company": %q, "nickname": "nick"}}`, email, company)))
+			return res.Get("id").String()
+		}
+		search := func(t *testing.T, queries ...string) []string {
+			q := url.Values{"query": queries}
+			res := get(t, adminTS, "/identities?"+q.Encode(), http.StatusOK)
+			var ids []string
+			for _, id := range res.Get("#.id").Array() {
+				ids = append(ids, id.String())
+			}
+			return ids
+		}
+
+		alice := create(t, "alice-"+suffix+"@ory.sh", "Ory "+suffix)
+		bob := create(t, "bob-"+suffix+"@example.org", "Acme "+suffix)
+
+		t.Run("case=equality is case-insensitive", func(t *testing.T) {
+			assert.Equal(t, []string{alice}, search(t, "email:eq:ALICE-"+suffix+"@ory.sh"))
+		})
+
+		t.Run("case=prefix", func(t *testing.T) {
+			assert.Equal(t, []string{bob}, search(t, "email:prefix:bob-"+suffix))
+			assert.Empty(t, search(t, "email:prefix:"+suffix))
+		})
+
+		t.Run("case=contains", func(t *testing.T) {
+			assert.ElementsMatch(t, []string{alice, bob}, search(t, "company:contains:"+suffix))
+			assert.Equal(t, []string{alice}, search(t, "company:contains:"+suffix, "email:prefix:alice"))
+		})
+
+		t.Run("case=wildcards are matched literally", func(t *testing.T) {
+			assert.Empty(t, search(t, "company:contains:%"+suffix))
+			assert.Empty(t, search(t, "email:prefix:_lice-"+suffix))
+		})
+
+		t.Run("case=updates the index", func(t *testing.T) {
+			send(t, adminTS, "PUT", "/identities/"+bob, http.StatusOK, json.RawMessage(fmt.Sprintf(
+				`{"schema_id": "searchable", "traits": {"email": "robert-%s@example.org", "company": "Acme"}, "state": "active"}`, suffix)))
+			assert.Empty(t, search(t, "email:prefix:bob-"+suffix))
+			assert.Equal(t, []string{bob}, search(t, "email:eq:robert-"+suffix+"@example.org"))
+		})
+
+		t.Run("case=rejects invalid queries", func(t *testing.T) {
+			for _, q := range []string{
+				"email",
+				"email:like:alice",
+				"email:eq:",
+				"nickname:eq:nick",
+				"email:contains:alice",
+			} {
+				t.Run("query="+q, func(t *testing.T) {
+					_ = get(t, adminTS, "/identities?"+url.Values{"query": {q}}.Encode(), http.StatusBadRequest)
+				})
+			}
+		})
+	})
+
+	t.Run("suite=migrate identities", func(t *testing.T) {
+		t.Run("case=validates identities without a migration", func(t *testing.T) {
+			res := send(t, adminTS, "POST", "/identities/migrate", http.StatusOK, &identity.MigrateIdentitiesBody{
+				From: config.DefaultIdentityTraitsSchemaID, To: config.DefaultIdentityTraitsSchemaID, DryRun: true, PageSize: 1,
+			})
+			assert.EqualValues(t, 1, res.Get("processed").Int(), "%s", res.Raw)
+			assert.True(t, res.Get("failed").IsArray(), "%s", res.Raw)
+			assert.NotEmpty(t, res.Get("next_page_token").String(), "%s", res.Raw)
+		})
+
+		t.Run("case=fails if the migration is not configured", func(t *testing.T) {
+			for name, ts := range map[string]*httptest.Server{"public": publicTS, "admin": adminTS} {
+				t.Run("endpoint="+name, func(t *testing.T) {
+					res := send(t, ts, "POST", "/identities/migrate", http.StatusBadRequest, &identity.MigrateIdentitiesBody{
+						From: config.DefaultIdentityTraitsSchemaID, To: "unknown",
+					})
+					assert.Contains(t, res.Get("error.reason").String(), "No identity schema migration", "%s", res.Raw)
+				})
+			}
+		})
+	})
+
+	t.Run("suite=identity states", func(t *testing.T) {
+		created := send(t, adminTS, "POST", "/identities", http.StatusCreated, json.RawMessage(`{"traits": {"bar":"state"}}`))
+		id := created.Get("id").String()
+
+		listed := func(t *testing.T, query string) bool {
+			t.Helper()
+			res := get(t, adminTS, "/identities?page_size=500"+query, http.StatusOK)
+			for _, i := range res.Array() {
+				if i.Get("id").String() == id {
+					return true
+				}
+			}
+			return false
+		}
+
+		t.Run("case=fails on an invalid state", func(t *testing.T) {
+			for name, ts := range map[string]*httptest.Server{"public": publicTS, "admin": adminTS} {
+				t.Run("endpoint="+name, func(t *testing.T) {
+					send(t, ts, "PUT", "/identities/"+id+"/state", http.StatusBadRequest, &identity.UpdateIdentityStateBody{State: "invalid"})
+				})
+			}
+			send(t, adminTS, "PUT", "/identities/"+id+"/state", http.StatusBadRequest, json.RawMessage(`{"state":"active","foo":"bar"}`))
+			get(t, adminTS, "/identities?state=invalid", http.StatusBadRequest)
+		})
+
+		t.Run("case=fails on an unknown identity", func(t *testing.T) {
+			send(t, adminTS, "PUT", "/identities/"+x.NewUUID().String()+"/state", http.StatusNotFound, &identity.UpdateIdentityStateBody{State: identity.StateDeactivated})
+		})
+
+		t.Run("case=deactivates the identity", func(t *testing.T) {
+			res := send(t, adminTS, "PUT", "/identities/"+id+"/state", http.StatusOK, &identity.UpdateIdentityStateBody{State: identity.StateDeactivated})
+			assert.EqualValues(t, identity.StateDeactivated, res.Get("state").String(), "%s", res.Raw)
+			assert.NotEqual(t, created.Get("state_changed_at").String(), res.Get("state_changed_at").String(), "%s", res.Raw)
+			assert.EqualValues(t, "state", res.Get("traits.bar").String(), "%s", res.Raw)
+
+			assert.True(t, listed(t, ""))
+			assert.True(t, listed(t, "&state=deactivated"))
+			assert.False(t, listed(t, "&state=active"))
+		})
+
+		t.Run("case=hides soft-deleted identities", func(t *testing.T) {
+			res := send(t, adminTS, "PUT", "/identities/"+id+"/state", http.StatusOK, &identity.UpdateIdentityStateBody{State: identity.StateSoftDeleted})
+			assert.EqualValues(t, identity.StateSoftDeleted, res.Get("state").String(), "%s", res.Raw)
+
+			assert.False(t, listed(t, ""))
+			assert.True(t, listed(t, "&state=soft_deleted"))
+			assert.EqualValues(t, identity.StateSoftDeleted, get(t, adminTS, "/identities/"+id, http.StatusOK).Get("state").String())
+		})
+
+		t.Run("case=restores the identity", func(t *testing.T) {
+			res := send(t, adminTS, "PUT", "/identities/"+id+"/state", http.StatusOK, &identity.UpdateIdentityStateBody{State: identity.StateActive})
+			assert.EqualValues(t, identity.StateActive, res.Get("state").String(), "%s", res.Raw)
+			assert.True(t, listed(t, ""))
+		})
+	})
+
+	t.Run("suite=audit events", func(t *testing.T) {
+		req, err := http.NewRequest("POST", adminTS.URL+"/identities", strings.NewReader(`{"traits": {"bar":"audit"}}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer audit-api-key")
+		res, err := adminTS.Client().Do(req)
+		require.NoError(t, err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		require.EqualValues(t, http.StatusCreated, res.StatusCode, "%s", body)
+		id := gjson.GetBytes(body, "id").String()
+
+		send(t, adminTS, "PATCH", "/identities/"+id, http.StatusOK, []patch{{"op": "replace", "path": "/traits/bar", "value": "audited"}})
+		remove(t, adminTS, "/identities/"+id, http.StatusNoContent)
+
+		t.Run("case=lists the audit trail of a deleted identity", func(t *testing.T) {
+			for name, ts := range map[string]*httptest.Server{"public": publicTS, "admin": adminTS} {
+				t.Run("endpoint="+name, func(t *testing.T) {
+					events := get(t, ts, "/audit/identities?identity_id="+id, http.StatusOK)
+					actions := make(map[string]gjson.Result)
+					for _, e := range events.Array() {
+						assert.Equal(t, id, e.Get("identity_id").String(), "%s", e.Raw)
+						assert.Equal(t, "admin_api", e.Get("actor_type").String(), "%s", e.Raw)
+						actions[e.Get("action").String()] = e
+					}
+					require.Len(t, actions, 3, "%s", events.Raw)
+
+					created := actions[string(identity.AuditActionIdentityCreated)]
+					assert.Regexp(t, "^sha256:[0-9a-f]{16}$", created.Get("actor_id").String(), "%s", created.Raw)
+					assert.NotContains(t, created.Raw, "audit-api-key")
+
+					updated := actions[string(identity.AuditActionIdentityUpdated)]
+					assert.JSONEq(t, `[{"path":"/traits/bar","before":"audit","after":"audited"}]`, updated.Get("changes").Raw)
+					assert.Empty(t, updated.Get("actor_id").String(), "%s", updated.Raw)
+
+					assert.True(t, actions[string(identity.AuditActionIdentityDeleted)].Exists())
+				})
+			}
+		})
+
+		t.Run("case=filters the audit trail", func(t *testing.T) {
+			events := get(t, adminTS, "/audit/identities?identity_id="+id+"&action=identity.updated", http.StatusOK)
+			assert.Len(t, events.Array(), 1, "%s", events.Raw)
+
+			events = get(t, adminTS, "/audit/identities?identity_id="+id+"&actor_type=self_service", http.StatusOK)
+			assert.Len(t, events.Array(), 0, "%s", events.Raw)
+		})
+
+		t.Run("case=fails on invalid filters", func(t *testing.T) {
+			get(t, adminTS, "/audit/identities?actor_type=unknown", http.StatusBadRequest)
+			get(t, adminTS, "/audit/identities?created_after=yesterday", http.StatusBadRequest)
+		})
+	})
+
+	t.Run("suite=export identities", func(t *testing.T) {
+		exportIdentities := func(t *testing.T, query string) ([]gjson.Result, *http.Response) {
+			t.Helper()
+			res, err := adminTS.Client().Get(adminTS.URL + "/export/identities" + query)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.EqualValues(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+
+			var records []gjson.Result
+			scanner := bufio.NewScanner(res.Body)
+			scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+			for scanner.Scan() {
+				records = append(records, gjson.ParseBytes(append([]byte{}, scanner.Bytes()...)))
+			}
+			require.NoError(t, scanner.Err())
+			return records, res
+		}
+
+		find := func(records []gjson.Result, id uuid.UUID) *gjson.Result {
+			for k := range records {
+				if records[k].Get("identity.id").String() == id.String() {
+					return &records[k]
+				}
+			}
+			return nil
+		}
+
+		body := validCreateIdentityBody("export", 0)
+		created := send(t, adminTS, "POST", "/identities", http.StatusCreated, body)
+		id := x.ParseUUID(created.Get("id").String())
+
+		i, err := reg.PrivilegedIdentityPool().GetIdentity(ctx, id, identity.ExpandNothing)
+		require.NoError(t, err)
+		sess, err := session.NewActiveSession(testhelpers.NewTestHTTPRequest(t, "GET", "/", nil), i, conf, time.Now(), identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1)
+		require.NoError(t, err)
+		require.NoError(t, reg.SessionPersister().UpsertSession(ctx, sess))
+
+		t.Run("case=fails on invalid parameters", func(t *testing.T) {
+			_ = get(t, adminTS, "/export/identities?stream=maybe", http.StatusBadRequest)
+			_ = get(t, adminTS, "/export/identities?include_credential_hashes=maybe", http.StatusBadRequest)
+		})
+
+		t.Run("case=streams all identities", func(t *testing.T) {
+			records, res := exportIdentities(t, "?stream=true&page_size=2")
+			assert.Empty(t, res.Header.Get("Link"))
+
+			total, err := reg.IdentityPool().CountIdentities(ctx)
+			require.NoError(t, err)
+			assert.EqualValues(t, total, len(records))
+
+			record := find(records, id)
+			require.NotNil(t, record)
+			assert.Equal(t, id.String(), record.Get("cursor").String())
+			assert.EqualValues(t, body.Traits, record.Get("identity.traits").Raw)
+			assert.Len(t, record.Get("identity.verifiable_addresses").Array(), 4)
+			assert.True(t, record.Get("identity.metadata_admin").Exists(), "%s", record.Raw)
+			assert.True(t, record.Get("identity.credentials.password").Exists(), "%s", record.Raw)
+			assert.False(t, record.Get("identity.credentials.password.config").Exists(), "%s", record.Raw)
+			assert.EqualValues(t, 1, record.Get("sessions.total").Int(), "%s", record.Raw)
+			assert.EqualValues(t, 1, record.Get("sessions.active").Int(), "%s", record.Raw)
+			assert.True(t, record.Get("sessions.last_authenticated_at").Exists(), "%s", record.Raw)
+		})
+
+		t.Run("case=includes credential hashes", func(t *testing.T) {
+			records, _ := exportIdentities(t, "?stream=true&include_credential_hashes=true")
+			record := find(records, id)
+			require.NotNil(t, record)
+			require.NoError(t, hash.Compare(ctx, []byte("password-0"), []byte(record.Get("identity.credentials.password.config.hashed_password").String())))
+		})
+
+		t.Run("case=paginates and resumes from a cursor", func(t *testing.T) {
+			records, res := exportIdentities(t, "?page_size=1")
+			require.Len(t, records, 1)
+			assert.Contains(t, res.Header.Get("Link"), `rel="next"`)
+
+			records, _ = exportIdentities(t, "?stream=true&page_token="+id.String())
+			assert.Nil(t, find(records, id))
+			for _, record := range records {
+				assert.Greater(t, record.Get("cursor").String(), id.String())
+			}
+		})
+	})
+
 	t.Run("case=PATCH update of state should update state changed at timestamp", func(t *testing.T) {
 		uuid := x.NewUUID().String()
 		email := "UPPER" + uuid + "@ory.sh"
@@ -876,6 +1236,84 @@ func TestHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("suite=PATCH verifiable addresses", func(t *testing.T) {
+		create := func(t *testing.T) (string, string) {
+			email := x.NewUUID().String() + "@ory.sh"
+			res := send(t, adminTS, "POST", "/identities", http.StatusCreated, &identity.CreateIdentityBody{
+				SchemaID: "employee",
+				Traits:   []byte(`{"email":"` + email + `"}`),
+			})
+			require.False(t, res.Get("verifiable_addresses.0.verified").Bool(), "%s", res.Raw)
+			return res.Get("id").String(), email
+		}
+
+		for name, ts := range map[string]*httptest.Server{"public": publicTS, "admin": adminTS} {
+			t.Run("endpoint="+name, func(t *testing.T) {
+				id, email := create(t)
+				verifiedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+				res := send(t, ts, "PATCH", "/identities/"+id+"/verifiable-addresses", http.StatusOK, &identity.PatchVerifiableAddressBody{
+					Value:      strings.ToUpper(email),
+					Verified:   true,
+					VerifiedAt: &verifiedAt,
+				})
+				assert.True(t, res.Get("verified").Bool(), "%s", res.Raw)
+				assert.EqualValues(t, identity.VerifiableAddressStatusCompleted, res.Get("status").String(), "%s", res.Raw)
+
+				res = get(t, ts, "/identities/"+id, http.StatusOK)
+				assert.True(t, res.Get("verifiable_addresses.0.verified").Bool(), "%s", res.Raw)
+				assert.Equal(t, verifiedAt, res.Get("verifiable_addresses.0.verified_at").Time().UTC(), "%s", res.Raw)
+
+				res = send(t, ts, "PATCH", "/identities/"+id+"/verifiable-addresses", http.StatusOK, &identity.PatchVerifiableAddressBody{
+					Value:    email,
+					Verified: false,
+				})
+				assert.False(t, res.Get("verified").Bool(), "%s", res.Raw)
+				assert.EqualValues(t, identity.VerifiableAddressStatusPending, res.Get("status").String(), "%s", res.Raw)
+				assert.False(t, res.Get("verified_at").Exists(), "%s", res.Raw)
+			})
+		}
+
+		t.Run("case=fails for unknown addresses", func(t *testing.T) {
+			id, _ := create(t)
+			send(t, adminTS, "PATCH", "/identities/"+id+"/verifiable-addresses", http.StatusNotFound, &identity.PatchVerifiableAddressBody{
+				Value:    "unknown@ory.sh",
+				Verified: true,
+			})
+			send(t, adminTS, "PATCH", "/identities/"+x.NewUUID().String()+"/verifiable-addresses", http.StatusNotFound, &identity.PatchVerifiableAddressBody{
+				Value:    "unknown@ory.sh",
+				Verified: true,
+			})
+		})
+
+		t.Run("case=batch", func(t *testing.T) {
+			first, firstEmail := create(t)
+			second, secondEmail := create(t)
+
+			res := send(t, adminTS, "PATCH", "/verifiable-addresses", http.StatusOK, &identity.BatchPatchVerifiableAddressesBody{
+				Addresses: []identity.BatchVerifiableAddressPatch{
+					{IdentityID: uuid.FromStringOrNil(first), PatchVerifiableAddressBody: identity.PatchVerifiableAddressBody{Value: firstEmail, Verified: true}},
+					{IdentityID: uuid.FromStringOrNil(second), PatchVerifiableAddressBody: identity.PatchVerifiableAddressBody{Value: "unknown@ory.sh", Verified: true}},
+					{IdentityID: uuid.FromStringOrNil(second), PatchVerifiableAddressBody: identity.PatchVerifiableAddressBody{Value: secondEmail, Verified: true}},
+				},
+			})
+			require.Len(t, res.Get("addresses").Array(), 3, "%s", res.Raw)
+			assert.True(t, res.Get("addresses.0.address.verified").Bool(), "%s", res.Raw)
+			assert.EqualValues(t, http.StatusNotFound, res.Get("addresses.1.error.code").Int(), "%s", res.Raw)
+			assert.False(t, res.Get("addresses.1.address").Exists(), "%s", res.Raw)
+			assert.True(t, res.Get("addresses.2.address.verified").Bool(), "%s", res.Raw)
+
+			assert.True(t, get(t, adminTS, "/identities/"+first, http.StatusOK).Get("verifiable_addresses.0.verified").Bool())
+			assert.True(t, get(t, adminTS, "/identities/"+second, http.StatusOK).Get("verifiable_addresses.0.verified").Bool())
+		})
+
+		t.Run("case=batch fails on too many addresses", func(t *testing.T) {
+			send(t, adminTS, "PATCH", "/verifiable-addresses", http.StatusBadRequest, &identity.BatchPatchVerifiableAddressesBody{
+				Addresses: make([]identity.BatchVerifiableAddressPatch, identity.BatchPatchVerifiableAddressesLimit+1),
+			})
+		})
+	})
+
 	t.Run("case=PATCH update should not persist if schema id is invalid", func(t *testing.T) {
 		uuid := x.NewUUID().String()
 		i := &identity.Identity{Traits: identity.Traits(fmt.Sprintf(`{"subject":"%s"}`, uuid))}
@@ -912,7 +1350,7 @@ func TestHandler(t *testing.T) {
 				}
 
 				res := send(t, ts, "PATCH", "/identities/"+i.ID.String(), http.StatusBadRequest, &patch)
-				assert.EqualValues(t, "The supplied state ('invalid-value') was not valid. Valid states are ('active', 'inactive').", res.Get("error.reason").String(), "%s", res.Raw)
+				assert.EqualValues(t, "The supplied state ('invalid-value') was not valid. Valid states are ('active', 'inactive', 'pending_approval', 'deactivated', 'soft_deleted').", res.Get("error.reason").String(), "%s", res.Raw)
 
 				res = get(t, ts, "/identities/"+i.ID.String(), http.StatusOK)
 				// Assert that the schema ID is unchanged
@@ -947,6 +1385,92 @@ func TestHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("case=PATCH should check leading test operations", func(t *testing.T) {
+		for name, ts := range map[string]*httptest.Server{"public": publicTS, "admin": adminTS} {
+			t.Run("endpoint="+name, func(t *testing.T) {
+				i := &identity.Identity{Traits: identity.Traits(`{"subject":"original"}`)}
+				require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+				res := send(t, ts, "PATCH", "/identities/"+i.ID.String(), http.StatusConflict, &[]patch{
+					{"op": "test", "path": "/traits/subject", "value": "outdated"},
+					{"op": "replace", "path": "/traits/subject", "value": "patched"},
+				})
+				assert.Contains(t, res.Get("error.reason").String(), "updated concurrently", "%s", res.Raw)
+
+				res = send(t, ts, "PATCH", "/identities/"+i.ID.String(), http.StatusBadRequest, &[]patch{
+					{"op": "replace", "path": "/traits/subject", "value": "patched"},
+					{"op": "test", "path": "/traits/subject", "value": "patched"},
+				})
+				assert.Contains(t, res.Get("error.reason").String(), "Test operations must precede", "%s", res.Raw)
+
+				res = get(t, ts, "/identities/"+i.ID.String(), http.StatusOK)
+				assert.EqualValues(t, "original", res.Get("traits.subject").String(), "%s", res.Raw)
+
+				res = send(t, ts, "PATCH", "/identities/"+i.ID.String(), http.StatusOK, &[]patch{
+					{"op": "test", "path": "/traits/subject", "value": "original"},
+					{"op": "test", "path": "/state", "value": "active"},
+					{"op": "replace", "path": "/traits/subject", "value": "patched"},
+				})
+				assert.EqualValues(t, "patched", res.Get("traits.subject").String(), "%s", res.Raw)
+			})
+		}
+	})
+
+	t.Run("case=PATCH should apply merge patches", func(t *testing.T) {
+		mergePatch := func(t *testing.T, base *httptest.Server, href string, expectCode int, body string) gjson.Result {
+			t.Helper()
+			req, err := http.NewRequest("PATCH", base.URL+href, strings.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			res, err := base.Client().Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			raw, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			require.EqualValues(t, expectCode, res.StatusCode, "%s", raw)
+			return gjson.ParseBytes(raw)
+		}
+
+		for name, ts := range map[string]*httptest.Server{"public": publicTS, "admin": adminTS} {
+			t.Run("endpoint="+name, func(t *testing.T) {
+				i := &identity.Identity{
+					Traits:         identity.Traits(`{"subject":"original","bar":"baz"}`),
+					MetadataPublic: sqlxx.NullJSONRawMessage(`{"public":"original","keep":true}`),
+				}
+				require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), i))
+
+				res := mergePatch(t, ts, "/identities/"+i.ID.String(), http.StatusOK,
+					`{"traits":{"subject":"patched","bar":null},"metadata_public":{"public":"patched"},"metadata_admin":{"admin":"patched"},"state":"inactive"}`)
+				assert.EqualValues(t, "patched", res.Get("traits.subject").String(), "%s", res.Raw)
+				assert.False(t, res.Get("traits.bar").Exists(), "%s", res.Raw)
+				assert.EqualValues(t, "patched", res.Get("metadata_public.public").String(), "%s", res.Raw)
+				assert.True(t, res.Get("metadata_public.keep").Bool(), "%s", res.Raw)
+				assert.EqualValues(t, "patched", res.Get("metadata_admin.admin").String(), "%s", res.Raw)
+				assert.EqualValues(t, identity.StateInactive, res.Get("state").String(), "%s", res.Raw)
+				assert.True(t, res.Get("state_changed_at").Exists(), "%s", res.Raw)
+
+				res = get(t, ts, "/identities/"+i.ID.String(), http.StatusOK)
+				assert.EqualValues(t, "patched", res.Get("traits.subject").String(), "%s", res.Raw)
+				assert.EqualValues(t, i.SchemaID, res.Get("schema_id").String(), "%s", res.Raw)
+
+				for _, body := range []string{`{"credentials":{}}`, `{"schema_id":"does-not-exist"}`, `{"id":"` + x.NewUUID().String() + `"}`} {
+					res = mergePatch(t, ts, "/identities/"+i.ID.String(), http.StatusBadRequest, body)
+					assert.Contains(t, res.Get("error.reason").String(), "can not be updated", "%s", res.Raw)
+				}
+
+				res = mergePatch(t, ts, "/identities/"+i.ID.String(), http.StatusBadRequest, `{"traits":{"bar":123}}`)
+				assert.Contains(t, res.Get("error.reason").String(), "expected string, but got number", "%s", res.Raw)
+
+				res = mergePatch(t, ts, "/identities/"+i.ID.String(), http.StatusBadRequest, `{"state":"invalid"}`)
+				assert.Contains(t, res.Get("error.reason").String(), "The supplied state", "%s", res.Raw)
+
+				res = get(t, ts, "/identities/"+i.ID.String(), http.StatusOK)
+				assert.False(t, res.Get("traits.bar").Exists(), "%s", res.Raw)
+				assert.EqualValues(t, identity.StateInactive, res.Get("state").String(), "%s", res.Raw)
+			})
+		}
+	})
+
 	t.Run("case=PATCH should fail if no JSON payload is sent", func(t *testing.T) {
 		uuid := x.NewUUID().String()
 		i := &identity.Identity{Traits: identity.Traits(fmt.Sprintf(`{"subject":"%s"}`, uuid))}
@@ -1453,6 +1977,46 @@ func TestHandler(t *testing.T) {
 				require.NoError(t, err)
 				snapshotx.SnapshotT(t, identity.WithCredentialsAndAdminMetadataInJSON(*actual), snapshotx.ExceptNestedKeys(append(ignoreDefault, "hashed_password")...), snapshotx.ExceptPaths("credentials.oidc.identifiers"))
 			})
+			t.Run("type=list and remove single webauthn credential/"+name, func(t *testing.T) {
+				lastUsedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+				message, err := json.Marshal(identity.CredentialsWebAuthnConfig{
+					Credentials: identity.CredentialsWebAuthn{
+						{ID: []byte("key-1"), DisplayName: "phone", AddedAt: time.Date(2022, 12, 16, 14, 11, 55, 0, time.UTC), LastUsedAt: &lastUsedAt, IsPasswordless: true},
+						{ID: []byte("key-2"), DisplayName: "yubikey", AddedAt: time.Date(2022, 12, 17, 14, 11, 55, 0, time.UTC)},
+					},
+					UserHandle: []byte("Ef5JiMpMRwuzauWs/9J0gQ=="),
+				})
+				require.NoError(t, err)
+
+				i := createIdentity(map[identity.CredentialsType]string{identity.CredentialsTypeWebAuthn: string(message)})(t)
+
+				res := get(t, ts, "/identities/"+i.ID.String()+"/webauthn/credentials", http.StatusOK)
+				require.Len(t, res.Array(), 2, "%s", res.Raw)
+				assert.Equal(t, fmt.Sprintf("%x", "key-1"), res.Get("0.id").String(), "%s", res.Raw)
+				assert.Equal(t, "phone", res.Get("0.display_name").String(), "%s", res.Raw)
+				assert.Equal(t, lastUsedAt.Format(time.RFC3339), res.Get("0.last_used_at").String(), "%s", res.Raw)
+				assert.True(t, res.Get("0.is_passwordless").Bool(), "%s", res.Raw)
+				assert.False(t, res.Get("1.last_used_at").Exists(), "%s", res.Raw)
+				assert.False(t, res.Get("0.public_key").Exists(), "%s", res.Raw)
+
+				remove(t, ts, "/identities/"+i.ID.String()+"/webauthn/credentials/"+fmt.Sprintf("%x", "unknown"), http.StatusNotFound)
+				remove(t, ts, "/identities/"+i.ID.String()+"/credentials/password/"+fmt.Sprintf("%x", "key-1"), http.StatusNotFound)
+				remove(t, ts, "/identities/"+i.ID.String()+"/webauthn/credentials/"+fmt.Sprintf("%x", "key-1"), http.StatusNoContent)
+
+				res = get(t, ts, "/identities/"+i.ID.String()+"/webauthn/credentials", http.StatusOK)
+				require.Len(t, res.Array(), 1, "%s", res.Raw)
+				assert.Equal(t, "yubikey", res.Get("0.display_name").String(), "%s", res.Raw)
+
+				remove(t, ts, "/identities/"+i.ID.String()+"/webauthn/credentials/"+fmt.Sprintf("%x", "key-2"), http.StatusNoContent)
+
+				actual, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(ctx, i.ID)
+				require.NoError(t, err)
+				_, ok := actual.GetCredentials(identity.CredentialsTypeWebAuthn)
+				assert.False(t, ok)
+
+				res = get(t, ts, "/identities/"+i.ID.String()+"/webauthn/credentials", http.StatusOK)
+				assert.Empty(t, res.Array(), "%s", res.Raw)
+			})
 			for ct, ctConf := range map[identity.CredentialsType]string{
 				identity.CredentialsTypeLookup:   `{"recovery_codes": [{"code": "aaa"}]}`,
 				identity.CredentialsTypeTOTP:     `{"totp_url":"otpauth://totp/test"}`,
@@ -0,0 +1,229 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/jsonx"
+
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteIdentityVerifiableAddresses = RouteItem + "/verifiable-addresses"
+	RouteVerifiableAddresses         = "/verifiable-addresses"
+
+	BatchPatchVerifiableAddressesLimit = 2000
+)
+
+// Patch Verifiable Address Body
+//
+// swagger:model patchVerifiableAddressBody
+type PatchVerifiableAddressBody struct {
+	// Value is the value of the verifiable address, for example the email address.
+	//
+	// required: true
+	Value string `json:"value"`
+
+	// Verified sets whether the address is verified.
+	//
+	// required: true
+	Verified bool `json:"verified"`
+
+	// VerifiedAt is the time the address was verified, for example in the system the identity
+	// was migrated from. Defaults to the current time. Ignored if verified is false.
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+}
+
+// Patch Verifiable Address Parameters
+//
+// swagger:parameters patchIdentityVerifiableAddress
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type patchIdentityVerifiableAddress struct {
+	// ID must be set to the ID of identity you want to update
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	Body PatchVerifiableAddressBody
+}
+
+// swagger:route PATCH /admin/identities/{id}/verifiable-addresses identity patchIdentityVerifiableAddress
+//
+// # Set the verified state of an identity's address
+//
+// Marks one of the verifiable addresses of an [identity](https://www.ory.sh/docs/kratos/concepts/identity-user-model)
+// as verified or unverified without running a verification flow. The verification time and status are set
+// accordingly. This is useful when migrating identities whose addresses were already verified.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: verifiableIdentityAddress
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) patchIdentityVerifiableAddress(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var body PatchVerifiableAddressBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+
+	address, err := h.setAddressVerified(r, x.ParseUUID(ps.ByName("id")), &body)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, address)
+}
+
+// Batch Patch Verifiable Addresses Body
+//
+// swagger:model batchPatchVerifiableAddressesBody
+type BatchPatchVerifiableAddressesBody struct {
+	// Addresses holds the addresses to update.
+	//
+	// required: true
+	Addresses []BatchVerifiableAddressPatch `json:"addresses"`
+}
+
+// Verifiable Address Patch
+//
+// swagger:model verifiableAddressPatch
+type BatchVerifiableAddressPatch struct {
+	PatchVerifiableAddressBody
+
+	// IdentityID is the ID of the identity the address belongs to.
+	//
+	// required: true
+	IdentityID uuid.UUID `json:"identity_id"`
+}
+
+// Batch Patch Verifiable Addresses Parameters
+//
+// swagger:parameters batchPatchVerifiableAddresses
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type batchPatchVerifiableAddresses struct {
+	// in: body
+	Body BatchPatchVerifiableAddressesBody
+}
+
+// Batch Patch Verifiable Addresses Response
+//
+// swagger:model batchPatchVerifiableAddressesResponse
+type batchPatchVerifiableAddressesResponse struct {
+	// Addresses holds the results in the order of the patches.
+	Addresses []*BatchVerifiableAddressPatchResponse `json:"addresses"`
+}
+
+// Verifiable Address Patch Response
+//
+// swagger:model verifiableAddressPatchResponse
+type BatchVerifiableAddressPatchResponse struct {
+	// IdentityID is the ID of the identity of the patch.
+	IdentityID uuid.UUID `json:"identity_id"`
+
+	// Value is the address value of the patch.
+	Value string `json:"value"`
+
+	// Address is the updated address, unless the patch failed.
+	Address *VerifiableAddress `json:"address,omitempty"`
+
+	// Error describes why the patch failed.
+	Error *herodot.DefaultError `json:"error,omitempty"`
+}
+
+// swagger:route PATCH /admin/verifiable-addresses identity batchPatchVerifiableAddresses
+//
+// # Set the verified state of multiple addresses
+//
+// Marks verifiable addresses of many identities as verified or unverified without running verification flows,
+// for example when migrating identities whose addresses were already verified. Patches are applied independently,
+// the response reports the result of each patch.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: batchPatchVerifiableAddressesResponse
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) batchPatchVerifiableAddresses(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body BatchPatchVerifiableAddressesBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+
+	if len(body.Addresses) > BatchPatchVerifiableAddressesLimit {
+		h.r.Writer().WriteErrorCode(w, r, http.StatusBadRequest,
+			errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+				"The maximum number of addresses that can be updated at once is %d.",
+				BatchPatchVerifiableAddressesLimit)))
+		return
+	}
+
+	res := batchPatchVerifiableAddressesResponse{
+		Addresses: make([]*BatchVerifiableAddressPatchResponse, len(body.Addresses)),
+	}
+	for k := range body.Addresses {
+		patch := &body.Addresses[k]
+		result := &BatchVerifiableAddressPatchResponse{IdentityID: patch.IdentityID, Value: patch.Value}
+		res.Addresses[k] = result
+
+		address, err := h.setAddressVerified(r, patch.IdentityID, &patch.PatchVerifiableAddressBody)
+		if err != nil {
+			result.Error = herodot.ToDefaultError(err, "")
+			continue
+		}
+		result.Address = address
+	}
+
+	h.r.Writer().Write(w, r, &res)
+}
+
+func (h *Handler) setAddressVerified(r *http.Request, id uuid.UUID, body *PatchVerifiableAddressBody) (*VerifiableAddress, error) {
+	if body.Value == "" {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The address value must be set."))
+	}
+
+	at := time.Now().UTC()
+	if body.VerifiedAt != nil {
+		at = *body.VerifiedAt
+	}
+
+	return h.r.IdentityManager().SetAddressVerified(r.Context(), id, body.Value, body.Verified, at)
+}
@@ -31,7 +31,7 @@ import (
 
 // An Identity's State
 //
-// The state can either be `active` or `inactive`.
+// The state can either be `active`, `inactive`, `pending_approval`, `deactivated`, or `soft_deleted`.
 //
 // swagger:model identityState
 type State string
@@ -39,16 +39,37 @@ type State string
 const (
 	StateActive   State = "active"
 	StateInactive State = "inactive"
+
+	// StatePendingApproval is the state of identities which registered while
+	// `selfservice.flows.registration.approval.required` was enabled and which
+	// were not yet approved by an administrator.
+	StatePendingApproval State = "pending_approval"
+
+	// StateDeactivated is the state of identities which were deactivated by an
+	// administrator. They can neither sign in nor recover their account, but
+	// their data is retained.
+	StateDeactivated State = "deactivated"
+
+	// StateSoftDeleted is the state of identities which were deleted but not yet
+	// purged. They are hidden from identity lists, can neither sign in nor recover
+	// their account, and are purged once `identity.soft_delete.retention` passed.
+	StateSoftDeleted State = "soft_deleted"
 )
 
 func (lt State) IsValid() error {
 	switch lt {
-	case StateActive, StateInactive:
+	case StateActive, StateInactive, StatePendingApproval, StateDeactivated, StateSoftDeleted:
 		return nil
 	}
 	return errors.New("identity state is not valid")
 }
 
+// IsBlocked returns true for deactivated and soft-deleted identities, which can
+// neither sign in nor recover their account.
+func (lt State) IsBlocked() bool {
+	return lt == StateDeactivated || lt == StateSoftDeleted
+}
+
 // Identity represents an Ory Kratos identity
 //
 // An [identity](https://www.ory.sh/docs/kratos/concepts/identity-user-model) represents a (human) user in Ory.
@@ -220,6 +241,16 @@ func (i *Identity) SetCredentialsWithConfig(t CredentialsType, c Credentials, co
 	return nil
 }
 
+// SetVerifiableAddress replaces the verifiable address with the same ID, for example after it was verified.
+func (i *Identity) SetVerifiableAddress(address *VerifiableAddress) {
+	for k := range i.VerifiableAddresses {
+		if i.VerifiableAddresses[k].ID == address.ID {
+			i.VerifiableAddresses[k] = *address
+			return
+		}
+	}
+}
+
 func (i *Identity) DeleteCredentialsType(t CredentialsType) {
 	i.lock().Lock()
 	defer i.lock().Unlock()
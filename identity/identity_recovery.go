@@ -13,6 +13,7 @@ import (
 
 const (
 	RecoveryAddressTypeEmail RecoveryAddressType = AddressTypeEmail
+	RecoveryAddressTypePhone RecoveryAddressType = AddressTypePhone
 )
 
 type (
@@ -47,6 +48,8 @@ func (v RecoveryAddressType) HTMLFormInputType() string {
 	switch v {
 	case RecoveryAddressTypeEmail:
 		return "email"
+	case RecoveryAddressTypePhone:
+		return "tel"
 	}
 	return ""
 }
@@ -78,3 +81,16 @@ func NewRecoveryEmailAddress(
 		IdentityID: identity,
 	}
 }
+
+// NewRecoveryPhoneAddress returns a recovery address for the phone number, which must be in E.164
+// format already.
+func NewRecoveryPhoneAddress(
+	value string,
+	identity uuid.UUID,
+) *RecoveryAddress {
+	return &RecoveryAddress{
+		Value:      value,
+		Via:        RecoveryAddressTypePhone,
+		IdentityID: identity,
+	}
+}
@@ -314,6 +314,27 @@ func TestVerifiableAddresses(t *testing.T) {
 	assert.Equal(t, addresses, CollectVerifiableAddresses([]*Identity{id1, id2, id3}))
 }
 
+func TestSetVerifiableAddress(t *testing.T) {
+	i := NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	first := NewVerifiableEmailAddress("first@ory.sh", i.ID)
+	first.ID = x.NewUUID()
+	second := NewVerifiableEmailAddress("second@ory.sh", i.ID)
+	second.ID = x.NewUUID()
+	i.VerifiableAddresses = []VerifiableAddress{*first, *second}
+
+	verified := *second
+	verified.Verified = true
+	verified.Status = VerifiableAddressStatusCompleted
+	i.SetVerifiableAddress(&verified)
+
+	assert.Equal(t, []VerifiableAddress{*first, verified}, i.VerifiableAddresses)
+
+	unknown := NewVerifiableEmailAddress("unknown@ory.sh", i.ID)
+	unknown.ID = x.NewUUID()
+	i.SetVerifiableAddress(unknown)
+	assert.Len(t, i.VerifiableAddresses, 2)
+}
+
 func TestWithDeclassifiedCredentials(t *testing.T) {
 	i := NewIdentity(config.DefaultIdentityTraitsSchemaID)
 	credentials := map[CredentialsType]Credentials{
@@ -22,6 +22,14 @@ const (
 	VerifiableAddressStatusCompleted VerifiableAddressStatus = "completed"
 )
 
+// VerificationReminderStats is the number of addresses which received RemindersSent verification reminders
+// and are verified or not.
+type VerificationReminderStats struct {
+	RemindersSent int   `db:"reminders_sent"`
+	Verified      bool  `db:"verified"`
+	Addresses     int64 `db:"addresses"`
+}
+
 // VerifiableAddressType must not exceed 16 characters as that is the limitation in the SQL Schema
 //
 // swagger:model identityVerifiableAddressType
@@ -82,6 +90,12 @@ type VerifiableAddress struct {
 	// example: 2014-01-01T23:28:56.782Z
 	UpdatedAt time.Time `json:"updated_at" faker:"-" db:"updated_at"`
 
+	// RemindersSent is the number of verification reminders sent for this address.
+	RemindersSent int `json:"-" faker:"-" db:"reminders_sent"`
+
+	// LastRemindedAt is the time the last verification reminder was sent.
+	LastRemindedAt *sqlxx.NullTime `json:"-" faker:"-" db:"last_reminded_at"`
+
 	// IdentityID is a helper struct field for gobuffalo.pop.
 	IdentityID uuid.UUID `json:"-" faker:"-" db:"identity_id"`
 	NID        uuid.UUID `json:"-"  faker:"-" db:"nid"`
@@ -121,6 +135,20 @@ func NewVerifiablePhoneAddress(value string, identity uuid.UUID) *VerifiableAddr
 	}
 }
 
+// SetVerified marks the address as verified at the given time, or as pending verification if verified is false.
+func (a *VerifiableAddress) SetVerified(verified bool, at time.Time) {
+	a.Verified = verified
+	if !verified {
+		a.VerifiedAt = nil
+		a.Status = VerifiableAddressStatusPending
+		return
+	}
+
+	verifiedAt := sqlxx.NullTime(at.UTC())
+	a.VerifiedAt = &verifiedAt
+	a.Status = VerifiableAddressStatusCompleted
+}
+
 func (a VerifiableAddress) GetID() uuid.UUID {
 	return a.ID
 }
@@ -7,7 +7,10 @@ import (
 	"context"
 	"encoding/json"
 	"reflect"
+	"slices"
 	"sort"
+	"strings"
+	"time"
 
 	"go.opentelemetry.io/otel/trace"
 
@@ -36,6 +39,9 @@ import (
 var ErrProtectedFieldModified = herodot.ErrForbidden.
 	WithReasonf(`A field was modified that updates one or more credentials-related settings. This action was blocked because an unprivileged method was used to execute the update. This is either a configuration issue or a bug and should be reported to the system administrator.`)
 
+var ErrIdentityModified = herodot.ErrConflict.
+	WithReasonf(`The identity was updated concurrently. Fetch the identity and try again.`)
+
 type (
 	managerDependencies interface {
 		config.Provider
@@ -57,6 +63,7 @@ type (
 	ManagerOptions struct {
 		ExposeValidationErrors    bool
 		AllowWriteProtectedTraits bool
+		UnmodifiedSince           *time.Time
 	}
 
 	ManagerOption func(*ManagerOptions)
@@ -74,6 +81,14 @@ func ManagerAllowWriteProtectedTraits(options *ManagerOptions) {
 	options.AllowWriteProtectedTraits = true
 }
 
+// ManagerRequireUnmodifiedSince makes the update fail with ErrIdentityModified if the identity was updated after
+// the given time, which is the `updated_at` of the identity the update is based on.
+func ManagerRequireUnmodifiedSince(updatedAt time.Time) ManagerOption {
+	return func(options *ManagerOptions) {
+		options.UnmodifiedSince = &updatedAt
+	}
+}
+
 func newManagerOptions(opts []ManagerOption) *ManagerOptions {
 	var o ManagerOptions
 	for _, f := range opts {
@@ -167,7 +182,18 @@ func (m *Manager) ConflictingIdentity(ctx context.Context, i *Identity) (found *
 }
 
 func (m *Manager) findExistingAuthMethod(ctx context.Context, e error, i *Identity) (err error) {
-	if !m.r.Config().SelfServiceFlowRegistrationLoginHints(ctx) {
+	// Login hints and the login handoff would reveal that, and how, the conflicting account signs in.
+	// Registrations respond as if they succeeded instead and notify the conflicting account.
+	if m.r.Config().SelfServiceAccountEnumerationMitigate(ctx) {
+		found, _, err := m.ConflictingIdentity(ctx, i)
+		if err != nil && !errors.Is(err, sqlcon.ErrNoRows) {
+			return err
+		}
+		return &ErrDuplicateRegistration{error: e, existing: found}
+	}
+
+	loginHints := m.r.Config().SelfServiceFlowRegistrationLoginHints(ctx)
+	if !loginHints && !m.r.Config().SelfServiceFlowRegistrationLoginHandoff(ctx) {
 		return &ErrDuplicateCredentials{error: e}
 	}
 
@@ -179,6 +205,16 @@ func (m *Manager) findExistingAuthMethod(ctx context.Context, e error, i *Identi
 		return err
 	}
 
+	// The identifiers of OpenID Connect credentials can not be used to sign in.
+	duplicateIdentifier := foundConflictAddress
+	if c, ok := i.GetCredentials(CredentialsTypeOIDC); ok && slices.Contains(c.Identifiers, duplicateIdentifier) {
+		duplicateIdentifier = ""
+	}
+
+	if !loginHints {
+		return &ErrDuplicateCredentials{error: e, duplicateIdentifier: duplicateIdentifier}
+	}
+
 	// We need to sort the credentials for the error message to be deterministic.
 	var creds []Credentials
 	for _, cred := range found.Credentials {
@@ -204,6 +240,7 @@ func (m *Manager) findExistingAuthMethod(ctx context.Context, e error, i *Identi
 			}
 			return &ErrDuplicateCredentials{
 				error:                e,
+				duplicateIdentifier:  duplicateIdentifier,
 				availableCredentials: []CredentialsType{cred.Type},
 				identifierHint:       identifierHint,
 			}
@@ -220,6 +257,7 @@ func (m *Manager) findExistingAuthMethod(ctx context.Context, e error, i *Identi
 
 			return &ErrDuplicateCredentials{
 				error:                  e,
+				duplicateIdentifier:    duplicateIdentifier,
 				availableCredentials:   []CredentialsType{cred.Type},
 				availableOIDCProviders: available,
 				identifierHint:         foundConflictAddress,
@@ -239,6 +277,7 @@ func (m *Manager) findExistingAuthMethod(ctx context.Context, e error, i *Identi
 				if webauthn.IsPasswordless {
 					return &ErrDuplicateCredentials{
 						error:                e,
+						duplicateIdentifier:  duplicateIdentifier,
 						availableCredentials: []CredentialsType{cred.Type},
 						identifierHint:       identifierHint,
 					}
@@ -248,7 +287,7 @@ func (m *Manager) findExistingAuthMethod(ctx context.Context, e error, i *Identi
 	}
 
 	// Still not found? Return generic error.
-	return &ErrDuplicateCredentials{error: e}
+	return &ErrDuplicateCredentials{error: e, duplicateIdentifier: duplicateIdentifier}
 }
 
 type ErrDuplicateCredentials struct {
@@ -257,6 +296,7 @@ type ErrDuplicateCredentials struct {
 	availableCredentials   []CredentialsType
 	availableOIDCProviders []string
 	identifierHint         string
+	duplicateIdentifier    string
 }
 
 var _ schema.DuplicateCredentialsHinter = (*ErrDuplicateCredentials)(nil)
@@ -280,10 +320,33 @@ func (e *ErrDuplicateCredentials) AvailableOIDCProviders() []string {
 func (e *ErrDuplicateCredentials) IdentifierHint() string {
 	return e.identifierHint
 }
+
+// DuplicateIdentifier returns the identifier of the existing account which can be used to sign in, if known.
+func (e *ErrDuplicateCredentials) DuplicateIdentifier() string {
+	return e.duplicateIdentifier
+}
+
 func (e *ErrDuplicateCredentials) HasHints() bool {
 	return len(e.availableCredentials) > 0 || len(e.availableOIDCProviders) > 0 || len(e.identifierHint) > 0
 }
 
+// ErrDuplicateRegistration is returned instead of ErrDuplicateCredentials if account enumeration is
+// mitigated. Registrations must not reveal it but respond as if they succeeded.
+type ErrDuplicateRegistration struct {
+	error
+
+	existing *Identity
+}
+
+func (e *ErrDuplicateRegistration) Unwrap() error {
+	return e.error
+}
+
+// ExistingIdentity returns the identity which the registered identifiers belong to, if it was found.
+func (e *ErrDuplicateRegistration) ExistingIdentity() *Identity {
+	return e.existing
+}
+
 func (m *Manager) CreateIdentities(ctx context.Context, identities []*Identity, opts ...ManagerOption) (err error) {
 	ctx, span := m.r.Tracer(ctx).Tracer().Start(ctx, "identity.Manager.CreateIdentities")
 	defer otelx.End(span, &err)
@@ -358,6 +421,9 @@ func (m *Manager) Update(ctx context.Context, updated *Identity, opts ...Manager
 		return err
 	}
 
+	if o.UnmodifiedSince != nil {
+		return m.r.PrivilegedIdentityPool().UpdateIdentityIfUnmodifiedSince(ctx, updated, *o.UnmodifiedSince)
+	}
 	return m.r.PrivilegedIdentityPool().UpdateIdentity(ctx, updated)
 }
 
@@ -421,6 +487,40 @@ func (m *Manager) UpdateTraits(ctx context.Context, id uuid.UUID, traits Traits,
 	return m.r.PrivilegedIdentityPool().UpdateIdentity(ctx, updated)
 }
 
+// SetAddressVerified sets the verified state of the verifiable address of the identity with the given value.
+// Verified addresses are marked as verified at the given time.
+func (m *Manager) SetAddressVerified(ctx context.Context, id uuid.UUID, value string, verified bool, at time.Time) (_ *VerifiableAddress, err error) {
+	ctx, span := m.r.Tracer(ctx).Tracer().Start(ctx, "identity.Manager.SetAddressVerified")
+	defer otelx.End(span, &err)
+
+	i, err := m.r.PrivilegedIdentityPool().GetIdentity(ctx, id, Expandables{ExpandFieldVerifiableAddresses})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []string{strings.ToLower(strings.TrimSpace(value))}
+	if phone, err := x.NormalizePhoneNumber(value); err == nil {
+		candidates = append(candidates, phone)
+	}
+
+	for k := range i.VerifiableAddresses {
+		address := &i.VerifiableAddresses[k]
+		if !slices.Contains(candidates, address.Value) {
+			continue
+		}
+
+		address.SetVerified(verified, at)
+		if err := m.r.PrivilegedIdentityPool().UpdateVerifiableAddress(ctx, address); err != nil {
+			return nil, err
+		}
+
+		trace.SpanFromContext(ctx).AddEvent(events.NewIdentityUpdated(ctx, id))
+		return address, nil
+	}
+
+	return nil, errors.WithStack(herodot.ErrNotFound.WithReasonf("The identity has no verifiable address %q.", value))
+}
+
 func (m *Manager) ValidateIdentity(ctx context.Context, i *Identity, o *ManagerOptions) (err error) {
 	// This trace is more noisy than it's worth in diagnostic power.
 	// ctx, span := m.r.Tracer(ctx).Tracer().Start(ctx, "identity.Manager.validate")
@@ -290,6 +290,27 @@ func TestManager(t *testing.T) {
 			t.Run("case=recovery address", func(t *testing.T) {
 				runAddress(t, "email_recovery")
 			})
+
+			t.Run("case=does not hint if account enumeration is mitigated", func(t *testing.T) {
+				conf.MustSet(ctx, config.ViperKeySelfServiceAccountEnumerationMitigate, true)
+				t.Cleanup(func() {
+					conf.MustSet(ctx, config.ViperKeySelfServiceAccountEnumerationMitigate, false)
+				})
+
+				email := uuid.Must(uuid.NewV4()).String() + "@ory.sh"
+				first := createIdentity(email, "email_verify", nil)
+				require.NoError(t, reg.IdentityManager().Create(context.Background(), first))
+
+				second := createIdentity(email, "email_verify", nil)
+				err := reg.IdentityManager().Create(context.Background(), second)
+				require.Error(t, err)
+
+				assert.False(t, errors.As(err, new(*identity.ErrDuplicateCredentials)))
+				var derr *identity.ErrDuplicateRegistration
+				require.ErrorAs(t, err, &derr)
+				require.NotNil(t, derr.ExistingIdentity())
+				assert.Equal(t, first.ID, derr.ExistingIdentity().ID)
+			})
 		})
 	})
 
@@ -379,6 +400,28 @@ func TestManager(t *testing.T) {
 			checkExtensionFields(fromStore, "email-update-1@ory.sh")(t)
 		})
 
+		t.Run("case=should not update the identity if it was modified since", func(t *testing.T) {
+			original := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+			original.Traits = newTraits("email-modified-1@ory.sh", "")
+			require.NoError(t, reg.IdentityManager().Create(context.Background(), original))
+
+			fetched, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), original.ID)
+			require.NoError(t, err)
+			updatedAt := fetched.UpdatedAt
+
+			fetched.Traits = newTraits("email-modified-2@ory.sh", "")
+			require.NoError(t, reg.IdentityManager().Update(context.Background(), fetched, identity.ManagerAllowWriteProtectedTraits, identity.ManagerRequireUnmodifiedSince(updatedAt)))
+
+			original.Traits = newTraits("email-modified-3@ory.sh", "")
+			err = reg.IdentityManager().Update(context.Background(), original, identity.ManagerAllowWriteProtectedTraits, identity.ManagerRequireUnmodifiedSince(updatedAt))
+			require.Error(t, err)
+			assert.Equal(t, identity.ErrIdentityModified, errors.Cause(err))
+
+			fromStore, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), original.ID)
+			require.NoError(t, err)
+			checkExtensionFields(fromStore, "email-modified-2@ory.sh")(t)
+		})
+
 		t.Run("case=should update unprotected traits with multiple credential identifiers", func(t *testing.T) {
 			original := identity.NewIdentity(extensionSchemaID)
 			original.Traits = identity.Traits(`{"email": "email-update-ewisdfuja@ory.sh", "names": ["username1", "username2"], "age": 30}`)
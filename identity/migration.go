@@ -0,0 +1,263 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/fetcher"
+	"github.com/ory/x/jsonnetsecure"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlxx"
+)
+
+// MigrateIdentitiesMaxPageSize is the maximum number of identities migrated at once.
+const MigrateIdentitiesMaxPageSize = 1000
+
+type (
+	migratorDependencies interface {
+		config.Provider
+		PoolProvider
+		ManagementProvider
+		x.HTTPClientProvider
+		x.TracingProvider
+		jsonnetsecure.VMProvider
+	}
+	MigratorProvider interface {
+		IdentityMigrator() *Migrator
+	}
+	Migrator struct {
+		r migratorDependencies
+	}
+
+	// Migrate Identities Request Body
+	//
+	// swagger:model migrateIdentitiesBody
+	MigrateIdentitiesBody struct {
+		// From is the ID of the identity schema the identities are migrated from.
+		//
+		// required: true
+		From string `json:"from"`
+
+		// To is the ID of the identity schema the identities are migrated to. The migration from `from` to `to`
+		// must be configured in `identity.migrations`, unless both are equal. In that case, the identities are
+		// only validated against the current version of their identity schema.
+		//
+		// required: true
+		To string `json:"to"`
+
+		// DryRun validates the migrated identities without storing them.
+		DryRun bool `json:"dry_run"`
+
+		// PageSize is the number of identities migrated at once. Defaults to 250.
+		PageSize int `json:"page_size"`
+
+		// PageToken continues the migration with the next page of identities.
+		PageToken string `json:"page_token"`
+	}
+
+	// Identity Migration Report
+	//
+	// swagger:model identityMigrationReport
+	MigrationReport struct {
+		// Processed is the number of identities of the source schema on this page.
+		Processed int `json:"processed"`
+
+		// Migrated is the number of identities that were migrated, or would have been migrated during a dry run.
+		Migrated int `json:"migrated"`
+
+		// Failed contains the identities which could not be migrated, for example because they do not match
+		// the target identity schema.
+		Failed []MigrationFailure `json:"failed"`
+
+		// NextPageToken must be passed as `page_token` to migrate the next page of identities. It is empty
+		// once all identities were processed.
+		NextPageToken string `json:"next_page_token,omitempty"`
+	}
+
+	// Identity Migration Failure
+	//
+	// swagger:model identityMigrationFailure
+	MigrationFailure struct {
+		// IdentityID is the ID of the identity which could not be migrated.
+		IdentityID uuid.UUID `json:"identity_id"`
+
+		// Error describes why the identity could not be migrated.
+		Error *herodot.DefaultError `json:"error"`
+	}
+
+	// migrationDocument contains the fields of an identity which can be changed by a JSON Patch migration.
+	migrationDocument struct {
+		Traits         Traits                   `json:"traits"`
+		MetadataPublic sqlxx.NullJSONRawMessage `json:"metadata_public"`
+		MetadataAdmin  sqlxx.NullJSONRawMessage `json:"metadata_admin"`
+	}
+
+	migrationTransform func(ctx context.Context, i *Identity) error
+)
+
+func NewMigrator(r migratorDependencies) *Migrator {
+	return &Migrator{r: r}
+}
+
+// MigrateIdentities migrates one page of the identities which use the source identity schema. Identities which
+// can not be migrated are left unchanged and are reported.
+func (m *Migrator) MigrateIdentities(ctx context.Context, body *MigrateIdentitiesBody) (_ *MigrationReport, err error) {
+	ctx, span := m.r.Tracer(ctx).Tracer().Start(ctx, "identity.Migrator.MigrateIdentities")
+	defer otelx.End(span, &err)
+
+	if body.From == "" || body.To == "" {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The source and the target identity schema must be set."))
+	}
+	if body.PageSize < 0 || body.PageSize > MigrateIdentitiesMaxPageSize {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The page size must be between 1 and %d.", MigrateIdentitiesMaxPageSize))
+	}
+
+	transform, err := m.transform(ctx, body.From, body.To)
+	if err != nil {
+		return nil, err
+	}
+
+	var pagination []keysetpagination.Option
+	if body.PageSize > 0 {
+		pagination = append(pagination, keysetpagination.WithSize(body.PageSize))
+	}
+	if body.PageToken != "" {
+		pagination = append(pagination, keysetpagination.WithToken(keysetpagination.StringPageToken(body.PageToken)))
+	}
+
+	is, next, err := m.r.IdentityPool().ListIdentities(ctx, ListIdentityParameters{
+		Expand:           ExpandEverything,
+		SchemaIDFilter:   body.From,
+		KeySetPagination: pagination,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MigrationReport{Processed: len(is), Failed: []MigrationFailure{}}
+	if !next.IsLast() {
+		report.NextPageToken = next.Token().Encode()
+	}
+
+	for k := range is {
+		if err := m.migrateIdentity(ctx, &is[k], body, transform); err != nil {
+			report.Failed = append(report.Failed, MigrationFailure{IdentityID: is[k].ID, Error: herodot.ToDefaultError(err, "")})
+			continue
+		}
+		report.Migrated++
+	}
+
+	return report, nil
+}
+
+func (m *Migrator) migrateIdentity(ctx context.Context, i *Identity, body *MigrateIdentitiesBody, transform migrationTransform) error {
+	if err := transform(ctx, i); err != nil {
+		return err
+	}
+
+	i.SchemaID = body.To
+	if body.DryRun {
+		return m.r.IdentityManager().ValidateIdentity(ctx, i, newManagerOptions(nil))
+	}
+
+	return m.r.IdentityManager().Update(ctx, i, ManagerAllowWriteProtectedTraits)
+}
+
+func (m *Migrator) transform(ctx context.Context, from, to string) (migrationTransform, error) {
+	migrations, err := m.r.Config().IdentitySchemaMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var migration *config.SchemaMigration
+	for k := range migrations {
+		if migrations[k].From == from && migrations[k].To == to {
+			migration = &migrations[k]
+			break
+		}
+	}
+
+	if migration == nil && from == to {
+		// Without a migration, the identities are only validated against the current version of their schema.
+		return func(context.Context, *Identity) error { return nil }, nil
+	} else if migration == nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("No identity schema migration from %q to %q is configured.", from, to))
+	}
+
+	fetch := fetcher.NewFetcher(fetcher.WithClient(m.r.HTTPClient(ctx)))
+	switch {
+	case migration.Jsonnet != "":
+		template, err := fetch.FetchContext(ctx, migration.Jsonnet)
+		if err != nil {
+			return nil, err
+		}
+		return m.jsonnetTransform(migration.Jsonnet, template.String()), nil
+	case migration.JSONPatch != "":
+		patch, err := fetch.FetchContext(ctx, migration.JSONPatch)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPatchTransform(patch.Bytes()), nil
+	}
+
+	return func(context.Context, *Identity) error { return nil }, nil
+}
+
+func (m *Migrator) jsonnetTransform(url, template string) migrationTransform {
+	return func(ctx context.Context, i *Identity) error {
+		input, err := json.Marshal(WithAdminMetadataInJSON(*i))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		vm, err := m.r.JsonnetVM(ctx)
+		if err != nil {
+			return err
+		}
+
+		vm.ExtCode("identity", string(input))
+		evaluated, err := vm.EvaluateAnonymousSnippet(url, template)
+		if err != nil {
+			return errors.WithStack(herodot.ErrBadRequest.WithReason("Unable to evaluate the Jsonnet template of the identity schema migration.").WithDebug(err.Error()))
+		}
+
+		traits := gjson.Get(evaluated, "identity.traits")
+		if !traits.IsObject() {
+			return errors.WithStack(herodot.ErrBadRequest.WithReason("The Jsonnet template of the identity schema migration must return the traits of the identity in identity.traits."))
+		}
+
+		i.Traits = Traits(traits.Raw)
+		if v := gjson.Get(evaluated, "identity.metadata_public"); v.Exists() {
+			i.MetadataPublic = sqlxx.NullJSONRawMessage(v.Raw)
+		}
+		if v := gjson.Get(evaluated, "identity.metadata_admin"); v.Exists() {
+			i.MetadataAdmin = sqlxx.NullJSONRawMessage(v.Raw)
+		}
+
+		return nil
+	}
+}
+
+func jsonPatchTransform(patch []byte) migrationTransform {
+	return func(_ context.Context, i *Identity) error {
+		doc := migrationDocument{Traits: i.Traits, MetadataPublic: i.MetadataPublic, MetadataAdmin: i.MetadataAdmin}
+		if err := jsonx.ApplyJSONPatch(patch, &doc); err != nil {
+			return errors.WithStack(herodot.ErrBadRequest.WithReason("Unable to apply the JSON Patch of the identity schema migration.").WithDebug(err.Error()))
+		}
+
+		i.Traits, i.MetadataPublic, i.MetadataAdmin = doc.Traits, doc.MetadataPublic, doc.MetadataAdmin
+		return nil
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+)
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) *driver.RegistryDefault {
+		conf, reg := internal.NewFastRegistryWithMocks(t)
+		testhelpers.SetIdentitySchemas(t, conf, map[string]string{
+			"default": "file://./stub/migration/v1.schema.json",
+			"v1":      "file://./stub/migration/v1.schema.json",
+			"v2":      "file://./stub/migration/v2.schema.json",
+			"v3":      "file://./stub/migration/v3.schema.json",
+		})
+		conf.MustSet(ctx, config.ViperKeyIdentitySchemaMigrations, []config.SchemaMigration{
+			{From: "v1", To: "v2", Jsonnet: "file://./stub/migration/v2.migration.jsonnet"},
+			{From: "v1", To: "v3", JSONPatch: "file://./stub/migration/v3.migration.patch.json"},
+		})
+		return reg
+	}
+
+	create := func(t *testing.T, reg *driver.RegistryDefault, traits string) *identity.Identity {
+		i := identity.NewIdentity("v1")
+		i.Traits = identity.Traits(traits)
+		require.NoError(t, reg.IdentityManager().Create(ctx, i))
+		return i
+	}
+
+	migrateAll := func(t *testing.T, reg *driver.RegistryDefault, body identity.MigrateIdentitiesBody) (processed, migrated int, failed []identity.MigrationFailure) {
+		for {
+			report, err := reg.IdentityMigrator().MigrateIdentities(ctx, &body)
+			require.NoError(t, err)
+			processed, migrated, failed = processed+report.Processed, migrated+report.Migrated, append(failed, report.Failed...)
+			if report.NextPageToken == "" {
+				return
+			}
+			body.PageToken = report.NextPageToken
+		}
+	}
+
+	t.Run("case=migrates identities using jsonnet", func(t *testing.T) {
+		reg := setup(t)
+		jane := create(t, reg, `{"email":"jane@ory.sh","name":"Jane Doe"}`)
+		john := create(t, reg, `{"email":"john@ory.sh","name":"John"}`)
+		invalid := create(t, reg, `{"email":"invalid@ory.sh"}`)
+
+		t.Run("dry run reports failures without changing identities", func(t *testing.T) {
+			processed, migrated, failed := migrateAll(t, reg, identity.MigrateIdentitiesBody{From: "v1", To: "v2", DryRun: true})
+			assert.Equal(t, 3, processed)
+			assert.Equal(t, 2, migrated)
+			require.Len(t, failed, 1)
+			assert.Equal(t, invalid.ID, failed[0].IdentityID)
+			assert.Equal(t, 400, failed[0].Error.CodeField)
+
+			actual, err := reg.IdentityPool().GetIdentity(ctx, jane.ID, identity.ExpandNothing)
+			require.NoError(t, err)
+			assert.Equal(t, "v1", actual.SchemaID)
+			assert.Equal(t, "Jane Doe", gjson.GetBytes(actual.Traits, "name").String())
+		})
+
+		t.Run("migrates identities page by page", func(t *testing.T) {
+			processed, migrated, failed := migrateAll(t, reg, identity.MigrateIdentitiesBody{From: "v1", To: "v2", PageSize: 2})
+			assert.Equal(t, 3, processed)
+			assert.Equal(t, 2, migrated)
+			require.Len(t, failed, 1)
+			assert.Equal(t, invalid.ID, failed[0].IdentityID)
+
+			actual, err := reg.IdentityPool().GetIdentity(ctx, jane.ID, identity.ExpandNothing)
+			require.NoError(t, err)
+			assert.Equal(t, "v2", actual.SchemaID)
+			assert.JSONEq(t, `{"email":"jane@ory.sh","name":{"first":"Jane","last":"Doe"}}`, string(actual.Traits))
+			assert.JSONEq(t, `{"migrated_from":"v1"}`, string(actual.MetadataAdmin))
+
+			actual, err = reg.IdentityPool().GetIdentity(ctx, john.ID, identity.ExpandNothing)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"email":"john@ory.sh","name":{"first":"John","last":""}}`, string(actual.Traits))
+
+			actual, err = reg.IdentityPool().GetIdentity(ctx, invalid.ID, identity.ExpandNothing)
+			require.NoError(t, err)
+			assert.Equal(t, "v1", actual.SchemaID)
+		})
+
+		t.Run("only the failed identities remain", func(t *testing.T) {
+			processed, migrated, failed := migrateAll(t, reg, identity.MigrateIdentitiesBody{From: "v1", To: "v2"})
+			assert.Equal(t, 1, processed)
+			assert.Equal(t, 0, migrated)
+			assert.Len(t, failed, 1)
+		})
+	})
+
+	t.Run("case=migrates identities using a json patch", func(t *testing.T) {
+		reg := setup(t)
+		valid := create(t, reg, `{"email":"valid@ory.sh"}`)
+		invalid := create(t, reg, `{"email":"invalid@ory.sh","name":"Not allowed in v3"}`)
+
+		processed, migrated, failed := migrateAll(t, reg, identity.MigrateIdentitiesBody{From: "v1", To: "v3"})
+		assert.Equal(t, 2, processed)
+		assert.Equal(t, 1, migrated)
+		require.Len(t, failed, 1)
+		assert.Equal(t, invalid.ID, failed[0].IdentityID)
+
+		actual, err := reg.IdentityPool().GetIdentity(ctx, valid.ID, identity.ExpandNothing)
+		require.NoError(t, err)
+		assert.Equal(t, "v3", actual.SchemaID)
+		assert.JSONEq(t, `{"email":"valid@ory.sh","newsletter":false}`, string(actual.Traits))
+		assert.JSONEq(t, `{"newsletter_migrated":true}`, string(actual.MetadataPublic))
+	})
+
+	t.Run("case=validates identities against their current schema", func(t *testing.T) {
+		reg := setup(t)
+		create(t, reg, `{"email":"jane@ory.sh"}`)
+		create(t, reg, `{"email":"john@ory.sh","name":"John"}`)
+
+		processed, migrated, failed := migrateAll(t, reg, identity.MigrateIdentitiesBody{From: "v1", To: "v1", DryRun: true})
+		assert.Equal(t, 2, processed)
+		assert.Equal(t, 2, migrated)
+		assert.Empty(t, failed)
+
+		// Simulate an incompatible change of the identity schema, which requires the newsletter trait.
+		testhelpers.SetIdentitySchemas(t, reg.Config(), map[string]string{
+			"default": "file://./stub/migration/v1.schema.json",
+			"v1":      "file://./stub/migration/v3.schema.json",
+		})
+
+		processed, migrated, failed = migrateAll(t, reg, identity.MigrateIdentitiesBody{From: "v1", To: "v1", DryRun: true})
+		assert.Equal(t, 2, processed)
+		assert.Equal(t, 0, migrated)
+		assert.Len(t, failed, 2)
+	})
+
+	t.Run("case=rejects invalid requests", func(t *testing.T) {
+		reg := setup(t)
+		for _, body := range []identity.MigrateIdentitiesBody{
+			{From: "v2", To: "v3"},
+			{From: "v1"},
+			{From: "v1", To: "v2", PageSize: identity.MigrateIdentitiesMaxPageSize + 1},
+		} {
+			_, err := reg.IdentityMigrator().MigrateIdentities(ctx, &body)
+			assert.ErrorIs(t, err, herodot.ErrBadRequest, "%+v", body)
+		}
+	})
+}
@@ -5,6 +5,7 @@ package identity
 
 import (
 	"context"
+	"time"
 
 	"github.com/ory/x/crdbx"
 
@@ -19,8 +20,11 @@ type (
 	ListIdentityParameters struct {
 		Expand                       Expandables
 		IdsFilter                    []string
+		StateFilter                  State
+		SchemaIDFilter               string
 		CredentialsIdentifier        string
 		CredentialsIdentifierSimilar string
+		TraitQueries                 []TraitQuery
 		KeySetPagination             []keysetpagination.Option
 		// DEPRECATED
 		PagePagination   *x.Page
@@ -59,6 +63,10 @@ type (
 		// FindByCredentialsIdentifier returns an identity by querying for it's credential identifiers.
 		FindByCredentialsIdentifier(ctx context.Context, ct CredentialsType, match string) (*Identity, *Credentials, error)
 
+		// FindIdentityByWebAuthnUserHandle returns the identity whose WebAuthn credentials carry the user handle, including
+		// its credentials. Will return sql.ErrNoRows if no identity could be found.
+		FindIdentityByWebAuthnUserHandle(ctx context.Context, userHandle []byte) (*Identity, error)
+
 		// DeleteIdentity removes an identity by its id. Will return an error
 		// if identity exists, backend connectivity is broken, or trait validation fails.
 		DeleteIdentity(context.Context, uuid.UUID) error
@@ -77,6 +85,16 @@ type (
 		// UpdateIdentity updates an identity including its confidential / privileged / protected data.
 		UpdateIdentity(context.Context, *Identity) error
 
+		// UpdateIdentityIfUnmodifiedSince updates an identity like UpdateIdentity, but only if it was not updated after
+		// updatedAt. Will return ErrIdentityModified otherwise.
+		UpdateIdentityIfUnmodifiedSince(ctx context.Context, i *Identity, updatedAt time.Time) error
+
+		// UpdateIdentityCredentialsConfig replaces the configuration of the identity's credentials of the given type
+		// with the one update returns without touching the rest of the identity. The credentials are locked while update
+		// computes the new configuration from the current one, so that concurrent changes are not lost. Will return
+		// sql.ErrNoRows if the identity has no such credentials.
+		UpdateIdentityCredentialsConfig(ctx context.Context, identityID uuid.UUID, ct CredentialsType, update func(config sqlxx.JSONRawMessage) (sqlxx.JSONRawMessage, error)) error
+
 		// GetIdentityConfidential returns the identity including it's raw credentials. This should only be used internally.
 		GetIdentityConfidential(context.Context, uuid.UUID) (*Identity, error)
 
@@ -84,6 +102,19 @@ type (
 		// or not.
 		ListVerifiableAddresses(ctx context.Context, page, itemsPerPage int) ([]VerifiableAddress, error)
 
+		// ListUnverifiedAddresses lists up to limit unverified addresses which were created before createdBefore,
+		// ordered by their ID and starting after the given ID.
+		ListUnverifiedAddresses(ctx context.Context, createdBefore time.Time, after uuid.UUID, limit int) ([]VerifiableAddress, error)
+
+		// ClaimVerifiableAddressReminder stores the address' reminders_sent and last_reminded_at, but only if
+		// reminders_sent is still previous. Will return sql.ErrNoRows otherwise, so that a reminder is only sent once
+		// even if several instances send reminders at the same time.
+		ClaimVerifiableAddressReminder(ctx context.Context, address *VerifiableAddress, previous int) error
+
+		// VerificationReminderStats counts the addresses which received verification reminders, grouped by the
+		// number of reminders sent and whether the address was verified.
+		VerificationReminderStats(ctx context.Context) ([]VerificationReminderStats, error)
+
 		// ListRecoveryAddresses lists all tracked recovery addresses.
 		ListRecoveryAddresses(ctx context.Context, page, itemsPerPage int) ([]RecoveryAddress, error)
 
@@ -95,5 +126,18 @@ type (
 
 		// FindIdentityByAnyCaseSensitiveCredentialIdentifier returns an identity by matching the identifier to any of the identity's credentials.
 		FindIdentityByCredentialIdentifier(ctx context.Context, identifier string, caseSensitive bool) (*Identity, error)
+
+		// ListAuditEvents lists the audit events matching the filter, most recent first.
+		ListAuditEvents(ctx context.Context, filter AuditEventFilter, page, itemsPerPage int) ([]AuditEvent, int64, error)
+
+		// ClaimLifecycleEvents returns undelivered lifecycle events which are due and postpones their next delivery
+		// attempt by the lease.
+		ClaimLifecycleEvents(ctx context.Context, limit, maxAttempts int, lease time.Duration) ([]LifecycleEvent, error)
+
+		// MarkLifecycleEventDelivered marks the lifecycle event as delivered to all sinks.
+		MarkLifecycleEventDelivered(ctx context.Context, id uuid.UUID) error
+
+		// MarkLifecycleEventFailed records a failed delivery attempt and schedules the next one.
+		MarkLifecycleEventFailed(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time, reason string) error
 	}
 )
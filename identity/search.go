@@ -0,0 +1,80 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/schema"
+)
+
+// SearchTrait is an indexed value of a searchable trait of an identity.
+type SearchTrait struct {
+	ID         uuid.UUID `db:"id"`
+	NID        uuid.UUID `db:"nid"`
+	IdentityID uuid.UUID `db:"identity_id"`
+	Trait      string    `db:"trait"`
+	Value      string    `db:"value"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+func (SearchTrait) TableName(context.Context) string {
+	return "identity_search_traits"
+}
+
+// TraitQueryOperator is the operator of a trait query.
+type TraitQueryOperator string
+
+const (
+	// TraitQueryEqual matches trait values which are equal to the term.
+	TraitQueryEqual TraitQueryOperator = "eq"
+
+	// TraitQueryPrefix matches trait values which start with the term.
+	TraitQueryPrefix TraitQueryOperator = "prefix"
+
+	// TraitQueryContains matches trait values which contain the term. It is only allowed for traits
+	// annotated with `"search": {"contains": true}`.
+	TraitQueryContains TraitQueryOperator = "contains"
+)
+
+// TraitQuery matches identities by the value of a searchable trait. Searches are case-insensitive.
+type TraitQuery struct {
+	Trait    string
+	Operator TraitQueryOperator
+	Term     string
+}
+
+// ParseTraitQueries parses queries of the form `<trait>:<operator>:<term>`, for example `emails:eq:foo@ory.sh`
+// or `name.first:prefix:ae`.
+func ParseTraitQueries(raw []string) ([]TraitQuery, error) {
+	queries := make([]TraitQuery, 0, len(raw))
+	for _, r := range raw {
+		parts := strings.SplitN(r, ":", 3)
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+				"The query %q is invalid. Queries must have the format <trait>:<operator>:<term>.", r))
+		}
+
+		q := TraitQuery{Trait: parts[0], Operator: TraitQueryOperator(parts[1]), Term: schema.NormalizeSearchValue(parts[2])}
+		switch q.Operator {
+		case TraitQueryEqual, TraitQueryPrefix, TraitQueryContains:
+		default:
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+				"The query %q uses the unknown operator %q. Supported operators are eq, prefix, and contains.", r, parts[1]))
+		}
+		if q.Term == "" {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The query %q must not have an empty term.", r))
+		}
+
+		queries = append(queries, q)
+	}
+	return queries, nil
+}
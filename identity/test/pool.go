@@ -17,6 +17,7 @@ import (
 
 	"github.com/go-faker/faker/v4"
 	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tidwall/gjson"
@@ -30,6 +31,7 @@ import (
 	"github.com/ory/x/assertx"
 	"github.com/ory/x/errorsx"
 	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/pointerx"
 	"github.com/ory/x/randx"
 	"github.com/ory/x/sqlcon"
 	"github.com/ory/x/sqlxx"
@@ -535,6 +537,105 @@ func TestPool(ctx context.Context, conf *config.Config, p persistence.Persister,
 			})
 		})
 
+		t.Run("case=update an identity if it was not modified", func(t *testing.T) {
+			initial := oidcIdentity("", x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(ctx, initial))
+			createdIDs = append(createdIDs, initial.ID)
+
+			fetched, err := p.GetIdentityConfidential(ctx, initial.ID)
+			require.NoError(t, err)
+
+			fetched.Traits = identity.Traits(`{"update":"me"}`)
+			require.NoError(t, p.UpdateIdentityIfUnmodifiedSince(ctx, fetched, fetched.UpdatedAt))
+
+			actual, err := p.GetIdentityConfidential(ctx, initial.ID)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"update":"me"}`, string(actual.Traits))
+			assert.NotEqual(t, fetched.UpdatedAt, actual.UpdatedAt)
+
+			t.Run("fails if the identity was modified since", func(t *testing.T) {
+				stale := actual.CopyWithoutCredentials()
+				actual.Traits = identity.Traits(`{"update":"again"}`)
+				require.NoError(t, p.UpdateIdentity(ctx, actual))
+
+				stale.Traits = identity.Traits(`{"update":"stale"}`)
+				require.ErrorIs(t, p.UpdateIdentityIfUnmodifiedSince(ctx, stale, stale.UpdatedAt), identity.ErrIdentityModified)
+
+				actual, err := p.GetIdentityConfidential(ctx, initial.ID)
+				require.NoError(t, err)
+				assert.JSONEq(t, `{"update":"again"}`, string(actual.Traits))
+			})
+
+			t.Run("fails on different network", func(t *testing.T) {
+				_, p := testhelpers.NewNetwork(t, ctx, p)
+				require.Error(t, p.UpdateIdentityIfUnmodifiedSince(ctx, actual, actual.UpdatedAt))
+			})
+		})
+
+		t.Run("case=update the config of an identity's credentials", func(t *testing.T) {
+			replace := func(config string) func(sqlxx.JSONRawMessage) (sqlxx.JSONRawMessage, error) {
+				return func(sqlxx.JSONRawMessage) (sqlxx.JSONRawMessage, error) {
+					return sqlxx.JSONRawMessage(config), nil
+				}
+			}
+
+			initial := oidcIdentity("", x.NewUUID().String())
+			require.NoError(t, p.CreateIdentity(ctx, initial))
+			createdIDs = append(createdIDs, initial.ID)
+
+			require.NoError(t, p.UpdateIdentityCredentialsConfig(ctx, initial.ID, identity.CredentialsTypeOIDC, replace(`{"updated":true}`)))
+
+			actual, err := p.GetIdentityConfidential(ctx, initial.ID)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"updated":true}`, string(actual.Credentials[identity.CredentialsTypeOIDC].Config))
+			assert.Equal(t, initial.Credentials[identity.CredentialsTypeOIDC].Identifiers, actual.Credentials[identity.CredentialsTypeOIDC].Identifiers)
+
+			require.ErrorIs(t, p.UpdateIdentityCredentialsConfig(ctx, initial.ID, identity.CredentialsTypePassword, replace(`{}`)), sqlcon.ErrNoRows)
+
+			t.Run("case=updates the current config instead of a stale one", func(t *testing.T) {
+				stale, err := p.GetIdentityConfidential(ctx, initial.ID)
+				require.NoError(t, err)
+
+				// The credentials are changed concurrently, e.g. in a settings flow.
+				changed, err := p.GetIdentityConfidential(ctx, initial.ID)
+				require.NoError(t, err)
+				changed.SetCredentials(identity.CredentialsTypeOIDC, identity.Credentials{
+					Type:        identity.CredentialsTypeOIDC,
+					Identifiers: changed.Credentials[identity.CredentialsTypeOIDC].Identifiers,
+					Config:      sqlxx.JSONRawMessage(`{"updated":true,"changed":true}`),
+				})
+				require.NoError(t, p.UpdateIdentity(ctx, changed))
+
+				require.NoError(t, p.UpdateIdentityCredentialsConfig(ctx, stale.ID, identity.CredentialsTypeOIDC, func(config sqlxx.JSONRawMessage) (sqlxx.JSONRawMessage, error) {
+					assert.JSONEq(t, `{"updated":true,"changed":true}`, string(config))
+					return sqlxx.JSONRawMessage(`{"updated":true,"changed":true,"used":true}`), nil
+				}))
+
+				actual, err := p.GetIdentityConfidential(ctx, initial.ID)
+				require.NoError(t, err)
+				assert.JSONEq(t, `{"updated":true,"changed":true,"used":true}`, string(actual.Credentials[identity.CredentialsTypeOIDC].Config))
+			})
+
+			t.Run("case=keeps the config if the update fails", func(t *testing.T) {
+				before, err := p.GetIdentityConfidential(ctx, initial.ID)
+				require.NoError(t, err)
+
+				expectedErr := errors.New("update failed")
+				require.ErrorIs(t, p.UpdateIdentityCredentialsConfig(ctx, initial.ID, identity.CredentialsTypeOIDC, func(sqlxx.JSONRawMessage) (sqlxx.JSONRawMessage, error) {
+					return nil, expectedErr
+				}), expectedErr)
+
+				actual, err := p.GetIdentityConfidential(ctx, initial.ID)
+				require.NoError(t, err)
+				assert.JSONEq(t, string(before.Credentials[identity.CredentialsTypeOIDC].Config), string(actual.Credentials[identity.CredentialsTypeOIDC].Config))
+			})
+
+			t.Run("fails on different network", func(t *testing.T) {
+				_, p := testhelpers.NewNetwork(t, ctx, p)
+				require.ErrorIs(t, p.UpdateIdentityCredentialsConfig(ctx, initial.ID, identity.CredentialsTypeOIDC, replace(`{}`)), sqlcon.ErrNoRows)
+			})
+		})
+
 		t.Run("case=fail to update because validation fails", func(t *testing.T) {
 			initial := oidcIdentity("", x.NewUUID().String())
 
@@ -877,6 +978,91 @@ func TestPool(ctx context.Context, conf *config.Config, p persistence.Persister,
 			})
 		})
 
+		t.Run("case=find identity by its webauthn user handle", func(t *testing.T) {
+			webAuthnIdentity := func(userHandle []byte, identifier string) *identity.Identity {
+				i := identity.NewIdentity("")
+				i.Traits = identity.Traits(`{}`)
+				i.SetCredentials(identity.CredentialsTypeWebAuthn, identity.Credentials{
+					Type:        identity.CredentialsTypeWebAuthn,
+					Identifiers: []string{identifier},
+					Config:      sqlxx.JSONRawMessage(`{"user_handle":"` + base64.StdEncoding.EncodeToString(userHandle) + `","credentials":[]}`),
+				})
+				require.NoError(t, p.CreateIdentity(ctx, i))
+				createdIDs = append(createdIDs, i.ID)
+				return i
+			}
+
+			userHandle := []byte(randx.MustString(64, randx.AlphaNum))
+			expected := webAuthnIdentity(userHandle, "find-webauthn-user-handle@ory.sh")
+
+			actual, err := p.FindIdentityByWebAuthnUserHandle(ctx, userHandle)
+			require.NoError(t, err)
+			assert.Equal(t, expected.ID, actual.ID)
+			creds, ok := actual.GetCredentials(identity.CredentialsTypeWebAuthn)
+			require.True(t, ok)
+			assert.Equal(t, expected.Credentials[identity.CredentialsTypeWebAuthn].ID, creds.ID)
+			assert.Equal(t, []string{"find-webauthn-user-handle@ory.sh"}, creds.Identifiers, "the user handle is not a credentials identifier")
+
+			_, err = p.FindIdentityByWebAuthnUserHandle(ctx, []byte(randx.MustString(64, randx.AlphaNum)))
+			require.ErrorIs(t, err, sqlcon.ErrNoRows)
+
+			t.Run("not if on another network", func(t *testing.T) {
+				_, p := testhelpers.NewNetwork(t, ctx, p)
+				_, err := p.FindIdentityByWebAuthnUserHandle(ctx, userHandle)
+				require.ErrorIs(t, err, sqlcon.ErrNoRows)
+			})
+
+			t.Run("case=identifiers do not collide with user handles", func(t *testing.T) {
+				other := webAuthnIdentity([]byte(randx.MustString(64, randx.AlphaNum)), base64.StdEncoding.EncodeToString(userHandle))
+
+				actual, err := p.FindIdentityByWebAuthnUserHandle(ctx, userHandle)
+				require.NoError(t, err)
+				assert.Equal(t, expected.ID, actual.ID)
+				assert.NotEqual(t, other.ID, actual.ID)
+			})
+
+			t.Run("case=finds credentials registered with earlier user handles", func(t *testing.T) {
+				earlier := []byte(randx.MustString(64, randx.AlphaNum))
+				current := []byte(randx.MustString(64, randx.AlphaNum))
+				expected := webAuthnIdentity(earlier, "find-webauthn-earlier-user-handle@ory.sh")
+
+				raw, err := json.Marshal(identity.CredentialsWebAuthnConfig{
+					UserHandle:  current,
+					Credentials: identity.CredentialsWebAuthn{{ID: []byte("credential"), UserHandle: earlier}},
+				})
+				require.NoError(t, err)
+				require.NoError(t, p.UpdateIdentityCredentialsConfig(ctx, expected.ID, identity.CredentialsTypeWebAuthn, func(sqlxx.JSONRawMessage) (sqlxx.JSONRawMessage, error) {
+					return raw, nil
+				}))
+
+				for _, userHandle := range [][]byte{earlier, current} {
+					actual, err := p.FindIdentityByWebAuthnUserHandle(ctx, userHandle)
+					require.NoError(t, err)
+					assert.Equal(t, expected.ID, actual.ID)
+				}
+			})
+
+			t.Run("case=falls back to the credentials config until cut over", func(t *testing.T) {
+				userHandle := []byte(randx.MustString(64, randx.AlphaNum))
+				expected := webAuthnIdentity(userHandle, "find-webauthn-legacy-user-handle@ory.sh")
+				// Identities created before the user handles were indexed have no index entries.
+				require.NoError(t, p.GetConnection(ctx).RawQuery("DELETE FROM identity_webauthn_user_handles WHERE identity_id = ?", expected.ID).Exec())
+
+				actual, err := p.FindIdentityByWebAuthnUserHandle(ctx, userHandle)
+				require.NoError(t, err)
+				assert.Equal(t, expected.ID, actual.ID)
+				_, ok := actual.GetCredentials(identity.CredentialsTypeWebAuthn)
+				assert.True(t, ok)
+
+				conf.MustSet(ctx, config.ViperKeyDatabasePhasedMigrationsCutover, []string{"webauthn_user_handles"})
+				t.Cleanup(func() {
+					conf.MustSet(ctx, config.ViperKeyDatabasePhasedMigrationsCutover, []string{})
+				})
+				_, err = p.FindIdentityByWebAuthnUserHandle(ctx, userHandle)
+				require.ErrorIs(t, err, sqlcon.ErrNoRows)
+			})
+		})
+
 		t.Run("case=find identity only by credentials identifier case sensitive", func(t *testing.T) {
 			expected := passwordIdentity("", "find-credentials-identifier-only-ci@ory.sh")
 			expected.Traits = identity.Traits(`{}`)
@@ -1143,6 +1329,85 @@ func TestPool(ctx context.Context, conf *config.Config, p persistence.Persister,
 					require.ErrorIs(t, err, sqlcon.ErrNoRows)
 				})
 			})
+
+			t.Run("case=list unverified and track reminders", func(t *testing.T) {
+				address := createIdentityWithAddresses(t, "verification.TestPersister.Unverified@ory.sh")
+
+				contains := func(addresses []identity.VerifiableAddress, id uuid.UUID) bool {
+					for _, a := range addresses {
+						if a.ID == id {
+							return true
+						}
+					}
+					return false
+				}
+
+				actual, err := p.ListUnverifiedAddresses(ctx, time.Now().Add(time.Hour), uuid.Nil, 1000)
+				require.NoError(t, err)
+				assert.True(t, contains(actual, address.ID))
+
+				actual, err = p.ListUnverifiedAddresses(ctx, time.Now().Add(-time.Hour), uuid.Nil, 1000)
+				require.NoError(t, err)
+				assert.False(t, contains(actual, address.ID))
+
+				t.Run("not if on another network", func(t *testing.T) {
+					_, p := testhelpers.NewNetwork(t, ctx, p)
+					actual, err := p.ListUnverifiedAddresses(ctx, time.Now().Add(time.Hour), uuid.Nil, 1000)
+					require.NoError(t, err)
+					assert.False(t, contains(actual, address.ID))
+				})
+
+				address.RemindersSent = 1
+				address.LastRemindedAt = pointerx.Ptr(sqlxx.NullTime(time.Now().UTC()))
+				require.NoError(t, p.UpdateVerifiableAddress(ctx, &address))
+
+				found, err := p.FindVerifiableAddressByValue(ctx, address.Via, address.Value)
+				require.NoError(t, err)
+				assert.Equal(t, 1, found.RemindersSent)
+				assert.NotNil(t, found.LastRemindedAt)
+
+				t.Run("case=claims a reminder only once", func(t *testing.T) {
+					claim := address
+					claim.RemindersSent = 2
+					require.ErrorIs(t, p.ClaimVerifiableAddressReminder(ctx, &claim, 0), sqlcon.ErrNoRows)
+
+					t.Run("not if on another network", func(t *testing.T) {
+						_, p := testhelpers.NewNetwork(t, ctx, p)
+						require.ErrorIs(t, p.ClaimVerifiableAddressReminder(ctx, &claim, 1), sqlcon.ErrNoRows)
+					})
+
+					require.NoError(t, p.ClaimVerifiableAddressReminder(ctx, &claim, 1))
+					require.ErrorIs(t, p.ClaimVerifiableAddressReminder(ctx, &claim, 1), sqlcon.ErrNoRows)
+
+					found, err := p.FindVerifiableAddressByValue(ctx, address.Via, address.Value)
+					require.NoError(t, err)
+					assert.Equal(t, 2, found.RemindersSent)
+				})
+				address.RemindersSent = 2
+
+				count := func(t *testing.T, verified bool) int64 {
+					stats, err := p.VerificationReminderStats(ctx)
+					require.NoError(t, err)
+					for _, s := range stats {
+						if s.RemindersSent == 2 && s.Verified == verified {
+							return s.Addresses
+						}
+					}
+					return 0
+				}
+				unverifiedBefore, verifiedBefore := count(t, false), count(t, true)
+				assert.GreaterOrEqual(t, unverifiedBefore, int64(1))
+
+				address.Verified = true
+				require.NoError(t, p.UpdateVerifiableAddress(ctx, &address))
+
+				assert.Equal(t, unverifiedBefore-1, count(t, false))
+				assert.Equal(t, verifiedBefore+1, count(t, true))
+
+				actual, err = p.ListUnverifiedAddresses(ctx, time.Now().Add(time.Hour), uuid.Nil, 1000)
+				require.NoError(t, err)
+				assert.False(t, contains(actual, address.ID))
+			})
 		})
 
 		t.Run("suite=recovery-address", func(t *testing.T) {
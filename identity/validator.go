@@ -60,7 +60,8 @@ func (v *Validator) ValidateWithRunner(ctx context.Context, i *Identity, runners
 		return errors.WithStack(herodot.ErrBadRequest.WithError(err.Error()))
 	}
 
-	return v.v.Validate(ctx, s.URL.String(), traits, schema.WithExtensionRunner(runner))
+	// Registration steps only validate the traits they collect.
+	return schema.FilterRegistrationStepErrors(ctx, v.v.Validate(ctx, s.URL.String(), traits, schema.WithExtensionRunner(runner)))
 }
 
 func (v *Validator) Validate(ctx context.Context, i *Identity) error {
@@ -69,6 +70,7 @@ func (v *Validator) Validate(ctx context.Context, i *Identity) error {
 			NewSchemaExtensionCredentials(i),
 			NewSchemaExtensionVerification(i, v.d.Config().SelfServiceFlowVerificationRequestLifespan(ctx)),
 			NewSchemaExtensionRecovery(i),
+			NewSchemaExtensionLocale(),
 		)
 	})
 }
@@ -171,3 +171,34 @@ func TestSchemaValidator(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatorLocale(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(ctx, config.ViperKeyIdentitySchemas, []config.Schema{
+		{ID: "default", URL: "file://./stub/locale.schema.json"},
+		{ID: "without-locale", URL: "file://./stub/identity.schema.json"},
+		{ID: "unreachable", URL: "file://./stub/does-not-exist.schema.json"},
+	})
+	v := NewValidator(reg)
+
+	for k, tc := range []struct {
+		i      *Identity
+		expect string
+	}{
+		{i: &Identity{Traits: Traits(`{"language":"de_at"}`)}, expect: "de-AT"},
+		{i: &Identity{Traits: Traits(`{"email":"not an email","language":"fr"}`)}, expect: "fr"},
+		{i: &Identity{Traits: Traits(`{"unknown":true,"language":"fr"}`)}, expect: "fr"},
+		{i: &Identity{Traits: Traits(`{"language":"not a locale"}`)}},
+		{i: &Identity{Traits: Traits(`{}`)}},
+		{i: &Identity{SchemaID: "without-locale", Traits: Traits(`{"language":"fr"}`)}},
+		{i: &Identity{SchemaID: "unreachable", Traits: Traits(`{"language":"fr"}`)}},
+		{i: &Identity{SchemaID: "unknown", Traits: Traits(`{"language":"fr"}`)}},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			if tc.i.SchemaID == "" {
+				tc.i.SchemaID = config.DefaultIdentityTraitsSchemaID
+			}
+			assert.Equal(t, tc.expect, v.Locale(ctx, tc.i))
+		})
+	}
+}
@@ -16,13 +16,14 @@ import (
 	"fmt"
 )
 
-// CourierMessageType It can either be `email` or `phone`
+// CourierMessageType It can either be `email`, `phone` or `whatsapp`
 type CourierMessageType string
 
 // List of courierMessageType
 const (
-	COURIERMESSAGETYPE_EMAIL CourierMessageType = "email"
-	COURIERMESSAGETYPE_PHONE CourierMessageType = "phone"
+	COURIERMESSAGETYPE_EMAIL    CourierMessageType = "email"
+	COURIERMESSAGETYPE_PHONE    CourierMessageType = "phone"
+	COURIERMESSAGETYPE_WHATSAPP CourierMessageType = "whatsapp"
 )
 
 func (v *CourierMessageType) UnmarshalJSON(src []byte) error {
@@ -32,7 +33,7 @@ func (v *CourierMessageType) UnmarshalJSON(src []byte) error {
 		return err
 	}
 	enumTypeValue := CourierMessageType(value)
-	for _, existing := range []CourierMessageType{"email", "phone"} {
+	for _, existing := range []CourierMessageType{"email", "phone", "whatsapp"} {
 		if existing == enumTypeValue {
 			*v = enumTypeValue
 			return nil
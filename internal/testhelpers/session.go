@@ -32,6 +32,18 @@ func (p *SessionLifespanProvider) SessionLifespan(ctx context.Context) time.Dura
 	return p.e
 }
 
+func (p *SessionLifespanProvider) SessionMethodLifespan(ctx context.Context, method string) (time.Duration, bool) {
+	return 0, false
+}
+
+func (p *SessionLifespanProvider) SessionDevicesEnabled(ctx context.Context) bool {
+	return true
+}
+
+func (p *SessionLifespanProvider) SessionDevicesLocationHeaders(ctx context.Context) []string {
+	return []string{"Cf-Ipcity", "Cf-Ipcountry"}
+}
+
 func NewSessionLifespanProvider(expiresIn time.Duration) *SessionLifespanProvider {
 	return &SessionLifespanProvider{e: expiresIn}
 }
@@ -0,0 +1,339 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package organization
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+	"github.com/ory/x/urlx"
+)
+
+const (
+	RouteCollection = "/organizations"
+	RouteItem       = RouteCollection + "/:id"
+)
+
+type (
+	handlerDependencies interface {
+		config.Provider
+		PersistenceProvider
+		x.WriterProvider
+	}
+
+	HandlerProvider interface {
+		OrganizationHandler() *Handler
+	}
+
+	Handler struct {
+		d handlerDependencies
+	}
+)
+
+func NewHandler(d handlerDependencies) *Handler {
+	return &Handler{d: d}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteCollection, h.listOrganizations)
+	admin.POST(RouteCollection, h.createOrganization)
+	admin.GET(RouteItem, h.getOrganization)
+	admin.PUT(RouteItem, h.updateOrganization)
+	admin.DELETE(RouteItem, h.deleteOrganization)
+}
+
+// Organization Body
+//
+// swagger:model organizationBody
+type OrganizationBody struct {
+	// Label is a human-readable name of the organization.
+	//
+	// required: true
+	Label string `json:"label"`
+
+	// Domains are the email domains of the organization, for example `example.org`. Each domain can
+	// only belong to one organization.
+	//
+	// required: true
+	Domains []string `json:"domains"`
+
+	// AllowedMethods are the login methods which members of the organization may use in addition to
+	// the organization's OpenID Connect providers, for example `password`.
+	AllowedMethods []string `json:"allowed_methods"`
+}
+
+func (h *Handler) decode(r *http.Request, o *Organization) error {
+	var body OrganizationBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("Unable to decode the request body.").WithDebug(err.Error()))
+	}
+
+	if body.Label == "" {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The label is required."))
+	} else if len(body.Domains) == 0 {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("At least one domain is required."))
+	}
+
+	for _, domain := range body.Domains {
+		if NormalizeDomain(domain) == "" {
+			return errors.WithStack(herodot.ErrBadRequest.WithReason("Domains must not be empty."))
+		}
+	}
+
+	for _, method := range body.AllowedMethods {
+		if _, ok := identity.ParseCredentialsType(method); !ok {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Login method %s is unknown.", method))
+		}
+	}
+
+	o.Label = body.Label
+	o.Domains = body.Domains
+	o.AllowedMethods = sqlxx.StringSliceJSONFormat(body.AllowedMethods)
+	if o.AllowedMethods == nil {
+		o.AllowedMethods = sqlxx.StringSliceJSONFormat{}
+	}
+	return nil
+}
+
+// List of Organizations
+//
+// swagger:model organizations
+type organizations []Organization
+
+// swagger:route GET /admin/organizations identity listOrganizations
+//
+// # List Organizations
+//
+// Lists all organizations including their domains.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: organizations
+//	  default: errorGeneric
+func (h *Handler) listOrganizations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	orgs, err := h.d.OrganizationPersister().ListOrganizations(r.Context())
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, organizations(orgs))
+}
+
+// Create Organization Parameters
+//
+// swagger:parameters createOrganization
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type createOrganization struct {
+	// in: body
+	// required: true
+	Body OrganizationBody
+}
+
+// swagger:route POST /admin/organizations identity createOrganization
+//
+// # Create an Organization
+//
+// Creates an organization. If identifier-first login is enabled, users whose email address belongs to
+// one of the organization's domains are routed to the organization's OpenID Connect providers and the
+// login methods the organization allows.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  201: organization
+//	  400: errorGeneric
+//	  409: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) createOrganization(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var o Organization
+	if err := h.decode(r, &o); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.d.OrganizationPersister().CreateOrganization(r.Context(), &o); errors.Is(err, sqlcon.ErrUniqueViolation) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrConflict.WithReason("A domain belongs to another organization already.")))
+		return
+	} else if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().WriteCreated(w, r,
+		urlx.AppendPaths(h.d.Config().SelfAdminURL(r.Context()), RouteCollection, o.ID.String()).String(),
+		&o,
+	)
+}
+
+// Get Organization Parameters
+//
+// swagger:parameters getOrganization
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type getOrganization struct {
+	// ID is the organization's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route GET /admin/organizations/{id} identity getOrganization
+//
+// # Get an Organization
+//
+// Returns the organization including its domains.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: organization
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) getOrganization(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	o, err := h.d.OrganizationPersister().GetOrganization(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, o)
+}
+
+// Update Organization Parameters
+//
+// swagger:parameters updateOrganization
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type updateOrganization struct {
+	// ID is the organization's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// in: body
+	// required: true
+	Body OrganizationBody
+}
+
+// swagger:route PUT /admin/organizations/{id} identity updateOrganization
+//
+// # Update an Organization
+//
+// Replaces the label, domains, and allowed login methods of the organization.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: organization
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  409: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) updateOrganization(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	o, err := h.d.OrganizationPersister().GetOrganization(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.decode(r, o); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.d.OrganizationPersister().UpdateOrganization(r.Context(), o); errors.Is(err, sqlcon.ErrUniqueViolation) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrConflict.WithReason("A domain belongs to another organization already.")))
+		return
+	} else if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, o)
+}
+
+// Delete Organization Parameters
+//
+// swagger:parameters deleteOrganization
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type deleteOrganization struct {
+	// ID is the organization's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /admin/organizations/{id} identity deleteOrganization
+//
+// # Delete an Organization
+//
+// Deletes the organization and its domains. Identities of the organization are kept.
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) deleteOrganization(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.d.OrganizationPersister().DeleteOrganization(r.Context(), x.ParseUUID(ps.ByName("id"))); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,129 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package organization_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/organization"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlcon"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	_, reg := internal.NewFastRegistryWithMocks(t)
+
+	router := x.NewRouterAdmin()
+	reg.OrganizationHandler().RegisterAdminRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	do := func(t *testing.T, method, path string, body interface{}) (*http.Response, string) {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		req, err := http.NewRequest(method, ts.URL+x.AdminPrefix+path, &buf)
+		require.NoError(t, err)
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(raw)
+	}
+
+	var id string
+	t.Run("case=creates an organization", func(t *testing.T) {
+		res, body := do(t, "POST", organization.RouteCollection, organization.OrganizationBody{
+			Label:          "Acme",
+			Domains:        []string{"Acme.example.org."},
+			AllowedMethods: []string{"password"},
+		})
+		require.Equal(t, http.StatusCreated, res.StatusCode, body)
+		id = gjson.Get(body, "id").String()
+		assert.Equal(t, `["acme.example.org"]`, gjson.Get(body, "domains").Raw)
+	})
+
+	t.Run("case=finds the organization by domain", func(t *testing.T) {
+		org, err := reg.OrganizationPersister().FindOrganizationByDomain(ctx, organization.DomainOf("user@ACME.example.org"))
+		require.NoError(t, err)
+		assert.Equal(t, id, org.ID.String())
+		assert.True(t, org.AllowsMethod(identity.CredentialsTypePassword))
+		assert.True(t, org.AllowsMethod(identity.CredentialsTypeOIDC))
+		assert.False(t, org.AllowsMethod(identity.CredentialsTypeCodeAuth))
+
+		_, err = reg.OrganizationPersister().FindOrganizationByDomain(ctx, "other.example.org")
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+	})
+
+	t.Run("case=rejects invalid organizations", func(t *testing.T) {
+		for _, body := range []organization.OrganizationBody{
+			{Domains: []string{"invalid.example.org"}},
+			{Label: "Invalid"},
+			{Label: "Invalid", Domains: []string{" "}},
+			{Label: "Invalid", Domains: []string{"invalid.example.org"}, AllowedMethods: []string{"unknown"}},
+		} {
+			res, raw := do(t, "POST", organization.RouteCollection, body)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, raw)
+		}
+	})
+
+	t.Run("case=rejects domains of another organization", func(t *testing.T) {
+		res, body := do(t, "POST", organization.RouteCollection, organization.OrganizationBody{
+			Label:   "Other",
+			Domains: []string{"acme.example.org"},
+		})
+		assert.Equal(t, http.StatusConflict, res.StatusCode, body)
+	})
+
+	t.Run("case=updates the organization", func(t *testing.T) {
+		res, body := do(t, "PUT", organization.RouteCollection+"/"+id, organization.OrganizationBody{
+			Label:   "Acme Inc.",
+			Domains: []string{"acme.example.com"},
+		})
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, "Acme Inc.", gjson.Get(body, "label").String())
+		assert.Equal(t, `[]`, gjson.Get(body, "allowed_methods").Raw)
+
+		_, err := reg.OrganizationPersister().FindOrganizationByDomain(ctx, "acme.example.org")
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+		_, err = reg.OrganizationPersister().FindOrganizationByDomain(ctx, "acme.example.com")
+		assert.NoError(t, err)
+	})
+
+	t.Run("case=lists and gets organizations", func(t *testing.T) {
+		res, body := do(t, "GET", organization.RouteCollection, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, `["acme.example.com"]`, gjson.Get(body, "0.domains").Raw)
+
+		res, body = do(t, "GET", organization.RouteCollection+"/"+id, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, "Acme Inc.", gjson.Get(body, "label").String())
+	})
+
+	t.Run("case=deletes the organization", func(t *testing.T) {
+		res, body := do(t, "DELETE", organization.RouteCollection+"/"+id, nil)
+		require.Equal(t, http.StatusNoContent, res.StatusCode, body)
+
+		_, err := reg.OrganizationPersister().FindOrganizationByDomain(ctx, "acme.example.com")
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+
+		res, _ = do(t, "DELETE", organization.RouteCollection+"/"+id, nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
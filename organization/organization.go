@@ -0,0 +1,120 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package organization
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/x/sqlxx"
+)
+
+// Organization
+//
+// An organization groups the identities of a company. If identifier-first login is enabled, users
+// whose email address belongs to one of the organization's domains are routed to the organization's
+// OpenID Connect providers and the login methods the organization allows.
+//
+// swagger:model organization
+type Organization struct {
+	// ID of the organization
+	//
+	// required: true
+	ID uuid.UUID `json:"id" faker:"-" db:"id"`
+
+	// Label is a human-readable name of the organization.
+	//
+	// required: true
+	Label string `json:"label" db:"label"`
+
+	// Domains are the email domains of the organization, for example `example.org`.
+	//
+	// required: true
+	Domains []string `json:"domains" faker:"-" db:"-"`
+
+	// AllowedMethods are the login methods which members of the organization may use in addition to
+	// the organization's OpenID Connect providers, for example `password`.
+	//
+	// required: true
+	AllowedMethods sqlxx.StringSliceJSONFormat `json:"allowed_methods" faker:"-" db:"allowed_methods"`
+
+	// CreatedAt is the time at which the organization was created.
+	CreatedAt time.Time `json:"created_at" faker:"-" db:"created_at"`
+
+	// UpdatedAt is the time at which the organization was last updated.
+	UpdatedAt time.Time `json:"updated_at" faker:"-" db:"updated_at"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (o Organization) TableName(context.Context) string {
+	return "organizations"
+}
+
+// Domain is an email domain of an organization. Each domain belongs to at most one organization.
+type Domain struct {
+	ID             uuid.UUID `json:"-" faker:"-" db:"id"`
+	OrganizationID uuid.UUID `json:"-" faker:"-" db:"organization_id"`
+	Domain         string    `json:"-" db:"domain"`
+	CreatedAt      time.Time `json:"-" faker:"-" db:"created_at"`
+	UpdatedAt      time.Time `json:"-" faker:"-" db:"updated_at"`
+	NID            uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (d Domain) TableName(context.Context) string {
+	return "organization_domains"
+}
+
+// AllowsMethod returns true if members of the organization may use the login method. The
+// organization's OpenID Connect providers are always allowed.
+func (o *Organization) AllowsMethod(method identity.CredentialsType) bool {
+	return method == identity.CredentialsTypeOIDC || slices.Contains(o.AllowedMethods, method.String())
+}
+
+// NormalizeDomain lower-cases the domain and removes surrounding whitespace and dots.
+func NormalizeDomain(domain string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+}
+
+// DomainOf returns the normalized domain of the email address or an empty string if the
+// identifier is not an email address.
+func DomainOf(identifier string) string {
+	_, domain, ok := strings.Cut(identifier, "@")
+	if !ok {
+		return ""
+	}
+	return NormalizeDomain(domain)
+}
+
+type (
+	Persister interface {
+		// CreateOrganization stores the organization and its domains. It returns sqlcon.ErrUniqueViolation
+		// if a domain belongs to another organization.
+		CreateOrganization(ctx context.Context, o *Organization) error
+
+		// GetOrganization returns the organization with its domains or sqlcon.ErrNoRows.
+		GetOrganization(ctx context.Context, id uuid.UUID) (*Organization, error)
+
+		// ListOrganizations returns all organizations with their domains.
+		ListOrganizations(ctx context.Context) ([]Organization, error)
+
+		// UpdateOrganization replaces the organization and its domains or returns sqlcon.ErrNoRows.
+		UpdateOrganization(ctx context.Context, o *Organization) error
+
+		// DeleteOrganization deletes the organization and its domains or returns sqlcon.ErrNoRows.
+		DeleteOrganization(ctx context.Context, id uuid.UUID) error
+
+		// FindOrganizationByDomain returns the organization which owns the email domain or sqlcon.ErrNoRows.
+		FindOrganizationByDomain(ctx context.Context, domain string) (*Organization, error)
+	}
+
+	PersistenceProvider interface {
+		OrganizationPersister() Persister
+	}
+)
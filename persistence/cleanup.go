@@ -0,0 +1,58 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package persistence
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+)
+
+type (
+	// CleanupOptions configures a database cleanup run.
+	CleanupOptions struct {
+		// OnExpiredSessions, if set, is called with the IDs of every batch of
+		// expired sessions before the cleanup removes them. If it fails, the
+		// batch is kept and passed again to the next cleanup.
+		OnExpiredSessions func(ctx context.Context, ids []uuid.UUID) error
+
+		// OnRowsDeleted, if set, is called with the number of rows removed from
+		// a table by the cleanup.
+		OnRowsDeleted func(ctx context.Context, table string, rows int)
+	}
+
+	CleanupOption func(o *CleanupOptions)
+)
+
+// WithExpiredSessionsCallback registers a callback which receives the IDs of
+// expired sessions before they are deleted.
+func WithExpiredSessionsCallback(cb func(ctx context.Context, ids []uuid.UUID) error) CleanupOption {
+	return func(o *CleanupOptions) {
+		o.OnExpiredSessions = cb
+	}
+}
+
+// WithDeletedRowsCallback registers a callback which receives the number of
+// rows deleted from each table.
+func WithDeletedRowsCallback(cb func(ctx context.Context, table string, rows int)) CleanupOption {
+	return func(o *CleanupOptions) {
+		o.OnRowsDeleted = cb
+	}
+}
+
+// NewCleanupOptions applies the given options.
+func NewCleanupOptions(opts ...CleanupOption) *CleanupOptions {
+	o := new(CleanupOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// ReportDeletedRows calls OnRowsDeleted, if set.
+func (o *CleanupOptions) ReportDeletedRows(ctx context.Context, table string, rows int) {
+	if o.OnRowsDeleted != nil {
+		o.OnRowsDeleted(ctx, table, rows)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	janitorDependencies interface {
+		config.Provider
+		x.LoggingProvider
+		Provider
+	}
+
+	// Janitor periodically removes expired flows, codes, sessions and continuity containers from the
+	// database and counts the deleted rows per table.
+	Janitor struct {
+		d       janitorDependencies
+		deleted *prometheus.CounterVec
+		runs    *prometheus.CounterVec
+	}
+)
+
+var _ prometheus.Collector = new(Janitor)
+
+func NewJanitor(d janitorDependencies) *Janitor {
+	return &Janitor{
+		d: d,
+		deleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kratos_cleanup_deleted_rows_total",
+			Help: "Number of expired rows deleted from a table by the database cleanup.",
+		}, []string{"table"}),
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kratos_cleanup_runs_total",
+			Help: "Number of database cleanup runs by result.",
+		}, []string{"result"}),
+	}
+}
+
+func (j *Janitor) Describe(ch chan<- *prometheus.Desc) {
+	j.deleted.Describe(ch)
+	j.runs.Describe(ch)
+}
+
+func (j *Janitor) Collect(ch chan<- prometheus.Metric) {
+	j.deleted.Collect(ch)
+	j.runs.Collect(ch)
+}
+
+// Run deletes one batch of records per table which expired more than keepLast ago.
+func (j *Janitor) Run(ctx context.Context, keepLast time.Duration, opts ...CleanupOption) error {
+	opts = append(opts, WithDeletedRowsCallback(func(_ context.Context, table string, rows int) {
+		j.deleted.WithLabelValues(table).Add(float64(rows))
+	}))
+
+	err := j.d.Persister().CleanupDatabase(ctx,
+		j.d.Config().DatabaseCleanupSleepTables(ctx),
+		keepLast,
+		j.d.Config().DatabaseCleanupBatchSize(ctx),
+		opts...)
+	if err != nil {
+		j.runs.WithLabelValues("error").Inc()
+		return err
+	}
+
+	j.runs.WithLabelValues("success").Inc()
+	return nil
+}
+
+// Watch runs the cleanup in the configured interval until the context is canceled.
+func (j *Janitor) Watch(ctx context.Context, keepLast time.Duration, opts ...CleanupOption) {
+	for {
+		if err := j.Run(ctx, keepLast, opts...); err != nil {
+			j.d.Logger().WithError(err).Warn("Unable to clean up expired database records.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(j.d.Config().DatabaseCleanupInterval(ctx)):
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package persistence
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ory/x/popx"
+)
+
+type (
+	// MigrationInfo describes a single migration for the dialect of the database.
+	MigrationInfo struct {
+		Version string `json:"version"`
+		Name    string `json:"name"`
+		State   string `json:"state"`
+
+		// Checksum is the hex encoded SHA-256 checksum of the migration file. It is
+		// empty for migrations implemented in Go.
+		Checksum string `json:"checksum"`
+	}
+
+	// MigrationReport lists the migrations known to this version of Kratos and the
+	// differences between the expected and the live database schema.
+	MigrationReport struct {
+		Migrations []MigrationInfo `json:"migrations"`
+
+		// Drift lists differences between the migrations known to this version of
+		// Kratos and the live database schema.
+		Drift []string `json:"drift"`
+	}
+
+	// MigrationScript is the SQL of a pending migration.
+	MigrationScript struct {
+		Version string
+		Name    string
+		SQL     string
+	}
+
+	MigrationReporter interface {
+		// MigrationReport returns the applied and pending migrations and detects
+		// drift between the migrations and the live database schema.
+		MigrationReport(ctx context.Context) (*MigrationReport, error)
+
+		// PendingMigrationScripts returns the SQL of all pending migrations without
+		// applying them.
+		PendingMigrationScripts(ctx context.Context) ([]MigrationScript, error)
+	}
+)
+
+func (r *MigrationReport) Header() []string {
+	return []string{"VERSION", "NAME", "STATUS", "CHECKSUM"}
+}
+
+func (r *MigrationReport) Table() [][]string {
+	rows := make([][]string, len(r.Migrations))
+	for k, m := range r.Migrations {
+		rows[k] = []string{m.Version, m.Name, m.State, m.Checksum}
+	}
+	return rows
+}
+
+func (r *MigrationReport) Interface() interface{} {
+	return r
+}
+
+func (r *MigrationReport) Len() int {
+	return len(r.Migrations)
+}
+
+// HasPending returns true if at least one migration was not applied yet.
+func (r *MigrationReport) HasPending() bool {
+	for _, m := range r.Migrations {
+		if m.State == popx.Pending {
+			return true
+		}
+	}
+	return false
+}
+
+// DriftString renders the drift as a human readable list.
+func (r *MigrationReport) DriftString() string {
+	var b strings.Builder
+	for _, d := range r.Drift {
+		b.WriteString("- ")
+		b.WriteString(d)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,221 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlxx"
+)
+
+// A phased migration changes the database schema without long table locks. It runs in four phases:
+//
+//  1. An additive SQL migration creates the new tables or columns next to the old ones. From this
+//     release on, writes go to the old and the new schema (see DualWrite) and reads still use the
+//     old schema (see DualRead).
+//  2. A backfill copies the existing rows in small batches while Kratos serves traffic.
+//  3. Once the backfill completed, the operator adds the migration to `database.phased_migrations.cutover`
+//     and reads switch to the new schema. Writes still go to both schemas so that the switch can be
+//     reverted.
+//  4. A later release removes the old schema with a regular SQL migration.
+const (
+	// PhasedMigrationPhasePending means that the backfill did not start yet.
+	PhasedMigrationPhasePending PhasedMigrationPhase = "pending"
+
+	// PhasedMigrationPhaseBackfilling means that the backfill started but did not complete yet.
+	PhasedMigrationPhaseBackfilling PhasedMigrationPhase = "backfilling"
+
+	// PhasedMigrationPhaseBackfilled means that all rows were backfilled and reads can be cut over.
+	PhasedMigrationPhaseBackfilled PhasedMigrationPhase = "backfilled"
+)
+
+type (
+	// PhasedMigrationPhase is the backfill phase of a phased migration.
+	PhasedMigrationPhase string
+
+	// PhasedMigration is the progress of a phased migration.
+	//
+	// swagger:model phasedMigration
+	PhasedMigration struct {
+		ID uuid.UUID `json:"-" db:"id"`
+
+		// Name is the unique name of the phased migration.
+		Name string `json:"name" db:"name"`
+
+		// Phase is the backfill phase of the migration.
+		Phase PhasedMigrationPhase `json:"phase" db:"phase"`
+
+		// ProcessedRows is the number of rows backfilled so far.
+		ProcessedRows int64 `json:"processed_rows" db:"processed_rows"`
+
+		// RemainingRows is the number of rows which still need to be backfilled.
+		RemainingRows int64 `json:"remaining_rows" db:"-"`
+
+		// Progress is the share of backfilled rows between 0 and 1.
+		Progress float64 `json:"progress" db:"-"`
+
+		// CutOver is true if reads use the new schema.
+		CutOver bool `json:"cut_over" db:"-"`
+
+		// LastError is the error of the last failed backfill batch.
+		LastError string `json:"last_error,omitempty" db:"last_error"`
+
+		StartedAt   sqlxx.NullTime `json:"started_at" db:"started_at"`
+		CompletedAt sqlxx.NullTime `json:"completed_at" db:"completed_at"`
+		CreatedAt   time.Time      `json:"-" db:"created_at"`
+		UpdatedAt   time.Time      `json:"updated_at" db:"updated_at"`
+	}
+
+	// Backfiller copies rows from the old to the new schema of a phased migration.
+	Backfiller interface {
+		// Name uniquely identifies the phased migration.
+		Name() string
+
+		// Remaining returns the number of rows which still need to be backfilled.
+		Remaining(ctx context.Context, c *pop.Connection) (int64, error)
+
+		// Backfill backfills at most limit rows which were not backfilled yet and returns the
+		// number of backfilled rows. It must be idempotent because rows written by DualWrite
+		// are already present in the new schema.
+		Backfill(ctx context.Context, c *pop.Connection, limit int) (int, error)
+	}
+
+	PhasedMigrator interface {
+		// PhasedMigrations returns the progress of all phased migrations known to this version of Kratos.
+		PhasedMigrations(ctx context.Context) ([]PhasedMigration, error)
+
+		// BackfillBatch backfills at most limit rows of the named phased migration in one
+		// transaction and records the progress. It returns zero once the backfill completed.
+		BackfillBatch(ctx context.Context, name string, limit int) (int, error)
+
+		// PhasedMigrationCutOver returns true if reads of the named phased migration use the new schema.
+		PhasedMigrationCutOver(ctx context.Context, name string) bool
+	}
+)
+
+func (m *PhasedMigration) TableName(context.Context) string {
+	return "phased_migrations"
+}
+
+// SetProgress sets the remaining rows and calculates the progress of the backfill.
+func (m *PhasedMigration) SetProgress(remaining int64) {
+	m.RemainingRows = remaining
+	switch total := m.ProcessedRows + remaining; {
+	case m.Phase == PhasedMigrationPhaseBackfilled || remaining == 0:
+		m.Progress = 1
+	case total == 0:
+		m.Progress = 0
+	default:
+		m.Progress = float64(m.ProcessedRows) / float64(total)
+	}
+}
+
+// ErrUnknownPhasedMigration is returned if no backfiller is registered for a phased migration.
+var ErrUnknownPhasedMigration = herodot.ErrNotFound.WithReason("The phased migration is not known to this version of Ory Kratos.")
+
+// DualWrite writes to the old and the new schema of a phased migration. Both writes must succeed,
+// so it should be called within a transaction.
+func DualWrite(ctx context.Context, writeOld, writeNew func(ctx context.Context) error) error {
+	if err := writeOld(ctx); err != nil {
+		return err
+	}
+	return writeNew(ctx)
+}
+
+// DualRead reads from the new schema once the phased migration was cut over and from the old schema
+// otherwise.
+func DualRead[T any](ctx context.Context, cutOver bool, readOld, readNew func(ctx context.Context) (T, error)) (T, error) {
+	if cutOver {
+		return readNew(ctx)
+	}
+	return readOld(ctx)
+}
+
+type (
+	phasedMigrationRunnerDependencies interface {
+		config.Provider
+		x.LoggingProvider
+		Provider
+	}
+
+	// PhasedMigrationRunner runs the backfills of phased migrations in small batches.
+	PhasedMigrationRunner struct {
+		d phasedMigrationRunnerDependencies
+	}
+)
+
+func NewPhasedMigrationRunner(d phasedMigrationRunnerDependencies) *PhasedMigrationRunner {
+	return &PhasedMigrationRunner{d: d}
+}
+
+// Run backfills the named phased migration until it completed or the context is canceled. The
+// progress callback is called after each batch and may be nil.
+func (r *PhasedMigrationRunner) Run(ctx context.Context, name string, progress func(processed int)) error {
+	batchSize := r.d.Config().DatabasePhasedMigrationsBackfillBatchSize(ctx)
+	for {
+		n, err := r.d.Persister().BackfillBatch(ctx, name, batchSize)
+		if err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(n)
+		}
+		if n == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		case <-time.After(r.d.Config().DatabasePhasedMigrationsBackfillInterval(ctx)):
+		}
+	}
+}
+
+// RunAll backfills all phased migrations which did not complete yet.
+func (r *PhasedMigrationRunner) RunAll(ctx context.Context) error {
+	migrations, err := r.d.Persister().PhasedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.Phase == PhasedMigrationPhaseBackfilled {
+			continue
+		}
+
+		r.d.Logger().WithField("phased_migration", m.Name).Info("Backfilling phased migration.")
+		if err := r.Run(ctx, m.Name, nil); err != nil {
+			return err
+		}
+		r.d.Logger().WithField("phased_migration", m.Name).Info("Backfilled phased migration.")
+	}
+	return nil
+}
+
+// Watch backfills all phased migrations in the background. Failed backfills are retried every
+// minute until the context is canceled.
+func (r *PhasedMigrationRunner) Watch(ctx context.Context) {
+	for {
+		err := r.RunAll(ctx)
+		if err == nil {
+			return
+		}
+		r.d.Logger().WithError(err).Warn("Unable to backfill phased migrations.")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Minute):
+		}
+	}
+}
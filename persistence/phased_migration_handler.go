@@ -0,0 +1,77 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package persistence
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/kratos/x"
+)
+
+const RouteAdminPhasedMigrations = "/migrations/phased"
+
+type (
+	phasedMigrationHandlerDependencies interface {
+		Provider
+		x.WriterProvider
+	}
+
+	PhasedMigrationHandler struct {
+		d phasedMigrationHandlerDependencies
+	}
+
+	// List of Phased Migrations
+	//
+	// swagger:model phasedMigrations
+	phasedMigrations []PhasedMigration
+)
+
+func NewPhasedMigrationHandler(d phasedMigrationHandlerDependencies) *PhasedMigrationHandler {
+	return &PhasedMigrationHandler{d: d}
+}
+
+func (h *PhasedMigrationHandler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteAdminPhasedMigrations, h.listPhasedMigrations)
+}
+
+// List Phased Migrations Response
+//
+// swagger:response listPhasedMigrations
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listPhasedMigrationsResponse struct {
+	// in: body
+	Body phasedMigrations
+}
+
+// swagger:route GET /admin/migrations/phased metadata listPhasedMigrations
+//
+// # List Phased Migrations
+//
+// Returns the backfill progress of all phased migrations and whether their reads were cut over
+// to the new schema.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: listPhasedMigrations
+//	  default: errorGeneric
+func (h *PhasedMigrationHandler) listPhasedMigrations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	migrations, err := h.d.Persister().PhasedMigrations(r.Context())
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, phasedMigrations(migrations))
+}
@@ -0,0 +1,664 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package redis
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+)
+
+const (
+	paginationMaxItemsSize     = 1000
+	paginationDefaultItemsSize = 250
+)
+
+type (
+	sessionPersisterDependencies interface {
+		config.Provider
+		x.LoggingProvider
+		x.TracingProvider
+		identity.PrivilegedPoolProvider
+		session.EventEmitterProvider
+	}
+
+	// SessionPersister stores sessions in Redis in front of or instead of the SQL database, depending on
+	// `session.persistence.mode`:
+	//
+	//  - In write-through mode, sessions are written to the SQL database. Lookups are served from Redis and fall
+	//    back to the SQL database. Changes in the SQL database remove the affected sessions from Redis through
+	//    the session cache.
+	//  - In Redis mode, sessions are only stored in Redis and removed when they expire.
+	//
+	// All other data, including identities, is always stored in the SQL database.
+	SessionPersister struct {
+		session.Persister
+
+		d sessionPersisterDependencies
+		s *Store
+	}
+)
+
+var _ session.Persister = new(SessionPersister)
+
+func NewSessionPersister(d sessionPersisterDependencies, sql session.Persister, s *Store) *SessionPersister {
+	return &SessionPersister{Persister: sql, d: d, s: s}
+}
+
+func (p *SessionPersister) mode(ctx context.Context) config.SessionPersistenceMode {
+	return p.d.Config().SessionPersistenceMode(ctx)
+}
+
+func (p *SessionPersister) redisOnly(ctx context.Context) bool {
+	return p.mode(ctx) == config.SessionPersistenceModeRedis
+}
+
+func errUnsupported(feature string) error {
+	return errors.WithStack(herodot.ErrInternalServerError.WithReasonf(
+		"%s is not supported if sessions are only stored in Redis. Set session.persistence.mode to write_through instead.", feature))
+}
+
+// expand loads the identity of the session and removes the devices unless they were requested.
+func (p *SessionPersister) expand(ctx context.Context, s *session.Session, expand session.Expandables, identityExpand identity.Expandables) (*session.Session, error) {
+	if !expand.Has(session.ExpandSessionDevices) {
+		s.Devices = nil
+	}
+
+	if expand.Has(session.ExpandSessionIdentity) {
+		i, err := p.d.PrivilegedIdentityPool().GetIdentity(ctx, s.IdentityID, identityExpand)
+		if errors.Is(err, sqlcon.ErrNoRows) && p.redisOnly(ctx) {
+			// The identity was deleted, which would have deleted its sessions in the SQL database.
+			if err := p.s.Delete(ctx, s.ID); err != nil {
+				p.d.Logger().WithError(err).Warn("Unable to remove the session of a deleted identity from Redis.")
+			}
+			return nil, err
+		} else if err != nil {
+			return nil, err
+		}
+		s.Identity = i
+	}
+
+	return s, nil
+}
+
+// cache stores a session loaded from the SQL database in Redis.
+func (p *SessionPersister) cache(ctx context.Context, s *session.Session) {
+	if err := p.s.Put(ctx, s); err != nil {
+		p.d.Logger().WithError(err).Warn("Unable to store session in Redis.")
+	}
+}
+
+func (p *SessionPersister) GetSession(ctx context.Context, sid uuid.UUID, expandables session.Expandables) (_ *session.Session, err error) {
+	mode := p.mode(ctx)
+	if mode == config.SessionPersistenceModeSQL {
+		return p.Persister.GetSession(ctx, sid, expandables)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.GetSession")
+	defer otelx.End(span, &err)
+
+	s, err := p.s.Get(ctx, sid)
+	if err != nil && mode == config.SessionPersistenceModeRedis {
+		return nil, err
+	} else if err != nil {
+		p.d.Logger().WithError(err).Warn("Unable to look up session in Redis, falling back to the SQL database.")
+	}
+
+	if s == nil {
+		if mode == config.SessionPersistenceModeRedis {
+			return nil, errors.WithStack(sqlcon.ErrNoRows)
+		}
+		if s, err = p.Persister.GetSession(ctx, sid, session.Expandables{session.ExpandSessionDevices}); err != nil {
+			return nil, err
+		}
+		p.cache(ctx, s)
+	}
+
+	if s, err = p.expand(ctx, s, expandables, identity.ExpandDefault); err != nil {
+		return nil, err
+	}
+	s.Active = s.IsActive()
+	return s, nil
+}
+
+func (p *SessionPersister) GetSessionByToken(ctx context.Context, token string, expand session.Expandables, identityExpand identity.Expandables) (_ *session.Session, err error) {
+	mode := p.mode(ctx)
+	if mode == config.SessionPersistenceModeSQL {
+		return p.Persister.GetSessionByToken(ctx, token, expand, identityExpand)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.GetSessionByToken")
+	defer otelx.End(span, &err)
+
+	s, err := p.s.GetByToken(ctx, token)
+	if err != nil && mode == config.SessionPersistenceModeRedis {
+		return nil, err
+	} else if err != nil {
+		p.d.Logger().WithError(err).Warn("Unable to look up session in Redis, falling back to the SQL database.")
+	}
+
+	if s == nil {
+		if mode == config.SessionPersistenceModeRedis {
+			return nil, errors.WithStack(sqlcon.ErrNoRows)
+		}
+		if s, err = p.Persister.GetSessionByToken(ctx, token, session.Expandables{session.ExpandSessionDevices}, identityExpand); err != nil {
+			return nil, err
+		}
+		p.cache(ctx, s)
+	}
+
+	return p.expand(ctx, s, expand, identityExpand)
+}
+
+// matches returns true if the filter matches the session.
+func matches(f session.Filter, s *session.Session, now time.Time) bool {
+	if f.Active != nil {
+		if active := s.Active && !s.ExpiresAt.Before(now); active != *f.Active {
+			return false
+		}
+	}
+	if !f.IdentityID.IsNil() && f.IdentityID != s.IdentityID {
+		return false
+	}
+	if f.AAL != "" && f.AAL != s.AuthenticatorAssuranceLevel {
+		return false
+	}
+	if !f.AuthenticatedAfter.IsZero() && s.AuthenticatedAt.Before(f.AuthenticatedAfter) {
+		return false
+	}
+	if !f.AuthenticatedBefore.IsZero() && !s.AuthenticatedAt.Before(f.AuthenticatedBefore) {
+		return false
+	}
+	if f.AuthenticationMethod != "" {
+		found := false
+		for _, m := range s.AMR {
+			found = found || m.Method == f.AuthenticationMethod
+		}
+		return found
+	}
+	return true
+}
+
+// filtered returns the sessions which match the filter. Sessions of a single identity are looked up using the
+// identity index, all others by scanning all sessions.
+func (p *SessionPersister) filtered(ctx context.Context, f session.Filter) ([]session.Session, error) {
+	now := x.Now()
+
+	if !f.IdentityID.IsNil() {
+		sessions, err := p.s.IdentitySessions(ctx, f.IdentityID)
+		if err != nil {
+			return nil, err
+		}
+
+		res := make([]session.Session, 0, len(sessions))
+		for k := range sessions {
+			if matches(f, &sessions[k], now) {
+				res = append(res, sessions[k])
+			}
+		}
+		sort.Slice(res, func(i, j int) bool { return res[i].ID.String() < res[j].ID.String() })
+		return res, nil
+	}
+
+	res := make([]session.Session, 0)
+	if err := p.s.Each(ctx, func(s *session.Session) error {
+		if matches(f, s, now) {
+			res = append(res, *s)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (p *SessionPersister) ListSessions(ctx context.Context, filter session.Filter, paginatorOpts []keysetpagination.Option, expandables session.Expandables) (_ []session.Session, _ int64, _ *keysetpagination.Paginator, err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.ListSessions(ctx, filter, paginatorOpts, expandables)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.ListSessions")
+	defer otelx.End(span, &err)
+
+	paginatorOpts = append(paginatorOpts, keysetpagination.WithDefaultSize(paginationDefaultItemsSize))
+	paginatorOpts = append(paginatorOpts, keysetpagination.WithMaxSize(paginationMaxItemsSize))
+	paginatorOpts = append(paginatorOpts, keysetpagination.WithDefaultToken(new(session.Session).DefaultPageToken()))
+	paginator := keysetpagination.GetPaginator(paginatorOpts...)
+
+	matching, err := p.filtered(ctx, filter)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	after := paginator.Token().Parse("id")["id"]
+	page := make([]session.Session, 0, paginator.Size()+1)
+	for k := range matching {
+		if len(page) > paginator.Size() {
+			break
+		} else if matching[k].ID.String() <= after {
+			continue
+		}
+
+		s, err := p.expand(ctx, &matching[k], expandables, identity.ExpandDefault)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		page = append(page, *s)
+	}
+
+	page, next := keysetpagination.Result(page, paginator)
+	return page, int64(len(matching)), next, nil
+}
+
+func (p *SessionPersister) ListSessionsByIdentity(ctx context.Context, iID uuid.UUID, active *bool, page, perPage int, except uuid.UUID, expandables session.Expandables) (_ []session.Session, _ int64, err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.ListSessionsByIdentity(ctx, iID, active, page, perPage, except, expandables)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.ListSessionsByIdentity")
+	defer otelx.End(span, &err)
+
+	matching, err := p.filtered(ctx, session.Filter{IdentityID: iID, Active: active})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sessions := make([]session.Session, 0, len(matching))
+	for _, s := range matching {
+		if s.ID != except {
+			sessions = append(sessions, s)
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].AuthenticatedAt.After(sessions[j].AuthenticatedAt) })
+
+	// Pages start at 1 like in the SQL persister.
+	page = max(page, 1)
+	perPage = max(perPage, 1)
+	from, to := min((page-1)*perPage, len(sessions)), min(page*perPage, len(sessions))
+
+	res := make([]session.Session, 0, to-from)
+	for k := from; k < to; k++ {
+		s, err := p.expand(ctx, &sessions[k], expandables, identity.ExpandDefault)
+		if err != nil {
+			return nil, 0, err
+		}
+		res = append(res, *s)
+	}
+	return res, int64(len(sessions)), nil
+}
+
+func (p *SessionPersister) UpsertSession(ctx context.Context, s *session.Session) (err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.UpsertSession(ctx, s)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.UpsertSession")
+	defer otelx.End(span, &err)
+
+	s.NID = p.s.nid(ctx)
+	now := time.Now().UTC()
+
+	var previous *session.Session
+	if !s.ID.IsNil() {
+		if previous, err = p.s.Get(ctx, s.ID); err != nil {
+			return err
+		}
+	} else {
+		s.ID = x.NewUUID()
+	}
+
+	var lifecycle []*session.Event
+	stored := *s
+	if previous != nil {
+		// Like in the SQL database, the devices are only stored when the session is created.
+		stored.Devices = previous.Devices
+		s.CreatedAt = previous.CreatedAt
+
+		if previous.AuthenticatorAssuranceLevel != s.AuthenticatorAssuranceLevel {
+			lifecycle = append(lifecycle, session.NewSessionEvent(session.EventAALChanged, s))
+		}
+		if s.ExpiresAt.After(previous.ExpiresAt) {
+			lifecycle = append(lifecycle, session.NewSessionEvent(session.EventExtended, s))
+		}
+	} else {
+		s.CreatedAt = now
+		for k := range s.Devices {
+			d := &s.Devices[k]
+			if d.ID.IsNil() {
+				d.ID = x.NewUUID()
+			}
+			d.SessionID = s.ID
+			d.NID = s.NID
+			d.CreatedAt = now
+			d.UpdatedAt = now
+		}
+		stored.Devices = s.Devices
+		lifecycle = append(lifecycle, session.NewSessionEvent(session.EventIssued, s))
+	}
+	s.UpdatedAt = now
+	stored.CreatedAt, stored.UpdatedAt = s.CreatedAt, s.UpdatedAt
+
+	if err := p.s.Put(ctx, &stored); err != nil {
+		return err
+	}
+
+	p.d.SessionEventEmitter().Emit(ctx, lifecycle...)
+	return nil
+}
+
+// remove deletes the sessions or marks them inactive and publishes one revocation event per identity for
+// the sessions which were active. It returns the number of sessions which were active.
+func (p *SessionPersister) remove(ctx context.Context, sessions []session.Session, del bool) (int, error) {
+	var identities []uuid.UUID
+	sessionsByIdentity := make(map[uuid.UUID][]uuid.UUID)
+	ids := make([]uuid.UUID, 0, len(sessions))
+	for k := range sessions {
+		s := &sessions[k]
+		ids = append(ids, s.ID)
+		if !s.Active {
+			continue
+		}
+
+		if !del {
+			s.Active = false
+			if err := p.s.Put(ctx, s); err != nil {
+				return 0, err
+			}
+		}
+
+		if _, ok := sessionsByIdentity[s.IdentityID]; !ok {
+			identities = append(identities, s.IdentityID)
+		}
+		sessionsByIdentity[s.IdentityID] = append(sessionsByIdentity[s.IdentityID], s.ID)
+	}
+
+	if del {
+		if err := p.s.Delete(ctx, ids...); err != nil {
+			return 0, err
+		}
+	}
+
+	lifecycle := make([]*session.Event, 0, len(identities))
+	revoked := 0
+	for _, iID := range identities {
+		lifecycle = append(lifecycle, session.NewEvent(session.EventRevoked, iID, sessionsByIdentity[iID]...))
+		revoked += len(sessionsByIdentity[iID])
+	}
+	p.d.SessionEventEmitter().Emit(ctx, lifecycle...)
+	return revoked, nil
+}
+
+// one returns the session as a list, or sqlcon.ErrNoRows if it is nil.
+func one(s *session.Session, err error) ([]session.Session, error) {
+	if err != nil {
+		return nil, err
+	} else if s == nil {
+		return nil, errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return []session.Session{*s}, nil
+}
+
+func (p *SessionPersister) DeleteSession(ctx context.Context, id uuid.UUID) (err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.DeleteSession(ctx, id)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.DeleteSession")
+	defer otelx.End(span, &err)
+
+	sessions, err := one(p.s.Get(ctx, id))
+	if err != nil {
+		return err
+	}
+	_, err = p.remove(ctx, sessions, true)
+	return err
+}
+
+func (p *SessionPersister) DeleteSessionsByIdentity(ctx context.Context, identityID uuid.UUID) (err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.DeleteSessionsByIdentity(ctx, identityID)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.DeleteSessionsByIdentity")
+	defer otelx.End(span, &err)
+
+	sessions, err := p.s.IdentitySessions(ctx, identityID)
+	if err != nil {
+		return err
+	} else if len(sessions) == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	_, err = p.remove(ctx, sessions, true)
+	return err
+}
+
+func (p *SessionPersister) DeleteSessionByToken(ctx context.Context, token string) (err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.DeleteSessionByToken(ctx, token)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.DeleteSessionByToken")
+	defer otelx.End(span, &err)
+
+	sessions, err := one(p.s.GetByToken(ctx, token))
+	if err != nil {
+		return err
+	}
+	_, err = p.remove(ctx, sessions, true)
+	return err
+}
+
+func (p *SessionPersister) RevokeSessionByToken(ctx context.Context, token string) (err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.RevokeSessionByToken(ctx, token)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.RevokeSessionByToken")
+	defer otelx.End(span, &err)
+
+	sessions, err := one(p.s.GetByToken(ctx, token))
+	if err != nil {
+		return err
+	}
+	_, err = p.remove(ctx, sessions, false)
+	return err
+}
+
+func (p *SessionPersister) RevokeSessionById(ctx context.Context, sID uuid.UUID) (err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.RevokeSessionById(ctx, sID)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.RevokeSessionById")
+	defer otelx.End(span, &err)
+
+	sessions, err := one(p.s.Get(ctx, sID))
+	if err != nil {
+		return err
+	}
+	_, err = p.remove(ctx, sessions, false)
+	return err
+}
+
+func (p *SessionPersister) RevokeSession(ctx context.Context, iID, sID uuid.UUID) (err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.RevokeSession(ctx, iID, sID)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.RevokeSession")
+	defer otelx.End(span, &err)
+
+	s, err := p.s.Get(ctx, sID)
+	if err != nil {
+		return err
+	} else if s == nil || s.IdentityID != iID {
+		// Like in the SQL persister, a session which does not exist counts as revoked.
+		return nil
+	}
+	_, err = p.remove(ctx, []session.Session{*s}, false)
+	return err
+}
+
+func (p *SessionPersister) RevokeSessionsIdentityExcept(ctx context.Context, iID, sID uuid.UUID) (_ int, err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.RevokeSessionsIdentityExcept(ctx, iID, sID)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.RevokeSessionsIdentityExcept")
+	defer otelx.End(span, &err)
+
+	sessions, err := p.s.IdentitySessions(ctx, iID)
+	if err != nil {
+		return 0, err
+	}
+
+	others := make([]session.Session, 0, len(sessions))
+	for _, s := range sessions {
+		if s.ID != sID {
+			others = append(others, s)
+		}
+	}
+	return p.remove(ctx, others, false)
+}
+
+func (p *SessionPersister) RevokeSessions(ctx context.Context, filter session.Filter) (_ int, err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.RevokeSessions(ctx, filter)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.RevokeSessions")
+	defer otelx.End(span, &err)
+
+	// Like in the SQL persister, the active state of the filter does not apply.
+	filter.Active = nil
+	sessions, err := p.filtered(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return p.remove(ctx, sessions, false)
+}
+
+func (p *SessionPersister) UpdateSessionLastActivity(ctx context.Context, sID uuid.UUID, at time.Time) (err error) {
+	mode := p.mode(ctx)
+	if mode == config.SessionPersistenceModeSQL {
+		return p.Persister.UpdateSessionLastActivity(ctx, sID, at)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.UpdateSessionLastActivity")
+	defer otelx.End(span, &err)
+
+	if mode == config.SessionPersistenceModeWriteThrough {
+		if err := p.Persister.UpdateSessionLastActivity(ctx, sID, at); err != nil {
+			return err
+		}
+	}
+
+	last := sqlxx.NullTime(at.UTC())
+	updated, err := p.s.Update(ctx, sID, func(s *session.Session) {
+		s.LastActivityAt = &last
+	})
+	if mode == config.SessionPersistenceModeRedis {
+		if err != nil {
+			return err
+		} else if !updated {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+		return nil
+	}
+
+	if err != nil {
+		// The SQL database is up to date, so the session is looked up there next time.
+		p.d.Logger().WithError(err).Warn("Unable to update session in Redis, removing it instead.")
+		if err := p.s.Delete(ctx, sID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *SessionPersister) SummarizeIdentitySessions(ctx context.Context, ids ...uuid.UUID) (_ map[uuid.UUID]identity.SessionSummary, err error) {
+	if !p.redisOnly(ctx) {
+		return p.Persister.SummarizeIdentitySessions(ctx, ids...)
+	}
+
+	ctx, span := p.d.Tracer(ctx).Tracer().Start(ctx, "persistence.redis.SummarizeIdentitySessions")
+	defer otelx.End(span, &err)
+
+	var sessions []session.Session
+	for _, id := range ids {
+		found, err := p.s.IdentitySessions(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, found...)
+	}
+	return session.Summarize(sessions), nil
+}
+
+// DeleteExpiredSessions does nothing in Redis mode, because Redis removes sessions when they expire.
+func (p *SessionPersister) DeleteExpiredSessions(ctx context.Context, expiresAt time.Time, limit int) error {
+	if p.redisOnly(ctx) {
+		return nil
+	}
+	return p.Persister.DeleteExpiredSessions(ctx, expiresAt, limit)
+}
+
+// ListExpiredSessionIDs returns no sessions in Redis mode, because Redis removes sessions when they expire.
+func (p *SessionPersister) ListExpiredSessionIDs(ctx context.Context, expiresAt time.Time, limit int) ([]uuid.UUID, error) {
+	if p.redisOnly(ctx) {
+		return nil, nil
+	}
+	return p.Persister.ListExpiredSessionIDs(ctx, expiresAt, limit)
+}
+
+// DeleteExpiredSessionsByID does nothing in Redis mode, because Redis removes sessions when they expire.
+func (p *SessionPersister) DeleteExpiredSessionsByID(ctx context.Context, expiresAt time.Time, ids []uuid.UUID) error {
+	if p.redisOnly(ctx) {
+		return nil
+	}
+	return p.Persister.DeleteExpiredSessionsByID(ctx, expiresAt, ids)
+}
+
+func (p *SessionPersister) CreateRefreshToken(ctx context.Context, t *session.RefreshToken, sessionTokenExpiresAt time.Time) error {
+	if p.redisOnly(ctx) {
+		return errUnsupported("Issuing refresh tokens")
+	}
+	return p.Persister.CreateRefreshToken(ctx, t, sessionTokenExpiresAt)
+}
+
+func (p *SessionPersister) RotateRefreshToken(ctx context.Context, usedID uuid.UUID, next *session.RefreshToken, sessionToken string, sessionTokenExpiresAt time.Time) error {
+	if p.redisOnly(ctx) {
+		return errUnsupported("Exchanging refresh tokens")
+	}
+	return p.Persister.RotateRefreshToken(ctx, usedID, next, sessionToken, sessionTokenExpiresAt)
+}
+
+// CreateUpstreamSession does nothing in Redis mode, because upstream sessions reference sessions in the SQL database.
+func (p *SessionPersister) CreateUpstreamSession(ctx context.Context, s *session.UpstreamSession) error {
+	if p.redisOnly(ctx) {
+		p.d.Logger().WithField("provider", s.Provider).Debug("Not linking the session to its upstream session, because sessions are only stored in Redis.")
+		return nil
+	}
+	return p.Persister.CreateUpstreamSession(ctx, s)
+}
+
+func (p *SessionPersister) RevokeSessionsByUpstreamSession(ctx context.Context, provider, subject, upstreamSessionID string) (int, error) {
+	if p.redisOnly(ctx) {
+		return 0, errUnsupported("Revoking sessions by upstream session")
+	}
+	return p.Persister.RevokeSessionsByUpstreamSession(ctx, provider, subject, upstreamSessionID)
+}
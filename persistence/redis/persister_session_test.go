@@ -0,0 +1,164 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package redis_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/persistence/redis"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+	"github.com/ory/kratos/x/redisx"
+	"github.com/ory/x/sqlcon"
+)
+
+func TestSessionPersister(t *testing.T) {
+	dsn := os.Getenv("TEST_REDIS_URL")
+	if dsn == "" {
+		t.Skip("Set TEST_REDIS_URL to the URL of a Redis server to run this test.")
+	}
+
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://../sql/stub/identity.schema.json")
+	conf.MustSet(ctx, config.ViperKeySessionPersistenceRedisKeyPrefix, "kratos-test:"+x.NewUUID().String()+":")
+
+	c, err := redisx.NewClient(dsn)
+	require.NoError(t, err)
+	store := redis.NewStore(reg, c, reg.Persister().NetworkID)
+	p := redis.NewSessionPersister(reg, reg.Persister(), store)
+
+	newSession := func(t *testing.T) *session.Session {
+		i := &identity.Identity{Traits: []byte("{}"), State: identity.StateActive}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+
+		req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+		s, err := session.NewActiveSession(req, i, conf, time.Now().UTC(), identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1)
+		require.NoError(t, err)
+		require.NoError(t, p.UpsertSession(ctx, s))
+		return s
+	}
+
+	t.Run("mode=redis", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySessionPersistenceMode, config.SessionPersistenceModeRedis)
+		t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionPersistenceMode, config.SessionPersistenceModeSQL) })
+
+		t.Run("case=stores sessions in redis only", func(t *testing.T) {
+			s := newSession(t)
+
+			actual, err := p.GetSessionByToken(ctx, s.Token, session.ExpandEverything, identity.ExpandDefault)
+			require.NoError(t, err)
+			assert.Equal(t, s.ID, actual.ID)
+			assert.Equal(t, s.IdentityID, actual.Identity.ID)
+			assert.True(t, actual.IsActive())
+
+			_, err = reg.Persister().GetSession(ctx, s.ID, session.ExpandNothing)
+			assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+		})
+
+		t.Run("case=revokes and deletes sessions", func(t *testing.T) {
+			s := newSession(t)
+
+			require.NoError(t, p.RevokeSessionByToken(ctx, s.Token))
+			actual, err := p.GetSession(ctx, s.ID, session.ExpandNothing)
+			require.NoError(t, err)
+			assert.False(t, actual.Active)
+
+			sessions, total, err := p.ListSessionsByIdentity(ctx, s.IdentityID, nil, 1, 10, x.NewUUID(), session.ExpandNothing)
+			require.NoError(t, err)
+			assert.EqualValues(t, 1, total)
+			require.Len(t, sessions, 1)
+			assert.Equal(t, s.ID, sessions[0].ID)
+
+			require.NoError(t, p.DeleteSessionsByIdentity(ctx, s.IdentityID))
+			_, err = p.GetSession(ctx, s.ID, session.ExpandNothing)
+			assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+			assert.ErrorIs(t, p.DeleteSessionsByIdentity(ctx, s.IdentityID), sqlcon.ErrNoRows)
+		})
+
+		t.Run("case=lists and revokes sessions by filter", func(t *testing.T) {
+			s1, s2 := newSession(t), newSession(t)
+
+			sessions, _, _, err := p.ListSessions(ctx, session.Filter{IdentityID: s1.IdentityID}, nil, session.ExpandNothing)
+			require.NoError(t, err)
+			require.Len(t, sessions, 1)
+			assert.Equal(t, s1.ID, sessions[0].ID)
+
+			revoked, err := p.RevokeSessions(ctx, session.Filter{IdentityID: s2.IdentityID})
+			require.NoError(t, err)
+			assert.Equal(t, 1, revoked)
+
+			actual, err := p.GetSession(ctx, s2.ID, session.ExpandNothing)
+			require.NoError(t, err)
+			assert.False(t, actual.Active)
+		})
+
+		t.Run("case=rejects refresh tokens", func(t *testing.T) {
+			s := newSession(t)
+			assert.Error(t, p.CreateRefreshToken(ctx, &session.RefreshToken{SessionID: s.ID}, time.Now().Add(time.Minute)))
+		})
+	})
+
+	t.Run("mode=write_through", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySessionPersistenceMode, config.SessionPersistenceModeWriteThrough)
+		t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionPersistenceMode, config.SessionPersistenceModeSQL) })
+
+		t.Run("case=writes sessions to sql and serves them from redis", func(t *testing.T) {
+			s := newSession(t)
+
+			_, err := reg.Persister().GetSession(ctx, s.ID, session.ExpandNothing)
+			require.NoError(t, err)
+
+			_, err = p.GetSessionByToken(ctx, s.Token, session.ExpandEverything, identity.ExpandDefault)
+			require.NoError(t, err)
+
+			cached, err := store.GetByToken(ctx, s.Token)
+			require.NoError(t, err)
+			require.NotNil(t, cached)
+			assert.Equal(t, s.ID, cached.ID)
+		})
+
+		t.Run("case=invalidation removes sessions from redis", func(t *testing.T) {
+			s := newSession(t)
+			_, err := p.GetSessionByToken(ctx, s.Token, session.ExpandEverything, identity.ExpandDefault)
+			require.NoError(t, err)
+
+			require.NoError(t, reg.Persister().RevokeSessionById(ctx, s.ID))
+			require.NoError(t, store.Invalidator().DeleteSessions(ctx, s.ID))
+
+			actual, err := p.GetSessionByToken(ctx, s.Token, session.ExpandNothing, identity.ExpandDefault)
+			require.NoError(t, err)
+			assert.False(t, actual.Active)
+		})
+
+		t.Run("case=records activity in both stores", func(t *testing.T) {
+			s := newSession(t)
+			_, err := p.GetSessionByToken(ctx, s.Token, session.ExpandNothing, identity.ExpandDefault)
+			require.NoError(t, err)
+
+			at := time.Now().UTC().Truncate(time.Second)
+			require.NoError(t, p.UpdateSessionLastActivity(ctx, s.ID, at))
+
+			cached, err := store.Get(ctx, s.ID)
+			require.NoError(t, err)
+			require.NotNil(t, cached.LastActivityAt)
+			assert.WithinDuration(t, at, time.Time(*cached.LastActivityAt), time.Second)
+
+			stored, err := reg.Persister().GetSession(ctx, s.ID, session.ExpandNothing)
+			require.NoError(t, err)
+			require.NotNil(t, stored.LastActivityAt)
+			assert.WithinDuration(t, at, time.Time(*stored.LastActivityAt), time.Second)
+		})
+	})
+}
@@ -0,0 +1,402 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlxx"
+)
+
+// scanBatchSize is the number of sessions loaded at once when iterating over all sessions of a network.
+const scanBatchSize = 500
+
+type (
+	storeDependencies interface {
+		config.Provider
+		x.LoggingProvider
+	}
+
+	// Store keeps sessions in Redis. Next to each session, it maintains an index from the token to the session,
+	// an index of the sessions of each identity, and an index of all sessions of the network. The indices are
+	// cleaned up lazily when they point to sessions which expired.
+	Store struct {
+		d   storeDependencies
+		c   *goredis.Client
+		nid func(ctx context.Context) uuid.UUID
+	}
+
+	// record is the stored representation of a session. It includes the fields which are hidden from the API.
+	record struct {
+		Session            *session.Session                     `json:"session"`
+		Token              string                               `json:"token"`
+		LogoutToken        string                               `json:"logout_token"`
+		IdentityID         uuid.UUID                            `json:"identity_id"`
+		NID                uuid.UUID                            `json:"nid"`
+		RequiredAAL        identity.AuthenticatorAssuranceLevel `json:"required_aal,omitempty"`
+		BoundKeyThumbprint string                               `json:"bound_key_thumbprint,omitempty"`
+		TokenExpiresAt     *sqlxx.NullTime                      `json:"token_expires_at,omitempty"`
+		CreatedAt          time.Time                            `json:"created_at"`
+		UpdatedAt          time.Time                            `json:"updated_at"`
+	}
+
+	// invalidator removes sessions from Redis when the session cache is invalidated.
+	invalidator struct {
+		s *Store
+	}
+)
+
+var _ session.CacheStore = new(invalidator)
+
+func NewStore(d storeDependencies, c *goredis.Client, nid func(ctx context.Context) uuid.UUID) *Store {
+	return &Store{d: d, c: c, nid: nid}
+}
+
+func (s *Store) key(ctx context.Context, kind, id string) string {
+	return s.d.Config().SessionPersistenceRedisKeyPrefix(ctx) + s.nid(ctx).String() + ":" + kind + ":" + id
+}
+
+func (s *Store) sessionKey(ctx context.Context, id string) string {
+	return s.key(ctx, "session", id)
+}
+
+func (s *Store) tokenKey(ctx context.Context, token string) string {
+	return s.key(ctx, "token", token)
+}
+
+func (s *Store) identityKey(ctx context.Context, identityID uuid.UUID) string {
+	return s.key(ctx, "identity", identityID.String())
+}
+
+func (s *Store) networkKey(ctx context.Context) string {
+	return s.key(ctx, "sessions", "all")
+}
+
+// ttl returns how long the session is kept in Redis. It is zero if the session must not be stored.
+func (s *Store) ttl(ctx context.Context, sess *session.Session) time.Duration {
+	ttl := sess.ExpiresAt.Sub(x.Now())
+	if s.d.Config().SessionPersistenceMode(ctx) == config.SessionPersistenceModeWriteThrough {
+		ttl = min(ttl, s.d.Config().SessionPersistenceRedisTTL(ctx))
+	}
+	return max(ttl, 0)
+}
+
+func encode(sess *session.Session) ([]byte, error) {
+	stored := *sess
+	stored.Identity = nil
+	stored.Tokenized = ""
+
+	b, err := json.Marshal(&record{
+		Session:            &stored,
+		Token:              sess.Token,
+		LogoutToken:        sess.LogoutToken,
+		IdentityID:         sess.IdentityID,
+		NID:                sess.NID,
+		RequiredAAL:        sess.RequiredAAL,
+		BoundKeyThumbprint: sess.BoundKeyThumbprint,
+		TokenExpiresAt:     sess.TokenExpiresAt,
+		CreatedAt:          sess.CreatedAt,
+		UpdatedAt:          sess.UpdatedAt,
+	})
+	return b, errors.WithStack(err)
+}
+
+func decode(b []byte) (*session.Session, error) {
+	var r record
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, errors.WithStack(err)
+	} else if r.Session == nil {
+		return nil, errors.New("the stored session is empty")
+	}
+
+	sess := r.Session
+	sess.Token = r.Token
+	sess.LogoutToken = r.LogoutToken
+	sess.IdentityID = r.IdentityID
+	sess.NID = r.NID
+	sess.RequiredAAL = r.RequiredAAL
+	sess.BoundKeyThumbprint = r.BoundKeyThumbprint
+	sess.TokenExpiresAt = r.TokenExpiresAt
+	sess.CreatedAt = r.CreatedAt
+	sess.UpdatedAt = r.UpdatedAt
+	for k := range sess.Devices {
+		sess.Devices[k].SessionID = sess.ID
+		sess.Devices[k].NID = sess.NID
+	}
+	return sess, nil
+}
+
+// putScript stores the session and adds it to the indices. The keys are the session, its token, the
+// sessions of its identity, and all sessions of the network. The arguments are the encoded session, the TTL
+// in milliseconds, and the session ID.
+var putScript = goredis.NewScript(`redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+redis.call("SET", KEYS[2], ARGV[3], "PX", ARGV[2])
+redis.call("SADD", KEYS[3], ARGV[3])
+redis.call("ZADD", KEYS[4], 0, ARGV[3])
+return 1`)
+
+// Get returns the session or nil if it is not stored.
+func (s *Store) Get(ctx context.Context, id uuid.UUID) (*session.Session, error) {
+	raw, err := s.c.Get(ctx, s.sessionKey(ctx, id.String())).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return decode(raw)
+}
+
+// GetByToken returns the session with the token or nil if it is not stored.
+func (s *Store) GetByToken(ctx context.Context, token string) (*session.Session, error) {
+	raw, err := s.c.Get(ctx, s.tokenKey(ctx, token)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	id, err := uuid.FromString(raw)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sess, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	} else if sess == nil || sess.Token != token {
+		// The token was replaced, for example by exchanging a refresh token.
+		return nil, nil
+	}
+	return sess, nil
+}
+
+// Put stores the session and indexes it. Sessions which expired are removed instead.
+func (s *Store) Put(ctx context.Context, sess *session.Session) error {
+	ttl := s.ttl(ctx, sess)
+	if ttl < time.Millisecond {
+		return s.Delete(ctx, sess.ID)
+	}
+
+	b, err := encode(sess)
+	if err != nil {
+		return err
+	}
+
+	id := sess.ID.String()
+	return errors.WithStack(putScript.Run(ctx, s.c, []string{
+		s.sessionKey(ctx, id),
+		s.tokenKey(ctx, sess.Token),
+		s.identityKey(ctx, sess.IdentityID),
+		s.networkKey(ctx),
+	}, b, ttl.Milliseconds(), id).Err())
+}
+
+// Update changes the stored session without changing when it is removed from Redis. It returns false if the
+// session is not stored. Concurrent updates of the same session overwrite each other.
+func (s *Store) Update(ctx context.Context, id uuid.UUID, f func(sess *session.Session)) (bool, error) {
+	sess, err := s.Get(ctx, id)
+	if err != nil || sess == nil {
+		return false, err
+	}
+	f(sess)
+
+	b, err := encode(sess)
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := s.c.SetXX(ctx, s.sessionKey(ctx, id.String()), b, goredis.KeepTTL).Result()
+	return updated, errors.WithStack(err)
+}
+
+// Delete removes the sessions and their indices.
+func (s *Store) Delete(ctx context.Context, ids ...uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	members := make([]string, len(ids))
+	for k, id := range ids {
+		members[k] = id.String()
+	}
+
+	found, _, err := s.load(ctx, members)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(found)+len(members))
+	byIdentity := make(map[uuid.UUID][]interface{})
+	for _, sess := range found {
+		keys = append(keys, s.tokenKey(ctx, sess.Token))
+		byIdentity[sess.IdentityID] = append(byIdentity[sess.IdentityID], sess.ID.String())
+	}
+	for _, id := range members {
+		keys = append(keys, s.sessionKey(ctx, id))
+	}
+
+	_, err = s.c.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Del(ctx, keys...)
+		for identityID, ids := range byIdentity {
+			pipe.SRem(ctx, s.identityKey(ctx, identityID), ids...)
+		}
+		pipe.ZRem(ctx, s.networkKey(ctx), toArgs(members)...)
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// IdentitySessions returns all stored sessions of the identity.
+func (s *Store) IdentitySessions(ctx context.Context, identityID uuid.UUID) ([]session.Session, error) {
+	key := s.identityKey(ctx, identityID)
+	members, err := s.c.SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	found, missing, err := s.load(ctx, members)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(missing) > 0 {
+		if err := s.c.SRem(ctx, key, toArgs(missing)...).Err(); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return found, nil
+}
+
+// Each calls f for all stored sessions of the network, ordered by their ID.
+func (s *Store) Each(ctx context.Context, f func(sess *session.Session) error) error {
+	key := s.networkKey(ctx)
+	from := "-"
+	for {
+		members, err := s.c.ZRangeByLex(ctx, key, &goredis.ZRangeBy{Min: from, Max: "+", Count: scanBatchSize}).Result()
+		if err != nil {
+			return errors.WithStack(err)
+		} else if len(members) == 0 {
+			return nil
+		}
+
+		found, missing, err := s.load(ctx, members)
+		if err != nil {
+			return err
+		}
+
+		if len(missing) > 0 {
+			if err := s.c.ZRem(ctx, key, toArgs(missing)...).Err(); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		for k := range found {
+			if err := f(&found[k]); err != nil {
+				return err
+			}
+		}
+
+		if len(members) < scanBatchSize {
+			return nil
+		}
+		from = "(" + members[len(members)-1]
+	}
+}
+
+// load returns the stored sessions with the IDs and the IDs of the sessions which are not stored.
+func (s *Store) load(ctx context.Context, ids []string) (found []session.Session, missing []string, err error) {
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	keys := make([]string, len(ids))
+	for k, id := range ids {
+		keys[k] = s.sessionKey(ctx, id)
+	}
+
+	values, err := s.c.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	} else if len(values) != len(ids) {
+		return nil, nil, errors.Errorf("unexpected redis reply: %v", values)
+	}
+
+	found = make([]session.Session, 0, len(values))
+	for k, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			missing = append(missing, ids[k])
+			continue
+		}
+
+		sess, err := decode([]byte(raw))
+		if err != nil {
+			return nil, nil, err
+		}
+		found = append(found, *sess)
+	}
+	return found, missing, nil
+}
+
+// toArgs converts the members of a set to command arguments.
+func toArgs(members []string) []interface{} {
+	args := make([]interface{}, len(members))
+	for k, m := range members {
+		args[k] = m
+	}
+	return args
+}
+
+// Invalidator returns a session cache store which removes sessions from Redis whenever they are removed from the
+// session cache. In write-through mode, this happens whenever sessions or their identities change in the SQL
+// database. It never serves or stores sessions itself.
+func (s *Store) Invalidator() session.CacheStore {
+	return &invalidator{s: s}
+}
+
+func (i *invalidator) enabled(ctx context.Context) bool {
+	return i.s.d.Config().SessionPersistenceMode(ctx) == config.SessionPersistenceModeWriteThrough
+}
+
+func (i *invalidator) GetSession(context.Context, string) (*session.Session, error) {
+	return nil, nil
+}
+
+func (i *invalidator) SetSession(context.Context, string, *session.Session, time.Duration) error {
+	return nil
+}
+
+func (i *invalidator) DeleteSessions(ctx context.Context, ids ...uuid.UUID) error {
+	if !i.enabled(ctx) {
+		return nil
+	}
+	return i.s.Delete(ctx, ids...)
+}
+
+func (i *invalidator) DeleteIdentitySessions(ctx context.Context, identityID uuid.UUID) error {
+	if !i.enabled(ctx) {
+		return nil
+	}
+
+	sessions, err := i.s.IdentitySessions(ctx, identityID)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uuid.UUID, len(sessions))
+	for k := range sessions {
+		ids[k] = sessions[k].ID
+	}
+	return i.s.Delete(ctx, ids...)
+}
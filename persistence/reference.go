@@ -19,14 +19,20 @@ import (
 	"github.com/ory/kratos/continuity"
 	"github.com/ory/kratos/courier"
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/organization"
+	"github.com/ory/kratos/selfservice/consent"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/invitation"
+	"github.com/ory/kratos/selfservice/lockout"
+	"github.com/ory/kratos/selfservice/signupcode"
 	"github.com/ory/kratos/selfservice/strategy/code"
 	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
 	"github.com/ory/kratos/session"
 )
 
@@ -41,6 +47,7 @@ type Persister interface {
 	registration.FlowPersister
 	login.FlowPersister
 	settings.FlowPersister
+	settings.EmailChangePersister
 	courier.Persister
 	session.Persister
 	sessiontokenexchange.Persister
@@ -53,8 +60,17 @@ type Persister interface {
 	code.VerificationCodePersister
 	code.RegistrationCodePersister
 	code.LoginCodePersister
+	lockout.Persister
+	consent.Persister
+	invitation.Persister
+	signupcode.Persister
+	oidc.ProviderPersister
+	organization.Persister
+	TableStatsProvider
+	MigrationReporter
+	PhasedMigrator
 
-	CleanupDatabase(context.Context, time.Duration, time.Duration, int) error
+	CleanupDatabase(context.Context, time.Duration, time.Duration, int, ...CleanupOption) error
 	Close(context.Context) error
 	Ping() error
 	MigrationStatus(c context.Context) (popx.MigrationStatuses, error)
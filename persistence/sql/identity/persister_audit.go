@@ -0,0 +1,107 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/persistence/sql/batch"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+// createAuditEvents appends the audit events to the audit trail. Nil events are skipped.
+func (p *IdentityPersister) createAuditEvents(ctx context.Context, conn *pop.Connection, events ...*identity.AuditEvent) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.createAuditEvents")
+	defer otelx.End(span, &err)
+
+	work := make([]*identity.AuditEvent, 0, len(events))
+	for _, e := range events {
+		if e == nil {
+			continue
+		}
+		e.NID = p.NetworkID(ctx)
+		work = append(work, e)
+	}
+
+	return sqlcon.HandleError(batch.Create(ctx, &batch.TracerConnection{Tracer: p.r.Tracer(ctx), Connection: conn}, work))
+}
+
+// getIdentityForAudit loads the identity with all its associations using the given connection. Unlike
+// HydrateIdentityAssociations, it queries sequentially and can therefore be used within a transaction.
+func (p *IdentityPersister) getIdentityForAudit(ctx context.Context, conn *pop.Connection, id uuid.UUID) (*identity.Identity, error) {
+	nid := p.NetworkID(ctx)
+
+	var i identity.Identity
+	if err := conn.Where("id = ? AND nid = ?", id, nid).First(&i); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	if err := conn.Where("identity_id = ? AND nid = ?", id, nid).Order("id ASC").All(&i.RecoveryAddresses); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	if err := conn.Where("identity_id = ? AND nid = ?", id, nid).Order("id ASC").All(&i.VerifiableAddresses); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	creds, err := QueryForCredentials(conn,
+		Where{"(identity_credentials.identity_id = ? AND identity_credentials.nid = ?)", []interface{}{id, nid}})
+	if err != nil {
+		return nil, err
+	}
+	i.Credentials = creds[id]
+
+	if err := identity.UpgradeCredentials(&i); err != nil {
+		return nil, err
+	}
+
+	return &i, nil
+}
+
+// ListAuditEvents lists the audit events matching the filter, most recent first. Pages are zero-indexed.
+func (p *IdentityPersister) ListAuditEvents(ctx context.Context, filter identity.AuditEventFilter, page, perPage int) (_ []identity.AuditEvent, _ int64, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListAuditEvents")
+	defer otelx.End(span, &err)
+
+	conditions := []string{"nid = ?"}
+	args := []interface{}{p.NetworkID(ctx)}
+	if filter.IdentityID != uuid.Nil {
+		conditions = append(conditions, "identity_id = ?")
+		args = append(args, filter.IdentityID)
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.ActorType != "" {
+		conditions = append(conditions, "actor_type = ?")
+		args = append(args, filter.ActorType)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.CreatedBefore)
+	}
+
+	events := make([]identity.AuditEvent, 0)
+	q := p.GetConnection(ctx).Where(strings.Join(conditions, " AND "), args...)
+
+	total, err := q.Count(new(identity.AuditEvent))
+	if err != nil {
+		return nil, 0, sqlcon.HandleError(err)
+	}
+
+	if err := q.Order("created_at DESC, id DESC").Paginate(page+1, perPage).All(&events); err != nil {
+		return nil, 0, sqlcon.HandleError(err)
+	}
+
+	return events, int64(total), nil
+}
@@ -0,0 +1,138 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/persistence/sql/batch"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+// createLifecycleEvents appends the lifecycle events to the outbox if identity events are enabled.
+func (p *IdentityPersister) createLifecycleEvents(ctx context.Context, conn *pop.Connection, events ...*identity.LifecycleEvent) (err error) {
+	if !p.r.Config().IdentityEventsEnabled(ctx) || len(events) == 0 {
+		return nil
+	}
+
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.createLifecycleEvents")
+	defer otelx.End(span, &err)
+
+	work := make([]*identity.LifecycleEvent, 0, len(events))
+	for _, e := range events {
+		if e == nil {
+			continue
+		}
+		e.NID = p.NetworkID(ctx)
+		work = append(work, e)
+	}
+
+	return sqlcon.HandleError(batch.Create(ctx, &batch.TracerConnection{Tracer: p.r.Tracer(ctx), Connection: conn}, work))
+}
+
+// recordIdentityChange appends the audit event and the lifecycle events of a change to an identity.
+func (p *IdentityPersister) recordIdentityChange(ctx context.Context, conn *pop.Connection, action identity.AuditAction, before, after *identity.Identity) error {
+	event, err := identity.NewAuditEvent(ctx, action, before, after)
+	if err != nil {
+		return err
+	}
+	if err := p.createAuditEvents(ctx, conn, event); err != nil {
+		return err
+	}
+
+	events, err := identity.NewLifecycleEvents(before, after)
+	if err != nil {
+		return err
+	}
+	return p.createLifecycleEvents(ctx, conn, events...)
+}
+
+// ClaimLifecycleEvents returns up to limit undelivered lifecycle events which are due, oldest first, and postpones
+// their next delivery attempt by the lease. Events which are not marked as delivered or failed before the lease
+// expires are claimed again.
+func (p *IdentityPersister) ClaimLifecycleEvents(ctx context.Context, limit, maxAttempts int, lease time.Duration) (_ []identity.LifecycleEvent, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ClaimLifecycleEvents")
+	defer otelx.End(span, &err)
+
+	events := make([]identity.LifecycleEvent, 0, limit)
+	if err := p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		now := time.Now().UTC()
+		if err := tx.
+			Where("nid = ? AND delivered_at IS NULL AND attempts < ? AND next_attempt_at <= ?", p.NetworkID(ctx), maxAttempts, now).
+			Order("next_attempt_at ASC, created_at ASC").
+			Limit(limit).
+			All(&events); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		args := []interface{}{now.Add(lease), p.NetworkID(ctx)}
+		placeholders := make([]string, len(events))
+		for k := range events {
+			placeholders[k] = "?"
+			args = append(args, events[k].ID)
+		}
+
+		// #nosec G201 -- TableName and placeholders are static
+		return sqlcon.HandleError(tx.RawQuery(
+			fmt.Sprintf("UPDATE %s SET next_attempt_at = ? WHERE nid = ? AND id IN (%s)",
+				new(identity.LifecycleEvent).TableName(ctx), strings.Join(placeholders, ", ")),
+			args...,
+		).Exec())
+	}); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// MarkLifecycleEventDelivered marks the lifecycle event as delivered to all sinks.
+func (p *IdentityPersister) MarkLifecycleEventDelivered(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.MarkLifecycleEventDelivered")
+	defer otelx.End(span, &err)
+
+	// #nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(
+		fmt.Sprintf("UPDATE %s SET delivered_at = ? WHERE id = ? AND nid = ?", new(identity.LifecycleEvent).TableName(ctx)),
+		time.Now().UTC(), id, p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+// MarkLifecycleEventFailed records a failed delivery attempt of the lifecycle event and schedules the next one.
+func (p *IdentityPersister) MarkLifecycleEventFailed(ctx context.Context, id uuid.UUID, nextAttemptAt time.Time, reason string) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.MarkLifecycleEventFailed")
+	defer otelx.End(span, &err)
+
+	// #nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(
+		fmt.Sprintf("UPDATE %s SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE id = ? AND nid = ?", new(identity.LifecycleEvent).TableName(ctx)),
+		nextAttemptAt.UTC(), reason, id, p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
@@ -99,6 +99,56 @@ func (p *IdentityPersister) ListVerifiableAddresses(ctx context.Context, page, i
 	return a, nil
 }
 
+func (p *IdentityPersister) ListUnverifiedAddresses(ctx context.Context, createdBefore time.Time, after uuid.UUID, limit int) (a []identity.VerifiableAddress, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListUnverifiedAddresses")
+	defer otelx.End(span, &err)
+
+	if err := p.GetConnection(ctx).
+		Where("nid = ? AND verified = ? AND created_at < ? AND id > ?", p.NetworkID(ctx), false, createdBefore.UTC(), after).
+		Order("id ASC").
+		Limit(limit).
+		All(&a); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return a, nil
+}
+
+func (p *IdentityPersister) ClaimVerifiableAddressReminder(ctx context.Context, address *identity.VerifiableAddress, previous int) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ClaimVerifiableAddressReminder")
+	defer otelx.End(span, &err)
+
+	// #nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(
+		fmt.Sprintf(
+			`UPDATE %s SET reminders_sent = ?, last_reminded_at = ?, updated_at = ? WHERE id = ? AND nid = ? AND reminders_sent = ?`,
+			new(identity.VerifiableAddress).TableName(ctx)),
+		address.RemindersSent, address.LastRemindedAt, time.Now().UTC(), address.ID, p.NetworkID(ctx), previous,
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *IdentityPersister) VerificationReminderStats(ctx context.Context) (_ []identity.VerificationReminderStats, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.VerificationReminderStats")
+	defer otelx.End(span, &err)
+
+	stats := make([]identity.VerificationReminderStats, 0)
+	if err := p.GetConnection(ctx).RawQuery(
+		"SELECT reminders_sent, verified, COUNT(*) AS addresses FROM identity_verifiable_addresses WHERE nid = ? AND reminders_sent > 0 GROUP BY reminders_sent, verified",
+		p.NetworkID(ctx),
+	).All(&stats); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return stats, nil
+}
+
 func (p *IdentityPersister) ListRecoveryAddresses(ctx context.Context, page, itemsPerPage int) (a []identity.RecoveryAddress, err error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListRecoveryAddresses")
 	defer otelx.End(span, &err)
@@ -326,7 +376,7 @@ func (p *IdentityPersister) createIdentityCredentials(ctx context.Context, conn
 		return err
 	}
 
-	return nil
+	return p.createWebAuthnUserHandles(ctx, conn, credentials...)
 }
 
 func (p *IdentityPersister) createVerifiableAddresses(ctx context.Context, conn *pop.Connection, identities ...*identity.Identity) (err error) {
@@ -445,11 +495,123 @@ func (p *IdentityPersister) createRecoveryAddresses(ctx context.Context, conn *p
 	return batch.Create(ctx, &batch.TracerConnection{Tracer: p.r.Tracer(ctx), Connection: conn}, work)
 }
 
+// searchableTraits returns the searchable traits of the identity schema. Compiling the schema is expensive, so
+// the result is stored in the cache.
+func (p *IdentityPersister) searchableTraits(ctx context.Context, cache map[string]schema.SearchableTraits, schemaID string) (schema.SearchableTraits, error) {
+	if traits, ok := cache[schemaID]; ok {
+		return traits, nil
+	}
+
+	ss, err := p.r.IdentityTraitsSchemas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ss.GetByID(schemaID)
+	if err != nil {
+		return nil, err
+	}
+
+	traits, err := schema.NewSearchableTraits(ctx, s.URL.String())
+	if err != nil {
+		return nil, err
+	}
+	cache[schemaID] = traits
+	return traits, nil
+}
+
+func (p *IdentityPersister) createSearchTraits(ctx context.Context, conn *pop.Connection, identities ...*identity.Identity) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.createSearchTraits")
+	defer otelx.End(span, &err)
+
+	cache := make(map[string]schema.SearchableTraits)
+	work := make([]*identity.SearchTrait, 0)
+	for _, i := range identities {
+		traits, err := p.searchableTraits(ctx, cache, i.SchemaID)
+		if err != nil {
+			return err
+		}
+
+		values := traits.Values(i.Traits)
+		for name, vs := range values {
+			for _, v := range vs {
+				work = append(work, &identity.SearchTrait{NID: i.NID, IdentityID: i.ID, Trait: name, Value: v})
+			}
+		}
+		if len(traits) > 0 && len(values) == 0 {
+			work = append(work, &identity.SearchTrait{NID: i.NID, IdentityID: i.ID, Trait: searchTraitsIndexedMarker})
+		}
+	}
+
+	return batch.Create(ctx, &batch.TracerConnection{Tracer: p.r.Tracer(ctx), Connection: conn}, work)
+}
+
+// traitQueryConditions validates the trait queries against the searchable traits of all identity schemas and
+// returns the SQL conditions matching them.
+func (p *IdentityPersister) traitQueryConditions(ctx context.Context, nid uuid.UUID, queries []identity.TraitQuery) (string, []any, error) {
+	if len(queries) == 0 {
+		return "", nil, nil
+	}
+
+	ss, err := p.r.IdentityTraitsSchemas(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cache := make(map[string]schema.SearchableTraits)
+	searchable := make(schema.SearchableTraits)
+	for _, s := range ss {
+		traits, err := p.searchableTraits(ctx, cache, s.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		for name, t := range traits {
+			t.Contains = t.Contains || searchable[name].Contains
+			searchable[name] = t
+		}
+	}
+
+	var (
+		wheres string
+		args   []any
+	)
+	for _, q := range queries {
+		t, ok := searchable[q.Trait]
+		if !ok {
+			return "", nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+				"The trait %q is not searchable. Annotate it in the identity schema to make it searchable.", q.Trait))
+		}
+
+		condition, term := "ist.value = ?", q.Term
+		switch q.Operator {
+		case identity.TraitQueryPrefix:
+			condition, term = "ist.value LIKE ? ESCAPE '!'", escapeLike(q.Term)+"%"
+		case identity.TraitQueryContains:
+			if !t.Contains {
+				return "", nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(
+					"The trait %q does not allow contains queries.", q.Trait))
+			}
+			condition, term = "ist.value LIKE ? ESCAPE '!'", "%"+escapeLike(q.Term)+"%"
+		}
+
+		wheres += fmt.Sprintf(`
+			AND identities.id IN (SELECT ist.identity_id FROM identity_search_traits ist WHERE ist.nid = ? AND ist.trait = ? AND %s)`, condition)
+		args = append(args, nid, q.Trait, term)
+	}
+
+	return wheres, args, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern using `!`, which unlike the backslash is treated the
+// same way by all databases.
+func escapeLike(v string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(v)
+}
+
 func (p *IdentityPersister) CountIdentities(ctx context.Context) (n int64, err error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CountIdentities")
 	defer otelx.End(span, &err)
 
-	count, err := p.c.WithContext(ctx).Where("nid = ?", p.NetworkID(ctx)).Count(new(identity.Identity))
+	count, err := p.c.WithContext(ctx).Where("nid = ? AND state != ?", p.NetworkID(ctx), identity.StateSoftDeleted).Count(new(identity.Identity))
 	if err != nil {
 		return 0, sqlcon.HandleError(err)
 	}
@@ -514,6 +676,15 @@ func (p *IdentityPersister) CreateIdentities(ctx context.Context, identities ...
 		if err = p.createIdentityCredentials(ctx, tx, identities...); err != nil {
 			return sqlcon.HandleError(err)
 		}
+		if err = p.createSearchTraits(ctx, tx, identities...); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		for _, ident := range identities {
+			if err := p.recordIdentityChange(ctx, tx, identity.AuditActionIdentityCreated, nil, ident); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 }
@@ -696,6 +867,8 @@ func paginationAttributes(params *identity.ListIdentityParameters, paginator *ke
 		attribute.StringSlice("expand", params.Expand.ToEager()),
 		attribute.Bool("use:credential_identifier_filter", params.CredentialsIdentifier != ""),
 		attribute.Bool("use:credential_identifier_similar_filter", params.CredentialsIdentifierSimilar != ""),
+		attribute.Bool("use:state_filter", params.StateFilter != ""),
+		attribute.Bool("use:schema_id_filter", params.SchemaIDFilter != ""),
 	}
 	if params.PagePagination != nil {
 		attrs = append(attrs,
@@ -724,6 +897,11 @@ func (p *IdentityPersister) ListIdentities(ctx context.Context, params identity.
 	nid := p.NetworkID(ctx)
 	var is []identity.Identity
 
+	traitWheres, traitArgs, err := p.traitQueryConditions(ctx, nid, params.TraitQueries)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	if err = p.Transaction(ctx, func(ctx context.Context, con *pop.Connection) error {
 		is = make([]identity.Identity, 0) // Make sure we reset this to 0 in case of retries.
 		nextPage = nil
@@ -781,6 +959,29 @@ func (p *IdentityPersister) ListIdentities(ctx context.Context, params identity.
 			args = append(args, params.IdsFilter)
 		}
 
+		if params.StateFilter != "" {
+			wheres += `
+				AND identities.state = ?
+			`
+			args = append(args, params.StateFilter)
+		} else {
+			// Soft-deleted identities are only listed if they are requested explicitly.
+			wheres += `
+				AND identities.state != ?
+			`
+			args = append(args, identity.StateSoftDeleted)
+		}
+
+		if params.SchemaIDFilter != "" {
+			wheres += `
+				AND identities.schema_id = ?
+			`
+			args = append(args, params.SchemaIDFilter)
+		}
+
+		wheres += traitWheres
+		args = append(args, traitArgs...)
+
 		query := fmt.Sprintf(`
 		SELECT DISTINCT identities.*
 		FROM identities AS identities
@@ -877,12 +1078,47 @@ func (p *IdentityPersister) UpdateIdentity(ctx context.Context, i *identity.Iden
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateIdentity")
 	defer otelx.End(span, &err)
 
+	return p.updateIdentity(ctx, i, nil)
+}
+
+func (p *IdentityPersister) UpdateIdentityIfUnmodifiedSince(ctx context.Context, i *identity.Identity, updatedAt time.Time) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateIdentityIfUnmodifiedSince")
+	defer otelx.End(span, &err)
+
+	return p.updateIdentity(ctx, i, &updatedAt)
+}
+
+func (p *IdentityPersister) updateIdentity(ctx context.Context, i *identity.Identity, unmodifiedSince *time.Time) error {
 	if err := p.validateIdentity(ctx, i); err != nil {
 		return err
 	}
 
 	i.NID = p.NetworkID(ctx)
 	return sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		before, err := p.getIdentityForAudit(ctx, tx, i.ID)
+		if err != nil {
+			return err
+		}
+
+		i.UpdatedAt = nextUpdatedAt(tx, before.UpdatedAt)
+		if unmodifiedSince != nil {
+			// Advancing the update time only if it did not change yet locks the identity until the transaction
+			// ends, so that it can not be updated concurrently.
+			// #nosec G201 -- TableName is static
+			count, err := tx.RawQuery(
+				fmt.Sprintf(
+					`UPDATE %s SET updated_at = ? WHERE id = ? AND nid = ? AND updated_at = ?`,
+					new(identity.Identity).TableName(ctx)),
+				i.UpdatedAt, i.ID, i.NID, *unmodifiedSince,
+			).ExecWithCount()
+			if err != nil {
+				return sqlcon.HandleError(err)
+			}
+			if count == 0 {
+				return errors.WithStack(identity.ErrIdentityModified)
+			}
+		}
+
 		// This returns "ErrNoRows" if the identity does not exist
 		if err := update.Generic(WithTransaction(ctx, tx), tx, p.r.Tracer(ctx).Tracer(), i); err != nil {
 			return err
@@ -906,7 +1142,94 @@ func (p *IdentityPersister) UpdateIdentity(ctx context.Context, i *identity.Iden
 			return sqlcon.HandleError(err)
 		}
 
-		return sqlcon.HandleError(p.createIdentityCredentials(ctx, tx, i))
+		if err := p.createIdentityCredentials(ctx, tx, i); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		// #nosec G201 -- TableName is static
+		if err := tx.RawQuery(
+			fmt.Sprintf(
+				`DELETE FROM %s WHERE identity_id = ? AND nid = ?`,
+				new(identity.SearchTrait).TableName(ctx)),
+			i.ID, p.NetworkID(ctx)).Exec(); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		if err := p.createSearchTraits(ctx, tx, i); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		return p.recordIdentityChange(ctx, tx, identity.AuditActionIdentityUpdated, before, i)
+	}))
+}
+
+// nextUpdatedAt returns the time at which an identity that was last updated at previous is updated. MySQL stores the
+// time with a precision of one second, which is why the time advances to the next second there if needed to keep
+// updates within the same second apart.
+func nextUpdatedAt(conn *pop.Connection, previous time.Time) time.Time {
+	now := time.Now().UTC()
+	if conn.Dialect.Name() == "mysql" && !now.Truncate(time.Second).After(previous.Truncate(time.Second)) {
+		return previous.UTC().Truncate(time.Second).Add(time.Second)
+	}
+	return now
+}
+
+func (p *IdentityPersister) UpdateIdentityCredentialsConfig(ctx context.Context, identityID uuid.UUID, ct identity.CredentialsType, update func(config sqlxx.JSONRawMessage) (sqlxx.JSONRawMessage, error)) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateIdentityCredentialsConfig")
+	defer otelx.End(span, &err)
+
+	t, err := p.findIdentityCredentialsType(ctx, ct)
+	if err != nil {
+		return err
+	}
+
+	return sqlcon.HandleError(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		// The credentials are locked, so that concurrent updates of the configuration are not lost.
+		// #nosec G201 -- TableName is static
+		query := fmt.Sprintf(
+			`SELECT * FROM %s WHERE identity_id = ? AND identity_credential_type_id = ? AND nid = ?`,
+			new(identity.Credentials).TableName(ctx))
+		if tx.Dialect.Name() != "sqlite3" {
+			query += " FOR UPDATE"
+		}
+
+		var cred identity.Credentials
+		if err := tx.RawQuery(query, identityID, t.ID, p.NetworkID(ctx)).First(&cred); err != nil {
+			return sqlcon.HandleError(err)
+		}
+		cred.Type = ct
+
+		config, err := update(cred.Config)
+		if err != nil {
+			return err
+		}
+		cred.Config = config
+
+		// #nosec G201 -- TableName is static
+		if err := tx.RawQuery(
+			fmt.Sprintf(
+				`UPDATE %s SET config = ?, updated_at = ? WHERE id = ? AND nid = ?`,
+				new(identity.Credentials).TableName(ctx)),
+			cred.Config, time.Now().UTC(), cred.ID, p.NetworkID(ctx),
+		).Exec(); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		if ct != identity.CredentialsTypeWebAuthn {
+			return nil
+		}
+
+		// The configuration holds the user handles, which have to be indexed again.
+		// #nosec G201 -- TableName is static
+		if err := tx.RawQuery(
+			fmt.Sprintf(
+				`DELETE FROM %s WHERE identity_credential_id = ? AND nid = ?`,
+				new(identity.WebAuthnUserHandle).TableName(ctx)),
+			cred.ID, p.NetworkID(ctx)).Exec(); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		return p.createWebAuthnUserHandles(ctx, tx, &cred)
 	}))
 }
 
@@ -915,17 +1238,25 @@ func (p *IdentityPersister) DeleteIdentity(ctx context.Context, id uuid.UUID) (e
 	defer otelx.End(span, &err)
 
 	nid := p.NetworkID(ctx)
-	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id = ? AND nid = ?", new(identity.Identity).TableName(ctx)),
-		id,
-		nid,
-	).ExecWithCount()
-	if err != nil {
-		return sqlcon.HandleError(err)
-	}
-	if count == 0 {
-		return errors.WithStack(sqlcon.ErrNoRows)
-	}
-	return nil
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		before, err := p.getIdentityForAudit(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		count, err := tx.RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id = ? AND nid = ?", new(identity.Identity).TableName(ctx)),
+			id,
+			nid,
+		).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		if count == 0 {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+
+		return p.recordIdentityChange(ctx, tx, identity.AuditActionIdentityDeleted, before, nil)
+	})
 }
 
 func (p *IdentityPersister) GetIdentity(ctx context.Context, id uuid.UUID, expand identity.Expandables) (_ *identity.Identity, err error) {
@@ -1020,7 +1351,30 @@ func (p *IdentityPersister) UpdateVerifiableAddress(ctx context.Context, address
 
 	address.NID = p.NetworkID(ctx)
 	address.Value = stringToLowerTrim(address.Value)
-	return update.Generic(ctx, p.GetConnection(ctx), p.r.Tracer(ctx).Tracer(), address)
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		var before identity.VerifiableAddress
+		if err := tx.Where("id = ? AND nid = ?", address.ID, address.NID).First(&before); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		if err := update.Generic(ctx, tx, p.r.Tracer(ctx).Tracer(), address); err != nil {
+			return err
+		}
+
+		event, err := identity.NewVerifiableAddressAuditEvent(ctx, &before, address)
+		if err != nil {
+			return err
+		}
+		if err := p.createAuditEvents(ctx, tx, event); err != nil {
+			return err
+		}
+
+		verified, err := identity.NewAddressVerifiedLifecycleEvent(&before, address)
+		if err != nil {
+			return err
+		}
+		return p.createLifecycleEvents(ctx, tx, verified)
+	})
 }
 
 func (p *IdentityPersister) validateIdentity(ctx context.Context, i *identity.Identity) (err error) {
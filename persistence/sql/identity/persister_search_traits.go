@@ -0,0 +1,125 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/persistence"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+)
+
+// SearchTraitsBackfillName is the phased migration which indexes the searchable traits of identities which were
+// created before their traits could be searched. Until it completed, searches do not find these identities.
+const SearchTraitsBackfillName = "identity_search_traits"
+
+// searchTraitsIndexedMarker is stored as the trait of identities whose searchable traits have no values, so that
+// the backfill knows that they were indexed. Trait queries never match it because traits have a name.
+const searchTraitsIndexedMarker = ""
+
+var _ persistence.Backfiller = new(SearchTraitsBackfiller)
+
+// SearchTraitsBackfiller indexes the searchable traits of identities which have no search traits yet.
+type SearchTraitsBackfiller struct {
+	p *IdentityPersister
+}
+
+type searchTraitsBackfillRow struct {
+	ID       uuid.UUID            `db:"id"`
+	NID      uuid.UUID            `db:"nid"`
+	SchemaID string               `db:"schema_id"`
+	Traits   sqlxx.JSONRawMessage `db:"traits"`
+}
+
+func NewSearchTraitsBackfiller(r dependencies) *SearchTraitsBackfiller {
+	return &SearchTraitsBackfiller{p: &IdentityPersister{r: r}}
+}
+
+func (b *SearchTraitsBackfiller) Name() string {
+	return SearchTraitsBackfillName
+}
+
+// searchableSchemas returns the IDs of the identity schemas which have searchable traits. Identities of other
+// schemas have nothing to index.
+func (b *SearchTraitsBackfiller) searchableSchemas(ctx context.Context) ([]interface{}, error) {
+	ss, err := b.p.r.IdentityTraitsSchemas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := make(map[string]schema.SearchableTraits)
+	ids := make([]interface{}, 0, len(ss))
+	for _, s := range ss {
+		traits, err := b.p.searchableTraits(ctx, cache, s.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(traits) > 0 {
+			ids = append(ids, s.ID)
+		}
+	}
+	return ids, nil
+}
+
+// pendingQuery returns the query for identities of the given number of schemas which have no search traits.
+func (b *SearchTraitsBackfiller) pendingQuery(columns string, schemas int) string {
+	// #nosec G201 -- the columns and placeholders are static
+	return fmt.Sprintf(`
+		SELECT %s
+		FROM identities i
+		WHERE i.schema_id IN (%s)
+		AND NOT EXISTS (
+			SELECT 1 FROM identity_search_traits ist
+			WHERE ist.identity_id = i.id AND ist.nid = i.nid
+		)`, columns, strings.TrimSuffix(strings.Repeat("?, ", schemas), ", "))
+}
+
+func (b *SearchTraitsBackfiller) Remaining(ctx context.Context, conn *pop.Connection) (int64, error) {
+	schemas, err := b.searchableSchemas(ctx)
+	if err != nil || len(schemas) == 0 {
+		return 0, err
+	}
+
+	var count int64
+	if err := conn.WithContext(ctx).RawQuery(
+		b.pendingQuery("COUNT(*)", len(schemas)),
+		schemas...,
+	).First(&count); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return count, nil
+}
+
+func (b *SearchTraitsBackfiller) Backfill(ctx context.Context, conn *pop.Connection, limit int) (int, error) {
+	schemas, err := b.searchableSchemas(ctx)
+	if err != nil || len(schemas) == 0 {
+		return 0, err
+	}
+
+	var rows []searchTraitsBackfillRow
+	if err := conn.WithContext(ctx).RawQuery(
+		b.pendingQuery("i.id, i.nid, i.schema_id, i.traits", len(schemas))+" ORDER BY i.id LIMIT ?",
+		append(schemas, limit)...,
+	).All(&rows); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+
+	identities := make([]*identity.Identity, len(rows))
+	for k, row := range rows {
+		identities[k] = &identity.Identity{ID: row.ID, NID: row.NID, SchemaID: row.SchemaID, Traits: identity.Traits(row.Traits)}
+	}
+	if err := b.p.createSearchTraits(ctx, conn, identities...); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+
+	return len(rows), nil
+}
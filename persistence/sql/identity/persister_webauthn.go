@@ -0,0 +1,190 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package identity
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/persistence"
+	"github.com/ory/kratos/persistence/sql/batch"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+)
+
+// WebAuthnUserHandleBackfillName is the phased migration which indexes the user handles of existing WebAuthn
+// credentials. Until it is cut over, user handles are also looked up in the credentials' configuration.
+const WebAuthnUserHandleBackfillName = "webauthn_user_handles"
+
+var _ persistence.Backfiller = new(WebAuthnUserHandleBackfiller)
+
+func (p *IdentityPersister) FindIdentityByWebAuthnUserHandle(ctx context.Context, userHandle []byte) (_ *identity.Identity, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FindIdentityByWebAuthnUserHandle")
+	defer otelx.End(span, &err)
+
+	var handle identity.WebAuthnUserHandle
+	err = sqlcon.HandleError(p.GetConnection(ctx).
+		Where("user_handle = ? AND nid = ?", base64.StdEncoding.EncodeToString(userHandle), p.NetworkID(ctx)).
+		First(&handle))
+	if err == nil {
+		return p.GetIdentityConfidential(ctx, handle.IdentityID)
+	} else if !errors.Is(err, sqlcon.ErrNoRows) || slices.Contains(p.r.Config().DatabasePhasedMigrationsCutover(ctx), WebAuthnUserHandleBackfillName) {
+		return nil, err
+	}
+
+	// The user handle was stored before it was indexed and was not backfilled yet.
+	var find struct {
+		IdentityID uuid.UUID `db:"identity_id"`
+	}
+	conn := p.GetConnection(ctx)
+	// #nosec G201 -- the JSON expression is static
+	if err := conn.RawQuery(fmt.Sprintf(`
+		SELECT
+			ic.identity_id
+		FROM identity_credentials ic
+				INNER JOIN identity_credential_types ict
+					ON ic.identity_credential_type_id = ict.id
+		WHERE %s = ?
+		AND ic.nid = ?
+		AND ict.name = ?
+		LIMIT 1`, webAuthnUserHandleColumn(conn)),
+		base64.StdEncoding.EncodeToString(userHandle),
+		p.NetworkID(ctx),
+		identity.CredentialsTypeWebAuthn,
+	).First(&find); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return p.GetIdentityConfidential(ctx, find.IdentityID)
+}
+
+// webAuthnUserHandles returns the index of the user handles of the WebAuthn credentials.
+func webAuthnUserHandles(credentials ...*identity.Credentials) ([]*identity.WebAuthnUserHandle, error) {
+	var handles []*identity.WebAuthnUserHandle
+	for _, cred := range credentials {
+		if cred.Type != identity.CredentialsTypeWebAuthn {
+			continue
+		}
+
+		var conf identity.CredentialsWebAuthnConfig
+		if err := json.Unmarshal(cred.Config, &conf); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		for _, userHandle := range conf.UserHandles() {
+			handles = append(handles, &identity.WebAuthnUserHandle{
+				NID:                   cred.NID,
+				IdentityID:            cred.IdentityID,
+				IdentityCredentialsID: cred.ID,
+				UserHandle:            base64.StdEncoding.EncodeToString(userHandle),
+			})
+		}
+	}
+	return handles, nil
+}
+
+func (p *IdentityPersister) createWebAuthnUserHandles(ctx context.Context, conn *pop.Connection, credentials ...*identity.Credentials) error {
+	handles, err := webAuthnUserHandles(credentials...)
+	if err != nil {
+		return err
+	}
+
+	return batch.Create(ctx, &batch.TracerConnection{Tracer: p.r.Tracer(ctx), Connection: conn}, handles)
+}
+
+// webAuthnUserHandleColumn returns the SQL expression which extracts the base64 encoded user handle from the
+// configuration of WebAuthn credentials aliased as `ic`.
+func webAuthnUserHandleColumn(conn *pop.Connection) string {
+	switch conn.Dialect.Name() {
+	case "sqlite3":
+		return "json_extract(ic.config, '$.user_handle')"
+	case "mysql":
+		return "JSON_UNQUOTE(JSON_EXTRACT(ic.config, '$.user_handle'))"
+	default:
+		return "ic.config->>'user_handle'"
+	}
+}
+
+// WebAuthnUserHandleBackfiller indexes the user handles of WebAuthn credentials, which were only kept in the
+// credentials' configuration.
+type WebAuthnUserHandleBackfiller struct{}
+
+type webAuthnUserHandleBackfillRow struct {
+	ID         uuid.UUID            `db:"id"`
+	NID        uuid.UUID            `db:"nid"`
+	IdentityID uuid.UUID            `db:"identity_id"`
+	Config     sqlxx.JSONRawMessage `db:"config"`
+}
+
+func (b *WebAuthnUserHandleBackfiller) Name() string {
+	return WebAuthnUserHandleBackfillName
+}
+
+// pendingQuery returns the query for WebAuthn credentials which have a user handle which is not indexed.
+func (b *WebAuthnUserHandleBackfiller) pendingQuery(conn *pop.Connection, columns string) string {
+	column := webAuthnUserHandleColumn(conn)
+	// #nosec G201 -- the columns and the JSON expression are static
+	return fmt.Sprintf(`
+		SELECT %s
+		FROM identity_credentials ic
+				INNER JOIN identity_credential_types ict
+					ON ic.identity_credential_type_id = ict.id
+		WHERE ict.name = ?
+		AND %s IS NOT NULL AND %s <> ''
+		AND NOT EXISTS (
+			SELECT 1 FROM identity_webauthn_user_handles iwuh
+			WHERE iwuh.identity_credential_id = ic.id AND iwuh.nid = ic.nid
+		)`, columns, column, column)
+}
+
+func (b *WebAuthnUserHandleBackfiller) Remaining(ctx context.Context, conn *pop.Connection) (int64, error) {
+	var count int64
+	if err := conn.WithContext(ctx).RawQuery(
+		b.pendingQuery(conn, "COUNT(*)"),
+		identity.CredentialsTypeWebAuthn,
+	).First(&count); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return count, nil
+}
+
+func (b *WebAuthnUserHandleBackfiller) Backfill(ctx context.Context, conn *pop.Connection, limit int) (int, error) {
+	var rows []webAuthnUserHandleBackfillRow
+	if err := conn.WithContext(ctx).RawQuery(
+		b.pendingQuery(conn, "ic.id, ic.nid, ic.identity_id, ic.config")+" ORDER BY ic.id LIMIT ?",
+		identity.CredentialsTypeWebAuthn, limit,
+	).All(&rows); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+
+	for _, row := range rows {
+		handles, err := webAuthnUserHandles(&identity.Credentials{
+			ID:         row.ID,
+			NID:        row.NID,
+			IdentityID: row.IdentityID,
+			Type:       identity.CredentialsTypeWebAuthn,
+			Config:     row.Config,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		for _, handle := range handles {
+			if err := conn.WithContext(ctx).Create(handle); err != nil {
+				return 0, sqlcon.HandleError(err)
+			}
+		}
+	}
+
+	return len(rows), nil
+}
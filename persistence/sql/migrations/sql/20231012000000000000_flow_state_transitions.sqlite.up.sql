@@ -0,0 +1,2 @@
+ALTER TABLE selfservice_recovery_flows ADD COLUMN state_transitions TEXT;
+ALTER TABLE selfservice_verification_flows ADD COLUMN state_transitions TEXT;
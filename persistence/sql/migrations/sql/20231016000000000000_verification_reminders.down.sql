@@ -0,0 +1,2 @@
+ALTER TABLE identity_verifiable_addresses DROP COLUMN last_reminded_at;
+ALTER TABLE identity_verifiable_addresses DROP COLUMN reminders_sent;
@@ -0,0 +1,2 @@
+ALTER TABLE identity_verifiable_addresses ADD COLUMN reminders_sent INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE identity_verifiable_addresses ADD COLUMN last_reminded_at TIMESTAMP NULL;
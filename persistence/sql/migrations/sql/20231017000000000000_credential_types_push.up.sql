@@ -0,0 +1 @@
+INSERT INTO identity_credential_types (id, name) SELECT '80dceba8-80d9-4f5e-bb38-a2c91dc3f95d', 'push' WHERE NOT EXISTS ( SELECT * FROM identity_credential_types WHERE name = 'push');
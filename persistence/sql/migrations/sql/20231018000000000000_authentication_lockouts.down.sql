@@ -0,0 +1 @@
+DROP TABLE authentication_lockouts;
@@ -0,0 +1,21 @@
+CREATE TABLE authentication_lockouts (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    nid CHAR(36) NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    lockouts INTEGER NOT NULL DEFAULT 0,
+    last_failed_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until timestamp NULL,
+
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT authentication_lockouts_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM authentication_lockouts WHERE nid = ? AND kind = ? AND subject = ?
+CREATE UNIQUE INDEX authentication_lockouts_nid_kind_subject_uq_idx ON authentication_lockouts (nid, kind, subject);
@@ -0,0 +1 @@
+DROP TABLE phased_migrations;
@@ -0,0 +1,16 @@
+CREATE TABLE phased_migrations (
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    phase VARCHAR(32) NOT NULL,
+    processed_rows BIGINT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL,
+    started_at timestamp NULL,
+    completed_at timestamp NULL,
+
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Relevant query:
+--   SELECT * FROM phased_migrations WHERE name = ?
+CREATE UNIQUE INDEX phased_migrations_name_uq_idx ON phased_migrations (name);
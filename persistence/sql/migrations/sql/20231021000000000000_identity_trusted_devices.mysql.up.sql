@@ -0,0 +1,23 @@
+CREATE TABLE identity_trusted_devices
+(
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    nid CHAR(36) NOT NULL,
+    identity_id CHAR(36) NOT NULL,
+    user_agent TEXT NOT NULL,
+    ip_address VARCHAR(64) NOT NULL,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT identity_trusted_devices_identities_id_fk
+        FOREIGN KEY (identity_id)
+        REFERENCES identities (id)
+        ON DELETE CASCADE,
+    CONSTRAINT identity_trusted_devices_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM identity_trusted_devices WHERE nid = ? AND identity_id = ? AND expires_at > ?
+CREATE INDEX identity_trusted_devices_nid_identity_id_idx ON identity_trusted_devices (nid, identity_id);
@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN required_actions VARCHAR(255) NOT NULL DEFAULT '[]';
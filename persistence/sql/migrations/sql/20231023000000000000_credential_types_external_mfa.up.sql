@@ -0,0 +1 @@
+INSERT INTO identity_credential_types (id, name) SELECT 'c1f9191b-7c77-4cfb-b288-220742ee58c7', 'external_mfa' WHERE NOT EXISTS ( SELECT * FROM identity_credential_types WHERE name = 'external_mfa');
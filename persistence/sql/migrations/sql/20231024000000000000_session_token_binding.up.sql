@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN bound_key_thumbprint VARCHAR(64) NOT NULL DEFAULT '';
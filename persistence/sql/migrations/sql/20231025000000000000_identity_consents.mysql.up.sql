@@ -0,0 +1,27 @@
+CREATE TABLE identity_consents
+(
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    nid CHAR(36) NOT NULL,
+    identity_id CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    version VARCHAR(255) NOT NULL,
+    optional BOOLEAN NOT NULL DEFAULT false,
+    ip_address VARCHAR(64) NOT NULL,
+    user_agent TEXT NOT NULL,
+    granted_at timestamp NOT NULL,
+    withdrawn_at timestamp NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT identity_consents_identities_id_fk
+        FOREIGN KEY (identity_id)
+        REFERENCES identities (id)
+        ON DELETE CASCADE,
+    CONSTRAINT identity_consents_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM identity_consents WHERE nid = ? AND identity_id = ? ORDER BY granted_at DESC
+CREATE INDEX identity_consents_nid_identity_id_idx ON identity_consents (nid, identity_id);
@@ -0,0 +1 @@
+DROP TABLE identity_email_changes;
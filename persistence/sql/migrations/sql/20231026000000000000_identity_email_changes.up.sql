@@ -0,0 +1,32 @@
+CREATE TABLE identity_email_changes
+(
+    id UUID NOT NULL PRIMARY KEY,
+    nid UUID NOT NULL,
+    identity_id UUID NOT NULL,
+    original_address VARCHAR(400) NOT NULL,
+    original_address_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    new_address VARCHAR(400) NOT NULL,
+    original_traits TEXT NOT NULL,
+    new_traits TEXT NOT NULL,
+    confirm_token VARCHAR(64) NOT NULL,
+    revert_token VARCHAR(64) NOT NULL,
+    state VARCHAR(16) NOT NULL,
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT identity_email_changes_identities_id_fk
+        FOREIGN KEY (identity_id)
+        REFERENCES identities (id)
+        ON DELETE CASCADE,
+    CONSTRAINT identity_email_changes_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant queries:
+--   SELECT * FROM identity_email_changes WHERE nid = ? AND confirm_token IN (?)
+--   SELECT * FROM identity_email_changes WHERE nid = ? AND revert_token IN (?)
+CREATE UNIQUE INDEX identity_email_changes_nid_confirm_token_uq_idx ON identity_email_changes (nid, confirm_token);
+CREATE UNIQUE INDEX identity_email_changes_nid_revert_token_uq_idx ON identity_email_changes (nid, revert_token);
+CREATE INDEX identity_email_changes_identity_id_idx ON identity_email_changes (identity_id);
@@ -0,0 +1,25 @@
+CREATE TABLE selfservice_invitations
+(
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    nid CHAR(36) NOT NULL,
+    email VARCHAR(400) NOT NULL,
+    traits TEXT NULL,
+    token VARCHAR(64) NOT NULL,
+    expires_at timestamp NOT NULL,
+    used_at timestamp NULL,
+    identity_id CHAR(36) NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT selfservice_invitations_identities_id_fk
+        FOREIGN KEY (identity_id)
+        REFERENCES identities (id)
+        ON DELETE SET NULL,
+    CONSTRAINT selfservice_invitations_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM selfservice_invitations WHERE nid = ? AND token IN (?)
+CREATE UNIQUE INDEX selfservice_invitations_nid_token_uq_idx ON selfservice_invitations (nid, token);
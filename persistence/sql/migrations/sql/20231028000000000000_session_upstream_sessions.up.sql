@@ -0,0 +1,27 @@
+CREATE TABLE session_upstream_sessions
+(
+    id UUID NOT NULL PRIMARY KEY,
+    nid UUID NOT NULL,
+    session_id UUID NOT NULL,
+    provider VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    upstream_session_id VARCHAR(255) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT session_upstream_sessions_sessions_id_fk
+        FOREIGN KEY (session_id)
+        REFERENCES sessions (id)
+        ON DELETE CASCADE,
+    CONSTRAINT session_upstream_sessions_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT session_id FROM session_upstream_sessions WHERE nid = ? AND provider = ? AND upstream_session_id = ?
+CREATE INDEX session_upstream_sessions_nid_provider_sid_idx ON session_upstream_sessions (nid, provider, upstream_session_id);
+
+-- Relevant query:
+--   SELECT session_id FROM session_upstream_sessions WHERE nid = ? AND provider = ? AND subject = ?
+CREATE INDEX session_upstream_sessions_nid_provider_subject_idx ON session_upstream_sessions (nid, provider, subject);
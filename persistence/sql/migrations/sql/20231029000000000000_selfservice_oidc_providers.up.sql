@@ -0,0 +1,17 @@
+CREATE TABLE selfservice_oidc_providers
+(
+    id UUID NOT NULL PRIMARY KEY,
+    nid UUID NOT NULL,
+    provider_id VARCHAR(255) NOT NULL,
+    config TEXT NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT selfservice_oidc_providers_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM selfservice_oidc_providers WHERE provider_id = ? AND nid = ?
+CREATE UNIQUE INDEX selfservice_oidc_providers_nid_provider_id_uq_idx ON selfservice_oidc_providers (nid, provider_id);
@@ -0,0 +1,39 @@
+CREATE TABLE organizations
+(
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    nid CHAR(36) NOT NULL,
+    label VARCHAR(255) NOT NULL,
+    allowed_methods TEXT NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT organizations_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+CREATE TABLE organization_domains
+(
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    nid CHAR(36) NOT NULL,
+    organization_id CHAR(36) NOT NULL,
+    domain VARCHAR(255) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT organization_domains_organizations_id_fk
+        FOREIGN KEY (organization_id)
+        REFERENCES organizations (id)
+        ON DELETE CASCADE,
+    CONSTRAINT organization_domains_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM organization_domains WHERE domain = ? AND nid = ?
+CREATE UNIQUE INDEX organization_domains_nid_domain_uq_idx ON organization_domains (nid, domain);
+
+-- Relevant query:
+--   SELECT * FROM organization_domains WHERE nid = ? AND organization_id IN (?)
+CREATE INDEX organization_domains_nid_organization_id_idx ON organization_domains (nid, organization_id);
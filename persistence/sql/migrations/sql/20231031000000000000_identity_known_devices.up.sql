@@ -0,0 +1,28 @@
+CREATE TABLE identity_known_devices
+(
+    id UUID NOT NULL PRIMARY KEY,
+    nid UUID NOT NULL,
+    identity_id UUID NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    ip_address VARCHAR(64) NOT NULL,
+    user_agent TEXT NOT NULL,
+    location VARCHAR(255) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT identity_known_devices_identities_id_fk
+        FOREIGN KEY (identity_id)
+        REFERENCES identities (id)
+        ON DELETE CASCADE,
+    CONSTRAINT identity_known_devices_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM identity_known_devices WHERE nid = ? AND identity_id = ? AND fingerprint = ? AND ip_address = ?
+CREATE UNIQUE INDEX identity_known_devices_nid_identity_fingerprint_ip_uq_idx ON identity_known_devices (nid, identity_id, fingerprint, ip_address);
+
+-- Relevant query:
+--   SELECT * FROM identity_known_devices WHERE nid = ? AND identity_id = ? AND ip_address = ?
+CREATE INDEX identity_known_devices_nid_identity_id_ip_idx ON identity_known_devices (nid, identity_id, ip_address);
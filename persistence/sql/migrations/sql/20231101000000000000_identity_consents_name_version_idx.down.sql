@@ -0,0 +1 @@
+DROP INDEX identity_consents_nid_name_version_id_idx;
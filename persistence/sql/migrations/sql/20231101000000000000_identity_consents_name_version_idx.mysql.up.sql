@@ -0,0 +1,3 @@
+-- Relevant query:
+--   SELECT * FROM identity_consents WHERE nid = ? AND name = ? AND version = ? AND withdrawn_at IS NULL ORDER BY id ASC
+CREATE INDEX identity_consents_nid_name_version_id_idx ON identity_consents (nid, name, version, id);
@@ -0,0 +1 @@
+DROP TABLE selfservice_signup_codes;
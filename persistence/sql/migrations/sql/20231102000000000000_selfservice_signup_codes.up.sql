@@ -0,0 +1,21 @@
+CREATE TABLE selfservice_signup_codes
+(
+    id UUID NOT NULL PRIMARY KEY,
+    nid UUID NOT NULL,
+    code VARCHAR(128) NOT NULL,
+    tenant VARCHAR(255) NOT NULL,
+    group_name VARCHAR(255) NOT NULL DEFAULT '',
+    max_uses INTEGER NOT NULL DEFAULT 0,
+    uses INTEGER NOT NULL DEFAULT 0,
+    expires_at timestamp NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT selfservice_signup_codes_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM selfservice_signup_codes WHERE code = ? AND nid = ?
+CREATE UNIQUE INDEX selfservice_signup_codes_nid_code_uq_idx ON selfservice_signup_codes (nid, code);
@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN last_activity_at timestamp NULL;
@@ -0,0 +1 @@
+DROP TABLE session_logout_callbacks;
@@ -0,0 +1,17 @@
+CREATE TABLE session_logout_callbacks
+(
+    id UUID NOT NULL PRIMARY KEY,
+    nid UUID NOT NULL,
+    app_id VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT session_logout_callbacks_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM session_logout_callbacks WHERE app_id = ? AND nid = ?
+CREATE UNIQUE INDEX session_logout_callbacks_nid_app_id_uq_idx ON session_logout_callbacks (nid, app_id);
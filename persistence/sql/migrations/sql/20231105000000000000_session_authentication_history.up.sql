@@ -0,0 +1 @@
+ALTER TABLE sessions ADD COLUMN authentication_history TEXT NULL;
@@ -0,0 +1,27 @@
+ALTER TABLE sessions ADD COLUMN token_expires_at timestamp NULL;
+
+CREATE TABLE session_refresh_tokens
+(
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    nid CHAR(36) NOT NULL,
+    session_id CHAR(36) NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    used_at timestamp NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT session_refresh_tokens_sessions_id_fk
+        FOREIGN KEY (session_id)
+        REFERENCES sessions (id)
+        ON DELETE CASCADE,
+    CONSTRAINT session_refresh_tokens_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM session_refresh_tokens WHERE nid = ? AND token IN (?)
+CREATE UNIQUE INDEX session_refresh_tokens_nid_token_uq_idx ON session_refresh_tokens (nid, token);
+-- Relevant query:
+--   DELETE FROM session_refresh_tokens WHERE session_id = ? AND nid = ?
+CREATE INDEX session_refresh_tokens_session_id_nid_idx ON session_refresh_tokens (session_id, nid);
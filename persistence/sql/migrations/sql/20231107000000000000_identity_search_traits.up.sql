@@ -0,0 +1,25 @@
+CREATE TABLE identity_search_traits
+(
+    id UUID NOT NULL PRIMARY KEY,
+    nid UUID NOT NULL,
+    identity_id UUID NOT NULL,
+    trait VARCHAR(255) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT identity_search_traits_identities_id_fk
+        FOREIGN KEY (identity_id)
+        REFERENCES identities (id)
+        ON DELETE CASCADE,
+    CONSTRAINT identity_search_traits_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT identity_id FROM identity_search_traits WHERE nid = ? AND trait = ? AND value LIKE ?
+CREATE INDEX identity_search_traits_nid_trait_value_idx ON identity_search_traits (nid, trait, value);
+-- Relevant query:
+--   DELETE FROM identity_search_traits WHERE identity_id = ? AND nid = ?
+CREATE INDEX identity_search_traits_identity_id_nid_idx ON identity_search_traits (identity_id, nid);
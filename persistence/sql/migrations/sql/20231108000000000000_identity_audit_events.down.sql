@@ -0,0 +1 @@
+DROP TABLE identity_audit_events;
@@ -0,0 +1,23 @@
+CREATE TABLE identity_audit_events
+(
+    id UUID NOT NULL PRIMARY KEY,
+    nid UUID NOT NULL,
+    -- The identity is not a foreign key, because audit events are kept when the identity is deleted.
+    identity_id UUID NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    actor_type VARCHAR(32) NOT NULL,
+    actor_id VARCHAR(255) NOT NULL,
+    changes TEXT NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT identity_audit_events_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM identity_audit_events WHERE nid = ? AND identity_id = ? ORDER BY created_at DESC, id DESC
+CREATE INDEX identity_audit_events_nid_identity_id_created_at_idx ON identity_audit_events (nid, identity_id, created_at);
+-- Relevant query:
+--   SELECT * FROM identity_audit_events WHERE nid = ? AND created_at >= ? ORDER BY created_at DESC, id DESC
+CREATE INDEX identity_audit_events_nid_created_at_idx ON identity_audit_events (nid, created_at);
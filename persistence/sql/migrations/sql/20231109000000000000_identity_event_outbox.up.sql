@@ -0,0 +1,22 @@
+CREATE TABLE identity_event_outbox
+(
+    id UUID NOT NULL PRIMARY KEY,
+    nid UUID NOT NULL,
+    -- The identity is not a foreign key, because the event of a deleted identity must still be delivered.
+    identity_id UUID NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    data TEXT NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at timestamp NULL,
+    last_error TEXT NOT NULL,
+    CONSTRAINT identity_event_outbox_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM identity_event_outbox WHERE nid = ? AND delivered_at IS NULL AND attempts < ? AND next_attempt_at <= ? ORDER BY next_attempt_at ASC, created_at ASC
+CREATE INDEX identity_event_outbox_nid_delivered_at_next_attempt_at_idx ON identity_event_outbox (nid, delivered_at, next_attempt_at);
@@ -0,0 +1,21 @@
+CREATE TABLE session_dpop_proofs
+(
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    nid CHAR(36) NOT NULL,
+    key_thumbprint VARCHAR(64) NOT NULL,
+    jti VARCHAR(255) NOT NULL,
+    expires_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT session_dpop_proofs_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Makes sure a proof is accepted only once.
+CREATE UNIQUE INDEX session_dpop_proofs_nid_key_thumbprint_jti_uq_idx ON session_dpop_proofs (nid, key_thumbprint, jti);
+
+-- Relevant query:
+--   DELETE FROM session_dpop_proofs WHERE expires_at <= ? AND nid = ?
+CREATE INDEX session_dpop_proofs_nid_expires_at_idx ON session_dpop_proofs (nid, expires_at);
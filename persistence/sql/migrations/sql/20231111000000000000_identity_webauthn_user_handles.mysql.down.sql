@@ -0,0 +1 @@
+DROP TABLE identity_webauthn_user_handles;
@@ -0,0 +1,29 @@
+CREATE TABLE identity_webauthn_user_handles
+(
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    nid CHAR(36) NOT NULL,
+    identity_id CHAR(36) NOT NULL,
+    identity_credential_id CHAR(36) NOT NULL,
+    user_handle VARCHAR(255) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT identity_webauthn_user_handles_identities_id_fk
+        FOREIGN KEY (identity_id)
+        REFERENCES identities (id)
+        ON DELETE CASCADE,
+    CONSTRAINT identity_webauthn_user_handles_identity_credentials_id_fk
+        FOREIGN KEY (identity_credential_id)
+        REFERENCES identity_credentials (id)
+        ON DELETE CASCADE,
+    CONSTRAINT identity_webauthn_user_handles_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Relevant query:
+--   SELECT * FROM identity_webauthn_user_handles WHERE user_handle = ? AND nid = ?
+CREATE UNIQUE INDEX identity_webauthn_user_handles_nid_user_handle_uq_idx ON identity_webauthn_user_handles (nid, user_handle);
+-- Relevant query:
+--   DELETE FROM identity_webauthn_user_handles WHERE identity_credential_id = ? AND nid = ?
+CREATE INDEX identity_webauthn_user_handles_identity_credential_id_nid_idx ON identity_webauthn_user_handles (identity_credential_id, nid);
@@ -0,0 +1 @@
+DROP TABLE session_upstream_logout_tokens;
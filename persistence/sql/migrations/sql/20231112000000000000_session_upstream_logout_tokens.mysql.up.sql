@@ -0,0 +1,21 @@
+CREATE TABLE session_upstream_logout_tokens
+(
+    id CHAR(36) NOT NULL PRIMARY KEY,
+    nid CHAR(36) NOT NULL,
+    provider VARCHAR(255) NOT NULL,
+    jti VARCHAR(255) NOT NULL,
+    expires_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT session_upstream_logout_tokens_networks_id_fk
+        FOREIGN KEY (nid)
+        REFERENCES networks (id)
+        ON UPDATE RESTRICT ON DELETE CASCADE
+);
+
+-- Makes sure a logout token is accepted only once.
+CREATE UNIQUE INDEX session_upstream_logout_tokens_nid_provider_jti_uq_idx ON session_upstream_logout_tokens (nid, provider, jti);
+
+-- Relevant query:
+--   DELETE FROM session_upstream_logout_tokens WHERE expires_at <= ? AND nid = ?
+CREATE INDEX session_upstream_logout_tokens_nid_expires_at_idx ON session_upstream_logout_tokens (nid, expires_at);
@@ -7,7 +7,6 @@ import (
 	"context"
 	"embed"
 	"io/fs"
-	"time"
 
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
@@ -42,6 +41,8 @@ type (
 		x.TracingProvider
 		schema.IdentityTraitsProvider
 		identity.ValidationProvider
+		session.EventEmitterProvider
+		session.CacheProvider
 	}
 	Persister struct {
 		nid uuid.UUID
@@ -51,6 +52,8 @@ type (
 		r   persisterDependencies
 		p   *networkx.Manager
 
+		backfillers []persistence.Backfiller
+
 		identity.PrivilegedPool
 		session.DevicePersister
 	}
@@ -58,6 +61,7 @@ type (
 
 type persisterOptions struct {
 	extraMigrations []fs.FS
+	backfillers     []persistence.Backfiller
 	disableLogging  bool
 }
 
@@ -69,6 +73,13 @@ func WithExtraMigrations(fss ...fs.FS) persisterOption {
 	}
 }
 
+// WithBackfillers registers the backfillers of additional phased migrations.
+func WithBackfillers(bs ...persistence.Backfiller) persisterOption {
+	return func(o *persisterOptions) {
+		o.backfillers = bs
+	}
+}
+
 func WithDisabledLogging(v bool) persisterOption {
 	return func(o *persisterOptions) {
 		o.disableLogging = v
@@ -100,6 +111,11 @@ func NewPersister(ctx context.Context, r persisterDependencies, c *pop.Connectio
 		return nil, err
 	}
 
+	bs := make([]persistence.Backfiller, 0, len(backfillers)+len(o.backfillers))
+	for _, newBackfiller := range backfillers {
+		bs = append(bs, newBackfiller(r))
+	}
+
 	m.DumpMigrations = false
 	return &Persister{
 		c:               c,
@@ -108,6 +124,7 @@ func NewPersister(ctx context.Context, r persisterDependencies, c *pop.Connectio
 		PrivilegedPool:  idpersistence.NewPersister(r, c),
 		DevicePersister: devices.NewPersister(r, c),
 		p:               networkx.NewManager(c, r.Logger(), r.Tracer(ctx)),
+		backfillers:     append(bs, o.backfillers...),
 	}, nil
 }
 
@@ -186,60 +203,3 @@ func (p *Persister) Ping() error {
 	// This can not be contextualized because of some gobuffalo/pop limitations.
 	return errors.WithStack(p.c.Store.(pinger).Ping())
 }
-
-func (p *Persister) CleanupDatabase(ctx context.Context, wait time.Duration, older time.Duration, batchSize int) error {
-	currentTime := time.Now().Add(-older)
-	p.r.Logger().Printf("Cleaning up records older than %s\n", currentTime)
-
-	p.r.Logger().Println("Cleaning up expired sessions")
-	if err := p.DeleteExpiredSessions(ctx, currentTime, batchSize); err != nil {
-		return err
-	}
-	time.Sleep(wait)
-
-	p.r.Logger().Println("Cleaning up expired continuity containers")
-	if err := p.DeleteExpiredContinuitySessions(ctx, currentTime, batchSize); err != nil {
-		return err
-	}
-	time.Sleep(wait)
-
-	p.r.Logger().Println("Cleaning up expired login flows")
-	if err := p.DeleteExpiredLoginFlows(ctx, currentTime, batchSize); err != nil {
-		return err
-	}
-	time.Sleep(wait)
-
-	p.r.Logger().Println("Cleaning up expired recovery flows")
-	if err := p.DeleteExpiredRecoveryFlows(ctx, currentTime, batchSize); err != nil {
-		return err
-	}
-	time.Sleep(wait)
-
-	p.r.Logger().Println("Cleaning up expired registation flows")
-	if err := p.DeleteExpiredRegistrationFlows(ctx, currentTime, batchSize); err != nil {
-		return err
-	}
-	time.Sleep(wait)
-
-	p.r.Logger().Println("Cleaning up expired settings flows")
-	if err := p.DeleteExpiredSettingsFlows(ctx, currentTime, batchSize); err != nil {
-		return err
-	}
-	time.Sleep(wait)
-
-	p.r.Logger().Println("Cleaning up expired verification flows")
-	if err := p.DeleteExpiredVerificationFlows(ctx, currentTime, batchSize); err != nil {
-		return err
-	}
-	time.Sleep(wait)
-
-	p.r.Logger().Println("Cleaning up expired session token exchangers")
-	if err := p.DeleteExpiredExchangers(ctx, currentTime, batchSize); err != nil {
-		return err
-	}
-	time.Sleep(wait)
-
-	p.r.Logger().Println("Successfully cleaned up the latest batch of the SQL database! " +
-		"This should be re-run periodically, to be sure that all expired data is purged.")
-	return nil
-}
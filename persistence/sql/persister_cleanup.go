@@ -0,0 +1,201 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/persistence"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/recovery"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+func (p *Persister) CleanupDatabase(ctx context.Context, wait time.Duration, older time.Duration, batchSize int, opts ...persistence.CleanupOption) error {
+	o := persistence.NewCleanupOptions(opts...)
+	currentTime := x.Now().Add(-older)
+	p.r.Logger().Printf("Cleaning up records older than %s\n", currentTime)
+
+	p.r.Logger().Println("Cleaning up expired sessions")
+	if o.OnExpiredSessions == nil {
+		rows, err := p.deleteExpiredRows(ctx, new(session.Session).TableName(ctx), currentTime, batchSize)
+		if err != nil {
+			return err
+		}
+		o.ReportDeletedRows(ctx, new(session.Session).TableName(ctx), rows)
+	} else if ids, err := p.ListExpiredSessionIDs(ctx, currentTime, batchSize); err != nil {
+		return err
+	} else if len(ids) > 0 {
+		// The sessions are only deleted once they were reported, so that a failed notification is
+		// retried by the next cleanup instead of being lost.
+		if err := o.OnExpiredSessions(ctx, ids); err != nil {
+			p.r.Logger().WithError(err).Warn("Unable to report expired sessions, they will be deleted by the next cleanup.")
+		} else if err := p.DeleteExpiredSessionsByID(ctx, currentTime, ids); err != nil {
+			return err
+		} else {
+			o.ReportDeletedRows(ctx, new(session.Session).TableName(ctx), len(ids))
+		}
+	}
+	time.Sleep(wait)
+
+	for _, t := range []struct{ description, table string }{
+		{"continuity containers", new(continuity.Container).TableName(ctx)},
+		{"login flows", new(login.Flow).TableName(ctx)},
+		{"recovery flows", new(recovery.Flow).TableName(ctx)},
+		{"registration flows", new(registration.Flow).TableName(ctx)},
+		{"settings flows", new(settings.Flow).TableName(ctx)},
+		{"verification flows", new(verification.Flow).TableName(ctx)},
+		{"email changes", new(settings.EmailChange).TableName(ctx)},
+		{"DPoP proofs", new(session.DPoPProof).TableName(ctx)},
+		{"upstream logout tokens", new(session.UpstreamLogoutToken).TableName(ctx)},
+	} {
+		p.r.Logger().Printf("Cleaning up expired %s\n", t.description)
+		rows, err := p.deleteExpiredRows(ctx, t.table, currentTime, batchSize)
+		if err != nil {
+			return err
+		}
+		o.ReportDeletedRows(ctx, t.table, rows)
+		time.Sleep(wait)
+	}
+
+	// Codes are removed together with their flows, but are purged on their own as well
+	// because they usually expire or are used long before the flow expires.
+	for _, t := range []struct{ description, table string }{
+		{"login codes", new(code.LoginCode).TableName(ctx)},
+		{"recovery codes", new(code.RecoveryCode).TableName(ctx)},
+		{"registration codes", new(code.RegistrationCode).TableName(ctx)},
+		{"verification codes", new(code.VerificationCode).TableName(ctx)},
+	} {
+		p.r.Logger().Printf("Cleaning up expired and used %s\n", t.description)
+		expired, err := p.deleteExpiredRows(ctx, t.table, currentTime, batchSize)
+		if err != nil {
+			return err
+		}
+		used, err := p.deleteRowsBefore(ctx, t.table, "used_at", currentTime, batchSize)
+		if err != nil {
+			return err
+		}
+		o.ReportDeletedRows(ctx, t.table, expired+used)
+		time.Sleep(wait)
+	}
+
+	p.r.Logger().Println("Cleaning up expired session token exchangers")
+	if err := p.DeleteExpiredExchangers(ctx, currentTime, batchSize); err != nil {
+		return err
+	}
+	time.Sleep(wait)
+
+	p.r.Logger().Println("Cleaning up expired authentication lockouts")
+	if err := p.DeleteExpiredLockouts(ctx, currentTime, batchSize); err != nil {
+		return err
+	}
+	time.Sleep(wait)
+
+	p.r.Logger().Println("Cleaning up expired trusted devices")
+	rows, err := p.deleteExpiredRows(ctx, new(session.TrustedDevice).TableName(ctx), currentTime, batchSize)
+	if err != nil {
+		return err
+	}
+	o.ReportDeletedRows(ctx, new(session.TrustedDevice).TableName(ctx), rows)
+	time.Sleep(wait)
+
+	p.r.Logger().Println("Cleaning up soft-deleted identities")
+	rows, err = p.deleteSoftDeletedIdentities(ctx, x.Now().Add(-p.r.Config().IdentitySoftDeleteRetention(ctx)), batchSize)
+	if err != nil {
+		return err
+	}
+	o.ReportDeletedRows(ctx, new(identity.Identity).TableName(ctx), rows)
+	time.Sleep(wait)
+
+	p.r.Logger().Println("Cleaning up delivered identity lifecycle events")
+	rows, err = p.deleteDeliveredLifecycleEvents(ctx, currentTime, batchSize)
+	if err != nil {
+		return err
+	}
+	o.ReportDeletedRows(ctx, new(identity.LifecycleEvent).TableName(ctx), rows)
+	time.Sleep(wait)
+
+	p.r.Logger().Println("Successfully cleaned up the latest batch of the SQL database! " +
+		"This should be re-run periodically, to be sure that all expired data is purged.")
+	return nil
+}
+
+// deleteExpiredRows removes up to limit rows of the current network from table which expired
+// before the given time and returns the number of deleted rows.
+func (p *Persister) deleteExpiredRows(ctx context.Context, table string, expiresAt time.Time, limit int) (_ int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.deleteExpiredRows")
+	defer otelx.End(span, &err)
+
+	return p.deleteRowsBefore(ctx, table, "expires_at", expiresAt, limit)
+}
+
+// deleteRowsBefore removes up to limit rows of the current network from table whose column is
+// before the given time, oldest first, and returns the number of deleted rows. Rows where the
+// column is NULL are kept.
+func (p *Persister) deleteRowsBefore(ctx context.Context, table, column string, before time.Time, limit int) (int, error) {
+	//#nosec G201 -- table and column are always static
+	rows, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE id in (SELECT id FROM (SELECT id FROM %s c WHERE %s <= ? and nid = ? ORDER BY %s ASC LIMIT %d ) AS s )",
+		table,
+		table,
+		column,
+		column,
+		limit,
+	),
+		before,
+		p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return rows, nil
+}
+
+// deleteSoftDeletedIdentities purges up to limit identities of the current network which were
+// soft-deleted before the given time, together with their credentials, addresses, and sessions.
+// The identities are deleted one by one so that every deletion is recorded in the audit trail.
+func (p *Persister) deleteSoftDeletedIdentities(ctx context.Context, deletedAt time.Time, limit int) (_ int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.deleteSoftDeletedIdentities")
+	defer otelx.End(span, &err)
+
+	var deleted []identity.Identity
+	if err := p.GetConnection(ctx).
+		Select("id").
+		Where("state = ? AND state_changed_at <= ? AND nid = ?", identity.StateSoftDeleted, deletedAt, p.NetworkID(ctx)).
+		Order("state_changed_at ASC").
+		Limit(limit).
+		All(&deleted); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+
+	ctx = identity.WithAuditActor(ctx, identity.AuditActor{Type: identity.AuditActorSystem, ID: "cleanup"})
+	for _, i := range deleted {
+		if err := p.DeleteIdentity(ctx, i.ID); err != nil && !errors.Is(err, sqlcon.ErrNoRows) {
+			return 0, err
+		}
+	}
+	return len(deleted), nil
+}
+
+// deleteDeliveredLifecycleEvents removes up to limit lifecycle events of the current network from the
+// outbox which were delivered before the given time. Undelivered events are kept.
+func (p *Persister) deleteDeliveredLifecycleEvents(ctx context.Context, deliveredAt time.Time, limit int) (_ int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.deleteDeliveredLifecycleEvents")
+	defer otelx.End(span, &err)
+
+	return p.deleteRowsBefore(ctx, new(identity.LifecycleEvent).TableName(ctx), "delivered_at", deliveredAt, limit)
+}
@@ -5,16 +5,33 @@ package sql_test
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/url"
 	"testing"
 	"time"
 
+	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/persistence"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/session"
+	"github.com/ory/x/pointerx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
 )
 
 func TestPersister_Cleanup(t *testing.T) {
-	_, reg := internal.NewFastRegistryWithMocks(t)
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/identity.schema.json")
 	p := reg.Persister()
 	ctx := context.Background()
 
@@ -22,6 +39,91 @@ func TestPersister_Cleanup(t *testing.T) {
 		assert.Nil(t, p.CleanupDatabase(ctx, 0, 0, reg.Config().DatabaseCleanupBatchSize(ctx)))
 	})
 
+	t.Run("case=should report the deleted rows", func(t *testing.T) {
+		expired, err := login.NewFlow(reg.Config(), -time.Minute, "csrf", &http.Request{URL: &url.URL{Path: "/"}, Host: "ory.sh"}, flow.TypeBrowser)
+		require.NoError(t, err)
+		require.NoError(t, p.CreateLoginFlow(ctx, expired))
+
+		active, err := login.NewFlow(reg.Config(), time.Hour, "csrf", &http.Request{URL: &url.URL{Path: "/"}, Host: "ory.sh"}, flow.TypeBrowser)
+		require.NoError(t, err)
+		require.NoError(t, p.CreateLoginFlow(ctx, active))
+
+		deleted := map[string]int{}
+		require.NoError(t, p.CleanupDatabase(ctx, 0, 0, reg.Config().DatabaseCleanupBatchSize(ctx), persistence.WithDeletedRowsCallback(func(_ context.Context, table string, rows int) {
+			deleted[table] += rows
+		})))
+		assert.Equal(t, 1, deleted[new(login.Flow).TableName(ctx)])
+		assert.Contains(t, deleted, new(code.RecoveryCode).TableName(ctx))
+
+		_, err = p.GetLoginFlow(ctx, expired.ID)
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+		_, err = p.GetLoginFlow(ctx, active.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("case=should purge used codes", func(t *testing.T) {
+		i := identity.NewIdentity("")
+		require.NoError(t, p.CreateIdentity(ctx, i))
+
+		f, err := login.NewFlow(reg.Config(), time.Hour, "csrf", &http.Request{URL: &url.URL{Path: "/"}, Host: "ory.sh"}, flow.TypeBrowser)
+		require.NoError(t, err)
+		require.NoError(t, p.CreateLoginFlow(ctx, f))
+
+		create := func(rawCode string) *code.LoginCode {
+			c, err := p.CreateLoginCode(ctx, &code.CreateLoginCodeParams{
+				Address:     "cleanup-used-codes@ory.sh",
+				AddressType: identity.CodeAddressTypeEmail,
+				RawCode:     rawCode,
+				FlowID:      f.ID,
+				IdentityID:  i.ID,
+			})
+			require.NoError(t, err)
+			return c
+		}
+		create("111111")
+		unused := create("222222")
+		used, err := p.UseLoginCode(ctx, f.ID, i.ID, "111111", nil)
+		require.NoError(t, err)
+
+		deleted := map[string]int{}
+		require.NoError(t, p.CleanupDatabase(ctx, 0, 0, reg.Config().DatabaseCleanupBatchSize(ctx), persistence.WithDeletedRowsCallback(func(_ context.Context, table string, rows int) {
+			deleted[table] += rows
+		})))
+		assert.Equal(t, 1, deleted[new(code.LoginCode).TableName(ctx)])
+
+		var remaining []code.LoginCode
+		require.NoError(t, p.GetConnection(ctx).Where("selfservice_login_flow_id = ?", f.ID).All(&remaining))
+		require.Len(t, remaining, 1)
+		assert.Equal(t, unused.ID, remaining[0].ID)
+		assert.NotEqual(t, used.ID, remaining[0].ID)
+	})
+
+	t.Run("case=should purge soft-deleted identities after the retention period", func(t *testing.T) {
+		reg.Config().MustSet(ctx, config.ViperKeyIdentitySoftDeleteRetention, "1h")
+		t.Cleanup(func() { reg.Config().MustSet(ctx, config.ViperKeyIdentitySoftDeleteRetention, nil) })
+
+		create := func(state identity.State, changedAt time.Time) *identity.Identity {
+			i := identity.NewIdentity("")
+			require.NoError(t, p.CreateIdentity(ctx, i))
+			i.State = state
+			i.StateChangedAt = pointerx.Ptr(sqlxx.NullTime(changedAt))
+			require.NoError(t, p.UpdateIdentity(ctx, i))
+			return i
+		}
+		expired := create(identity.StateSoftDeleted, time.Now().Add(-2*time.Hour))
+		retained := create(identity.StateSoftDeleted, time.Now().Add(-time.Minute))
+		deactivated := create(identity.StateDeactivated, time.Now().Add(-2*time.Hour))
+
+		require.NoError(t, p.CleanupDatabase(ctx, 0, 0, reg.Config().DatabaseCleanupBatchSize(ctx)))
+
+		_, err := p.GetIdentity(ctx, expired.ID, identity.ExpandNothing)
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+		for _, i := range []*identity.Identity{retained, deactivated} {
+			_, err = p.GetIdentity(ctx, i.ID, identity.ExpandNothing)
+			assert.NoError(t, err)
+		}
+	})
+
 	t.Run("case=should throw error on cleanup", func(t *testing.T) {
 		p.GetConnection(ctx).Close()
 		assert.Error(t, p.CleanupDatabase(ctx, 0, 0, reg.Config().DatabaseCleanupBatchSize(ctx)))
@@ -93,7 +195,8 @@ func TestPersister_Registration_Cleanup(t *testing.T) {
 }
 
 func TestPersister_Session_Cleanup(t *testing.T) {
-	_, reg := internal.NewFastRegistryWithMocks(t)
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/identity.schema.json")
 	p := reg.Persister()
 	currentTime := time.Now()
 	ctx := context.Background()
@@ -102,9 +205,52 @@ func TestPersister_Session_Cleanup(t *testing.T) {
 		assert.Nil(t, p.DeleteExpiredSessions(ctx, currentTime, reg.Config().DatabaseCleanupBatchSize(ctx)))
 	})
 
+	t.Run("case=should report expired sessions before deleting them", func(t *testing.T) {
+		i := identity.NewIdentity("")
+		require.NoError(t, p.CreateIdentity(ctx, i))
+
+		expired, err := session.NewActiveSession(&http.Request{Header: http.Header{}}, i, reg.Config(), currentTime.Add(-time.Hour), identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1)
+		require.NoError(t, err)
+		expired.ExpiresAt = currentTime.Add(-time.Minute)
+		require.NoError(t, p.UpsertSession(ctx, expired))
+
+		active, err := session.NewActiveSession(&http.Request{Header: http.Header{}}, i, reg.Config(), currentTime, identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1)
+		require.NoError(t, err)
+		active.ExpiresAt = currentTime.Add(time.Hour)
+		require.NoError(t, p.UpsertSession(ctx, active))
+
+		var notified []uuid.UUID
+		require.NoError(t, p.CleanupDatabase(ctx, 0, 0, reg.Config().DatabaseCleanupBatchSize(ctx), persistence.WithExpiredSessionsCallback(func(_ context.Context, ids []uuid.UUID) error {
+			notified = append(notified, ids...)
+			return errors.New("notifier is unavailable")
+		})), "a failing notifier does not abort the cleanup")
+		assert.Equal(t, []uuid.UUID{expired.ID}, notified)
+
+		_, err = p.GetSession(ctx, expired.ID, session.ExpandNothing)
+		require.NoError(t, err, "the session is kept until the notification succeeded")
+
+		notified = nil
+		require.NoError(t, p.CleanupDatabase(ctx, 0, 0, reg.Config().DatabaseCleanupBatchSize(ctx), persistence.WithExpiredSessionsCallback(func(_ context.Context, ids []uuid.UUID) error {
+			notified = append(notified, ids...)
+			return nil
+		})))
+		assert.Equal(t, []uuid.UUID{expired.ID}, notified)
+
+		_, err = p.GetSession(ctx, expired.ID, session.ExpandNothing)
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+		_, err = p.GetSession(ctx, active.ID, session.ExpandNothing)
+		assert.NoError(t, err)
+
+		ids, err := p.ListExpiredSessionIDs(ctx, currentTime, reg.Config().DatabaseCleanupBatchSize(ctx))
+		require.NoError(t, err)
+		assert.Empty(t, ids)
+	})
+
 	t.Run("case=should throw error on cleanup sessions", func(t *testing.T) {
 		p.GetConnection(ctx).Close()
 		assert.Error(t, p.DeleteExpiredSessions(ctx, currentTime, reg.Config().DatabaseCleanupBatchSize(ctx)))
+		_, err := p.ListExpiredSessionIDs(ctx, currentTime, reg.Config().DatabaseCleanupBatchSize(ctx))
+		assert.Error(t, err)
 	})
 }
 
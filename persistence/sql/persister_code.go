@@ -36,10 +36,18 @@ func withCheckIdentityID(id uuid.UUID) codeOption {
 	}
 }
 
+// validateCode calls the validator if it is set.
+func validateCode(ctx context.Context, validate code.CodeValidator, req *code.CodeRequest) error {
+	if validate == nil {
+		return nil
+	}
+	return validate(ctx, req)
+}
+
 func useOneTimeCode[P any, U interface {
 	*P
 	oneTimeCodeProvider
-}](ctx context.Context, p *Persister, flowID uuid.UUID, userProvidedCode string, flowTableName string, foreignKeyName string, opts ...codeOption) (U, error) {
+}](ctx context.Context, p *Persister, flowID uuid.UUID, userProvidedCode string, flowTableName string, foreignKeyName string, check func(ctx context.Context, tx *pop.Connection, target U) error, opts ...codeOption) (U, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.useOneTimeCode")
 	defer span.End()
 
@@ -48,7 +56,10 @@ func useOneTimeCode[P any, U interface {
 		opt(o)
 	}
 
-	var target U
+	var (
+		target   U
+		rejected bool
+	)
 	nid := p.NetworkID(ctx)
 	if err := p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
 		//#nosec G201 -- TableName is static
@@ -68,10 +79,10 @@ func useOneTimeCode[P any, U interface {
 		}
 
 		// This check prevents parallel brute force attacks by checking the submit count inside this database
-		// transaction. If the flow has been submitted more than 5 times, the transaction is aborted (regardless of
+		// transaction. If the flow has been submitted more often than allowed, the transaction is aborted (regardless of
 		// whether the code was correct or not) and we thus give no indication whether the supplied code was correct or
 		// not. For more explanation see [this comment](https://github.com/ory/kratos/pull/2645#discussion_r984732899).
-		if submitCount > 5 {
+		if submitCount > p.r.Config().SelfServiceCodeMethodMaxSubmissions(ctx) {
 			return errors.WithStack(code.ErrCodeSubmittedTooOften)
 		}
 
@@ -109,12 +120,25 @@ func useOneTimeCode[P any, U interface {
 			return nil
 		}
 
+		// The code is only marked as used once the check accepted it.
+		if err := check(ctx, tx, target); errors.Is(err, code.ErrCodeNotFound) {
+			// Return no error, as that would roll back the transaction and reset the submit count.
+			rejected = true
+			return nil
+		} else if err != nil {
+			return err
+		}
+
 		//#nosec G201 -- TableName is static
 		return tx.RawQuery(fmt.Sprintf("UPDATE %s SET used_at = ? WHERE id = ? AND nid = ?", target.TableName(ctx)), time.Now().UTC(), target.GetID(), nid).Exec()
 	}); err != nil {
 		return nil, sqlcon.HandleError(err)
 	}
 
+	if rejected {
+		return nil, errors.WithStack(code.ErrCodeNotFound)
+	}
+
 	if err := target.Validate(); err != nil {
 		return nil, err
 	}
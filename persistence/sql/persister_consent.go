@@ -0,0 +1,115 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/selfservice/consent"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ consent.Persister = new(Persister)
+
+func (p *Persister) CreateConsentRecords(ctx context.Context, records ...*consent.Record) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateConsentRecords")
+	defer otelx.End(span, &err)
+
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		for _, r := range records {
+			r.NID = p.NetworkID(ctx)
+			if err := tx.Create(r); err != nil {
+				return sqlcon.HandleError(err)
+			}
+		}
+		return nil
+	})
+}
+
+func (p *Persister) GetConsentRecord(ctx context.Context, identityID, id uuid.UUID) (_ *consent.Record, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetConsentRecord")
+	defer otelx.End(span, &err)
+
+	var r consent.Record
+	if err := p.GetConnection(ctx).Where("id = ? AND identity_id = ? AND nid = ?", id, identityID, p.NetworkID(ctx)).First(&r); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &r, nil
+}
+
+func (p *Persister) ListConsentRecords(ctx context.Context, identityID uuid.UUID) (_ []consent.Record, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListConsentRecords")
+	defer otelx.End(span, &err)
+
+	records := make([]consent.Record, 0)
+	if err := p.GetConnection(ctx).
+		Where("nid = ? AND identity_id = ?", p.NetworkID(ctx), identityID).
+		Order("granted_at DESC").
+		All(&records); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return records, nil
+}
+
+func (p *Persister) ListAllConsentRecords(ctx context.Context, params consent.ListRecordsParameters) (_ []consent.Record, _ *keysetpagination.Paginator, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListAllConsentRecords")
+	defer otelx.End(span, &err)
+
+	paginator := keysetpagination.GetPaginator(append(
+		params.KeySetPagination,
+		keysetpagination.WithDefaultToken(consent.DefaultPageToken()),
+		keysetpagination.WithDefaultSize(250),
+		keysetpagination.WithColumn("id", "ASC"))...)
+
+	q := p.GetConnection(ctx).Where("nid = ?", p.NetworkID(ctx))
+	if params.Name != "" {
+		q = q.Where("name = ?", params.Name)
+	}
+	if params.Version != "" {
+		q = q.Where("version = ?", params.Version)
+	}
+	if !params.IncludeWithdrawn {
+		q = q.Where("withdrawn_at IS NULL")
+	}
+
+	records := make([]consent.Record, 0, paginator.Size())
+	if err := q.Scope(keysetpagination.Paginate[consent.Record](paginator)).All(&records); err != nil {
+		return nil, nil, sqlcon.HandleError(err)
+	}
+
+	records, nextPage := keysetpagination.Result(records, paginator)
+	return records, nextPage, nil
+}
+
+func (p *Persister) WithdrawConsentRecord(ctx context.Context, identityID, id uuid.UUID, at time.Time) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.WithdrawConsentRecord")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET withdrawn_at = ?, updated_at = ? WHERE id = ? AND identity_id = ? AND nid = ?",
+		new(consent.Record).TableName(ctx),
+	),
+		at,
+		time.Now().UTC(),
+		id,
+		identityID,
+		p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
@@ -54,18 +54,6 @@ func (p *Persister) DeleteContinuitySession(ctx context.Context, id uuid.UUID) e
 }
 
 func (p *Persister) DeleteExpiredContinuitySessions(ctx context.Context, expiresAt time.Time, limit int) error {
-	//#nosec G201 -- TableName is static
-	err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
-		"DELETE FROM %s WHERE id in (SELECT id FROM (SELECT id FROM %s c WHERE expires_at <= ? and nid = ? ORDER BY expires_at ASC LIMIT %d ) AS s )",
-		new(continuity.Container).TableName(ctx),
-		new(continuity.Container).TableName(ctx),
-		limit,
-	),
-		expiresAt,
-		p.NetworkID(ctx),
-	).Exec()
-	if err != nil {
-		return sqlcon.HandleError(err)
-	}
-	return nil
+	_, err := p.deleteExpiredRows(ctx, new(continuity.Container).TableName(ctx), expiresAt, limit)
+	return err
 }
@@ -0,0 +1,73 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+
+	"github.com/ory/kratos/persistence/sql/update"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ settings.EmailChangePersister = new(Persister)
+
+func (p *Persister) CreateEmailChange(ctx context.Context, c *settings.EmailChange) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateEmailChange")
+	defer otelx.End(span, &err)
+
+	confirm, revert := c.ConfirmToken, c.RevertToken
+	c.ConfirmToken = p.hmacValue(ctx, confirm)
+	c.RevertToken = p.hmacValue(ctx, revert)
+	c.NID = p.NetworkID(ctx)
+	defer func() {
+		c.ConfirmToken, c.RevertToken = confirm, revert
+	}()
+
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(c))
+}
+
+func (p *Persister) GetEmailChangeByConfirmToken(ctx context.Context, token string) (_ *settings.EmailChange, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetEmailChangeByConfirmToken")
+	defer otelx.End(span, &err)
+
+	return p.getEmailChangeByToken(ctx, "confirm_token", token)
+}
+
+func (p *Persister) GetEmailChangeByRevertToken(ctx context.Context, token string) (_ *settings.EmailChange, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetEmailChangeByRevertToken")
+	defer otelx.End(span, &err)
+
+	return p.getEmailChangeByToken(ctx, "revert_token", token)
+}
+
+func (p *Persister) UpdateEmailChange(ctx context.Context, c *settings.EmailChange) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateEmailChange")
+	defer otelx.End(span, &err)
+
+	cp := *c
+	cp.NID = p.NetworkID(ctx)
+	// Only the state changes, the tokens on the change may not be digests.
+	return update.Generic(ctx, p.GetConnection(ctx), p.r.Tracer(ctx).Tracer(), cp, "state")
+}
+
+// getEmailChangeByToken looks up the change using the digests of the token for all session
+// secrets, so that links sent before a secret rotation keep working.
+func (p *Persister) getEmailChangeByToken(ctx context.Context, column, token string) (*settings.EmailChange, error) {
+	secrets := p.r.Config().SecretsSession(ctx)
+	digests := make([]interface{}, 0, len(secrets))
+	for _, secret := range secrets {
+		digests = append(digests, p.hmacValueWithSecret(ctx, token, secret))
+	}
+
+	var c settings.EmailChange
+	if err := p.GetConnection(ctx).
+		Where("nid = ?", p.NetworkID(ctx)).
+		Where(column+" IN (?)", digests...).
+		First(&c); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &c, nil
+}
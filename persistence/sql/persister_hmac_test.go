@@ -22,6 +22,7 @@ import (
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/session"
 )
 
 type logRegistryOnly struct {
@@ -60,6 +61,14 @@ func (l *logRegistryOnly) IdentityValidator() *identity.Validator {
 	panic("implement me")
 }
 
+func (l *logRegistryOnly) SessionEventEmitter() *session.EventEmitter {
+	panic("implement me")
+}
+
+func (l *logRegistryOnly) SessionCache() *session.Cache {
+	panic("implement me")
+}
+
 var _ persisterDependencies = &logRegistryOnly{}
 
 func TestPersisterHMAC(t *testing.T) {
@@ -0,0 +1,129 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/selfservice/invitation"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ invitation.Persister = new(Persister)
+
+func (p *Persister) CreateInvitation(ctx context.Context, i *invitation.Invitation) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateInvitation")
+	defer otelx.End(span, &err)
+
+	token := i.Token
+	i.Token = p.hmacValue(ctx, token)
+	i.NID = p.NetworkID(ctx)
+	defer func() {
+		i.Token = token
+	}()
+
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(i))
+}
+
+func (p *Persister) GetInvitation(ctx context.Context, id uuid.UUID) (_ *invitation.Invitation, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetInvitation")
+	defer otelx.End(span, &err)
+
+	var i invitation.Invitation
+	if err := p.GetConnection(ctx).Where("id = ? AND nid = ?", id, p.NetworkID(ctx)).First(&i); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &i, nil
+}
+
+func (p *Persister) GetInvitationByToken(ctx context.Context, token string) (_ *invitation.Invitation, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetInvitationByToken")
+	defer otelx.End(span, &err)
+
+	// Invitations are long-lived, so tokens created before the secrets were rotated must keep working.
+	secrets := p.r.Config().SecretsSession(ctx)
+	digests := make([]interface{}, 0, len(secrets))
+	for _, secret := range secrets {
+		digests = append(digests, p.hmacValueWithSecret(ctx, token, secret))
+	}
+
+	var i invitation.Invitation
+	if err := p.GetConnection(ctx).
+		Where("nid = ?", p.NetworkID(ctx)).
+		Where("token IN (?)", digests...).
+		First(&i); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &i, nil
+}
+
+func (p *Persister) UseInvitation(ctx context.Context, id uuid.UUID, at time.Time) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UseInvitation")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET used_at = ?, updated_at = ? WHERE id = ? AND nid = ? AND used_at IS NULL",
+		new(invitation.Invitation).TableName(ctx),
+	),
+		at,
+		time.Now().UTC(),
+		id,
+		p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) SetInvitationIdentity(ctx context.Context, id, identityID uuid.UUID) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.SetInvitationIdentity")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET identity_id = ?, updated_at = ? WHERE id = ? AND nid = ?",
+		new(invitation.Invitation).TableName(ctx),
+	),
+		identityID,
+		time.Now().UTC(),
+		id,
+		p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) DeleteInvitation(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteInvitation")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE id = ? AND nid = ?",
+		new(invitation.Invitation).TableName(ctx),
+	), id, p.NetworkID(ctx)).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
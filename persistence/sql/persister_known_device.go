@@ -0,0 +1,66 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/session"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ session.KnownDevicePersister = new(Persister)
+
+func (p *Persister) MatchKnownDevice(ctx context.Context, identityID uuid.UUID, fingerprint, ip string) (_ *session.KnownDeviceMatch, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.MatchKnownDevice")
+	defer otelx.End(span, &err)
+
+	var m session.KnownDeviceMatch
+	exists := func(query string, args ...interface{}) (bool, error) {
+		args = append([]interface{}{p.NetworkID(ctx), identityID}, args...)
+		ok, err := p.GetConnection(ctx).Where("nid = ? AND identity_id = ?"+query, args...).Exists(new(session.KnownDevice))
+		return ok, sqlcon.HandleError(err)
+	}
+
+	if m.Any, err = exists(""); err != nil || !m.Any {
+		return &m, err
+	}
+	if m.Device, err = exists(" AND fingerprint = ?", fingerprint); err != nil {
+		return nil, err
+	}
+	if m.IPAddress, err = exists(" AND ip_address = ?", ip); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (p *Persister) UpsertKnownDevice(ctx context.Context, d *session.KnownDevice) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpsertKnownDevice")
+	defer otelx.End(span, &err)
+
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		d.NID = p.NetworkID(ctx)
+
+		var existing session.KnownDevice
+		if err := tx.Where("nid = ? AND identity_id = ? AND fingerprint = ? AND ip_address = ?", d.NID, d.IdentityID, d.Fingerprint, d.IPAddress).First(&existing); err != nil {
+			if err := sqlcon.HandleError(err); !errors.Is(err, sqlcon.ErrNoRows) {
+				return err
+			}
+			return sqlcon.HandleError(tx.Create(d))
+		}
+
+		existing.UserAgent = d.UserAgent
+		existing.Location = d.Location
+		if err := tx.Update(&existing); err != nil {
+			return sqlcon.HandleError(err)
+		}
+		*d = existing
+		return nil
+	})
+}
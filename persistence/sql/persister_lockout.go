@@ -0,0 +1,103 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+
+	"github.com/ory/kratos/persistence/sql/update"
+	"github.com/ory/kratos/selfservice/lockout"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ lockout.Persister = new(Persister)
+
+func (p *Persister) GetLockout(ctx context.Context, kind lockout.Kind, subject string) (_ *lockout.Lockout, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetLockout")
+	defer otelx.End(span, &err)
+
+	var l lockout.Lockout
+	if err := p.GetConnection(ctx).Where("nid = ? AND kind = ? AND subject = ?", p.NetworkID(ctx), kind, subject).First(&l); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &l, nil
+}
+
+func (p *Persister) RecordLockoutFailure(ctx context.Context, kind lockout.Kind, subject string, now time.Time, record func(l *lockout.Lockout)) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RecordLockoutFailure")
+	defer otelx.End(span, &err)
+
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		table := new(lockout.Lockout).TableName(ctx)
+
+		// Creating the row first takes the write lock in SQLite and makes sure
+		// there is a row to lock in all other databases.
+		insert := "INSERT INTO %s (id, nid, kind, subject, failed_attempts, lockouts, last_failed_at, created_at, updated_at) VALUES (?, ?, ?, ?, 0, 0, ?, ?, ?) ON CONFLICT (nid, kind, subject) DO NOTHING"
+		if tx.Dialect.Name() == "mysql" {
+			insert = "INSERT IGNORE INTO %s (id, nid, kind, subject, failed_attempts, lockouts, last_failed_at, created_at, updated_at) VALUES (?, ?, ?, ?, 0, 0, ?, ?, ?)"
+		}
+		//#nosec G201 -- TableName is static
+		if err := tx.RawQuery(fmt.Sprintf(insert, table),
+			x.NewUUID(), p.NetworkID(ctx), kind, subject, now, now, now,
+		).Exec(); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		query := "SELECT * FROM %s WHERE nid = ? AND kind = ? AND subject = ?"
+		if tx.Dialect.Name() != "sqlite3" {
+			query += " FOR UPDATE"
+		}
+
+		var l lockout.Lockout
+		//#nosec G201 -- TableName is static
+		if err := tx.RawQuery(fmt.Sprintf(query, table), p.NetworkID(ctx), kind, subject).First(&l); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		record(&l)
+		return update.Generic(ctx, tx, p.r.Tracer(ctx).Tracer(), &l)
+	})
+}
+
+func (p *Persister) DeleteLockout(ctx context.Context, kind lockout.Kind, subject string) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteLockout")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	return sqlcon.HandleError(p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE nid = ? AND kind = ? AND subject = ?",
+		new(lockout.Lockout).TableName(ctx),
+	),
+		p.NetworkID(ctx),
+		kind,
+		subject,
+	).Exec())
+}
+
+func (p *Persister) DeleteExpiredLockouts(ctx context.Context, olderThan time.Time, limit int) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteExpiredLockouts")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	err = p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE id in (SELECT id FROM (SELECT id FROM %s c WHERE last_failed_at <= ? AND (locked_until IS NULL OR locked_until <= ?) AND nid = ? ORDER BY last_failed_at ASC LIMIT %d ) AS s )",
+		new(lockout.Lockout).TableName(ctx),
+		new(lockout.Lockout).TableName(ctx),
+		limit,
+	),
+		olderThan,
+		olderThan,
+		p.NetworkID(ctx),
+	).Exec()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	return nil
+}
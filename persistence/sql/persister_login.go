@@ -5,7 +5,6 @@ package sql
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/gobuffalo/pop/v6"
@@ -68,18 +67,6 @@ func (p *Persister) ForceLoginFlow(ctx context.Context, id uuid.UUID) error {
 }
 
 func (p *Persister) DeleteExpiredLoginFlows(ctx context.Context, expiresAt time.Time, limit int) error {
-	//#nosec G201 -- TableName is static
-	err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
-		"DELETE FROM %s WHERE id in (SELECT id FROM (SELECT id FROM %s c WHERE expires_at <= ? and nid = ? ORDER BY expires_at ASC LIMIT %d ) AS s )",
-		new(login.Flow).TableName(ctx),
-		new(login.Flow).TableName(ctx),
-		limit,
-	),
-		expiresAt,
-		p.NetworkID(ctx),
-	).Exec()
-	if err != nil {
-		return sqlcon.HandleError(err)
-	}
-	return nil
+	_, err := p.deleteExpiredRows(ctx, new(login.Flow).TableName(ctx), expiresAt, limit)
+	return err
 }
@@ -5,12 +5,14 @@ package sql
 
 import (
 	"context"
-	"time"
 
+	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
 
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/x"
 	"github.com/ory/x/sqlcon"
 )
 
@@ -18,7 +20,7 @@ func (p *Persister) CreateLoginCode(ctx context.Context, params *code.CreateLogi
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateLoginCode")
 	defer span.End()
 
-	now := time.Now().UTC()
+	now := x.Now().UTC()
 	loginCode := &code.LoginCode{
 		IdentityID:  params.IdentityID,
 		Address:     params.Address,
@@ -38,11 +40,13 @@ func (p *Persister) CreateLoginCode(ctx context.Context, params *code.CreateLogi
 	return loginCode, nil
 }
 
-func (p *Persister) UseLoginCode(ctx context.Context, flowID uuid.UUID, identityID uuid.UUID, userProvidedCode string) (*code.LoginCode, error) {
+func (p *Persister) UseLoginCode(ctx context.Context, flowID uuid.UUID, identityID uuid.UUID, userProvidedCode string, validate code.CodeValidator) (*code.LoginCode, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UseLoginCode")
 	defer span.End()
 
-	codeRow, err := useOneTimeCode[code.LoginCode, *code.LoginCode](ctx, p, flowID, userProvidedCode, new(login.Flow).TableName(ctx), "selfservice_login_flow_id", withCheckIdentityID(identityID))
+	codeRow, err := useOneTimeCode[code.LoginCode, *code.LoginCode](ctx, p, flowID, userProvidedCode, new(login.Flow).TableName(ctx), "selfservice_login_flow_id", func(ctx context.Context, _ *pop.Connection, c *code.LoginCode) error {
+		return validateCode(ctx, validate, &code.CodeRequest{Flow: flow.LoginFlow, FlowID: flowID, IdentityID: c.IdentityID, Address: c.Address})
+	}, withCheckIdentityID(identityID))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,84 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/session"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ session.LogoutCallbackPersister = new(Persister)
+
+func (p *Persister) ListLogoutCallbacks(ctx context.Context) (_ []session.LogoutCallback, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListLogoutCallbacks")
+	defer otelx.End(span, &err)
+
+	callbacks := make([]session.LogoutCallback, 0)
+	if err := p.GetConnection(ctx).
+		Where("nid = ?", p.NetworkID(ctx)).
+		Order("app_id ASC").
+		All(&callbacks); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return callbacks, nil
+}
+
+func (p *Persister) UpsertLogoutCallback(ctx context.Context, c *session.LogoutCallback) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpsertLogoutCallback")
+	defer otelx.End(span, &err)
+
+	c.NID = p.NetworkID(ctx)
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		var existing session.LogoutCallback
+		if err := tx.Where("app_id = ? AND nid = ?", c.AppID, c.NID).First(&existing); errors.Is(sqlcon.HandleError(err), sqlcon.ErrNoRows) {
+			return sqlcon.HandleError(tx.Create(c))
+		} else if err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		c.ID = existing.ID
+		c.CreatedAt = existing.CreatedAt
+		c.UpdatedAt = time.Now().UTC()
+
+		//#nosec G201 -- TableName is static
+		return sqlcon.HandleError(tx.RawQuery(fmt.Sprintf(
+			"UPDATE %s SET url = ?, updated_at = ? WHERE id = ? AND nid = ?",
+			new(session.LogoutCallback).TableName(ctx),
+		),
+			c.URL,
+			c.UpdatedAt,
+			c.ID,
+			c.NID,
+		).Exec())
+	})
+}
+
+func (p *Persister) DeleteLogoutCallback(ctx context.Context, appID string) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteLogoutCallback")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE app_id = ? AND nid = ?",
+		new(session.LogoutCallback).TableName(ctx),
+	),
+		appID,
+		p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
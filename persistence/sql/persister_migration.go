@@ -0,0 +1,248 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/popx"
+
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/organization"
+	"github.com/ory/kratos/persistence"
+	"github.com/ory/kratos/selfservice/consent"
+	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/invitation"
+	"github.com/ory/kratos/selfservice/lockout"
+	"github.com/ory/kratos/selfservice/sessiontokenexchange"
+	"github.com/ory/kratos/selfservice/signupcode"
+	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/kratos/session"
+)
+
+var _ persistence.MigrationReporter = new(Persister)
+
+func (p *Persister) MigrationReport(ctx context.Context) (_ *persistence.MigrationReport, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.MigrationReport")
+	defer otelx.End(span, &err)
+
+	status, err := p.mb.Status(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	conn := p.GetConnection(ctx)
+	migrations := p.mb.Migrations["up"].SortAndFilter(conn.Dialect.Name())
+	report := &persistence.MigrationReport{
+		Migrations: make([]persistence.MigrationInfo, len(status)),
+		Drift:      []string{},
+	}
+
+	var lastApplied int
+	for k, s := range status {
+		checksum, err := p.migrationChecksum(migrations[k])
+		if err != nil {
+			return nil, err
+		}
+
+		report.Migrations[k] = persistence.MigrationInfo{
+			Version:  s.Version,
+			Name:     s.Name,
+			State:    s.State,
+			Checksum: checksum,
+		}
+		if s.State == popx.Applied {
+			lastApplied = k
+		}
+	}
+
+	for _, m := range report.Migrations[:lastApplied] {
+		if m.State == popx.Pending {
+			report.Drift = append(report.Drift, fmt.Sprintf("migration %s (%s) is pending although later migrations were applied", m.Version, m.Name))
+		}
+	}
+
+	applied, err := p.appliedMigrationVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, version := range applied {
+		if !p.knowsMigrationVersion(version) {
+			report.Drift = append(report.Drift, fmt.Sprintf("migration %s was applied to the database but is unknown to this version of Ory Kratos", version))
+		}
+	}
+
+	// Tables are only expected to exist once all migrations were applied.
+	if !status.HasPending() {
+		for _, t := range schemaTables() {
+			name := t.TableName(ctx)
+			if err := conn.RawQuery(fmt.Sprintf("SELECT 1 FROM %s WHERE 1 = 0", conn.Dialect.Quote(name))).Exec(); err != nil {
+				report.Drift = append(report.Drift, fmt.Sprintf("table %s is missing or can not be read: %s", name, err))
+			}
+		}
+
+		phased, err := p.PhasedMigrations(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range phased {
+			if m.CutOver && m.Phase != persistence.PhasedMigrationPhaseBackfilled {
+				report.Drift = append(report.Drift, fmt.Sprintf("phased migration %s reads from the new schema but was not backfilled yet", m.Name))
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// staticTableName is a table which has no model implementing tableNamer.
+type staticTableName string
+
+func (n staticTableName) TableName(context.Context) string {
+	return string(n)
+}
+
+// schemaTables returns all tables of the schema. Tables added by a migration
+// must be added here so that the migration report notices if they are
+// missing.
+func schemaTables() []tableNamer {
+	return append(ownedTables(),
+		staticTableName("networks"),
+		new(identity.Credentials),
+		new(identity.CredentialsTypeTable),
+		new(identity.CredentialIdentifier),
+		new(identity.RecoveryAddress),
+		new(identity.VerifiableAddress),
+		new(identity.SearchTrait),
+		new(identity.AuditEvent),
+		new(identity.LifecycleEvent),
+		new(persistence.PhasedMigration),
+		new(continuity.Container),
+		new(errorx.ErrorContainer),
+		new(link.RecoveryToken),
+		new(link.VerificationToken),
+		staticTableName(new(sessiontokenexchange.Exchanger).TableName()),
+		staticTableName(courier.MessageDispatch{}.TableName()),
+		new(session.Device),
+		new(session.TrustedDevice),
+		new(session.KnownDevice),
+		new(session.UpstreamSession),
+		new(session.RefreshToken),
+		new(session.LogoutCallback),
+		new(session.DPoPProof),
+		new(session.UpstreamLogoutToken),
+		new(lockout.Lockout),
+		new(consent.Record),
+		new(settings.EmailChange),
+		new(invitation.Invitation),
+		new(signupcode.SignupCode),
+		new(oidc.StoredConfiguration),
+		new(organization.Organization),
+		new(organization.Domain),
+	)
+}
+
+func (p *Persister) PendingMigrationScripts(ctx context.Context) (_ []persistence.MigrationScript, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.PendingMigrationScripts")
+	defer otelx.End(span, &err)
+
+	status, err := p.mb.Status(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	conn := p.GetConnection(ctx)
+	migrations := p.mb.Migrations["up"].SortAndFilter(conn.Dialect.Name())
+	render := popx.ParameterizedMigrationContent(nil)
+
+	var scripts []persistence.MigrationScript
+	for k, s := range status {
+		if s.State != popx.Pending {
+			continue
+		}
+
+		m := migrations[k]
+		script := persistence.MigrationScript{Version: m.Version, Name: m.Name}
+		if m.Type == "go" {
+			script.SQL = "-- This migration is implemented in Go and can not be rendered as SQL."
+		} else {
+			b, err := fs.ReadFile(p.mb.Dir, m.Path)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			if script.SQL, err = render(m, conn, b, true); err != nil {
+				return nil, err
+			}
+		}
+
+		scripts = append(scripts, script)
+	}
+
+	return scripts, nil
+}
+
+func (p *Persister) migrationChecksum(m popx.Migration) (string, error) {
+	if m.Type == "go" {
+		return "", nil
+	}
+
+	b, err := fs.ReadFile(p.mb.Dir, m.Path)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+var nonWordCharacters = regexp.MustCompile(`\W`)
+
+func (p *Persister) appliedMigrationVersions(ctx context.Context) ([]string, error) {
+	conn := p.GetConnection(ctx)
+
+	var versions []string
+	//#nosec G201 -- the table name is sanitized
+	if err := conn.RawQuery(fmt.Sprintf(
+		"SELECT version FROM %s",
+		nonWordCharacters.ReplaceAllString(conn.MigrationTableName(), ""),
+	)).All(&versions); err != nil {
+		if isTableNotFound(err) {
+			// No migrations were applied yet.
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	return versions, nil
+}
+
+func (p *Persister) knowsMigrationVersion(version string) bool {
+	for _, m := range p.mb.Migrations["up"] {
+		// Older migrations were recorded with a 14 digit version.
+		if m.Version == version || (len(m.Version) > 14 && m.Version[:14] == version) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTableNotFound(err error) bool {
+	return strings.Contains(err.Error(), "no such table:") || // sqlite
+		strings.Contains(err.Error(), "Error 1146") || // MySQL
+		strings.Contains(err.Error(), "SQLSTATE 42P01") // PostgreSQL / CockroachDB
+}
@@ -0,0 +1,59 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/internal"
+)
+
+func TestPersister_MigrationReport(t *testing.T) {
+	_, reg := internal.NewFastRegistryWithMocks(t)
+	p := reg.Persister()
+	ctx := context.Background()
+
+	t.Run("case=reports applied migrations without drift", func(t *testing.T) {
+		report, err := p.MigrationReport(ctx)
+		require.NoError(t, err)
+
+		require.NotEmpty(t, report.Migrations)
+		assert.False(t, report.HasPending())
+		assert.Empty(t, report.Drift)
+		for _, m := range report.Migrations {
+			assert.Len(t, m.Checksum, 64, "%s", m.Name)
+		}
+
+		scripts, err := p.PendingMigrationScripts(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, scripts)
+	})
+
+	t.Run("case=detects missing tables", func(t *testing.T) {
+		conn := p.GetConnection(ctx)
+		require.NoError(t, conn.RawQuery("ALTER TABLE identity_search_traits RENAME TO identity_search_traits_moved").Exec())
+		t.Cleanup(func() {
+			require.NoError(t, conn.RawQuery("ALTER TABLE identity_search_traits_moved RENAME TO identity_search_traits").Exec())
+		})
+
+		report, err := p.MigrationReport(ctx)
+		require.NoError(t, err)
+		require.Len(t, report.Drift, 1)
+		assert.Contains(t, report.Drift[0], "table identity_search_traits is missing")
+	})
+
+	t.Run("case=detects unknown migrations", func(t *testing.T) {
+		conn := p.GetConnection(ctx)
+		require.NoError(t, conn.RawQuery("INSERT INTO schema_migration (version, version_self) VALUES (?, 0)", "29991231000000000000").Exec())
+
+		report, err := p.MigrationReport(ctx)
+		require.NoError(t, err)
+		require.Len(t, report.Drift, 1)
+		assert.Contains(t, report.Drift[0], "29991231000000000000")
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ oidc.ProviderPersister = new(Persister)
+
+func (p *Persister) ListOIDCProviders(ctx context.Context) (_ []oidc.StoredConfiguration, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListOIDCProviders")
+	defer otelx.End(span, &err)
+
+	providers := make([]oidc.StoredConfiguration, 0)
+	if err := p.GetConnection(ctx).
+		Where("nid = ?", p.NetworkID(ctx)).
+		Order("provider_id ASC").
+		All(&providers); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return providers, nil
+}
+
+func (p *Persister) GetOIDCProvider(ctx context.Context, providerID string) (_ *oidc.StoredConfiguration, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetOIDCProvider")
+	defer otelx.End(span, &err)
+
+	var c oidc.StoredConfiguration
+	if err := p.GetConnection(ctx).Where("provider_id = ? AND nid = ?", providerID, p.NetworkID(ctx)).First(&c); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &c, nil
+}
+
+func (p *Persister) CreateOIDCProvider(ctx context.Context, c *oidc.StoredConfiguration) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateOIDCProvider")
+	defer otelx.End(span, &err)
+
+	c.NID = p.NetworkID(ctx)
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(c))
+}
+
+func (p *Persister) UpdateOIDCProvider(ctx context.Context, c *oidc.StoredConfiguration) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateOIDCProvider")
+	defer otelx.End(span, &err)
+
+	c.UpdatedAt = time.Now().UTC()
+
+	//#nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET config = ?, updated_at = ? WHERE provider_id = ? AND nid = ?",
+		new(oidc.StoredConfiguration).TableName(ctx),
+	),
+		c.EncryptedConfiguration,
+		c.UpdatedAt,
+		c.ProviderID,
+		p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) DeleteOIDCProvider(ctx context.Context, providerID string) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteOIDCProvider")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE provider_id = ? AND nid = ?",
+		new(oidc.StoredConfiguration).TableName(ctx),
+	), providerID, p.NetworkID(ctx)).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
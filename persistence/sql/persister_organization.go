@@ -0,0 +1,182 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/organization"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ organization.Persister = new(Persister)
+
+func (p *Persister) CreateOrganization(ctx context.Context, o *organization.Organization) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateOrganization")
+	defer otelx.End(span, &err)
+
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		o.NID = p.NetworkID(ctx)
+		if err := tx.Create(o); err != nil {
+			return sqlcon.HandleError(err)
+		}
+		return p.createOrganizationDomains(ctx, tx, o)
+	})
+}
+
+func (p *Persister) createOrganizationDomains(ctx context.Context, tx *pop.Connection, o *organization.Organization) error {
+	for k, domain := range o.Domains {
+		o.Domains[k] = organization.NormalizeDomain(domain)
+		if err := tx.Create(&organization.Domain{
+			OrganizationID: o.ID,
+			Domain:         o.Domains[k],
+			NID:            p.NetworkID(ctx),
+		}); err != nil {
+			return sqlcon.HandleError(err)
+		}
+	}
+	return nil
+}
+
+func (p *Persister) loadOrganizationDomains(ctx context.Context, orgs ...*organization.Organization) error {
+	if len(orgs) == 0 {
+		return nil
+	}
+
+	ids := make([]interface{}, len(orgs))
+	for k, o := range orgs {
+		ids[k] = o.ID
+		o.Domains = make([]string, 0)
+	}
+
+	var domains []organization.Domain
+	if err := p.GetConnection(ctx).
+		Where("nid = ?", p.NetworkID(ctx)).
+		Where("organization_id IN (?)", ids...).
+		Order("domain ASC").
+		All(&domains); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	for _, d := range domains {
+		for _, o := range orgs {
+			if o.ID == d.OrganizationID {
+				o.Domains = append(o.Domains, d.Domain)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Persister) GetOrganization(ctx context.Context, id uuid.UUID) (_ *organization.Organization, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetOrganization")
+	defer otelx.End(span, &err)
+
+	var o organization.Organization
+	if err := p.GetConnection(ctx).Where("id = ? AND nid = ?", id, p.NetworkID(ctx)).First(&o); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	if err := p.loadOrganizationDomains(ctx, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (p *Persister) ListOrganizations(ctx context.Context) (_ []organization.Organization, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListOrganizations")
+	defer otelx.End(span, &err)
+
+	orgs := make([]organization.Organization, 0)
+	if err := p.GetConnection(ctx).
+		Where("nid = ?", p.NetworkID(ctx)).
+		Order("label ASC").
+		All(&orgs); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	refs := make([]*organization.Organization, len(orgs))
+	for k := range orgs {
+		refs[k] = &orgs[k]
+	}
+	if err := p.loadOrganizationDomains(ctx, refs...); err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+func (p *Persister) UpdateOrganization(ctx context.Context, o *organization.Organization) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateOrganization")
+	defer otelx.End(span, &err)
+
+	return p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		o.UpdatedAt = time.Now().UTC()
+
+		//#nosec G201 -- TableName is static
+		count, err := tx.RawQuery(fmt.Sprintf(
+			"UPDATE %s SET label = ?, allowed_methods = ?, updated_at = ? WHERE id = ? AND nid = ?",
+			new(organization.Organization).TableName(ctx),
+		),
+			o.Label,
+			o.AllowedMethods,
+			o.UpdatedAt,
+			o.ID,
+			p.NetworkID(ctx),
+		).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		if count == 0 {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+
+		//#nosec G201 -- TableName is static
+		if err := tx.RawQuery(fmt.Sprintf(
+			"DELETE FROM %s WHERE organization_id = ? AND nid = ?",
+			new(organization.Domain).TableName(ctx),
+		), o.ID, p.NetworkID(ctx)).Exec(); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		return p.createOrganizationDomains(ctx, tx, o)
+	})
+}
+
+func (p *Persister) DeleteOrganization(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteOrganization")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE id = ? AND nid = ?",
+		new(organization.Organization).TableName(ctx),
+	), id, p.NetworkID(ctx)).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) FindOrganizationByDomain(ctx context.Context, domain string) (_ *organization.Organization, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.FindOrganizationByDomain")
+	defer otelx.End(span, &err)
+
+	var d organization.Domain
+	if err := p.GetConnection(ctx).
+		Where("domain = ? AND nid = ?", organization.NormalizeDomain(domain), p.NetworkID(ctx)).
+		First(&d); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return p.GetOrganization(ctx, d.OrganizationID)
+}
@@ -0,0 +1,144 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/persistence"
+	idpersistence "github.com/ory/kratos/persistence/sql/identity"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+)
+
+var _ persistence.PhasedMigrator = new(Persister)
+
+// backfillers create the backfillers of the phased migrations of this version of Kratos. A backfiller is
+// added together with the additive SQL migration of a phased migration and removed together with the old schema.
+var backfillers = []func(r persisterDependencies) persistence.Backfiller{
+	func(persisterDependencies) persistence.Backfiller {
+		return new(idpersistence.WebAuthnUserHandleBackfiller)
+	},
+	func(r persisterDependencies) persistence.Backfiller {
+		return idpersistence.NewSearchTraitsBackfiller(r)
+	},
+}
+
+func (p *Persister) PhasedMigrations(ctx context.Context) (_ []persistence.PhasedMigration, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.PhasedMigrations")
+	defer otelx.End(span, &err)
+
+	migrations := make([]persistence.PhasedMigration, len(p.backfillers))
+	for k, b := range p.backfillers {
+		m, err := p.getPhasedMigration(ctx, b.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		var remaining int64
+		if m.Phase != persistence.PhasedMigrationPhaseBackfilled {
+			if remaining, err = b.Remaining(ctx, p.GetConnection(ctx)); err != nil {
+				return nil, sqlcon.HandleError(err)
+			}
+		}
+
+		m.SetProgress(remaining)
+		m.CutOver = p.PhasedMigrationCutOver(ctx, b.Name())
+		migrations[k] = *m
+	}
+
+	return migrations, nil
+}
+
+func (p *Persister) BackfillBatch(ctx context.Context, name string, limit int) (n int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.BackfillBatch")
+	defer otelx.End(span, &err)
+
+	b, err := p.backfiller(name)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := p.Transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
+		m, err := p.getPhasedMigration(ctx, name)
+		if err != nil {
+			return err
+		}
+		if m.Phase == persistence.PhasedMigrationPhaseBackfilled {
+			return nil
+		}
+
+		if n, err = b.Backfill(ctx, c, limit); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		now := time.Now().UTC()
+		if m.Phase == persistence.PhasedMigrationPhasePending {
+			m.Phase = persistence.PhasedMigrationPhaseBackfilling
+			m.StartedAt = sqlxx.NullTime(now)
+		}
+		m.ProcessedRows += int64(n)
+		m.LastError = ""
+		if n == 0 {
+			m.Phase = persistence.PhasedMigrationPhaseBackfilled
+			m.CompletedAt = sqlxx.NullTime(now)
+		}
+
+		return p.savePhasedMigration(c, m)
+	}); err != nil {
+		p.recordBackfillError(ctx, name, err)
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (p *Persister) PhasedMigrationCutOver(ctx context.Context, name string) bool {
+	return slices.Contains(p.r.Config().DatabasePhasedMigrationsCutover(ctx), name)
+}
+
+func (p *Persister) backfiller(name string) (persistence.Backfiller, error) {
+	for _, b := range p.backfillers {
+		if b.Name() == name {
+			return b, nil
+		}
+	}
+	return nil, errors.WithStack(persistence.ErrUnknownPhasedMigration.WithDetail("name", name))
+}
+
+func (p *Persister) getPhasedMigration(ctx context.Context, name string) (*persistence.PhasedMigration, error) {
+	var m persistence.PhasedMigration
+	if err := sqlcon.HandleError(p.GetConnection(ctx).Where("name = ?", name).First(&m)); errors.Is(err, sqlcon.ErrNoRows) {
+		return &persistence.PhasedMigration{Name: name, Phase: persistence.PhasedMigrationPhasePending}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (p *Persister) savePhasedMigration(c *pop.Connection, m *persistence.PhasedMigration) error {
+	if m.ID == uuid.Nil {
+		return sqlcon.HandleError(c.Create(m))
+	}
+	return sqlcon.HandleError(c.Update(m))
+}
+
+// recordBackfillError stores the error of a failed batch so that it shows up in the progress API.
+func (p *Persister) recordBackfillError(ctx context.Context, name string, cause error) {
+	m, err := p.getPhasedMigration(ctx, name)
+	if err == nil {
+		m.LastError = cause.Error()
+		err = p.savePhasedMigration(p.GetConnection(ctx), m)
+	}
+	if err != nil {
+		p.r.Logger().WithError(err).WithField("phased_migration", name).Warn("Unable to record the backfill error.")
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/persistence"
+	"github.com/ory/kratos/persistence/sql"
+	idpersistence "github.com/ory/kratos/persistence/sql/identity"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlxx"
+)
+
+type stubBackfiller struct {
+	name      string
+	remaining int64
+	err       error
+}
+
+func (b *stubBackfiller) Name() string {
+	return b.name
+}
+
+func (b *stubBackfiller) Remaining(context.Context, *pop.Connection) (int64, error) {
+	return b.remaining, nil
+}
+
+func (b *stubBackfiller) Backfill(_ context.Context, _ *pop.Connection, limit int) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n := min(b.remaining, int64(limit))
+	b.remaining -= n
+	return int(n), nil
+}
+
+func TestPersister_PhasedMigrations(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	ok := &stubBackfiller{name: "ok", remaining: 25}
+	failing := &stubBackfiller{name: "failing", remaining: 5, err: errors.New("backfill failed")}
+	p, err := sql.NewPersister(ctx, reg, reg.Persister().GetConnection(ctx), sql.WithBackfillers(ok, failing))
+	require.NoError(t, err)
+
+	find := func(t *testing.T, name string) persistence.PhasedMigration {
+		migrations, err := p.PhasedMigrations(ctx)
+		require.NoError(t, err)
+		for _, m := range migrations {
+			if m.Name == name {
+				return m
+			}
+		}
+		t.Fatalf("phased migration %s not found", name)
+		return persistence.PhasedMigration{}
+	}
+
+	t.Run("case=registers the phased migrations of this version", func(t *testing.T) {
+		migrations, err := reg.Persister().PhasedMigrations(ctx)
+		require.NoError(t, err)
+
+		names := make([]string, len(migrations))
+		for k, m := range migrations {
+			names[k] = m.Name
+		}
+		assert.Contains(t, names, idpersistence.WebAuthnUserHandleBackfillName)
+		assert.Contains(t, names, idpersistence.SearchTraitsBackfillName)
+	})
+
+	t.Run("case=reports pending migrations", func(t *testing.T) {
+		m := find(t, "ok")
+		assert.Equal(t, persistence.PhasedMigrationPhasePending, m.Phase)
+		assert.EqualValues(t, 25, m.RemainingRows)
+		assert.Zero(t, m.Progress)
+		assert.False(t, m.CutOver)
+	})
+
+	t.Run("case=backfills in batches", func(t *testing.T) {
+		n, err := p.BackfillBatch(ctx, "ok", 10)
+		require.NoError(t, err)
+		assert.Equal(t, 10, n)
+
+		m := find(t, "ok")
+		assert.Equal(t, persistence.PhasedMigrationPhaseBackfilling, m.Phase)
+		assert.EqualValues(t, 10, m.ProcessedRows)
+		assert.EqualValues(t, 15, m.RemainingRows)
+		assert.InDelta(t, 0.4, m.Progress, 0.001)
+		assert.False(t, time.Time(m.StartedAt).IsZero())
+
+		for n != 0 {
+			n, err = p.BackfillBatch(ctx, "ok", 10)
+			require.NoError(t, err)
+		}
+
+		m = find(t, "ok")
+		assert.Equal(t, persistence.PhasedMigrationPhaseBackfilled, m.Phase)
+		assert.EqualValues(t, 25, m.ProcessedRows)
+		assert.EqualValues(t, 1, m.Progress)
+		assert.False(t, time.Time(m.CompletedAt).IsZero())
+	})
+
+	t.Run("case=records errors", func(t *testing.T) {
+		_, err := p.BackfillBatch(ctx, "failing", 10)
+		require.Error(t, err)
+
+		m := find(t, "failing")
+		assert.Equal(t, persistence.PhasedMigrationPhasePending, m.Phase)
+		assert.Contains(t, m.LastError, "backfill failed")
+	})
+
+	t.Run("case=fails for unknown migrations", func(t *testing.T) {
+		_, err := p.BackfillBatch(ctx, "unknown", 10)
+		require.ErrorIs(t, err, persistence.ErrUnknownPhasedMigration)
+	})
+
+	t.Run("case=cuts over reads", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeyDatabasePhasedMigrationsCutover, []string{"ok"})
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeyDatabasePhasedMigrationsCutover, []string{})
+		})
+
+		assert.True(t, p.PhasedMigrationCutOver(ctx, "ok"))
+		assert.False(t, p.PhasedMigrationCutOver(ctx, "failing"))
+		assert.True(t, find(t, "ok").CutOver)
+
+		read := func(cutOver bool) string {
+			v, err := persistence.DualRead(ctx, cutOver,
+				func(context.Context) (string, error) { return "old", nil },
+				func(context.Context) (string, error) { return "new", nil },
+			)
+			require.NoError(t, err)
+			return v
+		}
+		assert.Equal(t, "new", read(p.PhasedMigrationCutOver(ctx, "ok")))
+		assert.Equal(t, "old", read(p.PhasedMigrationCutOver(ctx, "failing")))
+	})
+}
+
+func TestSearchTraitsBackfiller(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetIdentitySchemas(t, conf, map[string]string{
+		"default":    "file://../../identity/stub/identity.schema.json",
+		"searchable": "file://../../identity/stub/handler/searchable.schema.json",
+	})
+
+	create := func(t *testing.T, schemaID, traits string) *identity.Identity {
+		i := identity.NewIdentity(schemaID)
+		i.Traits = identity.Traits(traits)
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+		return i
+	}
+	indexed := create(t, "searchable", `{"email":"backfill@ory.sh"}`)
+	create(t, "searchable", `{"nickname":"nothing to index"}`)
+	create(t, "default", `{}`)
+
+	// Identities created before the search traits were introduced have none.
+	conn := reg.Persister().GetConnection(ctx)
+	require.NoError(t, conn.RawQuery("DELETE FROM identity_search_traits").Exec())
+
+	b := idpersistence.NewSearchTraitsBackfiller(reg)
+	assert.Equal(t, idpersistence.SearchTraitsBackfillName, b.Name())
+
+	remaining, err := b.Remaining(ctx, conn)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, remaining, "only identities of schemas with searchable traits are backfilled")
+
+	n, err := b.Backfill(ctx, conn, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	n, err = b.Backfill(ctx, conn, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	remaining, err = b.Remaining(ctx, conn)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, remaining, "identities without values are marked as indexed")
+
+	found, _, err := reg.PrivilegedIdentityPool().ListIdentities(ctx, identity.ListIdentityParameters{
+		TraitQueries: []identity.TraitQuery{{Trait: "email", Operator: identity.TraitQueryEqual, Term: "backfill@ory.sh"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, indexed.ID, found[0].ID)
+}
+
+func TestWebAuthnUserHandleBackfiller(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://../../identity/stub/identity.schema.json")
+
+	userHandle := []byte(x.NewUUID().String())
+	i := identity.NewIdentity("")
+	i.Traits = identity.Traits(`{}`)
+	i.SetCredentials(identity.CredentialsTypeWebAuthn, identity.Credentials{
+		Type:        identity.CredentialsTypeWebAuthn,
+		Identifiers: []string{x.NewUUID().String()},
+		Config:      sqlxx.JSONRawMessage(`{"user_handle":"` + base64.StdEncoding.EncodeToString(userHandle) + `","credentials":[]}`),
+	})
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+
+	// Credentials created before the user handles were indexed have no index entries.
+	conn := reg.Persister().GetConnection(ctx)
+	require.NoError(t, conn.RawQuery("DELETE FROM identity_webauthn_user_handles").Exec())
+
+	b := new(idpersistence.WebAuthnUserHandleBackfiller)
+	assert.Equal(t, idpersistence.WebAuthnUserHandleBackfillName, b.Name())
+
+	remaining, err := b.Remaining(ctx, conn)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, remaining)
+
+	n, err := b.Backfill(ctx, conn, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	remaining, err = b.Remaining(ctx, conn)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, remaining)
+
+	conf.MustSet(ctx, config.ViperKeyDatabasePhasedMigrationsCutover, []string{idpersistence.WebAuthnUserHandleBackfillName})
+	found, err := reg.PrivilegedIdentityPool().FindIdentityByWebAuthnUserHandle(ctx, userHandle)
+	require.NoError(t, err)
+	assert.Equal(t, i.ID, found.ID)
+}
@@ -6,6 +6,7 @@ package sql
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -15,8 +16,11 @@ import (
 
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/persistence/sql/update"
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/recovery"
+	"github.com/ory/kratos/selfservice/strategy/code"
 	"github.com/ory/kratos/selfservice/strategy/link"
+	"github.com/ory/x/otelx"
 	"github.com/ory/x/sqlcon"
 )
 
@@ -119,18 +123,78 @@ func (p *Persister) DeleteRecoveryToken(ctx context.Context, token string) error
 }
 
 func (p *Persister) DeleteExpiredRecoveryFlows(ctx context.Context, expiresAt time.Time, limit int) error {
-	//#nosec G201 -- TableName is static
-	err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
-		"DELETE FROM %s WHERE id in (SELECT id FROM (SELECT id FROM %s c WHERE expires_at <= ? and nid = ? ORDER BY expires_at ASC LIMIT %d ) AS s )",
-		new(recovery.Flow).TableName(ctx),
-		new(recovery.Flow).TableName(ctx),
-		limit,
-	),
-		expiresAt,
-		p.NetworkID(ctx),
-	).Exec()
-	if err != nil {
-		return sqlcon.HandleError(err)
+	_, err := p.deleteExpiredRows(ctx, new(recovery.Flow).TableName(ctx), expiresAt, limit)
+	return err
+}
+
+func (p *Persister) InvalidateRecoveryArtifacts(ctx context.Context, identityID uuid.UUID) (_ *recovery.InvalidatedArtifacts, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.InvalidateRecoveryArtifacts")
+	defer otelx.End(span, &err)
+
+	var invalidated recovery.InvalidatedArtifacts
+	nid := p.NetworkID(ctx)
+	if err := p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		// The flows are looked up first because deleting the codes and links removes the reference.
+		var codes []code.RecoveryCode
+		if err := tx.Where("identity_id = ? AND nid = ? AND used_at IS NULL", identityID, nid).All(&codes); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		var tokens []link.RecoveryToken
+		if err := tx.Where("identity_id = ? AND nid = ? AND NOT used", identityID, nid).All(&tokens); err != nil {
+			return sqlcon.HandleError(err)
+		}
+
+		seen := map[uuid.UUID]bool{}
+		args := []interface{}{nid, flow.StatePassedChallenge}
+		for _, c := range codes {
+			if !seen[c.FlowID] {
+				seen[c.FlowID] = true
+				args = append(args, c.FlowID)
+			}
+		}
+		for _, t := range tokens {
+			if t.FlowID.Valid && !seen[t.FlowID.UUID] {
+				seen[t.FlowID.UUID] = true
+				args = append(args, t.FlowID.UUID)
+			}
+		}
+
+		//#nosec G201 -- TableName is static
+		count, err := tx.RawQuery(fmt.Sprintf("DELETE FROM %s WHERE identity_id = ? AND nid = ? AND used_at IS NULL", new(code.RecoveryCode).TableName(ctx)),
+			identityID, nid).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		invalidated.Codes = int64(count)
+
+		//#nosec G201 -- TableName is static
+		count, err = tx.RawQuery(fmt.Sprintf("DELETE FROM %s WHERE identity_id = ? AND nid = ? AND NOT used", new(link.RecoveryToken).TableName(ctx)),
+			identityID, nid).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		invalidated.Links = int64(count)
+
+		if len(seen) == 0 {
+			return nil
+		}
+
+		//#nosec G201 -- TableName is static and the placeholders are generated
+		count, err = tx.RawQuery(fmt.Sprintf(
+			"DELETE FROM %s WHERE nid = ? AND state <> ? AND id IN (%s)",
+			new(recovery.Flow).TableName(ctx),
+			strings.TrimSuffix(strings.Repeat("?,", len(seen)), ","),
+		), args...).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		invalidated.Flows = int64(count)
+
+		return nil
+	}); err != nil {
+		return nil, err
 	}
-	return nil
+
+	return &invalidated, nil
 }
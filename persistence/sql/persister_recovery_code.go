@@ -5,14 +5,16 @@ package sql
 
 import (
 	"context"
-	"time"
 
+	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/x"
 	"github.com/ory/x/sqlcon"
 )
 
@@ -20,7 +22,7 @@ func (p *Persister) CreateRecoveryCode(ctx context.Context, params *code.CreateR
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateRecoveryCode")
 	defer span.End()
 
-	now := time.Now()
+	now := x.Now()
 	recoveryCode := &code.RecoveryCode{
 		ID:         uuid.Nil,
 		CodeHMAC:   p.hmacValue(ctx, params.RawCode),
@@ -53,27 +55,24 @@ func (p *Persister) CreateRecoveryCode(ctx context.Context, params *code.CreateR
 //
 // If the supplied code matched a code from the flow, no error is returned
 // If an invalid code was submitted with this flow more than 5 times, an error is returned
-func (p *Persister) UseRecoveryCode(ctx context.Context, flowID uuid.UUID, userProvidedCode string) (*code.RecoveryCode, error) {
+func (p *Persister) UseRecoveryCode(ctx context.Context, flowID uuid.UUID, userProvidedCode string, validate code.CodeValidator) (*code.RecoveryCode, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UseRecoveryCode")
 	defer span.End()
 
-	codeRow, err := useOneTimeCode[code.RecoveryCode, *code.RecoveryCode](ctx, p, flowID, userProvidedCode, new(recovery.Flow).TableName(ctx), "selfservice_recovery_flow_id")
-	if err != nil {
-		return nil, err
-	}
-
-	var ra identity.RecoveryAddress
-	if err := sqlcon.HandleError(p.GetConnection(ctx).Where("id = ? AND nid = ?", codeRow.RecoveryAddressID, p.NetworkID(ctx)).First(&ra)); err != nil {
-		if errors.Is(err, sqlcon.ErrNoRows) {
-			// This is ok, it can happen when an administrator initiates account recovery. This works even if the
-			// user has no recovery address!
-		} else {
-			return nil, err
+	return useOneTimeCode[code.RecoveryCode, *code.RecoveryCode](ctx, p, flowID, userProvidedCode, new(recovery.Flow).TableName(ctx), "selfservice_recovery_flow_id", func(ctx context.Context, tx *pop.Connection, c *code.RecoveryCode) error {
+		var ra identity.RecoveryAddress
+		if err := sqlcon.HandleError(tx.Where("id = ? AND nid = ?", c.RecoveryAddressID, p.NetworkID(ctx)).First(&ra)); err != nil {
+			if errors.Is(err, sqlcon.ErrNoRows) {
+				// This is ok, it can happen when an administrator initiates account recovery. This works even if the
+				// user has no recovery address!
+			} else {
+				return err
+			}
 		}
-	}
-	codeRow.RecoveryAddress = &ra
+		c.RecoveryAddress = &ra
 
-	return codeRow, nil
+		return validateCode(ctx, validate, &code.CodeRequest{Flow: flow.RecoveryFlow, FlowID: flowID, IdentityID: c.IdentityID, Address: ra.Value})
+	})
 }
 
 func (p *Persister) DeleteRecoveryCodesOfFlow(ctx context.Context, flowID uuid.UUID) error {
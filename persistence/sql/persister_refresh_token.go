@@ -0,0 +1,140 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/session"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ session.RefreshTokenPersister = new(Persister)
+
+func (p *Persister) CreateRefreshToken(ctx context.Context, t *session.RefreshToken, sessionTokenExpiresAt time.Time) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateRefreshToken")
+	defer otelx.End(span, &err)
+
+	if err := p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		if err := p.createRefreshToken(ctx, tx, t); err != nil {
+			return err
+		}
+
+		//#nosec G201 -- TableName is static
+		return sqlcon.HandleError(tx.RawQuery(fmt.Sprintf(
+			"UPDATE %s SET token_expires_at = ?, updated_at = ? WHERE id = ? AND nid = ?",
+			new(session.Session).TableName(ctx),
+		),
+			sessionTokenExpiresAt.UTC(),
+			time.Now().UTC(),
+			t.SessionID,
+			p.NetworkID(ctx),
+		).Exec())
+	}); err != nil {
+		return err
+	}
+
+	p.r.SessionCache().InvalidateSessions(ctx, t.SessionID)
+	return nil
+}
+
+// createRefreshToken stores the HMAC of the refresh token, but leaves the plain token in place for the caller.
+func (p *Persister) createRefreshToken(ctx context.Context, tx *pop.Connection, t *session.RefreshToken) error {
+	token := t.Token
+	t.Token = p.hmacValue(ctx, token)
+	t.NID = p.NetworkID(ctx)
+	defer func() {
+		t.Token = token
+	}()
+
+	return sqlcon.HandleError(tx.Create(t))
+}
+
+func (p *Persister) GetRefreshToken(ctx context.Context, token string) (_ *session.RefreshToken, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetRefreshToken")
+	defer otelx.End(span, &err)
+
+	// Refresh tokens live as long as their session, so tokens created before the secrets were rotated must keep working.
+	secrets := p.r.Config().SecretsSession(ctx)
+	digests := make([]interface{}, 0, len(secrets))
+	for _, secret := range secrets {
+		digests = append(digests, p.hmacValueWithSecret(ctx, token, secret))
+	}
+
+	var t session.RefreshToken
+	if err := p.GetConnection(ctx).
+		Where("nid = ?", p.NetworkID(ctx)).
+		Where("token IN (?)", digests...).
+		First(&t); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &t, nil
+}
+
+func (p *Persister) RotateRefreshToken(ctx context.Context, usedID uuid.UUID, next *session.RefreshToken, sessionToken string, sessionTokenExpiresAt time.Time) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RotateRefreshToken")
+	defer otelx.End(span, &err)
+
+	now := time.Now().UTC()
+	if err := p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		//#nosec G201 -- TableName is static
+		count, err := tx.RawQuery(fmt.Sprintf(
+			"UPDATE %s SET used_at = ?, updated_at = ? WHERE id = ? AND nid = ? AND used_at IS NULL",
+			new(session.RefreshToken).TableName(ctx),
+		),
+			now,
+			now,
+			usedID,
+			p.NetworkID(ctx),
+		).ExecWithCount()
+		if err != nil {
+			return sqlcon.HandleError(err)
+		}
+		if count == 0 {
+			return errors.WithStack(sqlcon.ErrNoRows)
+		}
+
+		if err := p.createRefreshToken(ctx, tx, next); err != nil {
+			return err
+		}
+
+		//#nosec G201 -- TableName is static
+		return sqlcon.HandleError(tx.RawQuery(fmt.Sprintf(
+			"UPDATE %s SET token = ?, token_expires_at = ?, updated_at = ? WHERE id = ? AND nid = ?",
+			new(session.Session).TableName(ctx),
+		),
+			sessionToken,
+			sessionTokenExpiresAt.UTC(),
+			now,
+			next.SessionID,
+			p.NetworkID(ctx),
+		).Exec())
+	}); err != nil {
+		return err
+	}
+
+	p.r.SessionCache().InvalidateSessions(ctx, next.SessionID)
+	return nil
+}
+
+func (p *Persister) DeleteSessionRefreshTokens(ctx context.Context, sessionID uuid.UUID) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteSessionRefreshTokens")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	return sqlcon.HandleError(p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE session_id = ? AND nid = ?",
+		new(session.RefreshToken).TableName(ctx),
+	),
+		sessionID,
+		p.NetworkID(ctx),
+	).Exec())
+}
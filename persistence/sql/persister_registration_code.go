@@ -5,14 +5,16 @@ package sql
 
 import (
 	"context"
-	"time"
 
 	"github.com/go-faker/faker/v4/pkg/slice"
+	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/x"
 	"github.com/ory/x/sqlcon"
 )
 
@@ -20,7 +22,7 @@ func (p *Persister) CreateRegistrationCode(ctx context.Context, params *code.Cre
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateRegistrationCode")
 	defer span.End()
 
-	now := time.Now().UTC()
+	now := x.Now().UTC()
 	registrationCode := &code.RegistrationCode{
 		Address:     params.Address,
 		AddressType: params.AddressType,
@@ -39,21 +41,17 @@ func (p *Persister) CreateRegistrationCode(ctx context.Context, params *code.Cre
 	return registrationCode, nil
 }
 
-func (p *Persister) UseRegistrationCode(ctx context.Context, flowID uuid.UUID, userProvidedCode string, addresses ...string) (*code.RegistrationCode, error) {
+func (p *Persister) UseRegistrationCode(ctx context.Context, flowID uuid.UUID, userProvidedCode string, validate code.CodeValidator, addresses ...string) (*code.RegistrationCode, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UseRegistrationCode")
 	defer span.End()
 
-	codeRow, err := useOneTimeCode[code.RegistrationCode, *code.RegistrationCode](ctx, p, flowID, userProvidedCode, new(registration.Flow).TableName(ctx), "selfservice_registration_flow_id")
-	if err != nil {
-		return nil, err
-	}
-
-	// ensure that the identifiers extracted from the traits are contained in the registration code
-	if !slice.Contains(addresses, codeRow.Address) {
-		return nil, errors.WithStack(code.ErrCodeNotFound)
-	}
-
-	return codeRow, nil
+	return useOneTimeCode[code.RegistrationCode, *code.RegistrationCode](ctx, p, flowID, userProvidedCode, new(registration.Flow).TableName(ctx), "selfservice_registration_flow_id", func(ctx context.Context, _ *pop.Connection, c *code.RegistrationCode) error {
+		// ensure that the identifiers extracted from the traits are contained in the registration code
+		if !slice.Contains(addresses, c.Address) {
+			return errors.WithStack(code.ErrCodeNotFound)
+		}
+		return validateCode(ctx, validate, &code.CodeRequest{Flow: flow.RegistrationFlow, FlowID: flowID, Address: c.Address})
+	})
 }
 
 func (p *Persister) GetUsedRegistrationCode(ctx context.Context, flowID uuid.UUID) (*code.RegistrationCode, error) {
@@ -6,6 +6,8 @@ package sql
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gobuffalo/pop/v6"
@@ -14,8 +16,10 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
 	"github.com/ory/kratos/x/events"
 	"github.com/ory/x/otelx"
 	"github.com/ory/x/pagination/keysetpagination"
@@ -62,7 +66,43 @@ func (p *Persister) GetSession(ctx context.Context, sid uuid.UUID, expandables s
 	return &s, nil
 }
 
-func (p *Persister) ListSessions(ctx context.Context, active *bool, paginatorOpts []keysetpagination.Option, expandables session.Expandables) (_ []session.Session, _ int64, _ *keysetpagination.Paginator, err error) {
+// sessionFilterConditions returns the conditions and arguments which match the filter, except for its Active field.
+func sessionFilterConditions(conn *pop.Connection, f session.Filter) (conditions []string, args []interface{}) {
+	if !f.IdentityID.IsNil() {
+		conditions = append(conditions, "identity_id = ?")
+		args = append(args, f.IdentityID)
+	}
+	if f.AAL != "" {
+		conditions = append(conditions, "aal = ?")
+		args = append(args, f.AAL)
+	}
+	if !f.AuthenticatedAfter.IsZero() {
+		conditions = append(conditions, "authenticated_at >= ?")
+		args = append(args, f.AuthenticatedAfter.UTC())
+	}
+	if !f.AuthenticatedBefore.IsZero() {
+		conditions = append(conditions, "authenticated_at < ?")
+		args = append(args, f.AuthenticatedBefore.UTC())
+	}
+	if f.AuthenticationMethod != "" {
+		// The authentication methods are stored as JSON, which some databases reformat with a space after colons.
+		column := "authentication_methods"
+		switch conn.Dialect.Name() {
+		case "mysql":
+			column = "CAST(authentication_methods AS CHAR)"
+		case "postgres", "cockroach":
+			column = "CAST(authentication_methods AS TEXT)"
+		}
+		conditions = append(conditions, fmt.Sprintf("(%[1]s LIKE ? OR %[1]s LIKE ?)", column))
+		args = append(args,
+			fmt.Sprintf(`%%"method":"%s"%%`, f.AuthenticationMethod),
+			fmt.Sprintf(`%%"method": "%s"%%`, f.AuthenticationMethod),
+		)
+	}
+	return conditions, args
+}
+
+func (p *Persister) ListSessions(ctx context.Context, filter session.Filter, paginatorOpts []keysetpagination.Option, expandables session.Expandables) (_ []session.Session, _ int64, _ *keysetpagination.Paginator, err error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListSessions")
 	defer otelx.End(span, &err)
 
@@ -77,13 +117,17 @@ func (p *Persister) ListSessions(ctx context.Context, active *bool, paginatorOpt
 
 	if err := p.Transaction(ctx, func(ctx context.Context, c *pop.Connection) error {
 		q := c.Where("nid = ?", nid)
-		if active != nil {
+		if active := filter.Active; active != nil {
 			if *active {
-				q.Where("active = ? AND expires_at >= ?", *active, time.Now().UTC())
+				q.Where("active = ? AND expires_at >= ?", *active, x.Now().UTC())
 			} else {
-				q.Where("(active = ? OR expires_at < ?)", *active, time.Now().UTC())
+				q.Where("(active = ? OR expires_at < ?)", *active, x.Now().UTC())
 			}
 		}
+		conditions, args := sessionFilterConditions(c, filter)
+		if len(conditions) > 0 {
+			q.Where(strings.Join(conditions, " AND "), args...)
+		}
 
 		// Get the total count of matching items
 		total, err := q.Count(new(session.Session))
@@ -142,9 +186,9 @@ func (p *Persister) ListSessionsByIdentity(
 		}
 		if active != nil {
 			if *active {
-				q.Where("active = ? AND expires_at >= ?", *active, time.Now().UTC())
+				q.Where("active = ? AND expires_at >= ?", *active, x.Now().UTC())
 			} else {
-				q.Where("(active = ? OR expires_at < ?)", *active, time.Now().UTC())
+				q.Where("(active = ? OR expires_at < ?)", *active, x.Now().UTC())
 			}
 		}
 
@@ -173,6 +217,32 @@ func (p *Persister) ListSessionsByIdentity(
 	return s, t, nil
 }
 
+// SummarizeIdentitySessions returns the session summaries of the given identities.
+func (p *Persister) SummarizeIdentitySessions(ctx context.Context, ids ...uuid.UUID) (_ map[uuid.UUID]identity.SessionSummary, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.SummarizeIdentitySessions")
+	defer otelx.End(span, &err)
+
+	if len(ids) == 0 {
+		return map[uuid.UUID]identity.SessionSummary{}, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for k, id := range ids {
+		args[k] = id
+	}
+
+	var sessions []session.Session
+	if err := p.GetConnection(ctx).
+		Select("id", "identity_id", "active", "expires_at", "authenticated_at").
+		Where("nid = ?", p.NetworkID(ctx)).
+		Where("identity_id IN (?)", args...).
+		All(&sessions); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	return session.Summarize(sessions), nil
+}
+
 // UpsertSession creates a session if not found else updates.
 // This operation also inserts Session device records when a session is being created.
 // The update operation skips updating Session device records since only one record would need to be updated in this case.
@@ -182,23 +252,34 @@ func (p *Persister) UpsertSession(ctx context.Context, s *session.Session) (err
 
 	s.NID = p.NetworkID(ctx)
 
-	return errors.WithStack(p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
-		exists := false
+	var lifecycle []*session.Event
+	if err := p.Transaction(ctx, func(ctx context.Context, tx *pop.Connection) error {
+		lifecycle = nil
+
+		var previous *session.Session
 		if !s.ID.IsNil() {
-			var err error
-			exists, err = tx.Where("id = ? AND nid = ?", s.ID, s.NID).Exists(new(session.Session))
-			if err != nil {
-				return sqlcon.HandleError(err)
+			var found session.Session
+			if err := tx.Select("aal", "expires_at").Where("id = ? AND nid = ?", s.ID, s.NID).First(&found); err == nil {
+				previous = &found
+			} else if err := sqlcon.HandleError(err); !errors.Is(err, sqlcon.ErrNoRows) {
+				return err
 			}
 		}
 
-		if exists {
+		if previous != nil {
 			// This must not be eager or identities will be created / updated
 			// Only update session and not corresponding session device records
 			if err := tx.Update(s); err != nil {
 				return sqlcon.HandleError(err)
 			}
 			trace.SpanFromContext(ctx).AddEvent(events.NewSessionChanged(ctx, string(s.AuthenticatorAssuranceLevel), s.ID, s.IdentityID))
+
+			if previous.AuthenticatorAssuranceLevel != s.AuthenticatorAssuranceLevel {
+				lifecycle = append(lifecycle, session.NewSessionEvent(session.EventAALChanged, s))
+			}
+			if s.ExpiresAt.After(previous.ExpiresAt) {
+				lifecycle = append(lifecycle, session.NewSessionEvent(session.EventExtended, s))
+			}
 			return nil
 		}
 
@@ -225,8 +306,15 @@ func (p *Persister) UpsertSession(ctx context.Context, s *session.Session) (err
 		}
 
 		trace.SpanFromContext(ctx).AddEvent(events.NewSessionIssued(ctx, string(s.AuthenticatorAssuranceLevel), s.ID, s.IdentityID))
+		lifecycle = append(lifecycle, session.NewSessionEvent(session.EventIssued, s))
 		return nil
-	}))
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	p.r.SessionCache().InvalidateSessions(ctx, s.ID)
+	p.r.SessionEventEmitter().Emit(ctx, lifecycle...)
+	return nil
 }
 
 func (p *Persister) DeleteSession(ctx context.Context, sid uuid.UUID) (err error) {
@@ -234,6 +322,11 @@ func (p *Persister) DeleteSession(ctx context.Context, sid uuid.UUID) (err error
 	defer otelx.End(span, &err)
 
 	nid := p.NetworkID(ctx)
+	revoked, err := p.revocableSessions(ctx, "id = ? AND nid = ?", sid, nid)
+	if err != nil {
+		return err
+	}
+
 	//#nosec G201 -- TableName is static
 	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf("DELETE FROM %s WHERE id = ? AND nid = ?", new(session.Session).TableName(ctx)),
 		sid,
@@ -245,6 +338,8 @@ func (p *Persister) DeleteSession(ctx context.Context, sid uuid.UUID) (err error
 	if count == 0 {
 		return errors.WithStack(sqlcon.ErrNoRows)
 	}
+
+	p.sessionsRevoked(ctx, revoked)
 	return nil
 }
 
@@ -252,6 +347,11 @@ func (p *Persister) DeleteSessionsByIdentity(ctx context.Context, identityID uui
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteSessionsByIdentity")
 	defer otelx.End(span, &err)
 
+	revoked, err := p.revocableSessions(ctx, "identity_id = ? AND nid = ?", identityID, p.NetworkID(ctx))
+	if err != nil {
+		return err
+	}
+
 	//#nosec G201 -- TableName is static
 	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
 		"DELETE FROM %s WHERE identity_id = ? AND nid = ?",
@@ -266,6 +366,8 @@ func (p *Persister) DeleteSessionsByIdentity(ctx context.Context, identityID uui
 	if count == 0 {
 		return errors.WithStack(sqlcon.ErrNoRows)
 	}
+
+	p.sessionsRevoked(ctx, revoked)
 	return nil
 }
 
@@ -318,6 +420,11 @@ func (p *Persister) DeleteSessionByToken(ctx context.Context, token string) (err
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteSessionByToken")
 	defer otelx.End(span, &err)
 
+	revoked, err := p.revocableSessions(ctx, "token = ? AND nid = ?", token, p.NetworkID(ctx))
+	if err != nil {
+		return err
+	}
+
 	//#nosec G201 -- TableName is static
 	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
 		"DELETE FROM %s WHERE token = ? AND nid = ?",
@@ -332,6 +439,8 @@ func (p *Persister) DeleteSessionByToken(ctx context.Context, token string) (err
 	if count == 0 {
 		return errors.WithStack(sqlcon.ErrNoRows)
 	}
+
+	p.sessionsRevoked(ctx, revoked)
 	return nil
 }
 
@@ -339,6 +448,11 @@ func (p *Persister) RevokeSessionByToken(ctx context.Context, token string) (err
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeSessionByToken")
 	defer otelx.End(span, &err)
 
+	revoked, err := p.revocableSessions(ctx, "token = ? AND nid = ?", token, p.NetworkID(ctx))
+	if err != nil {
+		return err
+	}
+
 	//#nosec G201 -- TableName is static
 	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
 		"UPDATE %s SET active = false WHERE token = ? AND nid = ?",
@@ -353,6 +467,8 @@ func (p *Persister) RevokeSessionByToken(ctx context.Context, token string) (err
 	if count == 0 {
 		return errors.WithStack(sqlcon.ErrNoRows)
 	}
+
+	p.sessionsRevoked(ctx, revoked)
 	return nil
 }
 
@@ -361,6 +477,11 @@ func (p *Persister) RevokeSessionById(ctx context.Context, sID uuid.UUID) (err e
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeSessionById")
 	defer otelx.End(span, &err)
 
+	revoked, err := p.revocableSessions(ctx, "id = ? AND nid = ?", sID, p.NetworkID(ctx))
+	if err != nil {
+		return err
+	}
+
 	//#nosec G201 -- TableName is static
 	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
 		"UPDATE %s SET active = false WHERE id = ? AND nid = ?",
@@ -375,6 +496,32 @@ func (p *Persister) RevokeSessionById(ctx context.Context, sID uuid.UUID) (err e
 	if count == 0 {
 		return errors.WithStack(sqlcon.ErrNoRows)
 	}
+
+	p.sessionsRevoked(ctx, revoked)
+	return nil
+}
+
+// UpdateSessionLastActivity only updates the time the session was last used at, so that concurrent
+// requests using the same session do not overwrite each other.
+func (p *Persister) UpdateSessionLastActivity(ctx context.Context, sID uuid.UUID, at time.Time) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UpdateSessionLastActivity")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET last_activity_at = ? WHERE id = ? AND nid = ?",
+		new(session.Session).TableName(ctx),
+	),
+		at.UTC(),
+		sID,
+		p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
 	return nil
 }
 
@@ -384,6 +531,11 @@ func (p *Persister) RevokeSession(ctx context.Context, iID, sID uuid.UUID) (err
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeSession")
 	defer otelx.End(span, &err)
 
+	revoked, err := p.revocableSessions(ctx, "id = ? AND identity_id = ? AND nid = ?", sID, iID, p.NetworkID(ctx))
+	if err != nil {
+		return err
+	}
+
 	//#nosec G201 -- TableName is static
 	err = p.GetConnection(ctx).RawQuery(fmt.Sprintf(
 		"UPDATE %s SET active = false WHERE id = ? AND identity_id = ? AND nid = ?",
@@ -396,6 +548,8 @@ func (p *Persister) RevokeSession(ctx context.Context, iID, sID uuid.UUID) (err
 	if err != nil {
 		return sqlcon.HandleError(err)
 	}
+
+	p.sessionsRevoked(ctx, revoked)
 	return nil
 }
 
@@ -404,6 +558,11 @@ func (p *Persister) RevokeSessionsIdentityExcept(ctx context.Context, iID, sID u
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeSessionsIdentityExcept")
 	defer otelx.End(span, &err)
 
+	revoked, err := p.revocableSessions(ctx, "identity_id = ? AND id != ? AND nid = ?", iID, sID, p.NetworkID(ctx))
+	if err != nil {
+		return 0, err
+	}
+
 	//#nosec G201 -- TableName is static
 	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
 		"UPDATE %s SET active = false WHERE identity_id = ? AND id != ? AND nid = ?",
@@ -416,25 +575,134 @@ func (p *Persister) RevokeSessionsIdentityExcept(ctx context.Context, iID, sID u
 	if err != nil {
 		return 0, sqlcon.HandleError(err)
 	}
+
+	p.sessionsRevoked(ctx, revoked)
 	return count, nil
 }
 
-func (p *Persister) DeleteExpiredSessions(ctx context.Context, expiresAt time.Time, limit int) (err error) {
-	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteExpiredSessions")
+// RevokeSessions marks all active sessions which match the filter inactive.
+func (p *Persister) RevokeSessions(ctx context.Context, filter session.Filter) (res int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeSessions")
 	defer otelx.End(span, &err)
 
-	//#nosec G201 -- TableName is static
-	err = p.GetConnection(ctx).RawQuery(fmt.Sprintf(
-		"DELETE FROM %s WHERE id in (SELECT id FROM (SELECT id FROM %s c WHERE expires_at <= ? and nid = ? ORDER BY expires_at ASC LIMIT %d ) AS s )",
-		new(session.Session).TableName(ctx),
+	conn := p.GetConnection(ctx)
+	conditions, args := sessionFilterConditions(conn, filter)
+	conditions = append([]string{"nid = ?"}, conditions...)
+	args = append([]interface{}{p.NetworkID(ctx)}, args...)
+
+	revoked, err := p.revocableSessions(ctx, strings.Join(conditions, " AND "), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	conditions = append(conditions, "active = ?")
+	args = append(args, true)
+
+	//#nosec G201 -- TableName is static and the conditions only contain placeholders
+	count, err := conn.RawQuery(fmt.Sprintf(
+		"UPDATE %s SET active = false WHERE %s",
 		new(session.Session).TableName(ctx),
-		limit,
-	),
-		expiresAt,
-		p.NetworkID(ctx),
-	).Exec()
+		strings.Join(conditions, " AND "),
+	), args...).ExecWithCount()
 	if err != nil {
-		return sqlcon.HandleError(err)
+		return 0, sqlcon.HandleError(err)
 	}
-	return nil
+
+	p.sessionsRevoked(ctx, revoked)
+	return count, nil
+}
+
+// revocableSessions looks up the active sessions which match the conditions, so that their revocation can be
+// published as session events and removed from the session cache and Redis. The lookup is skipped if none of
+// them is enabled.
+func (p *Persister) revocableSessions(ctx context.Context, conditions string, args ...interface{}) ([]session.Session, error) {
+	if !p.r.SessionEventEmitter().Enabled(ctx) && !p.r.Config().SessionWhoAmICacheEnabled(ctx) &&
+		p.r.Config().SessionPersistenceMode(ctx) != config.SessionPersistenceModeWriteThrough {
+		return nil, nil
+	}
+
+	var found []session.Session
+	if err := p.GetConnection(ctx).
+		Select("id", "identity_id").
+		Where(conditions+" AND active = ?", append(slices.Clip(args), true)...).
+		All(&found); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return found, nil
+}
+
+// sessionsRevoked removes the sessions from the session cache and publishes one revocation event per identity.
+func (p *Persister) sessionsRevoked(ctx context.Context, revoked []session.Session) {
+	if len(revoked) == 0 {
+		return
+	}
+
+	var identities []uuid.UUID
+	sessionsByIdentity := make(map[uuid.UUID][]uuid.UUID)
+	ids := make([]uuid.UUID, 0, len(revoked))
+	for _, s := range revoked {
+		ids = append(ids, s.ID)
+		if _, ok := sessionsByIdentity[s.IdentityID]; !ok {
+			identities = append(identities, s.IdentityID)
+		}
+		sessionsByIdentity[s.IdentityID] = append(sessionsByIdentity[s.IdentityID], s.ID)
+	}
+
+	p.r.SessionCache().InvalidateSessions(ctx, ids...)
+
+	lifecycle := make([]*session.Event, 0, len(identities))
+	for _, iID := range identities {
+		lifecycle = append(lifecycle, session.NewEvent(session.EventRevoked, iID, sessionsByIdentity[iID]...))
+	}
+	p.r.SessionEventEmitter().Emit(ctx, lifecycle...)
+}
+
+func (p *Persister) DeleteExpiredSessions(ctx context.Context, expiresAt time.Time, limit int) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteExpiredSessions")
+	defer otelx.End(span, &err)
+
+	_, err = p.deleteExpiredRows(ctx, new(session.Session).TableName(ctx), expiresAt, limit)
+	return err
+}
+
+func (p *Persister) ListExpiredSessionIDs(ctx context.Context, expiresAt time.Time, limit int) (ids []uuid.UUID, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListExpiredSessionIDs")
+	defer otelx.End(span, &err)
+
+	var expired []session.Session
+	if err := p.GetConnection(ctx).Select("id").
+		Where("expires_at <= ? AND nid = ?", expiresAt, p.NetworkID(ctx)).
+		Order("expires_at ASC").
+		Limit(limit).
+		All(&expired); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+
+	ids = make([]uuid.UUID, len(expired))
+	for k, s := range expired {
+		ids[k] = s.ID
+	}
+	return ids, nil
+}
+
+func (p *Persister) DeleteExpiredSessionsByID(ctx context.Context, expiresAt time.Time, ids []uuid.UUID) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteExpiredSessionsByID")
+	defer otelx.End(span, &err)
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, len(ids)+2)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, expiresAt, p.NetworkID(ctx))
+
+	//#nosec G201 -- TableName is static and the placeholders are generated
+	return sqlcon.HandleError(p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE id IN (%s) AND expires_at <= ? AND nid = ?",
+		new(session.Session).TableName(ctx),
+		strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","),
+	), args...).Exec())
 }
@@ -0,0 +1,52 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/identity"
+)
+
+// The identity is part of the sessions returned by whoami, so changing it must remove its sessions from
+// the session cache.
+
+func (p *Persister) UpdateIdentity(ctx context.Context, i *identity.Identity) error {
+	if err := p.PrivilegedPool.UpdateIdentity(ctx, i); err != nil {
+		return err
+	}
+
+	p.r.SessionCache().InvalidateIdentity(ctx, i.ID)
+	return nil
+}
+
+func (p *Persister) UpdateIdentityIfUnmodifiedSince(ctx context.Context, i *identity.Identity, updatedAt time.Time) error {
+	if err := p.PrivilegedPool.UpdateIdentityIfUnmodifiedSince(ctx, i, updatedAt); err != nil {
+		return err
+	}
+
+	p.r.SessionCache().InvalidateIdentity(ctx, i.ID)
+	return nil
+}
+
+func (p *Persister) DeleteIdentity(ctx context.Context, id uuid.UUID) error {
+	if err := p.PrivilegedPool.DeleteIdentity(ctx, id); err != nil {
+		return err
+	}
+
+	p.r.SessionCache().InvalidateIdentity(ctx, id)
+	return nil
+}
+
+func (p *Persister) UpdateVerifiableAddress(ctx context.Context, address *identity.VerifiableAddress) error {
+	if err := p.PrivilegedPool.UpdateVerifiableAddress(ctx, address); err != nil {
+		return err
+	}
+
+	p.r.SessionCache().InvalidateIdentity(ctx, address.IdentityID)
+	return nil
+}
@@ -5,7 +5,6 @@ package sql
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/ory/kratos/identity"
@@ -59,18 +58,6 @@ func (p *Persister) UpdateSettingsFlow(ctx context.Context, r *settings.Flow) er
 }
 
 func (p *Persister) DeleteExpiredSettingsFlows(ctx context.Context, expiresAt time.Time, limit int) error {
-	//#nosec G201 -- TableName is static
-	err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
-		"DELETE FROM %s WHERE id in (SELECT id FROM (SELECT id FROM %s c WHERE expires_at <= ? and nid = ? ORDER BY expires_at ASC LIMIT %d ) AS s )",
-		new(settings.Flow).TableName(ctx),
-		new(settings.Flow).TableName(ctx),
-		limit,
-	),
-		expiresAt,
-		p.NetworkID(ctx),
-	).Exec()
-	if err != nil {
-		return sqlcon.HandleError(err)
-	}
-	return nil
+	_, err := p.deleteExpiredRows(ctx, new(settings.Flow).TableName(ctx), expiresAt, limit)
+	return err
 }
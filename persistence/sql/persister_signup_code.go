@@ -0,0 +1,104 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/selfservice/signupcode"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ signupcode.Persister = new(Persister)
+
+func (p *Persister) CreateSignupCode(ctx context.Context, c *signupcode.SignupCode) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateSignupCode")
+	defer otelx.End(span, &err)
+
+	c.NID = p.NetworkID(ctx)
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(c))
+}
+
+func (p *Persister) GetSignupCode(ctx context.Context, id uuid.UUID) (_ *signupcode.SignupCode, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetSignupCode")
+	defer otelx.End(span, &err)
+
+	var c signupcode.SignupCode
+	if err := p.GetConnection(ctx).Where("id = ? AND nid = ?", id, p.NetworkID(ctx)).First(&c); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &c, nil
+}
+
+func (p *Persister) GetSignupCodeByCode(ctx context.Context, code string) (_ *signupcode.SignupCode, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.GetSignupCodeByCode")
+	defer otelx.End(span, &err)
+
+	var c signupcode.SignupCode
+	if err := p.GetConnection(ctx).Where("code = ? AND nid = ?", code, p.NetworkID(ctx)).First(&c); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return &c, nil
+}
+
+func (p *Persister) ListSignupCodes(ctx context.Context) (_ []signupcode.SignupCode, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.ListSignupCodes")
+	defer otelx.End(span, &err)
+
+	codes := make([]signupcode.SignupCode, 0)
+	if err := p.GetConnection(ctx).
+		Where("nid = ?", p.NetworkID(ctx)).
+		Order("code ASC").
+		All(&codes); err != nil {
+		return nil, sqlcon.HandleError(err)
+	}
+	return codes, nil
+}
+
+func (p *Persister) UseSignupCode(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UseSignupCode")
+	defer otelx.End(span, &err)
+
+	// The condition is part of the update so that concurrent registrations can not exceed the maximum uses.
+	//#nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET uses = uses + 1, updated_at = ? WHERE id = ? AND nid = ? AND (max_uses = 0 OR uses < max_uses)",
+		new(signupcode.SignupCode).TableName(ctx),
+	),
+		time.Now().UTC(),
+		id,
+		p.NetworkID(ctx),
+	).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
+
+func (p *Persister) DeleteSignupCode(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.DeleteSignupCode")
+	defer otelx.End(span, &err)
+
+	//#nosec G201 -- TableName is static
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"DELETE FROM %s WHERE id = ? AND nid = ?",
+		new(signupcode.SignupCode).TableName(ctx),
+	), id, p.NetworkID(ctx)).ExecWithCount()
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	if count == 0 {
+		return errors.WithStack(sqlcon.ErrNoRows)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/persistence"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/recovery"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/session"
+)
+
+var _ persistence.TableStatsProvider = new(Persister)
+
+type tableNamer interface {
+	TableName(ctx context.Context) string
+}
+
+// ownedTables returns the tables which hold the flows, sessions, codes, courier messages and identities.
+func ownedTables() []tableNamer {
+	return []tableNamer{
+		new(login.Flow),
+		new(registration.Flow),
+		new(settings.Flow),
+		new(recovery.Flow),
+		new(verification.Flow),
+		new(session.Session),
+		new(code.LoginCode),
+		new(code.RegistrationCode),
+		new(code.RecoveryCode),
+		new(code.VerificationCode),
+		new(courier.Message),
+		new(identity.Identity),
+	}
+}
+
+// TableStats returns the row counts of the flow, session, code, courier message and identity tables
+// for the current network, together with the table sizes if the database reports them.
+func (p *Persister) TableStats(ctx context.Context) (_ []persistence.TableStats, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.TableStats")
+	defer otelx.End(span, &err)
+
+	tables := ownedTables()
+
+	conn := p.GetConnection(ctx)
+	stats := make([]persistence.TableStats, 0, len(tables))
+	for _, t := range tables {
+		name := t.TableName(ctx)
+		s := persistence.TableStats{Table: name, SizeBytes: -1}
+
+		if err := conn.RawQuery(
+			fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE nid = ?", conn.Dialect.Quote(name)),
+			p.NetworkID(ctx),
+		).First(&s.Rows); err != nil {
+			return nil, sqlcon.HandleError(err)
+		}
+
+		if s.SizeBytes, err = p.tableSize(ctx, name); err != nil {
+			return nil, err
+		}
+
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
+
+// tableSize returns the approximate size of the table in bytes as estimated by the database, or -1 if the
+// dialect does not expose it.
+func (p *Persister) tableSize(ctx context.Context, table string) (int64, error) {
+	conn := p.GetConnection(ctx)
+
+	var query string
+	switch conn.Dialect.Name() {
+	case "postgres":
+		query = "SELECT pg_total_relation_size(?::regclass)"
+	case "mysql":
+		query = "SELECT COALESCE(data_length + index_length, 0) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?"
+	default:
+		return -1, nil
+	}
+
+	var size int64
+	if err := conn.RawQuery(query, table).First(&size); err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+	return size, nil
+}
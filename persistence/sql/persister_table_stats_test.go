@@ -0,0 +1,66 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/persistence"
+)
+
+func TestPersister_TableStats(t *testing.T) {
+	_, reg := internal.NewFastRegistryWithMocks(t)
+	p := reg.Persister()
+	ctx := context.Background()
+
+	i := identity.NewIdentity("")
+	i.Traits = identity.Traits("{}")
+	require.NoError(t, p.CreateIdentity(ctx, i))
+
+	t.Run("case=counts rows of the current network", func(t *testing.T) {
+		stats, err := p.TableStats(ctx)
+		require.NoError(t, err)
+
+		byTable := make(map[string]persistence.TableStats, len(stats))
+		for _, s := range stats {
+			byTable[s.Table] = s
+		}
+
+		for _, table := range []string{
+			"selfservice_login_flows",
+			"sessions",
+			"identity_recovery_codes",
+			"courier_messages",
+			"identities",
+		} {
+			assert.Contains(t, byTable, table)
+		}
+		assert.EqualValues(t, 1, byTable["identities"].Rows)
+		assert.EqualValues(t, -1, byTable["identities"].SizeBytes, "SQLite does not report table sizes")
+	})
+
+	t.Run("case=collector exports the last refresh", func(t *testing.T) {
+		c := persistence.NewTableMetricsCollector(reg)
+		assert.Equal(t, 0, testutil.CollectAndCount(c))
+
+		require.NoError(t, c.Refresh(ctx))
+		stats, err := p.TableStats(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, len(stats), testutil.CollectAndCount(c, "kratos_database_table_rows"))
+		assert.Equal(t, 0, testutil.CollectAndCount(c, "kratos_database_table_size_bytes"))
+	})
+
+	t.Run("case=fails if the database is unavailable", func(t *testing.T) {
+		p.GetConnection(ctx).Close()
+		_, err := p.TableStats(ctx)
+		assert.Error(t, err)
+	})
+}
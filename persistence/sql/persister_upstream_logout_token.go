@@ -0,0 +1,22 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+
+	"github.com/ory/kratos/session"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ session.UpstreamLogoutTokenPersister = new(Persister)
+
+func (p *Persister) CreateUpstreamLogoutToken(ctx context.Context, t *session.UpstreamLogoutToken) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateUpstreamLogoutToken")
+	defer otelx.End(span, &err)
+
+	t.NID = p.NetworkID(ctx)
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(t))
+}
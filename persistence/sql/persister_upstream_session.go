@@ -0,0 +1,70 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/session"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+var _ session.UpstreamSessionPersister = new(Persister)
+
+func (p *Persister) CreateUpstreamSession(ctx context.Context, s *session.UpstreamSession) (err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateUpstreamSession")
+	defer otelx.End(span, &err)
+
+	s.NID = p.NetworkID(ctx)
+	return sqlcon.HandleError(p.GetConnection(ctx).Create(s))
+}
+
+func (p *Persister) RevokeSessionsByUpstreamSession(ctx context.Context, provider, subject, upstreamSessionID string) (_ int, err error) {
+	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.RevokeSessionsByUpstreamSession")
+	defer otelx.End(span, &err)
+
+	if subject == "" && upstreamSessionID == "" {
+		return 0, errors.WithStack(herodot.ErrBadRequest.WithReason("Either the subject or the upstream session ID must be set."))
+	}
+
+	where := "nid = ? AND provider = ?"
+	args := []interface{}{p.NetworkID(ctx), p.NetworkID(ctx), provider}
+	if subject != "" {
+		where += " AND subject = ?"
+		args = append(args, subject)
+	}
+	if upstreamSessionID != "" {
+		where += " AND upstream_session_id = ?"
+		args = append(args, upstreamSessionID)
+	}
+
+	//#nosec G201 -- TableName is static and the conditions are constant
+	revoked, err := p.revocableSessions(ctx, fmt.Sprintf(
+		"nid = ? AND id IN (SELECT session_id FROM %s WHERE %s)",
+		new(session.UpstreamSession).TableName(ctx),
+		where,
+	), args...)
+	if err != nil {
+		return 0, err
+	}
+
+	//#nosec G201 -- TableName is static and the conditions are constant
+	count, err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
+		"UPDATE %s SET active = false WHERE nid = ? AND active = true AND id IN (SELECT session_id FROM %s WHERE %s)",
+		new(session.Session).TableName(ctx),
+		new(session.UpstreamSession).TableName(ctx),
+		where,
+	), args...).ExecWithCount()
+	if err != nil {
+		return 0, sqlcon.HandleError(err)
+	}
+
+	p.sessionsRevoked(ctx, revoked)
+	return count, nil
+}
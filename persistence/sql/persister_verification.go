@@ -119,18 +119,6 @@ func (p *Persister) DeleteVerificationToken(ctx context.Context, token string) e
 }
 
 func (p *Persister) DeleteExpiredVerificationFlows(ctx context.Context, expiresAt time.Time, limit int) error {
-	//#nosec G201 -- TableName is static
-	err := p.GetConnection(ctx).RawQuery(fmt.Sprintf(
-		"DELETE FROM %s WHERE id in (SELECT id FROM (SELECT id FROM %s c WHERE expires_at <= ? and nid = ? ORDER BY expires_at ASC LIMIT %d ) AS s )",
-		new(verification.Flow).TableName(ctx),
-		new(verification.Flow).TableName(ctx),
-		limit,
-	),
-		expiresAt,
-		p.NetworkID(ctx),
-	).Exec()
-	if err != nil {
-		return sqlcon.HandleError(err)
-	}
-	return nil
+	_, err := p.deleteExpiredRows(ctx, new(verification.Flow).TableName(ctx), expiresAt, limit)
+	return err
 }
@@ -5,15 +5,17 @@ package sql
 
 import (
 	"context"
-	"time"
 
+	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 
 	"github.com/ory/herodot"
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/verification"
 	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/x"
 	"github.com/ory/x/sqlcon"
 )
 
@@ -21,7 +23,7 @@ func (p *Persister) CreateVerificationCode(ctx context.Context, params *code.Cre
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.CreateVerificationCode")
 	defer span.End()
 
-	now := time.Now().UTC()
+	now := x.Now().UTC()
 	verificationCode := &code.VerificationCode{
 		ID:        uuid.Nil,
 		CodeHMAC:  p.hmacValue(ctx, params.RawCode),
@@ -50,23 +52,20 @@ func (p *Persister) CreateVerificationCode(ctx context.Context, params *code.Cre
 	return verificationCode, nil
 }
 
-func (p *Persister) UseVerificationCode(ctx context.Context, flowID uuid.UUID, userProvidedCode string) (*code.VerificationCode, error) {
+func (p *Persister) UseVerificationCode(ctx context.Context, flowID uuid.UUID, userProvidedCode string, validate code.CodeValidator) (*code.VerificationCode, error) {
 	ctx, span := p.r.Tracer(ctx).Tracer().Start(ctx, "persistence.sql.UseVerificationCode")
 	defer span.End()
 
-	codeRow, err := useOneTimeCode[code.VerificationCode, *code.VerificationCode](ctx, p, flowID, userProvidedCode, new(verification.Flow).TableName(ctx), "selfservice_verification_flow_id")
-	if err != nil {
-		return nil, err
-	}
-
-	var va identity.VerifiableAddress
-	if err := p.Connection(ctx).Where("id = ? AND nid = ?", codeRow.VerifiableAddressID, p.NetworkID(ctx)).First(&va); err != nil {
-		// This should fail on not found errors too, because the verifiable address must exist for the flow to work.
-		return nil, sqlcon.HandleError(err)
-	}
-	codeRow.VerifiableAddress = &va
+	return useOneTimeCode[code.VerificationCode, *code.VerificationCode](ctx, p, flowID, userProvidedCode, new(verification.Flow).TableName(ctx), "selfservice_verification_flow_id", func(ctx context.Context, tx *pop.Connection, c *code.VerificationCode) error {
+		var va identity.VerifiableAddress
+		if err := tx.Where("id = ? AND nid = ?", c.VerifiableAddressID, p.NetworkID(ctx)).First(&va); err != nil {
+			// This should fail on not found errors too, because the verifiable address must exist for the flow to work.
+			return sqlcon.HandleError(err)
+		}
+		c.VerifiableAddress = &va
 
-	return codeRow, nil
+		return validateCode(ctx, validate, &code.CodeRequest{Flow: flow.VerificationFlow, FlowID: flowID, IdentityID: va.IdentityID, Address: va.Value})
+	})
 }
 
 func (p *Persister) DeleteVerificationCodesOfFlow(ctx context.Context, fID uuid.UUID) error {
@@ -0,0 +1,115 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package persistence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+// TableStats describes the size of a table owned by Kratos.
+type TableStats struct {
+	// Table is the name of the table.
+	Table string
+
+	// Rows is the number of rows which belong to the current network.
+	Rows int64
+
+	// SizeBytes is the approximate size of the whole table including its indices, or -1 if the
+	// database does not report table sizes.
+	SizeBytes int64
+}
+
+type TableStatsProvider interface {
+	TableStats(ctx context.Context) ([]TableStats, error)
+}
+
+type (
+	tableMetricsDependencies interface {
+		config.Provider
+		x.LoggingProvider
+		Provider
+	}
+
+	// TableMetricsCollector exports the row counts and sizes of the tables owned by Kratos.
+	//
+	// Counting rows can be expensive on large tables, so the statistics are refreshed periodically by Watch
+	// and Collect reports the last result.
+	TableMetricsCollector struct {
+		d     tableMetricsDependencies
+		rows  *prometheus.Desc
+		size  *prometheus.Desc
+		mu    sync.RWMutex
+		stats []TableStats
+	}
+)
+
+var _ prometheus.Collector = new(TableMetricsCollector)
+
+func NewTableMetricsCollector(d tableMetricsDependencies) *TableMetricsCollector {
+	return &TableMetricsCollector{
+		d: d,
+		rows: prometheus.NewDesc(
+			"kratos_database_table_rows",
+			"Number of rows in a table owned by Kratos.",
+			[]string{"table"}, nil,
+		),
+		size: prometheus.NewDesc(
+			"kratos_database_table_size_bytes",
+			"Approximate size of a table owned by Kratos including its indices.",
+			[]string{"table"}, nil,
+		),
+	}
+}
+
+func (c *TableMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.rows
+	ch <- c.size
+}
+
+func (c *TableMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, s := range c.stats {
+		ch <- prometheus.MustNewConstMetric(c.rows, prometheus.GaugeValue, float64(s.Rows), s.Table)
+		if s.SizeBytes >= 0 {
+			ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(s.SizeBytes), s.Table)
+		}
+	}
+}
+
+// Refresh queries the current table statistics.
+func (c *TableMetricsCollector) Refresh(ctx context.Context) error {
+	stats, err := c.d.Persister().TableStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats = stats
+	return nil
+}
+
+// Watch refreshes the table statistics in the configured interval until the context is canceled.
+func (c *TableMetricsCollector) Watch(ctx context.Context) {
+	for {
+		if err := c.Refresh(ctx); err != nil {
+			c.d.Logger().WithError(err).Warn("Unable to collect database table statistics.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.d.Config().DatabaseTableMetricsInterval(ctx)):
+		}
+	}
+}
@@ -5,6 +5,7 @@ package schema
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -166,6 +167,18 @@ func NewDuplicateCredentialsError(err error) error {
 	})
 }
 
+// NewDuplicateCredentialsErrorWithContinueWith returns the error of NewDuplicateCredentialsError whose message
+// contains continue_with items, for example a login flow for the account which exists already.
+func NewDuplicateCredentialsErrorWithContinueWith(err error, continueWith ...any) error {
+	err = NewDuplicateCredentialsError(err)
+	if ve := new(ValidationError); errors.As(err, &ve) {
+		for k := range ve.Messages {
+			text.WithContinueWith(&ve.Messages[k], continueWith...)
+		}
+	}
+	return err
+}
+
 func NewNoLoginStrategyResponsible() error {
 	return errors.WithStack(&ValidationError{
 		ValidationError: &jsonschema.ValidationError{
@@ -226,6 +239,16 @@ func NewAddressNotVerifiedError() error {
 	})
 }
 
+func NewAddressNotVerifiedErrorWithContinueWith(continueWith any) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `account address not yet verified`,
+			InstancePtr: "#/",
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationAddressNotVerifiedWithContinueWith(continueWith)),
+	})
+}
+
 func NewNoTOTPDeviceRegistered() error {
 	return errors.WithStack(&ValidationError{
 		ValidationError: &jsonschema.ValidationError{
@@ -256,6 +279,138 @@ func NewNoWebAuthnRegistered() error {
 	})
 }
 
+func NewNoPushDeviceRegistered() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `you have no device set up for push approvals`,
+			InstancePtr: "#/",
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationNoPushDevice()),
+	})
+}
+
+func NewPushApprovalDeniedError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `the approval request was denied`,
+			InstancePtr: "#/",
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationPushDenied()),
+	})
+}
+
+func NewPushApprovalExpiredError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `the approval request expired`,
+			InstancePtr: "#/",
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationPushExpired()),
+	})
+}
+
+func NewExternalMFADeniedError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `the sign-in was denied by the MFA provider`,
+			InstancePtr: "#/",
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationExternalMFADenied()),
+	})
+}
+
+func NewExternalMFAExpiredError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `the sign-in request at the MFA provider expired`,
+			InstancePtr: "#/",
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationExternalMFAExpired()),
+	})
+}
+
+func NewInvalidPhoneNumberError(instancePtr string) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     `the phone number is invalid`,
+			InstancePtr: instancePtr,
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationInvalidPhoneNumber()),
+	})
+}
+
+func NewEmailDomainNotAllowedError(instancePtr, domain string) error {
+	t := text.NewErrorValidationEmailDomainNotAllowed(domain)
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf("the email domain %q is not allowed", domain),
+			InstancePtr: instancePtr,
+		},
+		Messages: new(text.Messages).Add(t),
+	})
+}
+
+func NewDisposableEmailError(instancePtr, domain string) error {
+	t := text.NewErrorValidationDisposableEmail(domain)
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf("the email domain %q belongs to a disposable email provider", domain),
+			InstancePtr: instancePtr,
+		},
+		Messages: new(text.Messages).Add(t),
+	})
+}
+
+func NewConsentRequiredError(instancePtr, id string) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf("the document %q must be accepted", id),
+			InstancePtr: instancePtr,
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationConsentRequired(id)),
+	})
+}
+
+func NewSignupCodeInvalidError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     "the sign-up code is invalid",
+			InstancePtr: "#/signup_code",
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationRegistrationSignupCodeInvalid()),
+	})
+}
+
+func NewSignupCodeRequiredError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     "a sign-up code is required",
+			InstancePtr: "#/signup_code",
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationRegistrationSignupCodeRequired()),
+	})
+}
+
+func NewLoginLockedError(lockedUntil time.Time) error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     fmt.Sprintf("too many failed sign in attempts, the sign in is locked until %s", lockedUntil.UTC().Format(time.RFC3339)),
+			InstancePtr: "#/",
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationLoginLocked(lockedUntil)),
+	})
+}
+
+func NewLoginRiskDeniedError() error {
+	return errors.WithStack(&ValidationError{
+		ValidationError: &jsonschema.ValidationError{
+			Message:     "the sign in was blocked by the risk assessment",
+			InstancePtr: "#/",
+		},
+		Messages: new(text.Messages).Add(text.NewErrorValidationLoginRiskDenied()),
+	})
+}
+
 func NewHookValidationError(instancePtr, message string, messages text.Messages) *ValidationError {
 	return &ValidationError{
 		ValidationError: &jsonschema.ValidationError{
@@ -12,6 +12,7 @@ import (
 
 	"github.com/ory/jsonschema/v3"
 	"github.com/ory/kratos/embedx"
+	"github.com/ory/x/jsonschemax"
 )
 
 const (
@@ -41,6 +42,15 @@ type (
 		Recovery struct {
 			Via string `json:"via"`
 		} `json:"recovery"`
+		Consent      ExtensionConsentConfig `json:"consent"`
+		Locale       bool                   `json:"locale"`
+		Registration struct {
+			Step int `json:"step"`
+		} `json:"registration"`
+		Search struct {
+			Indexed  bool `json:"indexed"`
+			Contains bool `json:"contains"`
+		} `json:"search"`
 		Mappings struct {
 			Identity struct {
 				Traits []struct {
@@ -50,6 +60,14 @@ type (
 		} `json:"mappings"`
 	}
 
+	// ExtensionConsentConfig marks a boolean trait as a consent, for example to the terms of
+	// service, which is recorded when the identity registers with the trait set to true.
+	ExtensionConsentConfig struct {
+		Name     string `json:"name"`
+		Version  string `json:"version"`
+		Optional bool   `json:"optional"`
+	}
+
 	Extension interface {
 		Run(ctx jsonschema.ValidationContext, config ExtensionConfig, value interface{}) error
 		Finish() error
@@ -64,6 +82,11 @@ type (
 	}
 )
 
+// EnhancePath exposes the extension config of a schema path in the path's custom properties.
+func (e *ExtensionConfig) EnhancePath(jsonschemax.Path) map[string]interface{} {
+	return map[string]interface{}{extensionName: e}
+}
+
 func NewExtensionRunner(ctx context.Context, runners ...Extension) (*ExtensionRunner, error) {
 	var err error
 	r := new(ExtensionRunner)
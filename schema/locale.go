@@ -0,0 +1,39 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/jsonschemax"
+)
+
+// LocaleTrait returns the path of the trait annotated with `"ory.sh/kratos": {"locale": true}` without the
+// `traits.` prefix (e.g. `language`), or an empty string if the schema at the given URL does not annotate one.
+func LocaleTrait(ctx context.Context, schemaURL string) (string, error) {
+	runner, err := NewExtensionRunner(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c := jsonschema.NewCompiler()
+	runner.Register(c)
+
+	paths, err := jsonschemax.ListPaths(ctx, schemaURL, c)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	for _, p := range paths {
+		if e, ok := p.CustomProperties[extensionName].(*ExtensionConfig); ok && e.Locale && strings.HasPrefix(p.Name, "traits.") {
+			return strings.TrimPrefix(p.Name, "traits."), nil
+		}
+	}
+
+	return "", nil
+}
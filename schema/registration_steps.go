@@ -0,0 +1,156 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/jsonschemax"
+)
+
+type registrationStepContextKey struct{}
+
+type registrationStep struct {
+	steps   *RegistrationSteps
+	current int
+}
+
+// RegistrationSteps holds the registration step of each path of an identity schema. Paths are
+// annotated using `"ory.sh/kratos": {"registration": {"step": 2}}`. Paths without an annotation
+// inherit the step of their parent and default to the first step.
+type RegistrationSteps struct {
+	steps map[string]int
+	last  int
+}
+
+// NewRegistrationSteps lists the registration steps of the schema at the given URL.
+func NewRegistrationSteps(ctx context.Context, schemaURL string) (*RegistrationSteps, error) {
+	runner, err := NewExtensionRunner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := jsonschema.NewCompiler()
+	runner.Register(c)
+
+	paths, err := jsonschemax.ListPaths(ctx, schemaURL, c)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	s := &RegistrationSteps{steps: make(map[string]int), last: 1}
+	for _, p := range paths {
+		e, ok := p.CustomProperties[extensionName].(*ExtensionConfig)
+		if !ok || e.Registration.Step < 1 {
+			continue
+		}
+
+		s.steps[p.Name] = e.Registration.Step
+		if e.Registration.Step > s.last {
+			s.last = e.Registration.Step
+		}
+	}
+
+	return s, nil
+}
+
+// Last returns the last registration step. Schemas without annotations have a single step.
+func (s *RegistrationSteps) Last() int {
+	return s.last
+}
+
+// Step returns the registration step in which the value at the path (e.g. `traits.email`) is collected.
+func (s *RegistrationSteps) Step(path string) int {
+	for {
+		if step, ok := s.steps[path]; ok {
+			return step
+		}
+
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			return 1
+		}
+		path = path[:i]
+	}
+}
+
+// FilterErrors drops the validation errors of traits whose registration step is not kept. Errors of
+// other values than traits are always kept.
+func (s *RegistrationSteps) FilterErrors(err error, keep func(step int) bool) error {
+	e := new(jsonschema.ValidationError)
+	if !errors.As(err, &e) {
+		return err
+	}
+
+	if e = filterValidationError(e, func(pointer string) bool {
+		path, err := jsonschemax.JSONPointerToDotNotation(pointer)
+		return err != nil || !strings.HasPrefix(path, "traits.") || keep(s.Step(path))
+	}); e == nil {
+		return nil
+	}
+
+	return errors.WithStack(e)
+}
+
+// ContextWithRegistrationStep returns a context in which the validation of identities and registration
+// payloads only reports errors of the traits which are collected in the given registration step.
+func ContextWithRegistrationStep(ctx context.Context, steps *RegistrationSteps, current int) context.Context {
+	return context.WithValue(ctx, registrationStepContextKey{}, &registrationStep{steps: steps, current: current})
+}
+
+// FilterRegistrationStepErrors drops the validation errors of traits which are not collected in the
+// registration step of the context. Without a registration step, the error is returned as is.
+func FilterRegistrationStepErrors(ctx context.Context, err error) error {
+	s, ok := ctx.Value(registrationStepContextKey{}).(*registrationStep)
+	if !ok || err == nil {
+		return err
+	}
+
+	return s.steps.FilterErrors(err, func(step int) bool {
+		return step == s.current
+	})
+}
+
+func filterValidationError(e *jsonschema.ValidationError, keep func(pointer string) bool) *jsonschema.ValidationError {
+	if ctx, ok := e.Context.(*jsonschema.ValidationErrorContextRequired); ok {
+		var missing []string
+		for _, pointer := range ctx.Missing {
+			if keep(pointer) {
+				missing = append(missing, pointer)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+
+		filtered := *e
+		filtered.Context = &jsonschema.ValidationErrorContextRequired{Missing: missing}
+		return &filtered
+	}
+
+	if len(e.Causes) == 0 {
+		if keep(e.InstancePtr) {
+			return e
+		}
+		return nil
+	}
+
+	var causes []*jsonschema.ValidationError
+	for _, cause := range e.Causes {
+		if c := filterValidationError(cause, keep); c != nil {
+			causes = append(causes, c)
+		}
+	}
+	if len(causes) == 0 {
+		return nil
+	}
+
+	filtered := *e
+	filtered.Causes = causes
+	return &filtered
+}
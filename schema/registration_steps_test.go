@@ -0,0 +1,56 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistrationSteps(t *testing.T) {
+	t.Run("case=single step without annotations", func(t *testing.T) {
+		s, err := NewRegistrationSteps(ctx, "file://./stub/extension/schema.json")
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, s.Last())
+		assert.Equal(t, 1, s.Step("email"))
+	})
+
+	t.Run("case=annotated steps", func(t *testing.T) {
+		s, err := NewRegistrationSteps(ctx, "file://./stub/registration/steps.schema.json")
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, s.Last())
+		assert.Equal(t, 1, s.Step("traits.email"))
+		assert.Equal(t, 2, s.Step("traits.name"))
+		assert.Equal(t, 2, s.Step("traits.name.first"), "inherits the step of the parent")
+		assert.Equal(t, 3, s.Step("traits.company"))
+		assert.Equal(t, 1, s.Step("traits.unknown"))
+	})
+
+	t.Run("case=filters the validation errors of other steps", func(t *testing.T) {
+		s, err := NewRegistrationSteps(ctx, "file://./stub/registration/steps.schema.json")
+		require.NoError(t, err)
+
+		err = NewValidator().Validate(ctx, "file://./stub/registration/steps.schema.json", json.RawMessage(`{"traits":{}}`))
+		require.Error(t, err)
+
+		assert.NoError(t, s.FilterErrors(err, func(step int) bool { return step == 2 }))
+		filtered := s.FilterErrors(err, func(step int) bool { return step <= 3 })
+		require.Error(t, filtered)
+		assert.Contains(t, filtered.Error(), "email")
+		assert.Contains(t, filtered.Error(), "company")
+
+		filtered = FilterRegistrationStepErrors(ContextWithRegistrationStep(ctx, s, 3), err)
+		require.Error(t, filtered)
+		assert.NotContains(t, filtered.Error(), "email")
+		assert.Contains(t, filtered.Error(), "company")
+
+		assert.Equal(t, err, FilterRegistrationStepErrors(context.Background(), err))
+	})
+}
@@ -0,0 +1,99 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/x/jsonschemax"
+)
+
+// SearchValueMaxLength is the maximum length of a searchable trait value. Longer values are not indexed.
+const SearchValueMaxLength = 255
+
+type (
+	// SearchableTraits holds the searchable traits of an identity schema by their name, which is the
+	// path of the trait without the `traits.` prefix and without array indices (e.g. `emails` or
+	// `address.city`). Traits are annotated using `"ory.sh/kratos": {"search": {"indexed": true}}`.
+	SearchableTraits map[string]SearchableTrait
+
+	SearchableTrait struct {
+		// Contains is true if the trait may be searched for values containing a term. Such searches
+		// can not fully use the index and must therefore be enabled using `"contains": true`.
+		Contains bool
+
+		path string
+	}
+)
+
+// NewSearchableTraits lists the searchable traits of the schema at the given URL.
+func NewSearchableTraits(ctx context.Context, schemaURL string) (SearchableTraits, error) {
+	runner, err := NewExtensionRunner(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := jsonschema.NewCompiler()
+	runner.Register(c)
+
+	paths, err := jsonschemax.ListPathsWithArraysIncluded(ctx, schemaURL, c)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	s := make(SearchableTraits)
+	for _, p := range paths {
+		e, ok := p.CustomProperties[extensionName].(*ExtensionConfig)
+		if !ok || !e.Search.Indexed || !strings.HasPrefix(p.Name, "traits.") {
+			continue
+		}
+
+		// gjson returns all elements of an array at `a.#.b`, and the array itself at `a`.
+		path := strings.TrimSuffix(strings.TrimPrefix(p.Name, "traits."), ".#")
+		name := strings.ReplaceAll(path, ".#", "")
+		s[name] = SearchableTrait{Contains: e.Search.Contains || s[name].Contains, path: path}
+	}
+
+	return s, nil
+}
+
+// Values returns the normalized values of the searchable traits.
+func (s SearchableTraits) Values(traits []byte) map[string][]string {
+	values := make(map[string][]string, len(s))
+	for name, t := range s {
+		var collect func(r gjson.Result)
+		collect = func(r gjson.Result) {
+			switch {
+			case r.IsArray():
+				r.ForEach(func(_, v gjson.Result) bool {
+					collect(v)
+					return true
+				})
+			case r.Type == gjson.String, r.Type == gjson.Number, r.Type == gjson.True, r.Type == gjson.False:
+				v := NormalizeSearchValue(r.String())
+				if v == "" || utf8.RuneCountInString(v) > SearchValueMaxLength {
+					return
+				}
+				values[name] = append(values[name], v)
+			}
+		}
+		collect(gjson.GetBytes(traits, t.path))
+		if len(values[name]) > 0 {
+			values[name] = lo.Uniq(values[name])
+		}
+	}
+	return values
+}
+
+// NormalizeSearchValue normalizes trait values and search terms so that searches are case-insensitive.
+func NormalizeSearchValue(v string) string {
+	return strings.ToLower(strings.TrimSpace(v))
+}
@@ -0,0 +1,50 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchableTraits(t *testing.T) {
+	t.Run("case=no annotations", func(t *testing.T) {
+		s, err := NewSearchableTraits(ctx, "file://./stub/extension/schema.json")
+		require.NoError(t, err)
+		assert.Empty(t, s)
+	})
+
+	s, err := NewSearchableTraits(ctx, "file://./stub/search/search.schema.json")
+	require.NoError(t, err)
+
+	t.Run("case=lists annotated traits", func(t *testing.T) {
+		require.Len(t, s, 3)
+		assert.False(t, s["emails"].Contains)
+		assert.True(t, s["name.first"].Contains)
+		assert.False(t, s["age"].Contains)
+		assert.NotContains(t, s, "name.last")
+	})
+
+	t.Run("case=extracts normalized values", func(t *testing.T) {
+		values := s.Values([]byte(`{
+  "emails": ["Foo@Example.org", " bar@example.org", "foo@example.org"],
+  "name": {"first": "Aeneas ", "last": "Rekkas"},
+  "age": 42,
+  "ignored": "value"
+}`))
+		assert.Equal(t, map[string][]string{
+			"emails":     {"foo@example.org", "bar@example.org"},
+			"name.first": {"aeneas"},
+			"age":        {"42"},
+		}, values)
+	})
+
+	t.Run("case=skips empty and long values", func(t *testing.T) {
+		values := s.Values([]byte(`{"emails": [""], "name": {"first": "` + strings.Repeat("a", SearchValueMaxLength+1) + `"}}`))
+		assert.Empty(t, values)
+	})
+}
@@ -0,0 +1,186 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package approval
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/pagination/keysetpagination"
+)
+
+const (
+	RouteCollection = "/pending-identities"
+	RouteApprove    = RouteCollection + "/:id/approve"
+	RouteReject     = RouteCollection + "/:id/reject"
+)
+
+type (
+	handlerDependencies interface {
+		ManagementProvider
+		identity.PoolProvider
+		x.WriterProvider
+	}
+
+	HandlerProvider interface {
+		RegistrationApprovalHandler() *Handler
+	}
+
+	Handler struct {
+		d handlerDependencies
+	}
+)
+
+func NewHandler(d handlerDependencies) *Handler {
+	return &Handler{d: d}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteCollection, h.listPendingIdentities)
+	admin.POST(RouteApprove, h.approveIdentity)
+	admin.POST(RouteReject, h.rejectIdentity)
+}
+
+// List Pending Identities Parameters
+//
+// swagger:parameters listPendingIdentities
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listPendingIdentities struct {
+	keysetpagination.RequestParameters
+}
+
+// swagger:route GET /admin/pending-identities identity listPendingIdentities
+//
+// # List Identities Pending Approval
+//
+// Lists the identities which registered while `selfservice.flows.registration.approval.required`
+// was enabled and which were neither approved nor rejected yet.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: listIdentities
+//	  default: errorGeneric
+func (h *Handler) listPendingIdentities(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	opts, err := keysetpagination.Parse(r.URL.Query(), keysetpagination.NewStringPageToken)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason(err.Error())))
+		return
+	}
+
+	is, nextPage, err := h.d.IdentityPool().ListIdentities(r.Context(), identity.ListIdentityParameters{
+		Expand:           identity.ExpandDefault,
+		StateFilter:      identity.StatePendingApproval,
+		KeySetPagination: opts,
+	})
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	isam := make([]identity.WithCredentialsMetadataAndAdminMetadataInJSON, len(is))
+	for k := range is {
+		isam[k] = identity.WithCredentialsMetadataAndAdminMetadataInJSON(is[k])
+	}
+
+	u := *r.URL
+	keysetpagination.Header(w, &u, nextPage)
+	h.d.Writer().Write(w, r, isam)
+}
+
+// Approve Identity Parameters
+//
+// swagger:parameters approveIdentity
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type approveIdentity struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route POST /admin/pending-identities/{id}/approve identity approveIdentity
+//
+// # Approve an Identity
+//
+// Activates an identity which is pending approval so that it can sign in, and sends it
+// an email that the registration was approved.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: identity
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) approveIdentity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	i, err := h.d.RegistrationApprovalManager().Approve(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, identity.WithCredentialsMetadataAndAdminMetadataInJSON(*i))
+}
+
+// Reject Identity Parameters
+//
+// swagger:parameters rejectIdentity
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type rejectIdentity struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route POST /admin/pending-identities/{id}/reject identity rejectIdentity
+//
+// # Reject an Identity
+//
+// Deletes an identity which is pending approval.
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) rejectIdentity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.d.RegistrationApprovalManager().Reject(r.Context(), x.ParseUUID(ps.ByName("id"))); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
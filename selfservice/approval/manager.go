@@ -0,0 +1,147 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package approval
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/courier/template/email"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlxx"
+)
+
+var ErrNotPendingApproval = herodot.ErrBadRequest.WithError("identity is not pending approval").WithReason("The identity is not pending approval.")
+
+type (
+	managerDependencies interface {
+		config.Provider
+		courier.Provider
+		courier.ConfigProvider
+		identity.PrivilegedPoolProvider
+		identity.ValidationProvider
+		x.HTTPClientProvider
+		x.LoggingProvider
+		x.TracingProvider
+	}
+
+	// Manager approves and rejects identities which registered while
+	// registration approval was required.
+	Manager struct {
+		d managerDependencies
+	}
+
+	ManagementProvider interface {
+		RegistrationApprovalManager() *Manager
+	}
+)
+
+func NewManager(d managerDependencies) *Manager {
+	return &Manager{d: d}
+}
+
+func (m *Manager) pendingIdentity(ctx context.Context, id uuid.UUID) (*identity.Identity, error) {
+	i, err := m.d.PrivilegedIdentityPool().GetIdentityConfidential(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.State != identity.StatePendingApproval {
+		return nil, errors.WithStack(ErrNotPendingApproval)
+	}
+
+	return i, nil
+}
+
+// Approve activates the identity so that it can sign in, and notifies it by email.
+func (m *Manager) Approve(ctx context.Context, id uuid.UUID) (_ *identity.Identity, err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "selfservice.approval.Manager.Approve")
+	defer otelx.End(span, &err)
+
+	i, err := m.pendingIdentity(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stateChangedAt := sqlxx.NullTime(time.Now().UTC())
+	i.State = identity.StateActive
+	i.StateChangedAt = &stateChangedAt
+	if err := m.d.PrivilegedIdentityPool().UpdateIdentity(ctx, i); err != nil {
+		return nil, err
+	}
+
+	if err := m.notify(ctx, i); err != nil {
+		return nil, err
+	}
+
+	m.d.Audit().
+		WithField("identity_id", i.ID).
+		Info("The registration of an identity was approved.")
+
+	return i, nil
+}
+
+// Reject deletes the identity.
+func (m *Manager) Reject(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "selfservice.approval.Manager.Reject")
+	defer otelx.End(span, &err)
+
+	i, err := m.pendingIdentity(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := m.d.PrivilegedIdentityPool().DeleteIdentity(ctx, i.ID); err != nil {
+		return err
+	}
+
+	m.d.Audit().
+		WithField("identity_id", i.ID).
+		Info("The registration of an identity was rejected.")
+
+	return nil
+}
+
+// notify sends the approval notice to the first email address of the identity. Identities
+// without an email address are not notified.
+func (m *Manager) notify(ctx context.Context, i *identity.Identity) error {
+	var to string
+	for _, a := range i.VerifiableAddresses {
+		if a.Via == identity.VerifiableAddressTypeEmail {
+			to = a.Value
+			break
+		}
+	}
+	if to == "" {
+		return nil
+	}
+
+	model, err := x.StructToMap(i)
+	if err != nil {
+		return err
+	}
+
+	locale := m.d.IdentityValidator().Locale(ctx, i)
+
+	c, err := m.d.Courier(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.QueueEmail(ctx, email.NewRegistrationApproved(m.d, &email.RegistrationApprovedModel{
+		To:       to,
+		LoginURL: m.d.Config().SelfServiceFlowLoginUI(ctx).String(),
+		Identity: model,
+		Locale:   locale,
+	}))
+	return err
+}
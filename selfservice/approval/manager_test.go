@@ -0,0 +1,74 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package approval_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/approval"
+	"github.com/ory/kratos/session"
+	"github.com/ory/x/sqlcon"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/approval.schema.json")
+	m := reg.RegistrationApprovalManager()
+
+	newIdentity := func(t *testing.T, email string, state identity.State) *identity.Identity {
+		i := identity.NewIdentity("default")
+		i.State = state
+		i.Traits = identity.Traits(json.RawMessage(`{"email":"` + email + `"}`))
+		require.NoError(t, reg.IdentityManager().Create(ctx, i))
+		return i
+	}
+
+	t.Run("case=approves a pending identity and notifies it", func(t *testing.T) {
+		i := newIdentity(t, "approve@ory.sh", identity.StatePendingApproval)
+		require.ErrorIs(t, session.NewInactiveSession().Activate(testhelpers.NewTestHTTPRequest(t, "GET", "/", nil), i, conf, i.CreatedAt), session.ErrIdentityPendingApproval)
+
+		approved, err := m.Approve(ctx, i.ID)
+		require.NoError(t, err)
+		assert.Equal(t, identity.StateActive, approved.State)
+
+		actual, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(ctx, i.ID)
+		require.NoError(t, err)
+		assert.True(t, actual.IsActive())
+		assert.Len(t, actual.Credentials, len(i.Credentials))
+
+		msg := testhelpers.CourierExpectMessage(ctx, t, reg, "approve@ory.sh", "Your account was approved")
+		assert.Contains(t, msg.Body, conf.SelfServiceFlowLoginUI(ctx).String())
+
+		_, err = m.Approve(ctx, i.ID)
+		assert.ErrorIs(t, err, approval.ErrNotPendingApproval)
+	})
+
+	t.Run("case=rejects a pending identity", func(t *testing.T) {
+		i := newIdentity(t, "reject@ory.sh", identity.StatePendingApproval)
+
+		require.NoError(t, m.Reject(ctx, i.ID))
+		_, err := reg.PrivilegedIdentityPool().GetIdentity(ctx, i.ID, identity.ExpandNothing)
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+	})
+
+	t.Run("case=does not change identities which are not pending", func(t *testing.T) {
+		i := newIdentity(t, "active@ory.sh", identity.StateActive)
+
+		_, err := m.Approve(ctx, i.ID)
+		assert.ErrorIs(t, err, approval.ErrNotPendingApproval)
+		assert.ErrorIs(t, m.Reject(ctx, i.ID), approval.ErrNotPendingApproval)
+
+		_, err = reg.PrivilegedIdentityPool().GetIdentity(ctx, i.ID, identity.ExpandNothing)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,138 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+)
+
+// Acceptance documents that a version of a configured consent document was accepted in a flow.
+type Acceptance struct {
+	// ID of the document as configured in `selfservice.methods.consent.config.documents`.
+	ID string `json:"id"`
+
+	// Version of the document which was accepted.
+	Version string `json:"version"`
+
+	// Optional is true if the document did not have to be accepted.
+	Optional bool `json:"optional"`
+
+	// AcceptedAt is the time at which the document was accepted.
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+var documentDecoder = decoderx.NewHTTP()
+
+// NodeName returns the name of the checkbox node of a document.
+func NodeName(id string) string {
+	return "consent." + id
+}
+
+// NewDocumentNode returns the checkbox node with which a document is accepted. The checkbox is
+// required unless the document is optional.
+func NewDocumentNode(d config.ConsentDocument) *node.Node {
+	var opts []node.InputAttributesModifier
+	if !d.Optional {
+		opts = append(opts, node.WithRequiredInputAttribute)
+	}
+
+	return node.NewInputField(NodeName(d.ID), false, node.ConsentGroup, node.InputAttributeTypeCheckbox, opts...).
+		WithMetaLabel(text.NewInfoNodeLabelConsent(d.ID, d.Version, d.URL, d.Label))
+}
+
+// PendingDocuments returns the documents of which the current version is not part of the accepted ones.
+func PendingDocuments(documents []config.ConsentDocument, accepted []Acceptance) []config.ConsentDocument {
+	var pending []config.ConsentDocument
+	for _, d := range documents {
+		if !isAccepted(d, accepted) {
+			pending = append(pending, d)
+		}
+	}
+	return pending
+}
+
+func isAccepted(d config.ConsentDocument, accepted []Acceptance) bool {
+	for _, a := range accepted {
+		if a.ID == d.ID && a.Version == d.Version {
+			return true
+		}
+	}
+	return false
+}
+
+// DecodeAcceptances reads the checkboxes of the documents from the request body and returns the
+// accepted documents. If a required document was not accepted, a validation error pointing to the
+// checkbox of the document is returned.
+func DecodeAcceptances(r *http.Request, documents []config.ConsentDocument) ([]Acceptance, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+
+	properties := make(map[string]any, len(documents))
+	for _, d := range documents {
+		properties[d.ID] = map[string]any{"type": "boolean"}
+	}
+
+	raw, err := json.Marshal(map[string]any{
+		"$id":     "https://schemas.ory.sh/kratos/selfservice/consent/documents.schema.json",
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type":    "object",
+		"properties": map[string]any{
+			"consent": map[string]any{
+				"type":       "object",
+				"properties": properties,
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	compiler, err := decoderx.HTTPRawJSONSchemaCompiler(raw)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var body json.RawMessage
+	if err := documentDecoder.Decode(r, &body, compiler,
+		decoderx.HTTPKeepRequestBody(true),
+		decoderx.HTTPDecoderAllowedMethods("POST", "PUT", "PATCH"),
+		decoderx.HTTPDecoderSetValidatePayloads(false),
+		decoderx.HTTPDecoderJSONFollowsFormFormat(),
+	); err != nil {
+		return nil, err
+	}
+
+	now := x.Now().UTC()
+	accepted := make([]Acceptance, 0, len(documents))
+	for _, d := range documents {
+		if !gjson.GetBytes(body, "consent."+d.ID).Bool() {
+			if d.Optional {
+				continue
+			}
+			return nil, schema.NewConsentRequiredError("#/consent/"+d.ID, d.ID)
+		}
+
+		accepted = append(accepted, Acceptance{
+			ID:         d.ID,
+			Version:    d.Version,
+			Optional:   d.Optional,
+			AcceptedAt: now,
+		})
+	}
+
+	return accepted, nil
+}
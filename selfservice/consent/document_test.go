@@ -0,0 +1,96 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/consent"
+	"github.com/ory/kratos/ui/node"
+)
+
+func TestDocuments(t *testing.T) {
+	documents := []config.ConsentDocument{
+		{ID: "tos", Version: "2023-10", URL: "https://www.ory.sh/tos"},
+		{ID: "newsletter", Version: "1", Optional: true},
+	}
+
+	t.Run("case=nodes", func(t *testing.T) {
+		n := consent.NewDocumentNode(documents[0])
+		assert.Equal(t, "consent.tos", n.ID())
+		assert.Equal(t, node.ConsentGroup, n.Group)
+		attrs := n.Attributes.(*node.InputAttributes)
+		assert.Equal(t, node.InputAttributeTypeCheckbox, attrs.Type)
+		assert.True(t, attrs.Required)
+		assert.Equal(t, "I accept the tos", n.Meta.Label.Text)
+
+		n = consent.NewDocumentNode(documents[1])
+		assert.False(t, n.Attributes.(*node.InputAttributes).Required)
+	})
+
+	t.Run("case=pending documents", func(t *testing.T) {
+		assert.Equal(t, documents, consent.PendingDocuments(documents, nil))
+		assert.Equal(t, documents[1:], consent.PendingDocuments(documents, []consent.Acceptance{{ID: "tos", Version: "2023-10"}}))
+		assert.Equal(t, documents, consent.PendingDocuments(documents, []consent.Acceptance{{ID: "tos", Version: "2022-01"}}),
+			"a new version of the document must be accepted again")
+	})
+
+	t.Run("case=decode", func(t *testing.T) {
+		newJSONRequest := func(body string) *http.Request {
+			r, _ := http.NewRequest("POST", "/", strings.NewReader(body))
+			r.Header.Set("Content-Type", "application/json")
+			return r
+		}
+
+		newFormRequest := func(values url.Values) *http.Request {
+			r, _ := http.NewRequest("POST", "/", strings.NewReader(values.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return r
+		}
+
+		t.Run("case=accepts required and optional documents", func(t *testing.T) {
+			accepted, err := consent.DecodeAcceptances(newJSONRequest(`{"consent":{"tos":true,"newsletter":true}}`), documents)
+			require.NoError(t, err)
+			require.Len(t, accepted, 2)
+			assert.Equal(t, "tos", accepted[0].ID)
+			assert.Equal(t, "2023-10", accepted[0].Version)
+			assert.False(t, accepted[0].Optional)
+			assert.False(t, accepted[0].AcceptedAt.IsZero())
+			assert.True(t, accepted[1].Optional)
+		})
+
+		t.Run("case=skips optional documents", func(t *testing.T) {
+			accepted, err := consent.DecodeAcceptances(newFormRequest(url.Values{"consent.tos": {"true"}}), documents)
+			require.NoError(t, err)
+			require.Len(t, accepted, 1)
+			assert.Equal(t, "tos", accepted[0].ID)
+		})
+
+		t.Run("case=fails if a required document is missing", func(t *testing.T) {
+			_, err := consent.DecodeAcceptances(newJSONRequest(`{"consent":{"newsletter":true}}`), documents)
+			var ve *schema.ValidationError
+			require.ErrorAs(t, err, &ve)
+			assert.Equal(t, "#/consent/tos", ve.InstancePtr)
+		})
+
+		t.Run("case=keeps the request body", func(t *testing.T) {
+			r := newJSONRequest(`{"consent":{"tos":true},"method":"password"}`)
+			_, err := consent.DecodeAcceptances(r, documents)
+			require.NoError(t, err)
+
+			var body strings.Builder
+			_, err = body.ReadFrom(r.Body)
+			require.NoError(t, err)
+			assert.Contains(t, body.String(), `"method":"password"`)
+		})
+	})
+}
@@ -0,0 +1,40 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"sync"
+
+	"github.com/ory/jsonschema/v3"
+
+	"github.com/ory/kratos/schema"
+)
+
+// schemaExtension collects the consents marked in the identity schema which
+// the identity granted by setting the trait to true.
+type schemaExtension struct {
+	l       sync.Mutex
+	granted []schema.ExtensionConsentConfig
+}
+
+var _ schema.Extension = new(schemaExtension)
+
+func (e *schemaExtension) Run(_ jsonschema.ValidationContext, s schema.ExtensionConfig, value interface{}) error {
+	if s.Consent.Name == "" {
+		return nil
+	}
+
+	if granted, ok := value.(bool); !ok || !granted {
+		return nil
+	}
+
+	e.l.Lock()
+	defer e.l.Unlock()
+	e.granted = append(e.granted, s.Consent)
+	return nil
+}
+
+func (e *schemaExtension) Finish() error {
+	return nil
+}
@@ -0,0 +1,354 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/pagination/keysetpagination"
+)
+
+const (
+	RouteCollection = "/self-service/consents"
+	RouteDownload   = RouteCollection + "/download"
+	RouteConsent    = RouteCollection + "/:id"
+
+	RouteAdminCollection = "/consents"
+)
+
+type (
+	handlerDependencies interface {
+		ManagementProvider
+		PersistenceProvider
+		session.ManagementProvider
+		x.CSRFProvider
+		x.WriterProvider
+	}
+
+	HandlerProvider interface {
+		ConsentHandler() *Handler
+	}
+
+	Handler struct {
+		d handlerDependencies
+	}
+)
+
+func NewHandler(d handlerDependencies) *Handler {
+	return &Handler{d: d}
+}
+
+func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
+	// Like the sessions endpoints, these endpoints are authenticated by the session and do not set cookies.
+	h.d.CSRFHandler().IgnorePath(RouteCollection)
+	h.d.CSRFHandler().IgnoreGlob(RouteCollection + "/*")
+
+	public.GET(RouteCollection, h.listMyConsents)
+	public.GET(RouteDownload, h.downloadMyConsents)
+	public.DELETE(RouteConsent, h.withdrawMyConsent)
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteAdminCollection, h.listConsents)
+}
+
+// List of Consent Records
+//
+// swagger:model consentRecords
+type consentRecords []Record
+
+// Consent Export
+//
+// swagger:model consentExport
+type consentExport struct {
+	// IdentityID is the ID of the identity the consent records belong to.
+	//
+	// required: true
+	IdentityID uuid.UUID `json:"identity_id"`
+
+	// ExportedAt is the time at which the export was created.
+	//
+	// required: true
+	ExportedAt time.Time `json:"exported_at"`
+
+	// Consents are all consent records of the identity, including withdrawn ones.
+	//
+	// required: true
+	Consents consentRecords `json:"consents"`
+}
+
+// List My Consents Parameters
+//
+// swagger:parameters listMyConsents downloadMyConsents
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listMyConsents struct {
+	// Set the Session Token when calling from non-browser clients. A session token has a format of `MP2YWEMeM8MxjkGKpH4dqOQ4Q4DlSPaj`.
+	//
+	// in: header
+	SessionToken string `json:"X-Session-Token"`
+
+	// Set the Cookie Header. This is especially useful when calling this endpoint from a server-side application. In that
+	// scenario you must include the HTTP Cookie Header which originally was included in the request to your server.
+	//
+	// in: header
+	Cookie string `json:"Cookie"`
+}
+
+// List My Consents Response
+//
+// swagger:response listMyConsents
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listMyConsentsResponse struct {
+	// in: body
+	Body consentRecords
+}
+
+// swagger:route GET /self-service/consents frontend listMyConsents
+//
+// # Get My Consent Records
+//
+// This endpoint returns the consents, such as accepted terms of service versions and marketing
+// opt-ins, which the identity of the current session granted during registration.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: listMyConsents
+//	  401: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) listMyConsents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	records, _, err := h.fetchMyConsents(r)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, records)
+}
+
+// Download My Consents Response
+//
+// swagger:response downloadMyConsents
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type downloadMyConsentsResponse struct {
+	// in: body
+	Body consentExport
+}
+
+// swagger:route GET /self-service/consents/download frontend downloadMyConsents
+//
+// # Download My Consent Records
+//
+// This endpoint returns all consent records of the identity of the current session as a JSON
+// file attachment, so that the user can keep a copy of them.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: downloadMyConsents
+//	  401: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) downloadMyConsents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	records, identityID, err := h.fetchMyConsents(r)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="consents.json"`)
+	h.d.Writer().Write(w, r, &consentExport{
+		IdentityID: identityID,
+		ExportedAt: x.Now().UTC(),
+		Consents:   records,
+	})
+}
+
+// Withdraw My Consent Parameters
+//
+// swagger:parameters withdrawMyConsent
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type withdrawMyConsent struct {
+	// ID is the ID of the consent record.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// Set the Session Token when calling from non-browser clients. A session token has a format of `MP2YWEMeM8MxjkGKpH4dqOQ4Q4DlSPaj`.
+	//
+	// in: header
+	SessionToken string `json:"X-Session-Token"`
+
+	// Set the Cookie Header. This is especially useful when calling this endpoint from a server-side application. In that
+	// scenario you must include the HTTP Cookie Header which originally was included in the request to your server.
+	//
+	// in: header
+	Cookie string `json:"Cookie"`
+}
+
+// Withdraw My Consent Response
+//
+// swagger:response withdrawMyConsent
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type withdrawMyConsentResponse struct {
+	// in: body
+	Body Record
+}
+
+// swagger:route DELETE /self-service/consents/{id} frontend withdrawMyConsent
+//
+// # Withdraw One of My Consents
+//
+// Calling this endpoint withdraws an optional consent, such as a marketing opt-in, of the identity
+// of the current session. Required consents can not be withdrawn. The consent record is kept and
+// marked as withdrawn.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: withdrawMyConsent
+//	  400: errorGeneric
+//	  401: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) withdrawMyConsent(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	s, err := h.d.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	id, err := uuid.FromString(ps.ByName("id"))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()).WithDebug("could not parse UUID"))
+		return
+	}
+
+	record, err := h.d.ConsentManager().Withdraw(r.Context(), s.IdentityID, id)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, record)
+}
+
+func (h *Handler) fetchMyConsents(r *http.Request) (consentRecords, uuid.UUID, error) {
+	s, err := h.d.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	records, err := h.d.ConsentPersister().ListConsentRecords(r.Context(), s.IdentityID)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	return records, s.IdentityID, nil
+}
+
+// List Consents Parameters
+//
+// swagger:parameters listConsents
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listConsents struct {
+	keysetpagination.RequestParameters
+
+	// Name only returns the records of this consent, for example "tos".
+	//
+	// in: query
+	Name string `json:"name"`
+
+	// Version only returns the records of this version of the consent.
+	//
+	// in: query
+	Version string `json:"version"`
+
+	// IncludeWithdrawn also returns withdrawn consents.
+	//
+	// in: query
+	IncludeWithdrawn bool `json:"include_withdrawn"`
+}
+
+// List Consents Response
+//
+// swagger:response listConsents
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listConsentsResponse struct {
+	// in: body
+	Body consentRecords
+}
+
+// swagger:route GET /admin/consents identity listConsents
+//
+// # List Consent Records
+//
+// Reports which identities accepted which version of a consent, for example of the terms of
+// service. Withdrawn consents are only returned if `include_withdrawn` is set.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: listConsents
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) listConsents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	opts, err := keysetpagination.Parse(r.URL.Query(), keysetpagination.NewStringPageToken)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason(err.Error())))
+		return
+	}
+
+	records, nextPage, err := h.d.ConsentPersister().ListAllConsentRecords(r.Context(), ListRecordsParameters{
+		Name:             r.URL.Query().Get("name"),
+		Version:          r.URL.Query().Get("version"),
+		IncludeWithdrawn: r.URL.Query().Get("include_withdrawn") == "true",
+		KeySetPagination: opts,
+	})
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	u := *r.URL
+	keysetpagination.Header(w, &u, nextPage)
+	h.d.Writer().Write(w, r, consentRecords(records))
+}
@@ -0,0 +1,173 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+	"github.com/ory/kratos/x/events"
+	"github.com/ory/x/httpx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlxx"
+)
+
+type (
+	managerDependencies interface {
+		identity.ValidationProvider
+		x.LoggingProvider
+		x.TracingProvider
+		PersistenceProvider
+	}
+
+	// Manager records the consents granted at registration and lets identities
+	// withdraw optional consents.
+	Manager struct {
+		d managerDependencies
+	}
+
+	ManagementProvider interface {
+		ConsentManager() *Manager
+	}
+)
+
+func NewManager(d managerDependencies) *Manager {
+	return &Manager{d: d}
+}
+
+// RecordRegistrationConsents stores a consent record for every trait of the identity which is
+// marked as a consent in the identity schema and set to true.
+func (m *Manager) RecordRegistrationConsents(r *http.Request, i *identity.Identity) (err error) {
+	ctx, span := m.d.Tracer(r.Context()).Tracer().Start(r.Context(), "consent.Manager.RecordRegistrationConsents")
+	defer otelx.End(span, &err)
+
+	e := new(schemaExtension)
+	if err := m.d.IdentityValidator().ValidateWithRunner(ctx, i, e); err != nil {
+		return err
+	}
+
+	if len(e.granted) == 0 {
+		return nil
+	}
+
+	now := x.Now().UTC()
+	records := make([]*Record, len(e.granted))
+	for k, c := range e.granted {
+		records[k] = &Record{
+			IdentityID: i.ID,
+			Name:       c.Name,
+			Version:    c.Version,
+			Optional:   c.Optional,
+			IPAddress:  httpx.ClientIP(r),
+			UserAgent:  strings.Join(r.Header["User-Agent"], " "),
+			GrantedAt:  now,
+		}
+	}
+
+	if err := m.d.ConsentPersister().CreateConsentRecords(ctx, records...); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		span.AddEvent(events.NewConsentGranted(ctx, i.ID, record.Name, record.Version))
+	}
+
+	return nil
+}
+
+// RecordAcceptances stores a consent record for every configured document which the identity
+// accepted in a registration or settings flow.
+func (m *Manager) RecordAcceptances(r *http.Request, i *identity.Identity, accepted []Acceptance) (err error) {
+	ctx, span := m.d.Tracer(r.Context()).Tracer().Start(r.Context(), "consent.Manager.RecordAcceptances")
+	defer otelx.End(span, &err)
+
+	if len(accepted) == 0 {
+		return nil
+	}
+
+	records := make([]*Record, len(accepted))
+	for k, a := range accepted {
+		records[k] = &Record{
+			IdentityID: i.ID,
+			Name:       a.ID,
+			Version:    a.Version,
+			Optional:   a.Optional,
+			IPAddress:  httpx.ClientIP(r),
+			UserAgent:  strings.Join(r.Header["User-Agent"], " "),
+			GrantedAt:  a.AcceptedAt,
+		}
+	}
+
+	if err := m.d.ConsentPersister().CreateConsentRecords(ctx, records...); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		span.AddEvent(events.NewConsentGranted(ctx, i.ID, record.Name, record.Version))
+	}
+
+	return nil
+}
+
+// AcceptedDocuments returns the documents of which the identity holds a consent record which was not withdrawn.
+func (m *Manager) AcceptedDocuments(ctx context.Context, identityID uuid.UUID) ([]Acceptance, error) {
+	records, err := m.d.ConsentPersister().ListConsentRecords(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := make([]Acceptance, 0, len(records))
+	for _, record := range records {
+		if record.IsWithdrawn() {
+			continue
+		}
+		accepted = append(accepted, Acceptance{
+			ID:         record.Name,
+			Version:    record.Version,
+			Optional:   record.Optional,
+			AcceptedAt: record.GrantedAt,
+		})
+	}
+	return accepted, nil
+}
+
+// Withdraw withdraws an optional consent of the identity. Withdrawing a consent twice has no effect.
+func (m *Manager) Withdraw(ctx context.Context, identityID, id uuid.UUID) (_ *Record, err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "consent.Manager.Withdraw")
+	defer otelx.End(span, &err)
+
+	record, err := m.d.ConsentPersister().GetConsentRecord(ctx, identityID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !record.Optional {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The consent %q is required and can not be withdrawn.", record.Name))
+	}
+
+	if record.IsWithdrawn() {
+		return record, nil
+	}
+
+	now := x.Now().UTC()
+	if err := m.d.ConsentPersister().WithdrawConsentRecord(ctx, identityID, id, now); err != nil {
+		return nil, err
+	}
+	record.WithdrawnAt = sqlxx.NullTime(now)
+
+	m.d.Logger().
+		WithField("identity_id", identityID).
+		WithField("consent", record.Name).
+		Info("An identity withdrew a consent.")
+	span.AddEvent(events.NewConsentWithdrawn(ctx, identityID, record.Name, record.Version))
+
+	return record, nil
+}
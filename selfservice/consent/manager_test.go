@@ -0,0 +1,143 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/consent"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlcon"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/consent.schema.json")
+	m := reg.ConsentManager()
+
+	newIdentity := func(t *testing.T, traits string) *identity.Identity {
+		i := identity.NewIdentity("default")
+		i.Traits = identity.Traits(json.RawMessage(traits))
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+		return i
+	}
+
+	newRequest := func() *http.Request {
+		r, _ := http.NewRequestWithContext(ctx, "POST", "/", nil)
+		r.Header.Set("User-Agent", "consent-test")
+		return r
+	}
+
+	t.Run("case=records only granted consents", func(t *testing.T) {
+		i := newIdentity(t, `{"email":"granted@ory.sh","tos":true,"newsletter":false}`)
+		require.NoError(t, m.RecordRegistrationConsents(newRequest(), i))
+
+		records, err := reg.ConsentPersister().ListConsentRecords(ctx, i.ID)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "tos", records[0].Name)
+		assert.Equal(t, "2023-10", records[0].Version)
+		assert.False(t, records[0].Optional)
+		assert.Equal(t, "consent-test", records[0].UserAgent)
+		assert.False(t, records[0].IsWithdrawn())
+	})
+
+	t.Run("case=withdraws optional consents only", func(t *testing.T) {
+		i := newIdentity(t, `{"email":"withdraw@ory.sh","tos":true,"newsletter":true}`)
+		require.NoError(t, m.RecordRegistrationConsents(newRequest(), i))
+
+		records, err := reg.ConsentPersister().ListConsentRecords(ctx, i.ID)
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		for _, record := range records {
+			switch record.Name {
+			case "tos":
+				_, err := m.Withdraw(ctx, i.ID, record.ID)
+				assert.ErrorIs(t, err, herodot.ErrBadRequest)
+			case "newsletter":
+				withdrawn, err := m.Withdraw(ctx, i.ID, record.ID)
+				require.NoError(t, err)
+				assert.True(t, withdrawn.IsWithdrawn())
+
+				actual, err := reg.ConsentPersister().GetConsentRecord(ctx, i.ID, record.ID)
+				require.NoError(t, err)
+				assert.True(t, actual.IsWithdrawn())
+
+				// Withdrawing again is a no-op.
+				_, err = m.Withdraw(ctx, i.ID, record.ID)
+				require.NoError(t, err)
+			}
+		}
+	})
+
+	t.Run("case=can not withdraw consents of other identities", func(t *testing.T) {
+		i := newIdentity(t, `{"email":"owner@ory.sh","newsletter":true}`)
+		require.NoError(t, m.RecordRegistrationConsents(newRequest(), i))
+
+		records, err := reg.ConsentPersister().ListConsentRecords(ctx, i.ID)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+
+		_, err = m.Withdraw(ctx, x.NewUUID(), records[0].ID)
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+	})
+
+	t.Run("case=records accepted documents", func(t *testing.T) {
+		i := newIdentity(t, `{"email":"documents@ory.sh"}`)
+		acceptedAt := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+		require.NoError(t, m.RecordAcceptances(newRequest(), i, []consent.Acceptance{
+			{ID: "privacy", Version: "v1", AcceptedAt: acceptedAt},
+			{ID: "marketing", Version: "v1", Optional: true, AcceptedAt: acceptedAt},
+		}))
+
+		accepted, err := m.AcceptedDocuments(ctx, i.ID)
+		require.NoError(t, err)
+		require.Len(t, accepted, 2)
+
+		records, err := reg.ConsentPersister().ListConsentRecords(ctx, i.ID)
+		require.NoError(t, err)
+		for _, record := range records {
+			if record.Name == "marketing" {
+				_, err := m.Withdraw(ctx, i.ID, record.ID)
+				require.NoError(t, err)
+			}
+		}
+
+		accepted, err = m.AcceptedDocuments(ctx, i.ID)
+		require.NoError(t, err)
+		require.Len(t, accepted, 1)
+		assert.Equal(t, "privacy", accepted[0].ID)
+		assert.Equal(t, "v1", accepted[0].Version)
+		assert.True(t, acceptedAt.Equal(accepted[0].AcceptedAt.UTC()))
+	})
+
+	t.Run("case=lists records of all identities", func(t *testing.T) {
+		first := newIdentity(t, `{"email":"first-report@ory.sh"}`)
+		second := newIdentity(t, `{"email":"second-report@ory.sh"}`)
+		require.NoError(t, m.RecordAcceptances(newRequest(), first, []consent.Acceptance{{ID: "report", Version: "v1", AcceptedAt: time.Now()}}))
+		require.NoError(t, m.RecordAcceptances(newRequest(), second, []consent.Acceptance{{ID: "report", Version: "v2", AcceptedAt: time.Now()}}))
+
+		records, _, err := reg.ConsentPersister().ListAllConsentRecords(ctx, consent.ListRecordsParameters{Name: "report"})
+		require.NoError(t, err)
+		assert.Len(t, records, 2)
+
+		records, _, err = reg.ConsentPersister().ListAllConsentRecords(ctx, consent.ListRecordsParameters{Name: "report", Version: "v2"})
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, second.ID, records[0].IdentityID)
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlxx"
+)
+
+// Consent Record
+//
+// A consent record documents that an identity accepted a consent, for example
+// a version of the terms of service or a marketing opt-in, during registration or in the settings flow.
+//
+// swagger:model consentRecord
+type Record struct {
+	// ID of the consent record
+	//
+	// required: true
+	ID uuid.UUID `json:"id" faker:"-" db:"id"`
+
+	// IdentityID is the ID of the identity which granted the consent.
+	//
+	// required: true
+	IdentityID uuid.UUID `json:"identity_id" faker:"-" db:"identity_id"`
+
+	// Name of the consent as configured in the identity schema or the consent documents, for example "tos".
+	//
+	// required: true
+	Name string `json:"name" db:"name"`
+
+	// Version of the consent as configured in the identity schema or the consent documents, for example "2023-10".
+	Version string `json:"version" db:"version"`
+
+	// Optional consents can be withdrawn by the identity.
+	//
+	// required: true
+	Optional bool `json:"optional" db:"optional"`
+
+	// IPAddress of the client which granted the consent
+	IPAddress string `json:"ip_address" faker:"ipv4" db:"ip_address"`
+
+	// UserAgent of the client which granted the consent
+	UserAgent string `json:"user_agent" faker:"-" db:"user_agent"`
+
+	// GrantedAt is the time at which the consent was granted.
+	//
+	// required: true
+	GrantedAt time.Time `json:"granted_at" faker:"-" db:"granted_at"`
+
+	// WithdrawnAt is the time at which the consent was withdrawn. Not set while the consent is granted.
+	WithdrawnAt sqlxx.NullTime `json:"withdrawn_at,omitempty" faker:"-" db:"withdrawn_at"`
+
+	// CreatedAt is a helper struct field for gobuffalo.pop.
+	CreatedAt time.Time `json:"-" faker:"-" db:"created_at"`
+
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (r Record) TableName(context.Context) string {
+	return "identity_consents"
+}
+
+// IsWithdrawn returns true if the consent was withdrawn.
+func (r *Record) IsWithdrawn() bool {
+	return !time.Time(r.WithdrawnAt).IsZero()
+}
+
+func (r Record) PageToken() keysetpagination.PageToken {
+	return keysetpagination.StringPageToken(r.ID.String())
+}
+
+func DefaultPageToken() keysetpagination.PageToken {
+	return keysetpagination.StringPageToken(uuid.Nil.String())
+}
+
+// ListRecordsParameters filter the consent records of all identities.
+type ListRecordsParameters struct {
+	// Name only returns records of the consent with this name, for example "tos".
+	Name string
+
+	// Version only returns records of this version of the consent.
+	Version string
+
+	// IncludeWithdrawn also returns withdrawn records.
+	IncludeWithdrawn bool
+
+	KeySetPagination []keysetpagination.Option
+}
+
+type (
+	Persister interface {
+		// CreateConsentRecords stores the consents granted by an identity.
+		CreateConsentRecords(ctx context.Context, records ...*Record) error
+
+		// GetConsentRecord returns a consent record of the identity or sqlcon.ErrNoRows.
+		GetConsentRecord(ctx context.Context, identityID, id uuid.UUID) (*Record, error)
+
+		// ListConsentRecords returns all consent records of the identity, including withdrawn ones.
+		ListConsentRecords(ctx context.Context, identityID uuid.UUID) ([]Record, error)
+
+		// ListAllConsentRecords returns the consent records of all identities matching the parameters.
+		ListAllConsentRecords(ctx context.Context, params ListRecordsParameters) ([]Record, *keysetpagination.Paginator, error)
+
+		// WithdrawConsentRecord marks a consent record of the identity as withdrawn at the given time.
+		WithdrawConsentRecord(ctx context.Context, identityID, id uuid.UUID, at time.Time) error
+	}
+
+	PersistenceProvider interface {
+		ConsentPersister() Persister
+	}
+)
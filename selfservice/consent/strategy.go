@@ -0,0 +1,219 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+)
+
+//go:embed .schema/settings.schema.json
+var settingsSchema []byte
+
+var _ settings.Strategy = new(Strategy)
+
+type strategyDependencies interface {
+	x.LoggingProvider
+	x.WriterProvider
+	x.CSRFTokenGeneratorProvider
+	x.CSRFProvider
+
+	config.Provider
+
+	continuity.ManagementProvider
+
+	settings.FlowPersistenceProvider
+	settings.HookExecutorProvider
+	settings.HooksProvider
+	settings.ErrorHandlerProvider
+
+	identity.PrivilegedPoolProvider
+
+	ManagementProvider
+}
+
+// Strategy asks identities in the settings flow to accept the configured documents which they
+// did not accept in their current version yet, for example after the terms of service changed.
+type Strategy struct {
+	d strategyDependencies
+}
+
+func NewStrategy(d any) *Strategy {
+	return &Strategy{d: d.(strategyDependencies)}
+}
+
+func (s *Strategy) NodeGroup() node.UiNodeGroup {
+	return node.ConsentGroup
+}
+
+func (s *Strategy) RegisterSettingsRoutes(_ *x.RouterPublic) {
+}
+
+func (s *Strategy) SettingsStrategyID() string {
+	return "consent"
+}
+
+// Update Settings Flow with Consent Method
+//
+// swagger:model updateSettingsFlowWithConsentMethod
+type updateSettingsFlowWithConsentMethod struct {
+	// Consent contains the accepted documents, for example `{"tos": true}`.
+	Consent map[string]bool `json:"consent"`
+
+	// CSRFToken is the anti-CSRF token
+	CSRFToken string `json:"csrf_token"`
+
+	// Method
+	//
+	// Should be set to "consent" when accepting documents.
+	//
+	// required: true
+	Method string `json:"method"`
+
+	// Flow is flow ID.
+	//
+	// swagger:ignore
+	Flow string `json:"flow"`
+}
+
+func (p *updateSettingsFlowWithConsentMethod) GetFlowID() uuid.UUID {
+	return x.ParseUUID(p.Flow)
+}
+
+func (p *updateSettingsFlowWithConsentMethod) SetFlowID(rid uuid.UUID) {
+	p.Flow = rid.String()
+}
+
+func (s *Strategy) Settings(w http.ResponseWriter, r *http.Request, f *settings.Flow, ss *session.Session) (*settings.UpdateContext, error) {
+	var p updateSettingsFlowWithConsentMethod
+	ctxUpdate, err := settings.PrepareUpdate(s.d, w, r, f, ss, settings.ContinuityKey(s.SettingsStrategyID()), &p)
+	if errors.Is(err, settings.ErrContinuePreviousAction) {
+		return ctxUpdate, s.continueSettingsFlow(w, r, ctxUpdate, &p)
+	} else if err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, err)
+	}
+
+	if err := s.decodeSettingsFlow(r, &p); err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, err)
+	}
+
+	if err := flow.MethodEnabledAndAllowedFromRequest(r, f.GetFlowName(), s.SettingsStrategyID(), s.d); err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, err)
+	}
+
+	// This does not come from the payload!
+	p.Flow = ctxUpdate.Flow.ID.String()
+	if err := s.continueSettingsFlow(w, r, ctxUpdate, &p); err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, err)
+	}
+
+	return ctxUpdate, nil
+}
+
+func (s *Strategy) decodeSettingsFlow(r *http.Request, dest interface{}) error {
+	compiler, err := decoderx.HTTPRawJSONSchemaCompiler(settingsSchema)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// The documents are decoded separately, so the body must be kept.
+	return decoderx.NewHTTP().Decode(r, dest, compiler,
+		decoderx.HTTPKeepRequestBody(true),
+		decoderx.HTTPDecoderAllowedMethods("POST", "GET"),
+		decoderx.HTTPDecoderSetValidatePayloads(true),
+		decoderx.HTTPDecoderJSONFollowsFormFormat(),
+	)
+}
+
+func (s *Strategy) continueSettingsFlow(
+	w http.ResponseWriter, r *http.Request,
+	ctxUpdate *settings.UpdateContext, p *updateSettingsFlowWithConsentMethod,
+) error {
+	if err := flow.MethodEnabledAndAllowed(r.Context(), flow.SettingsFlow, s.SettingsStrategyID(), p.Method, s.d); err != nil {
+		return err
+	}
+
+	if err := flow.EnsureCSRF(s.d, r, ctxUpdate.Flow.Type, s.d.Config().DisableAPIFlowEnforcement(r.Context()), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		return err
+	}
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), ctxUpdate.Session.IdentityID)
+	if err != nil {
+		return err
+	}
+
+	pending, err := s.pendingDocuments(r, i)
+	if err != nil {
+		return err
+	}
+
+	accepted, err := DecodeAcceptances(r, pending)
+	if err != nil {
+		return err
+	}
+
+	if err := s.d.ConsentManager().RecordAcceptances(r, i, accepted); err != nil {
+		return err
+	}
+
+	ctxUpdate.UpdateIdentity(i)
+	return nil
+}
+
+func (s *Strategy) PopulateSettingsMethod(r *http.Request, id *identity.Identity, f *settings.Flow) error {
+	pending, err := s.pendingDocuments(r, id)
+	if err != nil {
+		return err
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	f.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	for _, d := range pending {
+		f.UI.Nodes.Append(NewDocumentNode(d))
+	}
+	f.UI.Nodes.Append(node.NewInputField("method", s.SettingsStrategyID(), node.ConsentGroup, node.InputAttributeTypeSubmit).
+		WithMetaLabel(text.NewInfoNodeLabelSave()))
+
+	return nil
+}
+
+func (s *Strategy) pendingDocuments(r *http.Request, i *identity.Identity) ([]config.ConsentDocument, error) {
+	documents := s.d.Config().SelfServiceConsentDocuments(r.Context())
+	if len(documents) == 0 {
+		return nil, nil
+	}
+
+	accepted, err := s.d.ConsentManager().AcceptedDocuments(r.Context(), i.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return PendingDocuments(documents, accepted), nil
+}
+
+func (s *Strategy) handleSettingsError(_ http.ResponseWriter, r *http.Request, ctxUpdate *settings.UpdateContext, err error) error {
+	if ctxUpdate.Flow != nil {
+		ctxUpdate.Flow.UI.ResetMessages()
+		ctxUpdate.Flow.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	}
+
+	return err
+}
@@ -0,0 +1,48 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package flow
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ory/kratos/driver/config"
+)
+
+type (
+	// BeforeRenderHookExecutor may change the UI of a flow right before the flow is returned to the client.
+	BeforeRenderHookExecutor interface {
+		ExecuteBeforeRenderHook(w http.ResponseWriter, r *http.Request, f Flow) error
+	}
+
+	BeforeRenderHooksProvider interface {
+		BeforeRenderHooks(ctx context.Context, name FlowName) []BeforeRenderHookExecutor
+	}
+
+	// ThemedFlow is implemented by flows which expose the theme variables configured in
+	// `selfservice.theme.variables`.
+	ThemedFlow interface {
+		SetTheme(theme map[string]interface{})
+	}
+
+	beforeRenderDependencies interface {
+		BeforeRenderHooksProvider
+		config.Provider
+	}
+)
+
+// ExecuteBeforeRenderHooks sets the theme variables of the flow and runs the before render hooks configured for
+// the flow. Call it after the flow was stored, so that the changes only apply to the flow returned in this response.
+func ExecuteBeforeRenderHooks(w http.ResponseWriter, r *http.Request, d beforeRenderDependencies, f Flow) error {
+	if tf, ok := f.(ThemedFlow); ok {
+		tf.SetTheme(d.Config().SelfServiceThemeVariables(r.Context()))
+	}
+
+	for _, h := range d.BeforeRenderHooks(r.Context(), f.GetFlowName()) {
+		if err := h.ExecuteBeforeRenderHook(w, r, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
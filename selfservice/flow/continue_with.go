@@ -88,10 +88,16 @@ type ContinueWithVerificationUIFlow struct {
 	//
 	// required: false
 	URL string `json:"url,omitempty"`
+
+	// The URL the verification code is submitted to. Submitting the address to this URL again
+	// sends a new code. API and SPA clients can use it without fetching the flow first.
+	//
+	// required: false
+	SubmitURL string `json:"submit_url,omitempty"`
 }
 
 func NewContinueWithVerificationUI(f Flow, address, url string) *ContinueWithVerificationUI {
-	return &ContinueWithVerificationUI{
+	c := &ContinueWithVerificationUI{
 		Action: ContinueWithActionShowVerificationUIString,
 		Flow: ContinueWithVerificationUIFlow{
 			ID:                f.GetID(),
@@ -99,6 +105,10 @@ func NewContinueWithVerificationUI(f Flow, address, url string) *ContinueWithVer
 			URL:               url,
 		},
 	}
+	if ui := f.GetUI(); ui != nil {
+		c.Flow.SubmitURL = ui.Action
+	}
+	return c
 }
 
 func (c ContinueWithVerificationUI) AppendTo(src *url.URL) *url.URL {
@@ -107,6 +117,148 @@ func (c ContinueWithVerificationUI) AppendTo(src *url.URL) *url.URL {
 	return urlx.CopyWithQuery(src, values)
 }
 
+// swagger:enum ContinueWithActionRegenerateLookupSecrets
+type ContinueWithActionRegenerateLookupSecrets string
+
+// #nosec G101 -- only a key constant
+const (
+	ContinueWithActionRegenerateLookupSecretsString ContinueWithActionRegenerateLookupSecrets = "regenerate_lookup_secrets"
+)
+
+var _ ContinueWith = new(ContinueWithRegenerateLookupSecrets)
+
+// Indicates, that the user signed in with one of their last lookup secrets and should regenerate them
+// in the settings flow
+//
+// swagger:model continueWithRegenerateLookupSecrets
+type ContinueWithRegenerateLookupSecrets struct {
+	// Action will always be `regenerate_lookup_secrets`
+	//
+	// required: true
+	Action ContinueWithActionRegenerateLookupSecrets `json:"action"`
+
+	// The number of lookup secrets which were not used yet
+	//
+	// required: true
+	Remaining int `json:"remaining"`
+
+	// The URL of the settings UI where the lookup secrets can be regenerated
+	//
+	// required: false
+	URL string `json:"url,omitempty"`
+}
+
+func NewContinueWithRegenerateLookupSecrets(remaining int, url string) *ContinueWithRegenerateLookupSecrets {
+	return &ContinueWithRegenerateLookupSecrets{
+		Action:    ContinueWithActionRegenerateLookupSecretsString,
+		Remaining: remaining,
+		URL:       url,
+	}
+}
+
+// swagger:enum ContinueWithActionShowLoginUI
+type ContinueWithActionShowLoginUI string
+
+// #nosec G101 -- only a key constant
+const (
+	ContinueWithActionShowLoginUIString ContinueWithActionShowLoginUI = "show_login_ui"
+)
+
+var _ ContinueWith = new(ContinueWithLoginUI)
+
+// Indicates, that the UI flow could be continued by showing a login ui, for example because an account
+// with the registered identifier exists already
+//
+// swagger:model continueWithLoginUi
+type ContinueWithLoginUI struct {
+	// Action will always be `show_login_ui`
+	//
+	// required: true
+	Action ContinueWithActionShowLoginUI `json:"action"`
+
+	// Flow contains the ID of the login flow
+	//
+	// required: true
+	Flow ContinueWithLoginUIFlow `json:"flow"`
+}
+
+// swagger:model continueWithLoginUiFlow
+type ContinueWithLoginUIFlow struct {
+	// The ID of the login flow
+	//
+	// required: true
+	ID uuid.UUID `json:"id"`
+
+	// The identifier the login flow is pre-filled with
+	//
+	// required: false
+	Identifier string `json:"identifier,omitempty"`
+
+	// The URL of the login flow
+	//
+	// required: false
+	URL string `json:"url,omitempty"`
+}
+
+func NewContinueWithLoginUI(f Flow, identifier, url string) *ContinueWithLoginUI {
+	return &ContinueWithLoginUI{
+		Action: ContinueWithActionShowLoginUIString,
+		Flow: ContinueWithLoginUIFlow{
+			ID:         f.GetID(),
+			Identifier: identifier,
+			URL:        url,
+		},
+	}
+}
+
+// swagger:enum ContinueWithActionShowRecoveryUI
+type ContinueWithActionShowRecoveryUI string
+
+// #nosec G101 -- only a key constant
+const (
+	ContinueWithActionShowRecoveryUIString ContinueWithActionShowRecoveryUI = "show_recovery_ui"
+)
+
+var _ ContinueWith = new(ContinueWithRecoveryUI)
+
+// Indicates, that the UI flow could be continued by showing a recovery ui
+//
+// swagger:model continueWithRecoveryUi
+type ContinueWithRecoveryUI struct {
+	// Action will always be `show_recovery_ui`
+	//
+	// required: true
+	Action ContinueWithActionShowRecoveryUI `json:"action"`
+
+	// Flow contains the ID of the recovery flow
+	//
+	// required: true
+	Flow ContinueWithRecoveryUIFlow `json:"flow"`
+}
+
+// swagger:model continueWithRecoveryUiFlow
+type ContinueWithRecoveryUIFlow struct {
+	// The ID of the recovery flow
+	//
+	// required: true
+	ID uuid.UUID `json:"id"`
+
+	// The URL of the recovery flow
+	//
+	// required: false
+	URL string `json:"url,omitempty"`
+}
+
+func NewContinueWithRecoveryUI(f Flow, url string) *ContinueWithRecoveryUI {
+	return &ContinueWithRecoveryUI{
+		Action: ContinueWithActionShowRecoveryUIString,
+		Flow: ContinueWithRecoveryUIFlow{
+			ID:  f.GetID(),
+			URL: url,
+		},
+	}
+}
+
 type FlowWithContinueWith interface {
 	Flow
 	AddContinueWith(ContinueWith)
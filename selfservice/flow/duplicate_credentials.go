@@ -19,6 +19,10 @@ type DuplicateCredentialsData struct {
 	CredentialsType     identity.CredentialsType
 	CredentialsConfig   sqlxx.JSONRawMessage
 	DuplicateIdentifier string
+
+	// DuplicateIdentifierVerified is true if the provider of the new credentials verified the
+	// duplicate identifier, which allows confirming the linking with a code sent to it.
+	DuplicateIdentifierVerified bool
 }
 
 type InternalContexter interface {
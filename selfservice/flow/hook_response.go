@@ -0,0 +1,87 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package flow
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/x"
+)
+
+// HookResponse is returned by a hook which wants the flow to respond right away. The remaining hooks
+// are skipped and the flow handler writes the response instead of its default one.
+//
+// Browsers are redirected to RedirectTo if it is set. API and SPA clients receive Body as JSON with
+// the continue_with items added to it.
+type HookResponse struct {
+	RedirectTo   string
+	Body         json.RawMessage
+	ContinueWith []ContinueWith
+}
+
+var _ error = new(HookResponse)
+
+// NewHookRedirectResponse returns a hook response which sends the browser to the given URL.
+func NewHookRedirectResponse(to string) *HookResponse {
+	return &HookResponse{RedirectTo: to}
+}
+
+// NewHookJSONResponse returns a hook response which writes the given body as JSON.
+func NewHookJSONResponse(body json.RawMessage, continueWith ...ContinueWith) *HookResponse {
+	return &HookResponse{Body: body, ContinueWith: continueWith}
+}
+
+func (r *HookResponse) Error() string {
+	return "hook responded to the flow"
+}
+
+// AddContinueWith adds continue_with items to the JSON response.
+func (r *HookResponse) AddContinueWith(c ...ContinueWith) *HookResponse {
+	r.ContinueWith = append(r.ContinueWith, c...)
+	return r
+}
+
+// Write writes the hook response for a flow of the given type.
+func (r *HookResponse) Write(w http.ResponseWriter, req *http.Request, ft Type, writer herodot.Writer) {
+	if r.RedirectTo != "" && ft == TypeBrowser {
+		if x.IsJSONRequest(req) {
+			writer.WriteError(w, req, NewBrowserLocationChangeRequiredError(r.RedirectTo))
+			return
+		}
+		http.Redirect(w, req, r.RedirectTo, http.StatusSeeOther)
+		return
+	}
+
+	body, err := r.jsonBody()
+	if err != nil {
+		writer.WriteError(w, req, err)
+		return
+	}
+	writer.Write(w, req, body)
+}
+
+func (r *HookResponse) jsonBody() (json.RawMessage, error) {
+	body := []byte(r.Body)
+	if len(body) == 0 {
+		body = []byte("{}")
+	}
+
+	var err error
+	if r.RedirectTo != "" {
+		if body, err = sjson.SetBytes(body, "redirect_browser_to", r.RedirectTo); err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to encode hook response: %s", err))
+		}
+	}
+	if len(r.ContinueWith) > 0 {
+		if body, err = sjson.SetBytes(body, "continue_with", r.ContinueWith); err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to encode hook response: %s", err))
+		}
+	}
+	return body, nil
+}
@@ -34,6 +34,9 @@ var (
 
 	// ErrSessionRequiredForHigherAAL is returned when someone requests AAL2 or AAL3 even though no active session exists yet.
 	ErrSessionRequiredForHigherAAL = herodot.ErrUnauthorized.WithID(text.ErrIDSessionRequiredForHigherAAL).WithError("aal2 and aal3 can only be requested if a session exists already").WithReason("You can not requested a higher AAL (AAL2/AAL3) without an active session.")
+
+	// ErrStepUpSessionChanged is returned when a step-up flow is completed by another session than the one which started it.
+	ErrStepUpSessionChanged = herodot.ErrForbidden.WithID(text.ErrIDInitiatedBySomeoneElse).WithError("the step-up flow was started by another session").WithReason("This login flow was started to upgrade a different session. Please start a new flow.")
 )
 
 type (
@@ -42,6 +45,7 @@ type (
 		x.WriterProvider
 		x.LoggingProvider
 		config.Provider
+		flow.BeforeRenderHooksProvider
 		sessiontokenexchange.PersistenceProvider
 
 		FlowPersistenceProvider
@@ -111,7 +115,7 @@ func (s *ErrorHandler) WriteFlowError(w http.ResponseWriter, r *http.Request, f
 		return
 	}
 
-	if err := sortNodes(r.Context(), f.UI.Nodes); err != nil {
+	if err := SortNodes(r.Context(), f.UI.Nodes); err != nil {
 		s.forward(w, r, f, err)
 		return
 	}
@@ -135,6 +139,12 @@ func (s *ErrorHandler) WriteFlowError(w http.ResponseWriter, r *http.Request, f
 	updatedFlow, innerErr := s.d.LoginFlowPersister().GetLoginFlow(r.Context(), f.ID)
 	if innerErr != nil {
 		s.forward(w, r, updatedFlow, innerErr)
+		return
+	}
+
+	if err := flow.ExecuteBeforeRenderHooks(w, r, s.d, updatedFlow); err != nil {
+		s.forward(w, r, updatedFlow, err)
+		return
 	}
 
 	s.d.Writer().WriteCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), updatedFlow)
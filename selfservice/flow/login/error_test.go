@@ -178,6 +178,27 @@ func TestHandleError(t *testing.T) {
 				assert.Equal(t, loginFlow.ID.String(), gjson.GetBytes(body, "id").String())
 			})
 
+			t.Run("case=runs the before render hooks", func(t *testing.T) {
+				t.Cleanup(reset)
+				conf.MustSet(ctx, config.ViperKeySelfServiceThemeVariables, map[string]interface{}{"product_name": "Ory"})
+				t.Cleanup(func() {
+					conf.MustSet(ctx, config.ViperKeySelfServiceThemeVariables, nil)
+				})
+
+				loginFlow = newFlow(t, time.Minute, tc.t)
+				flowError = schema.NewInvalidCredentialsError()
+				ct = node.PasswordGroup
+
+				res, err := ts.Client().Do(testhelpers.NewHTTPGetJSONRequest(t, ts.URL+"/error"))
+				require.NoError(t, err)
+				defer res.Body.Close()
+				require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+				body, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+				assert.Equal(t, "Ory", gjson.GetBytes(body, "theme.product_name").String(), "%s", body)
+			})
+
 			t.Run("case=generic error", func(t *testing.T) {
 				t.Cleanup(reset)
 
@@ -15,6 +15,7 @@ import (
 	"github.com/gobuffalo/pop/v6"
 
 	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 
 	"github.com/ory/x/sqlxx"
 
@@ -34,6 +35,7 @@ import (
 
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
 
@@ -94,6 +96,10 @@ type Flow struct {
 	// ReturnTo contains the requested return_to URL.
 	ReturnTo string `json:"return_to,omitempty" db:"-"`
 
+	// Theme contains the branding variables configured in `selfservice.theme.variables`,
+	// e.g. the logo URL or product name.
+	Theme map[string]interface{} `json:"theme,omitempty" db:"-"`
+
 	// The active login method
 	//
 	// If set contains the login method used. If the flow is new, it is unset.
@@ -135,17 +141,26 @@ type Flow struct {
 	// required: true
 	State State `json:"state" faker:"-" db:"state"`
 
+	// Contains a list of actions, that could follow this flow
+	//
+	// It can, for example, contain a hint to regenerate the lookup secrets.
+	ContinueWithItems []flow.ContinueWith `json:"-" db:"-" faker:"-"`
+
 	// Only used internally
 	IDToken string `json:"-" db:"-"`
 
 	// Only used internally
 	RawIDTokenNonce string `json:"-" db:"-"`
+
+	// RememberDevice is set if the login form asked to remember the browser after completing the
+	// second factor. Only used internally.
+	RememberDevice bool `json:"-" db:"-"`
 }
 
 var _ flow.Flow = new(Flow)
 
 func NewFlow(conf *config.Config, exp time.Duration, csrf string, r *http.Request, flowType flow.Type) (*Flow, error) {
-	now := time.Now().UTC()
+	now := x.Now().UTC()
 	id := x.NewUUID()
 	requestURL := x.RequestURL(r).String()
 
@@ -203,7 +218,7 @@ func (f Flow) WhereID(ctx context.Context, alias string) string {
 }
 
 func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+	if f.ExpiresAt.Before(x.Now()) {
 		return errors.WithStack(flow.NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
@@ -269,6 +284,14 @@ func (f *Flow) GetUI() *container.Container {
 	return f.UI
 }
 
+func (f *Flow) AddContinueWith(c flow.ContinueWith) {
+	f.ContinueWithItems = append(f.ContinueWithItems, c)
+}
+
+func (f *Flow) ContinueWith() []flow.ContinueWith {
+	return f.ContinueWithItems
+}
+
 func (f *Flow) SecureRedirectToOpts(ctx context.Context, cfg config.Provider) (opts []x.SecureRedirectOption) {
 	return []x.SecureRedirectOption{
 		x.SecureRedirectReturnTo(f.ReturnTo),
@@ -287,6 +310,61 @@ func (f *Flow) GetFlowName() flow.FlowName {
 	return flow.LoginFlow
 }
 
+// SetTheme implements flow.ThemedFlow.
+func (f *Flow) SetTheme(theme map[string]interface{}) {
+	f.Theme = theme
+}
+
 func (f *Flow) SetState(state flow.State) {
 	f.State = State(state)
 }
+
+const internalContextReauthenticationHintPath = "reauthentication_hint"
+
+// SetReauthenticationHint stores the hint of an expired session in the flow's internal context.
+func (f *Flow) SetReauthenticationHint(hint *session.ReauthenticationHint) error {
+	f.EnsureInternalContext()
+	bytes, err := sjson.SetBytes(f.InternalContext, internalContextReauthenticationHintPath, hint)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	f.InternalContext = bytes
+	return nil
+}
+
+// ReauthenticationHint returns the hint of an expired session or nil if the flow was not started with one.
+func (f *Flow) ReauthenticationHint() *session.ReauthenticationHint {
+	raw := gjson.GetBytes(f.InternalContext, internalContextReauthenticationHintPath)
+	if !raw.IsObject() {
+		return nil
+	}
+
+	var hint session.ReauthenticationHint
+	if err := json.Unmarshal([]byte(raw.Raw), &hint); err != nil {
+		return nil
+	}
+	return &hint
+}
+
+const internalContextStepUpSessionPath = "step_up_session_id"
+
+// StepUpSessionID returns the ID of the session whose assurance level the flow upgrades or uuid.Nil if the flow was
+// not started through the step-up endpoint.
+func (f *Flow) StepUpSessionID() uuid.UUID {
+	return x.ParseUUID(gjson.GetBytes(f.InternalContext, internalContextStepUpSessionPath).String())
+}
+
+const internalContextRefreshMethodPath = "refresh_method"
+
+// SetRefreshMethod restricts a refresh to the given method, so that only this method has to be re-authenticated.
+func (f *Flow) SetRefreshMethod(method identity.CredentialsType) {
+	f.EnsureInternalContext()
+	// Setting a string on a JSON object can not fail.
+	f.InternalContext, _ = sjson.SetBytes(f.InternalContext, internalContextRefreshMethodPath, method)
+}
+
+// RefreshMethod returns the only method the flow re-authenticates or an empty string if the flow was not
+// started with the `via` query parameter.
+func (f *Flow) RefreshMethod() identity.CredentialsType {
+	return identity.CredentialsType(gjson.GetBytes(f.InternalContext, internalContextRefreshMethodPath).String())
+}
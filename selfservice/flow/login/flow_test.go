@@ -8,6 +8,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
@@ -73,6 +74,19 @@ func TestNewFlow(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("type=theme", func(t *testing.T) {
+		conf, reg := internal.NewFastRegistryWithMocks(t)
+		conf.MustSet(ctx, config.ViperKeySelfServiceThemeVariables, map[string]interface{}{"product_name": "Ory"})
+
+		req := &http.Request{URL: urlx.ParseOrPanic("/"), Host: "ory.sh"}
+		r, err := login.NewFlow(conf, 0, "csrf", req, flow.TypeBrowser)
+		require.NoError(t, err)
+		assert.Empty(t, r.Theme, "the theme is only set when the flow is rendered")
+
+		require.NoError(t, flow.ExecuteBeforeRenderHooks(httptest.NewRecorder(), req.WithContext(ctx), reg, r))
+		assert.Equal(t, map[string]interface{}{"product_name": "Ory"}, r.Theme)
+	})
+
 	t.Run("type=browser", func(t *testing.T) {
 		t.Run("case=regular flow creation without a session", func(t *testing.T) {
 			r, err := login.NewFlow(conf, 0, "csrf", &http.Request{
@@ -201,9 +215,10 @@ func TestDuplicateCredentials(t *testing.T) {
 		t.Parallel()
 		f := new(login.Flow)
 		dc := flow.DuplicateCredentialsData{
-			CredentialsType:     "foo",
-			CredentialsConfig:   sqlxx.JSONRawMessage(`{"bar":"baz"}`),
-			DuplicateIdentifier: "bar",
+			CredentialsType:             "foo",
+			CredentialsConfig:           sqlxx.JSONRawMessage(`{"bar":"baz"}`),
+			DuplicateIdentifier:         "bar",
+			DuplicateIdentifierVerified: true,
 		}
 
 		require.NoError(t, flow.SetDuplicateCredentials(f, dc))
@@ -6,20 +6,22 @@ package login
 import (
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
+	"github.com/tidwall/sjson"
 
 	"github.com/ory/herodot"
 	hydraclientgo "github.com/ory/hydra-client-go/v2"
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/hydra"
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/organization"
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/ratelimit"
 	"github.com/ory/kratos/selfservice/sessiontokenexchange"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/text"
@@ -35,6 +37,7 @@ import (
 const (
 	RouteInitBrowserFlow = "/self-service/login/browser"
 	RouteInitAPIFlow     = "/self-service/login/api"
+	RouteInitStepUpFlow  = "/self-service/login/browser/step-up"
 
 	RouteGetFlow = "/self-service/login/flows"
 
@@ -43,6 +46,7 @@ const (
 
 type (
 	handlerDependencies interface {
+		flow.BeforeRenderHooksProvider
 		HookExecutorProvider
 		FlowPersistenceProvider
 		errorx.ManagementProvider
@@ -57,6 +61,8 @@ type (
 		ErrorHandlerProvider
 		sessiontokenexchange.PersistenceProvider
 		x.LoggingProvider
+		organization.PersistenceProvider
+		ratelimit.ManagementProvider
 	}
 	HandlerProvider interface {
 		LoginHandler() *Handler
@@ -76,15 +82,17 @@ func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	h.d.CSRFHandler().IgnorePath(RouteSubmitFlow)
 
 	public.GET(RouteInitBrowserFlow, h.createBrowserLoginFlow)
+	public.GET(RouteInitStepUpFlow, h.createStepUpLoginFlow)
 	public.GET(RouteInitAPIFlow, h.createNativeLoginFlow)
 	public.GET(RouteGetFlow, h.getLoginFlow)
 
-	public.POST(RouteSubmitFlow, h.updateLoginFlow)
-	public.GET(RouteSubmitFlow, h.updateLoginFlow)
+	public.POST(RouteSubmitFlow, identity.WithSelfServiceAuditActor(h.updateLoginFlow))
+	public.GET(RouteSubmitFlow, identity.WithSelfServiceAuditActor(h.updateLoginFlow))
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 	admin.GET(RouteInitBrowserFlow, x.RedirectToPublicRoute(h.d))
+	admin.GET(RouteInitStepUpFlow, x.RedirectToPublicRoute(h.d))
 	admin.GET(RouteInitAPIFlow, x.RedirectToPublicRoute(h.d))
 	admin.GET(RouteGetFlow, x.RedirectToPublicRoute(h.d))
 
@@ -106,6 +114,15 @@ func WithInternalContext(internalContext []byte) FlowOption {
 	}
 }
 
+// WithStepUpSession binds the flow to the session whose assurance level it upgrades.
+func WithStepUpSession(sessionID uuid.UUID) FlowOption {
+	return func(f *Flow) {
+		f.EnsureInternalContext()
+		// Setting a string on a JSON object can not fail.
+		f.InternalContext, _ = sjson.SetBytes(f.InternalContext, internalContextStepUpSessionPath, sessionID.String())
+	}
+}
+
 func WithFormErrorMessage(messages []text.Message) FlowOption {
 	return func(f *Flow) {
 		for i := range messages {
@@ -157,6 +174,15 @@ func (h *Handler) NewLoginFlow(w http.ResponseWriter, r *http.Request, ft flow.T
 		// We are setting refresh to false if no session exists.
 		f.Refresh = false
 
+		// If the previous session expired recently, we remember who signed in and how.
+		if ft == flow.TypeBrowser {
+			if hint := h.d.SessionManager().FetchReauthenticationHint(r.Context(), r); hint != nil {
+				if err := f.SetReauthenticationHint(hint); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+
 		goto preLoginHook
 	} else if err != nil {
 		// Some other error happened - return that one.
@@ -164,6 +190,10 @@ func (h *Handler) NewLoginFlow(w http.ResponseWriter, r *http.Request, ft flow.T
 	} else {
 		// A session exists already
 		if f.Refresh {
+			if err := restrictRefreshToMethod(r, f, sess); err != nil {
+				return nil, nil, err
+			}
+
 			// We are refreshing so let's continue
 			goto preLoginHook
 		}
@@ -195,6 +225,12 @@ preLoginHook:
 	}
 
 	var strategyFilters []StrategyFilter
+	if hint := f.ReauthenticationHint(); hint != nil {
+		// The session expired recently, so we only ask for the password or passkey of the remembered identifier.
+		f.UI.Messages.Set(text.NewInfoLoginSessionExpired())
+		strategyFilters = []StrategyFilter{func(s Strategy) bool { return s.ID() == hint.Method }}
+	}
+
 	orgID := uuid.NullUUID{
 		Valid: false,
 	}
@@ -213,16 +249,39 @@ preLoginHook:
 
 	if orgID.Valid {
 		f.OrganizationID = orgID
-		strategyFilters = []StrategyFilter{func(s Strategy) bool { return s.ID() == identity.CredentialsTypeOIDC }}
+		org, err := findOrganization(r.Context(), h.d, orgID)
+		if err != nil {
+			return nil, nil, err
+		}
+		strategyFilters = append(strategyFilters, OrganizationStrategyFilter(org))
 	}
 
+	if method := f.RefreshMethod(); method != "" {
+		strategyFilters = append(strategyFilters, func(s Strategy) bool { return s.ID() == method })
+	}
+
+	// Identifier-first login only asks for the identifier and is only used if the method is not yet known.
+	strategyFilters = append(strategyFilters, IdentifierFirstStrategyFilter(
+		h.d.Config().SelfServiceStrategy(r.Context(), identity.CredentialsTypeIdentifierFirst.String()).Enabled &&
+			!orgID.Valid && !f.Refresh && f.ReauthenticationHint() == nil && f.RequestedAAL == identity.AuthenticatorAssuranceLevel1,
+	))
+
 	for _, s := range h.d.LoginStrategies(r.Context(), strategyFilters...) {
 		if err := s.PopulateLoginMethod(r, f.RequestedAAL, f); err != nil {
 			return nil, nil, err
 		}
 	}
 
-	if err := sortNodes(r.Context(), f.UI.Nodes); err != nil {
+	if f.Type == flow.TypeBrowser && f.RequestedAAL > identity.AuthenticatorAssuranceLevel1 && h.d.Config().SelfServiceTrustedDeviceEnabled(r.Context()) {
+		f.UI.Nodes.Append(NewTrustedDeviceRememberNode())
+	}
+
+	// Refreshed and re-authenticated sessions already know the identifier, so the hint is ignored for them.
+	if hint := r.URL.Query().Get("login_hint"); hint != "" && !f.Refresh && f.ReauthenticationHint() == nil {
+		f.UI.Nodes.SetValueAttribute("identifier", hint)
+	}
+
+	if err := SortNodes(r.Context(), f.UI.Nodes); err != nil {
 		return nil, nil, err
 	}
 
@@ -268,6 +327,15 @@ type createNativeLoginFlow struct {
 	// in: query
 	Refresh bool `json:"refresh"`
 
+	// Refresh a Specific Authentication Method
+	//
+	// If set together with `refresh=true`, only the given method (e.g. `password` or `totp`) has to be
+	// re-authenticated instead of all factors of the session. The method must have been used to
+	// authenticate the session.
+	//
+	// in: query
+	Via string `json:"via"`
+
 	// Request a Specific AuthenticationMethod Assurance Level
 	//
 	// Use this parameter to upgrade an existing session's authenticator assurance level (AAL). This
@@ -293,6 +361,13 @@ type createNativeLoginFlow struct {
 	//
 	// in: query
 	ReturnTo string `json:"return_to"`
+
+	// Login Hint
+	//
+	// Pre-fills the identifier, for example with the email address which your application already knows.
+	//
+	// in: query
+	LoginHint string `json:"login_hint"`
 }
 
 // swagger:route GET /self-service/login/api frontend createNativeLoginFlow
@@ -336,6 +411,11 @@ func (h *Handler) createNativeLoginFlow(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, f); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	h.d.Writer().Write(w, r, f)
 }
 
@@ -355,6 +435,15 @@ type createBrowserLoginFlow struct {
 	// in: query
 	Refresh bool `json:"refresh"`
 
+	// Refresh a Specific Authentication Method
+	//
+	// If set together with `refresh=true`, only the given method (e.g. `password` or `totp`) has to be
+	// re-authenticated instead of all factors of the session. The method must have been used to
+	// authenticate the session.
+	//
+	// in: query
+	Via string `json:"via"`
+
 	// Request a Specific AuthenticationMethod Assurance Level
 	//
 	// Use this parameter to upgrade an existing session's authenticator assurance level (AAL). This
@@ -370,6 +459,13 @@ type createBrowserLoginFlow struct {
 	// in: query
 	ReturnTo string `json:"return_to"`
 
+	// Login Hint
+	//
+	// Pre-fills the identifier, for example with the email address which your application already knows.
+	//
+	// in: query
+	LoginHint string `json:"login_hint"`
+
 	// HTTP Cookies
 	//
 	// When using the SDK in a browser app, on the server side you must include the HTTP Cookie Header
@@ -520,9 +616,122 @@ func (h *Handler) createBrowserLoginFlow(w http.ResponseWriter, r *http.Request,
 
 	a.HydraLoginRequest = hydraLoginRequest
 
+	if x.AcceptsJSON(r) {
+		if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, a); err != nil {
+			h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+			return
+		}
+	}
+
 	x.AcceptToRedirectOrJSON(w, r, h.d.Writer(), a, a.AppendTo(h.d.Config().SelfServiceFlowLoginUI(r.Context())).String())
 }
 
+// Create Step-Up Login Flow Parameters
+//
+// swagger:parameters createStepUpLoginFlow
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type createStepUpLoginFlow struct {
+	// Request a Specific AuthenticationMethod Assurance Level
+	//
+	// The authenticator assurance level the session should be upgraded to. Defaults to "aal2".
+	//
+	// in: query
+	RequestAAL identity.AuthenticatorAssuranceLevel `json:"aal"`
+
+	// The URL to return the browser to after the session was upgraded.
+	//
+	// in: query
+	ReturnTo string `json:"return_to"`
+
+	// HTTP Cookies
+	//
+	// When using the SDK in a browser app, on the server side you must include the HTTP Cookie Header
+	// sent by the client to your server here. This ensures that CSRF and session cookies are respected.
+	//
+	// in: header
+	// name: Cookie
+	Cookies string `json:"Cookie"`
+}
+
+// swagger:route GET /self-service/login/browser/step-up frontend createStepUpLoginFlow
+//
+// # Create Step-Up Login Flow for Browsers
+//
+// This endpoint upgrades the authenticator assurance level of an existing browser session, for example
+// before the user performs a sensitive action. It initializes a login flow which requests `aal2` (or the
+// level given in `?aal=`) and which can only be completed by the session that started it.
+//
+// If opened as a link in the browser, it redirects to `selfservice.flows.login.ui_url` with the flow ID set
+// as the query parameter `?flow=`. Once the login flow is completed, the browser is returned to `?return_to=`.
+// If the session already has the requested assurance level, the browser is returned to `?return_to=` right away.
+//
+// In the case of an error, the `error.id` of the JSON response body can be one of:
+//
+// - `session_inactive`: No active session was found in the request.
+// - `session_already_available`: The session already has the requested assurance level.
+// - `security_csrf_violation`: Unable to fetch the flow because a CSRF violation occurred.
+// - `self_service_flow_return_to_forbidden`: The requested `?return_to` address is not allowed to be used. Adjust this in the configuration!
+//
+// This endpoint is NOT INTENDED for clients that do not have a browser (Chrome, Firefox, ...) as cookies are needed.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: loginFlow
+//	  303: emptyResponse
+//	  400: errorGeneric
+//	  401: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) createStepUpLoginFlow(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	sess, err := h.d.SessionManager().FetchFromRequest(ctx, r)
+	if err != nil {
+		h.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+		return
+	}
+
+	// A step-up flow never refreshes the session and requests aal2 unless told otherwise.
+	q := r.URL.Query()
+	q.Del("refresh")
+	if q.Get("aal") == "" {
+		q.Set("aal", string(identity.AuthenticatorAssuranceLevel2))
+	}
+	r.URL.RawQuery = q.Encode()
+
+	f, _, err := h.NewLoginFlow(w, r, flow.TypeBrowser, WithStepUpSession(sess.ID))
+	if errors.Is(err, ErrAlreadyLoggedIn) {
+		returnTo, redirErr := x.SecureRedirectTo(r, h.d.Config().SelfServiceBrowserDefaultReturnTo(ctx),
+			x.SecureRedirectAllowSelfServiceURLs(h.d.Config().SelfPublicURL(ctx)),
+			x.SecureRedirectAllowURLs(h.d.Config().SelfServiceBrowserAllowedReturnToDomains(ctx)),
+		)
+		if redirErr != nil {
+			h.d.SelfServiceErrorManager().Forward(ctx, w, r, redirErr)
+			return
+		}
+
+		x.AcceptToRedirectOrJSON(w, r, h.d.Writer(), err, returnTo.String())
+		return
+	} else if err != nil {
+		h.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+		return
+	}
+
+	if x.AcceptsJSON(r) {
+		if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, f); err != nil {
+			h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+			return
+		}
+	}
+
+	x.AcceptToRedirectOrJSON(w, r, h.d.Writer(), f, f.AppendTo(h.d.Config().SelfServiceFlowLoginUI(ctx)).String())
+}
+
 // Get Login Flow Parameters
 //
 // swagger:parameters getLoginFlow
@@ -603,7 +812,7 @@ func (h *Handler) getLoginFlow(w http.ResponseWriter, r *http.Request, _ httprou
 		return
 	}
 
-	if ar.ExpiresAt.Before(time.Now()) {
+	if ar.ExpiresAt.Before(x.Now()) {
 		if ar.Type == flow.TypeBrowser {
 			redirectURL := flow.GetFlowExpiredRedirectURL(r.Context(), h.d.Config(), RouteInitBrowserFlow, ar.ReturnTo)
 
@@ -630,6 +839,11 @@ func (h *Handler) getLoginFlow(w http.ResponseWriter, r *http.Request, _ httprou
 		ar.HydraLoginRequest = hlr
 	}
 
+	if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, ar); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	h.d.Writer().Write(w, r, ar)
 }
 
@@ -778,9 +992,39 @@ continueLogin:
 		return
 	}
 
+	if id := f.StepUpSessionID(); id != uuid.Nil && id != sess.ID {
+		h.d.LoginFlowErrorHandler().WriteFlowError(w, r, f, node.DefaultGroup, errors.WithStack(ErrStepUpSessionChanged))
+		return
+	}
+
+	if err := h.rateLimit(w, r); err != nil {
+		h.d.LoginFlowErrorHandler().WriteFlowError(w, r, f, node.DefaultGroup, err)
+		return
+	}
+
+	if f.Type == flow.TypeBrowser && f.RequestedAAL > identity.AuthenticatorAssuranceLevel1 {
+		if f.RememberDevice, err = rememberDeviceRequested(r); err != nil {
+			h.d.LoginFlowErrorHandler().WriteFlowError(w, r, f, node.DefaultGroup, err)
+			return
+		}
+	}
+
+	org, err := findOrganization(r.Context(), h.d, f.OrganizationID)
+	if err != nil {
+		h.d.LoginFlowErrorHandler().WriteFlowError(w, r, f, node.DefaultGroup, err)
+		return
+	}
+
 	var i *identity.Identity
 	var group node.UiNodeGroup
 	for _, ss := range h.d.AllLoginStrategies() {
+		if org != nil && !OrganizationStrategyFilter(org)(ss) {
+			continue
+		}
+		if method := f.RefreshMethod(); method != "" && ss.ID() != method {
+			continue
+		}
+
 		interim, err := ss.Login(w, r, f, sess.IdentityID)
 		group = ss.NodeGroup()
 		if errors.Is(err, flow.ErrStrategyNotResponsible) {
@@ -799,7 +1043,11 @@ continueLogin:
 		}
 
 		method := ss.CompletedAuthenticationMethod(r.Context())
-		sess.CompletedLoginForMethod(method)
+		if f.RefreshMethod() != "" {
+			sess.RefreshedLoginForMethod(method)
+		} else {
+			sess.CompletedLoginForMethod(method)
+		}
 		i = interim
 		break
 	}
@@ -29,6 +29,7 @@ import (
 	stdtotp "github.com/pquerna/otp/totp"
 
 	"github.com/ory/kratos/ui/container"
+	"github.com/ory/kratos/ui/node"
 
 	"github.com/ory/kratos/text"
 
@@ -552,6 +553,11 @@ func TestFlowLifecycle(t *testing.T) {
 				assert.NotEmpty(t, gjson.GetBytes(body, "session_token_exchange_code").String())
 			})
 
+			t.Run("case=prefills the identifier with the login hint", func(t *testing.T) {
+				_, body := initFlow(t, url.Values{"login_hint": {"hint@ory.sh"}}, true)
+				assert.Equal(t, "hint@ory.sh", gjson.GetBytes(body, `ui.nodes.#(attributes.name=="identifier").attributes.value`).String(), "%s", body)
+			})
+
 			t.Run("case=can not request refresh and aal at the same time on unauthenticated request", func(t *testing.T) {
 				res, body := initFlow(t, url.Values{"refresh": {"true"}, "aal": {"aal2"}}, true)
 				assert.Contains(t, res.Request.URL.String(), login.RouteInitAPIFlow)
@@ -619,6 +625,19 @@ func TestFlowLifecycle(t *testing.T) {
 				assertion(body, true, true)
 				assert.Equal(t, gjson.GetBytes(body, "ui.messages.0.text").String(), text.NewInfoLoginReAuth().Text)
 			})
+
+			t.Run("case=refreshes only the requested method", func(t *testing.T) {
+				res, body := initAuthenticatedFlow(t, url.Values{"refresh": {"true"}, "via": {"password"}, "aal": {"aal2"}}, true)
+				assert.Contains(t, res.Request.URL.String(), login.RouteInitAPIFlow)
+				assertion(body, true, true)
+				assert.Equal(t, "aal1", gjson.GetBytes(body, "requested_aal").String(), "%s", body)
+			})
+
+			t.Run("case=can not refresh a method the session was not authenticated with", func(t *testing.T) {
+				res, body := initAuthenticatedFlow(t, url.Values{"refresh": {"true"}, "via": {"totp"}}, true)
+				assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+				assert.Equal(t, `The session was not authenticated via "totp" and can not be refreshed with it.`, gjson.GetBytes(body, "error.reason").String(), "%s", body)
+			})
 		})
 
 		t.Run("flow=browser", func(t *testing.T) {
@@ -779,6 +798,55 @@ func TestFlowLifecycle(t *testing.T) {
 			})
 		})
 
+		t.Run("flow=step-up", func(t *testing.T) {
+			initStepUpFlow := func(t *testing.T, query url.Values) (*http.Response, []byte) {
+				req := testhelpers.NewTestHTTPRequest(t, "GET", ts.URL+login.RouteInitStepUpFlow, nil)
+				req.URL.RawQuery = query.Encode()
+				body, res := testhelpers.MockMakeAuthenticatedRequest(t, reg, conf, router.Router, req)
+				return res, body
+			}
+
+			t.Run("case=requires a session", func(t *testing.T) {
+				res, err := ts.Client().Get(ts.URL + login.RouteInitStepUpFlow)
+				require.NoError(t, err)
+				defer res.Body.Close()
+				assert.Contains(t, res.Request.URL.String(), errorTS.URL)
+			})
+
+			t.Run("case=requests aal2 by default", func(t *testing.T) {
+				res, body := initStepUpFlow(t, url.Values{"refresh": {"true"}})
+				assert.Contains(t, res.Request.URL.String(), loginTS.URL)
+				assert.Equal(t, "aal2", gjson.GetBytes(body, "requested_aal").String(), "%s", body)
+				assert.False(t, gjson.GetBytes(body, "refresh").Bool(), "%s", body)
+				assert.Equal(t, text.NewInfoLoginMFA().Text, gjson.GetBytes(body, "ui.messages.0.text").String(), "%s", body)
+
+				f, err := reg.LoginFlowPersister().GetLoginFlow(ctx, uuid.FromStringOrNil(gjson.GetBytes(body, "id").String()))
+				require.NoError(t, err)
+				assert.NotEqual(t, uuid.Nil, f.StepUpSessionID())
+			})
+
+			t.Run("case=returns right away if the session has the requested aal already", func(t *testing.T) {
+				res, _ := initStepUpFlow(t, url.Values{"set_aal": {"aal2"}})
+				assert.Contains(t, res.Request.URL.String(), "https://www.ory.sh")
+			})
+
+			t.Run("case=can only be completed by the session which started it", func(t *testing.T) {
+				f := login.Flow{
+					Type: flow.TypeAPI, ExpiresAt: time.Now().Add(time.Minute), IssuedAt: time.Now(),
+					UI: container.New(""), RequestedAAL: identity.AuthenticatorAssuranceLevel2,
+				}
+				login.WithStepUpSession(x.NewUUID())(&f)
+				require.NoError(t, reg.LoginFlowPersister().CreateLoginFlow(ctx, &f))
+
+				req, err := http.NewRequest("POST", ts.URL+login.RouteSubmitFlow+"?flow="+f.ID.String(), strings.NewReader(url.Values{"method": {"password"}}.Encode()))
+				require.NoError(t, err)
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+				body, _ := testhelpers.MockMakeAuthenticatedRequest(t, reg, conf, router.Router, req)
+				assert.Equal(t, login.ErrStepUpSessionChanged.Reason(), gjson.GetBytes(body, "error.reason").String(), "%s", body)
+			})
+		})
+
 		t.Run("case=relative redirect when self-service login ui is a relative URL", func(t *testing.T) {
 			reg.Config().MustSet(ctx, config.ViperKeySelfServiceLoginUI, "/login-ts")
 			assert.Regexp(
@@ -891,3 +959,86 @@ func TestGetFlow(t *testing.T) {
 		assert.EqualValues(t, http.StatusNotFound, res.StatusCode)
 	})
 }
+
+func TestReauthenticationHint(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	router := x.NewRouterPublic()
+	ts, _ := testhelpers.NewKratosServerWithRouters(t, reg, router, x.NewRouterAdmin())
+	_ = testhelpers.NewLoginUIFlowEchoServer(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/password.schema.json")
+	conf.MustSet(ctx, config.ViperKeySelfServiceBrowserDefaultReturnTo, "https://www.ory.sh")
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".password.enabled", true)
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".code.passwordless_enabled", true)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginSoftReauthenticationEnabled, true)
+
+	identifier := x.NewUUID().String()
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, &identity.Identity{
+		Credentials: map[identity.CredentialsType]identity.Credentials{
+			identity.CredentialsTypePassword: {
+				Type:        identity.CredentialsTypePassword,
+				Identifiers: []string{identifier},
+				Config:      sqlxx.JSONRawMessage(`{"hashed_password":"$2a$08$.cOYmAd.vCpDOoiVJrO5B.hjTLKQQ6cAK40u8uB.FnZDyPvVvQ9Q."}`), // foobar
+			},
+		},
+		State:  identity.StateActive,
+		Traits: identity.Traits(`{"username":"` + identifier + `"}`),
+	}))
+
+	hintCookies := func(t *testing.T, c *http.Client) (cookies []*http.Cookie) {
+		for _, c := range c.Jar.Cookies(urlx.ParseOrPanic(ts.URL)) {
+			if c.Name == session.ReauthenticationHintCookieName {
+				cookies = append(cookies, c)
+			}
+		}
+		return cookies
+	}
+
+	signIn := func(t *testing.T, isAPI bool) *http.Client {
+		c := testhelpers.NewClientWithCookies(t)
+		testhelpers.SubmitLoginForm(t, isAPI, c, ts, func(v url.Values) {
+			v.Set("method", "password")
+			v.Set("identifier", identifier)
+			v.Set("password", "foobar")
+		}, !isAPI, false, http.StatusOK, "")
+		return c
+	}
+
+	t.Run("flow=browser", func(t *testing.T) {
+		// The new client only carries the hint, as if the session had expired.
+		c := testhelpers.NewClientWithCookies(t)
+		c.Jar.SetCookies(urlx.ParseOrPanic(ts.URL), hintCookies(t, signIn(t, false)))
+		require.Len(t, hintCookies(t, c), 1)
+
+		f := testhelpers.InitializeLoginFlowViaBrowser(t, c, ts, false, true, false, false)
+		raw, err := json.Marshal(f)
+		require.NoError(t, err)
+		body := string(raw)
+
+		assert.EqualValues(t, text.InfoSelfServiceLoginSessionExpired, gjson.Get(body, "ui.messages.0.id").Int(), "%s", body)
+		assert.Equal(t, identifier, gjson.Get(body, `ui.nodes.#(attributes.name=="identifier").attributes.value`).String(), "%s", body)
+		assert.Equal(t, "hidden", gjson.Get(body, `ui.nodes.#(attributes.name=="identifier").attributes.type`).String(), "the identifier can not be changed: %s", body)
+		assert.True(t, gjson.Get(body, `ui.nodes.#(attributes.name=="password")`).Exists(), "%s", body)
+
+		for _, g := range gjson.Get(body, "ui.nodes.#.group").Array() {
+			assert.Contains(t, []string{string(node.DefaultGroup), string(node.PasswordGroup)}, g.String(), "only the remembered method is offered: %s", body)
+		}
+	})
+
+	t.Run("flow=api", func(t *testing.T) {
+		assert.Empty(t, hintCookies(t, signIn(t, true)), "API flows never receive the hint cookie")
+
+		// Even if a hint is sent along, API flows ignore it.
+		c := testhelpers.NewClientWithCookies(t)
+		c.Jar.SetCookies(urlx.ParseOrPanic(ts.URL), hintCookies(t, signIn(t, false)))
+		f := testhelpers.InitializeLoginFlowViaAPI(t, c, ts, false)
+		raw, err := json.Marshal(f)
+		require.NoError(t, err)
+		body := string(raw)
+
+		assert.Empty(t, gjson.Get(body, "ui.messages").Array(), "%s", body)
+		assert.Empty(t, gjson.Get(body, `ui.nodes.#(attributes.name=="identifier").attributes.value`).String(), "%s", body)
+	})
+}
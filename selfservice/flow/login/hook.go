@@ -7,7 +7,6 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
@@ -58,6 +57,8 @@ type (
 
 		FlowPersistenceProvider
 		HooksProvider
+		NewDeviceNotifierProvider
+		RiskAssessorProvider
 		StrategyProvider
 	}
 	HookExecutor struct {
@@ -95,6 +96,44 @@ func (e *HookExecutor) requiresAAL2(r *http.Request, s *session.Session, a *Flow
 	return false, nil
 }
 
+// assessRisk asks the risk engine whether the session may be issued. Step-up logins are
+// not assessed because they already require a second factor.
+func (e *HookExecutor) assessRisk(r *http.Request, a *Flow, i *identity.Identity, s *session.Session) error {
+	ctx := r.Context()
+	if !e.d.Config().SelfServiceFlowLoginRiskAssessmentEnabled(ctx) || a.RequestedAAL > identity.AuthenticatorAssuranceLevel1 {
+		return nil
+	}
+
+	decision, err := e.d.LoginRiskAssessor().AssessLoginRisk(ctx, NewRiskContext(r, a, s))
+	if err != nil {
+		decision = RiskDecision(e.d.Config().SelfServiceFlowLoginRiskAssessmentOnError(ctx))
+		e.d.Logger().
+			WithRequest(r).
+			WithError(err).
+			WithField("identity_id", i.ID).
+			WithField("risk_decision", decision).
+			Warn("Unable to assess the login risk, falling back to the configured decision.")
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("risk_decision", string(decision)))
+
+	switch decision {
+	case RiskDecisionDeny:
+		return errors.WithStack(schema.NewLoginRiskDeniedError())
+	case RiskDecisionRequireAAL2:
+		if s.AuthenticatorAssuranceLevel > identity.AuthenticatorAssuranceLevel1 {
+			return nil
+		}
+		if available, ok := i.AvailableAAL.ToAAL(); ok && available <= identity.AuthenticatorAssuranceLevel1 {
+			// The identity has no second factor it could use to step up.
+			return errors.WithStack(schema.NewLoginRiskDeniedError())
+		}
+		s.RequiredAAL = identity.AuthenticatorAssuranceLevel2
+	}
+
+	return nil
+}
+
 func (e *HookExecutor) handleLoginError(_ http.ResponseWriter, r *http.Request, g node.UiNodeGroup, f *Flow, i *identity.Identity, flowError error) error {
 	if f != nil {
 		if i != nil {
@@ -136,10 +175,15 @@ func (e *HookExecutor) PostLoginHook(
 		return err
 	}
 
-	if err := s.Activate(r, i, e.d.Config(), time.Now().UTC()); err != nil {
+	if err := s.Activate(r, i, e.d.Config(), x.Now().UTC()); err != nil {
 		return err
 	}
 
+	if err := e.assessRisk(r, a, i, s); err != nil {
+		return e.handleLoginError(w, r, g, a, i, err)
+	}
+	e.trustDevice(r, a, i, s)
+
 	c := e.d.Config()
 	// Verify the redirect URL before we do any other processing.
 	returnTo, err := x.SecureRedirectTo(r,
@@ -167,8 +211,22 @@ func (e *HookExecutor) PostLoginHook(
 		WithField("identity_id", i.ID).
 		WithField("flow_method", a.Active).
 		Debug("Running ExecuteLoginPostHook.")
+	var hookResponse *flow.HookResponse
 	for k, executor := range e.d.PostLoginHooks(r.Context(), a.Active) {
 		if err := executor.ExecuteLoginPostHook(w, r, g, a, s); err != nil {
+			if errors.As(err, &hookResponse) {
+				e.d.Logger().
+					WithRequest(r).
+					WithField("executor", fmt.Sprintf("%T", executor)).
+					WithField("executor_position", k).
+					WithField("executors", PostHookExecutorNames(e.d.PostLoginHooks(r.Context(), a.Active))).
+					WithField("identity_id", i.ID).
+					WithField("flow_method", a.Active).
+					Debug("A ExecuteLoginPostHook hook responded to the flow.")
+
+				span.SetAttributes(attribute.String("redirect_reason", "responded by hook"), attribute.String("executor", fmt.Sprintf("%T", executor)))
+				break
+			}
 			if errors.Is(err, ErrHookAbortFlow) {
 				e.d.Logger().
 					WithRequest(r).
@@ -196,11 +254,38 @@ func (e *HookExecutor) PostLoginHook(
 			Debug("ExecuteLoginPostHook completed successfully.")
 	}
 
+	if err := e.d.LoginNewDeviceNotifier().NotifyNewDevice(r, a, i, classified); err != nil {
+		// The login succeeded, a failed notification must not prevent the identity from signing in.
+		e.d.Logger().
+			WithRequest(r).
+			WithError(err).
+			WithField("identity_id", i.ID).
+			Warn("Unable to notify the identity about a login from a new device.")
+	}
+
+	if hookResponse != nil && hookResponse.RedirectTo != "" {
+		redirectTo, err := x.SecureRedirectTo(r, returnTo,
+			x.SecureRedirectReturnTo(hookResponse.RedirectTo),
+			x.SecureRedirectAllowURLs(c.SelfServiceBrowserAllowedReturnToDomains(r.Context())),
+			x.SecureRedirectAllowSelfServiceURLs(c.SelfPublicURL(r.Context())),
+		)
+		if err != nil {
+			return err
+		}
+		hookResponse.RedirectTo = redirectTo.String()
+	}
+
 	if a.Type == flow.TypeAPI {
 		span.SetAttributes(attribute.String("flow_type", string(flow.TypeAPI)))
+		if err := e.d.SessionManager().BindSessionToRequestKey(r.Context(), r, s); err != nil {
+			return errors.WithStack(err)
+		}
 		if err := e.d.SessionPersister().UpsertSession(r.Context(), s); err != nil {
 			return errors.WithStack(err)
 		}
+		if err := e.d.SessionManager().LinkUpstreamSession(r.Context(), a, s); err != nil {
+			return err
+		}
 		e.d.Audit().
 			WithRequest(r).
 			WithField("session_id", s.ID).
@@ -225,7 +310,19 @@ func (e *HookExecutor) PostLoginHook(
 			return nil
 		}
 
-		response := &APIFlowResponse{Session: s, Token: s.Token}
+		if hookResponse != nil {
+			hookResponse.AddContinueWith(flow.NewContinueWithSetToken(s.Token))
+			hookResponse.AddContinueWith(a.ContinueWith()...)
+			hookResponse.Write(w, r, a.Type, e.d.Writer())
+			return nil
+		}
+
+		refreshToken, err := e.d.SessionManager().IssueRefreshToken(r.Context(), s)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		response := &APIFlowResponse{Session: s, Token: s.Token, RefreshToken: refreshToken, ContinueWith: a.ContinueWith()}
 		if required, _ := e.requiresAAL2(r, classified, a); required {
 			// If AAL is not satisfied, we omit the identity to preserve the user's privacy in case of a phishing attack.
 			response.Session.Identity = nil
@@ -238,6 +335,22 @@ func (e *HookExecutor) PostLoginHook(
 	if err := e.d.SessionManager().UpsertAndIssueCookie(r.Context(), w, r, s); err != nil {
 		return errors.WithStack(err)
 	}
+	if err := e.d.SessionManager().LinkUpstreamSession(r.Context(), a, s); err != nil {
+		return err
+	}
+
+	if a.RememberDevice && s.AuthenticatorAssuranceLevel > identity.AuthenticatorAssuranceLevel1 {
+		if err := e.d.SessionManager().IssueTrustedDeviceCookie(r.Context(), w, r, s); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	// API flows never send cookies, so only browser flows remember the identifier and method.
+	if a.Type == flow.TypeBrowser && (a.Active == identity.CredentialsTypePassword || a.Active == identity.CredentialsTypeWebAuthn) {
+		if err := e.d.SessionManager().IssueReauthenticationHint(r.Context(), w, r, session.NewReauthenticationHint(i, a.Active, s.ExpiresAt)); err != nil {
+			return errors.WithStack(err)
+		}
+	}
 
 	e.d.Audit().
 		WithRequest(r).
@@ -285,7 +398,13 @@ func (e *HookExecutor) PostLoginHook(
 			return err
 		}
 
-		response := &APIFlowResponse{Session: s}
+		if hookResponse != nil {
+			hookResponse.AddContinueWith(a.ContinueWith()...)
+			hookResponse.Write(w, r, a.Type, e.d.Writer())
+			return nil
+		}
+
+		response := &APIFlowResponse{Session: s, ContinueWith: a.ContinueWith()}
 		e.d.Writer().Write(w, r, response)
 		return nil
 	}
@@ -299,6 +418,11 @@ func (e *HookExecutor) PostLoginHook(
 		return errors.WithStack(err)
 	}
 
+	if hookResponse != nil && a.OAuth2LoginChallenge == "" {
+		hookResponse.Write(w, r, a.Type, e.d.Writer())
+		return nil
+	}
+
 	finalReturnTo := returnTo.String()
 	if a.OAuth2LoginChallenge != "" {
 		rt, err := e.d.Hydra().AcceptLoginRequest(r.Context(),
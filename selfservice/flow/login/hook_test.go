@@ -111,6 +111,42 @@ func TestLoginExecutor(t *testing.T) {
 					assert.Equal(t, "", body)
 				})
 
+				t.Run("case=hook responds with a custom redirect", func(t *testing.T) {
+					t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
+					conf.MustSet(ctx, config.ViperKeyURLsAllowedReturnToDomains, []string{"https://www.ory.sh/"})
+					viperSetPost(t, conf, strategy.String(), []config.SelfServiceHook{
+						{Name: "err", Config: []byte(`{"ExecuteLoginPostHook": "respond", "redirect_to": "https://www.ory.sh/custom"}`)},
+						{Name: "err", Config: []byte(`{"ExecuteLoginPostHook": "err"}`)},
+					})
+
+					res, _ := makeRequestPost(t, newServer(t, flow.TypeBrowser, nil), false, url.Values{})
+					assert.EqualValues(t, http.StatusOK, res.StatusCode)
+					assert.EqualValues(t, "https://www.ory.sh/custom", res.Request.URL.String())
+				})
+
+				t.Run("case=hook response redirect must be allowed", func(t *testing.T) {
+					t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
+					viperSetPost(t, conf, strategy.String(), []config.SelfServiceHook{
+						{Name: "err", Config: []byte(`{"ExecuteLoginPostHook": "respond", "redirect_to": "https://evil.example.com/"}`)},
+					})
+
+					res, _ := makeRequestPost(t, newServer(t, flow.TypeBrowser, nil), false, url.Values{})
+					assert.EqualValues(t, http.StatusInternalServerError, res.StatusCode)
+				})
+
+				t.Run("case=hook response includes the session token for API clients", func(t *testing.T) {
+					t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
+					viperSetPost(t, conf, strategy.String(), []config.SelfServiceHook{
+						{Name: "err", Config: []byte(`{"ExecuteLoginPostHook": "respond", "redirect_to": "https://www.ory.sh/"}`)},
+					})
+
+					res, body := makeRequestPost(t, newServer(t, flow.TypeAPI, nil), true, url.Values{})
+					assert.EqualValues(t, http.StatusOK, res.StatusCode)
+					assert.Equal(t, "https://www.ory.sh/", gjson.Get(body, "redirect_browser_to").String(), body)
+					assert.Equal(t, "set_ory_session_token", gjson.Get(body, "continue_with.0.action").String(), body)
+					assert.NotEmpty(t, gjson.Get(body, "continue_with.0.ory_session_token").String(), body)
+				})
+
 				t.Run("case=use return_to value", func(t *testing.T) {
 					t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
 					conf.MustSet(ctx, config.ViperKeyURLsAllowedReturnToDomains, []string{"https://www.ory.sh/"})
@@ -0,0 +1,122 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package login
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/ory/x/httpx"
+	"github.com/ory/x/stringslice"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/courier/template/email"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow/recovery"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	newDeviceNotifierDependencies interface {
+		config.Provider
+		courier.Provider
+		courier.ConfigProvider
+		identity.ValidationProvider
+		session.PersistenceProvider
+		x.HTTPClientProvider
+		x.LoggingProvider
+	}
+
+	// NewDeviceNotifier remembers the devices and IP addresses identities sign in from, and sends an
+	// email if an identity signs in from a device or IP address it did not use before.
+	NewDeviceNotifier struct {
+		d newDeviceNotifierDependencies
+	}
+
+	NewDeviceNotifierProvider interface {
+		LoginNewDeviceNotifier() *NewDeviceNotifier
+	}
+)
+
+func NewNewDeviceNotifier(d newDeviceNotifierDependencies) *NewDeviceNotifier {
+	return &NewDeviceNotifier{d: d}
+}
+
+// NotifyNewDevice records the device of the login and notifies the email addresses of the identity if
+// the device or the IP address is new. The first login of an identity is never notified. It does nothing
+// unless enabled in the config.
+func (n *NewDeviceNotifier) NotifyNewDevice(r *http.Request, f *Flow, i *identity.Identity, s *session.Session) error {
+	ctx := r.Context()
+	if !n.d.Config().SelfServiceFlowLoginNewDeviceNotificationEnabled(ctx) {
+		return nil
+	}
+
+	device := &session.KnownDevice{
+		IdentityID:  i.ID,
+		Fingerprint: deviceFingerprint(r),
+		IPAddress:   httpx.ClientIP(r),
+		UserAgent:   r.UserAgent(),
+		Location:    session.ClientLocation(r, n.d.Config().SessionDevicesLocationHeaders(ctx)),
+	}
+
+	match, err := n.d.SessionPersister().MatchKnownDevice(ctx, i.ID, device.Fingerprint, device.IPAddress)
+	if err != nil {
+		return err
+	}
+
+	if err := n.d.SessionPersister().UpsertKnownDevice(ctx, device); err != nil {
+		return err
+	}
+
+	if !match.IsNew() || !stringslice.Has(n.d.Config().SelfServiceFlowLoginNewDeviceNotificationFlowTypes(ctx), string(f.Type)) {
+		return nil
+	}
+
+	model, err := x.StructToMap(i)
+	if err != nil {
+		return err
+	}
+
+	locale := n.d.IdentityValidator().Locale(ctx, i)
+
+	c, err := n.d.Courier(ctx)
+	if err != nil {
+		return err
+	}
+
+	recoveryURL := urlx.AppendPaths(n.d.Config().SelfPublicURL(ctx), recovery.RouteInitBrowserFlow).String()
+	for _, to := range i.EmailAddresses() {
+		n.d.Audit().
+			WithField("identity_id", i.ID).
+			WithSensitiveField("address", to).
+			Info("Sending out new device notification.")
+
+		if _, err := c.QueueEmail(ctx, email.NewLoginNewDevice(n.d, &email.LoginNewDeviceModel{
+			To:          to,
+			IPAddress:   device.IPAddress,
+			UserAgent:   device.UserAgent,
+			Location:    device.Location,
+			LoggedInAt:  x.Now().UTC(),
+			RecoveryURL: recoveryURL,
+			Identity:    model,
+			Locale:      locale,
+		})); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deviceFingerprint identifies the device of the request by its user agent and the device fingerprint
+// sent by the client, if any.
+func deviceFingerprint(r *http.Request) string {
+	sum := sha256.Sum256([]byte(strings.Join(r.Header["User-Agent"], " ") + "\n" + r.Header.Get(RiskDeviceFingerprintHeader)))
+	return hex.EncodeToString(sum[:])
+}
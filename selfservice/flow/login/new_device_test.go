@@ -0,0 +1,118 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package login_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/session"
+	"github.com/ory/x/pagination/keysetpagination"
+	"github.com/ory/x/sqlxx"
+)
+
+func TestNewDeviceNotifier(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/password.schema.json")
+
+	newIdentity := func(t *testing.T) *identity.Identity {
+		email := testhelpers.RandomEmail()
+		i := &identity.Identity{
+			Credentials: map[identity.CredentialsType]identity.Credentials{
+				identity.CredentialsTypePassword: {
+					Type:        identity.CredentialsTypePassword,
+					Identifiers: []string{email},
+					Config:      sqlxx.JSONRawMessage(`{}`),
+				},
+			},
+			State:  identity.StateActive,
+			Traits: identity.Traits(`{"username":"` + email + `"}`),
+		}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+		return i
+	}
+
+	messagesTo := func(t *testing.T, recipient string) []courier.Message {
+		messages, _, _, err := reg.CourierPersister().ListMessages(ctx, courier.ListCourierMessagesParameters{
+			Recipient: recipient,
+		}, []keysetpagination.Option{})
+		require.NoError(t, err)
+		return messages
+	}
+
+	newRequest := func(ip, userAgent string) *http.Request {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.RemoteAddr = ip + ":1234"
+		r.Header.Set("User-Agent", userAgent)
+		return r
+	}
+
+	signIn := func(t *testing.T, r *http.Request, ft flow.Type, i *identity.Identity) {
+		f := &login.Flow{Type: ft}
+		require.NoError(t, reg.LoginNewDeviceNotifier().NotifyNewDevice(r, f, i, session.NewInactiveSession()))
+	}
+
+	t.Run("case=does nothing if disabled", func(t *testing.T) {
+		i := newIdentity(t)
+		signIn(t, newRequest("192.0.2.1", "Firefox"), flow.TypeBrowser, i)
+
+		match, err := reg.SessionPersister().MatchKnownDevice(ctx, i.ID, "", "192.0.2.1")
+		require.NoError(t, err)
+		assert.False(t, match.Any)
+	})
+
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginNewDeviceNotificationEnabled, true)
+
+	t.Run("case=notifies new devices and IP addresses", func(t *testing.T) {
+		i := newIdentity(t)
+		to := i.EmailAddresses()[0]
+
+		signIn(t, newRequest("192.0.2.1", "Firefox"), flow.TypeBrowser, i)
+		assert.Empty(t, messagesTo(t, to), "the first login is not notified")
+
+		signIn(t, newRequest("192.0.2.1", "Firefox"), flow.TypeBrowser, i)
+		assert.Empty(t, messagesTo(t, to), "known devices are not notified")
+
+		signIn(t, newRequest("192.0.2.2", "Firefox"), flow.TypeBrowser, i)
+		messages := messagesTo(t, to)
+		require.Len(t, messages, 1)
+		assert.Equal(t, courier.TypeLoginNewDevice, messages[0].TemplateType)
+		assert.Contains(t, messages[0].Body, "192.0.2.2")
+		assert.Contains(t, messages[0].Body, "/self-service/recovery/browser")
+
+		signIn(t, newRequest("192.0.2.1", "Chrome"), flow.TypeBrowser, i)
+		assert.Len(t, messagesTo(t, to), 2)
+	})
+
+	t.Run("case=only notifies the configured flow types", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceLoginNewDeviceNotificationFlowTypes, []string{"browser"})
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceLoginNewDeviceNotificationFlowTypes, []string{"browser", "api"})
+		})
+
+		i := newIdentity(t)
+		to := i.EmailAddresses()[0]
+
+		signIn(t, newRequest("192.0.2.1", "Firefox"), flow.TypeBrowser, i)
+		signIn(t, newRequest("192.0.2.2", "Firefox"), flow.TypeAPI, i)
+		assert.Empty(t, messagesTo(t, to))
+
+		match, err := reg.SessionPersister().MatchKnownDevice(ctx, i.ID, "", "192.0.2.2")
+		require.NoError(t, err)
+		assert.True(t, match.IPAddress, "devices are remembered for all flow types")
+	})
+}
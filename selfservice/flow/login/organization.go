@@ -0,0 +1,63 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package login
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/organization"
+	"github.com/ory/x/sqlcon"
+)
+
+// OrganizationStrategyFilter only allows the login methods of the organization. If the organization is
+// unknown, for example because it is only referenced by OpenID Connect providers, only OpenID Connect
+// is allowed.
+func OrganizationStrategyFilter(org *organization.Organization) StrategyFilter {
+	return func(s Strategy) bool {
+		if org == nil {
+			return s.ID() == identity.CredentialsTypeOIDC
+		}
+		return org.AllowsMethod(s.ID())
+	}
+}
+
+// IdentifierFirstStrategyFilter only allows the identifier-first method if enabled is true and all other
+// methods otherwise.
+func IdentifierFirstStrategyFilter(enabled bool) StrategyFilter {
+	return func(s Strategy) bool {
+		return (s.ID() == identity.CredentialsTypeIdentifierFirst) == enabled
+	}
+}
+
+// IdentityCredentialsStrategyFilter only allows the login methods for which the identity has credentials.
+// Login codes are always allowed, because identities without code credentials can sign in with a code
+// sent to one of their identifiers.
+func IdentityCredentialsStrategyFilter(i *identity.Identity) StrategyFilter {
+	return func(s Strategy) bool {
+		if s.ID() == identity.CredentialsTypeCodeAuth {
+			return true
+		}
+		c, ok := i.GetCredentials(s.ID())
+		return ok && len(c.Identifiers) > 0
+	}
+}
+
+// findOrganization returns the organization or nil if no organization with the ID exists.
+func findOrganization(ctx context.Context, d organization.PersistenceProvider, id uuid.NullUUID) (*organization.Organization, error) {
+	if !id.Valid {
+		return nil, nil
+	}
+
+	org, err := d.OrganizationPersister().GetOrganization(ctx, id.UUID)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
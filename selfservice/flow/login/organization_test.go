@@ -0,0 +1,33 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package login_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flow/login"
+)
+
+func TestIdentityCredentialsStrategyFilter(t *testing.T) {
+	_, reg := internal.NewFastRegistryWithMocks(t)
+
+	i := &identity.Identity{Credentials: map[identity.CredentialsType]identity.Credentials{
+		identity.CredentialsTypePassword: {Type: identity.CredentialsTypePassword, Identifiers: []string{"foo@ory.sh"}},
+		identity.CredentialsTypeWebAuthn: {Type: identity.CredentialsTypeWebAuthn},
+	}}
+
+	var allowed []identity.CredentialsType
+	for _, s := range reg.AllLoginStrategies() {
+		if login.IdentityCredentialsStrategyFilter(i)(s) {
+			allowed = append(allowed, s.ID())
+		}
+	}
+
+	assert.ElementsMatch(t, []identity.CredentialsType{identity.CredentialsTypePassword, identity.CredentialsTypeCodeAuth}, allowed,
+		"credentials without identifiers do not allow a login method")
+}
@@ -0,0 +1,51 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package login
+
+import (
+	_ "embed"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/selfservice/ratelimit"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+)
+
+//go:embed .schema/ratelimit.schema.json
+var rateLimitSchema []byte
+
+var rateLimitDecoder = decoderx.NewHTTP()
+
+// rateLimit counts the login attempt for the client IP address and the submitted identifier. If a
+// limit is exceeded, the `Retry-After` header is set and a *ratelimit.RateLimitedError returned.
+func (h *Handler) rateLimit(w http.ResponseWriter, r *http.Request) error {
+	if !h.d.Config().SelfServiceFlowLoginRateLimitEnabled(r.Context()) {
+		return nil
+	}
+
+	var body struct {
+		Identifier string `json:"identifier" form:"identifier"`
+	}
+
+	compiler, err := decoderx.HTTPRawJSONSchemaCompiler(rateLimitSchema)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// The strategies validate the payload, here we only need the identifier if there is one.
+	_ = rateLimitDecoder.Decode(r, &body, compiler,
+		decoderx.HTTPKeepRequestBody(true),
+		decoderx.HTTPDecoderAllowedMethods("POST", "PUT", "PATCH", "GET"),
+		decoderx.HTTPDecoderSetValidatePayloads(false),
+		decoderx.HTTPDecoderJSONFollowsFormFormat())
+
+	err = h.d.LoginRateLimiter().Limit(r.Context(), x.TrustedClientIP(r, h.d.Config().PublicTrustedProxies(r.Context())), body.Identifier)
+	if e := new(ratelimit.RateLimitedError); errors.As(err, &e) {
+		w.Header().Set("Retry-After", strconv.FormatInt(ratelimit.RetryAfterSeconds(e.RetryAfter), 10))
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package login_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/ioutilx"
+)
+
+func TestRateLimit(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	public, _ := testhelpers.NewKratosServer(t, reg)
+	_ = testhelpers.NewLoginUIFlowEchoServer(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/password.schema.json")
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".password.enabled", true)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginRateLimitEnabled, true)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginRateLimitIPLimit, 2)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginRateLimitIPWindow, "1m")
+
+	t.Run("case=spoofed headers and new connections share the IP address limit", func(t *testing.T) {
+		f := testhelpers.InitializeLoginFlowViaAPI(t, testhelpers.NewClientWithCookies(t), public, false)
+
+		submit := func(t *testing.T, forwardedFor string) (*http.Response, string) {
+			// A new connection per request, so that every request is sent from another port.
+			c := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+			req, err := http.NewRequest("POST", f.Ui.Action, bytes.NewBufferString(`{"method":"password","identifier":"`+x.NewUUID().String()+`","password":"not-the-password"}`))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json")
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			req.Header.Set("X-Real-IP", forwardedFor)
+			req.Header.Set("True-Client-IP", forwardedFor)
+
+			res, err := c.Do(req)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			return res, string(ioutilx.MustReadAll(res.Body))
+		}
+
+		for _, ip := range []string{"54.155.246.1", "54.155.246.2"} {
+			res, body := submit(t, ip)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s", body)
+		}
+
+		res, body := submit(t, "54.155.246.3")
+		assert.Equal(t, http.StatusTooManyRequests, res.StatusCode, "%s", body)
+		assert.Equal(t, text.ErrIDSelfServiceFlowRateLimited, gjson.Get(body, "error.id").String(), "%s", body)
+		assert.NotEmpty(t, res.Header.Get("Retry-After"))
+	})
+}
@@ -0,0 +1,37 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package login
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/session"
+)
+
+// restrictRefreshToMethod restricts a refresh of the session to the method given in the `via` query parameter.
+// The flow requests the assurance level the method was used with, so that for example only the second factor
+// is asked for if `via=totp` is set.
+func restrictRefreshToMethod(r *http.Request, f *Flow, sess *session.Session) error {
+	via := identity.CredentialsType(r.URL.Query().Get("via"))
+	if via == "" {
+		return nil
+	}
+
+	used := sess.LatestAuthenticationMethod(via)
+	if used == nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("The session was not authenticated via %q and can not be refreshed with it.", via))
+	}
+
+	f.SetRefreshMethod(via)
+	f.RequestedAAL = used.AAL
+	if f.RequestedAAL == "" {
+		// Sessions before Ory Kratos 0.9 did not have the AAL be part of the AMR.
+		f.RequestedAAL = identity.AuthenticatorAssuranceLevel1
+	}
+	return nil
+}
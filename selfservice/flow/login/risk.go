@@ -0,0 +1,155 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package login
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/request"
+	"github.com/ory/kratos/session"
+)
+
+// RiskDeviceFingerprintHeader is the header a client can use to send a device fingerprint
+// to the risk engine.
+const RiskDeviceFingerprintHeader = "X-Device-Fingerprint"
+
+const (
+	// RiskDecisionAllow issues the session as requested.
+	RiskDecisionAllow RiskDecision = "allow"
+
+	// RiskDecisionRequireAAL2 issues the session but requires a second factor before the
+	// session can be used. Identities without a second factor are denied.
+	RiskDecisionRequireAAL2 RiskDecision = "require_aal2"
+
+	// RiskDecisionDeny does not issue a session.
+	RiskDecisionDeny RiskDecision = "deny"
+)
+
+type (
+	// RiskDecision is the outcome of a risk assessment.
+	RiskDecision string
+
+	// RiskContext describes a login which is about to issue a session.
+	RiskContext struct {
+		IdentityID   uuid.UUID                            `json:"identity_id"`
+		FlowID       uuid.UUID                            `json:"flow_id"`
+		FlowType     string                               `json:"flow_type"`
+		Method       identity.CredentialsType             `json:"method"`
+		RequestedAAL identity.AuthenticatorAssuranceLevel `json:"requested_aal"`
+		SessionAAL   identity.AuthenticatorAssuranceLevel `json:"session_aal"`
+		Refresh      bool                                 `json:"refresh"`
+
+		IPAddress         string `json:"ip_address"`
+		UserAgent         string `json:"user_agent"`
+		Location          string `json:"location"`
+		DeviceFingerprint string `json:"device_fingerprint"`
+	}
+
+	// RiskAssessor decides whether a login may issue a session.
+	RiskAssessor interface {
+		AssessLoginRisk(ctx context.Context, rc *RiskContext) (RiskDecision, error)
+	}
+
+	RiskAssessorProvider interface {
+		LoginRiskAssessor() RiskAssessor
+	}
+
+	riskAssessorDependencies interface {
+		config.Provider
+		request.Dependencies
+	}
+
+	httpRiskAssessor struct {
+		d riskAssessorDependencies
+	}
+
+	httpRiskAssessorResponse struct {
+		Decision RiskDecision `json:"decision"`
+	}
+)
+
+func (d RiskDecision) IsValid() bool {
+	switch d {
+	case RiskDecisionAllow, RiskDecisionRequireAAL2, RiskDecisionDeny:
+		return true
+	}
+	return false
+}
+
+// NewRiskContext collects the risk signals of the request which is about to issue the session.
+func NewRiskContext(r *http.Request, f *Flow, s *session.Session) *RiskContext {
+	rc := &RiskContext{
+		IdentityID:        s.IdentityID,
+		FlowID:            f.ID,
+		FlowType:          string(f.Type),
+		Method:            f.Active,
+		RequestedAAL:      f.RequestedAAL,
+		SessionAAL:        s.AuthenticatorAssuranceLevel,
+		Refresh:           f.Refresh,
+		DeviceFingerprint: r.Header.Get(RiskDeviceFingerprintHeader),
+	}
+
+	if len(s.Devices) > 0 {
+		device := s.Devices[len(s.Devices)-1]
+		if device.IPAddress != nil {
+			rc.IPAddress = *device.IPAddress
+		}
+		if device.UserAgent != nil {
+			rc.UserAgent = *device.UserAgent
+		}
+		if device.Location != nil {
+			rc.Location = *device.Location
+		}
+	}
+
+	return rc
+}
+
+// NewHTTPRiskAssessor returns a risk assessor which sends the risk context to an external
+// risk engine. The engine must respond with a JSON object containing the decision.
+func NewHTTPRiskAssessor(d riskAssessorDependencies) RiskAssessor {
+	return &httpRiskAssessor{d: d}
+}
+
+func (a *httpRiskAssessor) AssessLoginRisk(ctx context.Context, rc *RiskContext) (RiskDecision, error) {
+	builder, err := request.NewBuilder(ctx, a.d.Config().SelfServiceFlowLoginRiskAssessmentRequestConfig(ctx), a.d)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := builder.BuildRequest(ctx, rc)
+	if err != nil {
+		return "", err
+	}
+
+	res, err := a.d.HTTPClient(ctx).Do(req)
+	if err != nil {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to reach the risk engine.").WithWrap(err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The risk engine responded with unexpected status code %d.", res.StatusCode))
+	}
+
+	var body httpRiskAssessorResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1024*1024)).Decode(&body); err != nil {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to decode the risk engine response.").WithWrap(err))
+	}
+
+	if !body.Decision.IsValid() {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The risk engine responded with the unknown decision %q.", body.Decision))
+	}
+
+	return body.Decision, nil
+}
@@ -0,0 +1,99 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package login_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+func TestRiskAssessor(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	r := httptest.NewRequest("POST", "/self-service/login", nil)
+	r.Header.Set("User-Agent", "risk-test")
+	r.Header.Set(login.RiskDeviceFingerprintHeader, "fingerprint")
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i.ID = x.NewUUID()
+	s, err := session.NewActiveSession(r, i, conf, time.Now(), identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1)
+	require.NoError(t, err)
+
+	f := &login.Flow{ID: x.NewUUID(), Type: flow.TypeBrowser, Active: identity.CredentialsTypePassword, RequestedAAL: identity.AuthenticatorAssuranceLevel1}
+
+	t.Run("method=NewRiskContext", func(t *testing.T) {
+		rc := login.NewRiskContext(r, f, s)
+		assert.Equal(t, i.ID, rc.IdentityID)
+		assert.Equal(t, f.ID, rc.FlowID)
+		assert.Equal(t, identity.CredentialsTypePassword, rc.Method)
+		assert.Equal(t, identity.AuthenticatorAssuranceLevel1, rc.SessionAAL)
+		assert.Equal(t, "risk-test", rc.UserAgent)
+		assert.Equal(t, "fingerprint", rc.DeviceFingerprint)
+		assert.NotEmpty(t, rc.IPAddress)
+	})
+
+	var status int
+	var decision string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rc login.RiskContext
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&rc))
+		assert.Equal(t, i.ID, rc.IdentityID)
+
+		if status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"decision": decision})
+	}))
+	t.Cleanup(ts.Close)
+
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginRiskAssessmentRequestConfig, map[string]interface{}{
+		"url":    ts.URL,
+		"method": "POST",
+		"body":   "base64://" + base64.StdEncoding.EncodeToString([]byte("function(ctx) ctx")),
+	})
+
+	a := reg.LoginRiskAssessor()
+	rc := login.NewRiskContext(r, f, s)
+
+	t.Run("case=returns the decision", func(t *testing.T) {
+		status = 0
+		for _, d := range []login.RiskDecision{login.RiskDecisionAllow, login.RiskDecisionRequireAAL2, login.RiskDecisionDeny} {
+			decision = string(d)
+			actual, err := a.AssessLoginRisk(ctx, rc)
+			require.NoError(t, err)
+			assert.Equal(t, d, actual)
+		}
+	})
+
+	t.Run("case=fails on unknown decisions", func(t *testing.T) {
+		status = 0
+		decision = "maybe"
+		_, err := a.AssessLoginRisk(ctx, rc)
+		require.Error(t, err)
+	})
+
+	t.Run("case=fails on server errors", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		_, err := a.AssessLoginRisk(ctx, rc)
+		require.Error(t, err)
+	})
+}
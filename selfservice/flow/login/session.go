@@ -3,7 +3,10 @@
 
 package login
 
-import "github.com/ory/kratos/session"
+import (
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/session"
+)
 
 // The Response for Login Flows via API
 //
@@ -19,6 +22,13 @@ type APIFlowResponse struct {
 	// The session token is only issued for API flows, not for Browser flows!
 	Token string `json:"session_token,omitempty"`
 
+	// The Refresh Token
+	//
+	// Only set for API flows if refresh tokens are enabled. The session token then expires shortly, and the
+	// refresh token is exchanged for a new session token and refresh token at `/sessions/token-refresh`.
+	// Each refresh token can only be used once.
+	RefreshToken string `json:"refresh_token,omitempty"`
+
 	// The Session
 	//
 	// The session contains information about the user, the session device, and so on.
@@ -26,4 +36,11 @@ type APIFlowResponse struct {
 	//
 	// required: true
 	Session *session.Session `json:"session"`
+
+	// Contains a list of actions, that could follow this flow
+	//
+	// It can, for example, contain a hint to regenerate the lookup secrets.
+	//
+	// required: false
+	ContinueWith []flow.ContinueWith `json:"continue_with,omitempty"`
 }
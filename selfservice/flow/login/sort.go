@@ -9,16 +9,20 @@ import (
 	"github.com/ory/kratos/ui/node"
 )
 
-func sortNodes(ctx context.Context, n node.Nodes) error {
+func SortNodes(ctx context.Context, n node.Nodes) error {
 	return n.SortBySchema(ctx,
 		node.SortByGroups([]node.UiNodeGroup{
 			node.OpenIDConnectGroup,
 			node.DefaultGroup,
+			node.IdentifierFirstGroup,
 			node.WebAuthnGroup,
 			node.CodeGroup,
 			node.PasswordGroup,
 			node.TOTPGroup,
+			node.PushGroup,
+			node.ExternalMFAGroup,
 			node.LookupGroup,
+			node.TrustedDeviceGroup,
 		}),
 		node.SortUseOrder([]string{
 			"csrf_token",
@@ -32,6 +32,12 @@ type LinkableStrategy interface {
 	Link(ctx context.Context, i *identity.Identity, credentials sqlxx.JSONRawMessage) error
 }
 
+// LinkingConfirmationStrategy is implemented by strategies which can confirm that the user owns the
+// account that new credentials are linked to, even if the strategy is not enabled for login.
+type LinkingConfirmationStrategy interface {
+	PopulateLinkingConfirmationMethod(r *http.Request, f *Flow) error
+}
+
 func (s Strategies) Strategy(id identity.CredentialsType) (Strategy, error) {
 	ids := make([]identity.CredentialsType, len(s))
 	for k, ss := range s {
@@ -0,0 +1,71 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package login
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/x/decoderx"
+)
+
+//go:embed .schema/trusted_device.schema.json
+var trustedDeviceSchema []byte
+
+// NewTrustedDeviceRememberNode returns the checkbox which asks to remember the browser after
+// completing the second factor.
+func NewTrustedDeviceRememberNode() *node.Node {
+	return node.NewInputField(node.TrustedDeviceRemember, false, node.TrustedDeviceGroup,
+		node.InputAttributeTypeCheckbox).
+		WithMetaLabel(text.NewInfoSelfServiceLoginRememberDevice())
+}
+
+// rememberDeviceRequested returns true if the login form was submitted with the checkbox
+// asking to remember the browser. The request body is kept for the login strategies.
+func rememberDeviceRequested(r *http.Request) (bool, error) {
+	var p struct {
+		Remember bool `json:"trusted_device_remember"`
+	}
+
+	compiler, err := decoderx.HTTPRawJSONSchemaCompiler(trustedDeviceSchema)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	if err := decoderx.NewHTTP().Decode(r, &p, compiler,
+		decoderx.HTTPKeepRequestBody(true),
+		decoderx.HTTPDecoderAllowedMethods("POST"),
+		decoderx.HTTPDecoderSetValidatePayloads(false),
+		decoderx.HTTPDecoderJSONFollowsFormFormat()); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return p.Remember, nil
+}
+
+// trustDevice lets a login from a trusted browser skip the second factor.
+func (e *HookExecutor) trustDevice(r *http.Request, a *Flow, i *identity.Identity, s *session.Session) {
+	ctx := r.Context()
+	if a.Type != flow.TypeBrowser || !e.d.Config().SelfServiceTrustedDeviceEnabled(ctx) {
+		return
+	}
+
+	// A risk assessment which requires a second factor can not be skipped.
+	if s.AuthenticatorAssuranceLevel > identity.AuthenticatorAssuranceLevel1 || s.RequiredAAL == identity.AuthenticatorAssuranceLevel2 {
+		return
+	}
+
+	if e.d.SessionManager().IsTrustedDevice(ctx, r, i.ID) {
+		s.CompletedLoginFor(identity.CredentialsTypeTrustedDevice, identity.AuthenticatorAssuranceLevel2)
+		s.SetAuthenticatorAssuranceLevel()
+		s.AttributeAuthentications(r)
+	}
+}
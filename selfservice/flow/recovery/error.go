@@ -39,6 +39,7 @@ type (
 		x.LoggingProvider
 		x.CSRFTokenGeneratorProvider
 		config.Provider
+		flow.BeforeRenderHooksProvider
 		StrategyProvider
 
 		FlowPersistenceProvider
@@ -134,6 +135,12 @@ func (s *ErrorHandler) WriteFlowError(
 	updatedFlow, innerErr := s.d.RecoveryFlowPersister().GetRecoveryFlow(r.Context(), f.ID)
 	if innerErr != nil {
 		s.forward(w, r, updatedFlow, innerErr)
+		return
+	}
+
+	if err := flow.ExecuteBeforeRenderHooks(w, r, s.d, updatedFlow); err != nil {
+		s.forward(w, r, updatedFlow, err)
+		return
 	}
 
 	s.d.Writer().WriteCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), updatedFlow)
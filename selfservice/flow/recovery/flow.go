@@ -64,6 +64,10 @@ type Flow struct {
 	// ReturnTo contains the requested return_to URL.
 	ReturnTo string `json:"return_to,omitempty" db:"-"`
 
+	// Theme contains the branding variables configured in `selfservice.theme.variables`,
+	// e.g. the logo URL or product name.
+	Theme map[string]interface{} `json:"theme,omitempty" db:"-"`
+
 	// Active, if set, contains the recovery method that is being used. It is initially
 	// not set.
 	Active sqlxx.NullString `json:"active,omitempty" faker:"-" db:"active_method"`
@@ -82,6 +86,29 @@ type Flow struct {
 	// required: true
 	State State `json:"state" faker:"-" db:"state"`
 
+	// Step is a more detailed representation of the state of this request:
+	//
+	// - choose_method: ask the user for the address or identifier to recover
+	// - sent: the recovery code or link has been sent, ask the user to enter the code
+	// - verifying: a code was submitted but could not be verified, ask the user to retry or to request a new code
+	// - passed_challenge: the recovery challenge was passed
+	//
+	// required: true
+	Step Step `json:"step" faker:"-" db:"-"`
+
+	// AllowedTransitions lists the steps this request may move to from its current step. It is
+	// empty once the recovery challenge was passed.
+	//
+	// required: true
+	AllowedTransitions []Step `json:"allowed_transitions" faker:"-" db:"-"`
+
+	// SubmitCount is the number of recovery codes submitted for this request. It is maintained
+	// by the code persister and therefore never written by the flow persister.
+	SubmitCount int `json:"-" faker:"-" db:"submit_count" rw:"r"`
+
+	// StateTransitions records every state change of this flow.
+	StateTransitions flow.StateTransitions `json:"-" faker:"-" db:"state_transitions"`
+
 	// CSRFToken contains the anti-csrf token associated with this request.
 	CSRFToken string `json:"-" db:"csrf_token"`
 
@@ -102,10 +129,10 @@ type Flow struct {
 	DangerousSkipCSRFCheck bool `json:"-" faker:"-" db:"skip_csrf_check"`
 }
 
-var _ flow.Flow = new(Flow)
+var _ flow.FlowWithStateTransitions = new(Flow)
 
 func NewFlow(conf *config.Config, exp time.Duration, csrf string, r *http.Request, strategy Strategy, ft flow.Type) (*Flow, error) {
-	now := time.Now().UTC()
+	now := x.Now().UTC()
 	id := x.NewUUID()
 
 	// Pre-validate the return to URL which is contained in the HTTP request.
@@ -129,7 +156,7 @@ func NewFlow(conf *config.Config, exp time.Duration, csrf string, r *http.Reques
 			Method: "POST",
 			Action: flow.AppendFlowTo(urlx.AppendPaths(conf.SelfPublicURL(r.Context()), RouteSubmitFlow), id).String(),
 		},
-		State:     flow.StateChooseMethod,
+		State:     StateMachine.Initial(),
 		CSRFToken: csrf,
 		Type:      ft,
 	}
@@ -180,7 +207,7 @@ func (f Flow) GetNID() uuid.UUID {
 }
 
 func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now().UTC()) {
+	if f.ExpiresAt.Before(x.Now().UTC()) {
 		return errors.WithStack(flow.NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
@@ -198,9 +225,16 @@ func (f *Flow) SetCSRFToken(token string) {
 func (f Flow) MarshalJSON() ([]byte, error) {
 	type local Flow
 	f.SetReturnTo()
+	f.SetStep()
 	return json.Marshal(local(f))
 }
 
+// SetStep derives the step and the allowed transitions from the state of the flow.
+func (f *Flow) SetStep() {
+	f.Step = StepOf(f.State, f.SubmitCount)
+	f.AllowedTransitions = AllowedSteps(f.Step)
+}
+
 func (f *Flow) SetReturnTo() {
 	// Return to is already set, do not overwrite it.
 	if len(f.ReturnTo) > 0 {
@@ -211,6 +245,33 @@ func (f *Flow) SetReturnTo() {
 	}
 }
 
+// ContinueReturnTo returns the URL the identity is sent to after it updated its credentials in the
+// settings flow issued by this recovery flow. The return_to URL of the flow takes precedence over
+// `selfservice.flows.recovery.after.default_browser_return_url` and is validated against the allowed
+// return URLs again, as they may have changed since the flow was created. An empty string is returned
+// if neither is set.
+func (f *Flow) ContinueReturnTo(r *http.Request, conf *config.Config) (string, error) {
+	ctx := r.Context()
+	f.SetReturnTo()
+	if f.ReturnTo == "" {
+		if returnTo := conf.SelfServiceFlowRecoveryReturnTo(ctx, nil); returnTo != nil {
+			return returnTo.String(), nil
+		}
+		return "", nil
+	}
+
+	returnTo, err := x.SecureRedirectTo(r,
+		conf.SelfServiceBrowserDefaultReturnTo(ctx),
+		x.SecureRedirectReturnTo(f.ReturnTo),
+		x.SecureRedirectAllowURLs(conf.SelfServiceBrowserAllowedReturnToDomains(ctx)),
+		x.SecureRedirectAllowSelfServiceURLs(conf.SelfPublicURL(ctx)),
+	)
+	if err != nil {
+		return "", err
+	}
+	return returnTo.String(), nil
+}
+
 func (f *Flow) AfterFind(*pop.Connection) error {
 	f.SetReturnTo()
 	return nil
@@ -233,6 +294,15 @@ func (f *Flow) GetFlowName() flow.FlowName {
 	return flow.RecoveryFlow
 }
 
+// SetTheme implements flow.ThemedFlow.
+func (f *Flow) SetTheme(theme map[string]interface{}) {
+	f.Theme = theme
+}
+
 func (f *Flow) SetState(state State) {
 	f.State = state
 }
+
+func (f *Flow) AppendStateTransition(t flow.StateTransition) {
+	f.StateTransitions = append(f.StateTransitions, t)
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/ory/x/jsonx"
 
+	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/internal"
 
 	"github.com/stretchr/testify/assert"
@@ -90,6 +91,32 @@ func TestFlowEncodeJSON(t *testing.T) {
 	assert.EqualValues(t, "/bar", gjson.Get(jsonx.TestMarshalJSONString(t, recovery.Flow{RequestURL: "https://foo.bar?return_to=/bar"}), "return_to").String())
 }
 
+func TestFlowStep(t *testing.T) {
+	for k, tc := range []struct {
+		state       recovery.State
+		submitCount int
+		step        recovery.Step
+		allowed     []string
+	}{
+		{state: flow.StateChooseMethod, step: recovery.StepChooseMethod, allowed: []string{"sent", "passed_challenge"}},
+		{state: flow.StateEmailSent, step: recovery.StepSent, allowed: []string{"sent", "verifying", "passed_challenge"}},
+		{state: flow.StateEmailSent, submitCount: 2, step: recovery.StepVerifying, allowed: []string{"sent", "verifying", "passed_challenge"}},
+		{state: flow.StatePassedChallenge, submitCount: 1, step: recovery.StepPassedChallenge, allowed: []string{}},
+	} {
+		t.Run(fmt.Sprintf("case=%d", k), func(t *testing.T) {
+			actual := jsonx.TestMarshalJSONString(t, recovery.Flow{State: tc.state, SubmitCount: tc.submitCount})
+			assert.EqualValues(t, tc.step, gjson.Get(actual, "step").String(), "%s", actual)
+
+			allowed := []string{}
+			for _, s := range gjson.Get(actual, "allowed_transitions").Array() {
+				allowed = append(allowed, s.String())
+			}
+			assert.Equal(t, tc.allowed, allowed, "%s", actual)
+			assert.False(t, gjson.Get(actual, "submit_count").Exists())
+		})
+	}
+}
+
 func TestFromOldFlow(t *testing.T) {
 	ctx := context.Background()
 	conf := internal.NewConfigurationWithDefaults(t)
@@ -117,3 +144,33 @@ func TestFlowDontOverrideReturnTo(t *testing.T) {
 	f.SetReturnTo()
 	assert.Equal(t, "/bar", f.ReturnTo)
 }
+
+func TestContinueReturnTo(t *testing.T) {
+	ctx := context.Background()
+	conf := internal.NewConfigurationWithDefaults(t)
+	conf.MustSet(ctx, config.ViperKeyURLsAllowedReturnToDomains, []string{"https://allowed.ory.sh/"})
+	r := &http.Request{URL: &url.URL{Path: "/"}, Host: "ory.sh"}
+
+	t.Run("case=no return_to", func(t *testing.T) {
+		returnTo, err := (&recovery.Flow{RequestURL: "https://ory.sh/self-service/recovery/browser"}).ContinueReturnTo(r, conf)
+		require.NoError(t, err)
+		assert.Empty(t, returnTo)
+	})
+
+	t.Run("case=rejects return_to which is no longer allowed", func(t *testing.T) {
+		_, err := (&recovery.Flow{RequestURL: "https://ory.sh/self-service/recovery/browser?return_to=https://evil.ory.sh/app"}).ContinueReturnTo(r, conf)
+		require.Error(t, err)
+	})
+
+	t.Run("case=falls back to the configured return_to", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryBrowserDefaultReturnTo, "https://allowed.ory.sh/recovered")
+
+		returnTo, err := (&recovery.Flow{RequestURL: "https://ory.sh/self-service/recovery/browser"}).ContinueReturnTo(r, conf)
+		require.NoError(t, err)
+		assert.Equal(t, "https://allowed.ory.sh/recovered", returnTo)
+
+		returnTo, err = (&recovery.Flow{RequestURL: "https://ory.sh/self-service/recovery/browser?return_to=https://allowed.ory.sh/app"}).ContinueReturnTo(r, conf)
+		require.NoError(t, err)
+		assert.Equal(t, "https://allowed.ory.sh/app", returnTo)
+	})
+}
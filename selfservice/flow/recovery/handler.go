@@ -5,7 +5,6 @@ package recovery
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/ory/nosurf"
 
@@ -43,6 +42,7 @@ type (
 		RecoveryHandler() *Handler
 	}
 	handlerDependencies interface {
+		flow.BeforeRenderHooksProvider
 		errorx.ManagementProvider
 		identity.ManagementProvider
 		identity.PrivilegedPoolProvider
@@ -83,8 +83,8 @@ func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 
 	public.GET(RouteGetFlow, h.getRecoveryFlow)
 
-	public.GET(RouteSubmitFlow, h.updateRecoveryFlow)
-	public.POST(RouteSubmitFlow, h.updateRecoveryFlow)
+	public.GET(RouteSubmitFlow, identity.WithSelfServiceAuditActor(h.updateRecoveryFlow))
+	public.POST(RouteSubmitFlow, identity.WithSelfServiceAuditActor(h.updateRecoveryFlow))
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
@@ -93,6 +93,22 @@ func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 	admin.GET(RouteGetFlow, x.RedirectToPublicRoute(h.d))
 	admin.GET(RouteSubmitFlow, x.RedirectToPublicRoute(h.d))
 	admin.POST(RouteSubmitFlow, x.RedirectToPublicRoute(h.d))
+
+	admin.DELETE(RouteAdminIdentityRecoveryArtifacts, h.deleteIdentityRecoveryArtifacts)
+}
+
+// Create Native Recovery Flow Parameters
+//
+// swagger:parameters createNativeRecoveryFlow
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type createNativeRecoveryFlow struct {
+	// The URL to return the browser to after the flow was completed. It must be part of the
+	// allowed return URLs and is carried through to the settings flow issued by the recovery.
+	//
+	// in: query
+	ReturnTo string `json:"return_to"`
 }
 
 // swagger:route GET /self-service/recovery/api frontend createNativeRecoveryFlow
@@ -146,6 +162,11 @@ func (h *Handler) createNativeRecoveryFlow(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, req); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	h.d.Writer().Write(w, r, req)
 }
 
@@ -156,7 +177,8 @@ func (h *Handler) createNativeRecoveryFlow(w http.ResponseWriter, r *http.Reques
 //nolint:deadcode,unused
 //lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
 type createBrowserRecoveryFlow struct {
-	// The URL to return the browser to after the flow was completed.
+	// The URL to return the browser to after the flow was completed. It must be part of the
+	// allowed return URLs and is carried through to the settings flow issued by the recovery.
 	//
 	// in: query
 	ReturnTo string `json:"return_to"`
@@ -211,6 +233,13 @@ func (h *Handler) createBrowserRecoveryFlow(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if x.AcceptsJSON(r) {
+		if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, f); err != nil {
+			h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+			return
+		}
+	}
+
 	redirTo := f.AppendTo(h.d.Config().SelfServiceFlowRecoveryUI(r.Context())).String()
 	x.AcceptToRedirectOrJSON(w, r, h.d.Writer(), f, redirTo)
 }
@@ -295,7 +324,7 @@ func (h *Handler) getRecoveryFlow(w http.ResponseWriter, r *http.Request, _ http
 		return
 	}
 
-	if f.ExpiresAt.Before(time.Now().UTC()) {
+	if f.ExpiresAt.Before(x.Now().UTC()) {
 		if f.Type == flow.TypeBrowser {
 			redirectURL := flow.GetFlowExpiredRedirectURL(r.Context(), h.d.Config(), RouteInitBrowserFlow, f.ReturnTo)
 
@@ -311,6 +340,11 @@ func (h *Handler) getRecoveryFlow(w http.ResponseWriter, r *http.Request, _ http
 		return
 	}
 
+	if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, f); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	h.d.Writer().Write(w, r, f)
 }
 
@@ -0,0 +1,94 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package recovery
+
+import (
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/kratos/identity"
+)
+
+const RouteAdminIdentityRecoveryArtifacts = "/identities/:id/recovery-artifacts"
+
+// Invalidated Recovery Artifacts
+//
+// swagger:model invalidatedRecoveryArtifacts
+type InvalidatedArtifacts struct {
+	// Codes is the number of deleted recovery codes.
+	//
+	// required: true
+	Codes int64 `json:"codes"`
+
+	// Links is the number of deleted recovery links.
+	//
+	// required: true
+	Links int64 `json:"links"`
+
+	// Flows is the number of deleted recovery flows.
+	//
+	// required: true
+	Flows int64 `json:"flows"`
+}
+
+// Delete Identity Recovery Artifacts Parameters
+//
+// swagger:parameters deleteIdentityRecoveryArtifacts
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type deleteIdentityRecoveryArtifacts struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /admin/identities/{id}/recovery-artifacts identity deleteIdentityRecoveryArtifacts
+//
+// # Invalidate all Recovery Codes, Links and Flows of an Identity
+//
+// Calling this endpoint deletes all recovery codes and links of the identity which were not used yet,
+// together with the recovery flows they were issued for. This is useful once a support case is resolved
+// and outstanding recovery attempts must no longer succeed. The response contains the number of
+// invalidated items.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: invalidatedRecoveryArtifacts
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) deleteIdentityRecoveryArtifacts(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	iID, err := uuid.FromString(ps.ByName("id"))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()).WithDebug("could not parse UUID"))
+		return
+	}
+
+	if _, err := h.d.PrivilegedIdentityPool().GetIdentity(r.Context(), iID, identity.ExpandNothing); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	invalidated, err := h.d.RecoveryFlowPersister().InvalidateRecoveryArtifacts(r.Context(), iID)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, invalidated)
+}
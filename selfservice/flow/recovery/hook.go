@@ -31,9 +31,14 @@ type (
 	}
 	PostHookExecutorFunc func(w http.ResponseWriter, r *http.Request, a *Flow, s *session.Session) error
 
+	UnknownAddressHookExecutor interface {
+		ExecuteRecoveryUnknownAddressHook(w http.ResponseWriter, r *http.Request, a *Flow, address string) error
+	}
+
 	HooksProvider interface {
 		PreRecoveryHooks(ctx context.Context) []PreHookExecutor
 		PostRecoveryHooks(ctx context.Context) []PostHookExecutor
+		RecoveryUnknownAddressHooks(ctx context.Context) []UnknownAddressHookExecutor
 	}
 )
 
@@ -85,6 +90,21 @@ func (e *HookExecutor) PostRecoveryHook(w http.ResponseWriter, r *http.Request,
 		WithRequest(r).
 		WithField("identity_id", s.Identity.ID).
 		Debug("Running ExecutePostRecoveryHooks.")
+
+	// The session may only be used for the settings flow until the required actions are completed.
+	s.RequiredActions = e.d.Config().SelfServiceFlowRecoveryRequiredActions(r.Context())
+	if e.d.Config().SelfServiceFlowRecoveryRestrictSession(r.Context()) {
+		s.RequireAction(session.RequiredActionSetCredential)
+	}
+
+	// Access to the recovery address alone must not bypass the second factor of the identity. If the
+	// available AAL is not known yet, it is resolved when the session is checked.
+	if e.d.Config().SelfServiceFlowRecoveryRequiredAAL(r.Context()) == config.HighestAvailableAAL {
+		if available, ok := s.Identity.AvailableAAL.ToAAL(); !ok || available > identity.AuthenticatorAssuranceLevel1 {
+			s.RequiredAAL = identity.AuthenticatorAssuranceLevel2
+		}
+	}
+
 	for k, executor := range e.d.PostRecoveryHooks(r.Context()) {
 		if err := executor.ExecutePostRecoveryHook(w, r, a, s); err != nil {
 			var traits identity.Traits
@@ -112,6 +132,20 @@ func (e *HookExecutor) PostRecoveryHook(w http.ResponseWriter, r *http.Request,
 	return nil
 }
 
+// UnknownAddressHook runs the hooks configured for recovery requests of unknown addresses. Errors are
+// only logged because the response must not differ from the one for known addresses.
+func (e *HookExecutor) UnknownAddressHook(w http.ResponseWriter, r *http.Request, a *Flow, address string) {
+	for k, executor := range e.d.RecoveryUnknownAddressHooks(r.Context()) {
+		if err := executor.ExecuteRecoveryUnknownAddressHook(w, r, a, address); err != nil {
+			e.d.Logger().WithRequest(r).
+				WithError(err).
+				WithField("executor", fmt.Sprintf("%T", executor)).
+				WithField("executor_position", k).
+				Warn("A recovery hook for an unknown address failed.")
+		}
+	}
+}
+
 func (e *HookExecutor) PreRecoveryHook(w http.ResponseWriter, r *http.Request, a *Flow) error {
 	for _, executor := range e.d.PreRecoveryHooks(r.Context()) {
 		if err := executor.ExecuteRecoveryPreHook(w, r, a); err != nil {
@@ -100,6 +100,32 @@ func TestRecoveryExecutor(t *testing.T) {
 			assert.EqualValues(t, http.StatusOK, res.StatusCode)
 			assert.Equal(t, "", body)
 		})
+
+		t.Run("case=requires the second factor if configured", func(t *testing.T) {
+			t.Cleanup(testhelpers.SelfServiceHookConfigReset(t, conf))
+			t.Cleanup(func() {
+				conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryRequiredAAL, "aal1")
+			})
+
+			run := func(t *testing.T, available identity.AuthenticatorAssuranceLevel) *session.Session {
+				r, err := http.NewRequest("GET", "/recovery/post", nil)
+				require.NoError(t, err)
+				i := testhelpers.SelfServiceHookFakeIdentity(t)
+				i.AvailableAAL = identity.NewNullableAuthenticatorAssuranceLevel(available)
+				f, err := recovery.NewFlow(conf, time.Minute, x.FakeCSRFToken, r, s, flow.TypeBrowser)
+				require.NoError(t, err)
+				sess, err := session.NewActiveSession(r, i, conf, time.Now().UTC(), identity.CredentialsTypeRecoveryCode, identity.AuthenticatorAssuranceLevel1)
+				require.NoError(t, err)
+				require.NoError(t, reg.RecoveryExecutor().PostRecoveryHook(nil, r, f, sess))
+				return sess
+			}
+
+			assert.Empty(t, run(t, identity.AuthenticatorAssuranceLevel2).RequiredAAL)
+
+			conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryRequiredAAL, config.HighestAvailableAAL)
+			assert.Equal(t, identity.AuthenticatorAssuranceLevel2, run(t, identity.AuthenticatorAssuranceLevel2).RequiredAAL)
+			assert.Empty(t, run(t, identity.AuthenticatorAssuranceLevel1).RequiredAAL)
+		})
 	})
 
 	for _, kind := range []flow.Type{flow.TypeBrowser, flow.TypeAPI} {
@@ -0,0 +1,139 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package recovery
+
+import (
+	"net/http"
+
+	"github.com/ory/x/httpx"
+
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/courier/template/email"
+	"github.com/ory/kratos/courier/template/sms"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	notifierDependencies interface {
+		config.Provider
+		courier.Provider
+		courier.ConfigProvider
+		identity.ValidationProvider
+		x.HTTPClientProvider
+		x.LoggingProvider
+	}
+
+	// Notifier informs the owner of an identity that the identity was recovered, so that account
+	// takeovers through a compromised address are noticed.
+	Notifier struct {
+		d notifierDependencies
+	}
+
+	NotifierProvider interface {
+		RecoveryNotifier() *Notifier
+	}
+
+	notifiedAddress struct {
+		via string
+		to  string
+	}
+)
+
+func NewNotifier(d notifierDependencies) *Notifier {
+	return &Notifier{d: d}
+}
+
+// NotifyRecovered sends a message to all email addresses and phone numbers of the recovered
+// identity except the address the identity was recovered with, which may be nil if the recovery
+// code or link was created by an administrator. It does nothing unless enabled in the config.
+func (n *Notifier) NotifyRecovered(r *http.Request, i *identity.Identity, used *identity.RecoveryAddress) error {
+	ctx := r.Context()
+	if !n.d.Config().SelfServiceFlowRecoveryNotifyOnSuccess(ctx) {
+		return nil
+	}
+
+	addresses := notifiedAddressesOf(i, used)
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	model, err := x.StructToMap(i)
+	if err != nil {
+		return err
+	}
+
+	locale := n.d.IdentityValidator().Locale(ctx, i)
+
+	c, err := n.d.Courier(ctx)
+	if err != nil {
+		return err
+	}
+
+	ip, userAgent, recoveredAt := httpx.ClientIP(r), r.UserAgent(), x.Now().UTC()
+	location := session.ClientLocation(r, n.d.Config().SessionDevicesLocationHeaders(ctx))
+	for _, address := range addresses {
+		n.d.Audit().
+			WithField("via", address.via).
+			WithField("identity_id", i.ID).
+			WithSensitiveField("address", address.to).
+			Info("Sending out recovery notification.")
+
+		switch address.via {
+		case identity.AddressTypePhone:
+			_, err = c.QueueSMS(ctx, sms.NewRecoveryNotification(n.d, &sms.RecoveryNotificationModel{
+				To:          address.to,
+				IPAddress:   ip,
+				UserAgent:   userAgent,
+				Location:    location,
+				RecoveredAt: recoveredAt,
+				Identity:    model,
+				Locale:      locale,
+			}))
+		default:
+			_, err = c.QueueEmail(ctx, email.NewRecoveryNotification(n.d, &email.RecoveryNotificationModel{
+				To:          address.to,
+				IPAddress:   ip,
+				UserAgent:   userAgent,
+				Location:    location,
+				RecoveredAt: recoveredAt,
+				Identity:    model,
+				Locale:      locale,
+			}))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// notifiedAddressesOf returns the distinct recovery and verifiable addresses of the identity
+// without the address used for the recovery.
+func notifiedAddressesOf(i *identity.Identity, used *identity.RecoveryAddress) []notifiedAddress {
+	seen := map[string]bool{}
+	if used != nil {
+		seen[used.Value] = true
+	}
+
+	var addresses []notifiedAddress
+	add := func(via, to string) {
+		if seen[to] || (via != identity.AddressTypeEmail && via != identity.AddressTypePhone) {
+			return
+		}
+		seen[to] = true
+		addresses = append(addresses, notifiedAddress{via: via, to: to})
+	}
+
+	for _, a := range i.RecoveryAddresses {
+		add(string(a.Via), a.Value)
+	}
+	for _, a := range i.VerifiableAddresses {
+		add(string(a.Via), a.Value)
+	}
+	return addresses
+}
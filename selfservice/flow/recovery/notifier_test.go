@@ -0,0 +1,94 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package recovery_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/x/pagination/keysetpagination"
+)
+
+func TestNotifier(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/identity.schema.json")
+
+	newIdentity := func() (*identity.Identity, *identity.RecoveryAddress) {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		used := identity.NewRecoveryEmailAddress(testhelpers.RandomEmail(), i.ID)
+		other := testhelpers.RandomEmail()
+		i.RecoveryAddresses = []identity.RecoveryAddress{
+			*used,
+			*identity.NewRecoveryEmailAddress(other, i.ID),
+			*identity.NewRecoveryPhoneAddress("+4917612345678", i.ID),
+		}
+		i.VerifiableAddresses = []identity.VerifiableAddress{
+			*identity.NewVerifiableEmailAddress(other, i.ID),
+		}
+		return i, used
+	}
+
+	messagesTo := func(t *testing.T, recipient string) []courier.Message {
+		messages, _, _, err := reg.CourierPersister().ListMessages(ctx, courier.ListCourierMessagesParameters{
+			Recipient: recipient,
+		}, []keysetpagination.Option{})
+		require.NoError(t, err)
+		return messages
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+
+	t.Run("case=does nothing if disabled", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryNotifyOnSuccess, false)
+
+		i, used := newIdentity()
+		require.NoError(t, reg.RecoveryNotifier().NotifyRecovered(r, i, used))
+		assert.Empty(t, messagesTo(t, i.RecoveryAddresses[1].Value))
+	})
+
+	t.Run("case=notifies all other addresses", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryNotifyOnSuccess, true)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryNotifyOnSuccess, false)
+		})
+
+		i, used := newIdentity()
+		require.NoError(t, reg.RecoveryNotifier().NotifyRecovered(r, i, used))
+
+		assert.Empty(t, messagesTo(t, used.Value))
+
+		emails := messagesTo(t, i.RecoveryAddresses[1].Value)
+		require.Len(t, emails, 1, "the address must be notified once although it is a recovery and verifiable address")
+		assert.Equal(t, courier.TypeRecoveryNotification, emails[0].TemplateType)
+		assert.Equal(t, courier.MessageTypeEmail, emails[0].Type)
+		assert.Contains(t, emails[0].Body, "192.0.2.1")
+
+		texts := messagesTo(t, "+4917612345678")
+		require.Len(t, texts, 1)
+		assert.Equal(t, courier.MessageTypePhone, texts[0].Type)
+	})
+
+	t.Run("case=notifies all addresses if no address was used", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryNotifyOnSuccess, true)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryNotifyOnSuccess, false)
+		})
+
+		i, used := newIdentity()
+		require.NoError(t, reg.RecoveryNotifier().NotifyRecovered(r, i, nil))
+		assert.Len(t, messagesTo(t, used.Value), 1)
+	})
+
+}
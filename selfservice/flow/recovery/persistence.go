@@ -16,6 +16,10 @@ type (
 		GetRecoveryFlow(ctx context.Context, id uuid.UUID) (*Flow, error)
 		UpdateRecoveryFlow(context.Context, *Flow) error
 		DeleteExpiredRecoveryFlows(context.Context, time.Time, int) error
+
+		// InvalidateRecoveryArtifacts deletes all unused recovery codes and links of the identity
+		// and the recovery flows they were issued for, unless the flow was already completed.
+		InvalidateRecoveryArtifacts(ctx context.Context, identityID uuid.UUID) (*InvalidatedArtifacts, error)
 	}
 	FlowPersistenceProvider interface {
 		RecoveryFlowPersister() FlowPersister
@@ -15,3 +15,55 @@ import "github.com/ory/kratos/selfservice/flow"
 //
 // swagger:model recoveryFlowState
 type State = flow.State
+
+// StateMachine describes the allowed state transitions of a recovery flow.
+var StateMachine = flow.NewStateMachine(flow.StateChooseMethod, map[State][]State{
+	flow.StateChooseMethod: {flow.StateEmailSent, flow.StatePassedChallenge},
+	flow.StateEmailSent:    {flow.StateEmailSent, flow.StatePassedChallenge},
+})
+
+// Recovery Flow Step
+//
+// The step is a more detailed representation of the recovery flow's state which tells
+// user interfaces which screen to render:
+//
+// - choose_method: ask the user for the address or identifier to recover
+// - sent: the recovery code or link has been sent, ask the user to enter the code
+// - verifying: a code was submitted but could not be verified, ask the user to retry or to request a new code
+// - passed_challenge: the recovery challenge was passed
+//
+// swagger:enum recoveryFlowStep
+type Step string
+
+const (
+	StepChooseMethod    Step = "choose_method"
+	StepSent            Step = "sent"
+	StepVerifying       Step = "verifying"
+	StepPassedChallenge Step = "passed_challenge"
+)
+
+var stepTransitions = map[Step][]Step{
+	StepChooseMethod: {StepSent, StepPassedChallenge},
+	StepSent:         {StepSent, StepVerifying, StepPassedChallenge},
+	StepVerifying:    {StepSent, StepVerifying, StepPassedChallenge},
+}
+
+// StepOf returns the step of a recovery flow in the given state to which submitCount codes were submitted.
+func StepOf(state State, submitCount int) Step {
+	switch state {
+	case flow.StateEmailSent:
+		if submitCount > 0 {
+			return StepVerifying
+		}
+		return StepSent
+	case flow.StatePassedChallenge:
+		return StepPassedChallenge
+	default:
+		return StepChooseMethod
+	}
+}
+
+// AllowedSteps returns the steps a recovery flow may move to from the given step.
+func AllowedSteps(step Step) []Step {
+	return append([]Step{}, stepTransitions[step]...)
+}
@@ -0,0 +1,76 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/kratos/selfservice/consent"
+)
+
+const internalContextConsentPath = "consent"
+
+// SetConsentAcceptances stores the documents which were accepted in the flow until the identity is created.
+func (f *Flow) SetConsentAcceptances(accepted []consent.Acceptance) error {
+	raw, err := json.Marshal(accepted)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	f.EnsureInternalContext()
+	f.InternalContext, err = sjson.SetRawBytes(f.InternalContext, internalContextConsentPath, raw)
+	return errors.WithStack(err)
+}
+
+// ConsentAcceptances returns the documents which were accepted in the flow.
+func (f *Flow) ConsentAcceptances() []consent.Acceptance {
+	raw := gjson.GetBytes(f.InternalContext, internalContextConsentPath)
+	if !raw.IsArray() {
+		return nil
+	}
+
+	var accepted []consent.Acceptance
+	if err := json.Unmarshal([]byte(raw.Raw), &accepted); err != nil {
+		return nil
+	}
+	return accepted
+}
+
+// populateConsentNodes adds a checkbox for every configured consent document to the flow.
+func (h *Handler) populateConsentNodes(r *http.Request, f *Flow) {
+	for _, d := range h.d.Config().SelfServiceConsentDocuments(r.Context()) {
+		f.UI.Nodes.Append(consent.NewDocumentNode(d))
+	}
+}
+
+// acceptConsents reads the accepted documents from the request and stores them in the flow. Documents
+// accepted in a previous step of the flow, for example before a one-time code was sent, do not have
+// to be accepted again.
+func (h *Handler) acceptConsents(r *http.Request, f *Flow) error {
+	previous := f.ConsentAcceptances()
+	pending := consent.PendingDocuments(h.d.Config().SelfServiceConsentDocuments(r.Context()), previous)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	accepted, err := consent.DecodeAcceptances(r, pending)
+	if err != nil {
+		return err
+	}
+
+	if len(accepted) == 0 {
+		return nil
+	}
+
+	if err := f.SetConsentAcceptances(append(previous, accepted...)); err != nil {
+		return err
+	}
+
+	return h.d.RegistrationFlowPersister().UpdateRegistrationFlow(r.Context(), f)
+}
@@ -10,15 +10,16 @@ import (
 	"github.com/tidwall/sjson"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/schema"
 	"github.com/ory/x/decoderx"
 )
 
-func DecodeBody(p interface{}, r *http.Request, dec *decoderx.HTTP, conf *config.Config, schema []byte) error {
+func DecodeBody(p interface{}, r *http.Request, dec *decoderx.HTTP, conf *config.Config, payloadSchema []byte, opts ...decoderx.HTTPDecoderOption) error {
 	ds, err := conf.DefaultIdentityTraitsSchemaURL(r.Context())
 	if err != nil {
 		return err
 	}
-	raw, err := sjson.SetBytes(schema,
+	raw, err := sjson.SetBytes(payloadSchema,
 		"properties.traits.$ref", ds.String()+"#/properties/traits")
 	if err != nil {
 		return errors.WithStack(err)
@@ -29,5 +30,7 @@ func DecodeBody(p interface{}, r *http.Request, dec *decoderx.HTTP, conf *config
 		return errors.WithStack(err)
 	}
 
-	return dec.Decode(r, p, compiler, decoderx.HTTPDecoderSetValidatePayloads(true), decoderx.HTTPDecoderJSONFollowsFormFormat())
+	return schema.FilterRegistrationStepErrors(r.Context(), dec.Decode(r, p, append([]decoderx.HTTPDecoderOption{
+		compiler, decoderx.HTTPDecoderSetValidatePayloads(true), decoderx.HTTPDecoderJSONFollowsFormFormat(),
+	}, opts...)...))
 }
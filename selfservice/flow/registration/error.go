@@ -15,6 +15,8 @@ import (
 	"github.com/ory/kratos/x/events"
 
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/text"
 
 	"github.com/pkg/errors"
@@ -37,10 +39,17 @@ type (
 		x.WriterProvider
 		x.LoggingProvider
 		config.Provider
+		flow.BeforeRenderHooksProvider
 
 		sessiontokenexchange.PersistenceProvider
 		FlowPersistenceProvider
 		HandlerProvider
+
+		x.CSRFTokenGeneratorProvider
+		login.HandlerProvider
+		login.FlowPersistenceProvider
+		recovery.StrategyProvider
+		recovery.FlowPersistenceProvider
 	}
 
 	ErrorHandlerProvider interface{ RegistrationFlowErrorHandler() *ErrorHandler }
@@ -81,7 +90,11 @@ func (s *ErrorHandler) WriteFlowError(
 ) {
 
 	if dup := new(identity.ErrDuplicateCredentials); errors.As(err, &dup) {
-		err = schema.NewDuplicateCredentialsError(dup)
+		if f != nil && s.d.Config().SelfServiceFlowRegistrationLoginHandoff(r.Context()) && !s.d.Config().SelfServiceAccountEnumerationMitigate(r.Context()) {
+			err = s.loginHandoff(w, r, f, dup)
+		} else {
+			err = schema.NewDuplicateCredentialsError(dup)
+		}
 	}
 
 	s.d.Audit().
@@ -143,6 +156,12 @@ func (s *ErrorHandler) WriteFlowError(
 	updatedFlow, innerErr := s.d.RegistrationFlowPersister().GetRegistrationFlow(r.Context(), f.ID)
 	if innerErr != nil {
 		s.forward(w, r, updatedFlow, innerErr)
+		return
+	}
+
+	if err := flow.ExecuteBeforeRenderHooks(w, r, s.d, updatedFlow); err != nil {
+		s.forward(w, r, updatedFlow, err)
+		return
 	}
 
 	s.d.Writer().WriteCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), updatedFlow)
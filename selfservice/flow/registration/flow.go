@@ -74,6 +74,10 @@ type Flow struct {
 	// ReturnTo contains the requested return_to URL.
 	ReturnTo string `json:"return_to,omitempty" db:"-"`
 
+	// Theme contains the branding variables configured in `selfservice.theme.variables`,
+	// e.g. the logo URL or product name.
+	Theme map[string]interface{} `json:"theme,omitempty" db:"-"`
+
 	// ReturnToVerification contains the redirect URL for the verification flow.
 	ReturnToVerification string `json:"-" db:"-"`
 
@@ -128,7 +132,7 @@ type Flow struct {
 var _ flow.Flow = new(Flow)
 
 func NewFlow(conf *config.Config, exp time.Duration, csrf string, r *http.Request, ft flow.Type) (*Flow, error) {
-	now := time.Now().UTC()
+	now := x.Now().UTC()
 	id := x.NewUUID()
 
 	// Pre-validate the return to URL which is contained in the HTTP request.
@@ -178,7 +182,7 @@ func (f Flow) GetNID() uuid.UUID {
 }
 
 func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+	if f.ExpiresAt.Before(x.Now()) {
 		return errors.WithStack(flow.NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
@@ -266,6 +270,11 @@ func (f *Flow) GetFlowName() flow.FlowName {
 	return flow.RegistrationFlow
 }
 
+// SetTheme implements flow.ThemedFlow.
+func (f *Flow) SetTheme(theme map[string]interface{}) {
+	f.Theme = theme
+}
+
 func (f *Flow) SetState(state State) {
 	f.State = state
 }
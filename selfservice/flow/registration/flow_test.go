@@ -27,6 +27,7 @@ import (
 
 	"github.com/ory/x/urlx"
 
+	"github.com/ory/kratos/selfservice/consent"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/x"
@@ -154,3 +155,13 @@ func TestFlowDontOverrideReturnTo(t *testing.T) {
 	f.SetReturnTo()
 	assert.Equal(t, "/bar", f.ReturnTo)
 }
+
+func TestFlowConsentAcceptances(t *testing.T) {
+	f := new(registration.Flow)
+	assert.Empty(t, f.ConsentAcceptances())
+
+	accepted := []consent.Acceptance{{ID: "tos", Version: "2023-10", AcceptedAt: time.Now().UTC().Round(time.Second)}}
+	require.NoError(t, f.SetConsentAcceptances(accepted))
+	assert.Equal(t, accepted, f.ConsentAcceptances())
+	assert.Equal(t, "2023-10", gjson.GetBytes(f.InternalContext, "consent.0.version").String())
+}
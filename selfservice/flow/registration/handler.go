@@ -4,9 +4,9 @@
 package registration
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/julienschmidt/httprouter"
@@ -20,12 +20,15 @@ import (
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/errorx"
 	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/invitation"
 	"github.com/ory/kratos/selfservice/sessiontokenexchange"
+	"github.com/ory/kratos/selfservice/signupcode"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/ui/node"
 	"github.com/ory/kratos/x"
 	"github.com/ory/nosurf"
+	"github.com/ory/x/jsonx"
 	"github.com/ory/x/sqlxx"
 	"github.com/ory/x/urlx"
 )
@@ -41,6 +44,7 @@ const (
 
 type (
 	handlerDependencies interface {
+		flow.BeforeRenderHooksProvider
 		config.Provider
 		errorx.ManagementProvider
 		hydra.Provider
@@ -53,7 +57,11 @@ type (
 		HookExecutorProvider
 		FlowPersistenceProvider
 		ErrorHandlerProvider
+		PendingRegistrationVerifierProvider
 		sessiontokenexchange.PersistenceProvider
+		invitation.ManagementProvider
+		signupcode.ManagementProvider
+		identity.ValidationProvider
 		x.LoggingProvider
 	}
 	HandlerProvider interface {
@@ -78,8 +86,8 @@ func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 
 	public.GET(RouteGetFlow, h.getRegistrationFlow)
 
-	public.POST(RouteSubmitFlow, h.d.SessionHandler().IsNotAuthenticated(h.updateRegistrationFlow, h.onAuthenticated))
-	public.GET(RouteSubmitFlow, h.d.SessionHandler().IsNotAuthenticated(h.updateRegistrationFlow, h.onAuthenticated))
+	public.POST(RouteSubmitFlow, identity.WithSelfServiceAuditActor(h.d.SessionHandler().IsNotAuthenticated(h.updateRegistrationFlow, h.onAuthenticated)))
+	public.GET(RouteSubmitFlow, identity.WithSelfServiceAuditActor(h.d.SessionHandler().IsNotAuthenticated(h.updateRegistrationFlow, h.onAuthenticated)))
 }
 
 func (h *Handler) onAuthenticated(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -113,6 +121,12 @@ func WithFlowOAuth2LoginChallenge(loginChallenge string) FlowOption {
 	}
 }
 
+func WithInternalContext(internalContext []byte) FlowOption {
+	return func(f *Flow) {
+		f.InternalContext = internalContext
+	}
+}
+
 func (h *Handler) NewRegistrationFlow(w http.ResponseWriter, r *http.Request, ft flow.Type, opts ...FlowOption) (*Flow, error) {
 	if !h.d.Config().SelfServiceFlowRegistrationEnabled(r.Context()) {
 		return nil, errors.WithStack(ErrRegistrationDisabled)
@@ -126,6 +140,11 @@ func (h *Handler) NewRegistrationFlow(w http.ResponseWriter, r *http.Request, ft
 		o(f)
 	}
 
+	inv, err := h.d.InvitationManager().AttachToFlow(r.Context(), f, r.URL.Query().Get(invitation.QueryParameterToken))
+	if err != nil {
+		return nil, err
+	}
+
 	if ft == flow.TypeAPI && r.URL.Query().Get("return_session_token_exchange_code") == "true" {
 		e, err := h.d.SessionTokenExchangePersister().CreateSessionTokenExchanger(r.Context(), f.ID)
 		if err != nil {
@@ -150,6 +169,24 @@ func (h *Handler) NewRegistrationFlow(w http.ResponseWriter, r *http.Request, ft
 		}
 	}
 
+	if err := applyRegistrationStep(r.Context(), h.d, f); err != nil {
+		return nil, err
+	}
+
+	if inv != nil {
+		// Pre-fill the traits of the invitation in every method which asks for them.
+		for k, v := range jsonx.Flatten(json.RawMessage(inv.Traits)) {
+			for _, n := range f.UI.Nodes {
+				if n.ID() == "traits."+k {
+					n.Attributes.SetValue(v)
+				}
+			}
+		}
+	}
+
+	h.populateSignupCodeNode(r, f)
+	h.populateConsentNodes(r, f)
+
 	ds, err := h.d.Config().DefaultIdentityTraitsSchemaURL(r.Context())
 	if err != nil {
 		return nil, err
@@ -171,7 +208,7 @@ func (h *Handler) NewRegistrationFlow(w http.ResponseWriter, r *http.Request, ft
 }
 
 func (h *Handler) FromOldFlow(w http.ResponseWriter, r *http.Request, of Flow) (*Flow, error) {
-	nf, err := h.NewRegistrationFlow(w, r, of.Type)
+	nf, err := h.NewRegistrationFlow(w, r, of.Type, WithInternalContext(of.InternalContext))
 	if err != nil {
 		return nil, err
 	}
@@ -217,6 +254,11 @@ func (h *Handler) createNativeRegistrationFlow(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, a); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	h.d.Writer().Write(w, r, a)
 }
 
@@ -237,6 +279,14 @@ type createNativeRegistrationFlow struct {
 	//
 	// in: query
 	ReturnTo string `json:"return_to"`
+
+	// The invitation token of an invitation created using the admin API.
+	//
+	// Required if `selfservice.flows.registration.invitations.required` is enabled.
+	//
+	// required: false
+	// in: query
+	InvitationToken string `json:"invitation_token"`
 }
 
 // Create Browser Registration Flow Parameters
@@ -277,6 +327,14 @@ type createBrowserRegistrationFlow struct {
 	// required: false
 	// in: query
 	Organization string `json:"organization"`
+
+	// The invitation token of an invitation created using the admin API.
+	//
+	// Required if `selfservice.flows.registration.invitations.required` is enabled.
+	//
+	// required: false
+	// in: query
+	InvitationToken string `json:"invitation_token"`
 }
 
 // swagger:route GET /self-service/registration/browser frontend createBrowserRegistrationFlow
@@ -408,6 +466,13 @@ func (h *Handler) createBrowserRegistrationFlow(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if x.AcceptsJSON(r) {
+		if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, a); err != nil {
+			h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+			return
+		}
+	}
+
 	redirTo := a.AppendTo(h.d.Config().SelfServiceFlowRegistrationUI(ctx)).String()
 	x.AcceptToRedirectOrJSON(w, r, h.d.Writer(), a, redirTo)
 }
@@ -497,7 +562,7 @@ func (h *Handler) getRegistrationFlow(w http.ResponseWriter, r *http.Request, ps
 		return
 	}
 
-	if ar.ExpiresAt.Before(time.Now()) {
+	if ar.ExpiresAt.Before(x.Now()) {
 		if ar.Type == flow.TypeBrowser {
 			redirectURL := flow.GetFlowExpiredRedirectURL(r.Context(), h.d.Config(), RouteInitBrowserFlow, ar.ReturnTo)
 
@@ -524,6 +589,11 @@ func (h *Handler) getRegistrationFlow(w http.ResponseWriter, r *http.Request, ps
 		ar.HydraLoginRequest = hlr
 	}
 
+	if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, ar); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	h.d.Writer().Write(w, r, ar)
 }
 
@@ -642,10 +712,43 @@ func (h *Handler) updateRegistrationFlow(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	if err := h.acceptConsents(r, f); err != nil {
+		h.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, f, node.ConsentGroup, err)
+		return
+	}
+
+	if err := h.attachSignupCode(r, f); err != nil {
+		h.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, f, node.DefaultGroup, err)
+		return
+	}
+
+	if err := h.continueRegistrationStep(w, r, f); err == nil {
+		return
+	} else if !errors.Is(err, flow.ErrStrategyNotResponsible) {
+		h.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, f, node.ProfileGroup, err)
+		return
+	}
+
+	if i, ct, err := h.d.PendingRegistrationVerifier().VerifyPendingRegistration(w, r, f); err == nil {
+		if err := h.d.RegistrationExecutor().PostRegistrationHook(w, r, ct, "", f, i); err != nil {
+			h.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, f, ct.ToUiNodeGroup(), err)
+		}
+		return
+	} else if !errors.Is(err, flow.ErrStrategyNotResponsible) {
+		h.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, f, node.CodeGroup, err)
+		return
+	}
+
+	stepCtx, err := h.StepValidationContext(r.Context(), f)
+	if err != nil {
+		h.d.RegistrationFlowErrorHandler().WriteFlowError(w, r, f, node.DefaultGroup, err)
+		return
+	}
+
 	i := identity.NewIdentity(h.d.Config().DefaultIdentityTraitsSchemaID(r.Context()))
 	var s Strategy
 	for _, ss := range h.d.AllRegistrationStrategies() {
-		if err := ss.Register(w, r, f, i); errors.Is(err, flow.ErrStrategyNotResponsible) {
+		if err := ss.Register(w, r.WithContext(stepCtx), f, i); errors.Is(err, flow.ErrStrategyNotResponsible) {
 			continue
 		} else if errors.Is(err, flow.ErrCompletedByStrategy) {
 			return
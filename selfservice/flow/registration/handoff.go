@@ -0,0 +1,65 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"net/http"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/recovery"
+)
+
+// loginHandoff returns the duplicate credentials error with continue_with items which point to a login flow
+// for the existing account and, if enabled, a recovery flow.
+func (s *ErrorHandler) loginHandoff(w http.ResponseWriter, r *http.Request, f *Flow, dup *identity.ErrDuplicateCredentials) error {
+	ctx := r.Context()
+	conf := s.d.Config()
+
+	lf, _, err := s.d.LoginHandler().NewLoginFlow(w, r, f.Type, login.WithFlowReturnTo(f.ReturnTo))
+	if err != nil {
+		return err
+	}
+
+	identifier := dup.DuplicateIdentifier()
+	if identifier != "" && lf.UI.Nodes.SetValueAttribute("identifier", identifier) {
+		if err := s.d.LoginFlowPersister().UpdateLoginFlow(ctx, lf); err != nil {
+			return err
+		}
+	}
+
+	var loginURL string
+	if lf.Type == flow.TypeBrowser {
+		loginURL = lf.AppendTo(conf.SelfServiceFlowLoginUI(ctx)).String()
+	}
+	continueWith := []any{flow.NewContinueWithLoginUI(lf, identifier, loginURL)}
+
+	if conf.SelfServiceFlowRegistrationLoginHandoffRecovery(ctx) && conf.SelfServiceFlowRecoveryEnabled(ctx) {
+		strategy, err := s.d.GetActiveRecoveryStrategy(ctx)
+		if err != nil {
+			return err
+		}
+
+		rf, err := recovery.NewFlow(conf, conf.SelfServiceFlowRecoveryRequestLifespan(ctx), s.d.GenerateCSRFToken(r), r, strategy, f.Type)
+		if err != nil {
+			return err
+		}
+		rf.RequestURL = f.RequestURL
+		rf.UI.Nodes.SetValueAttribute("email", identifier)
+
+		if err := s.d.RecoveryFlowPersister().CreateRecoveryFlow(ctx, rf); err != nil {
+			return err
+		}
+
+		var recoveryURL string
+		if rf.Type == flow.TypeBrowser {
+			recoveryURL = rf.AppendTo(conf.SelfServiceFlowRecoveryUI(ctx)).String()
+		}
+		continueWith = append(continueWith, flow.NewContinueWithRecoveryUI(rf, recoveryURL))
+	}
+
+	return schema.NewDuplicateCredentialsErrorWithContinueWith(dup, continueWith...)
+}
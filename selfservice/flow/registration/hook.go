@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -16,10 +17,14 @@ import (
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/hydra"
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/consent"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/invitation"
 	"github.com/ory/kratos/selfservice/sessiontokenexchange"
+	"github.com/ory/kratos/selfservice/signupcode"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 	"github.com/ory/kratos/x/events"
 	"github.com/ory/x/otelx"
@@ -72,6 +77,7 @@ func (f PostHookPrePersistExecutorFunc) ExecutePostRegistrationPrePersistHook(w
 type (
 	executorDependencies interface {
 		config.Provider
+		consent.ManagementProvider
 		identity.ManagementProvider
 		identity.PrivilegedPoolProvider
 		identity.ValidationProvider
@@ -81,6 +87,7 @@ type (
 		session.ManagementProvider
 		HooksProvider
 		FlowPersistenceProvider
+		StrategyProvider
 		hydra.Provider
 		x.CSRFTokenGeneratorProvider
 		x.HTTPClientProvider
@@ -88,6 +95,9 @@ type (
 		x.WriterProvider
 		x.TracingProvider
 		sessiontokenexchange.PersistenceProvider
+		invitation.ManagementProvider
+		signupcode.ManagementProvider
+		DuplicateRegistrationResponderProvider
 	}
 	HookExecutor struct {
 		d executorDependencies
@@ -107,6 +117,16 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 	r = r.WithContext(ctx)
 	defer otelx.End(span, &err)
 
+	if registrationFlow.Active == "" {
+		registrationFlow.Active = ct
+	}
+
+	if deferred, err := deferRegistrationStep(w, r, e.d, registrationFlow, ct, provider, i); err != nil {
+		return err
+	} else if deferred {
+		return nil
+	}
+
 	e.d.Logger().
 		WithRequest(r).
 		WithField("identity_id", i.ID).
@@ -149,13 +169,45 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 			Debug("ExecutePostRegistrationPrePersistHook completed successfully.")
 	}
 
+	inv, err := e.d.InvitationManager().FromFlow(r.Context(), registrationFlow)
+	if err != nil {
+		return err
+	}
+
+	signupCode, err := e.d.SignupCodeManager().FromFlow(r.Context(), registrationFlow)
+	if err != nil {
+		return err
+	} else if err := e.d.SignupCodeManager().Assign(signupCode, i); err != nil {
+		return err
+	}
+
+	if e.d.Config().SelfServiceFlowRegistrationApprovalRequired(r.Context()) {
+		i.State = identity.StatePendingApproval
+	}
+
 	// We need to make sure that the identity has a valid schema before passing it down to the identity pool.
 	if err := e.d.IdentityValidator().Validate(r.Context(), i); err != nil {
 		return err
+	} else if err := e.d.IdentityValidator().ValidateRegistrationEmailDomains(r.Context(), i); err != nil {
+		return err
+	} else if err := e.d.InvitationManager().CheckIdentity(inv, i); err != nil {
+		return err
+	} else if err := e.d.SignupCodeManager().Consume(r.Context(), signupCode); err != nil {
+		// The use is counted before the identity is created so that codes can not be used more often than allowed.
+		return err
+	} else if err := e.d.InvitationManager().Consume(r.Context(), inv); err != nil {
+		// The invitation is consumed before the identity is created so that it can only be used once.
+		return err
 		// We're now creating the identity because any of the hooks could trigger a "redirect" or a "session" which
 		// would imply that the identity has to exist already.
 	} else if err := e.d.IdentityManager().Create(r.Context(), i); err != nil {
-		if errors.Is(err, sqlcon.ErrUniqueViolation) {
+		if dup := new(identity.ErrDuplicateRegistration); errors.As(err, &dup) {
+			// Account enumeration is mitigated, so the registration must not fail visibly.
+			if err := e.d.DuplicateRegistrationResponder().RespondToDuplicateRegistration(w, r, registrationFlow, i, dup.ExistingIdentity()); !errors.Is(err, ErrHookAbortFlow) {
+				return err
+			}
+			return nil
+		} else if errors.Is(err, sqlcon.ErrUniqueViolation) {
 			strategy, err := e.d.AllLoginStrategies().Strategy(ct)
 			if err != nil {
 				return err
@@ -167,9 +219,10 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 					return err
 				}
 				registrationDuplicateCredentials := flow.DuplicateCredentialsData{
-					CredentialsType:     ct,
-					CredentialsConfig:   i.Credentials[ct].Config,
-					DuplicateIdentifier: duplicateIdentifier,
+					CredentialsType:             ct,
+					CredentialsConfig:           i.Credentials[ct].Config,
+					DuplicateIdentifier:         duplicateIdentifier,
+					DuplicateIdentifierVerified: isVerifiedEmailAddress(i, duplicateIdentifier),
 				}
 
 				if err := flow.SetDuplicateCredentials(registrationFlow, registrationDuplicateCredentials); err != nil {
@@ -180,6 +233,18 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 		return err
 	}
 
+	if err := e.d.InvitationManager().AssignIdentity(r.Context(), inv, i.ID); err != nil {
+		return err
+	}
+
+	if err := e.d.ConsentManager().RecordRegistrationConsents(r, i); err != nil {
+		return err
+	}
+
+	if err := e.d.ConsentManager().RecordAcceptances(r, i, registrationFlow.ConsentAcceptances()); err != nil {
+		return err
+	}
+
 	// Verify the redirect URL before we do any other processing.
 	c := e.d.Config()
 	returnTo, err := x.SecureRedirectTo(r, c.SelfServiceBrowserDefaultReturnTo(r.Context()),
@@ -205,27 +270,54 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 
 	span.AddEvent(events.NewRegistrationSucceeded(r.Context(), i.ID, string(registrationFlow.Type), registrationFlow.Active.String(), provider))
 
+	if i.State == identity.StatePendingApproval {
+		span.SetAttributes(attribute.String("redirect_reason", "pending approval"))
+		return e.respondPendingApproval(w, r, registrationFlow, i)
+	}
+
 	s := session.NewInactiveSession()
 
 	s.CompletedLoginForWithProvider(ct, identity.AuthenticatorAssuranceLevel1, provider,
 		httprouter.ParamsFromContext(r.Context()).ByName("organization"))
-	if err := s.Activate(r, i, c, time.Now().UTC()); err != nil {
+	if err := s.Activate(r, i, c, x.Now().UTC()); err != nil {
 		return err
 	}
 
 	// We persist the session here so that subsequent hooks (like verification) can use it.
 	s.AuthenticatedAt = time.Now().UTC()
+	if registrationFlow.Type == flow.TypeAPI {
+		if err := e.d.SessionManager().BindSessionToRequestKey(r.Context(), r, s); err != nil {
+			return err
+		}
+	}
 	if err := e.d.SessionPersister().UpsertSession(r.Context(), s); err != nil {
 		return err
 	}
+	if err := e.d.SessionManager().LinkUpstreamSession(r.Context(), registrationFlow, s); err != nil {
+		return err
+	}
 
 	e.d.Logger().
 		WithRequest(r).
 		WithField("identity_id", i.ID).
 		WithField("flow_method", ct).
 		Debug("Running PostRegistrationPostPersistHooks.")
+	var hookResponse *flow.HookResponse
 	for k, executor := range e.d.PostRegistrationPostPersistHooks(r.Context(), ct) {
 		if err := executor.ExecutePostRegistrationPostPersistHook(w, r, registrationFlow, s); err != nil {
+			if errors.As(err, &hookResponse) {
+				e.d.Logger().
+					WithRequest(r).
+					WithField("executor", fmt.Sprintf("%T", executor)).
+					WithField("executor_position", k).
+					WithField("executors", ExecutorNames(e.d.PostRegistrationPostPersistHooks(r.Context(), ct))).
+					WithField("identity_id", i.ID).
+					WithField("flow_method", ct).
+					Debug("A ExecutePostRegistrationPostPersistHook hook responded to the flow.")
+
+				span.SetAttributes(attribute.String("redirect_reason", "responded by hook"), attribute.String("executor", fmt.Sprintf("%T", executor)))
+				break
+			}
 			if errors.Is(err, ErrHookAbortFlow) {
 				e.d.Logger().
 					WithRequest(r).
@@ -272,6 +364,26 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 		WithField("identity_id", i.ID).
 		Debug("Post registration execution hooks completed successfully.")
 
+	if hookResponse != nil {
+		if hookResponse.RedirectTo != "" {
+			redirectTo, err := x.SecureRedirectTo(r, returnTo,
+				x.SecureRedirectReturnTo(hookResponse.RedirectTo),
+				x.SecureRedirectAllowURLs(c.SelfServiceBrowserAllowedReturnToDomains(r.Context())),
+				x.SecureRedirectAllowSelfServiceURLs(c.SelfPublicURL(r.Context())),
+			)
+			if err != nil {
+				return err
+			}
+			hookResponse.RedirectTo = redirectTo.String()
+		}
+
+		if registrationFlow.OAuth2LoginChallenge == "" {
+			hookResponse.AddContinueWith(registrationFlow.ContinueWith()...)
+			hookResponse.Write(w, r, registrationFlow.Type, e.d.Writer())
+			return nil
+		}
+	}
+
 	if registrationFlow.Type == flow.TypeAPI || x.IsJSONRequest(r) {
 		span.SetAttributes(attribute.String("flow_type", string(flow.TypeAPI)))
 
@@ -321,6 +433,38 @@ func (e *HookExecutor) PostRegistrationHook(w http.ResponseWriter, r *http.Reque
 	return nil
 }
 
+// respondPendingApproval tells the user that the identity awaits approval. No session is issued, and
+// the post-persist hooks are not executed because they require one.
+func (e *HookExecutor) respondPendingApproval(w http.ResponseWriter, r *http.Request, f *Flow, i *identity.Identity) error {
+	f.UI.ResetMessages()
+	f.UI.Messages.Add(text.NewInfoSelfServiceRegistrationPendingApproval())
+	if err := e.d.RegistrationFlowPersister().UpdateRegistrationFlow(r.Context(), f); err != nil {
+		return err
+	}
+
+	if f.Type == flow.TypeAPI || x.IsJSONRequest(r) {
+		e.d.Writer().Write(w, r, &APIFlowResponse{
+			Identity:     i,
+			ContinueWith: f.ContinueWith(),
+		})
+		return nil
+	}
+
+	http.Redirect(w, r, f.AppendTo(e.d.Config().SelfServiceFlowRegistrationUI(r.Context())).String(), http.StatusSeeOther)
+	return nil
+}
+
+// isVerifiedEmailAddress returns true if the identity has the email address and it was verified, for
+// example by the OpenID Connect provider.
+func isVerifiedEmailAddress(i *identity.Identity, address string) bool {
+	for _, va := range i.VerifiableAddresses {
+		if va.Via == identity.VerifiableAddressTypeEmail && va.Verified && strings.EqualFold(va.Value, address) {
+			return true
+		}
+	}
+	return false
+}
+
 func (e *HookExecutor) getDuplicateIdentifier(ctx context.Context, i *identity.Identity) (string, error) {
 	_, id, err := e.d.IdentityManager().ConflictingIdentity(ctx, i)
 	if err != nil {
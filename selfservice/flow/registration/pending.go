@@ -0,0 +1,38 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"net/http"
+
+	"github.com/ory/kratos/identity"
+)
+
+type (
+	// PendingRegistrationVerifier completes registrations whose identity is
+	// held back until the user proved access to their email address.
+	PendingRegistrationVerifier interface {
+		// VerifyPendingRegistration returns the held back identity and the
+		// method it registered with once the submitted code is correct. It
+		// returns flow.ErrStrategyNotResponsible if the flow has no pending
+		// identity.
+		VerifyPendingRegistration(w http.ResponseWriter, r *http.Request, f *Flow) (*identity.Identity, identity.CredentialsType, error)
+	}
+	PendingRegistrationVerifierProvider interface {
+		PendingRegistrationVerifier() PendingRegistrationVerifier
+	}
+
+	// DuplicateRegistrationResponder answers registrations of identifiers
+	// which belong to an existing identity if account enumeration is
+	// mitigated.
+	DuplicateRegistrationResponder interface {
+		// RespondToDuplicateRegistration responds as if the registration
+		// continued and notifies the existing identity, which may be nil if it
+		// could not be found. It returns ErrHookAbortFlow once it responded.
+		RespondToDuplicateRegistration(w http.ResponseWriter, r *http.Request, f *Flow, i, existing *identity.Identity) error
+	}
+	DuplicateRegistrationResponderProvider interface {
+		DuplicateRegistrationResponder() DuplicateRegistrationResponder
+	}
+)
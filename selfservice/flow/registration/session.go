@@ -25,6 +25,13 @@ type APIFlowResponse struct {
 	// The session token is only issued for API flows, not for Browser flows!
 	Token string `json:"session_token,omitempty"`
 
+	// The Refresh Token
+	//
+	// Only set for API flows if refresh tokens are enabled. The session token then expires shortly, and the
+	// refresh token is exchanged for a new session token and refresh token at `/sessions/token-refresh`.
+	// Each refresh token can only be used once.
+	RefreshToken string `json:"refresh_token,omitempty"`
+
 	// The Session
 	//
 	// This field is only set when the session hook is configured as a post-registration hook.
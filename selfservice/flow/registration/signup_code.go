@@ -0,0 +1,51 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"net/http"
+
+	"github.com/ory/kratos/selfservice/signupcode"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+)
+
+// populateSignupCodeNode asks for a sign-up code if sign-up codes are enabled. The `signup_code` query
+// parameter pre-fills the code, for example if it is part of a link sent by an employer.
+func (h *Handler) populateSignupCodeNode(r *http.Request, f *Flow) {
+	if !h.d.Config().SelfServiceFlowRegistrationSignupCodesEnabled(r.Context()) {
+		return
+	}
+
+	var opts []node.InputAttributesModifier
+	if h.d.Config().SelfServiceFlowRegistrationSignupCodesRequired(r.Context()) {
+		opts = append(opts, node.WithRequiredInputAttribute)
+	}
+
+	f.UI.Nodes.Upsert(node.NewInputField(signupcode.NodeName, r.URL.Query().Get(signupcode.NodeName), node.DefaultGroup, node.InputAttributeTypeText, opts...).
+		WithMetaLabel(text.NewInfoNodeLabelSignupCode()))
+}
+
+// attachSignupCode validates the sign-up code from the request and stores it in the flow. A code entered in
+// a previous step of the flow is kept if the request does not contain one.
+func (h *Handler) attachSignupCode(r *http.Request, f *Flow) error {
+	if !h.d.Config().SelfServiceFlowRegistrationSignupCodesEnabled(r.Context()) {
+		return nil
+	}
+
+	code, err := signupcode.DecodeCode(r)
+	if err != nil {
+		return err
+	}
+
+	if code == "" {
+		return nil
+	}
+
+	if _, err := h.d.SignupCodeManager().AttachToFlow(r.Context(), f, code); err != nil {
+		return err
+	}
+
+	return h.d.RegistrationFlowPersister().UpdateRegistrationFlow(r.Context(), f)
+}
@@ -14,6 +14,7 @@ func SortNodes(ctx context.Context, n node.Nodes, schemaRef string) error {
 		node.SortBySchema(schemaRef),
 		node.SortByGroups([]node.UiNodeGroup{
 			node.DefaultGroup,
+			node.ConsentGroup,
 			node.OpenIDConnectGroup,
 			node.WebAuthnGroup,
 			node.CodeGroup,
@@ -0,0 +1,356 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/container"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+)
+
+//go:embed .schema/step.schema.json
+var stepSchema []byte
+
+const (
+	// StepMethod is the method of the submissions which complete a registration step
+	// which only asks for traits.
+	StepMethod = "profile"
+
+	internalContextStepPath        = "registration_steps.step"
+	internalContextTraitsPath      = "registration_steps.traits"
+	internalContextCredentialsPath = "registration_steps.credentials"
+)
+
+// Update Registration Flow with Profile Method
+//
+// Completes a registration step if the identity schema splits the registration into
+// several steps using `"ory.sh/kratos": {"registration": {"step": 2}}`.
+//
+// swagger:model updateRegistrationFlowWithProfileMethod
+type UpdateRegistrationFlowWithProfileMethod struct {
+	// Traits collected in the current registration step
+	//
+	// required: true
+	Traits json.RawMessage `json:"traits"`
+
+	// Method
+	//
+	// Should be set to profile when completing a registration step.
+	//
+	// required: true
+	Method string `json:"method"`
+
+	// The CSRF Token
+	CSRFToken string `json:"csrf_token"`
+}
+
+type (
+	stepDependencies interface {
+		config.Provider
+		FlowPersistenceProvider
+		StrategyProvider
+		x.CSRFTokenGeneratorProvider
+		x.WriterProvider
+	}
+
+	// stepCredentials are the credentials of an identity which were submitted in the first registration
+	// step. They are stored in the flow until the traits of all steps were collected.
+	stepCredentials struct {
+		Type                identity.CredentialsType                          `json:"type"`
+		Provider            string                                            `json:"provider"`
+		Credentials         map[identity.CredentialsType]identity.Credentials `json:"credentials"`
+		VerifiableAddresses []identity.VerifiableAddress                      `json:"verifiable_addresses"`
+	}
+)
+
+func registrationSteps(ctx context.Context, d config.Provider) (*schema.RegistrationSteps, error) {
+	ds, err := d.Config().DefaultIdentityTraitsSchemaURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return schema.NewRegistrationSteps(ctx, ds.String())
+}
+
+// methodsStep returns the registration step in which the registration methods are offered.
+func methodsStep(ctx context.Context, d config.Provider, steps *schema.RegistrationSteps) int {
+	if d.Config().SelfServiceFlowRegistrationStepsCredentialsFirst(ctx) {
+		return 1
+	}
+	return steps.Last()
+}
+
+func currentRegistrationStep(f *Flow) int {
+	if step := gjson.GetBytes(f.InternalContext, internalContextStepPath).Int(); step > 1 {
+		return int(step)
+	}
+	return 1
+}
+
+// StepValidationContext returns the context in which the registration methods validate the submitted
+// identity. If the flow has several steps, only the traits of the current step are validated because
+// the traits of the other steps are stored in the flow or asked for later on.
+func (h *Handler) StepValidationContext(ctx context.Context, f *Flow) (context.Context, error) {
+	steps, err := registrationSteps(ctx, h.d)
+	if err != nil {
+		return nil, err
+	} else if steps.Last() <= 1 {
+		return ctx, nil
+	}
+
+	return schema.ContextWithRegistrationStep(ctx, steps, currentRegistrationStep(f)), nil
+}
+
+// applyRegistrationStep restricts the UI of the flow to the current registration step. The methods
+// are offered in the last step, or in the first one if credentials are asked for first, while the
+// other steps only ask for their traits. The traits of previous steps are stored in the flow.
+func applyRegistrationStep(ctx context.Context, d config.Provider, f *Flow) error {
+	steps, err := registrationSteps(ctx, d)
+	if err != nil {
+		return err
+	}
+
+	if steps.Last() <= 1 {
+		return nil
+	}
+
+	current := currentRegistrationStep(f)
+	offersMethods := current == methodsStep(ctx, d, steps)
+
+	nodes := f.UI.Nodes
+	if !offersMethods {
+		ds, err := d.Config().DefaultIdentityTraitsSchemaURL(ctx)
+		if err != nil {
+			return err
+		}
+
+		nodes, err = container.NodesFromJSONSchema(ctx, node.ProfileGroup, ds.String(), "", nil)
+		if err != nil {
+			return err
+		}
+
+		if csrf := f.UI.Nodes.Find(x.CSRFTokenName); csrf != nil {
+			nodes = append(node.Nodes{csrf}, nodes...)
+		}
+	}
+
+	f.UI.Nodes = node.Nodes{}
+	for _, n := range nodes {
+		if name := n.ID(); strings.HasPrefix(name, "traits.") && steps.Step(name) != current {
+			continue
+		}
+		f.UI.Nodes.Append(n)
+	}
+
+	if !offersMethods {
+		f.UI.Nodes.Append(node.NewInputField("method", StepMethod, node.ProfileGroup, node.InputAttributeTypeSubmit).
+			WithMetaLabel(text.NewInfoRegistrationContinue()))
+	}
+
+	return nil
+}
+
+// MergeStepTraits returns the traits collected in the previous registration steps of the flow,
+// overwritten by the submitted ones. Registration methods use it so that the identity has the
+// traits of all steps.
+func MergeStepTraits(f *Flow, submitted json.RawMessage) (json.RawMessage, error) {
+	traits := []byte(gjson.GetBytes(f.InternalContext, internalContextTraitsPath).Raw)
+	if len(traits) == 0 {
+		if len(submitted) == 0 {
+			return json.RawMessage(`{}`), nil
+		}
+		return submitted, nil
+	}
+
+	var err error
+	for k, v := range gjson.ParseBytes(submitted).Map() {
+		if traits, err = sjson.SetRawBytes(traits, gjson.Escape(k), []byte(v.Raw)); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return traits, nil
+}
+
+// continueRegistrationStep completes a registration step which only asks for traits. The submitted
+// traits are validated as far as they were collected and stored in the flow. If the methods were
+// offered in the first step, the last step registers the identity with the stored credentials. It
+// returns flow.ErrStrategyNotResponsible if the current step offers the methods.
+func (h *Handler) continueRegistrationStep(w http.ResponseWriter, r *http.Request, f *Flow) (err error) {
+	ctx := r.Context()
+
+	steps, err := registrationSteps(ctx, h.d)
+	if err != nil {
+		return err
+	}
+
+	current := currentRegistrationStep(f)
+	if steps.Last() <= 1 || current == methodsStep(ctx, h.d, steps) {
+		return errors.WithStack(flow.ErrStrategyNotResponsible)
+	}
+
+	var p UpdateRegistrationFlowWithProfileMethod
+	defer func() {
+		if err != nil && len(p.Traits) > 0 {
+			for _, n := range container.NewFromJSON("", node.ProfileGroup, p.Traits, "traits").Nodes {
+				f.UI.Nodes.SetValueAttribute(n.ID(), n.Attributes.GetValue())
+			}
+		}
+	}()
+
+	// The traits of the other steps are missing, so the payload is validated below instead.
+	if err := DecodeBody(&p, r, decoderx.NewHTTP(), h.d.Config(), stepSchema, decoderx.HTTPDecoderSetValidatePayloads(false)); err != nil {
+		return err
+	}
+
+	if err := flow.EnsureCSRF(h.d, r, f.Type, h.d.Config().DisableAPIFlowEnforcement(ctx), h.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		return err
+	}
+
+	if p.Method != StepMethod {
+		return errors.WithStack(schema.NewNoRegistrationStrategyResponsible())
+	}
+
+	traits, err := MergeStepTraits(f, p.Traits)
+	if err != nil {
+		return err
+	}
+
+	i := identity.NewIdentity(h.d.Config().DefaultIdentityTraitsSchemaID(ctx))
+	i.Traits = identity.Traits(traits)
+	if err := steps.FilterErrors(h.d.IdentityValidator().Validate(ctx, i), func(step int) bool { return step <= current }); err != nil {
+		return err
+	} else if err := h.d.IdentityValidator().ValidateRegistrationEmailDomains(ctx, i); err != nil {
+		return err
+	}
+
+	if current == steps.Last() {
+		return h.completeRegistrationSteps(w, r, f, i)
+	}
+
+	f.EnsureInternalContext()
+	if f.InternalContext, err = sjson.SetRawBytes(f.InternalContext, internalContextTraitsPath, traits); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nextRegistrationStep(w, r, h.d, f, current)
+}
+
+// completeRegistrationSteps registers the identity with the credentials which were submitted in the
+// first registration step.
+func (h *Handler) completeRegistrationSteps(w http.ResponseWriter, r *http.Request, f *Flow, i *identity.Identity) error {
+	raw := gjson.GetBytes(f.InternalContext, internalContextCredentialsPath)
+	if !raw.IsObject() {
+		return errors.WithStack(schema.NewNoRegistrationStrategyResponsible())
+	}
+
+	var c stepCredentials
+	if err := json.Unmarshal([]byte(raw.Raw), &c); err != nil {
+		return errors.WithStack(err)
+	}
+
+	i.Credentials = c.Credentials
+	i.VerifiableAddresses = c.VerifiableAddresses
+	return h.d.RegistrationExecutor().PostRegistrationHook(w, r, c.Type, c.Provider, f, i)
+}
+
+// deferRegistrationStep stores the credentials and traits of the identity in the flow and continues
+// with the next step, if the methods are offered in the first of several registration steps. It
+// returns false if the identity can be registered.
+func deferRegistrationStep(w http.ResponseWriter, r *http.Request, d stepDependencies, f *Flow, ct identity.CredentialsType, provider string, i *identity.Identity) (bool, error) {
+	ctx := r.Context()
+
+	steps, err := registrationSteps(ctx, d)
+	if err != nil {
+		return false, err
+	}
+
+	current := currentRegistrationStep(f)
+	if current == steps.Last() || current != methodsStep(ctx, d, steps) {
+		return false, nil
+	}
+
+	credentials, err := json.Marshal(&stepCredentials{
+		Type:                ct,
+		Provider:            provider,
+		Credentials:         i.Credentials,
+		VerifiableAddresses: i.VerifiableAddresses,
+	})
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	f.EnsureInternalContext()
+	if f.InternalContext, err = sjson.SetRawBytes(f.InternalContext, internalContextCredentialsPath, credentials); err != nil {
+		return false, errors.WithStack(err)
+	}
+	if f.InternalContext, err = sjson.SetRawBytes(f.InternalContext, internalContextTraitsPath, []byte(i.Traits)); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return true, nextRegistrationStep(w, r, d, f, current)
+}
+
+// nextRegistrationStep advances the flow to the step after the current one and responds with the flow.
+func nextRegistrationStep(w http.ResponseWriter, r *http.Request, d stepDependencies, f *Flow, current int) (err error) {
+	ctx := r.Context()
+
+	f.EnsureInternalContext()
+	if f.InternalContext, err = sjson.SetBytes(f.InternalContext, internalContextStepPath, current+1); err != nil {
+		return errors.WithStack(err)
+	}
+
+	var strategyFilters []StrategyFilter
+	if f.OrganizationID.Valid {
+		strategyFilters = []StrategyFilter{func(s Strategy) bool { return s.ID() == identity.CredentialsTypeOIDC }}
+	}
+
+	f.UI.ResetMessages()
+	f.UI.Nodes = node.Nodes{}
+	f.UI.SetCSRF(d.GenerateCSRFToken(r))
+	for _, s := range d.RegistrationStrategies(ctx, strategyFilters...) {
+		if err := s.PopulateRegistrationMethod(r, f); err != nil {
+			return err
+		}
+	}
+
+	if err := applyRegistrationStep(ctx, d, f); err != nil {
+		return err
+	}
+
+	ds, err := d.Config().DefaultIdentityTraitsSchemaURL(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := SortNodes(ctx, f.UI.Nodes, ds.String()); err != nil {
+		return err
+	}
+
+	if err := d.RegistrationFlowPersister().UpdateRegistrationFlow(ctx, f); err != nil {
+		return err
+	}
+
+	if f.Type == flow.TypeAPI || x.IsJSONRequest(r) {
+		d.Writer().Write(w, r, f)
+	} else {
+		http.Redirect(w, r, f.AppendTo(d.Config().SelfServiceFlowRegistrationUI(ctx)).String(), http.StatusSeeOther)
+	}
+
+	return nil
+}
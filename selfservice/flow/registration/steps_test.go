@@ -0,0 +1,110 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package registration_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlcon"
+)
+
+func TestRegistrationSteps(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationEnabled, true)
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypePassword)+".enabled", true)
+	conf.MustSet(ctx, config.ViperKeyPasswordHaveIBeenPwnedEnabled, false)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/steps.schema.json")
+
+	public, _ := testhelpers.NewKratosServerWithCSRF(t, reg)
+	_ = testhelpers.NewErrorTestServer(t, reg)
+	_ = testhelpers.NewRedirTS(t, "", conf)
+
+	hasNode := func(flow []byte, name string) bool {
+		return gjson.GetBytes(flow, fmt.Sprintf("ui.nodes.#(attributes.name==%q)", name)).Exists()
+	}
+
+	t.Run("case=asks for the credentials in the last step", func(t *testing.T) {
+		client := testhelpers.NewDebugClient(t)
+		f := testhelpers.InitializeRegistrationFlowViaAPI(t, client, public)
+		initial, err := json.Marshal(f)
+		require.NoError(t, err)
+
+		assert.True(t, hasNode(initial, "traits.email"))
+		assert.False(t, hasNode(initial, "traits.name"))
+		assert.False(t, hasNode(initial, "password"))
+		assert.True(t, hasNode(initial, "method"))
+
+		t.Run("case=validates the traits of the current step", func(t *testing.T) {
+			f := testhelpers.InitializeRegistrationFlowViaAPI(t, client, public)
+			body, res := testhelpers.RegistrationMakeRequest(t, true, false, f, client, `{"method":"profile","traits":{}}`)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+			assert.NotEmpty(t, gjson.Get(body, `ui.nodes.#(attributes.name=="traits.email").messages.0.text`).String(), body)
+		})
+
+		email := x.NewUUID().String() + "@ory.sh"
+		body, res := testhelpers.RegistrationMakeRequest(t, true, false, f, client, `{"method":"profile","traits":{"email":"`+email+`"}}`)
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+
+		assert.Equal(t, f.Id, gjson.Get(body, "id").String(), body)
+		assert.False(t, hasNode([]byte(body), "traits.email"), "the traits of the first step are stored in the flow")
+		assert.True(t, hasNode([]byte(body), "traits.name"))
+		assert.True(t, hasNode([]byte(body), "password"))
+
+		body, res = testhelpers.RegistrationMakeRequest(t, true, false, f, client, `{"method":"password","password":"`+x.NewUUID().String()+`","traits":{"name":"Ada"}}`)
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, email, gjson.Get(body, "identity.traits.email").String(), body)
+		assert.Equal(t, "Ada", gjson.Get(body, "identity.traits.name").String(), body)
+	})
+
+	t.Run("case=asks for the credentials in the first step", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationStepsCredentialsFirst, true)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationStepsCredentialsFirst, false)
+		})
+
+		client := testhelpers.NewDebugClient(t)
+		f := testhelpers.InitializeRegistrationFlowViaAPI(t, client, public)
+		initial, err := json.Marshal(f)
+		require.NoError(t, err)
+
+		assert.True(t, hasNode(initial, "traits.email"))
+		assert.True(t, hasNode(initial, "password"))
+		assert.False(t, hasNode(initial, "traits.name"))
+
+		email := x.NewUUID().String() + "@ory.sh"
+		body, res := testhelpers.RegistrationMakeRequest(t, true, false, f, client, `{"method":"password","password":"`+x.NewUUID().String()+`","traits":{"email":"`+email+`"}}`)
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+
+		assert.Equal(t, f.Id, gjson.Get(body, "id").String(), body)
+		assert.False(t, gjson.Get(body, "identity").Exists(), body)
+		assert.True(t, hasNode([]byte(body), "traits.name"))
+		assert.False(t, hasNode([]byte(body), "password"))
+
+		_, _, err = reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(ctx, identity.CredentialsTypePassword, email)
+		require.ErrorIs(t, err, sqlcon.ErrNoRows, "the identity is created in the last step")
+
+		body, res = testhelpers.RegistrationMakeRequest(t, true, false, f, client, `{"method":"profile","traits":{"name":"Ada"}}`)
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, email, gjson.Get(body, "identity.traits.email").String(), body)
+		assert.Equal(t, "Ada", gjson.Get(body, "identity.traits.name").String(), body)
+
+		_, c, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(ctx, identity.CredentialsTypePassword, email)
+		require.NoError(t, err)
+		assert.Equal(t, identity.CredentialsTypePassword, c.Type)
+	})
+}
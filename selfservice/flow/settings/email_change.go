@@ -0,0 +1,151 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package settings
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/randx"
+)
+
+// EmailChangeState is the state of an email change.
+type EmailChangeState string
+
+const (
+	// EmailChangeStatePending is the state of an email change which waits for the
+	// new address to be confirmed. The identity still uses the original address.
+	EmailChangeStatePending EmailChangeState = "pending"
+
+	// EmailChangeStateConfirmed is the state of an email change whose new address was
+	// confirmed and applied to the identity. It can still be reverted until it expires.
+	EmailChangeStateConfirmed EmailChangeState = "confirmed"
+
+	// EmailChangeStateReverted is the state of an email change which was reverted using
+	// the link sent to the original address.
+	EmailChangeStateReverted EmailChangeState = "reverted"
+)
+
+// Email Change
+//
+// An email change replaces the primary email address of an identity once the new address
+// was confirmed. Until then the identity keeps the original address, which receives a
+// link to revert the change.
+type EmailChange struct {
+	// ID of the email change
+	//
+	// required: true
+	ID uuid.UUID `json:"id" faker:"-" db:"id"`
+
+	// IdentityID is the ID of the identity whose email address is changed.
+	//
+	// required: true
+	IdentityID uuid.UUID `json:"identity_id" faker:"-" db:"identity_id"`
+
+	// OriginalAddress is the email address which is replaced.
+	//
+	// required: true
+	OriginalAddress string `json:"original_address" faker:"email" db:"original_address"`
+
+	// OriginalAddressVerified is true if the original address was verified. Reverting the
+	// change restores the verification.
+	OriginalAddressVerified bool `json:"-" faker:"-" db:"original_address_verified"`
+
+	// NewAddress is the email address which replaces the original one.
+	//
+	// required: true
+	NewAddress string `json:"new_address" faker:"email" db:"new_address"`
+
+	// OriginalTraits are the traits of the identity before the change.
+	OriginalTraits identity.Traits `json:"-" faker:"-" db:"original_traits"`
+
+	// NewTraits are the traits the identity receives once the change is confirmed.
+	NewTraits identity.Traits `json:"-" faker:"-" db:"new_traits"`
+
+	// ConfirmToken is sent to the new address. It is stored as a digest.
+	ConfirmToken string `json:"-" faker:"-" db:"confirm_token"`
+
+	// RevertToken is sent to the original address. It is stored as a digest.
+	RevertToken string `json:"-" faker:"-" db:"revert_token"`
+
+	// State is the state of the email change:
+	//
+	// - pending: the new address was not confirmed yet
+	// - confirmed: the new address was confirmed and applied
+	// - reverted: the change was reverted
+	//
+	// required: true
+	State EmailChangeState `json:"state" faker:"-" db:"state"`
+
+	// ExpiresAt is the time at which the confirmation and revert links expire.
+	//
+	// required: true
+	ExpiresAt time.Time `json:"expires_at" faker:"-" db:"expires_at"`
+
+	// CreatedAt is the time at which the change was requested.
+	CreatedAt time.Time `json:"created_at" faker:"-" db:"created_at"`
+
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (EmailChange) TableName(context.Context) string {
+	return "identity_email_changes"
+}
+
+func (c EmailChange) GetID() uuid.UUID {
+	return c.ID
+}
+
+func (c EmailChange) GetNID() uuid.UUID {
+	return c.NID
+}
+
+// NewEmailChange creates a pending change of the original address of the identity to the new address.
+func NewEmailChange(original *identity.Identity, from *identity.VerifiableAddress, updated *identity.Identity, to string, lifespan time.Duration) *EmailChange {
+	return &EmailChange{
+		ID:                      x.NewUUID(),
+		IdentityID:              original.ID,
+		OriginalAddress:         from.Value,
+		OriginalAddressVerified: from.Verified,
+		NewAddress:              to,
+		OriginalTraits:          original.Traits,
+		NewTraits:               updated.Traits,
+		ConfirmToken:            randx.MustString(32, randx.AlphaNum),
+		RevertToken:             randx.MustString(32, randx.AlphaNum),
+		State:                   EmailChangeStatePending,
+		ExpiresAt:               x.Now().UTC().Add(lifespan),
+	}
+}
+
+// IsActive returns true if the links of the change did not expire yet.
+func (c *EmailChange) IsActive() bool {
+	return c.ExpiresAt.After(x.Now())
+}
+
+type (
+	EmailChangePersister interface {
+		// CreateEmailChange stores the change. The tokens are stored as digests and
+		// remain readable on the given change.
+		CreateEmailChange(ctx context.Context, c *EmailChange) error
+
+		// GetEmailChangeByConfirmToken returns the change with the given confirmation token.
+		GetEmailChangeByConfirmToken(ctx context.Context, token string) (*EmailChange, error)
+
+		// GetEmailChangeByRevertToken returns the change with the given revert token.
+		GetEmailChangeByRevertToken(ctx context.Context, token string) (*EmailChange, error)
+
+		// UpdateEmailChange updates the state of the change.
+		UpdateEmailChange(ctx context.Context, c *EmailChange) error
+	}
+	EmailChangePersistenceProvider interface {
+		EmailChangePersister() EmailChangePersister
+	}
+)
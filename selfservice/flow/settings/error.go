@@ -37,6 +37,7 @@ var ErrHookAbortFlow = errors.New("aborted settings hook execution")
 type (
 	errorHandlerDependencies interface {
 		config.Provider
+		flow.BeforeRenderHooksProvider
 		errorx.ManagementProvider
 		x.WriterProvider
 		x.LoggingProvider
@@ -245,6 +246,12 @@ func (s *ErrorHandler) WriteFlowError(
 	updatedFlow, innerErr := s.d.SettingsFlowPersister().GetSettingsFlow(r.Context(), f.ID)
 	if innerErr != nil {
 		s.forward(w, r, updatedFlow, innerErr)
+		return
+	}
+
+	if err := flow.ExecuteBeforeRenderHooks(w, r, s.d, updatedFlow); err != nil {
+		s.forward(w, r, updatedFlow, err)
+		return
 	}
 
 	s.d.Writer().WriteCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), updatedFlow)
@@ -75,6 +75,10 @@ type Flow struct {
 	// ReturnTo contains the requested return_to URL.
 	ReturnTo string `json:"return_to,omitempty" db:"-"`
 
+	// Theme contains the branding variables configured in `selfservice.theme.variables`,
+	// e.g. the logo URL or product name.
+	Theme map[string]interface{} `json:"theme,omitempty" db:"-"`
+
 	// Active, if set, contains the registration method that is being used. It is initially
 	// not set.
 	Active sqlxx.NullString `json:"active,omitempty" db:"active_method"`
@@ -132,7 +136,7 @@ func MustNewFlow(conf *config.Config, exp time.Duration, r *http.Request, i *ide
 }
 
 func NewFlow(conf *config.Config, exp time.Duration, r *http.Request, i *identity.Identity, ft flow.Type) (*Flow, error) {
-	now := time.Now().UTC()
+	now := x.Now().UTC()
 	id := x.NewUUID()
 
 	// Pre-validate the return to URL which is contained in the HTTP request.
@@ -189,7 +193,7 @@ func (f *Flow) AppendTo(src *url.URL) *url.URL {
 }
 
 func (f *Flow) Valid(s *session.Session) error {
-	if f.ExpiresAt.Before(time.Now().UTC()) {
+	if f.ExpiresAt.Before(x.Now().UTC()) {
 		return errors.WithStack(flow.NewFlowExpiredError(f.ExpiresAt))
 	}
 
@@ -253,6 +257,11 @@ func (f *Flow) GetFlowName() flow.FlowName {
 	return flow.SettingsFlow
 }
 
+// SetTheme implements flow.ThemedFlow.
+func (f *Flow) SetTheme(theme map[string]interface{}) {
+	f.Theme = theme
+}
+
 func (f *Flow) SetState(state State) {
 	f.State = state
 }
@@ -6,7 +6,6 @@ package settings
 import (
 	"net/http"
 	"net/url"
-	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/pkg/errors"
@@ -45,6 +44,7 @@ func ContinuityKey(id string) string {
 
 type (
 	handlerDependencies interface {
+		flow.BeforeRenderHooksProvider
 		x.CSRFProvider
 		x.WriterProvider
 		x.LoggingProvider
@@ -106,8 +106,8 @@ func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	public.GET(RouteInitAPIFlow, h.d.SessionHandler().IsAuthenticated(h.createNativeSettingsFlow, nil))
 	public.GET(RouteGetFlow, h.d.SessionHandler().IsAuthenticated(h.getSettingsFlow, OnUnauthenticated(h.d)))
 
-	public.POST(RouteSubmitFlow, h.d.SessionHandler().IsAuthenticated(h.updateSettingsFlow, OnUnauthenticated(h.d)))
-	public.GET(RouteSubmitFlow, h.d.SessionHandler().IsAuthenticated(h.updateSettingsFlow, OnUnauthenticated(h.d)))
+	public.POST(RouteSubmitFlow, identity.WithSelfServiceAuditActor(h.d.SessionHandler().IsAuthenticated(h.updateSettingsFlow, OnUnauthenticated(h.d))))
+	public.GET(RouteSubmitFlow, identity.WithSelfServiceAuditActor(h.d.SessionHandler().IsAuthenticated(h.updateSettingsFlow, OnUnauthenticated(h.d))))
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
@@ -219,7 +219,7 @@ func (h *Handler) createNativeSettingsFlow(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := h.d.SessionManager().DoesSessionSatisfy(r, s, h.d.Config().SelfServiceSettingsRequiredAAL(r.Context())); err != nil {
+	if _, err := h.requireAAL(r, s); err != nil {
 		h.d.Writer().WriteError(w, r, err)
 		return
 	}
@@ -230,6 +230,11 @@ func (h *Handler) createNativeSettingsFlow(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, f); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	h.d.Writer().Write(w, r, f)
 }
 
@@ -308,7 +313,7 @@ func (h *Handler) createBrowserSettingsFlow(w http.ResponseWriter, r *http.Reque
 		managerOptions = append(managerOptions, session.WithRequestURL(requestURL.String()))
 	}
 
-	if err := h.d.SessionManager().DoesSessionSatisfy(r, s, h.d.Config().SelfServiceSettingsRequiredAAL(r.Context()), managerOptions...); err != nil {
+	if _, err := h.requireAAL(r, s, managerOptions...); err != nil {
 		h.d.SettingsFlowErrorHandler().WriteFlowError(w, r, node.DefaultGroup, nil, nil, err)
 		return
 	}
@@ -319,6 +324,13 @@ func (h *Handler) createBrowserSettingsFlow(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if x.AcceptsJSON(r) {
+		if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, f); err != nil {
+			h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+			return
+		}
+	}
+
 	redirTo := f.AppendTo(h.d.Config().SelfServiceFlowSettingsUI(r.Context())).String()
 	x.AcceptToRedirectOrJSON(w, r, h.d.Writer(), f, redirTo)
 }
@@ -420,11 +432,11 @@ func (h *Handler) fetchFlow(w http.ResponseWriter, r *http.Request) error {
 	// to a page displaying raw JSON to the client (browser), which is not what we want.
 	// Let's rather carry over the flow ID as a query parameter and redirect to the settings UI URL.
 	requestURL := urlx.CopyWithQuery(h.d.Config().SelfServiceFlowSettingsUI(r.Context()), url.Values{"flow": {rid.String()}})
-	if err := h.d.SessionManager().DoesSessionSatisfy(r, sess, h.d.Config().SelfServiceSettingsRequiredAAL(r.Context()), session.WithRequestURL(requestURL.String())); err != nil {
+	if _, err := h.requireAAL(r, sess, session.WithRequestURL(requestURL.String())); err != nil {
 		return err
 	}
 
-	if pr.ExpiresAt.Before(time.Now().UTC()) {
+	if pr.ExpiresAt.Before(x.Now().UTC()) {
 		if pr.Type == flow.TypeBrowser {
 			redirectURL := flow.GetFlowExpiredRedirectURL(r.Context(), h.d.Config(), RouteInitBrowserFlow, pr.ReturnTo)
 
@@ -440,6 +452,10 @@ func (h *Handler) fetchFlow(w http.ResponseWriter, r *http.Request) error {
 		return nil
 	}
 
+	if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, pr); err != nil {
+		return err
+	}
+
 	h.d.Writer().Write(w, r, pr)
 	return nil
 }
@@ -579,7 +595,8 @@ func (h *Handler) updateSettingsFlow(w http.ResponseWriter, r *http.Request, ps
 	}
 
 	requestURL := x.RequestURL(r).String()
-	if err := h.d.SessionManager().DoesSessionSatisfy(r, ss, h.d.Config().SelfServiceSettingsRequiredAAL(r.Context()), session.WithRequestURL(requestURL)); err != nil {
+	restricted, err := h.requireAAL(r, ss, session.WithRequestURL(requestURL))
+	if err != nil {
 		h.d.SettingsFlowErrorHandler().WriteFlowError(w, r, node.DefaultGroup, f, nil, err)
 		return
 	}
@@ -592,6 +609,10 @@ func (h *Handler) updateSettingsFlow(w http.ResponseWriter, r *http.Request, ps
 	var s string
 	var updateContext *UpdateContext
 	for _, strat := range h.d.AllSettingsStrategies() {
+		if restricted != nil && strat.SettingsStrategyID() != identity.CredentialsTypeTOTP.String() {
+			continue
+		}
+
 		uc, err := strat.Settings(w, r, f, ss)
 		if errors.Is(err, flow.ErrStrategyNotResponsible) {
 			continue
@@ -607,7 +628,10 @@ func (h *Handler) updateSettingsFlow(w http.ResponseWriter, r *http.Request, ps
 		break
 	}
 
-	if updateContext == nil {
+	if updateContext == nil && restricted != nil {
+		h.d.SettingsFlowErrorHandler().WriteFlowError(w, r, node.DefaultGroup, f, nil, restricted)
+		return
+	} else if updateContext == nil {
 		h.d.SettingsFlowErrorHandler().WriteFlowError(w, r, node.DefaultGroup, f, ss.Identity, errors.WithStack(schema.NewNoSettingsStrategyResponsible()))
 		return
 	}
@@ -58,6 +58,7 @@ type (
 		identity.ManagementProvider
 		identity.ValidationProvider
 		session.ManagementProvider
+		session.PersistenceProvider
 		config.Provider
 
 		HandlerProvider
@@ -151,6 +152,23 @@ func (e *HookExecutor) handleSettingsError(_ http.ResponseWriter, r *http.Reques
 	return flowError
 }
 
+// requiredActionsFor returns the required session actions which are completed by updating the
+// settings of the given method.
+func requiredActionsFor(settingsType string) []session.RequiredAction {
+	switch settingsType {
+	case identity.CredentialsTypePassword.String():
+		return []session.RequiredAction{session.RequiredActionChangePassword, session.RequiredActionSetCredential}
+	case identity.CredentialsTypeTOTP.String(),
+		identity.CredentialsTypeWebAuthn.String(),
+		identity.CredentialsTypeLookup.String(),
+		identity.CredentialsTypePush.String():
+		return []session.RequiredAction{session.RequiredActionReviewMFA, session.RequiredActionSetCredential}
+	case StrategyProfile:
+		return []session.RequiredAction{session.RequiredActionConfirmProfile}
+	}
+	return nil
+}
+
 func (e *HookExecutor) PostSettingsHook(w http.ResponseWriter, r *http.Request, settingsType string, ctxUpdate *UpdateContext, i *identity.Identity, opts ...PostSettingsHookOption) error {
 	e.d.Logger().
 		WithRequest(r).
@@ -230,6 +248,18 @@ func (e *HookExecutor) PostSettingsHook(w http.ResponseWriter, r *http.Request,
 		WithField("identity_id", i.ID).
 		Debug("An identity's settings have been updated.")
 
+	var completed bool
+	for _, action := range requiredActionsFor(settingsType) {
+		if ctxUpdate.Session.CompleteRequiredAction(action) {
+			completed = true
+		}
+	}
+	if completed {
+		if err := e.d.SessionPersister().UpsertSession(r.Context(), ctxUpdate.Session); err != nil {
+			return err
+		}
+	}
+
 	ctxUpdate.UpdateIdentity(i)
 	ctxUpdate.Flow.State = flow.StateSuccess
 	if hookOptions.cb != nil {
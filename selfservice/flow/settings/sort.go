@@ -20,6 +20,10 @@ func sortNodes(ctx context.Context, n node.Nodes, schemaRef string) error {
 			node.LookupGroup,
 			node.WebAuthnGroup,
 			node.TOTPGroup,
+			node.PushGroup,
+			node.TrustedDeviceGroup,
+			node.ConsentGroup,
+			node.SessionsGroup,
 		}),
 		node.SortUseOrderAppend([]string{
 			// Lookup
@@ -30,6 +34,8 @@ func sortNodes(ctx context.Context, n node.Nodes, schemaRef string) error {
 
 			// WebAuthn
 			node.WebAuthnRemove,
+			node.WebAuthnRenameDisplayName,
+			node.WebAuthnRename,
 			node.WebAuthnRegisterDisplayName,
 			node.WebAuthnRegister,
 
@@ -38,6 +44,20 @@ func sortNodes(ctx context.Context, n node.Nodes, schemaRef string) error {
 			node.TOTPSecretKey,
 			node.TOTPUnlink,
 			node.TOTPCode,
+
+			// Push
+			node.PushRemove,
+			node.PushRegisterToken,
+			node.PushRegisterDisplayName,
+			node.PushRegisterPlatform,
+
+			// Trusted Devices
+			node.TrustedDeviceRevoke,
+			node.TrustedDeviceRevokeAll,
+
+			// Sessions
+			node.SessionsRevoke,
+			node.SessionsRevokeOthers,
 		}),
 	)
 }
@@ -0,0 +1,59 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package settings
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/session"
+)
+
+// requireAAL checks that the session satisfies the AAL required for the settings flow.
+//
+// A session issued by a recovery flow can not reach AAL2 if the user lost the TOTP authenticator app which is
+// their second factor. If `selfservice.flows.recovery.allow_totp_reset` is enabled, such a session may still use
+// the settings flow, but only to unlink the authenticator app. In that case err is nil and restricted is the
+// error to return for any other update.
+func (h *Handler) requireAAL(r *http.Request, s *session.Session, opts ...session.ManagerOptions) (restricted error, err error) {
+	err = h.d.SessionManager().DoesSessionSatisfy(r, s, h.d.Config().SelfServiceSettingsRequiredAAL(r.Context()), opts...)
+	var aalErr *session.ErrAALNotSatisfied
+	if !errors.As(err, &aalErr) {
+		return nil, err
+	}
+
+	allowed, resetErr := h.mayResetTOTP(r.Context(), s)
+	if resetErr != nil {
+		return nil, resetErr
+	} else if !allowed {
+		return nil, err
+	}
+	return err, nil
+}
+
+// mayResetTOTP returns true if the session was issued by a recovery flow with AAL1 and its identity has a TOTP
+// authenticator app which the session may unlink.
+func (h *Handler) mayResetTOTP(ctx context.Context, s *session.Session) (bool, error) {
+	if !h.d.Config().SelfServiceFlowRecoveryAllowTOTPReset(ctx) || s.AuthenticatorAssuranceLevel != identity.AuthenticatorAssuranceLevel1 {
+		return false, nil
+	}
+
+	if !slices.ContainsFunc(s.AMR, func(m session.AuthenticationMethod) bool {
+		return m.Method == identity.CredentialsTypeRecoveryLink || m.Method == identity.CredentialsTypeRecoveryCode
+	}) {
+		return false, nil
+	}
+
+	i, err := h.d.PrivilegedIdentityPool().GetIdentityConfidential(ctx, s.IdentityID)
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := i.GetCredentials(identity.CredentialsTypeTOTP)
+	return ok, nil
+}
@@ -0,0 +1,111 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package flow
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ory/kratos/x/events"
+)
+
+// ErrStateTransitionNotAllowed is returned when a flow is moved into a state which can not be reached from
+// its current state.
+var ErrStateTransitionNotAllowed = errors.New("flow state transition is not allowed")
+
+// StateTransition records a single state change of a flow.
+type StateTransition struct {
+	From State     `json:"from"`
+	To   State     `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// StateTransitions is the state change history of a flow.
+type StateTransitions []StateTransition
+
+// Scan implements the Scanner interface.
+func (t *StateTransitions) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	v := fmt.Sprintf("%s", value)
+	if len(v) == 0 {
+		return nil
+	}
+	return errors.WithStack(json.Unmarshal([]byte(v), t))
+}
+
+// Value implements the driver Valuer interface.
+func (t StateTransitions) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return "[]", nil
+	}
+	value, err := json.Marshal(t)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return string(value), nil
+}
+
+// FlowWithStateTransitions is implemented by flows which keep a history of their state changes.
+type FlowWithStateTransitions interface {
+	Flow
+	AppendStateTransition(StateTransition)
+}
+
+// StateMachine describes which states a flow may move into from a given state.
+//
+// States which have no outgoing transitions are final.
+type StateMachine struct {
+	initial     State
+	transitions map[State][]State
+}
+
+// NewStateMachine returns a state machine which starts in the initial state and allows the given transitions.
+func NewStateMachine(initial State, transitions map[State][]State) *StateMachine {
+	return &StateMachine{initial: initial, transitions: transitions}
+}
+
+// Initial returns the state a new flow starts in.
+func (m *StateMachine) Initial() State {
+	return m.initial
+}
+
+// CanTransition returns true if a flow in state from may move to state to.
+func (m *StateMachine) CanTransition(from, to State) bool {
+	for _, s := range m.transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal returns true if no transitions leave the given state.
+func (m *StateMachine) IsFinal(s State) bool {
+	return len(m.transitions[s]) == 0
+}
+
+// Transition moves the flow into the given state. If the flow keeps a history of its state changes,
+// the transition is recorded on it. Every transition emits a trace event.
+func (m *StateMachine) Transition(ctx context.Context, f Flow, to State) error {
+	from := f.GetState()
+	if !m.CanTransition(from, to) {
+		return errors.WithStack(fmt.Errorf("%w: %s -> %s", ErrStateTransitionNotAllowed, from, to))
+	}
+
+	f.SetState(to)
+	if h, ok := f.(FlowWithStateTransitions); ok {
+		h.AppendStateTransition(StateTransition{From: from, To: to, At: time.Now().UTC()})
+	}
+
+	trace.SpanFromContext(ctx).AddEvent(events.NewFlowStateTransitioned(ctx, f.GetID(), string(f.GetType()), string(f.GetFlowName()), from.String(), to.String()))
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package flow_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/recovery"
+	"github.com/ory/kratos/x"
+)
+
+func TestStateMachine(t *testing.T) {
+	ctx := context.Background()
+	m := flow.NewStateMachine(flow.StateChooseMethod, map[flow.State][]flow.State{
+		flow.StateChooseMethod: {flow.StateEmailSent},
+		flow.StateEmailSent:    {flow.StateEmailSent, flow.StatePassedChallenge},
+	})
+
+	t.Run("case=validates transitions", func(t *testing.T) {
+		assert.Equal(t, flow.StateChooseMethod, m.Initial())
+		assert.True(t, m.CanTransition(flow.StateChooseMethod, flow.StateEmailSent))
+		assert.True(t, m.CanTransition(flow.StateEmailSent, flow.StateEmailSent))
+		assert.False(t, m.CanTransition(flow.StateChooseMethod, flow.StatePassedChallenge))
+		assert.False(t, m.CanTransition(flow.StatePassedChallenge, flow.StateEmailSent))
+		assert.False(t, m.CanTransition("", flow.StateEmailSent))
+
+		assert.True(t, m.IsFinal(flow.StatePassedChallenge))
+		assert.False(t, m.IsFinal(flow.StateEmailSent))
+	})
+
+	t.Run("case=records transition history", func(t *testing.T) {
+		f := &recovery.Flow{ID: x.NewUUID(), State: m.Initial()}
+
+		require.NoError(t, m.Transition(ctx, f, flow.StateEmailSent))
+		require.NoError(t, m.Transition(ctx, f, flow.StatePassedChallenge))
+		assert.Equal(t, flow.StatePassedChallenge, f.State)
+
+		require.Len(t, f.StateTransitions, 2)
+		assert.Equal(t, flow.StateChooseMethod, f.StateTransitions[0].From)
+		assert.Equal(t, flow.StateEmailSent, f.StateTransitions[0].To)
+		assert.Equal(t, flow.StateEmailSent, f.StateTransitions[1].From)
+		assert.Equal(t, flow.StatePassedChallenge, f.StateTransitions[1].To)
+		assert.False(t, f.StateTransitions[1].At.IsZero())
+	})
+
+	t.Run("case=rejects invalid transition", func(t *testing.T) {
+		f := &recovery.Flow{ID: x.NewUUID(), State: flow.StatePassedChallenge}
+
+		err := m.Transition(ctx, f, flow.StateEmailSent)
+		require.ErrorIs(t, err, flow.ErrStateTransitionNotAllowed)
+		assert.Equal(t, flow.StatePassedChallenge, f.State)
+		assert.Empty(t, f.StateTransitions)
+	})
+
+	t.Run("case=transitions survive a database round trip", func(t *testing.T) {
+		in := flow.StateTransitions{{From: flow.StateChooseMethod, To: flow.StateEmailSent}}
+		v, err := in.Value()
+		require.NoError(t, err)
+
+		var out flow.StateTransitions
+		require.NoError(t, out.Scan(v))
+		assert.Equal(t, in[0].From, out[0].From)
+		assert.Equal(t, in[0].To, out[0].To)
+
+		var empty flow.StateTransitions
+		require.NoError(t, empty.Scan(nil))
+		assert.Empty(t, empty)
+	})
+}
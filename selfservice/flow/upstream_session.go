@@ -0,0 +1,53 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package flow
+
+import (
+	"encoding/json"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const internalContextUpstreamSessionPath = "upstream_session"
+
+// UpstreamSessionData identifies the session of the upstream identity provider which completed the flow.
+type UpstreamSessionData struct {
+	Provider  string
+	Subject   string
+	SessionID string
+}
+
+// SetUpstreamSession sets the upstream session data in the flow's internal context.
+func SetUpstreamSession(flow InternalContexter, data UpstreamSessionData) error {
+	if flow.GetInternalContext() == nil {
+		flow.EnsureInternalContext()
+	}
+	bytes, err := sjson.SetBytes(
+		flow.GetInternalContext(),
+		internalContextUpstreamSessionPath,
+		data,
+	)
+	if err != nil {
+		return err
+	}
+	flow.SetInternalContext(bytes)
+
+	return nil
+}
+
+// UpstreamSession returns the upstream session data from the flow's internal context.
+func UpstreamSession(flow InternalContexter) (*UpstreamSessionData, error) {
+	if flow.GetInternalContext() == nil {
+		flow.EnsureInternalContext()
+	}
+	raw := gjson.GetBytes(flow.GetInternalContext(), internalContextUpstreamSessionPath)
+	if !raw.IsObject() {
+		return nil, nil
+	}
+	var data UpstreamSessionData
+	err := json.Unmarshal([]byte(raw.Raw), &data)
+
+	return &data, err
+}
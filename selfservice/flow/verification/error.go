@@ -36,6 +36,7 @@ type (
 		x.CSRFProvider
 		x.CSRFTokenGeneratorProvider
 		config.Provider
+		flow.BeforeRenderHooksProvider
 		FlowPersistenceProvider
 		StrategyProvider
 	}
@@ -129,6 +130,12 @@ func (s *ErrorHandler) WriteFlowError(
 	updatedFlow, innerErr := s.d.VerificationFlowPersister().GetVerificationFlow(r.Context(), f.ID)
 	if innerErr != nil {
 		s.forward(w, r, updatedFlow, innerErr)
+		return
+	}
+
+	if err := flow.ExecuteBeforeRenderHooks(w, r, s.d, updatedFlow); err != nil {
+		s.forward(w, r, updatedFlow, err)
+		return
 	}
 
 	s.d.Writer().WriteCode(w, r, x.RecoverStatusCode(err, http.StatusBadRequest), updatedFlow)
@@ -62,6 +62,10 @@ type Flow struct {
 	// ReturnTo contains the requested return_to URL.
 	ReturnTo string `json:"return_to,omitempty" db:"-"`
 
+	// Theme contains the branding variables configured in `selfservice.theme.variables`,
+	// e.g. the logo URL or product name.
+	Theme map[string]interface{} `json:"theme,omitempty" db:"-"`
+
 	// Active, if set, contains the registration method that is being used. It is initially
 	// not set.
 	Active sqlxx.NullString `json:"active,omitempty" faker:"-" db:"active_method"`
@@ -80,6 +84,9 @@ type Flow struct {
 	// required: true
 	State State `json:"state" faker:"-" db:"state"`
 
+	// StateTransitions records every state change of this flow.
+	StateTransitions flow.StateTransitions `json:"-" faker:"-" db:"state_transitions"`
+
 	// OAuth2LoginChallenge holds the login challenge originally set during the registration flow.
 	OAuth2LoginChallenge sqlxx.NullString `json:"-" db:"oauth2_login_challenge"`
 	OAuth2LoginChallengeParams
@@ -106,7 +113,7 @@ type OAuth2LoginChallengeParams struct {
 	AMR session.AuthenticationMethods `db:"authentication_methods" json:"-"`
 }
 
-var _ flow.Flow = new(Flow)
+var _ flow.FlowWithStateTransitions = new(Flow)
 
 func (f *Flow) GetType() flow.Type {
 	return f.Type
@@ -121,7 +128,7 @@ func (f Flow) TableName(context.Context) string {
 }
 
 func NewFlow(conf *config.Config, exp time.Duration, csrf string, r *http.Request, strategy Strategy, ft flow.Type) (*Flow, error) {
-	now := time.Now().UTC()
+	now := x.Now().UTC()
 	id := x.NewUUID()
 
 	// Pre-validate the return to URL which is contained in the HTTP request.
@@ -146,7 +153,7 @@ func NewFlow(conf *config.Config, exp time.Duration, csrf string, r *http.Reques
 			Action: flow.AppendFlowTo(urlx.AppendPaths(conf.SelfPublicURL(r.Context()), RouteSubmitFlow), id).String(),
 		},
 		CSRFToken: csrf,
-		State:     flow.StateChooseMethod,
+		State:     StateMachine.Initial(),
 		Type:      ft,
 	}
 
@@ -197,7 +204,7 @@ func NewPostHookFlow(conf *config.Config, exp time.Duration, csrf string, r *htt
 }
 
 func (f *Flow) Valid() error {
-	if f.ExpiresAt.Before(time.Now()) {
+	if f.ExpiresAt.Before(x.Now()) {
 		return errors.WithStack(flow.NewFlowExpiredError(f.ExpiresAt))
 	}
 	return nil
@@ -285,6 +292,15 @@ func (f *Flow) GetFlowName() flow.FlowName {
 	return flow.VerificationFlow
 }
 
+// SetTheme implements flow.ThemedFlow.
+func (f *Flow) SetTheme(theme map[string]interface{}) {
+	f.Theme = theme
+}
+
 func (f *Flow) SetState(state State) {
 	f.State = state
 }
+
+func (f *Flow) AppendStateTransition(t flow.StateTransition) {
+	f.StateTransitions = append(f.StateTransitions, t)
+}
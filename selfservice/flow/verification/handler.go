@@ -5,7 +5,6 @@ package verification
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/ory/kratos/hydra"
 	"github.com/ory/kratos/session"
@@ -42,6 +41,7 @@ type (
 		VerificationHandler() *Handler
 	}
 	handlerDependencies interface {
+		flow.BeforeRenderHooksProvider
 		errorx.ManagementProvider
 		identity.ManagementProvider
 		identity.PrivilegedPoolProvider
@@ -77,8 +77,8 @@ func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	public.GET(RouteInitAPIFlow, h.createNativeVerificationFlow)
 	public.GET(RouteGetFlow, h.getVerificationFlow)
 
-	public.POST(RouteSubmitFlow, h.updateVerificationFlow)
-	public.GET(RouteSubmitFlow, h.updateVerificationFlow)
+	public.POST(RouteSubmitFlow, identity.WithSelfServiceAuditActor(h.updateVerificationFlow))
+	public.GET(RouteSubmitFlow, identity.WithSelfServiceAuditActor(h.updateVerificationFlow))
 }
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
@@ -157,6 +157,11 @@ func (h *Handler) createNativeVerificationFlow(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, req); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	h.d.Writer().Write(w, r, req)
 }
 
@@ -204,6 +209,13 @@ func (h *Handler) createBrowserVerificationFlow(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if x.AcceptsJSON(r) {
+		if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, req); err != nil {
+			h.d.SelfServiceErrorManager().Forward(r.Context(), w, r, err)
+			return
+		}
+	}
+
 	redirTo := req.AppendTo(h.d.Config().SelfServiceFlowVerificationUI(r.Context())).String()
 	x.AcceptToRedirectOrJSON(w, r, h.d.Writer(), req, redirTo)
 }
@@ -288,7 +300,7 @@ func (h *Handler) getVerificationFlow(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
-	if req.ExpiresAt.Before(time.Now().UTC()) {
+	if req.ExpiresAt.Before(x.Now().UTC()) {
 		if req.Type == flow.TypeBrowser {
 			redirectURL := flow.GetFlowExpiredRedirectURL(r.Context(), h.d.Config(), RouteInitBrowserFlow, req.ReturnTo)
 
@@ -304,6 +316,11 @@ func (h *Handler) getVerificationFlow(w http.ResponseWriter, r *http.Request, _
 		return
 	}
 
+	if err := flow.ExecuteBeforeRenderHooks(w, r, h.d, req); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
 	h.d.Writer().Write(w, r, req)
 }
 
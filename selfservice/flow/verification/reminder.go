@@ -0,0 +1,278 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package verification
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/pointerx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	ReminderProvider interface {
+		VerificationReminder() *Reminder
+	}
+	reminderDependencies interface {
+		config.Provider
+		identity.PrivilegedPoolProvider
+		x.LoggingProvider
+		x.TracingProvider
+
+		FlowPersistenceProvider
+		StrategyProvider
+	}
+
+	// Reminder reminds identities to verify their addresses and expires accounts which stay unverified,
+	// following the policy in `selfservice.flows.verification.reminders`.
+	//
+	// It is meant to be run periodically, either from a cron job running `kratos verification remind` or in
+	// the background of the server using Watch. Several instances can run at the same time, because every
+	// reminder is claimed before it is sent.
+	Reminder struct {
+		d       reminderDependencies
+		metrics *reminderMetrics
+	}
+
+	// ReminderReport summarizes a single run of the Reminder.
+	ReminderReport struct {
+		RemindersSent       int
+		AccountsDeactivated int
+		AccountsDeleted     int
+		IdentitiesOptedOut  int
+		IdentitiesFailed    int
+	}
+)
+
+func NewReminder(d reminderDependencies) *Reminder {
+	return &Reminder{d: d, metrics: newReminderMetrics()}
+}
+
+// Watch runs the reminder in the configured interval until the context is canceled.
+func (r *Reminder) Watch(ctx context.Context, batchSize int) {
+	for {
+		if report, err := r.Run(ctx, batchSize); err != nil {
+			r.d.Logger().WithError(err).Warn("Unable to send verification reminders.")
+		} else if report.RemindersSent > 0 || report.AccountsDeactivated > 0 || report.AccountsDeleted > 0 {
+			r.d.Logger().
+				WithField("reminders_sent", report.RemindersSent).
+				WithField("accounts_deactivated", report.AccountsDeactivated).
+				WithField("accounts_deleted", report.AccountsDeleted).
+				WithField("identities_failed", report.IdentitiesFailed).
+				Info("Sent verification reminders.")
+		}
+
+		if err := r.RefreshMetrics(ctx); err != nil {
+			r.d.Logger().WithError(err).Warn("Unable to collect verification reminder statistics.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.d.Config().SelfServiceFlowVerificationRemindersInterval(ctx)):
+		}
+	}
+}
+
+// Run processes all unverified addresses in batches of batchSize.
+func (r *Reminder) Run(ctx context.Context, batchSize int) (_ *ReminderReport, err error) {
+	ctx, span := r.d.Tracer(ctx).Tracer().Start(ctx, "selfservice.flow.verification.Reminder.Run")
+	defer otelx.End(span, &err)
+
+	report := new(ReminderReport)
+
+	policies, err := r.d.Config().SelfServiceFlowVerificationReminders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	minAge, ok := policies.MinAge()
+	if !ok {
+		r.d.Logger().Info("Verification reminders are disabled, nothing to do.")
+		return report, nil
+	}
+
+	now := x.Now().UTC()
+	after := uuid.Nil
+	handled := make(map[uuid.UUID]bool)
+	for {
+		addresses, err := r.d.PrivilegedIdentityPool().ListUnverifiedAddresses(ctx, now.Add(-minAge), after, batchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for k := range addresses {
+			address := &addresses[k]
+			after = address.ID
+
+			if handled[address.IdentityID] {
+				continue
+			}
+			handled[address.IdentityID] = true
+
+			if err := r.remindIdentity(ctx, policies, address.IdentityID, now, report); err != nil {
+				r.d.Logger().
+					WithError(err).
+					WithField("identity_id", address.IdentityID).
+					Warn("Unable to send verification reminders to identity.")
+				report.IdentitiesFailed++
+			}
+		}
+
+		if len(addresses) < batchSize {
+			return report, nil
+		}
+	}
+}
+
+func (r *Reminder) remindIdentity(ctx context.Context, policies *config.VerificationReminders, id uuid.UUID, now time.Time, report *ReminderReport) error {
+	// The credentials are loaded as well, because they are kept when the account is deactivated.
+	i, err := r.d.PrivilegedIdentityPool().GetIdentityConfidential(ctx, id)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		// The identity was deleted in the meantime.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	policy := policies.ForSchema(i.SchemaID)
+	if !policy.Enabled {
+		report.IdentitiesOptedOut++
+		return nil
+	}
+
+	var (
+		unverified []*identity.VerifiableAddress
+		verified   bool
+		oldest     time.Time
+	)
+	for k := range i.VerifiableAddresses {
+		address := &i.VerifiableAddresses[k]
+		if address.Verified {
+			verified = true
+			continue
+		}
+		unverified = append(unverified, address)
+		if oldest.IsZero() || address.CreatedAt.Before(oldest) {
+			oldest = address.CreatedAt
+		}
+	}
+
+	if after, expires := policy.ExpiresAfter(); expires && !verified && len(unverified) > 0 && now.Sub(oldest) >= after {
+		return r.expire(ctx, i, policy.UnverifiedAccountAction, report)
+	}
+
+	if !i.IsActive() {
+		return nil
+	}
+
+	for _, address := range unverified {
+		due := policy.DueReminders(now.Sub(address.CreatedAt))
+		if address.RemindersSent >= due {
+			continue
+		}
+
+		// The reminder is claimed before it is sent, so that it is sent only once if several instances run at
+		// the same time.
+		previous, lastRemindedAt := address.RemindersSent, address.LastRemindedAt
+		address.RemindersSent = due
+		address.LastRemindedAt = pointerx.Ptr(sqlxx.NullTime(now))
+		if err := r.d.PrivilegedIdentityPool().ClaimVerifiableAddressReminder(ctx, address, previous); errors.Is(err, sqlcon.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if err := r.remind(ctx, i, address); err != nil {
+			// Release the claim, so that the reminder is sent by the next run.
+			claimed := due
+			address.RemindersSent, address.LastRemindedAt = previous, lastRemindedAt
+			if err := r.d.PrivilegedIdentityPool().ClaimVerifiableAddressReminder(ctx, address, claimed); err != nil && !errors.Is(err, sqlcon.ErrNoRows) {
+				r.d.Logger().WithError(err).WithField("identity_id", i.ID).Warn("Unable to release the claim of a failed verification reminder.")
+			}
+			return err
+		}
+		r.metrics.sent.WithLabelValues(string(address.Via)).Inc()
+		report.RemindersSent++
+	}
+
+	return nil
+}
+
+func (r *Reminder) expire(ctx context.Context, i *identity.Identity, action config.UnverifiedAccountAction, report *ReminderReport) error {
+	logger := r.d.Logger().WithField("identity_id", i.ID).WithField("action", action)
+
+	switch action {
+	case config.UnverifiedAccountActionDeactivate:
+		if !i.IsActive() {
+			return nil
+		}
+		i.State = identity.StateInactive
+		if err := r.d.PrivilegedIdentityPool().UpdateIdentityIfUnmodifiedSince(ctx, i, i.UpdatedAt); errors.Is(err, identity.ErrIdentityModified) {
+			// The identity was changed in the meantime, for example by another instance. The next run decides again.
+			return nil
+		} else if err != nil {
+			return err
+		}
+		report.AccountsDeactivated++
+		r.metrics.expired.WithLabelValues(string(action)).Inc()
+	case config.UnverifiedAccountActionDelete:
+		if err := r.d.PrivilegedIdentityPool().DeleteIdentity(ctx, i.ID); errors.Is(err, sqlcon.ErrNoRows) {
+			// The identity was deleted in the meantime, for example by another instance.
+			return nil
+		} else if err != nil {
+			return err
+		}
+		report.AccountsDeleted++
+		r.metrics.expired.WithLabelValues(string(action)).Inc()
+	default:
+		return errors.Errorf("unknown unverified account action: %s", action)
+	}
+
+	logger.Info("Expired unverified account.")
+	return nil
+}
+
+func (r *Reminder) remind(ctx context.Context, i *identity.Identity, address *identity.VerifiableAddress) error {
+	strategy, err := r.d.GetActiveVerificationStrategy(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The flow is created on behalf of the identity, so we construct the request it would have sent to start it.
+	req, err := http.NewRequestWithContext(ctx, "GET", urlx.AppendPaths(r.d.Config().SelfPublicURL(ctx), RouteInitBrowserFlow).String(), nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// There is no browser yet, so the flow has no CSRF token. It is set once the link in the email is opened.
+	f, err := NewFlow(r.d.Config(), r.d.Config().SelfServiceFlowVerificationRequestLifespan(ctx), "", req, strategy, flow.TypeBrowser)
+	if err != nil {
+		return err
+	}
+	f.IdentityID = uuid.NullUUID{UUID: i.ID, Valid: true}
+
+	if err := StateMachine.Transition(ctx, f, flow.StateEmailSent); err != nil {
+		return err
+	}
+
+	if err := r.d.VerificationFlowPersister().CreateVerificationFlow(ctx, f); err != nil {
+		return err
+	}
+
+	return strategy.SendVerificationEmail(ctx, f, i, address)
+}
@@ -0,0 +1,76 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package verification
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/kratos/identity"
+)
+
+// reminderMetrics tracks how effective verification reminders are: how many reminders were sent and how
+// many of the reminded addresses were verified afterwards.
+type reminderMetrics struct {
+	sent      *prometheus.CounterVec
+	expired   *prometheus.CounterVec
+	addresses *prometheus.Desc
+
+	mu    sync.RWMutex
+	stats []identity.VerificationReminderStats
+}
+
+var _ prometheus.Collector = new(Reminder)
+
+func newReminderMetrics() *reminderMetrics {
+	return &reminderMetrics{
+		sent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kratos_verification_reminders_sent_total",
+			Help: "Number of verification reminders sent.",
+		}, []string{"via"}),
+		expired: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kratos_verification_unverified_accounts_expired_total",
+			Help: "Number of accounts deactivated or deleted because they stayed unverified.",
+		}, []string{"action"}),
+		addresses: prometheus.NewDesc(
+			"kratos_verification_reminded_addresses",
+			"Number of addresses which received verification reminders, by number of reminders and whether they were verified.",
+			[]string{"reminders_sent", "verified"}, nil,
+		),
+	}
+}
+
+func (r *Reminder) Describe(ch chan<- *prometheus.Desc) {
+	r.metrics.sent.Describe(ch)
+	r.metrics.expired.Describe(ch)
+	ch <- r.metrics.addresses
+}
+
+func (r *Reminder) Collect(ch chan<- prometheus.Metric) {
+	r.metrics.sent.Collect(ch)
+	r.metrics.expired.Collect(ch)
+
+	r.metrics.mu.RLock()
+	defer r.metrics.mu.RUnlock()
+	for _, s := range r.metrics.stats {
+		ch <- prometheus.MustNewConstMetric(r.metrics.addresses, prometheus.GaugeValue, float64(s.Addresses),
+			strconv.Itoa(s.RemindersSent), strconv.FormatBool(s.Verified))
+	}
+}
+
+// RefreshMetrics queries how many of the reminded addresses were verified.
+func (r *Reminder) RefreshMetrics(ctx context.Context) error {
+	stats, err := r.d.PrivilegedIdentityPool().VerificationReminderStats(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.metrics.mu.Lock()
+	defer r.metrics.mu.Unlock()
+	r.metrics.stats = stats
+	return nil
+}
@@ -0,0 +1,207 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package verification_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/x/sqlcon"
+)
+
+func TestReminder(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/reminder.schema.json")
+	conf.MustSet(ctx, config.ViperKeyPublicBaseURL, "https://www.ory.sh/")
+	conf.MustSet(ctx, config.ViperKeySelfServiceVerificationEnabled, true)
+
+	createIdentity := func(t *testing.T, email string, age time.Duration) *identity.Identity {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{"emails":["` + email + `"]}`)
+		address := identity.NewVerifiableEmailAddress(email, i.ID)
+		address.CreatedAt = time.Now().UTC().Add(-age)
+		i.VerifiableAddresses = []identity.VerifiableAddress{*address}
+		require.NoError(t, reg.IdentityManager().Create(ctx, i))
+		return i
+	}
+
+	setPolicy := func(t *testing.T, policy map[string]interface{}) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceVerificationReminders, policy)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceVerificationReminders, map[string]interface{}{})
+		})
+	}
+
+	t.Run("case=does nothing if disabled", func(t *testing.T) {
+		createIdentity(t, "reminder-disabled@ory.sh", 48*time.Hour)
+
+		report, err := reg.VerificationReminder().Run(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.RemindersSent)
+	})
+
+	t.Run("case=sends each due reminder once", func(t *testing.T) {
+		setPolicy(t, map[string]interface{}{
+			"enabled":   true,
+			"intervals": []string{"24h", "72h"},
+		})
+		createIdentity(t, "reminder-young@ory.sh", time.Hour)
+		createIdentity(t, "reminder-old@ory.sh", 25*time.Hour)
+
+		_, err := reg.VerificationReminder().Run(ctx, 1)
+		require.NoError(t, err)
+
+		testhelpers.CourierExpectMessage(ctx, t, reg, "reminder-old@ory.sh", "Please verify your email address")
+
+		address, err := reg.IdentityPool().FindVerifiableAddressByValue(ctx, identity.VerifiableAddressTypeEmail, "reminder-old@ory.sh")
+		require.NoError(t, err)
+		assert.Equal(t, 1, address.RemindersSent)
+		assert.NotNil(t, address.LastRemindedAt)
+
+		address, err = reg.IdentityPool().FindVerifiableAddressByValue(ctx, identity.VerifiableAddressTypeEmail, "reminder-young@ory.sh")
+		require.NoError(t, err)
+		assert.Equal(t, 0, address.RemindersSent)
+
+		report, err := reg.VerificationReminder().Run(ctx, 1)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.RemindersSent, "reminders must not be sent twice")
+	})
+
+	t.Run("case=does not send reminders claimed by another instance", func(t *testing.T) {
+		setPolicy(t, map[string]interface{}{
+			"enabled":   true,
+			"intervals": []string{"24h"},
+		})
+		createIdentity(t, "reminder-claimed@ory.sh", 25*time.Hour)
+
+		address, err := reg.IdentityPool().FindVerifiableAddressByValue(ctx, identity.VerifiableAddressTypeEmail, "reminder-claimed@ory.sh")
+		require.NoError(t, err)
+		address.RemindersSent = 1
+		require.NoError(t, reg.PrivilegedIdentityPool().ClaimVerifiableAddressReminder(ctx, address, 0))
+
+		report, err := reg.VerificationReminder().Run(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.RemindersSent)
+		assert.Zero(t, report.IdentitiesFailed)
+	})
+
+	t.Run("case=schemas can opt out", func(t *testing.T) {
+		setPolicy(t, map[string]interface{}{
+			"enabled":   true,
+			"intervals": []string{"24h"},
+			"schemas": []map[string]interface{}{
+				{"id": config.DefaultIdentityTraitsSchemaID, "enabled": false},
+			},
+		})
+		createIdentity(t, "reminder-opted-out@ory.sh", 48*time.Hour)
+
+		report, err := reg.VerificationReminder().Run(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.RemindersSent)
+		assert.NotZero(t, report.IdentitiesOptedOut)
+	})
+
+	t.Run("case=deactivates unverified accounts", func(t *testing.T) {
+		setPolicy(t, map[string]interface{}{
+			"enabled": true,
+			"unverified_accounts": map[string]interface{}{
+				"action": "deactivate",
+				"after":  "720h",
+			},
+		})
+		expired := createIdentity(t, "reminder-deactivate@ory.sh", 721*time.Hour)
+		fresh := createIdentity(t, "reminder-keep@ory.sh", 24*time.Hour)
+
+		report, err := reg.VerificationReminder().Run(ctx, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.AccountsDeactivated)
+
+		actual, err := reg.IdentityPool().GetIdentity(ctx, expired.ID, identity.ExpandNothing)
+		require.NoError(t, err)
+		assert.Equal(t, identity.StateInactive, actual.State)
+
+		actual, err = reg.IdentityPool().GetIdentity(ctx, fresh.ID, identity.ExpandNothing)
+		require.NoError(t, err)
+		assert.Equal(t, identity.StateActive, actual.State)
+	})
+
+	t.Run("case=deletes unverified accounts", func(t *testing.T) {
+		setPolicy(t, map[string]interface{}{
+			"enabled": true,
+			"unverified_accounts": map[string]interface{}{
+				"action": "delete",
+				"after":  "720h",
+			},
+		})
+		expired := createIdentity(t, "reminder-delete@ory.sh", 721*time.Hour)
+
+		_, err := reg.VerificationReminder().Run(ctx, 10)
+		require.NoError(t, err)
+
+		_, err = reg.IdentityPool().GetIdentity(ctx, expired.ID, identity.ExpandNothing)
+		require.ErrorIs(t, err, sqlcon.ErrNoRows)
+	})
+
+	t.Run("case=reports how many reminded addresses were verified", func(t *testing.T) {
+		setPolicy(t, map[string]interface{}{
+			"enabled":   true,
+			"intervals": []string{"24h"},
+		})
+		i := createIdentity(t, "reminder-metrics@ory.sh", 25*time.Hour)
+
+		reminder := reg.VerificationReminder()
+		metrics := prometheus.NewRegistry()
+		require.NoError(t, metrics.Register(reminder))
+
+		value := func(t *testing.T, name string, labels map[string]string) float64 {
+			families, err := metrics.Gather()
+			require.NoError(t, err)
+			for _, f := range families {
+				if f.GetName() != name {
+					continue
+				}
+			metrics:
+				for _, m := range f.GetMetric() {
+					for _, l := range m.GetLabel() {
+						if labels[l.GetName()] != l.GetValue() {
+							continue metrics
+						}
+					}
+					if m.GetCounter() != nil {
+						return m.GetCounter().GetValue()
+					}
+					return m.GetGauge().GetValue()
+				}
+			}
+			return 0
+		}
+
+		_, err := reminder.Run(ctx, 10)
+		require.NoError(t, err)
+		testhelpers.CourierExpectMessage(ctx, t, reg, "reminder-metrics@ory.sh", "Please verify your email address")
+		assert.GreaterOrEqual(t, value(t, "kratos_verification_reminders_sent_total", map[string]string{"via": "email"}), float64(1))
+
+		require.NoError(t, reminder.RefreshMetrics(ctx))
+		verified := value(t, "kratos_verification_reminded_addresses", map[string]string{"reminders_sent": "1", "verified": "true"})
+
+		address, err := reg.IdentityPool().FindVerifiableAddressByValue(ctx, identity.VerifiableAddressTypeEmail, "reminder-metrics@ory.sh")
+		require.NoError(t, err)
+		require.Equal(t, i.ID, address.IdentityID)
+		address.Verified = true
+		require.NoError(t, reg.PrivilegedIdentityPool().UpdateVerifiableAddress(ctx, address))
+
+		require.NoError(t, reminder.RefreshMetrics(ctx))
+		assert.Equal(t, verified+1, value(t, "kratos_verification_reminded_addresses", map[string]string{"reminders_sent": "1", "verified": "true"}))
+	})
+}
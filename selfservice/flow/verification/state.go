@@ -15,3 +15,9 @@ import "github.com/ory/kratos/selfservice/flow"
 //
 // swagger:model verificationFlowState
 type State = flow.State
+
+// StateMachine describes the allowed state transitions of a verification flow.
+var StateMachine = flow.NewStateMachine(flow.StateChooseMethod, map[State][]State{
+	flow.StateChooseMethod: {flow.StateEmailSent, flow.StatePassedChallenge},
+	flow.StateEmailSent:    {flow.StateEmailSent, flow.StatePassedChallenge},
+})
@@ -0,0 +1,91 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package hook
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+	grpccodes "google.golang.org/grpc/codes"
+
+	"github.com/ory/herodot"
+	"github.com/ory/jsonschema/v3"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/ui/container"
+)
+
+//go:embed .schema/before_render_response.schema.json
+var beforeRenderResponseSchema []byte
+
+const beforeRenderResponseSchemaID = "https://schemas.ory.sh/kratos/selfservice/hook/before_render_response.schema.json"
+
+// applyBeforeRenderResponse validates the response of a before render web hook against the
+// response schema and applies the requested changes to the UI of the flow.
+func applyBeforeRenderResponse(ctx context.Context, resp *http.Response, f flow.Flow) error {
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "unable to read the web hook response")
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	if err := validateBeforeRenderResponse(ctx, body); err != nil {
+		return err
+	}
+
+	var hookResponse struct {
+		UI container.Mutations `json:"ui"`
+	}
+	if err := json.Unmarshal(body, &hookResponse); err != nil {
+		return errors.Wrap(err, "webhook response could not be unmarshalled properly from JSON")
+	}
+
+	if err := f.GetUI().ApplyMutations(&hookResponse.UI); err != nil {
+		return newBeforeRenderResponseError("unable to apply the UI changes of the web hook: %s", err)
+	}
+	return nil
+}
+
+func validateBeforeRenderResponse(ctx context.Context, body []byte) error {
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(beforeRenderResponseSchemaID, bytes.NewReader(beforeRenderResponseSchema)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	s, err := c.Compile(ctx, beforeRenderResponseSchemaID)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	doc, err := jsonschema.DecodeJSON(bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "webhook response could not be unmarshalled properly from JSON")
+	}
+
+	if err := s.ValidateInterface(doc); err != nil {
+		return newBeforeRenderResponseError("the web hook response does not match the before render response schema: %s", err)
+	}
+	return nil
+}
+
+func newBeforeRenderResponseError(format string, args ...any) error {
+	return errors.WithStack(herodot.DefaultError{
+		CodeField:     http.StatusBadGateway,
+		StatusField:   http.StatusText(http.StatusBadGateway),
+		GRPCCodeField: grpccodes.Aborted,
+		ReasonField:   "A third-party upstream service responded improperly. Please try again later.",
+		ErrorField:    fmt.Sprintf(format, args...),
+	})
+}
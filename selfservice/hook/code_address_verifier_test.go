@@ -47,7 +47,7 @@ func TestCodeAddressVerifier(t *testing.T) {
 		})
 		require.NoError(t, err)
 
-		_, err = reg.RegistrationCodePersister().UseRegistrationCode(ctx, rf.ID, rawCode, address)
+		_, err = reg.RegistrationCodePersister().UseRegistrationCode(ctx, rf.ID, rawCode, nil, address)
 		require.NoError(t, err)
 
 		return
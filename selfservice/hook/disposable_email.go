@@ -0,0 +1,224 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package hook
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/request"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/jsonnetsecure"
+	"github.com/ory/x/otelx"
+)
+
+//go:embed disposable_email_domains.txt
+var disposableEmailDomainList string
+
+var (
+	_ registration.PostHookPrePersistExecutor = new(DisposableEmail)
+
+	builtinDisposableEmailDomains = parseDomainList(disposableEmailDomainList)
+)
+
+const (
+	// DisposableEmailActionReject rejects registrations with disposable email addresses.
+	DisposableEmailActionReject = "reject"
+
+	// DisposableEmailActionFlag lets registrations with disposable email addresses pass but marks
+	// the identity in its admin metadata.
+	DisposableEmailActionFlag = "flag"
+
+	// metadataAdminDisposableEmailPath is the path of the admin metadata which flags identities.
+	metadataAdminDisposableEmailPath = "disposable_email"
+
+	// disposableEmailAPIBody is used if the reputation API is configured without a body template.
+	// It renders to `{"email": "..."}`.
+	disposableEmailAPIBody = "base64://ZnVuY3Rpb24oY3R4KSB7IGVtYWlsOiBjdHguZW1haWwgfQo="
+)
+
+type (
+	disposableEmailDependencies interface {
+		identity.ValidationProvider
+		x.LoggingProvider
+		x.HTTPClientProvider
+		x.TracingProvider
+		jsonnetsecure.VMProvider
+	}
+
+	// DisposableEmail detects registrations with email addresses of disposable email providers
+	// using a built-in list of domains, configured domains, and optionally a reputation API.
+	DisposableEmail struct {
+		d    disposableEmailDependencies
+		conf json.RawMessage
+	}
+
+	disposableEmailConfig struct {
+		// Action is either DisposableEmailActionReject (default) or DisposableEmailActionFlag.
+		Action string `json:"action"`
+
+		// Domains extends the built-in list of disposable email domains.
+		Domains []string `json:"domains"`
+
+		// Allow lists domains which are never treated as disposable.
+		Allow []string `json:"allow"`
+
+		// API is the request config of the reputation API, which must respond with
+		// `{"disposable": true}` for disposable addresses.
+		API json.RawMessage `json:"api"`
+	}
+
+	disposableEmailAPIContext struct {
+		Email string `json:"email"`
+	}
+)
+
+func NewDisposableEmail(d disposableEmailDependencies, conf json.RawMessage) *DisposableEmail {
+	return &DisposableEmail{d: d, conf: conf}
+}
+
+// ExecutePostRegistrationPrePersistHook rejects or flags the identity if one of its email addresses
+// belongs to a disposable email provider.
+func (e *DisposableEmail) ExecutePostRegistrationPrePersistHook(_ http.ResponseWriter, r *http.Request, _ *registration.Flow, i *identity.Identity) error {
+	return otelx.WithSpan(r.Context(), "selfservice.hook.DisposableEmail.ExecutePostRegistrationPrePersistHook", func(ctx context.Context) error {
+		var c disposableEmailConfig
+		if len(e.conf) > 0 {
+			if err := json.Unmarshal(e.conf, &c); err != nil {
+				return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the configuration of the %s hook.", KeyDisposableEmail).WithWrap(err))
+			}
+		}
+
+		// The email addresses are only known once the traits were validated.
+		if err := e.d.IdentityValidator().Validate(ctx, i); err != nil {
+			return err
+		}
+
+		for _, address := range i.EmailAddresses() {
+			_, domain, _ := strings.Cut(address, "@")
+			if !e.isDisposable(ctx, &c, address, domain) {
+				continue
+			}
+
+			if c.Action != DisposableEmailActionFlag {
+				return schema.NewDisposableEmailError(i.TraitPointer(address), domain)
+			}
+
+			metadata := []byte(i.MetadataAdmin)
+			if !gjson.ParseBytes(metadata).IsObject() {
+				metadata = []byte(`{}`)
+			}
+			metadata, err := sjson.SetBytes(metadata, metadataAdminDisposableEmailPath, true)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			i.MetadataAdmin = metadata
+
+			e.d.Logger().
+				WithRequest(r).
+				WithSensitiveField("address", address).
+				Info("Flagged a registration with a disposable email address.")
+			return nil
+		}
+
+		return nil
+	})
+}
+
+func (e *DisposableEmail) isDisposable(ctx context.Context, c *disposableEmailConfig, address, domain string) bool {
+	if matchesDomainList(domain, c.Allow) {
+		return false
+	} else if matchesDomainList(domain, builtinDisposableEmailDomains) || matchesDomainList(domain, c.Domains) {
+		return true
+	} else if len(c.API) == 0 {
+		return false
+	}
+
+	disposable, err := e.checkReputationAPI(ctx, c.API, address)
+	if err != nil {
+		// An unavailable reputation API must not block registrations.
+		e.d.Logger().WithError(err).Warn("Unable to check the email address against the reputation API, treating it as permanent.")
+		return false
+	}
+	return disposable
+}
+
+func (e *DisposableEmail) checkReputationAPI(ctx context.Context, conf json.RawMessage, address string) (_ bool, err error) {
+	ctx, span := e.d.Tracer(ctx).Tracer().Start(ctx, "selfservice.hook.DisposableEmail.checkReputationAPI")
+	defer otelx.End(span, &err)
+
+	if !gjson.GetBytes(conf, "method").Exists() {
+		if conf, err = sjson.SetBytes(conf, "method", http.MethodPost); err != nil {
+			return false, errors.WithStack(err)
+		}
+	}
+	if !gjson.GetBytes(conf, "body").Exists() {
+		if conf, err = sjson.SetBytes(conf, "body", disposableEmailAPIBody); err != nil {
+			return false, errors.WithStack(err)
+		}
+	}
+
+	builder, err := request.NewBuilder(ctx, conf, e.d)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := builder.BuildRequest(ctx, &disposableEmailAPIContext{Email: address})
+	if err != nil {
+		return false, err
+	}
+
+	res, err := e.d.HTTPClient(ctx).Do(req.WithContext(ctx))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return false, errors.Errorf("reputation API responded with status code %d", res.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return gjson.GetBytes(body, "disposable").Bool(), nil
+}
+
+// parseDomainList returns the domains of a list with one domain per line. Empty lines and
+// comments starting with `#` are skipped.
+func parseDomainList(list string) []string {
+	var domains []string
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, line)
+	}
+	return domains
+}
+
+// matchesDomainList returns true if the domain or one of its parent domains is in the list.
+func matchesDomainList(domain string, list []string) bool {
+	for _, d := range list {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d != "" && (domain == d || strings.HasSuffix(domain, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package hook_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/hook"
+)
+
+func TestDisposableEmail(t *testing.T) {
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/verify.schema.json")
+
+	execute := func(t *testing.T, config string, address string) (*identity.Identity, error) {
+		i := identity.NewIdentity("")
+		i.Traits = identity.Traits(fmt.Sprintf(`{"emails":[%q]}`, address))
+
+		r := httptest.NewRequest("POST", "/self-service/registration", nil)
+		err := hook.NewDisposableEmail(reg, json.RawMessage(config)).
+			ExecutePostRegistrationPrePersistHook(httptest.NewRecorder(), r, new(registration.Flow), i)
+		return i, err
+	}
+
+	t.Run("case=rejects addresses of the built-in list", func(t *testing.T) {
+		_, err := execute(t, `{}`, "foo@mailinator.com")
+		var e *schema.ValidationError
+		require.ErrorAs(t, err, &e)
+		assert.Equal(t, "#/traits/emails/0", e.InstancePtr)
+
+		_, err = execute(t, `{}`, "foo@inbox.mailinator.com")
+		require.Error(t, err, "subdomains are matched as well")
+	})
+
+	t.Run("case=accepts permanent addresses", func(t *testing.T) {
+		_, err := execute(t, `{}`, "foo@ory.sh")
+		require.NoError(t, err)
+	})
+
+	t.Run("case=uses the configured domains", func(t *testing.T) {
+		_, err := execute(t, `{"domains":["throwaway.example"]}`, "foo@throwaway.example")
+		require.Error(t, err)
+
+		_, err = execute(t, `{"allow":["mailinator.com"]}`, "foo@mailinator.com")
+		require.NoError(t, err)
+	})
+
+	t.Run("case=flags the identity", func(t *testing.T) {
+		i, err := execute(t, `{"action":"flag"}`, "foo@yopmail.com")
+		require.NoError(t, err)
+		assert.True(t, gjson.GetBytes(i.MetadataAdmin, "disposable_email").Bool())
+	})
+
+	t.Run("case=asks the reputation API", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"disposable":%t}`, gjson.GetBytes(body, "email").String() == "foo@burner.example")
+		}))
+		t.Cleanup(ts.Close)
+		config := fmt.Sprintf(`{"api":{"url":%q}}`, ts.URL)
+
+		_, err := execute(t, config, "foo@burner.example")
+		require.Error(t, err)
+
+		_, err = execute(t, config, "foo@ory.sh")
+		require.NoError(t, err)
+	})
+
+	t.Run("case=treats addresses as permanent if the reputation API fails", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		t.Cleanup(ts.Close)
+
+		_, err := execute(t, fmt.Sprintf(`{"api":{"url":%q}}`, ts.URL), "foo@burner.example")
+		require.NoError(t, err)
+	})
+}
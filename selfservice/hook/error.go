@@ -10,6 +10,7 @@ import (
 
 	"github.com/tidwall/gjson"
 
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/recovery"
 	"github.com/ory/kratos/selfservice/flow/verification"
 	"github.com/ory/kratos/ui/node"
@@ -48,6 +49,8 @@ func (e Error) err(path string, abort error) error {
 		return errors.New("err")
 	case "abort":
 		return abort
+	case "respond":
+		return flow.NewHookRedirectResponse(gjson.GetBytes(e.Config, "redirect_to").String())
 	}
 	return nil
 }
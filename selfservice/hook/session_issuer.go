@@ -63,10 +63,16 @@ func (e *SessionIssuer) executePostRegistrationPostPersistHook(w http.ResponseWr
 			}
 		}
 
+		refreshToken, err := e.r.SessionManager().IssueRefreshToken(r.Context(), s)
+		if err != nil {
+			return err
+		}
+
 		a.AddContinueWith(flow.NewContinueWithSetToken(s.Token))
 		e.r.Writer().Write(w, r, &registration.APIFlowResponse{
 			Session:      s,
 			Token:        s.Token,
+			RefreshToken: refreshToken,
 			Identity:     s.Identity,
 			ContinueWith: a.ContinueWithItems,
 		})
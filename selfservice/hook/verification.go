@@ -16,6 +16,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/flow/verification"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 	"github.com/ory/x/otelx"
 )
@@ -86,6 +87,11 @@ func (e *Verifier) do(
 			continue
 		}
 
+		// Phone numbers are only verified if codes may be sent to them via SMS.
+		if address.Via == identity.VerifiableAddressTypePhone && !e.r.Config().SelfServiceFlowVerificationPhoneNumbers(ctx) {
+			continue
+		}
+
 		var csrf string
 
 		// TODO: this is pretty ugly, we should probably have a better way to handle CSRF tokens here.
@@ -110,11 +116,16 @@ func (e *Verifier) do(
 			flowCallback(verificationFlow)
 		}
 
-		verificationFlow.State = flow.StateEmailSent
+		if err := verification.StateMachine.Transition(ctx, verificationFlow, flow.StateEmailSent); err != nil {
+			return err
+		}
 
 		if err := strategy.PopulateVerificationMethod(r, verificationFlow); err != nil {
 			return err
 		}
+		if address.Via == identity.VerifiableAddressTypePhone {
+			verificationFlow.UI.Messages.Set(text.NewVerificationSMSWithCodeSent())
+		}
 
 		if err := e.r.VerificationFlowPersister().CreateVerificationFlow(ctx, verificationFlow); err != nil {
 			return err
@@ -94,6 +94,7 @@ func TestVerifier(t *testing.T) {
 			expectedVerificationFlow, err := reg.VerificationFlowPersister().GetVerificationFlow(ctx, fView.ID)
 			require.NoError(t, err)
 			require.Equal(t, expectedVerificationFlow.State, flow.StateEmailSent)
+			assert.Equal(t, expectedVerificationFlow.UI.Action, fView.SubmitURL)
 
 			messages, err := reg.CourierPersister().NextMessages(context.Background(), 12)
 			require.NoError(t, err)
@@ -0,0 +1,154 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package hook
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+)
+
+var _ login.PostHookExecutor = new(VerifiedAddressEnforcer)
+
+type (
+	verifiedAddressEnforcerDependencies interface {
+		config.Provider
+		x.CSRFTokenGeneratorProvider
+		verification.StrategyProvider
+		verification.FlowPersistenceProvider
+		identity.PrivilegedPoolProvider
+	}
+
+	// VerifiedAddressEnforcer refuses to issue a session if the address the identity
+	// signed in with is not verified. It is enabled for all or some login methods
+	// using `selfservice.flows.login.require_verified_address`.
+	VerifiedAddressEnforcer struct {
+		r verifiedAddressEnforcerDependencies
+	}
+)
+
+func NewVerifiedAddressEnforcer(r verifiedAddressEnforcerDependencies) *VerifiedAddressEnforcer {
+	return &VerifiedAddressEnforcer{r: r}
+}
+
+func (e *VerifiedAddressEnforcer) ExecuteLoginPostHook(_ http.ResponseWriter, r *http.Request, _ node.UiNodeGroup, f *login.Flow, s *session.Session) error {
+	return otelx.WithSpan(r.Context(), "selfservice.hook.VerifiedAddressEnforcer.ExecuteLoginPostHook", func(ctx context.Context) error {
+		return e.do(r.WithContext(ctx), f, s)
+	})
+}
+
+func (e *VerifiedAddressEnforcer) do(r *http.Request, f *login.Flow, s *session.Session) error {
+	ctx := r.Context()
+
+	if len(s.Identity.VerifiableAddresses) == 0 {
+		return nil
+	}
+
+	// The session holds the declassified identity, we need the credentials to find the identifiers.
+	i, err := e.r.PrivilegedIdentityPool().GetIdentityConfidential(ctx, s.Identity.ID)
+	if err != nil {
+		return err
+	}
+
+	address := e.unverifiedAddress(i, f.Active)
+	if address == nil {
+		return nil
+	}
+
+	if !e.r.Config().SelfServiceFlowVerificationEnabled(ctx) {
+		return errors.WithStack(schema.NewAddressNotVerifiedError())
+	}
+
+	strategy, err := e.r.GetActiveVerificationStrategy(ctx)
+	if err != nil {
+		return err
+	}
+
+	var csrf string
+	if f.Type == flow.TypeBrowser {
+		csrf = e.r.GenerateCSRFToken(r)
+	}
+
+	verificationFlow, err := verification.NewPostHookFlow(e.r.Config(),
+		e.r.Config().SelfServiceFlowVerificationRequestLifespan(ctx),
+		csrf, r, strategy, f)
+	if err != nil {
+		return err
+	}
+	verificationFlow.IdentityID = uuid.NullUUID{UUID: i.ID, Valid: true}
+
+	if err := verification.StateMachine.Transition(ctx, verificationFlow, flow.StateEmailSent); err != nil {
+		return err
+	}
+
+	if err := strategy.PopulateVerificationMethod(r, verificationFlow); err != nil {
+		return err
+	}
+	if address.Via == identity.VerifiableAddressTypePhone {
+		verificationFlow.UI.Messages.Set(text.NewVerificationSMSWithCodeSent())
+	}
+
+	if err := e.r.VerificationFlowPersister().CreateVerificationFlow(ctx, verificationFlow); err != nil {
+		return err
+	}
+
+	if err := strategy.SendVerificationEmail(ctx, verificationFlow, i, address); err != nil {
+		return err
+	}
+
+	flowURL := ""
+	if verificationFlow.Type == flow.TypeBrowser {
+		flowURL = verificationFlow.AppendTo(e.r.Config().SelfServiceFlowVerificationUI(ctx)).String()
+	}
+
+	return errors.WithStack(schema.NewAddressNotVerifiedErrorWithContinueWith(
+		flow.NewContinueWithVerificationUI(verificationFlow, address.Value, flowURL)))
+}
+
+// unverifiedAddress returns the address the identity signed in with, if it is not verified. Credentials whose
+// identifiers are not addresses, for example OpenID Connect subjects, require any of the addresses to be verified.
+func (e *VerifiedAddressEnforcer) unverifiedAddress(i *identity.Identity, ct identity.CredentialsType) *identity.VerifiableAddress {
+	var identifiers []string
+	if c, ok := i.GetCredentials(ct); ok {
+		identifiers = c.Identifiers
+	}
+
+	var used []*identity.VerifiableAddress
+	for k := range i.VerifiableAddresses {
+		address := &i.VerifiableAddresses[k]
+		for _, identifier := range identifiers {
+			if strings.EqualFold(strings.TrimSpace(identifier), address.Value) {
+				used = append(used, address)
+				break
+			}
+		}
+	}
+	if len(used) == 0 {
+		for k := range i.VerifiableAddresses {
+			used = append(used, &i.VerifiableAddresses[k])
+		}
+	}
+
+	for _, address := range used {
+		if address.Verified {
+			return nil
+		}
+	}
+	return used[0]
+}
@@ -0,0 +1,90 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package hook_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/hook"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/x/urlx"
+)
+
+func TestVerifiedAddressEnforcer(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/verify.schema.json")
+	conf.MustSet(ctx, config.ViperKeyPublicBaseURL, "https://www.ory.sh/")
+	conf.MustSet(ctx, config.ViperKeyCourierSMTPURL, "smtp://foo@bar@dev.null/")
+	conf.MustSet(ctx, config.ViperKeySelfServiceVerificationEnabled, true)
+
+	i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+	i.Traits = identity.Traits(`{"emails":["foo@ory.sh","bar@ory.sh"]}`)
+	i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+		Type: identity.CredentialsTypePassword, Identifiers: []string{"bar@ory.sh"}, Config: []byte(`{}`),
+	})
+	i.SetCredentials(identity.CredentialsTypeOIDC, identity.Credentials{
+		Type: identity.CredentialsTypeOIDC, Identifiers: []string{"google:1234"}, Config: []byte(`{}`),
+	})
+	require.NoError(t, reg.IdentityManager().Create(ctx, i))
+
+	_, err := reg.IdentityManager().SetAddressVerified(ctx, i.ID, "foo@ory.sh", true, time.Now().UTC())
+	require.NoError(t, err)
+
+	execute := func(t *testing.T, ct identity.CredentialsType) error {
+		i, err := reg.IdentityPool().GetIdentity(ctx, i.ID, identity.ExpandDefault)
+		require.NoError(t, err)
+
+		f := &login.Flow{Type: flow.TypeAPI, RequestURL: "https://www.ory.sh/self-service/login/api", Active: ct}
+		r := &http.Request{URL: urlx.ParseOrPanic("https://www.ory.sh/")}
+		return hook.NewVerifiedAddressEnforcer(reg).ExecuteLoginPostHook(
+			httptest.NewRecorder(), r.WithContext(ctx), node.DefaultGroup, f, &session.Session{Identity: i})
+	}
+
+	t.Run("case=refuses login with the unverified identifier", func(t *testing.T) {
+		err := execute(t, identity.CredentialsTypePassword)
+
+		var ve *schema.ValidationError
+		require.ErrorAs(t, err, &ve)
+		require.Len(t, ve.Messages, 1)
+		assert.EqualValues(t, text.ErrorValidationAddressNotVerified, ve.Messages[0].ID)
+
+		c := gjson.GetBytes(ve.Messages[0].Context, "continue_with.0")
+		assert.EqualValues(t, flow.ContinueWithActionShowVerificationUIString, c.Get("action").String(), "%s", c.Raw)
+		assert.Equal(t, "bar@ory.sh", c.Get("flow.verifiable_address").String(), "%s", c.Raw)
+
+		vf, err := reg.VerificationFlowPersister().GetVerificationFlow(ctx, uuid.FromStringOrNil(c.Get("flow.id").String()))
+		require.NoError(t, err)
+		assert.Equal(t, flow.StateEmailSent, vf.State)
+	})
+
+	t.Run("case=allows login if any address is verified and the identifier is not an address", func(t *testing.T) {
+		assert.NoError(t, execute(t, identity.CredentialsTypeOIDC))
+	})
+
+	t.Run("case=allows login with the verified identifier", func(t *testing.T) {
+		_, err := reg.IdentityManager().SetAddressVerified(ctx, i.ID, "bar@ory.sh", true, time.Now().UTC())
+		require.NoError(t, err)
+
+		assert.NoError(t, execute(t, identity.CredentialsTypePassword))
+	})
+}
@@ -0,0 +1,289 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package hook
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/courier"
+	"github.com/ory/kratos/courier/template/email"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/httpx"
+)
+
+const (
+	internalContextPendingIdentityPath = "verify_before_creation.identity"
+	internalContextPendingMethodPath   = "verify_before_creation.method"
+	internalContextVerifiedAddressPath = "verify_before_creation.verified_address"
+)
+
+var (
+	_ registration.PostHookPrePersistExecutor     = new(VerifyBeforeCreation)
+	_ registration.PendingRegistrationVerifier    = new(VerifyBeforeCreation)
+	_ registration.DuplicateRegistrationResponder = new(VerifyBeforeCreation)
+)
+
+type (
+	verifyBeforeCreationDependencies interface {
+		config.Provider
+		courier.Provider
+		code.SenderProvider
+		code.RegistrationCodePersistenceProvider
+		identity.PrivilegedPoolProvider
+		identity.ValidationProvider
+		registration.FlowPersistenceProvider
+		x.CSRFTokenGeneratorProvider
+		x.WriterProvider
+	}
+
+	// VerifyBeforeCreation holds back the identity of a registration until the
+	// user entered the code sent to their email address, so that identities
+	// with unverified addresses are never created.
+	VerifyBeforeCreation struct {
+		d verifyBeforeCreationDependencies
+	}
+
+	// pendingIdentity keeps the credentials when the identity is stored in
+	// and restored from the flow's internal context.
+	pendingIdentity identity.Identity
+
+	verifyBeforeCreationBody struct {
+		Code      string `json:"code"`
+		CSRFToken string `json:"csrf_token"`
+	}
+)
+
+func NewVerifyBeforeCreation(d verifyBeforeCreationDependencies) *VerifyBeforeCreation {
+	return &VerifyBeforeCreation{d: d}
+}
+
+// ExecutePostRegistrationPrePersistHook sends a code to the email addresses of the identity and
+// aborts the registration until the code was entered. It does nothing if one of the addresses
+// was verified already.
+func (e *VerifyBeforeCreation) ExecutePostRegistrationPrePersistHook(w http.ResponseWriter, r *http.Request, f *registration.Flow, i *identity.Identity) error {
+	ctx := r.Context()
+
+	// The traits must be valid for the verifiable addresses to be known.
+	if err := e.d.IdentityValidator().Validate(ctx, i); err != nil {
+		return err
+	}
+
+	var addresses []code.Address
+	verified := gjson.GetBytes(f.InternalContext, internalContextVerifiedAddressPath).String()
+	for k := range i.VerifiableAddresses {
+		va := &i.VerifiableAddresses[k]
+		if va.Via != identity.VerifiableAddressTypeEmail {
+			continue
+		}
+		if verified != "" && va.Value == verified {
+			va.Verified = true
+			va.Status = identity.VerifiableAddressStatusCompleted
+			return nil
+		}
+		addresses = append(addresses, code.Address{To: va.Value, Via: identity.CodeAddressTypeEmail})
+	}
+
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	if err := e.d.RegistrationCodePersister().DeleteRegistrationCodesOfFlow(ctx, f.ID); err != nil {
+		return err
+	}
+
+	if err := e.d.CodeSender().SendCode(ctx, f, i, addresses...); err != nil {
+		return err
+	}
+
+	return e.holdBack(w, r, f, i)
+}
+
+// RespondToDuplicateRegistration responds like ExecutePostRegistrationPrePersistHook if account
+// enumeration is mitigated and the identifiers of the registration belong to an existing identity.
+// No code is sent, so the registration can not be completed, but the existing identity is notified.
+func (e *VerifyBeforeCreation) RespondToDuplicateRegistration(w http.ResponseWriter, r *http.Request, f *registration.Flow, i, existing *identity.Identity) error {
+	ctx := r.Context()
+
+	if err := e.d.RegistrationCodePersister().DeleteRegistrationCodesOfFlow(ctx, f.ID); err != nil {
+		return err
+	}
+
+	if existing != nil {
+		if err := e.notifyExisting(r, existing); err != nil {
+			return err
+		}
+	}
+
+	return e.holdBack(w, r, f, i)
+}
+
+// notifyExisting tells the existing identity that someone tried to register with its address.
+// Identities without an email address are not notified.
+func (e *VerifyBeforeCreation) notifyExisting(r *http.Request, existing *identity.Identity) error {
+	ctx := r.Context()
+
+	// The conflicting identity might have been found without its addresses.
+	existing, err := e.d.PrivilegedIdentityPool().GetIdentity(ctx, existing.ID, identity.ExpandDefault)
+	if err != nil {
+		return err
+	}
+
+	var to string
+	for _, va := range existing.VerifiableAddresses {
+		if va.Via == identity.VerifiableAddressTypeEmail {
+			to = va.Value
+			break
+		}
+	}
+	if to == "" {
+		return nil
+	}
+
+	model, err := x.StructToMap(existing)
+	if err != nil {
+		return err
+	}
+
+	c, err := e.d.Courier(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.QueueEmail(ctx, email.NewRegistrationDuplicate(e.d, &email.RegistrationDuplicateModel{
+		To:        to,
+		LoginURL:  e.d.Config().SelfServiceFlowLoginUI(ctx).String(),
+		IPAddress: httpx.ClientIP(r),
+		UserAgent: r.UserAgent(),
+		Identity:  model,
+		Locale:    e.d.IdentityValidator().Locale(ctx, existing),
+	}))
+	return err
+}
+
+// holdBack stores the identity in the flow and asks for the code which was sent to its addresses.
+func (e *VerifyBeforeCreation) holdBack(w http.ResponseWriter, r *http.Request, f *registration.Flow, i *identity.Identity) error {
+	ctx := r.Context()
+
+	raw, err := json.Marshal((*pendingIdentity)(i))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	f.EnsureInternalContext()
+	f.InternalContext, err = sjson.SetRawBytes(f.InternalContext, internalContextPendingIdentityPath, raw)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	f.InternalContext, err = sjson.SetBytes(f.InternalContext, internalContextPendingMethodPath, f.Active)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	f.SetState(flow.StateEmailSent)
+	f.UI.ResetMessages()
+	f.UI.Nodes = node.Nodes{}
+	f.UI.Nodes.Append(node.NewInputField("code", nil, node.CodeGroup, node.InputAttributeTypeText, node.WithRequiredInputAttribute).
+		WithMetaLabel(text.NewInfoNodeLabelRegistrationCode()))
+	f.UI.Nodes.Append(node.NewInputField("method", identity.CredentialsTypeCodeAuth, node.CodeGroup, node.InputAttributeTypeSubmit).
+		WithMetaLabel(text.NewInfoNodeLabelSubmit()))
+	if f.Type == flow.TypeBrowser {
+		f.UI.SetCSRF(e.d.GenerateCSRFToken(r))
+	}
+	f.UI.Messages.Set(text.NewRegistrationEmailWithCodeSent())
+
+	if err := e.d.RegistrationFlowPersister().UpdateRegistrationFlow(ctx, f); err != nil {
+		return err
+	}
+
+	if x.IsJSONRequest(r) {
+		e.d.Writer().WriteCode(w, r, http.StatusBadRequest, f)
+	} else {
+		http.Redirect(w, r, f.AppendTo(e.d.Config().SelfServiceFlowRegistrationUI(ctx)).String(), http.StatusSeeOther)
+	}
+
+	return errors.WithStack(registration.ErrHookAbortFlow)
+}
+
+// VerifyPendingRegistration checks the submitted code and returns the identity which was held
+// back by ExecutePostRegistrationPrePersistHook.
+func (e *VerifyBeforeCreation) VerifyPendingRegistration(_ http.ResponseWriter, r *http.Request, f *registration.Flow) (*identity.Identity, identity.CredentialsType, error) {
+	ctx := r.Context()
+
+	pending := gjson.GetBytes(f.InternalContext, internalContextPendingIdentityPath)
+	if f.GetState() != flow.StateEmailSent || !pending.IsObject() {
+		return nil, "", errors.WithStack(flow.ErrStrategyNotResponsible)
+	}
+
+	var i identity.Identity
+	if err := json.Unmarshal([]byte(pending.Raw), (*pendingIdentity)(&i)); err != nil {
+		return nil, "", errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to restore the pending identity.").WithWrap(err))
+	}
+	ct := identity.CredentialsType(gjson.GetBytes(f.InternalContext, internalContextPendingMethodPath).String())
+
+	var p verifyBeforeCreationBody
+	if x.IsJSONRequest(r) {
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			return nil, "", errors.WithStack(herodot.ErrBadRequest.WithReason("Unable to decode the request body.").WithWrap(err))
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			return nil, "", errors.WithStack(herodot.ErrBadRequest.WithReason("Unable to parse the request body.").WithWrap(err))
+		}
+		p.Code, p.CSRFToken = r.PostForm.Get("code"), r.PostForm.Get("csrf_token")
+	}
+
+	if err := flow.EnsureCSRF(e.d, r, f.Type, e.d.Config().DisableAPIFlowEnforcement(ctx), e.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		return nil, "", err
+	}
+
+	if len(p.Code) == 0 {
+		return nil, "", errors.WithStack(schema.NewRequiredError("#/code", "code"))
+	}
+
+	var addresses []string
+	for _, va := range i.VerifiableAddresses {
+		if va.Via == identity.VerifiableAddressTypeEmail {
+			addresses = append(addresses, va.Value)
+		}
+	}
+
+	registrationCode, err := e.d.CodeSender().UseRegistrationCode(ctx, f.ID, p.Code, addresses...)
+	if errors.Is(err, code.ErrCodeNotFound) {
+		return nil, "", errors.WithStack(schema.NewRegistrationCodeInvalid())
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	// The pending identity is dropped so that a failing registration can be retried with the
+	// registration form, which does not require the code again for the verified address.
+	f.InternalContext, err = sjson.DeleteBytes(f.InternalContext, "verify_before_creation")
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	f.InternalContext, err = sjson.SetBytes(f.InternalContext, internalContextVerifiedAddressPath, registrationCode.Address)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	f.SetState(flow.StatePassedChallenge)
+
+	if err := e.d.RegistrationFlowPersister().UpdateRegistrationFlow(ctx, f); err != nil {
+		return nil, "", err
+	}
+
+	return &i, ct, nil
+}
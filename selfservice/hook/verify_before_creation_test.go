@@ -0,0 +1,142 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package hook_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/hook"
+	"github.com/ory/kratos/selfservice/strategy/code"
+)
+
+func TestVerifyBeforeCreation(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/verify.schema.json")
+	h := hook.NewVerifyBeforeCreation(reg)
+
+	newRequest := func(body string) *http.Request {
+		r := httptest.NewRequest("POST", "/self-service/registration", strings.NewReader(body))
+		r.Header.Set("Accept", "application/json")
+		r.Header.Set("Content-Type", "application/json")
+		return r
+	}
+
+	setup := func(t *testing.T) (*registration.Flow, *identity.Identity, string) {
+		address := testhelpers.RandomEmail()
+		f := &registration.Flow{Type: flow.TypeAPI, Active: identity.CredentialsTypePassword, State: flow.StateChooseMethod, ExpiresAt: time.Now().Add(time.Hour)}
+		require.NoError(t, reg.RegistrationFlowPersister().CreateRegistrationFlow(ctx, f))
+
+		i := identity.NewIdentity("")
+		i.Traits = identity.Traits(fmt.Sprintf(`{"emails":[%q]}`, address))
+		i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+			Type:        identity.CredentialsTypePassword,
+			Identifiers: []string{address},
+			Config:      []byte(`{"hashed_password":"foo"}`),
+		})
+		return f, i, address
+	}
+
+	holdBack := func(t *testing.T, f *registration.Flow, i *identity.Identity) {
+		w := httptest.NewRecorder()
+		err := h.ExecutePostRegistrationPrePersistHook(w, newRequest(""), f, i)
+		require.ErrorIs(t, err, registration.ErrHookAbortFlow)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, flow.StateEmailSent, f.State)
+	}
+
+	useKnownCode := func(t *testing.T, f *registration.Flow, address string) {
+		require.NoError(t, reg.RegistrationCodePersister().DeleteRegistrationCodesOfFlow(ctx, f.ID))
+		_, err := reg.RegistrationCodePersister().CreateRegistrationCode(ctx, &code.CreateRegistrationCodeParams{
+			Address:     address,
+			AddressType: identity.CodeAddressTypeEmail,
+			RawCode:     "123456",
+			ExpiresIn:   time.Hour,
+			FlowID:      f.ID,
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("case=holds back the identity until the code was entered", func(t *testing.T) {
+		f, i, address := setup(t)
+		holdBack(t, f, i)
+
+		_, _, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(ctx, identity.CredentialsTypePassword, address)
+		require.Error(t, err, "the identity must not be created before the code was entered")
+
+		useKnownCode(t, f, address)
+		restored, ct, err := h.VerifyPendingRegistration(nil, newRequest(`{"code":"123456"}`), f)
+		require.NoError(t, err)
+		assert.Equal(t, identity.CredentialsTypePassword, ct)
+		assert.Equal(t, flow.StatePassedChallenge, f.State)
+		assert.JSONEq(t, string(i.Traits), string(restored.Traits))
+		assert.JSONEq(t, `{"hashed_password":"foo"}`, string(restored.Credentials[identity.CredentialsTypePassword].Config))
+
+		// The hook now lets the registration pass and marks the address as verified.
+		require.NoError(t, h.ExecutePostRegistrationPrePersistHook(nil, newRequest(""), f, restored))
+		require.Len(t, restored.VerifiableAddresses, 1)
+		assert.True(t, restored.VerifiableAddresses[0].Verified)
+		assert.Equal(t, identity.VerifiableAddressStatusCompleted, restored.VerifiableAddresses[0].Status)
+	})
+
+	t.Run("case=rejects a wrong code", func(t *testing.T) {
+		f, i, address := setup(t)
+		holdBack(t, f, i)
+		useKnownCode(t, f, address)
+
+		_, _, err := h.VerifyPendingRegistration(nil, newRequest(`{"code":"654321"}`), f)
+		var validationErr *schema.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, flow.StateEmailSent, f.State)
+	})
+
+	t.Run("case=is not responsible for flows without pending identity", func(t *testing.T) {
+		f, _, _ := setup(t)
+		_, _, err := h.VerifyPendingRegistration(nil, newRequest(`{"code":"123456"}`), f)
+		require.ErrorIs(t, err, flow.ErrStrategyNotResponsible)
+	})
+
+	t.Run("case=responds to duplicate registrations as if a code was sent", func(t *testing.T) {
+		f, i, address := setup(t)
+		existing := identity.NewIdentity("")
+		existing.Traits = i.Traits
+		require.NoError(t, reg.IdentityManager().Create(ctx, existing))
+
+		w := httptest.NewRecorder()
+		err := h.RespondToDuplicateRegistration(w, newRequest(""), f, i, existing)
+		require.ErrorIs(t, err, registration.ErrHookAbortFlow)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, flow.StateEmailSent, f.State)
+
+		testhelpers.CourierExpectMessage(ctx, t, reg, address, "Someone tried to sign up with your email address")
+
+		// No code was sent, so the registration can not be completed.
+		_, _, err = h.VerifyPendingRegistration(nil, newRequest(`{"code":"123456"}`), f)
+		var validationErr *schema.ValidationError
+		require.ErrorAs(t, err, &validationErr)
+	})
+
+	t.Run("case=does nothing for identities without email addresses", func(t *testing.T) {
+		f, i, _ := setup(t)
+		i.Traits = identity.Traits(`{"emails":[]}`)
+		require.NoError(t, h.ExecutePostRegistrationPrePersistHook(nil, newRequest(""), f, i))
+		assert.Equal(t, flow.StateChooseMethod, f.State)
+		assert.NotContains(t, string(f.InternalContext), "verify_before_creation")
+	})
+}
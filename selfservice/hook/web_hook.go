@@ -54,10 +54,13 @@ var _ interface {
 
 	recovery.PreHookExecutor
 	recovery.PostHookExecutor
+	recovery.UnknownAddressHookExecutor
 
 	settings.PreHookExecutor
 	settings.PostHookPrePersistExecutor
 	settings.PostHookPostPersistExecutor
+
+	flow.BeforeRenderHookExecutor
 } = (*WebHook)(nil)
 
 type (
@@ -75,6 +78,11 @@ type (
 		RequestURL     string             `json:"request_url"`
 		RequestCookies map[string]string  `json:"request_cookies"`
 		Identity       *identity.Identity `json:"identity,omitempty"`
+		Address        string             `json:"address,omitempty"`
+
+		// AuthenticationHistory is set for hooks which run after a login or registration, so that
+		// they can inspect how the session was authenticated.
+		AuthenticationHistory session.AuthenticationHistory `json:"authentication_history,omitempty"`
 	}
 
 	WebHook struct {
@@ -127,14 +135,19 @@ func (e *WebHook) ExecuteLoginPreHook(_ http.ResponseWriter, req *http.Request,
 
 func (e *WebHook) ExecuteLoginPostHook(_ http.ResponseWriter, req *http.Request, _ node.UiNodeGroup, flow *login.Flow, session *session.Session) error {
 	return otelx.WithSpan(req.Context(), "selfservice.hook.WebHook.ExecuteLoginPostHook", func(ctx context.Context) error {
-		return e.execute(ctx, &templateContext{
-			Flow:           flow,
-			RequestHeaders: req.Header,
-			RequestMethod:  req.Method,
-			RequestURL:     x.RequestURL(req).String(),
-			RequestCookies: cookies(req),
-			Identity:       session.Identity,
-		})
+		data := &templateContext{
+			Flow:                  flow,
+			RequestHeaders:        req.Header,
+			RequestMethod:         req.Method,
+			RequestURL:            x.RequestURL(req).String(),
+			RequestCookies:        cookies(req),
+			Identity:              session.Identity,
+			AuthenticationHistory: session.AuthenticationHistory,
+		}
+		if !gjson.GetBytes(e.conf, "response.parse").Bool() {
+			return e.execute(ctx, data)
+		}
+		return e.dispatch(ctx, data, parseFlowResponse)
 	})
 }
 
@@ -188,6 +201,19 @@ func (e *WebHook) ExecutePostRecoveryHook(_ http.ResponseWriter, req *http.Reque
 	})
 }
 
+func (e *WebHook) ExecuteRecoveryUnknownAddressHook(_ http.ResponseWriter, req *http.Request, flow *recovery.Flow, address string) error {
+	return otelx.WithSpan(req.Context(), "selfservice.hook.WebHook.ExecuteRecoveryUnknownAddressHook", func(ctx context.Context) error {
+		return e.execute(ctx, &templateContext{
+			Flow:           flow,
+			RequestHeaders: req.Header,
+			RequestMethod:  req.Method,
+			RequestURL:     x.RequestURL(req).String(),
+			RequestCookies: cookies(req),
+			Address:        address,
+		})
+	})
+}
+
 func (e *WebHook) ExecuteRegistrationPreHook(_ http.ResponseWriter, req *http.Request, flow *registration.Flow) error {
 	return otelx.WithSpan(req.Context(), "selfservice.hook.WebHook.ExecuteRegistrationPreHook", func(ctx context.Context) error {
 		return e.execute(ctx, &templateContext{
@@ -228,12 +254,13 @@ func (e *WebHook) ExecutePostRegistrationPostPersistHook(_ http.ResponseWriter,
 
 	return otelx.WithSpan(ctx, "selfservice.hook.WebHook.ExecutePostRegistrationPostPersistHook", func(ctx context.Context) error {
 		return e.execute(ctx, &templateContext{
-			Flow:           flow,
-			RequestHeaders: req.Header,
-			RequestMethod:  req.Method,
-			RequestURL:     x.RequestURL(req).String(),
-			RequestCookies: cookies(req),
-			Identity:       session.Identity,
+			Flow:                  flow,
+			RequestHeaders:        req.Header,
+			RequestMethod:         req.Method,
+			RequestURL:            x.RequestURL(req).String(),
+			RequestCookies:        cookies(req),
+			Identity:              session.Identity,
+			AuthenticationHistory: session.AuthenticationHistory,
 		})
 	})
 }
@@ -282,14 +309,36 @@ func (e *WebHook) ExecuteSettingsPrePersistHook(_ http.ResponseWriter, req *http
 	})
 }
 
+func (e *WebHook) ExecuteBeforeRenderHook(_ http.ResponseWriter, req *http.Request, f flow.Flow) error {
+	return otelx.WithSpan(req.Context(), "selfservice.hook.WebHook.ExecuteBeforeRenderHook", func(ctx context.Context) error {
+		return e.dispatch(ctx, &templateContext{
+			Flow:           f,
+			RequestHeaders: req.Header,
+			RequestMethod:  req.Method,
+			RequestURL:     x.RequestURL(req).String(),
+			RequestCookies: cookies(req),
+		}, func(resp *http.Response) error {
+			return applyBeforeRenderResponse(ctx, resp, f)
+		})
+	})
+}
+
 func (e *WebHook) execute(ctx context.Context, data *templateContext) error {
+	return e.dispatch(ctx, data, nil)
+}
+
+// dispatch calls the web hook. If onResponse is set, the response is always parsed and successful
+// responses are passed to onResponse instead of being parsed into the identity. A flow.HookResponse
+// returned by onResponse is passed on to the flow.
+func (e *WebHook) dispatch(ctx context.Context, data *templateContext, onResponse func(*http.Response) error) error {
 	var (
 		httpClient     = e.deps.HTTPClient(ctx)
 		ignoreResponse = gjson.GetBytes(e.conf, "response.ignore").Bool()
 		canInterrupt   = gjson.GetBytes(e.conf, "can_interrupt").Bool()
-		parseResponse  = gjson.GetBytes(e.conf, "response.parse").Bool()
+		parseResponse  = gjson.GetBytes(e.conf, "response.parse").Bool() || onResponse != nil
 		emitEvent      = gjson.GetBytes(e.conf, "emit_analytics_event").Bool() || !gjson.GetBytes(e.conf, "emit_analytics_event").Exists() // default true
 		tracer         = trace.SpanFromContext(ctx).TracerProvider().Tracer("kratos-webhooks")
+		hookResponse   *flow.HookResponse
 	)
 	if ignoreResponse && (parseResponse || canInterrupt) {
 		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("A webhook is configured to ignore the response but also to parse the response. This is not possible."))
@@ -398,6 +447,13 @@ func (e *WebHook) execute(ctx context.Context, data *templateContext) error {
 			}
 		}
 
+		if onResponse != nil {
+			// A hook response is not a failed request.
+			if err := onResponse(resp); !errors.As(err, &hookResponse) {
+				return err
+			}
+			return nil
+		}
 		if parseResponse {
 			return parseWebhookResponse(resp, data.Identity)
 		}
@@ -405,7 +461,12 @@ func (e *WebHook) execute(ctx context.Context, data *templateContext) error {
 	}
 
 	if !ignoreResponse {
-		return makeRequest()
+		if err := makeRequest(); err != nil {
+			return err
+		} else if hookResponse != nil {
+			return hookResponse
+		}
+		return nil
 	}
 	go func() {
 		// we cannot handle the error as we are running async, and it is logged anyway
@@ -503,6 +564,39 @@ func parseWebhookResponse(resp *http.Response, id *identity.Identity) (err error
 	return nil
 }
 
+// parseFlowResponse returns a flow.HookResponse if the web hook asks the flow to respond right away, for example
+//
+//	{"respond": {"redirect_browser_to": "https://www.ory.sh/welcome", "body": {"custom": "data"}}}
+//
+// Browsers are redirected to `redirect_browser_to` while API and SPA clients receive `body` as JSON.
+func parseFlowResponse(resp *http.Response) error {
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	var hookResponse struct {
+		Respond *struct {
+			RedirectBrowserTo string          `json:"redirect_browser_to"`
+			Body              json.RawMessage `json:"body"`
+		} `json:"respond"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&hookResponse); err != nil {
+		return errors.Wrap(err, "webhook response could not be unmarshalled properly from JSON")
+	}
+
+	if hookResponse.Respond == nil {
+		return nil
+	}
+	if len(hookResponse.Respond.Body) > 0 && !gjson.ParseBytes(hookResponse.Respond.Body).IsObject() {
+		return errors.New("webhook response body must be a JSON object")
+	}
+
+	return &flow.HookResponse{
+		RedirectTo: hookResponse.Respond.RedirectBrowserTo,
+		Body:       hookResponse.Respond.Body,
+	}
+}
+
 func isTimeoutError(err error) bool {
 	var te interface{ Timeout() bool }
 	return errors.As(err, &te) && te.Timeout() || errors.Is(err, context.DeadlineExceeded)
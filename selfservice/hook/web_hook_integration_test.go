@@ -9,6 +9,7 @@ import (
 	_ "embed"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -27,6 +28,7 @@ import (
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"golang.org/x/exp/slices"
 
+	"github.com/ory/herodot"
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
@@ -40,6 +42,7 @@ import (
 	"github.com/ory/kratos/selfservice/hook"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/container"
 	"github.com/ory/kratos/ui/node"
 	"github.com/ory/kratos/x"
 	"github.com/ory/x/jsonnetsecure"
@@ -800,6 +803,112 @@ func TestWebHooks(t *testing.T) {
 		})
 	}
 
+	t.Run("before render", func(t *testing.T) {
+		t.Parallel()
+		run := func(t *testing.T, code int, response string) (*login.Flow, error) {
+			req := &http.Request{
+				Host:       "www.ory.sh",
+				Header:     map[string][]string{},
+				RequestURI: "/some_end_point",
+				Method:     http.MethodGet,
+				URL:        &url.URL{Path: "some_end_point"},
+			}
+			f := &login.Flow{ID: x.NewUUID(), UI: container.New("/action")}
+			f.UI.SetCSRF("csrf")
+			f.UI.Nodes.Append(node.NewInputField("identifier", "", node.DefaultGroup, node.InputAttributeTypeText))
+
+			ts := newServer(webHookHttpCodeWithBodyEndPoint(t, code, []byte(response)))
+			conf := json.RawMessage(fmt.Sprintf(`{"url": "%s", "method": "POST", "body": "%s"}`, ts.URL+path, "file://./stub/test_body.jsonnet"))
+			return f, hook.NewWebHook(&whDeps, conf).ExecuteBeforeRenderHook(nil, req, f)
+		}
+
+		t.Run("case=applies the changes", func(t *testing.T) {
+			f, err := run(t, http.StatusOK, `{"ui":{
+				"nodes":{
+					"modify":[{"id":"identifier","disabled":true}],
+					"add":[{"type":"text","id":"notice","text":{"id":1070003,"text":"Maintenance tonight","type":"info"}}]
+				},
+				"messages":[{"id":1070003,"text":"Hello","type":"info"}]
+			}}`)
+			require.NoError(t, err)
+			assert.True(t, f.UI.Nodes.Find("identifier").Attributes.(*node.InputAttributes).Disabled)
+			require.NotNil(t, f.UI.Nodes.Find("notice"))
+			require.Len(t, f.UI.Messages, 1)
+		})
+
+		t.Run("case=no content keeps the flow", func(t *testing.T) {
+			f, err := run(t, http.StatusNoContent, "")
+			require.NoError(t, err)
+			assert.Len(t, f.UI.Nodes, 2)
+		})
+
+		t.Run("case=rejects responses not matching the schema", func(t *testing.T) {
+			for _, response := range []string{
+				`{"identity":{"traits":{}}}`,
+				`{"ui":{"nodes":{"add":[{"type":"input","id":"token"}]}}}`,
+				`{"ui":{"nodes":{"add":[{"type":"img","id":"logo","src":"http://www.ory.sh/logo.png"}]}}}`,
+			} {
+				_, err := run(t, http.StatusOK, response)
+				var he herodot.DefaultError
+				require.ErrorAs(t, err, &he, response)
+				assert.Equal(t, http.StatusBadGateway, he.StatusCode())
+			}
+		})
+
+		t.Run("case=rejects duplicate nodes", func(t *testing.T) {
+			_, err := run(t, http.StatusOK, `{"ui":{"nodes":{"add":[{"type":"text","id":"identifier","text":{"id":1070003,"text":"Save","type":"info"}}]}}}`)
+			require.Error(t, err)
+		})
+
+		t.Run("case=fails on error responses", func(t *testing.T) {
+			_, err := run(t, http.StatusInternalServerError, "")
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("responds to the login flow", func(t *testing.T) {
+		t.Parallel()
+		run := func(t *testing.T, parse bool, code int, response string) error {
+			req := &http.Request{
+				Host:       "www.ory.sh",
+				Header:     map[string][]string{},
+				RequestURI: "/some_end_point",
+				Method:     http.MethodPost,
+				URL:        &url.URL{Path: "some_end_point"},
+			}
+			f := &login.Flow{ID: x.NewUUID()}
+			s := &session.Session{ID: x.NewUUID(), Identity: &identity.Identity{ID: x.NewUUID()}}
+
+			ts := newServer(webHookHttpCodeWithBodyEndPoint(t, code, []byte(response)))
+			conf := json.RawMessage(fmt.Sprintf(`{"url": "%s", "method": "POST", "body": "%s", "response": {"parse": %t}}`, ts.URL+path, "file://./stub/test_body.jsonnet", parse))
+			return hook.NewWebHook(&whDeps, conf).ExecuteLoginPostHook(nil, req, node.DefaultGroup, f, s)
+		}
+
+		t.Run("case=returns a hook response", func(t *testing.T) {
+			err := run(t, true, http.StatusOK, `{"respond":{"redirect_browser_to":"https://www.ory.sh/welcome","body":{"custom":"data"}}}`)
+			var hr *flow.HookResponse
+			require.ErrorAs(t, err, &hr)
+			assert.Equal(t, "https://www.ory.sh/welcome", hr.RedirectTo)
+			assert.JSONEq(t, `{"custom":"data"}`, string(hr.Body))
+		})
+
+		t.Run("case=continues without respond", func(t *testing.T) {
+			require.NoError(t, run(t, true, http.StatusOK, `{"identity":{"traits":{}}}`))
+			require.NoError(t, run(t, true, http.StatusNoContent, ""))
+		})
+
+		t.Run("case=ignores respond if the response is not parsed", func(t *testing.T) {
+			require.NoError(t, run(t, false, http.StatusOK, `{"respond":{"redirect_browser_to":"https://www.ory.sh/welcome"}}`))
+		})
+
+		t.Run("case=rejects bodies which are not objects", func(t *testing.T) {
+			err := run(t, true, http.StatusOK, `{"respond":{"body":[1,2,3]}}`)
+			require.Error(t, err)
+			var hr *flow.HookResponse
+			assert.False(t, errors.As(err, &hr))
+		})
+	})
+
 	t.Run("must error when template is erroneous", func(t *testing.T) {
 		t.Parallel()
 		ts := newServer(webHookHttpCodeEndPoint(200))
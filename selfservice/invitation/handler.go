@@ -0,0 +1,240 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package invitation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/urlx"
+)
+
+const (
+	RouteCollection = "/invitations"
+	RouteItem       = RouteCollection + "/:id"
+
+	// QueryParameterToken is the query parameter of the registration flow initialization which holds
+	// the invitation token.
+	QueryParameterToken = "invitation_token"
+
+	// routeInitBrowserRegistrationFlow mirrors registration.RouteInitBrowserFlow, which can not be
+	// imported because the registration flow depends on this package.
+	routeInitBrowserRegistrationFlow = "/self-service/registration/browser"
+)
+
+type (
+	handlerDependencies interface {
+		config.Provider
+		ManagementProvider
+		PersistenceProvider
+		x.WriterProvider
+	}
+
+	HandlerProvider interface {
+		InvitationHandler() *Handler
+	}
+
+	Handler struct {
+		d handlerDependencies
+	}
+)
+
+func NewHandler(d handlerDependencies) *Handler {
+	return &Handler{d: d}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.POST(RouteCollection, h.createInvitation)
+	admin.GET(RouteItem, h.getInvitation)
+	admin.DELETE(RouteItem, h.deleteInvitation)
+}
+
+// Create Invitation Body
+//
+// swagger:model createInvitationBody
+type CreateInvitationBody struct {
+	// Email is the email address the invitation is bound to.
+	//
+	// required: true
+	Email string `json:"email"`
+
+	// Traits are used to pre-fill the registration form.
+	Traits json.RawMessage `json:"traits,omitempty"`
+
+	// ExpiresAt is the time at which the invitation expires. Defaults to the
+	// configured invitation lifespan.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Create Invitation Parameters
+//
+// swagger:parameters createInvitation
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type createInvitation struct {
+	// in: body
+	Body CreateInvitationBody
+}
+
+// Created Invitation
+//
+// swagger:model createdInvitation
+type createdInvitation struct {
+	*Invitation
+
+	// Token is the invitation token. It is only returned once and must be passed to the registration
+	// flow in the `invitation_token` query parameter.
+	//
+	// required: true
+	Token string `json:"token"`
+
+	// RegistrationURL initializes a browser registration flow with the invitation.
+	//
+	// required: true
+	RegistrationURL string `json:"registration_url"`
+}
+
+// swagger:route POST /admin/invitations identity createInvitation
+//
+// # Create an Invitation
+//
+// Creates an invitation for an email address. The invitation token is passed to the registration flow
+// using the `invitation_token` query parameter and pre-fills the registration form with the traits of
+// the invitation. If `selfservice.flows.registration.invitations.required` is enabled, identities can
+// only register with an invitation. Each invitation can be used once.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  201: createdInvitation
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) createInvitation(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body CreateInvitationBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		h.d.Writer().WriteErrorCode(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+
+	var expiresAt time.Time
+	if body.ExpiresAt != nil {
+		expiresAt = *body.ExpiresAt
+	}
+
+	i, err := h.d.InvitationManager().Create(r.Context(), body.Email, body.Traits, expiresAt)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().WriteCreated(w, r,
+		urlx.AppendPaths(h.d.Config().SelfAdminURL(r.Context()), RouteCollection, i.ID.String()).String(),
+		&createdInvitation{
+			Invitation: i,
+			Token:      i.Token,
+			RegistrationURL: urlx.CopyWithQuery(
+				urlx.AppendPaths(h.d.Config().SelfPublicURL(r.Context()), routeInitBrowserRegistrationFlow),
+				url.Values{QueryParameterToken: {i.Token}},
+			).String(),
+		})
+}
+
+// Get Invitation Parameters
+//
+// swagger:parameters getInvitation
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type getInvitation struct {
+	// ID is the invitation's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route GET /admin/invitations/{id} identity getInvitation
+//
+// # Get an Invitation
+//
+// Returns the invitation including whether and by which identity it was used.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: invitation
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) getInvitation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	i, err := h.d.InvitationPersister().GetInvitation(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, i)
+}
+
+// Delete Invitation Parameters
+//
+// swagger:parameters deleteInvitation
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type deleteInvitation struct {
+	// ID is the invitation's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /admin/invitations/{id} identity deleteInvitation
+//
+// # Revoke an Invitation
+//
+// Deletes the invitation. Registration flows which were started with the invitation can no longer
+// be completed.
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) deleteInvitation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.d.InvitationPersister().DeleteInvitation(r.Context(), x.ParseUUID(ps.ByName("id"))); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
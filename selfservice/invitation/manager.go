@@ -0,0 +1,225 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package invitation
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/randx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+)
+
+const internalContextInvitationPath = "invitation_id"
+
+var (
+	ErrInvitationRequired = herodot.ErrForbidden.WithID(text.ErrIDSelfServiceInvitationRequired).WithError("invitation required").WithReason("Registration requires a valid invitation.")
+	ErrInvitationInvalid  = herodot.ErrBadRequest.WithID(text.ErrIDSelfServiceInvitationRequired).WithError("invitation invalid").WithReason("The invitation is invalid, was used already, or has expired.")
+)
+
+type (
+	managerDependencies interface {
+		config.Provider
+		x.LoggingProvider
+		x.TracingProvider
+		PersistenceProvider
+	}
+
+	// Manager creates invitations and enforces them in registration flows.
+	Manager struct {
+		d managerDependencies
+	}
+
+	ManagementProvider interface {
+		InvitationManager() *Manager
+	}
+)
+
+func NewManager(d managerDependencies) *Manager {
+	return &Manager{d: d}
+}
+
+// Create creates an invitation for the email address. The returned invitation holds the token in plain text,
+// it can not be retrieved later on. The lifespan from the config is used if expiresAt is zero.
+func (m *Manager) Create(ctx context.Context, email string, traits json.RawMessage, expiresAt time.Time) (_ *Invitation, err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "invitation.Manager.Create")
+	defer otelx.End(span, &err)
+
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The email address of the invitation must be set."))
+	}
+	if len(traits) > 0 && !gjson.ParseBytes(traits).IsObject() {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The traits of the invitation must be a JSON object."))
+	}
+
+	now := x.Now().UTC()
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(m.d.Config().SelfServiceFlowRegistrationInvitationsLifespan(ctx))
+	} else if !expiresAt.After(now) {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The invitation must expire in the future."))
+	}
+
+	i := &Invitation{
+		ID:        x.NewUUID(),
+		Email:     email,
+		Traits:    sqlxx.NullJSONRawMessage(traits),
+		Token:     randx.MustString(32, randx.AlphaNum),
+		ExpiresAt: expiresAt.UTC(),
+	}
+	if err := m.d.InvitationPersister().CreateInvitation(ctx, i); err != nil {
+		return nil, err
+	}
+
+	m.d.Logger().
+		WithField("invitation_id", i.ID).
+		WithSensitiveField("email", i.Email).
+		Info("An invitation was created.")
+
+	return i, nil
+}
+
+// AttachToFlow stores the invitation with the given token on the registration flow. Without a token the
+// invitation of the flow is kept, for example if the flow replaces an expired flow. It returns
+// ErrInvitationRequired if the flow has no invitation but registration requires one.
+func (m *Manager) AttachToFlow(ctx context.Context, f flow.InternalContexter, token string) (_ *Invitation, err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "invitation.Manager.AttachToFlow")
+	defer otelx.End(span, &err)
+
+	if token == "" {
+		return m.FromFlow(ctx, f)
+	}
+
+	i, err := m.d.InvitationPersister().GetInvitationByToken(ctx, token)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, errors.WithStack(ErrInvitationInvalid)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !i.IsValid() {
+		return nil, errors.WithStack(ErrInvitationInvalid)
+	}
+
+	f.EnsureInternalContext()
+	raw, err := sjson.SetBytes(f.GetInternalContext(), internalContextInvitationPath, i.ID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	f.SetInternalContext(raw)
+
+	return i, nil
+}
+
+// FromFlow returns the invitation attached to the registration flow, or nil if the flow has no invitation
+// and registration does not require one.
+func (m *Manager) FromFlow(ctx context.Context, f flow.InternalContexter) (_ *Invitation, err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "invitation.Manager.FromFlow")
+	defer otelx.End(span, &err)
+
+	id := uuid.FromStringOrNil(gjson.GetBytes(f.GetInternalContext(), internalContextInvitationPath).String())
+	if id.IsNil() {
+		if m.d.Config().SelfServiceFlowRegistrationInvitationsRequired(ctx) {
+			return nil, errors.WithStack(ErrInvitationRequired)
+		}
+		return nil, nil
+	}
+
+	i, err := m.d.InvitationPersister().GetInvitation(ctx, id)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, errors.WithStack(ErrInvitationInvalid)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !i.IsValid() {
+		return nil, errors.WithStack(ErrInvitationInvalid)
+	}
+
+	return i, nil
+}
+
+// CheckIdentity returns an error unless the identity uses the email address of the invitation. Identities
+// registering without an invitation (nil) are not checked.
+func (m *Manager) CheckIdentity(inv *Invitation, i *identity.Identity) error {
+	if inv == nil {
+		return nil
+	}
+
+	for _, a := range i.VerifiableAddresses {
+		if strings.EqualFold(a.Value, inv.Email) {
+			return nil
+		}
+	}
+	for _, a := range i.RecoveryAddresses {
+		if strings.EqualFold(a.Value, inv.Email) {
+			return nil
+		}
+	}
+	for _, c := range i.Credentials {
+		for _, identifier := range c.Identifiers {
+			if strings.EqualFold(identifier, inv.Email) {
+				return nil
+			}
+		}
+	}
+
+	return errors.WithStack(herodot.ErrBadRequest.WithReason("The invitation can only be used to register with the email address it was sent to."))
+}
+
+// Consume marks the invitation as used. An invitation can only be consumed once. Identities registering
+// without an invitation (nil) are not checked.
+func (m *Manager) Consume(ctx context.Context, inv *Invitation) (err error) {
+	if inv == nil {
+		return nil
+	}
+
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "invitation.Manager.Consume")
+	defer otelx.End(span, &err)
+
+	if err := m.d.InvitationPersister().UseInvitation(ctx, inv.ID, x.Now().UTC()); errors.Is(err, sqlcon.ErrNoRows) {
+		return errors.WithStack(ErrInvitationInvalid)
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AssignIdentity records the identity which registered using the consumed invitation. Identities
+// registering without an invitation (nil) are not recorded.
+func (m *Manager) AssignIdentity(ctx context.Context, inv *Invitation, identityID uuid.UUID) (err error) {
+	if inv == nil {
+		return nil
+	}
+
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "invitation.Manager.AssignIdentity")
+	defer otelx.End(span, &err)
+
+	if err := m.d.InvitationPersister().SetInvitationIdentity(ctx, inv.ID, identityID); err != nil {
+		return err
+	}
+
+	m.d.Logger().
+		WithField("invitation_id", inv.ID).
+		WithField("identity_id", identityID).
+		Info("An identity registered using an invitation.")
+
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package invitation_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/invitation"
+	"github.com/ory/x/sqlcon"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	m := reg.InvitationManager()
+
+	newFlow := func() *registration.Flow {
+		return &registration.Flow{InternalContext: []byte("{}")}
+	}
+
+	newIdentity := func(t *testing.T, email string) *identity.Identity {
+		i := identity.NewIdentity("default")
+		i.VerifiableAddresses = []identity.VerifiableAddress{{Value: email, Via: identity.VerifiableAddressTypeEmail}}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+		return i
+	}
+
+	t.Run("case=creates an invitation and finds it by its token", func(t *testing.T) {
+		inv, err := m.Create(ctx, "invited@ory.sh", json.RawMessage(`{"email":"invited@ory.sh"}`), time.Time{})
+		require.NoError(t, err)
+		require.NotEmpty(t, inv.Token)
+		assert.WithinDuration(t, time.Now().Add(conf.SelfServiceFlowRegistrationInvitationsLifespan(ctx)), inv.ExpiresAt, time.Minute)
+
+		actual, err := reg.InvitationPersister().GetInvitationByToken(ctx, inv.Token)
+		require.NoError(t, err)
+		assert.Equal(t, inv.ID, actual.ID)
+		assert.NotEqual(t, inv.Token, actual.Token, "the token must be stored as a digest")
+	})
+
+	t.Run("case=rejects invalid invitations", func(t *testing.T) {
+		_, err := m.Create(ctx, "", nil, time.Time{})
+		require.Error(t, err)
+
+		_, err = m.Create(ctx, "invited@ory.sh", json.RawMessage(`"traits"`), time.Time{})
+		require.Error(t, err)
+
+		_, err = m.Create(ctx, "invited@ory.sh", nil, time.Now().Add(-time.Minute))
+		require.Error(t, err)
+	})
+
+	t.Run("case=attaches the invitation to the flow", func(t *testing.T) {
+		inv, err := m.Create(ctx, "attach@ory.sh", nil, time.Time{})
+		require.NoError(t, err)
+
+		f := newFlow()
+		attached, err := m.AttachToFlow(ctx, f, inv.Token)
+		require.NoError(t, err)
+		assert.Equal(t, inv.ID, attached.ID)
+
+		actual, err := m.FromFlow(ctx, f)
+		require.NoError(t, err)
+		assert.Equal(t, inv.ID, actual.ID)
+
+		_, err = m.AttachToFlow(ctx, newFlow(), "not-a-token")
+		assert.ErrorIs(t, err, invitation.ErrInvitationInvalid)
+	})
+
+	t.Run("case=requires an invitation if configured", func(t *testing.T) {
+		actual, err := m.FromFlow(ctx, newFlow())
+		require.NoError(t, err)
+		assert.Nil(t, actual)
+
+		conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationInvitationsRequired, true)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationInvitationsRequired, false)
+		})
+
+		_, err = m.AttachToFlow(ctx, newFlow(), "")
+		assert.ErrorIs(t, err, invitation.ErrInvitationRequired)
+	})
+
+	t.Run("case=checks the email address of the identity", func(t *testing.T) {
+		inv, err := m.Create(ctx, "Check@ory.sh", nil, time.Time{})
+		require.NoError(t, err)
+
+		require.NoError(t, m.CheckIdentity(inv, newIdentity(t, "check@ory.sh")))
+		require.Error(t, m.CheckIdentity(inv, newIdentity(t, "other@ory.sh")))
+		require.NoError(t, m.CheckIdentity(nil, newIdentity(t, "no-invitation@ory.sh")))
+	})
+
+	t.Run("case=consumes the invitation only once", func(t *testing.T) {
+		inv, err := m.Create(ctx, "consume@ory.sh", nil, time.Time{})
+		require.NoError(t, err)
+		i := newIdentity(t, "consume@ory.sh")
+
+		require.NoError(t, m.Consume(ctx, inv))
+		assert.ErrorIs(t, m.Consume(ctx, inv), invitation.ErrInvitationInvalid)
+		require.NoError(t, m.Consume(ctx, nil))
+
+		actual, err := reg.InvitationPersister().GetInvitation(ctx, inv.ID)
+		require.NoError(t, err)
+		assert.True(t, actual.IsUsed())
+		assert.False(t, actual.IdentityID.Valid)
+
+		require.NoError(t, m.AssignIdentity(ctx, inv, i.ID))
+		actual, err = reg.InvitationPersister().GetInvitation(ctx, inv.ID)
+		require.NoError(t, err)
+		assert.Equal(t, i.ID, actual.IdentityID.UUID)
+
+		_, err = m.AttachToFlow(ctx, newFlow(), inv.Token)
+		assert.ErrorIs(t, err, invitation.ErrInvitationInvalid)
+	})
+
+	t.Run("case=deletes the invitation", func(t *testing.T) {
+		inv, err := m.Create(ctx, "delete@ory.sh", nil, time.Time{})
+		require.NoError(t, err)
+
+		require.NoError(t, reg.InvitationPersister().DeleteInvitation(ctx, inv.ID))
+		_, err = reg.InvitationPersister().GetInvitation(ctx, inv.ID)
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+		assert.ErrorIs(t, reg.InvitationPersister().DeleteInvitation(ctx, inv.ID), sqlcon.ErrNoRows)
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package invitation
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlxx"
+)
+
+// Invitation
+//
+// An invitation allows to register an identity with the email address of the invitation. If
+// `selfservice.flows.registration.invitations.required` is enabled, identities can only
+// register with an invitation.
+//
+// swagger:model invitation
+type Invitation struct {
+	// ID of the invitation
+	//
+	// required: true
+	ID uuid.UUID `json:"id" faker:"-" db:"id"`
+
+	// Email is the email address the invitation is bound to. The registered identity
+	// must use this address.
+	//
+	// required: true
+	Email string `json:"email" faker:"email" db:"email"`
+
+	// Traits are used to pre-fill the registration form.
+	Traits sqlxx.NullJSONRawMessage `json:"traits,omitempty" faker:"-" db:"traits"`
+
+	// Token is the secret which is passed to the registration flow. It is stored as a digest.
+	Token string `json:"-" faker:"-" db:"token"`
+
+	// ExpiresAt is the time at which the invitation expires.
+	//
+	// required: true
+	ExpiresAt time.Time `json:"expires_at" faker:"-" db:"expires_at"`
+
+	// UsedAt is the time at which an identity registered using the invitation.
+	UsedAt sqlxx.NullTime `json:"used_at,omitempty" faker:"-" db:"used_at"`
+
+	// IdentityID is the ID of the identity which registered using the invitation.
+	IdentityID uuid.NullUUID `json:"identity_id,omitempty" faker:"-" db:"identity_id"`
+
+	// CreatedAt is the time at which the invitation was created.
+	CreatedAt time.Time `json:"created_at" faker:"-" db:"created_at"`
+
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (i Invitation) TableName(context.Context) string {
+	return "selfservice_invitations"
+}
+
+// IsUsed returns true if an identity registered using the invitation.
+func (i *Invitation) IsUsed() bool {
+	return !time.Time(i.UsedAt).IsZero()
+}
+
+// IsValid returns true if the invitation was not used and did not expire.
+func (i *Invitation) IsValid() bool {
+	return !i.IsUsed() && i.ExpiresAt.After(x.Now())
+}
+
+type (
+	Persister interface {
+		// CreateInvitation stores the invitation. The token is stored as a digest and
+		// remains readable on the given invitation.
+		CreateInvitation(ctx context.Context, i *Invitation) error
+
+		// GetInvitation returns the invitation with the given ID or sqlcon.ErrNoRows.
+		GetInvitation(ctx context.Context, id uuid.UUID) (*Invitation, error)
+
+		// GetInvitationByToken returns the invitation with the given token or sqlcon.ErrNoRows.
+		GetInvitationByToken(ctx context.Context, token string) (*Invitation, error)
+
+		// UseInvitation marks the invitation as used. It returns sqlcon.ErrNoRows if the invitation
+		// does not exist or was used already.
+		UseInvitation(ctx context.Context, id uuid.UUID, at time.Time) error
+
+		// SetInvitationIdentity stores the identity which registered using the invitation. It returns
+		// sqlcon.ErrNoRows if the invitation does not exist.
+		SetInvitationIdentity(ctx context.Context, id, identityID uuid.UUID) error
+
+		// DeleteInvitation deletes the invitation.
+		DeleteInvitation(ctx context.Context, id uuid.UUID) error
+	}
+
+	PersistenceProvider interface {
+		InvitationPersister() Persister
+	}
+)
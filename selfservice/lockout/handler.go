@@ -0,0 +1,129 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package lockout
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlcon"
+)
+
+const RouteIdentityLockout = "/identities/:id/lockout"
+
+type (
+	handlerDependencies interface {
+		ManagementProvider
+		PersistenceProvider
+		x.WriterProvider
+	}
+
+	HandlerProvider interface {
+		LockoutHandler() *Handler
+	}
+
+	Handler struct {
+		d handlerDependencies
+	}
+)
+
+func NewHandler(d handlerDependencies) *Handler {
+	return &Handler{d: d}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteIdentityLockout, h.getIdentityLockout)
+	admin.DELETE(RouteIdentityLockout, h.unlockIdentity)
+}
+
+// Get Identity Lockout Parameters
+//
+// swagger:parameters getIdentityLockout
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type getIdentityLockout struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route GET /admin/identities/{id}/lockout identity getIdentityLockout
+//
+// # Get the Lockout State of an Identity
+//
+// Returns the failed sign in attempts and the lock of an identity. Responds with 404 if no
+// failed attempts were recorded for the identity.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: authenticationLockout
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) getIdentityLockout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	l, err := h.d.LockoutPersister().GetLockout(r.Context(), KindIdentity, x.ParseUUID(ps.ByName("id")).String())
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("No failed sign in attempts were recorded for this identity.")))
+		return
+	} else if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, l)
+}
+
+// Unlock Identity Parameters
+//
+// swagger:parameters unlockIdentity
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type unlockIdentity struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /admin/identities/{id}/lockout identity unlockIdentity
+//
+// # Unlock an Identity
+//
+// Removes the lock and resets the failed sign in attempts of an identity which was locked
+// because of too many failed sign in attempts.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  default: errorGeneric
+func (h *Handler) unlockIdentity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.d.LockoutManager().Unlock(r.Context(), x.ParseUUID(ps.ByName("id"))); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
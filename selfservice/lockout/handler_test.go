@@ -0,0 +1,64 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package lockout_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/lockout"
+	"github.com/ory/kratos/x"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginLockoutEnabled, true)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginLockoutMaxAttempts, 1)
+
+	router := x.NewRouterAdmin()
+	reg.LockoutHandler().RegisterAdminRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	id := x.NewUUID()
+	url := ts.URL + "/admin/identities/" + id.String() + "/lockout"
+
+	do := func(t *testing.T, method string) *http.Response {
+		req, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = res.Body.Close() })
+		return res
+	}
+
+	res := do(t, "GET")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	require.NoError(t, reg.LockoutManager().RecordFailure(ctx, id, ""))
+
+	res = do(t, "GET")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var l lockout.Lockout
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&l))
+	assert.Equal(t, lockout.KindIdentity, l.Kind)
+	assert.Equal(t, id.String(), l.Subject)
+	assert.Equal(t, 1, l.Lockouts)
+
+	res = do(t, "DELETE")
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	require.NoError(t, reg.LockoutManager().Check(ctx, id, ""))
+
+	res = do(t, "GET")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
@@ -0,0 +1,186 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+)
+
+// ErrIdentityLocked is returned by Check if the identity is locked.
+var ErrIdentityLocked = errors.New("the identity is locked")
+
+type (
+	managerDependencies interface {
+		config.Provider
+		x.LoggingProvider
+		x.TracingProvider
+		PersistenceProvider
+	}
+
+	// Manager counts failed sign in attempts and locks identities and IP
+	// addresses which exceed the configured thresholds.
+	Manager struct {
+		d managerDependencies
+	}
+
+	ManagementProvider interface {
+		LockoutManager() *Manager
+	}
+
+	subject struct {
+		kind        Kind
+		subject     string
+		maxAttempts int
+	}
+)
+
+func NewManager(d managerDependencies) *Manager {
+	return &Manager{d: d}
+}
+
+func (m *Manager) subjects(ctx context.Context, identityID uuid.UUID, ip string) []subject {
+	var subjects []subject
+	if identityID != uuid.Nil {
+		subjects = append(subjects, subject{
+			kind:        KindIdentity,
+			subject:     identityID.String(),
+			maxAttempts: m.d.Config().SelfServiceFlowLoginLockoutMaxAttempts(ctx),
+		})
+	}
+	if ip != "" {
+		subjects = append(subjects, subject{
+			kind:        KindIP,
+			subject:     ip,
+			maxAttempts: m.d.Config().SelfServiceFlowLoginLockoutIPMaxAttempts(ctx),
+		})
+	}
+	return subjects
+}
+
+// Check returns ErrIdentityLocked if the identity is currently locked and a
+// validation error if the IP address is currently locked. The identity ID may
+// be nil if the identity is unknown.
+//
+// Callers must not tell the user that the identity is locked, as that would
+// reveal that the account exists, but answer as if the credentials were
+// invalid.
+func (m *Manager) Check(ctx context.Context, identityID uuid.UUID, ip string) (err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "selfservice.lockout.Manager.Check")
+	defer otelx.End(span, &err)
+
+	if !m.d.Config().SelfServiceFlowLoginLockoutEnabled(ctx) {
+		return nil
+	}
+
+	now := x.Now().UTC()
+	for _, s := range m.subjects(ctx, identityID, ip) {
+		l, err := m.d.LockoutPersister().GetLockout(ctx, s.kind, s.subject)
+		if errors.Is(err, sqlcon.ErrNoRows) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if !l.IsLocked(now) {
+			continue
+		}
+		if s.kind == KindIdentity {
+			return errors.WithStack(ErrIdentityLocked)
+		}
+		return schema.NewLoginLockedError(time.Time(l.LockedUntil))
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed attempt for the identity and the IP address
+// and locks them once the configured number of attempts is reached. The lock
+// duration doubles with every consecutive lockout.
+func (m *Manager) RecordFailure(ctx context.Context, identityID uuid.UUID, ip string) (err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "selfservice.lockout.Manager.RecordFailure")
+	defer otelx.End(span, &err)
+
+	if !m.d.Config().SelfServiceFlowLoginLockoutEnabled(ctx) {
+		return nil
+	}
+
+	now := x.Now().UTC()
+	window := m.d.Config().SelfServiceFlowLoginLockoutAttemptWindow(ctx)
+	maxDuration := m.d.Config().SelfServiceFlowLoginLockoutMaxDuration(ctx)
+
+	for _, s := range m.subjects(ctx, identityID, ip) {
+		var locked time.Time
+		if err := m.d.LockoutPersister().RecordLockoutFailure(ctx, s.kind, s.subject, now, func(l *Lockout) {
+			locked = time.Time{}
+			if now.Sub(l.LastFailedAt) > window {
+				l.FailedAttempts = 0
+			}
+			if now.Sub(l.LastFailedAt) > window+maxDuration {
+				l.Lockouts = 0
+			}
+
+			l.FailedAttempts++
+			l.LastFailedAt = now
+			if l.FailedAttempts >= s.maxAttempts {
+				l.Lockouts++
+				l.FailedAttempts = 0
+				l.LockedUntil = sqlxx.NullTime(now.Add(m.lockDuration(ctx, l.Lockouts)))
+				locked = time.Time(l.LockedUntil)
+			}
+		}); err != nil {
+			return err
+		}
+
+		if !locked.IsZero() {
+			m.d.Logger().
+				WithField("lockout_kind", s.kind).
+				WithField("locked_until", locked).
+				Warn("Too many failed sign in attempts, locking the sign in.")
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) lockDuration(ctx context.Context, lockouts int) time.Duration {
+	d := m.d.Config().SelfServiceFlowLoginLockoutBaseDuration(ctx)
+	max := m.d.Config().SelfServiceFlowLoginLockoutMaxDuration(ctx)
+	for i := 1; i < lockouts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
+
+// RecordSuccess resets the failed attempts of the identity. Failed attempts
+// of the IP address are kept, as a successful sign in to one account does not
+// make failed attempts against other accounts less suspicious.
+func (m *Manager) RecordSuccess(ctx context.Context, identityID uuid.UUID) (err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "selfservice.lockout.Manager.RecordSuccess")
+	defer otelx.End(span, &err)
+
+	if !m.d.Config().SelfServiceFlowLoginLockoutEnabled(ctx) {
+		return nil
+	}
+
+	return m.Unlock(ctx, identityID)
+}
+
+// Unlock removes the lock and the failed attempts of the identity.
+func (m *Manager) Unlock(ctx context.Context, identityID uuid.UUID) error {
+	return m.d.LockoutPersister().DeleteLockout(ctx, KindIdentity, identityID.String())
+}
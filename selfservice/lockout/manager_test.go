@@ -0,0 +1,138 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package lockout_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/lockout"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlcon"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	m := reg.LockoutManager()
+
+	t.Run("case=does nothing if disabled", func(t *testing.T) {
+		id := x.NewUUID()
+		for k := 0; k < 10; k++ {
+			require.NoError(t, m.RecordFailure(ctx, id, "10.0.0.1"))
+		}
+		require.NoError(t, m.Check(ctx, id, "10.0.0.1"))
+
+		_, err := reg.LockoutPersister().GetLockout(ctx, lockout.KindIdentity, id.String())
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+	})
+
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginLockoutEnabled, true)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginLockoutMaxAttempts, 3)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginLockoutIPMaxAttempts, 5)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginLockoutBaseDuration, "1m")
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginLockoutMaxDuration, "3m")
+
+	assertIdentityLocked := func(t *testing.T, err error) {
+		require.Error(t, err)
+		assert.ErrorIs(t, err, lockout.ErrIdentityLocked)
+	}
+
+	assertIPLocked := func(t *testing.T, err error) {
+		require.Error(t, err)
+		var ve *schema.ValidationError
+		require.ErrorAs(t, err, &ve)
+		assert.Equal(t, text.ErrorValidationLoginLocked, ve.Messages[0].ID)
+	}
+
+	t.Run("case=locks the identity after the configured attempts", func(t *testing.T) {
+		id := x.NewUUID()
+		for k := 0; k < 2; k++ {
+			require.NoError(t, m.RecordFailure(ctx, id, ""))
+			require.NoError(t, m.Check(ctx, id, ""))
+		}
+
+		require.NoError(t, m.RecordFailure(ctx, id, ""))
+		assertIdentityLocked(t, m.Check(ctx, id, ""))
+
+		l, err := reg.LockoutPersister().GetLockout(ctx, lockout.KindIdentity, id.String())
+		require.NoError(t, err)
+		assert.Equal(t, 1, l.Lockouts)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), time.Time(l.LockedUntil), 5*time.Second)
+	})
+
+	t.Run("case=doubles the lock duration up to the maximum", func(t *testing.T) {
+		id := x.NewUUID()
+		for _, expected := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+			for k := 0; k < 3; k++ {
+				require.NoError(t, m.RecordFailure(ctx, id, ""))
+			}
+
+			l, err := reg.LockoutPersister().GetLockout(ctx, lockout.KindIdentity, id.String())
+			require.NoError(t, err)
+			assert.WithinDuration(t, time.Now().Add(expected), time.Time(l.LockedUntil), 5*time.Second)
+		}
+	})
+
+	t.Run("case=locks the ip address across identities", func(t *testing.T) {
+		ip := "10.0.0.2"
+		for k := 0; k < 5; k++ {
+			require.NoError(t, m.RecordFailure(ctx, x.NewUUID(), ip))
+		}
+
+		assertIPLocked(t, m.Check(ctx, uuid.Nil, ip))
+		assertIPLocked(t, m.Check(ctx, x.NewUUID(), ip))
+		require.NoError(t, m.Check(ctx, x.NewUUID(), "10.0.0.3"))
+	})
+
+	t.Run("case=success and unlock reset the identity", func(t *testing.T) {
+		id := x.NewUUID()
+		for k := 0; k < 2; k++ {
+			require.NoError(t, m.RecordFailure(ctx, id, ""))
+		}
+		require.NoError(t, m.RecordSuccess(ctx, id))
+		require.NoError(t, m.RecordFailure(ctx, id, ""))
+		require.NoError(t, m.Check(ctx, id, ""))
+
+		for k := 0; k < 2; k++ {
+			require.NoError(t, m.RecordFailure(ctx, id, ""))
+		}
+		assertIdentityLocked(t, m.Check(ctx, id, ""))
+
+		require.NoError(t, m.Unlock(ctx, id))
+		require.NoError(t, m.Check(ctx, id, ""))
+	})
+
+	t.Run("case=counts concurrent failed attempts", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceLoginLockoutMaxAttempts, 100)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceLoginLockoutMaxAttempts, 3)
+		})
+
+		id := x.NewUUID()
+		var wg sync.WaitGroup
+		for k := 0; k < 10; k++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, m.RecordFailure(ctx, id, ""))
+			}()
+		}
+		wg.Wait()
+
+		l, err := reg.LockoutPersister().GetLockout(ctx, lockout.KindIdentity, id.String())
+		require.NoError(t, err)
+		assert.Equal(t, 10, l.FailedAttempts)
+	})
+}
@@ -0,0 +1,97 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package lockout
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/x/sqlxx"
+)
+
+// Kind is the kind of subject failed attempts are counted for.
+type Kind string
+
+const (
+	KindIdentity Kind = "identity"
+	KindIP       Kind = "ip"
+)
+
+// Lockout tracks the failed sign in attempts of a subject, which is either
+// an identity or an IP address.
+//
+// swagger:model authenticationLockout
+type Lockout struct {
+	ID  uuid.UUID `json:"-" faker:"-" db:"id"`
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+
+	// Kind is either "identity" or "ip".
+	Kind Kind `json:"kind" db:"kind"`
+
+	// Subject is the identity ID or the IP address.
+	Subject string `json:"subject" db:"subject"`
+
+	// FailedAttempts is the number of failed attempts since the last lockout.
+	FailedAttempts int `json:"failed_attempts" db:"failed_attempts"`
+
+	// Lockouts is the number of consecutive lockouts. It determines the lock
+	// duration.
+	Lockouts int `json:"lockouts" db:"lockouts"`
+
+	// LastFailedAt is the time of the last failed attempt.
+	LastFailedAt time.Time `json:"last_failed_at" db:"last_failed_at"`
+
+	// LockedUntil is set while the subject is locked.
+	LockedUntil sqlxx.NullTime `json:"locked_until" db:"locked_until"`
+
+	// CreatedAt is a helper struct field for gobuffalo.pop.
+	CreatedAt time.Time `json:"-" db:"created_at"`
+
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" db:"updated_at"`
+}
+
+func (l Lockout) TableName(context.Context) string {
+	return "authentication_lockouts"
+}
+
+func (l *Lockout) GetID() uuid.UUID {
+	return l.ID
+}
+
+func (l *Lockout) GetNID() uuid.UUID {
+	return l.NID
+}
+
+// IsLocked returns true if the subject is locked at the given time.
+func (l *Lockout) IsLocked(now time.Time) bool {
+	return now.Before(time.Time(l.LockedUntil))
+}
+
+type (
+	Persister interface {
+		// GetLockout returns the lockout of the subject or sqlcon.ErrNoRows if
+		// no failed attempts were recorded.
+		GetLockout(ctx context.Context, kind Kind, subject string) (*Lockout, error)
+
+		// RecordLockoutFailure locks the lockout of the subject, creating it
+		// if no failed attempts were recorded yet, and stores the changes
+		// record applied to it. Concurrent failed attempts for the same
+		// subject are serialized. record may be called more than once if the
+		// transaction is retried.
+		RecordLockoutFailure(ctx context.Context, kind Kind, subject string, now time.Time, record func(l *Lockout)) error
+
+		DeleteLockout(ctx context.Context, kind Kind, subject string) error
+
+		// DeleteExpiredLockouts deletes lockouts which are no longer locked and
+		// which did not see a failed attempt since the given time.
+		DeleteExpiredLockouts(context.Context, time.Time, int) error
+	}
+
+	PersistenceProvider interface {
+		LockoutPersister() Persister
+	}
+)
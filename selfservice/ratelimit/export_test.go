@@ -0,0 +1,10 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package ratelimit
+
+import "time"
+
+func SetMemoryStoreClockForTest(s *MemoryStore, now func() time.Time) {
+	s.now = now
+}
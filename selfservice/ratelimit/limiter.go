@@ -0,0 +1,112 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+)
+
+type (
+	limiterDependencies interface {
+		config.Provider
+		x.LoggingProvider
+		x.TracingProvider
+	}
+
+	// Limiter limits login attempts per identifier and per IP address.
+	Limiter struct {
+		d     limiterDependencies
+		store Store
+	}
+
+	ManagementProvider interface {
+		LoginRateLimiter() *Limiter
+	}
+
+	// RateLimitedError is returned when a limit is exceeded.
+	RateLimitedError struct {
+		*herodot.DefaultError
+		RetryAfter time.Duration
+	}
+)
+
+func NewLimiter(d limiterDependencies, store Store) *Limiter {
+	return &Limiter{d: d, store: store}
+}
+
+func NewRateLimitedError(retryAfter time.Duration) *RateLimitedError {
+	return &RateLimitedError{
+		DefaultError: &herodot.DefaultError{
+			IDField:     text.ErrIDSelfServiceFlowRateLimited,
+			CodeField:   http.StatusTooManyRequests,
+			StatusField: http.StatusText(http.StatusTooManyRequests),
+			ErrorField:  "Too many login attempts",
+			ReasonField: fmt.Sprintf("Too many login attempts. Please try again in %d seconds.", RetryAfterSeconds(retryAfter)),
+			DetailsField: map[string]interface{}{
+				"retry_after": RetryAfterSeconds(retryAfter),
+			},
+		},
+		RetryAfter: retryAfter,
+	}
+}
+
+// RetryAfterSeconds rounds the duration up to whole seconds as used by the `Retry-After` header.
+func RetryAfterSeconds(d time.Duration) int64 {
+	return int64(math.Max(1, math.Ceil(d.Seconds())))
+}
+
+// Limit counts a login attempt for the IP address and the identifier. It returns a *RateLimitedError if
+// either limit is exceeded. Empty values are not counted. If the store is unavailable, the attempt is
+// allowed so that an outage of the store does not prevent users from signing in.
+func (l *Limiter) Limit(ctx context.Context, ip, identifier string) (err error) {
+	ctx, span := l.d.Tracer(ctx).Tracer().Start(ctx, "ratelimit.Limiter.Limit")
+	defer otelx.End(span, &err)
+
+	conf := l.d.Config()
+	if ip != "" {
+		if err := l.limit(ctx, "ip:"+ip, conf.SelfServiceFlowLoginRateLimitIPLimit(ctx), conf.SelfServiceFlowLoginRateLimitIPWindow(ctx)); err != nil {
+			return err
+		}
+	}
+
+	if identifier = strings.ToLower(strings.TrimSpace(identifier)); identifier != "" {
+		// Identifiers are hashed so that they are not stored in plain text.
+		sum := sha256.Sum256([]byte(identifier))
+		if err := l.limit(ctx, "identifier:"+hex.EncodeToString(sum[:]), conf.SelfServiceFlowLoginRateLimitIdentifierLimit(ctx), conf.SelfServiceFlowLoginRateLimitIdentifierWindow(ctx)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *Limiter) limit(ctx context.Context, key string, limit int, window time.Duration) error {
+	if limit <= 0 || window <= 0 {
+		return nil
+	}
+
+	count, resetIn, err := l.store.Increment(ctx, key, window)
+	if err != nil {
+		l.d.Logger().WithError(err).Warn("Unable to count the login attempt, the rate limit is not enforced.")
+		return nil
+	}
+
+	if count > int64(limit) {
+		return NewRateLimitedError(resetIn)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/ratelimit"
+	"github.com/ory/kratos/text"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginRateLimitIdentifierLimit, 2)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginRateLimitIdentifierWindow, "1m")
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginRateLimitIPLimit, 3)
+	conf.MustSet(ctx, config.ViperKeySelfServiceLoginRateLimitIPWindow, "1m")
+
+	assertLimited := func(t *testing.T, err error) {
+		require.Error(t, err)
+		var e *ratelimit.RateLimitedError
+		require.ErrorAs(t, err, &e)
+		assert.Equal(t, http.StatusTooManyRequests, e.StatusCode())
+		assert.Equal(t, text.ErrIDSelfServiceFlowRateLimited, e.ID())
+		assert.InDelta(t, 60, ratelimit.RetryAfterSeconds(e.RetryAfter), 1)
+	}
+
+	t.Run("case=limits per identifier", func(t *testing.T) {
+		l := ratelimit.NewLimiter(reg, ratelimit.NewMemoryStore())
+		require.NoError(t, l.Limit(ctx, "10.0.0.1", "foo@ory.sh"))
+		require.NoError(t, l.Limit(ctx, "10.0.0.2", " FOO@ory.sh "))
+		assertLimited(t, l.Limit(ctx, "10.0.0.3", "foo@ory.sh"))
+
+		require.NoError(t, l.Limit(ctx, "10.0.0.4", "bar@ory.sh"), "other identifiers are not limited")
+	})
+
+	t.Run("case=limits per IP address", func(t *testing.T) {
+		l := ratelimit.NewLimiter(reg, ratelimit.NewMemoryStore())
+		require.NoError(t, l.Limit(ctx, "10.0.0.1", "a@ory.sh"))
+		require.NoError(t, l.Limit(ctx, "10.0.0.1", "b@ory.sh"))
+		require.NoError(t, l.Limit(ctx, "10.0.0.1", ""))
+		assertLimited(t, l.Limit(ctx, "10.0.0.1", "c@ory.sh"))
+
+		require.NoError(t, l.Limit(ctx, "10.0.0.2", "c@ory.sh"), "other IP addresses are not limited")
+	})
+}
@@ -0,0 +1,54 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ory/kratos/x/redisx"
+)
+
+var _ Store = new(RedisStore)
+
+// incrementScript increments the counter and starts the window on the first request. It returns
+// the count and the remaining time of the window in milliseconds.
+var incrementScript = redis.NewScript(`local c = redis.call("INCR", KEYS[1])
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+  redis.call("PEXPIRE", KEYS[1], ARGV[1])
+  ttl = tonumber(ARGV[1])
+end
+return {c, ttl}`)
+
+const redisKeyPrefix = "kratos:ratelimit:"
+
+// RedisStore counts requests in Redis so that all instances of Ory Kratos share the same limits.
+type RedisStore struct {
+	c *redis.Client
+}
+
+// NewRedisStore creates a store for a URL of the form `redis://[user:password@]host:port[/db]`. Use
+// the `rediss` scheme to connect with TLS.
+func NewRedisStore(u string) (*RedisStore, error) {
+	c, err := redisx.NewClient(u)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{c: c}, nil
+}
+
+func (s *RedisStore) Increment(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	values, err := incrementScript.Run(ctx, s.c, []string{redisKeyPrefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, errors.WithStack(err)
+	} else if len(values) != 2 {
+		return 0, 0, errors.Errorf("unexpected redis reply: %v", values)
+	}
+
+	return values[0], time.Duration(values[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,66 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store counts requests per key in fixed windows.
+type Store interface {
+	// Increment counts a request for the key. It returns the number of requests in the current window
+	// and the time until the window resets. The window starts with the first request for the key.
+	Increment(ctx context.Context, key string, window time.Duration) (count int64, resetIn time.Duration, err error)
+}
+
+var _ Store = new(MemoryStore)
+
+type (
+	// MemoryStore counts requests in the memory of the process. Each instance of Ory Kratos counts
+	// separately, which is why deployments with more than one instance should use the RedisStore.
+	MemoryStore struct {
+		sync.Mutex
+		counters  map[string]*counter
+		lastSweep time.Time
+		now       func() time.Time
+	}
+
+	counter struct {
+		count   int64
+		resetAt time.Time
+	}
+)
+
+// memorySweepInterval is how often expired counters are removed.
+const memorySweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: map[string]*counter{}, now: time.Now}
+}
+
+func (s *MemoryStore) Increment(_ context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) > memorySweepInterval {
+		for k, c := range s.counters {
+			if !now.Before(c.resetAt) {
+				delete(s.counters, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.resetAt) {
+		c = &counter{resetAt: now.Add(window)}
+		s.counters[key] = c
+	}
+
+	c.count++
+	return c.count, c.resetAt.Sub(now), nil
+}
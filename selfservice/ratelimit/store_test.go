@@ -0,0 +1,134 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package ratelimit_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/selfservice/ratelimit"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := ratelimit.NewMemoryStore()
+	ratelimit.SetMemoryStoreClockForTest(s, func() time.Time { return now })
+
+	for k := int64(1); k <= 3; k++ {
+		count, resetIn, err := s.Increment(ctx, "a", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, k, count)
+		assert.Equal(t, time.Minute, resetIn)
+	}
+
+	count, _, err := s.Increment(ctx, "b", time.Minute)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count, "keys are counted separately")
+
+	now = now.Add(30 * time.Second)
+	count, resetIn, err := s.Increment(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, count)
+	assert.Equal(t, 30*time.Second, resetIn)
+
+	now = now.Add(30 * time.Second)
+	count, resetIn, err = s.Increment(ctx, "a", time.Minute)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count, "the window resets")
+	assert.Equal(t, time.Minute, resetIn)
+}
+
+func TestRedisStore(t *testing.T) {
+	t.Run("case=rejects invalid URLs", func(t *testing.T) {
+		for _, u := range []string{"http://localhost:6379", "redis://localhost:6379/abc"} {
+			_, err := ratelimit.NewRedisStore(u)
+			assert.Error(t, err, u)
+		}
+	})
+
+	t.Run("case=increments through the script", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = l.Close() })
+
+		commands := make(chan []string, 10)
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			r := bufio.NewReader(conn)
+			for {
+				cmd, err := readCommand(r)
+				if err != nil {
+					return
+				}
+				commands <- cmd
+
+				reply := "+OK\r\n"
+				if cmd[0] == "EVAL" {
+					reply = "*2\r\n:1\r\n:60000\r\n"
+				}
+				if _, err := conn.Write([]byte(reply)); err != nil {
+					return
+				}
+			}
+		}()
+
+		s, err := ratelimit.NewRedisStore("redis://:secret@" + l.Addr().String() + "/2")
+		require.NoError(t, err)
+
+		count, resetIn, err := s.Increment(context.Background(), "ip:10.0.0.1", time.Minute)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, count)
+		assert.Equal(t, time.Minute, resetIn)
+
+		assert.Equal(t, []string{"AUTH", "secret"}, <-commands)
+		assert.Equal(t, []string{"SELECT", "2"}, <-commands)
+		eval := <-commands
+		require.Len(t, eval, 5)
+		assert.Equal(t, "EVAL", eval[0])
+		assert.Equal(t, []string{"1", "kratos:ratelimit:ip:10.0.0.1", "60000"}, eval[2:])
+
+		_, _, err = s.Increment(context.Background(), "ip:10.0.0.1", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, "EVAL", (<-commands)[0], "the connection is reused")
+	})
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	var n int
+	if _, err := fmt.Sscanf(line, "*%d\r\n", &n); err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
@@ -0,0 +1,250 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package signupcode
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/sqlxx"
+	"github.com/ory/x/urlx"
+)
+
+const (
+	RouteCollection = "/signup-codes"
+	RouteItem       = RouteCollection + "/:id"
+)
+
+type (
+	handlerDependencies interface {
+		config.Provider
+		ManagementProvider
+		PersistenceProvider
+		x.WriterProvider
+	}
+
+	HandlerProvider interface {
+		SignupCodeHandler() *Handler
+	}
+
+	Handler struct {
+		d handlerDependencies
+	}
+)
+
+func NewHandler(d handlerDependencies) *Handler {
+	return &Handler{d: d}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteCollection, h.listSignupCodes)
+	admin.POST(RouteCollection, h.createSignupCode)
+	admin.GET(RouteItem, h.getSignupCode)
+	admin.DELETE(RouteItem, h.deleteSignupCode)
+}
+
+// List of Sign-Up Codes
+//
+// swagger:model signupCodes
+type signupCodes []SignupCode
+
+// swagger:route GET /admin/signup-codes identity listSignupCodes
+//
+// # List Sign-Up Codes
+//
+// Lists all sign-up codes including how often they were used.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: signupCodes
+//	  default: errorGeneric
+func (h *Handler) listSignupCodes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	codes, err := h.d.SignupCodePersister().ListSignupCodes(r.Context())
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, signupCodes(codes))
+}
+
+// Create Sign-Up Code Body
+//
+// swagger:model createSignupCodeBody
+type CreateSignupCodeBody struct {
+	// Code is entered in the registration form. Codes are case-insensitive.
+	//
+	// required: true
+	Code string `json:"code"`
+
+	// Tenant is stored as `tenant` in the admin metadata of identities which register with the code.
+	//
+	// required: true
+	Tenant string `json:"tenant"`
+
+	// Group is stored as `group` in the admin metadata of identities which register with the code.
+	Group string `json:"group,omitempty"`
+
+	// MaxUses limits how many identities can register with the code. Defaults to unlimited.
+	MaxUses int `json:"max_uses,omitempty"`
+
+	// ExpiresAt is the time at which the code expires. Defaults to never.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Create Sign-Up Code Parameters
+//
+// swagger:parameters createSignupCode
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type createSignupCode struct {
+	// in: body
+	Body CreateSignupCodeBody
+}
+
+// swagger:route POST /admin/signup-codes identity createSignupCode
+//
+// # Create a Sign-Up Code
+//
+// Creates a sign-up code, for example for an employer or a class. Identities which enter the code in
+// the registration form are assigned to the tenant and group of the code in their admin metadata.
+// Sign-up codes are only asked for if `selfservice.flows.registration.signup_codes.enabled` is set.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  201: signupCode
+//	  400: errorGeneric
+//	  409: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) createSignupCode(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body CreateSignupCodeBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		h.d.Writer().WriteErrorCode(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+
+	c := &SignupCode{
+		Code:    body.Code,
+		Tenant:  body.Tenant,
+		Group:   body.Group,
+		MaxUses: body.MaxUses,
+	}
+	if body.ExpiresAt != nil {
+		c.ExpiresAt = sqlxx.NullTime(body.ExpiresAt.UTC())
+	}
+
+	if err := h.d.SignupCodeManager().Create(r.Context(), c); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().WriteCreated(w, r,
+		urlx.AppendPaths(h.d.Config().SelfAdminURL(r.Context()), RouteCollection, c.ID.String()).String(),
+		c)
+}
+
+// Get Sign-Up Code Parameters
+//
+// swagger:parameters getSignupCode
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type getSignupCode struct {
+	// ID is the sign-up code's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route GET /admin/signup-codes/{id} identity getSignupCode
+//
+// # Get a Sign-Up Code
+//
+// Returns the sign-up code including how often it was used.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: signupCode
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) getSignupCode(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	c, err := h.d.SignupCodePersister().GetSignupCode(r.Context(), x.ParseUUID(ps.ByName("id")))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, c)
+}
+
+// Delete Sign-Up Code Parameters
+//
+// swagger:parameters deleteSignupCode
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type deleteSignupCode struct {
+	// ID is the sign-up code's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /admin/signup-codes/{id} identity deleteSignupCode
+//
+// # Revoke a Sign-Up Code
+//
+// Deletes the sign-up code. Identities which registered with the code keep their tenant, but
+// registration flows in which the code was entered can no longer be completed.
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) deleteSignupCode(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.d.SignupCodePersister().DeleteSignupCode(r.Context(), x.ParseUUID(ps.ByName("id"))); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
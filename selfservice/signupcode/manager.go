@@ -0,0 +1,233 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package signupcode
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+const internalContextSignupCodePath = "signup_code_id"
+
+// NodeName is the name of the registration form field which holds the sign-up code.
+const NodeName = "signup_code"
+
+//go:embed .schema/code.schema.json
+var codeSchema []byte
+
+var codeDecoder = decoderx.NewHTTP()
+
+type (
+	managerDependencies interface {
+		config.Provider
+		x.LoggingProvider
+		x.TracingProvider
+		PersistenceProvider
+	}
+
+	// Manager creates sign-up codes and assigns identities registering with them to their tenant.
+	Manager struct {
+		d managerDependencies
+	}
+
+	ManagementProvider interface {
+		SignupCodeManager() *Manager
+	}
+)
+
+func NewManager(d managerDependencies) *Manager {
+	return &Manager{d: d}
+}
+
+// NormalizeCode trims the code and makes it case-insensitive.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Create validates and stores the sign-up code.
+func (m *Manager) Create(ctx context.Context, c *SignupCode) (err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "signupcode.Manager.Create")
+	defer otelx.End(span, &err)
+
+	c.Code = NormalizeCode(c.Code)
+	c.Tenant = strings.TrimSpace(c.Tenant)
+	c.Group = strings.TrimSpace(c.Group)
+	if c.Code == "" {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The code must be set."))
+	}
+	if c.Tenant == "" {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The tenant of the code must be set."))
+	}
+	if c.MaxUses < 0 {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The maximum number of uses must not be negative."))
+	}
+	if expiresAt := time.Time(c.ExpiresAt); !expiresAt.IsZero() && !expiresAt.After(x.Now()) {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The code must expire in the future."))
+	}
+
+	c.ID = x.NewUUID()
+	c.Uses = 0
+	if err := m.d.SignupCodePersister().CreateSignupCode(ctx, c); errors.Is(err, sqlcon.ErrUniqueViolation) {
+		return errors.WithStack(herodot.ErrConflict.WithReason("A sign-up code with this code exists already."))
+	} else if err != nil {
+		return err
+	}
+
+	m.d.Logger().
+		WithField("signup_code_id", c.ID).
+		WithField("tenant", c.Tenant).
+		Info("A sign-up code was created.")
+
+	return nil
+}
+
+// DecodeCode returns the sign-up code submitted in the registration form. The request body is kept for
+// the registration method.
+func DecodeCode(r *http.Request) (string, error) {
+	compiler, err := decoderx.HTTPRawJSONSchemaCompiler(codeSchema)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	var body json.RawMessage
+	if err := codeDecoder.Decode(r, &body, compiler,
+		decoderx.HTTPKeepRequestBody(true),
+		decoderx.HTTPDecoderAllowedMethods("POST", "PUT", "PATCH"),
+		decoderx.HTTPDecoderSetValidatePayloads(false),
+		decoderx.HTTPDecoderJSONFollowsFormFormat(),
+	); err != nil {
+		return "", err
+	}
+
+	return gjson.GetBytes(body, NodeName).String(), nil
+}
+
+// AttachToFlow stores the sign-up code on the registration flow. Without a code the sign-up code of the
+// flow is kept, for example if it was submitted in a previous step of the flow.
+func (m *Manager) AttachToFlow(ctx context.Context, f flow.InternalContexter, code string) (_ *SignupCode, err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "signupcode.Manager.AttachToFlow")
+	defer otelx.End(span, &err)
+
+	code = NormalizeCode(code)
+	if code == "" {
+		return m.FromFlow(ctx, f)
+	}
+
+	c, err := m.d.SignupCodePersister().GetSignupCodeByCode(ctx, code)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, schema.NewSignupCodeInvalidError()
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !c.IsValid() {
+		return nil, schema.NewSignupCodeInvalidError()
+	}
+
+	f.EnsureInternalContext()
+	raw, err := sjson.SetBytes(f.GetInternalContext(), internalContextSignupCodePath, c.ID)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	f.SetInternalContext(raw)
+
+	return c, nil
+}
+
+// FromFlow returns the sign-up code attached to the registration flow, or nil if the flow has no sign-up
+// code and registration does not require one.
+func (m *Manager) FromFlow(ctx context.Context, f flow.InternalContexter) (_ *SignupCode, err error) {
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "signupcode.Manager.FromFlow")
+	defer otelx.End(span, &err)
+
+	id := uuid.FromStringOrNil(gjson.GetBytes(f.GetInternalContext(), internalContextSignupCodePath).String())
+	if id.IsNil() {
+		if m.d.Config().SelfServiceFlowRegistrationSignupCodesRequired(ctx) {
+			return nil, schema.NewSignupCodeRequiredError()
+		}
+		return nil, nil
+	}
+
+	c, err := m.d.SignupCodePersister().GetSignupCode(ctx, id)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, schema.NewSignupCodeInvalidError()
+	} else if err != nil {
+		return nil, err
+	}
+
+	if !c.IsValid() {
+		return nil, schema.NewSignupCodeInvalidError()
+	}
+
+	return c, nil
+}
+
+// Assign records the tenant and group of the sign-up code in the admin metadata of the identity. Identities
+// registering without a sign-up code (nil) are not changed.
+func (m *Manager) Assign(c *SignupCode, i *identity.Identity) error {
+	if c == nil {
+		return nil
+	}
+
+	metadata := []byte(i.MetadataAdmin)
+	if !gjson.ParseBytes(metadata).IsObject() {
+		metadata = []byte("{}")
+	}
+
+	metadata, err := sjson.SetBytes(metadata, "tenant", c.Tenant)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if c.Group != "" {
+		if metadata, err = sjson.SetBytes(metadata, "group", c.Group); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	i.MetadataAdmin = metadata
+	return nil
+}
+
+// Consume counts a use of the sign-up code. Identities registering without a sign-up code (nil) are not
+// counted.
+func (m *Manager) Consume(ctx context.Context, c *SignupCode) (err error) {
+	if c == nil {
+		return nil
+	}
+
+	ctx, span := m.d.Tracer(ctx).Tracer().Start(ctx, "signupcode.Manager.Consume")
+	defer otelx.End(span, &err)
+
+	if err := m.d.SignupCodePersister().UseSignupCode(ctx, c.ID); errors.Is(err, sqlcon.ErrNoRows) {
+		return schema.NewSignupCodeInvalidError()
+	} else if err != nil {
+		return err
+	}
+
+	m.d.Logger().
+		WithField("signup_code_id", c.ID).
+		WithField("tenant", c.Tenant).
+		Info("An identity registered using a sign-up code.")
+
+	return nil
+}
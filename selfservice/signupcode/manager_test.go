@@ -0,0 +1,147 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package signupcode_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/signupcode"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	m := reg.SignupCodeManager()
+
+	newFlow := func() *registration.Flow {
+		return &registration.Flow{InternalContext: []byte("{}")}
+	}
+
+	create := func(t *testing.T, c *signupcode.SignupCode) *signupcode.SignupCode {
+		require.NoError(t, m.Create(ctx, c))
+		return c
+	}
+
+	t.Run("case=creates a code and finds it case-insensitively", func(t *testing.T) {
+		c := create(t, &signupcode.SignupCode{Code: " acme-2023 ", Tenant: "acme", Group: "engineering"})
+		assert.Equal(t, "ACME-2023", c.Code)
+
+		f := newFlow()
+		attached, err := m.AttachToFlow(ctx, f, "Acme-2023")
+		require.NoError(t, err)
+		assert.Equal(t, c.ID, attached.ID)
+
+		actual, err := m.FromFlow(ctx, f)
+		require.NoError(t, err)
+		assert.Equal(t, c.ID, actual.ID)
+	})
+
+	t.Run("case=rejects invalid codes", func(t *testing.T) {
+		require.Error(t, m.Create(ctx, &signupcode.SignupCode{Tenant: "acme"}))
+		require.Error(t, m.Create(ctx, &signupcode.SignupCode{Code: "no-tenant"}))
+		require.Error(t, m.Create(ctx, &signupcode.SignupCode{Code: "negative", Tenant: "acme", MaxUses: -1}))
+		require.Error(t, m.Create(ctx, &signupcode.SignupCode{Code: "expired", Tenant: "acme", ExpiresAt: sqlxx.NullTime(time.Now().Add(-time.Minute))}))
+
+		create(t, &signupcode.SignupCode{Code: "duplicate", Tenant: "acme"})
+		err := m.Create(ctx, &signupcode.SignupCode{Code: "DUPLICATE", Tenant: "other"})
+		var he *herodot.DefaultError
+		require.ErrorAs(t, err, &he)
+		assert.Equal(t, http.StatusConflict, he.StatusCode())
+	})
+
+	t.Run("case=fails to attach unknown codes", func(t *testing.T) {
+		_, err := m.AttachToFlow(ctx, newFlow(), "unknown")
+		var ve *schema.ValidationError
+		require.ErrorAs(t, err, &ve)
+		assert.Equal(t, "#/signup_code", ve.InstancePtr)
+	})
+
+	t.Run("case=requires a code if configured", func(t *testing.T) {
+		actual, err := m.FromFlow(ctx, newFlow())
+		require.NoError(t, err)
+		assert.Nil(t, actual)
+
+		conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationSignupCodesRequired, true)
+		conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationSignupCodesEnabled, true)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationSignupCodesRequired, false)
+			conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationSignupCodesEnabled, false)
+		})
+
+		_, err = m.FromFlow(ctx, newFlow())
+		var ve *schema.ValidationError
+		require.ErrorAs(t, err, &ve)
+		assert.Equal(t, "#/signup_code", ve.InstancePtr)
+	})
+
+	t.Run("case=assigns the tenant to the identity", func(t *testing.T) {
+		c := create(t, &signupcode.SignupCode{Code: "assign", Tenant: "acme", Group: "class-1"})
+
+		i := identity.NewIdentity("default")
+		i.MetadataAdmin = []byte(`{"plan":"pro"}`)
+		require.NoError(t, m.Assign(c, i))
+		assert.Equal(t, "acme", gjson.GetBytes(i.MetadataAdmin, "tenant").String())
+		assert.Equal(t, "class-1", gjson.GetBytes(i.MetadataAdmin, "group").String())
+		assert.Equal(t, "pro", gjson.GetBytes(i.MetadataAdmin, "plan").String())
+
+		i = identity.NewIdentity("default")
+		require.NoError(t, m.Assign(nil, i))
+		assert.Empty(t, i.MetadataAdmin)
+	})
+
+	t.Run("case=consumes the code at most max uses times", func(t *testing.T) {
+		c := create(t, &signupcode.SignupCode{Code: "limited", Tenant: "acme", MaxUses: 2})
+
+		require.NoError(t, m.Consume(ctx, c))
+		require.NoError(t, m.Consume(ctx, c))
+		var ve *schema.ValidationError
+		require.ErrorAs(t, m.Consume(ctx, c), &ve)
+
+		actual, err := reg.SignupCodePersister().GetSignupCode(ctx, c.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, actual.Uses)
+		assert.False(t, actual.IsValid())
+
+		_, err = m.AttachToFlow(ctx, newFlow(), c.Code)
+		require.ErrorAs(t, err, &ve)
+	})
+
+	t.Run("case=decodes the code and keeps the body", func(t *testing.T) {
+		r, _ := http.NewRequest("POST", "/", strings.NewReader(url.Values{"signup_code": {"acme-2023"}, "method": {"password"}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		code, err := signupcode.DecodeCode(r)
+		require.NoError(t, err)
+		assert.Equal(t, "acme-2023", code)
+
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "password", r.PostForm.Get("method"))
+	})
+
+	t.Run("case=deletes the code", func(t *testing.T) {
+		c := create(t, &signupcode.SignupCode{Code: "delete", Tenant: "acme"})
+
+		require.NoError(t, reg.SignupCodePersister().DeleteSignupCode(ctx, c.ID))
+		_, err := reg.SignupCodePersister().GetSignupCode(ctx, c.ID)
+		assert.ErrorIs(t, err, sqlcon.ErrNoRows)
+		assert.ErrorIs(t, reg.SignupCodePersister().DeleteSignupCode(ctx, c.ID), sqlcon.ErrNoRows)
+	})
+}
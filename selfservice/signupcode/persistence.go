@@ -0,0 +1,101 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package signupcode
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlxx"
+)
+
+// Sign-Up Code
+//
+// A sign-up code assigns identities which register with it to a tenant, for example the employer
+// or the class of the identity. Codes are shared by everyone who should join the tenant and are
+// case-insensitive.
+//
+// swagger:model signupCode
+type SignupCode struct {
+	// ID of the sign-up code
+	//
+	// required: true
+	ID uuid.UUID `json:"id" faker:"-" db:"id"`
+
+	// Code is entered in the registration form.
+	//
+	// required: true
+	Code string `json:"code" faker:"-" db:"code"`
+
+	// Tenant is stored as `tenant` in the admin metadata of identities which register with the code.
+	//
+	// required: true
+	Tenant string `json:"tenant" faker:"-" db:"tenant"`
+
+	// Group is stored as `group` in the admin metadata of identities which register with the code.
+	Group string `json:"group,omitempty" faker:"-" db:"group_name"`
+
+	// MaxUses limits how many identities can register with the code. Zero means unlimited.
+	MaxUses int `json:"max_uses" faker:"-" db:"max_uses"`
+
+	// Uses is the number of identities which registered with the code.
+	//
+	// required: true
+	Uses int `json:"uses" faker:"-" db:"uses"`
+
+	// ExpiresAt is the time at which the code expires. Codes without an expiry never expire.
+	ExpiresAt sqlxx.NullTime `json:"expires_at,omitempty" faker:"-" db:"expires_at"`
+
+	// CreatedAt is the time at which the code was created.
+	CreatedAt time.Time `json:"created_at" faker:"-" db:"created_at"`
+
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (c SignupCode) TableName(context.Context) string {
+	return "selfservice_signup_codes"
+}
+
+// IsValid returns true if the code did not expire and can still be used.
+func (c *SignupCode) IsValid() bool {
+	if c.MaxUses > 0 && c.Uses >= c.MaxUses {
+		return false
+	}
+	expiresAt := time.Time(c.ExpiresAt)
+	return expiresAt.IsZero() || expiresAt.After(x.Now())
+}
+
+type (
+	Persister interface {
+		// CreateSignupCode stores the sign-up code. It returns sqlcon.ErrUniqueViolation if the
+		// code exists already.
+		CreateSignupCode(ctx context.Context, c *SignupCode) error
+
+		// GetSignupCode returns the sign-up code with the given ID or sqlcon.ErrNoRows.
+		GetSignupCode(ctx context.Context, id uuid.UUID) (*SignupCode, error)
+
+		// GetSignupCodeByCode returns the sign-up code with the given normalized code or sqlcon.ErrNoRows.
+		GetSignupCodeByCode(ctx context.Context, code string) (*SignupCode, error)
+
+		// ListSignupCodes returns all sign-up codes ordered by their code.
+		ListSignupCodes(ctx context.Context) ([]SignupCode, error)
+
+		// UseSignupCode counts a use of the sign-up code. It returns sqlcon.ErrNoRows if the code
+		// does not exist or was used as often as allowed.
+		UseSignupCode(ctx context.Context, id uuid.UUID) error
+
+		// DeleteSignupCode deletes the sign-up code.
+		DeleteSignupCode(ctx context.Context, id uuid.UUID) error
+	}
+
+	PersistenceProvider interface {
+		SignupCodePersister() Persister
+	}
+)
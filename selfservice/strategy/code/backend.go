@@ -0,0 +1,270 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package code
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/request"
+	"github.com/ory/kratos/selfservice/flow"
+)
+
+const (
+	BackendTypeBuiltin      = "builtin"
+	BackendTypeHTTP         = "http"
+	BackendTypeTwilioVerify = "twilio_verify"
+)
+
+type (
+	// Backend generates and validates one-time codes.
+	//
+	// Codes are persisted and checked by Kratos regardless of the backend, so
+	// that expiry, submission limits and the flow states stay the same. An
+	// external backend is asked to generate the code and can additionally
+	// reject a code which Kratos would accept, for example because it was
+	// revoked in the external system.
+	Backend interface {
+		// GenerateCode returns a new code for the request.
+		GenerateCode(ctx context.Context, req *CodeRequest) (string, error)
+
+		// ValidateCode returns ErrCodeNotFound if the backend rejects the code.
+		ValidateCode(ctx context.Context, req *CodeRequest, code string) error
+	}
+
+	// DeliveringBackend is implemented by backends which send and check the
+	// codes of some flows themselves instead of the courier, such as Twilio
+	// Verify.
+	//
+	// Kratos never learns these codes. To keep expiry, submission limits and
+	// the flow states the same, GenerateCode returns a secret reference which
+	// is persisted in place of the code and which is only used once the
+	// backend approved the submitted code.
+	DeliveringBackend interface {
+		Backend
+
+		// DeliversCode returns true if the backend sends and checks the codes
+		// of the flow.
+		DeliversCode(flow flow.FlowName) bool
+
+		// SendCode asks the backend to send a code to the address of the request.
+		SendCode(ctx context.Context, req *CodeRequest, via identity.CodeAddressType) error
+
+		// CheckCode returns ErrCodeNotFound if the backend rejects the code for
+		// the address of the request.
+		CheckCode(ctx context.Context, req *CodeRequest, code string) error
+	}
+
+	BackendProvider interface {
+		CodeBackend(ctx context.Context) Backend
+	}
+
+	// CodeRequest describes what a code is generated or validated for.
+	CodeRequest struct {
+		// Flow is the name of the flow the code belongs to, e.g. "login".
+		Flow flow.FlowName `json:"flow"`
+
+		// FlowID is the ID of the flow the code belongs to.
+		FlowID uuid.UUID `json:"flow_id"`
+
+		// IdentityID is the ID of the identity the code is sent to. It is not
+		// set for registration flows.
+		IdentityID uuid.UUID `json:"identity_id,omitempty"`
+
+		// Address is the address the code is sent to. It is not set for codes
+		// created by an administrator.
+		Address string `json:"address,omitempty"`
+	}
+
+	builtinBackend struct{}
+
+	httpBackendDependencies interface {
+		request.Dependencies
+		config.Provider
+	}
+
+	httpBackend struct {
+		d httpBackendDependencies
+	}
+
+	httpBackendRequestBody struct {
+		Operation string       `json:"operation"`
+		Request   *CodeRequest `json:"request"`
+		Code      string       `json:"code,omitempty"`
+	}
+
+	httpBackendGenerateResponse struct {
+		Code string `json:"code"`
+	}
+)
+
+var (
+	_ Backend = new(builtinBackend)
+	_ Backend = new(httpBackend)
+)
+
+// NewBackend returns the code backend selected in the configuration.
+func NewBackend(ctx context.Context, d httpBackendDependencies) Backend {
+	switch d.Config().SelfServiceCodeMethodBackend(ctx) {
+	case BackendTypeHTTP:
+		return NewHTTPBackend(d)
+	case BackendTypeTwilioVerify:
+		return NewTwilioVerifyBackend(d)
+	default:
+		return NewBuiltinBackend()
+	}
+}
+
+// ValidateBackendConfiguration returns an error if the code backend selected in the configuration
+// lacks the settings it needs to send or check codes.
+func ValidateBackendConfiguration(ctx context.Context, c *config.Config) error {
+	switch c.SelfServiceCodeMethodBackend(ctx) {
+	case BackendTypeHTTP:
+		var rc struct {
+			URL string `json:"url"`
+		}
+		if err := json.Unmarshal(c.SelfServiceCodeMethodBackendRequestConfig(ctx), &rc); err != nil || rc.URL == "" {
+			return errors.New("the HTTP code backend requires a request URL")
+		}
+	case BackendTypeTwilioVerify:
+		tc := c.SelfServiceCodeMethodBackendTwilioVerify(ctx)
+		if tc.ServiceSID == "" || tc.AccountSID == "" || tc.AuthToken == "" {
+			return errors.New("the Twilio Verify code backend requires a service SID, an account SID, and an auth token")
+		}
+	}
+	return nil
+}
+
+// deliveredBy returns the backend if it sends and checks the codes of the flow itself.
+func deliveredBy(b Backend, flow flow.FlowName) (DeliveringBackend, bool) {
+	db, ok := b.(DeliveringBackend)
+	if !ok || !db.DeliversCode(flow) {
+		return nil, false
+	}
+	return db, true
+}
+
+// resolveSubmittedCode returns the code to look up in the persister. For
+// backends which deliver the codes of the flow themselves, the submitted code
+// is checked against each address the code may have been sent to, and the
+// reference persisted for the approved address is returned. For all other
+// backends, the submitted code is returned as is.
+func resolveSubmittedCode(ctx context.Context, b Backend, req *CodeRequest, submitted string, addresses ...string) (string, error) {
+	db, ok := deliveredBy(b, req.Flow)
+	if !ok {
+		return submitted, nil
+	}
+
+	for _, address := range addresses {
+		addressReq := *req
+		addressReq.Address = address
+		if err := db.CheckCode(ctx, &addressReq, submitted); errors.Is(err, ErrCodeNotFound) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		return db.GenerateCode(ctx, &addressReq)
+	}
+
+	return "", errors.WithStack(ErrCodeNotFound)
+}
+
+// validateWith returns a CodeValidator which asks the backend to validate the submitted code.
+func validateWith(b Backend, submitted string) CodeValidator {
+	return func(ctx context.Context, req *CodeRequest) error {
+		return b.ValidateCode(ctx, req, submitted)
+	}
+}
+
+// NewBuiltinBackend returns a backend which generates random numeric codes.
+func NewBuiltinBackend() Backend {
+	return &builtinBackend{}
+}
+
+func (b *builtinBackend) GenerateCode(context.Context, *CodeRequest) (string, error) {
+	return GenerateCode(), nil
+}
+
+func (b *builtinBackend) ValidateCode(context.Context, *CodeRequest, string) error {
+	// The code was already checked against the persisted hash.
+	return nil
+}
+
+// NewHTTPBackend returns a backend which asks an external service to
+// generate and validate codes.
+//
+// The service receives the operation ("generate" or "validate"), the code
+// request and, for validations, the submitted code. Generate calls must
+// respond with a JSON object containing the code. Validate calls must respond
+// with a 2xx status code if the code is valid and with a 4xx status code if
+// it is not.
+func NewHTTPBackend(d httpBackendDependencies) Backend {
+	return &httpBackend{d: d}
+}
+
+func (b *httpBackend) do(ctx context.Context, body *httpBackendRequestBody) (*http.Response, error) {
+	builder, err := request.NewBuilder(ctx, b.d.Config().SelfServiceCodeMethodBackendRequestConfig(ctx), b.d)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := builder.BuildRequest(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := b.d.HTTPClient(ctx).Do(r)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to reach the code backend.").WithWrap(err))
+	}
+	return res, nil
+}
+
+func (b *httpBackend) GenerateCode(ctx context.Context, req *CodeRequest) (string, error) {
+	res, err := b.do(ctx, &httpBackendRequestBody{Operation: "generate", Request: req})
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The code backend responded with unexpected status code %d.", res.StatusCode))
+	}
+
+	var body httpBackendGenerateResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1024*1024)).Decode(&body); err != nil {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to decode the code backend response.").WithWrap(err))
+	}
+
+	if body.Code == "" {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReason("The code backend did not return a code."))
+	}
+
+	return body.Code, nil
+}
+
+func (b *httpBackend) ValidateCode(ctx context.Context, req *CodeRequest, code string) error {
+	res, err := b.do(ctx, &httpBackendRequestBody{Operation: "validate", Request: req, Code: code})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return nil
+	case res.StatusCode >= 400 && res.StatusCode < 500:
+		return errors.WithStack(ErrCodeNotFound)
+	default:
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The code backend responded with unexpected status code %d.", res.StatusCode))
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package code_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/strategy/code"
+	"github.com/ory/kratos/x"
+)
+
+func TestBackend(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	req := &code.CodeRequest{
+		Flow:       flow.LoginFlow,
+		FlowID:     x.NewUUID(),
+		IdentityID: x.NewUUID(),
+		Address:    "foo@ory.sh",
+	}
+
+	t.Run("type=builtin", func(t *testing.T) {
+		b := reg.CodeBackend(ctx)
+
+		generated, err := b.GenerateCode(ctx, req)
+		require.NoError(t, err)
+		assert.Len(t, generated, code.CodeLength)
+		assert.NoError(t, b.ValidateCode(ctx, req, generated))
+	})
+
+	t.Run("type=http", func(t *testing.T) {
+		var status int
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Operation string            `json:"operation"`
+				Request   *code.CodeRequest `json:"request"`
+				Code      string            `json:"code"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, req.FlowID, body.Request.FlowID)
+			assert.Equal(t, req.Address, body.Request.Address)
+
+			if status != 0 {
+				w.WriteHeader(status)
+				return
+			}
+
+			switch body.Operation {
+			case "generate":
+				_ = json.NewEncoder(w).Encode(map[string]string{"code": "external-code"})
+			case "validate":
+				if body.Code != "external-code" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}))
+		t.Cleanup(ts.Close)
+
+		conf.MustSet(ctx, config.ViperKeyCodeBackendType, code.BackendTypeHTTP)
+		conf.MustSet(ctx, config.ViperKeyCodeBackendRequestConfig, map[string]interface{}{
+			"url":    ts.URL,
+			"method": "POST",
+			"body":   "base64://" + base64.StdEncoding.EncodeToString([]byte("function(ctx) ctx")),
+		})
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeyCodeBackendType, code.BackendTypeBuiltin)
+		})
+
+		b := reg.CodeBackend(ctx)
+
+		t.Run("case=generates and validates codes", func(t *testing.T) {
+			status = 0
+
+			generated, err := b.GenerateCode(ctx, req)
+			require.NoError(t, err)
+			assert.Equal(t, "external-code", generated)
+
+			require.NoError(t, b.ValidateCode(ctx, req, generated))
+			assert.True(t, errors.Is(b.ValidateCode(ctx, req, "wrong-code"), code.ErrCodeNotFound))
+		})
+
+		t.Run("case=fails on server errors", func(t *testing.T) {
+			status = http.StatusInternalServerError
+
+			_, err := b.GenerateCode(ctx, req)
+			require.Error(t, err)
+
+			err = b.ValidateCode(ctx, req, "external-code")
+			require.Error(t, err)
+			assert.False(t, errors.Is(err, code.ErrCodeNotFound))
+		})
+
+		t.Run("case=fails if no code is returned", func(t *testing.T) {
+			status = http.StatusOK
+
+			_, err := b.GenerateCode(ctx, req)
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("type=twilio_verify", func(t *testing.T) {
+		var sent []string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, r.ParseForm())
+			user, pass, ok := r.BasicAuth()
+			require.True(t, ok)
+			assert.Equal(t, "AC123", user)
+			assert.Equal(t, "secret", pass)
+
+			switch r.URL.Path {
+			case "/v2/Services/VA123/Verifications":
+				sent = append(sent, r.Form.Get("To")+":"+r.Form.Get("Channel"))
+				w.WriteHeader(http.StatusCreated)
+			case "/v2/Services/VA123/VerificationCheck":
+				if r.Form.Get("To") != req.Address {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				status := "pending"
+				if r.Form.Get("Code") == "123456" {
+					status = "approved"
+				}
+				_ = json.NewEncoder(w).Encode(map[string]string{"status": status})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		t.Cleanup(ts.Close)
+
+		conf.MustSet(ctx, config.ViperKeyCodeBackendType, code.BackendTypeTwilioVerify)
+		conf.MustSet(ctx, config.ViperKeyCodeBackendTwilioVerify, map[string]interface{}{
+			"url":         ts.URL,
+			"service_sid": "VA123",
+			"account_sid": "AC123",
+			"auth_token":  "secret",
+		})
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeyCodeBackendType, code.BackendTypeBuiltin)
+		})
+
+		b, ok := reg.CodeBackend(ctx).(code.DeliveringBackend)
+		require.True(t, ok)
+
+		t.Run("case=delivers login and registration codes only", func(t *testing.T) {
+			assert.True(t, b.DeliversCode(flow.LoginFlow))
+			assert.True(t, b.DeliversCode(flow.RegistrationFlow))
+			assert.False(t, b.DeliversCode(flow.RecoveryFlow))
+			assert.False(t, b.DeliversCode(flow.VerificationFlow))
+		})
+
+		t.Run("case=references are stable per address", func(t *testing.T) {
+			first, err := b.GenerateCode(ctx, req)
+			require.NoError(t, err)
+			second, err := b.GenerateCode(ctx, req)
+			require.NoError(t, err)
+			assert.Equal(t, first, second)
+
+			other := *req
+			other.Address = "bar@ory.sh"
+			third, err := b.GenerateCode(ctx, &other)
+			require.NoError(t, err)
+			assert.NotEqual(t, first, third)
+		})
+
+		t.Run("case=sends and checks codes", func(t *testing.T) {
+			require.NoError(t, b.SendCode(ctx, req, identity.CodeAddressTypePhone))
+			assert.Equal(t, []string{req.Address + ":sms"}, sent)
+
+			require.NoError(t, b.CheckCode(ctx, req, "123456"))
+			assert.True(t, errors.Is(b.CheckCode(ctx, req, "000000"), code.ErrCodeNotFound))
+
+			other := *req
+			other.Address = "bar@ory.sh"
+			assert.True(t, errors.Is(b.CheckCode(ctx, &other, "123456"), code.ErrCodeNotFound))
+		})
+	})
+}
@@ -0,0 +1,136 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package code
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/x/urlx"
+)
+
+type (
+	twilioVerifyBackend struct {
+		d httpBackendDependencies
+	}
+
+	twilioVerifyCheckResponse struct {
+		Status string `json:"status"`
+	}
+)
+
+var _ DeliveringBackend = new(twilioVerifyBackend)
+
+// NewTwilioVerifyBackend returns a backend which lets Twilio Verify, or an API
+// compatible with it, send and check the codes of login and registration
+// flows. Codes of all other flows are generated by Kratos and sent by the
+// courier.
+func NewTwilioVerifyBackend(d httpBackendDependencies) Backend {
+	return &twilioVerifyBackend{d: d}
+}
+
+func (b *twilioVerifyBackend) DeliversCode(f flow.FlowName) bool {
+	return f == flow.LoginFlow || f == flow.RegistrationFlow
+}
+
+func (b *twilioVerifyBackend) GenerateCode(ctx context.Context, req *CodeRequest) (string, error) {
+	if !b.DeliversCode(req.Flow) {
+		return GenerateCode(), nil
+	}
+
+	secrets := b.d.Config().SecretsDefault(ctx)
+	if len(secrets) == 0 {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReason("No secret is configured to derive code references."))
+	}
+
+	mac := hmac.New(sha256.New, secrets[0])
+	_, _ = mac.Write([]byte(strings.Join([]string{string(req.Flow), req.FlowID.String(), req.Address}, "\x00")))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (b *twilioVerifyBackend) ValidateCode(context.Context, *CodeRequest, string) error {
+	// Delivered codes were already checked by CheckCode and all other codes
+	// were checked against the persisted hash.
+	return nil
+}
+
+func (b *twilioVerifyBackend) SendCode(ctx context.Context, req *CodeRequest, via identity.CodeAddressType) error {
+	channel := "email"
+	if via == identity.CodeAddressTypePhone {
+		channel = "sms"
+	}
+
+	res, err := b.do(ctx, "Verifications", url.Values{"To": {req.Address}, "Channel": {channel}})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Twilio Verify responded with unexpected status code %d when sending the code.", res.StatusCode))
+	}
+	return nil
+}
+
+func (b *twilioVerifyBackend) CheckCode(ctx context.Context, req *CodeRequest, code string) error {
+	res, err := b.do(ctx, "VerificationCheck", url.Values{"To": {req.Address}, "Code": {code}})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		// There is no pending verification for the address, e.g. because the
+		// code was sent to another address or expired.
+		return errors.WithStack(ErrCodeNotFound)
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Twilio Verify responded with unexpected status code %d when checking the code.", res.StatusCode))
+	}
+
+	var body twilioVerifyCheckResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1024*1024)).Decode(&body); err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to decode the Twilio Verify response.").WithWrap(err))
+	}
+
+	if body.Status != "approved" {
+		return errors.WithStack(ErrCodeNotFound)
+	}
+	return nil
+}
+
+func (b *twilioVerifyBackend) do(ctx context.Context, resource string, values url.Values) (*http.Response, error) {
+	conf := b.d.Config().SelfServiceCodeMethodBackendTwilioVerify(ctx)
+	base, err := url.Parse(conf.URL)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The Twilio Verify URL is invalid.").WithWrap(err))
+	}
+
+	u := urlx.AppendPaths(base, "v2", "Services", url.PathEscape(conf.ServiceSID), resource)
+	r, err := retryablehttp.NewRequestWithContext(ctx, "POST", u.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.SetBasicAuth(conf.AccountSID, conf.AuthToken)
+
+	res, err := b.d.HTTPClient(ctx).Do(r)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to reach Twilio Verify.").WithWrap(err))
+	}
+	return res, nil
+}
@@ -13,6 +13,8 @@ import (
 
 	"github.com/ory/herodot"
 	"github.com/ory/kratos/courier/template/email"
+	"github.com/ory/kratos/courier/template/sms"
+	"github.com/ory/kratos/courier/template/whatsapp"
 
 	"github.com/ory/x/httpx"
 	"github.com/ory/x/sqlcon"
@@ -36,6 +38,7 @@ type (
 		identity.PoolProvider
 		identity.ManagementProvider
 		identity.PrivilegedPoolProvider
+		identity.ValidationProvider
 		x.LoggingProvider
 		config.Provider
 
@@ -43,6 +46,7 @@ type (
 		VerificationCodePersistenceProvider
 		RegistrationCodePersistenceProvider
 		LoginCodePersistenceProvider
+		BackendProvider
 
 		HTTPClient(ctx context.Context, opts ...httpx.ResilientOptions) *retryablehttp.Client
 	}
@@ -76,7 +80,15 @@ func (s *Sender) SendCode(ctx context.Context, f flow.Flow, id *identity.Identit
 		// address was used to verify the code.
 		//
 		// See also [this discussion](https://github.com/ory/kratos/pull/3456#discussion_r1307560988).
-		rawCode := GenerateCode()
+		req := &CodeRequest{Flow: f.GetFlowName(), FlowID: f.GetID(), Address: address.To}
+		if f.GetFlowName() == flow.LoginFlow {
+			req.IdentityID = id.ID
+		}
+
+		rawCode, err := s.deps.CodeBackend(ctx).GenerateCode(ctx, req)
+		if err != nil {
+			return err
+		}
 
 		switch f.GetFlowName() {
 		case flow.RegistrationFlow:
@@ -92,15 +104,25 @@ func (s *Sender) SendCode(ctx context.Context, f flow.Flow, id *identity.Identit
 			if err != nil {
 				return err
 			}
+
+			if delivered, err := s.deliver(ctx, req, address.Via); err != nil {
+				return err
+			} else if delivered {
+				continue
+			}
+
 			model, err := x.StructToMap(id.Traits)
 			if err != nil {
 				return err
 			}
 
+			locale := s.deps.IdentityValidator().Locale(ctx, id)
+
 			emailModel := email.RegistrationCodeValidModel{
 				To:               address.To,
 				RegistrationCode: rawCode,
 				Traits:           model,
+				Locale:           locale,
 			}
 
 			s.deps.Audit().
@@ -128,15 +150,37 @@ func (s *Sender) SendCode(ctx context.Context, f flow.Flow, id *identity.Identit
 				return err
 			}
 
+			if delivered, err := s.deliver(ctx, req, address.Via); err != nil {
+				return err
+			} else if delivered {
+				continue
+			}
+
 			model, err := x.StructToMap(id)
 			if err != nil {
 				return err
 			}
 
+			locale := s.deps.IdentityValidator().Locale(ctx, id)
+
+			if address.Via == identity.CodeAddressTypePhone {
+				s.deps.Audit().
+					WithField("login_flow_id", code.FlowID).
+					WithField("login_code_id", code.ID).
+					WithSensitiveField("login_code", rawCode).
+					Info("Sending out login code to phone number.")
+
+				if err := s.sendOTP(ctx, address.To, rawCode, model, locale); err != nil {
+					return errors.WithStack(err)
+				}
+				continue
+			}
+
 			emailModel := email.LoginCodeValidModel{
 				To:        address.To,
 				LoginCode: rawCode,
 				Identity:  model,
+				Locale:    locale,
 			}
 			s.deps.Audit().
 				WithField("login_flow_id", code.FlowID).
@@ -160,22 +204,24 @@ func (s *Sender) SendCode(ctx context.Context, f flow.Flow, id *identity.Identit
 //
 // If the address does not exist in the store and dispatching invalid emails is enabled (CourierEnableInvalidDispatch is
 // true), an email is still being sent to prevent account enumeration attacks. In that case, this function returns the
-// ErrUnknownAddress error.
+// ErrUnknownAddress error. Unknown phone numbers are never notified.
 func (s *Sender) SendRecoveryCode(ctx context.Context, f *recovery.Flow, via identity.VerifiableAddressType, to string) error {
 	s.deps.Logger().
 		WithField("via", via).
 		WithSensitiveField("address", to).
 		Debug("Preparing recovery code.")
 
-	address, err := s.deps.IdentityPool().FindRecoveryAddressByValue(ctx, identity.RecoveryAddressTypeEmail, to)
+	address, err := s.deps.IdentityPool().FindRecoveryAddressByValue(ctx, identity.RecoveryAddressType(via), to)
 	if errors.Is(err, sqlcon.ErrNoRows) {
-		notifyUnknownRecipients := s.deps.Config().SelfServiceFlowRecoveryNotifyUnknownRecipients(ctx)
-		s.deps.Audit().
-			WithField("via", via).
-			WithSensitiveField("email_address", address).
-			WithField("strategy", "code").
-			WithField("was_notified", notifyUnknownRecipients).
-			Info("Account recovery was requested for an unknown address.")
+		notifyUnknownRecipients := s.deps.Config().SelfServiceFlowRecoveryNotifyUnknownRecipients(ctx) && via == identity.VerifiableAddressTypeEmail
+		if s.deps.Config().SelfServiceFlowRecoveryUnknownAddressAudit(ctx) {
+			s.deps.Audit().
+				WithField("via", via).
+				WithSensitiveField("email_address", address).
+				WithField("strategy", "code").
+				WithField("was_notified", notifyUnknownRecipients).
+				Info("Account recovery was requested for an unknown address.")
+		}
 		if !notifyUnknownRecipients {
 			// do nothing
 		} else if err := s.send(ctx, string(via), email.NewRecoveryCodeInvalid(s.deps, &email.RecoveryCodeInvalidModel{To: to})); err != nil {
@@ -187,13 +233,37 @@ func (s *Sender) SendRecoveryCode(ctx context.Context, f *recovery.Flow, via ide
 		return err
 	}
 
+	return s.SendRecoveryCodeToAddress(ctx, f, address)
+}
+
+// SendRecoveryCodeToAddress creates a new recovery code for the given, already known, recovery address and sends
+// it out.
+func (s *Sender) SendRecoveryCodeToAddress(ctx context.Context, f *recovery.Flow, address *identity.RecoveryAddress) error {
 	// Get the identity associated with the recovery address
 	i, err := s.deps.IdentityPool().GetIdentity(ctx, address.IdentityID, identity.ExpandDefault)
 	if err != nil {
 		return err
 	}
 
-	rawCode := GenerateCode()
+	// Deactivated and deleted identities are treated like unknown addresses, so that their state is not revealed.
+	if i.State.IsBlocked() {
+		s.deps.Audit().
+			WithField("identity_id", i.ID).
+			WithField("identity_state", i.State).
+			WithField("strategy", "code").
+			Info("Account recovery was requested for an identity which can not be recovered.")
+		return errors.WithStack(ErrUnknownAddress)
+	}
+
+	rawCode, err := s.deps.CodeBackend(ctx).GenerateCode(ctx, &CodeRequest{
+		Flow:       flow.RecoveryFlow,
+		FlowID:     f.ID,
+		IdentityID: i.ID,
+		Address:    address.Value,
+	})
+	if err != nil {
+		return err
+	}
 
 	var code *RecoveryCode
 	if code, err = s.deps.
@@ -213,6 +283,25 @@ func (s *Sender) SendRecoveryCode(ctx context.Context, f *recovery.Flow, via ide
 }
 
 func (s *Sender) SendRecoveryCodeTo(ctx context.Context, i *identity.Identity, codeString string, code *RecoveryCode) error {
+	model, err := x.StructToMap(i)
+	if err != nil {
+		return err
+	}
+
+	locale := s.deps.IdentityValidator().Locale(ctx, i)
+
+	if code.RecoveryAddress.Via == identity.RecoveryAddressTypePhone {
+		s.deps.Audit().
+			WithField("via", code.RecoveryAddress.Via).
+			WithField("identity_id", code.RecoveryAddress.IdentityID).
+			WithField("recovery_code_id", code.ID).
+			WithSensitiveField("phone_number", code.RecoveryAddress.Value).
+			WithSensitiveField("recovery_code", codeString).
+			Info("Sending out recovery code to phone number.")
+
+		return s.sendOTP(ctx, code.RecoveryAddress.Value, codeString, model, locale)
+	}
+
 	s.deps.Audit().
 		WithField("via", code.RecoveryAddress.Via).
 		WithField("identity_id", code.RecoveryAddress.IdentityID).
@@ -221,15 +310,11 @@ func (s *Sender) SendRecoveryCodeTo(ctx context.Context, i *identity.Identity, c
 		WithSensitiveField("recovery_code", codeString).
 		Info("Sending out recovery email with recovery code.")
 
-	model, err := x.StructToMap(i)
-	if err != nil {
-		return err
-	}
-
 	emailModel := email.RecoveryCodeValidModel{
 		To:           code.RecoveryAddress.Value,
 		RecoveryCode: codeString,
 		Identity:     model,
+		Locale:       locale,
 	}
 
 	return s.send(ctx, string(code.RecoveryAddress.Via), email.NewRecoveryCodeValid(s.deps, &emailModel))
@@ -239,7 +324,7 @@ func (s *Sender) SendRecoveryCodeTo(ctx context.Context, i *identity.Identity, c
 //
 // If the address does not exist in the store and dispatching invalid emails is enabled (CourierEnableInvalidDispatch is
 // true), an email is still being sent to prevent account enumeration attacks. In that case, this function returns the
-// ErrUnknownAddress error.
+// ErrUnknownAddress error. Unknown phone numbers are never notified.
 func (s *Sender) SendVerificationCode(ctx context.Context, f *verification.Flow, via identity.VerifiableAddressType, to string) error {
 	s.deps.Logger().
 		WithField("via", via).
@@ -248,7 +333,7 @@ func (s *Sender) SendVerificationCode(ctx context.Context, f *verification.Flow,
 
 	address, err := s.deps.IdentityPool().FindVerifiableAddressByValue(ctx, via, to)
 	if errors.Is(err, sqlcon.ErrNoRows) {
-		notifyUnknownRecipients := s.deps.Config().SelfServiceFlowVerificationNotifyUnknownRecipients(ctx)
+		notifyUnknownRecipients := s.deps.Config().SelfServiceFlowVerificationNotifyUnknownRecipients(ctx) && via == identity.VerifiableAddressTypeEmail
 		s.deps.Audit().
 			WithField("via", via).
 			WithField("strategy", "code").
@@ -266,7 +351,16 @@ func (s *Sender) SendVerificationCode(ctx context.Context, f *verification.Flow,
 		return err
 	}
 
-	rawCode := GenerateCode()
+	rawCode, err := s.deps.CodeBackend(ctx).GenerateCode(ctx, &CodeRequest{
+		Flow:       flow.VerificationFlow,
+		FlowID:     f.ID,
+		IdentityID: address.IdentityID,
+		Address:    address.Value,
+	})
+	if err != nil {
+		return err
+	}
+
 	var code *VerificationCode
 	if code, err = s.deps.VerificationCodePersister().CreateVerificationCode(ctx, &CreateVerificationCodeParams{
 		RawCode:           rawCode,
@@ -299,6 +393,10 @@ func (s *Sender) constructVerificationLink(ctx context.Context, fID uuid.UUID, c
 }
 
 func (s *Sender) SendVerificationCodeTo(ctx context.Context, f *verification.Flow, i *identity.Identity, codeString string, code *VerificationCode) error {
+	if code.VerifiableAddress.Via == identity.VerifiableAddressTypePhone {
+		return s.sendVerificationOTP(ctx, i, codeString, code)
+	}
+
 	s.deps.Audit().
 		WithField("via", code.VerifiableAddress.Via).
 		WithField("identity_id", i.ID).
@@ -312,12 +410,15 @@ func (s *Sender) SendVerificationCodeTo(ctx context.Context, f *verification.Flo
 		return err
 	}
 
+	locale := s.deps.IdentityValidator().Locale(ctx, i)
+
 	if err := s.send(ctx, string(code.VerifiableAddress.Via), email.NewVerificationCodeValid(s.deps,
 		&email.VerificationCodeValidModel{
 			To:               code.VerifiableAddress.Value,
 			VerificationURL:  s.constructVerificationLink(ctx, f.ID, codeString),
 			Identity:         model,
 			VerificationCode: codeString,
+			Locale:           locale,
 		})); err != nil {
 		return err
 	}
@@ -325,6 +426,61 @@ func (s *Sender) SendVerificationCodeTo(ctx context.Context, f *verification.Flo
 	return s.deps.PrivilegedIdentityPool().UpdateVerifiableAddress(ctx, code.VerifiableAddress)
 }
 
+// sendVerificationOTP sends the verification code to the phone number of the verifiable address.
+func (s *Sender) sendVerificationOTP(ctx context.Context, i *identity.Identity, codeString string, code *VerificationCode) error {
+	s.deps.Audit().
+		WithField("via", code.VerifiableAddress.Via).
+		WithField("identity_id", i.ID).
+		WithField("verification_code_id", code.ID).
+		WithSensitiveField("phone_number", code.VerifiableAddress.Value).
+		WithSensitiveField("verification_code", codeString).
+		Info("Sending out verification code to phone number.")
+
+	model, err := x.StructToMap(i)
+	if err != nil {
+		return err
+	}
+
+	locale := s.deps.IdentityValidator().Locale(ctx, i)
+
+	if err := s.sendOTP(ctx, code.VerifiableAddress.Value, codeString, model, locale); err != nil {
+		return err
+	}
+	code.VerifiableAddress.Status = identity.VerifiableAddressStatusSent
+	return s.deps.PrivilegedIdentityPool().UpdateVerifiableAddress(ctx, code.VerifiableAddress)
+}
+
+// UseRegistrationCode marks the registration code submitted for one of the
+// addresses as used and returns it. It returns ErrCodeNotFound if the code is
+// wrong, expired or was already used.
+func (s *Sender) UseRegistrationCode(ctx context.Context, flowID uuid.UUID, submitted string, addresses ...string) (*RegistrationCode, error) {
+	resolved, err := resolveSubmittedCode(ctx, s.deps.CodeBackend(ctx), &CodeRequest{
+		Flow:   flow.RegistrationFlow,
+		FlowID: flowID,
+	}, submitted, addresses...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.deps.RegistrationCodePersister().UseRegistrationCode(ctx, flowID, resolved, validateWith(s.deps.CodeBackend(ctx), submitted), addresses...)
+}
+
+// deliver lets the code backend send the code if it delivers the codes of the flow itself.
+func (s *Sender) deliver(ctx context.Context, req *CodeRequest, via identity.CodeAddressType) (bool, error) {
+	b, ok := deliveredBy(s.deps.CodeBackend(ctx), req.Flow)
+	if !ok {
+		return false, nil
+	}
+
+	s.deps.Audit().
+		WithField("flow", req.Flow).
+		WithField("flow_id", req.FlowID).
+		WithSensitiveField("address", req.Address).
+		Info("Asking the code backend to send the code.")
+
+	return true, b.SendCode(ctx, req, via)
+}
+
 func (s *Sender) send(ctx context.Context, via string, t courier.EmailTemplate) error {
 	switch f := stringsx.SwitchExact(via); {
 	case f.AddCase(identity.AddressTypeEmail):
@@ -339,3 +495,30 @@ func (s *Sender) send(ctx context.Context, via string, t courier.EmailTemplate)
 		return f.ToUnknownCaseErr()
 	}
 }
+
+// sendOTP sends the code to the phone number via WhatsApp if the channel is enabled and via SMS
+// otherwise.
+func (s *Sender) sendOTP(ctx context.Context, to, code string, model map[string]interface{}, locale string) error {
+	c, err := s.deps.Courier(ctx)
+	if err != nil {
+		return err
+	}
+
+	if s.deps.Config().CourierWhatsAppEnabled(ctx) {
+		_, err = c.QueueWhatsApp(ctx, whatsapp.NewOTPMessage(s.deps, &whatsapp.OTPMessageModel{
+			To:       to,
+			Code:     code,
+			Identity: model,
+			Locale:   locale,
+		}))
+		return err
+	}
+
+	_, err = c.QueueSMS(ctx, sms.NewOTPMessage(s.deps, &sms.OTPMessageModel{
+		To:       to,
+		Code:     code,
+		Identity: model,
+		Locale:   locale,
+	}))
+	return err
+}
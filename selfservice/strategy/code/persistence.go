@@ -10,9 +10,14 @@ import (
 )
 
 type (
+	// CodeValidator is called with the request of the persisted code which matched the submitted code, before the
+	// code is marked as used. The code is only marked as used if the validator returns no error. A nil validator
+	// accepts all codes.
+	CodeValidator func(ctx context.Context, req *CodeRequest) error
+
 	RecoveryCodePersister interface {
 		CreateRecoveryCode(ctx context.Context, dto *CreateRecoveryCodeParams) (*RecoveryCode, error)
-		UseRecoveryCode(ctx context.Context, fID uuid.UUID, code string) (*RecoveryCode, error)
+		UseRecoveryCode(ctx context.Context, fID uuid.UUID, code string, validate CodeValidator) (*RecoveryCode, error)
 		DeleteRecoveryCodesOfFlow(ctx context.Context, fID uuid.UUID) error
 	}
 
@@ -22,7 +27,7 @@ type (
 
 	VerificationCodePersister interface {
 		CreateVerificationCode(context.Context, *CreateVerificationCodeParams) (*VerificationCode, error)
-		UseVerificationCode(context.Context, uuid.UUID, string) (*VerificationCode, error)
+		UseVerificationCode(ctx context.Context, fID uuid.UUID, code string, validate CodeValidator) (*VerificationCode, error)
 		DeleteVerificationCodesOfFlow(context.Context, uuid.UUID) error
 	}
 
@@ -36,7 +41,7 @@ type (
 
 	RegistrationCodePersister interface {
 		CreateRegistrationCode(context.Context, *CreateRegistrationCodeParams) (*RegistrationCode, error)
-		UseRegistrationCode(ctx context.Context, flowID uuid.UUID, code string, addresses ...string) (*RegistrationCode, error)
+		UseRegistrationCode(ctx context.Context, flowID uuid.UUID, code string, validate CodeValidator, addresses ...string) (*RegistrationCode, error)
 		DeleteRegistrationCodesOfFlow(ctx context.Context, flowID uuid.UUID) error
 		GetUsedRegistrationCode(ctx context.Context, flowID uuid.UUID) (*RegistrationCode, error)
 	}
@@ -47,7 +52,7 @@ type (
 
 	LoginCodePersister interface {
 		CreateLoginCode(context.Context, *CreateLoginCodeParams) (*LoginCode, error)
-		UseLoginCode(ctx context.Context, flowID uuid.UUID, identityID uuid.UUID, code string) (*LoginCode, error)
+		UseLoginCode(ctx context.Context, flowID uuid.UUID, identityID uuid.UUID, code string, validate CodeValidator) (*LoginCode, error)
 		DeleteLoginCodesOfFlow(ctx context.Context, flowID uuid.UUID) error
 		GetUsedLoginCode(ctx context.Context, flowID uuid.UUID) (*LoginCode, error)
 	}
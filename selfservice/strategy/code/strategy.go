@@ -4,6 +4,7 @@
 package code
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
@@ -22,6 +23,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/lockout"
 	"github.com/ory/kratos/selfservice/sessiontokenexchange"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/text"
@@ -83,6 +85,7 @@ type (
 		recovery.FlowPersistenceProvider
 		recovery.StrategyProvider
 		recovery.HookExecutorProvider
+		recovery.NotifierProvider
 
 		verification.FlowPersistenceProvider
 		verification.StrategyProvider
@@ -100,12 +103,15 @@ type (
 
 		RegistrationCodePersistenceProvider
 		LoginCodePersistenceProvider
+		BackendProvider
 
 		schema.IdentityTraitsProvider
 
 		sessiontokenexchange.PersistenceProvider
 
 		continuity.ManagementProvider
+
+		lockout.ManagementProvider
 	}
 
 	Strategy struct {
@@ -130,6 +136,11 @@ func (s *Strategy) NodeGroup() node.UiNodeGroup {
 	return node.CodeGroup
 }
 
+// ValidateConfiguration returns an error if the configured code backend can not be used.
+func (s *Strategy) ValidateConfiguration(ctx context.Context) error {
+	return ValidateBackendConfiguration(ctx, s.deps.Config())
+}
+
 func (s *Strategy) PopulateMethod(r *http.Request, f flow.Flow) error {
 	if string(f.GetState()) == "" {
 		f.SetState(flow.StateChooseMethod)
@@ -8,19 +8,24 @@ import (
 	"database/sql"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/ory/x/sqlcon"
 
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 
 	"github.com/ory/herodot"
 	"github.com/ory/x/otelx"
+	"github.com/ory/x/stringsx"
 
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/lockout"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/ui/node"
@@ -28,7 +33,10 @@ import (
 	"github.com/ory/x/decoderx"
 )
 
-var _ login.Strategy = new(Strategy)
+var (
+	_ login.Strategy                    = new(Strategy)
+	_ login.LinkingConfirmationStrategy = new(Strategy)
+)
 
 // Update Login flow using the code method
 //
@@ -57,8 +65,20 @@ type updateLoginFlowWithCodeMethod struct {
 	// Resend is set when the user wants to resend the code
 	// required: false
 	Resend string `json:"resend" form:"resend"`
+
+	// Channel is the channel the code should be sent through
+	//
+	// Only used if the identity has both a verified email address and a verified phone number.
+	// Allowed values are `email` and `phone`.
+	//
+	// required: false
+	Channel string `json:"channel" form:"channel"`
 }
 
+// loginChannelMetadataKey is the key in the identity's admin metadata which holds the
+// channel the identity prefers to receive login codes through.
+const loginChannelMetadataKey = "code_login_channel"
+
 func (s *Strategy) RegisterLoginRoutes(*x.RouterPublic) {}
 
 func (s *Strategy) CompletedAuthenticationMethod(ctx context.Context) session.AuthenticationMethod {
@@ -96,6 +116,27 @@ func (s *Strategy) PopulateLoginMethod(r *http.Request, requestedAAL identity.Au
 	return s.PopulateMethod(r, lf)
 }
 
+// PopulateLinkingConfirmationMethod offers to confirm account linking with a code if the provider of the
+// new credentials verified the email address of the existing account. Passwordless login offers codes
+// on its own.
+func (s *Strategy) PopulateLinkingConfirmationMethod(r *http.Request, f *login.Flow) error {
+	if s.deps.Config().SelfServiceCodeStrategy(r.Context()).PasswordlessEnabled || !s.confirmsAccountLinking(r.Context(), f) {
+		return nil
+	}
+	return s.PopulateMethod(r, f)
+}
+
+// confirmsAccountLinking returns true if the login flow links new credentials to an account whose email
+// address was verified by the provider of the new credentials.
+func (s *Strategy) confirmsAccountLinking(ctx context.Context, f *login.Flow) bool {
+	if !s.deps.Config().SelfServiceCodeStrategy(ctx).Enabled {
+		return false
+	}
+
+	dc, err := flow.DuplicateCredentials(f)
+	return err == nil && dc != nil && dc.DuplicateIdentifierVerified
+}
+
 // findIdentityByIdentifier returns the identity and the code credential for the given identifier.
 // If the identity does not have a code credential, it will attempt to find
 // the identity through other credentials matching the identifier.
@@ -110,7 +151,7 @@ func (s *Strategy) findIdentityByIdentifier(ctx context.Context, identifier stri
 		// we might be able to do a fallback login since we could not find a credential on this identifier
 		// Case insensitive because we only care about emails.
 		id, err := s.deps.PrivilegedIdentityPool().FindIdentityByCredentialIdentifier(ctx, identifier, false)
-		if err != nil {
+		if err != nil || id.State.IsBlocked() {
 			return nil, false, errors.WithStack(schema.NewNoCodeAuthnCredentials())
 		}
 
@@ -121,7 +162,8 @@ func (s *Strategy) findIdentityByIdentifier(ctx context.Context, identifier stri
 		return nil, false, errors.WithStack(schema.NewNoCodeAuthnCredentials())
 	}
 
-	if len(cred.Identifiers) == 0 {
+	// Deactivated and deleted identities can not sign in, so no code is sent to them.
+	if len(cred.Identifiers) == 0 || id.State.IsBlocked() {
 		return nil, false, errors.WithStack(schema.NewNoCodeAuthnCredentials())
 	}
 
@@ -134,7 +176,10 @@ func (s *Strategy) Login(w http.ResponseWriter, r *http.Request, f *login.Flow,
 	defer otelx.End(span, &err)
 
 	if err := flow.MethodEnabledAndAllowedFromRequest(r, f.GetFlowName(), s.ID().String(), s.deps); err != nil {
-		return nil, err
+		// Codes confirm account linking even if passwordless login is disabled.
+		if errors.Is(err, flow.ErrStrategyNotResponsible) || !s.confirmsAccountLinking(ctx, f) {
+			return nil, err
+		}
 	}
 
 	if err := login.CheckAAL(f, identity.AuthenticatorAssuranceLevel1); err != nil {
@@ -187,9 +232,14 @@ func (s *Strategy) loginSendEmail(ctx context.Context, w http.ResponseWriter, r
 
 	p.Identifier = maybeNormalizeEmail(p.Identifier)
 
+	// If account enumeration is mitigated, unknown identifiers get the same response as known ones
+	// but no code is sent.
+	start := time.Now()
+	mitigate := s.deps.Config().SelfServiceAccountEnumerationMitigate(ctx)
+
 	// Step 1: Get the identity
 	i, _, err := s.findIdentityByIdentifier(ctx, p.Identifier)
-	if err != nil {
+	if err != nil && !mitigate {
 		return err
 	}
 
@@ -203,10 +253,32 @@ func (s *Strategy) loginSendEmail(ctx context.Context, w http.ResponseWriter, r
 		Via: identity.CodeAddressType(identity.AddressTypeEmail),
 	}}
 
+	// If the identity can receive codes through more than one channel, the user decides which one to use.
+	// Offering the choice would reveal that the identifier belongs to an account, so codes are always sent
+	// to the identifier if account enumeration is mitigated.
+	var channels map[identity.CodeAddressType]string
+	var channel identity.CodeAddressType
+	if i != nil && !mitigate {
+		channels = loginChannels(i)
+		channel = identity.CodeAddressType(stringsx.Coalesce(p.Channel, preferredLoginChannel(i)))
+	}
+	if len(channels) > 1 {
+		to, ok := channels[channel]
+		if !ok {
+			return s.loginChooseChannel(ctx, w, r, f, p, channels)
+		}
+
+		if channel == identity.CodeAddressTypePhone {
+			addresses = []Address{{To: to, Via: channel}}
+		}
+	}
+
 	// kratos only supports `email` identifiers at the moment with the code method
 	// this is validated in the identity validation step above
-	if err := s.deps.CodeSender().SendCode(ctx, f, i, addresses...); err != nil {
-		return errors.WithStack(err)
+	if i != nil {
+		if err := s.deps.CodeSender().SendCode(ctx, f, i, addresses...); err != nil {
+			return errors.WithStack(err)
+		}
 	}
 
 	// sets the flow state to code sent
@@ -216,11 +288,23 @@ func (s *Strategy) loginSendEmail(ctx context.Context, w http.ResponseWriter, r
 		return err
 	}
 
+	if len(channels) > 1 {
+		// keep the channel so that resending the code does not ask for it again
+		f.UI.Nodes.Upsert(node.NewInputField("channel", channel, node.DefaultGroup, node.InputAttributeTypeHidden))
+		if channel == identity.CodeAddressTypePhone {
+			f.UI.Messages.Set(text.NewLoginSMSWithCodeSent())
+		}
+	}
+
 	f.Active = identity.CredentialsTypeCodeAuth
 	if err = s.deps.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
 		return err
 	}
 
+	if mitigate {
+		x.WaitAtLeast(ctx, start, s.deps.Config().SelfServiceAccountEnumerationResponseTime(ctx))
+	}
+
 	if x.IsJSONRequest(r) {
 		s.deps.Writer().WriteCode(w, r, http.StatusBadRequest, f)
 	} else {
@@ -232,6 +316,70 @@ func (s *Strategy) loginSendEmail(ctx context.Context, w http.ResponseWriter, r
 	return errors.WithStack(flow.ErrCompletedByStrategy)
 }
 
+// loginChooseChannel asks the user through which channel the login code should be sent.
+func (s *Strategy) loginChooseChannel(ctx context.Context, w http.ResponseWriter, r *http.Request, f *login.Flow, p *updateLoginFlowWithCodeMethod, channels map[identity.CodeAddressType]string) error {
+	nodes := node.Nodes{
+		node.NewInputField("identifier", p.Identifier, node.DefaultGroup, node.InputAttributeTypeHidden),
+		node.NewInputField("method", s.ID(), node.CodeGroup, node.InputAttributeTypeHidden),
+	}
+	for _, channel := range []identity.CodeAddressType{identity.CodeAddressTypeEmail, identity.CodeAddressTypePhone} {
+		nodes = append(nodes, node.NewInputField("channel", channel, node.CodeGroup, node.InputAttributeTypeSubmit).
+			WithMetaLabel(text.NewInfoNodeLabelCodeChannel(string(channel), x.MaskAddress(channels[channel]))))
+	}
+
+	f.UI.Nodes = nodes
+	f.UI.SetCSRF(s.deps.GenerateCSRFToken(r))
+	f.UI.Messages.Set(text.NewInfoSelfServiceLoginCodeChooseChannel())
+
+	f.Active = identity.CredentialsTypeCodeAuth
+	if err := s.deps.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
+		return err
+	}
+
+	if x.IsJSONRequest(r) {
+		s.deps.Writer().WriteCode(w, r, http.StatusBadRequest, f)
+	} else {
+		http.Redirect(w, r, f.AppendTo(s.deps.Config().SelfServiceFlowLoginUI(ctx)).String(), http.StatusSeeOther)
+	}
+
+	return errors.WithStack(flow.ErrCompletedByStrategy)
+}
+
+// loginChannels returns the first verified email address and phone number of the identity, keyed by channel.
+func loginChannels(i *identity.Identity) map[identity.CodeAddressType]string {
+	channels := make(map[identity.CodeAddressType]string)
+	for _, va := range i.VerifiableAddresses {
+		channel := identity.CodeAddressType(va.Via)
+		if !va.Verified || (channel != identity.CodeAddressTypeEmail && channel != identity.CodeAddressTypePhone) {
+			continue
+		}
+		if _, ok := channels[channel]; !ok {
+			channels[channel] = va.Value
+		}
+	}
+	return channels
+}
+
+// preferredLoginChannel returns the channel stored in the identity's admin metadata.
+func preferredLoginChannel(i *identity.Identity) string {
+	return gjson.GetBytes(i.MetadataAdmin, loginChannelMetadataKey).String()
+}
+
+// rememberLoginChannel stores the channel in the identity's admin metadata, which unlike the public metadata is not
+// exposed to the identity.
+func rememberLoginChannel(i *identity.Identity, channel identity.CodeAddressType) error {
+	metadata := []byte(i.MetadataAdmin)
+	if !gjson.ValidBytes(metadata) || !gjson.ParseBytes(metadata).IsObject() {
+		metadata = []byte("{}")
+	}
+	metadata, err := sjson.SetBytes(metadata, loginChannelMetadataKey, string(channel))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	i.MetadataAdmin = metadata
+	return nil
+}
+
 // If identifier is an email, we lower case it because on mobile phones the first letter sometimes is capitalized.
 func maybeNormalizeEmail(input string) string {
 	if strings.Contains(input, "@") {
@@ -256,26 +404,77 @@ func (s *Strategy) loginVerifyCode(ctx context.Context, r *http.Request, f *logi
 
 	p.Identifier = maybeNormalizeEmail(p.Identifier)
 
+	mitigate := s.deps.Config().SelfServiceAccountEnumerationMitigate(ctx)
+	if mitigate {
+		defer x.WaitAtLeast(ctx, time.Now(), s.deps.Config().SelfServiceAccountEnumerationResponseTime(ctx))
+	}
+
+	// The IP address is checked first so that its lock does not depend on whether the identifier is known.
+	ip := x.TrustedClientIP(r, s.deps.Config().PublicTrustedProxies(ctx))
+	if err := s.deps.LockoutManager().Check(ctx, uuid.Nil, ip); err != nil {
+		return nil, err
+	}
+
 	// Step 1: Get the identity
 	i, isFallback, err := s.findIdentityByIdentifier(ctx, p.Identifier)
 	if err != nil {
+		if mitigate {
+			// No code was sent for unknown identifiers, so any code is invalid.
+			if err := s.deps.LockoutManager().RecordFailure(ctx, uuid.Nil, ip); err != nil {
+				return nil, err
+			}
+			return nil, errors.WithStack(schema.NewLoginCodeInvalid())
+		}
 		return nil, err
 	}
 
-	loginCode, err := s.deps.LoginCodePersister().UseLoginCode(ctx, f.ID, i.ID, p.Code)
+	if err := s.deps.LockoutManager().Check(ctx, i.ID, ""); errors.Is(err, lockout.ErrIdentityLocked) {
+		// Answer as if the code was wrong to not reveal that the account exists. The attempt
+		// counts for the IP address like attempts with unknown identifiers.
+		if err := s.deps.LockoutManager().RecordFailure(ctx, uuid.Nil, ip); err != nil {
+			return nil, err
+		}
+		return nil, schema.NewLoginCodeInvalid()
+	} else if err != nil {
+		return nil, err
+	}
+
+	candidates := []string{p.Identifier}
+	for _, to := range loginChannels(i) {
+		candidates = append(candidates, to)
+	}
+
+	submitted, err := resolveSubmittedCode(ctx, s.deps.CodeBackend(ctx), &CodeRequest{
+		Flow:       flow.LoginFlow,
+		FlowID:     f.ID,
+		IdentityID: i.ID,
+	}, p.Code, candidates...)
+	var loginCode *LoginCode
+	if err == nil {
+		loginCode, err = s.deps.LoginCodePersister().UseLoginCode(ctx, f.ID, i.ID, submitted, validateWith(s.deps.CodeBackend(ctx), p.Code))
+	}
 	if err != nil {
 		if errors.Is(err, ErrCodeNotFound) {
+			if err := s.deps.LockoutManager().RecordFailure(ctx, i.ID, ip); err != nil {
+				return nil, err
+			}
 			return nil, schema.NewLoginCodeInvalid()
 		}
 		return nil, errors.WithStack(err)
 	}
 
-	i, err = s.deps.PrivilegedIdentityPool().GetIdentity(ctx, loginCode.IdentityID, identity.ExpandDefault)
+	if err := s.deps.LockoutManager().RecordSuccess(ctx, i.ID); err != nil {
+		return nil, err
+	}
+
+	i, err = s.deps.PrivilegedIdentityPool().GetIdentityConfidential(ctx, loginCode.IdentityID)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	updatedAt := i.UpdatedAt
 
 	// the code is correct, if the login happened through a different credential, we need to update the identity
+	updateIdentity := false
 	if isFallback {
 		if err := i.SetCredentialsWithConfig(
 			s.ID(),
@@ -285,8 +484,24 @@ func (s *Strategy) loginVerifyCode(ctx context.Context, r *http.Request, f *logi
 		); err != nil {
 			return nil, errors.WithStack(err)
 		}
+		updateIdentity = true
+	}
+
+	// remember the channel the code was received through if the identity could choose between several
+	if len(loginChannels(i)) > 1 && preferredLoginChannel(i) != string(loginCode.AddressType) {
+		if err := rememberLoginChannel(i, loginCode.AddressType); err != nil {
+			return nil, err
+		}
+		updateIdentity = true
+	}
 
-		if err := s.deps.PrivilegedIdentityPool().UpdateIdentity(ctx, i); err != nil {
+	if updateIdentity {
+		if err := s.deps.IdentityManager().Update(ctx, i,
+			identity.ManagerAllowWriteProtectedTraits,
+			identity.ManagerRequireUnmodifiedSince(updatedAt),
+		); errors.Is(err, identity.ErrIdentityModified) && !isFallback {
+			// the preference is remembered on the next login instead of failing this one
+		} else if err != nil {
 			return nil, errors.WithStack(err)
 		}
 	}
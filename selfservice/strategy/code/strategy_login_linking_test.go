@@ -0,0 +1,79 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package code_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/x/urlx"
+)
+
+func TestLoginCodeLinkingConfirmation(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(ctx, fmt.Sprintf("%s.%s.enabled", config.ViperKeySelfServiceStrategyConfig, identity.CredentialsTypeCodeAuth), true)
+	conf.MustSet(ctx, fmt.Sprintf("%s.%s.passwordless_enabled", config.ViperKeySelfServiceStrategyConfig, identity.CredentialsTypeCodeAuth), false)
+
+	s, err := reg.AllLoginStrategies().Strategy(identity.CredentialsTypeCodeAuth)
+	require.NoError(t, err)
+	lc, ok := s.(login.LinkingConfirmationStrategy)
+	require.True(t, ok)
+
+	r := &http.Request{URL: urlx.ParseOrPanic("https://www.ory.sh/")}
+	newFlow := func(t *testing.T, verified bool) *login.Flow {
+		f, err := login.NewFlow(conf, time.Hour, "", r, flow.TypeBrowser)
+		require.NoError(t, err)
+		require.NoError(t, flow.SetDuplicateCredentials(f, flow.DuplicateCredentialsData{
+			CredentialsType:             identity.CredentialsTypeOIDC,
+			DuplicateIdentifier:         "linking@ory.sh",
+			DuplicateIdentifierVerified: verified,
+		}))
+		return f
+	}
+
+	hasCodeMethod := func(f *login.Flow) bool {
+		for _, n := range f.UI.Nodes {
+			if n.Group == node.CodeGroup && n.ID() == "method" {
+				return true
+			}
+		}
+		return false
+	}
+
+	t.Run("case=offers a code if the provider verified the address", func(t *testing.T) {
+		f := newFlow(t, true)
+		require.NoError(t, lc.PopulateLinkingConfirmationMethod(r, f))
+		assert.True(t, hasCodeMethod(f))
+	})
+
+	t.Run("case=does not offer a code if the address is not verified", func(t *testing.T) {
+		f := newFlow(t, false)
+		require.NoError(t, lc.PopulateLinkingConfirmationMethod(r, f))
+		assert.False(t, hasCodeMethod(f))
+	})
+
+	t.Run("case=does not offer a code if the code method is disabled", func(t *testing.T) {
+		conf.MustSet(ctx, fmt.Sprintf("%s.%s.enabled", config.ViperKeySelfServiceStrategyConfig, identity.CredentialsTypeCodeAuth), false)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, fmt.Sprintf("%s.%s.enabled", config.ViperKeySelfServiceStrategyConfig, identity.CredentialsTypeCodeAuth), true)
+		})
+
+		f := newFlow(t, true)
+		require.NoError(t, lc.PopulateLinkingConfirmationMethod(r, f))
+		assert.False(t, hasCodeMethod(f))
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"testing"
 
+	"github.com/ory/x/randx"
 	"github.com/ory/x/sqlcon"
 	"github.com/ory/x/stringsx"
 
@@ -25,6 +26,7 @@ import (
 	oryClient "github.com/ory/kratos/internal/httpclient"
 	"github.com/ory/kratos/internal/testhelpers"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 	"github.com/ory/x/sqlxx"
 )
@@ -246,6 +248,55 @@ func TestLoginCodeStrategy(t *testing.T) {
 				}, true, nil)
 			})
 
+			t.Run("case=should let the user choose the channel if the identity has a verified phone number", func(t *testing.T) {
+				s := createLoginFlow(ctx, t, public, tc.apiType, false)
+
+				phone := "+4915" + randx.MustString(9, randx.Numeric)
+				for k := range s.identity.VerifiableAddresses {
+					s.identity.VerifiableAddresses[k].Via = identity.VerifiableAddressTypeEmail
+				}
+				s.identity.VerifiableAddresses = append(s.identity.VerifiableAddresses, identity.VerifiableAddress{
+					Value:    phone,
+					Via:      identity.VerifiableAddressTypePhone,
+					Verified: true,
+					Status:   identity.VerifiableAddressStatusCompleted,
+				})
+				require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentity(ctx, s.identity))
+
+				// submit email
+				s = submitLogin(ctx, t, s, tc.apiType, func(v *url.Values) {
+					v.Set("identifier", s.identityEmail)
+				}, false, func(t *testing.T, s *state, body string, res *http.Response) {
+					assert.EqualValues(t, text.InfoSelfServiceLoginCodeChooseChannel, gjson.Get(body, "ui.messages.0.id").Int(), "%s", body)
+					assert.Len(t, gjson.Get(body, "ui.nodes.#(attributes.name==channel)#").Array(), 2, "%s", body)
+					assert.Equal(t, x.MaskAddress(phone), gjson.Get(body, "ui.nodes.#(attributes.value==phone).meta.label.context.address").String(), "%s", body)
+				})
+
+				// choose the phone channel
+				s = submitLogin(ctx, t, s, tc.apiType, func(v *url.Values) {
+					v.Set("channel", "phone")
+				}, false, nil)
+
+				message := testhelpers.CourierExpectMessage(ctx, t, reg, phone, "")
+				loginCode := gjson.GetBytes(message.TemplateData, "Code").String()
+				assert.NotEmpty(t, loginCode)
+
+				// submit OTP
+				submitLogin(ctx, t, s, tc.apiType, func(v *url.Values) {
+					v.Set("code", loginCode)
+				}, true, nil)
+
+				i, err := reg.PrivilegedIdentityPool().GetIdentity(ctx, s.identity.ID, identity.ExpandNothing)
+				require.NoError(t, err)
+				assert.Equal(t, "phone", gjson.GetBytes(i.MetadataAdmin, "code_login_channel").String())
+				assert.Empty(t, gjson.GetBytes(i.MetadataPublic, "code_login_channel").String(), "the preference is not exposed to the identity")
+
+				events, _, err := reg.PrivilegedIdentityPool().ListAuditEvents(ctx, identity.AuditEventFilter{IdentityID: s.identity.ID}, 0, 10)
+				require.NoError(t, err)
+				require.NotEmpty(t, events)
+				assert.Contains(t, events[0].Changes, identity.AuditChange{Path: "/metadata_admin/code_login_channel", After: json.RawMessage(`"phone"`)})
+			})
+
 			t.Run("case=new identities automatically have login with code", func(t *testing.T) {
 				ctx := context.Background()
 
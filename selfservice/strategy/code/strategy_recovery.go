@@ -4,8 +4,10 @@
 package code
 
 import (
+	"context"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -52,10 +54,7 @@ func (s *Strategy) RegisterAdminRecoveryRoutes(admin *x.RouterAdmin) {
 
 func (s *Strategy) PopulateRecoveryMethod(r *http.Request, f *recovery.Flow) error {
 	f.UI.SetCSRF(s.deps.GenerateCSRFToken(r))
-	f.UI.GetNodes().Upsert(
-		node.NewInputField("email", nil, node.CodeGroup, node.InputAttributeTypeEmail, node.WithRequiredInputAttribute).
-			WithMetaLabel(text.NewInfoNodeInputEmail()),
-	)
+	s.upsertRecoveryAddressNodes(r.Context(), f.UI.GetNodes(), nil, nil, nil)
 	f.UI.
 		GetNodes().
 		Append(node.NewInputField("method", s.RecoveryStrategyID(), node.CodeGroup, node.InputAttributeTypeSubmit).
@@ -98,6 +97,29 @@ type createRecoveryCodeForIdentityBody struct {
 	//	- 1m
 	//	- 1s
 	ExpiresIn string `json:"expires_in"`
+
+	// Send Code via Email
+	//
+	// If set to true, the recovery code is also sent to the first recovery address of the identity
+	// using the courier. Fails if the identity has no recovery address.
+	SendEmail bool `json:"send_email"`
+
+	// Magic Link UI URL
+	//
+	// If set, the response contains a `magic_link` pointing at this URL with the flow ID and the recovery
+	// code appended as query parameters, so that the UI can submit the code without user input. The URL
+	// must point to the configured recovery UI or one of the allowed return URLs.
+	//
+	// format: uri
+	MagicLinkUIURL string `json:"magic_link_ui_url"`
+
+	// Flow Type
+	//
+	// The type of the recovery flow which is created. Use `api` if the code is entered in a native
+	// application and `browser` if it is entered in the browser. Defaults to `browser`.
+	//
+	// enum: api,browser
+	FlowType flow.Type `json:"flow_type"`
 }
 
 // Recovery Code for Identity
@@ -126,6 +148,27 @@ type recoveryCodeForIdentity struct {
 	//
 	// The timestamp when the recovery link expires.
 	ExpiresAt time.Time `json:"expires_at"`
+
+	// MagicLink with flow and code
+	//
+	// This link opens the UI chosen with `magic_link_ui_url`, or the recovery UI if none was chosen,
+	// with the `flow` and `code` query parameters set.
+	//
+	// required: true
+	// format: uri
+	MagicLink string `json:"magic_link"`
+
+	// FlowID is the ID of the recovery flow the code belongs to
+	//
+	// Native applications use it to submit the code to the recovery flow.
+	//
+	// required: true
+	FlowID uuid.UUID `json:"flow_id"`
+
+	// FlowType is the type of the recovery flow the code belongs to
+	//
+	// required: true
+	FlowType flow.Type `json:"flow_type"`
 }
 
 // swagger:route POST /admin/recovery/code identity createRecoveryCodeForIdentity
@@ -179,13 +222,35 @@ func (s *Strategy) createRecoveryCodeForIdentity(w http.ResponseWriter, r *http.
 		return
 	}
 
-	recoveryFlow, err := recovery.NewFlow(config, expiresIn, s.deps.GenerateCSRFToken(r), r, s, flow.TypeBrowser)
+	switch p.FlowType {
+	case "":
+		p.FlowType = flow.TypeBrowser
+	case flow.TypeAPI, flow.TypeBrowser:
+	default:
+		s.deps.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Value from "flow_type" must be "api" or "browser": %s`, p.FlowType)))
+		return
+	}
+
+	magicLinkUI := config.SelfServiceFlowRecoveryUI(ctx)
+	if len(p.MagicLinkUIURL) > 0 {
+		var err error
+		magicLinkUI, err = s.magicLinkUIURL(r, p.MagicLinkUIURL)
+		if err != nil {
+			s.deps.Writer().WriteError(w, r, err)
+			return
+		}
+	}
+
+	recoveryFlow, err := recovery.NewFlow(config, expiresIn, s.deps.GenerateCSRFToken(r), r, s, p.FlowType)
 	if err != nil {
 		s.deps.Writer().WriteError(w, r, err)
 		return
 	}
 	recoveryFlow.DangerousSkipCSRFCheck = true
-	recoveryFlow.State = flow.StateEmailSent
+	if err := recovery.StateMachine.Transition(ctx, recoveryFlow, flow.StateEmailSent); err != nil {
+		s.deps.Writer().WriteError(w, r, err)
+		return
+	}
 	recoveryFlow.UI.Nodes = node.Nodes{}
 	recoveryFlow.UI.Nodes.Append(node.NewInputField("code", nil, node.CodeGroup, node.InputAttributeTypeText, node.WithRequiredInputAttribute).
 		WithMetaLabel(text.NewInfoNodeLabelRecoveryCode()),
@@ -207,26 +272,62 @@ func (s *Strategy) createRecoveryCodeForIdentity(w http.ResponseWriter, r *http.
 	} else if err != nil {
 		s.deps.Writer().WriteError(w, r, err)
 		return
+	} else if id.State.IsBlocked() {
+		s.deps.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The identity can not be recovered because its state is %s.", id.State)))
+		return
 	}
 
-	rawCode := GenerateCode()
-
-	if _, err := s.deps.RecoveryCodePersister().CreateRecoveryCode(ctx, &CreateRecoveryCodeParams{
-		RawCode:    rawCode,
-		CodeType:   RecoveryCodeTypeAdmin,
-		ExpiresIn:  expiresIn,
+	rawCode, err := s.deps.CodeBackend(ctx).GenerateCode(ctx, &CodeRequest{
+		Flow:       flow.RecoveryFlow,
 		FlowID:     recoveryFlow.ID,
 		IdentityID: id.ID,
-	}); err != nil {
+	})
+	if err != nil {
+		s.deps.Writer().WriteError(w, r, err)
+		return
+	}
+
+	var address *identity.RecoveryAddress
+	if p.SendEmail {
+		for k := range id.RecoveryAddresses {
+			if id.RecoveryAddresses[k].Via == identity.RecoveryAddressTypeEmail {
+				address = &id.RecoveryAddresses[k]
+				break
+			}
+		}
+		if address == nil {
+			s.deps.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The recovery code can not be sent because the identity has no recovery email address.")))
+			return
+		}
+	}
+
+	code, err := s.deps.RecoveryCodePersister().CreateRecoveryCode(ctx, &CreateRecoveryCodeParams{
+		RawCode:         rawCode,
+		CodeType:        RecoveryCodeTypeAdmin,
+		ExpiresIn:       expiresIn,
+		FlowID:          recoveryFlow.ID,
+		IdentityID:      id.ID,
+		RecoveryAddress: address,
+	})
+	if err != nil {
 		s.deps.Writer().WriteError(w, r, err)
 		return
 	}
 
 	s.deps.Audit().
 		WithField("identity_id", id.ID).
+		WithField("send_email", p.SendEmail).
+		WithField("flow_type", p.FlowType).
 		WithSensitiveField("recovery_code", rawCode).
 		Info("A recovery code has been created.")
 
+	if p.SendEmail {
+		if err := s.deps.CodeSender().SendRecoveryCodeTo(ctx, id, rawCode, code); err != nil {
+			s.deps.Writer().WriteError(w, r, err)
+			return
+		}
+	}
+
 	body := &recoveryCodeForIdentity{
 		ExpiresAt: recoveryFlow.ExpiresAt.UTC(),
 		RecoveryLink: urlx.CopyWithQuery(
@@ -235,11 +336,36 @@ func (s *Strategy) createRecoveryCodeForIdentity(w http.ResponseWriter, r *http.
 				"flow": {recoveryFlow.ID.String()},
 			}).String(),
 		RecoveryCode: rawCode,
+		MagicLink: urlx.CopyWithQuery(magicLinkUI, url.Values{
+			"flow": {recoveryFlow.ID.String()},
+			"code": {rawCode},
+		}).String(),
+		FlowID:   recoveryFlow.ID,
+		FlowType: recoveryFlow.Type,
 	}
 
 	s.deps.Writer().WriteCode(w, r, http.StatusCreated, body, herodot.UnescapedHTML)
 }
 
+// magicLinkUIURL parses the UI URL of a magic recovery link and makes sure it points to the recovery UI or one of
+// the allowed return URLs, so that recovery codes are not leaked to arbitrary hosts.
+func (s *Strategy) magicLinkUIURL(r *http.Request, raw string) (*url.URL, error) {
+	ctx := r.Context()
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Value from "magic_link_ui_url" must be an absolute HTTP(S) URL: %s`, raw))
+	}
+
+	allowed := append([]url.URL{*s.deps.Config().SelfServiceFlowRecoveryUI(ctx)}, s.deps.Config().SelfServiceBrowserAllowedReturnToDomains(ctx)...)
+	for _, a := range allowed {
+		if x.SecureRedirectToIsAllowedHost(u, a) {
+			return u, nil
+		}
+	}
+
+	return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf(`Value from "magic_link_ui_url" is not an allowed URL: %s`, raw))
+}
+
 // Update Recovery Flow with Code Method
 //
 // swagger:model updateRecoveryFlowWithCodeMethod
@@ -260,6 +386,15 @@ type updateRecoveryFlowWithCodeMethod struct {
 	// required: false
 	Email string `json:"email" form:"email"`
 
+	// The phone number of the account to recover
+	//
+	// Only used if `selfservice.flows.recovery.phone_numbers` is enabled and no email address was submitted. The
+	// phone number must include the country calling code. If it belongs to a valid account, a recovery code will be
+	// sent via SMS.
+	//
+	// required: false
+	Phone string `json:"phone" form:"phone"`
+
 	// Code from the recovery email
 	//
 	// If you want to submit a code, use this field, but make sure to _not_ include the email field, as well.
@@ -267,6 +402,16 @@ type updateRecoveryFlowWithCodeMethod struct {
 	// required: false
 	Code string `json:"code" form:"code"`
 
+	// The recovery address the code should be sent to
+	//
+	// Only used if `selfservice.flows.recovery.address_selection` is enabled and the account has more than one
+	// recovery address. Must be submitted together with the email field and contain the ID of one of the
+	// recovery addresses offered in the flow's UI nodes.
+	//
+	// format: uuid
+	// required: false
+	RecoveryAddress string `json:"recovery_address" form:"recovery_address"`
+
 	// Sending the anti-csrf token is only required for browser login flows.
 	CSRFToken string `form:"csrf_token" json:"csrf_token"`
 
@@ -314,8 +459,8 @@ func (s *Strategy) Recover(w http.ResponseWriter, r *http.Request, f *recovery.F
 
 	f.UI.ResetMessages()
 
-	// If the email is present in the submission body, the user needs a new code via resend
-	if f.State != flow.StateChooseMethod && len(body.Email) == 0 {
+	// If the email, phone number or identifier is present in the submission body, the user needs a new code via resend
+	if f.State != flow.StateChooseMethod && len(body.Email) == 0 && len(body.Phone) == 0 && len(body.Identifier) == 0 {
 		if err := flow.MethodEnabledAndAllowed(ctx, flow.RecoveryFlow, sID, sID, s.deps); err != nil {
 			return s.HandleRecoveryError(w, r, nil, body, err)
 		}
@@ -345,12 +490,10 @@ func (s *Strategy) Recover(w http.ResponseWriter, r *http.Request, f *recovery.F
 		return s.HandleRecoveryError(w, r, recoveryFlow, body, err)
 	}
 
-	switch recoveryFlow.State {
-	case flow.StateChooseMethod:
-		fallthrough
-	case flow.StateEmailSent:
+	switch {
+	case recovery.StateMachine.CanTransition(recoveryFlow.State, flow.StateEmailSent):
 		return s.recoveryHandleFormSubmission(w, r, recoveryFlow, body)
-	case flow.StatePassedChallenge:
+	case recoveryFlow.State == flow.StatePassedChallenge:
 		// was already handled, do not allow retry
 		return s.retryRecoveryFlowWithMessage(w, r, recoveryFlow.Type, text.NewErrorValidationRecoveryRetrySuccess())
 	default:
@@ -358,11 +501,13 @@ func (s *Strategy) Recover(w http.ResponseWriter, r *http.Request, f *recovery.F
 	}
 }
 
-func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request, f *recovery.Flow, id *identity.Identity) error {
+func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request, f *recovery.Flow, id *identity.Identity, address *identity.RecoveryAddress) error {
 	ctx := r.Context()
 
 	f.UI.Messages.Clear()
-	f.State = flow.StatePassedChallenge
+	if err := recovery.StateMachine.Transition(ctx, f, flow.StatePassedChallenge); err != nil {
+		return s.retryRecoveryFlowWithError(w, r, f.Type, err)
+	}
 	f.SetCSRFToken(s.deps.CSRFHandler().RegenerateToken(w, r))
 	f.RecoveredIdentityID = uuid.NullUUID{
 		UUID:  id.ID,
@@ -372,7 +517,7 @@ func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request,
 		return s.retryRecoveryFlowWithError(w, r, f.Type, err)
 	}
 
-	sess, err := session.NewActiveSession(r, id, s.deps.Config(), time.Now().UTC(),
+	sess, err := session.NewActiveSession(r, id, s.deps.Config(), x.Now().UTC(),
 		identity.CredentialsTypeRecoveryCode, identity.AuthenticatorAssuranceLevel1)
 	if err != nil {
 		return s.retryRecoveryFlowWithError(w, r, f.Type, err)
@@ -387,24 +532,32 @@ func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request,
 		return s.retryRecoveryFlowWithError(w, r, f.Type, err)
 	}
 
+	if err := s.deps.RecoveryNotifier().NotifyRecovered(r, sess.Identity, address); err != nil {
+		return s.retryRecoveryFlowWithError(w, r, f.Type, err)
+	}
+
 	sf, err := s.deps.SettingsHandler().NewFlow(w, r, sess.Identity, f.Type)
 	if err != nil {
 		return s.retryRecoveryFlowWithError(w, r, f.Type, err)
 	}
 
-	returnToURL := s.deps.Config().SelfServiceFlowRecoveryReturnTo(r.Context(), nil)
-	returnTo := ""
-	if returnToURL != nil {
-		returnTo = returnToURL.String()
+	returnTo, err := f.ContinueReturnTo(r, s.deps.Config())
+	if err != nil {
+		return s.retryRecoveryFlowWithError(w, r, flow.TypeBrowser, err)
 	}
+
 	sf.RequestURL, err = x.TakeOverReturnToParameter(f.RequestURL, sf.RequestURL, returnTo)
 	if err != nil {
 		return s.retryRecoveryFlowWithError(w, r, flow.TypeBrowser, err)
 	}
+	sf.ReturnTo = returnTo
 
 	config := s.deps.Config()
 
-	sf.UI.Messages.Set(text.NewRecoverySuccessful(time.Now().Add(config.SelfServiceFlowSettingsPrivilegedSessionMaxAge(ctx))))
+	sf.UI.Messages.Set(text.NewRecoverySuccessful(x.Now().Add(config.SelfServiceFlowSettingsPrivilegedSessionMaxAge(ctx))))
+	for _, action := range sess.RequiredActions {
+		sf.UI.Messages.Add(text.NewInfoSelfServiceSettingsRequiredAction(action))
+	}
 	if err := s.deps.SettingsFlowPersister().UpdateSettingsFlow(r.Context(), sf); err != nil {
 		return s.retryRecoveryFlowWithError(w, r, f.Type, err)
 	}
@@ -420,8 +573,10 @@ func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request,
 
 func (s *Strategy) recoveryUseCode(w http.ResponseWriter, r *http.Request, body *recoverySubmitPayload, f *recovery.Flow) error {
 	ctx := r.Context()
-	code, err := s.deps.RecoveryCodePersister().UseRecoveryCode(ctx, f.ID, body.Code)
+	code, err := s.deps.RecoveryCodePersister().UseRecoveryCode(ctx, f.ID, body.Code, validateWith(s.deps.CodeBackend(ctx), body.Code))
 	if errors.Is(err, ErrCodeNotFound) {
+		// The submission was counted by the persister, reflect it in the flow's step.
+		f.SubmitCount++
 		f.UI.Messages.Clear()
 		f.UI.Messages.Add(text.NewErrorValidationRecoveryCodeInvalidOrAlreadyUsed())
 		if err := s.deps.RecoveryFlowPersister().UpdateRecoveryFlow(ctx, f); err != nil {
@@ -430,6 +585,16 @@ func (s *Strategy) recoveryUseCode(w http.ResponseWriter, r *http.Request, body
 
 		// No error
 		return nil
+	} else if errors.Is(err, ErrCodeSubmittedTooOften) {
+		// Invalidate the flow so that no further codes can be submitted for it.
+		f.ExpiresAt = x.Now().UTC()
+		if err := s.deps.RecoveryFlowPersister().UpdateRecoveryFlow(ctx, f); err != nil {
+			return s.retryRecoveryFlowWithError(w, r, f.Type, err)
+		}
+
+		return s.retryRecoveryFlowWithMessage(w, r, f.Type,
+			text.NewErrorValidationRecoveryCodeInvalidOrAlreadyUsed(),
+			text.NewErrorValidationRecoveryCodeSubmittedTooOften())
 	} else if err != nil {
 		return s.retryRecoveryFlowWithError(w, r, f.Type, err)
 	}
@@ -446,13 +611,13 @@ func (s *Strategy) recoveryUseCode(w http.ResponseWriter, r *http.Request, body
 		}
 	}
 
-	return s.recoveryIssueSession(w, r, f, recovered)
+	return s.recoveryIssueSession(w, r, f, recovered, code.RecoveryAddress)
 }
 
-func (s *Strategy) retryRecoveryFlowWithMessage(w http.ResponseWriter, r *http.Request, ft flow.Type, message *text.Message) error {
+func (s *Strategy) retryRecoveryFlowWithMessage(w http.ResponseWriter, r *http.Request, ft flow.Type, messages ...*text.Message) error {
 	s.deps.Logger().
 		WithRequest(r).
-		WithField("message", message).
+		WithField("messages", messages).
 		Debug("A recovery flow is being retried because a validation error occurred.")
 
 	ctx := r.Context()
@@ -463,7 +628,9 @@ func (s *Strategy) retryRecoveryFlowWithMessage(w http.ResponseWriter, r *http.R
 		return err
 	}
 
-	f.UI.Messages.Add(message)
+	for _, message := range messages {
+		f.UI.Messages.Add(message)
+	}
 	if err := s.deps.RecoveryFlowPersister().CreateRecoveryFlow(ctx, f); err != nil {
 		return err
 	}
@@ -512,27 +679,75 @@ func (s *Strategy) retryRecoveryFlowWithError(w http.ResponseWriter, r *http.Req
 	return errors.WithStack(flow.ErrCompletedByStrategy)
 }
 
-// recoveryHandleFormSubmission handles the submission of an Email for recovery
+// recoveryHandleFormSubmission handles the submission of an Email or phone number for recovery
 func (s *Strategy) recoveryHandleFormSubmission(w http.ResponseWriter, r *http.Request, f *recovery.Flow, body *recoverySubmitPayload) error {
-	if len(body.Email) == 0 {
-		return s.HandleRecoveryError(w, r, f, body, schema.NewRequiredError("#/email", "email"))
-	}
-
 	ctx := r.Context()
 	config := s.deps.Config()
 
+	via, to, err := s.submittedRecoveryAddress(ctx, body)
+	if err != nil {
+		return s.HandleRecoveryError(w, r, f, body, err)
+	}
+
 	if err := flow.EnsureCSRF(s.deps, r, f.Type, config.DisableAPIFlowEnforcement(ctx), s.deps.GenerateCSRFToken, body.CSRFToken); err != nil {
 		return s.HandleRecoveryError(w, r, f, body, err)
 	}
 
+	// If account enumeration is mitigated, the response must not reveal whether the address or
+	// identifier belongs to an account, so the recovery address can not be chosen either.
+	mitigate := config.SelfServiceAccountEnumerationMitigate(ctx)
+	if mitigate {
+		defer x.WaitAtLeast(ctx, time.Now(), config.SelfServiceAccountEnumerationResponseTime(ctx))
+	}
+
+	var selected *identity.RecoveryAddress
+	if via == recoveryAddressTypeIdentifier || config.SelfServiceFlowRecoveryAddressSelection(ctx) {
+		addresses, err := s.recoveryAddressesOf(ctx, via, to)
+		if err != nil {
+			return s.HandleRecoveryError(w, r, f, body, err)
+		}
+
+		if len(addresses) > 1 && config.SelfServiceFlowRecoveryAddressSelection(ctx) && !mitigate {
+			for k := range addresses {
+				if addresses[k].ID.String() == body.RecoveryAddress {
+					selected = &addresses[k]
+					break
+				}
+			}
+
+			if selected == nil {
+				return s.recoveryChooseAddress(w, r, f, body, via, to, addresses)
+			}
+		}
+
+		// Identifiers are not addresses, the code is sent to the primary recovery address instead.
+		if selected == nil && via == recoveryAddressTypeIdentifier && len(addresses) > 0 {
+			selected = &addresses[0]
+		}
+	}
+
 	if err := s.deps.RecoveryCodePersister().DeleteRecoveryCodesOfFlow(ctx, f.ID); err != nil {
 		return s.HandleRecoveryError(w, r, f, body, err)
 	}
 
-	if err := s.deps.CodeSender().SendRecoveryCode(ctx, f, identity.VerifiableAddressTypeEmail, body.Email); err != nil {
+	if selected != nil {
+		if err := s.deps.CodeSender().SendRecoveryCodeToAddress(ctx, f, selected); err != nil {
+			return s.HandleRecoveryError(w, r, f, body, err)
+		}
+	} else if via == recoveryAddressTypeIdentifier {
+		if config.SelfServiceFlowRecoveryUnknownAddressAudit(ctx) {
+			s.deps.Audit().
+				WithField("via", via).
+				WithSensitiveField("identifier", to).
+				WithField("strategy", "code").
+				Info("Account recovery was requested for an unknown identifier.")
+		}
+		s.deps.RecoveryExecutor().UnknownAddressHook(w, r, f, to)
+	} else if err := s.deps.CodeSender().SendRecoveryCode(ctx, f, identity.VerifiableAddressType(via), to); err != nil {
 		if !errors.Is(err, ErrUnknownAddress) {
 			return s.HandleRecoveryError(w, r, f, body, err)
 		}
+		s.deps.RecoveryExecutor().UnknownAddressHook(w, r, f, to)
 		// Continue execution
 	}
 
@@ -545,8 +760,18 @@ func (s *Strategy) recoveryHandleFormSubmission(w http.ResponseWriter, r *http.R
 	f.UI.SetCSRF(s.deps.GenerateCSRFToken(r))
 
 	f.Active = sqlxx.NullString(s.NodeGroup())
-	f.State = flow.StateEmailSent
-	f.UI.Messages.Set(text.NewRecoveryEmailWithCodeSent())
+	if err := recovery.StateMachine.Transition(ctx, f, flow.StateEmailSent); err != nil {
+		return s.HandleRecoveryError(w, r, f, body, err)
+	}
+	if via == recoveryAddressTypeIdentifier && mitigate {
+		f.UI.Messages.Set(text.NewRecoveryEmailWithCodeSent())
+	} else if via == recoveryAddressTypeIdentifier && selected != nil {
+		f.UI.Messages.Set(text.NewRecoveryCodeSentToAddress(x.MaskAddress(selected.Value)))
+	} else if via == identity.RecoveryAddressTypePhone || (selected != nil && selected.Via == identity.RecoveryAddressTypePhone) {
+		f.UI.Messages.Set(text.NewRecoverySMSWithCodeSent())
+	} else {
+		f.UI.Messages.Set(text.NewRecoveryEmailWithCodeSent())
+	}
 	f.UI.Nodes.Append(node.NewInputField("code", nil, node.CodeGroup, node.InputAttributeTypeText, node.WithInputAttributes(func(a *node.InputAttributes) {
 		a.Required = true
 		a.Pattern = "[0-9]+"
@@ -560,7 +785,7 @@ func (s *Strategy) recoveryHandleFormSubmission(w http.ResponseWriter, r *http.R
 		Append(node.NewInputField("method", s.RecoveryStrategyID(), node.CodeGroup, node.InputAttributeTypeSubmit).
 			WithMetaLabel(text.NewInfoNodeLabelSubmit()))
 
-	f.UI.Nodes.Append(node.NewInputField("email", body.Email, node.CodeGroup, node.InputAttributeTypeSubmit).
+	f.UI.Nodes.Append(node.NewInputField(recoveryAddressField(via), to, node.CodeGroup, node.InputAttributeTypeSubmit).
 		WithMetaLabel(text.NewInfoNodeResendOTP()),
 	)
 	if err := s.deps.RecoveryFlowPersister().UpdateRecoveryFlow(r.Context(), f); err != nil {
@@ -570,6 +795,129 @@ func (s *Strategy) recoveryHandleFormSubmission(w http.ResponseWriter, r *http.R
 	return nil
 }
 
+// recoveryAddressTypeIdentifier is the pseudo address type of login identifiers submitted to the recovery flow.
+const recoveryAddressTypeIdentifier identity.RecoveryAddressType = "identifier"
+
+// submittedRecoveryAddress returns the type and value of the address submitted to the recovery flow. Phone
+// numbers are normalized to E.164 format.
+func (s *Strategy) submittedRecoveryAddress(ctx context.Context, body *recoverySubmitPayload) (identity.RecoveryAddressType, string, error) {
+	if len(body.Email) > 0 {
+		return identity.RecoveryAddressTypeEmail, body.Email, nil
+	}
+
+	if len(body.Phone) > 0 && s.deps.Config().SelfServiceFlowRecoveryPhoneNumbers(ctx) {
+		phone, err := x.NormalizePhoneNumber(body.Phone)
+		if err != nil {
+			return "", "", schema.NewInvalidPhoneNumberError("#/phone")
+		}
+		return identity.RecoveryAddressTypePhone, phone, nil
+	}
+
+	if len(body.Identifier) > 0 && s.deps.Config().SelfServiceFlowRecoveryIdentifiers(ctx) {
+		return recoveryAddressTypeIdentifier, strings.TrimSpace(body.Identifier), nil
+	}
+
+	return "", "", schema.NewRequiredError("#/email", "email")
+}
+
+// recoveryAddressField returns the name of the form field for the given recovery address type.
+func recoveryAddressField(via identity.RecoveryAddressType) string {
+	switch via {
+	case identity.RecoveryAddressTypePhone:
+		return "phone"
+	case recoveryAddressTypeIdentifier:
+		return "identifier"
+	}
+	return "email"
+}
+
+// upsertRecoveryAddressNodes adds the fields for the address of the account to recover. The email field is only
+// required if accounts can not be recovered by phone number or identifier.
+func (s *Strategy) upsertRecoveryAddressNodes(ctx context.Context, nodes *node.Nodes, email, phone, identifier interface{}) {
+	phoneNumbers, identifiers := s.deps.Config().SelfServiceFlowRecoveryPhoneNumbers(ctx), s.deps.Config().SelfServiceFlowRecoveryIdentifiers(ctx)
+	if !phoneNumbers && !identifiers {
+		nodes.Upsert(
+			node.NewInputField("email", email, node.CodeGroup, node.InputAttributeTypeEmail, node.WithRequiredInputAttribute).
+				WithMetaLabel(text.NewInfoNodeInputEmail()),
+		)
+		return
+	}
+
+	nodes.Upsert(
+		node.NewInputField("email", email, node.CodeGroup, node.InputAttributeTypeEmail).
+			WithMetaLabel(text.NewInfoNodeInputEmail()),
+	)
+	if phoneNumbers {
+		nodes.Upsert(
+			node.NewInputField("phone", phone, node.CodeGroup, node.InputAttributeTypeTel).
+				WithMetaLabel(text.NewInfoNodeInputPhoneNumber()),
+		)
+	}
+	if identifiers {
+		nodes.Upsert(
+			node.NewInputField("identifier", identifier, node.CodeGroup, node.InputAttributeTypeText).
+				WithMetaLabel(text.NewInfoNodeLabelID()),
+		)
+	}
+}
+
+// recoveryAddressesOf returns all recovery addresses of the identity the given address or identifier belongs to. If
+// the address or identifier is unknown, no addresses are returned.
+func (s *Strategy) recoveryAddressesOf(ctx context.Context, via identity.RecoveryAddressType, value string) ([]identity.RecoveryAddress, error) {
+	if via == recoveryAddressTypeIdentifier {
+		i, err := s.deps.PrivilegedIdentityPool().FindIdentityByCredentialIdentifier(ctx, value, false)
+		if errors.Is(err, sqlcon.ErrNoRows) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		} else if i.State.IsBlocked() {
+			return nil, nil
+		}
+		return i.RecoveryAddresses, nil
+	}
+
+	address, err := s.deps.IdentityPool().FindRecoveryAddressByValue(ctx, via, value)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	i, err := s.deps.IdentityPool().GetIdentity(ctx, address.IdentityID, identity.ExpandDefault)
+	if err != nil {
+		return nil, err
+	} else if i.State.IsBlocked() {
+		return nil, nil
+	}
+
+	return i.RecoveryAddresses, nil
+}
+
+// recoveryChooseAddress asks the user which of the (masked) recovery addresses the code should be sent to.
+func (s *Strategy) recoveryChooseAddress(w http.ResponseWriter, r *http.Request, f *recovery.Flow, body *recoverySubmitPayload, via identity.RecoveryAddressType, to string, addresses []identity.RecoveryAddress) error {
+	f.UI = &container.Container{
+		Method: "POST",
+		Action: flow.AppendFlowTo(urlx.AppendPaths(s.deps.Config().SelfPublicURL(r.Context()), recovery.RouteSubmitFlow), f.ID).String(),
+	}
+
+	f.UI.SetCSRF(s.deps.GenerateCSRFToken(r))
+
+	f.Active = sqlxx.NullString(s.NodeGroup())
+	f.UI.Messages.Set(text.NewRecoveryChooseAddress())
+	f.UI.Nodes.Append(node.NewInputField(recoveryAddressField(via), to, node.CodeGroup, node.InputAttributeTypeHidden))
+	f.UI.Nodes.Append(node.NewInputField("method", s.NodeGroup(), node.CodeGroup, node.InputAttributeTypeHidden))
+	for _, address := range addresses {
+		f.UI.Nodes.Append(node.NewInputField("recovery_address", address.ID.String(), node.CodeGroup, node.InputAttributeTypeSubmit).
+			WithMetaLabel(text.NewInfoNodeLabelRecoveryAddress(x.MaskAddress(address.Value))))
+	}
+
+	if err := s.deps.RecoveryFlowPersister().UpdateRecoveryFlow(r.Context(), f); err != nil {
+		return s.HandleRecoveryError(w, r, f, body, err)
+	}
+
+	return nil
+}
+
 func (s *Strategy) markRecoveryAddressVerified(w http.ResponseWriter, r *http.Request, f *recovery.Flow, id *identity.Identity, recoveryAddress *identity.RecoveryAddress) error {
 	var address *identity.VerifiableAddress
 	for idx := range id.VerifiableAddresses {
@@ -595,16 +943,13 @@ func (s *Strategy) markRecoveryAddressVerified(w http.ResponseWriter, r *http.Re
 
 func (s *Strategy) HandleRecoveryError(w http.ResponseWriter, r *http.Request, flow *recovery.Flow, body *recoverySubmitPayload, err error) error {
 	if flow != nil {
-		email := ""
+		email, phone, identifier := "", "", ""
 		if body != nil {
-			email = body.Email
+			email, phone, identifier = body.Email, body.Phone, body.Identifier
 		}
 
 		flow.UI.SetCSRF(s.deps.GenerateCSRFToken(r))
-		flow.UI.GetNodes().Upsert(
-			node.NewInputField("email", email, node.CodeGroup, node.InputAttributeTypeEmail, node.WithRequiredInputAttribute).
-				WithMetaLabel(text.NewInfoNodeInputEmail()),
-		)
+		s.upsertRecoveryAddressNodes(r.Context(), flow.UI.GetNodes(), email, phone, identifier)
 	}
 
 	return err
@@ -616,6 +961,11 @@ type recoverySubmitPayload struct {
 	CSRFToken string `json:"csrf_token" form:"csrf_token"`
 	Flow      string `json:"flow" form:"flow"`
 	Email     string `json:"email" form:"email"`
+	Phone     string `json:"phone" form:"phone"`
+
+	Identifier string `json:"identifier" form:"identifier"`
+
+	RecoveryAddress string `json:"recovery_address" form:"recovery_address"`
 }
 
 func (s *Strategy) decodeRecovery(r *http.Request) (*recoverySubmitPayload, error) {
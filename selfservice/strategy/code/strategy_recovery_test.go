@@ -7,11 +7,13 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -27,6 +29,7 @@ import (
 	kratos "github.com/ory/kratos/internal/httpclient"
 
 	"github.com/ory/kratos/corpx"
+	"github.com/ory/kratos/courier"
 
 	"github.com/ory/x/ioutilx"
 	"github.com/ory/x/pointerx"
@@ -217,6 +220,120 @@ func TestAdminStrategy(t *testing.T) {
 		testhelpers.AssertMessage(t, body, "The recovery code is invalid or has already been used. Please try again.")
 	})
 
+	createCodeWithBody := func(t *testing.T, body map[string]interface{}) (*http.Response, []byte) {
+		t.Helper()
+		payload, err := json.Marshal(body)
+		require.NoError(t, err)
+
+		res, err := adminTS.Client().Post(adminTS.URL+"/admin"+code.RouteAdminCreateRecoveryCode, "application/json", bytes.NewReader(payload))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, ioutilx.MustReadAll(res.Body)
+	}
+
+	t.Run("case=should return a magic link pointing at the chosen UI", func(t *testing.T) {
+		i := createIdentityToRecover(t, reg, testhelpers.RandomEmail())
+		ui := urlx.AppendPaths(conf.SelfServiceFlowRecoveryUI(ctx), "/helpdesk").String()
+
+		res, body := createCodeWithBody(t, map[string]interface{}{
+			"identity_id":       i.ID,
+			"magic_link_ui_url": ui,
+		})
+		require.Equal(t, http.StatusCreated, res.StatusCode, "%s", body)
+
+		magicLink, err := url.Parse(gjson.GetBytes(body, "magic_link").String())
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(magicLink.String(), ui), "%s", magicLink)
+		assert.Equal(t, gjson.GetBytes(body, "recovery_code").String(), magicLink.Query().Get("code"))
+		assert.Equal(t, urlx.ParseOrPanic(gjson.GetBytes(body, "recovery_link").String()).Query().Get("flow"), magicLink.Query().Get("flow"))
+	})
+
+	t.Run("case=should not return a magic link to a disallowed host", func(t *testing.T) {
+		i := createIdentityToRecover(t, reg, testhelpers.RandomEmail())
+
+		res, body := createCodeWithBody(t, map[string]interface{}{
+			"identity_id":       i.ID,
+			"magic_link_ui_url": "https://not-allowed.ory.sh/recovery",
+		})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s", body)
+		assert.Contains(t, gjson.GetBytes(body, "error.reason").String(), "is not an allowed URL")
+	})
+
+	t.Run("case=should return the flow and a magic link to the recovery UI by default", func(t *testing.T) {
+		i := createIdentityToRecover(t, reg, testhelpers.RandomEmail())
+
+		res, body := createCodeWithBody(t, map[string]interface{}{"identity_id": i.ID})
+		require.Equal(t, http.StatusCreated, res.StatusCode, "%s", body)
+
+		flowID := gjson.GetBytes(body, "flow_id").String()
+		assert.Equal(t, string(flow.TypeBrowser), gjson.GetBytes(body, "flow_type").String())
+
+		magicLink := urlx.ParseOrPanic(gjson.GetBytes(body, "magic_link").String())
+		assert.True(t, strings.HasPrefix(magicLink.String(), conf.SelfServiceFlowRecoveryUI(ctx).String()), "%s", magicLink)
+		assert.Equal(t, flowID, magicLink.Query().Get("flow"))
+		assert.Equal(t, gjson.GetBytes(body, "recovery_code").String(), magicLink.Query().Get("code"))
+
+		f, err := reg.RecoveryFlowPersister().GetRecoveryFlow(ctx, uuid.FromStringOrNil(flowID))
+		require.NoError(t, err)
+		assert.Equal(t, flow.TypeBrowser, f.Type)
+	})
+
+	t.Run("case=should create an api flow", func(t *testing.T) {
+		i := createIdentityToRecover(t, reg, testhelpers.RandomEmail())
+
+		res, body := createCodeWithBody(t, map[string]interface{}{
+			"identity_id": i.ID,
+			"flow_type":   "api",
+		})
+		require.Equal(t, http.StatusCreated, res.StatusCode, "%s", body)
+		assert.Equal(t, string(flow.TypeAPI), gjson.GetBytes(body, "flow_type").String())
+
+		f, err := reg.RecoveryFlowPersister().GetRecoveryFlow(ctx, uuid.FromStringOrNil(gjson.GetBytes(body, "flow_id").String()))
+		require.NoError(t, err)
+		assert.Equal(t, flow.TypeAPI, f.Type)
+	})
+
+	t.Run("case=should reject an unknown flow type", func(t *testing.T) {
+		i := createIdentityToRecover(t, reg, testhelpers.RandomEmail())
+
+		res, body := createCodeWithBody(t, map[string]interface{}{
+			"identity_id": i.ID,
+			"flow_type":   "native",
+		})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s", body)
+		assert.Contains(t, gjson.GetBytes(body, "error.reason").String(), `"flow_type"`)
+	})
+
+	t.Run("case=should send the code via email", func(t *testing.T) {
+		email := testhelpers.RandomEmail()
+		i := createIdentityToRecover(t, reg, email)
+
+		res, body := createCodeWithBody(t, map[string]interface{}{
+			"identity_id": i.ID,
+			"expires_in":  "10m",
+			"send_email":  true,
+		})
+		require.Equal(t, http.StatusCreated, res.StatusCode, "%s", body)
+
+		message := testhelpers.CourierExpectMessage(ctx, t, reg, email, "Recover access to your account")
+		assert.Contains(t, message.Body, gjson.GetBytes(body, "recovery_code").String())
+
+		submitted := submitRecoveryLink(t, gjson.GetBytes(body, "recovery_link").String(), gjson.GetBytes(body, "recovery_code").String())
+		testhelpers.AssertMessage(t, submitted, "You successfully recovered your account. Please change your password or set up an alternative login method (e.g. social sign in) within the next 60.00 minutes.")
+	})
+
+	t.Run("case=should fail to send the code if the identity has no recovery address", func(t *testing.T) {
+		id := identity.Identity{Traits: identity.Traits(`{}`)}
+		require.NoError(t, reg.IdentityManager().Create(ctx, &id, identity.ManagerAllowWriteProtectedTraits))
+
+		res, body := createCodeWithBody(t, map[string]interface{}{
+			"identity_id": id.ID,
+			"send_email":  true,
+		})
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s", body)
+		assert.Contains(t, gjson.GetBytes(body, "error.reason").String(), "no recovery email address")
+	})
+
 	t.Run("case=form should not contain email field when creating recovery code", func(t *testing.T) {
 		email := testhelpers.RandomEmail()
 		i := createIdentityToRecover(t, reg, email)
@@ -720,6 +837,36 @@ func TestRecovery(t *testing.T) {
 		})
 	})
 
+	t.Run("description=should call the hooks for unknown addresses", func(t *testing.T) {
+		addresses := make(chan string, 1)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addresses <- gjson.GetBytes(ioutilx.MustReadAll(r.Body), "address").String()
+		}))
+		t.Cleanup(ts.Close)
+
+		conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryUnknownAddressHooks, []config.SelfServiceHook{{
+			Name: "web_hook",
+			Config: json.RawMessage(fmt.Sprintf(`{"url":%q,"method":"POST","body":"base64://%s"}`,
+				ts.URL, base64.StdEncoding.EncodeToString([]byte("function(ctx) { address: ctx.address }")))),
+		}})
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryUnknownAddressHooks, nil)
+		})
+
+		email := "recover_unknown_hook@ory.sh"
+		body := submitRecovery(t, apiHttpClient(t), RecoveryFlowTypeAPI, func(v url.Values) {
+			v.Set("email", email)
+		}, http.StatusOK)
+		assertx.EqualAsJSON(t, text.NewRecoveryEmailWithCodeSent(), json.RawMessage(gjson.Get(body, "ui.messages.0").Raw))
+
+		select {
+		case address := <-addresses:
+			assert.Equal(t, email, address)
+		case <-time.After(5 * time.Second):
+			t.Fatal("the web hook was not called")
+		}
+	})
+
 	t.Run("description=should not be able to recover an inactive account", func(t *testing.T) {
 		for _, flowType := range flowTypeCases {
 			t.Run("type="+flowType.FlowType, func(t *testing.T) {
@@ -814,12 +961,18 @@ func TestRecovery(t *testing.T) {
 		// submit an invalid code for the 6th time
 		body = submitRecoveryCode(t, c, body, RecoveryFlowTypeBrowser, "12312312", http.StatusOK)
 
-		require.Len(t, gjson.Get(body, "ui.messages").Array(), 1)
-		assert.Equal(t, "The request was submitted too often. Please request another code.", gjson.Get(body, "ui.messages.0.text").String())
+		require.Len(t, gjson.Get(body, "ui.messages").Array(), 2)
+		assert.EqualValues(t, text.ErrorValidationRecoveryCodeInvalidOrAlreadyUsed, gjson.Get(body, "ui.messages.0.id").Int())
+		assert.EqualValues(t, text.ErrorValidationRecoveryCodeSubmittedTooOften, gjson.Get(body, "ui.messages.1.id").Int())
 
 		// check that a new flow has been created
 		assert.NotEqual(t, gjson.Get(body, "id"), initialFlowId)
 
+		// check that the initial flow has been invalidated
+		initialFlow, err := reg.RecoveryFlowPersister().GetRecoveryFlow(ctx, uuid.FromStringOrNil(initialFlowId.String()))
+		require.NoError(t, err)
+		assert.Error(t, initialFlow.Valid())
+
 		assert.True(t, gjson.Get(body, "ui.nodes.#(attributes.name==email)").Exists())
 	})
 
@@ -897,10 +1050,13 @@ func TestRecovery(t *testing.T) {
 		body := submitRecovery(t, c, RecoveryFlowTypeBrowser, func(v url.Values) {
 			v.Set("email", email)
 		}, http.StatusOK)
+		assert.Equal(t, "sent", gjson.Get(body, "step").String(), "%s", body)
 
 		body = submitRecoveryCode(t, c, body, RecoveryFlowTypeBrowser, "12312312", http.StatusOK)
 
 		testhelpers.AssertMessage(t, []byte(body), "The recovery code is invalid or has already been used. Please try again.")
+		assert.Equal(t, "verifying", gjson.Get(body, "step").String(), "%s", body)
+		assert.Equal(t, "sent_email", gjson.Get(body, "state").String(), "%s", body)
 	})
 
 	t.Run("description=should not be able to submit recover address after flow expired", func(t *testing.T) {
@@ -1006,6 +1162,132 @@ func TestRecovery(t *testing.T) {
 		submitRecoveryCode(t, c, body, RecoveryFlowTypeBrowser, recoveryCode, http.StatusOK)
 	})
 
+	t.Run("description=should let the user choose the recovery address", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryAddressSelection, true)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryAddressSelection, false)
+		})
+
+		recoveryEmail := testhelpers.RandomEmail()
+		backupEmail := testhelpers.RandomEmail()
+		require.NoError(t, reg.IdentityManager().Create(ctx, &identity.Identity{
+			Traits:   identity.Traits(fmt.Sprintf(`{"email":"%s","backup_email":"%s"}`, recoveryEmail, backupEmail)),
+			SchemaID: config.DefaultIdentityTraitsSchemaID,
+			State:    identity.StateActive,
+		}, identity.ManagerAllowWriteProtectedTraits))
+
+		backupAddress, err := reg.IdentityPool().FindRecoveryAddressByValue(ctx, identity.RecoveryAddressTypeEmail, backupEmail)
+		require.NoError(t, err)
+
+		c := testhelpers.NewClientWithCookies(t)
+		body := expectSuccessfulRecovery(t, c, RecoveryFlowTypeBrowser, func(v url.Values) {
+			v.Set("email", recoveryEmail)
+		})
+
+		assert.EqualValues(t, text.InfoSelfServiceRecoveryChooseAddress, gjson.Get(body, "ui.messages.0.id").Int(), "%s", body)
+		assert.False(t, gjson.Get(body, "ui.nodes.#(attributes.name==code)").Exists())
+		require.Len(t, gjson.Get(body, "ui.nodes.#(attributes.name==recovery_address)#").Array(), 2)
+		assert.Equal(t,
+			x.MaskAddress(backupEmail),
+			gjson.Get(body, fmt.Sprintf("ui.nodes.#(attributes.value==%s).meta.label.context.address", backupAddress.ID)).String(),
+		)
+
+		values := withCSRFToken(t, RecoveryFlowTypeBrowser, body, url.Values{
+			"method":           {"code"},
+			"email":            {recoveryEmail},
+			"recovery_address": {backupAddress.ID.String()},
+		})
+		res, err := c.Post(gjson.Get(body, "ui.action").String(), "application/x-www-form-urlencoded", bytes.NewBufferString(values))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		body = string(ioutilx.MustReadAll(res.Body))
+		assert.True(t, gjson.Get(body, "ui.nodes.#(attributes.name==code)").Exists(), "%s", body)
+
+		message := testhelpers.CourierExpectMessage(ctx, t, reg, backupEmail, "Recover access to your account")
+		recoveryCode := testhelpers.CourierExpectCodeInMessage(t, message, 1)
+
+		submitRecoveryCode(t, c, body, RecoveryFlowTypeBrowser, recoveryCode, http.StatusOK)
+	})
+
+	t.Run("description=should recover an account by phone number", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryPhoneNumbers, true)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryPhoneNumbers, false)
+		})
+
+		require.NoError(t, reg.IdentityManager().Create(ctx, &identity.Identity{
+			Traits:   identity.Traits(fmt.Sprintf(`{"email":"%s","phone":"+49 151 12345678"}`, testhelpers.RandomEmail())),
+			SchemaID: config.DefaultIdentityTraitsSchemaID,
+			State:    identity.StateActive,
+		}, identity.ManagerAllowWriteProtectedTraits))
+
+		address, err := reg.IdentityPool().FindRecoveryAddressByValue(ctx, identity.RecoveryAddressTypePhone, "+4915112345678")
+		require.NoError(t, err)
+		assert.Equal(t, identity.RecoveryAddressTypePhone, address.Via)
+
+		t.Run("case=rejects invalid phone numbers", func(t *testing.T) {
+			body := expectValidationError(t, nil, RecoveryFlowTypeAPI, func(v url.Values) {
+				v.Set("phone", "015112345678")
+			})
+			assert.EqualValues(t, text.ErrorValidationInvalidPhoneNumber, gjson.Get(body, "ui.nodes.#(attributes.name==phone).messages.0.id").Int(), "%s", body)
+		})
+
+		c := testhelpers.NewClientWithCookies(t)
+		body := expectSuccessfulRecovery(t, c, RecoveryFlowTypeBrowser, func(v url.Values) {
+			v.Set("phone", "+49 (151) 123-456-78")
+		})
+		assert.EqualValues(t, text.InfoSelfServiceRecoverySMSWithCodeSent, gjson.Get(body, "ui.messages.0.id").Int(), "%s", body)
+		assert.Equal(t, "+4915112345678", gjson.Get(body, "ui.nodes.#(attributes.name==phone).attributes.value").String(), "%s", body)
+
+		message := testhelpers.CourierExpectMessage(ctx, t, reg, "+4915112345678", "")
+		assert.Equal(t, courier.MessageTypeSMS, message.Type)
+		recoveryCode := gjson.GetBytes(message.TemplateData, "Code").String()
+		require.NotEmpty(t, recoveryCode)
+
+		submitRecoveryCode(t, c, body, RecoveryFlowTypeBrowser, recoveryCode, http.StatusOK)
+	})
+
+	t.Run("description=should recover an account by identifier", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryIdentifiers, true)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryIdentifiers, false)
+		})
+
+		recoveryEmail := testhelpers.RandomEmail()
+		username := "recover-" + x.NewUUID().String()
+		require.NoError(t, reg.IdentityManager().Create(ctx, &identity.Identity{
+			Credentials: map[identity.CredentialsType]identity.Credentials{
+				identity.CredentialsTypePassword: {
+					Type:   identity.CredentialsTypePassword,
+					Config: sqlxx.JSONRawMessage(`{"hashed_password":"$2a$08$.cOYmAd.vCpDOoiVJrO5B.hjTLKQQ6cAK40u8uB.FnZDyPvVvQ9Q."}`),
+				},
+			},
+			Traits:   identity.Traits(fmt.Sprintf(`{"email":"%s","username":"%s"}`, recoveryEmail, username)),
+			SchemaID: config.DefaultIdentityTraitsSchemaID,
+			State:    identity.StateActive,
+		}, identity.ManagerAllowWriteProtectedTraits))
+
+		t.Run("case=unknown identifiers look like known ones", func(t *testing.T) {
+			body := expectSuccessfulRecovery(t, testhelpers.NewClientWithCookies(t), RecoveryFlowTypeBrowser, func(v url.Values) {
+				v.Set("identifier", "unknown-"+username)
+			})
+			assertx.EqualAsJSON(t, text.NewRecoveryEmailWithCodeSent(), json.RawMessage(gjson.Get(body, "ui.messages.0").Raw))
+		})
+
+		c := testhelpers.NewClientWithCookies(t)
+		body := expectSuccessfulRecovery(t, c, RecoveryFlowTypeBrowser, func(v url.Values) {
+			v.Set("identifier", strings.ToUpper(username))
+		})
+		assertx.EqualAsJSON(t, text.NewRecoveryCodeSentToAddress(x.MaskAddress(recoveryEmail)), json.RawMessage(gjson.Get(body, "ui.messages.0").Raw))
+		assert.Equal(t, strings.ToUpper(username), gjson.Get(body, "ui.nodes.#(attributes.name==identifier).attributes.value").String(), "%s", body)
+		assert.NotContains(t, body, recoveryEmail)
+
+		message := testhelpers.CourierExpectMessage(ctx, t, reg, recoveryEmail, "Recover access to your account")
+		recoveryCode := testhelpers.CourierExpectCodeInMessage(t, message, 1)
+
+		submitRecoveryCode(t, c, body, RecoveryFlowTypeBrowser, recoveryCode, http.StatusOK)
+	})
+
 	t.Run("description=should not be able to use first code after re-sending email", func(t *testing.T) {
 		recoveryEmail := testhelpers.RandomEmail()
 		createIdentityToRecover(t, reg, recoveryEmail)
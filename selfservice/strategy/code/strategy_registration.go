@@ -105,6 +105,11 @@ func (s *Strategy) handleIdentityTraits(ctx context.Context, f *registration.Flo
 		traits = json.RawMessage("{}")
 	}
 
+	traits, err := registration.MergeStepTraits(f, traits)
+	if err != nil {
+		return err
+	}
+
 	// we explicitly set the Code credentials type
 	i.Traits = identity.Traits(traits)
 	if err := i.SetCredentialsWithConfig(s.ID(), identity.Credentials{Type: s.ID(), Identifiers: []string{}}, &identity.CredentialsCode{UsedAt: sql.NullTime{}}); err != nil {
@@ -122,6 +127,10 @@ func (s *Strategy) handleIdentityTraits(ctx context.Context, f *registration.Flo
 		return err
 	}
 
+	if err := s.deps.IdentityValidator().ValidateRegistrationEmailDomains(ctx, i); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -257,7 +266,7 @@ func (s *Strategy) registrationVerifyCode(ctx context.Context, f *registration.F
 	}
 
 	// Step 3: Attempt to use the code
-	registrationCode, err := s.deps.RegistrationCodePersister().UseRegistrationCode(ctx, f.ID, p.Code, cred.Identifiers...)
+	registrationCode, err := s.deps.CodeSender().UseRegistrationCode(ctx, f.ID, p.Code, cred.Identifiers...)
 	if err != nil {
 		if errors.Is(err, ErrCodeNotFound) {
 			return errors.WithStack(schema.NewRegistrationCodeInvalid())
@@ -40,7 +40,53 @@ func (s *Strategy) RegisterAdminVerificationRoutes(admin *x.RouterAdmin) {
 // Otherwise, the default email input is added.
 // If the flow is a browser flow, the CSRF token is added to the UI.
 func (s *Strategy) PopulateVerificationMethod(r *http.Request, f *verification.Flow) error {
-	return s.PopulateMethod(r, f)
+	if err := s.PopulateMethod(r, f); err != nil {
+		return err
+	}
+
+	if f.State == flow.StateChooseMethod {
+		s.upsertVerificationAddressNodes(r.Context(), f.UI.GetNodes(), nil, nil)
+	}
+	return nil
+}
+
+// submittedVerificationAddress returns the type and value of the address submitted to the verification flow.
+// Phone numbers are normalized to E.164 format.
+func (s *Strategy) submittedVerificationAddress(ctx context.Context, body *updateVerificationFlowWithCodeMethod) (identity.VerifiableAddressType, string, error) {
+	if len(body.Email) > 0 {
+		return identity.VerifiableAddressTypeEmail, body.Email, nil
+	}
+
+	if len(body.Phone) > 0 && s.deps.Config().SelfServiceFlowVerificationPhoneNumbers(ctx) {
+		phone, err := x.NormalizePhoneNumber(body.Phone)
+		if err != nil {
+			return "", "", schema.NewInvalidPhoneNumberError("#/phone")
+		}
+		return identity.VerifiableAddressTypePhone, phone, nil
+	}
+
+	return "", "", schema.NewRequiredError("#/email", "email")
+}
+
+// upsertVerificationAddressNodes adds the fields for the address to verify. The email field is only required
+// if phone numbers can not be verified.
+func (s *Strategy) upsertVerificationAddressNodes(ctx context.Context, nodes *node.Nodes, email, phone interface{}) {
+	if !s.deps.Config().SelfServiceFlowVerificationPhoneNumbers(ctx) {
+		nodes.Upsert(
+			node.NewInputField("email", email, node.CodeGroup, node.InputAttributeTypeEmail, node.WithRequiredInputAttribute).
+				WithMetaLabel(text.NewInfoNodeInputEmail()),
+		)
+		return
+	}
+
+	nodes.Upsert(
+		node.NewInputField("email", email, node.CodeGroup, node.InputAttributeTypeEmail).
+			WithMetaLabel(text.NewInfoNodeInputEmail()),
+	)
+	nodes.Upsert(
+		node.NewInputField("phone", phone, node.CodeGroup, node.InputAttributeTypeTel).
+			WithMetaLabel(text.NewInfoNodeInputPhoneNumber()),
+	)
 }
 
 func (s *Strategy) decodeVerification(r *http.Request) (*updateVerificationFlowWithCodeMethod, error) {
@@ -67,10 +113,13 @@ func (s *Strategy) decodeVerification(r *http.Request) (*updateVerificationFlowW
 // handleVerificationError is a convenience function for handling all types of errors that may occur (e.g. validation error).
 func (s *Strategy) handleVerificationError(w http.ResponseWriter, r *http.Request, f *verification.Flow, body *updateVerificationFlowWithCodeMethod, err error) error {
 	if f != nil {
+		email, phone := "", ""
+		if body != nil {
+			email, phone = body.Email, body.Phone
+		}
+
 		f.UI.SetCSRF(s.deps.GenerateCSRFToken(r))
-		f.UI.GetNodes().Upsert(
-			node.NewInputField("email", body.Email, node.CodeGroup, node.InputAttributeTypeEmail, node.WithRequiredInputAttribute).WithMetaLabel(text.NewInfoNodeInputEmail()),
-		)
+		s.upsertVerificationAddressNodes(r.Context(), f.UI.GetNodes(), email, phone)
 	}
 
 	return err
@@ -91,6 +140,15 @@ type updateVerificationFlowWithCodeMethod struct {
 	// required: false
 	Email string `form:"email" json:"email"`
 
+	// The phone number to verify
+	//
+	// Only used if `selfservice.flows.verification.phone_numbers` is enabled and no email address was submitted.
+	// The phone number must include the country calling code. If it belongs to a valid account, a verification
+	// code will be sent via SMS.
+	//
+	// required: false
+	Phone string `form:"phone" json:"phone"`
+
 	// Sending the anti-csrf token is only required for browser login flows.
 	CSRFToken string `form:"csrf_token" json:"csrf_token"`
 
@@ -142,12 +200,10 @@ func (s *Strategy) Verify(w http.ResponseWriter, r *http.Request, f *verificatio
 		return s.handleVerificationError(w, r, f, body, err)
 	}
 
-	switch f.State {
-	case flow.StateChooseMethod:
-		fallthrough
-	case flow.StateEmailSent:
+	switch {
+	case verification.StateMachine.CanTransition(f.State, flow.StateEmailSent):
 		return s.verificationHandleFormSubmission(w, r, f, body)
-	case flow.StatePassedChallenge:
+	case f.State == flow.StatePassedChallenge:
 		return s.retryVerificationFlowWithMessage(w, r, f.Type, text.NewErrorValidationVerificationRetrySuccess())
 	default:
 		return s.retryVerificationFlowWithMessage(w, r, f.Type, text.NewErrorValidationVerificationStateFailure())
@@ -187,9 +243,12 @@ func (s *Strategy) verificationHandleFormSubmission(w http.ResponseWriter, r *ht
 
 		// If not GET: try to use the submitted code
 		return s.verificationUseCode(w, r, body.Code, f)
-	} else if len(body.Email) == 0 {
-		// If no code and no email was provided, fail with a validation error
-		return s.handleVerificationError(w, r, f, body, schema.NewRequiredError("#/email", "email"))
+	}
+
+	// If no code and no address was provided, fail with a validation error
+	via, to, err := s.submittedVerificationAddress(r.Context(), body)
+	if err != nil {
+		return s.handleVerificationError(w, r, f, body, err)
 	}
 
 	if err := flow.EnsureCSRF(s.deps, r, f.Type, s.deps.Config().DisableAPIFlowEnforcement(r.Context()), s.deps.GenerateCSRFToken, body.CSRFToken); err != nil {
@@ -200,26 +259,30 @@ func (s *Strategy) verificationHandleFormSubmission(w http.ResponseWriter, r *ht
 		return s.handleVerificationError(w, r, f, body, err)
 	}
 
-	if err := s.deps.CodeSender().SendVerificationCode(r.Context(), f, identity.VerifiableAddressTypeEmail, body.Email); err != nil {
+	if err := s.deps.CodeSender().SendVerificationCode(r.Context(), f, via, to); err != nil {
 		if !errors.Is(err, ErrUnknownAddress) {
 			return s.handleVerificationError(w, r, f, body, err)
 		}
 		// Continue execution
 	}
 
-	f.State = flow.StateEmailSent
+	if err := verification.StateMachine.Transition(r.Context(), f, flow.StateEmailSent); err != nil {
+		return s.handleVerificationError(w, r, f, body, err)
+	}
 
 	if err := s.PopulateVerificationMethod(r, f); err != nil {
 		return s.handleVerificationError(w, r, f, body, err)
 	}
 
-	if body.Email != "" {
-		f.UI.Nodes.Append(
-			node.NewInputField("email", body.Email, node.CodeGroup, node.InputAttributeTypeSubmit).
-				WithMetaLabel(text.NewInfoNodeResendOTP()),
-		)
+	if via == identity.VerifiableAddressTypePhone {
+		f.UI.Messages.Set(text.NewVerificationSMSWithCodeSent())
 	}
 
+	f.UI.Nodes.Append(
+		node.NewInputField(string(via), to, node.CodeGroup, node.InputAttributeTypeSubmit).
+			WithMetaLabel(text.NewInfoNodeResendOTP()),
+	)
+
 	if err := s.deps.VerificationFlowPersister().UpdateVerificationFlow(r.Context(), f); err != nil {
 		return s.handleVerificationError(w, r, f, body, err)
 	}
@@ -228,7 +291,7 @@ func (s *Strategy) verificationHandleFormSubmission(w http.ResponseWriter, r *ht
 }
 
 func (s *Strategy) verificationUseCode(w http.ResponseWriter, r *http.Request, codeString string, f *verification.Flow) error {
-	code, err := s.deps.VerificationCodePersister().UseVerificationCode(r.Context(), f.ID, codeString)
+	code, err := s.deps.VerificationCodePersister().UseVerificationCode(r.Context(), f.ID, codeString, validateWith(s.deps.CodeBackend(r.Context()), codeString))
 	if errors.Is(err, ErrCodeNotFound) {
 		f.UI.Messages.Clear()
 		f.UI.Messages.Add(text.NewErrorValidationVerificationCodeInvalidOrAlreadyUsed())
@@ -247,10 +310,6 @@ func (s *Strategy) verificationUseCode(w http.ResponseWriter, r *http.Request, c
 		return s.retryVerificationFlowWithError(w, r, f.Type, err)
 	}
 
-	if err := s.deps.VerificationExecutor().PostVerificationHook(w, r, f, i); err != nil {
-		return s.retryVerificationFlowWithError(w, r, f.Type, err)
-	}
-
 	address := code.VerifiableAddress
 	address.Verified = true
 	verifiedAt := sqlxx.NullTime(time.Now().UTC())
@@ -260,6 +319,12 @@ func (s *Strategy) verificationUseCode(w http.ResponseWriter, r *http.Request, c
 		return s.retryVerificationFlowWithError(w, r, f.Type, err)
 	}
 
+	// The hooks run once the address is verified, so they see the identity as it is stored now.
+	i.SetVerifiableAddress(address)
+	if err := s.deps.VerificationExecutor().PostVerificationHook(w, r, f, i); err != nil {
+		return s.retryVerificationFlowWithError(w, r, f.Type, err)
+	}
+
 	returnTo := f.ContinueURL(r.Context(), s.deps.Config())
 
 	f.UI = &container.Container{
@@ -267,7 +332,9 @@ func (s *Strategy) verificationUseCode(w http.ResponseWriter, r *http.Request, c
 		Action: returnTo.String(),
 	}
 
-	f.State = flow.StatePassedChallenge
+	if err := verification.StateMachine.Transition(r.Context(), f, flow.StatePassedChallenge); err != nil {
+		return s.retryVerificationFlowWithError(w, r, flow.TypeBrowser, err)
+	}
 	// See https://github.com/ory/kratos/issues/1547
 	f.SetCSRFToken(flow.GetCSRFToken(s.deps, w, r, f.Type))
 	f.UI.Messages.Set(text.NewInfoSelfServiceVerificationSuccessful())
@@ -351,7 +418,15 @@ func (s *Strategy) retryVerificationFlowWithError(w http.ResponseWriter, r *http
 }
 
 func (s *Strategy) SendVerificationEmail(ctx context.Context, f *verification.Flow, i *identity.Identity, a *identity.VerifiableAddress) (err error) {
-	rawCode := GenerateCode()
+	rawCode, err := s.deps.CodeBackend(ctx).GenerateCode(ctx, &CodeRequest{
+		Flow:       flow.VerificationFlow,
+		FlowID:     f.ID,
+		IdentityID: i.ID,
+		Address:    a.Value,
+	})
+	if err != nil {
+		return err
+	}
 
 	code, err := s.deps.VerificationCodePersister().CreateVerificationCode(ctx, &CreateVerificationCodeParams{
 		RawCode:           rawCode,
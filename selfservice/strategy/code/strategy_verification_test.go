@@ -25,12 +25,14 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/tidwall/gjson"
 
+	"github.com/ory/kratos/courier"
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
 	"github.com/ory/kratos/internal/testhelpers"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/selfservice/hook"
 	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 	"github.com/ory/x/assertx"
@@ -558,6 +560,90 @@ func TestVerification(t *testing.T) {
 		assert.True(t, gjson.Get(body, "ui.nodes.#(attributes.name==email)").Exists())
 	})
 
+	t.Run("description=should run post verification hooks after the address was verified", func(t *testing.T) {
+		var verified []identity.VerifiableAddress
+		reg.WithHooks(map[string]func(config.SelfServiceHook) interface{}{
+			"err": func(c config.SelfServiceHook) interface{} {
+				return &hook.Error{Config: c.Config}
+			},
+			"capture": func(config.SelfServiceHook) interface{} {
+				return verification.PostHookExecutorFunc(func(_ http.ResponseWriter, r *http.Request, _ *verification.Flow, i *identity.Identity) error {
+					stored, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), i.ID)
+					require.NoError(t, err)
+					verified = append(i.VerifiableAddresses, stored.VerifiableAddresses...)
+					return nil
+				})
+			},
+		})
+		conf.MustSet(ctx, config.HookStrategyKey(config.ViperKeySelfServiceVerificationAfter, config.HookGlobal), []config.SelfServiceHook{{Name: "capture"}})
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.HookStrategyKey(config.ViperKeySelfServiceVerificationAfter, config.HookGlobal), nil)
+		})
+
+		email := testhelpers.RandomEmail()
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(fmt.Sprintf(`{"email":"%s"}`, email))
+		require.NoError(t, reg.IdentityManager().Create(ctx, i, identity.ManagerAllowWriteProtectedTraits))
+
+		c := testhelpers.NewClientWithCookies(t)
+		body := expectSuccess(t, c, false, false, func(v url.Values) {
+			v.Set("email", email)
+		})
+
+		message := testhelpers.CourierExpectMessage(ctx, t, reg, email, "Please verify your email address")
+		verificationCode := testhelpers.CourierExpectCodeInMessage(t, message, 1)
+
+		body, res := submitVerificationCode(t, body, c, verificationCode)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.EqualValues(t, "passed_challenge", gjson.Get(body, "state").String(), "%s", body)
+
+		require.Len(t, verified, 2, "the hook must see the identity passed to it and the stored identity")
+		for _, address := range verified {
+			assert.Equal(t, email, address.Value)
+			assert.True(t, address.Verified)
+			assert.Equal(t, identity.VerifiableAddressStatusCompleted, address.Status)
+		}
+	})
+
+	t.Run("description=should verify a phone number", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceVerificationPhoneNumbers, true)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceVerificationPhoneNumbers, false)
+		})
+
+		i := &identity.Identity{
+			Traits:   identity.Traits(fmt.Sprintf(`{"email":"%s","phone":"+49 151 12345678"}`, testhelpers.RandomEmail())),
+			SchemaID: config.DefaultIdentityTraitsSchemaID,
+			State:    identity.StateActive,
+		}
+		require.NoError(t, reg.IdentityManager().Create(ctx, i, identity.ManagerAllowWriteProtectedTraits))
+
+		address, err := reg.IdentityPool().FindVerifiableAddressByValue(ctx, identity.VerifiableAddressTypePhone, "+4915112345678")
+		require.NoError(t, err)
+		assert.False(t, address.Verified)
+
+		c := testhelpers.NewClientWithCookies(t)
+		body := expectSuccess(t, c, false, false, func(v url.Values) {
+			v.Set("phone", "+49 (151) 123-456-78")
+		})
+		assert.EqualValues(t, text.InfoSelfServiceVerificationSMSWithCodeSent, gjson.Get(body, "ui.messages.0.id").Int(), "%s", body)
+		assert.Equal(t, "+4915112345678", gjson.Get(body, "ui.nodes.#(attributes.name==phone).attributes.value").String(), "%s", body)
+
+		message := testhelpers.CourierExpectMessage(ctx, t, reg, "+4915112345678", "")
+		assert.Equal(t, courier.MessageTypeSMS, message.Type)
+		verificationCode := gjson.GetBytes(message.TemplateData, "Code").String()
+		require.NotEmpty(t, verificationCode)
+
+		body, res := submitVerificationCode(t, body, c, verificationCode)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.EqualValues(t, "passed_challenge", gjson.Get(body, "state").String(), "%s", body)
+
+		address, err = reg.IdentityPool().FindVerifiableAddressByValue(ctx, identity.VerifiableAddressTypePhone, "+4915112345678")
+		require.NoError(t, err)
+		assert.True(t, address.Verified)
+		assert.EqualValues(t, identity.VerifiableAddressStatusCompleted, address.Status)
+	})
+
 	t.Run("description=should be able to verify already verified email address", func(t *testing.T) {
 		email := strings.ToLower(testhelpers.RandomEmail())
 		createIdentityToRecover(t, reg, email)
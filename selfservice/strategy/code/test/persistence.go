@@ -13,8 +13,10 @@ import (
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/strategy/code"
 	"github.com/ory/x/randx"
+	"github.com/ory/x/sqlcon"
 
 	"github.com/go-faker/faker/v4"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -60,7 +62,7 @@ func TestPersister(ctx context.Context, conf *config.Config, p interface {
 			}
 
 			t.Run("case=should error when the recovery token does not exist", func(t *testing.T) {
-				_, err := p.UseRecoveryCode(ctx, x.NewUUID(), "i-do-not-exist")
+				_, err := p.UseRecoveryCode(ctx, x.NewUUID(), "i-do-not-exist", nil)
 				require.Error(t, err)
 			})
 
@@ -82,28 +84,75 @@ func TestPersister(ctx context.Context, conf *config.Config, p interface {
 
 				t.Run("not work on another network", func(t *testing.T) {
 					_, p := testhelpers.NewNetwork(t, ctx, p)
-					_, err := p.UseRecoveryCode(ctx, f.ID, dto.RawCode)
+					_, err := p.UseRecoveryCode(ctx, f.ID, dto.RawCode, nil)
 					require.ErrorIs(t, err, code.ErrCodeNotFound)
 				})
 
-				actual, err := p.UseRecoveryCode(ctx, f.ID, dto.RawCode)
+				actual, err := p.UseRecoveryCode(ctx, f.ID, dto.RawCode, nil)
 				require.NoError(t, err)
 				assert.Equal(t, nid, actual.NID)
 				assert.Equal(t, dto.IdentityID, actual.IdentityID)
 				assert.NotEqual(t, dto.RawCode, actual.CodeHMAC)
 				assert.EqualValues(t, f.ID, actual.FlowID)
 
-				_, err = p.UseRecoveryCode(ctx, f.ID, dto.RawCode)
+				_, err = p.UseRecoveryCode(ctx, f.ID, dto.RawCode, nil)
 				require.ErrorIs(t, err, code.ErrCodeAlreadyUsed)
 			})
 
+			t.Run("case=should only use a code the validator accepted", func(t *testing.T) {
+				dto, f, a := newRecoveryCodeDTO(t, "validated-code@ory.sh")
+				_, err := p.CreateRecoveryCode(ctx, dto)
+				require.NoError(t, err)
+
+				var req *code.CodeRequest
+				_, err = p.UseRecoveryCode(ctx, f.ID, dto.RawCode, func(_ context.Context, r *code.CodeRequest) error {
+					req = r
+					return errors.WithStack(code.ErrCodeNotFound)
+				})
+				require.ErrorIs(t, err, code.ErrCodeNotFound)
+				require.NotNil(t, req)
+				assert.Equal(t, f.ID, req.FlowID)
+				assert.Equal(t, dto.IdentityID, req.IdentityID)
+				assert.Equal(t, a.Value, req.Address)
+
+				actual, err := p.UseRecoveryCode(ctx, f.ID, dto.RawCode, nil)
+				require.NoError(t, err, "the rejected code must not be marked as used")
+				assert.Equal(t, a.ID, actual.RecoveryAddress.ID)
+			})
+
+			t.Run("case=should only store the digest of the code", func(t *testing.T) {
+				dto, _, _ := newRecoveryCodeDTO(t, "digest-code@ory.sh")
+				created, err := p.CreateRecoveryCode(ctx, dto)
+				require.NoError(t, err)
+
+				var stored []string
+				require.NoError(t, p.GetConnection(ctx).RawQuery("SELECT code FROM identity_recovery_codes WHERE id = ?", created.ID).All(&stored))
+				require.Len(t, stored, 1)
+				assert.NotEqual(t, dto.RawCode, stored[0])
+				assert.Equal(t, created.CodeHMAC, stored[0])
+			})
+
+			t.Run("case=should use codes created before the secrets were rotated", func(t *testing.T) {
+				dto, f, _ := newRecoveryCodeDTO(t, "rotated-secret@ory.sh")
+				_, err := p.CreateRecoveryCode(ctx, dto)
+				require.NoError(t, err)
+
+				conf.MustSet(ctx, config.ViperKeySecretsDefault, []string{"secret-c", "secret-a", "secret-b"})
+				t.Cleanup(func() {
+					conf.MustSet(ctx, config.ViperKeySecretsDefault, []string{"secret-a", "secret-b"})
+				})
+
+				_, err = p.UseRecoveryCode(ctx, f.ID, dto.RawCode, nil)
+				require.NoError(t, err)
+			})
+
 			t.Run("case=should not be able to use expired codes", func(t *testing.T) {
 				dto, f, _ := newRecoveryCodeDTO(t, "expired-code@ory.sh")
 				dto.ExpiresIn = -time.Hour
 				_, err := p.CreateRecoveryCode(ctx, dto)
 				require.NoError(t, err)
 
-				_, err = p.UseRecoveryCode(ctx, f.ID, dto.RawCode)
+				_, err = p.UseRecoveryCode(ctx, f.ID, dto.RawCode, nil)
 				assert.Error(t, err)
 			})
 
@@ -113,15 +162,34 @@ func TestPersister(ctx context.Context, conf *config.Config, p interface {
 				require.NoError(t, err)
 
 				for i := 1; i <= 5; i++ {
-					_, err = p.UseRecoveryCode(ctx, f.ID, "i-do-not-exist")
+					_, err = p.UseRecoveryCode(ctx, f.ID, "i-do-not-exist", nil)
 					require.Error(t, err)
 				}
 
-				_, err = p.UseRecoveryCode(ctx, f.ID, "i-do-not-exist")
+				_, err = p.UseRecoveryCode(ctx, f.ID, "i-do-not-exist", nil)
 				require.ErrorIs(t, err, code.ErrCodeSubmittedTooOften)
 
 				// Submit again, just to be sure
-				_, err = p.UseRecoveryCode(ctx, f.ID, "i-do-not-exist")
+				_, err = p.UseRecoveryCode(ctx, f.ID, "i-do-not-exist", nil)
+				require.ErrorIs(t, err, code.ErrCodeSubmittedTooOften)
+			})
+
+			t.Run("case=should respect configured max submissions", func(t *testing.T) {
+				conf.MustSet(ctx, config.ViperKeyCodeMaxSubmissions, 2)
+				t.Cleanup(func() {
+					conf.MustSet(ctx, config.ViperKeyCodeMaxSubmissions, nil)
+				})
+
+				dto, f, _ := newRecoveryCodeDTO(t, testhelpers.RandomEmail())
+				_, err := p.CreateRecoveryCode(ctx, dto)
+				require.NoError(t, err)
+
+				for i := 1; i <= 2; i++ {
+					_, err = p.UseRecoveryCode(ctx, f.ID, "i-do-not-exist", nil)
+					require.ErrorIs(t, err, code.ErrCodeNotFound)
+				}
+
+				_, err = p.UseRecoveryCode(ctx, f.ID, "i-do-not-exist", nil)
 				require.ErrorIs(t, err, code.ErrCodeSubmittedTooOften)
 			})
 
@@ -145,6 +213,43 @@ func TestPersister(ctx context.Context, conf *config.Config, p interface {
 				require.NoError(t, err)
 				require.Equal(t, 0, count)
 			})
+
+			t.Run("case=should invalidate the recovery artifacts of an identity", func(t *testing.T) {
+				dto, f, _ := newRecoveryCodeDTO(t, testhelpers.RandomEmail())
+				for i := 0; i < 3; i++ {
+					dto.RawCode = string(randx.MustString(8, randx.Numeric))
+					_, err := p.CreateRecoveryCode(ctx, dto)
+					require.NoError(t, err)
+				}
+
+				other, otherFlow, _ := newRecoveryCodeDTO(t, testhelpers.RandomEmail())
+				_, err := p.CreateRecoveryCode(ctx, other)
+				require.NoError(t, err)
+
+				invalidated, err := p.InvalidateRecoveryArtifacts(ctx, dto.IdentityID)
+				require.NoError(t, err)
+				assert.EqualValues(t, 3, invalidated.Codes)
+				assert.EqualValues(t, 0, invalidated.Links)
+				assert.EqualValues(t, 1, invalidated.Flows)
+
+				_, err = p.GetRecoveryFlow(ctx, f.ID)
+				require.ErrorIs(t, err, sqlcon.ErrNoRows)
+
+				count, err := p.GetConnection(ctx).Where("identity_id = ?", dto.IdentityID).Count(&code.RecoveryCode{})
+				require.NoError(t, err)
+				assert.Equal(t, 0, count)
+
+				// Artifacts of other identities are kept.
+				_, err = p.GetRecoveryFlow(ctx, otherFlow.ID)
+				require.NoError(t, err)
+				count, err = p.GetConnection(ctx).Where("identity_id = ?", other.IdentityID).Count(&code.RecoveryCode{})
+				require.NoError(t, err)
+				assert.Equal(t, 1, count)
+
+				invalidated, err = p.InvalidateRecoveryArtifacts(ctx, dto.IdentityID)
+				require.NoError(t, err)
+				assert.Equal(t, &recovery.InvalidatedArtifacts{}, invalidated)
+			})
 		})
 	}
 }
@@ -0,0 +1,107 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package externalmfa
+
+import (
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/selfservice/strategy"
+	"github.com/ory/kratos/x"
+)
+
+const RouteCallback = "/self-service/methods/external_mfa/callback"
+
+func (s *Strategy) RegisterLoginRoutes(r *x.RouterPublic) {
+	if handle, _, _ := r.Lookup("GET", RouteCallback); handle == nil {
+		r.GET(RouteCallback, strategy.IsDisabled(s.d, s.ID().String(), s.handleCallback))
+	}
+}
+
+// External MFA Callback Parameters
+//
+// swagger:parameters externalMFACallback
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type externalMFACallback struct {
+	// The ID of the login flow waiting for the second factor.
+	//
+	// required: true
+	// in: query
+	Flow string `json:"flow"`
+
+	// The code the MFA provider appends when redirecting the user back.
+	//
+	// in: query
+	Code string `json:"code"`
+}
+
+// swagger:route GET /self-service/methods/external_mfa/callback frontend externalMFACallback
+//
+// # External MFA Provider Callback
+//
+// The external MFA provider redirects the user to this endpoint after a challenge which
+// required a redirect was completed. The endpoint remembers the code appended by the
+// provider and redirects the user to the login UI, where the login flow is submitted
+// again to finish the second factor.
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  303: emptyResponse
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (s *Strategy) handleCallback(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	notFound := errors.WithStack(herodot.ErrNotFound.WithReason("The login flow does not exist or is not waiting for the MFA provider."))
+
+	id, err := uuid.FromString(r.URL.Query().Get("flow"))
+	if err != nil {
+		s.d.Writer().WriteError(w, r, notFound)
+		return
+	}
+
+	f, err := s.d.LoginFlowPersister().GetLoginFlow(ctx, id)
+	if err != nil {
+		s.d.Writer().WriteError(w, r, notFound)
+		return
+	}
+
+	tx, err := getTransaction(f.InternalContext)
+	if err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	} else if tx == nil {
+		s.d.Writer().WriteError(w, r, notFound)
+		return
+	}
+
+	if err := f.Valid(); err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if code := r.URL.Query().Get("code"); code != "" {
+		tx.Code = code
+		f.InternalContext, err = setTransaction(f.InternalContext, tx)
+		if err != nil {
+			s.d.Writer().WriteError(w, r, errors.WithStack(err))
+			return
+		}
+
+		if err := s.d.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
+			s.d.Writer().WriteError(w, r, err)
+			return
+		}
+	}
+
+	http.Redirect(w, r, f.AppendTo(s.d.Config().SelfServiceFlowLoginUI(ctx)).String(), http.StatusSeeOther)
+}
@@ -0,0 +1,292 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package externalmfa
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/httpx"
+	"github.com/ory/x/urlx"
+)
+
+// pollInterval is the interval in which the provider is asked for the result
+// of a challenge while waiting for the user to complete it.
+const pollInterval = time.Second
+
+func (s *Strategy) PopulateLoginMethod(r *http.Request, requestedAAL identity.AuthenticatorAssuranceLevel, sr *login.Flow) error {
+	// This strategy can only solve AAL2
+	if requestedAAL != identity.AuthenticatorAssuranceLevel2 {
+		return nil
+	}
+
+	// Identities are enrolled at the provider, which is why the method is
+	// offered to all identities.
+	sr.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	sr.UI.GetNodes().Append(node.NewInputField("method", s.ID(), node.ExternalMFAGroup, node.InputAttributeTypeSubmit).WithMetaLabel(text.NewInfoSelfServiceLoginExternalMFA()))
+
+	return nil
+}
+
+func (s *Strategy) handleLoginError(r *http.Request, f *login.Flow, err error) error {
+	if errors.Is(err, flow.ErrCompletedByStrategy) {
+		return err
+	}
+
+	if f != nil && f.Type == flow.TypeBrowser {
+		f.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	}
+
+	return err
+}
+
+// Update Login Flow with External MFA Method
+//
+// Submitting this payload starts a challenge at the external MFA provider.
+// Submitting it again while the challenge is pending waits for its result.
+//
+// swagger:model updateLoginFlowWithExternalMFAMethod
+type updateLoginFlowWithExternalMFAMethod struct {
+	// Method should be set to "external_mfa" when logging in using the external MFA strategy.
+	//
+	// required: true
+	Method string `json:"method"`
+
+	// Sending the anti-csrf token is only required for browser login flows.
+	CSRFToken string `json:"csrf_token"`
+}
+
+func (s *Strategy) Login(w http.ResponseWriter, r *http.Request, f *login.Flow, identityID uuid.UUID) (i *identity.Identity, err error) {
+	if err := login.CheckAAL(f, identity.AuthenticatorAssuranceLevel2); err != nil {
+		return nil, err
+	}
+
+	if err := flow.MethodEnabledAndAllowedFromRequest(r, f.GetFlowName(), s.ID().String(), s.d); err != nil {
+		return nil, err
+	}
+
+	var p updateLoginFlowWithExternalMFAMethod
+	if err := s.hd.Decode(r, &p,
+		decoderx.HTTPDecoderSetValidatePayloads(true),
+		decoderx.MustHTTPRawJSONSchemaCompiler(loginSchema),
+		decoderx.HTTPDecoderJSONFollowsFormFormat()); err != nil {
+		return nil, s.handleLoginError(r, f, err)
+	}
+
+	if err := flow.EnsureCSRF(s.d, r, f.Type, s.d.Config().DisableAPIFlowEnforcement(r.Context()), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		return nil, s.handleLoginError(r, f, err)
+	}
+
+	tx, err := getTransaction(f.InternalContext)
+	if err != nil {
+		return nil, s.handleLoginError(r, f, err)
+	} else if tx == nil {
+		return nil, s.handleLoginError(r, f, s.initiate(w, r, f, identityID))
+	} else if tx.expired() {
+		return nil, s.handleLoginError(r, f, s.discardTransaction(r.Context(), f, schema.NewExternalMFAExpiredError()))
+	}
+
+	status, err := s.waitForResult(r.Context(), f, identityID, tx)
+	if err != nil {
+		return nil, s.handleLoginError(r, f, err)
+	}
+
+	switch {
+	case status == StatusApproved:
+		i, err := s.completeTransaction(r.Context(), f, identityID)
+		if err != nil {
+			return nil, s.handleLoginError(r, f, err)
+		}
+		return i, nil
+	case status == StatusDenied:
+		return nil, s.handleLoginError(r, f, s.discardTransaction(r.Context(), f, schema.NewExternalMFADeniedError()))
+	case tx.expired():
+		return nil, s.handleLoginError(r, f, s.discardTransaction(r.Context(), f, schema.NewExternalMFAExpiredError()))
+	}
+
+	// The challenge was not completed yet, the client needs to poll again. The
+	// flow is not persisted here as that could overwrite a concurrent callback.
+	return nil, s.handleLoginError(r, f, s.writePendingTransaction(w, r, f, tx, false))
+}
+
+// initiate starts a new challenge at the provider and redirects the user to
+// the provider if the challenge requires it.
+func (s *Strategy) initiate(w http.ResponseWriter, r *http.Request, f *login.Flow, identityID uuid.UUID) error {
+	ctx := r.Context()
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentity(ctx, identityID, identity.ExpandDefault)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.provider.Initiate(ctx, &InitiateRequest{
+		FlowID:    f.ID,
+		Identity:  i,
+		ReturnTo:  urlx.CopyWithQuery(urlx.AppendPaths(s.d.Config().SelfPublicURL(ctx), RouteCallback), url.Values{"flow": {f.ID.String()}}).String(),
+		ClientIP:  httpx.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		return err
+	}
+
+	tx := &transaction{
+		ID:        res.TransactionID,
+		ExpiresAt: x.Now().UTC().Add(s.d.Config().ExternalMFALifespan(ctx)),
+	}
+	f.InternalContext, err = setTransaction(f.InternalContext, tx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if res.RedirectURL == "" {
+		return s.writePendingTransaction(w, r, f, tx, true)
+	}
+
+	f.Active = s.ID()
+	if err := s.d.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
+		return err
+	}
+
+	if x.IsJSONRequest(r) {
+		s.d.Writer().WriteError(w, r, flow.NewBrowserLocationChangeRequiredError(res.RedirectURL))
+	} else {
+		http.Redirect(w, r, res.RedirectURL, http.StatusSeeOther)
+	}
+
+	return errors.WithStack(flow.ErrCompletedByStrategy)
+}
+
+// waitForResult asks the provider for the result of the challenge until it
+// was completed, the challenge expired, or the poll timeout was reached.
+func (s *Strategy) waitForResult(ctx context.Context, f *login.Flow, identityID uuid.UUID, tx *transaction) (Status, error) {
+	timeout := time.NewTimer(s.d.Config().ExternalMFAPollTimeout(ctx))
+	defer timeout.Stop()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	req := &VerifyRequest{
+		FlowID:        f.ID,
+		IdentityID:    identityID,
+		TransactionID: tx.ID,
+		Code:          tx.Code,
+	}
+
+	for {
+		status, err := s.provider.Verify(ctx, req)
+		if err != nil {
+			return "", err
+		} else if status != StatusPending || tx.expired() {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", errors.WithStack(ctx.Err())
+		case <-timeout.C:
+			return status, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// completeTransaction removes the completed challenge from the flow and
+// enrolls the identity in this method or records its use.
+func (s *Strategy) completeTransaction(ctx context.Context, f *login.Flow, identityID uuid.UUID) (_ *identity.Identity, err error) {
+	f.InternalContext, err = deleteTransaction(f.InternalContext)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	f.Active = s.ID()
+	if err := s.d.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("Could not update flow").WithDebug(err.Error()))
+	}
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Round(time.Second)
+	o := identity.CredentialsExternalMFAConfig{EnrolledAt: now}
+	c, ok := i.GetCredentials(s.ID())
+	if ok && len(c.Config) > 0 {
+		if err := json.Unmarshal(c.Config, &o); err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The external MFA credentials could not be decoded properly").WithDebug(err.Error()).WithWrap(err))
+		}
+	}
+	o.LastUsedAt = &now
+
+	encoded, err := json.Marshal(o)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to encode updated external MFA credentials.").WithDebug(err.Error()))
+	}
+
+	i.SetCredentials(s.ID(), identity.Credentials{
+		Type:        s.ID(),
+		Identifiers: []string{i.ID.String()},
+		Config:      encoded,
+	})
+	if err := s.d.PrivilegedIdentityPool().UpdateIdentity(ctx, i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// discardTransaction removes the challenge from the flow so that the next
+// submission starts a new one, and returns the given error.
+func (s *Strategy) discardTransaction(ctx context.Context, f *login.Flow, cause error) (err error) {
+	f.InternalContext, err = deleteTransaction(f.InternalContext)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := s.d.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
+		return err
+	}
+
+	return cause
+}
+
+func (s *Strategy) writePendingTransaction(w http.ResponseWriter, r *http.Request, f *login.Flow, tx *transaction, persist bool) error {
+	ctx := r.Context()
+
+	f.Active = s.ID()
+	f.UI.Messages.Set(text.NewInfoSelfServiceLoginExternalMFAPending(tx.ExpiresAt))
+	if f.Type == flow.TypeBrowser {
+		f.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	}
+
+	if persist {
+		if err := s.d.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
+			return err
+		}
+	}
+
+	if x.IsJSONRequest(r) {
+		s.d.Writer().WriteCode(w, r, http.StatusBadRequest, f)
+	} else {
+		http.Redirect(w, r, f.AppendTo(s.d.Config().SelfServiceFlowLoginUI(ctx)).String(), http.StatusSeeOther)
+	}
+
+	// The login flow is not completed until the provider confirmed the challenge.
+	return errors.WithStack(flow.ErrCompletedByStrategy)
+}
@@ -0,0 +1,229 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package externalmfa_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/driver"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/strategy/externalmfa"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+type mfaProvider struct {
+	sync.Mutex
+	redirectURL string
+	status      externalmfa.Status
+	initiated   []gjson.Result
+	verified    []gjson.Result
+}
+
+func (p *mfaProvider) set(status externalmfa.Status, redirectURL string) {
+	p.Lock()
+	defer p.Unlock()
+	p.status, p.redirectURL = status, redirectURL
+}
+
+func (p *mfaProvider) lastInitiated(t *testing.T) gjson.Result {
+	p.Lock()
+	defer p.Unlock()
+	require.NotEmpty(t, p.initiated)
+	return p.initiated[len(p.initiated)-1]
+}
+
+func (p *mfaProvider) lastVerified(t *testing.T) gjson.Result {
+	p.Lock()
+	defer p.Unlock()
+	require.NotEmpty(t, p.verified)
+	return p.verified[len(p.verified)-1]
+}
+
+func newProviderServer(t *testing.T) (*httptest.Server, *mfaProvider) {
+	provider := &mfaProvider{status: externalmfa.StatusPending}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		provider.Lock()
+		defer provider.Unlock()
+		switch r.URL.Path {
+		case "/initiate":
+			provider.initiated = append(provider.initiated, gjson.ParseBytes(body))
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"transaction_id": fmt.Sprintf("tx-%d", len(provider.initiated)),
+				"redirect_url":   provider.redirectURL,
+			})
+		case "/verify":
+			provider.verified = append(provider.verified, gjson.ParseBytes(body))
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": string(provider.status)})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, provider
+}
+
+func createIdentity(t *testing.T, reg driver.Registry) *identity.Identity {
+	ctx := context.Background()
+	identifier := x.NewUUID().String() + "@ory.sh"
+	p, err := reg.Hasher(ctx).Generate(ctx, []byte(x.NewUUID().String()))
+	require.NoError(t, err)
+
+	i := &identity.Identity{Traits: identity.Traits(fmt.Sprintf(`{"subject":"%s"}`, identifier))}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+
+	i.Credentials = map[identity.CredentialsType]identity.Credentials{
+		identity.CredentialsTypePassword: {
+			Type:        identity.CredentialsTypePassword,
+			Identifiers: []string{identifier},
+			Config:      sqlxx.JSONRawMessage(`{"hashed_password":"` + string(p) + `"}`),
+		},
+	}
+	require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentity(ctx, i))
+	return i
+}
+
+func TestCompleteLogin(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	providerTS, provider := newProviderServer(t)
+
+	body := "base64://" + base64.StdEncoding.EncodeToString([]byte("function(ctx) ctx"))
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypePassword), map[string]interface{}{"enabled": true})
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypeExternalMFA), map[string]interface{}{"enabled": true})
+	conf.MustSet(ctx, config.ViperKeyExternalMFAInitiateRequestConfig, map[string]interface{}{
+		"url":    providerTS.URL + "/initiate",
+		"method": "POST",
+		"body":   body,
+	})
+	conf.MustSet(ctx, config.ViperKeyExternalMFAVerifyRequestConfig, map[string]interface{}{
+		"url":    providerTS.URL + "/verify",
+		"method": "POST",
+		"body":   body,
+	})
+	conf.MustSet(ctx, config.ViperKeyExternalMFAPollTimeout, "1ms")
+
+	router := x.NewRouterPublic()
+	publicTS, _ := testhelpers.NewKratosServerWithRouters(t, reg, router, x.NewRouterAdmin())
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/login.schema.json")
+	conf.MustSet(ctx, config.ViperKeySecretsDefault, []string{"not-a-secure-session-key"})
+
+	submit := func(t *testing.T, id *identity.Identity) (func() (string, *http.Response), string) {
+		apiClient := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, id)
+		f := testhelpers.InitializeLoginFlowViaAPI(t, apiClient, publicTS, false, testhelpers.InitFlowWithAAL(identity.AuthenticatorAssuranceLevel2))
+
+		var found bool
+		for _, n := range f.Ui.Nodes {
+			if n.Group == "external_mfa" {
+				found = true
+			}
+		}
+		require.True(t, found)
+
+		return func() (string, *http.Response) {
+			return testhelpers.LoginMakeRequest(t, true, false, f, apiClient, `{"method":"external_mfa"}`)
+		}, f.Id
+	}
+
+	t.Run("case=should sign in once the provider approved the challenge", func(t *testing.T) {
+		provider.set(externalmfa.StatusPending, "")
+		id := createIdentity(t, reg)
+
+		do, flowID := submit(t, id)
+		body, res := do()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.EqualValues(t, text.InfoSelfServiceLoginExternalMFAPending, gjson.Get(body, "ui.messages.0.id").Int(), "%s", body)
+
+		initiated := provider.lastInitiated(t)
+		assert.Equal(t, flowID, initiated.Get("flow_id").String())
+		assert.Equal(t, id.ID.String(), initiated.Get("identity.id").String())
+		assert.False(t, initiated.Get("identity.credentials").Exists(), "%s", initiated.Raw)
+
+		// Still waiting for the user.
+		body, res = do()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.EqualValues(t, text.InfoSelfServiceLoginExternalMFAPending, gjson.Get(body, "ui.messages.0.id").Int(), "%s", body)
+		assert.Equal(t, flowID, provider.lastVerified(t).Get("flow_id").String())
+		assert.NotEmpty(t, provider.lastVerified(t).Get("transaction_id").String())
+
+		provider.set(externalmfa.StatusApproved, "")
+		body, res = do()
+		assert.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.EqualValues(t, identity.AuthenticatorAssuranceLevel2, gjson.Get(body, "session.authenticator_assurance_level").String(), "%s", body)
+		assert.EqualValues(t, identity.CredentialsTypeExternalMFA, gjson.Get(body, "session.authentication_methods.1.method").String(), "%s", body)
+
+		actual, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(ctx, id.ID)
+		require.NoError(t, err)
+		c, ok := actual.GetCredentials(identity.CredentialsTypeExternalMFA)
+		require.True(t, ok, "the identity must be enrolled after the first sign-in")
+		assert.Equal(t, []string{id.ID.String()}, c.Identifiers)
+		assert.True(t, gjson.GetBytes(c.Config, "last_used_at").Exists(), "%s", c.Config)
+	})
+
+	t.Run("case=should fail if the provider denied the challenge", func(t *testing.T) {
+		provider.set(externalmfa.StatusPending, "")
+		id := createIdentity(t, reg)
+
+		do, _ := submit(t, id)
+		_, res := do()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		first := provider.lastInitiated(t)
+
+		provider.set(externalmfa.StatusDenied, "")
+		body, res := do()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, text.NewErrorValidationExternalMFADenied().Text, gjson.Get(body, "ui.messages.0.text").String(), "%s", body)
+
+		// A new challenge is started on the next submission.
+		body, _ = do()
+		assert.EqualValues(t, text.InfoSelfServiceLoginExternalMFAPending, gjson.Get(body, "ui.messages.0.id").Int(), "%s", body)
+		assert.NotEqual(t, first.Raw, provider.lastInitiated(t).Raw)
+	})
+
+	t.Run("case=should redirect to the provider and pass on the callback code", func(t *testing.T) {
+		provider.set(externalmfa.StatusPending, "https://mfa.example.com/prompt")
+		id := createIdentity(t, reg)
+
+		do, flowID := submit(t, id)
+		body, res := do()
+		assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode, "%s", body)
+		assert.Equal(t, "https://mfa.example.com/prompt", gjson.Get(body, "redirect_browser_to").String(), "%s", body)
+
+		returnTo := provider.lastInitiated(t).Get("return_to").String()
+		assert.Contains(t, returnTo, externalmfa.RouteCallback)
+		assert.Contains(t, returnTo, flowID)
+
+		client := testhelpers.NewNoRedirectClientWithCookies(t)
+		res, err := client.Get(returnTo + "&code=provider-code")
+		require.NoError(t, err)
+		require.NoError(t, res.Body.Close())
+		assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+		assert.Contains(t, res.Header.Get("Location"), conf.SelfServiceFlowLoginUI(ctx).String())
+
+		provider.set(externalmfa.StatusApproved, "")
+		body, res = do()
+		assert.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.Equal(t, "provider-code", provider.lastVerified(t).Get("code").String())
+	})
+}
@@ -0,0 +1,159 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package externalmfa
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/request"
+)
+
+// Status is the result of a challenge at the external MFA provider.
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusDenied   Status = "denied"
+)
+
+type (
+	// Provider starts challenges at the external MFA provider and reports
+	// their results.
+	//
+	// Implementations must not block until the challenge was completed. The
+	// result is requested with Verify until it is no longer pending.
+	Provider interface {
+		Initiate(ctx context.Context, req *InitiateRequest) (*InitiateResponse, error)
+		Verify(ctx context.Context, req *VerifyRequest) (Status, error)
+	}
+
+	// InitiateRequest is sent to the provider when an identity starts the
+	// second factor.
+	InitiateRequest struct {
+		// FlowID is the ID of the login flow waiting for the second factor.
+		FlowID uuid.UUID `json:"flow_id"`
+
+		// Identity is the identity signing in.
+		Identity *identity.Identity `json:"identity"`
+
+		// ReturnTo is the URL the provider redirects the user to after a
+		// challenge which required a redirect was completed.
+		ReturnTo string `json:"return_to"`
+
+		// ClientIP is the IP address of the client signing in.
+		ClientIP string `json:"client_ip,omitempty"`
+
+		// UserAgent is the user agent of the client signing in.
+		UserAgent string `json:"user_agent,omitempty"`
+	}
+
+	// InitiateResponse is returned by the provider for a started challenge.
+	InitiateResponse struct {
+		// TransactionID identifies the challenge at the provider.
+		TransactionID string `json:"transaction_id"`
+
+		// RedirectURL is set if the user must be redirected to the provider
+		// to complete the challenge.
+		RedirectURL string `json:"redirect_url,omitempty"`
+	}
+
+	// VerifyRequest is sent to the provider to ask for the result of a
+	// challenge.
+	VerifyRequest struct {
+		// FlowID is the ID of the login flow waiting for the second factor.
+		FlowID uuid.UUID `json:"flow_id"`
+
+		// IdentityID is the ID of the identity signing in.
+		IdentityID uuid.UUID `json:"identity_id"`
+
+		// TransactionID identifies the challenge at the provider.
+		TransactionID string `json:"transaction_id"`
+
+		// Code is the code the provider appended to the callback URL, if any.
+		Code string `json:"code,omitempty"`
+	}
+
+	webhookProviderDependencies interface {
+		request.Dependencies
+		config.Provider
+	}
+
+	webhookProvider struct {
+		d webhookProviderDependencies
+	}
+
+	webhookVerifyResponse struct {
+		Status Status `json:"status"`
+	}
+)
+
+var _ Provider = new(webhookProvider)
+
+// NewWebhookProvider returns a provider which starts and verifies challenges
+// using the configured HTTP requests.
+func NewWebhookProvider(d webhookProviderDependencies) Provider {
+	return &webhookProvider{d: d}
+}
+
+func (p *webhookProvider) Initiate(ctx context.Context, req *InitiateRequest) (*InitiateResponse, error) {
+	var res InitiateResponse
+	if err := p.do(ctx, p.d.Config().ExternalMFAInitiateRequestConfig(ctx), req, &res); err != nil {
+		return nil, err
+	}
+
+	if res.TransactionID == "" {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The MFA provider did not return a transaction ID."))
+	}
+	return &res, nil
+}
+
+func (p *webhookProvider) Verify(ctx context.Context, req *VerifyRequest) (Status, error) {
+	var res webhookVerifyResponse
+	if err := p.do(ctx, p.d.Config().ExternalMFAVerifyRequestConfig(ctx), req, &res); err != nil {
+		return "", err
+	}
+
+	switch res.Status {
+	case StatusPending, StatusApproved, StatusDenied:
+		return res.Status, nil
+	}
+	return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The MFA provider returned the unknown status %q.", res.Status))
+}
+
+func (p *webhookProvider) do(ctx context.Context, conf json.RawMessage, body, dest interface{}) error {
+	builder, err := request.NewBuilder(ctx, conf, p.d)
+	if err != nil {
+		return err
+	}
+
+	r, err := builder.BuildRequest(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	res, err := p.d.HTTPClient(ctx).Do(r)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to reach the MFA provider.").WithWrap(err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The MFA provider responded with unexpected status code %d.", res.StatusCode))
+	}
+
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1024*1024)).Decode(dest); err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to decode the MFA provider response.").WithWrap(err))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package externalmfa
+
+import (
+	"context"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/request"
+	"github.com/ory/kratos/selfservice/errorx"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+)
+
+var _ login.Strategy = new(Strategy)
+var _ identity.ActiveCredentialsCounter = new(Strategy)
+
+type externalMFAStrategyDependencies interface {
+	x.LoggingProvider
+	x.WriterProvider
+	x.CSRFTokenGeneratorProvider
+	x.CSRFProvider
+	request.Dependencies
+
+	config.Provider
+
+	errorx.ManagementProvider
+
+	login.HooksProvider
+	login.ErrorHandlerProvider
+	login.HookExecutorProvider
+	login.FlowPersistenceProvider
+	login.HandlerProvider
+
+	identity.PrivilegedPoolProvider
+	identity.ValidationProvider
+
+	session.HandlerProvider
+	session.ManagementProvider
+	session.PersistenceProvider
+}
+
+// Strategy delegates the second factor to an external MFA provider such as
+// Duo or Okta Verify. The provider either challenges the user out of band,
+// for example with a push notification, or after a redirect to its own UI.
+type Strategy struct {
+	d        externalMFAStrategyDependencies
+	hd       *decoderx.HTTP
+	provider Provider
+}
+
+type StrategyOption func(*Strategy)
+
+// WithProvider replaces the provider which challenges the second factor. By
+// default, the HTTP requests configured in
+// `selfservice.methods.external_mfa.config` are used.
+func WithProvider(p Provider) StrategyOption {
+	return func(s *Strategy) {
+		s.provider = p
+	}
+}
+
+func NewStrategy(d any, opts ...StrategyOption) *Strategy {
+	deps := d.(externalMFAStrategyDependencies)
+	s := &Strategy{
+		d:        deps,
+		hd:       decoderx.NewHTTP(),
+		provider: NewWebhookProvider(deps),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Strategy) CountActiveFirstFactorCredentials(cc map[identity.CredentialsType]identity.Credentials) (count int, err error) {
+	return 0, nil
+}
+
+func (s *Strategy) CountActiveMultiFactorCredentials(cc map[identity.CredentialsType]identity.Credentials) (count int, err error) {
+	for _, c := range cc {
+		if c.Type == s.ID() && len(c.Identifiers) > 0 && len(c.Identifiers[0]) > 0 {
+			count++
+		}
+	}
+	return
+}
+
+func (s *Strategy) ID() identity.CredentialsType {
+	return identity.CredentialsTypeExternalMFA
+}
+
+func (s *Strategy) NodeGroup() node.UiNodeGroup {
+	return node.ExternalMFAGroup
+}
+
+func (s *Strategy) CompletedAuthenticationMethod(ctx context.Context) session.AuthenticationMethod {
+	return session.AuthenticationMethod{
+		Method: s.ID(),
+		AAL:    identity.AuthenticatorAssuranceLevel2,
+	}
+}
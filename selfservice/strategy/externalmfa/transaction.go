@@ -0,0 +1,67 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package externalmfa
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/x"
+)
+
+const InternalContextKeyTransaction = "transaction"
+
+// transaction is the pending challenge of a login flow at the external MFA
+// provider. It is stored in the flow's internal context so that the callback
+// endpoint and the login request can share it.
+type transaction struct {
+	ID        string    `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	// Code is the code the provider appended to the callback URL, if any.
+	Code string `json:"code,omitempty"`
+}
+
+func (t *transaction) expired() bool {
+	return t.ExpiresAt.Before(x.Now())
+}
+
+func transactionKey() string {
+	return flow.PrefixInternalContextKey(identity.CredentialsTypeExternalMFA, InternalContextKeyTransaction)
+}
+
+// getTransaction returns the transaction stored in the internal context or
+// nil if no challenge was started yet.
+func getTransaction(internalContext []byte) (*transaction, error) {
+	raw := gjson.GetBytes(internalContext, transactionKey())
+	if !raw.IsObject() {
+		return nil, nil
+	}
+
+	var t transaction
+	if err := json.Unmarshal([]byte(raw.Raw), &t); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &t, nil
+}
+
+func setTransaction(internalContext []byte, t *transaction) ([]byte, error) {
+	if len(internalContext) == 0 {
+		internalContext = []byte("{}")
+	}
+	return sjson.SetBytes(internalContext, transactionKey(), t)
+}
+
+func deleteTransaction(internalContext []byte) ([]byte, error) {
+	if len(internalContext) == 0 {
+		return internalContext, nil
+	}
+	return sjson.DeleteBytes(internalContext, transactionKey())
+}
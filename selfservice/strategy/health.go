@@ -0,0 +1,119 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package strategy
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/kratos/x"
+)
+
+const RouteStrategiesHealth = "/health/strategies"
+
+type (
+	// ConfigurationValidator is implemented by strategies which can detect that their configuration is
+	// unusable, for example because a provider is not supported or the credentials of a delivery backend
+	// are missing. Enabled strategies which report an error are hidden from the flows.
+	ConfigurationValidator interface {
+		ValidateConfiguration(ctx context.Context) error
+	}
+
+	// Misconfiguration describes an enabled strategy which is hidden because its configuration is unusable.
+	//
+	// swagger:model strategyMisconfiguration
+	Misconfiguration struct {
+		// Strategy is the ID of the strategy, for example "oidc".
+		//
+		// required: true
+		Strategy string `json:"strategy"`
+
+		// Reason explains why the configuration can not be used.
+		//
+		// required: true
+		Reason string `json:"reason"`
+	}
+
+	MisconfigurationProvider interface {
+		StrategyMisconfigurations(ctx context.Context) []Misconfiguration
+	}
+
+	healthHandlerDependencies interface {
+		MisconfigurationProvider
+		x.WriterProvider
+	}
+
+	HealthHandler struct {
+		d healthHandlerDependencies
+	}
+)
+
+func NewHealthHandler(d healthHandlerDependencies) *HealthHandler {
+	return &HealthHandler{d: d}
+}
+
+func (h *HealthHandler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteStrategiesHealth, h.getStrategiesHealth)
+}
+
+// Strategies Health Status
+//
+// swagger:model strategiesHealthStatus
+type strategiesHealthStatus struct {
+	// Status is "ok" if all enabled strategies are usable and "degraded" otherwise.
+	//
+	// required: true
+	Status string `json:"status"`
+
+	// Misconfigured lists the enabled strategies which are hidden because of their configuration.
+	//
+	// required: true
+	Misconfigured []Misconfiguration `json:"misconfigured"`
+}
+
+// Get Strategies Health Status Response
+//
+// swagger:response getStrategiesHealth
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type getStrategiesHealthResponse struct {
+	// in: body
+	Body strategiesHealthStatus
+}
+
+// swagger:route GET /admin/health/strategies metadata getStrategiesHealth
+//
+// # Check the Configuration of the Enabled Strategies
+//
+// This endpoint lists the enabled strategies which are hidden from the self-service flows because their
+// configuration is unusable, for example because an OpenID Connect provider is not supported. A degraded
+// status does not affect the readiness of the instance.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: getStrategiesHealth
+//	  default: errorGeneric
+func (h *HealthHandler) getStrategiesHealth(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	status := strategiesHealthStatus{
+		Status:        "ok",
+		Misconfigured: h.d.StrategyMisconfigurations(r.Context()),
+	}
+	if len(status.Misconfigured) > 0 {
+		status.Status = "degraded"
+	} else {
+		status.Misconfigured = []Misconfiguration{}
+	}
+
+	h.d.Writer().Write(w, r, &status)
+}
@@ -0,0 +1,52 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package idfirst
+
+import (
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/organization"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+)
+
+var _ login.Strategy = new(Strategy)
+
+type identifierFirstStrategyDependencies interface {
+	x.LoggingProvider
+	x.WriterProvider
+	x.CSRFTokenGeneratorProvider
+	x.CSRFProvider
+	x.TracingProvider
+
+	config.Provider
+
+	login.StrategyProvider
+	login.FlowPersistenceProvider
+
+	identity.PrivilegedPoolProvider
+
+	organization.PersistenceProvider
+}
+
+// Strategy asks for the identifier first and routes the user to the login methods of the organization
+// which owns the domain of the identifier's email address, or to the login methods of the identity.
+type Strategy struct {
+	d  identifierFirstStrategyDependencies
+	dx *decoderx.HTTP
+}
+
+func NewStrategy(d any) *Strategy {
+	return &Strategy{d: d.(identifierFirstStrategyDependencies), dx: decoderx.NewHTTP()}
+}
+
+func (s *Strategy) ID() identity.CredentialsType {
+	return identity.CredentialsTypeIdentifierFirst
+}
+
+func (s *Strategy) NodeGroup() node.UiNodeGroup {
+	return node.IdentifierFirstGroup
+}
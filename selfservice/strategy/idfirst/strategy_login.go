@@ -0,0 +1,188 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package idfirst
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/organization"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlcon"
+)
+
+// Update Login Flow with Identifier First Method
+//
+// swagger:model updateLoginFlowWithIdentifierFirstMethod
+type updateLoginFlowWithIdentifierFirstMethod struct {
+	// Method should be set to "identifier_first" when asking for the identifier first.
+	//
+	// required: true
+	Method string `json:"method" form:"method"`
+
+	// The identifier of the account, usually an email address.
+	//
+	// required: true
+	Identifier string `json:"identifier" form:"identifier"`
+
+	// Sending the anti-csrf token is only required for browser login flows.
+	CSRFToken string `json:"csrf_token" form:"csrf_token"`
+}
+
+func (s *Strategy) RegisterLoginRoutes(*x.RouterPublic) {}
+
+func (s *Strategy) PopulateLoginMethod(r *http.Request, requestedAAL identity.AuthenticatorAssuranceLevel, f *login.Flow) error {
+	if requestedAAL > identity.AuthenticatorAssuranceLevel1 {
+		return nil
+	}
+
+	f.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	f.UI.Nodes.Upsert(node.NewInputField("identifier", "", node.DefaultGroup, node.InputAttributeTypeText, node.WithRequiredInputAttribute).
+		WithMetaLabel(text.NewInfoNodeLabelID()))
+	f.UI.Nodes.Append(node.NewInputField("method", s.ID(), s.NodeGroup(), node.InputAttributeTypeSubmit).
+		WithMetaLabel(text.NewInfoNodeLabelContinue()))
+	return nil
+}
+
+// CompletedAuthenticationMethod is never used because this strategy does not complete a login.
+func (s *Strategy) CompletedAuthenticationMethod(context.Context) session.AuthenticationMethod {
+	return session.AuthenticationMethod{
+		Method: s.ID(),
+		AAL:    identity.AuthenticatorAssuranceLevel1,
+	}
+}
+
+// Login routes the user to the login methods of the organization which owns the domain of the identifier.
+// If no organization owns the domain, the login methods the identity has credentials for are shown. All
+// login methods are shown if the identifier is unknown or account enumeration is mitigated.
+func (s *Strategy) Login(w http.ResponseWriter, r *http.Request, f *login.Flow, _ uuid.UUID) (_ *identity.Identity, err error) {
+	ctx, span := s.d.Tracer(r.Context()).Tracer().Start(r.Context(), "selfservice.strategy.idfirst.strategy.Login")
+	defer otelx.End(span, &err)
+
+	if err := flow.MethodEnabledAndAllowedFromRequest(r, f.GetFlowName(), s.ID().String(), s.d); err != nil {
+		return nil, err
+	}
+
+	if err := login.CheckAAL(f, identity.AuthenticatorAssuranceLevel1); err != nil {
+		return nil, err
+	}
+
+	var p updateLoginFlowWithIdentifierFirstMethod
+	if err := s.dx.Decode(r, &p,
+		decoderx.HTTPDecoderSetValidatePayloads(true),
+		decoderx.MustHTTPRawJSONSchemaCompiler(loginMethodSchema),
+		decoderx.HTTPDecoderJSONFollowsFormFormat()); err != nil {
+		return nil, s.handleLoginError(r, f, &p, err)
+	}
+
+	if err := flow.EnsureCSRF(s.d, r, f.Type, s.d.Config().DisableAPIFlowEnforcement(ctx), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		return nil, s.handleLoginError(r, f, &p, err)
+	}
+
+	if len(p.Identifier) == 0 {
+		return nil, s.handleLoginError(r, f, &p, errors.WithStack(schema.NewRequiredError("#/identifier", "identifier")))
+	}
+
+	org, err := s.findOrganization(ctx, p.Identifier)
+	if err != nil {
+		return nil, s.handleLoginError(r, f, &p, err)
+	}
+
+	filters := []login.StrategyFilter{login.IdentifierFirstStrategyFilter(false)}
+	if org != nil {
+		f.OrganizationID = uuid.NullUUID{UUID: org.ID, Valid: true}
+		filters = append(filters, login.OrganizationStrategyFilter(org))
+	}
+
+	strategies := s.d.LoginStrategies(ctx, filters...)
+	if org == nil && !s.d.Config().SelfServiceAccountEnumerationMitigate(ctx) {
+		i, err := s.findIdentity(ctx, p.Identifier)
+		if err != nil {
+			return nil, s.handleLoginError(r, f, &p, err)
+		}
+
+		// Only shows the methods the identity can sign in with, unless it has none of them enabled.
+		if i != nil {
+			if available := s.d.LoginStrategies(ctx, append(filters, login.IdentityCredentialsStrategyFilter(i))...); len(available) > 0 {
+				strategies = available
+			}
+		}
+	}
+
+	f.UI.Nodes = node.Nodes{}
+	for _, ls := range strategies {
+		if err := ls.PopulateLoginMethod(r, f.RequestedAAL, f); err != nil {
+			return nil, s.handleLoginError(r, f, &p, err)
+		}
+	}
+
+	// Pre-fills the identifier of the routed methods, if they have one.
+	f.UI.Nodes.SetValueAttribute("identifier", p.Identifier)
+
+	if err := login.SortNodes(ctx, f.UI.Nodes); err != nil {
+		return nil, s.handleLoginError(r, f, &p, err)
+	}
+
+	f.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	if err := s.d.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
+		return nil, s.handleLoginError(r, f, &p, err)
+	}
+
+	if x.IsJSONRequest(r) {
+		s.d.Writer().WriteCode(w, r, http.StatusBadRequest, f)
+	} else {
+		http.Redirect(w, r, f.AppendTo(s.d.Config().SelfServiceFlowLoginUI(ctx)).String(), http.StatusSeeOther)
+	}
+
+	// The login is not complete yet. The user continues with one of the routed methods.
+	return nil, errors.WithStack(flow.ErrCompletedByStrategy)
+}
+
+// findOrganization returns the organization which owns the domain of the identifier or nil.
+func (s *Strategy) findOrganization(ctx context.Context, identifier string) (*organization.Organization, error) {
+	domain := organization.DomainOf(identifier)
+	if domain == "" {
+		return nil, nil
+	}
+
+	org, err := s.d.OrganizationPersister().FindOrganizationByDomain(ctx, domain)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// findIdentity returns the identity including its credentials which uses the identifier or nil.
+func (s *Strategy) findIdentity(ctx context.Context, identifier string) (*identity.Identity, error) {
+	i, err := s.d.PrivilegedIdentityPool().FindIdentityByCredentialIdentifier(ctx, identifier, false)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return s.d.PrivilegedIdentityPool().GetIdentityConfidential(ctx, i.ID)
+}
+
+func (s *Strategy) handleLoginError(r *http.Request, f *login.Flow, p *updateLoginFlowWithIdentifierFirstMethod, err error) error {
+	if f != nil {
+		f.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+		f.UI.Nodes.SetValueAttribute("identifier", p.Identifier)
+	}
+
+	return err
+}
@@ -34,6 +34,7 @@ type (
 		identity.PoolProvider
 		identity.ManagementProvider
 		identity.PrivilegedPoolProvider
+		identity.ValidationProvider
 		x.LoggingProvider
 		config.Provider
 
@@ -71,12 +72,14 @@ func (s *Sender) SendRecoveryLink(ctx context.Context, f *recovery.Flow, via ide
 	address, err := s.r.IdentityPool().FindRecoveryAddressByValue(ctx, identity.RecoveryAddressTypeEmail, to)
 	if errors.Is(err, sqlcon.ErrNoRows) {
 		notifyUnknownRecipients := s.r.Config().SelfServiceFlowRecoveryNotifyUnknownRecipients(ctx)
-		s.r.Audit().
-			WithField("via", via).
-			WithField("strategy", "link").
-			WithSensitiveField("email_address", address).
-			WithField("was_notified", notifyUnknownRecipients).
-			Info("Account recovery was requested for an unknown address.")
+		if s.r.Config().SelfServiceFlowRecoveryUnknownAddressAudit(ctx) {
+			s.r.Audit().
+				WithField("via", via).
+				WithField("strategy", "link").
+				WithSensitiveField("email_address", address).
+				WithField("was_notified", notifyUnknownRecipients).
+				Info("Account recovery was requested for an unknown address.")
+		}
 		if !notifyUnknownRecipients {
 			// do nothing
 		} else if err := s.send(ctx, string(via), email.NewRecoveryInvalid(s.r, &email.RecoveryInvalidModel{To: to})); err != nil {
@@ -94,6 +97,16 @@ func (s *Sender) SendRecoveryLink(ctx context.Context, f *recovery.Flow, via ide
 		return err
 	}
 
+	// Deactivated and deleted identities are treated like unknown addresses, so that their state is not revealed.
+	if i.State.IsBlocked() {
+		s.r.Audit().
+			WithField("identity_id", i.ID).
+			WithField("identity_state", i.State).
+			WithField("strategy", "link").
+			Info("Account recovery was requested for an identity which can not be recovered.")
+		return errors.WithStack(ErrUnknownAddress)
+	}
+
 	token := NewSelfServiceRecoveryToken(address, f, s.r.Config().SelfServiceLinkMethodLifespan(ctx))
 	if err := s.r.RecoveryTokenPersister().CreateRecoveryToken(ctx, token); err != nil {
 		return err
@@ -168,13 +181,15 @@ func (s *Sender) SendRecoveryTokenTo(ctx context.Context, f *recovery.Flow, i *i
 		return err
 	}
 
+	locale := s.r.IdentityValidator().Locale(ctx, i)
+
 	return s.send(ctx, string(address.Via), email.NewRecoveryValid(s.r,
 		&email.RecoveryValidModel{To: address.Value, RecoveryURL: urlx.CopyWithQuery(
 			urlx.AppendPaths(s.r.Config().SelfServiceLinkMethodBaseURL(ctx), recovery.RouteSubmitFlow),
 			url.Values{
 				"token": {token.Token},
 				"flow":  {f.ID.String()},
-			}).String(), Identity: model}))
+			}).String(), Identity: model, Locale: locale}))
 }
 
 func (s *Sender) SendVerificationTokenTo(ctx context.Context, f *verification.Flow, i *identity.Identity, address *identity.VerifiableAddress, token *VerificationToken) error {
@@ -191,13 +206,15 @@ func (s *Sender) SendVerificationTokenTo(ctx context.Context, f *verification.Fl
 		return err
 	}
 
+	locale := s.r.IdentityValidator().Locale(ctx, i)
+
 	if err := s.send(ctx, string(address.Via), email.NewVerificationValid(s.r,
 		&email.VerificationValidModel{To: address.Value, VerificationURL: urlx.CopyWithQuery(
 			urlx.AppendPaths(s.r.Config().SelfServiceLinkMethodBaseURL(ctx), verification.RouteSubmitFlow),
 			url.Values{
 				"flow":  {f.ID.String()},
 				"token": {token.Token},
-			}).String(), Identity: model})); err != nil {
+			}).String(), Identity: model, Locale: locale})); err != nil {
 		return err
 	}
 	address.Status = identity.VerifiableAddressStatusSent
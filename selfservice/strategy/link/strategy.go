@@ -64,6 +64,7 @@ type (
 		recovery.FlowPersistenceProvider
 		recovery.StrategyProvider
 		recovery.HookExecutorProvider
+		recovery.NotifierProvider
 
 		verification.ErrorHandlerProvider
 		verification.FlowPersistenceProvider
@@ -179,6 +179,9 @@ func (s *Strategy) createRecoveryLinkForIdentity(w http.ResponseWriter, r *http.
 	} else if err != nil {
 		s.d.Writer().WriteError(w, r, err)
 		return
+	} else if id.State.IsBlocked() {
+		s.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The identity can not be recovered because its state is %s.", id.State)))
+		return
 	}
 
 	token := NewAdminRecoveryToken(id.ID, req.ID, expiresIn)
@@ -272,12 +275,10 @@ func (s *Strategy) Recover(w http.ResponseWriter, r *http.Request, f *recovery.F
 		return s.HandleRecoveryError(w, r, req, body, err)
 	}
 
-	switch req.State {
-	case flow.StateChooseMethod:
-		fallthrough
-	case flow.StateEmailSent:
+	switch {
+	case recovery.StateMachine.CanTransition(req.State, flow.StateEmailSent):
 		return s.recoveryHandleFormSubmission(w, r, req)
-	case flow.StatePassedChallenge:
+	case req.State == flow.StatePassedChallenge:
 		// was already handled, do not allow retry
 		return s.retryRecoveryFlowWithMessage(w, r, req.Type, text.NewErrorValidationRecoveryRetrySuccess())
 	default:
@@ -285,9 +286,11 @@ func (s *Strategy) Recover(w http.ResponseWriter, r *http.Request, f *recovery.F
 	}
 }
 
-func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request, f *recovery.Flow, id *identity.Identity) error {
+func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request, f *recovery.Flow, id *identity.Identity, address *identity.RecoveryAddress) error {
 	f.UI.Messages.Clear()
-	f.State = flow.StatePassedChallenge
+	if err := recovery.StateMachine.Transition(r.Context(), f, flow.StatePassedChallenge); err != nil {
+		return s.retryRecoveryFlowWithError(w, r, flow.TypeBrowser, err)
+	}
 	f.SetCSRFToken(s.d.CSRFHandler().RegenerateToken(w, r))
 	f.RecoveredIdentityID = uuid.NullUUID{
 		UUID:  id.ID,
@@ -297,7 +300,7 @@ func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request,
 		return s.retryRecoveryFlowWithError(w, r, flow.TypeBrowser, err)
 	}
 
-	sess, err := session.NewActiveSession(r, id, s.d.Config(), time.Now().UTC(), identity.CredentialsTypeRecoveryLink, identity.AuthenticatorAssuranceLevel1)
+	sess, err := session.NewActiveSession(r, id, s.d.Config(), x.Now().UTC(), identity.CredentialsTypeRecoveryLink, identity.AuthenticatorAssuranceLevel1)
 	if err != nil {
 		return s.retryRecoveryFlowWithError(w, r, flow.TypeBrowser, err)
 	}
@@ -310,23 +313,30 @@ func (s *Strategy) recoveryIssueSession(w http.ResponseWriter, r *http.Request,
 		return s.retryRecoveryFlowWithError(w, r, flow.TypeBrowser, err)
 	}
 
+	if err := s.d.RecoveryNotifier().NotifyRecovered(r, sess.Identity, address); err != nil {
+		return s.retryRecoveryFlowWithError(w, r, flow.TypeBrowser, err)
+	}
+
 	sf, err := s.d.SettingsHandler().NewFlow(w, r, sess.Identity, flow.TypeBrowser)
 	if err != nil {
 		return s.retryRecoveryFlowWithError(w, r, flow.TypeBrowser, err)
 	}
 
-	returnToURL := s.d.Config().SelfServiceFlowRecoveryReturnTo(r.Context(), nil)
-	returnTo := ""
-	if returnToURL != nil {
-		returnTo = returnToURL.String()
+	returnTo, err := f.ContinueReturnTo(r, s.d.Config())
+	if err != nil {
+		return s.retryRecoveryFlowWithError(w, r, flow.TypeBrowser, err)
 	}
 
 	sf.RequestURL, err = x.TakeOverReturnToParameter(f.RequestURL, sf.RequestURL, returnTo)
 	if err != nil {
 		return s.retryRecoveryFlowWithError(w, r, flow.TypeBrowser, err)
 	}
+	sf.ReturnTo = returnTo
 
 	sf.UI.Messages.Set(text.NewRecoverySuccessful(time.Now().Add(s.d.Config().SelfServiceFlowSettingsPrivilegedSessionMaxAge(r.Context()))))
+	for _, action := range sess.RequiredActions {
+		sf.UI.Messages.Add(text.NewInfoSelfServiceSettingsRequiredAction(action))
+	}
 	if err := s.d.SettingsFlowPersister().UpdateSettingsFlow(r.Context(), sf); err != nil {
 		return s.retryRecoveryFlowWithError(w, r, flow.TypeBrowser, err)
 	}
@@ -378,7 +388,7 @@ func (s *Strategy) recoveryUseToken(w http.ResponseWriter, r *http.Request, fID
 		}
 	}
 
-	return s.recoveryIssueSession(w, r, f, recovered)
+	return s.recoveryIssueSession(w, r, f, recovered, token.RecoveryAddress)
 }
 
 func (s *Strategy) retryRecoveryFlowWithMessage(w http.ResponseWriter, r *http.Request, ft flow.Type, message *text.Message) error {
@@ -448,10 +458,15 @@ func (s *Strategy) recoveryHandleFormSubmission(w http.ResponseWriter, r *http.R
 		return s.HandleRecoveryError(w, r, f, body, err)
 	}
 
+	if s.d.Config().SelfServiceAccountEnumerationMitigate(r.Context()) {
+		defer x.WaitAtLeast(r.Context(), time.Now(), s.d.Config().SelfServiceAccountEnumerationResponseTime(r.Context()))
+	}
+
 	if err := s.d.LinkSender().SendRecoveryLink(r.Context(), f, identity.VerifiableAddressTypeEmail, body.Email); err != nil {
 		if !errors.Is(err, ErrUnknownAddress) {
 			return s.HandleRecoveryError(w, r, f, body, err)
 		}
+		s.d.RecoveryExecutor().UnknownAddressHook(w, r, f, body.Email)
 		// Continue execution
 	}
 
@@ -462,7 +477,9 @@ func (s *Strategy) recoveryHandleFormSubmission(w http.ResponseWriter, r *http.R
 	)
 
 	f.Active = sqlxx.NullString(s.NodeGroup())
-	f.State = flow.StateEmailSent
+	if err := recovery.StateMachine.Transition(r.Context(), f, flow.StateEmailSent); err != nil {
+		return s.HandleRecoveryError(w, r, f, body, err)
+	}
 	f.UI.Messages.Set(text.NewRecoveryEmailSent())
 	if err := s.d.RecoveryFlowPersister().UpdateRecoveryFlow(r.Context(), f); err != nil {
 		return s.HandleRecoveryError(w, r, f, body, err)
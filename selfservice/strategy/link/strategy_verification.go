@@ -144,13 +144,10 @@ func (s *Strategy) Verify(w http.ResponseWriter, r *http.Request, f *verificatio
 		return s.handleVerificationError(w, r, f, body, err)
 	}
 
-	switch f.State {
-	case flow.StateChooseMethod:
-		fallthrough
-	case flow.StateEmailSent:
-		// Do nothing (continue with execution after this switch statement)
+	switch {
+	case verification.StateMachine.CanTransition(f.State, flow.StateEmailSent):
 		return s.verificationHandleFormSubmission(w, r, f)
-	case flow.StatePassedChallenge:
+	case f.State == flow.StatePassedChallenge:
 		return s.retryVerificationFlowWithMessage(w, r, f.Type, text.NewErrorValidationVerificationRetrySuccess())
 	default:
 		return s.retryVerificationFlowWithMessage(w, r, f.Type, text.NewErrorValidationVerificationStateFailure())
@@ -185,7 +182,9 @@ func (s *Strategy) verificationHandleFormSubmission(w http.ResponseWriter, r *ht
 	)
 
 	f.Active = sqlxx.NullString(s.NodeGroup())
-	f.State = flow.StateEmailSent
+	if err := verification.StateMachine.Transition(r.Context(), f, flow.StateEmailSent); err != nil {
+		return s.handleVerificationError(w, r, f, body, err)
+	}
 	f.UI.Messages.Set(text.NewVerificationEmailSent())
 	if err := s.d.VerificationFlowPersister().UpdateVerificationFlow(r.Context(), f); err != nil {
 		return s.handleVerificationError(w, r, f, body, err)
@@ -213,10 +212,6 @@ func (s *Strategy) verificationUseToken(w http.ResponseWriter, r *http.Request,
 		return s.retryVerificationFlowWithError(w, r, flow.TypeBrowser, err)
 	}
 
-	if err := s.d.VerificationExecutor().PostVerificationHook(w, r, f, i); err != nil {
-		return s.retryVerificationFlowWithError(w, r, flow.TypeBrowser, err)
-	}
-
 	address := token.VerifiableAddress
 	address.Verified = true
 	verifiedAt := sqlxx.NullTime(time.Now().UTC())
@@ -226,6 +221,12 @@ func (s *Strategy) verificationUseToken(w http.ResponseWriter, r *http.Request,
 		return s.retryVerificationFlowWithError(w, r, flow.TypeBrowser, err)
 	}
 
+	// The hooks run once the address is verified, so they see the identity as it is stored now.
+	i.SetVerifiableAddress(address)
+	if err := s.d.VerificationExecutor().PostVerificationHook(w, r, f, i); err != nil {
+		return s.retryVerificationFlowWithError(w, r, flow.TypeBrowser, err)
+	}
+
 	returnTo := f.ContinueURL(r.Context(), s.d.Config())
 
 	f.UI.
@@ -238,7 +239,9 @@ func (s *Strategy) verificationUseToken(w http.ResponseWriter, r *http.Request,
 		Action: returnTo.String(),
 	}
 	f.UI.Messages.Clear()
-	f.State = flow.StatePassedChallenge
+	if err := verification.StateMachine.Transition(r.Context(), f, flow.StatePassedChallenge); err != nil {
+		return s.retryVerificationFlowWithError(w, r, flow.TypeBrowser, err)
+	}
 	// See https://github.com/ory/kratos/issues/1547
 	f.SetCSRFToken(flow.GetCSRFToken(s.d, w, r, f.Type))
 	f.UI.Messages.Set(text.NewInfoSelfServiceVerificationSuccessful())
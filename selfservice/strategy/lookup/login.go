@@ -155,6 +155,13 @@ func (s *Strategy) Login(w http.ResponseWriter, r *http.Request, f *login.Flow,
 		return nil, s.handleLoginError(r, f, errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to update identity.").WithDebug(err.Error())))
 	}
 
+	if warn := s.d.Config().LookupSecretWarnRemaining(r.Context()); warn > 0 {
+		if remaining := o.RemainingCodes(); remaining <= warn {
+			f.UI.Messages.Add(text.NewInfoSelfServiceLoginLookupSecretsLow(remaining))
+			f.AddContinueWith(flow.NewContinueWithRegenerateLookupSecrets(remaining, s.d.Config().SelfServiceFlowSettingsUI(r.Context()).String()))
+		}
+	}
+
 	f.Active = s.ID()
 	if err = s.d.LoginFlowPersister().UpdateLoginFlow(r.Context(), f); err != nil {
 		return nil, s.handleLoginError(r, f, errors.WithStack(herodot.ErrInternalServerError.WithReason("Could not update flow.").WithDebug(err.Error())))
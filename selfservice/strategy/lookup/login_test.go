@@ -23,6 +23,7 @@ import (
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
 	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/ui/node"
@@ -262,6 +263,30 @@ func TestCompleteLogin(t *testing.T) {
 		})
 	})
 
+	t.Run("case=should ask to regenerate when only few codes remain", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeyLookupSecretWarnRemaining, 7)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeyLookupSecretWarnRemaining, 0)
+		})
+
+		id, _ := createIdentity(t, reg)
+		apiClient := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, id)
+
+		// The identity has eight unused codes, so the first login leaves seven.
+		body, _ := doAPIFlowWithClient(t, func(v url.Values) {
+			v.Set(node.LookupCodeEnter, "key-0")
+		}, id, apiClient, false)
+		assert.EqualValues(t, flow.ContinueWithActionRegenerateLookupSecretsString, gjson.Get(body, "continue_with.0.action").String(), "%s", body)
+		assert.EqualValues(t, 7, gjson.Get(body, "continue_with.0.remaining").Int(), "%s", body)
+		assert.Equal(t, conf.SelfServiceFlowSettingsUI(ctx).String(), gjson.Get(body, "continue_with.0.url").String(), "%s", body)
+
+		conf.MustSet(ctx, config.ViperKeyLookupSecretWarnRemaining, 5)
+		body, _ = doAPIFlowWithClient(t, func(v url.Values) {
+			v.Set(node.LookupCodeEnter, "key-2")
+		}, id, apiClient, true)
+		assert.False(t, gjson.Get(body, "continue_with").Exists(), "%s", body)
+	})
+
 	t.Run("case=should fail because lookup can not handle AAL1", func(t *testing.T) {
 		apiClient := testhelpers.NewDebugClient(t)
 		f := testhelpers.InitializeLoginFlowViaAPI(t, apiClient, publicTS, false)
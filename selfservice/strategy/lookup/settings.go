@@ -41,8 +41,6 @@ const (
 	InternalContextKeyRegenerated = "regenerated"
 )
 
-const numCodes = 12
-
 var allSettingsNodes = []string{
 	node.LookupRegenerate,
 	node.LookupReveal,
@@ -247,9 +245,15 @@ func (s *Strategy) continueSettingsFlowReveal(w http.ResponseWriter, r *http.Req
 }
 
 func (s *Strategy) continueSettingsFlowRegenerate(w http.ResponseWriter, r *http.Request, ctxUpdate *settings.UpdateContext, p *updateSettingsFlowWithLookupMethod) error {
-	codes := make([]identity.RecoveryCode, numCodes)
+	ctx := r.Context()
+	charset := randx.AlphaLowerNum
+	if s.d.Config().LookupSecretFormat(ctx) == "numeric" {
+		charset = randx.Numeric
+	}
+
+	codes := make([]identity.RecoveryCode, s.d.Config().LookupSecretCount(ctx))
 	for k := range codes {
-		codes[k] = identity.RecoveryCode{Code: randx.MustString(8, randx.AlphaLowerNum)}
+		codes[k] = identity.RecoveryCode{Code: randx.MustString(s.d.Config().LookupSecretLength(ctx), charset)}
 	}
 
 	for _, n := range allSettingsNodes {
@@ -274,7 +278,7 @@ func (s *Strategy) continueSettingsFlowRegenerate(w http.ResponseWriter, r *http
 
 func (s *Strategy) continueSettingsFlowConfirm(w http.ResponseWriter, r *http.Request, ctxUpdate *settings.UpdateContext, p *updateSettingsFlowWithLookupMethod) error {
 	codes := gjson.GetBytes(ctxUpdate.Flow.InternalContext, flow.PrefixInternalContextKey(s.ID(), InternalContextKeyRegenerated)).Array()
-	if len(codes) != numCodes {
+	if len(codes) == 0 {
 		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("You must (re-)generate recovery backup codes before you can save them."))
 	}
 
@@ -0,0 +1,188 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	gooidc "github.com/coreos/go-oidc"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlcon"
+)
+
+const (
+	// backChannelLogoutEvent is the member of the `events` claim which marks a logout token.
+	backChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+	// logoutTokenMaxAge is how long after it was issued a logout token is accepted. Accepted tokens are
+	// remembered for as long to reject replays.
+	logoutTokenMaxAge = 5 * time.Minute
+
+	// logoutTokenClockSkew is how far ahead the clock of the provider may be.
+	logoutTokenClockSkew = time.Minute
+)
+
+// LogoutToken contains the claims of an OpenID Connect Back-Channel Logout token.
+type LogoutToken struct {
+	Subject   string                 `json:"sub"`
+	SessionID string                 `json:"sid"`
+	Events    map[string]interface{} `json:"events"`
+	Nonce     *string                `json:"nonce"`
+	Expiry    int64                  `json:"exp"`
+	IssuedAt  int64                  `json:"iat"`
+	ID        string                 `json:"jti"`
+}
+
+// VerifyLogoutToken verifies the signature of the logout token against the provider's JWKS and validates
+// its claims as defined by OpenID Connect Back-Channel Logout 1.0. Tokens must have been issued recently
+// and are accepted only once.
+func (g *ProviderGenericOIDC) VerifyLogoutToken(ctx context.Context, rawLogoutToken string) (*LogoutToken, error) {
+	p, err := g.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Logout tokens are not required to expire, which is checked below if they do.
+	token, err := p.Verifier(&gooidc.Config{ClientID: g.config.ClientID, SkipExpiryCheck: true}).Verify(ctx, rawLogoutToken)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The logout token is invalid.").WithDebug(err.Error()))
+	}
+
+	var claims LogoutToken
+	if err := token.Claims(&claims); err != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The logout token is invalid.").WithDebug(err.Error()))
+	}
+
+	issuedAt := time.Unix(claims.IssuedAt, 0)
+	now := x.Now()
+	if claims.Expiry > 0 && time.Unix(claims.Expiry, 0).Before(now) {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The logout token is expired."))
+	} else if claims.IssuedAt == 0 || issuedAt.After(now.Add(logoutTokenClockSkew)) || issuedAt.Before(now.Add(-logoutTokenMaxAge)) {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The logout token was issued too long ago or in the future."))
+	} else if claims.ID == "" {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason(`The logout token must have a "jti" claim.`))
+	} else if len(claims.ID) > 255 {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason(`The "jti" claim of the logout token must not be longer than 255 characters.`))
+	} else if _, ok := claims.Events[backChannelLogoutEvent]; !ok {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The logout token does not contain the %s event.", backChannelLogoutEvent))
+	} else if claims.Nonce != nil {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The logout token must not contain a nonce."))
+	} else if claims.Subject == "" && claims.SessionID == "" {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The logout token must contain a subject or a session ID."))
+	}
+
+	if err := g.reg.SessionPersister().CreateUpstreamLogoutToken(ctx, &session.UpstreamLogoutToken{
+		Provider:  g.config.ID,
+		TokenID:   claims.ID,
+		ExpiresAt: issuedAt.Add(logoutTokenMaxAge).UTC(),
+	}); errors.Is(err, sqlcon.ErrUniqueViolation) {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReason("The logout token was already used."))
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+// setUpstreamSession remembers the provider's session in the flow, so that the session issued by the flow
+// can be revoked by the provider's back-channel logout.
+func setUpstreamSession(f flow.InternalContexter, provider Provider, claims *Claims) error {
+	if _, ok := provider.(BackChannelLogoutProvider); !ok {
+		return nil
+	}
+
+	return flow.SetUpstreamSession(f, flow.UpstreamSessionData{
+		Provider:  provider.Config().ID,
+		Subject:   claims.Subject,
+		SessionID: claims.SessionID,
+	})
+}
+
+// swagger:parameters oidcBackChannelLogout
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type oidcBackChannelLogout struct {
+	// The ID of the provider which sends the logout token.
+	//
+	// required: true
+	// in: path
+	Provider string `json:"provider"`
+
+	// The logout token issued by the provider.
+	//
+	// required: true
+	// in: formData
+	LogoutToken string `json:"logout_token"`
+}
+
+// swagger:route POST /self-service/methods/oidc/backchannel-logout/{provider} frontend oidcBackChannelLogout
+//
+// # Receive an OpenID Connect Back-Channel Logout Token
+//
+// This endpoint is called by the upstream OpenID Connect provider when the user logs out there. It
+// verifies the logout token against the provider's JWKS and revokes all sessions which were issued
+// from the provider's session identified by the token's `sid` or, if absent, `sub` claim.
+//
+//	Consumes:
+//	- application/x-www-form-urlencoded
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: emptyResponse
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (s *Strategy) handleBackChannelLogout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+	w.Header().Set("Cache-Control", "no-store")
+
+	provider, err := s.provider(ctx, r, ps.ByName("provider"))
+	if err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	verifier, ok := provider.(BackChannelLogoutProvider)
+	if !ok {
+		s.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Provider %s does not support back-channel logout.", provider.Config().ID)))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		s.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("Unable to parse the request body.").WithDebug(err.Error())))
+		return
+	}
+
+	token, err := verifier.VerifyLogoutToken(ctx, r.PostForm.Get("logout_token"))
+	if err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	revoked, err := s.d.SessionPersister().RevokeSessionsByUpstreamSession(ctx, provider.Config().ID, token.Subject, token.SessionID)
+	if err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	s.d.Audit().
+		WithRequest(r).
+		WithField("provider", provider.Config().ID).
+		WithField("revoked_sessions", revoked).
+		Info("Revoked sessions because the OpenID Connect provider signalled a logout.")
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,141 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+)
+
+func TestProviderGenericOIDC_VerifyLogoutToken(t *testing.T) {
+	ctx := context.Background()
+	_, reg := internal.NewFastRegistryWithMocks(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                ts.URL,
+			"authorization_endpoint":                ts.URL + "/auth",
+			"token_endpoint":                        ts.URL + "/token",
+			"jwks_uri":                              ts.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+
+	p := oidc.NewProviderGenericOIDC(&oidc.Configuration{
+		Provider:  "generic",
+		ID:        "valid",
+		ClientID:  "client",
+		IssuerURL: ts.URL,
+	}, reg).(*oidc.ProviderGenericOIDC)
+
+	sign := func(t *testing.T, k *rsa.PrivateKey, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "key-1"
+		raw, err := token.SignedString(k)
+		require.NoError(t, err)
+		return raw
+	}
+
+	newClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":    ts.URL,
+			"aud":    "client",
+			"iat":    time.Now().Unix(),
+			"jti":    uuid.Must(uuid.NewV4()).String(),
+			"sub":    "subject",
+			"sid":    "session",
+			"events": map[string]interface{}{"http://schemas.openid.net/event/backchannel-logout": map[string]interface{}{}},
+		}
+	}
+
+	t.Run("case=accepts a valid logout token", func(t *testing.T) {
+		token, err := p.VerifyLogoutToken(ctx, sign(t, key, newClaims()))
+		require.NoError(t, err)
+		assert.Equal(t, "subject", token.Subject)
+		assert.Equal(t, "session", token.SessionID)
+	})
+
+	t.Run("case=accepts a logout token with only a session ID", func(t *testing.T) {
+		claims := newClaims()
+		delete(claims, "sub")
+		token, err := p.VerifyLogoutToken(ctx, sign(t, key, claims))
+		require.NoError(t, err)
+		assert.Empty(t, token.Subject)
+		assert.Equal(t, "session", token.SessionID)
+	})
+
+	for _, tc := range []struct {
+		d      string
+		modify func(jwt.MapClaims)
+	}{
+		{d: "without the logout event", modify: func(c jwt.MapClaims) { c["events"] = map[string]interface{}{} }},
+		{d: "with a nonce", modify: func(c jwt.MapClaims) { c["nonce"] = "nonce" }},
+		{d: "without subject and session ID", modify: func(c jwt.MapClaims) { delete(c, "sub"); delete(c, "sid") }},
+		{d: "which expired", modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{d: "without an issue time", modify: func(c jwt.MapClaims) { delete(c, "iat") }},
+		{d: "which was issued too long ago", modify: func(c jwt.MapClaims) { c["iat"] = time.Now().Add(-time.Hour).Unix() }},
+		{d: "which was issued in the future", modify: func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() }},
+		{d: "without an ID", modify: func(c jwt.MapClaims) { delete(c, "jti") }},
+		{d: "for another client", modify: func(c jwt.MapClaims) { c["aud"] = "other" }},
+		{d: "from another issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://example.org" }},
+	} {
+		t.Run("case=rejects a logout token "+tc.d, func(t *testing.T) {
+			claims := newClaims()
+			tc.modify(claims)
+			_, err := p.VerifyLogoutToken(ctx, sign(t, key, claims))
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("case=rejects a replayed logout token", func(t *testing.T) {
+		raw := sign(t, key, newClaims())
+		_, err := p.VerifyLogoutToken(ctx, raw)
+		require.NoError(t, err)
+
+		_, err = p.VerifyLogoutToken(ctx, raw)
+		require.Error(t, err)
+	})
+
+	t.Run("case=rejects a logout token signed with an unknown key", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		_, err = p.VerifyLogoutToken(ctx, sign(t, other, newClaims()))
+		require.Error(t, err)
+	})
+}
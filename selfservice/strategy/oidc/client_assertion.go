@@ -0,0 +1,137 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	TokenEndpointAuthClientSecretBasic = "client_secret_basic"
+	TokenEndpointAuthClientSecretPost  = "client_secret_post"
+	TokenEndpointAuthClientSecretJWT   = "client_secret_jwt"
+	TokenEndpointAuthPrivateKeyJWT     = "private_key_jwt"
+
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// ClientAssertionOptions returns the parameters which authenticate the client at the token endpoint if the
+// provider is configured to use `client_secret_jwt` or `private_key_jwt`.
+func (g *ProviderGenericOIDC) ClientAssertionOptions(ctx context.Context, tokenURL string) ([]oauth2.AuthCodeOption, error) {
+	params, err := g.ClientAssertionParams(ctx, tokenURL)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := make([]oauth2.AuthCodeOption, 0, len(params))
+	for k := range params {
+		opts = append(opts, oauth2.SetAuthURLParam(k, params.Get(k)))
+	}
+	return opts, nil
+}
+
+// ClientAssertionParams returns the form parameters which authenticate the client at the token endpoint, or nil
+// if the provider does not use a client assertion.
+func (g *ProviderGenericOIDC) ClientAssertionParams(_ context.Context, tokenURL string) (url.Values, error) {
+	var (
+		method jwt.SigningMethod
+		key    interface{}
+	)
+
+	switch g.config.TokenEndpointAuthMethod {
+	case TokenEndpointAuthClientSecretJWT:
+		method, key = jwt.SigningMethodHS256, []byte(g.config.ClientSecret)
+	case TokenEndpointAuthPrivateKeyJWT:
+		signer, err := parseClientAssertionPrivateKey(g.config.ClientAssertionPrivateKey)
+		if err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to parse the client assertion private key of provider %s.", g.config.ID).WithDebug(err.Error()))
+		}
+		method, key = clientAssertionSigningMethod(signer), signer
+		if method == nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The client assertion private key of provider %s uses an unsupported algorithm.", g.config.ID))
+		}
+	default:
+		return nil, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(method, jwt.RegisteredClaims{
+		Issuer:    g.config.ClientID,
+		Subject:   g.config.ClientID,
+		Audience:  []string{tokenURL},
+		ID:        x.NewUUID().String(),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(5 * time.Minute)),
+	})
+	if g.config.TokenEndpointAuthMethod == TokenEndpointAuthPrivateKeyJWT && g.config.ClientAssertionKeyID != "" {
+		token.Header["kid"] = g.config.ClientAssertionKeyID
+	}
+
+	assertion, err := token.SignedString(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return url.Values{
+		"client_assertion_type": {clientAssertionType},
+		"client_assertion":      {assertion},
+	}, nil
+}
+
+// parseClientAssertionPrivateKey parses a PEM encoded PKCS #8, PKCS #1, or SEC 1 private key.
+func parseClientAssertionPrivateKey(raw string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(raw))
+	if block == nil {
+		return nil, errors.New("failed to decode PEM block containing private key")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.New("private key is neither a PKCS #8, PKCS #1, nor SEC 1 key")
+	}
+	return key, nil
+}
+
+func clientAssertionSigningMethod(key crypto.Signer) jwt.SigningMethod {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().BitSize {
+		case 256:
+			return jwt.SigningMethodES256
+		case 384:
+			return jwt.SigningMethodES384
+		case 521:
+			return jwt.SigningMethodES512
+		}
+	}
+	return nil
+}
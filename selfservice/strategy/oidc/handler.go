@@ -0,0 +1,452 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/jsonx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/urlx"
+)
+
+const (
+	RouteAdminProviders = "/oidc/providers"
+	RouteAdminProvider  = RouteAdminProviders + "/:id"
+)
+
+var providerIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+type (
+	HandlerProvider interface {
+		OIDCProviderHandler() *Handler
+	}
+
+	// Handler manages the providers which are stored in the database. Changes take effect
+	// immediately, without restarting or reconfiguring Ory Kratos.
+	Handler struct {
+		d            Dependencies
+		refreshLocks refreshLocks
+	}
+)
+
+func NewHandler(d Dependencies) *Handler {
+	return &Handler{d: d}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteAdminProviders, h.listProviders)
+	admin.POST(RouteAdminProviders, h.createProvider)
+	admin.GET(RouteAdminProvider, h.getProvider)
+	admin.PUT(RouteAdminProvider, h.updateProvider)
+	admin.DELETE(RouteAdminProvider, h.deleteProvider)
+
+	admin.GET(RouteAdminIdentityToken, h.getIdentityOidcToken)
+}
+
+// OpenID Connect Provider
+//
+// A provider configuration managed using the admin API. Secrets are never returned.
+//
+// swagger:model oidcProvider
+type managedProvider struct {
+	Configuration
+
+	// CreatedAt is the time at which the provider was created.
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt is the time at which the provider was last updated.
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// List of OpenID Connect Providers
+//
+// swagger:model oidcProviders
+type managedProviders []managedProvider
+
+func (h *Handler) toManagedProvider(ctx context.Context, sc *StoredConfiguration) (*managedProvider, error) {
+	c, err := decryptConfiguration(ctx, h.d, sc)
+	if err != nil {
+		return nil, err
+	}
+
+	c.ClientSecret = ""
+	c.ClientAssertionPrivateKey = ""
+	c.PrivateKey = ""
+	return &managedProvider{Configuration: *c, CreatedAt: sc.CreatedAt, UpdatedAt: sc.UpdatedAt}, nil
+}
+
+// swagger:route GET /admin/oidc/providers identity listOidcProviders
+//
+// # List OpenID Connect Providers
+//
+// Lists the OpenID Connect providers which are managed using the admin API. Providers of the
+// configuration file are not included.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: oidcProviders
+//	  default: errorGeneric
+func (h *Handler) listProviders(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	stored, err := h.d.OIDCProviderPersister().ListOIDCProviders(r.Context())
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	providers := make(managedProviders, 0, len(stored))
+	for k := range stored {
+		p, err := h.toManagedProvider(r.Context(), &stored[k])
+		if err != nil {
+			h.d.Writer().WriteError(w, r, err)
+			return
+		}
+		providers = append(providers, *p)
+	}
+
+	h.d.Writer().Write(w, r, providers)
+}
+
+// Get OpenID Connect Provider Parameters
+//
+// swagger:parameters getOidcProvider
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type getOidcProvider struct {
+	// ID is the provider's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route GET /admin/oidc/providers/{id} identity getOidcProvider
+//
+// # Get an OpenID Connect Provider
+//
+// Returns the configuration of a provider which is managed using the admin API.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: oidcProvider
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) getProvider(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	sc, err := h.d.OIDCProviderPersister().GetOIDCProvider(r.Context(), ps.ByName("id"))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	p, err := h.toManagedProvider(r.Context(), sc)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, p)
+}
+
+// Create OpenID Connect Provider Parameters
+//
+// swagger:parameters createOidcProvider
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type createOidcProvider struct {
+	// The provider configuration, which uses the same format as the providers of the configuration file.
+	//
+	// in: body
+	// required: true
+	Body Configuration
+}
+
+// swagger:route POST /admin/oidc/providers identity createOidcProvider
+//
+// # Create an OpenID Connect Provider
+//
+// Creates a provider which can be used for sign in immediately. The ID must not be used by another
+// provider, including the providers of the configuration file. Mappers must be given as `base64://`
+// or `http(s)://` URLs.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  201: oidcProvider
+//	  400: errorGeneric
+//	  409: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) createProvider(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var c Configuration
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&c); err != nil {
+		h.d.Writer().WriteErrorCode(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+
+	if err := h.validate(&c); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if conf, err := fileConfig(ctx, h.d); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	} else if conf.has(c.ID) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrConflict.WithReasonf("Provider %s is defined in the configuration file.", c.ID)))
+		return
+	}
+
+	sc := StoredConfiguration{ProviderID: c.ID}
+	if err := encryptConfiguration(ctx, h.d, &sc, &c); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.d.OIDCProviderPersister().CreateOIDCProvider(ctx, &sc); errors.Is(err, sqlcon.ErrUniqueViolation) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrConflict.WithReasonf("Provider %s exists already.", c.ID)))
+		return
+	} else if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.d.OIDCProviderCache().Invalidate(h.d.OIDCProviderPersister().NetworkID(ctx))
+
+	p, err := h.toManagedProvider(ctx, &sc)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().WriteCreated(w, r,
+		urlx.AppendPaths(h.d.Config().SelfAdminURL(ctx), RouteAdminProviders, c.ID).String(),
+		p,
+	)
+}
+
+// Update OpenID Connect Provider Parameters
+//
+// swagger:parameters updateOidcProvider
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type updateOidcProvider struct {
+	// ID is the provider's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// The provider configuration. Secrets which are left empty keep their current value.
+	//
+	// in: body
+	// required: true
+	Body Configuration
+}
+
+// swagger:route PUT /admin/oidc/providers/{id} identity updateOidcProvider
+//
+// # Update an OpenID Connect Provider
+//
+// Replaces the configuration of a provider which is managed using the admin API. The change is used
+// by all flows which start afterwards.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: oidcProvider
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) updateProvider(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+
+	sc, err := h.d.OIDCProviderPersister().GetOIDCProvider(ctx, ps.ByName("id"))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	current, err := decryptConfiguration(ctx, h.d, sc)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	var c Configuration
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&c); err != nil {
+		h.d.Writer().WriteErrorCode(w, r, http.StatusBadRequest, errors.WithStack(err))
+		return
+	}
+
+	if c.ID == "" {
+		c.ID = sc.ProviderID
+	} else if c.ID != sc.ProviderID {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The provider ID can not be changed.")))
+		return
+	}
+
+	// Secrets are never returned, so they are kept if the configuration is sent back without them.
+	if c.ClientSecret == "" {
+		c.ClientSecret = current.ClientSecret
+	}
+	if c.ClientAssertionPrivateKey == "" {
+		c.ClientAssertionPrivateKey = current.ClientAssertionPrivateKey
+	}
+	if c.PrivateKey == "" {
+		c.PrivateKey = current.PrivateKey
+	}
+
+	if err := h.validate(&c); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := encryptConfiguration(ctx, h.d, sc, &c); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.d.OIDCProviderPersister().UpdateOIDCProvider(ctx, sc); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.d.OIDCProviderCache().Invalidate(h.d.OIDCProviderPersister().NetworkID(ctx))
+
+	p, err := h.toManagedProvider(ctx, sc)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, p)
+}
+
+// Delete OpenID Connect Provider Parameters
+//
+// swagger:parameters deleteOidcProvider
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type deleteOidcProvider struct {
+	// ID is the provider's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /admin/oidc/providers/{id} identity deleteOidcProvider
+//
+// # Delete an OpenID Connect Provider
+//
+// Deletes a provider which is managed using the admin API. Identities keep their credentials of the
+// provider, which can be used again once a provider with the same ID is created.
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) deleteProvider(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.d.OIDCProviderPersister().DeleteOIDCProvider(r.Context(), ps.ByName("id")); err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+	h.d.OIDCProviderCache().Invalidate(h.d.OIDCProviderPersister().NetworkID(r.Context()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validate checks the fields which are required by the configuration schema and that the provider
+// type is supported.
+func (h *Handler) validate(c *Configuration) error {
+	if !providerIDPattern.MatchString(c.ID) {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The provider ID must only contain letters, digits, dashes, and underscores."))
+	} else if c.ClientID == "" {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The client ID is required."))
+	} else if c.Mapper == "" {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The mapper URL is required."))
+	} else if strings.HasPrefix(c.Mapper, "file://") {
+		// Reading files of the server is reserved for the configuration file.
+		return errors.WithStack(herodot.ErrBadRequest.WithReason("The mapper URL must use the base64, http, or https scheme."))
+	}
+
+	if _, err := (ConfigurationCollection{Providers: []Configuration{*c}}).Provider(c.ID, h.d); err != nil {
+		return errors.WithStack(herodot.ErrBadRequest.WithReason(err.Error()))
+	}
+	return nil
+}
+
+func encryptConfiguration(ctx context.Context, d Dependencies, sc *StoredConfiguration, c *Configuration) error {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	sc.EncryptedConfiguration, err = d.Cipher(ctx).Encrypt(ctx, raw)
+	return err
+}
+
+func decryptConfiguration(ctx context.Context, d Dependencies, sc *StoredConfiguration) (*Configuration, error) {
+	raw, err := d.Cipher(ctx).Decrypt(ctx, sc.EncryptedConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Configuration
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c.ID = sc.ProviderID
+	return &c, nil
+}
@@ -0,0 +1,155 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/kratos/x"
+)
+
+func TestProviderHandler(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	viperSetProviderConfig(t, conf, oidc.Configuration{
+		Provider: "generic",
+		ID:       "from-file",
+		ClientID: "client",
+		Mapper:   "file://./stub/oidc.hydra.jsonnet",
+	})
+
+	router := x.NewRouterAdmin()
+	reg.OIDCProviderHandler().RegisterAdminRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	do := func(t *testing.T, method, path string, body interface{}) (*http.Response, string) {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		req, err := http.NewRequest(method, ts.URL+x.AdminPrefix+path, &buf)
+		require.NoError(t, err)
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(raw)
+	}
+
+	strategyProviders := func(t *testing.T) []string {
+		s, err := reg.AllLoginStrategies().Strategy(identity.CredentialsTypeOIDC)
+		require.NoError(t, err)
+		c, err := s.(*oidc.Strategy).Config(ctx)
+		require.NoError(t, err)
+		ids := make([]string, len(c.Providers))
+		for k, p := range c.Providers {
+			ids[k] = p.ID
+		}
+		return ids
+	}
+
+	provider := oidc.Configuration{
+		Provider:     "generic",
+		ID:           "managed",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		IssuerURL:    "https://example.org",
+		Mapper:       "base64://bG9jYWwgY2xhaW1zID0gc3RkLmV4dFZhcignY2xhaW1zJyk7IHsgaWRlbnRpdHk6IHsgdHJhaXRzOiB7IHN1YmplY3Q6IGNsYWltcy5zdWIgfSB9IH0=",
+	}
+
+	t.Run("case=creates a provider which is used immediately", func(t *testing.T) {
+		res, body := do(t, "POST", oidc.RouteAdminProviders, provider)
+		require.Equal(t, http.StatusCreated, res.StatusCode, body)
+		assert.Equal(t, "managed", gjson.Get(body, "id").String())
+		assert.Empty(t, gjson.Get(body, "client_secret").String(), "secrets are never returned")
+
+		assert.ElementsMatch(t, []string{"from-file", "managed"}, strategyProviders(t))
+	})
+
+	t.Run("case=rejects duplicate providers", func(t *testing.T) {
+		res, body := do(t, "POST", oidc.RouteAdminProviders, provider)
+		assert.Equal(t, http.StatusConflict, res.StatusCode, body)
+
+		fromFile := provider
+		fromFile.ID = "from-file"
+		res, body = do(t, "POST", oidc.RouteAdminProviders, fromFile)
+		assert.Equal(t, http.StatusConflict, res.StatusCode, body)
+	})
+
+	t.Run("case=rejects invalid providers", func(t *testing.T) {
+		for _, modify := range []func(*oidc.Configuration){
+			func(c *oidc.Configuration) { c.Provider = "unknown" },
+			func(c *oidc.Configuration) { c.Mapper = "file:///etc/passwd" },
+			func(c *oidc.Configuration) { c.ID = "not/valid" },
+		} {
+			c := provider
+			c.ID = "invalid"
+			modify(&c)
+			res, body := do(t, "POST", oidc.RouteAdminProviders, c)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+		}
+	})
+
+	t.Run("case=updates a provider and keeps its secret", func(t *testing.T) {
+		update := provider
+		update.ClientSecret = ""
+		update.Label = "Managed"
+		res, body := do(t, "PUT", "/oidc/providers/managed", update)
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, "Managed", gjson.Get(body, "label").String())
+
+		sc, err := reg.OIDCProviderPersister().GetOIDCProvider(ctx, "managed")
+		require.NoError(t, err)
+		raw, err := reg.Cipher(ctx).Decrypt(ctx, sc.EncryptedConfiguration)
+		require.NoError(t, err)
+		assert.Equal(t, "secret", gjson.GetBytes(raw, "client_secret").String())
+	})
+
+	t.Run("case=lists and gets providers", func(t *testing.T) {
+		res, body := do(t, "GET", oidc.RouteAdminProviders, nil)
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, `["managed"]`, gjson.Get(body, "#.id").Raw)
+
+		res, body = do(t, "GET", "/oidc/providers/managed", nil)
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, "Managed", gjson.Get(body, "label").String())
+	})
+
+	t.Run("case=deletes a provider", func(t *testing.T) {
+		res, body := do(t, "DELETE", "/oidc/providers/managed", nil)
+		require.Equal(t, http.StatusNoContent, res.StatusCode, body)
+		assert.Equal(t, []string{"from-file"}, strategyProviders(t))
+
+		res, _ = do(t, "DELETE", "/oidc/providers/managed", nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("case=caches stored providers until they are changed", func(t *testing.T) {
+		res, body := do(t, "POST", oidc.RouteAdminProviders, provider)
+		require.Equal(t, http.StatusCreated, res.StatusCode, body)
+		assert.ElementsMatch(t, []string{"from-file", "managed"}, strategyProviders(t))
+
+		// Changes which bypass the admin API are not seen until the cache expires.
+		require.NoError(t, reg.OIDCProviderPersister().DeleteOIDCProvider(ctx, "managed"))
+		assert.ElementsMatch(t, []string{"from-file", "managed"}, strategyProviders(t))
+
+		reg.OIDCProviderCache().Invalidate(reg.OIDCProviderPersister().NetworkID(ctx))
+		assert.Equal(t, []string{"from-file"}, strategyProviders(t))
+	})
+}
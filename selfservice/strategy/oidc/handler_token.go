@@ -0,0 +1,373 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	grpccodes "google.golang.org/grpc/codes"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/cipher"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/sqlxx"
+)
+
+const RouteAdminIdentityToken = "/identities/:id/credentials/oidc/:provider/token"
+
+// tokenExpiryLeeway refreshes access tokens shortly before they expire, so that they can still be used by
+// the caller.
+const tokenExpiryLeeway = time.Minute
+
+// Upstream OpenID Connect Token
+//
+// The access token which the provider issued for the identity.
+//
+// swagger:model identityOidcToken
+type identityOidcToken struct {
+	// Provider is the ID of the provider.
+	//
+	// required: true
+	Provider string `json:"provider"`
+
+	// Subject is the identity's subject at the provider.
+	//
+	// required: true
+	Subject string `json:"subject"`
+
+	// AccessToken is the access token which can be used to call the provider's APIs.
+	//
+	// required: true
+	AccessToken string `json:"access_token"`
+
+	// ExpiresAt is the time at which the access token expires, if the provider returned it.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Refreshed is true if the access token was refreshed by this request.
+	//
+	// required: true
+	Refreshed bool `json:"refreshed"`
+}
+
+// Get Identity OpenID Connect Token Parameters
+//
+// swagger:parameters getIdentityOidcToken
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type getIdentityOidcToken struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// Provider is the ID of the provider which the identity is linked to.
+	//
+	// required: true
+	// in: path
+	Provider string `json:"provider"`
+}
+
+// swagger:route GET /admin/identities/{id}/credentials/oidc/{provider}/token identity getIdentityOidcToken
+//
+// # Get the Upstream Access Token of an Identity
+//
+// Returns the access token which the provider issued when the identity signed in, so that backend
+// services can call the provider's APIs on behalf of the identity. If a refresh token is stored, the
+// access token is refreshed when it expired or its expiry is unknown, and the new tokens are stored.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: identityOidcToken
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  502: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) getIdentityOidcToken(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	token, err := h.identityToken(r.Context(), x.ParseUUID(ps.ByName("id")), ps.ByName("provider"))
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, token)
+}
+
+func (h *Handler) identityToken(ctx context.Context, identityID uuid.UUID, providerID string) (_ *identityOidcToken, err error) {
+	ctx, span := h.d.Tracer(ctx).Tracer().Start(ctx, "strategy.oidc.Handler.identityToken")
+	defer otelx.End(span, &err)
+
+	// Providers which rotate refresh tokens only accept them once, so concurrent requests for the same
+	// credentials must not refresh the access token at the same time. Requests which waited for the lock
+	// read the tokens stored by the refresh which held it.
+	unlock := h.refreshLocks.lock(identityID.String() + ":" + providerID)
+	defer unlock()
+
+	i, err := h.d.PrivilegedIdentityPool().GetIdentityConfidential(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+
+	var conf identity.CredentialsOIDC
+	if _, err := i.ParseCredentials(identity.CredentialsTypeOIDC, &conf); errors.Is(err, herodot.ErrNotFound) {
+		return nil, errors.WithStack(herodot.ErrNotFound.WithReason("The identity is not linked to any OpenID Connect provider."))
+	} else if err != nil {
+		return nil, err
+	}
+
+	k := -1
+	for j, p := range conf.Providers {
+		if p.Provider == providerID {
+			k = j
+			break
+		}
+	}
+	if k < 0 {
+		return nil, errors.WithStack(herodot.ErrNotFound.WithReasonf("The identity is not linked to provider %s.", providerID))
+	}
+	linked := &conf.Providers[k]
+
+	accessToken, err := h.d.Cipher(ctx).Decrypt(ctx, linked.InitialAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := h.d.Cipher(ctx).Decrypt(ctx, linked.InitialRefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &identityOidcToken{
+		Provider:    linked.Provider,
+		Subject:     linked.Subject,
+		AccessToken: string(accessToken),
+		ExpiresAt:   linked.AccessTokenExpiresAt,
+	}
+
+	if len(refreshToken) == 0 || (linked.AccessTokenExpiresAt != nil && time.Now().Add(tokenExpiryLeeway).Before(*linked.AccessTokenExpiresAt)) {
+		return result, nil
+	}
+
+	token, err := h.refreshToken(ctx, providerID, string(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setTokens(ctx, h.d.Cipher(ctx), linked, token); err != nil {
+		return nil, err
+	}
+
+	if err := storeTokens(ctx, h.d.PrivilegedIdentityPool(), i.ID, linked); err != nil {
+		return nil, err
+	}
+
+	result.AccessToken = token.AccessToken
+	result.ExpiresAt = linked.AccessTokenExpiresAt
+	result.Refreshed = true
+	return result, nil
+}
+
+// refreshToken exchanges the refresh token for a new access token at the provider's token endpoint.
+func (h *Handler) refreshToken(ctx context.Context, providerID, refreshToken string) (*oauth2.Token, error) {
+	c, err := allConfig(ctx, h.d)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := c.Provider(providerID, h.d)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := provider.(TokenExchanger); ok {
+		return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Provider %s does not support refreshing access tokens.", providerID))
+	}
+
+	conf, err := provider.OAuth2(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client := h.d.HTTPClient(ctx).HTTPClient
+	if ca, ok := provider.(ClientAssertionProvider); ok {
+		params, err := ca.ClientAssertionParams(ctx, conf.Endpoint.TokenURL)
+		if err != nil {
+			return nil, err
+		} else if len(params) > 0 {
+			client = clientWithAssertion(client, conf.Endpoint.TokenURL, params)
+		}
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	token, err := conf.TokenSource(ctx, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	if err != nil {
+		return nil, errors.WithStack(herodot.DefaultError{
+			CodeField:     http.StatusBadGateway,
+			StatusField:   http.StatusText(http.StatusBadGateway),
+			GRPCCodeField: grpccodes.Unavailable,
+			ReasonField:   fmt.Sprintf("Unable to refresh the access token at provider %s. The identity may need to sign in again.", providerID),
+			ErrorField:    err.Error(),
+		})
+	}
+	return token, nil
+}
+
+// setTokens encrypts the tokens which the provider issued and stores them in the linked provider's credentials.
+func setTokens(ctx context.Context, c cipher.Cipher, linked *identity.CredentialsOIDCProvider, token *oauth2.Token) (err error) {
+	if linked.InitialAccessToken, err = c.Encrypt(ctx, []byte(token.AccessToken)); err != nil {
+		return err
+	}
+	// Providers which do not rotate refresh tokens omit them from the response.
+	if token.RefreshToken != "" {
+		if linked.InitialRefreshToken, err = c.Encrypt(ctx, []byte(token.RefreshToken)); err != nil {
+			return err
+		}
+	}
+	if idToken, ok := token.Extra("id_token").(string); ok && idToken != "" {
+		if linked.InitialIDToken, err = c.Encrypt(ctx, []byte(idToken)); err != nil {
+			return err
+		}
+	}
+	linked.AccessTokenExpiresAt = accessTokenExpiry(token)
+	return nil
+}
+
+// storeTokens stores the tokens of the linked provider in the identity's current OpenID Connect credentials, so that
+// concurrent changes to the credentials are not lost.
+func storeTokens(ctx context.Context, pool identity.PrivilegedPool, identityID uuid.UUID, linked *identity.CredentialsOIDCProvider) error {
+	return pool.UpdateIdentityCredentialsConfig(ctx, identityID, identity.CredentialsTypeOIDC, func(config sqlxx.JSONRawMessage) (sqlxx.JSONRawMessage, error) {
+		var conf identity.CredentialsOIDC
+		if err := json.Unmarshal(config, &conf); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		for k := range conf.Providers {
+			if conf.Providers[k].Provider == linked.Provider && conf.Providers[k].Subject == linked.Subject {
+				conf.Providers[k].InitialAccessToken = linked.InitialAccessToken
+				conf.Providers[k].InitialRefreshToken = linked.InitialRefreshToken
+				conf.Providers[k].InitialIDToken = linked.InitialIDToken
+				conf.Providers[k].AccessTokenExpiresAt = linked.AccessTokenExpiresAt
+			}
+		}
+
+		encoded, err := json.Marshal(conf)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return encoded, nil
+	})
+}
+
+// accessTokenExpiry returns the time at which the access token expires, or nil if the provider did not return it.
+func accessTokenExpiry(token *oauth2.Token) *time.Time {
+	if token == nil || token.Expiry.IsZero() {
+		return nil
+	}
+	expiresAt := token.Expiry.UTC()
+	return &expiresAt
+}
+
+// clientWithAssertion returns a copy of the client which adds the client assertion to the requests to the token
+// endpoint. The token source of golang.org/x/oauth2 has no option to authenticate the client with an assertion
+// when refreshing tokens.
+func clientWithAssertion(client *http.Client, tokenURL string, params url.Values) *http.Client {
+	c := *client
+	c.Transport = &clientAssertionTransport{base: client.Transport, tokenURL: tokenURL, params: params}
+	return &c
+}
+
+type clientAssertionTransport struct {
+	base     http.RoundTripper
+	tokenURL string
+	params   url.Values
+}
+
+func (t *clientAssertionTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if r.Method != http.MethodPost || r.URL.String() != t.tokenURL || r.Body == nil {
+		return base.RoundTrip(r)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for k, v := range t.params {
+		form[k] = v
+	}
+
+	encoded := form.Encode()
+	r = r.Clone(r.Context())
+	r.Body = io.NopCloser(strings.NewReader(encoded))
+	r.ContentLength = int64(len(encoded))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(encoded)), nil
+	}
+	return base.RoundTrip(r)
+}
+
+// refreshLocks serializes the refreshes of the same credentials within this process.
+type refreshLocks struct {
+	mu    sync.Mutex
+	locks map[string]*refreshLock
+}
+
+type refreshLock struct {
+	sync.Mutex
+	waiters int
+}
+
+func (l *refreshLocks) lock(key string) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*refreshLock)
+	}
+	k, ok := l.locks[key]
+	if !ok {
+		k = new(refreshLock)
+		l.locks[key] = k
+	}
+	k.waiters++
+	l.mu.Unlock()
+
+	k.Lock()
+	return func() {
+		k.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if k.waiters--; k.waiters == 0 {
+			delete(l.locks, key)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/strategy/oidc"
+	"github.com/ory/kratos/x"
+)
+
+func TestIdentityTokenHandler(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/registration.schema.json")
+
+	var (
+		refreshes int32
+		upstream  *httptest.Server
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 upstream.URL,
+			"authorization_endpoint": upstream.URL + "/auth",
+			"token_endpoint":         upstream.URL + "/token",
+			"jwks_uri":               upstream.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+		if r.PostForm.Get("client_id") == "assertion-client" {
+			assert.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer", r.PostForm.Get("client_assertion_type"))
+			assert.NotEmpty(t, r.PostForm.Get("client_assertion"))
+			assert.Empty(t, r.PostForm.Get("client_secret"))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("refresh_token") == "revoked" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+
+		atomic.AddInt32(&refreshes, 1)
+		_, _ = w.Write([]byte(`{"access_token":"refreshed-access","refresh_token":"rotated-refresh","token_type":"bearer","expires_in":3600}`))
+	})
+	upstream = httptest.NewServer(mux)
+	t.Cleanup(upstream.Close)
+
+	viperSetProviderConfig(t, conf, oidc.Configuration{
+		Provider:     "generic",
+		ID:           "upstream",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		IssuerURL:    upstream.URL,
+		Mapper:       "file://./stub/oidc.hydra.jsonnet",
+	}, oidc.Configuration{
+		Provider:                "generic",
+		ID:                      "assertion",
+		ClientID:                "assertion-client",
+		ClientSecret:            "secret",
+		IssuerURL:               upstream.URL,
+		Mapper:                  "file://./stub/oidc.hydra.jsonnet",
+		TokenEndpointAuthMethod: oidc.TokenEndpointAuthClientSecretJWT,
+	})
+
+	router := x.NewRouterAdmin()
+	reg.OIDCProviderHandler().RegisterAdminRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	get := func(t *testing.T, id, provider string) (*http.Response, string) {
+		path := strings.NewReplacer(":id", id, ":provider", provider).Replace(oidc.RouteAdminIdentityToken)
+		res, err := ts.Client().Get(ts.URL + x.AdminPrefix + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(raw)
+	}
+
+	encrypt := func(t *testing.T, token string) string {
+		if token == "" {
+			return ""
+		}
+		ciphertext, err := reg.Cipher(ctx).Encrypt(ctx, []byte(token))
+		require.NoError(t, err)
+		return ciphertext
+	}
+
+	createLinkedIdentity := func(t *testing.T, provider, accessToken, refreshToken string) *identity.Identity {
+		i := identity.NewIdentity(config.DefaultIdentityTraitsSchemaID)
+		i.Traits = identity.Traits(`{"subject":"` + x.NewUUID().String() + `@ory.sh"}`)
+		subject := x.NewUUID().String()
+		creds, err := identity.NewCredentialsOIDC("", encrypt(t, accessToken), encrypt(t, refreshToken), provider, subject, "")
+		require.NoError(t, err)
+		i.SetCredentials(identity.CredentialsTypeOIDC, *creds)
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+		return i
+	}
+	createIdentity := func(t *testing.T, accessToken, refreshToken string) *identity.Identity {
+		return createLinkedIdentity(t, "upstream", accessToken, refreshToken)
+	}
+
+	t.Run("case=returns the stored access token without a refresh token", func(t *testing.T) {
+		i := createIdentity(t, "initial-access", "")
+
+		res, body := get(t, i.ID.String(), "upstream")
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, "initial-access", gjson.Get(body, "access_token").String())
+		assert.Equal(t, "upstream", gjson.Get(body, "provider").String())
+		assert.False(t, gjson.Get(body, "refreshed").Bool())
+	})
+
+	t.Run("case=refreshes and stores the access token", func(t *testing.T) {
+		i := createIdentity(t, "initial-access", "initial-refresh")
+		before := atomic.LoadInt32(&refreshes)
+
+		res, body := get(t, i.ID.String(), "upstream")
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, "refreshed-access", gjson.Get(body, "access_token").String())
+		assert.True(t, gjson.Get(body, "refreshed").Bool())
+		assert.True(t, gjson.Get(body, "expires_at").Exists())
+
+		actual, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(ctx, i.ID)
+		require.NoError(t, err)
+		declassified, err := actual.WithDeclassifiedCredentials(ctx, reg, []identity.CredentialsType{identity.CredentialsTypeOIDC})
+		require.NoError(t, err)
+		raw := declassified.Credentials[identity.CredentialsTypeOIDC].Config
+		assert.Equal(t, "refreshed-access", gjson.GetBytes(raw, "providers.0.initial_access_token").String())
+		assert.Equal(t, "rotated-refresh", gjson.GetBytes(raw, "providers.0.initial_refresh_token").String())
+
+		t.Run("case=reuses the access token until it expires", func(t *testing.T) {
+			res, body := get(t, i.ID.String(), "upstream")
+			require.Equal(t, http.StatusOK, res.StatusCode, body)
+			assert.Equal(t, "refreshed-access", gjson.Get(body, "access_token").String())
+			assert.False(t, gjson.Get(body, "refreshed").Bool())
+			assert.Equal(t, before+1, atomic.LoadInt32(&refreshes))
+		})
+	})
+
+	t.Run("case=refreshes the access token only once for concurrent requests", func(t *testing.T) {
+		i := createIdentity(t, "initial-access", "initial-refresh")
+		before := atomic.LoadInt32(&refreshes)
+
+		var wg sync.WaitGroup
+		for k := 0; k < 5; k++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, body := get(t, i.ID.String(), "upstream")
+				assert.Equal(t, http.StatusOK, res.StatusCode, body)
+				assert.Equal(t, "refreshed-access", gjson.Get(body, "access_token").String())
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, before+1, atomic.LoadInt32(&refreshes))
+	})
+
+	t.Run("case=authenticates the client with an assertion", func(t *testing.T) {
+		i := createLinkedIdentity(t, "assertion", "initial-access", "initial-refresh")
+
+		res, body := get(t, i.ID.String(), "assertion")
+		require.Equal(t, http.StatusOK, res.StatusCode, body)
+		assert.Equal(t, "refreshed-access", gjson.Get(body, "access_token").String())
+		assert.True(t, gjson.Get(body, "refreshed").Bool())
+	})
+
+	t.Run("case=fails if the provider rejects the refresh token", func(t *testing.T) {
+		i := createIdentity(t, "initial-access", "revoked")
+
+		res, body := get(t, i.ID.String(), "upstream")
+		assert.Equal(t, http.StatusBadGateway, res.StatusCode, body)
+	})
+
+	t.Run("case=fails if the identity is not linked to the provider", func(t *testing.T) {
+		i := createIdentity(t, "initial-access", "")
+
+		res, body := get(t, i.ID.String(), "other")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode, body)
+
+		res, body = get(t, x.NewUUID().String(), "upstream")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode, body)
+	})
+}
@@ -0,0 +1,58 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// StoredConfiguration is a provider configuration which is managed using the admin API instead of the
+// configuration file. The configuration is encrypted as it contains the client secret.
+type StoredConfiguration struct {
+	ID uuid.UUID `json:"-" faker:"-" db:"id"`
+
+	// ProviderID is the ID of the provider, see Configuration.ID.
+	ProviderID string `json:"id" db:"provider_id"`
+
+	// EncryptedConfiguration is the encrypted JSON encoding of the Configuration.
+	EncryptedConfiguration string `json:"-" db:"config"`
+
+	CreatedAt time.Time `json:"created_at" faker:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" faker:"-" db:"updated_at"`
+	NID       uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (c StoredConfiguration) TableName(context.Context) string {
+	return "selfservice_oidc_providers"
+}
+
+type (
+	ProviderPersister interface {
+		// ListOIDCProviders returns all stored provider configurations.
+		ListOIDCProviders(ctx context.Context) ([]StoredConfiguration, error)
+
+		// GetOIDCProvider returns the stored configuration of the provider or sqlcon.ErrNoRows.
+		GetOIDCProvider(ctx context.Context, providerID string) (*StoredConfiguration, error)
+
+		// CreateOIDCProvider stores the configuration of a new provider. It returns sqlcon.ErrUniqueViolation
+		// if a provider with the same ID exists.
+		CreateOIDCProvider(ctx context.Context, c *StoredConfiguration) error
+
+		// UpdateOIDCProvider replaces the stored configuration of the provider or returns sqlcon.ErrNoRows.
+		UpdateOIDCProvider(ctx context.Context, c *StoredConfiguration) error
+
+		// DeleteOIDCProvider deletes the stored configuration of the provider or returns sqlcon.ErrNoRows.
+		DeleteOIDCProvider(ctx context.Context, providerID string) error
+
+		// NetworkID returns the network whose providers are stored.
+		NetworkID(ctx context.Context) uuid.UUID
+	}
+
+	ProviderPersistenceProvider interface {
+		OIDCProviderPersister() ProviderPersister
+	}
+)
@@ -0,0 +1,79 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"slices"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	PKCEAuto  = "auto"
+	PKCEForce = "force"
+	PKCENever = "never"
+)
+
+// PKCEEnabled returns true if the provider is configured to use Proof Key for Code Exchange. In `auto`
+// mode, PKCE is used if the provider's discovery document supports the S256 challenge method.
+func (g *ProviderGenericOIDC) PKCEEnabled(ctx context.Context) bool {
+	switch g.config.PKCE {
+	case PKCEForce:
+		return true
+	case PKCENever:
+		return false
+	}
+
+	if g.config.IssuerURL == "" {
+		return false
+	}
+
+	p, err := g.provider(ctx)
+	if err != nil {
+		return false
+	}
+
+	var discovery struct {
+		CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+	}
+	if err := p.Claims(&discovery); err != nil {
+		return false
+	}
+
+	return slices.Contains(discovery.CodeChallengeMethodsSupported, "S256")
+}
+
+// pkceVerifier derives the code verifier from the state parameter using the default secret. It does not
+// need to be stored between the authorization request and the callback, which also works for API flows.
+func (s *Strategy) pkceVerifier(ctx context.Context, state string) string {
+	mac := hmac.New(sha256.New, s.d.Config().SecretsDefault(ctx)[0])
+	_, _ = mac.Write([]byte(state))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// pkceChallengeOptions returns the code challenge parameters of the authorization request.
+func (s *Strategy) pkceChallengeOptions(ctx context.Context, provider Provider, state string) []oauth2.AuthCodeOption {
+	if p, ok := provider.(PKCEProvider); !ok || !p.PKCEEnabled(ctx) {
+		return nil
+	}
+
+	challenge := sha256.Sum256([]byte(s.pkceVerifier(ctx, state)))
+	return []oauth2.AuthCodeOption{
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	}
+}
+
+// pkceVerifierOptions returns the code verifier parameter of the token request.
+func (s *Strategy) pkceVerifierOptions(ctx context.Context, provider Provider, state string) []oauth2.AuthCodeOption {
+	if p, ok := provider.(PKCEProvider); !ok || !p.PKCEEnabled(ctx) {
+		return nil
+	}
+
+	return []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("code_verifier", s.pkceVerifier(ctx, state))}
+}
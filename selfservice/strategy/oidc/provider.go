@@ -27,6 +27,23 @@ type TokenExchanger interface {
 	Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error)
 }
 
+// PKCEProvider is implemented by providers which support Proof Key for Code Exchange.
+type PKCEProvider interface {
+	PKCEEnabled(ctx context.Context) bool
+}
+
+// ClientAssertionProvider is implemented by providers which can authenticate the client at the token
+// endpoint with a signed assertion.
+type ClientAssertionProvider interface {
+	ClientAssertionOptions(ctx context.Context, tokenURL string) ([]oauth2.AuthCodeOption, error)
+	ClientAssertionParams(ctx context.Context, tokenURL string) (url.Values, error)
+}
+
+// BackChannelLogoutProvider is implemented by providers which support OpenID Connect Back-Channel Logout.
+type BackChannelLogoutProvider interface {
+	VerifyLogoutToken(ctx context.Context, rawLogoutToken string) (*LogoutToken, error)
+}
+
 type IDTokenVerifier interface {
 	Verify(ctx context.Context, rawIDToken string) (*Claims, error)
 }
@@ -62,6 +79,7 @@ type Claims struct {
 	Team                string                 `json:"team,omitempty"`
 	Nonce               string                 `json:"nonce,omitempty"`
 	NonceSupported      bool                   `json:"nonce_supported,omitempty"`
+	SessionID           string                 `json:"sid,omitempty"`
 	RawClaims           map[string]interface{} `json:"raw_claims,omitempty"`
 }
 
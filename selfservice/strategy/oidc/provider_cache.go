@@ -0,0 +1,72 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// providerCacheTTL limits how long other instances use the stored providers after they were changed using
+// the admin API of one instance, which invalidates its own cache immediately.
+const providerCacheTTL = time.Minute
+
+type (
+	// ProviderCache caches the decrypted configurations of the providers which are stored using the admin
+	// API per network, so that they are not listed and decrypted for every request.
+	ProviderCache struct {
+		sync.Mutex
+
+		entries    map[uuid.UUID]providerCacheEntry
+		generation uint64
+	}
+
+	providerCacheEntry struct {
+		providers []Configuration
+		expiresAt time.Time
+	}
+
+	ProviderCacheProvider interface {
+		OIDCProviderCache() *ProviderCache
+	}
+)
+
+func NewProviderCache() *ProviderCache {
+	return &ProviderCache{entries: make(map[uuid.UUID]providerCacheEntry)}
+}
+
+// get returns the cached providers of the network and the generation to pass to set once they were loaded
+// if they are not cached.
+func (c *ProviderCache) get(nid uuid.UUID) ([]Configuration, uint64, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.entries[nid]
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, c.generation, false
+	}
+	return e.providers, c.generation, true
+}
+
+// set caches the providers of the network unless the cache was invalidated since they were loaded.
+func (c *ProviderCache) set(nid uuid.UUID, generation uint64, providers []Configuration) {
+	c.Lock()
+	defer c.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	c.entries[nid] = providerCacheEntry{providers: providers, expiresAt: time.Now().Add(providerCacheTTL)}
+}
+
+// Invalidate drops the cached providers of the network after they were changed.
+func (c *ProviderCache) Invalidate(nid uuid.UUID) {
+	c.Lock()
+	defer c.Unlock()
+
+	delete(c.entries, nid)
+	c.generation++
+}
@@ -105,6 +105,26 @@ type Configuration struct {
 	// More information: https://openid.net/specs/openid-connect-core-1_0.html#ClaimsParameter
 	RequestedClaims json.RawMessage `json:"requested_claims"`
 
+	// PKCE controls the use of Proof Key for Code Exchange (S256). Can be either `auto` (the default), which uses
+	// PKCE if the provider's discovery document supports S256, `force`, or `never`.
+	PKCE string `json:"pkce"`
+
+	// TokenEndpointAuthMethod is the method the client authenticates with at the token endpoint. Can be either
+	// `client_secret_basic`, `client_secret_post`, `client_secret_jwt`, or `private_key_jwt`. If empty, the method
+	// is detected automatically.
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method"`
+
+	// ClientAssertionPrivateKey is the PEM encoded RSA or ECDSA private key which signs the client assertion
+	// when `token_endpoint_auth_method` is set to `private_key_jwt`.
+	ClientAssertionPrivateKey string `json:"client_assertion_private_key"`
+
+	// ClientAssertionKeyID is the key ID included in the header of client assertions signed with
+	// `client_assertion_private_key`.
+	ClientAssertionKeyID string `json:"client_assertion_key_id"`
+
+	// ResponseMode is the response mode requested from the provider. Can be either `query` or `form_post`.
+	ResponseMode string `json:"response_mode"`
+
 	// An optional organization ID that this provider belongs to.
 	// This parameter is only effective in the Ory Network.
 	OrganizationID string `json:"organization_id"`
@@ -156,6 +176,15 @@ var supportedProviders = map[string]func(config *Configuration, reg Dependencies
 	"lark":       NewProviderLark,
 }
 
+func (c ConfigurationCollection) has(id string) bool {
+	for _, p := range c.Providers {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
 func (c ConfigurationCollection) Provider(id string, reg Dependencies) (Provider, error) {
 	for k := range c.Providers {
 		p := c.Providers[k]
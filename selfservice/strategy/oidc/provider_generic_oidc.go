@@ -16,7 +16,11 @@ import (
 	gooidc "github.com/coreos/go-oidc"
 )
 
-var _ Provider = new(ProviderGenericOIDC)
+var (
+	_ Provider                = new(ProviderGenericOIDC)
+	_ PKCEProvider            = new(ProviderGenericOIDC)
+	_ ClientAssertionProvider = new(ProviderGenericOIDC)
+)
 
 type ProviderGenericOIDC struct {
 	p      *gooidc.Provider
@@ -55,9 +59,21 @@ func (g *ProviderGenericOIDC) oauth2ConfigFromEndpoint(ctx context.Context, endp
 		scope = append(scope, gooidc.ScopeOpenID)
 	}
 
+	clientSecret := g.config.ClientSecret
+	switch g.config.TokenEndpointAuthMethod {
+	case TokenEndpointAuthClientSecretBasic:
+		endpoint.AuthStyle = oauth2.AuthStyleInHeader
+	case TokenEndpointAuthClientSecretPost:
+		endpoint.AuthStyle = oauth2.AuthStyleInParams
+	case TokenEndpointAuthClientSecretJWT, TokenEndpointAuthPrivateKeyJWT:
+		// The client authenticates with an assertion, see ClientAssertionOptions, and never sends its secret.
+		endpoint.AuthStyle = oauth2.AuthStyleInParams
+		clientSecret = ""
+	}
+
 	return &oauth2.Config{
 		ClientID:     g.config.ClientID,
-		ClientSecret: g.config.ClientSecret,
+		ClientSecret: clientSecret,
 		Endpoint:     endpoint,
 		Scopes:       scope,
 		RedirectURL:  g.config.Redir(g.reg.Config().OIDCRedirectURIBase(ctx)),
@@ -83,6 +99,9 @@ func (g *ProviderGenericOIDC) AuthCodeURLOptions(r ider) []oauth2.AuthCodeOption
 	if len(g.config.RequestedClaims) != 0 {
 		options = append(options, oauth2.SetAuthURLParam("claims", string(g.config.RequestedClaims)))
 	}
+	if g.config.ResponseMode != "" {
+		options = append(options, oauth2.SetAuthURLParam("response_mode", g.config.ResponseMode))
+	}
 
 	return options
 }
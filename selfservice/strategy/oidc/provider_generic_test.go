@@ -5,10 +5,17 @@ package oidc_test
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"net/url"
 	"testing"
 
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/oauth2"
+
 	"github.com/ory/kratos/driver"
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/internal"
@@ -94,3 +101,86 @@ func TestProviderGenericOIDC_AddAuthCodeURLOptions(t *testing.T) {
 		assert.Contains(t, makeAuthCodeURL(t, r, reg), "claims="+url.QueryEscape(string(makeOIDCClaims())))
 	})
 }
+
+func TestProviderGenericOIDC_ClientAuthentication(t *testing.T) {
+	ctx := context.Background()
+	_, reg := internal.NewFastRegistryWithMocks(t)
+
+	// optionsQuery renders the options as the query of a URL to inspect them.
+	optionsQuery := func(t *testing.T, opts []oauth2.AuthCodeOption) url.Values {
+		c := &oauth2.Config{Endpoint: oauth2.Endpoint{AuthURL: "https://example.org/auth"}}
+		u, err := url.Parse(c.AuthCodeURL("state", opts...))
+		require.NoError(t, err)
+		return u.Query()
+	}
+
+	newProvider := func(c *oidc.Configuration) *oidc.ProviderGenericOIDC {
+		c.Provider, c.ID, c.ClientID = "generic", "valid", "client"
+		return oidc.NewProviderGenericOIDC(c, reg).(*oidc.ProviderGenericOIDC)
+	}
+
+	t.Run("case=signs the client assertion with the private key", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+
+		p := newProvider(&oidc.Configuration{
+			TokenEndpointAuthMethod:   oidc.TokenEndpointAuthPrivateKeyJWT,
+			ClientAssertionPrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+			ClientAssertionKeyID:      "key-1",
+		})
+
+		opts, err := p.ClientAssertionOptions(ctx, "https://example.org/token")
+		require.NoError(t, err)
+		q := optionsQuery(t, opts)
+		assert.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer", q.Get("client_assertion_type"))
+
+		var claims jwt.RegisteredClaims
+		token, err := jwt.ParseWithClaims(q.Get("client_assertion"), &claims, func(*jwt.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "RS256", token.Method.Alg())
+		assert.Equal(t, "key-1", token.Header["kid"])
+		assert.Equal(t, "client", claims.Issuer)
+		assert.Equal(t, "client", claims.Subject)
+		assert.Equal(t, jwt.ClaimStrings{"https://example.org/token"}, claims.Audience)
+	})
+
+	t.Run("case=signs the client assertion with the client secret", func(t *testing.T) {
+		p := newProvider(&oidc.Configuration{TokenEndpointAuthMethod: oidc.TokenEndpointAuthClientSecretJWT, ClientSecret: "secret"})
+
+		opts, err := p.ClientAssertionOptions(ctx, "https://example.org/token")
+		require.NoError(t, err)
+
+		_, err = jwt.Parse(optionsQuery(t, opts).Get("client_assertion"), func(*jwt.Token) (interface{}, error) {
+			return []byte("secret"), nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("case=rejects invalid private keys", func(t *testing.T) {
+		p := newProvider(&oidc.Configuration{TokenEndpointAuthMethod: oidc.TokenEndpointAuthPrivateKeyJWT, ClientAssertionPrivateKey: "not a key"})
+
+		_, err := p.ClientAssertionOptions(ctx, "https://example.org/token")
+		require.Error(t, err)
+	})
+
+	t.Run("case=does not use an assertion by default", func(t *testing.T) {
+		opts, err := newProvider(&oidc.Configuration{ClientSecret: "secret"}).ClientAssertionOptions(ctx, "https://example.org/token")
+		require.NoError(t, err)
+		assert.Empty(t, opts)
+	})
+
+	t.Run("case=requests the configured response mode", func(t *testing.T) {
+		p := newProvider(&oidc.Configuration{ResponseMode: "form_post"})
+		assert.Equal(t, "form_post", optionsQuery(t, p.AuthCodeURLOptions(&login.Flow{ID: x.NewUUID()})).Get("response_mode"))
+	})
+
+	t.Run("case=uses PKCE as configured", func(t *testing.T) {
+		assert.True(t, newProvider(&oidc.Configuration{PKCE: oidc.PKCEForce}).PKCEEnabled(ctx))
+		assert.False(t, newProvider(&oidc.Configuration{PKCE: oidc.PKCENever}).PKCEEnabled(ctx))
+		assert.False(t, newProvider(&oidc.Configuration{}).PKCEEnabled(ctx), "auto mode requires discovery")
+	})
+}
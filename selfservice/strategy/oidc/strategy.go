@@ -14,6 +14,7 @@ import (
 	"net/url"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/ory/x/urlx"
 
@@ -63,6 +64,7 @@ const (
 	RouteAuth                 = RouteBase + "/auth/:flow"
 	RouteCallback             = RouteBase + "/callback/:provider"
 	RouteOrganizationCallback = RouteBase + "/organization/:organization/callback/:provider"
+	RouteBackChannelLogout    = RouteBase + "/backchannel-logout/:provider"
 )
 
 var _ identity.ActiveCredentialsCounter = new(Strategy)
@@ -86,6 +88,7 @@ type Dependencies interface {
 	identity.ManagementProvider
 
 	session.ManagementProvider
+	session.PersistenceProvider
 	session.HandlerProvider
 	sessiontokenexchange.PersistenceProvider
 
@@ -112,6 +115,9 @@ type Dependencies interface {
 	cipher.Provider
 
 	jsonnetsecure.VMProvider
+
+	ProviderPersistenceProvider
+	ProviderCacheProvider
 }
 
 func isForced(req interface{}) bool {
@@ -214,11 +220,27 @@ func (s *Strategy) setRoutes(r *x.RouterPublic) {
 		// CSRF handler does not allow to define a rule based on the request method, at least not yet.
 		s.d.CSRFHandler().ExemptPath(RouteBase + "/callback/apple")
 
+		// Providers can also be configured to use the form_post response mode. Providers added after
+		// the routes were set up require a restart.
+		if conf, err := s.Config(context.Background()); err == nil {
+			for _, p := range conf.Providers {
+				if p.ResponseMode == "form_post" {
+					s.d.CSRFHandler().ExemptPath(RouteBase + "/callback/" + p.ID)
+				}
+			}
+		}
+
 		// When handler is called using POST method, the cookies are not attached to the request
 		// by the browser. So here we just redirect the request to the same location rewriting the
 		// form fields to query params. This second GET request should have the cookies attached.
 		r.POST(RouteCallback, s.redirectToGET)
 	}
+
+	// Providers send logout tokens from their backend, which never carries a CSRF token.
+	if handle, _, _ := r.Lookup("POST", RouteBackChannelLogout); handle == nil {
+		s.d.CSRFHandler().IgnoreGlob(RouteBase + "/backchannel-logout/*")
+		r.POST(RouteBackChannelLogout, strategy.IsDisabled(s.d, s.ID().String(), s.handleBackChannelLogout))
+	}
 }
 
 // Redirect POST request to GET rewriting form fields to query params.
@@ -400,6 +422,9 @@ func (s *Strategy) HandleCallback(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
+	// The callback URL does not contain the flow, so changes to the identity are attributed to it here.
+	r = r.WithContext(identity.WithAuditActor(r.Context(), identity.AuditActor{Type: identity.AuditActorSelfService, ID: req.GetID().String()}))
+
 	if authenticated, err := s.alreadyAuthenticated(w, r, req); err != nil {
 		s.forwardError(w, r, req, s.handleError(w, r, req, pid, nil, err))
 	} else if authenticated {
@@ -412,7 +437,7 @@ func (s *Strategy) HandleCallback(w http.ResponseWriter, r *http.Request, ps htt
 		return
 	}
 
-	token, err := s.ExchangeCode(r.Context(), provider, code)
+	token, err := s.ExchangeCode(r.Context(), provider, code, r.URL.Query().Get("state"))
 	if err != nil {
 		s.forwardError(w, r, req, s.handleError(w, r, req, pid, nil, err))
 		return
@@ -467,21 +492,32 @@ func (s *Strategy) HandleCallback(w http.ResponseWriter, r *http.Request, ps htt
 	}
 }
 
-func (s *Strategy) ExchangeCode(ctx context.Context, provider Provider, code string) (token *oauth2.Token, err error) {
+func (s *Strategy) ExchangeCode(ctx context.Context, provider Provider, code, state string) (token *oauth2.Token, err error) {
 	ctx, span := s.d.Tracer(ctx).Tracer().Start(ctx, "strategy.oidc.ExchangeCode")
 	defer otelx.End(span, &err)
 	span.SetAttributes(attribute.String("provider_id", provider.Config().ID))
 	span.SetAttributes(attribute.String("provider_label", provider.Config().Label))
 
+	opts := s.pkceVerifierOptions(ctx, provider, state)
+
 	te, ok := provider.(TokenExchanger)
 	if !ok {
-		te, err = provider.OAuth2(ctx)
+		c, err := provider.OAuth2(ctx)
 		if err != nil {
 			return nil, err
 		}
+		te = c
+
+		if ca, ok := provider.(ClientAssertionProvider); ok {
+			assertion, err := ca.ClientAssertionOptions(ctx, c.Endpoint.TokenURL)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, assertion...)
+		}
 	}
 
-	token, err = te.Exchange(ctx, code)
+	token, err = te.Exchange(ctx, code, opts...)
 	return token, err
 }
 
@@ -519,6 +555,10 @@ func (s *Strategy) populateMethod(r *http.Request, f flow.Flow, message func(pro
 		}
 	}
 
+	if lf, ok := f.(*login.Flow); ok {
+		providers = s.providersOfOrganization(r.Context(), lf, providers)
+	}
+
 	// does not need sorting because there is only one field
 	c := f.GetUI()
 	c.SetCSRF(s.d.GenerateCSRFToken(r))
@@ -527,20 +567,136 @@ func (s *Strategy) populateMethod(r *http.Request, f flow.Flow, message func(pro
 	return nil
 }
 
+// providersOfOrganization only returns the providers of the login flow's organization. If identifier-first
+// login is enabled, the providers of organizations are hidden until the user was routed to their organization.
+func (s *Strategy) providersOfOrganization(ctx context.Context, f *login.Flow, providers []Configuration) []Configuration {
+	if !f.OrganizationID.Valid && !s.d.Config().SelfServiceStrategy(ctx, identity.CredentialsTypeIdentifierFirst.String()).Enabled {
+		return providers
+	}
+
+	var orgID string
+	if f.OrganizationID.Valid {
+		orgID = f.OrganizationID.UUID.String()
+	}
+
+	filtered := make([]Configuration, 0, len(providers))
+	for _, p := range providers {
+		if p.OrganizationID == orgID {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered
+}
+
+// Config returns the providers of the configuration file and the providers managed using the admin API.
+// Providers of the configuration file take precedence over stored providers with the same ID.
 func (s *Strategy) Config(ctx context.Context) (*ConfigurationCollection, error) {
+	return allConfig(ctx, s.d)
+}
+
+// allConfig returns the providers of the configuration file and the providers managed using the admin API.
+// If the stored providers can not be loaded, only the providers of the configuration file are returned.
+func allConfig(ctx context.Context, d Dependencies) (*ConfigurationCollection, error) {
+	c, err := fileConfig(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := storedConfig(ctx, d)
+	if err != nil {
+		d.Logger().WithError(err).Error("Unable to load the stored OpenID Connect provider configurations, only using the configuration file.")
+		return c, nil
+	}
+
+	for _, p := range stored {
+		if !c.has(p.ID) {
+			c.Providers = append(c.Providers, p)
+		}
+	}
+
+	return c, nil
+}
+
+// storedConfig returns the decrypted providers which are managed using the admin API. They are cached per
+// network until they are changed.
+func storedConfig(ctx context.Context, d Dependencies) ([]Configuration, error) {
+	nid := d.OIDCProviderPersister().NetworkID(ctx)
+	providers, generation, ok := d.OIDCProviderCache().get(nid)
+	if ok {
+		return providers, nil
+	}
+
+	stored, err := d.OIDCProviderPersister().ListOIDCProviders(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	providers = make([]Configuration, 0, len(stored))
+	for _, sc := range stored {
+		p, err := decryptConfiguration(ctx, d, &sc)
+		if err != nil {
+			// A single broken provider must not prevent signing in with the others.
+			d.Logger().WithError(err).WithField("provider", sc.ProviderID).Error("Unable to decrypt the stored OpenID Connect provider configuration.")
+			continue
+		}
+		providers = append(providers, *p)
+	}
+
+	d.OIDCProviderCache().set(nid, generation, providers)
+	return providers, nil
+}
+
+// fileConfig returns the providers of the configuration file.
+func fileConfig(ctx context.Context, d Dependencies) (*ConfigurationCollection, error) {
 	var c ConfigurationCollection
 
-	conf := s.d.Config().SelfServiceStrategy(ctx, string(s.ID())).Config
+	conf := d.Config().SelfServiceStrategy(ctx, string(identity.CredentialsTypeOIDC)).Config
 	if err := jsonx.
 		NewStrictDecoder(bytes.NewBuffer(conf)).
 		Decode(&c); err != nil {
-		s.d.Logger().WithError(err).WithField("config", conf)
+		d.Logger().WithError(err).WithField("config", conf)
 		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode OpenID Connect Provider configuration: %s", err))
 	}
 
+	removeUnsupportedProviders(d, &c)
 	return &c, nil
 }
 
+// ValidateConfiguration returns an error if the provider configuration can not be decoded or
+// none of the configured providers is supported. Unsupported providers are only logged and
+// hidden, so that they do not prevent signing in with the others.
+func (s *Strategy) ValidateConfiguration(ctx context.Context) error {
+	var c ConfigurationCollection
+	if err := jsonx.
+		NewStrictDecoder(bytes.NewBuffer(s.d.Config().SelfServiceStrategy(ctx, string(s.ID())).Config)).
+		Decode(&c); err != nil {
+		return errors.Wrap(err, "unable to decode the OpenID Connect provider configuration")
+	}
+
+	for _, p := range c.Providers {
+		if _, err := c.Provider(p.ID, s.d); err == nil {
+			return nil
+		}
+	}
+	if len(c.Providers) > 0 {
+		return errors.New("none of the configured OpenID Connect providers is supported")
+	}
+	return nil
+}
+
+// removeUnsupportedProviders removes and logs the providers whose type is not supported.
+func removeUnsupportedProviders(d Dependencies, c *ConfigurationCollection) {
+	supported := make([]Configuration, 0, len(c.Providers))
+	for _, p := range c.Providers {
+		if _, err := c.Provider(p.ID, d); err != nil {
+			d.Logger().WithError(err).WithField("provider", p.ID).Error("Ignoring the OpenID Connect provider because its configuration is unusable.")
+			continue
+		}
+		supported = append(supported, p)
+	}
+	c.Providers = supported
+}
+
 func (s *Strategy) provider(ctx context.Context, r *http.Request, id string) (Provider, error) {
 	if c, err := s.Config(ctx); err != nil {
 		return nil, err
@@ -610,6 +766,17 @@ func (s *Strategy) handleError(w http.ResponseWriter, r *http.Request, f flow.Fl
 					}
 				}
 
+				for _, ls := range s.d.AllLoginStrategies() {
+					if lc, ok := ls.(login.LinkingConfirmationStrategy); ok {
+						if err := lc.PopulateLinkingConfirmationMethod(r, lf); err != nil {
+							return err
+						}
+					}
+				}
+
+				// Guide the user to sign in to the account the credentials are linked to.
+				lf.UI.Nodes.SetValueAttribute("identifier", dc.DuplicateIdentifier)
+
 				newLoginURL := s.d.Config().SelfServiceFlowLoginUI(r.Context()).String()
 				lf.UI.Messages.Add(text.NewInfoLoginLinkMessage(dc.DuplicateIdentifier, provider, newLoginURL))
 
@@ -735,3 +902,34 @@ func (s *Strategy) linkCredentials(ctx context.Context, i *identity.Identity, id
 
 	return nil
 }
+
+// setAccessTokenExpiry records when the access token stored for the provider and subject expires, so that it is
+// only refreshed once it expired.
+func setAccessTokenExpiry(creds *identity.Credentials, provider, subject string, expiresAt *time.Time) error {
+	var conf identity.CredentialsOIDC
+	if err := json.Unmarshal(creds.Config, &conf); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for k := range conf.Providers {
+		if conf.Providers[k].Provider == provider && conf.Providers[k].Subject == subject {
+			conf.Providers[k].AccessTokenExpiresAt = expiresAt
+		}
+	}
+
+	config, err := json.Marshal(conf)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	creds.Config = config
+	return nil
+}
+
+// updateLoginTokens stores the tokens which the provider issued when the identity signed in, so that the admin API
+// returns the most recent access token and knows when it expires.
+func (s *Strategy) updateLoginTokens(ctx context.Context, i *identity.Identity, linked *identity.CredentialsOIDCProvider, token *oauth2.Token) error {
+	if err := setTokens(ctx, s.d.Cipher(ctx), linked, token); err != nil {
+		return err
+	}
+	return storeTokens(ctx, s.d.PrivilegedIdentityPool(), i.ID, linked)
+}
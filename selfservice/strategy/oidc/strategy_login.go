@@ -169,8 +169,17 @@ func (s *Strategy) processLogin(w http.ResponseWriter, r *http.Request, loginFlo
 	sess := session.NewInactiveSession()
 	sess.CompletedLoginForWithProvider(s.ID(), identity.AuthenticatorAssuranceLevel1, provider.Config().ID,
 		httprouter.ParamsFromContext(r.Context()).ByName("organization"))
-	for _, c := range oidcCredentials.Providers {
-		if c.Subject == claims.Subject && c.Provider == provider.Config().ID {
+	for k := range oidcCredentials.Providers {
+		linked := &oidcCredentials.Providers[k]
+		if linked.Subject == claims.Subject && linked.Provider == provider.Config().ID {
+			if token != nil {
+				if err := s.updateLoginTokens(r.Context(), i, linked, token); err != nil {
+					return nil, s.handleError(w, r, loginFlow, provider.Config().ID, nil, err)
+				}
+			}
+			if err := setUpstreamSession(loginFlow, provider, claims); err != nil {
+				return nil, s.handleError(w, r, loginFlow, provider.Config().ID, nil, err)
+			}
 			if err = s.d.LoginHookExecutor().PostLoginHook(w, r, node.OpenIDConnectGroup, loginFlow, i, sess, provider.Config().ID); err != nil {
 				return nil, s.handleError(w, r, loginFlow, provider.Config().ID, nil, err)
 			}
@@ -276,7 +285,8 @@ func (s *Strategy) Login(w http.ResponseWriter, r *http.Request, f *login.Flow,
 		return nil, err
 	}
 
-	codeURL := c.AuthCodeURL(state.String(), append(UpstreamParameters(provider, up), provider.AuthCodeURLOptions(req)...)...)
+	opts := append(UpstreamParameters(provider, up), provider.AuthCodeURLOptions(req)...)
+	codeURL := c.AuthCodeURL(state.String(), append(opts, s.pkceChallengeOptions(ctx, provider, state.String())...)...)
 	if x.IsJSONRequest(r) {
 		s.d.Writer().WriteError(w, r, flow.NewBrowserLocationChangeRequiredError(codeURL))
 	} else {
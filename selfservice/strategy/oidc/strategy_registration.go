@@ -240,7 +240,8 @@ func (s *Strategy) Register(w http.ResponseWriter, r *http.Request, f *registrat
 		return err
 	}
 
-	codeURL := c.AuthCodeURL(state.String(), append(UpstreamParameters(provider, up), provider.AuthCodeURLOptions(req)...)...)
+	opts := append(UpstreamParameters(provider, up), provider.AuthCodeURLOptions(req)...)
+	codeURL := c.AuthCodeURL(state.String(), append(opts, s.pkceChallengeOptions(ctx, provider, state.String())...)...)
 	if x.IsJSONRequest(r) {
 		s.d.Writer().WriteError(w, r, flow.NewBrowserLocationChangeRequiredError(codeURL))
 	} else {
@@ -320,7 +321,15 @@ func (s *Strategy) processRegistration(w http.ResponseWriter, r *http.Request, r
 	}
 
 	// Validate the identity itself
-	if err := s.d.IdentityValidator().Validate(r.Context(), i); err != nil {
+	stepCtx, err := s.d.RegistrationHandler().StepValidationContext(r.Context(), rf)
+	if err != nil {
+		return nil, s.handleError(w, r, rf, provider.Config().ID, i.Traits, err)
+	}
+	if err := s.d.IdentityValidator().Validate(stepCtx, i); err != nil {
+		return nil, s.handleError(w, r, rf, provider.Config().ID, i.Traits, err)
+	}
+
+	if err := s.d.IdentityValidator().ValidateRegistrationEmailDomains(r.Context(), i); err != nil {
 		return nil, s.handleError(w, r, rf, provider.Config().ID, i.Traits, err)
 	}
 
@@ -360,8 +369,14 @@ func (s *Strategy) processRegistration(w http.ResponseWriter, r *http.Request, r
 	if err != nil {
 		return nil, s.handleError(w, r, rf, provider.Config().ID, i.Traits, err)
 	}
+	if err := setAccessTokenExpiry(creds, provider.Config().ID, claims.Subject, accessTokenExpiry(token)); err != nil {
+		return nil, s.handleError(w, r, rf, provider.Config().ID, i.Traits, err)
+	}
 
 	i.SetCredentials(s.ID(), *creds)
+	if err := setUpstreamSession(rf, provider, claims); err != nil {
+		return nil, s.handleError(w, r, rf, provider.Config().ID, i.Traits, err)
+	}
 	if err := s.d.RegistrationExecutor().PostRegistrationHook(w, r, identity.CredentialsTypeOIDC, provider.Config().ID, rf, i); err != nil {
 		return nil, s.handleError(w, r, rf, provider.Config().ID, i.Traits, err)
 	}
@@ -434,6 +449,12 @@ func (s *Strategy) setTraits(w http.ResponseWriter, r *http.Request, a *registra
 	} else {
 		i.Traits = identity.Traits(json.RawMessage(jsonTraits.Raw))
 	}
+
+	traits, err := registration.MergeStepTraits(a, json.RawMessage(i.Traits))
+	if err != nil {
+		return s.handleError(w, r, a, provider.Config().ID, nil, err)
+	}
+	i.Traits = identity.Traits(traits)
 	s.d.Logger().
 		WithRequest(r).
 		WithField("oidc_provider", provider.Config().ID).
@@ -375,7 +375,8 @@ func (s *Strategy) initLinkProvider(w http.ResponseWriter, r *http.Request, ctxU
 		return err
 	}
 
-	codeURL := c.AuthCodeURL(state, append(UpstreamParameters(provider, up), provider.AuthCodeURLOptions(req)...)...)
+	opts := append(UpstreamParameters(provider, up), provider.AuthCodeURLOptions(req)...)
+	codeURL := c.AuthCodeURL(state, append(opts, s.pkceChallengeOptions(r.Context(), provider, state)...)...)
 	if x.IsJSONRequest(r) {
 		s.d.Writer().WriteError(w, r, flow.NewBrowserLocationChangeRequiredError(codeURL))
 	} else {
@@ -417,6 +418,12 @@ func (s *Strategy) linkProvider(w http.ResponseWriter, r *http.Request, ctxUpdat
 	if err := s.linkCredentials(r.Context(), i, it, cat, crt, provider.Config().ID, claims.Subject, provider.Config().OrganizationID); err != nil {
 		return s.handleSettingsError(w, r, ctxUpdate, p, err)
 	}
+	if creds, ok := i.GetCredentials(s.ID()); ok {
+		if err := setAccessTokenExpiry(creds, provider.Config().ID, claims.Subject, accessTokenExpiry(token)); err != nil {
+			return s.handleSettingsError(w, r, ctxUpdate, p, err)
+		}
+		i.SetCredentials(s.ID(), *creds)
+	}
 
 	if err := s.d.SettingsHookExecutor().PostSettingsHook(w, r, s.SettingsStrategyID(), ctxUpdate, i, settings.WithCallback(func(ctxUpdate *settings.UpdateContext) error {
 		return s.PopulateSettingsMethod(r, ctxUpdate.Session.Identity, ctxUpdate.Flow)
@@ -537,6 +544,12 @@ func (s *Strategy) Link(ctx context.Context, i *identity.Identity, credentialsCo
 	); err != nil {
 		return err
 	}
+	if creds, ok := i.GetCredentials(s.ID()); ok {
+		if err := setAccessTokenExpiry(creds, credentialsOIDCProvider.Provider, credentialsOIDCProvider.Subject, credentialsOIDCProvider.AccessTokenExpiresAt); err != nil {
+			return err
+		}
+		i.SetCredentials(s.ID(), *creds)
+	}
 
 	options := []identity.ManagerOption{identity.ManagerAllowWriteProtectedTraits}
 	if err := s.d.IdentityManager().Update(ctx, i, options...); err != nil {
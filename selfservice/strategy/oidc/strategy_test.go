@@ -414,7 +414,7 @@ func TestStrategy(t *testing.T) {
 				t,
 				json.RawMessage(fmt.Sprintf(`{"providers": [{"subject":"%s","provider":"%s"}]}`, subject, provider)),
 				json.RawMessage(c),
-				[]string{"providers.0.initial_id_token", "providers.0.initial_access_token", "providers.0.initial_refresh_token"},
+				[]string{"providers.0.initial_id_token", "providers.0.initial_access_token", "providers.0.initial_refresh_token", "providers.0.access_token_expires_at"},
 			)
 		}
 
@@ -443,11 +443,12 @@ func TestStrategy(t *testing.T) {
 		require.NoError(t, err)
 		c := i.Credentials[identity.CredentialsTypeOIDC].Config
 		assert.NotEmpty(t, gjson.GetBytes(c, "providers.0.initial_access_token").String())
+		assert.NotEmpty(t, gjson.GetBytes(c, "providers.0.access_token_expires_at").String())
 		assertx.EqualAsJSONExcept(
 			t,
 			json.RawMessage(fmt.Sprintf(`{"providers": [{"subject":"%s","provider":"%s"}]}`, subject, provider)),
 			json.RawMessage(c),
-			[]string{"providers.0.initial_id_token", "providers.0.initial_access_token", "providers.0.initial_refresh_token"},
+			[]string{"providers.0.initial_id_token", "providers.0.initial_access_token", "providers.0.initial_refresh_token", "providers.0.access_token_expires_at"},
 		)
 		return id
 	}
@@ -456,12 +457,19 @@ func TestStrategy(t *testing.T) {
 		subject = "register-then-login@ory.sh"
 		scope = []string{"openid", "offline"}
 
+		storedAccessToken := func(t *testing.T, id uuid.UUID) string {
+			i, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), id)
+			require.NoError(t, err)
+			return gjson.GetBytes(i.Credentials[identity.CredentialsTypeOIDC].Config, "providers.0.initial_access_token").String()
+		}
+
+		var registered string
 		t.Run("case=should pass registration", func(t *testing.T) {
 			r := newBrowserRegistrationFlow(t, returnTS.URL, time.Minute)
 			action := assertFormValues(t, r.ID, "valid")
 			res, body := makeRequest(t, "valid", action, url.Values{})
 			assertIdentity(t, res, body)
-			expectTokens(t, "valid", body)
+			registered = storedAccessToken(t, expectTokens(t, "valid", body))
 			assert.Equal(t, "valid", gjson.GetBytes(body, "authentication_methods.0.provider").String(), "%s", body)
 		})
 
@@ -470,7 +478,8 @@ func TestStrategy(t *testing.T) {
 			action := assertFormValues(t, r.ID, "valid")
 			res, body := makeRequest(t, "valid", action, url.Values{})
 			assertIdentity(t, res, body)
-			expectTokens(t, "valid", body)
+			id := expectTokens(t, "valid", body)
+			assert.NotEqual(t, registered, storedAccessToken(t, id), "the tokens issued at login are stored")
 			assert.Equal(t, "valid", gjson.GetBytes(body, "authentication_methods.0.provider").String(), "%s", body)
 		})
 	})
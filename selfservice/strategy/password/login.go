@@ -26,6 +26,7 @@ import (
 	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/lockout"
 	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/ui/node"
 	"github.com/ory/kratos/x"
@@ -67,22 +68,62 @@ func (s *Strategy) Login(w http.ResponseWriter, r *http.Request, f *login.Flow,
 		return nil, s.handleLoginError(w, r, f, &p, err)
 	}
 
+	mitigate := s.d.Config().SelfServiceAccountEnumerationMitigate(r.Context())
+	if mitigate {
+		defer x.WaitAtLeast(r.Context(), time.Now(), s.d.Config().SelfServiceAccountEnumerationResponseTime(r.Context()))
+	}
+
+	ip := x.TrustedClientIP(r, s.d.Config().PublicTrustedProxies(r.Context()))
+	if err := s.d.LockoutManager().Check(r.Context(), uuid.Nil, ip); err != nil {
+		return nil, s.handleLoginError(w, r, f, &p, err)
+	}
+
 	i, c, err := s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), s.ID(), stringsx.Coalesce(p.Identifier, p.LegacyIdentifier))
 	if err != nil {
-		time.Sleep(x.RandomDelay(s.d.Config().HasherArgon2(r.Context()).ExpectedDuration, s.d.Config().HasherArgon2(r.Context()).ExpectedDeviation))
+		if mitigate {
+			// Hashing the password costs as much as comparing it to the hash of a known identity.
+			_, _ = s.d.Hasher(r.Context()).Generate(r.Context(), []byte(p.Password))
+		} else {
+			time.Sleep(x.RandomDelay(s.d.Config().HasherArgon2(r.Context()).ExpectedDuration, s.d.Config().HasherArgon2(r.Context()).ExpectedDeviation))
+		}
+		if err := s.d.LockoutManager().RecordFailure(r.Context(), uuid.Nil, ip); err != nil {
+			return nil, s.handleLoginError(w, r, f, &p, err)
+		}
 		return nil, s.handleLoginError(w, r, f, &p, errors.WithStack(schema.NewInvalidCredentialsError()))
 	}
 
+	locked := s.d.LockoutManager().Check(r.Context(), i.ID, "")
+	if locked != nil && !errors.Is(locked, lockout.ErrIdentityLocked) {
+		return nil, s.handleLoginError(w, r, f, &p, locked)
+	}
+
 	var o identity.CredentialsPassword
 	d := json.NewDecoder(bytes.NewBuffer(c.Config))
 	if err := d.Decode(&o); err != nil {
 		return nil, herodot.ErrInternalServerError.WithReason("The password credentials could not be decoded properly").WithDebug(err.Error()).WithWrap(err)
 	}
 
-	if err := hash.Compare(r.Context(), []byte(p.Password), []byte(o.HashedPassword)); err != nil {
+	err = hash.Compare(r.Context(), []byte(p.Password), []byte(o.HashedPassword))
+	if locked != nil {
+		// The password is compared regardless so that locked accounts can not be told
+		// apart from unknown ones by the response or the response time. The attempt
+		// counts for the IP address like attempts with unknown identifiers.
+		if err := s.d.LockoutManager().RecordFailure(r.Context(), uuid.Nil, ip); err != nil {
+			return nil, s.handleLoginError(w, r, f, &p, err)
+		}
+		return nil, s.handleLoginError(w, r, f, &p, errors.WithStack(schema.NewInvalidCredentialsError()))
+	}
+	if err != nil {
+		if err := s.d.LockoutManager().RecordFailure(r.Context(), i.ID, ip); err != nil {
+			return nil, s.handleLoginError(w, r, f, &p, err)
+		}
 		return nil, s.handleLoginError(w, r, f, &p, errors.WithStack(schema.NewInvalidCredentialsError()))
 	}
 
+	if err := s.d.LockoutManager().RecordSuccess(r.Context(), i.ID); err != nil {
+		return nil, s.handleLoginError(w, r, f, &p, err)
+	}
+
 	if !s.d.Hasher(r.Context()).Understands([]byte(o.HashedPassword)) {
 		if err := s.migratePasswordHash(r.Context(), i.ID, []byte(p.Password)); err != nil {
 			return nil, s.handleLoginError(w, r, f, &p, err)
@@ -146,6 +187,9 @@ func (s *Strategy) PopulateLoginMethod(r *http.Request, requestedAAL identity.Au
 
 		sr.UI.SetCSRF(s.d.GenerateCSRFToken(r))
 		sr.UI.SetNode(node.NewInputField("identifier", identifier, node.DefaultGroup, node.InputAttributeTypeHidden))
+	} else if hint := sr.ReauthenticationHint(); hint != nil && hint.Method == s.ID() {
+		// The session expired recently, so we only ask for the password of the remembered identifier.
+		sr.UI.SetNode(node.NewInputField("identifier", hint.Identifier, node.DefaultGroup, node.InputAttributeTypeHidden))
 	} else {
 		sr.UI.SetNode(node.NewInputField("identifier", "", node.DefaultGroup, node.InputAttributeTypeText, node.WithRequiredInputAttribute).WithMetaLabel(text.NewInfoNodeLabelID()))
 	}
@@ -101,6 +101,11 @@ func (s *Strategy) Register(w http.ResponseWriter, r *http.Request, f *registrat
 		p.Traits = json.RawMessage("{}")
 	}
 
+	traits, err := registration.MergeStepTraits(f, p.Traits)
+	if err != nil {
+		return s.handleRegistrationError(w, r, f, &p, err)
+	}
+
 	hpw := make(chan []byte)
 	errC := make(chan error)
 	go func() {
@@ -119,7 +124,7 @@ func (s *Strategy) Register(w http.ResponseWriter, r *http.Request, f *registrat
 		return s.handleRegistrationError(w, r, f, &p, err)
 	}
 
-	i.Traits = identity.Traits(p.Traits)
+	i.Traits = identity.Traits(traits)
 	// We have to set the credential here, so the identity validator can populate the identifiers.
 	// The password hash is computed in parallel and set later.
 	if err := i.SetCredentialsWithConfig(s.ID(), identity.Credentials{Type: s.ID(), Identifiers: []string{}}, json.RawMessage("{}")); err != nil {
@@ -130,6 +135,10 @@ func (s *Strategy) Register(w http.ResponseWriter, r *http.Request, f *registrat
 		return s.handleRegistrationError(w, r, f, &p, err)
 	}
 
+	if err := s.d.IdentityValidator().ValidateRegistrationEmailDomains(r.Context(), i); err != nil {
+		return s.handleRegistrationError(w, r, f, &p, err)
+	}
+
 	select {
 	case err := <-errC:
 		return s.handleRegistrationError(w, r, f, &p, err)
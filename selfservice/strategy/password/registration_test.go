@@ -20,7 +20,9 @@ import (
 	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/ui/node"
 
+	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tidwall/gjson"
 
 	"github.com/ory/kratos/ui/container"
@@ -354,6 +356,36 @@ func TestRegistration(t *testing.T) {
 			})
 		})
 
+		t.Run("case=should hand off duplicate registrations to login", func(t *testing.T) {
+			testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/registration.schema.json")
+			conf.MustSet(ctx, config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypePassword.String()), []config.SelfServiceHook{{Name: "session"}})
+			conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationLoginHandoffEnabled, true)
+			t.Cleanup(func() {
+				conf.MustSet(ctx, config.HookStrategyKey(config.ViperKeySelfServiceRegistrationAfter, identity.CredentialsTypePassword.String()), nil)
+				conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationLoginHandoffEnabled, false)
+			})
+
+			values := func(v url.Values) {
+				v.Set("traits.username", "registration-identifier-handoff")
+				v.Set("password", x.NewUUID().String())
+				v.Set("traits.foobar", "bar")
+			}
+
+			_ = expectSuccessfulLogin(t, true, false, apiClient, values)
+			body := testhelpers.SubmitRegistrationForm(t, true, apiClient, publicTS, values,
+				false, http.StatusBadRequest, publicTS.URL+registration.RouteSubmitFlow)
+
+			continueWith := gjson.Get(body, "ui.messages.0.context.continue_with.0")
+			assert.Equal(t, "show_login_ui", continueWith.Get("action").String(), "%s", body)
+			assert.Equal(t, "registration-identifier-handoff", continueWith.Get("flow.identifier").String(), "%s", body)
+			assert.False(t, gjson.Get(body, "ui.messages.0.context.continue_with.1").Exists(), "recovery is not offered by default: %s", body)
+
+			lf, err := reg.LoginFlowPersister().GetLoginFlow(ctx, uuid.FromStringOrNil(continueWith.Get("flow.id").String()))
+			require.NoError(t, err)
+			require.NotNil(t, lf.UI.Nodes.Find("identifier"), "%+v", lf.UI.Nodes)
+			assert.Equal(t, "registration-identifier-handoff", lf.UI.Nodes.Find("identifier").Attributes.GetValue())
+		})
+
 		t.Run("case=should return correct error ids from validation failures", func(t *testing.T) {
 			test := func(t *testing.T, constraint string, setValues func(url.Values), expectedId text.ID, expectedMesage string) {
 				template := `{
@@ -22,6 +22,7 @@ import (
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/kratos/selfservice/flow/registration"
 	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/lockout"
 	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
@@ -68,6 +69,8 @@ type registrationStrategyDependencies interface {
 
 	session.HandlerProvider
 	session.ManagementProvider
+
+	lockout.ManagementProvider
 }
 
 type Strategy struct {
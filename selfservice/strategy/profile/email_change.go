@@ -0,0 +1,244 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package profile
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/httpx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/urlx"
+
+	"github.com/ory/kratos/courier/template/email"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	RouteSettingsEmailChangeConfirm = "/self-service/settings/email-change/confirm"
+	RouteSettingsEmailChangeRevert  = "/self-service/settings/email-change/revert"
+)
+
+var errEmailChangeInvalid = herodot.ErrBadRequest.WithReason("The email change link is invalid or has expired. Please try again.")
+
+// changedPrimaryEmail returns the email address of the original identity which is no longer present in the
+// updated identity, and the email address which replaces it. It returns nil if no email address was replaced.
+func changedPrimaryEmail(original, updated *identity.Identity) (*identity.VerifiableAddress, string) {
+	var from *identity.VerifiableAddress
+	for k := range original.VerifiableAddresses {
+		if a := &original.VerifiableAddresses[k]; a.Via == identity.VerifiableAddressTypeEmail && !hasEmailAddress(updated, a.Value) {
+			from = a
+			break
+		}
+	}
+	if from == nil {
+		return nil, ""
+	}
+
+	for _, a := range updated.VerifiableAddresses {
+		if a.Via == identity.VerifiableAddressTypeEmail && !hasEmailAddress(original, a.Value) {
+			return from, a.Value
+		}
+	}
+	return nil, ""
+}
+
+func hasEmailAddress(i *identity.Identity, value string) bool {
+	for _, a := range i.VerifiableAddresses {
+		if a.Via == identity.VerifiableAddressTypeEmail && a.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// startEmailChange keeps the original traits of the identity and sends a confirmation link to the new address
+// and a link to revert the change to the original address. The traits are updated once the new address is confirmed.
+func (s *Strategy) startEmailChange(w http.ResponseWriter, r *http.Request, ctxUpdate *settings.UpdateContext, original *identity.Identity, from *identity.VerifiableAddress, updated *identity.Identity, to string) error {
+	ctx := r.Context()
+
+	change := settings.NewEmailChange(original, from, updated, to,
+		s.d.Config().SelfServiceFlowSettingsVerifiedEmailChangeLifespan(ctx))
+	if err := s.d.EmailChangePersister().CreateEmailChange(ctx, change); err != nil {
+		return err
+	}
+
+	model, err := x.StructToMap(original)
+	if err != nil {
+		return err
+	}
+
+	locale := s.d.IdentityValidator().Locale(ctx, original)
+
+	c, err := s.d.Courier(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", original.ID).
+		WithSensitiveField("original_address", from.Value).
+		WithSensitiveField("new_address", to).
+		Info("Sending out email change confirmation and notice.")
+
+	if _, err := c.QueueEmail(ctx, email.NewEmailChangeConfirm(s.d, &email.EmailChangeConfirmModel{
+		To:              to,
+		ConfirmURL:      s.emailChangeURL(r, RouteSettingsEmailChangeConfirm, change.ConfirmToken),
+		OriginalAddress: from.Value,
+		Identity:        model,
+		Locale:          locale,
+	})); err != nil {
+		return err
+	}
+
+	if _, err := c.QueueEmail(ctx, email.NewEmailChangeNotice(s.d, &email.EmailChangeNoticeModel{
+		To:         from.Value,
+		NewAddress: to,
+		RevertURL:  s.emailChangeURL(r, RouteSettingsEmailChangeRevert, change.RevertToken),
+		IPAddress:  httpx.ClientIP(r),
+		UserAgent:  r.UserAgent(),
+		Identity:   model,
+		Locale:     locale,
+	})); err != nil {
+		return err
+	}
+
+	f := ctxUpdate.Flow
+	if err := s.hydrateForm(r, f, ctxUpdate.Session, json.RawMessage(original.Traits)); err != nil {
+		return err
+	}
+	f.UI.ResetMessages()
+	f.UI.Messages.Add(text.NewInfoSelfServiceSettingsEmailChangePending(to))
+	if err := s.d.SettingsFlowPersister().UpdateSettingsFlow(ctx, f); err != nil {
+		return err
+	}
+
+	if f.Type == flow.TypeAPI || x.IsJSONRequest(r) {
+		s.d.Writer().Write(w, r, f)
+	} else {
+		http.Redirect(w, r, f.AppendTo(s.d.Config().SelfServiceFlowSettingsUI(ctx)).String(), http.StatusSeeOther)
+	}
+
+	return errors.WithStack(flow.ErrCompletedByStrategy)
+}
+
+func (s *Strategy) emailChangeURL(r *http.Request, route, token string) string {
+	return urlx.CopyWithQuery(
+		urlx.AppendPaths(s.d.Config().SelfPublicURL(r.Context()), route),
+		url.Values{"token": {token}},
+	).String()
+}
+
+func (s *Strategy) confirmEmailChange(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	change, err := s.d.EmailChangePersister().GetEmailChangeByConfirmToken(ctx, r.URL.Query().Get("token"))
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, errors.WithStack(errEmailChangeInvalid))
+		return
+	} else if err != nil {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+		return
+	}
+
+	if change.State != settings.EmailChangeStatePending || !change.IsActive() {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, errors.WithStack(errEmailChangeInvalid))
+		return
+	}
+
+	// The identity may have been changed in the meantime, in which case the change no longer applies.
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(ctx, change.IdentityID)
+	if err != nil {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+		return
+	}
+	if !hasEmailAddress(i, change.OriginalAddress) {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, errors.WithStack(errEmailChangeInvalid))
+		return
+	}
+
+	if err := s.d.IdentityManager().UpdateTraits(ctx, change.IdentityID, change.NewTraits, identity.ManagerAllowWriteProtectedTraits); err != nil {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+		return
+	}
+
+	if _, err := s.d.IdentityManager().SetAddressVerified(ctx, change.IdentityID, change.NewAddress, true, x.Now().UTC()); err != nil {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+		return
+	}
+
+	change.State = settings.EmailChangeStateConfirmed
+	if err := s.d.EmailChangePersister().UpdateEmailChange(ctx, change); err != nil {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+		return
+	}
+
+	s.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", change.IdentityID).
+		Info("The new email address was confirmed and applied to the identity.")
+
+	x.ContentNegotiationRedirection(w, r, change, s.d.Writer(), s.d.Config().SelfServiceFlowSettingsUI(ctx).String())
+}
+
+func (s *Strategy) revertEmailChange(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	change, err := s.d.EmailChangePersister().GetEmailChangeByRevertToken(ctx, r.URL.Query().Get("token"))
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, errors.WithStack(errEmailChangeInvalid))
+		return
+	} else if err != nil {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+		return
+	}
+
+	if change.State == settings.EmailChangeStateReverted || !change.IsActive() {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, errors.WithStack(errEmailChangeInvalid))
+		return
+	}
+
+	if change.State == settings.EmailChangeStateConfirmed {
+		if err := s.d.IdentityManager().UpdateTraits(ctx, change.IdentityID, change.OriginalTraits, identity.ManagerAllowWriteProtectedTraits); err != nil {
+			s.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+			return
+		}
+
+		if change.OriginalAddressVerified {
+			if _, err := s.d.IdentityManager().SetAddressVerified(ctx, change.IdentityID, change.OriginalAddress, true, x.Now().UTC()); err != nil {
+				s.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+				return
+			}
+		}
+
+		// Whoever confirmed the change may still be signed in.
+		if err := s.d.SessionPersister().DeleteSessionsByIdentity(ctx, change.IdentityID); err != nil {
+			s.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+			return
+		}
+	}
+
+	change.State = settings.EmailChangeStateReverted
+	if err := s.d.EmailChangePersister().UpdateEmailChange(ctx, change); err != nil {
+		s.d.SelfServiceErrorManager().Forward(ctx, w, r, err)
+		return
+	}
+
+	s.d.Audit().
+		WithRequest(r).
+		WithField("identity_id", change.IdentityID).
+		Info("The email change was reverted using the link sent to the original address.")
+
+	x.ContentNegotiationRedirection(w, r, change, s.d.Writer(), s.d.Config().SelfServiceFlowSettingsUI(ctx).String())
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/ory/herodot"
 	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/courier"
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/schema"
@@ -47,6 +48,7 @@ type (
 
 		session.HandlerProvider
 		session.ManagementProvider
+		session.PersistenceProvider
 
 		identity.ValidationProvider
 		identity.ManagementProvider
@@ -60,6 +62,12 @@ type (
 		settings.StrategyProvider
 		settings.HooksProvider
 
+		settings.EmailChangePersistenceProvider
+
+		courier.Provider
+		courier.ConfigProvider
+		x.HTTPClientProvider
+
 		schema.IdentityTraitsProvider
 	}
 	Strategy struct {
@@ -76,7 +84,10 @@ func (s *Strategy) SettingsStrategyID() string {
 	return settings.StrategyProfile
 }
 
-func (s *Strategy) RegisterSettingsRoutes(public *x.RouterPublic) {}
+func (s *Strategy) RegisterSettingsRoutes(public *x.RouterPublic) {
+	public.GET(RouteSettingsEmailChangeConfirm, s.confirmEmailChange)
+	public.GET(RouteSettingsEmailChangeRevert, s.revertEmailChange)
+}
 
 func (s *Strategy) PopulateSettingsMethod(r *http.Request, id *identity.Identity, f *settings.Flow) error {
 	schemas, err := s.d.Config().IdentityTraitsSchemas(r.Context())
@@ -174,6 +185,16 @@ func (s *Strategy) continueFlow(w http.ResponseWriter, r *http.Request, ctxUpdat
 		return err
 	}
 
+	if s.d.Config().SelfServiceFlowSettingsVerifiedEmailChangeEnabled(r.Context()) {
+		original, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), update.ID)
+		if err != nil {
+			return err
+		}
+		if from, to := changedPrimaryEmail(original, update); from != nil {
+			return s.startEmailChange(w, r, ctxUpdate, original, from, update, to)
+		}
+	}
+
 	ctxUpdate.UpdateIdentity(update)
 	return nil
 }
@@ -0,0 +1,101 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package push
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/randx"
+)
+
+const InternalContextKeyChallenge = "challenge"
+
+type challengeStatus string
+
+const (
+	challengeStatusPending  challengeStatus = "pending"
+	challengeStatusApproved challengeStatus = "approved"
+	challengeStatusDenied   challengeStatus = "denied"
+)
+
+// challenge is the pending approval request of a login flow. It is stored
+// in the flow's internal context so that the approval endpoint and the
+// polling login request can share it.
+type challenge struct {
+	ID         uuid.UUID       `json:"id"`
+	SecretHash string          `json:"secret_hash"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	Status     challengeStatus `json:"status"`
+
+	// DeviceID is the device which answered the approval request.
+	DeviceID uuid.UUID `json:"device_id"`
+}
+
+func newChallenge(lifespan time.Duration) (*challenge, string) {
+	secret := randx.MustString(32, randx.AlphaNum)
+	return &challenge{
+		ID:         x.NewUUID(),
+		SecretHash: hashSecret(secret),
+		ExpiresAt:  x.Now().UTC().Add(lifespan),
+		Status:     challengeStatusPending,
+	}, secret
+}
+
+func hashSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+func (c *challenge) verifySecret(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(c.SecretHash)) == 1
+}
+
+func (c *challenge) expired() bool {
+	return c.ExpiresAt.Before(x.Now())
+}
+
+func challengeKey() string {
+	return flow.PrefixInternalContextKey(identity.CredentialsTypePush, InternalContextKeyChallenge)
+}
+
+// getChallenge returns the challenge stored in the internal context or nil
+// if no approval request was sent yet.
+func getChallenge(internalContext []byte) (*challenge, error) {
+	raw := gjson.GetBytes(internalContext, challengeKey())
+	if !raw.IsObject() {
+		return nil, nil
+	}
+
+	var c challenge
+	if err := json.Unmarshal([]byte(raw.Raw), &c); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &c, nil
+}
+
+func setChallenge(internalContext []byte, c *challenge) ([]byte, error) {
+	if len(internalContext) == 0 {
+		internalContext = []byte("{}")
+	}
+	return sjson.SetBytes(internalContext, challengeKey(), c)
+}
+
+func deleteChallenge(internalContext []byte) ([]byte, error) {
+	if len(internalContext) == 0 {
+		return internalContext, nil
+	}
+	return sjson.DeleteBytes(internalContext, challengeKey())
+}
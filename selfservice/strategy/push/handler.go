@@ -0,0 +1,143 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package push
+
+import (
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/selfservice/strategy"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+)
+
+const RouteApprove = "/self-service/methods/push/approve"
+
+// Answer Push Approval Request Parameters
+//
+// swagger:parameters answerPushApprovalRequest
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type answerPushApprovalRequest struct {
+	// in: body
+	Body answerPushApprovalRequestBody
+}
+
+// Answer Push Approval Request Request Body
+//
+// swagger:model answerPushApprovalRequestBody
+type answerPushApprovalRequestBody struct {
+	// The ID of the login flow waiting for the approval.
+	//
+	// required: true
+	Flow uuid.UUID `json:"flow"`
+
+	// The ID of the approval request.
+	//
+	// required: true
+	Request uuid.UUID `json:"request"`
+
+	// The secret which was sent to the device together with the approval request.
+	//
+	// required: true
+	Secret string `json:"secret"`
+
+	// The ID of the device answering the approval request.
+	Device uuid.UUID `json:"device"`
+
+	// Whether the sign-in attempt is approved or denied.
+	Approve bool `json:"approve"`
+}
+
+func (s *Strategy) RegisterLoginRoutes(r *x.RouterPublic) {
+	if handle, _, _ := r.Lookup("POST", RouteApprove); handle == nil {
+		// The approval request is answered by the device and not by the browser
+		// which started the login flow.
+		s.d.CSRFHandler().IgnorePath(RouteApprove)
+		r.POST(RouteApprove, strategy.IsDisabled(s.d, s.ID().String(), s.answerPushApprovalRequest))
+	}
+}
+
+// swagger:route POST /self-service/methods/push/approve frontend answerPushApprovalRequest
+//
+// # Answer a Push Approval Request
+//
+// This endpoint is called by a registered device to approve or deny a sign-in attempt
+// for which an approval request was sent to the device.
+//
+// The login flow waiting for the approval continues once it polls again.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  204: emptyResponse
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  409: errorGeneric
+//	  410: errorGeneric
+//	  default: errorGeneric
+func (s *Strategy) answerPushApprovalRequest(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+
+	var p answerPushApprovalRequestBody
+	if err := s.hd.Decode(r, &p, decoderx.HTTPJSONDecoder()); err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	notFound := errors.WithStack(herodot.ErrNotFound.WithReason("The approval request does not exist or the secret is invalid."))
+
+	f, err := s.d.LoginFlowPersister().GetLoginFlow(ctx, p.Flow)
+	if err != nil {
+		s.d.Writer().WriteError(w, r, notFound)
+		return
+	}
+
+	ch, err := getChallenge(f.InternalContext)
+	if err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	} else if ch == nil || ch.ID != p.Request || !ch.verifySecret(p.Secret) {
+		s.d.Writer().WriteError(w, r, notFound)
+		return
+	}
+
+	if err := f.Valid(); err != nil || ch.expired() {
+		s.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("The approval request expired.")))
+		return
+	} else if ch.Status != challengeStatusPending {
+		s.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrConflict.WithReason("The approval request was already answered.")))
+		return
+	}
+
+	ch.Status = challengeStatusDenied
+	if p.Approve {
+		ch.Status = challengeStatusApproved
+	}
+	ch.DeviceID = p.Device
+
+	f.InternalContext, err = setChallenge(f.InternalContext, ch)
+	if err != nil {
+		s.d.Writer().WriteError(w, r, errors.WithStack(err))
+		return
+	}
+
+	if err := s.d.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
+		s.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,300 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/httpx"
+	"github.com/ory/x/pointerx"
+)
+
+// pollInterval is the interval in which the login flow is re-read while
+// waiting for the device to answer the approval request.
+const pollInterval = 500 * time.Millisecond
+
+func (s *Strategy) PopulateLoginMethod(r *http.Request, requestedAAL identity.AuthenticatorAssuranceLevel, sr *login.Flow) error {
+	// This strategy can only solve AAL2
+	if requestedAAL != identity.AuthenticatorAssuranceLevel2 {
+		return nil
+	}
+
+	// We have done proper validation before so this should never error
+	sess, err := s.d.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		return err
+	}
+
+	id, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), sess.IdentityID)
+	if err != nil {
+		return err
+	}
+
+	count, err := s.CountActiveMultiFactorCredentials(id.Credentials)
+	if err != nil {
+		return err
+	} else if count == 0 {
+		// Identity has no push devices
+		return nil
+	}
+
+	sr.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	sr.UI.GetNodes().Append(node.NewInputField("method", s.ID(), node.PushGroup, node.InputAttributeTypeSubmit).WithMetaLabel(text.NewInfoSelfServiceLoginPush()))
+
+	return nil
+}
+
+func (s *Strategy) handleLoginError(r *http.Request, f *login.Flow, err error) error {
+	if errors.Is(err, flow.ErrCompletedByStrategy) {
+		return err
+	}
+
+	if f != nil {
+		f.UI.Nodes.Remove(node.PushChallenge)
+		if f.Type == flow.TypeBrowser {
+			f.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+		}
+	}
+
+	return err
+}
+
+// Update Login Flow with Push Method
+//
+// Submitting this payload sends an approval request to all devices of the
+// identity. Submitting it again while the approval request is pending waits
+// for the device to answer.
+//
+// swagger:model updateLoginFlowWithPushMethod
+type updateLoginFlowWithPushMethod struct {
+	// Method should be set to "push" when logging in using the push strategy.
+	//
+	// required: true
+	Method string `json:"method"`
+
+	// Sending the anti-csrf token is only required for browser login flows.
+	CSRFToken string `json:"csrf_token"`
+}
+
+func (s *Strategy) Login(w http.ResponseWriter, r *http.Request, f *login.Flow, identityID uuid.UUID) (i *identity.Identity, err error) {
+	if err := login.CheckAAL(f, identity.AuthenticatorAssuranceLevel2); err != nil {
+		return nil, err
+	}
+
+	if err := flow.MethodEnabledAndAllowedFromRequest(r, f.GetFlowName(), s.ID().String(), s.d); err != nil {
+		return nil, err
+	}
+
+	var p updateLoginFlowWithPushMethod
+	if err := s.hd.Decode(r, &p,
+		decoderx.HTTPDecoderSetValidatePayloads(true),
+		decoderx.MustHTTPRawJSONSchemaCompiler(loginSchema),
+		decoderx.HTTPDecoderJSONFollowsFormFormat()); err != nil {
+		return nil, s.handleLoginError(r, f, err)
+	}
+
+	if err := flow.EnsureCSRF(s.d, r, f.Type, s.d.Config().DisableAPIFlowEnforcement(r.Context()), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		return nil, s.handleLoginError(r, f, err)
+	}
+
+	i, c, err := s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), s.ID(), identityID.String())
+	if err != nil {
+		return nil, s.handleLoginError(r, f, errors.WithStack(schema.NewNoPushDeviceRegistered()))
+	}
+
+	var o identity.CredentialsPushConfig
+	if err := json.Unmarshal(c.Config, &o); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The push credentials could not be decoded properly").WithDebug(err.Error()).WithWrap(err))
+	}
+
+	if len(o.Devices) == 0 {
+		return nil, s.handleLoginError(r, f, errors.WithStack(schema.NewNoPushDeviceRegistered()))
+	}
+
+	ch, err := getChallenge(f.InternalContext)
+	if err != nil {
+		return nil, s.handleLoginError(r, f, err)
+	} else if ch == nil {
+		return nil, s.handleLoginError(r, f, s.sendChallenge(w, r, f, i, o.Devices))
+	}
+
+	ch, err = s.waitForAnswer(r.Context(), f, ch)
+	if err != nil {
+		return nil, s.handleLoginError(r, f, err)
+	}
+
+	switch {
+	case ch.Status == challengeStatusApproved:
+		if err := s.completeChallenge(r.Context(), f, i, c, &o, ch); err != nil {
+			return nil, s.handleLoginError(r, f, err)
+		}
+		return i, nil
+	case ch.Status == challengeStatusDenied:
+		return nil, s.handleLoginError(r, f, s.discardChallenge(r.Context(), f, schema.NewPushApprovalDeniedError()))
+	case ch.expired():
+		return nil, s.handleLoginError(r, f, s.discardChallenge(r.Context(), f, schema.NewPushApprovalExpiredError()))
+	}
+
+	// The device did not answer yet, the client needs to poll again. The flow
+	// is not persisted here as that could overwrite a concurrent answer.
+	return nil, s.handleLoginError(r, f, s.writePendingChallenge(w, r, f, ch, false))
+}
+
+// sendChallenge sends a new approval request to all devices of the identity.
+func (s *Strategy) sendChallenge(w http.ResponseWriter, r *http.Request, f *login.Flow, i *identity.Identity, devices identity.CredentialsPushDevices) error {
+	ctx := r.Context()
+	ch, secret := newChallenge(s.d.Config().PushChallengeLifespan(ctx))
+	req := &ApprovalRequest{
+		ID:         ch.ID,
+		FlowID:     f.ID,
+		IdentityID: i.ID,
+		Secret:     secret,
+		ExpiresAt:  ch.ExpiresAt,
+		ClientIP:   httpx.ClientIP(r),
+		UserAgent:  r.UserAgent(),
+	}
+
+	var sent int
+	for _, device := range devices {
+		if err := s.provider.SendApprovalRequest(ctx, device, req); err != nil {
+			s.d.Logger().WithError(err).WithField("device_id", device.ID).Warn("Unable to send push approval request to device.")
+			continue
+		}
+		sent++
+	}
+
+	if sent == 0 {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReason("The approval request could not be delivered to any of your devices. Please try again later."))
+	}
+
+	var err error
+	f.InternalContext, err = setChallenge(f.InternalContext, ch)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return s.writePendingChallenge(w, r, f, ch, true)
+}
+
+// waitForAnswer re-reads the login flow until the device answered the
+// approval request, the request expired, or the poll timeout was reached.
+func (s *Strategy) waitForAnswer(ctx context.Context, f *login.Flow, ch *challenge) (*challenge, error) {
+	timeout := time.NewTimer(s.d.Config().PushPollTimeout(ctx))
+	defer timeout.Stop()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for ch.Status == challengeStatusPending && !ch.expired() {
+		select {
+		case <-ctx.Done():
+			return nil, errors.WithStack(ctx.Err())
+		case <-timeout.C:
+			return ch, nil
+		case <-ticker.C:
+		}
+
+		current, err := s.d.LoginFlowPersister().GetLoginFlow(ctx, f.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		next, err := getChallenge(current.InternalContext)
+		if err != nil {
+			return nil, err
+		} else if next == nil || next.ID != ch.ID {
+			return nil, errors.WithStack(schema.NewPushApprovalExpiredError())
+		}
+
+		f.InternalContext = current.InternalContext
+		ch = next
+	}
+
+	return ch, nil
+}
+
+// completeChallenge removes the answered approval request from the flow and
+// records the use of the device which approved it.
+func (s *Strategy) completeChallenge(ctx context.Context, f *login.Flow, i *identity.Identity, c *identity.Credentials, o *identity.CredentialsPushConfig, ch *challenge) (err error) {
+	f.InternalContext, err = deleteChallenge(f.InternalContext)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	f.Active = s.ID()
+	if err := s.d.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReason("Could not update flow").WithDebug(err.Error()))
+	}
+
+	device, ok := o.Devices.Find(ch.DeviceID.String())
+	if !ok {
+		return nil
+	}
+
+	device.LastUsedAt = pointerx.Ptr(time.Now().UTC().Round(time.Second))
+	encoded, err := json.Marshal(o)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to encode updated push credentials.").WithDebug(err.Error()))
+	}
+
+	c.Config = encoded
+	i.SetCredentials(s.ID(), *c)
+	return s.d.PrivilegedIdentityPool().UpdateIdentity(ctx, i)
+}
+
+// discardChallenge removes the approval request from the flow so that the
+// next submission sends a new one, and returns the given error.
+func (s *Strategy) discardChallenge(ctx context.Context, f *login.Flow, cause error) (err error) {
+	f.InternalContext, err = deleteChallenge(f.InternalContext)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := s.d.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
+		return err
+	}
+
+	return cause
+}
+
+func (s *Strategy) writePendingChallenge(w http.ResponseWriter, r *http.Request, f *login.Flow, ch *challenge, persist bool) error {
+	ctx := r.Context()
+
+	f.Active = s.ID()
+	f.UI.Messages.Set(text.NewInfoSelfServiceLoginPushSent(ch.ExpiresAt))
+	f.UI.Nodes.Upsert(node.NewInputField(node.PushChallenge, ch.ID.String(), node.PushGroup, node.InputAttributeTypeHidden))
+	if f.Type == flow.TypeBrowser {
+		f.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	}
+
+	if persist {
+		if err := s.d.LoginFlowPersister().UpdateLoginFlow(ctx, f); err != nil {
+			return err
+		}
+	}
+
+	if x.IsJSONRequest(r) {
+		s.d.Writer().WriteCode(w, r, http.StatusBadRequest, f)
+	} else {
+		http.Redirect(w, r, f.AppendTo(s.d.Config().SelfServiceFlowLoginUI(ctx)).String(), http.StatusSeeOther)
+	}
+
+	// The login flow is not completed until the device approved the request.
+	return errors.WithStack(flow.ErrCompletedByStrategy)
+}
@@ -0,0 +1,235 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package push_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/x/sqlxx"
+
+	"github.com/ory/kratos/driver"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/selfservice/strategy/push"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+)
+
+type approvalRequests struct {
+	sync.Mutex
+	bodies []string
+}
+
+func (a *approvalRequests) last(t *testing.T) gjson.Result {
+	a.Lock()
+	defer a.Unlock()
+	require.NotEmpty(t, a.bodies)
+	return gjson.Parse(a.bodies[len(a.bodies)-1])
+}
+
+func newProviderServer(t *testing.T) (*httptest.Server, *approvalRequests) {
+	requests := new(approvalRequests)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests.Lock()
+		requests.bodies = append(requests.bodies, string(body))
+		requests.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, requests
+}
+
+func createIdentity(t *testing.T, reg driver.Registry, devices ...identity.CredentialPushDevice) *identity.Identity {
+	ctx := context.Background()
+	identifier := x.NewUUID().String() + "@ory.sh"
+	p, err := reg.Hasher(ctx).Generate(ctx, []byte(x.NewUUID().String()))
+	require.NoError(t, err)
+
+	i := &identity.Identity{Traits: identity.Traits(fmt.Sprintf(`{"subject":"%s"}`, identifier))}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+
+	config, err := json.Marshal(identity.CredentialsPushConfig{Devices: devices})
+	require.NoError(t, err)
+	i.Credentials = map[identity.CredentialsType]identity.Credentials{
+		identity.CredentialsTypePassword: {
+			Type:        identity.CredentialsTypePassword,
+			Identifiers: []string{identifier},
+			Config:      sqlxx.JSONRawMessage(`{"hashed_password":"` + string(p) + `"}`),
+		},
+		identity.CredentialsTypePush: {
+			Type:        identity.CredentialsTypePush,
+			Identifiers: []string{i.ID.String()},
+			Config:      config,
+		},
+	}
+	require.NoError(t, i.SetAvailableAAL(ctx, reg.IdentityManager()))
+	require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentity(ctx, i))
+	return i
+}
+
+func TestCompleteLogin(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	providerTS, requests := newProviderServer(t)
+
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypePassword), map[string]interface{}{"enabled": true})
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+"."+string(identity.CredentialsTypePush), map[string]interface{}{"enabled": true})
+	conf.MustSet(ctx, config.ViperKeyPushRequestConfig, map[string]interface{}{
+		"url":    providerTS.URL,
+		"method": "POST",
+		"body":   "base64://" + base64.StdEncoding.EncodeToString([]byte("function(ctx) ctx")),
+	})
+	conf.MustSet(ctx, config.ViperKeyPushPollTimeout, "1ms")
+
+	router := x.NewRouterPublic()
+	publicTS, _ := testhelpers.NewKratosServerWithRouters(t, reg, router, x.NewRouterAdmin())
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/login.schema.json")
+	conf.MustSet(ctx, config.ViperKeySecretsDefault, []string{"not-a-secure-session-key"})
+
+	device := identity.CredentialPushDevice{
+		ID:          x.NewUUID(),
+		DisplayName: "Phone",
+		Platform:    "ios",
+		Token:       "device-token",
+		AddedAt:     time.Now().UTC().Round(time.Second),
+	}
+
+	submit := func(t *testing.T, id *identity.Identity) (func() (string, *http.Response), string) {
+		apiClient := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, id)
+		f := testhelpers.InitializeLoginFlowViaAPI(t, apiClient, publicTS, false, testhelpers.InitFlowWithAAL(identity.AuthenticatorAssuranceLevel2))
+		return func() (string, *http.Response) {
+			return testhelpers.LoginMakeRequest(t, true, false, f, apiClient, `{"method":"push"}`)
+		}, f.Id
+	}
+
+	answer := func(t *testing.T, flowID string, request gjson.Result, secret string, approve bool) *http.Response {
+		body, err := json.Marshal(map[string]interface{}{
+			"flow":    flowID,
+			"request": request.Get("id").String(),
+			"secret":  secret,
+			"device":  device.ID,
+			"approve": approve,
+		})
+		require.NoError(t, err)
+		res, err := publicTS.Client().Post(publicTS.URL+push.RouteApprove, "application/json", bytes.NewReader(body))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res
+	}
+
+	t.Run("case=push payload is set when identity has devices", func(t *testing.T) {
+		id := createIdentity(t, reg, device)
+
+		apiClient := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, id)
+		f := testhelpers.InitializeLoginFlowViaAPI(t, apiClient, publicTS, false, testhelpers.InitFlowWithAAL(identity.AuthenticatorAssuranceLevel2))
+		var found bool
+		for _, n := range f.Ui.Nodes {
+			if n.Group == "push" {
+				found = true
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("case=should fail if identity has no devices", func(t *testing.T) {
+		id := createIdentity(t, reg)
+
+		do, _ := submit(t, id)
+		body, res := do()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, text.NewErrorValidationNoPushDevice().Text, gjson.Get(body, "ui.messages.0.text").String(), "%s", body)
+	})
+
+	t.Run("case=should sign in once the device approved the request", func(t *testing.T) {
+		id := createIdentity(t, reg, device)
+
+		do, flowID := submit(t, id)
+		body, res := do()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.EqualValues(t, text.InfoSelfServiceLoginPushSent, gjson.Get(body, "ui.messages.0.id").Int(), "%s", body)
+
+		sent := requests.last(t)
+		assert.Equal(t, device.Token, sent.Get("device_token").String())
+		assert.Equal(t, flowID, sent.Get("request.flow_id").String())
+		assert.Equal(t, id.ID.String(), sent.Get("request.identity_id").String())
+
+		// Still waiting for the device.
+		body, res = do()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.EqualValues(t, text.InfoSelfServiceLoginPushSent, gjson.Get(body, "ui.messages.0.id").Int(), "%s", body)
+
+		assert.Equal(t, http.StatusNotFound, answer(t, flowID, sent.Get("request"), "wrong-secret", true).StatusCode)
+		assert.Equal(t, http.StatusNoContent, answer(t, flowID, sent.Get("request"), sent.Get("request.secret").String(), true).StatusCode)
+		assert.Equal(t, http.StatusConflict, answer(t, flowID, sent.Get("request"), sent.Get("request.secret").String(), true).StatusCode)
+
+		body, res = do()
+		assert.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.EqualValues(t, identity.AuthenticatorAssuranceLevel2, gjson.Get(body, "session.authenticator_assurance_level").String(), "%s", body)
+		assert.EqualValues(t, identity.CredentialsTypePush, gjson.Get(body, "session.authentication_methods.1.method").String(), "%s", body)
+
+		actual, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(ctx, id.ID)
+		require.NoError(t, err)
+		c, ok := actual.GetCredentials(identity.CredentialsTypePush)
+		require.True(t, ok)
+		assert.True(t, gjson.GetBytes(c.Config, "devices.0.last_used_at").Exists(), "%s", c.Config)
+	})
+
+	t.Run("case=should fail if the device denied the request", func(t *testing.T) {
+		id := createIdentity(t, reg, device)
+
+		do, flowID := submit(t, id)
+		_, res := do()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		sent := requests.last(t)
+		assert.Equal(t, http.StatusNoContent, answer(t, flowID, sent.Get("request"), sent.Get("request.secret").String(), false).StatusCode)
+
+		body, res := do()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, text.NewErrorValidationPushDenied().Text, gjson.Get(body, "ui.messages.0.text").String(), "%s", body)
+
+		// A new approval request is sent on the next submission.
+		body, _ = do()
+		assert.EqualValues(t, text.InfoSelfServiceLoginPushSent, gjson.Get(body, "ui.messages.0.id").Int(), "%s", body)
+		assert.NotEqual(t, sent.Get("request.id").String(), requests.last(t).Get("request.id").String())
+	})
+
+	t.Run("case=should fail if the request expired", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeyPushChallengeLifespan, "1ms")
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeyPushChallengeLifespan, "2m")
+		})
+		id := createIdentity(t, reg, device)
+
+		do, flowID := submit(t, id)
+		_, res := do()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+		time.Sleep(5 * time.Millisecond)
+		sent := requests.last(t)
+		assert.Equal(t, http.StatusBadRequest, answer(t, flowID, sent.Get("request"), sent.Get("request.secret").String(), true).StatusCode)
+
+		body, res := do()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, text.NewErrorValidationPushExpired().Text, gjson.Get(body, "ui.messages.0.text").String(), "%s", body)
+	})
+}
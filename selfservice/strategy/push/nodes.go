@@ -0,0 +1,40 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package push
+
+import (
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+)
+
+func NewPushRegisterTokenNode() *node.Node {
+	return node.NewInputField(node.PushRegisterToken, "", node.PushGroup,
+		node.InputAttributeTypeText).
+		WithMetaLabel(text.NewInfoSelfServiceRegisterPushToken())
+}
+
+func NewPushRegisterDisplayNameNode() *node.Node {
+	return node.NewInputField(node.PushRegisterDisplayName, "", node.PushGroup,
+		node.InputAttributeTypeText).
+		WithMetaLabel(text.NewInfoSelfServiceRegisterPushDisplayName())
+}
+
+func NewPushRegisterPlatformNode() *node.Node {
+	return node.NewInputField(node.PushRegisterPlatform, "", node.PushGroup,
+		node.InputAttributeTypeText).
+		WithMetaLabel(text.NewInfoSelfServiceRegisterPushPlatform())
+}
+
+func NewPushRegisterNode() *node.Node {
+	return node.NewInputField("method", identity.CredentialsTypePush, node.PushGroup,
+		node.InputAttributeTypeSubmit).
+		WithMetaLabel(text.NewInfoSelfServiceSettingsRegisterPush())
+}
+
+func NewPushRemoveNode(device identity.CredentialPushDevice) *node.Node {
+	return node.NewInputField(node.PushRemove, device.ID.String(), node.PushGroup,
+		node.InputAttributeTypeSubmit).
+		WithMetaLabel(text.NewInfoSelfServiceRemovePush(device.DisplayName, device.AddedAt))
+}
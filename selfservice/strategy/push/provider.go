@@ -0,0 +1,105 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package push
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/request"
+)
+
+type (
+	// Provider delivers approval requests to registered devices.
+	//
+	// Implementations must not block until the request was answered. The
+	// device answers the approval request by calling the approval endpoint
+	// with the request ID and secret.
+	Provider interface {
+		SendApprovalRequest(ctx context.Context, device identity.CredentialPushDevice, req *ApprovalRequest) error
+	}
+
+	// ApprovalRequest is sent to a device when an identity tries to sign in.
+	ApprovalRequest struct {
+		// ID identifies the approval request.
+		ID uuid.UUID `json:"id"`
+
+		// FlowID is the ID of the login flow waiting for the approval.
+		FlowID uuid.UUID `json:"flow_id"`
+
+		// IdentityID is the ID of the identity signing in.
+		IdentityID uuid.UUID `json:"identity_id"`
+
+		// Secret must be returned by the device when answering the request.
+		Secret string `json:"secret"`
+
+		// ExpiresAt is the time after which the request can no longer be answered.
+		ExpiresAt time.Time `json:"expires_at"`
+
+		// ClientIP is the IP address of the client signing in.
+		ClientIP string `json:"client_ip,omitempty"`
+
+		// UserAgent is the user agent of the client signing in.
+		UserAgent string `json:"user_agent,omitempty"`
+	}
+
+	webhookProviderDependencies interface {
+		request.Dependencies
+		config.Provider
+	}
+
+	webhookProvider struct {
+		d webhookProviderDependencies
+	}
+
+	webhookRequestBody struct {
+		DeviceID       uuid.UUID        `json:"device_id"`
+		DeviceToken    string           `json:"device_token"`
+		DevicePlatform string           `json:"device_platform"`
+		Request        *ApprovalRequest `json:"request"`
+	}
+)
+
+var _ Provider = new(webhookProvider)
+
+// NewWebhookProvider returns a provider which delivers approval requests
+// using the configured HTTP request.
+func NewWebhookProvider(d webhookProviderDependencies) Provider {
+	return &webhookProvider{d: d}
+}
+
+func (p *webhookProvider) SendApprovalRequest(ctx context.Context, device identity.CredentialPushDevice, req *ApprovalRequest) error {
+	builder, err := request.NewBuilder(ctx, p.d.Config().PushRequestConfig(ctx), p.d)
+	if err != nil {
+		return err
+	}
+
+	r, err := builder.BuildRequest(ctx, &webhookRequestBody{
+		DeviceID:       device.ID,
+		DeviceToken:    device.Token,
+		DevicePlatform: device.Platform,
+		Request:        req,
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := p.d.HTTPClient(ctx).Do(r)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to deliver the push approval request.").WithWrap(err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The push provider responded with unexpected status code %d.", res.StatusCode))
+	}
+
+	return nil
+}
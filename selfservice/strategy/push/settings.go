@@ -0,0 +1,259 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package push
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+)
+
+func (s *Strategy) RegisterSettingsRoutes(_ *x.RouterPublic) {
+}
+
+func (s *Strategy) SettingsStrategyID() string {
+	return identity.CredentialsTypePush.String()
+}
+
+// Update Settings Flow with Push Method
+//
+// swagger:model updateSettingsFlowWithPushMethod
+type updateSettingsFlowWithPushMethod struct {
+	// RegisterToken is the push token of the device to register.
+	RegisterToken string `json:"push_register_token"`
+
+	// RegisterDisplayName is the name of the device to register.
+	RegisterDisplayName string `json:"push_register_displayname"`
+
+	// RegisterPlatform is the platform (e.g. "ios" or "android") of the device to register.
+	RegisterPlatform string `json:"push_register_platform"`
+
+	// Remove is the ID of a registered device which should be removed.
+	Remove string `json:"push_remove"`
+
+	// CSRFToken is the anti-CSRF token
+	CSRFToken string `json:"csrf_token"`
+
+	// Method
+	//
+	// Should be set to "push" when trying to add or remove a device.
+	//
+	// required: true
+	Method string `json:"method"`
+
+	// Flow is flow ID.
+	//
+	// swagger:ignore
+	Flow string `json:"flow"`
+}
+
+func (p *updateSettingsFlowWithPushMethod) GetFlowID() uuid.UUID {
+	return x.ParseUUID(p.Flow)
+}
+
+func (p *updateSettingsFlowWithPushMethod) SetFlowID(rid uuid.UUID) {
+	p.Flow = rid.String()
+}
+
+func (s *Strategy) Settings(w http.ResponseWriter, r *http.Request, f *settings.Flow, ss *session.Session) (*settings.UpdateContext, error) {
+	var p updateSettingsFlowWithPushMethod
+	ctxUpdate, err := settings.PrepareUpdate(s.d, w, r, f, ss, settings.ContinuityKey(s.SettingsStrategyID()), &p)
+	if errors.Is(err, settings.ErrContinuePreviousAction) {
+		return ctxUpdate, s.continueSettingsFlow(w, r, ctxUpdate, &p)
+	} else if err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, &p, err)
+	}
+
+	if err := s.decodeSettingsFlow(r, &p); err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, &p, err)
+	}
+
+	if len(p.Remove) > 0 {
+		// This is a submit so we need to manually set the type to push
+		p.Method = s.SettingsStrategyID()
+		if err := flow.MethodEnabledAndAllowed(r.Context(), f.GetFlowName(), s.SettingsStrategyID(), p.Method, s.d); err != nil {
+			return nil, s.handleSettingsError(w, r, ctxUpdate, &p, err)
+		}
+	} else if err := flow.MethodEnabledAndAllowedFromRequest(r, f.GetFlowName(), s.SettingsStrategyID(), s.d); err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, &p, err)
+	}
+
+	// This does not come from the payload!
+	p.Flow = ctxUpdate.Flow.ID.String()
+	if err := s.continueSettingsFlow(w, r, ctxUpdate, &p); err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, &p, err)
+	}
+
+	return ctxUpdate, nil
+}
+
+func (s *Strategy) decodeSettingsFlow(r *http.Request, dest interface{}) error {
+	compiler, err := decoderx.HTTPRawJSONSchemaCompiler(settingsSchema)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return decoderx.NewHTTP().Decode(r, dest, compiler,
+		decoderx.HTTPDecoderAllowedMethods("POST", "GET"),
+		decoderx.HTTPDecoderSetValidatePayloads(true),
+		decoderx.HTTPDecoderJSONFollowsFormFormat(),
+	)
+}
+
+func (s *Strategy) continueSettingsFlow(
+	w http.ResponseWriter, r *http.Request,
+	ctxUpdate *settings.UpdateContext, p *updateSettingsFlowWithPushMethod,
+) error {
+	if err := flow.MethodEnabledAndAllowed(r.Context(), flow.SettingsFlow, s.SettingsStrategyID(), p.Method, s.d); err != nil {
+		return err
+	}
+
+	if err := flow.EnsureCSRF(s.d, r, ctxUpdate.Flow.Type, s.d.Config().DisableAPIFlowEnforcement(r.Context()), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		return err
+	}
+
+	if ctxUpdate.Session.AuthenticatedAt.Add(s.d.Config().SelfServiceFlowSettingsPrivilegedSessionMaxAge(r.Context())).Before(time.Now()) {
+		return errors.WithStack(settings.NewFlowNeedsReAuth())
+	}
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), ctxUpdate.Session.IdentityID)
+	if err != nil {
+		return err
+	}
+
+	var cc identity.CredentialsPushConfig
+	if cred, ok := i.GetCredentials(s.ID()); ok && len(cred.Config) > 0 {
+		if err := json.Unmarshal(cred.Config, &cc); err != nil {
+			return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode identity credentials.").WithDebug(err.Error()))
+		}
+	}
+
+	if len(p.Remove) > 0 {
+		err = s.continueSettingsFlowRemove(i, &cc, p)
+	} else {
+		err = s.continueSettingsFlowAdd(i, &cc, p)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctxUpdate.UpdateIdentity(i)
+	return nil
+}
+
+func (s *Strategy) continueSettingsFlowAdd(i *identity.Identity, cc *identity.CredentialsPushConfig, p *updateSettingsFlowWithPushMethod) error {
+	if len(p.RegisterToken) == 0 {
+		return schema.NewRequiredError("#/push_register_token", "push_register_token")
+	}
+
+	for _, device := range cc.Devices {
+		if device.Token == p.RegisterToken {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("This device is already registered."))
+		}
+	}
+
+	cc.Devices = append(cc.Devices, identity.CredentialPushDevice{
+		ID:          x.NewUUID(),
+		DisplayName: p.RegisterDisplayName,
+		Platform:    p.RegisterPlatform,
+		Token:       p.RegisterToken,
+		AddedAt:     time.Now().UTC().Round(time.Second),
+	})
+
+	return s.setCredentials(i, cc)
+}
+
+func (s *Strategy) continueSettingsFlowRemove(i *identity.Identity, cc *identity.CredentialsPushConfig, p *updateSettingsFlowWithPushMethod) error {
+	updated := make(identity.CredentialsPushDevices, 0, len(cc.Devices))
+	for _, device := range cc.Devices {
+		if device.ID.String() != p.Remove {
+			updated = append(updated, device)
+		}
+	}
+
+	if len(updated) == len(cc.Devices) {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("You tried to remove a device which does not exist."))
+	}
+
+	if len(updated) == 0 {
+		i.DeleteCredentialsType(s.ID())
+		return nil
+	}
+
+	cc.Devices = updated
+	return s.setCredentials(i, cc)
+}
+
+func (s *Strategy) setCredentials(i *identity.Identity, cc *identity.CredentialsPushConfig) error {
+	co, err := json.Marshal(cc)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to encode identity credentials.").WithDebug(err.Error()))
+	}
+
+	c, ok := i.GetCredentials(s.ID())
+	if !ok {
+		c = &identity.Credentials{Type: s.ID()}
+	}
+
+	// We do not really need the identifier, so we add the identity's ID
+	c.Identifiers = []string{i.ID.String()}
+	c.Config = co
+	i.SetCredentials(s.ID(), *c)
+	return nil
+}
+
+func (s *Strategy) PopulateSettingsMethod(r *http.Request, id *identity.Identity, f *settings.Flow) error {
+	f.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+
+	confidential, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), id.ID)
+	if err != nil {
+		return err
+	}
+
+	if cred, ok := confidential.GetCredentials(s.ID()); ok && len(cred.Config) > 0 {
+		var cc identity.CredentialsPushConfig
+		if err := json.Unmarshal(cred.Config, &cc); err != nil {
+			return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode identity credentials.").WithDebug(err.Error()))
+		}
+
+		for _, device := range cc.Devices {
+			f.UI.Nodes.Append(NewPushRemoveNode(device))
+		}
+	}
+
+	f.UI.Nodes.Upsert(NewPushRegisterTokenNode())
+	f.UI.Nodes.Upsert(NewPushRegisterDisplayNameNode())
+	f.UI.Nodes.Upsert(NewPushRegisterPlatformNode())
+	f.UI.Nodes.Append(NewPushRegisterNode())
+
+	return nil
+}
+
+func (s *Strategy) handleSettingsError(w http.ResponseWriter, r *http.Request, ctxUpdate *settings.UpdateContext, p *updateSettingsFlowWithPushMethod, err error) error {
+	// Do not pause flow if the flow type is an API flow as we can't save cookies in those flows.
+	if e := new(settings.FlowNeedsReAuth); errors.As(err, &e) && ctxUpdate.Flow != nil && ctxUpdate.Flow.Type == flow.TypeBrowser {
+		if err := s.d.ContinuityManager().Pause(r.Context(), w, r, settings.ContinuityKey(s.SettingsStrategyID()), settings.ContinuityOptions(p, ctxUpdate.GetSessionIdentity())...); err != nil {
+			return err
+		}
+	}
+
+	if ctxUpdate.Flow != nil {
+		ctxUpdate.Flow.UI.ResetMessages()
+		ctxUpdate.Flow.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	}
+
+	return err
+}
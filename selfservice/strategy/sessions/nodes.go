@@ -0,0 +1,37 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sessions
+
+import (
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/x/pointerx"
+)
+
+func NewRevokeNode(s session.Session) *node.Node {
+	var userAgent, ipAddress, location string
+	// A device is added whenever the session is authenticated, so the latest one was used last.
+	var latest *session.Device
+	for k := range s.Devices {
+		if latest == nil || s.Devices[k].CreatedAt.After(latest.CreatedAt) {
+			latest = &s.Devices[k]
+		}
+	}
+	if latest != nil {
+		userAgent = pointerx.Deref(latest.UserAgent)
+		ipAddress = pointerx.Deref(latest.IPAddress)
+		location = pointerx.Deref(latest.Location)
+	}
+
+	return node.NewInputField(node.SessionsRevoke, s.ID.String(), node.SessionsGroup,
+		node.InputAttributeTypeSubmit).
+		WithMetaLabel(text.NewInfoSelfServiceSettingsRevokeSession(userAgent, ipAddress, location, s.AuthenticatedAt, string(s.AuthenticatorAssuranceLevel)))
+}
+
+func NewRevokeOthersNode() *node.Node {
+	return node.NewInputField(node.SessionsRevokeOthers, "true", node.SessionsGroup,
+		node.InputAttributeTypeSubmit).
+		WithMetaLabel(text.NewInfoSelfServiceSettingsRevokeOtherSessions())
+}
@@ -0,0 +1,50 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sessions_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/strategy/sessions"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/pointerx"
+)
+
+func TestNewRevokeNode(t *testing.T) {
+	authenticatedAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	s := session.Session{
+		ID:                          x.NewUUID(),
+		AuthenticatedAt:             authenticatedAt,
+		AuthenticatorAssuranceLevel: identity.AuthenticatorAssuranceLevel2,
+		Devices: []session.Device{
+			{UserAgent: pointerx.Ptr("Safari on iOS"), IPAddress: pointerx.Ptr("192.0.2.2"), CreatedAt: authenticatedAt},
+			{UserAgent: pointerx.Ptr("Firefox on Linux"), IPAddress: pointerx.Ptr("192.0.2.1"), CreatedAt: authenticatedAt.Add(-time.Hour)},
+		},
+	}
+
+	n := sessions.NewRevokeNode(s)
+	assert.Equal(t, node.SessionsGroup, n.Group)
+	assert.Equal(t, s.ID.String(), n.Attributes.(*node.InputAttributes).FieldValue)
+
+	require.NotNil(t, n.Meta.Label)
+	assert.Equal(t, text.InfoSelfServiceSettingsRevokeSession, n.Meta.Label.ID)
+	assert.Equal(t, `Sign out "Safari on iOS"`, n.Meta.Label.Text)
+	assert.Equal(t, "192.0.2.2", gjson.GetBytes(n.Meta.Label.Context, "ip_address").String(), "uses the device which authenticated the session last")
+	assert.Equal(t, "aal2", gjson.GetBytes(n.Meta.Label.Context, "aal").String())
+	assert.Equal(t, authenticatedAt.Unix(), gjson.GetBytes(n.Meta.Label.Context, "authenticated_at_unix").Int())
+
+	t.Run("case=without devices", func(t *testing.T) {
+		n := sessions.NewRevokeNode(session.Session{ID: x.NewUUID()})
+		assert.Equal(t, `Sign out ""`, n.Meta.Label.Text)
+	})
+}
@@ -0,0 +1,189 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sessions
+
+import (
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/decoderx"
+	"github.com/ory/x/pointerx"
+)
+
+// listedSessions limits how many of the most recently authenticated sessions are shown in the settings flow.
+// All sessions can be listed using the public API.
+const listedSessions = 100
+
+func (s *Strategy) RegisterSettingsRoutes(_ *x.RouterPublic) {
+}
+
+func (s *Strategy) SettingsStrategyID() string {
+	return StrategyID
+}
+
+// Update Settings Flow with Sessions Method
+//
+// swagger:model updateSettingsFlowWithSessionsMethod
+type updateSettingsFlowWithSessionsMethod struct {
+	// Revoke is the ID of a session which should be signed out.
+	Revoke string `json:"sessions_revoke"`
+
+	// RevokeOthers signs out all sessions except the current one if set to true.
+	RevokeOthers bool `json:"sessions_revoke_others"`
+
+	// CSRFToken is the anti-CSRF token
+	CSRFToken string `json:"csrf_token"`
+
+	// Method
+	//
+	// Should be set to "sessions" when trying to sign out sessions.
+	//
+	// required: true
+	Method string `json:"method"`
+
+	// Flow is flow ID.
+	//
+	// swagger:ignore
+	Flow string `json:"flow"`
+}
+
+func (p *updateSettingsFlowWithSessionsMethod) GetFlowID() uuid.UUID {
+	return x.ParseUUID(p.Flow)
+}
+
+func (p *updateSettingsFlowWithSessionsMethod) SetFlowID(rid uuid.UUID) {
+	p.Flow = rid.String()
+}
+
+func (s *Strategy) Settings(w http.ResponseWriter, r *http.Request, f *settings.Flow, ss *session.Session) (*settings.UpdateContext, error) {
+	var p updateSettingsFlowWithSessionsMethod
+	ctxUpdate, err := settings.PrepareUpdate(s.d, w, r, f, ss, settings.ContinuityKey(s.SettingsStrategyID()), &p)
+	if errors.Is(err, settings.ErrContinuePreviousAction) {
+		return ctxUpdate, s.continueSettingsFlow(w, r, ctxUpdate, &p)
+	} else if err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, err)
+	}
+
+	if err := s.decodeSettingsFlow(r, &p); err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, err)
+	}
+
+	if len(p.Revoke) > 0 || p.RevokeOthers {
+		// This is a submit so we need to manually set the type to sessions
+		p.Method = s.SettingsStrategyID()
+		if err := flow.MethodEnabledAndAllowed(r.Context(), f.GetFlowName(), s.SettingsStrategyID(), p.Method, s.d); err != nil {
+			return nil, s.handleSettingsError(w, r, ctxUpdate, err)
+		}
+	} else if err := flow.MethodEnabledAndAllowedFromRequest(r, f.GetFlowName(), s.SettingsStrategyID(), s.d); err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, err)
+	}
+
+	// This does not come from the payload!
+	p.Flow = ctxUpdate.Flow.ID.String()
+	if err := s.continueSettingsFlow(w, r, ctxUpdate, &p); err != nil {
+		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, err)
+	}
+
+	return ctxUpdate, nil
+}
+
+func (s *Strategy) decodeSettingsFlow(r *http.Request, dest interface{}) error {
+	compiler, err := decoderx.HTTPRawJSONSchemaCompiler(settingsSchema)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return decoderx.NewHTTP().Decode(r, dest, compiler,
+		decoderx.HTTPDecoderAllowedMethods("POST", "GET"),
+		decoderx.HTTPDecoderSetValidatePayloads(true),
+		decoderx.HTTPDecoderJSONFollowsFormFormat(),
+	)
+}
+
+func (s *Strategy) continueSettingsFlow(
+	_ http.ResponseWriter, r *http.Request,
+	ctxUpdate *settings.UpdateContext, p *updateSettingsFlowWithSessionsMethod,
+) error {
+	if err := flow.MethodEnabledAndAllowed(r.Context(), flow.SettingsFlow, s.SettingsStrategyID(), p.Method, s.d); err != nil {
+		return err
+	}
+
+	if err := flow.EnsureCSRF(s.d, r, ctxUpdate.Flow.Type, s.d.Config().DisableAPIFlowEnforcement(r.Context()), s.d.GenerateCSRFToken, p.CSRFToken); err != nil {
+		return err
+	}
+
+	// Signing out other sessions does not require a privileged session, just like the public API.
+	current := ctxUpdate.Session
+	switch {
+	case p.RevokeOthers:
+		if _, err := s.d.SessionPersister().RevokeSessionsIdentityExcept(r.Context(), current.IdentityID, current.ID); err != nil {
+			return err
+		}
+	case len(p.Revoke) > 0:
+		id, err := uuid.FromString(p.Revoke)
+		if err != nil {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("You tried to sign out a session which does not exist."))
+		}
+
+		if id == current.ID {
+			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("You can not sign out the current session here. Please use the logout flow instead."))
+		}
+
+		// Sessions of other identities are never matched, as the identity is part of the condition.
+		if err := s.d.SessionPersister().RevokeSession(r.Context(), current.IdentityID, id); err != nil {
+			return err
+		}
+	default:
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("You must choose a session to sign out."))
+	}
+
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), current.IdentityID)
+	if err != nil {
+		return err
+	}
+
+	ctxUpdate.UpdateIdentity(i)
+	return nil
+}
+
+func (s *Strategy) PopulateSettingsMethod(r *http.Request, id *identity.Identity, f *settings.Flow) error {
+	current, err := s.d.SessionManager().FetchFromRequest(r.Context(), r)
+	if err != nil {
+		return err
+	}
+
+	sessions, _, err := s.d.SessionPersister().ListSessionsByIdentity(r.Context(), id.ID, pointerx.Bool(true), 1, listedSessions, current.ID, session.ExpandEverything)
+	if err != nil {
+		return err
+	}
+
+	if len(sessions) == 0 {
+		return nil
+	}
+
+	f.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	for _, ss := range sessions {
+		f.UI.Nodes.Append(NewRevokeNode(ss))
+	}
+	f.UI.Nodes.Append(NewRevokeOthersNode())
+
+	return nil
+}
+
+func (s *Strategy) handleSettingsError(_ http.ResponseWriter, r *http.Request, ctxUpdate *settings.UpdateContext, err error) error {
+	if ctxUpdate.Flow != nil {
+		ctxUpdate.Flow.UI.ResetMessages()
+		ctxUpdate.Flow.UI.SetCSRF(s.d.GenerateCSRFToken(r))
+	}
+
+	return err
+}
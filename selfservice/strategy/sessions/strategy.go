@@ -0,0 +1,53 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package sessions
+
+import (
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+)
+
+const StrategyID = "sessions"
+
+var _ settings.Strategy = new(Strategy)
+
+type sessionsStrategyDependencies interface {
+	x.LoggingProvider
+	x.WriterProvider
+	x.CSRFTokenGeneratorProvider
+	x.CSRFProvider
+
+	config.Provider
+
+	continuity.ManagementProvider
+
+	settings.FlowPersistenceProvider
+	settings.HookExecutorProvider
+	settings.HooksProvider
+	settings.ErrorHandlerProvider
+
+	identity.PrivilegedPoolProvider
+
+	session.ManagementProvider
+	session.PersistenceProvider
+}
+
+// Strategy lists the other active sessions of an identity in the settings flow and lets the identity
+// sign them out, for example after losing a device.
+type Strategy struct {
+	d sessionsStrategyDependencies
+}
+
+func NewStrategy(d any) *Strategy {
+	return &Strategy{d: d.(sessionsStrategyDependencies)}
+}
+
+func (s *Strategy) NodeGroup() node.UiNodeGroup {
+	return node.SessionsGroup
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/base64"
 	"image/png"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/pquerna/otp"
@@ -33,8 +34,8 @@ func NewKey(ctx context.Context, accountName string, d interface {
 		Issuer:      d.Config().TOTPIssuer(ctx),
 		AccountName: accountName,
 		SecretSize:  secretSize,
-		Digits:      otp.DigitsSix,
-		Period:      30,
+		Digits:      otp.Digits(d.Config().TOTPDigits(ctx)),
+		Period:      d.Config().TOTPPeriod(ctx),
 	})
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -43,6 +44,20 @@ func NewKey(ctx context.Context, accountName string, d interface {
 	return key, err
 }
 
+// ValidateCode checks the code against the key. The digits and period are taken from the key so that devices
+// enrolled before the configuration changed keep working, while the allowed clock skew follows the configuration.
+func ValidateCode(ctx context.Context, code string, key *otp.Key, d interface {
+	config.Provider
+}) bool {
+	valid, err := stdtotp.ValidateCustom(code, key.Secret(), time.Now().UTC(), stdtotp.ValidateOpts{
+		Period:    uint(key.Period()),
+		Skew:      d.Config().TOTPSkew(ctx),
+		Digits:    key.Digits(),
+		Algorithm: key.Algorithm(),
+	})
+	return err == nil && valid
+}
+
 func KeyToHTMLImage(key *otp.Key) (string, error) {
 	var buf bytes.Buffer
 	img, err := key.Image(256, 256)
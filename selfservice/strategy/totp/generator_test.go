@@ -7,7 +7,10 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/pquerna/otp"
+	stdtotp "github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -35,3 +38,38 @@ func TestGenerator(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, strings.HasPrefix(img, "data:image/png;base64,"), "image is a base64 encoded png")
 }
+
+func TestGeneratorParameters(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	key, err := totp.NewKey(ctx, "foo", reg)
+	require.NoError(t, err)
+	assert.Equal(t, otp.DigitsSix, key.Digits())
+	assert.EqualValues(t, 30, key.Period())
+
+	require.NoError(t, conf.Set(ctx, config.ViperKeyTOTPDigits, 8))
+	require.NoError(t, conf.Set(ctx, config.ViperKeyTOTPPeriod, 60))
+
+	key, err = totp.NewKey(ctx, "foo", reg)
+	require.NoError(t, err)
+	assert.Equal(t, otp.DigitsEight, key.Digits())
+	assert.EqualValues(t, 60, key.Period())
+
+	opts := stdtotp.ValidateOpts{Period: 60, Digits: otp.DigitsEight, Algorithm: key.Algorithm()}
+	code, err := stdtotp.GenerateCodeCustom(key.Secret(), time.Now(), opts)
+	require.NoError(t, err)
+	assert.Len(t, code, 8)
+	assert.True(t, totp.ValidateCode(ctx, code, key, reg))
+
+	t.Run("case=respects configured skew", func(t *testing.T) {
+		previous, err := stdtotp.GenerateCodeCustom(key.Secret(), time.Now().Add(-60*time.Second), opts)
+		require.NoError(t, err)
+
+		require.NoError(t, conf.Set(ctx, config.ViperKeyTOTPSkew, 1))
+		assert.True(t, totp.ValidateCode(ctx, previous, key, reg))
+
+		require.NoError(t, conf.Set(ctx, config.ViperKeyTOTPSkew, 0))
+		assert.False(t, totp.ValidateCode(ctx, previous, key, reg))
+	})
+}
@@ -10,7 +10,6 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 	"github.com/pquerna/otp"
-	"github.com/pquerna/otp/totp"
 
 	"github.com/ory/herodot"
 	"github.com/ory/kratos/identity"
@@ -121,7 +120,7 @@ func (s *Strategy) Login(w http.ResponseWriter, r *http.Request, f *login.Flow,
 		return nil, s.handleLoginError(r, f, errors.WithStack(err))
 	}
 
-	if !totp.Validate(p.TOTPCode, key.Secret()) {
+	if !ValidateCode(r.Context(), p.TOTPCode, key, s.d) {
 		return nil, s.handleLoginError(r, f, errors.WithStack(schema.NewTOTPVerifierWrongError("#/")))
 	}
 
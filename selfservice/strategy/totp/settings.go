@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/pquerna/otp"
-	"github.com/pquerna/otp/totp"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 
@@ -182,7 +181,7 @@ func (s *Strategy) continueSettingsFlowAddTOTP(w http.ResponseWriter, r *http.Re
 		return nil, schema.NewRequiredError("#/totp_code", "totp_code")
 	}
 
-	if !totp.Validate(p.ValidationTOTP, key.Secret()) {
+	if !ValidateCode(r.Context(), p.ValidationTOTP, key, s.d) {
 		return nil, schema.NewTOTPVerifierWrongError("#/totp_code")
 	}
 
@@ -37,6 +37,7 @@ import (
 	"github.com/ory/kratos/identity"
 	"github.com/ory/kratos/internal"
 	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/session"
 	"github.com/ory/kratos/x"
 )
 
@@ -373,4 +374,66 @@ func TestCompleteSettings(t *testing.T) {
 			run(t, false, false, id, user, f)
 		})
 	})
+
+	t.Run("type=unlink lost TOTP device after recovery", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySelfServiceSettingsRequiredAAL, config.HighestAvailableAAL)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceSettingsRequiredAAL, "aal1")
+		})
+
+		recoveredClient := func(t *testing.T, id *identity.Identity) *http.Client {
+			req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+			sess, err := session.NewActiveSession(req, id, testhelpers.NewSessionLifespanProvider(time.Hour), time.Now(), identity.CredentialsTypeRecoveryCode, identity.AuthenticatorAssuranceLevel1)
+			require.NoError(t, err)
+			return testhelpers.NewHTTPClientWithSessionToken(t, reg, sess)
+		}
+
+		t.Run("case=requires the second factor unless enabled", func(t *testing.T) {
+			id, _, key := createIdentity(t, reg)
+			res, err := recoveredClient(t, id).Get(publicTS.URL + settings.RouteInitAPIFlow)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+			_, cred, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(ctx, identity.CredentialsTypeTOTP, id.ID.String())
+			require.NoError(t, err)
+			assert.Equal(t, key.URL(), gjson.GetBytes(cred.Config, "totp_url").String())
+		})
+
+		conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryAllowTOTPReset, true)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceRecoveryAllowTOTPReset, false)
+		})
+
+		t.Run("case=only allows unlinking the TOTP device", func(t *testing.T) {
+			id, _, _ := createIdentity(t, reg)
+			hc := recoveredClient(t, id)
+
+			f := testhelpers.InitializeSettingsFlowViaAPI(t, hc, publicTS)
+			values := testhelpers.SDKFormFieldsToURLValues(f.Ui.Nodes)
+			values.Set("method", "profile")
+			actual, res := testhelpers.SettingsMakeRequest(t, true, false, f, hc, testhelpers.EncodeFormAsJSON(t, true, values))
+			assert.Equal(t, http.StatusForbidden, res.StatusCode, actual)
+			assert.Equal(t, text.ErrIDHigherAALRequired, gjson.Get(actual, "error.id").String(), actual)
+
+			f = testhelpers.InitializeSettingsFlowViaAPI(t, hc, publicTS)
+			values = testhelpers.SDKFormFieldsToURLValues(f.Ui.Nodes)
+			values.Set("method", "totp")
+			values.Set("totp_unlink", "true")
+			actual, res = testhelpers.SettingsMakeRequest(t, true, false, f, hc, testhelpers.EncodeFormAsJSON(t, true, values))
+			assert.Equal(t, http.StatusOK, res.StatusCode, actual)
+			assert.EqualValues(t, flow.StateSuccess, gjson.Get(actual, "state").String(), actual)
+
+			_, _, err := reg.PrivilegedIdentityPool().FindByCredentialsIdentifier(ctx, identity.CredentialsTypeTOTP, id.ID.String())
+			require.ErrorIs(t, err, sqlcon.ErrNoRows)
+		})
+
+		t.Run("case=does not apply to sessions which were not recovered", func(t *testing.T) {
+			id, _, _ := createIdentity(t, reg)
+			res, err := testhelpers.NewHTTPClientWithIdentitySessionToken(t, reg, id).Get(publicTS.URL + settings.RouteInitAPIFlow)
+			require.NoError(t, err)
+			defer res.Body.Close()
+			assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		})
+	})
 }
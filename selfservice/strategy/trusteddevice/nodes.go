@@ -0,0 +1,22 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package trusteddevice
+
+import (
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+)
+
+func NewRevokeNode(device session.TrustedDevice) *node.Node {
+	return node.NewInputField(node.TrustedDeviceRevoke, device.ID.String(), node.TrustedDeviceGroup,
+		node.InputAttributeTypeSubmit).
+		WithMetaLabel(text.NewInfoSelfServiceSettingsRevokeTrustedDevice(device.UserAgent, device.CreatedAt))
+}
+
+func NewRevokeAllNode() *node.Node {
+	return node.NewInputField(node.TrustedDeviceRevokeAll, "true", node.TrustedDeviceGroup,
+		node.InputAttributeTypeSubmit).
+		WithMetaLabel(text.NewInfoSelfServiceSettingsRevokeAllTrustedDevices())
+}
@@ -0,0 +1,54 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package trusteddevice
+
+import (
+	"github.com/ory/kratos/continuity"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/kratos/x"
+)
+
+var _ settings.Strategy = new(Strategy)
+
+type trustedDeviceStrategyDependencies interface {
+	x.LoggingProvider
+	x.WriterProvider
+	x.CSRFTokenGeneratorProvider
+	x.CSRFProvider
+
+	config.Provider
+
+	continuity.ManagementProvider
+
+	settings.FlowPersistenceProvider
+	settings.HookExecutorProvider
+	settings.HooksProvider
+	settings.ErrorHandlerProvider
+
+	identity.PrivilegedPoolProvider
+
+	session.ManagementProvider
+	session.PersistenceProvider
+}
+
+// Strategy lets identities revoke the devices they trusted to skip the second factor.
+type Strategy struct {
+	d trustedDeviceStrategyDependencies
+}
+
+func NewStrategy(d any) *Strategy {
+	return &Strategy{d: d.(trustedDeviceStrategyDependencies)}
+}
+
+func (s *Strategy) ID() identity.CredentialsType {
+	return identity.CredentialsTypeTrustedDevice
+}
+
+func (s *Strategy) NodeGroup() node.UiNodeGroup {
+	return node.TrustedDeviceGroup
+}
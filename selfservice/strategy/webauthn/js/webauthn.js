@@ -40,7 +40,8 @@
     }
 
     opt.publicKey.challenge = __oryWebAuthnBufferDecode(opt.publicKey.challenge)
-    opt.publicKey.allowCredentials = opt.publicKey.allowCredentials.map(
+    // Passkey (discoverable credential) requests do not list any credentials.
+    opt.publicKey.allowCredentials = (opt.publicKey.allowCredentials || []).map(
       function (value) {
         return {
           ...value,
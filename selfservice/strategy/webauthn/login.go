@@ -4,7 +4,9 @@
 package webauthn
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -32,6 +34,9 @@ import (
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/login"
 	"github.com/ory/x/decoderx"
+	"github.com/ory/x/pointerx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
 )
 
 func (s *Strategy) PopulateLoginMethod(r *http.Request, requestedAAL identity.AuthenticatorAssuranceLevel, sr *login.Flow) error {
@@ -90,9 +95,54 @@ func (s *Strategy) populateLoginMethodForPasswordless(r *http.Request, sr *login
 		return nil
 	}
 
+	if hint := sr.ReauthenticationHint(); hint != nil && hint.Method == s.ID() {
+		// The session expired recently, so we directly ask for the passkey of the remembered identifier.
+		if id, _, err := s.d.PrivilegedIdentityPool().FindByCredentialsIdentifier(r.Context(), s.ID(), hint.Identifier); err == nil {
+			if err := s.populateLoginMethod(r, sr, id, text.NewInfoSelfServiceLoginWebAuthn(), identity.AuthenticatorAssuranceLevel1); err == nil {
+				return nil
+			} else if !errors.Is(err, ErrNoCredentials) {
+				return err
+			}
+		}
+	}
+
 	sr.UI.SetCSRF(s.d.GenerateCSRFToken(r))
 	sr.UI.SetNode(node.NewInputField("identifier", "", node.DefaultGroup, node.InputAttributeTypeText, node.WithRequiredInputAttribute).WithMetaLabel(text.NewInfoNodeLabelID()))
 	sr.UI.GetNodes().Append(node.NewInputField("method", "webauthn", node.WebAuthnGroup, node.InputAttributeTypeSubmit).WithMetaLabel(text.NewInfoSelfServiceLoginWebAuthn()))
+
+	if s.d.Config().WebAuthnForPasskeys(r.Context()) {
+		return s.populateLoginMethodForPasskeys(r, sr)
+	}
+	return nil
+}
+
+// populateLoginMethodForPasskeys adds a button which signs in with a discoverable credential, without
+// asking for the identifier first.
+func (s *Strategy) populateLoginMethodForPasskeys(r *http.Request, sr *login.Flow) error {
+	web, err := webauthn.New(s.d.Config().WebAuthnConfig(r.Context()))
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to initiate WebAuth.").WithDebug(err.Error()))
+	}
+
+	options, sessionData, err := web.BeginDiscoverableLogin()
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to initiate WebAuth login.").WithDebug(err.Error()))
+	}
+
+	sr.InternalContext, err = sjson.SetBytes(sr.InternalContext, flow.PrefixInternalContextKey(s.ID(), InternalContextKeyPasskeySessionData), sessionData)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	injectWebAuthnOptions, err := json.Marshal(options)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	sr.UI.Nodes.Upsert(NewWebAuthnScript(urlx.AppendPaths(s.d.Config().SelfPublicURL(r.Context()), webAuthnRoute).String(), jsOnLoad))
+	sr.UI.SetNode(NewPasskeyLoginTrigger(string(injectWebAuthnOptions)).
+		WithMetaLabel(text.NewInfoSelfServiceLoginPasskey()))
+	sr.UI.Nodes.Upsert(NewPasskeyLoginInput())
 	return nil
 }
 
@@ -160,6 +210,7 @@ func (s *Strategy) populateLoginMethod(r *http.Request, sr *login.Flow, i *ident
 func (s *Strategy) handleLoginError(r *http.Request, f *login.Flow, err error) error {
 	if f != nil {
 		f.UI.Nodes.ResetNodes("webauth_login")
+		f.UI.Nodes.ResetNodes(node.PasskeyLogin)
 		if f.Type == flow.TypeBrowser {
 			f.UI.SetCSRF(s.d.GenerateCSRFToken(r))
 		}
@@ -189,6 +240,12 @@ type updateLoginFlowWithWebAuthnMethod struct {
 	//
 	// This must contain the ID of the WebAuthN connection.
 	Login string `json:"webauthn_login"`
+
+	// Login with a Passkey
+	//
+	// This must contain the response of a discoverable WebAuthn credential. No identifier is
+	// required when signing in with a passkey.
+	PasskeyLogin string `json:"passkey_login"`
 }
 
 func (s *Strategy) Login(w http.ResponseWriter, r *http.Request, f *login.Flow, identityID uuid.UUID) (i *identity.Identity, err error) {
@@ -204,7 +261,7 @@ func (s *Strategy) Login(w http.ResponseWriter, r *http.Request, f *login.Flow,
 		return nil, s.handleLoginError(r, f, err)
 	}
 
-	if len(p.Login) > 0 || p.Method == s.SettingsStrategyID() {
+	if len(p.Login) > 0 || len(p.PasskeyLogin) > 0 || p.Method == s.SettingsStrategyID() {
 		// This method has only two submit buttons
 		p.Method = s.SettingsStrategyID()
 	} else {
@@ -219,6 +276,13 @@ func (s *Strategy) Login(w http.ResponseWriter, r *http.Request, f *login.Flow,
 		return nil, s.handleLoginError(r, f, err)
 	}
 
+	if len(p.PasskeyLogin) > 0 {
+		if !s.d.Config().WebAuthnForPasskeys(r.Context()) {
+			return nil, s.handleLoginError(r, f, errors.WithStack(herodot.ErrBadRequest.WithReason("Signing in with a passkey is not enabled.")))
+		}
+		return s.loginPasskey(w, r, f, &p)
+	}
+
 	if s.d.Config().WebAuthnForPasswordless(r.Context()) || f.IsForced() && f.RequestedAAL == identity.AuthenticatorAssuranceLevel1 {
 		return s.loginPasswordless(w, r, f, &p)
 	}
@@ -315,10 +379,15 @@ func (s *Strategy) loginAuthenticate(_ http.ResponseWriter, r *http.Request, f *
 		webAuthCreds = o.Credentials.ToWebAuthn()
 	}
 
-	if _, err := web.ValidateLogin(NewUser(o.UserHandle, webAuthCreds, web.Config), webAuthnSess, webAuthnResponse); err != nil {
+	used, err := web.ValidateLogin(NewUser(o.UserHandle, webAuthCreds, web.Config), webAuthnSess, webAuthnResponse)
+	if err != nil {
 		return nil, s.handleLoginError(r, f, errors.WithStack(schema.NewWebAuthnVerifierWrongError("#/")))
 	}
 
+	if err := s.markCredentialUsed(r.Context(), i, used); err != nil {
+		return nil, s.handleLoginError(r, f, err)
+	}
+
 	// Remove the WebAuthn URL from the internal context now that it is set!
 	f.InternalContext, err = sjson.DeleteBytes(f.InternalContext, flow.PrefixInternalContextKey(s.ID(), InternalContextKeySessionData))
 	if err != nil {
@@ -333,9 +402,113 @@ func (s *Strategy) loginAuthenticate(_ http.ResponseWriter, r *http.Request, f *
 	return i, nil
 }
 
+// loginPasskey signs in with a discoverable credential. The identity is resolved from the credential's
+// user handle.
+func (s *Strategy) loginPasskey(_ http.ResponseWriter, r *http.Request, f *login.Flow, p *updateLoginFlowWithWebAuthnMethod) (*identity.Identity, error) {
+	if err := login.CheckAAL(f, identity.AuthenticatorAssuranceLevel1); err != nil {
+		return nil, s.handleLoginError(r, f, err)
+	}
+
+	web, err := webauthn.New(s.d.Config().WebAuthnConfig(r.Context()))
+	if err != nil {
+		return nil, s.handleLoginError(r, f, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to get webAuthn config.").WithDebug(err.Error())))
+	}
+
+	webAuthnResponse, err := protocol.ParseCredentialRequestResponseBody(strings.NewReader(p.PasskeyLogin))
+	if err != nil {
+		return nil, s.handleLoginError(r, f, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to parse WebAuthn response.").WithDebug(err.Error())))
+	}
+
+	var webAuthnSess webauthn.SessionData
+	if err := json.Unmarshal([]byte(gjson.GetBytes(f.InternalContext, flow.PrefixInternalContextKey(s.ID(), InternalContextKeyPasskeySessionData)).Raw), &webAuthnSess); err != nil {
+		return nil, s.handleLoginError(r, f, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Expected WebAuthN in internal context to be an object but got: %s", err)))
+	}
+
+	var i *identity.Identity
+	used, err := web.ValidateDiscoverableLogin(func(_, userHandle []byte) (_ webauthn.User, err error) {
+		i, err = s.d.PrivilegedIdentityPool().FindIdentityByWebAuthnUserHandle(r.Context(), userHandle)
+		if err != nil {
+			return nil, errors.WithStack(schema.NewNoWebAuthnCredentials())
+		}
+
+		c, ok := i.GetCredentials(s.ID())
+		if !ok {
+			return nil, errors.WithStack(schema.NewNoWebAuthnCredentials())
+		}
+
+		var o identity.CredentialsWebAuthnConfig
+		if err := json.Unmarshal(c.Config, &o); err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The WebAuthn credentials could not be decoded properly").WithDebug(err.Error()).WithWrap(err))
+		}
+
+		// The credentials may have been registered with an earlier user handle of the identity.
+		return NewUser(userHandle, o.Credentials.ToWebAuthnFiltered(identity.AuthenticatorAssuranceLevel1), web.Config), nil
+	}, webAuthnSess, webAuthnResponse)
+	if err != nil {
+		time.Sleep(x.RandomDelay(s.d.Config().HasherArgon2(r.Context()).ExpectedDuration, s.d.Config().HasherArgon2(r.Context()).ExpectedDeviation))
+		return nil, s.handleLoginError(r, f, errors.WithStack(schema.NewWebAuthnVerifierWrongError("#/")))
+	}
+
+	if err := s.markCredentialUsed(r.Context(), i, used); err != nil {
+		return nil, s.handleLoginError(r, f, err)
+	}
+
+	f.InternalContext, err = sjson.DeleteBytes(f.InternalContext, flow.PrefixInternalContextKey(s.ID(), InternalContextKeyPasskeySessionData))
+	if err != nil {
+		return nil, s.handleLoginError(r, f, errors.WithStack(err))
+	}
+
+	f.Active = s.ID()
+	if err = s.d.LoginFlowPersister().UpdateLoginFlow(r.Context(), f); err != nil {
+		return nil, s.handleLoginError(r, f, errors.WithStack(herodot.ErrInternalServerError.WithReason("Could not update flow").WithDebug(err.Error())))
+	}
+
+	return i, nil
+}
+
 func (s *Strategy) loginMultiFactor(w http.ResponseWriter, r *http.Request, f *login.Flow, identityID uuid.UUID, p *updateLoginFlowWithWebAuthnMethod) (*identity.Identity, error) {
 	if err := login.CheckAAL(f, identity.AuthenticatorAssuranceLevel2); err != nil {
 		return nil, err
 	}
 	return s.loginAuthenticate(w, r, f, identityID, p, identity.AuthenticatorAssuranceLevel2)
 }
+
+// markCredentialUsed records when the credential was last used to sign in and stores its new signature counter. The
+// change is applied to the stored credentials, so that credentials which were added or removed in the meantime are
+// kept as they are.
+func (s *Strategy) markCredentialUsed(ctx context.Context, i *identity.Identity, used *webauthn.Credential) error {
+	c, ok := i.GetCredentials(s.ID())
+	if !ok {
+		return errors.WithStack(schema.NewNoWebAuthnRegistered())
+	}
+
+	lastUsedAt := time.Now().UTC().Round(time.Second)
+	if err := s.d.PrivilegedIdentityPool().UpdateIdentityCredentialsConfig(ctx, i.ID, s.ID(), func(config sqlxx.JSONRawMessage) (sqlxx.JSONRawMessage, error) {
+		var o identity.CredentialsWebAuthnConfig
+		if err := json.Unmarshal(config, &o); err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The WebAuthn credentials could not be decoded properly").WithDebug(err.Error()).WithWrap(err))
+		}
+
+		wc, ok := o.Credentials.Find(fmt.Sprintf("%x", used.ID))
+		if !ok {
+			return nil, errors.WithStack(schema.NewNoWebAuthnCredentials())
+		}
+		wc.LastUsedAt = pointerx.Ptr(lastUsedAt)
+		wc.Authenticator.SignCount = used.Authenticator.SignCount
+		wc.Authenticator.CloneWarning = used.Authenticator.CloneWarning
+
+		encoded, err := json.Marshal(&o)
+		if err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to encode updated WebAuthn credentials.").WithDebug(err.Error()))
+		}
+		c.Config = encoded
+		return encoded, nil
+	}); errors.Is(err, sqlcon.ErrNoRows) {
+		return errors.WithStack(schema.NewNoWebAuthnRegistered())
+	} else if err != nil {
+		return err
+	}
+
+	i.SetCredentials(s.ID(), *c)
+	return nil
+}
@@ -293,6 +293,25 @@ func TestCompleteLogin(t *testing.T) {
 			testhelpers.SnapshotTExcept(t, f.Ui.Nodes, []string{"0.attributes.value"})
 		})
 
+		t.Run("case=passkey button exists if passkeys are enabled", func(t *testing.T) {
+			conf.MustSet(ctx, config.ViperKeyWebAuthnPasskeys, true)
+			t.Cleanup(func() {
+				conf.MustSet(ctx, config.ViperKeyWebAuthnPasskeys, false)
+			})
+
+			client := testhelpers.NewClientWithCookies(t)
+			f := testhelpers.InitializeLoginFlowViaBrowser(t, client, publicTS, false, true, false, false)
+			nodes, err := json.Marshal(f.Ui.Nodes)
+			require.NoError(t, err)
+
+			trigger := gjson.GetBytes(nodes, "#(attributes.name==passkey_login_trigger)")
+			require.True(t, trigger.Exists(), "%s", nodes)
+			assert.Contains(t, trigger.Get("attributes.onclick").String(), `"userVerification"`, "%s", nodes)
+			assert.EqualValues(t, text.InfoSelfServiceLoginPasskey, trigger.Get("meta.label.id").Int(), "%s", nodes)
+			assert.True(t, gjson.GetBytes(nodes, "#(attributes.name==passkey_login)").Exists(), "%s", nodes)
+			assert.True(t, gjson.GetBytes(nodes, "#(attributes.name==identifier)").Exists(), "identifier based login is still offered: %s", nodes)
+		})
+
 		t.Run("case=webauthn shows error if user tries to sign in but no such user exists", func(t *testing.T) {
 			payload := func(v url.Values) {
 				v.Set("method", identity.CredentialsTypeWebAuthn.String())
@@ -446,6 +465,12 @@ func TestCompleteLogin(t *testing.T) {
 				actualFlow, err := reg.LoginFlowPersister().GetLoginFlow(context.Background(), uuid.FromStringOrNil(f.Id))
 				require.NoError(t, err)
 				assert.Empty(t, gjson.GetBytes(actualFlow.InternalContext, flow.PrefixInternalContextKey(identity.CredentialsTypeWebAuthn, webauthn.InternalContextKeySessionData)))
+
+				actual, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(context.Background(), id.ID)
+				require.NoError(t, err)
+				cred, ok := actual.GetCredentials(identity.CredentialsTypeWebAuthn)
+				require.True(t, ok)
+				assert.NotEmpty(t, gjson.GetBytes(cred.Config, "credentials.0.last_used_at").String(), "%s", cred.Config)
 			}
 
 			t.Run("type=browser", func(t *testing.T) {
@@ -45,6 +45,18 @@ func NewWebAuthnLoginInput() *node.Node {
 		node.InputAttributeTypeHidden)
 }
 
+func NewPasskeyLoginTrigger(options string) *node.Node {
+	return node.NewInputField(node.PasskeyLoginTrigger, "", node.WebAuthnGroup,
+		node.InputAttributeTypeButton, node.WithInputAttributes(func(a *node.InputAttributes) {
+			a.OnClick = "window.__oryWebAuthnLogin(" + options + `, '*[name="` + node.PasskeyLogin + `"]', '*[name="` + node.PasskeyLoginTrigger + `"]')`
+		}))
+}
+
+func NewPasskeyLoginInput() *node.Node {
+	return node.NewInputField(node.PasskeyLogin, "", node.WebAuthnGroup,
+		node.InputAttributeTypeHidden)
+}
+
 func NewWebAuthnConnectionName() *node.Node {
 	return node.NewInputField(node.WebAuthnRegisterDisplayName, "", node.WebAuthnGroup, node.InputAttributeTypeText).
 		WithMetaLabel(text.NewInfoSelfServiceRegisterWebAuthnDisplayName())
@@ -53,5 +65,16 @@ func NewWebAuthnConnectionName() *node.Node {
 func NewWebAuthnUnlink(c *identity.CredentialWebAuthn) *node.Node {
 	return node.NewInputField(node.WebAuthnRemove, fmt.Sprintf("%x", c.ID), node.WebAuthnGroup,
 		node.InputAttributeTypeSubmit).
-		WithMetaLabel(text.NewInfoSelfServiceRemoveWebAuthn(stringsx.Coalesce(c.DisplayName, "unnamed"), c.AddedAt))
+		WithMetaLabel(text.NewInfoSelfServiceRemoveWebAuthn(stringsx.Coalesce(c.DisplayName, "unnamed"), c.AddedAt, c.LastUsedAt))
+}
+
+func NewWebAuthnRename(c *identity.CredentialWebAuthn) *node.Node {
+	return node.NewInputField(node.WebAuthnRename, c.HexID(), node.WebAuthnGroup,
+		node.InputAttributeTypeSubmit).
+		WithMetaLabel(text.NewInfoSelfServiceRenameWebAuthn(stringsx.Coalesce(c.DisplayName, "unnamed"), c.AddedAt, c.LastUsedAt))
+}
+
+func NewWebAuthnRenameDisplayName() *node.Node {
+	return node.NewInputField(node.WebAuthnRenameDisplayName, "", node.WebAuthnGroup, node.InputAttributeTypeText).
+		WithMetaLabel(text.NewInfoSelfServiceRenameWebAuthnDisplayName())
 }
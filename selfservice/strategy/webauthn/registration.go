@@ -11,6 +11,7 @@ import (
 
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gofrs/uuid"
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
@@ -120,7 +121,11 @@ func (s *Strategy) Register(w http.ResponseWriter, r *http.Request, f *registrat
 	if len(p.Traits) == 0 {
 		p.Traits = json.RawMessage("{}")
 	}
-	i.Traits = identity.Traits(p.Traits)
+	traits, err := registration.MergeStepTraits(f, p.Traits)
+	if err != nil {
+		return s.handleRegistrationError(w, r, f, &p, err)
+	}
+	i.Traits = identity.Traits(traits)
 
 	webAuthnSession := gjson.GetBytes(f.InternalContext, flow.PrefixInternalContextKey(s.ID(), InternalContextKeySessionData))
 	if !webAuthnSession.IsObject() {
@@ -157,6 +162,13 @@ func (s *Strategy) Register(w http.ResponseWriter, r *http.Request, f *registrat
 	wc.IsPasswordless = s.d.Config().WebAuthnForPasswordless(r.Context())
 	cc.UserHandle = webAuthnSess.UserID
 
+	// The user handle is a random UUID which we reuse as the identity's ID for passkeys.
+	if s.d.Config().WebAuthnForPasskeys(r.Context()) && i.ID == uuid.Nil {
+		if userHandle, err := uuid.FromBytes(webAuthnSess.UserID); err == nil {
+			i.ID = userHandle
+		}
+	}
+
 	cc.Credentials = append(cc.Credentials, *wc)
 	co, err := json.Marshal(cc)
 	if err != nil {
@@ -168,6 +180,10 @@ func (s *Strategy) Register(w http.ResponseWriter, r *http.Request, f *registrat
 		return s.handleRegistrationError(w, r, f, &p, err)
 	}
 
+	if err := s.d.IdentityValidator().ValidateRegistrationEmailDomains(r.Context(), i); err != nil {
+		return s.handleRegistrationError(w, r, f, &p, err)
+	}
+
 	// Remove the WebAuthn URL from the internal context now that it is set!
 	f.InternalContext, err = sjson.DeleteBytes(f.InternalContext, flow.PrefixInternalContextKey(s.ID(), InternalContextKeySessionData))
 	if err != nil {
@@ -33,6 +33,7 @@ import (
 	"github.com/ory/x/decoderx"
 
 	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/schema"
 	"github.com/ory/kratos/selfservice/flow"
 	"github.com/ory/kratos/selfservice/flow/settings"
 	"github.com/ory/kratos/x"
@@ -46,7 +47,8 @@ func (s *Strategy) SettingsStrategyID() string {
 }
 
 const (
-	InternalContextKeySessionData = "session_data"
+	InternalContextKeySessionData        = "session_data"
+	InternalContextKeyPasskeySessionData = "passkey_session_data"
 )
 
 // Update Settings Flow with WebAuthn Method
@@ -69,6 +71,16 @@ type updateSettingsFlowWithWebAuthnMethod struct {
 	// This must contain the ID of the WebAuthN connection.
 	Remove string `json:"webauthn_remove"`
 
+	// Rename a WebAuthn Security Key
+	//
+	// This must contain the ID of the WebAuthN connection.
+	Rename string `json:"webauthn_rename"`
+
+	// New Name of the WebAuthn Security Key to be Renamed
+	//
+	// A human-readable name for the security key which will be renamed.
+	RenameDisplayName string `json:"webauthn_rename_displayname"`
+
 	// CSRFToken is the anti-CSRF token
 	CSRFToken string `json:"csrf_token"`
 
@@ -109,8 +121,8 @@ func (s *Strategy) Settings(w http.ResponseWriter, r *http.Request, f *settings.
 		return ctxUpdate, s.handleSettingsError(w, r, ctxUpdate, &p, err)
 	}
 
-	if len(p.Register+p.Remove) > 0 {
-		// This method has only two submit buttons
+	if len(p.Register+p.Remove+p.Rename) > 0 {
+		// This method has only three submit buttons
 		p.Method = s.SettingsStrategyID()
 		if err := flow.MethodEnabledAndAllowed(r.Context(), f.GetFlowName(), s.SettingsStrategyID(), p.Method, s.d); err != nil {
 			return nil, s.handleSettingsError(w, r, ctxUpdate, &p, err)
@@ -145,7 +157,7 @@ func (s *Strategy) continueSettingsFlow(
 	w http.ResponseWriter, r *http.Request,
 	ctxUpdate *settings.UpdateContext, p *updateSettingsFlowWithWebAuthnMethod,
 ) error {
-	if len(p.Register+p.Remove) > 0 {
+	if len(p.Register+p.Remove+p.Rename) > 0 {
 		if err := flow.MethodEnabledAndAllowed(r.Context(), flow.SettingsFlow, s.SettingsStrategyID(), s.SettingsStrategyID(), s.d); err != nil {
 			return err
 		}
@@ -165,6 +177,8 @@ func (s *Strategy) continueSettingsFlow(
 		return s.continueSettingsFlowAdd(w, r, ctxUpdate, p)
 	} else if len(p.Remove) > 0 {
 		return s.continueSettingsFlowRemove(w, r, ctxUpdate, p)
+	} else if len(p.Rename) > 0 {
+		return s.continueSettingsFlowRename(r, ctxUpdate, p)
 	}
 
 	return errors.New("ended up in unexpected state")
@@ -226,6 +240,42 @@ func (s *Strategy) continueSettingsFlowRemove(w http.ResponseWriter, r *http.Req
 	return nil
 }
 
+func (s *Strategy) continueSettingsFlowRename(r *http.Request, ctxUpdate *settings.UpdateContext, p *updateSettingsFlowWithWebAuthnMethod) error {
+	i, err := s.d.PrivilegedIdentityPool().GetIdentityConfidential(r.Context(), ctxUpdate.Session.IdentityID)
+	if err != nil {
+		return err
+	}
+
+	cred, ok := i.GetCredentials(s.ID())
+	if !ok {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("You tried to rename a WebAuthn but you have no WebAuthn set up."))
+	}
+
+	var cc identity.CredentialsWebAuthnConfig
+	if err := json.Unmarshal(cred.Config, &cc); err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode identity credentials.").WithDebug(err.Error()))
+	}
+
+	if len(p.RenameDisplayName) == 0 {
+		return schema.NewRequiredError("#/webauthn_rename_displayname", "webauthn_rename_displayname")
+	}
+
+	wc, ok := cc.Credentials.Find(p.Rename)
+	if !ok {
+		return errors.WithStack(herodot.ErrBadRequest.WithReasonf("You tried to rename a WebAuthn credential which does not exist."))
+	}
+	wc.DisplayName = p.RenameDisplayName
+
+	cred.Config, err = json.Marshal(cc)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to encode identity credentials.").WithDebug(err.Error()))
+	}
+
+	i.SetCredentials(s.ID(), *cred)
+	ctxUpdate.UpdateIdentity(i)
+	return nil
+}
+
 func (s *Strategy) continueSettingsFlowAdd(w http.ResponseWriter, r *http.Request, ctxUpdate *settings.UpdateContext, p *updateSettingsFlowWithWebAuthnMethod) error {
 	webAuthnSession := gjson.GetBytes(ctxUpdate.Flow.InternalContext, flow.PrefixInternalContextKey(s.ID(), InternalContextKeySessionData))
 	if !webAuthnSession.IsObject() {
@@ -264,6 +314,13 @@ func (s *Strategy) continueSettingsFlowAdd(w http.ResponseWriter, r *http.Reques
 		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode identity credentials.").WithDebug(err.Error()))
 	}
 
+	// Credentials added before keep signing in with the previous user handle.
+	for k := range cc.Credentials {
+		if len(cc.Credentials[k].UserHandle) == 0 {
+			cc.Credentials[k].UserHandle = cc.UserHandle
+		}
+	}
+
 	wc := identity.CredentialFromWebAuthn(credential, s.d.Config().WebAuthnForPasswordless(r.Context()))
 	wc.AddedAt = time.Now().UTC().Round(time.Second)
 	wc.DisplayName = p.RegisterDisplayName
@@ -348,12 +405,17 @@ func (s *Strategy) PopulateSettingsMethod(r *http.Request, id *identity.Identity
 			// We only show the option to remove a credential, if it is not the last one when passwordless,
 			// or, if it is for MFA we show it always.
 			cred := &webAuthns.Credentials[k]
+			f.UI.Nodes.Append(NewWebAuthnRename(cred))
 			if cred.IsPasswordless && count < 2 {
 				// Do not remove this node because it is the last credential the identity can sign in with.
 				continue
 			}
 			f.UI.Nodes.Append(NewWebAuthnUnlink(cred))
 		}
+
+		if len(webAuthns.Credentials) > 0 {
+			f.UI.Nodes.Upsert(NewWebAuthnRenameDisplayName())
+		}
 	}
 
 	web, err := webauthn.New(s.d.Config().WebAuthnConfig(r.Context()))
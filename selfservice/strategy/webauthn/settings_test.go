@@ -142,11 +142,11 @@ func TestCompleteSettings(t *testing.T) {
 
 		testhelpers.SnapshotTExcept(t, f.Ui.Nodes, []string{
 			"0.attributes.value",
-			"4.attributes.onclick",
-			"6.attributes.src",
-			"6.attributes.nonce",
+			"7.attributes.onclick",
+			"9.attributes.src",
+			"9.attributes.nonce",
 		})
-		ensureReplacement(t, "4", f.Ui, "Ory Corp")
+		ensureReplacement(t, "7", f.Ui, "Ory Corp")
 	})
 
 	t.Run("case=one activation element is shown", func(t *testing.T) {
@@ -406,6 +406,52 @@ func TestCompleteSettings(t *testing.T) {
 		})
 	})
 
+	t.Run("case=rename a security key", func(t *testing.T) {
+		run := func(t *testing.T, spa bool) {
+			id := createIdentity(t, reg)
+
+			body, res := doBrowserFlow(t, spa, func(v url.Values) {
+				v.Del(node.WebAuthnRemove)
+				v.Set(node.WebAuthnRename, "666f6f666f6f")
+				v.Set(node.WebAuthnRenameDisplayName, "my phone")
+			}, id)
+
+			if spa {
+				assert.Contains(t, res.Request.URL.String(), publicTS.URL+settings.RouteSubmitFlow)
+			} else {
+				assert.Contains(t, res.Request.URL.String(), uiTS.URL)
+			}
+			assert.EqualValues(t, flow.StateSuccess, gjson.Get(body, "state").String(), body)
+
+			actual, err := reg.Persister().GetIdentityConfidential(context.Background(), id.ID)
+			require.NoError(t, err)
+			cred, ok := actual.GetCredentials(identity.CredentialsTypeWebAuthn)
+			require.True(t, ok)
+			assert.Equal(t, "my phone", gjson.GetBytes(cred.Config, "credentials.0.display_name").String())
+			assert.Equal(t, "bar", gjson.GetBytes(cred.Config, "credentials.1.display_name").String())
+		}
+
+		t.Run("type=browser", func(t *testing.T) {
+			run(t, false)
+		})
+
+		t.Run("type=spa", func(t *testing.T) {
+			run(t, true)
+		})
+	})
+
+	t.Run("case=fails to rename a security key without a name", func(t *testing.T) {
+		id := createIdentity(t, reg)
+
+		body, _ := doBrowserFlow(t, true, func(v url.Values) {
+			v.Del(node.WebAuthnRemove)
+			v.Set(node.WebAuthnRename, "666f6f666f6f")
+		}, id)
+
+		assert.EqualValues(t, flow.StateShowForm, gjson.Get(body, "state").String(), body)
+		assert.Contains(t, gjson.Get(body, "ui.nodes.#(attributes.name==webauthn_rename_displayname).messages.0.text").String(), "Property webauthn_rename_displayname is missing.", "%s", body)
+	})
+
 	t.Run("case=possible to remove webauthn credential if it is MFA at all times", func(t *testing.T) {
 		run := func(t *testing.T, spa bool) {
 			id := createIdentity(t, reg)
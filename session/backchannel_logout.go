@@ -0,0 +1,149 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/jwksx"
+)
+
+// BackChannelLogoutEvent is the member of the `events` claim which marks a logout token.
+const BackChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+type (
+	backChannelLogoutDependencies interface {
+		config.Provider
+		x.HTTPClientProvider
+		x.JWKFetchProvider
+		PersistenceProvider
+	}
+
+	// BackChannelLogout sends an OpenID Connect Back-Channel Logout token to the logout callbacks of
+	// all applications when sessions are revoked or expire. It is a sink of the session event emitter.
+	BackChannelLogout struct {
+		d       backChannelLogoutDependencies
+		nowFunc func() time.Time
+	}
+)
+
+var _ EventSink = new(BackChannelLogout)
+
+func NewBackChannelLogout(d backChannelLogoutDependencies) *BackChannelLogout {
+	return &BackChannelLogout{d: d, nowFunc: time.Now}
+}
+
+// Enabled returns true if back-channel logout is enabled.
+func (b *BackChannelLogout) Enabled(ctx context.Context) bool {
+	return b.d.Config().SessionBackChannelLogoutEnabled(ctx)
+}
+
+// SendSessionEvent sends one logout token per session and application. It tries all callbacks and
+// returns the first error.
+func (b *BackChannelLogout) SendSessionEvent(ctx context.Context, e *Event) error {
+	if e.Type != EventRevoked && e.Type != EventExpired {
+		return nil
+	}
+
+	callbacks, err := b.d.SessionPersister().ListLogoutCallbacks(ctx)
+	if err != nil {
+		return err
+	} else if len(callbacks) == 0 {
+		return nil
+	}
+
+	key, err := b.d.Fetcher().ResolveKey(
+		ctx,
+		b.d.Config().SessionBackChannelLogoutJWKSURL(ctx),
+		jwksx.WithCacheEnabled(),
+		jwksx.WithCacheTTL(time.Hour),
+		jwksx.WithHTTPClient(b.d.HTTPClient(ctx)))
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, c := range callbacks {
+		for _, sid := range e.SessionIDs {
+			token, err := b.logoutToken(ctx, key, c.AppID, e.IdentityID, sid)
+			if err != nil {
+				return err
+			}
+
+			if err := b.send(ctx, &c, token); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	return firstErr
+}
+
+func (b *BackChannelLogout) logoutToken(ctx context.Context, key jwk.Key, audience string, identityID uuid.NullUUID, sid uuid.UUID) (string, error) {
+	alg := jwt.GetSigningMethod(key.Algorithm())
+	if alg == nil {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The JSON Web Key must include a valid \"alg\" parameter but \"%s\" was given.", key.Algorithm()))
+	}
+
+	var privateKey interface{}
+	if err := key.Raw(&privateKey); err != nil {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithWrap(err).WithReasonf("Unable to decode the given private key."))
+	}
+
+	now := b.nowFunc()
+	claims := jwt.MapClaims{
+		"jti":    x.NewUUID().String(),
+		"iss":    b.d.Config().SelfPublicURL(ctx).String(),
+		"aud":    audience,
+		"iat":    now.Unix(),
+		"exp":    now.Add(2 * time.Minute).Unix(),
+		"sid":    sid.String(),
+		"events": map[string]interface{}{BackChannelLogoutEvent: map[string]interface{}{}},
+	}
+	if identityID.Valid {
+		claims["sub"] = identityID.UUID.String()
+	}
+
+	token := jwt.NewWithClaims(alg, claims)
+	token.Header["kid"] = key.KeyID()
+	token.Header["typ"] = "logout+jwt"
+
+	signed, err := token.SignedString(privateKey)
+	if err != nil {
+		return "", errors.WithStack(herodot.ErrInternalServerError.WithWrap(err).WithReasonf("Unable to sign the logout token."))
+	}
+	return signed, nil
+}
+
+func (b *BackChannelLogout) send(ctx context.Context, c *LogoutCallback, token string) error {
+	req, err := retryablehttp.NewRequestWithContext(ctx, "POST", c.URL, strings.NewReader(url.Values{"logout_token": {token}}.Encode()))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := b.d.HTTPClient(ctx).Do(req)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to reach the logout callback of application %s.", c.AppID).WithWrap(err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The logout callback of application %s responded with unexpected status code %d.", c.AppID, res.StatusCode))
+	}
+	return nil
+}
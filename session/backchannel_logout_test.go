@@ -0,0 +1,144 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+func TestLogoutCallbackHandler(t *testing.T) {
+	_, reg := internal.NewFastRegistryWithMocks(t)
+	_, ts, _, _ := testhelpers.NewKratosServerWithCSRFAndRouters(t, reg)
+
+	do := func(t *testing.T, method, path string, body string) (*http.Response, []byte) {
+		req, err := http.NewRequest(method, ts.URL+"/admin"+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		res, err := ts.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		raw, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, raw
+	}
+
+	list := func(t *testing.T) (callbacks []session.LogoutCallback) {
+		res, body := do(t, "GET", session.RouteAdminLogoutCallbacks, "")
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		require.NoError(t, json.Unmarshal(body, &callbacks))
+		return callbacks
+	}
+
+	t.Run("case=registers and replaces a callback", func(t *testing.T) {
+		res, body := do(t, "PUT", session.RouteAdminLogoutCallbacks+"/app-a", `{"url":"https://a.example.com/logout"}`)
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		var actual session.LogoutCallback
+		require.NoError(t, json.Unmarshal(body, &actual))
+		assert.Equal(t, "app-a", actual.AppID)
+		assert.Equal(t, "https://a.example.com/logout", actual.URL)
+
+		res, body = do(t, "PUT", session.RouteAdminLogoutCallbacks+"/app-a", `{"url":"https://a.example.com/backchannel"}`)
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+
+		callbacks := list(t)
+		require.Len(t, callbacks, 1)
+		assert.Equal(t, "https://a.example.com/backchannel", callbacks[0].URL)
+	})
+
+	t.Run("case=rejects invalid urls", func(t *testing.T) {
+		for _, body := range []string{
+			`{"url":"/relative"}`,
+			`{"url":"ftp://a.example.com/logout"}`,
+			`{"url":""}`,
+			`{"uri":"https://a.example.com/logout"}`,
+		} {
+			res, raw := do(t, "PUT", session.RouteAdminLogoutCallbacks+"/app-b", body)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s: %s", body, raw)
+		}
+	})
+
+	t.Run("case=deletes a callback", func(t *testing.T) {
+		res, body := do(t, "DELETE", session.RouteAdminLogoutCallbacks+"/app-a", "")
+		require.Equal(t, http.StatusNoContent, res.StatusCode, "%s", body)
+		assert.Empty(t, list(t))
+
+		res, body = do(t, "DELETE", session.RouteAdminLogoutCallbacks+"/app-a", "")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode, "%s", body)
+	})
+}
+
+func TestBackChannelLogout(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(ctx, config.ViperKeySessionBackChannelLogoutJWKSURL, "file://stub/jwk.es256.json")
+	conf.MustSet(ctx, config.ViperKeySessionBackChannelLogoutEnabled, true)
+
+	tokens := make(chan string, 10)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens <- r.PostFormValue("logout_token")
+	}))
+	t.Cleanup(callback.Close)
+
+	require.NoError(t, reg.SessionPersister().UpsertLogoutCallback(ctx, &session.LogoutCallback{AppID: "app-a", URL: callback.URL}))
+	t.Cleanup(func() { _ = reg.SessionPersister().DeleteLogoutCallback(ctx, "app-a") })
+
+	sink := session.NewBackChannelLogout(reg)
+	require.True(t, sink.Enabled(ctx))
+
+	t.Run("case=sends a logout token per session", func(t *testing.T) {
+		identityID, sessionID := x.NewUUID(), x.NewUUID()
+		require.NoError(t, sink.SendSessionEvent(ctx, session.NewEvent(session.EventRevoked, identityID, sessionID)))
+
+		token := validateTokenized(t, receiveEvent(t, tokens), es256Key)
+		assert.Equal(t, "logout+jwt", token.Header["typ"])
+
+		claims := token.Claims.(jwt.MapClaims)
+		assert.Equal(t, "app-a", claims["aud"])
+		assert.Equal(t, sessionID.String(), claims["sid"])
+		assert.Equal(t, identityID.String(), claims["sub"])
+		assert.Contains(t, claims["events"], session.BackChannelLogoutEvent)
+	})
+
+	t.Run("case=omits the subject of expired sessions", func(t *testing.T) {
+		sessionID := x.NewUUID()
+		require.NoError(t, sink.SendSessionEvent(ctx, session.NewEvent(session.EventExpired, uuid.Nil, sessionID)))
+
+		claims := validateTokenized(t, receiveEvent(t, tokens), es256Key).Claims.(jwt.MapClaims)
+		assert.Equal(t, sessionID.String(), claims["sid"])
+		assert.NotContains(t, claims, "sub")
+	})
+
+	t.Run("case=is not limited to the configured event types", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySessionEventsTypes, []string{string(session.EventIssued)})
+		t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionEventsTypes, []string{}) })
+
+		sessionID := x.NewUUID()
+		session.NewEventEmitter(reg, sink).Emit(ctx, session.NewEvent(session.EventRevoked, x.NewUUID(), sessionID))
+
+		claims := validateTokenized(t, receiveEvent(t, tokens), es256Key).Claims.(jwt.MapClaims)
+		assert.Equal(t, sessionID.String(), claims["sid"])
+	})
+
+	t.Run("case=ignores other events", func(t *testing.T) {
+		require.NoError(t, sink.SendSessionEvent(ctx, session.NewEvent(session.EventIssued, x.NewUUID(), x.NewUUID())))
+		assert.Empty(t, tokens)
+	})
+}
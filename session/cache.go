@@ -0,0 +1,241 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+type (
+	// CacheStore keeps sessions by their token for the session cache, for example in memory or in Redis.
+	//
+	// Implementations must return sessions with all fields, including those which are not part of the JSON
+	// representation, because the sessions may be written back to the database. GetSession returns nil if
+	// the session is not stored.
+	CacheStore interface {
+		GetSession(ctx context.Context, token string) (*Session, error)
+		SetSession(ctx context.Context, token string, s *Session, ttl time.Duration) error
+		DeleteSessions(ctx context.Context, ids ...uuid.UUID) error
+		DeleteIdentitySessions(ctx context.Context, identityID uuid.UUID) error
+	}
+
+	cacheDependencies interface {
+		config.Provider
+		x.LoggingProvider
+	}
+
+	// Cache caches the sessions looked up by whoami. It first asks the in-memory store of this instance
+	// and then all additional stores, which are registered using driver.WithSessionCacheStores.
+	Cache struct {
+		d       cacheDependencies
+		stores  []CacheStore
+		lookups *prometheus.CounterVec
+	}
+
+	CacheProvider interface {
+		SessionCache() *Cache
+	}
+
+	memoryCacheStore struct {
+		sync.Mutex
+		maxEntries func(ctx context.Context) int
+		entries    map[string]memoryCacheEntry
+		tokens     map[uuid.UUID]string
+	}
+
+	memoryCacheEntry struct {
+		session   *Session
+		expiresAt time.Time
+	}
+)
+
+var _ prometheus.Collector = new(Cache)
+
+func NewCache(d cacheDependencies, stores ...CacheStore) *Cache {
+	return &Cache{
+		d: d,
+		stores: append([]CacheStore{&memoryCacheStore{
+			maxEntries: d.Config().SessionWhoAmICacheMaxEntries,
+			entries:    make(map[string]memoryCacheEntry),
+			tokens:     make(map[uuid.UUID]string),
+		}}, stores...),
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "kratos_session_cache_lookups_total",
+			Help: "Number of session cache lookups by result.",
+		}, []string{"result"}),
+	}
+}
+
+func (c *Cache) Describe(ch chan<- *prometheus.Desc) {
+	c.lookups.Describe(ch)
+}
+
+func (c *Cache) Collect(ch chan<- prometheus.Metric) {
+	c.lookups.Collect(ch)
+}
+
+// Get returns the cached session for the token or nil if the session is not cached or the cache is disabled.
+// A session found in a slower store is copied to the faster stores before it.
+func (c *Cache) Get(ctx context.Context, token string) *Session {
+	if !c.d.Config().SessionWhoAmICacheEnabled(ctx) {
+		return nil
+	}
+
+	for k, store := range c.stores {
+		s, err := store.GetSession(ctx, token)
+		if err != nil {
+			c.d.Logger().WithError(err).Warn("Unable to look up session in the session cache.")
+			continue
+		} else if s == nil {
+			continue
+		}
+
+		ttl := c.d.Config().SessionWhoAmICacheTTL(ctx)
+		for _, faster := range c.stores[:k] {
+			if err := faster.SetSession(ctx, token, s, ttl); err != nil {
+				c.d.Logger().WithError(err).Warn("Unable to store session in the session cache.")
+			}
+		}
+
+		c.lookups.WithLabelValues("hit").Inc()
+		return s
+	}
+
+	c.lookups.WithLabelValues("miss").Inc()
+	return nil
+}
+
+// Set caches the session for the token if the cache is enabled.
+func (c *Cache) Set(ctx context.Context, token string, s *Session) {
+	if !c.d.Config().SessionWhoAmICacheEnabled(ctx) {
+		return
+	}
+
+	ttl := c.d.Config().SessionWhoAmICacheTTL(ctx)
+	for _, store := range c.stores {
+		if err := store.SetSession(ctx, token, s, ttl); err != nil {
+			c.d.Logger().WithError(err).Warn("Unable to store session in the session cache.")
+		}
+	}
+}
+
+// InvalidateSessions removes the sessions from all stores. It runs even if the cache is disabled, so that
+// no outdated sessions remain when the cache is enabled again.
+func (c *Cache) InvalidateSessions(ctx context.Context, ids ...uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+
+	for _, store := range c.stores {
+		if err := store.DeleteSessions(ctx, ids...); err != nil {
+			c.d.Logger().WithError(err).Error("Unable to remove sessions from the session cache. They are served until they expire from the cache.")
+		}
+	}
+}
+
+// InvalidateIdentity removes all sessions of the identity from all stores.
+func (c *Cache) InvalidateIdentity(ctx context.Context, identityID uuid.UUID) {
+	for _, store := range c.stores {
+		if err := store.DeleteIdentitySessions(ctx, identityID); err != nil {
+			c.d.Logger().WithError(err).Error("Unable to remove sessions from the session cache. They are served until they expire from the cache.")
+		}
+	}
+}
+
+// copySession copies the session and its identity, so that callers can not modify cached sessions.
+func copySession(s *Session) *Session {
+	cp := *s
+	if s.Identity != nil {
+		i := *s.Identity
+		cp.Identity = &i
+	}
+	return &cp
+}
+
+func (m *memoryCacheStore) GetSession(_ context.Context, token string) (*Session, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	e, ok := m.entries[token]
+	if !ok {
+		return nil, nil
+	} else if time.Now().After(e.expiresAt) {
+		m.delete(token)
+		return nil, nil
+	}
+
+	return copySession(e.session), nil
+}
+
+func (m *memoryCacheStore) SetSession(ctx context.Context, token string, s *Session, ttl time.Duration) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if _, ok := m.entries[token]; !ok && len(m.entries) >= m.maxEntries(ctx) {
+		m.evict()
+	}
+
+	m.entries[token] = memoryCacheEntry{session: copySession(s), expiresAt: time.Now().Add(ttl)}
+	m.tokens[s.ID] = token
+	return nil
+}
+
+func (m *memoryCacheStore) DeleteSessions(_ context.Context, ids ...uuid.UUID) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for _, id := range ids {
+		if token, ok := m.tokens[id]; ok {
+			m.delete(token)
+		}
+	}
+	return nil
+}
+
+func (m *memoryCacheStore) DeleteIdentitySessions(_ context.Context, identityID uuid.UUID) error {
+	m.Lock()
+	defer m.Unlock()
+
+	for token, e := range m.entries {
+		if e.session.IdentityID == identityID {
+			m.delete(token)
+		}
+	}
+	return nil
+}
+
+// evict removes all expired entries or, if none expired, an arbitrary one.
+func (m *memoryCacheStore) evict() {
+	now := time.Now()
+	evicted := false
+	for token, e := range m.entries {
+		if now.After(e.expiresAt) {
+			m.delete(token)
+			evicted = true
+		}
+	}
+	if evicted {
+		return
+	}
+
+	for token := range m.entries {
+		m.delete(token)
+		return
+	}
+}
+
+func (m *memoryCacheStore) delete(token string) {
+	if e, ok := m.entries[token]; ok {
+		delete(m.tokens, e.session.ID)
+		delete(m.entries, token)
+	}
+}
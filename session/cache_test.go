@@ -0,0 +1,134 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/session"
+)
+
+func TestSessionCache(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/identity.schema.json")
+	conf.MustSet(ctx, config.ViperKeySessionWhoAmICacheEnabled, true)
+
+	newSession := func(t *testing.T) *session.Session {
+		i := &identity.Identity{Traits: []byte("{}"), State: identity.StateActive}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+
+		req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+		sess := session.NewInactiveSession()
+		require.NoError(t, sess.Activate(req, i, conf, time.Now()))
+		require.NoError(t, reg.SessionPersister().UpsertSession(ctx, sess))
+		return sess
+	}
+
+	whoami := func(t *testing.T, sess *session.Session) (*session.Session, error) {
+		req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+		req.Header.Set("X-Session-Token", sess.Token)
+		return reg.SessionManager().FetchFromRequestCached(ctx, req)
+	}
+
+	// deactivate changes the session without going through the persister, which would remove it from the cache.
+	deactivate := func(t *testing.T, sess *session.Session) {
+		require.NoError(t, reg.Persister().GetConnection(ctx).RawQuery("UPDATE sessions SET active = false WHERE id = ?", sess.ID).Exec())
+	}
+
+	t.Run("case=serves the session from the cache", func(t *testing.T) {
+		sess := newSession(t)
+		_, err := whoami(t, sess)
+		require.NoError(t, err)
+
+		deactivate(t, sess)
+
+		actual, err := whoami(t, sess)
+		require.NoError(t, err)
+		assert.Equal(t, sess.ID, actual.ID)
+		assert.Equal(t, sess.IdentityID, actual.Identity.ID)
+
+		req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+		req.Header.Set("X-Session-Token", sess.Token)
+		_, err = reg.SessionManager().FetchFromRequest(ctx, req)
+		require.Error(t, err, "only whoami uses the cache")
+	})
+
+	t.Run("case=does not cache sessions if disabled", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySessionWhoAmICacheEnabled, false)
+		t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionWhoAmICacheEnabled, true) })
+
+		sess := newSession(t)
+		_, err := whoami(t, sess)
+		require.NoError(t, err)
+
+		deactivate(t, sess)
+
+		_, err = whoami(t, sess)
+		require.Error(t, err)
+	})
+
+	t.Run("case=revoking a session removes it from the cache", func(t *testing.T) {
+		sess := newSession(t)
+		_, err := whoami(t, sess)
+		require.NoError(t, err)
+
+		require.NoError(t, reg.SessionPersister().RevokeSessionById(ctx, sess.ID))
+
+		_, err = whoami(t, sess)
+		require.Error(t, err)
+	})
+
+	t.Run("case=extending a session removes it from the cache", func(t *testing.T) {
+		sess := newSession(t)
+		_, err := whoami(t, sess)
+		require.NoError(t, err)
+
+		sess.ExpiresAt = sess.ExpiresAt.Add(time.Hour)
+		require.NoError(t, reg.SessionPersister().UpsertSession(ctx, sess))
+
+		actual, err := whoami(t, sess)
+		require.NoError(t, err)
+		assert.WithinDuration(t, sess.ExpiresAt, actual.ExpiresAt, time.Second)
+	})
+
+	t.Run("case=updating the identity removes its sessions from the cache", func(t *testing.T) {
+		sess := newSession(t)
+		_, err := whoami(t, sess)
+		require.NoError(t, err)
+
+		i, err := reg.PrivilegedIdentityPool().GetIdentityConfidential(ctx, sess.IdentityID)
+		require.NoError(t, err)
+		i.MetadataPublic = []byte(`{"updated":true}`)
+		require.NoError(t, reg.PrivilegedIdentityPool().UpdateIdentity(ctx, i))
+
+		actual, err := whoami(t, sess)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"updated":true}`, string(actual.Identity.MetadataPublic))
+	})
+
+	t.Run("case=expires cached sessions", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySessionWhoAmICacheTTL, "10ms")
+		t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionWhoAmICacheTTL, "10s") })
+
+		sess := newSession(t)
+		_, err := whoami(t, sess)
+		require.NoError(t, err)
+
+		deactivate(t, sess)
+		time.Sleep(50 * time.Millisecond)
+
+		_, err = whoami(t, sess)
+		require.Error(t, err)
+	})
+}
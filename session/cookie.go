@@ -0,0 +1,98 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/x"
+)
+
+// HostCookiePrefix is prepended to the name of the session cookie if `session.cookie.host_prefix` is set.
+const HostCookiePrefix = "__Host-"
+
+// cookieDomain returns the configured cookie domain which the host of the request belongs to, or nil if
+// there is none. The most specific domain wins.
+func (s *ManagerHTTP) cookieDomain(r *http.Request) *config.SessionCookieDomain {
+	domains := s.r.Config().SessionCookieDomains(r.Context())
+	if len(domains) == 0 {
+		return nil
+	}
+
+	host := strings.ToLower(x.RequestURL(r).Hostname())
+
+	var match *config.SessionCookieDomain
+	for _, d := range domains {
+		domain := strings.ToLower(strings.TrimPrefix(d.Domain, "."))
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		if match == nil || len(domain) > len(strings.TrimPrefix(match.Domain, ".")) {
+			d := d
+			match = &d
+		}
+	}
+	return match
+}
+
+// sessionCookieName returns the name of the session cookie for the request.
+func (s *ManagerHTTP) sessionCookieName(r *http.Request) string {
+	name := s.cookieName(r.Context())
+	if d := s.cookieDomain(r); d != nil && d.Name != "" {
+		name = d.Name
+	}
+
+	if s.r.Config().SessionCookieHostPrefix(r.Context()) && !strings.HasPrefix(name, HostCookiePrefix) {
+		name = HostCookiePrefix + name
+	}
+	return name
+}
+
+// scopeCookie applies the domain, SameSite, and prefix settings which depend on the request to the session cookie.
+func (s *ManagerHTTP) scopeCookie(r *http.Request, cookie *sessions.Session) {
+	ctx := r.Context()
+
+	if d := s.cookieDomain(r); d != nil {
+		cookie.Options.Domain = d.Domain
+	}
+
+	var prefix string
+	for _, o := range s.r.Config().SessionCookieSameSiteOverrides(ctx) {
+		if strings.HasPrefix(r.URL.Path, o.Path) && len(o.Path) >= len(prefix) {
+			prefix = o.Path
+			cookie.Options.SameSite = o.Mode()
+		}
+	}
+
+	if s.r.Config().SessionCookieHostPrefix(ctx) {
+		// Browsers reject cookies with this prefix unless they are secure, for the root path, and host-only.
+		cookie.Options.Domain = ""
+		cookie.Options.Path = "/"
+		cookie.Options.Secure = true
+	}
+}
+
+// saveCookie writes the session cookie and marks it as partitioned if configured.
+func (s *ManagerHTTP) saveCookie(w http.ResponseWriter, r *http.Request, cookie *sessions.Session) error {
+	if err := cookie.Save(r, w); err != nil {
+		return err
+	}
+
+	if !s.r.Config().SessionCookiePartitioned(r.Context()) {
+		return nil
+	}
+
+	// The Go standard library in use does not support the Partitioned attribute, so it is appended to the header.
+	headers := w.Header()["Set-Cookie"]
+	for k, h := range headers {
+		if strings.HasPrefix(h, cookie.Name()+"=") && !strings.Contains(h, "; Partitioned") {
+			headers[k] = h + "; Partitioned"
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// DPoPProof records a DPoP proof which was accepted, so that it can not be replayed. It is kept until the proof
+// is too old to be accepted anyway.
+type DPoPProof struct {
+	ID uuid.UUID `json:"-" faker:"-" db:"id"`
+
+	// KeyThumbprint is the thumbprint of the key which signed the proof.
+	KeyThumbprint string `json:"-" db:"key_thumbprint"`
+
+	// ProofID is the `jti` claim of the proof.
+	ProofID string `json:"-" db:"jti"`
+
+	// ExpiresAt is the time at which the proof is no longer accepted.
+	ExpiresAt time.Time `json:"-" faker:"-" db:"expires_at"`
+
+	CreatedAt time.Time `json:"-" faker:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (p DPoPProof) TableName(context.Context) string {
+	return "session_dpop_proofs"
+}
+
+type DPoPProofPersister interface {
+	// CreateDPoPProof records an accepted DPoP proof. It returns sqlcon.ErrUniqueViolation if the key signed
+	// a proof with the same ID before.
+	CreateDPoPProof(ctx context.Context, p *DPoPProof) error
+}
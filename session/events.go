@@ -0,0 +1,205 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/request"
+	"github.com/ory/kratos/x"
+)
+
+const (
+	EventIssued     EventType = "session.issued"
+	EventExtended   EventType = "session.extended"
+	EventRevoked    EventType = "session.revoked"
+	EventExpired    EventType = "session.expired"
+	EventAALChanged EventType = "session.aal_changed"
+)
+
+type (
+	// EventType is the type of a session lifecycle event.
+	EventType string
+
+	// Event describes a change in the lifecycle of one or more sessions.
+	Event struct {
+		// ID identifies the event, so that consumers can deduplicate deliveries.
+		ID uuid.UUID `json:"id"`
+
+		// Type is the type of the event.
+		Type EventType `json:"type"`
+
+		// Time is when the change happened.
+		Time time.Time `json:"time"`
+
+		// IdentityID is the identity the sessions belong to. It is null if the
+		// sessions belong to more than one identity, such as for expired sessions.
+		IdentityID uuid.NullUUID `json:"identity_id"`
+
+		// SessionIDs are the sessions which changed.
+		SessionIDs []uuid.UUID `json:"session_ids"`
+
+		// AAL is the authenticator assurance level of the session, if known.
+		AAL identity.AuthenticatorAssuranceLevel `json:"aal,omitempty"`
+
+		// ExpiresAt is when the session expires, if known.
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	}
+
+	// EventSink delivers session events to an external system, such as a
+	// message bus. Custom sinks are registered using driver.WithSessionEventSinks.
+	//
+	// Sinks which also implement `Enabled(ctx context.Context) bool` are skipped
+	// while they are disabled.
+	EventSink interface {
+		SendSessionEvent(ctx context.Context, e *Event) error
+	}
+
+	optionalEventSink interface {
+		Enabled(ctx context.Context) bool
+	}
+
+	eventEmitterDependencies interface {
+		config.Provider
+		request.Dependencies
+	}
+
+	// EventEmitter publishes session lifecycle events to the configured
+	// webhook and all registered sinks.
+	EventEmitter struct {
+		d     eventEmitterDependencies
+		sinks []EventSink
+	}
+
+	EventEmitterProvider interface {
+		SessionEventEmitter() *EventEmitter
+	}
+
+	webhookEventSink struct {
+		d       eventEmitterDependencies
+		webhook []byte
+	}
+)
+
+// NewEvent creates an event for sessions of a single identity. Pass uuid.Nil as
+// the identity if the sessions belong to several identities.
+func NewEvent(t EventType, identityID uuid.UUID, sessionIDs ...uuid.UUID) *Event {
+	if sessionIDs == nil {
+		sessionIDs = []uuid.UUID{}
+	}
+
+	return &Event{
+		ID:         x.NewUUID(),
+		Type:       t,
+		Time:       time.Now().UTC(),
+		IdentityID: uuid.NullUUID{UUID: identityID, Valid: !identityID.IsNil()},
+		SessionIDs: sessionIDs,
+	}
+}
+
+// NewSessionEvent creates an event for the session, including its
+// authenticator assurance level and expiry.
+func NewSessionEvent(t EventType, s *Session) *Event {
+	e := NewEvent(t, s.IdentityID, s.ID)
+	e.AAL = s.AuthenticatorAssuranceLevel
+	if !s.ExpiresAt.IsZero() {
+		expiresAt := s.ExpiresAt.UTC()
+		e.ExpiresAt = &expiresAt
+	}
+	return e
+}
+
+func NewEventEmitter(d eventEmitterDependencies, sinks ...EventSink) *EventEmitter {
+	return &EventEmitter{d: d, sinks: sinks}
+}
+
+// Enabled returns true if events are published at all. Callers use it to skip
+// looking up data which is only needed for the events.
+func (e *EventEmitter) Enabled(ctx context.Context) bool {
+	return len(e.enabledSinks(ctx)) > 0
+}
+
+func (e *EventEmitter) enabledSinks(ctx context.Context) []EventSink {
+	sinks := make([]EventSink, 0, len(e.sinks)+1)
+	for _, sink := range e.sinks {
+		if optional, ok := sink.(optionalEventSink); !ok || optional.Enabled(ctx) {
+			sinks = append(sinks, sink)
+		}
+	}
+
+	if webhook := e.d.Config().SessionEventsWebhook(ctx); len(webhook) > 0 {
+		sinks = append(sinks, &webhookEventSink{d: e.d, webhook: webhook})
+	}
+	return sinks
+}
+
+// Emit publishes the events in the background, so that slow or unavailable
+// sinks do not delay the request. Delivery failures are logged but not retried
+// beyond what the webhook configuration allows.
+func (e *EventEmitter) Emit(ctx context.Context, events ...*Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	sinks := e.enabledSinks(ctx)
+	if len(sinks) == 0 {
+		return
+	}
+
+	types := e.d.Config().SessionEventsTypes(ctx)
+	ctx = context.WithoutCancel(ctx)
+	for _, event := range events {
+		filtered := len(types) > 0 && !slices.Contains(types, string(event.Type))
+		for _, sink := range sinks {
+			// Back-channel logout must learn about every revoked session, regardless of the event types
+			// which are published to the other sinks.
+			if _, ok := sink.(*BackChannelLogout); filtered && !ok {
+				continue
+			}
+
+			go func(sink EventSink, event *Event) {
+				if err := sink.SendSessionEvent(ctx, event); err != nil {
+					e.d.Logger().
+						WithError(err).
+						WithField("event_id", event.ID).
+						WithField("event_type", event.Type).
+						Warn("Unable to deliver session event.")
+				}
+			}(sink, event)
+		}
+	}
+}
+
+func (s *webhookEventSink) SendSessionEvent(ctx context.Context, e *Event) error {
+	builder, err := request.NewBuilder(ctx, s.webhook, s.d)
+	if err != nil {
+		return err
+	}
+
+	req, err := builder.BuildRequest(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.d.HTTPClient(ctx).Do(req)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to reach the session events webhook.").WithWrap(err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The session events webhook responded with unexpected status code %d.", res.StatusCode))
+	}
+
+	return nil
+}
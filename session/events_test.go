@@ -0,0 +1,184 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+type recordingEventSink struct {
+	events chan *session.Event
+}
+
+func newRecordingEventSink() *recordingEventSink {
+	return &recordingEventSink{events: make(chan *session.Event, 10)}
+}
+
+func (s *recordingEventSink) SendSessionEvent(_ context.Context, e *session.Event) error {
+	s.events <- e
+	return nil
+}
+
+func receiveEvent[T any](t *testing.T, events <-chan T) (e T) {
+	select {
+	case e = <-events:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "expected a session event to be published")
+	}
+	return e
+}
+
+func TestEventEmitter(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	t.Run("case=is disabled without sinks and webhook", func(t *testing.T) {
+		assert.False(t, reg.SessionEventEmitter().Enabled(ctx))
+	})
+
+	t.Run("case=publishes events to the sinks", func(t *testing.T) {
+		sink := newRecordingEventSink()
+		e := session.NewEventEmitter(reg, sink)
+		assert.True(t, e.Enabled(ctx))
+
+		iID, sID := x.NewUUID(), x.NewUUID()
+		e.Emit(ctx, session.NewEvent(session.EventRevoked, iID, sID))
+
+		actual := receiveEvent(t, sink.events)
+		assert.Equal(t, session.EventRevoked, actual.Type)
+		assert.Equal(t, uuid.NullUUID{UUID: iID, Valid: true}, actual.IdentityID)
+		assert.Equal(t, []uuid.UUID{sID}, actual.SessionIDs)
+	})
+
+	t.Run("case=only publishes the configured event types", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySessionEventsTypes, []string{string(session.EventExpired)})
+		t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionEventsTypes, []string{}) })
+
+		sink := newRecordingEventSink()
+		session.NewEventEmitter(reg, sink).Emit(ctx,
+			session.NewEvent(session.EventRevoked, x.NewUUID(), x.NewUUID()),
+			session.NewEvent(session.EventExpired, uuid.Nil, x.NewUUID()),
+		)
+
+		actual := receiveEvent(t, sink.events)
+		assert.Equal(t, session.EventExpired, actual.Type)
+		assert.False(t, actual.IdentityID.Valid)
+
+		time.Sleep(100 * time.Millisecond)
+		assert.Empty(t, sink.events)
+	})
+
+	t.Run("case=skips disabled sinks", func(t *testing.T) {
+		e := session.NewEventEmitter(reg, session.NewBackChannelLogout(reg))
+		assert.False(t, e.Enabled(ctx))
+
+		conf.MustSet(ctx, config.ViperKeySessionBackChannelLogoutJWKSURL, "file://stub/jwk.es256.json")
+		conf.MustSet(ctx, config.ViperKeySessionBackChannelLogoutEnabled, true)
+		t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionBackChannelLogoutEnabled, false) })
+		assert.True(t, e.Enabled(ctx))
+	})
+
+	t.Run("case=publishes session lifecycle events to the webhook", func(t *testing.T) {
+		testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/identity.schema.json")
+
+		received := make(chan json.RawMessage, 10)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body json.RawMessage
+			if err := json.NewDecoder(r.Body).Decode(&body); err == nil {
+				received <- body
+			}
+		}))
+		t.Cleanup(ts.Close)
+
+		conf.MustSet(ctx, config.ViperKeySessionEventsWebhook, map[string]interface{}{
+			"url":    ts.URL,
+			"method": "POST",
+			"body":   "base64://" + base64.StdEncoding.EncodeToString([]byte("function(ctx) ctx")),
+		})
+		require.True(t, reg.SessionEventEmitter().Enabled(ctx))
+
+		next := func(t *testing.T) *session.Event {
+			var e session.Event
+			require.NoError(t, json.Unmarshal(receiveEvent(t, received), &e))
+			return &e
+		}
+
+		i := &identity.Identity{Traits: []byte("{}"), State: identity.StateActive}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+
+		req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+		sess := session.NewInactiveSession()
+		require.NoError(t, sess.Activate(req, i, conf, time.Now()))
+		require.NoError(t, reg.SessionPersister().UpsertSession(ctx, sess))
+
+		issued := next(t)
+		assert.Equal(t, session.EventIssued, issued.Type)
+		assert.Equal(t, []uuid.UUID{sess.ID}, issued.SessionIDs)
+		assert.Equal(t, i.ID, issued.IdentityID.UUID)
+		require.NotNil(t, issued.ExpiresAt)
+
+		sess.AuthenticatorAssuranceLevel = identity.AuthenticatorAssuranceLevel2
+		require.NoError(t, reg.SessionPersister().UpsertSession(ctx, sess))
+		assert.Equal(t, session.EventAALChanged, next(t).Type)
+
+		sess.ExpiresAt = sess.ExpiresAt.Add(time.Hour)
+		require.NoError(t, reg.SessionPersister().UpsertSession(ctx, sess))
+		extended := next(t)
+		assert.Equal(t, session.EventExtended, extended.Type)
+		assert.WithinDuration(t, sess.ExpiresAt, *extended.ExpiresAt, time.Second)
+
+		require.NoError(t, reg.SessionPersister().RevokeSessionById(ctx, sess.ID))
+		revoked := next(t)
+		assert.Equal(t, session.EventRevoked, revoked.Type)
+		assert.Equal(t, []uuid.UUID{sess.ID}, revoked.SessionIDs)
+		assert.Equal(t, i.ID, revoked.IdentityID.UUID)
+
+		t.Run("case=does not publish revocations of inactive sessions", func(t *testing.T) {
+			require.NoError(t, reg.SessionPersister().RevokeSession(ctx, i.ID, sess.ID))
+			time.Sleep(100 * time.Millisecond)
+			assert.Empty(t, received)
+		})
+
+		t.Run("case=only sends the configured event types", func(t *testing.T) {
+			conf.MustSet(ctx, config.ViperKeySessionEventsTypes, []string{string(session.EventExpired)})
+			t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionEventsTypes, []string{}) })
+
+			reg.SessionEventEmitter().Emit(ctx,
+				session.NewEvent(session.EventRevoked, x.NewUUID(), x.NewUUID()),
+				session.NewEvent(session.EventExpired, uuid.Nil, x.NewUUID()),
+			)
+
+			assert.Equal(t, session.EventExpired, next(t).Type)
+			time.Sleep(100 * time.Millisecond)
+			assert.Empty(t, received)
+		})
+
+		t.Run("case=publishes expired sessions", func(t *testing.T) {
+			ids := []uuid.UUID{x.NewUUID(), x.NewUUID()}
+			require.NoError(t, reg.SessionExpiredNotifier().Notify(ctx, ids))
+
+			expired := next(t)
+			assert.Equal(t, session.EventExpired, expired.Type)
+			assert.Equal(t, ids, expired.SessionIDs)
+			assert.False(t, expired.IdentityID.Valid)
+		})
+	})
+}
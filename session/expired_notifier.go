@@ -0,0 +1,101 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/request"
+	"github.com/ory/kratos/x/events"
+)
+
+type (
+	expiredNotifierDependencies interface {
+		config.Provider
+		request.Dependencies
+		EventEmitterProvider
+	}
+
+	// ExpiredNotifier informs external systems about expired sessions before
+	// the database cleanup removes them, so that they can release resources
+	// tied to these sessions. Sessions are reported at least once.
+	ExpiredNotifier struct {
+		d expiredNotifierDependencies
+	}
+
+	ExpiredNotifierProvider interface {
+		SessionExpiredNotifier() *ExpiredNotifier
+	}
+
+	expiredSessionsWebhookBody struct {
+		SessionIDs []uuid.UUID `json:"session_ids"`
+	}
+)
+
+func NewExpiredNotifier(d expiredNotifierDependencies) *ExpiredNotifier {
+	return &ExpiredNotifier{d: d}
+}
+
+// Notify emits a trace event and session expired events for the expired
+// sessions and, if a webhook is configured, sends the session IDs to the
+// webhook in batches.
+func (n *ExpiredNotifier) Notify(ctx context.Context, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	trace.SpanFromContext(ctx).AddEvent(events.NewSessionsExpired(ctx, ids))
+
+	webhook := n.d.Config().DatabaseCleanupExpiredSessionsWebhook(ctx)
+	batchSize := n.d.Config().DatabaseCleanupExpiredSessionsBatchSize(ctx)
+	for start := 0; start < len(ids); start += batchSize {
+		end := start + batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		// The sessions may belong to several identities, which are no longer known after deleting the sessions.
+		n.d.SessionEventEmitter().Emit(ctx, NewEvent(EventExpired, uuid.Nil, ids[start:end]...))
+
+		if len(webhook) == 0 {
+			continue
+		}
+		if err := n.send(ctx, webhook, ids[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *ExpiredNotifier) send(ctx context.Context, webhook []byte, ids []uuid.UUID) error {
+	builder, err := request.NewBuilder(ctx, webhook, n.d)
+	if err != nil {
+		return err
+	}
+
+	req, err := builder.BuildRequest(ctx, &expiredSessionsWebhookBody{SessionIDs: ids})
+	if err != nil {
+		return err
+	}
+
+	res, err := n.d.HTTPClient(ctx).Do(req)
+	if err != nil {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to reach the expired sessions webhook.").WithWrap(err))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return errors.WithStack(herodot.ErrInternalServerError.WithReasonf("The expired sessions webhook responded with unexpected status code %d.", res.StatusCode))
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/x"
+)
+
+func TestExpiredNotifier(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+
+	ids := []uuid.UUID{x.NewUUID(), x.NewUUID(), x.NewUUID()}
+
+	t.Run("case=does nothing without a webhook", func(t *testing.T) {
+		require.NoError(t, reg.SessionExpiredNotifier().Notify(ctx, ids))
+	})
+
+	var status int
+	var received [][]uuid.UUID
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			SessionIDs []uuid.UUID `json:"session_ids"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		received = append(received, body.SessionIDs)
+		if status != 0 {
+			w.WriteHeader(status)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	conf.MustSet(ctx, config.ViperKeyDatabaseCleanupExpiredSessionsWebhook, map[string]interface{}{
+		"url":    ts.URL,
+		"method": "POST",
+		"body":   "base64://" + base64.StdEncoding.EncodeToString([]byte("function(ctx) ctx")),
+	})
+	conf.MustSet(ctx, config.ViperKeyDatabaseCleanupExpiredSessionsBatchSize, 2)
+
+	t.Run("case=sends the session ids in batches", func(t *testing.T) {
+		received, status = nil, 0
+
+		require.NoError(t, reg.SessionExpiredNotifier().Notify(ctx, ids))
+		assert.Equal(t, [][]uuid.UUID{ids[:2], ids[2:]}, received)
+	})
+
+	t.Run("case=fails if the webhook fails", func(t *testing.T) {
+		received, status = nil, http.StatusInternalServerError
+
+		require.Error(t, reg.SessionExpiredNotifier().Notify(ctx, ids))
+		assert.Len(t, received, 1)
+	})
+}
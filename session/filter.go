@@ -0,0 +1,95 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/identity"
+)
+
+// Filter narrows down the sessions which administrators list or revoke. Empty fields match all sessions.
+type Filter struct {
+	// Active matches active sessions if true and inactive or expired sessions if false.
+	Active *bool
+
+	// IdentityID matches the sessions of the identity.
+	IdentityID uuid.UUID
+
+	// AAL matches sessions with the authenticator assurance level.
+	AAL identity.AuthenticatorAssuranceLevel
+
+	// AuthenticationMethod matches sessions which were authenticated using the method.
+	AuthenticationMethod identity.CredentialsType
+
+	// AuthenticatedAfter matches sessions which were authenticated at or after the time.
+	AuthenticatedAfter time.Time
+
+	// AuthenticatedBefore matches sessions which were authenticated before the time.
+	AuthenticatedBefore time.Time
+}
+
+// IsEmpty returns true if the filter matches all sessions, regardless of their state.
+func (f *Filter) IsEmpty() bool {
+	return f.IdentityID.IsNil() && f.AAL == "" && f.AuthenticationMethod == "" &&
+		f.AuthenticatedAfter.IsZero() && f.AuthenticatedBefore.IsZero()
+}
+
+// ParseFilter parses the filter from the query parameters of the admin API.
+func ParseFilter(q url.Values) (f Filter, err error) {
+	if raw := q.Get("active"); raw != "" {
+		active, err := strconv.ParseBool(raw)
+		if err != nil {
+			return f, errors.WithStack(herodot.ErrBadRequest.WithError("could not parse parameter active"))
+		}
+		f.Active = &active
+	}
+
+	if raw := q.Get("identity_id"); raw != "" {
+		if f.IdentityID, err = uuid.FromString(raw); err != nil {
+			return f, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Could not parse parameter identity_id: %s", err))
+		}
+	}
+
+	if raw := q.Get("aal"); raw != "" {
+		switch aal := identity.AuthenticatorAssuranceLevel(raw); aal {
+		case identity.AuthenticatorAssuranceLevel1, identity.AuthenticatorAssuranceLevel2:
+			f.AAL = aal
+		default:
+			return f, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Could not parse parameter aal: %s", raw))
+		}
+	}
+
+	if raw := q.Get("authentication_method"); raw != "" {
+		f.AuthenticationMethod = identity.CredentialsType(raw)
+	}
+
+	if f.AuthenticatedAfter, err = parseFilterTime(q, "authenticated_after"); err != nil {
+		return f, err
+	}
+	if f.AuthenticatedBefore, err = parseFilterTime(q, "authenticated_before"); err != nil {
+		return f, err
+	}
+
+	return f, nil
+}
+
+func parseFilterTime(q url.Values, key string) (time.Time, error) {
+	raw := q.Get(key)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Could not parse parameter %s, expected an RFC 3339 timestamp: %s", key, err))
+	}
+	return t.UTC(), nil
+}
@@ -25,7 +25,10 @@ import (
 	"github.com/ory/herodot"
 
 	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
+	"github.com/ory/x/jsonx"
 )
 
 type (
@@ -61,25 +64,39 @@ func NewHandler(
 const (
 	RouteCollection                  = "/sessions"
 	RouteExchangeCodeForSessionToken = RouteCollection + "/token-exchange" // #nosec G101
+	RouteRefreshSessionToken         = RouteCollection + "/token-refresh"  // #nosec G101
 	RouteWhoami                      = RouteCollection + "/whoami"
+	RouteWhoamiExtend                = RouteWhoami + "/extend"
 	RouteSession                     = RouteCollection + "/:id"
 )
 
 const (
-	AdminRouteIdentity           = "/identities"
-	AdminRouteIdentitiesSessions = AdminRouteIdentity + "/:id/sessions"
-	AdminRouteSessionExtendId    = RouteSession + "/extend"
+	AdminRouteIdentity                 = "/identities"
+	AdminRouteIdentitiesSessions       = AdminRouteIdentity + "/:id/sessions"
+	AdminRouteIdentitiesTrustedDevices = AdminRouteIdentity + "/:id/trusted-devices"
+	AdminRouteIdentitiesTrustedDevice  = AdminRouteIdentitiesTrustedDevices + "/:device_id"
+	AdminRouteSessionExtendId          = RouteSession + "/extend"
+	AdminRouteSessionsRevoke           = RouteCollection + "/revoke"
 )
 
 func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
 	admin.GET(RouteCollection, h.adminListSessions)
 	admin.GET(RouteSession, h.getSession)
 	admin.DELETE(RouteSession, h.disableSession)
+	admin.POST(AdminRouteSessionsRevoke, h.adminRevokeSessions)
 
 	admin.GET(AdminRouteIdentitiesSessions, h.listIdentitySessions)
 	admin.DELETE(AdminRouteIdentitiesSessions, h.deleteIdentitySessions)
 	admin.PATCH(AdminRouteSessionExtendId, h.adminSessionExtend)
 
+	admin.GET(RouteAdminLogoutCallbacks, h.adminListLogoutCallbacks)
+	admin.PUT(RouteAdminLogoutCallback, h.adminSetLogoutCallback)
+	admin.DELETE(RouteAdminLogoutCallback, h.adminDeleteLogoutCallback)
+
+	admin.GET(AdminRouteIdentitiesTrustedDevices, h.listIdentityTrustedDevices)
+	admin.DELETE(AdminRouteIdentitiesTrustedDevices, h.deleteIdentityTrustedDevices)
+	admin.DELETE(AdminRouteIdentitiesTrustedDevice, h.deleteIdentityTrustedDevice)
+
 	admin.DELETE(RouteCollection, x.RedirectToPublicRoute(h.r))
 }
 
@@ -101,6 +118,9 @@ func (h *Handler) RegisterPublicRoutes(public *x.RouterPublic) {
 	public.GET(RouteCollection, h.listMySessions)
 
 	public.GET(RouteExchangeCodeForSessionToken, h.exchangeCode)
+	public.POST(RouteRefreshSessionToken, h.refreshSessionToken)
+	public.GET(RouteTokenizerJWKS, h.tokenizerJWKS)
+	public.PATCH(RouteWhoamiExtend, h.extendMySession)
 
 	public.DELETE(AdminRouteIdentitiesSessions, x.RedirectToAdminRoute(h.r))
 }
@@ -132,6 +152,21 @@ type toSession struct {
 	//
 	// in: query
 	TokenizeAs string `json:"tokenize_as"`
+
+	// Selects which related objects the session includes. If not set, the identity and the devices
+	// are included. Set it to an empty value to include neither.
+	//
+	// required: false
+	// enum: identity,devices
+	// in: query
+	ExpandOptions []string `json:"expand"`
+
+	// Limits the response to these top-level fields of the session, for example `id,authenticator_assurance_level,expires_at`.
+	// If not set, all fields are included.
+	//
+	// required: false
+	// in: query
+	Fields []string `json:"fields"`
 }
 
 // swagger:route GET /sessions/whoami frontend toSession
@@ -164,6 +199,9 @@ type toSession struct {
 //	// console.log(session)
 //	```
 //
+// High-traffic callers can keep the response small using the `expand` and `fields` query parameters. For example,
+// `?expand=&fields=id,authenticator_assurance_level,expires_at` responds with only these three fields.
+//
 // When using a token template, the token is included in the `tokenized` field of the session.
 //
 //	```js
@@ -197,6 +235,7 @@ type toSession struct {
 //
 // - `session_inactive`: No active session was found in the request (e.g. no Ory Session Cookie / Ory Session Token).
 // - `session_aal2_required`: An active session was found but it does not fulfil the Authenticator Assurance Level, implying that the session must (e.g.) authenticate the second factor.
+// - `session_required_actions_pending`: An active session was found but the required actions, e.g. changing the password after a recovery, must be completed in the settings flow first.
 //
 //	Produces:
 //	- application/json
@@ -212,7 +251,13 @@ func (h *Handler) whoami(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 	ctx, span := h.r.Tracer(r.Context()).Tracer().Start(r.Context(), "sessions.Handler.whoami")
 	defer span.End()
 
-	s, err := h.r.SessionManager().FetchFromRequest(r.Context(), r)
+	shape, err := parseWhoamiShape(r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	s, err := h.r.SessionManager().FetchFromRequestCached(r.Context(), r)
 	c := h.r.Config()
 	if err != nil {
 		// We cache errors (and set cache header only when configured) where no session was found.
@@ -221,10 +266,21 @@ func (h *Handler) whoami(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 		}
 
 		h.r.Audit().WithRequest(r).WithError(err).Info("No valid session found.")
+		if noSess := new(ErrNoActiveSessionFound); errors.As(err, &noSess) && noSess.IDField == text.ErrIDSessionIdle {
+			// Let clients tell an idle session apart from a missing one, for example to show a different message.
+			h.r.Writer().WriteError(w, r, ErrNoSessionFound.WithWrap(err).WithID(text.ErrIDSessionIdle).WithReason(noSess.Reason()))
+			return
+		}
 		h.r.Writer().WriteError(w, r, ErrNoSessionFound.WithWrap(err))
 		return
 	}
 
+	if s.HasRequiredActions() {
+		h.r.Audit().WithRequest(r).Info("Session was found but has pending required actions.")
+		h.r.Writer().WriteError(w, r, NewErrRequiredActionsPending(c.SelfServiceFlowSettingsUI(r.Context()).String(), s.RequiredActions))
+		return
+	}
+
 	var aalErr *ErrAALNotSatisfied
 	if err := h.r.SessionManager().DoesSessionSatisfy(r, s, c.SessionWhoAmIAAL(r.Context()),
 		// For the time being we want to update the AAL in the database if it is unset.
@@ -264,7 +320,13 @@ func (h *Handler) whoami(w http.ResponseWriter, r *http.Request, _ httprouter.Pa
 		return
 	}
 
-	h.r.Writer().Write(w, r, s)
+	response, err := shape.apply(s)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, response)
 }
 
 // Delete Identity Session Parameters
@@ -329,6 +391,37 @@ type listSessionsRequest struct {
 	// in: query
 	Active bool `json:"active"`
 
+	// IdentityID only returns the sessions of the identity with this ID.
+	//
+	// required: false
+	// in: query
+	IdentityID string `json:"identity_id"`
+
+	// AAL only returns sessions with this authenticator assurance level.
+	//
+	// required: false
+	// enum: aal1,aal2
+	// in: query
+	AAL string `json:"aal"`
+
+	// AuthenticationMethod only returns sessions which were authenticated using this method, for example `password` or `oidc`.
+	//
+	// required: false
+	// in: query
+	AuthenticationMethod string `json:"authentication_method"`
+
+	// AuthenticatedAfter only returns sessions which were authenticated at or after this RFC 3339 timestamp.
+	//
+	// required: false
+	// in: query
+	AuthenticatedAfter time.Time `json:"authenticated_after"`
+
+	// AuthenticatedBefore only returns sessions which were authenticated before this RFC 3339 timestamp.
+	//
+	// required: false
+	// in: query
+	AuthenticatedBefore time.Time `json:"authenticated_before"`
+
 	// ExpandOptions is a query parameter encoded list of all properties that must be expanded in the Session.
 	// If no value is provided, the expandable properties are skipped.
 	//
@@ -370,18 +463,12 @@ type listSessionsResponse struct {
 //	  400: errorGeneric
 //	  default: errorGeneric
 func (h *Handler) adminListSessions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	activeRaw := r.URL.Query().Get("active")
-	activeBool, err := strconv.ParseBool(activeRaw)
-	if activeRaw != "" && err != nil {
-		h.r.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError("could not parse parameter active"))
+	filter, err := ParseFilter(r.URL.Query())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
 		return
 	}
 
-	var active *bool
-	if activeRaw != "" {
-		active = &activeBool
-	}
-
 	// Parse request pagination parameters
 	opts, err := keysetpagination.Parse(r.URL.Query(), keysetpagination.NewStringPageToken)
 	if err != nil {
@@ -401,7 +488,7 @@ func (h *Handler) adminListSessions(w http.ResponseWriter, r *http.Request, ps h
 		}
 	}
 
-	sess, total, nextPage, err := h.r.SessionPersister().ListSessions(r.Context(), active, opts, expandables)
+	sess, total, nextPage, err := h.r.SessionPersister().ListSessions(r.Context(), filter, opts, expandables)
 	if err != nil {
 		h.r.Writer().WriteError(w, r, err)
 		return
@@ -413,6 +500,109 @@ func (h *Handler) adminListSessions(w http.ResponseWriter, r *http.Request, ps h
 	h.r.Writer().Write(w, r, sess)
 }
 
+// Revoke Sessions Request
+//
+// swagger:parameters revokeSessions
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type revokeSessionsRequest struct {
+	// in: body
+	// required: true
+	Body revokeSessionsBody
+}
+
+// Revoke Sessions Request Body
+//
+// At least one of the conditions must be set. Only sessions which match all conditions are revoked.
+//
+// swagger:model revokeSessionsBody
+type revokeSessionsBody struct {
+	// IdentityID revokes the sessions of the identity with this ID.
+	IdentityID uuid.UUID `json:"identity_id"`
+
+	// AAL revokes sessions with this authenticator assurance level.
+	AAL identity.AuthenticatorAssuranceLevel `json:"aal"`
+
+	// AuthenticationMethod revokes sessions which were authenticated using this method, for example `password` or `oidc`.
+	AuthenticationMethod identity.CredentialsType `json:"authentication_method"`
+
+	// AuthenticatedAfter revokes sessions which were authenticated at or after this time.
+	AuthenticatedAfter time.Time `json:"authenticated_after"`
+
+	// AuthenticatedBefore revokes sessions which were authenticated before this time, for example
+	// to sign out all sessions older than a certain age.
+	AuthenticatedBefore time.Time `json:"authenticated_before"`
+}
+
+// Revoke Sessions Response
+//
+// swagger:model revokedSessions
+type revokedSessions struct {
+	// The number of sessions that were revoked.
+	//
+	// required: true
+	Count int `json:"revoked"`
+}
+
+// swagger:route POST /admin/sessions/revoke identity revokeSessions
+//
+// # Revoke Sessions in Bulk
+//
+// Revokes all active sessions which match the given conditions, for example all sessions of an identity, all sessions
+// which were authenticated using a certain method, or all sessions which were authenticated before a certain time.
+// The sessions are deactivated, not deleted.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: revokedSessions
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) adminRevokeSessions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body revokeSessionsBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	}
+
+	switch body.AAL {
+	case "", identity.AuthenticatorAssuranceLevel1, identity.AuthenticatorAssuranceLevel2:
+	default:
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unknown authenticator assurance level: %s", body.AAL)))
+		return
+	}
+
+	filter := Filter{
+		IdentityID:           body.IdentityID,
+		AAL:                  body.AAL,
+		AuthenticationMethod: body.AuthenticationMethod,
+		AuthenticatedAfter:   body.AuthenticatedAfter,
+		AuthenticatedBefore:  body.AuthenticatedBefore,
+	}
+	if filter.IsEmpty() {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("At least one condition is required to revoke sessions in bulk.")))
+		return
+	}
+
+	count, err := h.r.SessionPersister().RevokeSessions(r.Context(), filter)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, &revokedSessions{Count: count})
+}
+
 // Session Get Request
 //
 // The request object for getting a session in an administrative context.
@@ -897,6 +1087,86 @@ func (h *Handler) adminSessionExtend(w http.ResponseWriter, r *http.Request, ps
 	h.r.Writer().Write(w, r, s)
 }
 
+// Extend My Session Parameters
+//
+// swagger:parameters extendMySession
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type extendMySession struct {
+	// Set the Session Token when calling from non-browser clients. A session token has a format of `MP2YWEMeM8MxjkGKpH4dqOQ4Q4DlSPaj`.
+	//
+	// in: header
+	SessionToken string `json:"X-Session-Token"`
+
+	// Set the Cookie Header. This is especially useful when calling this endpoint from a server-side application. In that
+	// scenario you must include the HTTP Cookie Header which originally was included in the request to your server.
+	// An example of a session in the HTTP Cookie Header is: `ory_kratos_session=a19iOVAbdzdgl70Rq1QZmrKmcjDtdsviCTZx7m9a9yHIUS8Wa9T7hvqyGTsLHi6Qifn2WUfpAKx9DWp0SJGleIn9vh2YF4A16id93kXFTgIgmwIOvbVAScyrx7yVl6bPZnCx27ec4WQDtaTewC1CpgudeDV2jQQnSaCP6ny3xa8qLH-QUgYqdQuoA_LF1phxgRCUfIrCLQOkolX5nv3ze_f==`.
+	//
+	// in: header
+	Cookie string `json:"Cookie"`
+}
+
+// swagger:route PATCH /sessions/whoami/extend frontend extendMySession
+//
+// # Extend My Session
+//
+// Calling this endpoint extends the session of the request, so that long-lived clients such as native apps
+// do not need to sign in again before their session expires. It must be enabled using
+// `session.self_service_extend.enabled`.
+//
+// Sessions are never extended beyond `session.self_service_extend.max_lifetime` after they were authenticated
+// and only if they fulfill `session.self_service_extend.required_aal`. If `session.earliest_possible_extend`
+// is set, the session is only extended once that time before its expiry has been reached. The response
+// contains the session with its (possibly unchanged) expiry.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: session
+//	  401: errorGeneric
+//	  403: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) extendMySession(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	c := h.r.Config()
+	if !c.SessionSelfServiceExtendEnabled(ctx) {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("Extending sessions is disabled.")))
+		return
+	}
+
+	s, err := h.r.SessionManager().FetchFromRequest(ctx, r)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if err := h.r.SessionManager().DoesSessionSatisfy(r, s, c.SessionSelfServiceExtendRequiredAAL(ctx)); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	if s.CanBeRefreshed(ctx, c) && s.RefreshWithin(ctx, c, c.SessionSelfServiceExtendMaxLifetime(ctx)) {
+		if err := h.r.SessionPersister().UpsertSession(ctx, s); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+
+		// Browsers would otherwise drop the cookie when the original expiry is reached.
+		if err := h.r.SessionManager().RefreshCookie(ctx, w, r, s); err != nil {
+			h.r.Writer().WriteError(w, r, err)
+			return
+		}
+	}
+
+	s.Identity = s.Identity.CopyWithoutCredentials()
+	h.r.Writer().Write(w, r, s)
+}
+
 func (h *Handler) IsNotAuthenticated(wrap httprouter.Handle, onAuthenticated httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		if _, err := h.r.SessionManager().FetchFromRequest(r.Context(), r); err != nil {
@@ -976,6 +1246,13 @@ type CodeExchangeResponse struct {
 	// The session token is only issued for API flows, not for Browser flows!
 	Token string `json:"session_token,omitempty"`
 
+	// The Refresh Token
+	//
+	// Only set for API flows if refresh tokens are enabled. The session token then expires shortly, and the
+	// refresh token is exchanged for a new session token and refresh token at `/sessions/token-refresh`.
+	// Each refresh token can only be used once.
+	RefreshToken string `json:"refresh_token,omitempty"`
+
 	// The Session
 	//
 	// The session contains information about the user, the session device, and so on.
@@ -1024,8 +1301,84 @@ func (h *Handler) exchangeCode(w http.ResponseWriter, r *http.Request, _ httprou
 		return
 	}
 
+	refreshToken, err := h.r.SessionManager().IssueRefreshToken(ctx, sess)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, &CodeExchangeResponse{
+		Token:        sess.Token,
+		RefreshToken: refreshToken,
+		Session:      sess,
+	})
+}
+
+// Refresh Session Token Request Body
+//
+// swagger:model refreshSessionTokenBody
+type refreshSessionTokenBody struct {
+	// The refresh token which was issued together with the session token.
+	//
+	// required: true
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Refresh Session Token Parameters
+//
+// swagger:parameters refreshSessionToken
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type refreshSessionToken struct {
+	// in: body
+	// required: true
+	Body refreshSessionTokenBody
+}
+
+// swagger:route POST /sessions/token-refresh frontend refreshSessionToken
+//
+// # Refresh Session Token
+//
+// Exchanges a refresh token for a new session token and refresh token. Each refresh token can only be
+// used once. Using a refresh token a second time revokes its session, as it means that the token was
+// leaked.
+//
+// If the session token is bound to a key, the request must carry a DPoP proof signed with that key.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: successfulCodeExchangeResponse
+//	  400: errorGeneric
+//	  401: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) refreshSessionToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var body refreshSessionTokenBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	} else if body.RefreshToken == "" {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason(`"refresh_token" must be set`)))
+		return
+	}
+
+	sess, refreshToken, err := h.r.SessionManager().ExchangeRefreshToken(r.Context(), r, body.RefreshToken)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
 	h.r.Writer().Write(w, r, &CodeExchangeResponse{
-		Token:   sess.Token,
-		Session: sess,
+		Token:        sess.Token,
+		RefreshToken: refreshToken,
+		Session:      sess.Declassified(),
 	})
 }
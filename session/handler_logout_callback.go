@@ -0,0 +1,164 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/x/jsonx"
+)
+
+const (
+	RouteAdminLogoutCallbacks = "/logout-callbacks"
+	RouteAdminLogoutCallback  = RouteAdminLogoutCallbacks + "/:app_id"
+)
+
+// List of Logout Callbacks
+//
+// swagger:model logoutCallbacks
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type logoutCallbacks []LogoutCallback
+
+// swagger:route GET /admin/logout-callbacks identity listLogoutCallbacks
+//
+// # List Logout Callbacks
+//
+// Lists the back-channel logout callbacks of all applications.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: logoutCallbacks
+//	  default: errorGeneric
+func (h *Handler) adminListLogoutCallbacks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	callbacks, err := h.r.SessionPersister().ListLogoutCallbacks(r.Context())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, callbacks)
+}
+
+// Set Logout Callback Parameters
+//
+// swagger:parameters setLogoutCallback
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type setLogoutCallback struct {
+	// AppID identifies the application.
+	//
+	// required: true
+	// in: path
+	AppID string `json:"app_id"`
+
+	// in: body
+	// required: true
+	Body setLogoutCallbackBody
+}
+
+// Set Logout Callback Request Body
+//
+// swagger:model setLogoutCallbackBody
+type setLogoutCallbackBody struct {
+	// URL receives the logout tokens. It must be an absolute HTTP or HTTPS URL.
+	//
+	// required: true
+	URL string `json:"url"`
+}
+
+// swagger:route PUT /admin/logout-callbacks/{app_id} identity setLogoutCallback
+//
+// # Set Logout Callback
+//
+// Registers the back-channel logout callback of an application or replaces its URL. Whenever a session
+// is revoked or expires, Ory Kratos sends a signed logout token to the callback.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: logoutCallback
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) adminSetLogoutCallback(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var body setLogoutCallbackBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unable to decode the request body: %s", err)))
+		return
+	}
+
+	if u, err := url.Parse(body.URL); err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		h.r.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("The logout callback must be an absolute HTTP or HTTPS URL but got: %s", body.URL)))
+		return
+	}
+
+	c := &LogoutCallback{AppID: ps.ByName("app_id"), URL: body.URL}
+	if err := h.r.SessionPersister().UpsertLogoutCallback(r.Context(), c); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, c)
+}
+
+// Delete Logout Callback Parameters
+//
+// swagger:parameters deleteLogoutCallback
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type deleteLogoutCallback struct {
+	// AppID identifies the application.
+	//
+	// required: true
+	// in: path
+	AppID string `json:"app_id"`
+}
+
+// swagger:route DELETE /admin/logout-callbacks/{app_id} identity deleteLogoutCallback
+//
+// # Delete Logout Callback
+//
+// Removes the back-channel logout callback of an application.
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) adminDeleteLogoutCallback(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	if err := h.r.SessionPersister().DeleteLogoutCallback(r.Context(), ps.ByName("app_id")); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().WriteCode(w, r, http.StatusNoContent, nil)
+}
@@ -239,6 +239,49 @@ func TestSessionWhoAmI(t *testing.T) {
 		assert.Empty(t, res.Header.Get("Ory-Session-Cache-For"))
 	})
 
+	t.Run("case=response shaping", func(t *testing.T) {
+		h, _ := testhelpers.MockSessionCreateHandlerWithIdentityAndAMR(t, reg, createAAL1Identity(t, reg), []identity.CredentialsType{identity.CredentialsTypePassword})
+		r.GET("/set/shaping", h)
+
+		client := testhelpers.NewClientWithCookies(t)
+		testhelpers.MockHydrateCookieClient(t, client, ts.URL+"/set/shaping")
+
+		whoami := func(t *testing.T, query string, code int) string {
+			res, err := client.Get(ts.URL + RouteWhoami + query)
+			require.NoError(t, err)
+			body := x.MustReadAll(res.Body)
+			require.EqualValues(t, code, res.StatusCode, "%s", body)
+			return string(body)
+		}
+
+		t.Run("case=includes everything by default", func(t *testing.T) {
+			body := whoami(t, "", http.StatusOK)
+			assert.True(t, gjson.Get(body, "identity.id").Exists(), body)
+			assert.True(t, gjson.Get(body, "devices").IsArray(), body)
+		})
+
+		t.Run("case=expands only the given objects", func(t *testing.T) {
+			body := whoami(t, "?expand=devices", http.StatusOK)
+			assert.Equal(t, "null", gjson.Get(body, "identity").Raw, body)
+			assert.True(t, gjson.Get(body, "devices").IsArray(), body)
+
+			body = whoami(t, "?expand=", http.StatusOK)
+			assert.Equal(t, "null", gjson.Get(body, "identity").Raw, body)
+			assert.Equal(t, "null", gjson.Get(body, "devices").Raw, body)
+		})
+
+		t.Run("case=returns only the given fields", func(t *testing.T) {
+			body := whoami(t, "?fields=id,authenticator_assurance_level&fields=expires_at", http.StatusOK)
+			assert.ElementsMatch(t, []string{"id", "authenticator_assurance_level", "expires_at"}, gjson.Parse(body).Get("@keys").Value(), body)
+			assert.Equal(t, "aal1", gjson.Get(body, "authenticator_assurance_level").String(), body)
+		})
+
+		t.Run("case=rejects unknown options", func(t *testing.T) {
+			whoami(t, "?expand=credentials", http.StatusBadRequest)
+			whoami(t, "?fields=token", http.StatusBadRequest)
+		})
+	})
+
 	/*
 		t.Run("case=respects AAL config", func(t *testing.T) {
 			conf.MustSet(ctx, config.ViperKeySessionLifespan, "1m")
@@ -819,6 +862,58 @@ func TestHandlerAdminSessionManagement(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("case=should revoke sessions in bulk", func(t *testing.T) {
+		var i *identity.Identity
+		require.NoError(t, faker.FakeData(&i))
+		require.NoError(t, reg.Persister().CreateIdentity(ctx, i))
+
+		sess := make([]Session, 2)
+		for j := range sess {
+			require.NoError(t, faker.FakeData(&sess[j]))
+			sess[j].Identity = i
+			sess[j].Active = true
+			sess[j].ExpiresAt = time.Now().Add(time.Hour)
+			require.NoError(t, reg.SessionPersister().UpsertSession(ctx, &sess[j]))
+		}
+
+		revoke := func(t *testing.T, body string) (*http.Response, []byte) {
+			res, err := ts.Client().Post(ts.URL+"/admin/sessions/revoke", "application/json", strings.NewReader(body))
+			require.NoError(t, err)
+			defer res.Body.Close()
+			raw, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			return res, raw
+		}
+
+		t.Run("case=rejects an empty filter", func(t *testing.T) {
+			res, body := revoke(t, `{}`)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s", body)
+		})
+
+		t.Run("case=rejects an unknown aal", func(t *testing.T) {
+			res, body := revoke(t, `{"identity_id":"`+i.ID.String()+`","aal":"aal9"}`)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, "%s", body)
+		})
+
+		t.Run("case=lists the sessions of the identity", func(t *testing.T) {
+			res, err := ts.Client().Get(ts.URL + "/admin/sessions?active=true&identity_id=" + i.ID.String())
+			require.NoError(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, "2", res.Header.Get("X-Total-Count"))
+		})
+
+		res, body := revoke(t, `{"identity_id":"`+i.ID.String()+`"}`)
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.EqualValues(t, 2, gjson.GetBytes(body, "revoked").Int(), "%s", body)
+
+		for _, s := range sess {
+			actual, err := reg.SessionPersister().GetSession(ctx, s.ID, ExpandNothing)
+			require.NoError(t, err)
+			assert.False(t, actual.Active)
+		}
+	})
 }
 
 func TestHandlerSelfServiceSessionManagement(t *testing.T) {
@@ -1076,3 +1171,69 @@ func (s byAuthenticatedAt) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 func (s byAuthenticatedAt) Less(i, j int) bool {
 	return s[i].AuthenticatedAt.Before(s[j].AuthenticatedAt)
 }
+
+func TestHandlerExtendMySession(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	publicServer, _, _, _ := testhelpers.NewKratosServerWithCSRFAndRouters(t, reg)
+
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/identity.schema.json")
+	conf.MustSet(ctx, config.ViperKeySessionLifespan, "1h")
+	conf.MustSet(ctx, config.ViperKeySessionRefreshMinTimeLeft, "1h")
+	conf.MustSet(ctx, config.ViperKeySessionSelfServiceExtendRequiredAAL, "aal1")
+	conf.MustSet(ctx, config.ViperKeySessionSelfServiceExtendMaxLifetime, "2h")
+
+	newSession := func(t *testing.T, authenticatedAt time.Time) *Session {
+		i := identity.NewIdentity("")
+		require.NoError(t, reg.IdentityManager().Create(ctx, i))
+		s, err := NewActiveSession(testhelpers.NewTestHTTPRequest(t, "GET", "/", nil), i, conf, authenticatedAt, identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1)
+		require.NoError(t, err)
+		s.ExpiresAt = time.Now().Add(5 * time.Minute).UTC()
+		require.NoError(t, reg.SessionPersister().UpsertSession(ctx, s))
+		return s
+	}
+
+	extend := func(t *testing.T, s *Session) (*http.Response, []byte) {
+		req, err := http.NewRequest("PATCH", publicServer.URL+"/sessions/whoami/extend", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Session-Token", s.Token)
+		res, err := publicServer.Client().Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		return res, ioutilx.MustReadAll(res.Body)
+	}
+
+	t.Run("case=disabled", func(t *testing.T) {
+		res, body := extend(t, newSession(t, time.Now()))
+		assert.Equal(t, http.StatusNotFound, res.StatusCode, "%s", body)
+	})
+
+	conf.MustSet(ctx, config.ViperKeySessionSelfServiceExtendEnabled, true)
+
+	t.Run("case=extends the session by its lifespan", func(t *testing.T) {
+		s := newSession(t, time.Now())
+		res, body := extend(t, s)
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+		assert.Equal(t, s.ID.String(), gjson.GetBytes(body, "id").String())
+
+		actual, err := reg.SessionPersister().GetSession(ctx, s.ID, ExpandNothing)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), actual.ExpiresAt, time.Minute)
+	})
+
+	t.Run("case=does not extend beyond the maximum lifetime", func(t *testing.T) {
+		authenticatedAt := time.Now().Add(-90 * time.Minute).UTC()
+		s := newSession(t, authenticatedAt)
+		res, body := extend(t, s)
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+
+		actual, err := reg.SessionPersister().GetSession(ctx, s.ID, ExpandNothing)
+		require.NoError(t, err)
+		assert.WithinDuration(t, authenticatedAt.Add(2*time.Hour), actual.ExpiresAt, time.Second)
+	})
+
+	t.Run("case=requires a session", func(t *testing.T) {
+		res, body := extend(t, &Session{Token: "invalid"})
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, "%s", body)
+	})
+}
@@ -0,0 +1,58 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const RouteTokenizerJWKS = "/.well-known/ory/tokenizer/jwks.json"
+
+// JSON Web Key Set of the Session Tokenizer
+//
+// swagger:response tokenizerJsonWebKeySet
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type tokenizerJsonWebKeySetResponse struct {
+	// in: body
+	Body struct {
+		// The public keys which tokenized sessions are signed with.
+		//
+		// required: true
+		Keys []map[string]interface{} `json:"keys"`
+	}
+}
+
+// swagger:route GET /.well-known/ory/tokenizer/jwks.json frontend getTokenizerJsonWebKeySet
+//
+// # Get the Public Keys of the Session Tokenizer
+//
+// This endpoint returns the public keys of all tokenizer templates as a JSON Web Key Set. Use it to
+// verify sessions which were tokenized into JSON Web Tokens by calling `/sessions/whoami?tokenize_as=...`,
+// for example in an API gateway, without calling Ory Kratos for every request.
+//
+// Symmetric keys are never returned.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Responses:
+//	  200: tokenizerJsonWebKeySet
+//	  default: errorGeneric
+func (h *Handler) tokenizerJWKS(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	keys, err := h.r.SessionTokenizer().PublicKeys(r.Context())
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	// Gateways usually cache the keys, but should pick up rotated keys quickly.
+	w.Header().Set("Cache-Control", "max-age=300")
+	h.r.Writer().Write(w, r, keys)
+}
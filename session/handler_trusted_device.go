@@ -0,0 +1,181 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"net/http"
+
+	"github.com/gofrs/uuid"
+	"github.com/julienschmidt/httprouter"
+
+	"github.com/ory/herodot"
+)
+
+// List of Trusted Devices
+//
+// swagger:model trustedDevices
+type trustedDevices []TrustedDevice
+
+// List Identity Trusted Devices Parameters
+//
+// swagger:parameters listIdentityTrustedDevices
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listIdentityTrustedDevices struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// List Identity Trusted Devices Response
+//
+// swagger:response listIdentityTrustedDevices
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type listIdentityTrustedDevicesResponse struct {
+	// in: body
+	Body trustedDevices
+}
+
+// swagger:route GET /admin/identities/{id}/trusted-devices identity listIdentityTrustedDevices
+//
+// # List an Identity's Trusted Devices
+//
+// This endpoint returns all devices of the identity which are trusted to skip the second factor
+// and did not expire yet.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: listIdentityTrustedDevices
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) listIdentityTrustedDevices(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	iID, err := uuid.FromString(ps.ByName("id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()).WithDebug("could not parse UUID"))
+		return
+	}
+
+	devices, err := h.r.SessionPersister().ListTrustedDevices(r.Context(), iID)
+	if err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.r.Writer().Write(w, r, trustedDevices(devices))
+}
+
+// Delete Identity Trusted Devices Parameters
+//
+// swagger:parameters deleteIdentityTrustedDevices
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type deleteIdentityTrustedDevices struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+}
+
+// swagger:route DELETE /admin/identities/{id}/trusted-devices identity deleteIdentityTrustedDevices
+//
+// # Revoke all Trusted Devices of an Identity
+//
+// Calling this endpoint revokes all trusted devices of the identity. The next login on any of these
+// devices requires the second factor again.
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  400: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) deleteIdentityTrustedDevices(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	iID, err := uuid.FromString(ps.ByName("id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()).WithDebug("could not parse UUID"))
+		return
+	}
+
+	if err := h.r.SessionPersister().DeleteTrustedDevicesByIdentity(r.Context(), iID); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Delete Identity Trusted Device Parameters
+//
+// swagger:parameters deleteIdentityTrustedDevice
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type deleteIdentityTrustedDevice struct {
+	// ID is the identity's ID.
+	//
+	// required: true
+	// in: path
+	ID string `json:"id"`
+
+	// DeviceID is the ID of the trusted device.
+	//
+	// required: true
+	// in: path
+	DeviceID string `json:"device_id"`
+}
+
+// swagger:route DELETE /admin/identities/{id}/trusted-devices/{device_id} identity deleteIdentityTrustedDevice
+//
+// # Revoke a Trusted Device of an Identity
+//
+// Calling this endpoint revokes the trusted device. The next login on this device requires the
+// second factor again.
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  204: emptyResponse
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *Handler) deleteIdentityTrustedDevice(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	iID, err := uuid.FromString(ps.ByName("id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()).WithDebug("could not parse UUID"))
+		return
+	}
+
+	dID, err := uuid.FromString(ps.ByName("device_id"))
+	if err != nil {
+		h.r.Writer().WriteError(w, r, herodot.ErrBadRequest.WithError(err.Error()).WithDebug("could not parse UUID"))
+		return
+	}
+
+	if err := h.r.SessionPersister().DeleteTrustedDevice(r.Context(), iID, dID); err != nil {
+		h.r.Writer().WriteError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
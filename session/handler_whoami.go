@@ -0,0 +1,109 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+)
+
+// sessionFields are the names of the top-level fields of a session in its JSON representation.
+var sessionFields = func() map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(Session{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" && t.Field(i).IsExported() {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// whoamiShape describes which parts of the session whoami responds with.
+type whoamiShape struct {
+	// expand is nil if all expandable fields are included.
+	expand Expandables
+
+	// fields is empty if all fields are included.
+	fields []string
+}
+
+// queryValues returns the values of the query parameter, which may be repeated or comma-separated.
+func queryValues(r *http.Request, key string) (values []string, ok bool) {
+	raw, ok := r.URL.Query()[key]
+	for _, v := range raw {
+		for _, value := range strings.Split(v, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values, ok
+}
+
+func parseWhoamiShape(r *http.Request) (*whoamiShape, error) {
+	var shape whoamiShape
+
+	if values, ok := queryValues(r, "expand"); ok {
+		shape.expand = Expandables{}
+		for _, v := range values {
+			e, ok := ParseExpandable(v)
+			if !ok {
+				return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Could not parse expand option: %s", v))
+			}
+			shape.expand = append(shape.expand, e)
+		}
+	}
+
+	shape.fields, _ = queryValues(r, "fields")
+	for _, f := range shape.fields {
+		if !sessionFields[f] {
+			return nil, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Unknown session field: %s", f))
+		}
+	}
+
+	return &shape, nil
+}
+
+// apply strips the session down to the shape. The result is either the session itself or an object with
+// the selected fields only.
+func (shape *whoamiShape) apply(s *Session) (interface{}, error) {
+	if shape.expand != nil {
+		if !shape.expand.Has(ExpandSessionIdentity) {
+			s.Identity = nil
+		}
+		if !shape.expand.Has(ExpandSessionDevices) {
+			s.Devices = nil
+		}
+	}
+
+	if len(shape.fields) == 0 {
+		return s, nil
+	}
+
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &all); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	selected := make(map[string]json.RawMessage, len(shape.fields))
+	for _, f := range shape.fields {
+		if v, ok := all[f]; ok {
+			selected[f] = v
+		}
+	}
+	return selected, nil
+}
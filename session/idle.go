@@ -0,0 +1,50 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/pointerx"
+	"github.com/ory/x/sqlxx"
+)
+
+// NewErrSessionIdle creates a new ErrNoActiveSessionFound for a session which was not used for longer
+// than the idle timeout. It carries its own error ID so that clients can tell it apart from other
+// reasons for a missing session.
+func NewErrSessionIdle() *ErrNoActiveSessionFound {
+	e := NewErrNoActiveSessionFound()
+	e.DefaultError = e.DefaultError.WithID(text.ErrIDSessionIdle).WithReason("The session expired because it was not used for too long.")
+	return e
+}
+
+// trackActivity rejects sessions which were idle for longer than the idle timeout and otherwise
+// records that the session was used. The last activity is written at most once per update interval
+// so that frequent requests do not cause a write each.
+func (s *ManagerHTTP) trackActivity(ctx context.Context, sess *Session) error {
+	timeout := s.r.Config().SessionIdleTimeout(ctx)
+	if timeout <= 0 {
+		return nil
+	}
+
+	now := x.Now().UTC()
+	idle := now.Sub(sess.LastActiveAt())
+	if idle > timeout {
+		return errors.WithStack(NewErrSessionIdle())
+	}
+
+	if idle < s.r.Config().SessionIdleUpdateInterval(ctx) {
+		return nil
+	}
+
+	if err := s.r.SessionPersister().UpdateSessionLastActivity(ctx, sess.ID, now); err != nil {
+		return err
+	}
+	sess.LastActivityAt = pointerx.Ptr(sqlxx.NullTime(now))
+	return nil
+}
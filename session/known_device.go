@@ -0,0 +1,56 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type (
+	// KnownDevice is a device and IP address combination which an identity signed in from. It is used
+	// to notify identities about logins from new devices or locations.
+	KnownDevice struct {
+		ID         uuid.UUID `json:"id" faker:"-" db:"id"`
+		IdentityID uuid.UUID `json:"identity_id" faker:"-" db:"identity_id"`
+
+		// Fingerprint identifies the device, it is a hash of the user agent and the device fingerprint
+		// sent by the client.
+		Fingerprint string `json:"fingerprint" faker:"-" db:"fingerprint"`
+		IPAddress   string `json:"ip_address" faker:"ipv4" db:"ip_address"`
+		UserAgent   string `json:"user_agent" faker:"-" db:"user_agent"`
+		Location    string `json:"location" faker:"-" db:"location"`
+
+		// CreatedAt is the time of the first login from the device and IP address.
+		CreatedAt time.Time `json:"created_at" faker:"-" db:"created_at"`
+
+		// UpdatedAt is the time of the latest login from the device and IP address.
+		UpdatedAt time.Time `json:"updated_at" faker:"-" db:"updated_at"`
+
+		NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+	}
+
+	// KnownDeviceMatch describes which parts of a login were seen before.
+	KnownDeviceMatch struct {
+		// Any is true if the identity signed in before at all.
+		Any bool
+
+		// Device is true if the identity signed in from the device before.
+		Device bool
+
+		// IPAddress is true if the identity signed in from the IP address before.
+		IPAddress bool
+	}
+)
+
+func (d KnownDevice) TableName(context.Context) string {
+	return "identity_known_devices"
+}
+
+// IsNew returns true if the identity signed in before, but not from the device or not from the IP address.
+func (m *KnownDeviceMatch) IsNew() bool {
+	return m.Any && (!m.Device || !m.IPAddress)
+}
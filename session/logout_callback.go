@@ -0,0 +1,55 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Logout Callback
+//
+// A logout callback is the back-channel logout URL of an application. Whenever a session is revoked
+// or expires, Ory Kratos sends a signed logout token to the callbacks of all applications, so that
+// they can end their own sessions.
+//
+// swagger:model logoutCallback
+type LogoutCallback struct {
+	ID uuid.UUID `json:"-" faker:"-" db:"id"`
+
+	// AppID identifies the application. It is the audience of the logout tokens sent to the callback.
+	//
+	// required: true
+	AppID string `json:"app_id" db:"app_id"`
+
+	// URL receives the logout tokens.
+	//
+	// required: true
+	URL string `json:"url" db:"url"`
+
+	// CreatedAt is the time at which the callback was registered.
+	CreatedAt time.Time `json:"created_at" faker:"-" db:"created_at"`
+
+	// UpdatedAt is the time at which the callback was last changed.
+	UpdatedAt time.Time `json:"updated_at" faker:"-" db:"updated_at"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (c LogoutCallback) TableName(context.Context) string {
+	return "session_logout_callbacks"
+}
+
+type LogoutCallbackPersister interface {
+	// ListLogoutCallbacks returns the logout callbacks of all applications.
+	ListLogoutCallbacks(ctx context.Context) ([]LogoutCallback, error)
+
+	// UpsertLogoutCallback registers the logout callback of the application or replaces its URL.
+	UpsertLogoutCallback(ctx context.Context, c *LogoutCallback) error
+
+	// DeleteLogoutCallback removes the logout callback of the application or returns sqlcon.ErrNoRows.
+	DeleteLogoutCallback(ctx context.Context, appID string) error
+}
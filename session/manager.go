@@ -130,6 +130,11 @@ type Manager interface {
 	// FetchFromRequest creates an HTTP session using cookies.
 	FetchFromRequest(context.Context, *http.Request) (*Session, error)
 
+	// FetchFromRequestCached works like FetchFromRequest, but looks the session up in the session cache
+	// first. Use it only where a session which another instance changed within the cache TTL is acceptable,
+	// such as in whoami.
+	FetchFromRequestCached(context.Context, *http.Request) (*Session, error)
+
 	// PurgeFromRequest removes an HTTP session.
 	PurgeFromRequest(context.Context, http.ResponseWriter, *http.Request) error
 
@@ -139,6 +144,36 @@ type Manager interface {
 	// SessionAddAuthenticationMethods adds one or more authentication method to the session.
 	SessionAddAuthenticationMethods(ctx context.Context, sid uuid.UUID, methods ...AuthenticationMethod) error
 
+	// IssueReauthenticationHint remembers the identifier and method of the last login in a signed cookie.
+	IssueReauthenticationHint(context.Context, http.ResponseWriter, *http.Request, *ReauthenticationHint) error
+
+	// FetchReauthenticationHint returns the hint stored in the request or nil if none was found or
+	// the hint is no longer valid.
+	FetchReauthenticationHint(context.Context, *http.Request) *ReauthenticationHint
+
+	// IssueTrustedDeviceCookie trusts the browser of the request for the identity of the session, so that
+	// later logins from it may skip the second factor.
+	IssueTrustedDeviceCookie(context.Context, http.ResponseWriter, *http.Request, *Session) error
+
+	// IsTrustedDevice returns true if the request was sent from a browser trusted by the identity.
+	IsTrustedDevice(ctx context.Context, r *http.Request, identityID uuid.UUID) bool
+
+	// BindSessionToRequestKey binds the session token to the key of the request's DPoP proof, if session
+	// token binding is enabled and the request carries a proof.
+	BindSessionToRequestKey(ctx context.Context, r *http.Request, sess *Session) error
+
+	// IssueRefreshToken issues a refresh token for the persisted session if refresh tokens are enabled, and
+	// shortens the lifespan of the session token accordingly. It returns an empty string otherwise.
+	IssueRefreshToken(ctx context.Context, sess *Session) (string, error)
+
+	// ExchangeRefreshToken rotates the refresh token and the token of its session. Exchanging a refresh
+	// token which was used already revokes the session.
+	ExchangeRefreshToken(ctx context.Context, r *http.Request, refreshToken string) (*Session, string, error)
+
+	// LinkUpstreamSession links the persisted session to the session of the upstream identity provider
+	// which completed the flow, so that the provider's back-channel logout revokes it.
+	LinkUpstreamSession(ctx context.Context, f flow.InternalContexter, sess *Session) error
+
 	// MaybeRedirectAPICodeFlow for API+Code flows redirects the user to the return_to URL and adds the code query parameter.
 	// `handled` is true if the request a redirect was written, false otherwise.
 	MaybeRedirectAPICodeFlow(w http.ResponseWriter, r *http.Request, f flow.Flow, sessionID uuid.UUID, uiNode node.UiNodeGroup) (handled bool, err error)
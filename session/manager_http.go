@@ -47,7 +47,9 @@ type (
 		x.CookieProvider
 		x.CSRFProvider
 		x.TracingProvider
+		x.LoggingProvider
 		PersistenceProvider
+		CacheProvider
 		sessiontokenexchange.PersistenceProvider
 	}
 	ManagerHTTP struct {
@@ -105,7 +107,7 @@ func (s *ManagerHTTP) RefreshCookie(ctx context.Context, w http.ResponseWriter,
 	defer otelx.End(span, &err)
 
 	// If it is a session token there is nothing to do.
-	_, cookieErr := r.Cookie(s.cookieName(r.Context()))
+	_, cookieErr := r.Cookie(s.sessionCookieName(r))
 	if errors.Is(cookieErr, http.ErrNoCookie) {
 		return nil
 	}
@@ -129,7 +131,7 @@ func (s *ManagerHTTP) IssueCookie(ctx context.Context, w http.ResponseWriter, r
 	ctx, span := s.r.Tracer(ctx).Tracer().Start(ctx, "sessions.ManagerHTTP.IssueCookie")
 	defer otelx.End(span, &err)
 
-	cookie, err := s.r.CookieManager(r.Context()).Get(r, s.cookieName(ctx))
+	cookie, err := s.r.CookieManager(r.Context()).Get(r, s.sessionCookieName(r))
 	// Fix for https://github.com/ory/kratos/issues/1695
 	if err != nil && cookie == nil {
 		return errors.WithStack(err)
@@ -175,7 +177,8 @@ func (s *ManagerHTTP) IssueCookie(ctx context.Context, w http.ResponseWriter, r
 	cookie.Values["expires_at"] = session.ExpiresAt.UTC().Format(time.RFC3339Nano)
 	cookie.Values["nonce"] = randx.MustString(8, randx.Alpha) // Guarantee new kratos session identifier
 
-	if err := cookie.Save(r, w); err != nil {
+	s.scopeCookie(r, cookie)
+	if err := s.saveCookie(w, r, cookie); err != nil {
 		return errors.WithStack(err)
 	}
 	return nil
@@ -195,7 +198,7 @@ func getCookieExpiry(s *sessions.Session) *time.Time {
 }
 
 func (s *ManagerHTTP) getCookie(r *http.Request) (*sessions.Session, error) {
-	return s.r.CookieManager(r.Context()).Get(r, s.cookieName(r.Context()))
+	return s.r.CookieManager(r.Context()).Get(r, s.sessionCookieName(r))
 }
 
 func (s *ManagerHTTP) extractToken(r *http.Request) string {
@@ -222,6 +225,14 @@ func (s *ManagerHTTP) extractToken(r *http.Request) string {
 }
 
 func (s *ManagerHTTP) FetchFromRequest(ctx context.Context, r *http.Request) (_ *Session, err error) {
+	return s.fetchFromRequest(ctx, r, false)
+}
+
+func (s *ManagerHTTP) FetchFromRequestCached(ctx context.Context, r *http.Request) (_ *Session, err error) {
+	return s.fetchFromRequest(ctx, r, true)
+}
+
+func (s *ManagerHTTP) fetchFromRequest(ctx context.Context, r *http.Request, cached bool) (_ *Session, err error) {
 	ctx, span := s.r.Tracer(ctx).Tracer().Start(ctx, "sessions.ManagerHTTP.FetchFromRequest")
 	defer func() {
 		if e := new(ErrNoActiveSessionFound); errors.As(err, &e) {
@@ -236,12 +247,20 @@ func (s *ManagerHTTP) FetchFromRequest(ctx context.Context, r *http.Request) (_
 		return nil, errors.WithStack(NewErrNoCredentialsForSession())
 	}
 
-	se, err := s.r.SessionPersister().GetSessionByToken(ctx, token, ExpandEverything, identity.ExpandDefault)
-	if err != nil {
-		if errors.Is(err, herodot.ErrNotFound) || errors.Is(err, sqlcon.ErrNoRows) {
-			return nil, errors.WithStack(NewErrNoActiveSessionFound())
+	var se *Session
+	if cached {
+		se = s.r.SessionCache().Get(ctx, token)
+	}
+	fromCache := se != nil
+
+	if !fromCache {
+		se, err = s.r.SessionPersister().GetSessionByToken(ctx, token, ExpandEverything, identity.ExpandDefault)
+		if err != nil {
+			if errors.Is(err, herodot.ErrNotFound) || errors.Is(err, sqlcon.ErrNoRows) {
+				return nil, errors.WithStack(NewErrNoActiveSessionFound())
+			}
+			return nil, err
 		}
-		return nil, err
 	}
 
 	trace.SpanFromContext(ctx).AddEvent(events.NewSessionChecked(ctx, se.ID, se.IdentityID))
@@ -250,6 +269,25 @@ func (s *ManagerHTTP) FetchFromRequest(ctx context.Context, r *http.Request) (_
 		return nil, errors.WithStack(NewErrNoActiveSessionFound())
 	}
 
+	if err := s.verifyTokenExpiry(se); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyTokenBinding(ctx, r, se); err != nil {
+		return nil, err
+	}
+
+	lastActivityAt := se.LastActivityAt
+	if err := s.trackActivity(ctx, se); err != nil {
+		return nil, err
+	}
+
+	// Cache the session again if its last activity changed, as the cached one would be written over and
+	// over again otherwise.
+	if cached && (!fromCache || se.LastActivityAt != lastActivityAt) {
+		s.r.SessionCache().Set(ctx, token, se)
+	}
+
 	return se, nil
 }
 
@@ -261,7 +299,7 @@ func (s *ManagerHTTP) PurgeFromRequest(ctx context.Context, w http.ResponseWrite
 		return errors.WithStack(s.r.SessionPersister().RevokeSessionByToken(ctx, token))
 	}
 
-	cookie, _ := s.r.CookieManager(r.Context()).Get(r, s.cookieName(ctx))
+	cookie, _ := s.r.CookieManager(r.Context()).Get(r, s.sessionCookieName(r))
 	token, ok := cookie.Values["session_token"].(string)
 	if !ok {
 		return nil
@@ -272,12 +310,118 @@ func (s *ManagerHTTP) PurgeFromRequest(ctx context.Context, w http.ResponseWrite
 	}
 
 	cookie.Options.MaxAge = -1
-	if err := cookie.Save(r, w); err != nil {
+	s.scopeCookie(r, cookie)
+	if err := s.saveCookie(w, r, cookie); err != nil {
 		return errors.WithStack(err)
 	}
+
+	// Logging out explicitly means the user does not want to be remembered.
+	if err := x.SessionUnset(w, r, s.r.ContinuityCookieManager(ctx), ReauthenticationHintCookieName); err != nil {
+		return err
+	}
 	return nil
 }
 
+func (s *ManagerHTTP) IssueReauthenticationHint(ctx context.Context, w http.ResponseWriter, r *http.Request, hint *ReauthenticationHint) (err error) {
+	ctx, span := s.r.Tracer(ctx).Tracer().Start(ctx, "sessions.ManagerHTTP.IssueReauthenticationHint")
+	defer otelx.End(span, &err)
+
+	if hint == nil || !s.r.Config().SelfServiceFlowLoginSoftReauthenticationEnabled(ctx) {
+		return nil
+	}
+
+	cookie, _ := s.r.ContinuityCookieManager(ctx).Get(r, ReauthenticationHintCookieName)
+	cookie.Options.MaxAge = int(time.Until(hint.SessionExpiresAt.Add(s.r.Config().SelfServiceFlowLoginSoftReauthenticationLifespan(ctx))).Seconds())
+	cookie.Values["identifier"] = hint.Identifier
+	cookie.Values["method"] = hint.Method.String()
+	cookie.Values["session_expires_at"] = hint.SessionExpiresAt.UTC().Format(time.RFC3339Nano)
+
+	return errors.WithStack(cookie.Save(r, w))
+}
+
+func (s *ManagerHTTP) FetchReauthenticationHint(ctx context.Context, r *http.Request) *ReauthenticationHint {
+	if !s.r.Config().SelfServiceFlowLoginSoftReauthenticationEnabled(ctx) {
+		return nil
+	}
+
+	store := s.r.ContinuityCookieManager(ctx)
+	expiresAt, err := time.Parse(time.RFC3339Nano, x.SessionGetStringOr(r, store, ReauthenticationHintCookieName, "session_expires_at", ""))
+	if err != nil {
+		return nil
+	}
+
+	hint := &ReauthenticationHint{
+		Identifier:       x.SessionGetStringOr(r, store, ReauthenticationHintCookieName, "identifier", ""),
+		Method:           identity.CredentialsType(x.SessionGetStringOr(r, store, ReauthenticationHintCookieName, "method", "")),
+		SessionExpiresAt: expiresAt,
+	}
+	if !hint.IsValid(s.r.Config().SelfServiceFlowLoginSoftReauthenticationLifespan(ctx)) {
+		return nil
+	}
+
+	return hint
+}
+
+func (s *ManagerHTTP) IssueTrustedDeviceCookie(ctx context.Context, w http.ResponseWriter, r *http.Request, sess *Session) (err error) {
+	ctx, span := s.r.Tracer(ctx).Tracer().Start(ctx, "sessions.ManagerHTTP.IssueTrustedDeviceCookie")
+	defer otelx.End(span, &err)
+
+	if !s.r.Config().SelfServiceTrustedDeviceEnabled(ctx) {
+		return nil
+	}
+
+	lifespan := s.r.Config().SelfServiceTrustedDeviceLifespan(ctx)
+	device := NewTrustedDevice(r, sess.IdentityID, lifespan)
+	if err := s.r.SessionPersister().CreateTrustedDevice(ctx, device); err != nil {
+		return err
+	}
+
+	cookie, _ := s.r.ContinuityCookieManager(ctx).Get(r, TrustedDeviceCookieName)
+	cookie.Options.MaxAge = int(lifespan.Seconds())
+	cookie.Values["device_id"] = device.ID.String()
+	cookie.Values["identity_id"] = device.IdentityID.String()
+
+	return errors.WithStack(cookie.Save(r, w))
+}
+
+func (s *ManagerHTTP) LinkUpstreamSession(ctx context.Context, f flow.InternalContexter, sess *Session) (err error) {
+	ctx, span := s.r.Tracer(ctx).Tracer().Start(ctx, "sessions.ManagerHTTP.LinkUpstreamSession")
+	defer otelx.End(span, &err)
+
+	upstream, err := flow.UpstreamSession(f)
+	if err != nil {
+		return errors.WithStack(err)
+	} else if upstream == nil || sess.ID == uuid.Nil {
+		return nil
+	}
+
+	return s.r.SessionPersister().CreateUpstreamSession(ctx, &UpstreamSession{
+		SessionID:         sess.ID,
+		Provider:          upstream.Provider,
+		Subject:           upstream.Subject,
+		UpstreamSessionID: upstream.SessionID,
+	})
+}
+
+func (s *ManagerHTTP) IsTrustedDevice(ctx context.Context, r *http.Request, identityID uuid.UUID) bool {
+	if !s.r.Config().SelfServiceTrustedDeviceEnabled(ctx) {
+		return false
+	}
+
+	store := s.r.ContinuityCookieManager(ctx)
+	if x.ParseUUID(x.SessionGetStringOr(r, store, TrustedDeviceCookieName, "identity_id", "")) != identityID {
+		return false
+	}
+
+	// The device is looked up so that revoked devices are no longer trusted.
+	device, err := s.r.SessionPersister().GetTrustedDevice(ctx, identityID, x.ParseUUID(x.SessionGetStringOr(r, store, TrustedDeviceCookieName, "device_id", "")))
+	if err != nil {
+		return false
+	}
+
+	return device.IsActive()
+}
+
 func (s *ManagerHTTP) DoesSessionSatisfy(r *http.Request, sess *Session, requestedAAL string, opts ...ManagerOptions) (err error) {
 	ctx, span := s.r.Tracer(r.Context()).Tracer().Start(r.Context(), "sessions.ManagerHTTP.DoesSessionSatisfy")
 	defer otelx.End(span, &err)
@@ -293,6 +437,11 @@ func (s *ManagerHTTP) DoesSessionSatisfy(r *http.Request, sess *Session, request
 		o(managerOpts)
 	}
 
+	// A risk assessment may have required a second factor regardless of the requested AAL.
+	if sess.RequiredAAL == identity.AuthenticatorAssuranceLevel2 {
+		requestedAAL = config.HighestAvailableAAL
+	}
+
 	sess.SetAuthenticatorAssuranceLevel()
 	switch requestedAAL {
 	case string(identity.AuthenticatorAssuranceLevel1):
@@ -7,12 +7,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/tidwall/gjson"
+
 	"github.com/ory/nosurf"
 	"github.com/ory/x/urlx"
 
@@ -27,6 +31,7 @@ import (
 	"github.com/ory/kratos/internal"
 	"github.com/ory/kratos/internal/testhelpers"
 	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/text"
 	"github.com/ory/kratos/x"
 )
 
@@ -148,6 +153,69 @@ func TestManagerHTTP(t *testing.T) {
 			assert.EqualValues(t, true, actual.HttpOnly)
 			assert.EqualValues(t, true, actual.Secure)
 		})
+
+		t.Run("case=with advanced session cookie customization", func(t *testing.T) {
+			conf.MustSet(ctx, config.ViperKeySessionName, "ory_session")
+			conf.MustSet(ctx, config.ViperKeySessionCookieDomains, []map[string]interface{}{
+				{"domain": "example.org"},
+				{"domain": "shop.example.org", "name": "shop_session"},
+			})
+			conf.MustSet(ctx, config.ViperKeySessionCookieSameSiteOverrides, []map[string]interface{}{
+				{"path": "/self-service", "same_site": "Lax"},
+				{"path": "/self-service/methods/oidc", "same_site": "Strict"},
+			})
+			t.Cleanup(func() {
+				conf.MustSet(ctx, config.ViperKeySessionName, "")
+				conf.MustSet(ctx, config.ViperKeySessionCookieDomains, nil)
+				conf.MustSet(ctx, config.ViperKeySessionCookieSameSiteOverrides, nil)
+			})
+
+			t.Run("case=selects the most specific domain", func(t *testing.T) {
+				actual := getCookie(t, httptest.NewRequest("GET", "https://www.example.org/bar", nil))
+				assert.EqualValues(t, "ory_session", actual.Name)
+				assert.EqualValues(t, "example.org", actual.Domain)
+
+				actual = getCookie(t, httptest.NewRequest("GET", "https://eu.shop.example.org/bar", nil))
+				assert.EqualValues(t, "shop_session", actual.Name)
+				assert.EqualValues(t, "shop.example.org", actual.Domain)
+
+				actual = getCookie(t, httptest.NewRequest("GET", "https://notexample.org/bar", nil))
+				assert.EqualValues(t, "ory_session", actual.Name)
+				assert.EqualValues(t, "session.com", actual.Domain, "falls back to the session domain")
+			})
+
+			t.Run("case=overrides SameSite by path", func(t *testing.T) {
+				assert.EqualValues(t, http.SameSiteNoneMode, getCookie(t, httptest.NewRequest("GET", "https://baseurl.com/bar", nil)).SameSite)
+				assert.EqualValues(t, http.SameSiteLaxMode, getCookie(t, httptest.NewRequest("GET", "https://baseurl.com/self-service/login", nil)).SameSite)
+				assert.EqualValues(t, http.SameSiteStrictMode, getCookie(t, httptest.NewRequest("GET", "https://baseurl.com/self-service/methods/oidc/callback", nil)).SameSite)
+			})
+
+			t.Run("case=enforces the host prefix", func(t *testing.T) {
+				conf.MustSet(ctx, config.ViperKeySessionCookieHostPrefix, true)
+				t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionCookieHostPrefix, false) })
+
+				actual := getCookie(t, httptest.NewRequest("GET", "https://eu.shop.example.org/bar", nil))
+				assert.EqualValues(t, "__Host-shop_session", actual.Name)
+				assert.EqualValues(t, "", actual.Domain)
+				assert.EqualValues(t, "/", actual.Path)
+				assert.EqualValues(t, true, actual.Secure)
+			})
+
+			t.Run("case=marks the cookie as partitioned", func(t *testing.T) {
+				req := httptest.NewRequest("GET", "https://baseurl.com/bar", nil)
+
+				rec := httptest.NewRecorder()
+				require.NoError(t, reg.SessionManager().IssueCookie(ctx, rec, req, s))
+				assert.NotContains(t, rec.Header().Get("Set-Cookie"), "Partitioned")
+
+				conf.MustSet(ctx, config.ViperKeySessionCookiePartitioned, true)
+				t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionCookiePartitioned, false) })
+
+				rec = httptest.NewRecorder()
+				require.NoError(t, reg.SessionManager().IssueCookie(ctx, rec, req, s))
+				assert.True(t, strings.HasSuffix(rec.Header().Get("Set-Cookie"), "; Partitioned"), rec.Header().Get("Set-Cookie"))
+			})
+		})
 	})
 
 	t.Run("suite=SessionAddAuthenticationMethod", func(t *testing.T) {
@@ -361,6 +429,61 @@ func TestManagerHTTP(t *testing.T) {
 			assert.EqualValues(t, http.StatusUnauthorized, res.StatusCode)
 		})
 
+		t.Run("case=idle timeout", func(t *testing.T) {
+			conf.MustSet(ctx, config.ViperKeySessionLifespan, "1h")
+			conf.MustSet(ctx, config.ViperKeySessionIdleTimeout, "10m")
+			conf.MustSet(ctx, config.ViperKeySessionIdleUpdateInterval, "1m")
+			t.Cleanup(func() {
+				conf.MustSet(ctx, config.ViperKeySessionLifespan, "1m")
+				conf.MustSet(ctx, config.ViperKeySessionIdleTimeout, "0s")
+			})
+
+			fetch := func(t *testing.T, authenticatedAt time.Time) (*session.Session, *http.Response, []byte) {
+				req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+				i := identity.Identity{Traits: []byte("{}"), State: identity.StateActive}
+				require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(context.Background(), &i))
+				s, err := session.NewActiveSession(req, &i, conf, authenticatedAt, identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1)
+				require.NoError(t, err)
+				require.NoError(t, reg.SessionPersister().UpsertSession(context.Background(), s))
+
+				req, err = http.NewRequest("GET", pts.URL+"/session/get", nil)
+				require.NoError(t, err)
+				req.Header.Set("X-Session-Token", s.Token)
+				res, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				defer res.Body.Close()
+				body, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+				return s, res, body
+			}
+
+			t.Run("case=rejects idle session", func(t *testing.T) {
+				_, res, body := fetch(t, time.Now().Add(-15*time.Minute))
+				assert.EqualValues(t, http.StatusUnauthorized, res.StatusCode)
+				assert.Equal(t, text.ErrIDSessionIdle, gjson.GetBytes(body, "error.id").String(), "%s", body)
+			})
+
+			t.Run("case=records activity after the update interval", func(t *testing.T) {
+				s, res, body := fetch(t, time.Now().Add(-5*time.Minute))
+				assert.EqualValues(t, http.StatusOK, res.StatusCode, "%s", body)
+
+				actual, err := reg.SessionPersister().GetSession(ctx, s.ID, session.ExpandNothing)
+				require.NoError(t, err)
+				require.NotNil(t, actual.LastActivityAt)
+				assert.WithinDuration(t, time.Now(), time.Time(*actual.LastActivityAt), time.Minute)
+				assert.WithinDuration(t, time.Now(), actual.LastActiveAt(), time.Minute)
+			})
+
+			t.Run("case=does not record activity within the update interval", func(t *testing.T) {
+				s, res, body := fetch(t, time.Now())
+				assert.EqualValues(t, http.StatusOK, res.StatusCode, "%s", body)
+
+				actual, err := reg.SessionPersister().GetSession(ctx, s.ID, session.ExpandNothing)
+				require.NoError(t, err)
+				assert.Nil(t, actual.LastActivityAt)
+			})
+		})
+
 		t.Run("case=respects AAL config", func(t *testing.T) {
 			conf.MustSet(ctx, config.ViperKeySessionLifespan, "1m")
 
@@ -475,6 +598,7 @@ func TestDoesSessionSatisfy(t *testing.T) {
 		d                     string
 		err                   error
 		requested             identity.AuthenticatorAssuranceLevel
+		required              identity.AuthenticatorAssuranceLevel
 		creds                 []identity.Credentials
 		amr                   session.AuthenticationMethods
 		sessionManagerOptions []session.ManagerOptions
@@ -583,6 +707,21 @@ func TestDoesSessionSatisfy(t *testing.T) {
 				require.Equal(t, tcError.(*session.ErrAALNotSatisfied).RedirectTo, err.(*session.ErrAALNotSatisfied).RedirectTo)
 			},
 		},
+		{
+			d:         "has=aal1, requested=aal1, required=aal2, available=aal2, credentials=password+webauthn_mfa",
+			requested: identity.AuthenticatorAssuranceLevel1,
+			required:  identity.AuthenticatorAssuranceLevel2,
+			creds:     []identity.Credentials{password, mfaWebAuth},
+			amr:       session.AuthenticationMethods{amrPassword},
+			err:       new(session.ErrAALNotSatisfied),
+		},
+		{
+			d:         "has=aal2, requested=aal1, required=aal2, available=aal2, credentials=password+webauthn_mfa",
+			requested: identity.AuthenticatorAssuranceLevel1,
+			required:  identity.AuthenticatorAssuranceLevel2,
+			creds:     []identity.Credentials{password, mfaWebAuth},
+			amr:       session.AuthenticationMethods{amrPassword, {Method: identity.CredentialsTypeWebAuthn, AAL: identity.AuthenticatorAssuranceLevel2}},
+		},
 	} {
 		t.Run(fmt.Sprintf("run=%d/desc=%s", k, tc.d), func(t *testing.T) {
 			id := identity.NewIdentity("")
@@ -600,6 +739,7 @@ func TestDoesSessionSatisfy(t *testing.T) {
 				s.CompletedLoginFor(m.Method, m.AAL)
 			}
 			require.NoError(t, s.Activate(req, id, conf, time.Now().UTC()))
+			s.RequiredAAL = tc.required
 
 			err := reg.SessionManager().DoesSessionSatisfy((&http.Request{}).WithContext(context.Background()), s, string(tc.requested), tc.sessionManagerOptions...)
 			if tc.err != nil {
@@ -26,8 +26,8 @@ type Persister interface {
 	// GetSession retrieves a session from the store.
 	GetSession(ctx context.Context, sid uuid.UUID, expandables Expandables) (*Session, error)
 
-	// ListSessions retrieves all sessions.
-	ListSessions(ctx context.Context, active *bool, paginatorOpts []keysetpagination.Option, expandables Expandables) ([]Session, int64, *keysetpagination.Paginator, error)
+	// ListSessions retrieves all sessions which match the filter.
+	ListSessions(ctx context.Context, filter Filter, paginatorOpts []keysetpagination.Option, expandables Expandables) ([]Session, int64, *keysetpagination.Paginator, error)
 
 	// ListSessionsByIdentity retrieves sessions for an identity from the store.
 	ListSessionsByIdentity(ctx context.Context, iID uuid.UUID, active *bool, page, perPage int, except uuid.UUID, expandables Expandables) ([]Session, int64, error)
@@ -50,6 +50,13 @@ type Persister interface {
 	// DeleteExpiredSessions deletes sessions that expired before the given time.
 	DeleteExpiredSessions(context.Context, time.Time, int) error
 
+	// ListExpiredSessionIDs returns the IDs of up to limit sessions that expired before the given time, the
+	// earliest expired first.
+	ListExpiredSessionIDs(ctx context.Context, expiresAt time.Time, limit int) ([]uuid.UUID, error)
+
+	// DeleteExpiredSessionsByID deletes the given sessions unless they were extended past the given time.
+	DeleteExpiredSessionsByID(ctx context.Context, expiresAt time.Time, ids []uuid.UUID) error
+
 	// DeleteSessionByToken deletes a session associated with the given token.
 	//
 	// Functionality is similar to DeleteSession but accepts a session token
@@ -67,6 +74,74 @@ type Persister interface {
 
 	// RevokeSessionsIdentityExcept marks all except the given session of an identity inactive. It returns the number of sessions that were revoked.
 	RevokeSessionsIdentityExcept(ctx context.Context, iID, sID uuid.UUID) (int, error)
+
+	// UpdateSessionLastActivity sets the time the session was last used at.
+	UpdateSessionLastActivity(ctx context.Context, sID uuid.UUID, at time.Time) error
+
+	// RevokeSessions marks all active sessions which match the filter inactive. It returns the number of sessions that were revoked.
+	RevokeSessions(ctx context.Context, filter Filter) (int, error)
+
+	identity.SessionSummarizer
+	TrustedDevicePersister
+	LogoutCallbackPersister
+	RefreshTokenPersister
+	UpstreamSessionPersister
+	KnownDevicePersister
+	DPoPProofPersister
+	UpstreamLogoutTokenPersister
+}
+
+type KnownDevicePersister interface {
+	// MatchKnownDevice returns whether the identity signed in before, from the device, and from the IP address.
+	MatchKnownDevice(ctx context.Context, identityID uuid.UUID, fingerprint, ip string) (*KnownDeviceMatch, error)
+
+	// UpsertKnownDevice records a login from the device and IP address.
+	UpsertKnownDevice(ctx context.Context, d *KnownDevice) error
+}
+
+type RefreshTokenPersister interface {
+	// CreateRefreshToken stores the refresh token and lets the token of its session expire at the given time.
+	CreateRefreshToken(ctx context.Context, t *RefreshToken, sessionTokenExpiresAt time.Time) error
+
+	// GetRefreshToken returns the refresh token, regardless of whether it was used already.
+	GetRefreshToken(ctx context.Context, token string) (*RefreshToken, error)
+
+	// RotateRefreshToken marks the refresh token as used, stores the next refresh token of the session,
+	// and replaces the token of the session. It returns sqlcon.ErrNoRows if the refresh token was used already.
+	RotateRefreshToken(ctx context.Context, usedID uuid.UUID, next *RefreshToken, sessionToken string, sessionTokenExpiresAt time.Time) error
+
+	// DeleteSessionRefreshTokens removes all refresh tokens of the session.
+	DeleteSessionRefreshTokens(ctx context.Context, sessionID uuid.UUID) error
+}
+
+type UpstreamSessionPersister interface {
+	// CreateUpstreamSession links a session to the session of an upstream identity provider.
+	CreateUpstreamSession(ctx context.Context, s *UpstreamSession) error
+
+	// RevokeSessionsByUpstreamSession marks all sessions inactive which were created from the upstream
+	// session. Either the subject or the upstream session ID may be empty, in which case it is not used to
+	// match sessions. It returns the number of sessions that were revoked.
+	RevokeSessionsByUpstreamSession(ctx context.Context, provider, subject, upstreamSessionID string) (int, error)
+}
+
+type TrustedDevicePersister interface {
+	// CreateTrustedDevice stores a device which may skip the second factor.
+	CreateTrustedDevice(ctx context.Context, d *TrustedDevice) error
+
+	// GetTrustedDevice returns a trusted device of the identity.
+	GetTrustedDevice(ctx context.Context, identityID, id uuid.UUID) (*TrustedDevice, error)
+
+	// ListTrustedDevices returns the trusted devices of the identity which did not expire yet.
+	ListTrustedDevices(ctx context.Context, identityID uuid.UUID) ([]TrustedDevice, error)
+
+	// DeleteTrustedDevice revokes a trusted device of the identity.
+	DeleteTrustedDevice(ctx context.Context, identityID, id uuid.UUID) error
+
+	// DeleteTrustedDevicesByIdentity revokes all trusted devices of the identity.
+	DeleteTrustedDevicesByIdentity(ctx context.Context, identityID uuid.UUID) error
+
+	// DeleteExpiredTrustedDevices deletes trusted devices that expired before the given time.
+	DeleteExpiredTrustedDevices(ctx context.Context, olderThan time.Time, limit int) error
 }
 
 type DevicePersister interface {
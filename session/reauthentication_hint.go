@@ -0,0 +1,55 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"time"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/x"
+)
+
+// ReauthenticationHintCookieName is the name of the cookie which remembers the identifier and
+// method of the last successful browser login.
+const ReauthenticationHintCookieName = "ory_kratos_reauthentication_hint"
+
+// ReauthenticationHint remembers who signed in last and how, so that a login flow
+// started after the session expired can skip asking for the identifier.
+type ReauthenticationHint struct {
+	// Identifier is the credentials identifier (e.g. email address) used to sign in.
+	Identifier string `json:"identifier"`
+
+	// Method is the credentials type used to sign in.
+	Method identity.CredentialsType `json:"method"`
+
+	// SessionExpiresAt is the time at which the session the hint was issued for expires.
+	SessionExpiresAt time.Time `json:"session_expires_at"`
+}
+
+// NewReauthenticationHint creates a hint for the given identity and method. It returns nil if the
+// identity has no identifier for the method.
+func NewReauthenticationHint(i *identity.Identity, method identity.CredentialsType, sessionExpiresAt time.Time) *ReauthenticationHint {
+	if i == nil {
+		return nil
+	}
+
+	creds, ok := i.GetCredentials(method)
+	if !ok || len(creds.Identifiers) == 0 {
+		return nil
+	}
+
+	return &ReauthenticationHint{
+		Identifier:       creds.Identifiers[0],
+		Method:           method,
+		SessionExpiresAt: sessionExpiresAt.UTC(),
+	}
+}
+
+// IsValid returns true if the hint has not outlived the session it was issued for by more than lifespan.
+func (h *ReauthenticationHint) IsValid(lifespan time.Duration) bool {
+	return h != nil &&
+		h.Identifier != "" &&
+		h.Method != "" &&
+		x.Now().Before(h.SessionExpiresAt.Add(lifespan))
+}
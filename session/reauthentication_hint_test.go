@@ -0,0 +1,44 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/session"
+)
+
+func TestReauthenticationHint(t *testing.T) {
+	i := identity.NewIdentity("")
+	i.SetCredentials(identity.CredentialsTypePassword, identity.Credentials{
+		Type:        identity.CredentialsTypePassword,
+		Identifiers: []string{"foo@ory.sh"},
+	})
+
+	t.Run("case=returns nil without identifier", func(t *testing.T) {
+		assert.Nil(t, session.NewReauthenticationHint(nil, identity.CredentialsTypePassword, time.Now()))
+		assert.Nil(t, session.NewReauthenticationHint(i, identity.CredentialsTypeWebAuthn, time.Now()))
+	})
+
+	t.Run("case=uses first identifier of method", func(t *testing.T) {
+		hint := session.NewReauthenticationHint(i, identity.CredentialsTypePassword, time.Now())
+		require.NotNil(t, hint)
+		assert.Equal(t, "foo@ory.sh", hint.Identifier)
+		assert.Equal(t, identity.CredentialsTypePassword, hint.Method)
+	})
+
+	t.Run("case=validity depends on lifespan", func(t *testing.T) {
+		hint := session.NewReauthenticationHint(i, identity.CredentialsTypePassword, time.Now().Add(-time.Hour))
+		assert.True(t, hint.IsValid(2*time.Hour))
+		assert.False(t, hint.IsValid(time.Minute))
+
+		var nilHint *session.ReauthenticationHint
+		assert.False(t, nilHint.IsValid(time.Hour))
+	})
+}
@@ -0,0 +1,162 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/pointerx"
+	"github.com/ory/x/randx"
+	"github.com/ory/x/sqlcon"
+	"github.com/ory/x/sqlxx"
+)
+
+// RefreshToken can be exchanged once for a new session token and refresh token of its session. The
+// refresh tokens of a session form a family: using one of them twice revokes the session, as it means
+// that the token was stolen.
+type RefreshToken struct {
+	ID uuid.UUID `json:"-" faker:"-" db:"id"`
+
+	// SessionID is the session the refresh token belongs to.
+	SessionID uuid.UUID `json:"-" faker:"-" db:"session_id"`
+
+	// Token is the HMAC of the refresh token once stored.
+	Token string `json:"-" db:"token"`
+
+	// UsedAt is the time at which the refresh token was exchanged.
+	UsedAt sqlxx.NullTime `json:"-" faker:"-" db:"used_at"`
+
+	// CreatedAt is a helper struct field for gobuffalo.pop.
+	CreatedAt time.Time `json:"-" faker:"-" db:"created_at"`
+
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (t RefreshToken) TableName(context.Context) string {
+	return "session_refresh_tokens"
+}
+
+func newRefreshToken(sessionID uuid.UUID) *RefreshToken {
+	return &RefreshToken{
+		SessionID: sessionID,
+		Token:     x.OryRefreshToken + randx.MustString(32, randx.AlphaNum),
+	}
+}
+
+func errNoActiveSession(reason string) error {
+	noSession := NewErrNoActiveSessionFound()
+	noSession.DefaultError = noSession.DefaultError.WithReason(reason)
+	return errors.WithStack(noSession)
+}
+
+// sessionTokenExpiresAt returns when a session token issued now together with a refresh token expires.
+func (s *ManagerHTTP) sessionTokenExpiresAt(ctx context.Context, sess *Session) time.Time {
+	expiresAt := x.Now().Add(s.r.Config().SessionRefreshTokensSessionTokenLifespan(ctx)).UTC()
+	if sess.ExpiresAt.Before(expiresAt) {
+		return sess.ExpiresAt
+	}
+	return expiresAt
+}
+
+func (s *ManagerHTTP) IssueRefreshToken(ctx context.Context, sess *Session) (_ string, err error) {
+	ctx, span := s.r.Tracer(ctx).Tracer().Start(ctx, "sessions.ManagerHTTP.IssueRefreshToken")
+	defer otelx.End(span, &err)
+
+	if !s.r.Config().SessionRefreshTokensEnabled(ctx) {
+		return "", nil
+	}
+
+	t := newRefreshToken(sess.ID)
+	expiresAt := s.sessionTokenExpiresAt(ctx, sess)
+	if err := s.r.SessionPersister().CreateRefreshToken(ctx, t, expiresAt); err != nil {
+		return "", err
+	}
+
+	sess.TokenExpiresAt = pointerx.Ptr(sqlxx.NullTime(expiresAt))
+	return t.Token, nil
+}
+
+func (s *ManagerHTTP) ExchangeRefreshToken(ctx context.Context, r *http.Request, refreshToken string) (_ *Session, _ string, err error) {
+	ctx, span := s.r.Tracer(ctx).Tracer().Start(ctx, "sessions.ManagerHTTP.ExchangeRefreshToken")
+	defer otelx.End(span, &err)
+
+	if !s.r.Config().SessionRefreshTokensEnabled(ctx) {
+		return nil, "", errors.WithStack(herodot.ErrNotFound.WithReason("Refresh tokens are disabled."))
+	}
+
+	used, err := s.r.SessionPersister().GetRefreshToken(ctx, refreshToken)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, "", errNoActiveSession("The refresh token is invalid.")
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	if used.UsedAt.Valid {
+		return nil, "", s.revokeRefreshTokenFamily(ctx, used)
+	}
+
+	sess, err := s.r.SessionPersister().GetSession(ctx, used.SessionID, ExpandEverything)
+	if errors.Is(err, sqlcon.ErrNoRows) {
+		return nil, "", errNoActiveSession("The refresh token is invalid.")
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	if !sess.IsActive() {
+		return nil, "", errNoActiveSession("The session of the refresh token is no longer active.")
+	}
+
+	if err := s.verifyTokenBinding(ctx, r, sess); err != nil {
+		return nil, "", err
+	}
+
+	next := newRefreshToken(sess.ID)
+	token := x.OrySessionToken + randx.MustString(32, randx.AlphaNum)
+	expiresAt := s.sessionTokenExpiresAt(ctx, sess)
+	if err := s.r.SessionPersister().RotateRefreshToken(ctx, used.ID, next, token, expiresAt); errors.Is(err, sqlcon.ErrNoRows) {
+		// Another request exchanged the refresh token in the meantime.
+		return nil, "", s.revokeRefreshTokenFamily(ctx, used)
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	sess.Token = token
+	sess.TokenExpiresAt = pointerx.Ptr(sqlxx.NullTime(expiresAt))
+	return sess, next.Token, nil
+}
+
+// revokeRefreshTokenFamily revokes the session of a refresh token which was used twice, and returns the
+// error to respond with.
+func (s *ManagerHTTP) revokeRefreshTokenFamily(ctx context.Context, used *RefreshToken) error {
+	s.r.Logger().
+		WithField("session_id", used.SessionID).
+		Warn("A refresh token was used more than once, revoking its session.")
+
+	if err := s.r.SessionPersister().RevokeSessionById(ctx, used.SessionID); err != nil && !errors.Is(err, sqlcon.ErrNoRows) {
+		return err
+	}
+	if err := s.r.SessionPersister().DeleteSessionRefreshTokens(ctx, used.SessionID); err != nil {
+		return err
+	}
+	return errNoActiveSession("The refresh token was used already. The session has been revoked.")
+}
+
+// verifyTokenExpiry rejects session tokens which were issued together with a refresh token and expired.
+func (s *ManagerHTTP) verifyTokenExpiry(sess *Session) error {
+	if sess.TokenExpiresAt == nil || time.Time(*sess.TokenExpiresAt).After(x.Now()) {
+		return nil
+	}
+	return errNoActiveSession("The session token expired. Exchange the refresh token for a new one.")
+}
@@ -0,0 +1,161 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/session"
+)
+
+func TestRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/identity.schema.json")
+	conf.MustSet(ctx, config.ViperKeySessionRefreshTokensEnabled, true)
+	conf.MustSet(ctx, config.ViperKeySessionLifespan, "1h")
+
+	newSession := func(t *testing.T) (*session.Session, string) {
+		i := &identity.Identity{Traits: []byte("{}"), State: identity.StateActive}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+
+		req := testhelpers.NewTestHTTPRequest(t, "POST", "/self-service/login", nil)
+		sess := session.NewInactiveSession()
+		require.NoError(t, sess.Activate(req, i, conf, time.Now()))
+		require.NoError(t, reg.SessionPersister().UpsertSession(ctx, sess))
+
+		refreshToken, err := reg.SessionManager().IssueRefreshToken(ctx, sess)
+		require.NoError(t, err)
+		require.NotEmpty(t, refreshToken)
+		return sess, refreshToken
+	}
+
+	fetch := func(t *testing.T, token string) (*session.Session, error) {
+		req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+		req.Header.Set("X-Session-Token", token)
+		return reg.SessionManager().FetchFromRequest(ctx, req)
+	}
+
+	exchange := func(t *testing.T, refreshToken string) (*session.Session, string, error) {
+		req := testhelpers.NewTestHTTPRequest(t, "POST", session.RouteRefreshSessionToken, nil)
+		return reg.SessionManager().ExchangeRefreshToken(ctx, req, refreshToken)
+	}
+
+	t.Run("case=does not issue refresh tokens if disabled", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySessionRefreshTokensEnabled, false)
+		t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionRefreshTokensEnabled, true) })
+
+		sess := &session.Session{ExpiresAt: time.Now().Add(time.Hour)}
+		refreshToken, err := reg.SessionManager().IssueRefreshToken(ctx, sess)
+		require.NoError(t, err)
+		assert.Empty(t, refreshToken)
+		assert.Nil(t, sess.TokenExpiresAt)
+	})
+
+	t.Run("case=shortens the lifespan of the session token", func(t *testing.T) {
+		sess, _ := newSession(t)
+		require.NotNil(t, sess.TokenExpiresAt)
+		assert.WithinDuration(t, time.Now().Add(15*time.Minute), time.Time(*sess.TokenExpiresAt), time.Minute)
+
+		actual, err := fetch(t, sess.Token)
+		require.NoError(t, err)
+		assert.Equal(t, sess.ID, actual.ID)
+	})
+
+	t.Run("case=rejects expired session tokens", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySessionRefreshTokensSessionTokenLifespan, "1ms")
+		t.Cleanup(func() { conf.MustSet(ctx, config.ViperKeySessionRefreshTokensSessionTokenLifespan, "15m") })
+
+		sess, refreshToken := newSession(t)
+		time.Sleep(10 * time.Millisecond)
+
+		_, err := fetch(t, sess.Token)
+		require.ErrorAs(t, err, new(*session.ErrNoActiveSessionFound))
+
+		refreshed, _, err := exchange(t, refreshToken)
+		require.NoError(t, err, "expired session tokens can be refreshed")
+		assert.Equal(t, sess.ID, refreshed.ID)
+	})
+
+	t.Run("case=rotates the session token and refresh token", func(t *testing.T) {
+		sess, refreshToken := newSession(t)
+
+		refreshed, nextRefreshToken, err := exchange(t, refreshToken)
+		require.NoError(t, err)
+		assert.Equal(t, sess.ID, refreshed.ID)
+		assert.NotEqual(t, sess.Token, refreshed.Token)
+		assert.NotEqual(t, refreshToken, nextRefreshToken)
+
+		_, err = fetch(t, sess.Token)
+		require.Error(t, err, "the previous session token is no longer valid")
+
+		actual, err := fetch(t, refreshed.Token)
+		require.NoError(t, err)
+		assert.Equal(t, sess.ID, actual.ID)
+
+		_, _, err = exchange(t, nextRefreshToken)
+		require.NoError(t, err)
+	})
+
+	t.Run("case=reusing a refresh token revokes the session", func(t *testing.T) {
+		sess, refreshToken := newSession(t)
+
+		refreshed, nextRefreshToken, err := exchange(t, refreshToken)
+		require.NoError(t, err)
+
+		_, _, err = exchange(t, refreshToken)
+		require.ErrorAs(t, err, new(*session.ErrNoActiveSessionFound))
+
+		_, err = fetch(t, refreshed.Token)
+		require.Error(t, err)
+
+		_, _, err = exchange(t, nextRefreshToken)
+		require.Error(t, err, "the whole family of refresh tokens is revoked")
+
+		actual, err := reg.SessionPersister().GetSession(ctx, sess.ID, session.ExpandNothing)
+		require.NoError(t, err)
+		assert.False(t, actual.Active)
+	})
+
+	t.Run("case=rejects unknown refresh tokens", func(t *testing.T) {
+		_, _, err := exchange(t, "ory_rt_unknown")
+		require.ErrorAs(t, err, new(*session.ErrNoActiveSessionFound))
+	})
+
+	t.Run("case=refreshes the session token over http", func(t *testing.T) {
+		ts, _, _, _ := testhelpers.NewKratosServerWithCSRFAndRouters(t, reg)
+		sess, refreshToken := newSession(t)
+
+		res, err := ts.Client().Post(ts.URL+session.RouteRefreshSessionToken, "application/json", strings.NewReader(`{"refresh_token":"`+refreshToken+`"}`))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, res.StatusCode, "%s", body)
+
+		var actual session.CodeExchangeResponse
+		require.NoError(t, json.Unmarshal(body, &actual))
+		assert.Equal(t, sess.ID, actual.Session.ID)
+		assert.NotEmpty(t, actual.Token)
+		assert.NotEmpty(t, actual.RefreshToken)
+
+		res, err = ts.Client().Post(ts.URL+session.RouteRefreshSessionToken, "application/json", strings.NewReader(`{"refresh_token":"`+refreshToken+`"}`))
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+}
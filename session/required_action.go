@@ -0,0 +1,80 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"net/http"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/text"
+)
+
+// RequiredAction is a setting which must be completed before a session can be used for anything
+// but the settings flow.
+type RequiredAction string
+
+const (
+	RequiredActionChangePassword RequiredAction = "change_password"
+	RequiredActionReviewMFA      RequiredAction = "review_mfa"
+	RequiredActionConfirmProfile RequiredAction = "confirm_profile"
+
+	// RequiredActionSetCredential is completed by setting either a password or a second factor.
+	RequiredActionSetCredential RequiredAction = "set_credential"
+)
+
+// HasRequiredActions returns true if the session is restricted to the settings flow.
+func (s *Session) HasRequiredActions() bool {
+	return len(s.RequiredActions) > 0
+}
+
+// RequireAction adds the action to the session's required actions unless it is required already.
+func (s *Session) RequireAction(action RequiredAction) {
+	for _, a := range s.RequiredActions {
+		if a == string(action) {
+			return
+		}
+	}
+	s.RequiredActions = append(s.RequiredActions, string(action))
+}
+
+// CompleteRequiredAction removes the action from the session's required actions and returns true
+// if it was required.
+func (s *Session) CompleteRequiredAction(action RequiredAction) bool {
+	for k, a := range s.RequiredActions {
+		if a == string(action) {
+			s.RequiredActions = append(s.RequiredActions[:k], s.RequiredActions[k+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// ErrRequiredActionsPending is returned when a session is used before its required actions were
+// completed.
+type ErrRequiredActionsPending struct {
+	*herodot.DefaultError `json:"error"`
+	RedirectTo            string `json:"redirect_browser_to"`
+}
+
+func (e *ErrRequiredActionsPending) EnhanceJSONError() interface{} {
+	return e
+}
+
+// NewErrRequiredActionsPending creates a new ErrRequiredActionsPending.
+func NewErrRequiredActionsPending(redirectTo string, actions []string) *ErrRequiredActionsPending {
+	return &ErrRequiredActionsPending{
+		RedirectTo: redirectTo,
+		DefaultError: &herodot.DefaultError{
+			IDField:     text.ErrIDRequiredActionsPending,
+			StatusField: http.StatusText(http.StatusForbidden),
+			ErrorField:  "Session has pending required actions",
+			ReasonField: "An active session was found but it can only be used to complete the required actions in the settings flow.",
+			CodeField:   http.StatusForbidden,
+			DetailsField: map[string]interface{}{
+				"redirect_browser_to": redirectTo,
+				"required_actions":    actions,
+			},
+		},
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ory/kratos/session"
+)
+
+func TestRequiredActions(t *testing.T) {
+	s := &session.Session{RequiredActions: []string{
+		string(session.RequiredActionChangePassword),
+		string(session.RequiredActionReviewMFA),
+	}}
+	assert.True(t, s.HasRequiredActions())
+
+	assert.False(t, s.CompleteRequiredAction(session.RequiredActionConfirmProfile))
+	assert.True(t, s.CompleteRequiredAction(session.RequiredActionChangePassword))
+	assert.False(t, s.CompleteRequiredAction(session.RequiredActionChangePassword))
+	assert.EqualValues(t, []string{string(session.RequiredActionReviewMFA)}, s.RequiredActions)
+	assert.True(t, s.HasRequiredActions())
+
+	assert.True(t, s.CompleteRequiredAction(session.RequiredActionReviewMFA))
+	assert.False(t, s.HasRequiredActions())
+
+	s.RequireAction(session.RequiredActionSetCredential)
+	s.RequireAction(session.RequiredActionSetCredential)
+	assert.EqualValues(t, []string{string(session.RequiredActionSetCredential)}, s.RequiredActions)
+}
@@ -25,12 +25,27 @@ import (
 	"github.com/ory/herodot"
 	"github.com/ory/kratos/identity"
 	"github.com/ory/x/randx"
+	"github.com/ory/x/sqlxx"
 )
 
-var ErrIdentityDisabled = herodot.ErrUnauthorized.WithError("identity is disabled").WithReason("This account was disabled.")
+var (
+	ErrIdentityDisabled        = herodot.ErrUnauthorized.WithError("identity is disabled").WithReason("This account was disabled.")
+	ErrIdentityPendingApproval = herodot.ErrUnauthorized.WithError("identity is pending approval").WithReason("This account was not approved yet.")
+)
 
 type lifespanProvider interface {
 	SessionLifespan(ctx context.Context) time.Duration
+	SessionMethodLifespan(ctx context.Context, method string) (time.Duration, bool)
+}
+
+type deviceProvider interface {
+	SessionDevicesEnabled(ctx context.Context) bool
+	SessionDevicesLocationHeaders(ctx context.Context) []string
+}
+
+type activationProvider interface {
+	lifespanProvider
+	deviceProvider
 }
 
 type refreshWindowProvider interface {
@@ -105,16 +120,41 @@ type Session struct {
 	// To learn more about these levels please head over to: https://www.ory.sh/kratos/docs/concepts/credentials
 	AuthenticatorAssuranceLevel identity.AuthenticatorAssuranceLevel `faker:"len=4" db:"aal" json:"authenticator_assurance_level"`
 
+	// RequiredAAL is the minimum Authenticator Assurance Level this session must reach before it can be used.
+	// It is set if a risk assessment required a second factor for the login which issued the session.
+	RequiredAAL identity.AuthenticatorAssuranceLevel `faker:"-" db:"required_aal" json:"-"`
+
+	// RequiredActions lists the settings which must be completed before this session can be used
+	// for anything but the settings flow, for example changing the password after a recovery.
+	RequiredActions sqlxx.StringSliceJSONFormat `faker:"-" db:"required_actions" json:"required_actions,omitempty"`
+
+	// BoundKeyThumbprint is the JWK SHA-256 thumbprint of the key this session's token is bound to. Requests
+	// using the token must carry a DPoP proof signed with that key. Empty if the token is not bound.
+	BoundKeyThumbprint string `faker:"-" db:"bound_key_thumbprint" json:"-"`
+
 	// Authentication Method References (AMR)
 	//
 	// A list of authentication methods (e.g. password, oidc, ...) used to issue this session.
 	AMR AuthenticationMethods `db:"authentication_methods" json:"authentication_methods"`
 
+	// Authentication History
+	//
+	// Every authentication which happened on this session, in chronological order. Unlike the
+	// authentication method references, re-authenticating with a method adds a new entry instead of
+	// replacing the previous one.
+	AuthenticationHistory AuthenticationHistory `db:"authentication_history" json:"authentication_history,omitempty" faker:"-"`
+
 	// The Session Issuance Timestamp
 	//
 	// When this session was issued at. Usually equal or close to `authenticated_at`.
 	IssuedAt time.Time `json:"issued_at" db:"issued_at" faker:"time_type"`
 
+	// The Session Last Activity Timestamp
+	//
+	// When this session was last used at. It is only tracked if an idle timeout is configured, and
+	// updated at most once per configured update interval.
+	LastActivityAt *sqlxx.NullTime `json:"last_activity_at,omitempty" db:"last_activity_at" faker:"-"`
+
 	// The Logout Token
 	//
 	// Use this token to log out a user.
@@ -145,11 +185,19 @@ type Session struct {
 	// It is only set when the `tokenize` query parameter was set to a valid tokenize template during calls to `/session/whoami`.
 	Tokenized string `json:"tokenized,omitempty" faker:"-" db:"-"`
 
+	// TokenExpiresAt is when the session token expires if it was issued together with a refresh token.
+	// The refresh token can be exchanged for a new session token as long as the session is active.
+	TokenExpiresAt *sqlxx.NullTime `json:"-" db:"token_expires_at" faker:"-"`
+
 	// The Session Token
 	//
 	// The token of this session.
 	Token string    `json:"-" db:"token"`
 	NID   uuid.UUID `json:"-"  faker:"-" db:"nid"`
+
+	// unattributed is the number of authentications at the end of the history which were added
+	// during the current request and do not have a client IP address yet.
+	unattributed int `json:"-" faker:"-" db:"-"`
 }
 
 func (s Session) PageToken() keysetpagination.PageToken {
@@ -165,8 +213,9 @@ func (s Session) TableName(ctx context.Context) string {
 }
 
 func (s *Session) CompletedLoginForMethod(method AuthenticationMethod) {
-	method.CompletedAt = time.Now().UTC()
+	method.CompletedAt = x.Now().UTC()
 	s.AMR = append(s.AMR, method)
+	s.recordAuthentication(method)
 }
 
 func (s *Session) CompletedLoginFor(method identity.CredentialsType, aal identity.AuthenticatorAssuranceLevel) {
@@ -182,6 +231,53 @@ func (s *Session) CompletedLoginForWithProvider(method identity.CredentialsType,
 	})
 }
 
+// RefreshedLoginForMethod updates the latest use of the method in the authentication method references, or adds
+// the method if the session was not authenticated via it. It is used if only one method of the session was
+// re-authenticated.
+func (s *Session) RefreshedLoginForMethod(method AuthenticationMethod) {
+	if latest := s.LatestAuthenticationMethod(method.Method); latest != nil {
+		method.CompletedAt = x.Now().UTC()
+		*latest = method
+		s.recordAuthentication(method)
+		return
+	}
+	s.CompletedLoginForMethod(method)
+}
+
+func (s *Session) recordAuthentication(method AuthenticationMethod) {
+	s.AuthenticationHistory = append(s.AuthenticationHistory, AuthenticationEvent{
+		Method:       method.Method,
+		AAL:          method.AAL,
+		CompletedAt:  method.CompletedAt,
+		Provider:     method.Provider,
+		Organization: method.Organization,
+	})
+	s.unattributed++
+}
+
+// AttributeAuthentications sets the client IP address of the authentications which were added
+// during the request.
+func (s *Session) AttributeAuthentications(r *http.Request) {
+	ip := httpx.ClientIP(r)
+	for k := len(s.AuthenticationHistory) - s.unattributed; k < len(s.AuthenticationHistory); k++ {
+		if k >= 0 {
+			s.AuthenticationHistory[k].IPAddress = ip
+		}
+	}
+	s.unattributed = 0
+}
+
+// LatestAuthenticationMethod returns the latest use of the method in the authentication method references or nil if
+// the session was not authenticated via the method.
+func (s *Session) LatestAuthenticationMethod(method identity.CredentialsType) *AuthenticationMethod {
+	for k := len(s.AMR) - 1; k >= 0; k-- {
+		if s.AMR[k].Method == method {
+			return &s.AMR[k]
+		}
+	}
+	return nil
+}
+
 func (s *Session) AuthenticatedVia(method identity.CredentialsType) bool {
 	for _, authMethod := range s.AMR {
 		if authMethod.Method == method {
@@ -237,7 +333,7 @@ func (s *Session) SetAuthenticatorAssuranceLevel() {
 	}
 }
 
-func NewActiveSession(r *http.Request, i *identity.Identity, c lifespanProvider, authenticatedAt time.Time, completedLoginFor identity.CredentialsType, completedLoginAAL identity.AuthenticatorAssuranceLevel) (*Session, error) {
+func NewActiveSession(r *http.Request, i *identity.Identity, c activationProvider, authenticatedAt time.Time, completedLoginFor identity.CredentialsType, completedLoginAAL identity.AuthenticatorAssuranceLevel) (*Session, error) {
 	s := NewInactiveSession()
 	s.CompletedLoginFor(completedLoginFor, completedLoginAAL)
 	if err := s.Activate(r, i, c, authenticatedAt); err != nil {
@@ -256,27 +352,35 @@ func NewInactiveSession() *Session {
 	}
 }
 
-func (s *Session) Activate(r *http.Request, i *identity.Identity, c lifespanProvider, authenticatedAt time.Time) error {
-	if i != nil && !i.IsActive() {
+func (s *Session) Activate(r *http.Request, i *identity.Identity, c activationProvider, authenticatedAt time.Time) error {
+	if i != nil && i.State == identity.StatePendingApproval {
+		return ErrIdentityPendingApproval.WithDetail("identity_id", i.ID)
+	} else if i != nil && !i.IsActive() {
 		return ErrIdentityDisabled.WithDetail("identity_id", i.ID)
 	}
 
 	s.Active = true
-	s.ExpiresAt = authenticatedAt.Add(c.SessionLifespan(r.Context()))
+	s.ExpiresAt = authenticatedAt.Add(s.lifespan(r.Context(), c))
 	s.AuthenticatedAt = authenticatedAt
 	s.IssuedAt = authenticatedAt
 	s.Identity = i
 	s.IdentityID = i.ID
 
-	s.SetSessionDeviceInformation(r)
+	if c.SessionDevicesEnabled(r.Context()) {
+		s.SetSessionDeviceInformation(r, c.SessionDevicesLocationHeaders(r.Context()))
+	}
 	s.SetAuthenticatorAssuranceLevel()
+	s.AttributeAuthentications(r)
 	return nil
 }
 
-func (s *Session) SetSessionDeviceInformation(r *http.Request) {
+// SetSessionDeviceInformation records the IP address, user agent, and location of the client of the request.
+// The location is looked up from the given headers, which are usually set by a CDN or reverse proxy.
+func (s *Session) SetSessionDeviceInformation(r *http.Request, locationHeaders []string) {
 	device := Device{
 		SessionID: s.ID,
 		IPAddress: stringsx.GetPointer(httpx.ClientIP(r)),
+		Location:  stringsx.GetPointer(ClientLocation(r, locationHeaders)),
 	}
 
 	agent := r.Header["User-Agent"]
@@ -284,32 +388,90 @@ func (s *Session) SetSessionDeviceInformation(r *http.Request) {
 		device.UserAgent = stringsx.GetPointer(strings.Join(agent, " "))
 	}
 
-	var clientGeoLocation []string
-	if r.Header.Get("Cf-Ipcity") != "" {
-		clientGeoLocation = append(clientGeoLocation, r.Header.Get("Cf-Ipcity"))
-	}
-	if r.Header.Get("Cf-Ipcountry") != "" {
-		clientGeoLocation = append(clientGeoLocation, r.Header.Get("Cf-Ipcountry"))
-	}
-	device.Location = stringsx.GetPointer(strings.Join(clientGeoLocation, ", "))
-
 	s.Devices = append(s.Devices, device)
 }
 
+// ClientLocation joins the values of the given location headers of the request, for example
+// "Berlin, DE" for the headers set by Cloudflare. It returns an empty string if none of the headers is set.
+func ClientLocation(r *http.Request, headers []string) string {
+	var location []string
+	for _, h := range headers {
+		if v := r.Header.Get(h); v != "" {
+			location = append(location, v)
+		}
+	}
+	return strings.Join(location, ", ")
+}
+
 func (s Session) Declassified() *Session {
 	s.Identity = s.Identity.CopyWithoutCredentials()
 	return &s
 }
 
+// LastActiveAt returns when the session was last used or authenticated, whichever happened later.
+func (s *Session) LastActiveAt() time.Time {
+	if s.LastActivityAt != nil && time.Time(*s.LastActivityAt).After(s.AuthenticatedAt) {
+		return time.Time(*s.LastActivityAt)
+	}
+	return s.AuthenticatedAt
+}
+
 func (s *Session) IsActive() bool {
-	return s.Active && s.ExpiresAt.After(time.Now()) && (s.Identity == nil || s.Identity.IsActive())
+	return s.Active && s.ExpiresAt.After(x.Now()) && (s.Identity == nil || s.Identity.IsActive())
+}
+
+// Summarize returns the session summaries of the identities of the sessions.
+func Summarize(sessions []Session) map[uuid.UUID]identity.SessionSummary {
+	summaries := make(map[uuid.UUID]identity.SessionSummary)
+	for k := range sessions {
+		s := &sessions[k]
+		summary := summaries[s.IdentityID]
+		summary.Total++
+		if s.IsActive() {
+			summary.Active++
+		}
+		if at := s.AuthenticatedAt; summary.LastAuthenticatedAt == nil || at.After(*summary.LastAuthenticatedAt) {
+			summary.LastAuthenticatedAt = &at
+		}
+		summaries[s.IdentityID] = summary
+	}
+	return summaries
 }
 
 func (s *Session) Refresh(ctx context.Context, c lifespanProvider) *Session {
-	s.ExpiresAt = time.Now().Add(c.SessionLifespan(ctx)).UTC()
+	s.ExpiresAt = x.Now().Add(s.lifespan(ctx, c)).UTC()
 	return s
 }
 
+// RefreshWithin extends the session like Refresh, but never beyond the maximum lifetime counted from
+// when the session was authenticated, and never shortens it. It returns false if the expiry did not change.
+func (s *Session) RefreshWithin(ctx context.Context, c lifespanProvider, maxLifetime time.Duration) bool {
+	expiresAt := x.Now().Add(s.lifespan(ctx, c)).UTC()
+	if limit := s.AuthenticatedAt.Add(maxLifetime).UTC(); expiresAt.After(limit) {
+		expiresAt = limit
+	}
+	if !expiresAt.After(s.ExpiresAt) {
+		return false
+	}
+	s.ExpiresAt = expiresAt
+	return true
+}
+
+// lifespan returns the shortest lifespan configured for the methods the session was
+// authenticated with, or the default session lifespan if none of them has one.
+func (s *Session) lifespan(ctx context.Context, c lifespanProvider) time.Duration {
+	var lifespan time.Duration
+	for _, m := range s.AMR {
+		if l, ok := c.SessionMethodLifespan(ctx, string(m.Method)); ok && (lifespan == 0 || l < lifespan) {
+			lifespan = l
+		}
+	}
+	if lifespan == 0 {
+		return c.SessionLifespan(ctx)
+	}
+	return lifespan
+}
+
 func (s *Session) MarshalJSON() ([]byte, error) {
 	type ss Session
 	out := ss(*s)
@@ -318,7 +480,7 @@ func (s *Session) MarshalJSON() ([]byte, error) {
 }
 
 func (s *Session) CanBeRefreshed(ctx context.Context, c refreshWindowProvider) bool {
-	return s.ExpiresAt.Add(-c.SessionRefreshMinTimeLeft(ctx)).Before(time.Now())
+	return s.ExpiresAt.Add(-c.SessionRefreshMinTimeLeft(ctx)).Before(x.Now())
 }
 
 // List of (Used) AuthenticationMethods
@@ -391,3 +553,56 @@ func (n AuthenticationMethods) Value() (driver.Value, error) {
 	}
 	return string(value), nil
 }
+
+// Authentication History
+//
+// Every authentication which happened on a session, in chronological order.
+//
+// swagger:model sessionAuthenticationHistory
+type AuthenticationHistory []AuthenticationEvent
+
+// AuthenticationEvent records one authentication on a session.
+//
+// swagger:model sessionAuthenticationEvent
+type AuthenticationEvent struct {
+	// The method used in this authentication.
+	Method identity.CredentialsType `json:"method"`
+
+	// The AAL this authentication introduced.
+	AAL identity.AuthenticatorAssuranceLevel `json:"aal"`
+
+	// When the authentication challenge was completed.
+	CompletedAt time.Time `json:"completed_at"`
+
+	// OIDC or SAML provider id used for authentication
+	Provider string `json:"provider,omitempty"`
+
+	// The Organization id used for authentication
+	Organization string `json:"organization,omitempty"`
+
+	// The IP address of the client which completed the authentication. It is empty if the
+	// authentication was not completed by the client itself, for example when a second factor
+	// was added in the settings flow.
+	IPAddress string `json:"ip_address,omitempty"`
+}
+
+// Scan implements the Scanner interface.
+func (n *AuthenticationHistory) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	v := fmt.Sprintf("%s", value)
+	if len(v) == 0 {
+		return nil
+	}
+	return errors.WithStack(json.Unmarshal([]byte(v), n))
+}
+
+// Value implements the driver Valuer interface.
+func (n AuthenticationHistory) Value() (driver.Value, error) {
+	value, err := json.Marshal(n)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return string(value), nil
+}
@@ -58,6 +58,56 @@ func TestSession(t *testing.T) {
 		assert.EqualValues(t, identity.CredentialsTypeRecoveryCode, s.AMR[2].Method)
 	})
 
+	t.Run("case=refresh one method", func(t *testing.T) {
+		s := session.NewInactiveSession()
+		s.CompletedLoginFor(identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1)
+		s.CompletedLoginFor(identity.CredentialsTypeTOTP, identity.AuthenticatorAssuranceLevel2)
+		s.AMR[0].CompletedAt = time.Now().Add(-time.Hour)
+
+		s.RefreshedLoginForMethod(session.AuthenticationMethod{Method: identity.CredentialsTypePassword, AAL: identity.AuthenticatorAssuranceLevel1})
+		require.Len(t, s.AMR, 2)
+		assert.WithinDuration(t, time.Now(), s.AMR[0].CompletedAt, time.Minute)
+		assert.EqualValues(t, identity.CredentialsTypeTOTP, s.AMR[1].Method)
+
+		s.RefreshedLoginForMethod(session.AuthenticationMethod{Method: identity.CredentialsTypeWebAuthn, AAL: identity.AuthenticatorAssuranceLevel2})
+		require.Len(t, s.AMR, 3)
+		assert.Nil(t, s.LatestAuthenticationMethod(identity.CredentialsTypeLookup))
+		assert.Equal(t, &s.AMR[2], s.LatestAuthenticationMethod(identity.CredentialsTypeWebAuthn))
+	})
+
+	t.Run("case=authentication history", func(t *testing.T) {
+		s := session.NewInactiveSession()
+		s.CompletedLoginFor(identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1)
+
+		req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+		req.Header.Set("X-Real-IP", "54.155.246.155")
+		require.NoError(t, s.Activate(req, &identity.Identity{State: identity.StateActive}, conf, authAt))
+
+		s.RefreshedLoginForMethod(session.AuthenticationMethod{Method: identity.CredentialsTypePassword, AAL: identity.AuthenticatorAssuranceLevel1})
+		s.CompletedLoginFor(identity.CredentialsTypeTOTP, identity.AuthenticatorAssuranceLevel2)
+
+		req.Header.Set("X-Real-IP", "54.155.246.232")
+		require.NoError(t, s.Activate(req, &identity.Identity{State: identity.StateActive}, conf, authAt))
+
+		require.Len(t, s.AMR, 2, "the refresh replaces the method reference")
+		require.Len(t, s.AuthenticationHistory, 3, "the refresh is recorded in the history")
+		for k, expected := range []struct {
+			method identity.CredentialsType
+			aal    identity.AuthenticatorAssuranceLevel
+			ip     string
+		}{
+			{identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1, "54.155.246.155"},
+			{identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1, "54.155.246.232"},
+			{identity.CredentialsTypeTOTP, identity.AuthenticatorAssuranceLevel2, "54.155.246.232"},
+		} {
+			actual := s.AuthenticationHistory[k]
+			assert.Equal(t, expected.method, actual.Method, "%d", k)
+			assert.Equal(t, expected.aal, actual.AAL, "%d", k)
+			assert.Equal(t, expected.ip, actual.IPAddress, "%d", k)
+			assert.WithinDuration(t, time.Now(), actual.CompletedAt, time.Minute, "%d", k)
+		}
+	})
+
 	t.Run("case=activate", func(t *testing.T) {
 		req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
 
@@ -72,6 +122,10 @@ func TestSession(t *testing.T) {
 		assert.False(t, s.Active)
 		assert.Equal(t, identity.NoAuthenticatorAssuranceLevel, s.AuthenticatorAssuranceLevel)
 		assert.Empty(t, s.AuthenticatedAt)
+
+		s = session.NewInactiveSession()
+		require.ErrorIs(t, s.Activate(req, &identity.Identity{State: identity.StatePendingApproval}, conf, authAt), session.ErrIdentityPendingApproval)
+		assert.False(t, s.Active)
 	})
 
 	t.Run("case=client information reverse proxy forward", func(t *testing.T) {
@@ -170,6 +224,37 @@ func TestSession(t *testing.T) {
 		assert.Equal(t, "Munich, Germany", *s.Devices[0].Location)
 	})
 
+	t.Run("case=client location from configured headers", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySessionDevicesLocationHeaders, []string{"CloudFront-Viewer-City", "CloudFront-Viewer-Country"})
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySessionDevicesLocationHeaders, []string{"Cf-Ipcity", "Cf-Ipcountry"})
+		})
+
+		req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+		req.Header.Set("Cf-Ipcity", "Munich")
+		req.Header.Set("CloudFront-Viewer-Country", "DE")
+
+		s := session.NewInactiveSession()
+		require.NoError(t, s.Activate(req, &identity.Identity{State: identity.StateActive}, conf, authAt))
+		require.Len(t, s.Devices, 1)
+		assert.Equal(t, "DE", *s.Devices[0].Location)
+	})
+
+	t.Run("case=client information is not recorded if disabled", func(t *testing.T) {
+		conf.MustSet(ctx, config.ViperKeySessionDevicesEnabled, false)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySessionDevicesEnabled, true)
+		})
+
+		req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+		req.Header.Set("Cf-Ipcity", "Munich")
+
+		s := session.NewInactiveSession()
+		require.NoError(t, s.Activate(req, &identity.Identity{State: identity.StateActive}, conf, authAt))
+		assert.True(t, s.Active)
+		assert.Empty(t, s.Devices)
+	})
+
 	for k, tc := range []struct {
 		d        string
 		methods  []session.AuthenticationMethod
@@ -356,4 +441,44 @@ func TestSession(t *testing.T) {
 		s.ExpiresAt = s.ExpiresAt.Add(-12 * time.Hour)
 		assert.True(t, s.CanBeRefreshed(ctx, conf), "session is refreshable after 12hrs")
 	})
+
+	t.Run("case=method lifespans", func(t *testing.T) {
+		req := testhelpers.NewTestHTTPRequest(t, "GET", "/sessions/whoami", nil)
+
+		conf.MustSet(ctx, config.ViperKeySessionLifespan, "24h")
+		conf.MustSet(ctx, config.ViperKeySessionMethodLifespans, map[string]any{
+			"oidc":          "8h",
+			"password":      "720h",
+			"code_recovery": "15m",
+		})
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySessionLifespan, "1m")
+			conf.MustSet(ctx, config.ViperKeySessionMethodLifespans, nil)
+		})
+		i := &identity.Identity{State: identity.StateActive}
+
+		for _, tc := range []struct {
+			method   identity.CredentialsType
+			expected time.Duration
+		}{
+			{method: identity.CredentialsTypeOIDC, expected: 8 * time.Hour},
+			{method: identity.CredentialsTypePassword, expected: 720 * time.Hour},
+			{method: identity.CredentialsTypeRecoveryCode, expected: 15 * time.Minute},
+			{method: identity.CredentialsTypeWebAuthn, expected: 24 * time.Hour},
+		} {
+			t.Run("method="+string(tc.method), func(t *testing.T) {
+				s, err := session.NewActiveSession(req, i, conf, authAt, tc.method, identity.AuthenticatorAssuranceLevel1)
+				require.NoError(t, err)
+				assert.Equal(t, authAt.Add(tc.expected), s.ExpiresAt)
+			})
+		}
+
+		t.Run("case=shortest lifespan applies", func(t *testing.T) {
+			s, err := session.NewActiveSession(req, i, conf, authAt, identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1)
+			require.NoError(t, err)
+			s.CompletedLoginFor(identity.CredentialsTypeRecoveryCode, identity.AuthenticatorAssuranceLevel1)
+			s.Refresh(ctx, conf)
+			assert.WithinDuration(t, time.Now().Add(15*time.Minute), s.ExpiresAt, time.Minute)
+		})
+	})
 }
@@ -282,7 +282,7 @@ func TestPersister(ctx context.Context, conf *config.Config, p interface {
 			} {
 				t.Run("case=all "+tc.desc, func(t *testing.T) {
 					paginatorOpts := make([]keysetpagination.Option, 0)
-					actual, total, nextPage, err := l.ListSessions(ctx, tc.active, paginatorOpts, session.ExpandEverything)
+					actual, total, nextPage, err := l.ListSessions(ctx, session.Filter{Active: tc.active}, paginatorOpts, session.ExpandEverything)
 					require.NoError(t, err, "%+v", err)
 
 					require.Equal(t, len(tc.expected), len(actual))
@@ -306,7 +306,7 @@ func TestPersister(ctx context.Context, conf *config.Config, p interface {
 
 			t.Run("case=all sessions pagination only one page", func(t *testing.T) {
 				paginatorOpts := make([]keysetpagination.Option, 0)
-				actual, total, page, err := l.ListSessions(ctx, nil, paginatorOpts, session.ExpandEverything)
+				actual, total, page, err := l.ListSessions(ctx, session.Filter{}, paginatorOpts, session.ExpandEverything)
 				require.NoError(t, err)
 
 				require.Equal(t, 6, len(actual))
@@ -319,7 +319,7 @@ func TestPersister(ctx context.Context, conf *config.Config, p interface {
 			t.Run("case=all sessions pagination multiple pages", func(t *testing.T) {
 				paginatorOpts := make([]keysetpagination.Option, 0)
 				paginatorOpts = append(paginatorOpts, keysetpagination.WithSize(3))
-				firstPageItems, total, page1, err := l.ListSessions(ctx, nil, paginatorOpts, session.ExpandEverything)
+				firstPageItems, total, page1, err := l.ListSessions(ctx, session.Filter{}, paginatorOpts, session.ExpandEverything)
 				require.NoError(t, err)
 				require.Equal(t, int64(6), total)
 				assert.Len(t, firstPageItems, 3)
@@ -329,7 +329,7 @@ func TestPersister(ctx context.Context, conf *config.Config, p interface {
 				assert.Equal(t, 3, page1.Size())
 
 				// Validate secondPageItems page
-				secondPageItems, total, page2, err := l.ListSessions(ctx, nil, page1.ToOptions(), session.ExpandEverything)
+				secondPageItems, total, page2, err := l.ListSessions(ctx, session.Filter{}, page1.ToOptions(), session.ExpandEverything)
 				require.NoError(t, err)
 
 				acutalIDs := make([]uuid.UUID, 0)
@@ -497,6 +497,95 @@ func TestPersister(ctx context.Context, conf *config.Config, p interface {
 			}
 		})
 
+		t.Run("method=filter and revoke sessions", func(t *testing.T) {
+			// One identity with an old password session and a recent aal2 session which used OIDC, and another identity with a password session.
+			now := time.Now().UTC().Round(time.Second)
+			sessions := make([]session.Session, 3)
+			for i := range sessions {
+				require.NoError(t, faker.FakeData(&sessions[i]))
+				sessions[i].Active = true
+				sessions[i].ExpiresAt = now.Add(time.Hour)
+				sessions[i].AuthenticatorAssuranceLevel = identity.AuthenticatorAssuranceLevel1
+				sessions[i].AMR = session.AuthenticationMethods{{Method: identity.CredentialsTypePassword, CompletedAt: now}}
+			}
+			require.NoError(t, p.CreateIdentity(ctx, sessions[0].Identity))
+			require.NoError(t, p.CreateIdentity(ctx, sessions[2].Identity))
+			sessions[1].IdentityID, sessions[1].Identity = sessions[0].IdentityID, sessions[0].Identity
+			sessions[0].AuthenticatedAt = now.Add(-48 * time.Hour)
+			sessions[1].AuthenticatedAt = now
+			sessions[1].AuthenticatorAssuranceLevel = identity.AuthenticatorAssuranceLevel2
+			sessions[1].AMR = append(sessions[1].AMR, session.AuthenticationMethod{Method: identity.CredentialsTypeOIDC, CompletedAt: now})
+			sessions[2].AuthenticatedAt = now
+			for i := range sessions {
+				require.NoError(t, p.UpsertSession(ctx, &sessions[i]))
+			}
+
+			for _, tc := range []struct {
+				desc     string
+				filter   session.Filter
+				expected []uuid.UUID
+			}{
+				{
+					desc:     "identity",
+					filter:   session.Filter{IdentityID: sessions[0].IdentityID},
+					expected: []uuid.UUID{sessions[0].ID, sessions[1].ID},
+				},
+				{
+					desc:     "aal",
+					filter:   session.Filter{IdentityID: sessions[0].IdentityID, AAL: identity.AuthenticatorAssuranceLevel2},
+					expected: []uuid.UUID{sessions[1].ID},
+				},
+				{
+					desc:     "authentication method",
+					filter:   session.Filter{IdentityID: sessions[0].IdentityID, AuthenticationMethod: identity.CredentialsTypeOIDC},
+					expected: []uuid.UUID{sessions[1].ID},
+				},
+				{
+					desc:     "authenticated before",
+					filter:   session.Filter{IdentityID: sessions[0].IdentityID, AuthenticatedBefore: now.Add(-time.Hour)},
+					expected: []uuid.UUID{sessions[0].ID},
+				},
+				{
+					desc:     "authenticated after",
+					filter:   session.Filter{IdentityID: sessions[0].IdentityID, AuthenticatedAfter: now.Add(-time.Hour)},
+					expected: []uuid.UUID{sessions[1].ID},
+				},
+			} {
+				t.Run("case=list by "+tc.desc, func(t *testing.T) {
+					actual, total, _, err := l.ListSessions(ctx, tc.filter, nil, session.ExpandNothing)
+					require.NoError(t, err)
+					assert.Equal(t, int64(len(tc.expected)), total)
+
+					actualIDs := make([]uuid.UUID, 0, len(actual))
+					for _, s := range actual {
+						actualIDs = append(actualIDs, s.ID)
+					}
+					assert.ElementsMatch(t, tc.expected, actualIDs)
+				})
+			}
+
+			t.Run("on another network", func(t *testing.T) {
+				_, other := testhelpers.NewNetwork(t, ctx, p)
+				n, err := other.RevokeSessions(ctx, session.Filter{IdentityID: sessions[0].IdentityID})
+				require.NoError(t, err)
+				assert.Equal(t, 0, n)
+			})
+
+			n, err := p.RevokeSessions(ctx, session.Filter{AuthenticationMethod: identity.CredentialsTypeOIDC, IdentityID: sessions[0].IdentityID})
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+
+			n, err = p.RevokeSessions(ctx, session.Filter{IdentityID: sessions[0].IdentityID, AuthenticatedBefore: now.Add(-time.Hour)})
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+
+			for k, expected := range []bool{false, false, true} {
+				actual, err := p.GetSession(ctx, sessions[k].ID, session.ExpandNothing)
+				require.NoError(t, err)
+				assert.Equal(t, expected, actual.Active, "session %d", k)
+			}
+		})
+
 		t.Run("method=revoke specific session for identity", func(t *testing.T) {
 			sessions := make([]session.Session, 2)
 			for i := range sessions {
@@ -537,6 +626,54 @@ func TestPersister(ctx context.Context, conf *config.Config, p interface {
 			}
 		})
 
+		t.Run("case=revoke sessions by upstream session", func(t *testing.T) {
+			sessions := make([]session.Session, 3)
+			for k := range sessions {
+				require.NoError(t, faker.FakeData(&sessions[k]))
+				sessions[k].Active = true
+				require.NoError(t, p.CreateIdentity(ctx, sessions[k].Identity))
+				require.NoError(t, p.UpsertSession(ctx, &sessions[k]))
+			}
+
+			for k, us := range []session.UpstreamSession{
+				{Provider: "provider", Subject: "subject", UpstreamSessionID: "sid-1"},
+				{Provider: "provider", Subject: "subject", UpstreamSessionID: "sid-2"},
+				{Provider: "other", Subject: "subject", UpstreamSessionID: "sid-1"},
+			} {
+				us.SessionID = sessions[k].ID
+				require.NoError(t, p.CreateUpstreamSession(ctx, &us))
+			}
+
+			isActive := func(t *testing.T, id uuid.UUID) bool {
+				actual, err := p.GetSession(ctx, id, session.ExpandNothing)
+				require.NoError(t, err)
+				return actual.Active
+			}
+
+			t.Run("on another network", func(t *testing.T) {
+				_, other := testhelpers.NewNetwork(t, ctx, p)
+				count, err := other.RevokeSessionsByUpstreamSession(ctx, "provider", "subject", "")
+				require.NoError(t, err)
+				assert.Zero(t, count)
+			})
+
+			count, err := p.RevokeSessionsByUpstreamSession(ctx, "provider", "", "sid-1")
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+			assert.False(t, isActive(t, sessions[0].ID))
+			assert.True(t, isActive(t, sessions[1].ID))
+			assert.True(t, isActive(t, sessions[2].ID))
+
+			count, err = p.RevokeSessionsByUpstreamSession(ctx, "provider", "subject", "")
+			require.NoError(t, err)
+			assert.Equal(t, 1, count)
+			assert.False(t, isActive(t, sessions[1].ID))
+			assert.True(t, isActive(t, sessions[2].ID))
+
+			_, err = p.RevokeSessionsByUpstreamSession(ctx, "provider", "", "")
+			require.Error(t, err)
+		})
+
 		t.Run("case=delete session for", func(t *testing.T) {
 			var expected1 session.Session
 			var expected2 session.Session
@@ -566,6 +703,41 @@ func TestPersister(ctx context.Context, conf *config.Config, p interface {
 			require.Error(t, err)
 		})
 
+		t.Run("case=summarize identity sessions", func(t *testing.T) {
+			var active, revoked session.Session
+			require.NoError(t, faker.FakeData(&active))
+			require.NoError(t, p.CreateIdentity(ctx, active.Identity))
+			active.Active = true
+			active.ExpiresAt = time.Now().Add(time.Hour).UTC()
+			active.AuthenticatedAt = time.Now().Add(-time.Hour).UTC().Round(time.Second)
+			require.NoError(t, p.UpsertSession(ctx, &active))
+
+			require.NoError(t, faker.FakeData(&revoked))
+			revoked.Identity = active.Identity
+			revoked.IdentityID = active.IdentityID
+			revoked.Active = false
+			revoked.AuthenticatedAt = time.Now().UTC().Round(time.Second)
+			require.NoError(t, p.UpsertSession(ctx, &revoked))
+
+			without := x.NewUUID()
+			summaries, err := p.SummarizeIdentitySessions(ctx, active.IdentityID, without)
+			require.NoError(t, err)
+			require.Len(t, summaries, 1)
+
+			summary := summaries[active.IdentityID]
+			assert.Equal(t, 2, summary.Total)
+			assert.Equal(t, 1, summary.Active)
+			require.NotNil(t, summary.LastAuthenticatedAt)
+			assert.WithinDuration(t, revoked.AuthenticatedAt, *summary.LastAuthenticatedAt, time.Second)
+
+			t.Run("on another network", func(t *testing.T) {
+				_, other := testhelpers.NewNetwork(t, ctx, p)
+				summaries, err := other.SummarizeIdentitySessions(ctx, active.IdentityID)
+				require.NoError(t, err)
+				assert.Empty(t, summaries)
+			})
+		})
+
 		t.Run("network isolation", func(t *testing.T) {
 			nid1, p := testhelpers.NewNetwork(t, ctx, p)
 			nid2, _ := testhelpers.NewNetwork(t, ctx, p)
@@ -0,0 +1,175 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/sqlcon"
+)
+
+// DPoPHeader is the header which carries the proof of possession of the key a session token is bound to.
+const DPoPHeader = "DPoP"
+
+// dpopProofType is the value of the `typ` header every DPoP proof must have.
+const dpopProofType = "dpop+jwt"
+
+// dpopProofClaims are the claims of a DPoP proof as defined by RFC 9449.
+type dpopProofClaims struct {
+	ID         string `json:"jti"`
+	HTTPMethod string `json:"htm"`
+	HTTPURI    string `json:"htu"`
+	IssuedAt   int64  `json:"iat"`
+}
+
+// errInvalidDPoPProof is returned when a DPoP proof is missing or can not be verified.
+func errInvalidDPoPProof(reason string) error {
+	return errors.WithStack(herodot.ErrBadRequest.WithError("invalid DPoP proof").WithReason(reason))
+}
+
+// VerifyDPoPProof checks the DPoP proof of the request and returns it with the thumbprint of the key
+// which signed it. The proof must be signed with an asymmetric key embedded in its header, match the
+// method and URL of the request, and be issued within maxAge.
+//
+// VerifyDPoPProof does not check for replays. The returned proof expires once it is too old to be
+// accepted, which is how long it has to be remembered to reject replays.
+func VerifyDPoPProof(r *http.Request, maxAge time.Duration) (*DPoPProof, error) {
+	headers := r.Header.Values(DPoPHeader)
+	if len(headers) == 0 {
+		return nil, errInvalidDPoPProof("The request does not contain a DPoP proof.")
+	} else if len(headers) > 1 {
+		return nil, errInvalidDPoPProof("The request must contain exactly one DPoP proof.")
+	}
+
+	jws, err := jose.ParseSigned(headers[0])
+	if err != nil {
+		return nil, errInvalidDPoPProof("The DPoP proof is not a valid JSON Web Signature.")
+	} else if len(jws.Signatures) != 1 {
+		return nil, errInvalidDPoPProof("The DPoP proof must have exactly one signature.")
+	}
+
+	header := jws.Signatures[0].Protected
+	if typ, _ := header.ExtraHeaders[jose.HeaderType].(string); typ != dpopProofType {
+		return nil, errInvalidDPoPProof(`The DPoP proof must have the "typ" header "dpop+jwt".`)
+	}
+
+	key := header.JSONWebKey
+	if key == nil || !key.Valid() || !key.IsPublic() {
+		return nil, errInvalidDPoPProof(`The DPoP proof must contain a public asymmetric key in its "jwk" header.`)
+	}
+
+	payload, err := jws.Verify(key)
+	if err != nil {
+		return nil, errInvalidDPoPProof("The signature of the DPoP proof is invalid.")
+	}
+
+	var claims dpopProofClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errInvalidDPoPProof("The claims of the DPoP proof are invalid.")
+	}
+
+	if claims.ID == "" {
+		return nil, errInvalidDPoPProof(`The DPoP proof must have a "jti" claim.`)
+	} else if len(claims.ID) > 255 {
+		return nil, errInvalidDPoPProof(`The "jti" claim of the DPoP proof must not be longer than 255 characters.`)
+	}
+
+	if claims.HTTPMethod != r.Method {
+		return nil, errInvalidDPoPProof(`The "htm" claim of the DPoP proof does not match the request method.`)
+	}
+
+	if !dpopURIMatches(r, claims.HTTPURI) {
+		return nil, errInvalidDPoPProof(`The "htu" claim of the DPoP proof does not match the request URL.`)
+	}
+
+	issuedAt := time.Unix(claims.IssuedAt, 0)
+	now := x.Now()
+	// Allow a little clock skew for proofs issued by clients whose clock is ahead.
+	if claims.IssuedAt == 0 || issuedAt.After(now.Add(5*time.Second)) || issuedAt.Before(now.Add(-maxAge)) {
+		return nil, errInvalidDPoPProof("The DPoP proof was issued too long ago or in the future.")
+	}
+
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to compute the thumbprint of the DPoP key.").WithWrap(err))
+	}
+
+	return &DPoPProof{
+		KeyThumbprint: base64.RawURLEncoding.EncodeToString(thumbprint),
+		ProofID:       claims.ID,
+		ExpiresAt:     issuedAt.Add(maxAge).UTC(),
+	}, nil
+}
+
+// dpopURIMatches compares the `htu` claim with the request URL, ignoring the query and fragment.
+func dpopURIMatches(r *http.Request, htu string) bool {
+	u := x.RequestURL(r)
+	return strings.EqualFold(strings.TrimRight(htu, "/"), strings.TrimRight(u.Scheme+"://"+u.Host+u.Path, "/"))
+}
+
+// BindSessionToRequestKey binds the token of the session to the key of the request's DPoP proof if
+// session token binding is enabled. Sessions of requests without a DPoP proof are not bound.
+func (s *ManagerHTTP) BindSessionToRequestKey(ctx context.Context, r *http.Request, sess *Session) error {
+	if !s.r.Config().SessionTokenBindingEnabled(ctx) || len(r.Header.Values(DPoPHeader)) == 0 {
+		return nil
+	}
+
+	proof, err := VerifyDPoPProof(r, s.r.Config().SessionTokenBindingProofMaxAge(ctx))
+	if err != nil {
+		return err
+	}
+
+	if sess.BoundKeyThumbprint == proof.KeyThumbprint {
+		// The proof was accepted when the session was fetched from this request.
+		return nil
+	} else if sess.BoundKeyThumbprint != "" {
+		return errInvalidDPoPProof("The DPoP proof was signed with a different key than the one the session is bound to.")
+	}
+
+	if err := s.r.SessionPersister().CreateDPoPProof(ctx, proof); errors.Is(err, sqlcon.ErrUniqueViolation) {
+		return errInvalidDPoPProof("The DPoP proof was already used.")
+	} else if err != nil {
+		return err
+	}
+
+	sess.BoundKeyThumbprint = proof.KeyThumbprint
+	return nil
+}
+
+// verifyTokenBinding makes sure the request proves possession of the key the session is bound to.
+func (s *ManagerHTTP) verifyTokenBinding(ctx context.Context, r *http.Request, sess *Session) error {
+	if sess.BoundKeyThumbprint == "" {
+		return nil
+	}
+
+	proof, err := VerifyDPoPProof(r, s.r.Config().SessionTokenBindingProofMaxAge(ctx))
+	if err == nil && proof.KeyThumbprint == sess.BoundKeyThumbprint {
+		if err = s.r.SessionPersister().CreateDPoPProof(ctx, proof); err == nil {
+			return nil
+		} else if !errors.Is(err, sqlcon.ErrUniqueViolation) {
+			return err
+		}
+		err = errInvalidDPoPProof("The DPoP proof was already used.")
+	}
+
+	reason := "The session token is bound to a key but the request does not prove possession of it."
+	if e := new(herodot.DefaultError); errors.As(err, &e) {
+		reason = e.Reason()
+	}
+
+	noSession := NewErrNoActiveSessionFound()
+	noSession.DefaultError = noSession.DefaultError.WithReason(reason)
+	return errors.WithStack(noSession)
+}
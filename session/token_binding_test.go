@@ -0,0 +1,168 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/session"
+	"github.com/ory/kratos/x"
+)
+
+func TestTokenBinding(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	conf.MustSet(ctx, config.ViperKeySessionTokenBindingEnabled, true)
+	conf.MustSet(ctx, config.ViperKeySessionLifespan, "1h")
+
+	newKey := func(t *testing.T) *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		return key
+	}
+
+	newProof := func(t *testing.T, key *ecdsa.PrivateKey, method, uri string, issuedAt time.Time) string {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, (&jose.SignerOptions{EmbedJWK: true}).WithType("dpop+jwt"))
+		require.NoError(t, err)
+
+		payload, err := json.Marshal(map[string]interface{}{
+			"jti": x.NewUUID().String(),
+			"htm": method,
+			"htu": uri,
+			"iat": issuedAt.Unix(),
+		})
+		require.NoError(t, err)
+
+		jws, err := signer.Sign(payload)
+		require.NoError(t, err)
+		proof, err := jws.CompactSerialize()
+		require.NoError(t, err)
+		return proof
+	}
+
+	newRequest := func(method, uri, proof string) *http.Request {
+		r := httptest.NewRequest(method, uri, nil)
+		if proof != "" {
+			r.Header.Set(session.DPoPHeader, proof)
+		}
+		return r
+	}
+
+	const loginURL = "http://kratos.ory.sh/self-service/login"
+	const whoamiURL = "http://kratos.ory.sh/sessions/whoami"
+
+	t.Run("method=VerifyDPoPProof", func(t *testing.T) {
+		key := newKey(t)
+
+		for _, tc := range []struct {
+			d     string
+			r     *http.Request
+			valid bool
+		}{
+			{d: "valid proof", r: newRequest("POST", loginURL+"?flow=123", newProof(t, key, "POST", loginURL, time.Now())), valid: true},
+			{d: "missing proof", r: newRequest("POST", loginURL, "")},
+			{d: "malformed proof", r: newRequest("POST", loginURL, "not-a-proof")},
+			{d: "wrong method", r: newRequest("POST", loginURL, newProof(t, key, "GET", loginURL, time.Now()))},
+			{d: "wrong url", r: newRequest("POST", loginURL, newProof(t, key, "POST", whoamiURL, time.Now()))},
+			{d: "expired proof", r: newRequest("POST", loginURL, newProof(t, key, "POST", loginURL, time.Now().Add(-time.Hour)))},
+			{d: "proof from the future", r: newRequest("POST", loginURL, newProof(t, key, "POST", loginURL, time.Now().Add(time.Hour)))},
+		} {
+			t.Run("case="+tc.d, func(t *testing.T) {
+				proof, err := session.VerifyDPoPProof(tc.r, time.Minute)
+				if tc.valid {
+					require.NoError(t, err)
+					assert.NotEmpty(t, proof.KeyThumbprint)
+					assert.NotEmpty(t, proof.ProofID)
+				} else {
+					require.Error(t, err)
+				}
+			})
+		}
+	})
+
+	t.Run("method=FetchFromRequest", func(t *testing.T) {
+		key := newKey(t)
+
+		login := newRequest("POST", loginURL, newProof(t, key, "POST", loginURL, time.Now()))
+		i := &identity.Identity{Traits: []byte("{}"), State: identity.StateActive}
+		require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+		sess, err := session.NewActiveSession(login, i, conf, time.Now(), identity.CredentialsTypePassword, identity.AuthenticatorAssuranceLevel1)
+		require.NoError(t, err)
+		require.NoError(t, reg.SessionManager().BindSessionToRequestKey(ctx, login, sess))
+		require.NotEmpty(t, sess.BoundKeyThumbprint)
+		require.NoError(t, reg.SessionPersister().UpsertSession(ctx, sess))
+
+		fetch := func(proof string) (*session.Session, error) {
+			r := newRequest("GET", whoamiURL, proof)
+			r.Header.Set("X-Session-Token", sess.Token)
+			return reg.SessionManager().FetchFromRequest(ctx, r)
+		}
+
+		t.Run("case=accepts a proof signed with the bound key", func(t *testing.T) {
+			actual, err := fetch(newProof(t, key, "GET", whoamiURL, time.Now()))
+			require.NoError(t, err)
+			assert.Equal(t, sess.ID, actual.ID)
+		})
+
+		t.Run("case=rejects a replayed proof", func(t *testing.T) {
+			proof := newProof(t, key, "GET", whoamiURL, time.Now())
+			_, err := fetch(proof)
+			require.NoError(t, err)
+
+			_, err = fetch(proof)
+			assert.ErrorAs(t, err, new(*session.ErrNoActiveSessionFound))
+		})
+
+		t.Run("case=rejects requests without a proof", func(t *testing.T) {
+			_, err := fetch("")
+			assert.ErrorAs(t, err, new(*session.ErrNoActiveSessionFound))
+		})
+
+		t.Run("case=rejects a proof signed with another key", func(t *testing.T) {
+			_, err := fetch(newProof(t, newKey(t), "GET", whoamiURL, time.Now()))
+			assert.ErrorAs(t, err, new(*session.ErrNoActiveSessionFound))
+		})
+	})
+
+	t.Run("method=BindSessionToRequestKey", func(t *testing.T) {
+		t.Run("case=does not bind sessions of requests without a proof", func(t *testing.T) {
+			sess := session.NewInactiveSession()
+			require.NoError(t, reg.SessionManager().BindSessionToRequestKey(ctx, newRequest("POST", loginURL, ""), sess))
+			assert.Empty(t, sess.BoundKeyThumbprint)
+		})
+
+		t.Run("case=rejects invalid proofs", func(t *testing.T) {
+			sess := session.NewInactiveSession()
+			require.Error(t, reg.SessionManager().BindSessionToRequestKey(ctx, newRequest("POST", loginURL, "not-a-proof"), sess))
+			assert.Empty(t, sess.BoundKeyThumbprint)
+		})
+
+		t.Run("case=does not bind sessions if disabled", func(t *testing.T) {
+			conf.MustSet(ctx, config.ViperKeySessionTokenBindingEnabled, false)
+			t.Cleanup(func() {
+				conf.MustSet(ctx, config.ViperKeySessionTokenBindingEnabled, true)
+			})
+
+			sess := session.NewInactiveSession()
+			r := newRequest("POST", loginURL, newProof(t, newKey(t), "POST", loginURL, time.Now()))
+			require.NoError(t, reg.SessionManager().BindSessionToRequestKey(ctx, r, sess))
+			assert.Empty(t, sess.BoundKeyThumbprint)
+		})
+	})
+}
@@ -6,14 +6,18 @@ package session
 import (
 	"context"
 	"encoding/json"
+	"sort"
 	"time"
 
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/ory/kratos/x/events"
 
+	"github.com/go-jose/go-jose/v3"
 	"github.com/gofrs/uuid"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
 
@@ -61,12 +65,7 @@ func (s *Tokenizer) TokenizeSession(ctx context.Context, template string, sessio
 	}
 
 	httpClient := s.r.HTTPClient(ctx)
-	key, err := s.r.Fetcher().ResolveKey(
-		ctx,
-		tpl.JWKSURL,
-		jwksx.WithCacheEnabled(),
-		jwksx.WithCacheTTL(time.Hour),
-		jwksx.WithHTTPClient(httpClient))
+	key, err := s.signingKey(ctx, tpl.JWKSURL)
 	if err != nil {
 		if errors.Is(err, jwksx.ErrUnableToFindKeyID) {
 			return errors.WithStack(herodot.ErrBadRequest.WithReasonf("Could not find key a suitable key for tokenization in the JWKS url."))
@@ -98,6 +97,9 @@ func (s *Tokenizer) TokenizeSession(ctx context.Context, template string, sessio
 		"nbf": now.Unix(),
 		"iat": now.Unix(),
 	}
+	if len(tpl.Audience) > 0 {
+		claims["aud"] = tpl.Audience
+	}
 
 	if mapper := tpl.ClaimsMapperURL; len(mapper) > 0 {
 		jn, err := fetch.FetchContext(ctx, mapper)
@@ -133,6 +135,9 @@ func (s *Tokenizer) TokenizeSession(ctx context.Context, template string, sessio
 		}
 
 		claims["sub"] = session.IdentityID.String()
+		if len(tpl.Audience) > 0 {
+			claims["aud"] = tpl.Audience
+		}
 	}
 
 	var privateKey interface{}
@@ -150,3 +155,66 @@ func (s *Tokenizer) TokenizeSession(ctx context.Context, template string, sessio
 	session.Tokenized = result
 	return nil
 }
+
+// signingKey returns the key which signs the tokens of a template, which is the first key of its JSON Web Key Set.
+func (s *Tokenizer) signingKey(ctx context.Context, jwksURL string) (jwk.Key, error) {
+	return s.r.Fetcher().ResolveKey(
+		ctx,
+		jwksURL,
+		jwksx.WithCacheEnabled(),
+		jwksx.WithCacheTTL(time.Hour),
+		jwksx.WithHTTPClient(s.r.HTTPClient(ctx)))
+}
+
+// PublicKeys returns the public keys which sign the tokens of all tokenizer templates, so that tokenized sessions
+// can be verified without calling Ory Kratos. Symmetric keys are never returned.
+func (s *Tokenizer) PublicKeys(ctx context.Context) (_ *jose.JSONWebKeySet, err error) {
+	ctx, span := s.r.Tracer(ctx).Tracer().Start(ctx, "sessions.Tokenizer.PublicKeys")
+	defer otelx.End(span, &err)
+
+	templates, err := s.r.Config().TokenizeTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sort the templates so that the keys are always returned in the same order.
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{}}
+	seen := map[string]bool{}
+	for _, name := range names {
+		key, err := s.signingKey(ctx, templates[name].JWKSURL)
+		if err != nil {
+			return nil, err
+		}
+		if key.KeyType() == jwa.OctetSeq || seen[key.KeyID()] {
+			continue
+		}
+
+		public, err := key.PublicKey()
+		if err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to derive the public key of tokenizer template \"%s\": %s", name, err))
+		}
+		raw, err := json.Marshal(public)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var converted jose.JSONWebKey
+		if err := json.Unmarshal(raw, &converted); err != nil {
+			return nil, errors.WithStack(herodot.ErrInternalServerError.WithReasonf("Unable to decode the public key of tokenizer template \"%s\": %s", name, err))
+		}
+		if !converted.Valid() {
+			continue
+		}
+
+		seen[key.KeyID()] = true
+		result.Keys = append(result.Keys, converted)
+	}
+
+	return result, nil
+}
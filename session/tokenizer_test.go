@@ -6,7 +6,10 @@ package session_test
 import (
 	"context"
 	_ "embed"
+	"encoding/json"
+	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -17,6 +20,7 @@ import (
 	"github.com/lestrrat-go/jwx/jwk"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
 
 	"github.com/ory/kratos/driver/config"
 	"github.com/ory/kratos/identity"
@@ -118,6 +122,23 @@ func TestTokenizer(t *testing.T) {
 		snapshotx.SnapshotT(t, token.Claims, snapshotx.ExceptPaths("jti"))
 	})
 
+	t.Run("case=with audience", func(t *testing.T) {
+		tid := "es256-audience"
+		conf.MustSet(ctx, config.ViperKeySessionTokenizerTemplates+"."+tid, &config.SessionTokenizeFormat{
+			TTL:             time.Minute,
+			JWKSURL:         "file://stub/jwk.es512.json",
+			ClaimsMapperURL: "file://stub/rs512-template.jsonnet",
+			Audience:        []string{"https://api.example.org"},
+		})
+
+		require.NoError(t, tkn.TokenizeSession(ctx, tid, s))
+		token := validateTokenized(t, s.Tokenized, es512Key)
+
+		aud, err := token.Claims.GetAudience()
+		require.NoError(t, err)
+		assert.EqualValues(t, []string{"https://api.example.org"}, aud)
+	})
+
 	t.Run("case=rs512-with-broken-keyfile", func(t *testing.T) {
 		tid := "rs512-template"
 		setTokenizeConfig(conf, tid, "jwk.es512.broken.json", "file://stub/rs512-template.jsonnet")
@@ -125,3 +146,60 @@ func TestTokenizer(t *testing.T) {
 		require.ErrorIs(t, err, herodot.ErrBadRequest)
 	})
 }
+
+func TestTokenizerPublicKeys(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	tkn := session.NewTokenizer(reg)
+
+	t.Run("case=without templates", func(t *testing.T) {
+		keys, err := tkn.PublicKeys(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, keys.Keys)
+	})
+
+	t.Run("case=returns the public keys of all templates", func(t *testing.T) {
+		setTokenizeConfig(conf, "es256", "jwk.es256.json", "")
+		setTokenizeConfig(conf, "es512", "jwk.es512.json", "")
+		// Templates which share a key only return it once.
+		setTokenizeConfig(conf, "es512-again", "jwk.es512.json", "")
+
+		keys, err := tkn.PublicKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys.Keys, 2)
+
+		for _, key := range keys.Keys {
+			assert.True(t, key.IsPublic())
+			raw, err := json.Marshal(key)
+			require.NoError(t, err)
+			assert.False(t, gjson.GetBytes(raw, "d").Exists(), "%s", raw)
+		}
+	})
+
+	t.Run("case=caches the key sets", func(t *testing.T) {
+		_, reg := internal.NewFastRegistryWithMocks(t)
+		var fetched int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&fetched, 1)
+			_, _ = w.Write(es256Key)
+		}))
+		t.Cleanup(ts.Close)
+
+		reg.Config().MustSet(ctx, config.ViperKeySessionTokenizerTemplates+".remote", &config.SessionTokenizeFormat{
+			TTL:     time.Minute,
+			JWKSURL: ts.URL,
+		})
+
+		tkn := session.NewTokenizer(reg)
+		keys, err := tkn.PublicKeys(ctx)
+		require.NoError(t, err)
+		require.Len(t, keys.Keys, 1)
+
+		// The cache is populated asynchronously.
+		assert.Eventually(t, func() bool {
+			before := atomic.LoadInt32(&fetched)
+			_, err := tkn.PublicKeys(ctx)
+			return err == nil && atomic.LoadInt32(&fetched) == before
+		}, time.Second, 10*time.Millisecond)
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/httpx"
+)
+
+// TrustedDeviceCookieName is the name of the cookie which marks a browser as a trusted device.
+const TrustedDeviceCookieName = "ory_kratos_trusted_device"
+
+// Trusted Device
+//
+// A trusted device is a browser on which the identity completed a second factor and chose to
+// be remembered. Logins from a trusted device skip the second factor until the device expires
+// or is revoked.
+//
+// swagger:model trustedDevice
+type TrustedDevice struct {
+	// ID of the trusted device
+	//
+	// required: true
+	ID uuid.UUID `json:"id" faker:"-" db:"id"`
+
+	// IdentityID is the ID of the identity which trusts the device.
+	//
+	// required: true
+	IdentityID uuid.UUID `json:"identity_id" faker:"-" db:"identity_id"`
+
+	// UserAgent of the browser at the time the device was trusted
+	UserAgent string `json:"user_agent" faker:"-" db:"user_agent"`
+
+	// IPAddress of the browser at the time the device was trusted
+	IPAddress string `json:"ip_address" faker:"ipv4" db:"ip_address"`
+
+	// ExpiresAt is the time at which the device is no longer trusted.
+	//
+	// required: true
+	ExpiresAt time.Time `json:"expires_at" faker:"-" db:"expires_at"`
+
+	// CreatedAt is the time at which the device was trusted.
+	CreatedAt time.Time `json:"created_at" faker:"-" db:"created_at"`
+
+	// UpdatedAt is a helper struct field for gobuffalo.pop.
+	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (d TrustedDevice) TableName(context.Context) string {
+	return "identity_trusted_devices"
+}
+
+// NewTrustedDevice trusts the browser of the request for the given identity.
+func NewTrustedDevice(r *http.Request, identityID uuid.UUID, lifespan time.Duration) *TrustedDevice {
+	return &TrustedDevice{
+		IdentityID: identityID,
+		UserAgent:  strings.Join(r.Header["User-Agent"], " "),
+		IPAddress:  httpx.ClientIP(r),
+		ExpiresAt:  x.Now().UTC().Add(lifespan),
+	}
+}
+
+// IsActive returns true if the device did not expire yet.
+func (d *TrustedDevice) IsActive() bool {
+	return d.ExpiresAt.After(x.Now())
+}
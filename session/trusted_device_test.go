@@ -0,0 +1,87 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/identity"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/session"
+)
+
+func TestTrustedDevice(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/identity.schema.json")
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".trusted_device.enabled", true)
+
+	i := &identity.Identity{Traits: []byte("{}"), State: identity.StateActive}
+	require.NoError(t, reg.PrivilegedIdentityPool().CreateIdentity(ctx, i))
+	sess := &session.Session{IdentityID: i.ID}
+
+	trust := func(t *testing.T) *http.Request {
+		req := httptest.NewRequest("POST", "/self-service/login", nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0")
+		w := httptest.NewRecorder()
+		require.NoError(t, reg.SessionManager().IssueTrustedDeviceCookie(ctx, w, req, sess))
+
+		next := httptest.NewRequest("POST", "/self-service/login", nil)
+		for _, c := range w.Result().Cookies() {
+			next.AddCookie(c)
+		}
+		return next
+	}
+
+	t.Run("case=device is active until it expires", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/self-service/login", nil)
+		assert.True(t, session.NewTrustedDevice(req, i.ID, time.Hour).IsActive())
+		assert.False(t, session.NewTrustedDevice(req, i.ID, -time.Hour).IsActive())
+	})
+
+	t.Run("case=trusts the browser which received the cookie", func(t *testing.T) {
+		req := trust(t)
+		assert.True(t, reg.SessionManager().IsTrustedDevice(ctx, req, i.ID))
+		assert.False(t, reg.SessionManager().IsTrustedDevice(ctx, req, identity.NewIdentity("").ID))
+		assert.False(t, reg.SessionManager().IsTrustedDevice(ctx, httptest.NewRequest("POST", "/self-service/login", nil), i.ID))
+
+		devices, err := reg.SessionPersister().ListTrustedDevices(ctx, i.ID)
+		require.NoError(t, err)
+		require.NotEmpty(t, devices)
+		assert.Equal(t, "Mozilla/5.0", devices[0].UserAgent)
+	})
+
+	t.Run("case=revoked devices are no longer trusted", func(t *testing.T) {
+		req := trust(t)
+		devices, err := reg.SessionPersister().ListTrustedDevices(ctx, i.ID)
+		require.NoError(t, err)
+		require.NotEmpty(t, devices)
+
+		require.NoError(t, reg.SessionPersister().DeleteTrustedDevice(ctx, i.ID, devices[0].ID))
+		assert.False(t, reg.SessionManager().IsTrustedDevice(ctx, req, i.ID))
+
+		req = trust(t)
+		require.NoError(t, reg.SessionPersister().DeleteTrustedDevicesByIdentity(ctx, i.ID))
+		assert.False(t, reg.SessionManager().IsTrustedDevice(ctx, req, i.ID))
+	})
+
+	t.Run("case=does not trust devices if disabled", func(t *testing.T) {
+		req := trust(t)
+		conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".trusted_device.enabled", false)
+		t.Cleanup(func() {
+			conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".trusted_device.enabled", true)
+		})
+
+		assert.False(t, reg.SessionManager().IsTrustedDevice(ctx, req, i.ID))
+	})
+}
@@ -0,0 +1,41 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// UpstreamLogoutToken records a back-channel logout token of an upstream identity provider which was accepted,
+// so that it can not be replayed. It is kept until the token is too old to be accepted anyway.
+type UpstreamLogoutToken struct {
+	ID uuid.UUID `json:"-" faker:"-" db:"id"`
+
+	// Provider is the ID of the identity provider which issued the token.
+	Provider string `json:"-" db:"provider"`
+
+	// TokenID is the `jti` claim of the token.
+	TokenID string `json:"-" db:"jti"`
+
+	// ExpiresAt is the time at which the token is no longer accepted.
+	ExpiresAt time.Time `json:"-" faker:"-" db:"expires_at"`
+
+	CreatedAt time.Time `json:"-" faker:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+
+	NID uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (t UpstreamLogoutToken) TableName(context.Context) string {
+	return "session_upstream_logout_tokens"
+}
+
+type UpstreamLogoutTokenPersister interface {
+	// CreateUpstreamLogoutToken records an accepted logout token. It returns sqlcon.ErrUniqueViolation if the
+	// provider issued a token with the same ID before.
+	CreateUpstreamLogoutToken(ctx context.Context, t *UpstreamLogoutToken) error
+}
@@ -0,0 +1,38 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// UpstreamSession links a session to the session of the upstream identity provider which
+// authenticated it. It allows revoking the session when the provider signals a logout.
+type UpstreamSession struct {
+	ID uuid.UUID `json:"id" faker:"-" db:"id"`
+
+	// SessionID is the ID of the session which was created from the upstream session.
+	SessionID uuid.UUID `json:"session_id" faker:"-" db:"session_id"`
+
+	// Provider is the ID of the identity provider.
+	Provider string `json:"provider" db:"provider"`
+
+	// Subject is the `sub` claim of the upstream identity provider.
+	Subject string `json:"subject" db:"subject"`
+
+	// UpstreamSessionID is the `sid` claim of the upstream identity provider, if the provider
+	// issued one.
+	UpstreamSessionID string `json:"upstream_session_id" db:"upstream_session_id"`
+
+	CreatedAt time.Time `json:"created_at" faker:"-" db:"created_at"`
+	UpdatedAt time.Time `json:"-" faker:"-" db:"updated_at"`
+	NID       uuid.UUID `json:"-" faker:"-" db:"nid"`
+}
+
+func (s UpstreamSession) TableName(context.Context) string {
+	return "session_upstream_sessions"
+}
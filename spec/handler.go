@@ -0,0 +1,300 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package spec
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/schema"
+	"github.com/ory/kratos/selfservice/flow/login"
+	"github.com/ory/kratos/selfservice/flow/recovery"
+	"github.com/ory/kratos/selfservice/flow/registration"
+	"github.com/ory/kratos/selfservice/flow/settings"
+	"github.com/ory/kratos/selfservice/flow/verification"
+	"github.com/ory/kratos/x"
+	"github.com/ory/x/otelx"
+)
+
+const RouteConfigured = "/spec/openapi.json"
+
+type (
+	handlerDependencies interface {
+		config.Provider
+		schema.HandlerProvider
+		schema.IdentityTraitsProvider
+		login.StrategyProvider
+		registration.StrategyProvider
+		settings.StrategyProvider
+		recovery.StrategyProvider
+		verification.StrategyProvider
+		x.TracingProvider
+		x.WriterProvider
+	}
+
+	HandlerProvider interface {
+		SpecHandler() *Handler
+	}
+
+	Handler struct {
+		d handlerDependencies
+	}
+
+	document = map[string]any
+)
+
+func NewHandler(d handlerDependencies) *Handler {
+	return &Handler{d: d}
+}
+
+func (h *Handler) RegisterAdminRoutes(admin *x.RouterAdmin) {
+	admin.GET(RouteConfigured, h.getConfiguredSpec)
+}
+
+// Configured OpenAPI Document
+//
+// swagger:model configuredOpenApiDocument
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type configuredOpenApiDocument = json.RawMessage
+
+// Get Configured OpenAPI Document Response
+//
+// swagger:response getConfiguredOpenApiDocument
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type getConfiguredOpenApiDocumentResponse struct {
+	// in: body
+	Body configuredOpenApiDocument
+}
+
+// swagger:route GET /admin/spec/openapi.json metadata getConfiguredOpenApiDocument
+//
+// # Get the OpenAPI Document of This Deployment
+//
+// This endpoint returns the OpenAPI document of Ory Kratos, reduced to the flows and strategies which are
+// enabled in the current configuration. The identity traits are described by the default identity schema.
+// Use this document to generate API clients which match the deployment.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: getConfiguredOpenApiDocument
+//	  default: errorGeneric
+func (h *Handler) getConfiguredSpec(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	doc, err := h.Configured(r.Context())
+	if err != nil {
+		h.d.Writer().WriteError(w, r, err)
+		return
+	}
+
+	h.d.Writer().Write(w, r, doc)
+}
+
+// Configured returns the OpenAPI document adjusted to the current configuration.
+func (h *Handler) Configured(ctx context.Context) (_ map[string]any, err error) {
+	ctx, span := h.d.Tracer(ctx).Tracer().Start(ctx, "spec.Handler.Configured")
+	defer otelx.End(span, &err)
+
+	var doc document
+	if err := json.Unmarshal(API, &doc); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("Unable to decode the embedded OpenAPI document.").WithWrap(err))
+	}
+
+	c := h.d.Config()
+	if !c.SelfServiceFlowRegistrationEnabled(ctx) {
+		removePaths(doc, "/self-service/registration")
+	}
+	if !c.SelfServiceFlowRecoveryEnabled(ctx) {
+		removePaths(doc, "/self-service/recovery", "/admin/recovery")
+	}
+	if !c.SelfServiceFlowVerificationEnabled(ctx) {
+		removePaths(doc, "/self-service/verification")
+	}
+
+	var loginMethods, registrationMethods, settingsMethods, recoveryMethods, verificationMethods []string
+	for _, s := range h.d.LoginStrategies(ctx) {
+		loginMethods = append(loginMethods, s.ID().String())
+	}
+	for _, s := range h.d.RegistrationStrategies(ctx) {
+		registrationMethods = append(registrationMethods, s.ID().String())
+	}
+	for _, s := range h.d.SettingsStrategies(ctx) {
+		settingsMethods = append(settingsMethods, s.SettingsStrategyID())
+	}
+	for _, s := range h.d.RecoveryStrategies(ctx) {
+		recoveryMethods = append(recoveryMethods, s.RecoveryStrategyID())
+	}
+	for _, s := range h.d.VerificationStrategies(ctx) {
+		verificationMethods = append(verificationMethods, s.VerificationStrategyID())
+	}
+
+	restrictMethods(doc, "updateLoginFlowBody", loginMethods)
+	restrictMethods(doc, "updateRegistrationFlowBody", registrationMethods)
+	restrictMethods(doc, "updateSettingsFlowBody", settingsMethods)
+	restrictMethods(doc, "updateRecoveryFlowBody", recoveryMethods)
+	restrictMethods(doc, "updateVerificationFlowBody", verificationMethods)
+
+	traits, err := h.identityTraits(ctx)
+	if err != nil {
+		return nil, err
+	}
+	setIdentityTraits(doc, traits)
+
+	if info, ok := doc["info"].(document); ok {
+		info["version"] = config.Version
+	}
+
+	return doc, nil
+}
+
+// identityTraits returns the traits of the default identity schema.
+func (h *Handler) identityTraits(ctx context.Context) (document, error) {
+	ss, err := h.d.IdentityTraitsSchemas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := ss.GetByID(h.d.Config().DefaultIdentityTraitsSchemaID(ctx))
+	if err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The default identity schema could not be found. This is a configuration issue.").WithWrap(err))
+	}
+
+	src, err := h.d.SchemaHandler().ReadSchema(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	var raw document
+	if err := json.NewDecoder(src).Decode(&raw); err != nil {
+		return nil, errors.WithStack(herodot.ErrInternalServerError.WithReason("The default identity schema is not valid JSON. This is a configuration issue.").WithWrap(err))
+	}
+
+	properties, _ := raw["properties"].(document)
+	traits, ok := properties["traits"].(document)
+	if !ok {
+		return document{"type": "object"}, nil
+	}
+
+	return stripSchemaKeywords(traits).(document), nil
+}
+
+// removePaths removes all paths starting with one of the given prefixes.
+func removePaths(doc document, prefixes ...string) {
+	paths, _ := doc["paths"].(document)
+	for path := range paths {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(path, prefix) {
+				delete(paths, path)
+			}
+		}
+	}
+}
+
+// restrictMethods reduces the discriminated union of a flow's update body to the given methods
+// and removes the schemas of all other methods.
+func restrictMethods(doc document, name string, methods []string) {
+	schemas := componentSchemas(doc)
+	body, ok := schemas[name].(document)
+	if !ok {
+		return
+	}
+
+	discriminator, _ := body["discriminator"].(document)
+	mapping, _ := discriminator["mapping"].(document)
+
+	enabled := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		enabled[m] = true
+	}
+
+	keep := make(map[string]bool)
+	for method, ref := range mapping {
+		if enabled[method] {
+			keep[ref.(string)] = true
+			continue
+		}
+		delete(mapping, method)
+		delete(schemas, strings.TrimPrefix(ref.(string), "#/components/schemas/"))
+	}
+
+	oneOf, _ := body["oneOf"].([]any)
+	filtered := make([]any, 0, len(oneOf))
+	for _, item := range oneOf {
+		if ref, _ := item.(document)["$ref"].(string); keep[ref] {
+			filtered = append(filtered, item)
+		}
+	}
+	body["oneOf"] = filtered
+}
+
+// setIdentityTraits replaces the generic identity traits with the traits of the identity schema.
+func setIdentityTraits(doc document, traits document) {
+	schemas := componentSchemas(doc)
+	schemas["identityTraits"] = traits
+
+	for name, s := range schemas {
+		if !strings.HasPrefix(name, "updateRegistrationFlowWith") && name != "updateSettingsFlowWithProfileMethod" {
+			continue
+		}
+
+		properties, _ := s.(document)["properties"].(document)
+		original, ok := properties["traits"].(document)
+		if !ok {
+			continue
+		}
+
+		replaced := document{"allOf": []any{document{"$ref": "#/components/schemas/identityTraits"}}}
+		if description, ok := original["description"]; ok {
+			replaced["description"] = description
+		}
+		properties["traits"] = replaced
+	}
+}
+
+func componentSchemas(doc document) document {
+	components, _ := doc["components"].(document)
+	schemas, _ := components["schemas"].(document)
+	return schemas
+}
+
+// stripSchemaKeywords removes the JSON Schema keywords which are not allowed in OpenAPI
+// documents, such as the Ory Kratos extension.
+func stripSchemaKeywords(v any) any {
+	switch t := v.(type) {
+	case document:
+		out := make(document, len(t))
+		for k, v := range t {
+			if k == "$id" || k == "$schema" || k == "ory.sh/kratos" {
+				continue
+			}
+			out[k] = stripSchemaKeywords(v)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for k, v := range t {
+			out[k] = stripSchemaKeywords(v)
+		}
+		return out
+	default:
+		return v
+	}
+}
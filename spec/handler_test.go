@@ -0,0 +1,70 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package spec_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/kratos/internal"
+	"github.com/ory/kratos/internal/testhelpers"
+	"github.com/ory/kratos/spec"
+	"github.com/ory/kratos/x"
+)
+
+func TestConfiguredSpec(t *testing.T) {
+	ctx := context.Background()
+	conf, reg := internal.NewFastRegistryWithMocks(t)
+	testhelpers.SetDefaultIdentitySchema(conf, "file://./stub/identity.schema.json")
+	conf.MustSet(ctx, config.ViperKeySelfServiceRegistrationEnabled, false)
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".password.enabled", true)
+	conf.MustSet(ctx, config.ViperKeySelfServiceStrategyConfig+".totp.enabled", false)
+
+	router := x.NewRouterAdmin()
+	reg.SpecHandler().RegisterAdminRoutes(router)
+	ts := httptest.NewServer(router)
+	t.Cleanup(ts.Close)
+
+	res, err := ts.Client().Get(ts.URL + x.AdminPrefix + spec.RouteConfigured)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var doc json.RawMessage
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&doc))
+	body := string(doc)
+
+	t.Run("case=removes disabled flows", func(t *testing.T) {
+		assert.False(t, gjson.Get(body, "paths./self-service/registration/api").Exists())
+		assert.True(t, gjson.Get(body, "paths./self-service/login/api").Exists())
+	})
+
+	t.Run("case=removes disabled methods", func(t *testing.T) {
+		assert.True(t, gjson.Get(body, "components.schemas.updateLoginFlowBody.discriminator.mapping.password").Exists())
+		assert.False(t, gjson.Get(body, "components.schemas.updateLoginFlowBody.discriminator.mapping.totp").Exists())
+		assert.False(t, gjson.Get(body, "components.schemas.updateLoginFlowWithTotpMethod").Exists())
+
+		for _, ref := range gjson.Get(body, "components.schemas.updateLoginFlowBody.oneOf.#.$ref").Array() {
+			assert.NotEqual(t, "#/components/schemas/updateLoginFlowWithTotpMethod", ref.String())
+		}
+	})
+
+	t.Run("case=uses the identity schema traits", func(t *testing.T) {
+		traits := gjson.Get(body, "components.schemas.identityTraits")
+		assert.Equal(t, "email", traits.Get("properties.email.format").String())
+		assert.Equal(t, `["email"]`, traits.Get("required").Raw)
+		assert.False(t, traits.Get(`properties.email.ory\.sh/kratos`).Exists())
+
+		assert.Equal(t, "#/components/schemas/identityTraits",
+			gjson.Get(body, "components.schemas.updateSettingsFlowWithProfileMethod.properties.traits.allOf.0.$ref").String())
+	})
+}
@@ -28,6 +28,16 @@ const (
 	InfoSelfServiceLoginLink                                     // 1010016
 	InfoSelfServiceLoginAndLink                                  // 1010017
 	InfoSelfServiceLoginWithAndLink                              // 1010018
+	InfoSelfServiceLoginSessionExpired                           // 1010019
+	InfoSelfServiceLoginCodeChooseChannel                        // 1010020
+	InfoSelfServiceLoginSMSWithCodeSent                          // 1010021
+	InfoSelfServiceLoginPasskey                                  // 1010022
+	InfoSelfServiceLoginPush                                     // 1010023
+	InfoSelfServiceLoginPushSent                                 // 1010024
+	InfoSelfServiceLoginRememberDevice                           // 1010025
+	InfoSelfServiceLoginLookupSecretsLow                         // 1010026
+	InfoSelfServiceLoginExternalMFA                              // 1010027
+	InfoSelfServiceLoginExternalMFAPending                       // 1010028
 )
 
 const (
@@ -46,6 +56,7 @@ const (
 	InfoSelfServiceRegistrationRegisterWebAuthn                      // 1040004
 	InfoSelfServiceRegistrationEmailWithCodeSent                     // 1040005
 	InfoSelfServiceRegistrationRegisterCode                          // 1040006
+	InfoSelfServiceRegistrationPendingApproval                       // 1040007
 )
 
 const (
@@ -68,6 +79,19 @@ const (
 	InfoSelfServiceSettingsDisableLookup
 	InfoSelfServiceSettingsTOTPSecretLabel
 	InfoSelfServiceSettingsRemoveWebAuthn
+	InfoSelfServiceSettingsRenameWebAuthn
+	InfoSelfServiceSettingsRenameWebAuthnDisplayName
+	InfoSelfServiceSettingsRegisterPush
+	InfoSelfServiceSettingsRegisterPushDisplayName
+	InfoSelfServiceSettingsRegisterPushToken
+	InfoSelfServiceSettingsRegisterPushPlatform
+	InfoSelfServiceSettingsRemovePush
+	InfoSelfServiceSettingsRevokeTrustedDevice
+	InfoSelfServiceSettingsRevokeAllTrustedDevices
+	InfoSelfServiceSettingsRequiredAction
+	InfoSelfServiceSettingsEmailChangePending
+	InfoSelfServiceSettingsRevokeSession
+	InfoSelfServiceSettingsRevokeOtherSessions
 )
 
 const (
@@ -75,24 +99,32 @@ const (
 	InfoSelfServiceRecoverySuccessful                            // 1060001
 	InfoSelfServiceRecoveryEmailSent                             // 1060002
 	InfoSelfServiceRecoveryEmailWithCodeSent                     // 1060003
+	InfoSelfServiceRecoveryChooseAddress                         // 1060004
+	InfoSelfServiceRecoverySMSWithCodeSent                       // 1060005
+	InfoSelfServiceRecoveryCodeSentToAddress                     // 1060006
 )
 
 const (
-	InfoNodeLabel                 ID = 1070000 + iota // 1070000
-	InfoNodeLabelInputPassword                        // 1070001
-	InfoNodeLabelGenerated                            // 1070002
-	InfoNodeLabelSave                                 // 1070003
-	InfoNodeLabelID                                   // 1070004
-	InfoNodeLabelSubmit                               // 1070005
-	InfoNodeLabelVerifyOTP                            // 1070006
-	InfoNodeLabelEmail                                // 1070007
-	InfoNodeLabelResendOTP                            // 1070008
-	InfoNodeLabelContinue                             // 1070009
-	InfoNodeLabelRecoveryCode                         // 1070010
-	InfoNodeLabelVerificationCode                     // 1070011
-	InfoNodeLabelRegistrationCode                     // 1070012
-	InfoNodeLabelLoginCode                            // 1070013
-	InfoNodeLabelLoginAndLinkCredential
+	InfoNodeLabel                       ID = 1070000 + iota // 1070000
+	InfoNodeLabelInputPassword                              // 1070001
+	InfoNodeLabelGenerated                                  // 1070002
+	InfoNodeLabelSave                                       // 1070003
+	InfoNodeLabelID                                         // 1070004
+	InfoNodeLabelSubmit                                     // 1070005
+	InfoNodeLabelVerifyOTP                                  // 1070006
+	InfoNodeLabelEmail                                      // 1070007
+	InfoNodeLabelResendOTP                                  // 1070008
+	InfoNodeLabelContinue                                   // 1070009
+	InfoNodeLabelRecoveryCode                               // 1070010
+	InfoNodeLabelVerificationCode                           // 1070011
+	InfoNodeLabelRegistrationCode                           // 1070012
+	InfoNodeLabelLoginCode                                  // 1070013
+	InfoNodeLabelLoginAndLinkCredential                     // 1070014
+	InfoNodeLabelRecoveryAddress                            // 1070015
+	InfoNodeLabelCodeChannel                                // 1070016
+	InfoNodeLabelPhone                                      // 1070017
+	InfoNodeLabelConsent                                    // 1070018
+	InfoNodeLabelSignupCode                                 // 1070019
 )
 
 const (
@@ -100,6 +132,7 @@ const (
 	InfoSelfServiceVerificationEmailSent                             // 1080001
 	InfoSelfServiceVerificationSuccessful                            // 1080002
 	InfoSelfServiceVerificationEmailWithCodeSent                     // 1080003
+	InfoSelfServiceVerificationSMSWithCodeSent                       // 1080004
 )
 
 const (
@@ -140,6 +173,15 @@ const (
 	ErrorValidationPasswordTooManyBreaches
 	ErrorValidationNoCodeUser
 	ErrorValidationTraitsMismatch
+	ErrorValidationNoPushDevice
+	ErrorValidationPushDenied
+	ErrorValidationPushExpired
+	ErrorValidationInvalidPhoneNumber
+	ErrorValidationExternalMFADenied
+	ErrorValidationExternalMFAExpired
+	ErrorValidationEmailDomainNotAllowed
+	ErrorValidationDisposableEmail
+	ErrorValidationConsentRequired
 )
 
 const (
@@ -153,6 +195,8 @@ const (
 	ErrorValidationLoginRetrySuccess                                    // 4010007
 	ErrorValidationLoginCodeInvalidOrAlreadyUsed                        // 4010008
 	ErrorValidationLoginLinkedCredentialsDoNotMatch                     // 4010009
+	ErrorValidationLoginLocked                                          // 4010010
+	ErrorValidationLoginRiskDenied                                      // 4010011
 )
 
 const (
@@ -160,6 +204,8 @@ const (
 	ErrorValidationRegistrationFlowExpired                 // 4040001
 	ErrorValidateionRegistrationRetrySuccess               // 4040002
 	ErrorValidationRegistrationCodeInvalidOrAlreadyUsed    // 4040003
+	ErrorValidationRegistrationSignupCodeInvalid           // 4040004
+	ErrorValidationRegistrationSignupCodeRequired          // 4040005
 )
 
 const (
@@ -175,6 +221,7 @@ const (
 	ErrorValidationRecoveryTokenInvalidOrAlreadyUsed                     // 4060004
 	ErrorValidationRecoveryFlowExpired                                   // 4060005
 	ErrorValidationRecoveryCodeInvalidOrAlreadyUsed                      // 4060006
+	ErrorValidationRecoveryCodeSubmittedTooOften                         // 4060007
 )
 
 const (
@@ -11,13 +11,17 @@ const (
 	ErrIDSelfServiceFlowDisabled                       = "self_service_flow_disabled"
 	ErrIDSelfServiceBrowserLocationChangeRequiredError = "browser_location_change_required"
 	ErrIDSelfServiceFlowReplaced                       = "self_service_flow_replaced"
+	ErrIDSelfServiceInvitationRequired                 = "self_service_invitation_required"
+	ErrIDSelfServiceFlowRateLimited                    = "self_service_flow_rate_limited"
 
 	ErrIDAlreadyLoggedIn             = "session_already_available"
 	ErrIDAddressNotVerified          = "session_verified_address_required"
 	ErrIDSessionHasAALAlready        = "session_aal_already_fulfilled"
 	ErrIDSessionRequiredForHigherAAL = "session_aal1_required"
 	ErrIDHigherAALRequired           = "session_aal2_required"
+	ErrIDRequiredActionsPending      = "session_required_actions_pending"
 	ErrNoActiveSession               = "session_inactive"
+	ErrIDSessionIdle                 = "session_idle_timeout"
 	ErrIDRedirectURLNotAllowed       = "self_service_flow_return_to_forbidden"
 	ErrIDInitiatedBySomeoneElse      = "security_identity_mismatch"
 
@@ -16,6 +16,14 @@ func NewInfoLoginReAuth() *Message {
 	}
 }
 
+func NewInfoLoginSessionExpired() *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginSessionExpired,
+		Type: Info,
+		Text: "Your session expired. Please sign in again to continue.",
+	}
+}
+
 func NewInfoLoginMFA() *Message {
 	return &Message{
 		ID:   InfoSelfServiceLoginMFA,
@@ -188,6 +196,14 @@ func NewInfoSelfServiceLoginWebAuthn() *Message {
 	}
 }
 
+func NewInfoSelfServiceLoginPasskey() *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginPasskey,
+		Text: "Sign in with passkey",
+		Type: Info,
+	}
+}
+
 func NewInfoSelfServiceContinueLoginWebAuthn() *Message {
 	return &Message{
 		ID:   InfoSelfServiceLoginContinueWebAuthn,
@@ -212,6 +228,22 @@ func NewLoginEmailWithCodeSent() *Message {
 	}
 }
 
+func NewLoginSMSWithCodeSent() *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginSMSWithCodeSent,
+		Type: Info,
+		Text: "An SMS containing a code has been sent to your phone number. If you have not received an SMS, retry the login.",
+	}
+}
+
+func NewInfoSelfServiceLoginCodeChooseChannel() *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginCodeChooseChannel,
+		Type: Info,
+		Text: "Please choose where you want to receive the code.",
+	}
+}
+
 func NewErrorValidationLoginCodeInvalidOrAlreadyUsed() *Message {
 	return &Message{
 		ID:   ErrorValidationLoginCodeInvalidOrAlreadyUsed,
@@ -243,3 +275,82 @@ func NewErrorValidationLoginLinkedCredentialsDoNotMatch() *Message {
 		Type: Error,
 	}
 }
+
+func NewErrorValidationLoginRiskDenied() *Message {
+	return &Message{
+		ID:   ErrorValidationLoginRiskDenied,
+		Text: "The sign in was blocked for security reasons. Please contact support if this problem persists.",
+		Type: Error,
+	}
+}
+
+func NewErrorValidationLoginLocked(lockedUntil time.Time) *Message {
+	return &Message{
+		ID:   ErrorValidationLoginLocked,
+		Text: fmt.Sprintf("Too many failed sign in attempts. Please try again in %.2f minutes.", Until(lockedUntil).Minutes()),
+		Type: Error,
+		Context: context(map[string]any{
+			"locked_until":      lockedUntil,
+			"locked_until_unix": lockedUntil.Unix(),
+		}),
+	}
+}
+
+func NewInfoSelfServiceLoginPush() *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginPush,
+		Text: "Approve on your device",
+		Type: Info,
+	}
+}
+
+func NewInfoSelfServiceLoginPushSent(expiresAt time.Time) *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginPushSent,
+		Text: "An approval request was sent to your registered devices. Approve it to continue.",
+		Type: Info,
+		Context: context(map[string]any{
+			"expires_at":      expiresAt,
+			"expires_at_unix": expiresAt.Unix(),
+		}),
+	}
+}
+
+func NewInfoSelfServiceLoginRememberDevice() *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginRememberDevice,
+		Text: "Remember this device",
+		Type: Info,
+	}
+}
+
+func NewInfoSelfServiceLoginLookupSecretsLow(remaining int) *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginLookupSecretsLow,
+		Text: fmt.Sprintf("You have %d backup recovery codes left. Please generate new backup recovery codes in your account settings.", remaining),
+		Type: Info,
+		Context: context(map[string]any{
+			"remaining": remaining,
+		}),
+	}
+}
+
+func NewInfoSelfServiceLoginExternalMFA() *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginExternalMFA,
+		Text: "Continue with your MFA provider",
+		Type: Info,
+	}
+}
+
+func NewInfoSelfServiceLoginExternalMFAPending(expiresAt time.Time) *Message {
+	return &Message{
+		ID:   InfoSelfServiceLoginExternalMFAPending,
+		Text: "Confirm the sign-in with your MFA provider, then continue.",
+		Type: Info,
+		Context: context(map[string]any{
+			"expires_at":      expiresAt,
+			"expires_at_unix": expiresAt.Unix(),
+		}),
+	}
+}
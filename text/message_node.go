@@ -3,6 +3,8 @@
 
 package text
 
+import "fmt"
+
 func NewInfoNodeLabelVerifyOTP() *Message {
 	return &Message{
 		ID:   InfoNodeLabelVerifyOTP,
@@ -27,6 +29,29 @@ func NewInfoNodeLabelRecoveryCode() *Message {
 	}
 }
 
+func NewInfoNodeLabelRecoveryAddress(maskedAddress string) *Message {
+	return &Message{
+		ID:   InfoNodeLabelRecoveryAddress,
+		Text: fmt.Sprintf("Send code to %s", maskedAddress),
+		Type: Info,
+		Context: context(map[string]any{
+			"address": maskedAddress,
+		}),
+	}
+}
+
+func NewInfoNodeLabelCodeChannel(channel, maskedAddress string) *Message {
+	return &Message{
+		ID:   InfoNodeLabelCodeChannel,
+		Text: fmt.Sprintf("Send code to %s", maskedAddress),
+		Type: Info,
+		Context: context(map[string]any{
+			"channel": channel,
+			"address": maskedAddress,
+		}),
+	}
+}
+
 func NewInfoNodeLabelRegistrationCode() *Message {
 	return &Message{
 		ID:   InfoNodeLabelRegistrationCode,
@@ -102,6 +127,14 @@ func NewInfoNodeInputEmail() *Message {
 	}
 }
 
+func NewInfoNodeInputPhoneNumber() *Message {
+	return &Message{
+		ID:   InfoNodeLabelPhone,
+		Text: "Phone number",
+		Type: Info,
+	}
+}
+
 func NewInfoNodeResendOTP() *Message {
 	return &Message{
 		ID:   InfoNodeLabelResendOTP,
@@ -117,3 +150,27 @@ func NewInfoNodeLoginAndLinkCredential() *Message {
 		Type: Info,
 	}
 }
+
+func NewInfoNodeLabelConsent(id, version, url, label string) *Message {
+	if label == "" {
+		label = fmt.Sprintf("I accept the %s", id)
+	}
+	return &Message{
+		ID:   InfoNodeLabelConsent,
+		Text: label,
+		Type: Info,
+		Context: context(map[string]any{
+			"id":      id,
+			"version": version,
+			"url":     url,
+		}),
+	}
+}
+
+func NewInfoNodeLabelSignupCode() *Message {
+	return &Message{
+		ID:   InfoNodeLabelSignupCode,
+		Text: "Sign-up code",
+		Type: Info,
+	}
+}
@@ -50,6 +50,33 @@ func NewRecoveryEmailWithCodeSent() *Message {
 	}
 }
 
+func NewRecoverySMSWithCodeSent() *Message {
+	return &Message{
+		ID:   InfoSelfServiceRecoverySMSWithCodeSent,
+		Type: Info,
+		Text: "A text message containing a recovery code has been sent to the phone number you provided. If you have not received a message, check the number and make sure to use the number you registered with.",
+	}
+}
+
+func NewRecoveryCodeSentToAddress(address string) *Message {
+	return &Message{
+		ID:   InfoSelfServiceRecoveryCodeSentToAddress,
+		Type: Info,
+		Text: fmt.Sprintf("A recovery code has been sent to %s.", address),
+		Context: context(map[string]any{
+			"address": address,
+		}),
+	}
+}
+
+func NewRecoveryChooseAddress() *Message {
+	return &Message{
+		ID:   InfoSelfServiceRecoveryChooseAddress,
+		Type: Info,
+		Text: "Please choose the address the recovery code should be sent to.",
+	}
+}
+
 func NewErrorValidationRecoveryTokenInvalidOrAlreadyUsed() *Message {
 	return &Message{
 		ID:   ErrorValidationRecoveryTokenInvalidOrAlreadyUsed,
@@ -66,6 +93,14 @@ func NewErrorValidationRecoveryCodeInvalidOrAlreadyUsed() *Message {
 	}
 }
 
+func NewErrorValidationRecoveryCodeSubmittedTooOften() *Message {
+	return &Message{
+		ID:   ErrorValidationRecoveryCodeSubmittedTooOften,
+		Text: "The recovery code was submitted too often. Please request a new code.",
+		Type: Error,
+	}
+}
+
 func NewErrorValidationRecoveryRetrySuccess() *Message {
 	return &Message{
 		ID:   ErrorValidationRecoveryRetrySuccess,
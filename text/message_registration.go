@@ -71,6 +71,22 @@ func NewErrorValidationRegistrationCodeInvalidOrAlreadyUsed() *Message {
 	}
 }
 
+func NewErrorValidationRegistrationSignupCodeInvalid() *Message {
+	return &Message{
+		ID:   ErrorValidationRegistrationSignupCodeInvalid,
+		Text: "The sign-up code is invalid, has expired, or can no longer be used.",
+		Type: Error,
+	}
+}
+
+func NewErrorValidationRegistrationSignupCodeRequired() *Message {
+	return &Message{
+		ID:   ErrorValidationRegistrationSignupCodeRequired,
+		Text: "A sign-up code is required to register.",
+		Type: Error,
+	}
+}
+
 func NewErrorValidationRegistrationRetrySuccessful() *Message {
 	return &Message{
 		ID:   ErrorValidateionRegistrationRetrySuccess,
@@ -86,3 +102,11 @@ func NewInfoSelfServiceRegistrationRegisterCode() *Message {
 		Type: Info,
 	}
 }
+
+func NewInfoSelfServiceRegistrationPendingApproval() *Message {
+	return &Message{
+		ID:   InfoSelfServiceRegistrationPendingApproval,
+		Text: "Your account was created and awaits approval. You will receive an email once you can sign in.",
+		Type: Info,
+	}
+}
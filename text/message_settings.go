@@ -174,15 +174,165 @@ func NewInfoSelfServiceRegisterWebAuthnDisplayName() *Message {
 	}
 }
 
-func NewInfoSelfServiceRemoveWebAuthn(name string, createdAt time.Time) *Message {
+func NewInfoSelfServiceRemoveWebAuthn(name string, createdAt time.Time, lastUsedAt *time.Time) *Message {
 	return &Message{
-		ID:   InfoSelfServiceSettingsRemoveWebAuthn,
-		Text: fmt.Sprintf("Remove security key \"%s\"", name),
+		ID:      InfoSelfServiceSettingsRemoveWebAuthn,
+		Text:    fmt.Sprintf("Remove security key \"%s\"", name),
+		Type:    Info,
+		Context: webAuthnCredentialContext(name, createdAt, lastUsedAt),
+	}
+}
+
+func NewInfoSelfServiceRenameWebAuthn(name string, createdAt time.Time, lastUsedAt *time.Time) *Message {
+	return &Message{
+		ID:      InfoSelfServiceSettingsRenameWebAuthn,
+		Text:    fmt.Sprintf("Rename security key \"%s\"", name),
+		Type:    Info,
+		Context: webAuthnCredentialContext(name, createdAt, lastUsedAt),
+	}
+}
+
+func NewInfoSelfServiceRenameWebAuthnDisplayName() *Message {
+	return &Message{
+		ID:   InfoSelfServiceSettingsRenameWebAuthnDisplayName,
+		Text: "New name of the security key",
+		Type: Info,
+	}
+}
+
+func NewInfoSelfServiceSettingsRegisterPush() *Message {
+	return &Message{
+		ID:   InfoSelfServiceSettingsRegisterPush,
+		Text: "Add device",
+		Type: Info,
+	}
+}
+
+func NewInfoSelfServiceRegisterPushDisplayName() *Message {
+	return &Message{
+		ID:   InfoSelfServiceSettingsRegisterPushDisplayName,
+		Text: "Name of the device",
+		Type: Info,
+	}
+}
+
+func NewInfoSelfServiceRegisterPushToken() *Message {
+	return &Message{
+		ID:   InfoSelfServiceSettingsRegisterPushToken,
+		Text: "Push token of the device",
+		Type: Info,
+	}
+}
+
+func NewInfoSelfServiceRegisterPushPlatform() *Message {
+	return &Message{
+		ID:   InfoSelfServiceSettingsRegisterPushPlatform,
+		Text: "Platform of the device",
+		Type: Info,
+	}
+}
+
+func NewInfoSelfServiceRemovePush(name string, addedAt time.Time) *Message {
+	return &Message{
+		ID:   InfoSelfServiceSettingsRemovePush,
+		Text: fmt.Sprintf("Remove device \"%s\"", name),
 		Type: Info,
 		Context: context(map[string]any{
 			"display_name":  name,
-			"added_at":      createdAt,
-			"added_at_unix": createdAt.Unix(),
+			"added_at":      addedAt,
+			"added_at_unix": addedAt.Unix(),
 		}),
 	}
 }
+
+func NewInfoSelfServiceSettingsRevokeTrustedDevice(userAgent string, trustedAt time.Time) *Message {
+	return &Message{
+		ID:   InfoSelfServiceSettingsRevokeTrustedDevice,
+		Text: fmt.Sprintf("Forget device \"%s\"", userAgent),
+		Type: Info,
+		Context: context(map[string]any{
+			"user_agent":      userAgent,
+			"trusted_at":      trustedAt,
+			"trusted_at_unix": trustedAt.Unix(),
+		}),
+	}
+}
+
+func NewInfoSelfServiceSettingsRevokeAllTrustedDevices() *Message {
+	return &Message{
+		ID:   InfoSelfServiceSettingsRevokeAllTrustedDevices,
+		Text: "Forget all devices",
+		Type: Info,
+	}
+}
+
+func NewInfoSelfServiceSettingsRequiredAction(action string) *Message {
+	var t string
+	switch action {
+	case "change_password":
+		t = "Change your password to continue."
+	case "review_mfa":
+		t = "Review your second factors to continue. If you lost your authenticator app, link a new one."
+	case "confirm_profile":
+		t = "Confirm your profile to continue."
+	default:
+		t = "Set a password or a second factor to continue."
+	}
+
+	return &Message{
+		ID:   InfoSelfServiceSettingsRequiredAction,
+		Text: t,
+		Type: Info,
+		Context: context(map[string]any{
+			"action": action,
+		}),
+	}
+}
+
+func NewInfoSelfServiceSettingsEmailChangePending(address string) *Message {
+	return &Message{
+		ID:   InfoSelfServiceSettingsEmailChangePending,
+		Text: fmt.Sprintf("We sent a confirmation link to %s. Your changes are saved once you confirm the new email address.", address),
+		Type: Info,
+		Context: context(map[string]any{
+			"address": address,
+		}),
+	}
+}
+
+func NewInfoSelfServiceSettingsRevokeSession(userAgent, ipAddress, location string, authenticatedAt time.Time, aal string) *Message {
+	return &Message{
+		ID:   InfoSelfServiceSettingsRevokeSession,
+		Text: fmt.Sprintf("Sign out \"%s\"", userAgent),
+		Type: Info,
+		Context: context(map[string]any{
+			"user_agent":            userAgent,
+			"ip_address":            ipAddress,
+			"location":              location,
+			"authenticated_at":      authenticatedAt,
+			"authenticated_at_unix": authenticatedAt.Unix(),
+			"aal":                   aal,
+		}),
+	}
+}
+
+func NewInfoSelfServiceSettingsRevokeOtherSessions() *Message {
+	return &Message{
+		ID:   InfoSelfServiceSettingsRevokeOtherSessions,
+		Text: "Sign out all other sessions",
+		Type: Info,
+	}
+}
+
+func webAuthnCredentialContext(name string, createdAt time.Time, lastUsedAt *time.Time) []byte {
+	ctx := map[string]any{
+		"display_name":  name,
+		"added_at":      createdAt,
+		"added_at_unix": createdAt.Unix(),
+	}
+	if lastUsedAt != nil {
+		ctx["last_used_at"] = *lastUsedAt
+		ctx["last_used_at_unix"] = lastUsedAt.Unix()
+	}
+	return context(ctx)
+}
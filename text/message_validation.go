@@ -4,6 +4,7 @@
 package text
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -315,6 +316,20 @@ func NewErrorValidationDuplicateCredentialsWithHints(availableCredentialTypes []
 	}
 }
 
+// WithContinueWith adds continue_with items to the message's context, for example to offer signing in to the
+// account which exists already.
+func WithContinueWith(m *Message, continueWith ...any) *Message {
+	ctx := map[string]any{}
+	if len(m.Context) > 0 {
+		if err := json.Unmarshal(m.Context, &ctx); err != nil {
+			panic(err)
+		}
+	}
+	ctx["continue_with"] = continueWith
+	m.Context = context(ctx)
+	return m
+}
+
 func NewErrorValidationDuplicateCredentialsOnOIDCLink() *Message {
 	return &Message{
 		ID: ErrorValidationDuplicateCredentialsOnOIDCLink,
@@ -364,6 +379,17 @@ func NewErrorValidationAddressNotVerified() *Message {
 	}
 }
 
+func NewErrorValidationAddressNotVerifiedWithContinueWith(continueWith any) *Message {
+	return &Message{
+		ID:   ErrorValidationAddressNotVerified,
+		Text: "Account not active yet. Did you forget to verify your email address?",
+		Type: Error,
+		Context: context(map[string]any{
+			"continue_with": []any{continueWith},
+		}),
+	}
+}
+
 func NewErrorValidationNoTOTPDevice() *Message {
 	return &Message{
 		ID:   ErrorValidationNoTOTPDevice,
@@ -411,3 +437,84 @@ func NewErrorValidationTraitsMismatch() *Message {
 		Type: Error,
 	}
 }
+
+func NewErrorValidationNoPushDevice() *Message {
+	return &Message{
+		ID:   ErrorValidationNoPushDevice,
+		Text: "You have no device set up for push approvals.",
+		Type: Error,
+	}
+}
+
+func NewErrorValidationPushDenied() *Message {
+	return &Message{
+		ID:   ErrorValidationPushDenied,
+		Text: "The sign-in request was denied on your device.",
+		Type: Error,
+	}
+}
+
+func NewErrorValidationPushExpired() *Message {
+	return &Message{
+		ID:   ErrorValidationPushExpired,
+		Text: "The approval request expired, please request a new one.",
+		Type: Error,
+	}
+}
+
+func NewErrorValidationInvalidPhoneNumber() *Message {
+	return &Message{
+		ID:   ErrorValidationInvalidPhoneNumber,
+		Text: "The phone number is invalid. Please enter it including the country calling code, e.g. +4915112345678.",
+		Type: Error,
+	}
+}
+
+func NewErrorValidationExternalMFADenied() *Message {
+	return &Message{
+		ID:   ErrorValidationExternalMFADenied,
+		Text: "The sign-in was denied by your MFA provider.",
+		Type: Error,
+	}
+}
+
+func NewErrorValidationExternalMFAExpired() *Message {
+	return &Message{
+		ID:   ErrorValidationExternalMFAExpired,
+		Text: "The sign-in request at your MFA provider expired, please start again.",
+		Type: Error,
+	}
+}
+
+func NewErrorValidationEmailDomainNotAllowed(domain string) *Message {
+	return &Message{
+		ID:   ErrorValidationEmailDomainNotAllowed,
+		Text: fmt.Sprintf("Email addresses of the domain %q can not be used to sign up.", domain),
+		Type: Error,
+		Context: context(map[string]any{
+			"domain": domain,
+		}),
+	}
+}
+
+func NewErrorValidationDisposableEmail(domain string) *Message {
+	return &Message{
+		ID:   ErrorValidationDisposableEmail,
+		Text: fmt.Sprintf("The email address belongs to the disposable email provider %q. Please use a permanent email address.", domain),
+		Type: Error,
+		Context: context(map[string]any{
+			"domain": domain,
+		}),
+	}
+}
+
+func NewErrorValidationConsentRequired(id string) *Message {
+	return &Message{
+		ID:   ErrorValidationConsentRequired,
+		Text: fmt.Sprintf("You must accept the %s to continue.", id),
+		Type: Error,
+		Context: context(map[string]any{
+			"id": id,
+		}),
+	}
+}
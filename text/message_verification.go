@@ -75,3 +75,11 @@ func NewVerificationEmailWithCodeSent() *Message {
 		Text: "An email containing a verification code has been sent to the email address you provided. If you have not received an email, check the spelling of the address and make sure to use the address you registered with.",
 	}
 }
+
+func NewVerificationSMSWithCodeSent() *Message {
+	return &Message{
+		ID:   InfoSelfServiceVerificationSMSWithCodeSent,
+		Type: Info,
+		Text: "A text message containing a verification code has been sent to the phone number you provided. If you have not received a message, check the number and make sure to use the number you registered with.",
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package container
+
+import (
+	"fmt"
+
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+)
+
+type (
+	// Mutations describe changes to the nodes and messages of a container, for example as requested
+	// by a before render web hook.
+	Mutations struct {
+		Nodes    NodeMutations  `json:"nodes"`
+		Messages []text.Message `json:"messages"`
+	}
+
+	NodeMutations struct {
+		// Add appends informational nodes. Input nodes can not be added.
+		Add []AddedNode `json:"add"`
+
+		// Remove removes all nodes matching one of the selectors.
+		Remove []NodeSelector `json:"remove"`
+
+		// Modify changes the label or the disabled state of all nodes matching the selector.
+		Modify []NodeModification `json:"modify"`
+	}
+
+	// NodeSelector matches nodes by ID, by group, or by both.
+	NodeSelector struct {
+		ID    string           `json:"id"`
+		Group node.UiNodeGroup `json:"group"`
+	}
+
+	NodeModification struct {
+		NodeSelector
+
+		Label    *text.Message `json:"label"`
+		Disabled *bool         `json:"disabled"`
+	}
+
+	AddedNode struct {
+		Type  node.UiNodeType  `json:"type"`
+		ID    string           `json:"id"`
+		Group node.UiNodeGroup `json:"group"`
+
+		// Text is the text of `text` nodes.
+		Text *text.Message `json:"text"`
+
+		// HREF and Title are used by `a` nodes.
+		HREF  string        `json:"href"`
+		Title *text.Message `json:"title"`
+
+		// Source, Width, and Height are used by `img` nodes.
+		Source string `json:"src"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+
+		// Label is shown next to the node.
+		Label *text.Message `json:"label"`
+	}
+)
+
+// protectedNodes can not be removed or modified by mutations.
+var protectedNodes = []string{"csrf_token"}
+
+func (s NodeSelector) matches(n *node.Node) bool {
+	if s.ID != "" && s.ID != n.ID() {
+		return false
+	}
+	if s.Group != "" && s.Group != n.Group {
+		return false
+	}
+	return s.ID != "" || s.Group != ""
+}
+
+func isProtected(n *node.Node) bool {
+	for _, id := range protectedNodes {
+		if n.ID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (n AddedNode) toNode() (*node.Node, error) {
+	group := n.Group
+	if group == "" {
+		group = node.DefaultGroup
+	}
+
+	var added *node.Node
+	switch n.Type {
+	case node.Text:
+		added = node.NewTextField(n.ID, n.Text, group)
+	case node.Anchor:
+		added = node.NewAnchorField(n.ID, n.HREF, group, n.Title)
+	case node.Image:
+		added = node.NewImageField(n.ID, n.Source, group, func(a *node.ImageAttributes) {
+			a.Width = n.Width
+			a.Height = n.Height
+		})
+	default:
+		return nil, fmt.Errorf("nodes of type %q can not be added", n.Type)
+	}
+
+	if n.Label != nil {
+		added.WithMetaLabel(n.Label)
+	}
+	return added, nil
+}
+
+// ApplyMutations applies the mutations to the container. Nodes are removed before they are modified,
+// and new nodes are added last, so that removals and modifications never affect added nodes. The
+// anti-CSRF token can neither be removed nor modified.
+func (c *Container) ApplyMutations(m *Mutations) error {
+	for _, s := range m.Nodes.Remove {
+		kept := make(node.Nodes, 0, len(c.Nodes))
+		for _, n := range c.Nodes {
+			if s.matches(n) && !isProtected(n) {
+				continue
+			}
+			kept = append(kept, n)
+		}
+		c.Nodes = kept
+	}
+
+	for _, mod := range m.Nodes.Modify {
+		for _, n := range c.Nodes {
+			if !mod.matches(n) || isProtected(n) {
+				continue
+			}
+			if mod.Label != nil {
+				n.WithMetaLabel(mod.Label)
+			}
+			if mod.Disabled != nil {
+				if a, ok := n.Attributes.(*node.InputAttributes); ok {
+					a.Disabled = *mod.Disabled
+				}
+			}
+		}
+	}
+
+	for _, a := range m.Nodes.Add {
+		if c.Nodes.Find(a.ID) != nil {
+			return fmt.Errorf("a node with the ID %q already exists", a.ID)
+		}
+
+		added, err := a.toNode()
+		if err != nil {
+			return err
+		}
+		c.Nodes.Append(added)
+	}
+
+	for k := range m.Messages {
+		c.Messages.Add(&m.Messages[k])
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/kratos/text"
+	"github.com/ory/kratos/ui/node"
+	"github.com/ory/x/pointerx"
+)
+
+func TestApplyMutations(t *testing.T) {
+	newContainer := func() *Container {
+		c := New("/action")
+		c.SetCSRF("csrf")
+		c.Nodes.Append(node.NewInputField("identifier", "", node.DefaultGroup, node.InputAttributeTypeText))
+		c.Nodes.Append(node.NewInputField("password", "", node.PasswordGroup, node.InputAttributeTypePassword))
+		c.Nodes.Append(node.NewInputField("method", "password", node.PasswordGroup, node.InputAttributeTypeSubmit))
+		return c
+	}
+
+	ids := func(c *Container) (ids []string) {
+		for _, n := range c.Nodes {
+			ids = append(ids, n.ID())
+		}
+		return ids
+	}
+
+	t.Run("case=removes nodes by group and id", func(t *testing.T) {
+		c := newContainer()
+		require.NoError(t, c.ApplyMutations(&Mutations{Nodes: NodeMutations{Remove: []NodeSelector{{Group: node.PasswordGroup}}}}))
+		assert.Equal(t, []string{"csrf_token", "identifier"}, ids(c))
+
+		c = newContainer()
+		require.NoError(t, c.ApplyMutations(&Mutations{Nodes: NodeMutations{Remove: []NodeSelector{{ID: "identifier"}}}}))
+		assert.Equal(t, []string{"csrf_token", "password", "method"}, ids(c))
+	})
+
+	t.Run("case=does not touch the csrf token", func(t *testing.T) {
+		c := newContainer()
+		require.NoError(t, c.ApplyMutations(&Mutations{Nodes: NodeMutations{
+			Remove: []NodeSelector{{Group: node.DefaultGroup}},
+			Modify: []NodeModification{{NodeSelector: NodeSelector{ID: "csrf_token"}, Disabled: pointerx.Ptr(true)}},
+		}}))
+		assert.Equal(t, []string{"csrf_token", "password", "method"}, ids(c))
+		assert.False(t, c.Nodes.Find("csrf_token").Attributes.(*node.InputAttributes).Disabled)
+	})
+
+	t.Run("case=an empty selector matches nothing", func(t *testing.T) {
+		c := newContainer()
+		require.NoError(t, c.ApplyMutations(&Mutations{Nodes: NodeMutations{Remove: []NodeSelector{{}}}}))
+		assert.Len(t, c.Nodes, 4)
+	})
+
+	t.Run("case=modifies label and disabled state", func(t *testing.T) {
+		c := newContainer()
+		label := text.NewInfoNodeLabelSave()
+		require.NoError(t, c.ApplyMutations(&Mutations{Nodes: NodeMutations{Modify: []NodeModification{
+			{NodeSelector: NodeSelector{ID: "identifier"}, Label: label, Disabled: pointerx.Ptr(true)},
+		}}}))
+
+		n := c.Nodes.Find("identifier")
+		assert.Equal(t, label, n.Meta.Label)
+		assert.True(t, n.Attributes.(*node.InputAttributes).Disabled)
+		assert.False(t, c.Nodes.Find("password").Attributes.(*node.InputAttributes).Disabled)
+	})
+
+	t.Run("case=adds informational nodes", func(t *testing.T) {
+		c := newContainer()
+		require.NoError(t, c.ApplyMutations(&Mutations{Nodes: NodeMutations{Add: []AddedNode{
+			{Type: node.Text, ID: "notice", Text: text.NewInfoNodeLabelSave()},
+			{Type: node.Anchor, ID: "help", HREF: "https://www.ory.sh/help", Title: text.NewInfoNodeLabelSave()},
+			{Type: node.Image, ID: "logo", Source: "https://www.ory.sh/logo.png", Width: 64, Height: 32},
+		}}}))
+
+		assert.Equal(t, []string{"csrf_token", "identifier", "password", "method", "notice", "help", "logo"}, ids(c))
+		assert.Equal(t, node.DefaultGroup, c.Nodes.Find("notice").Group)
+		assert.Equal(t, "https://www.ory.sh/help", c.Nodes.Find("help").Attributes.(*node.AnchorAttributes).HREF)
+		assert.Equal(t, 64, c.Nodes.Find("logo").Attributes.(*node.ImageAttributes).Width)
+	})
+
+	t.Run("case=fails to add input nodes", func(t *testing.T) {
+		c := newContainer()
+		require.Error(t, c.ApplyMutations(&Mutations{Nodes: NodeMutations{Add: []AddedNode{{Type: node.Input, ID: "token"}}}}))
+	})
+
+	t.Run("case=fails to add a node with an existing id", func(t *testing.T) {
+		c := newContainer()
+		require.Error(t, c.ApplyMutations(&Mutations{Nodes: NodeMutations{Add: []AddedNode{{Type: node.Text, ID: "identifier", Text: text.NewInfoNodeLabelSave()}}}}))
+
+		c = newContainer()
+		require.NoError(t, c.ApplyMutations(&Mutations{Nodes: NodeMutations{
+			Remove: []NodeSelector{{ID: "identifier"}},
+			Add:    []AddedNode{{Type: node.Text, ID: "identifier", Text: text.NewInfoNodeLabelSave()}},
+		}}), "removed nodes can be replaced")
+	})
+
+	t.Run("case=adds messages", func(t *testing.T) {
+		c := newContainer()
+		require.NoError(t, c.ApplyMutations(&Mutations{Messages: []text.Message{*text.NewInfoNodeLabelSave()}}))
+		require.Len(t, c.Messages, 1)
+		assert.Equal(t, text.InfoNodeLabelSave, c.Messages[0].ID)
+	})
+}
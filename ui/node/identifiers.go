@@ -26,5 +26,28 @@ const (
 	WebAuthnLoginTrigger        = "webauthn_login_trigger"
 	WebAuthnRegisterDisplayName = "webauthn_register_displayname"
 	WebAuthnRemove              = "webauthn_remove"
+	WebAuthnRename              = "webauthn_rename"
+	WebAuthnRenameDisplayName   = "webauthn_rename_displayname"
 	WebAuthnScript              = "webauthn_script"
+	PasskeyLogin                = "passkey_login"
+	PasskeyLoginTrigger         = "passkey_login_trigger"
+)
+
+const (
+	PushChallenge           = "push_challenge"
+	PushRegisterToken       = "push_register_token"
+	PushRegisterDisplayName = "push_register_displayname"
+	PushRegisterPlatform    = "push_register_platform"
+	PushRemove              = "push_remove"
+)
+
+const (
+	TrustedDeviceRemember  = "trusted_device_remember"
+	TrustedDeviceRevoke    = "trusted_device_revoke"
+	TrustedDeviceRevokeAll = "trusted_device_revoke_all"
+)
+
+const (
+	SessionsRevoke       = "sessions_revoke"
+	SessionsRevokeOthers = "sessions_revoke_others"
 )
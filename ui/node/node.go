@@ -48,6 +48,13 @@ const (
 	TOTPGroup          UiNodeGroup = "totp"
 	LookupGroup        UiNodeGroup = "lookup_secret"
 	WebAuthnGroup      UiNodeGroup = "webauthn"
+	PushGroup          UiNodeGroup = "push"
+	ExternalMFAGroup   UiNodeGroup = "external_mfa"
+	TrustedDeviceGroup UiNodeGroup = "trusted_device"
+	ConsentGroup       UiNodeGroup = "consent"
+	SessionsGroup      UiNodeGroup = "sessions"
+
+	IdentifierFirstGroup UiNodeGroup = "identifier_first"
 )
 
 func (g UiNodeGroup) String() string {
@@ -0,0 +1,70 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedClientIP returns the IP address of the client without the port.
+//
+// Unlike httpx.ClientIP, the `X-Forwarded-For`, `X-Real-IP`, and `True-Client-IP` headers are only used if the
+// request was sent by one of the trusted proxies, which are IP addresses or CIDR ranges. `X-Forwarded-For` is read
+// from right to left and the first address which is not a trusted proxy is the client, because clients can put any
+// address in front of the ones the proxies append.
+func TrustedClientIP(r *http.Request, trustedProxies []string) string {
+	ip := stripPort(r.RemoteAddr)
+	if !isTrustedProxy(ip, trustedProxies) {
+		return ip
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		for k := len(hops) - 1; k >= 0; k-- {
+			hop := stripPort(strings.TrimSpace(hops[k]))
+			if hop == "" {
+				continue
+			}
+			ip = hop
+			if !isTrustedProxy(hop, trustedProxies) {
+				break
+			}
+		}
+		return ip
+	}
+
+	for _, header := range []string{"X-Real-IP", "True-Client-IP"} {
+		if v := stripPort(strings.TrimSpace(r.Header.Get(header))); v != "" {
+			return v
+		}
+	}
+	return ip
+}
+
+func stripPort(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return strings.Trim(address, "[]")
+}
+
+func isTrustedProxy(address string, trustedProxies []string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range trustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if trusted := net.ParseIP(proxy); trusted != nil && trusted.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustedClientIP(t *testing.T) {
+	request := func(remoteAddr string, header http.Header) *http.Request {
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Request{RemoteAddr: remoteAddr, Header: header}
+	}
+
+	t.Run("case=ignores the port", func(t *testing.T) {
+		assert.Equal(t, "54.155.246.155", TrustedClientIP(request("54.155.246.155:1234", nil), nil))
+		assert.Equal(t, "54.155.246.155", TrustedClientIP(request("54.155.246.155:4321", nil), nil))
+		assert.Equal(t, "2001:db8::1", TrustedClientIP(request("[2001:db8::1]:1234", nil), nil))
+	})
+
+	t.Run("case=ignores the headers of untrusted clients", func(t *testing.T) {
+		for _, header := range []http.Header{
+			{"X-Forwarded-For": {"10.0.0.1"}},
+			{"X-Real-IP": {"10.0.0.2"}},
+			{"True-Client-IP": {"10.0.0.3"}},
+		} {
+			assert.Equal(t, "54.155.246.155", TrustedClientIP(request("54.155.246.155:1234", header), nil))
+			assert.Equal(t, "54.155.246.155", TrustedClientIP(request("54.155.246.155:1234", header), []string{"10.0.0.0/8"}))
+		}
+	})
+
+	t.Run("case=uses the headers of trusted proxies", func(t *testing.T) {
+		trusted := []string{"10.0.0.0/8", "192.168.1.1"}
+
+		assert.Equal(t, "54.155.246.155", TrustedClientIP(request("10.0.0.1:1234", http.Header{"X-Forwarded-For": {"54.155.246.155"}}), trusted))
+		assert.Equal(t, "54.155.246.155", TrustedClientIP(request("192.168.1.1:1234", http.Header{"X-Real-IP": {"54.155.246.155"}}), trusted))
+		assert.Equal(t, "54.155.246.155", TrustedClientIP(request("192.168.1.1:1234", http.Header{"True-Client-IP": {"54.155.246.155"}}), trusted))
+		assert.Equal(t, "192.168.1.2", TrustedClientIP(request("192.168.1.2:1234", http.Header{"X-Forwarded-For": {"54.155.246.155"}}), trusted))
+	})
+
+	t.Run("case=skips trusted proxies and spoofed addresses in X-Forwarded-For", func(t *testing.T) {
+		trusted := []string{"10.0.0.0/8"}
+
+		for _, forwarded := range [][]string{
+			{"54.155.246.155, 10.0.0.2"},
+			{"1.2.3.4, 54.155.246.155, 10.0.0.2"},
+			{"5.6.7.8", "54.155.246.155:4321", "10.0.0.2"},
+		} {
+			assert.Equal(t, "54.155.246.155", TrustedClientIP(request("10.0.0.1:1234", http.Header{"X-Forwarded-For": forwarded}), trusted), "%v", forwarded)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var testClock atomic.Pointer[TestClock]
+
+// Now returns the time used for flow, code and session lifespans. It is the wall clock time unless
+// the test clock was enabled.
+func Now() time.Time {
+	if c := testClock.Load(); c != nil {
+		if c.enabled() {
+			return c.Now()
+		}
+		// The test clock was turned off in the configuration.
+		testClock.CompareAndSwap(c, nil)
+	}
+	return time.Now()
+}
+
+// EnableTestClock makes Now use the test clock and returns it. The test clock is shared by the
+// whole process and must only be enabled in development environments. Now uses the wall clock
+// again as soon as enabled returns false.
+func EnableTestClock(enabled func() bool) *TestClock {
+	testClock.CompareAndSwap(nil, &TestClock{enabled: enabled})
+	return testClock.Load()
+}
+
+// DisableTestClock makes Now use the wall clock again.
+func DisableTestClock() {
+	testClock.Store(nil)
+}
+
+// TestClock is a clock which can be moved forward or set to a fixed time so that expiry can be
+// tested without waiting or editing the database.
+type TestClock struct {
+	mu      sync.RWMutex
+	offset  time.Duration
+	frozen  *time.Time
+	enabled func() bool
+}
+
+// TestClockState is the state of the test clock.
+//
+// swagger:model testClock
+type TestClockState struct {
+	// Now is the current time of the test clock.
+	//
+	// required: true
+	Now time.Time `json:"now"`
+
+	// Offset is the difference between the test clock and the wall clock.
+	//
+	// required: true
+	Offset string `json:"offset"`
+
+	// Frozen is true if the test clock stands still at a fixed time.
+	//
+	// required: true
+	Frozen bool `json:"frozen"`
+}
+
+// Now returns the current time of the test clock.
+func (c *TestClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.frozen != nil {
+		return *c.frozen
+	}
+	return time.Now().Add(c.offset)
+}
+
+// Advance moves the test clock forward by the given duration.
+func (c *TestClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.frozen != nil {
+		t := c.frozen.Add(d)
+		c.frozen = &t
+		return
+	}
+	c.offset += d
+}
+
+// Set sets the test clock to the given time. A frozen clock stands still until it is advanced,
+// set or reset again.
+func (c *TestClock) Set(t time.Time, frozen bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if frozen {
+		c.frozen = &t
+		c.offset = 0
+		return
+	}
+	c.frozen = nil
+	c.offset = time.Until(t)
+}
+
+// Reset sets the test clock back to the wall clock time.
+func (c *TestClock) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.frozen = nil
+	c.offset = 0
+}
+
+// State returns the state of the test clock.
+func (c *TestClock) State() *TestClockState {
+	now := c.Now()
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return &TestClockState{
+		Now:    now,
+		Offset: time.Until(now).Round(time.Second).String(),
+		Frozen: c.frozen != nil,
+	}
+}
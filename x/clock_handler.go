@@ -0,0 +1,257 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+	"github.com/ory/kratos/driver/config"
+	"github.com/ory/x/jsonx"
+)
+
+const (
+	RouteAdminTestClock        = "/test-clock"
+	RouteAdminTestClockAdvance = RouteAdminTestClock + "/advance"
+)
+
+type (
+	testClockHandlerDependencies interface {
+		config.Provider
+		WriterProvider
+	}
+
+	TestClockHandler struct {
+		d testClockHandlerDependencies
+	}
+)
+
+func NewTestClockHandler(d testClockHandlerDependencies) *TestClockHandler {
+	return &TestClockHandler{d: d}
+}
+
+func (h *TestClockHandler) RegisterAdminRoutes(admin *RouterAdmin) {
+	admin.GET(RouteAdminTestClock, h.getTestClock)
+	admin.PUT(RouteAdminTestClock, h.setTestClock)
+	admin.POST(RouteAdminTestClockAdvance, h.advanceTestClock)
+	admin.DELETE(RouteAdminTestClock, h.resetTestClock)
+}
+
+// clock returns the test clock or writes an error if it is not enabled.
+func (h *TestClockHandler) clock(w http.ResponseWriter, r *http.Request) *TestClock {
+	if !h.d.Config().TestClockEnabled(r.Context()) {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrNotFound.WithReason("The test clock is not enabled.")))
+		return nil
+	}
+	ctx := context.WithoutCancel(r.Context())
+	return EnableTestClock(func() bool { return h.d.Config().TestClockEnabled(ctx) })
+}
+
+// Test Clock Response
+//
+// swagger:response testClock
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type testClockResponse struct {
+	// in: body
+	Body TestClockState
+}
+
+// swagger:route GET /admin/test-clock metadata getTestClock
+//
+// # Get the Test Clock
+//
+// Returns the time used for flow, code and session lifespans. Only available if the test clock is
+// enabled and Ory Kratos runs with the `--dev` flag.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: testClock
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *TestClockHandler) getTestClock(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c := h.clock(w, r)
+	if c == nil {
+		return
+	}
+
+	h.d.Writer().Write(w, r, c.State())
+}
+
+// Set Test Clock Request Body
+//
+// swagger:model setTestClockBody
+type setTestClockBody struct {
+	// Time is the time the test clock is set to.
+	//
+	// required: true
+	Time time.Time `json:"time"`
+
+	// Frozen stops the test clock at the given time until it is advanced, set or reset again.
+	Frozen bool `json:"frozen"`
+}
+
+// Set Test Clock Parameters
+//
+// swagger:parameters setTestClock
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type setTestClock struct {
+	// in: body
+	// required: true
+	Body setTestClockBody
+}
+
+// swagger:route PUT /admin/test-clock metadata setTestClock
+//
+// # Set the Test Clock
+//
+// Sets the time used for flow, code and session lifespans. Only available if the test clock is
+// enabled and Ory Kratos runs with the `--dev` flag.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: testClock
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *TestClockHandler) setTestClock(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c := h.clock(w, r)
+	if c == nil {
+		return
+	}
+
+	var body setTestClockBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError(err.Error())))
+		return
+	}
+	if body.Time.IsZero() {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReason("Field `time` is required.")))
+		return
+	}
+
+	c.Set(body.Time, body.Frozen)
+	h.d.Writer().Write(w, r, c.State())
+}
+
+// Advance Test Clock Request Body
+//
+// swagger:model advanceTestClockBody
+type advanceTestClockBody struct {
+	// Duration by which the test clock is moved forward, for example "1h30m".
+	//
+	// required: true
+	Duration string `json:"duration"`
+}
+
+// Advance Test Clock Parameters
+//
+// swagger:parameters advanceTestClock
+//
+//nolint:deadcode,unused
+//lint:ignore U1000 Used to generate Swagger and OpenAPI definitions
+type advanceTestClock struct {
+	// in: body
+	// required: true
+	Body advanceTestClockBody
+}
+
+// swagger:route POST /admin/test-clock/advance metadata advanceTestClock
+//
+// # Advance the Test Clock
+//
+// Moves the time used for flow, code and session lifespans forward. Only available if the test
+// clock is enabled and Ory Kratos runs with the `--dev` flag.
+//
+//	Consumes:
+//	- application/json
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: testClock
+//	  400: errorGeneric
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *TestClockHandler) advanceTestClock(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c := h.clock(w, r)
+	if c == nil {
+		return
+	}
+
+	var body advanceTestClockBody
+	if err := jsonx.NewStrictDecoder(r.Body).Decode(&body); err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithError(err.Error())))
+		return
+	}
+
+	d, err := time.ParseDuration(body.Duration)
+	if err != nil {
+		h.d.Writer().WriteError(w, r, errors.WithStack(herodot.ErrBadRequest.WithReasonf("Field `duration` must be a duration such as \"1h30m\".").WithError(err.Error())))
+		return
+	}
+
+	c.Advance(d)
+	h.d.Writer().Write(w, r, c.State())
+}
+
+// swagger:route DELETE /admin/test-clock metadata resetTestClock
+//
+// # Reset the Test Clock
+//
+// Sets the time used for flow, code and session lifespans back to the wall clock time. Only
+// available if the test clock is enabled and Ory Kratos runs with the `--dev` flag.
+//
+//	Produces:
+//	- application/json
+//
+//	Schemes: http, https
+//
+//	Security:
+//	  oryAccessToken:
+//
+//	Responses:
+//	  200: testClock
+//	  404: errorGeneric
+//	  default: errorGeneric
+func (h *TestClockHandler) resetTestClock(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	c := h.clock(w, r)
+	if c == nil {
+		return
+	}
+
+	c.Reset()
+	h.d.Writer().Write(w, r, c.State())
+}
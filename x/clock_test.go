@@ -0,0 +1,79 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestClock(t *testing.T) {
+	t.Cleanup(DisableTestClock)
+	enabled := func() bool { return true }
+
+	t.Run("case=uses wall clock if disabled", func(t *testing.T) {
+		DisableTestClock()
+		assert.WithinDuration(t, time.Now(), Now(), time.Second)
+	})
+
+	t.Run("case=advances", func(t *testing.T) {
+		c := EnableTestClock(enabled)
+		t.Cleanup(c.Reset)
+
+		c.Advance(time.Hour)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), Now(), time.Second)
+		assert.Equal(t, "1h0m0s", c.State().Offset)
+		assert.False(t, c.State().Frozen)
+
+		c.Reset()
+		assert.WithinDuration(t, time.Now(), Now(), time.Second)
+	})
+
+	t.Run("case=freezes at a fixed time", func(t *testing.T) {
+		c := EnableTestClock(enabled)
+		t.Cleanup(c.Reset)
+
+		fixed := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		c.Set(fixed, true)
+		assert.Equal(t, fixed, Now())
+		assert.True(t, c.State().Frozen)
+
+		c.Advance(time.Minute)
+		assert.Equal(t, fixed.Add(time.Minute), Now())
+	})
+
+	t.Run("case=runs from a set time", func(t *testing.T) {
+		c := EnableTestClock(enabled)
+		t.Cleanup(c.Reset)
+
+		start := time.Now().Add(-24 * time.Hour)
+		c.Set(start, false)
+		first := Now()
+		assert.WithinDuration(t, start, first, time.Second)
+
+		time.Sleep(10 * time.Millisecond)
+		require.True(t, Now().After(first))
+	})
+
+	t.Run("case=uses wall clock once turned off", func(t *testing.T) {
+		var off atomic.Bool
+		DisableTestClock()
+		c := EnableTestClock(func() bool { return !off.Load() })
+
+		c.Advance(time.Hour)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), Now(), time.Second)
+
+		off.Store(true)
+		assert.WithinDuration(t, time.Now(), Now(), time.Second)
+		assert.NotSame(t, c, EnableTestClock(enabled), "a new test clock is started once enabled again")
+	})
+
+	t.Run("case=is shared", func(t *testing.T) {
+		assert.Same(t, EnableTestClock(enabled), EnableTestClock(enabled))
+	})
+}
@@ -36,6 +36,10 @@ const (
 	WebhookDelivered      semconv.Event = "WebhookDelivered"
 	WebhookSucceeded      semconv.Event = "WebhookSucceeded"
 	WebhookFailed         semconv.Event = "WebhookFailed"
+	FlowStateTransitioned semconv.Event = "FlowStateTransitioned"
+	SessionsExpired       semconv.Event = "SessionsExpired"
+	ConsentGranted        semconv.Event = "ConsentGranted"
+	ConsentWithdrawn      semconv.Event = "ConsentWithdrawn"
 )
 
 const (
@@ -53,12 +57,40 @@ const (
 	attributeKeyWebhookResponseStatusCode       semconv.AttributeKey = "WebhookResponseStatusCode"
 	attributeKeyWebhookAttemptNumber            semconv.AttributeKey = "WebhookAttemptNumber"
 	attributeKeyWebhookRequestID                semconv.AttributeKey = "WebhookRequestID"
+	attributeKeySelfServiceFlowID               semconv.AttributeKey = "SelfServiceFlowID"
+	attributeKeySelfServiceFlowName             semconv.AttributeKey = "SelfServiceFlowName"
+	attributeKeySelfServiceFlowStateFrom        semconv.AttributeKey = "SelfServiceFlowStateFrom"
+	attributeKeySelfServiceFlowStateTo          semconv.AttributeKey = "SelfServiceFlowStateTo"
+	attributeKeySessionIDs                      semconv.AttributeKey = "SessionIDs"
+	attributeKeySessionCount                    semconv.AttributeKey = "SessionCount"
+	attributeKeyConsentName                     semconv.AttributeKey = "ConsentName"
+	attributeKeyConsentVersion                  semconv.AttributeKey = "ConsentVersion"
 )
 
 func attrSessionID(val uuid.UUID) otelattr.KeyValue {
 	return otelattr.String(attributeKeySessionID.String(), val.String())
 }
 
+func attrSessionIDs(val []uuid.UUID) otelattr.KeyValue {
+	ids := make([]string, len(val))
+	for k, id := range val {
+		ids[k] = id.String()
+	}
+	return otelattr.StringSlice(attributeKeySessionIDs.String(), ids)
+}
+
+func attrSessionCount(val int) otelattr.KeyValue {
+	return otelattr.Int(attributeKeySessionCount.String(), val)
+}
+
+func attrConsentName(val string) otelattr.KeyValue {
+	return otelattr.String(attributeKeyConsentName.String(), val)
+}
+
+func attrConsentVersion(val string) otelattr.KeyValue {
+	return otelattr.String(attributeKeyConsentVersion.String(), val)
+}
+
 func attrTokenizedSessionTTL(ttl time.Duration) otelattr.KeyValue {
 	return otelattr.String(attributeKeyTokenizedSessionTTL.String(), ttl.String())
 }
@@ -79,6 +111,22 @@ func attrSelfServiceFlowType(val string) otelattr.KeyValue {
 	return otelattr.String(attributeKeySelfServiceFlowType.String(), val)
 }
 
+func attrSelfServiceFlowID(val uuid.UUID) otelattr.KeyValue {
+	return otelattr.String(attributeKeySelfServiceFlowID.String(), val.String())
+}
+
+func attrSelfServiceFlowName(val string) otelattr.KeyValue {
+	return otelattr.String(attributeKeySelfServiceFlowName.String(), val)
+}
+
+func attrSelfServiceFlowStateFrom(val string) otelattr.KeyValue {
+	return otelattr.String(attributeKeySelfServiceFlowStateFrom.String(), val)
+}
+
+func attrSelfServiceFlowStateTo(val string) otelattr.KeyValue {
+	return otelattr.String(attributeKeySelfServiceFlowStateTo.String(), val)
+}
+
 func attrSelfServiceMethodUsed(val string) otelattr.KeyValue {
 	return otelattr.String(attributeKeySelfServiceMethodUsed.String(), val)
 }
@@ -327,3 +375,50 @@ func NewWebhookFailed(ctx context.Context, err error) (string, trace.EventOption
 			)...,
 		)
 }
+
+func NewFlowStateTransitioned(ctx context.Context, flowID uuid.UUID, flowType, flowName, from, to string) (string, trace.EventOption) {
+	return FlowStateTransitioned.String(),
+		trace.WithAttributes(append(
+			semconv.AttributesFromContext(ctx),
+			attrSelfServiceFlowID(flowID),
+			attrSelfServiceFlowType(flowType),
+			attrSelfServiceFlowName(flowName),
+			attrSelfServiceFlowStateFrom(from),
+			attrSelfServiceFlowStateTo(to),
+		)...)
+}
+
+func NewSessionsExpired(ctx context.Context, sessionIDs []uuid.UUID) (string, trace.EventOption) {
+	return SessionsExpired.String(),
+		trace.WithAttributes(
+			append(
+				semconv.AttributesFromContext(ctx),
+				attrSessionIDs(sessionIDs),
+				attrSessionCount(len(sessionIDs)),
+			)...,
+		)
+}
+
+func NewConsentGranted(ctx context.Context, identityID uuid.UUID, name, version string) (string, trace.EventOption) {
+	return ConsentGranted.String(),
+		trace.WithAttributes(
+			append(
+				semconv.AttributesFromContext(ctx),
+				semconv.AttrIdentityID(identityID),
+				attrConsentName(name),
+				attrConsentVersion(version),
+			)...,
+		)
+}
+
+func NewConsentWithdrawn(ctx context.Context, identityID uuid.UUID, name, version string) (string, trace.EventOption) {
+	return ConsentWithdrawn.String(),
+		trace.WithAttributes(
+			append(
+				semconv.AttributesFromContext(ctx),
+				semconv.AttrIdentityID(identityID),
+				attrConsentName(name),
+				attrConsentVersion(version),
+			)...,
+		)
+}
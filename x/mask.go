@@ -0,0 +1,27 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MaskAddress masks an email address or phone number so that it can be shown to
+// a user who has not yet proven ownership of it, e.g. "j***@example.com" or "+49•••123".
+func MaskAddress(address string) string {
+	if at := strings.LastIndex(address, "@"); at > 0 {
+		first, _ := utf8.DecodeRuneInString(address)
+		return string(first) + "***" + address[at:]
+	}
+
+	if r := []rune(address); len(r) > 6 {
+		return string(r[:3]) + "•••" + string(r[len(r)-3:])
+	}
+
+	if address == "" {
+		return ""
+	}
+	return "•••"
+}
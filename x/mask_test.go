@@ -0,0 +1,25 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskAddress(t *testing.T) {
+	for _, tc := range []struct{ in, expected string }{
+		{in: "john@example.com", expected: "j***@example.com"},
+		{in: "j@example.com", expected: "j***@example.com"},
+		{in: "jöhn@example.com", expected: "j***@example.com"},
+		{in: "+4915112345123", expected: "+49•••123"},
+		{in: "123456", expected: "•••"},
+		{in: "", expected: ""},
+	} {
+		t.Run("case="+tc.in, func(t *testing.T) {
+			assert.Equal(t, tc.expected, MaskAddress(tc.in))
+		})
+	}
+}
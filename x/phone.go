@@ -0,0 +1,24 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+	"github.com/pkg/errors"
+)
+
+// NormalizePhoneNumber returns the phone number in E.164 format, e.g. "+4915112345678". The number
+// must include the country calling code.
+func NormalizePhoneNumber(raw string) (string, error) {
+	number, err := phonenumbers.Parse(strings.TrimSpace(raw), "")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if !phonenumbers.IsValidNumber(number) {
+		return "", errors.Errorf("%q is not a valid phone number", raw)
+	}
+	return phonenumbers.Format(number, phonenumbers.E164), nil
+}
@@ -0,0 +1,24 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package x
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	for _, raw := range []string{"+4915112345678", "+49 151 12345678", " +49 (151) 123-456-78 "} {
+		actual, err := NormalizePhoneNumber(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, "+4915112345678", actual, raw)
+	}
+
+	for _, raw := range []string{"", "015112345678", "+49", "not a number"} {
+		_, err := NormalizePhoneNumber(raw)
+		assert.Error(t, err, raw)
+	}
+}
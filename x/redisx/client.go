@@ -0,0 +1,25 @@
+// Copyright © 2023 Ory Corp
+// SPDX-License-Identifier: Apache-2.0
+
+package redisx
+
+import (
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// NewClient creates a client for a URL of the form `redis://[user:password@]host:port[/db]`. Use
+// the `rediss` scheme to connect with TLS.
+func NewClient(u string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(u)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse the redis URL")
+	}
+
+	if opts.Password == "" && opts.Username != "" {
+		// redis://password@host is a common shorthand.
+		opts.Password, opts.Username = opts.Username, ""
+	}
+
+	return redis.NewClient(opts), nil
+}
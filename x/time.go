@@ -4,6 +4,7 @@
 package x
 
 import (
+	"context"
 	"math"
 	"math/rand"
 	"testing"
@@ -40,3 +41,20 @@ func RandomDelay(base, deviation time.Duration) time.Duration {
 	boundedSample := math.Min(math.Max(sample, min), max)
 	return time.Duration(boundedSample)
 }
+
+// WaitAtLeast blocks until the duration has passed since start or the context is done. It pads the
+// response time of requests which would otherwise reveal which code path they took.
+func WaitAtLeast(ctx context.Context, start time.Time, d time.Duration) {
+	remaining := d - time.Since(start)
+	if remaining <= 0 {
+		return
+	}
+
+	t := time.NewTimer(remaining)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
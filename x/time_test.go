@@ -4,6 +4,7 @@
 package x
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -19,3 +20,19 @@ func TestRandomDelay(t *testing.T) {
 		require.GreaterOrEqual(t, delay, base-deviation)
 	}
 }
+
+func TestWaitAtLeast(t *testing.T) {
+	start := time.Now()
+	WaitAtLeast(context.Background(), start, 20*time.Millisecond)
+	require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	start = time.Now()
+	WaitAtLeast(context.Background(), start.Add(-time.Second), 20*time.Millisecond)
+	require.Less(t, time.Since(start), 20*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start = time.Now()
+	WaitAtLeast(ctx, start, time.Minute)
+	require.Less(t, time.Since(start), time.Second)
+}